@@ -0,0 +1,526 @@
+// Package activity watches how often each strategy trades. From the fills
+// journal it measures trade frequency, holding periods, churn and
+// expectancy per strategy and symbol, compares today's figures against
+// configured norms, and raises advisory notifications when a strategy
+// looks to be overtrading. It never blocks an order.
+package activity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/fills"
+)
+
+func logger() *slog.Logger { return slog.With("module", "activity") }
+
+// Untagged labels fills whose order carried no strategy tag.
+const Untagged = "untagged"
+
+// minCloses is how many closing trades today it takes before short holds
+// are judged, so one quick stop-out is not called churning.
+const minCloses = 3
+
+// Flags raised when a norm is breached.
+const (
+	FlagTrades     = "trades"      // more trades today than the norm
+	FlagShortHolds = "short_holds" // positions closed faster than the norm
+	FlagChurn      = "churn"       // shares traded today many times the position
+)
+
+// Norms are what a strategy's trading on one symbol is expected to stay
+// within each day. A zero field is not checked.
+type Norms struct {
+	MaxTradesPerDay int     `json:"max_trades_per_day"`
+	MinHoldMinutes  float64 `json:"min_hold_minutes"` // share-weighted average over today's closes
+	MaxChurn        float64 `json:"max_churn"`        // round trips of the peak position
+}
+
+// Validate reports the first invalid field.
+func (n Norms) Validate() error {
+	switch {
+	case n.MaxTradesPerDay < 0:
+		return errors.New("max_trades_per_day must not be negative")
+	case n.MinHoldMinutes < 0:
+		return errors.New("min_hold_minutes must not be negative")
+	case n.MaxChurn < 0:
+		return errors.New("max_churn must not be negative")
+	}
+	return nil
+}
+
+// Policy holds the norms and whether breaches are notified.
+type Policy struct {
+	Notify     bool             `json:"notify"`
+	Norms      Norms            `json:"norms"`                // for every strategy without its own
+	Strategies map[string]Norms `json:"strategies,omitempty"` // by strategy tag
+}
+
+// DefaultPolicy notifies when a strategy trades a symbol more than ten
+// times in a day, holds for under five minutes on average or turns its
+// position over more than six times.
+func DefaultPolicy() Policy {
+	return Policy{
+		Notify: true,
+		Norms:  Norms{MaxTradesPerDay: 10, MinHoldMinutes: 5, MaxChurn: 6},
+	}
+}
+
+// Validate reports the first invalid field.
+func (p Policy) Validate() error {
+	if err := p.Norms.Validate(); err != nil {
+		return err
+	}
+	for tag, n := range p.Strategies {
+		if strings.TrimSpace(tag) == "" {
+			return errors.New("strategy norms need a tag")
+		}
+		if err := n.Validate(); err != nil {
+			return fmt.Errorf("strategy %s: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+// NormsFor returns the norms that apply to strategy.
+func (p Policy) NormsFor(strategy string) Norms {
+	if n, ok := p.Strategies[strategy]; ok {
+		return n
+	}
+	return p.Norms
+}
+
+// Stats summarizes the fills of a group over a period. Holds and
+// expectancy come from closing trades, matched first in first out against
+// the opening fills before them.
+type Stats struct {
+	Trades         int     `json:"trades"`
+	Closes         int     `json:"closes"`
+	SharesTraded   float64 `json:"shares_traded"`
+	PeakPosition   float64 `json:"peak_position"` // largest absolute position held
+	Churn          float64 `json:"churn"`         // shares traded over twice the peak position; 1 is one full round trip
+	AvgHoldMinutes float64 `json:"avg_hold_minutes"`
+	RealizedPL     float64 `json:"realized_pl"`
+	Expectancy     float64 `json:"expectancy"` // realized P&L per closing trade
+	WinRate        float64 `json:"win_rate"`
+}
+
+// Activity is one strategy's trading, on one symbol or, with Symbol empty,
+// across all of them.
+type Activity struct {
+	Strategy     string   `json:"strategy"`
+	Symbol       string   `json:"symbol,omitempty"`
+	ActiveDays   int      `json:"active_days"`
+	TradesPerDay float64  `json:"trades_per_day"` // per day with a trade
+	Window       Stats    `json:"window"`
+	Today        Stats    `json:"today"`
+	Flags        []string `json:"flags"`
+	Advisory     string   `json:"advisory,omitempty"`
+}
+
+// Report is the activity of every strategy since a time.
+type Report struct {
+	Since      time.Time  `json:"since"`
+	Day        string     `json:"day"` // today, YYYY-MM-DD in market time
+	Flagged    int        `json:"flagged"`
+	ByStrategy []Activity `json:"by_strategy"`
+	BySymbol   []Activity `json:"by_symbol"` // flagged first, then by trades today
+}
+
+// accumulator gathers one period's figures for a group.
+type accumulator struct {
+	trades, closes, wins  int
+	shares, peak          float64
+	holdWeighted, holdQty float64
+	realized              float64
+	days                  map[string]bool
+}
+
+func (a *accumulator) stats() Stats {
+	s := Stats{
+		Trades:       a.trades,
+		Closes:       a.closes,
+		SharesTraded: a.shares,
+		PeakPosition: a.peak,
+		RealizedPL:   round(a.realized),
+	}
+	if a.peak > 0 {
+		s.Churn = round(a.shares / (2 * a.peak))
+	}
+	if a.holdQty > 0 {
+		s.AvgHoldMinutes = round(a.holdWeighted / a.holdQty)
+	}
+	if a.closes > 0 {
+		s.Expectancy = round(a.realized / float64(a.closes))
+		s.WinRate = round(float64(a.wins) / float64(a.closes))
+	}
+	return s
+}
+
+func (a *accumulator) merge(b *accumulator) {
+	a.trades += b.trades
+	a.closes += b.closes
+	a.wins += b.wins
+	a.shares += b.shares
+	a.peak += b.peak
+	a.holdWeighted += b.holdWeighted
+	a.holdQty += b.holdQty
+	a.realized += b.realized
+	for d := range b.days {
+		a.days[d] = true
+	}
+}
+
+func newAccumulator() *accumulator { return &accumulator{days: make(map[string]bool)} }
+
+// lot is an open piece of a position; qty is negative for a short.
+type lot struct {
+	qty, price float64
+	at         time.Time
+}
+
+// book pairs one group's fills and accumulates the window and today.
+type book struct {
+	lots          []lot
+	position      float64
+	window, today *accumulator
+}
+
+type groupKey struct{ strategy, symbol string }
+
+// fillTime is when r filled.
+func fillTime(r fills.Record) time.Time {
+	if r.FilledAt != nil {
+		return *r.FilledAt
+	}
+	return r.FinishedAt
+}
+
+// Strategy is the strategy label of r.
+func Strategy(r fills.Record) string {
+	if r.Tag == "" {
+		return Untagged
+	}
+	return r.Tag
+}
+
+// Analyze measures records against policy. Fills before since still open
+// the positions later fills close, but only fills at or after since are
+// counted. Today is now's date in loc. Pure.
+func Analyze(records []fills.Record, since, now time.Time, loc *time.Location, policy Policy) Report {
+	if loc == nil {
+		loc = time.UTC
+	}
+	day := now.In(loc).Format("2006-01-02")
+	filled := make([]fills.Record, 0, len(records))
+	for _, r := range records {
+		if r.FilledQty > 0 && r.FillPrice > 0 {
+			filled = append(filled, r)
+		}
+	}
+	sort.SliceStable(filled, func(i, j int) bool { return fillTime(filled[i]).Before(fillTime(filled[j])) })
+
+	books := make(map[groupKey]*book)
+	for _, r := range filled {
+		k := groupKey{Strategy(r), strings.ToUpper(r.Symbol)}
+		b, ok := books[k]
+		if !ok {
+			b = &book{window: newAccumulator(), today: newAccumulator()}
+			books[k] = b
+		}
+		at := fillTime(r)
+		var accs []*accumulator
+		if !at.Before(since) {
+			accs = append(accs, b.window)
+			if at.In(loc).Format("2006-01-02") == day {
+				accs = append(accs, b.today)
+			}
+		}
+		b.apply(r, at, loc, accs)
+	}
+
+	report := Report{Since: since, Day: day, ByStrategy: []Activity{}, BySymbol: []Activity{}}
+	byStrategy := make(map[string][2]*accumulator)
+	for k, b := range books {
+		if b.window.trades == 0 {
+			continue
+		}
+		a := activity(k.strategy, k.symbol, b.window, b.today)
+		a.Flags, a.Advisory = check(a, policy.NormsFor(k.strategy))
+		if len(a.Flags) > 0 {
+			report.Flagged++
+		}
+		report.BySymbol = append(report.BySymbol, a)
+
+		s, ok := byStrategy[k.strategy]
+		if !ok {
+			s = [2]*accumulator{newAccumulator(), newAccumulator()}
+			byStrategy[k.strategy] = s
+		}
+		s[0].merge(b.window)
+		s[1].merge(b.today)
+	}
+	for strategy, s := range byStrategy {
+		a := activity(strategy, "", s[0], s[1])
+		a.Flags = []string{}
+		report.ByStrategy = append(report.ByStrategy, a)
+	}
+	sort.Slice(report.BySymbol, func(i, j int) bool {
+		a, b := report.BySymbol[i], report.BySymbol[j]
+		if (len(a.Flags) > 0) != (len(b.Flags) > 0) {
+			return len(a.Flags) > 0
+		}
+		if a.Today.Trades != b.Today.Trades {
+			return a.Today.Trades > b.Today.Trades
+		}
+		if a.Strategy != b.Strategy {
+			return a.Strategy < b.Strategy
+		}
+		return a.Symbol < b.Symbol
+	})
+	sort.Slice(report.ByStrategy, func(i, j int) bool { return report.ByStrategy[i].Strategy < report.ByStrategy[j].Strategy })
+	return report
+}
+
+func activity(strategy, symbol string, window, today *accumulator) Activity {
+	a := Activity{
+		Strategy:   strategy,
+		Symbol:     symbol,
+		ActiveDays: len(window.days),
+		Window:     window.stats(),
+		Today:      today.stats(),
+	}
+	if a.ActiveDays > 0 {
+		a.TradesPerDay = round(float64(window.trades) / float64(a.ActiveDays))
+	}
+	return a
+}
+
+// apply folds one fill into the book, closing open lots of the opposite
+// side first in first out, and counts it in accs.
+func (b *book) apply(r fills.Record, at time.Time, loc *time.Location, accs []*accumulator) {
+	qty := r.FilledQty
+	sign := 1.0
+	if strings.EqualFold(r.Side, "sell") {
+		sign = -1
+	}
+	closed, pl, holdWeighted := 0.0, 0.0, 0.0
+	remaining := qty
+	for remaining > 0 && len(b.lots) > 0 && b.lots[0].qty*sign < 0 {
+		l := &b.lots[0]
+		take := math.Min(remaining, math.Abs(l.qty))
+		// A closing sell gains on a long lot; a closing buy on a short one
+		pl += take * (r.FillPrice - l.price) * -sign
+		holdWeighted += take * at.Sub(l.at).Minutes()
+		closed += take
+		remaining -= take
+		if l.qty > 0 {
+			l.qty -= take
+		} else {
+			l.qty += take
+		}
+		if math.Abs(l.qty) < 1e-9 {
+			b.lots = b.lots[1:]
+		}
+	}
+	if remaining > 0 {
+		b.lots = append(b.lots, lot{qty: remaining * sign, price: r.FillPrice, at: at})
+	}
+	b.position += qty * sign
+
+	for _, a := range accs {
+		a.trades++
+		a.shares += qty
+		a.peak = math.Max(a.peak, math.Abs(b.position))
+		a.days[at.In(loc).Format("2006-01-02")] = true
+		if closed > 0 {
+			a.closes++
+			a.realized += pl
+			if pl > 0 {
+				a.wins++
+			}
+			a.holdWeighted += holdWeighted
+			a.holdQty += closed
+		}
+	}
+}
+
+// check compares today's figures against n and describes any breach.
+func check(a Activity, n Norms) ([]string, string) {
+	flags := []string{}
+	var parts []string
+	t := a.Today
+	if n.MaxTradesPerDay > 0 && t.Trades > n.MaxTradesPerDay {
+		flags = append(flags, FlagTrades)
+	}
+	if n.MinHoldMinutes > 0 && t.Closes >= minCloses && t.AvgHoldMinutes < n.MinHoldMinutes {
+		flags = append(flags, FlagShortHolds)
+		parts = append(parts, fmt.Sprintf("holding %.1f minutes on average", t.AvgHoldMinutes))
+	}
+	if n.MaxChurn > 0 && t.Churn > n.MaxChurn {
+		flags = append(flags, FlagChurn)
+		parts = append(parts, fmt.Sprintf("turning the position over %.1f times", t.Churn))
+	}
+	if len(flags) == 0 {
+		return flags, ""
+	}
+	msg := fmt.Sprintf("strategy %s has traded %s %d times today", a.Strategy, a.Symbol, t.Trades)
+	switch {
+	case t.Closes == 0:
+	case t.Expectancy < 0:
+		msg += fmt.Sprintf(" with negative expectancy ($%.2f per trade)", t.Expectancy)
+	default:
+		msg += fmt.Sprintf(" with expectancy of $%.2f per trade", t.Expectancy)
+	}
+	if len(parts) > 0 {
+		msg += ", " + strings.Join(parts, ", ")
+	}
+	return flags, msg
+}
+
+func round(v float64) float64 { return math.Round(v*100) / 100 }
+
+// Source returns the finished fill records submitted at or after since.
+type Source func(since time.Time) []fills.Record
+
+// Notifier raises an advisory notification.
+type Notifier func(title, message string, metadata map[string]interface{})
+
+// Manager keeps the policy, builds reports from the fills journal and
+// notifies each breach once a day. It is safe for concurrent use.
+type Manager struct {
+	path   string
+	source Source
+	loc    *time.Location
+
+	mu       sync.Mutex
+	policy   Policy
+	notify   Notifier
+	notified map[string]string // strategy, symbol and flag → day last notified
+	now      func() time.Time
+}
+
+// New opens the policy saved at path, starting from DefaultPolicy when
+// there is none. Fills come from source; days are counted in loc.
+func New(path string, source Source, loc *time.Location) (*Manager, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create activity directory: %w", err)
+	}
+	m := &Manager{path: path, source: source, loc: loc, policy: DefaultPolicy(), notified: make(map[string]string), now: time.Now}
+	if data, err := os.ReadFile(path); err == nil {
+		policy := DefaultPolicy()
+		if err := json.Unmarshal(data, &policy); err != nil {
+			return nil, fmt.Errorf("failed to decode activity policy: %w", err)
+		}
+		if err := policy.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid activity policy: %w", err)
+		}
+		m.policy = policy
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read activity policy: %w", err)
+	}
+	return m, nil
+}
+
+// SetClock replaces the clock, for replays and tests.
+func (m *Manager) SetClock(now func() time.Time) { m.mu.Lock(); m.now = now; m.mu.Unlock() }
+
+// SetNotifier sets where advisories go.
+func (m *Manager) SetNotifier(fn Notifier) { m.mu.Lock(); m.notify = fn; m.mu.Unlock() }
+
+// Policy returns the current policy.
+func (m *Manager) Policy() Policy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.policy
+}
+
+// SetPolicy validates, applies and saves p.
+func (m *Manager) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policy = p
+	return m.saveLocked()
+}
+
+// Report analyzes the last days days of fills.
+func (m *Manager) Report(days int) Report {
+	m.mu.Lock()
+	now, policy := m.now(), m.policy
+	m.mu.Unlock()
+	since := now.AddDate(0, 0, -days)
+	// Earlier fills open the positions the window's fills close
+	return Analyze(m.source(time.Time{}), since, now, m.loc, policy)
+}
+
+// Observe checks the strategy and symbol of a new fill against the norms
+// and notifies breaches not already notified today.
+func (m *Manager) Observe(r fills.Record) {
+	m.mu.Lock()
+	now, policy, notify := m.now(), m.policy, m.notify
+	m.mu.Unlock()
+	if !policy.Notify || notify == nil {
+		return
+	}
+	strategy, symbol := Strategy(r), strings.ToUpper(r.Symbol)
+	var records []fills.Record
+	for _, rec := range m.source(time.Time{}) {
+		if Strategy(rec) == strategy && strings.EqualFold(rec.Symbol, symbol) {
+			records = append(records, rec)
+		}
+	}
+	day := now.In(m.loc)
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, m.loc)
+	report := Analyze(records, start, now, m.loc, policy)
+	for _, a := range report.BySymbol {
+		var fresh []string
+		m.mu.Lock()
+		for _, flag := range a.Flags {
+			key := a.Strategy + "|" + a.Symbol + "|" + flag
+			if m.notified[key] != report.Day {
+				m.notified[key] = report.Day
+				fresh = append(fresh, flag)
+			}
+		}
+		m.mu.Unlock()
+		if len(fresh) == 0 {
+			continue
+		}
+		logger().Warn("Possible overtrading", "strategy", a.Strategy, "symbol", a.Symbol, "flags", a.Flags, "trades_today", a.Today.Trades)
+		notify("Possible overtrading: "+a.Strategy+" on "+a.Symbol, a.Advisory, map[string]interface{}{
+			"strategy": a.Strategy,
+			"symbol":   a.Symbol,
+			"flags":    a.Flags,
+			"today":    a.Today,
+			"norms":    policy.NormsFor(a.Strategy),
+			"advisory": true,
+		})
+	}
+}
+
+func (m *Manager) saveLocked() error {
+	data, err := json.MarshalIndent(m.policy, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode activity policy: %w", err)
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write activity policy: %w", err)
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		return fmt.Errorf("failed to save activity policy: %w", err)
+	}
+	return nil
+}
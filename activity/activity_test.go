@@ -0,0 +1,89 @@
+package activity
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/fills"
+)
+
+func fill(tag, side string, qty, price float64, at time.Time) fills.Record {
+	return fills.Record{Tag: tag, Symbol: "AAPL", Side: side, Qty: qty, FilledQty: qty, FillPrice: price, SubmittedAt: at, FilledAt: &at, FinishedAt: at}
+}
+
+func TestAnalyzePairsFillsFirstInFirstOut(t *testing.T) {
+	loc := time.UTC
+	day := time.Date(2026, 3, 2, 14, 0, 0, 0, loc)
+	records := []fills.Record{
+		// Opened yesterday, before the window
+		fill("momo", "buy", 100, 10, day.Add(-24*time.Hour)),
+		fill("momo", "sell", 50, 11, day),
+		fill("momo", "sell", 100, 12, day.Add(30*time.Minute)), // closes 50, opens a 50 short
+		fill("momo", "buy", 50, 13, day.Add(90*time.Minute)),
+		fill("", "buy", 10, 5, day),
+	}
+	r := Analyze(records, day.Add(-time.Hour), day.Add(2*time.Hour), loc, DefaultPolicy())
+	if len(r.BySymbol) != 2 || len(r.ByStrategy) != 2 {
+		t.Fatalf("groups = %+v", r)
+	}
+	var momo Activity
+	for _, a := range r.BySymbol {
+		if a.Strategy == "momo" {
+			momo = a
+		}
+	}
+	s := momo.Today
+	// 50 × $1 + 50 × $2 - 50 × $1 on the short
+	if s.Trades != 3 || s.Closes != 3 || s.RealizedPL != 100 || s.Expectancy != 33.33 || s.WinRate != 0.67 {
+		t.Fatalf("today = %+v", s)
+	}
+	if s.SharesTraded != 200 || s.PeakPosition != 50 || s.Churn != 2 {
+		t.Fatalf("turnover = %+v", s)
+	}
+	if r.ByStrategy[1].Strategy != Untagged || r.Flagged != 0 {
+		t.Fatalf("by strategy = %+v, flagged %d", r.ByStrategy, r.Flagged)
+	}
+}
+
+func TestObserveNotifiesOvertradingOncePerDay(t *testing.T) {
+	loc := time.UTC
+	start := time.Date(2026, 3, 2, 14, 0, 0, 0, loc)
+	var records []fills.Record
+	m, err := New(filepath.Join(t.TempDir(), "policy.json"), func(time.Time) []fills.Record { return records }, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := start
+	m.SetClock(func() time.Time { return now })
+	var messages []string
+	m.SetNotifier(func(title, message string, metadata map[string]interface{}) { messages = append(messages, message) })
+
+	policy := m.Policy()
+	policy.Strategies = map[string]Norms{"scalper": {MaxTradesPerDay: 4}}
+	if err := m.SetPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 8; i++ {
+		side, price := "buy", 10.0
+		if i%2 == 1 {
+			side, price = "sell", 9.9
+		}
+		now = start.Add(time.Duration(i) * time.Minute)
+		records = append(records, fill("scalper", side, 10, price, now))
+		m.Observe(records[len(records)-1])
+	}
+	if len(messages) != 1 || !strings.Contains(messages[0], "strategy scalper has traded AAPL 5 times today with negative expectancy") {
+		t.Fatalf("messages = %q", messages)
+	}
+
+	// The saved norms come back on a restart
+	reopened, err := New(m.path, m.source, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Policy().NormsFor("scalper").MaxTradesPerDay != 4 {
+		t.Fatalf("policy = %+v", reopened.Policy())
+	}
+}
@@ -0,0 +1,67 @@
+package activity
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// Handler exposes trading activity over HTTP.
+type Handler struct {
+	manager *Manager
+}
+
+// NewHandler creates a handler for manager.
+func NewHandler(manager *Manager) *Handler {
+	return &Handler{manager: manager}
+}
+
+// RegisterRoutes registers the activity routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/reports/activity?days=30 - trade frequency, holds, churn and expectancy per strategy and symbol
+	mux.HandleFunc("/api/reports/activity", h.handleReport)
+
+	// GET/POST /api/reports/activity/policy - read or update the overtrading norms
+	mux.HandleFunc("/api/reports/activity/policy", h.handlePolicy)
+}
+
+func (h *Handler) handleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	days := 30
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "days must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"policy": h.manager.Policy(),
+		"report": h.manager.Report(days),
+	})
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.manager.Policy())
+	case http.MethodPost, http.MethodPut:
+		// Start from the current policy so partial updates work
+		policy := h.manager.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.manager.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(policy)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
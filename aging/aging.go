@@ -0,0 +1,392 @@
+// Package aging tracks how long each position has been held. A position's
+// age runs from the fill that opened it in the fills journal, and one held
+// for more sessions than its strategy's horizon — a triple barrier whose
+// time barrier passed without an exit, say — is flagged as stale, notified
+// once a session and, when the policy says so, closed.
+package aging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/calendar"
+	"github.com/rileyseaburg/go-trader/fills"
+	"github.com/rileyseaburg/go-trader/gaprisk"
+)
+
+func logger() *slog.Logger { return slog.With("module", "aging") }
+
+// Actions on a stale position.
+const (
+	ActionOff    = "off"
+	ActionNotify = "notify" // alert and suggest a review
+	ActionClose  = "close"  // alert and close the position at market
+)
+
+// Policy sets how long positions may be held and what happens after.
+type Policy struct {
+	Action string `json:"action"` // off, notify or close
+	// DefaultHorizon is how many sessions a position may be held when its
+	// strategy has no horizon of its own
+	DefaultHorizon int `json:"default_horizon"`
+	// Horizons are sessions by strategy tag, as prefixed to client order IDs
+	Horizons map[string]int `json:"horizons,omitempty"`
+}
+
+// DefaultPolicy notifies about positions held past five sessions, the
+// triple barrier's default time horizon.
+func DefaultPolicy() Policy {
+	return Policy{Action: ActionNotify, DefaultHorizon: 5, Horizons: map[string]int{}}
+}
+
+// Validate reports the first invalid field.
+func (p Policy) Validate() error {
+	switch p.Action {
+	case ActionOff, ActionNotify, ActionClose:
+	default:
+		return fmt.Errorf("action must be one of %s, %s, %s", ActionOff, ActionNotify, ActionClose)
+	}
+	if p.DefaultHorizon < 1 {
+		return errors.New("default_horizon must be at least 1 session")
+	}
+	for tag, n := range p.Horizons {
+		if strings.TrimSpace(tag) == "" {
+			return errors.New("horizons must be keyed by a strategy tag")
+		}
+		if n < 1 {
+			return fmt.Errorf("horizon for %s must be at least 1 session", tag)
+		}
+	}
+	return nil
+}
+
+// horizon is the sessions a position opened by tag may be held.
+func (p Policy) horizon(tag string) int {
+	if n, ok := p.Horizons[tag]; ok && tag != "" {
+		return n
+	}
+	return p.DefaultHorizon
+}
+
+// Source returns the finished fill records submitted at or after since.
+type Source func(since time.Time) []fills.Record
+
+// Notifier is told about stale positions and closes.
+type Notifier func(title, message string, metadata map[string]interface{})
+
+// Opening is when the journal shows a position opening.
+type Opening struct {
+	Symbol   string    `json:"symbol"`
+	Qty      float64   `json:"qty"` // negative for shorts
+	Tag      string    `json:"tag,omitempty"`
+	OpenedAt time.Time `json:"opened_at"`
+}
+
+// OpenPositions walks records, oldest first, and returns the positions
+// open as of now by symbol. A position opens on the fill that takes it
+// from flat, or through flat to the other side; adding to it keeps the
+// first fill's time and tag. Positions are taken as flat at the start of
+// the journal. Pure.
+func OpenPositions(records []fills.Record, now time.Time) map[string]Opening {
+	filled := make([]fills.Record, 0, len(records))
+	for _, r := range records {
+		if r.FilledQty > 0 {
+			filled = append(filled, r)
+		}
+	}
+	sort.SliceStable(filled, func(i, j int) bool { return filledAt(filled[i]).Before(filledAt(filled[j])) })
+
+	open := make(map[string]Opening)
+	for _, r := range filled {
+		at := filledAt(r)
+		if at.After(now) {
+			break
+		}
+		sym := strings.ToUpper(r.Symbol)
+		qty := r.FilledQty
+		if strings.EqualFold(r.Side, "sell") {
+			qty = -qty
+		}
+		o := open[sym]
+		position := o.Qty + qty
+		switch {
+		case math.Abs(position) < 1e-9:
+			delete(open, sym)
+			continue
+		case o.Qty == 0 || o.Qty*position < 0:
+			o = Opening{Symbol: sym, Tag: r.Tag, OpenedAt: at}
+		}
+		o.Qty = position
+		open[sym] = o
+	}
+	return open
+}
+
+// Age is how long a held position has been open.
+type Age struct {
+	Symbol string  `json:"symbol"`
+	Qty    float64 `json:"qty"`
+	Side   string  `json:"side"`
+	Tag    string  `json:"tag,omitempty"`
+	// OpenedAt is nil when the journal does not show the position
+	// opening, as for one opened before the journal or elsewhere; such a
+	// position has no age and is never stale
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+	AgeHours float64    `json:"age_hours"`
+	// Sessions are the sessions since the one the position opened in
+	Sessions int  `json:"sessions"`
+	Horizon  int  `json:"horizon"`
+	Stale    bool `json:"stale"` // held more sessions than the horizon
+}
+
+// Status is the positions' ages at /api/positions/aging.
+type Status struct {
+	Policy    Policy `json:"policy"`
+	Positions []Age  `json:"positions"`
+	Stale     int    `json:"stale"`
+	// BrokerError is set when held positions could not be read and the
+	// journal's were used instead
+	BrokerError string `json:"broker_error,omitempty"`
+}
+
+// Monitor ages positions and acts on stale ones. It is safe for
+// concurrent use.
+type Monitor struct {
+	path   string
+	source Source
+	cal    *calendar.Calendar
+
+	mu       sync.Mutex
+	policy   Policy
+	broker   gaprisk.Broker
+	notify   Notifier
+	notified map[string]string // symbol → session last acted on
+	now      func() time.Time
+}
+
+// New opens the policy saved at path, starting from DefaultPolicy when
+// there is none. Fills come from source; sessions from cal.
+func New(path string, source Source, cal *calendar.Calendar) (*Monitor, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create aging directory: %w", err)
+	}
+	m := &Monitor{path: path, source: source, cal: cal, policy: DefaultPolicy(), notified: make(map[string]string), now: time.Now}
+	if data, err := os.ReadFile(path); err == nil {
+		policy := DefaultPolicy()
+		if err := json.Unmarshal(data, &policy); err != nil {
+			return nil, fmt.Errorf("failed to decode aging policy: %w", err)
+		}
+		if err := policy.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid aging policy: %w", err)
+		}
+		m.policy = policy
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read aging policy: %w", err)
+	}
+	return m, nil
+}
+
+// SetClock replaces the clock, for replays and tests.
+func (m *Monitor) SetClock(now func() time.Time) { m.mu.Lock(); m.now = now; m.mu.Unlock() }
+
+// SetBroker wires the broker that lists held positions and closes stale
+// ones. Without one, the journal's open positions are aged and nothing is
+// closed.
+func (m *Monitor) SetBroker(b gaprisk.Broker) { m.mu.Lock(); m.broker = b; m.mu.Unlock() }
+
+// SetNotifier sets where stale positions are reported.
+func (m *Monitor) SetNotifier(fn Notifier) { m.mu.Lock(); m.notify = fn; m.mu.Unlock() }
+
+// Policy returns a copy of the current policy.
+func (m *Monitor) Policy() Policy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p := m.policy
+	p.Horizons = make(map[string]int, len(m.policy.Horizons))
+	for tag, n := range m.policy.Horizons {
+		p.Horizons[tag] = n
+	}
+	return p
+}
+
+// SetPolicy validates, applies and saves p.
+func (m *Monitor) SetPolicy(p Policy) error {
+	if p.Horizons == nil {
+		p.Horizons = map[string]int{}
+	}
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policy = p
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode aging policy: %w", err)
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to save aging policy: %w", err)
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		return fmt.Errorf("failed to save aging policy: %w", err)
+	}
+	return nil
+}
+
+// Ages returns the age of every position the journal shows open, by
+// symbol, without asking the broker.
+func (m *Monitor) Ages() map[string]Age {
+	m.mu.Lock()
+	now, policy := m.now(), m.policy
+	m.mu.Unlock()
+	out := make(map[string]Age)
+	for sym, o := range OpenPositions(m.source(time.Time{}), now) {
+		out[sym] = m.age(policy, sym, o.Qty, o, true, now)
+	}
+	return out
+}
+
+// Status ages the held positions, read from the broker when one is set.
+func (m *Monitor) Status(ctx context.Context) Status {
+	m.mu.Lock()
+	now, policy, broker := m.now(), m.policy, m.broker
+	m.mu.Unlock()
+	openings := OpenPositions(m.source(time.Time{}), now)
+
+	s := Status{Policy: policy, Positions: []Age{}}
+	var held []gaprisk.Position
+	if broker != nil {
+		positions, err := broker.Positions(ctx)
+		if err == nil {
+			held = positions
+		} else {
+			s.BrokerError = err.Error()
+		}
+	}
+	if broker == nil || s.BrokerError != "" {
+		for sym, o := range openings {
+			held = append(held, gaprisk.Position{Symbol: sym, Qty: o.Qty})
+		}
+	}
+	for _, p := range held {
+		sym := strings.ToUpper(p.Symbol)
+		o, ok := openings[sym]
+		a := m.age(policy, sym, p.Qty, o, ok, now)
+		if a.Stale {
+			s.Stale++
+		}
+		s.Positions = append(s.Positions, a)
+	}
+	sort.Slice(s.Positions, func(i, j int) bool { return s.Positions[i].Symbol < s.Positions[j].Symbol })
+	return s
+}
+
+// age dates a position of qty from o when the journal shows it open on
+// the same side.
+func (m *Monitor) age(policy Policy, symbol string, qty float64, o Opening, known bool, now time.Time) Age {
+	a := Age{Symbol: symbol, Qty: qty, Side: "long", Horizon: policy.DefaultHorizon}
+	if qty < 0 {
+		a.Side = "short"
+	}
+	if !known || o.Qty*qty <= 0 {
+		return a
+	}
+	opened := o.OpenedAt
+	a.Tag = o.Tag
+	a.OpenedAt = &opened
+	a.AgeHours = math.Round(now.Sub(opened).Hours()*100) / 100
+	a.Sessions = m.cal.TradingDaysBetween(opened, now)
+	a.Horizon = policy.horizon(o.Tag)
+	a.Stale = a.Sessions > a.Horizon
+	return a
+}
+
+// Check acts on stale positions under the policy: each is notified once
+// a session and, in close mode, closed at market while the market is
+// open.
+func (m *Monitor) Check(ctx context.Context) {
+	s := m.Status(ctx)
+	if s.Policy.Action == ActionOff || s.Stale == 0 {
+		return
+	}
+	m.mu.Lock()
+	now, broker, notify := m.now(), m.broker, m.notify
+	m.mu.Unlock()
+	closing := s.Policy.Action == ActionClose && broker != nil && s.BrokerError == ""
+	if closing && !m.cal.IsOpen(now) {
+		// Wait for the session so the close is not queued overnight
+		return
+	}
+	session := now.In(m.cal.Location()).Format("2006-01-02")
+
+	for _, a := range s.Positions {
+		if !a.Stale {
+			continue
+		}
+		m.mu.Lock()
+		seen := m.notified[a.Symbol] == session
+		m.notified[a.Symbol] = session
+		m.mu.Unlock()
+		if seen {
+			continue
+		}
+		reason := fmt.Sprintf("held %d sessions, past the %d-session horizon", a.Sessions, a.Horizon)
+		title, msg := "Stale position", fmt.Sprintf("%s %s has been %s; review whether to close it.", a.Side, a.Symbol, reason)
+		meta := map[string]interface{}{"symbol": a.Symbol, "sessions": a.Sessions, "horizon": a.Horizon, "tag": a.Tag, "opened_at": a.OpenedAt}
+		if closing {
+			plan := gaprisk.PlanReductions(gaprisk.Policy{Mode: gaprisk.ModeFlatten}, []gaprisk.Position{{Symbol: a.Symbol, Qty: a.Qty}}, "stale position: "+reason)
+			var err error
+			for _, r := range plan {
+				if err = broker.Reduce(ctx, r); err != nil {
+					break
+				}
+			}
+			if err != nil {
+				logger().Error("Failed to close stale position", "symbol", a.Symbol, "error", err)
+				title, msg = "Stale position close failed", fmt.Sprintf("%s %s has been %s; closing it failed: %v", a.Side, a.Symbol, reason, err)
+				meta["error"] = err.Error()
+			} else {
+				logger().Warn("Stale position closed", "symbol", a.Symbol, "sessions", a.Sessions, "horizon", a.Horizon)
+				title, msg = "Stale position closed", fmt.Sprintf("%s %s has been %s; submitted an order to close it.", a.Side, a.Symbol, reason)
+			}
+		} else {
+			logger().Warn("Stale position", "symbol", a.Symbol, "sessions", a.Sessions, "horizon", a.Horizon)
+		}
+		if notify != nil {
+			notify(title, msg, meta)
+		}
+	}
+}
+
+// Run checks positions every minute until ctx is cancelled.
+func (m *Monitor) Run(ctx context.Context) {
+	t := time.NewTicker(time.Minute)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			m.Check(ctx)
+		}
+	}
+}
+
+// filledAt is when r filled.
+func filledAt(r fills.Record) time.Time {
+	if r.FilledAt != nil {
+		return *r.FilledAt
+	}
+	return r.FinishedAt
+}
@@ -0,0 +1,185 @@
+package aging
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/calendar"
+	"github.com/rileyseaburg/go-trader/fills"
+	"github.com/rileyseaburg/go-trader/gaprisk"
+)
+
+func fill(symbol, side, tag string, qty float64, at time.Time) fills.Record {
+	return fills.Record{Symbol: symbol, Side: side, Tag: tag, FilledQty: qty, FillPrice: 100, FilledAt: &at, Outcome: fills.OutcomeFilled}
+}
+
+type fakeBroker struct {
+	positions []gaprisk.Position
+	reduced   []gaprisk.Reduction
+	err       error
+}
+
+func (b *fakeBroker) Positions(ctx context.Context) ([]gaprisk.Position, error) {
+	return b.positions, b.err
+}
+
+func (b *fakeBroker) Reduce(ctx context.Context, r gaprisk.Reduction) error {
+	b.reduced = append(b.reduced, r)
+	return nil
+}
+
+func TestOpenPositions(t *testing.T) {
+	at := func(day, hour int) time.Time { return time.Date(2025, 3, day, hour, 0, 0, 0, time.UTC) }
+	records := []fills.Record{
+		// Added to: the first fill dates it
+		fill("AAPL", "buy", "tb", 5, at(3, 15)), fill("AAPL", "buy", "", 5, at(4, 15)),
+		// Round trip, then opened again
+		fill("MSFT", "buy", "tb", 5, at(3, 15)), fill("MSFT", "sell", "tb", 5, at(4, 15)),
+		fill("MSFT", "buy", "mr", 2, at(5, 15)),
+		// Long flipped short through flat
+		fill("TSLA", "buy", "tb", 2, at(3, 15)), fill("TSLA", "sell", "mr", 5, at(6, 15)),
+		// After now
+		fill("GME", "buy", "tb", 1, at(10, 15)),
+	}
+	got := OpenPositions(records, at(7, 12))
+	if len(got) != 3 {
+		t.Fatalf("open = %+v", got)
+	}
+	for sym, want := range map[string]Opening{
+		"AAPL": {Symbol: "AAPL", Qty: 10, Tag: "tb", OpenedAt: at(3, 15)},
+		"MSFT": {Symbol: "MSFT", Qty: 2, Tag: "mr", OpenedAt: at(5, 15)},
+		"TSLA": {Symbol: "TSLA", Qty: -3, Tag: "mr", OpenedAt: at(6, 15)},
+	} {
+		if o := got[sym]; o.Qty != want.Qty || o.Tag != want.Tag || !o.OpenedAt.Equal(want.OpenedAt) {
+			t.Errorf("%s = %+v, want %+v", sym, o, want)
+		}
+	}
+}
+
+func TestMonitorFlagsAndClosesStalePositions(t *testing.T) {
+	cal := calendar.New()
+	loc := cal.Location()
+	// Monday 3 March to Tuesday 11 March 2025 is six sessions
+	now := time.Date(2025, 3, 11, 11, 0, 0, 0, loc)
+	records := []fills.Record{
+		fill("AAPL", "buy", "tb", 10, time.Date(2025, 3, 3, 10, 0, 0, 0, loc)),
+		fill("MSFT", "sell", "mr", 4, time.Date(2025, 3, 3, 10, 0, 0, 0, loc)),
+		fill("NVDA", "buy", "tb", 1, time.Date(2025, 3, 10, 10, 0, 0, 0, loc)),
+	}
+	m, err := New(filepath.Join(t.TempDir(), "policy.json"), func(time.Time) []fills.Record { return records }, cal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetClock(func() time.Time { return now })
+	var notes []string
+	m.SetNotifier(func(title, _ string, _ map[string]interface{}) { notes = append(notes, title) })
+	if err := m.SetPolicy(Policy{Action: ActionNotify, DefaultHorizon: 5, Horizons: map[string]int{"mr": 10}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// AAPL, past its default horizon, is stale; MSFT's strategy allows
+	// ten sessions; NVDA was opened yesterday; GME is not in the journal
+	broker := &fakeBroker{positions: []gaprisk.Position{{Symbol: "AAPL", Qty: 10}, {Symbol: "MSFT", Qty: -4}, {Symbol: "NVDA", Qty: 1}, {Symbol: "GME", Qty: 3}}}
+	m.SetBroker(broker)
+	s := m.Status(context.Background())
+	if s.Stale != 1 || len(s.Positions) != 4 {
+		t.Fatalf("status = %+v", s)
+	}
+	bySymbol := map[string]Age{}
+	for _, a := range s.Positions {
+		bySymbol[a.Symbol] = a
+	}
+	if a := bySymbol["AAPL"]; !a.Stale || a.Sessions != 6 || a.Horizon != 5 || a.Tag != "tb" || a.OpenedAt == nil {
+		t.Errorf("AAPL = %+v", a)
+	}
+	if a := bySymbol["MSFT"]; a.Stale || a.Horizon != 10 || a.Side != "short" {
+		t.Errorf("MSFT = %+v", a)
+	}
+	if a := bySymbol["NVDA"]; a.Stale || a.Sessions != 1 {
+		t.Errorf("NVDA = %+v", a)
+	}
+	if a := bySymbol["GME"]; a.Stale || a.OpenedAt != nil {
+		t.Errorf("GME = %+v", a)
+	}
+
+	// Notified once a session, nothing closed
+	m.Check(context.Background())
+	m.Check(context.Background())
+	if len(notes) != 1 || notes[0] != "Stale position" || len(broker.reduced) != 0 {
+		t.Errorf("notify mode: notes %v, reduced %+v", notes, broker.reduced)
+	}
+
+	// Close mode waits for the session, then closes at market
+	policy := m.Policy()
+	policy.Action = ActionClose
+	if err := m.SetPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	now = time.Date(2025, 3, 12, 8, 0, 0, 0, loc)
+	m.Check(context.Background())
+	if len(broker.reduced) != 0 {
+		t.Errorf("closed before the open: %+v", broker.reduced)
+	}
+	now = time.Date(2025, 3, 12, 10, 0, 0, 0, loc)
+	m.Check(context.Background())
+	if len(broker.reduced) != 1 || broker.reduced[0].Symbol != "AAPL" || broker.reduced[0].Side != "sell" || broker.reduced[0].Qty != 10 {
+		t.Errorf("reduced = %+v", broker.reduced)
+	}
+	if notes[len(notes)-1] != "Stale position closed" {
+		t.Errorf("notes = %v", notes)
+	}
+
+	// Without the broker's positions the journal's are aged, and nothing
+	// is closed
+	broker.err = errors.New("down")
+	if s := m.Status(context.Background()); s.BrokerError == "" || len(s.Positions) != 3 {
+		t.Errorf("status without broker = %+v", s)
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	if err := DefaultPolicy().Validate(); err != nil {
+		t.Errorf("default policy: %v", err)
+	}
+	for _, p := range []Policy{
+		{Action: "sell", DefaultHorizon: 5},
+		{Action: ActionNotify, DefaultHorizon: 0},
+		{Action: ActionNotify, DefaultHorizon: 5, Horizons: map[string]int{"tb": 0}},
+		{Action: ActionNotify, DefaultHorizon: 5, Horizons: map[string]int{" ": 3}},
+	} {
+		if p.Validate() == nil {
+			t.Errorf("%+v accepted", p)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	m, err := New(filepath.Join(t.TempDir(), "policy.json"), func(time.Time) []fills.Record { return nil }, calendar.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	NewHandler(m).RegisterRoutes(mux)
+	for _, c := range []struct {
+		method, path, body string
+		code               int
+		contains           string
+	}{
+		{"GET", "/api/positions/aging", ``, 200, `"positions":[]`},
+		{"POST", "/api/positions/aging/policy", `{"horizons":{"tb":3}}`, 200, `"tb":3`},
+		{"POST", "/api/positions/aging/policy", `{"action":"sell"}`, 400, ``},
+		{"GET", "/api/positions/aging/policy", ``, 200, `"action":"notify"`},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(c.method, c.path, strings.NewReader(c.body)))
+		if rec.Code != c.code || !strings.Contains(rec.Body.String(), c.contains) {
+			t.Errorf("%s %s = %d %s, want %d containing %s", c.method, c.path, rec.Code, rec.Body.String(), c.code, c.contains)
+		}
+	}
+}
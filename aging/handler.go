@@ -0,0 +1,61 @@
+package aging
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Handler exposes position ages over HTTP.
+type Handler struct {
+	monitor *Monitor
+}
+
+// NewHandler creates a handler for monitor.
+func NewHandler(monitor *Monitor) *Handler {
+	return &Handler{monitor: monitor}
+}
+
+// RegisterRoutes registers the aging routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/positions/aging - every held position's age against its strategy's horizon
+	mux.HandleFunc("/api/positions/aging", h.json(h.handleStatus))
+
+	// GET/POST /api/positions/aging/policy - read or update the horizons and what happens past them
+	mux.HandleFunc("/api/positions/aging/policy", h.json(h.handlePolicy))
+}
+
+func (h *Handler) json(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		next(w, r)
+	}
+}
+
+func (h *Handler) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(h.monitor.Status(r.Context()))
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.monitor.Policy())
+	case http.MethodPost, http.MethodPut:
+		// Start from the current policy so partial updates work
+		policy := h.monitor.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.monitor.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(h.monitor.Policy())
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,410 @@
+// Package aicost prices the Claude tokens each signal consumes. Every
+// priced signal is journaled with its strategy, so costs can be reported
+// per strategy against the realized P&L the fills journal attributes to
+// it, net of cost. When the month's cost reaches the monthly cap an alert
+// is raised, once a month.
+package aicost
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+)
+
+func logger() *slog.Logger { return slog.With("module", "aicost") }
+
+// Untagged is the strategy of realized P&L from orders without one.
+const Untagged = "untagged"
+
+// Policy prices tokens and caps the monthly cost. Prices are dollars per
+// million tokens.
+type Policy struct {
+	InputPerMillion  float64 `json:"input_per_million"`
+	OutputPerMillion float64 `json:"output_per_million"`
+	MonthlyCap       float64 `json:"monthly_cap"` // dollars; zero never alerts
+}
+
+// DefaultPolicy prices tokens at Claude Sonnet's list prices and sets no
+// cap.
+func DefaultPolicy() Policy {
+	return Policy{InputPerMillion: 3, OutputPerMillion: 15}
+}
+
+// Validate reports the first invalid field.
+func (p Policy) Validate() error {
+	switch {
+	case p.InputPerMillion < 0:
+		return errors.New("input_per_million must not be negative")
+	case p.OutputPerMillion < 0:
+		return errors.New("output_per_million must not be negative")
+	case p.MonthlyCap < 0:
+		return errors.New("monthly_cap must not be negative")
+	}
+	return nil
+}
+
+// Price returns what input and output tokens cost in dollars.
+func (p Policy) Price(input, output int) float64 {
+	return (float64(input)*p.InputPerMillion + float64(output)*p.OutputPerMillion) / 1e6
+}
+
+// Entry is one priced signal.
+type Entry struct {
+	At           time.Time `json:"at"`
+	Symbol       string    `json:"symbol"`
+	Signal       string    `json:"signal"`
+	Strategy     string    `json:"strategy"`
+	InputTokens  int       `json:"input_tokens"`
+	OutputTokens int       `json:"output_tokens"`
+	Estimated    bool      `json:"estimated,omitempty"`
+	Cost         float64   `json:"cost"`
+}
+
+// Strategy is one strategy's AI cost against its realized P&L.
+type Strategy struct {
+	Strategy      string  `json:"strategy"`
+	Signals       int     `json:"signals"`
+	InputTokens   int     `json:"input_tokens"`
+	OutputTokens  int     `json:"output_tokens"`
+	Cost          float64 `json:"cost"`
+	CostPerSignal float64 `json:"cost_per_signal"`
+	Trades        int     `json:"trades"` // round trips closed
+	RealizedPL    float64 `json:"realized_pl"`
+	NetPL         float64 `json:"net_pl"` // realized P&L less AI cost
+}
+
+// Report is AI cost and net-of-cost performance since a time.
+type Report struct {
+	Since       time.Time  `json:"since"`
+	Month       string     `json:"month"` // YYYY-MM in market time
+	MonthToDate float64    `json:"month_to_date"`
+	MonthlyCap  float64    `json:"monthly_cap"`
+	Signals     int        `json:"signals"`
+	Cost        float64    `json:"cost"`
+	RealizedPL  float64    `json:"realized_pl"`
+	NetPL       float64    `json:"net_pl"`
+	Strategies  []Strategy `json:"strategies"`
+}
+
+// state is what survives a restart.
+type state struct {
+	AlertedMonth string `json:"alerted_month,omitempty"` // YYYY-MM of the last cap alert
+}
+
+// OutcomeSource returns the round trips closed so far.
+type OutcomeSource func() []algorithm.TradeOutcome
+
+// Notifier raises a high-priority notification.
+type Notifier func(title, message string, metadata map[string]interface{})
+
+// Tracker prices and journals signal costs and reports them. It is safe
+// for concurrent use.
+type Tracker struct {
+	dir string
+	loc *time.Location
+
+	mu       sync.Mutex
+	policy   Policy
+	state    state
+	entries  []Entry
+	journal  *os.File
+	outcomes OutcomeSource
+	notify   Notifier
+	now      func() time.Time
+}
+
+// New opens the policy, state and cost journal saved in dir, starting from
+// DefaultPolicy when there is none. Months roll over in loc.
+func New(dir string, loc *time.Location) (*Tracker, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create AI cost directory: %w", err)
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	t := &Tracker{dir: dir, loc: loc, policy: DefaultPolicy(), now: time.Now}
+	if data, err := os.ReadFile(filepath.Join(dir, "policy.json")); err == nil {
+		policy := DefaultPolicy()
+		if err := json.Unmarshal(data, &policy); err != nil {
+			return nil, fmt.Errorf("failed to decode AI cost policy: %w", err)
+		}
+		if err := policy.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid AI cost policy: %w", err)
+		}
+		t.policy = policy
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read AI cost policy: %w", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "state.json")); err == nil {
+		if err := json.Unmarshal(data, &t.state); err != nil {
+			return nil, fmt.Errorf("failed to decode AI cost state: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read AI cost state: %w", err)
+	}
+
+	path := filepath.Join(dir, "costs.jsonl")
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var e Entry
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				logger().Warn("Skipping corrupt AI cost entry", "error", err)
+				continue
+			}
+			t.entries = append(t.entries, e)
+		}
+		err := scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read AI cost journal: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to open AI cost journal: %w", err)
+	}
+	journal, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open AI cost journal: %w", err)
+	}
+	t.journal = journal
+	return t, nil
+}
+
+// Close closes the journal.
+func (t *Tracker) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.journal.Close()
+}
+
+// SetClock replaces the clock, for replays and tests.
+func (t *Tracker) SetClock(now func() time.Time) { t.mu.Lock(); t.now = now; t.mu.Unlock() }
+
+// SetNotifier sets where cap alerts are raised.
+func (t *Tracker) SetNotifier(fn Notifier) { t.mu.Lock(); t.notify = fn; t.mu.Unlock() }
+
+// SetOutcomeSource sets where realized P&L comes from; without one the
+// report carries costs only.
+func (t *Tracker) SetOutcomeSource(fn OutcomeSource) { t.mu.Lock(); t.outcomes = fn; t.mu.Unlock() }
+
+// Policy returns the current policy.
+func (t *Tracker) Policy() Policy {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.policy
+}
+
+// SetPolicy validates, applies and saves p, then checks the month's cost
+// against its cap. New prices apply to signals from now on.
+func (t *Tracker) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	t.policy = p
+	err := t.saveLocked("policy.json", t.policy)
+	t.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	t.Check()
+	return nil
+}
+
+// Record prices signal's token usage, setting its cost, and journals it
+// under the signal's strategy. Signals without usage cost nothing and are
+// not journaled.
+func (t *Tracker) Record(signal *algorithm.TradeSignal) {
+	if signal == nil || signal.AIUsage == nil {
+		return
+	}
+	u := signal.AIUsage
+	t.mu.Lock()
+	u.Cost = t.policy.Price(u.InputTokens, u.OutputTokens)
+	at := signal.Timestamp
+	if at.IsZero() {
+		at = t.now()
+	}
+	e := Entry{
+		At:           at,
+		Symbol:       signal.Symbol,
+		Signal:       signal.Signal,
+		Strategy:     signal.OrderTag(),
+		InputTokens:  u.InputTokens,
+		OutputTokens: u.OutputTokens,
+		Estimated:    u.Estimated,
+		Cost:         u.Cost,
+	}
+	t.entries = append(t.entries, e)
+	err := t.appendLocked(e)
+	t.mu.Unlock()
+	if err != nil {
+		logger().Error("Failed to journal AI cost", "symbol", e.Symbol, "error", err)
+	}
+	t.Check()
+}
+
+// MonthToDate returns the cost of this month's signals.
+func (t *Tracker) MonthToDate() float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return round(t.monthToDateLocked(t.now()))
+}
+
+// Check raises the cap alert when this month's cost has reached the cap
+// and it has not been raised this month. It reports whether it raised it.
+func (t *Tracker) Check() bool {
+	t.mu.Lock()
+	now := t.now()
+	month := now.In(t.loc).Format("2006-01")
+	limit := t.policy.MonthlyCap
+	spent := t.monthToDateLocked(now)
+	if limit <= 0 || spent < limit || t.state.AlertedMonth == month {
+		t.mu.Unlock()
+		return false
+	}
+	t.state.AlertedMonth = month
+	err := t.saveLocked("state.json", t.state)
+	notify := t.notify
+	t.mu.Unlock()
+	if err != nil {
+		logger().Error("Failed to save AI cost state", "error", err)
+	}
+	logger().Warn("AI cost reached the monthly cap", "month", month, "cost", round(spent), "cap", limit)
+	if notify != nil {
+		notify("AI cost cap reached",
+			fmt.Sprintf("Claude usage has cost $%.2f in %s, at least the monthly_cap of $%.2f", spent, month, limit),
+			map[string]interface{}{
+				"month": month,
+				"cost":  round(spent),
+				"cap":   limit,
+			})
+	}
+	return true
+}
+
+// Report sums AI cost and realized P&L per strategy over the last days
+// days, through now.
+func (t *Tracker) Report(days int) Report {
+	t.mu.Lock()
+	now, policy, outcomes := t.now(), t.policy, t.outcomes
+	since := now.AddDate(0, 0, -days)
+	r := Report{
+		Since:       since,
+		Month:       now.In(t.loc).Format("2006-01"),
+		MonthToDate: round(t.monthToDateLocked(now)),
+		MonthlyCap:  policy.MonthlyCap,
+	}
+	byStrategy := make(map[string]*Strategy)
+	group := func(name string) *Strategy {
+		s, ok := byStrategy[name]
+		if !ok {
+			s = &Strategy{Strategy: name}
+			byStrategy[name] = s
+		}
+		return s
+	}
+	for _, e := range t.entries {
+		if e.At.Before(since) {
+			continue
+		}
+		s := group(e.Strategy)
+		s.Signals++
+		s.InputTokens += e.InputTokens
+		s.OutputTokens += e.OutputTokens
+		s.Cost += e.Cost
+	}
+	t.mu.Unlock()
+
+	if outcomes != nil {
+		for _, o := range outcomes() {
+			if o.ClosedAt.Before(since) {
+				continue
+			}
+			name := o.Strategy
+			if name == "" {
+				name = Untagged
+			}
+			s := group(name)
+			s.Trades++
+			s.RealizedPL += o.PL
+		}
+	}
+
+	r.Strategies = make([]Strategy, 0, len(byStrategy))
+	for _, s := range byStrategy {
+		r.Signals += s.Signals
+		r.Cost += s.Cost
+		r.RealizedPL += s.RealizedPL
+		if s.Signals > 0 {
+			s.CostPerSignal = round4(s.Cost / float64(s.Signals))
+		}
+		s.NetPL = round(s.RealizedPL - s.Cost)
+		s.Cost = round4(s.Cost)
+		s.RealizedPL = round(s.RealizedPL)
+		r.Strategies = append(r.Strategies, *s)
+	}
+	r.NetPL = round(r.RealizedPL - r.Cost)
+	r.Cost = round4(r.Cost)
+	r.RealizedPL = round(r.RealizedPL)
+	sort.Slice(r.Strategies, func(i, j int) bool {
+		if r.Strategies[i].Cost != r.Strategies[j].Cost {
+			return r.Strategies[i].Cost > r.Strategies[j].Cost
+		}
+		return r.Strategies[i].Strategy < r.Strategies[j].Strategy
+	})
+	return r
+}
+
+// monthToDateLocked sums the cost of the signals in now's month.
+func (t *Tracker) monthToDateLocked(now time.Time) float64 {
+	local := now.In(t.loc)
+	start := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, t.loc)
+	var total float64
+	// Entries are journaled in order, so walk back to the month's start
+	for i := len(t.entries) - 1; i >= 0 && !t.entries[i].At.Before(start); i-- {
+		total += t.entries[i].Cost
+	}
+	return total
+}
+
+func (t *Tracker) appendLocked(e Entry) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = t.journal.Write(append(data, '\n'))
+	return err
+}
+
+// round rounds dollars to cents, round4 to a hundredth of a cent, which
+// per-signal costs need.
+func round(v float64) float64  { return math.Round(v*100) / 100 }
+func round4(v float64) float64 { return math.Round(v*1e4) / 1e4 }
+
+func (t *Tracker) saveLocked(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	path := filepath.Join(t.dir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to save %s: %w", name, err)
+	}
+	return nil
+}
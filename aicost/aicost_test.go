@@ -0,0 +1,106 @@
+package aicost
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+)
+
+func signal(symbol string, at time.Time, input, output int) *algorithm.TradeSignal {
+	return &algorithm.TradeSignal{Symbol: symbol, Signal: algorithm.SignalBuy, Source: "claude", Timestamp: at,
+		AIUsage: &algorithm.AIUsage{InputTokens: input, OutputTokens: output}}
+}
+
+func TestRecordPricesSignalsAndAlertsOncePerMonth(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 3, 20, 15, 0, 0, 0, time.UTC)
+	tr, err := New(dir, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.SetClock(func() time.Time { return now })
+	var alerts []string
+	tr.SetNotifier(func(title, message string, metadata map[string]interface{}) { alerts = append(alerts, message) })
+	if err := tr.SetPolicy(Policy{InputPerMillion: 3, OutputPerMillion: 15, MonthlyCap: 0.05}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Last month's signal counts toward neither the cap nor this month
+	tr.Record(signal("AAPL", now.AddDate(0, -1, 0), 1e6, 0))
+	// 5000 input and 1000 output tokens cost $0.015 + $0.015
+	s := signal("AAPL", now.Add(-time.Hour), 5000, 1000)
+	tr.Record(s)
+	if s.AIUsage.Cost != 0.03 {
+		t.Fatalf("cost = %v", s.AIUsage.Cost)
+	}
+	tr.Record(&algorithm.TradeSignal{Symbol: "MSFT", Source: "system", Timestamp: now})
+	if len(alerts) != 0 || tr.MonthToDate() != 0.03 {
+		t.Fatalf("month to date %v, alerts %v", tr.MonthToDate(), alerts)
+	}
+	tr.Record(signal("MSFT", now, 5000, 1000))
+	tr.Record(signal("MSFT", now, 5000, 1000))
+	if len(alerts) != 1 {
+		t.Fatalf("alerts = %v", alerts)
+	}
+
+	// The journal and the alert survive a restart
+	reopened, err := New(dir, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened.SetClock(func() time.Time { return now })
+	reopened.SetNotifier(func(title, message string, metadata map[string]interface{}) { alerts = append(alerts, message) })
+	if reopened.Check() || len(alerts) != 1 {
+		t.Errorf("alerted again: %v", alerts)
+	}
+	if got := reopened.MonthToDate(); got != 0.09 {
+		t.Errorf("reopened month to date = %v", got)
+	}
+
+	// Next month starts afresh
+	now = now.AddDate(0, 1, 0)
+	reopened.Record(signal("AAPL", now, 1e4, 1e4))
+	if len(alerts) != 2 {
+		t.Errorf("next month alerts = %v", alerts)
+	}
+}
+
+func TestReportNetsCostAgainstRealizedPL(t *testing.T) {
+	now := time.Date(2026, 3, 20, 15, 0, 0, 0, time.UTC)
+	tr, err := New(t.TempDir(), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.SetClock(func() time.Time { return now })
+	tr.SetOutcomeSource(func() []algorithm.TradeOutcome {
+		return []algorithm.TradeOutcome{
+			{Symbol: "AAPL", Strategy: "claude", PL: 12.5, ClosedAt: now.Add(-time.Hour)},
+			{Symbol: "AAPL", Strategy: "claude", PL: 40, ClosedAt: now.AddDate(0, 0, -40)},
+			{Symbol: "MSFT", Strategy: "", PL: -3, ClosedAt: now.Add(-time.Hour)},
+		}
+	})
+	tr.Record(signal("AAPL", now.Add(-2*time.Hour), 5000, 1000))
+	tr.Record(signal("AAPL", now.AddDate(0, 0, -40), 5000, 1000))
+	tagged := signal("MSFT", now.Add(-time.Hour), 1e6, 0)
+	tagged.Tag = "momo"
+	tr.Record(tagged)
+
+	r := tr.Report(30)
+	if r.Signals != 2 || r.Cost != 3.03 || r.RealizedPL != 9.5 || r.NetPL != 6.47 {
+		t.Fatalf("report = %+v", r)
+	}
+	if len(r.Strategies) != 3 {
+		t.Fatalf("strategies = %+v", r.Strategies)
+	}
+	momo, claude, untagged := r.Strategies[0], r.Strategies[1], r.Strategies[2]
+	if momo.Strategy != "momo" || momo.Cost != 3 || momo.NetPL != -3 {
+		t.Errorf("momo = %+v", momo)
+	}
+	if claude.Strategy != "claude" || claude.Signals != 1 || claude.Trades != 1 || claude.CostPerSignal != 0.03 || claude.NetPL != 12.47 {
+		t.Errorf("claude = %+v", claude)
+	}
+	if untagged.Strategy != Untagged || untagged.RealizedPL != -3 || untagged.Signals != 0 {
+		t.Errorf("untagged = %+v", untagged)
+	}
+}
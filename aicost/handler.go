@@ -0,0 +1,67 @@
+package aicost
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// Handler exposes AI cost over HTTP.
+type Handler struct {
+	tracker *Tracker
+}
+
+// NewHandler creates a handler for tracker.
+func NewHandler(tracker *Tracker) *Handler {
+	return &Handler{tracker: tracker}
+}
+
+// RegisterRoutes registers the AI cost routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/reports/ai-cost?days=30 - Claude cost per strategy and realized P&L net of it
+	mux.HandleFunc("/api/reports/ai-cost", h.handleReport)
+
+	// GET/POST /api/reports/ai-cost/policy - read or update token prices and the monthly cap
+	mux.HandleFunc("/api/reports/ai-cost/policy", h.handlePolicy)
+}
+
+func (h *Handler) handleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	days := 30
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "days must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"policy": h.tracker.Policy(),
+		"report": h.tracker.Report(days),
+	})
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.tracker.Policy())
+	case http.MethodPost, http.MethodPut:
+		// Start from the current policy so partial updates work
+		policy := h.tracker.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.tracker.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(h.tracker.Policy())
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
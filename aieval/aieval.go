@@ -0,0 +1,452 @@
+// Package aieval evaluates Claude's signals out of sample, month by month:
+// how often a call's direction was right over a forward horizon, the
+// average forward return at each stated confidence, and how the quant
+// pipeline did on the same timestamps. Every signal is judged only on
+// prices after it was made, and each month's record is also accumulated
+// from the first month on, anchored walk-forward style.
+package aieval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+func logger() *slog.Logger { return slog.With("module", "aieval") }
+
+// Request defaults and bounds.
+const (
+	DefaultMonths      = 12
+	DefaultHorizonDays = 5
+	maxMonths          = 36
+	maxHorizonDays     = 60
+)
+
+// pairWindow is how far apart a quant signal may be from a Claude signal
+// on the same symbol and still count as made at the same time.
+const pairWindow = 5 * time.Minute
+
+// barFinal is how long after a daily bar's timestamp its close is final.
+const barFinal = 24 * time.Hour
+
+// Signal is one recorded signal.
+type Signal struct {
+	Time       time.Time
+	Symbol     string
+	Signal     string // buy, sell, hold or close
+	Confidence *float64
+	Price      float64 // when the signal was made
+}
+
+// directional reports whether s calls a direction, and its sign.
+func (s Signal) direction() (float64, bool) {
+	switch strings.ToLower(s.Signal) {
+	case "buy":
+		return 1, true
+	case "sell":
+		return -1, true
+	}
+	return 0, false
+}
+
+// Bar is a daily close.
+type Bar struct {
+	Time  time.Time
+	Close float64
+}
+
+// Sources supplies the signals and prices evaluated.
+type Sources struct {
+	// Claude returns Claude's signals since a time
+	Claude func(since time.Time) []Signal
+	// Quant returns the quant pipeline's signals since a time, nil when
+	// it is not shadowed
+	Quant func(since time.Time) ([]Signal, error)
+	// Bars returns a symbol's daily bars between from and to
+	Bars func(ctx context.Context, symbol string, from, to time.Time) ([]Bar, error)
+}
+
+// Request selects the report.
+type Request struct {
+	Months      int // calendar months back, including the current one
+	HorizonDays int // sessions the forward return is measured over
+}
+
+// withDefaults fills unset fields and validates the rest.
+func (r Request) withDefaults() (Request, error) {
+	if r.Months == 0 {
+		r.Months = DefaultMonths
+	}
+	if r.HorizonDays == 0 {
+		r.HorizonDays = DefaultHorizonDays
+	}
+	if r.Months < 1 || r.Months > maxMonths {
+		return r, fmt.Errorf("months must be between 1 and %d", maxMonths)
+	}
+	if r.HorizonDays < 1 || r.HorizonDays > maxHorizonDays {
+		return r, fmt.Errorf("horizon_days must be between 1 and %d", maxHorizonDays)
+	}
+	return r, nil
+}
+
+// Stats is a record of directional calls. Returns are signed by the call,
+// so a sell followed by a fall counts as a positive return and a hit.
+type Stats struct {
+	Signals   int     `json:"signals"` // with a forward return
+	Hits      int     `json:"hits"`
+	HitRate   float64 `json:"hit_rate"`   // percent
+	AvgReturn float64 `json:"avg_return"` // percent over the horizon
+	sum       float64
+}
+
+func (s *Stats) add(ret float64) {
+	s.Signals++
+	if ret > 0 {
+		s.Hits++
+	}
+	s.sum += ret
+}
+
+func (s *Stats) merge(o Stats) {
+	s.Signals += o.Signals
+	s.Hits += o.Hits
+	s.sum += o.sum
+}
+
+func (s *Stats) finish() {
+	if s.Signals == 0 {
+		return
+	}
+	s.HitRate = round2(float64(s.Hits) / float64(s.Signals) * 100)
+	s.AvgReturn = round2(s.sum / float64(s.Signals))
+}
+
+// Bucket is the record of calls made at a range of confidence.
+type Bucket struct {
+	Confidence string `json:"confidence"` // 0.6-0.7, or unstated
+	Stats
+}
+
+// buckets are the confidence ranges, each including its lower bound.
+var buckets = []struct {
+	label    string
+	min, max float64
+}{
+	{"0.0-0.5", 0, 0.5},
+	{"0.5-0.6", 0.5, 0.6},
+	{"0.6-0.7", 0.6, 0.7},
+	{"0.7-0.8", 0.7, 0.8},
+	{"0.8-0.9", 0.8, 0.9},
+	{"0.9-1.0", 0.9, math.Inf(1)},
+}
+
+const unstated = "unstated"
+
+// bucketOf returns the bucket a confidence falls in.
+func bucketOf(confidence *float64) string {
+	if confidence == nil {
+		return unstated
+	}
+	for _, b := range buckets {
+		if *confidence < b.max {
+			return b.label
+		}
+	}
+	return buckets[len(buckets)-1].label
+}
+
+// Comparison is Claude against the quant pipeline on the signals both
+// made for a symbol at the same time.
+type Comparison struct {
+	Paired    int     `json:"paired"`
+	Agreement float64 `json:"agreement"` // percent of pairs with the same signal
+	Claude    Stats   `json:"claude"`
+	Quant     Stats   `json:"quant"`
+	agreed    int
+}
+
+func (c *Comparison) merge(o Comparison) {
+	c.Paired += o.Paired
+	c.agreed += o.agreed
+	c.Claude.merge(o.Claude)
+	c.Quant.merge(o.Quant)
+}
+
+func (c *Comparison) finish() {
+	if c.Paired > 0 {
+		c.Agreement = round2(float64(c.agreed) / float64(c.Paired) * 100)
+	}
+	c.Claude.finish()
+	c.Quant.finish()
+}
+
+// Period is the evaluation of the signals made in one month, or in all of
+// them.
+type Period struct {
+	Month  string `json:"month"` // YYYY-MM in exchange time, or all
+	Claude Stats  `json:"claude"`
+	// Holds counts hold and close signals, which call no direction
+	Holds int `json:"holds"`
+	// Pending counts calls whose horizon has not passed, and Unpriced
+	// those without a price when made or bars after
+	Pending  int        `json:"pending"`
+	Unpriced int        `json:"unpriced"`
+	Buckets  []Bucket   `json:"confidence_buckets"`
+	VsQuant  Comparison `json:"vs_quant"`
+	// Cumulative is Claude's record from the first month through this
+	// one
+	Cumulative *Stats `json:"cumulative,omitempty"`
+	buckets    map[string]*Stats
+}
+
+func newPeriod(month string) *Period {
+	return &Period{Month: month, buckets: make(map[string]*Stats)}
+}
+
+func (p *Period) bucket(label string) *Stats {
+	s, ok := p.buckets[label]
+	if !ok {
+		s = &Stats{}
+		p.buckets[label] = s
+	}
+	return s
+}
+
+func (p *Period) merge(o *Period) {
+	p.Claude.merge(o.Claude)
+	p.Holds += o.Holds
+	p.Pending += o.Pending
+	p.Unpriced += o.Unpriced
+	p.VsQuant.merge(o.VsQuant)
+	for label, s := range o.buckets {
+		p.bucket(label).merge(*s)
+	}
+}
+
+func (p *Period) finish() {
+	p.Claude.finish()
+	p.VsQuant.finish()
+	p.Buckets = []Bucket{}
+	labels := make([]string, 0, len(buckets)+1)
+	for _, b := range buckets {
+		labels = append(labels, b.label)
+	}
+	for _, label := range append(labels, unstated) {
+		if s, ok := p.buckets[label]; ok && s.Signals > 0 {
+			s.finish()
+			p.Buckets = append(p.Buckets, Bucket{Confidence: label, Stats: *s})
+		}
+	}
+}
+
+// Report is the monthly evaluation.
+type Report struct {
+	HorizonDays int       `json:"horizon_days"`
+	From        time.Time `json:"from"`
+	Months      []Period  `json:"months"` // oldest first
+	Overall     Period    `json:"overall"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// Evaluator builds the report from its sources.
+type Evaluator struct {
+	src Sources
+	loc *time.Location
+
+	mu  sync.Mutex
+	now func() time.Time
+}
+
+// New creates an evaluator over src that groups months in loc, the
+// exchange's time zone.
+func New(src Sources, loc *time.Location) *Evaluator {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return &Evaluator{src: src, loc: loc, now: time.Now}
+}
+
+// SetClock replaces the evaluator's clock, for replay and tests.
+func (e *Evaluator) SetClock(now func() time.Time) { e.mu.Lock(); e.now = now; e.mu.Unlock() }
+
+// Evaluate builds the report for req.
+func (e *Evaluator) Evaluate(ctx context.Context, req Request) (Report, error) {
+	req, err := req.withDefaults()
+	if err != nil {
+		return Report{}, err
+	}
+	e.mu.Lock()
+	now := e.now()
+	e.mu.Unlock()
+	src, loc := e.src, e.loc
+	if src.Claude == nil || src.Bars == nil {
+		return Report{}, errors.New("no signal or price source")
+	}
+	local := now.In(loc)
+	from := time.Date(local.Year(), local.Month()-time.Month(req.Months-1), 1, 0, 0, 0, 0, loc)
+
+	signals := src.Claude(from)
+	sort.SliceStable(signals, func(i, j int) bool { return signals[i].Time.Before(signals[j].Time) })
+	quant := map[string][]Signal{}
+	if src.Quant != nil {
+		list, err := src.Quant(from)
+		if err != nil {
+			return Report{}, fmt.Errorf("failed to read quant signals: %w", err)
+		}
+		for _, s := range list {
+			quant[s.Symbol] = append(quant[s.Symbol], s)
+		}
+	}
+
+	// Daily closes of every symbol called, fetched once
+	bars := map[string][]Bar{}
+	for _, s := range signals {
+		if _, done := bars[s.Symbol]; done {
+			continue
+		}
+		list, err := src.Bars(ctx, s.Symbol, from.AddDate(0, 0, -7), now)
+		if err != nil {
+			if ctx.Err() != nil {
+				return Report{}, ctx.Err()
+			}
+			logger().Warn("Failed to fetch bars for evaluation", "symbol", s.Symbol, "error", err)
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Time.Before(list[j].Time) })
+		bars[s.Symbol] = list
+	}
+	forward := func(s Signal) (float64, outcome) {
+		return forwardReturn(bars[s.Symbol], s, req.HorizonDays, now)
+	}
+
+	months := map[string]*Period{}
+	for _, s := range signals {
+		key := s.Time.In(loc).Format("2006-01")
+		p, ok := months[key]
+		if !ok {
+			p = newPeriod(key)
+			months[key] = p
+		}
+		sign, directional := s.direction()
+		if !directional {
+			p.Holds++
+		}
+		var ret float64
+		result := unpriced
+		if directional {
+			ret, result = forward(s)
+			switch result {
+			case priced:
+				p.Claude.add(sign * ret)
+				p.bucket(bucketOf(s.Confidence)).add(sign * ret)
+			case pending:
+				p.Pending++
+			default:
+				p.Unpriced++
+			}
+		}
+
+		// The quant signal for the same moment, judged over the same
+		// horizon; pairs whose horizon has not passed wait
+		q, ok := pairOf(quant[s.Symbol], s.Time)
+		if !ok || (directional && result != priced) {
+			continue
+		}
+		if !directional {
+			if _, r := forward(s); r == pending {
+				continue
+			}
+		}
+		p.VsQuant.Paired++
+		if strings.EqualFold(q.Signal, s.Signal) {
+			p.VsQuant.agreed++
+		}
+		if directional {
+			p.VsQuant.Claude.add(sign * ret)
+		}
+		if qSign, ok := q.direction(); ok {
+			if q.Price <= 0 {
+				q.Price = s.Price
+			}
+			if qRet, r := forward(q); r == priced {
+				p.VsQuant.Quant.add(qSign * qRet)
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(months))
+	for k := range months {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	report := Report{HorizonDays: req.HorizonDays, From: from, Months: []Period{}, GeneratedAt: now}
+	overall := newPeriod("all")
+	var running Stats
+	for _, k := range keys {
+		p := months[k]
+		overall.merge(p)
+		running.merge(p.Claude)
+		cumulative := running
+		cumulative.finish()
+		p.Cumulative = &cumulative
+		p.finish()
+		report.Months = append(report.Months, *p)
+	}
+	overall.finish()
+	report.Overall = *overall
+	return report, nil
+}
+
+// outcome is whether a call could be judged.
+type outcome int
+
+const (
+	priced outcome = iota
+	pending
+	unpriced
+)
+
+// forwardReturn is the percentage move from s's price to the close
+// horizon sessions after the session s was made in. The exit bar must
+// have closed by now.
+func forwardReturn(bars []Bar, s Signal, horizon int, now time.Time) (float64, outcome) {
+	if s.Price <= 0 || len(bars) == 0 {
+		return 0, unpriced
+	}
+	// The session the signal was made in, or the last one before it
+	i := sort.Search(len(bars), func(i int) bool { return bars[i].Time.After(s.Time) }) - 1
+	if i < 0 {
+		return 0, unpriced
+	}
+	exit := i + horizon
+	if exit >= len(bars) || bars[exit].Time.Add(barFinal).After(now) {
+		return 0, pending
+	}
+	return (bars[exit].Close/s.Price - 1) * 100, priced
+}
+
+// pairOf returns the signal in list, sorted by time, nearest to t within
+// pairWindow.
+func pairOf(list []Signal, t time.Time) (Signal, bool) {
+	var best Signal
+	bestGap := pairWindow + 1
+	for _, s := range list {
+		gap := s.Time.Sub(t)
+		if gap < 0 {
+			gap = -gap
+		}
+		if gap <= pairWindow && gap < bestGap {
+			best, bestGap = s, gap
+		}
+	}
+	return best, bestGap <= pairWindow
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
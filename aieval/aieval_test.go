@@ -0,0 +1,124 @@
+package aieval
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func conf(v float64) *float64 { return &v }
+
+var newYork, _ = time.LoadLocation("America/New_York")
+
+// day is midnight d days after 2026-07-25.
+func day(d int) time.Time { return time.Date(2026, 7, 25+d, 0, 0, 0, 0, newYork) }
+
+// evaluator returns an evaluator over daily bars to 2026-09-15 that close
+// at 100 on 2026-07-25 and one higher each day after.
+func evaluator(t *testing.T, claude, quant []Signal) *Evaluator {
+	t.Helper()
+	var bars []Bar
+	for d := 0; day(d).Month() < time.September || day(d).Day() < 16; d++ {
+		bars = append(bars, Bar{Time: day(d), Close: 100 + float64(d)})
+	}
+	since := func(list []Signal, from time.Time) []Signal {
+		var out []Signal
+		for _, s := range list {
+			if !s.Time.Before(from) {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	e := New(Sources{
+		Claude: func(from time.Time) []Signal { return since(claude, from) },
+		Quant:  func(from time.Time) ([]Signal, error) { return since(quant, from), nil },
+		Bars: func(_ context.Context, symbol string, from, to time.Time) ([]Bar, error) {
+			return bars, nil
+		},
+	}, newYork)
+	e.SetClock(func() time.Time { return time.Date(2026, 9, 16, 12, 0, 0, 0, newYork) })
+	return e
+}
+
+func TestEvaluateByMonth(t *testing.T) {
+	// The close on day d is 100+d
+	at := func(d int, minute int) time.Time {
+		return day(d).Add(10*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	claude := []Signal{
+		{Time: at(2, 0), Symbol: "AAPL", Signal: "buy", Confidence: conf(0.9), Price: 102}, // before the window
+		{Time: at(9, 0), Symbol: "AAPL", Signal: "buy", Confidence: conf(0.75), Price: 109},
+		{Time: at(10, 0), Symbol: "AAPL", Signal: "sell", Confidence: conf(0.85), Price: 110},
+		{Time: at(11, 0), Symbol: "AAPL", Signal: "hold", Confidence: conf(0.6), Price: 111},
+		{Time: at(38, 0), Symbol: "AAPL", Signal: "buy"}, // no price
+		{Time: at(39, 0), Symbol: "AAPL", Signal: "buy", Price: 139},
+		{Time: at(51, 0), Symbol: "AAPL", Signal: "buy", Confidence: conf(0.7), Price: 151}, // horizon not passed
+	}
+	quant := []Signal{
+		{Time: at(9, 2), Symbol: "AAPL", Signal: "sell"},
+		{Time: at(11, 1), Symbol: "AAPL", Signal: "buy", Price: 111},
+		{Time: at(39, 30), Symbol: "AAPL", Signal: "buy", Price: 139}, // too late to pair
+	}
+	e := evaluator(t, claude, quant)
+
+	r, err := e.Evaluate(context.Background(), Request{Months: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.HorizonDays != DefaultHorizonDays || len(r.Months) != 2 || r.Months[0].Month != "2026-08" || r.Months[1].Month != "2026-09" {
+		t.Fatalf("report = %+v", r)
+	}
+
+	aug := r.Months[0]
+	// The buy gained 114/109, the sell lost 115/110
+	avg := math.Round(((114.0/109-1)*100-(115.0/110-1)*100)/2*100) / 100
+	if aug.Claude.Signals != 2 || aug.Claude.Hits != 1 || aug.Claude.HitRate != 50 || aug.Claude.AvgReturn != avg || aug.Holds != 1 {
+		t.Errorf("august = %+v", aug)
+	}
+	if len(aug.Buckets) != 2 || aug.Buckets[0].Confidence != "0.7-0.8" || aug.Buckets[0].Hits != 1 || aug.Buckets[1].Confidence != "0.8-0.9" || aug.Buckets[1].Hits != 0 {
+		t.Errorf("august buckets = %+v", aug.Buckets)
+	}
+	// The buy against quant's sell, judged from Claude's price, and the
+	// hold against quant's buy
+	vs := aug.VsQuant
+	if vs.Paired != 2 || vs.Agreement != 0 || vs.Claude.Signals != 1 || vs.Quant.Signals != 2 || vs.Quant.Hits != 1 {
+		t.Errorf("august vs quant = %+v", vs)
+	}
+
+	sep := r.Months[1]
+	if sep.Claude.Signals != 1 || sep.Claude.Hits != 1 || sep.Pending != 1 || sep.Unpriced != 1 || sep.VsQuant.Paired != 0 {
+		t.Errorf("september = %+v", sep)
+	}
+	if len(sep.Buckets) != 1 || sep.Buckets[0].Confidence != unstated {
+		t.Errorf("september buckets = %+v", sep.Buckets)
+	}
+	if c := sep.Cumulative; c == nil || c.Signals != 3 || c.Hits != 2 || c.HitRate != 66.67 {
+		t.Errorf("cumulative = %+v", c)
+	}
+	if o := r.Overall; o.Month != "all" || o.Claude.Signals != 3 || o.Holds != 1 || o.Pending != 1 || o.VsQuant.Paired != 2 || len(o.Buckets) != 3 {
+		t.Errorf("overall = %+v", o)
+	}
+}
+
+func TestHandlerValidatesRequest(t *testing.T) {
+	e := evaluator(t, nil, nil)
+	mux := http.NewServeMux()
+	NewHandler(e).RegisterRoutes(mux)
+	for path, want := range map[string]int{
+		"/api/reports/ai-evaluation":                 http.StatusOK,
+		"/api/reports/ai-evaluation?months=3":        http.StatusOK,
+		"/api/reports/ai-evaluation?months=40":       http.StatusBadRequest,
+		"/api/reports/ai-evaluation?horizon_days=0x": http.StatusBadRequest,
+		"/api/reports/ai-evaluation?horizon_days=90": http.StatusBadRequest,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("%s = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package aieval
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// Handler exposes the evaluation over HTTP.
+type Handler struct {
+	evaluator *Evaluator
+}
+
+// NewHandler creates a handler for evaluator.
+func NewHandler(evaluator *Evaluator) *Handler {
+	return &Handler{evaluator: evaluator}
+}
+
+// RegisterRoutes registers the evaluation routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/reports/ai-evaluation?months=12&horizon_days=5 - Claude's hit rate and forward return by month and confidence, against the quant pipeline
+	mux.HandleFunc("/api/reports/ai-evaluation", h.handleReport)
+}
+
+func (h *Handler) handleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req Request
+	for name, dst := range map[string]*int{"months": &req.Months, "horizon_days": &req.HorizonDays} {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, name+" must be an integer", http.StatusBadRequest)
+			return
+		}
+		*dst = n
+	}
+	if _, err := req.withDefaults(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	report, err := h.evaluator.Evaluate(r.Context(), req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(report)
+}
@@ -2,10 +2,8 @@ package algorithm
 
 import (
 	"errors"
-	"log"
 	"time"
 "github.com/rileyseaburg/go-trader/types"
-"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
 
 
 
@@ -16,7 +14,7 @@ import (
 
 // GetHistoricalDataV2 provides a compatible wrapper for the algorithm handler
 func (a *TradingAlgorithm) GetHistoricalDataV2(request types.HistoricalDataRequest) (*types.HistoricalData, error) {
-	log.Printf("GetHistoricalDataV2: fetching data for %s", request.Symbol)
+	logger().Debug("Fetching historical data", "symbol", request.Symbol)
 
 	// Validate request
 	if request.Symbol == "" {
@@ -32,20 +30,17 @@ func (a *TradingAlgorithm) GetHistoricalDataV2(request types.HistoricalDataReque
 		return nil, err
 	}
 
-	// Get bars from Alpaca
-	bars, err := a.mdClient.GetBars(request.Symbol,
-		marketdata.GetBarsRequest{
-			TimeFrame: timeframe,
-			Start:     request.StartDate,
-			End:       request.EndDate,
-		})
+	// Get bars from Alpaca, chunking long ranges
+	bars, err := a.fetchBarsChunked(request.Symbol, timeframe, request.TimeFrame, request.StartDate, request.EndDate)
 	if err != nil {
 		return nil, err
 	}
 
 	// Convert bars to historical data points
 	data := make([]types.HistoricalDataPoint, len(bars))
+	closes := make([]float64, len(bars))
 	for i, bar := range bars {
+		closes[i] = bar.Close
 		data[i] = types.HistoricalDataPoint{
 			Symbol:    request.Symbol, // Alpaca bars don't have symbol
 			Timestamp: bar.Timestamp,
@@ -57,6 +52,8 @@ func (a *TradingAlgorithm) GetHistoricalDataV2(request types.HistoricalDataReque
 		}
 	}
 
+	a.recordDailyCloses(request.Symbol, timeframe, closes)
+
 	// Create historical data
 	historicalData := &types.HistoricalData{
 		Symbol:    request.Symbol,
@@ -66,9 +63,8 @@ func (a *TradingAlgorithm) GetHistoricalDataV2(request types.HistoricalDataReque
 		Data:      data,
 	}
 
-	log.Printf("Fetched %d data points for %s from %s to %s",
-		len(data), request.Symbol, request.StartDate.Format("2006-01-02"),
-		request.EndDate.Format("2006-01-02"))
+	logger().Info("Fetched historical data", "symbol", request.Symbol, "points", len(data),
+		"from", request.StartDate.Format("2006-01-02"), "to", request.EndDate.Format("2006-01-02"))
 
 	return historicalData, nil
 }
@@ -87,7 +83,7 @@ func (a *TradingAlgorithm) AnalyzeHistoricalDataV2(data *types.HistoricalData) *
 		}
 	}
 
-	log.Printf("AnalyzeHistoricalDataV2: analyzing data for %s", data.Symbol)
+	logger().Debug("Analyzing historical data", "symbol", data.Symbol)
 
 	if len(data.Data) == 0 {
 		return &types.HistoricalDataAnalysis{
@@ -178,7 +174,7 @@ func (a *TradingAlgorithm) AnalyzeHistoricalDataV2(data *types.HistoricalData) *
 
 // RecommendTickersV2 converts from RecommendedTicker to []string for the V2 API
 func (a *TradingAlgorithm) RecommendTickersV2(sector string, maxResults int) ([]string, error) {
-	log.Printf("RecommendTickersV2: getting recommendations for %s sector", sector)
+	logger().Debug("Getting ticker recommendations", "sector", sector)
 
 	// Convert old RecommendedTicker to types.RecommendedTicker
 	recommendations, err := a.RecommendTickersList(sector, maxResults)
@@ -197,7 +193,7 @@ func (a *TradingAlgorithm) RecommendTickersV2(sector string, maxResults int) ([]
 
 // RecommendTickers provides backward compatibility with the main.go API
 func (a *TradingAlgorithm) RecommendTickers(sector string, maxResults int) ([]*types.RecommendedTicker, error) {
-	  log.Printf("Getting ticker recommendations for sector %s via recommendations module", sector)
+	  logger().Debug("Getting ticker recommendations via recommendations module", "sector", sector)
 	  oldRecs, err := a.RecommendTickersList(sector, maxResults)
   if err != nil {
     return nil, err
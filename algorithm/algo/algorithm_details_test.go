@@ -0,0 +1,55 @@
+package algo
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+func TestEveryAlgorithmPopulatesDetails(t *testing.T) {
+	history := make([]types.MarketData, 120)
+	for i := range history {
+		p := 100 + float64(i)*0.3 + 4*math.Sin(float64(i)/5)
+		history[i] = types.MarketData{Symbol: "TEST", Price: p, High24h: p * 1.01, Low24h: p * 0.99, Volume24h: 1e6}
+	}
+	current := history[len(history)-1]
+
+	for _, algType := range GetRegisteredAlgorithms() {
+		alg, err := Create(algType)
+		if err != nil {
+			t.Fatalf("%s: %v", algType, err)
+		}
+		if err := alg.Configure(AlgorithmConfig{}); err != nil {
+			t.Fatalf("%s configure: %v", algType, err)
+		}
+		result, err := alg.Process("TEST", &current, history)
+		if err != nil {
+			t.Logf("%s: skipped, process failed: %v", algType, err)
+			continue
+		}
+		if len(result.Details) == 0 {
+			t.Errorf("%s: result has no details", algType)
+			continue
+		}
+		if _, err := json.Marshal(result); err != nil {
+			t.Errorf("%s: details do not serialize: %v", algType, err)
+		}
+	}
+}
+
+func TestCombinedResultDetails(t *testing.T) {
+	am := NewAlgorithmManager()
+	combined, err := am.combineResults("TEST", []*AlgorithmResult{
+		{Signal: types.SignalBuy, Confidence: 0.8, Details: map[string]interface{}{"sharpe_ratio": 1.2}},
+		{Signal: types.SignalHold, Confidence: 0.5},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	components, ok := combined.Details["components"].([]map[string]interface{})
+	if !ok || len(components) != 2 || components[0]["details"] == nil {
+		t.Errorf("combined details = %+v", combined.Details)
+	}
+}
@@ -2,7 +2,9 @@ package algo
 
 import (
 	"fmt"
+	"sort"
 	"time"
+	"github.com/rileyseaburg/go-trader/algorithm/algo/volatility"
 	"github.com/rileyseaburg/go-trader/types"
 )
 
@@ -30,6 +32,12 @@ const (
 	AlgorithmTypePurgedCV AlgorithmType = "purged_cv"
 	// AlgorithmTypePositionSizing represents Advanced Position Sizing algorithm
 	AlgorithmTypePositionSizing AlgorithmType = "position_sizing"
+	// AlgorithmTypeMeanReversionOU represents Ornstein-Uhlenbeck mean reversion
+	AlgorithmTypeMeanReversionOU AlgorithmType = "mean_reversion_ou"
+	// AlgorithmTypeTSMOM represents time-series momentum
+	AlgorithmTypeTSMOM AlgorithmType = "tsmom"
+	// AlgorithmTypeATRSizing represents ATR-based position sizing
+	AlgorithmTypeATRSizing AlgorithmType = "atr_sizing"
 )
 
 // AlgorithmConfig represents the configuration for an algorithm
@@ -41,6 +49,31 @@ type AlgorithmConfig struct {
 	TargetReturn      float64            `json:"target_return"`
 	HistoricalDays    int                `json:"historical_days"`
 	AdditionalParams  map[string]float64 `json:"additional_params"`
+	// TimeFrame is the bar resolution the algorithm runs on: 1Min, 5Min,
+	// 15Min, 1H or 1D. Empty means DefaultTimeFrame.
+	TimeFrame string `json:"timeframe,omitempty"`
+	// BarCount is how many bars of history Process receives. Zero means
+	// the algorithm's own requirement, and never less than
+	// DefaultRequiredHistory.
+	BarCount int `json:"bar_count,omitempty"`
+	// Volatility names the estimator volatility-based algorithms use:
+	// close_to_close, parkinson, garman_klass, yang_zhang or garch. Empty
+	// keeps each algorithm's own close-based estimate.
+	Volatility string `json:"volatility,omitempty"`
+	// LabelingMethod names how meta-labeling labels the history it trains
+	// on: triple_barrier or trend_scanning. Empty leaves it untrained.
+	LabelingMethod string `json:"labeling_method,omitempty"`
+}
+
+// DefaultTimeFrame is the resolution used when a config names none
+const DefaultTimeFrame = "1D"
+
+// Resolution returns the configured timeframe, or DefaultTimeFrame
+func (c AlgorithmConfig) Resolution() string {
+	if c.TimeFrame == "" {
+		return DefaultTimeFrame
+	}
+	return c.TimeFrame
 }
 
 // AlgorithmResult represents the output of an algorithm
@@ -51,6 +84,9 @@ type AlgorithmResult struct {
 	Weights     map[string]float64 `json:"weights,omitempty"`
 	Confidence  float64            `json:"confidence"`
 	Explanation string             `json:"explanation"`
+	// Details carries the structured values behind Explanation — barrier
+	// levels, feature values, fold stats, weights — for the UI to render.
+	Details map[string]interface{} `json:"details,omitempty"`
 }
 
 // Algorithm defines the interface for all trading algorithms
@@ -75,6 +111,30 @@ type Algorithm interface {
 
 	// Explain provides an explanation of how the algorithm made its decision
 	Explain() string
+
+	// Metadata describes the algorithm and its parameters for discovery
+	Metadata() AlgorithmMetadata
+}
+
+// AlgorithmMetadata describes a registered algorithm: what it is, the
+// parameters it accepts and their defaults
+type AlgorithmMetadata struct {
+	Type        AlgorithmType          `json:"type"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]string      `json:"parameters"`
+	Defaults    map[string]interface{} `json:"defaults"`
+}
+
+// describe builds metadata from alg's own descriptions
+func describe(alg Algorithm, defaults map[string]interface{}) AlgorithmMetadata {
+	return AlgorithmMetadata{
+		Type:        alg.Type(),
+		Name:        alg.Name(),
+		Description: alg.Description(),
+		Parameters:  alg.ParameterDescription(),
+		Defaults:    defaults,
+	}
 }
 
 // BaseAlgorithm provides a base implementation for all algorithms
@@ -86,6 +146,12 @@ type BaseAlgorithm struct {
 
 // Configure configures the base algorithm
 func (b *BaseAlgorithm) Configure(config AlgorithmConfig) error {
+	if _, err := volatility.Parse(config.Volatility); err != nil {
+		return err
+	}
+	if _, err := ParseLabelingMethod(config.LabelingMethod); err != nil {
+		return err
+	}
 	b.config = config
 	return nil
 }
@@ -95,6 +161,74 @@ func (b *BaseAlgorithm) Explain() string {
 	return b.explanation
 }
 
+// DefaultRequiredHistory is the lookback, in bars, fed to algorithms whose
+// requirement is smaller or unstated
+const DefaultRequiredHistory = 30
+
+// HistoryRequirer is implemented by algorithms that need a minimum number
+// of historical observations before Process can succeed
+type HistoryRequirer interface {
+	RequiredHistory() int
+}
+
+// RequiredHistory returns the configured historical days, zero when unset,
+// or the bars a GARCH fit needs when that is more
+func (b *BaseAlgorithm) RequiredHistory() int {
+	if volatility.Estimator(b.config.Volatility) == volatility.GARCHForecast {
+		return maxInt(b.config.HistoricalDays, volatility.MinGARCHReturns+1)
+	}
+	return b.config.HistoricalDays
+}
+
+// RequiredHistory returns the minimum number of historical bars alg needs,
+// zero when it states none
+func RequiredHistory(alg Algorithm) int {
+	if r, ok := alg.(HistoryRequirer); ok {
+		return r.RequiredHistory()
+	}
+	return 0
+}
+
+// HistoryBars returns how many bars of history to pass alg configured with
+// config: the explicit BarCount, or its requirement but at least
+// DefaultRequiredHistory
+func HistoryBars(alg Algorithm, config AlgorithmConfig) int {
+	if config.BarCount > 0 {
+		return config.BarCount
+	}
+	return maxInt(RequiredHistory(alg), DefaultRequiredHistory)
+}
+
+// CheckBarCount returns an error if config sets a BarCount below what alg
+// needs
+func CheckBarCount(alg Algorithm, config AlgorithmConfig) error {
+	if need := RequiredHistory(alg); config.BarCount > 0 && config.BarCount < need {
+		return fmt.Errorf("bar_count %d is below the %d bars %s needs with these parameters",
+			config.BarCount, need, alg.Name())
+	}
+	return nil
+}
+
+// primaryHistory returns the history needed by a default-configured
+// primary algorithm of the given type
+func primaryHistory(algType AlgorithmType) int {
+	alg, err := Create(algType)
+	if err != nil || alg.Configure(AlgorithmConfig{}) != nil {
+		return 0
+	}
+	return RequiredHistory(alg)
+}
+
+func maxInt(values ...int) int {
+	m := 0
+	for _, v := range values {
+		if v > m {
+			m = v
+		}
+	}
+	return m
+}
+
 // FactoryFunc is a function that creates a new algorithm
 type FactoryFunc func() Algorithm
 
@@ -115,6 +249,22 @@ func Create(algType AlgorithmType) (Algorithm, error) {
 	return factory(), nil
 }
 
+// AllMetadata returns metadata for every registered algorithm, ordered by
+// type
+func AllMetadata() []AlgorithmMetadata {
+	algTypes := GetRegisteredAlgorithms()
+	sort.Slice(algTypes, func(i, j int) bool { return algTypes[i] < algTypes[j] })
+	out := make([]AlgorithmMetadata, 0, len(algTypes))
+	for _, algType := range algTypes {
+		alg, err := Create(algType)
+		if err != nil {
+			continue
+		}
+		out = append(out, alg.Metadata())
+	}
+	return out
+}
+
 // GetRegisteredAlgorithms returns all registered algorithm types
 func GetRegisteredAlgorithms() []AlgorithmType {
 	types := make([]AlgorithmType, 0, len(algorithmRegistry))
@@ -2,7 +2,7 @@ package algo
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
 	"sync"
 	"time"
@@ -10,6 +10,8 @@ import (
 	"github.com/rileyseaburg/go-trader/types"
 )
 
+func logger() *slog.Logger { return slog.With("module", "algo") }
+
 // AlgorithmManager manages the trading algorithms and their integration with Claude
 type AlgorithmManager struct {
 	algorithms    map[AlgorithmType]Algorithm
@@ -65,7 +67,7 @@ func (am *AlgorithmManager) RegisterAlgorithm(alg Algorithm) error {
 		return fmt.Errorf("failed to configure algorithm %s: %w", alg.Name(), err)
 	}
 
-	log.Printf("Registered algorithm: %s (%s)", alg.Name(), algType)
+	logger().Info("Registered algorithm", "name", alg.Name(), "type", algType)
 	return nil
 }
 
@@ -157,7 +159,7 @@ func (am *AlgorithmManager) ProcessWithAllAlgorithms(symbol string, data *types.
 	for _, alg := range algorithms {
 		result, err := alg.Process(symbol, data, historicalData)
 		if err != nil {
-			log.Printf("Warning: Algorithm %s failed to process data: %v", alg.Name(), err)
+			logger().Warn("Algorithm failed to process data", "name", alg.Name(), "error", err)
 			continue
 		}
 		results = append(results, result)
@@ -246,6 +248,7 @@ func (am *AlgorithmManager) combineResults(symbol string, results []*AlgorithmRe
 
 	// Combine explanations
 	combinedExplanation := fmt.Sprintf("Combined analysis from %d algorithms:\n", len(results))
+	components := make([]map[string]interface{}, 0, len(results))
 	for _, result := range results {
 		algorithmName := "Unknown" // In a real implementation, you'd get this from the algorithm
 		for _, alg := range am.GetAvailableAlgorithms() {
@@ -256,6 +259,12 @@ func (am *AlgorithmManager) combineResults(symbol string, results []*AlgorithmRe
 		}
 		combinedExplanation += fmt.Sprintf("- %s (%.0f%% confidence): %s\n",
 			algorithmName, result.Confidence*100, result.Signal)
+		components = append(components, map[string]interface{}{
+			"algorithm":  algorithmName,
+			"signal":     result.Signal,
+			"confidence": result.Confidence,
+			"details":    result.Details,
+		})
 	}
 
 	combinedExplanation += fmt.Sprintf("\nFinal recommendation: %s with %.0f%% confidence.\n",
@@ -276,6 +285,12 @@ func (am *AlgorithmManager) combineResults(symbol string, results []*AlgorithmRe
 		LimitPrice:  limitPrice,
 		Confidence:  maxWeight,
 		Explanation: combinedExplanation,
+		Details: map[string]interface{}{
+			"signal_weights": signalWeights,
+			"buy_weight":     totalBuyWeight,
+			"sell_weight":    totalSellWeight,
+			"components":     components,
+		},
 	}
 
 	return combinedResult, nil
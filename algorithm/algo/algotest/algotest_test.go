@@ -0,0 +1,48 @@
+package algotest
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRegisteredAlgorithmsMatchGolden(t *testing.T) {
+	CheckGolden(t, filepath.Join("testdata", "golden.json"), Run(Fixtures()))
+}
+
+func TestCompareReportsChanges(t *testing.T) {
+	limit := 101.5
+	want := []Result{
+		{Algorithm: "mvo", Fixture: "trending", Signal: "buy", Confidence: 0.7, Weights: map[string]float64{"FIXT": 1}},
+		{Algorithm: "mvo", Fixture: "crash", Signal: "sell", Confidence: 0.8, LimitPrice: &limit},
+		{Algorithm: "hrp", Fixture: "gap", Error: "insufficient data"},
+		{Algorithm: "bootstrap", Fixture: "gap", Unstable: true},
+	}
+	got := []Result{
+		{Algorithm: "mvo", Fixture: "trending", Signal: "buy", Confidence: 0.7 + DefaultTolerance/2, Weights: map[string]float64{"FIXT": 0.5}},
+		{Algorithm: "mvo", Fixture: "crash", Signal: "hold", Confidence: 0.8},
+		{Algorithm: "bootstrap", Fixture: "gap", Unstable: true},
+		{Algorithm: "cusum", Fixture: "gap", Signal: "hold"},
+	}
+	fields := make(map[string]bool)
+	for _, d := range Compare(got, want, DefaultTolerance) {
+		fields[string(d.Algorithm)+" "+d.Fixture+" "+d.Field] = true
+	}
+	for _, f := range []string{
+		"mvo trending weights.FIXT", "mvo crash signal", "mvo crash limit_price",
+		"hrp gap result", "cusum gap result",
+	} {
+		if !fields[f] {
+			t.Errorf("no diff for %s in %v", f, fields)
+		}
+	}
+	if len(fields) != 5 {
+		t.Errorf("diffs = %v", fields)
+	}
+
+	// Every fixture has a year of dated sessions
+	for _, f := range Fixtures() {
+		if len(f.History) != FixtureBars || f.History[0].Timestamp.IsZero() || f.Current().Price <= 0 {
+			t.Errorf("%s: %d bars, current %+v", f.Name, len(f.History), f.Current())
+		}
+	}
+}
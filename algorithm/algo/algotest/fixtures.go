@@ -0,0 +1,143 @@
+// Package algotest exercises registered algorithms against canned market
+// scenarios and compares what they decide with golden results, so a change
+// to an algorithm's internals that changes its behavior shows up as a
+// failing test instead of in production. Every fixture is generated
+// deterministically and stamped with session dates, so results do not
+// depend on when the tests run.
+package algotest
+
+import (
+	"math"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+// Fixture is a named market scenario: daily bars, oldest first. The last
+// bar is the current data passed to Process.
+type Fixture struct {
+	Name        string
+	Description string
+	Symbol      string
+	History     []types.MarketData
+}
+
+// Current returns the fixture's latest bar.
+func (f Fixture) Current() *types.MarketData {
+	last := f.History[len(f.History)-1]
+	return &last
+}
+
+// FixtureBars is how many daily bars each fixture has: a year of sessions,
+// enough for every algorithm's default lookback.
+const FixtureBars = 252
+
+// fixtureStart is the first fixture session.
+var fixtureStart = time.Date(2024, 1, 2, 21, 0, 0, 0, time.UTC)
+
+// Fixtures returns every canned scenario, in a stable order.
+func Fixtures() []Fixture {
+	return []Fixture{Trending(), MeanReverting(), Crash(), Gap()}
+}
+
+// Trending is a steady uptrend of about 0.15% a session with mild noise.
+func Trending() Fixture {
+	noise := newNoise(1)
+	return build("trending", "steady uptrend with mild noise", func(i int, prev float64) float64 {
+		return prev * (1 + 0.0015 + 0.006*noise.next())
+	})
+}
+
+// MeanReverting oscillates around 100, each session pulled a fifth of the
+// way back toward it, with no trend.
+func MeanReverting() Fixture {
+	noise := newNoise(2)
+	return build("mean_reverting", "range bound around 100 with strong reversion", func(i int, prev float64) float64 {
+		return prev + 0.2*(100-prev) + 1.5*noise.next()
+	})
+}
+
+// Crash drifts up for most of the year, then falls about 35% over ten
+// sessions and stabilizes near the low.
+func Crash() Fixture {
+	noise := newNoise(3)
+	const start, length = 220, 10
+	return build("crash", "uptrend ending in a 35% crash over ten sessions", func(i int, prev float64) float64 {
+		switch {
+		case i >= start && i < start+length:
+			return prev * (1 - 0.042 + 0.004*noise.next())
+		case i >= start+length:
+			return prev * (1 + 0.008*noise.next())
+		}
+		return prev * (1 + 0.001 + 0.008*noise.next())
+	})
+}
+
+// Gap trades in a range, gaps down 15% overnight and drifts lower.
+func Gap() Fixture {
+	noise := newNoise(4)
+	const at = 200
+	return build("gap", "range, then a 15% overnight gap down and a weak drift", func(i int, prev float64) float64 {
+		switch {
+		case i == at:
+			return prev * 0.85
+		case i > at:
+			return prev * (1 - 0.002 + 0.01*noise.next())
+		}
+		return prev + 0.1*(100-prev) + 1.2*noise.next()
+	})
+}
+
+// build generates FixtureBars sessions starting at 100, each close from
+// the previous by step. Highs and lows straddle the close by a fixed
+// range and volume rises with the size of the move.
+func build(name, description string, step func(i int, prev float64) float64) Fixture {
+	f := Fixture{Name: name, Description: description, Symbol: "FIXT", History: make([]types.MarketData, FixtureBars)}
+	day, price := fixtureStart, 100.0
+	for i := range f.History {
+		prev := price
+		if i > 0 {
+			price = round(step(i, prev))
+			day = nextSession(day)
+		}
+		change := 0.0
+		if i > 0 {
+			change = round((price/prev - 1) * 100)
+		}
+		f.History[i] = types.MarketData{
+			Symbol:    f.Symbol,
+			Price:     price,
+			High24h:   round(price * 1.01),
+			Low24h:    round(price * 0.99),
+			Volume24h: math.Round(1e6 * (1 + math.Abs(change)/2)),
+			Change24h: change,
+			Timestamp: day,
+		}
+	}
+	return f
+}
+
+// nextSession is the weekday after day.
+func nextSession(day time.Time) time.Time {
+	day = day.AddDate(0, 0, 1)
+	for day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+func round(v float64) float64 { return math.Round(v*1e4) / 1e4 }
+
+// noise is a seeded linear congruential generator, so fixtures are the
+// same on every platform and Go release.
+type noise struct{ state uint64 }
+
+func newNoise(seed uint64) *noise {
+	return &noise{state: seed*6364136223846793005 + 1442695040888963407}
+}
+
+// next returns a value uniform in [-1, 1).
+func (n *noise) next() float64 {
+	n.state = n.state*6364136223846793005 + 1442695040888963407
+	return float64(n.state>>11)/float64(1<<53)*2 - 1
+}
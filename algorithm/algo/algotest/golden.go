@@ -0,0 +1,259 @@
+package algotest
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/rileyseaburg/go-trader/algorithm/algo"
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+// update rewrites golden files instead of comparing against them:
+// go test ./algotest -update
+var update = flag.Bool("update", false, "rewrite golden files with the current results")
+
+// DefaultTolerance is how far a golden confidence or weight may drift
+// before it counts as a change.
+const DefaultTolerance = 1e-6
+
+// repeats is how many times Run processes each fixture, to tell
+// nondeterministic algorithms from regressions.
+const repeats = 3
+
+// Result is what one algorithm decided on one fixture, reduced to the
+// fields that define its behavior. Floats are rounded to six places.
+type Result struct {
+	Algorithm  algo.AlgorithmType `json:"algorithm"`
+	Fixture    string             `json:"fixture"`
+	Signal     string             `json:"signal,omitempty"`
+	OrderType  string             `json:"order_type,omitempty"`
+	LimitPrice *float64           `json:"limit_price,omitempty"`
+	Confidence float64            `json:"confidence"`
+	Weights    map[string]float64 `json:"weights,omitempty"`
+	// Error is the error or panic Process ended with
+	Error string `json:"error,omitempty"`
+	// Unstable marks results that differed between runs on the same
+	// fixture; only their error is compared
+	Unstable bool `json:"unstable,omitempty"`
+}
+
+// Diff is one field of one result that differs from its golden value.
+type Diff struct {
+	Algorithm algo.AlgorithmType `json:"algorithm"`
+	Fixture   string             `json:"fixture"`
+	Field     string             `json:"field"`
+	Got       string             `json:"got"`
+	Want      string             `json:"want"`
+}
+
+func (d Diff) String() string {
+	return fmt.Sprintf("%s on %s: %s is %s, golden %s", d.Algorithm, d.Fixture, d.Field, d.Got, d.Want)
+}
+
+// Run processes every fixture with every registered algorithm, each
+// freshly created and configured with defaults. Results are ordered by
+// algorithm, then fixture.
+func Run(fixtures []Fixture) []Result {
+	algTypes := algo.GetRegisteredAlgorithms()
+	sort.Slice(algTypes, func(i, j int) bool { return algTypes[i] < algTypes[j] })
+	var out []Result
+	for _, algType := range algTypes {
+		for _, f := range fixtures {
+			r := RunOne(algType, f)
+			for i := 1; i < repeats && !r.Unstable; i++ {
+				if again := RunOne(algType, f); !reflect.DeepEqual(again, r) {
+					r = Result{Algorithm: algType, Fixture: f.Name, Error: r.Error, Unstable: true}
+				}
+			}
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// RunOne processes fixture f with a new default-configured algorithm of
+// type algType, recovering a panic into the result's error.
+func RunOne(algType algo.AlgorithmType, f Fixture) (r Result) {
+	r = Result{Algorithm: algType, Fixture: f.Name}
+	defer func() {
+		if p := recover(); p != nil {
+			r = Result{Algorithm: algType, Fixture: f.Name, Error: fmt.Sprintf("panic: %v", p)}
+		}
+	}()
+	alg, err := algo.Create(algType)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	if err := alg.Configure(algo.AlgorithmConfig{}); err != nil {
+		r.Error = "configure: " + err.Error()
+		return r
+	}
+	history := make([]types.MarketData, len(f.History))
+	copy(history, f.History)
+	res, err := alg.Process(f.Symbol, f.Current(), history)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	if res == nil {
+		r.Error = "no result"
+		return r
+	}
+	r.Signal, r.OrderType, r.Confidence = res.Signal, res.OrderType, round6(res.Confidence)
+	if res.LimitPrice != nil {
+		p := round6(*res.LimitPrice)
+		r.LimitPrice = &p
+	}
+	if len(res.Weights) > 0 {
+		r.Weights = make(map[string]float64, len(res.Weights))
+		for k, v := range res.Weights {
+			r.Weights[k] = round6(v)
+		}
+	}
+	return r
+}
+
+// Compare returns how got differs from want: results missing from either
+// side, and changed fields of the rest. Floats may differ by tolerance.
+func Compare(got, want []Result, tolerance float64) []Diff {
+	type key struct {
+		alg     algo.AlgorithmType
+		fixture string
+	}
+	wanted := make(map[key]Result, len(want))
+	for _, w := range want {
+		wanted[key{w.Algorithm, w.Fixture}] = w
+	}
+	var diffs []Diff
+	seen := make(map[key]bool, len(got))
+	for _, g := range got {
+		k := key{g.Algorithm, g.Fixture}
+		seen[k] = true
+		w, ok := wanted[k]
+		if !ok {
+			diffs = append(diffs, Diff{Algorithm: g.Algorithm, Fixture: g.Fixture, Field: "result", Got: "present", Want: "missing"})
+			continue
+		}
+		diffs = append(diffs, compareResult(g, w, tolerance)...)
+	}
+	for _, w := range want {
+		if !seen[key{w.Algorithm, w.Fixture}] {
+			diffs = append(diffs, Diff{Algorithm: w.Algorithm, Fixture: w.Fixture, Field: "result", Got: "missing", Want: "present"})
+		}
+	}
+	return diffs
+}
+
+func compareResult(g, w Result, tolerance float64) []Diff {
+	var diffs []Diff
+	add := func(field string, got, want interface{}) {
+		diffs = append(diffs, Diff{Algorithm: g.Algorithm, Fixture: g.Fixture, Field: field,
+			Got: fmt.Sprint(got), Want: fmt.Sprint(want)})
+	}
+	if g.Error != w.Error {
+		add("error", g.Error, w.Error)
+	}
+	if g.Unstable != w.Unstable {
+		add("unstable", g.Unstable, w.Unstable)
+	}
+	if w.Unstable || g.Unstable {
+		return diffs
+	}
+	if g.Signal != w.Signal {
+		add("signal", g.Signal, w.Signal)
+	}
+	if g.OrderType != w.OrderType {
+		add("order_type", g.OrderType, w.OrderType)
+	}
+	if math.Abs(g.Confidence-w.Confidence) > tolerance {
+		add("confidence", g.Confidence, w.Confidence)
+	}
+	switch {
+	case (g.LimitPrice == nil) != (w.LimitPrice == nil):
+		add("limit_price", fmtPrice(g.LimitPrice), fmtPrice(w.LimitPrice))
+	case g.LimitPrice != nil && math.Abs(*g.LimitPrice-*w.LimitPrice) > tolerance:
+		add("limit_price", *g.LimitPrice, *w.LimitPrice)
+	}
+	names := make(map[string]bool)
+	for k := range g.Weights {
+		names[k] = true
+	}
+	for k := range w.Weights {
+		names[k] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for k := range names {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	for _, k := range sorted {
+		gv, gok := g.Weights[k]
+		wv, wok := w.Weights[k]
+		if gok != wok || math.Abs(gv-wv) > tolerance {
+			add("weights."+k, gv, wv)
+		}
+	}
+	return diffs
+}
+
+func fmtPrice(p *float64) string {
+	if p == nil {
+		return "none"
+	}
+	return fmt.Sprint(*p)
+}
+
+// LoadGolden reads golden results from path.
+func LoadGolden(path string) ([]Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var results []Result
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("invalid golden file %s: %w", path, err)
+	}
+	return results, nil
+}
+
+// WriteGolden writes results to path as indented JSON.
+func WriteGolden(path string, results []Result) error {
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// CheckGolden fails t for every way got differs from the golden results at
+// path. With -update it rewrites the file from got instead.
+func CheckGolden(t testing.TB, path string, got []Result) {
+	t.Helper()
+	if *update {
+		if err := WriteGolden(path, got); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+		t.Logf("updated %s with %d results", path, len(got))
+		return
+	}
+	want, err := LoadGolden(path)
+	if err != nil {
+		t.Fatalf("failed to load golden results (run with -update to create them): %v", err)
+	}
+	for _, d := range Compare(got, want, DefaultTolerance) {
+		t.Error(d)
+	}
+}
+
+func round6(v float64) float64 { return math.Round(v*1e6) / 1e6 }
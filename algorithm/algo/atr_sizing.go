@@ -0,0 +1,205 @@
+package algo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/rileyseaburg/go-trader/algorithm/algo/volatility"
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+// init registers the ATR sizing algorithm with the factory
+func init() {
+	Register(AlgorithmTypeATRSizing, func() Algorithm {
+		return &ATRSizingAlgorithm{}
+	})
+}
+
+// ATRSizingAlgorithm sizes the primary algorithm's signal in shares so
+// that an adverse move of one average true range loses a fixed fraction
+// of equity, the volatility-normalized sizing of the Turtle traders:
+// shares = equity × risk ÷ ATR. Quiet symbols get more shares than
+// volatile ones for the same risk, up to a cap on the position's value.
+type ATRSizingAlgorithm struct {
+	BaseAlgorithm
+	riskPercent      float64       // Equity lost on a one-ATR adverse move, in percent
+	atrPeriod        int           // Bars in the average true range
+	equity           float64       // Account equity the shares are sized for
+	maxSize          float64       // Largest position value as a fraction of equity
+	primaryAlgorithm AlgorithmType // Signal generator whose signal is sized
+}
+
+// Name returns the name of the algorithm
+func (s *ATRSizingAlgorithm) Name() string {
+	return "ATR Position Sizing"
+}
+
+// Type returns the type of the algorithm
+func (s *ATRSizingAlgorithm) Type() AlgorithmType {
+	return AlgorithmTypeATRSizing
+}
+
+// Description returns a brief description of the algorithm
+func (s *ATRSizingAlgorithm) Description() string {
+	return "Sizes the primary algorithm's signal in shares so a one-ATR adverse move loses a fixed percent of equity"
+}
+
+// ParameterDescription returns a description of the parameters
+func (s *ATRSizingAlgorithm) ParameterDescription() map[string]string {
+	return map[string]string{
+		"risk_percent":      "Percent of equity lost on a one-ATR adverse move (default: 1.0)",
+		"atr_period":        "Bars in the average true range (default: 14)",
+		"equity":            "Account equity the shares are sized for; orders are sized against the live account (default: 100000)",
+		"max_size":          "Largest position value as a fraction of equity (default: 0.2)",
+		"primary_algorithm": "Type of primary algorithm to use for signal generation (default: sequential_bootstrap)",
+	}
+}
+
+// Metadata describes the algorithm and its default parameters
+func (s *ATRSizingAlgorithm) Metadata() AlgorithmMetadata {
+	return describe(s, map[string]interface{}{
+		"risk_percent":      1.0,
+		"atr_period":        14,
+		"equity":            100000.0,
+		"max_size":          0.2,
+		"primary_algorithm": string(AlgorithmTypeSequentialBootstrap),
+	})
+}
+
+// Configure configures the algorithm with the given parameters
+func (s *ATRSizingAlgorithm) Configure(config AlgorithmConfig) error {
+	if err := s.BaseAlgorithm.Configure(config); err != nil {
+		return err
+	}
+
+	// Set default values
+	s.riskPercent = 1.0
+	s.atrPeriod = 14
+	s.equity = 100000
+	s.maxSize = 0.2
+	s.primaryAlgorithm = AlgorithmTypeSequentialBootstrap
+
+	// Override with provided values
+	if val, ok := config.AdditionalParams["risk_percent"]; ok {
+		if val <= 0 || val > 10 {
+			return errors.New("risk_percent must be above 0 and at most 10")
+		}
+		s.riskPercent = val
+	}
+
+	if val, ok := config.AdditionalParams["atr_period"]; ok {
+		if val < 1 {
+			return errors.New("atr_period must be at least 1")
+		}
+		s.atrPeriod = int(val)
+	}
+
+	if val, ok := config.AdditionalParams["equity"]; ok {
+		if val <= 0 {
+			return errors.New("equity must be positive")
+		}
+		s.equity = val
+	}
+
+	if val, ok := config.AdditionalParams["max_size"]; ok {
+		if val <= 0 || val > 1 {
+			return errors.New("max_size must be between 0 and 1")
+		}
+		s.maxSize = val
+	}
+
+	return nil
+}
+
+// RequiredHistory returns the bars needed for the ATR, with the close
+// before its first bar, and the primary algorithm
+func (s *ATRSizingAlgorithm) RequiredHistory() int {
+	return maxInt(s.BaseAlgorithm.RequiredHistory(), s.atrPeriod+1, primaryHistory(s.primaryAlgorithm))
+}
+
+// Process runs the primary algorithm and sizes its signal in shares
+func (s *ATRSizingAlgorithm) Process(
+	symbol string,
+	currentData *types.MarketData,
+	historicalData []types.MarketData,
+) (*AlgorithmResult, error) {
+	if currentData == nil || currentData.Price <= 0 {
+		return nil, errors.New("current price is required")
+	}
+	bars := appendCurrent(historicalData, currentData)
+	atr, err := volatility.ATR(VolatilityBars(bars), s.atrPeriod)
+	if err != nil {
+		return nil, fmt.Errorf("error calculating ATR: %v", err)
+	}
+
+	primaryAlg, err := Create(s.primaryAlgorithm)
+	if err != nil {
+		return nil, fmt.Errorf("error creating primary algorithm: %v", err)
+	}
+	if err := primaryAlg.Configure(AlgorithmConfig{}); err != nil {
+		return nil, fmt.Errorf("error configuring primary algorithm: %v", err)
+	}
+	primaryResult, err := primaryAlg.Process(symbol, currentData, historicalData)
+	if err != nil {
+		return nil, fmt.Errorf("error processing primary algorithm: %v", err)
+	}
+	if primaryResult.Signal != types.SignalBuy && primaryResult.Signal != types.SignalSell {
+		s.explanation = fmt.Sprintf("Primary algorithm (%s) signals %s. No position to size.", primaryAlg.Name(), primaryResult.Signal)
+		return primaryResult, nil
+	}
+
+	size := ATRShares(s.equity, currentData.Price, atr, s.riskPercent, s.maxSize)
+
+	s.explanation = fmt.Sprintf("Primary algorithm (%s) generated %s signal with confidence %.2f.\n",
+		primaryAlg.Name(), primaryResult.Signal, primaryResult.Confidence)
+	s.explanation += fmt.Sprintf("%d-bar ATR: %.4f (%.2f%% of price).\n", s.atrPeriod, atr, atr/currentData.Price*100)
+	s.explanation += fmt.Sprintf("Risking %.2f%% of $%.0f on a one-ATR move: %.0f shares, $%.2f (%.2f%% of equity)",
+		s.riskPercent, s.equity, size.Shares, size.Value, size.Value/s.equity*100)
+	if size.Capped {
+		s.explanation += fmt.Sprintf(", capped at max_size %.2f", s.maxSize)
+	}
+
+	return &AlgorithmResult{
+		Signal:      primaryResult.Signal,
+		OrderType:   primaryResult.OrderType,
+		LimitPrice:  primaryResult.LimitPrice,
+		Confidence:  primaryResult.Confidence,
+		Explanation: s.explanation,
+		Details: map[string]interface{}{
+			"primary_algorithm":  primaryAlg.Name(),
+			"primary_signal":     primaryResult.Signal,
+			"primary_confidence": primaryResult.Confidence,
+			"atr":                atr,
+			"atr_period":         s.atrPeriod,
+			"risk_percent":       s.riskPercent,
+			"risk_amount":        s.equity * s.riskPercent / 100,
+			"shares":             size.Shares,
+			"position_value":     size.Value,
+			"position_size":      size.Value / s.equity,
+			"capped":             size.Capped,
+		},
+	}, nil
+}
+
+// ATRSize is a position sized by ATRShares.
+type ATRSize struct {
+	Shares float64 // whole shares
+	Value  float64 // Shares at the price
+	Capped bool    // maxSize bound
+}
+
+// ATRShares sizes a position at price so that a one-ATR adverse move
+// loses riskPercent of equity, in whole shares rounded down, and no more
+// than maxSize of equity in value. A non-positive input sizes nothing.
+func ATRShares(equity, price, atr, riskPercent, maxSize float64) ATRSize {
+	if equity <= 0 || price <= 0 || atr <= 0 || riskPercent <= 0 {
+		return ATRSize{}
+	}
+	size := ATRSize{Shares: math.Floor(equity * riskPercent / 100 / atr)}
+	if limit := math.Floor(equity * maxSize / price); maxSize > 0 && size.Shares > limit {
+		size.Shares, size.Capped = limit, true
+	}
+	size.Value = size.Shares * price
+	return size
+}
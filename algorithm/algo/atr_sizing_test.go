@@ -0,0 +1,59 @@
+package algo
+
+import (
+	"math"
+	"testing"
+
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+func TestATRShares(t *testing.T) {
+	// $100k risking 1% on a $2 ATR is 500 shares, $25,000 at $50
+	size := ATRShares(100000, 50, 2, 1, 0.5)
+	if size.Shares != 500 || size.Value != 25000 || size.Capped {
+		t.Errorf("size = %+v", size)
+	}
+	// A quiet symbol wants 2000 shares, but 20% of equity buys only 400
+	size = ATRShares(100000, 50, 0.5, 1, 0.2)
+	if size.Shares != 400 || !size.Capped {
+		t.Errorf("capped size = %+v", size)
+	}
+	if size := ATRShares(100000, 50, 0, 1, 0.2); size.Shares != 0 {
+		t.Errorf("zero ATR sized %v shares", size.Shares)
+	}
+}
+
+func TestATRSizingProcess(t *testing.T) {
+	alg := &ATRSizingAlgorithm{}
+	if err := alg.Configure(AlgorithmConfig{AdditionalParams: map[string]float64{"atr_period": 10, "equity": 50000}}); err != nil {
+		t.Fatal(err)
+	}
+	alg.primaryAlgorithm = AlgorithmTypeTSMOM
+	history := driftHistory(alg.RequiredHistory()+1, 0.003, 1)
+	for i := range history {
+		history[i].High24h = history[i].Price * 1.01
+		history[i].Low24h = history[i].Price * 0.99
+	}
+	current := history[len(history)-1]
+	history = history[:len(history)-1]
+	result, err := alg.Process("ATR", &current, history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Signal != types.SignalBuy {
+		t.Fatalf("signal = %s", result.Signal)
+	}
+	// 50,000 × 1% over a 10-bar ATR, within 20% of equity
+	atr := result.Details["atr"].(float64)
+	shares := result.Details["shares"].(float64)
+	if want := math.Min(math.Floor(500/atr), math.Floor(10000/current.Price)); shares != want || atr <= 0 {
+		t.Errorf("shares = %v at ATR %v, want %v", shares, atr, want)
+	}
+
+	if err := alg.Configure(AlgorithmConfig{AdditionalParams: map[string]float64{"risk_percent": 0}}); err == nil {
+		t.Error("accepted a zero risk_percent")
+	}
+	if _, err := alg.Process("SHORT", &current, history[:3]); err == nil {
+		t.Error("processed too little history for the ATR")
+	}
+}
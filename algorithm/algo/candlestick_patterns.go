@@ -0,0 +1,174 @@
+package algo
+
+import (
+	"math"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+// CandlePattern names a candlestick pattern
+type CandlePattern string
+
+const (
+	// PatternDoji is a candle whose open and close are nearly equal
+	PatternDoji CandlePattern = "doji"
+	// PatternHammer is a small-bodied candle with a long lower shadow after a decline
+	PatternHammer CandlePattern = "hammer"
+	// PatternBullishEngulfing is an up candle whose body engulfs the prior down candle
+	PatternBullishEngulfing CandlePattern = "bullish_engulfing"
+	// PatternBearishEngulfing is a down candle whose body engulfs the prior up candle
+	PatternBearishEngulfing CandlePattern = "bearish_engulfing"
+	// PatternBullishThreeLineStrike is three falling down candles reversed by one up candle
+	PatternBullishThreeLineStrike CandlePattern = "bullish_three_line_strike"
+	// PatternBearishThreeLineStrike is three rising up candles reversed by one down candle
+	PatternBearishThreeLineStrike CandlePattern = "bearish_three_line_strike"
+)
+
+// PatternFeatureOrder is the fixed order in which patterns are emitted as
+// boolean features by PatternFeatures.
+var PatternFeatureOrder = []CandlePattern{
+	PatternDoji,
+	PatternHammer,
+	PatternBullishEngulfing,
+	PatternBearishEngulfing,
+	PatternBullishThreeLineStrike,
+	PatternBearishThreeLineStrike,
+}
+
+// Pattern shape thresholds, as fractions of the candle's range or body
+const (
+	dojiBodyRatio       = 0.1  // body at most 10% of range
+	hammerShadowRatio   = 2.0  // lower shadow at least twice the body
+	hammerUpperRatio    = 0.25 // upper shadow at most 25% of range
+	hammerTrendLookback = 3    // bars used to confirm the prior decline
+)
+
+// Candle is a single OHLC bar
+type Candle struct {
+	Timestamp time.Time `json:"timestamp"`
+	Open      float64   `json:"open"`
+	High      float64   `json:"high"`
+	Low       float64   `json:"low"`
+	Close     float64   `json:"close"`
+}
+
+// PatternMatch is a pattern detected on the candle at Index
+type PatternMatch struct {
+	Pattern   CandlePattern `json:"pattern"`
+	Direction string        `json:"direction"` // bullish, bearish or neutral
+	Index     int           `json:"index"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+func (c Candle) body() float64        { return math.Abs(c.Close - c.Open) }
+func (c Candle) rng() float64         { return c.High - c.Low }
+func (c Candle) bullish() bool        { return c.Close > c.Open }
+func (c Candle) bearish() bool        { return c.Close < c.Open }
+func (c Candle) upperShadow() float64 { return c.High - math.Max(c.Open, c.Close) }
+func (c Candle) lowerShadow() float64 { return math.Min(c.Open, c.Close) - c.Low }
+
+// CandlesFromMarketData approximates candles from market data snapshots,
+// using the previous snapshot's price as each candle's open. The first
+// snapshot only seeds the open and does not produce a candle.
+func CandlesFromMarketData(data []types.MarketData) []Candle {
+	if len(data) < 2 {
+		return nil
+	}
+	candles := make([]Candle, 0, len(data)-1)
+	for i := 1; i < len(data); i++ {
+		open, cl := data[i-1].Price, data[i].Price
+		high := math.Max(data[i].High24h, math.Max(open, cl))
+		low := data[i].Low24h
+		if low <= 0 || low > math.Min(open, cl) {
+			low = math.Min(open, cl)
+		}
+		candles = append(candles, Candle{Open: open, High: high, Low: low, Close: cl})
+	}
+	return candles
+}
+
+// DetectPatterns scans every candle and returns all pattern matches in
+// chronological order.
+func DetectPatterns(candles []Candle) []PatternMatch {
+	var matches []PatternMatch
+	for i := range candles {
+		matches = append(matches, patternsAt(candles, i)...)
+	}
+	return matches
+}
+
+// LatestPatterns returns the patterns completed by the most recent candle
+func LatestPatterns(candles []Candle) []PatternMatch {
+	if len(candles) == 0 {
+		return nil
+	}
+	return patternsAt(candles, len(candles)-1)
+}
+
+// PatternFeatures returns one 0/1 feature per entry of PatternFeatureOrder
+// for the most recent candle.
+func PatternFeatures(candles []Candle) []float64 {
+	found := make(map[CandlePattern]bool)
+	for _, m := range LatestPatterns(candles) {
+		found[m.Pattern] = true
+	}
+	features := make([]float64, len(PatternFeatureOrder))
+	for i, p := range PatternFeatureOrder {
+		if found[p] {
+			features[i] = 1
+		}
+	}
+	return features
+}
+
+// patternsAt returns the patterns that complete on candle i
+func patternsAt(candles []Candle, i int) []PatternMatch {
+	c := candles[i]
+	if c.rng() <= 0 {
+		return nil
+	}
+
+	var matches []PatternMatch
+	add := func(p CandlePattern, dir string) {
+		matches = append(matches, PatternMatch{Pattern: p, Direction: dir, Index: i, Timestamp: c.Timestamp})
+	}
+
+	if c.body() <= dojiBodyRatio*c.rng() {
+		add(PatternDoji, "neutral")
+	}
+
+	if i >= hammerTrendLookback &&
+		c.lowerShadow() >= hammerShadowRatio*c.body() &&
+		c.upperShadow() <= hammerUpperRatio*c.rng() &&
+		c.body() > 0 &&
+		math.Max(c.Open, c.Close) < candles[i-hammerTrendLookback].Close {
+		add(PatternHammer, "bullish")
+	}
+
+	if i >= 1 {
+		prev := candles[i-1]
+		if prev.bearish() && c.bullish() && c.Open <= prev.Close && c.Close >= prev.Open && c.body() > prev.body() {
+			add(PatternBullishEngulfing, "bullish")
+		}
+		if prev.bullish() && c.bearish() && c.Open >= prev.Close && c.Close <= prev.Open && c.body() > prev.body() {
+			add(PatternBearishEngulfing, "bearish")
+		}
+	}
+
+	if i >= 3 {
+		a, b, d := candles[i-3], candles[i-2], candles[i-1]
+		if a.bearish() && b.bearish() && d.bearish() &&
+			b.Close < a.Close && d.Close < b.Close &&
+			c.bullish() && c.Open <= d.Close && c.Close >= a.Open {
+			add(PatternBullishThreeLineStrike, "bullish")
+		}
+		if a.bullish() && b.bullish() && d.bullish() &&
+			b.Close > a.Close && d.Close > b.Close &&
+			c.bearish() && c.Open >= d.Close && c.Close <= a.Open {
+			add(PatternBearishThreeLineStrike, "bearish")
+		}
+	}
+
+	return matches
+}
@@ -0,0 +1,136 @@
+package algo
+
+import (
+	"testing"
+
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+func hasPattern(matches []PatternMatch, p CandlePattern) bool {
+	for _, m := range matches {
+		if m.Pattern == p {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDetectPatterns(t *testing.T) {
+	tests := []struct {
+		name    string
+		candles []Candle
+		want    CandlePattern
+	}{
+		{
+			name:    "Doji",
+			candles: []Candle{{Open: 100, High: 102, Low: 98, Close: 100.1}},
+			want:    PatternDoji,
+		},
+		{
+			name: "Hammer",
+			candles: []Candle{
+				{Open: 110, High: 111, Low: 107, Close: 108},
+				{Open: 108, High: 108.5, Low: 104, Close: 105},
+				{Open: 105, High: 105.5, Low: 101, Close: 102},
+				{Open: 100, High: 101.2, Low: 95, Close: 101},
+			},
+			want: PatternHammer,
+		},
+		{
+			name: "BullishEngulfing",
+			candles: []Candle{
+				{Open: 102, High: 102.5, Low: 99.5, Close: 100},
+				{Open: 99.5, High: 103.5, Low: 99, Close: 103},
+			},
+			want: PatternBullishEngulfing,
+		},
+		{
+			name: "BearishEngulfing",
+			candles: []Candle{
+				{Open: 100, High: 102.5, Low: 99.5, Close: 102},
+				{Open: 102.5, High: 103, Low: 98.5, Close: 99},
+			},
+			want: PatternBearishEngulfing,
+		},
+		{
+			name: "BullishThreeLineStrike",
+			candles: []Candle{
+				{Open: 110, High: 110.5, Low: 107.5, Close: 108},
+				{Open: 108, High: 108.5, Low: 105.5, Close: 106},
+				{Open: 106, High: 106.5, Low: 103.5, Close: 104},
+				{Open: 103.5, High: 111, Low: 103, Close: 110.5},
+			},
+			want: PatternBullishThreeLineStrike,
+		},
+		{
+			name: "BearishThreeLineStrike",
+			candles: []Candle{
+				{Open: 100, High: 102.5, Low: 99.5, Close: 102},
+				{Open: 102, High: 104.5, Low: 101.5, Close: 104},
+				{Open: 104, High: 106.5, Low: 103.5, Close: 106},
+				{Open: 106.5, High: 107, Low: 99, Close: 99.5},
+			},
+			want: PatternBearishThreeLineStrike,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := LatestPatterns(tt.candles)
+			if !hasPattern(got, tt.want) {
+				t.Errorf("LatestPatterns() = %+v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectPatterns_NoFalsePositives(t *testing.T) {
+	// A steady up-trend of full-bodied candles matches nothing
+	candles := []Candle{
+		{Open: 100, High: 102.2, Low: 99.8, Close: 102},
+		{Open: 102.5, High: 104.2, Low: 102.3, Close: 104},
+		{Open: 104.5, High: 106.2, Low: 104.3, Close: 106},
+	}
+	if got := DetectPatterns(candles); len(got) != 0 {
+		t.Errorf("DetectPatterns() = %+v, want none", got)
+	}
+}
+
+func TestPatternFeatures(t *testing.T) {
+	candles := []Candle{
+		{Open: 102, High: 102.5, Low: 99.5, Close: 100},
+		{Open: 99.5, High: 103.5, Low: 99, Close: 103},
+	}
+	features := PatternFeatures(candles)
+	if len(features) != len(PatternFeatureOrder) {
+		t.Fatalf("len(PatternFeatures()) = %d, want %d", len(features), len(PatternFeatureOrder))
+	}
+	for i, p := range PatternFeatureOrder {
+		want := 0.0
+		if p == PatternBullishEngulfing {
+			want = 1
+		}
+		if features[i] != want {
+			t.Errorf("feature %s = %v, want %v", p, features[i], want)
+		}
+	}
+}
+
+func TestCandlesFromMarketData(t *testing.T) {
+	data := []types.MarketData{
+		{Price: 100, High24h: 101, Low24h: 99},
+		{Price: 98, High24h: 100.5, Low24h: 97},
+		{Price: 99, High24h: 0, Low24h: 0},
+	}
+	candles := CandlesFromMarketData(data)
+	if len(candles) != 2 {
+		t.Fatalf("len(candles) = %d, want 2", len(candles))
+	}
+	if c := candles[0]; c.Open != 100 || c.Close != 98 || c.High != 100.5 || c.Low != 97 {
+		t.Errorf("candles[0] = %+v", c)
+	}
+	// Missing high/low falls back to the open/close envelope
+	if c := candles[1]; c.High != 99 || c.Low != 98 {
+		t.Errorf("candles[1] = %+v", c)
+	}
+}
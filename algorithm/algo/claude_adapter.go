@@ -22,13 +22,13 @@ func NewClaudeAlgorithmAdapter() *ClaudeAlgorithmAdapter {
 		alg, err := Create(algType)
 		if err != nil {
 			// Log error but continue
-			fmt.Printf("Error creating algorithm %s: %v\n", algType, err)
+			logger().Error("Failed to create algorithm", "type", algType, "error", err)
 			continue
 		}
 		
 		err = manager.RegisterAlgorithm(alg)
 		if err != nil {
-			fmt.Printf("Error registering algorithm %s: %v\n", algType, err)
+			logger().Error("Failed to register algorithm", "type", algType, "error", err)
 		}
 	}
 	
@@ -51,6 +51,14 @@ func (c *CUSUMFilterAlgorithm) ParameterDescription() map[string]string {
 	}
 }
 
+// Metadata describes the algorithm and its default parameters
+func (c *CUSUMFilterAlgorithm) Metadata() AlgorithmMetadata {
+	return describe(c, map[string]interface{}{
+		"threshold": 1.0,
+		"drift":     0.02,
+	})
+}
+
 // Configure configures the algorithm with the given parameters
 func (c *CUSUMFilterAlgorithm) Configure(config AlgorithmConfig) error {
 	if err := c.BaseAlgorithm.Configure(config); err != nil {
@@ -156,6 +164,16 @@ func (c *CUSUMFilterAlgorithm) Process(symbol string, data *types.MarketData, hi
 		LimitPrice:  limitPrice,
 		Confidence:  confidence,
 		Explanation: explanation,
+		Details: map[string]interface{}{
+			"mean_return":         mean,
+			"stddev":              stddev,
+			"last_return":         lastReturn,
+			"standardized_return": standardizedReturn,
+			"cusum_positive":      sp,
+			"cusum_negative":      sn,
+			"threshold":           c.threshold,
+			"drift":               c.drift,
+		},
 	}, nil
 }
 
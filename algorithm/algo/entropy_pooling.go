@@ -50,6 +50,17 @@ func (e *EntropyPoolingAlgorithm) ParameterDescription() map[string]string {
 	}
 }
 
+// Metadata describes the algorithm and its default parameters
+func (e *EntropyPoolingAlgorithm) Metadata() AlgorithmMetadata {
+	return describe(e, map[string]interface{}{
+		"risk_aversion":       1.0,
+		"max_position_weight": 0.3,
+		"min_position_weight": 0.01,
+		"historical_days":     30,
+		"view_confidence":     0.5,
+	})
+}
+
 // Process processes the market data and returns a trading signal
 func (e *EntropyPoolingAlgorithm) Process(symbol string, data *types.MarketData, historicalData []types.MarketData) (*AlgorithmResult, error) {
 	if data == nil {
@@ -76,6 +87,7 @@ func (e *EntropyPoolingAlgorithm) Process(symbol string, data *types.MarketData,
 	var limitPrice *float64
 	var confidence float64
 	var explanation string
+	details := map[string]interface{}{"observations": len(returns)}
 
 	if len(returns) > 0 {
 		// Prior: Use historical return distribution
@@ -110,6 +122,14 @@ func (e *EntropyPoolingAlgorithm) Process(symbol string, data *types.MarketData,
 		// Weighted combination of prior and view
 		adjustedReturn := meanReturn*(1-viewConfidence) + shortTermMean*viewConfidence
 
+		details["prior_mean_return"] = meanReturn
+		details["prior_volatility"] = volatility
+		details["view_mean_return"] = shortTermMean
+		details["view_confidence"] = viewConfidence
+		details["adjusted_return"] = adjustedReturn
+		details["volatility_trend"] = volatilityTrend
+		details["momentum"] = momentum
+
 		// Decision logic based on adjusted return and volatility trends
 		// Higher momentum and stable/decreasing volatility is positive
 		if adjustedReturn > 0 && momentum > 0 && volatilityTrend <= 1.1 {
@@ -165,6 +185,7 @@ func (e *EntropyPoolingAlgorithm) Process(symbol string, data *types.MarketData,
 		LimitPrice:  limitPrice,
 		Confidence:  confidence,
 		Explanation: explanation,
+		Details:     details,
 	}
 
 	return result, nil
@@ -52,6 +52,16 @@ func (f *FractionalDiffAlgorithm) ParameterDescription() map[string]string {
 	}
 }
 
+// Metadata describes the algorithm and its default parameters
+func (f *FractionalDiffAlgorithm) Metadata() AlgorithmMetadata {
+	return describe(f, map[string]interface{}{
+		"d":               0.5,
+		"threshold":       1e-5,
+		"window_size":     10,
+		"use_fixed_width": 0,
+	})
+}
+
 // Configure configures the algorithm with the given parameters
 func (f *FractionalDiffAlgorithm) Configure(config AlgorithmConfig) error {
 	if err := f.BaseAlgorithm.Configure(config); err != nil {
@@ -145,6 +155,22 @@ func (f *FractionalDiffAlgorithm) Process(
 		f.explanation += "]"
 	}
 
+	details := map[string]interface{}{
+		"d":            f.d,
+		"observations": len(diffPrices),
+		"first_values": diffPrices[:min(5, len(diffPrices))],
+	}
+	if f.useFixedWidth {
+		details["method"] = "fixed_width"
+		details["window_size"] = f.windowSize
+	} else {
+		details["method"] = "ffd"
+		details["threshold"] = f.threshold
+	}
+	if len(diffPrices) > 0 {
+		details["latest_value"] = diffPrices[len(diffPrices)-1]
+	}
+
 	// For pure data transformation algorithms, we just return a "hold" signal
 	// The transformed data itself can be used by other algorithms
 	return &AlgorithmResult{
@@ -152,6 +178,7 @@ func (f *FractionalDiffAlgorithm) Process(
 		OrderType:   "none",
 		Confidence:  0.5,
 		Explanation: f.explanation,
+		Details:     details,
 	}, nil
 }
 
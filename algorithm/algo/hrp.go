@@ -53,6 +53,16 @@ func (h *HRPAlgorithm) ParameterDescription() map[string]string {
 	}
 }
 
+// Metadata describes the algorithm and its default parameters
+func (h *HRPAlgorithm) Metadata() AlgorithmMetadata {
+	return describe(h, map[string]interface{}{
+		"risk_aversion":       1.0,
+		"max_position_weight": 0.3,
+		"min_position_weight": 0.01,
+		"historical_days":     30,
+	})
+}
+
 // Process processes the market data and returns a trading signal
 func (h *HRPAlgorithm) Process(symbol string, data *types.MarketData, historicalData []types.MarketData) (*AlgorithmResult, error) {
 	if data == nil {
@@ -82,6 +92,7 @@ func (h *HRPAlgorithm) Process(symbol string, data *types.MarketData, historical
 	var limitPrice *float64
 	var confidence float64
 	var explanation string
+	details := map[string]interface{}{"observations": len(returns)}
 
 	if len(returns) > 0 {
 		// Calculate mean return and volatility
@@ -91,6 +102,10 @@ func (h *HRPAlgorithm) Process(symbol string, data *types.MarketData, historical
 		// Calculate Sharpe ratio (simplified)
 		sharpeRatio := meanReturn / volatility
 
+		details["mean_return"] = meanReturn
+		details["volatility"] = volatility
+		details["sharpe_ratio"] = sharpeRatio
+
 		// Decision logic based on Sharpe ratio
 		if sharpeRatio > 0.5 {
 			signal = types.SignalBuy
@@ -132,6 +147,7 @@ func (h *HRPAlgorithm) Process(symbol string, data *types.MarketData, historical
 		LimitPrice:  limitPrice,
 		Confidence:  confidence,
 		Explanation: explanation,
+		Details:     details,
 	}
 
 	return result, nil
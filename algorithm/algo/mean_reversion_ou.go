@@ -0,0 +1,295 @@
+package algo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+// dickeyFullerCritical is the 1% critical value of the Dickey-Fuller test
+// with a constant; a fit whose mean-reversion t-statistic reaches it is
+// fully trusted.
+const dickeyFullerCritical = -3.43
+
+// OUFit is an Ornstein-Uhlenbeck process dx = θ(μ − x)dt + σ dW fitted to
+// a series sampled once per bar, through its exact discretization, the
+// AR(1) x[t+1] = a + b x[t] + ε.
+type OUFit struct {
+	Theta float64 `json:"theta"` // speed of mean reversion per bar, −ln b
+	Mu    float64 `json:"mu"`    // long-run mean, a / (1 − b)
+	// Sigma is the standard deviation of x around μ once the process has
+	// settled, sd(ε) / √(1 − b²)
+	Sigma    float64 `json:"sigma"`
+	HalfLife float64 `json:"half_life"` // bars for a deviation to halve, ln 2 / θ
+	B        float64 `json:"b"`         // AR(1) coefficient
+	// TStat is the t-statistic of b − 1, the Dickey-Fuller statistic for a
+	// unit root; the more negative, the stronger the evidence of reversion
+	TStat        float64 `json:"t_stat"`
+	Observations int     `json:"observations"`
+}
+
+// FitOU fits an Ornstein-Uhlenbeck process to xs by least squares on
+// consecutive pairs. It fails when xs does not revert to a mean, that is
+// when b is not between 0 and 1.
+func FitOU(xs []float64) (OUFit, error) {
+	n := len(xs) - 1
+	if n < 3 {
+		return OUFit{}, errors.New("need at least 4 observations to fit an OU process")
+	}
+	xMean, yMean := 0.0, 0.0
+	for i := 0; i < n; i++ {
+		xMean += xs[i]
+		yMean += xs[i+1]
+	}
+	xMean /= float64(n)
+	yMean /= float64(n)
+	sxx, sxy := 0.0, 0.0
+	for i := 0; i < n; i++ {
+		sxx += (xs[i] - xMean) * (xs[i] - xMean)
+		sxy += (xs[i] - xMean) * (xs[i+1] - yMean)
+	}
+	if sxx == 0 {
+		return OUFit{}, errors.New("series has no variance")
+	}
+	b := sxy / sxx
+	a := yMean - b*xMean
+	sse := 0.0
+	for i := 0; i < n; i++ {
+		e := xs[i+1] - a - b*xs[i]
+		sse += e * e
+	}
+	residual := sse / float64(n-2)
+
+	fit := OUFit{B: b, Observations: len(xs)}
+	if se := math.Sqrt(residual / sxx); se > 0 {
+		fit.TStat = (b - 1) / se
+	}
+	if b <= 0 || b >= 1 {
+		return fit, fmt.Errorf("series is not mean-reverting (AR(1) coefficient %.4f)", b)
+	}
+	fit.Theta = -math.Log(b)
+	fit.Mu = a / (1 - b)
+	fit.Sigma = math.Sqrt(residual / (1 - b*b))
+	fit.HalfLife = math.Ln2 / fit.Theta
+	return fit, nil
+}
+
+// Quality scores how strongly the fit shows mean reversion, from 0 for
+// no evidence to 1 at the Dickey-Fuller 1% critical value.
+func (f OUFit) Quality() float64 {
+	return math.Max(0, math.Min(1, f.TStat/dickeyFullerCritical))
+}
+
+// init registers the OU mean-reversion algorithm with the factory
+func init() {
+	Register(AlgorithmTypeMeanReversionOU, func() Algorithm {
+		return &MeanReversionOUAlgorithm{}
+	})
+}
+
+// MeanReversionOUAlgorithm trades deviations of a fractionally differenced
+// log price from the mean of an Ornstein-Uhlenbeck process fitted to it,
+// entering against deviations beyond entry_z equilibrium standard
+// deviations and exiting once they are back within exit_z.
+type MeanReversionOUAlgorithm struct {
+	BaseAlgorithm
+	lookback    int     // Differenced observations the process is fitted to
+	d           float64 // Fractional differencing order; 0 fits log prices
+	window      int     // Fixed-width differencing window
+	entryZ      float64 // Deviation that opens a position
+	exitZ       float64 // Deviation within which positions close
+	maxHalfLife float64 // Slowest reversion, in bars, worth trading
+}
+
+// Name returns the name of the algorithm
+func (o *MeanReversionOUAlgorithm) Name() string {
+	return "Ornstein-Uhlenbeck Mean Reversion"
+}
+
+// Type returns the type of the algorithm
+func (o *MeanReversionOUAlgorithm) Type() AlgorithmType {
+	return AlgorithmTypeMeanReversionOU
+}
+
+// Description returns a brief description of the algorithm
+func (o *MeanReversionOUAlgorithm) Description() string {
+	return "Fits an Ornstein-Uhlenbeck process to the fractionally differenced price and trades deviations from its mean"
+}
+
+// ParameterDescription returns a description of the parameters
+func (o *MeanReversionOUAlgorithm) ParameterDescription() map[string]string {
+	return map[string]string{
+		"lookback":      "Differenced observations the process is fitted to (default: 60)",
+		"d":             "Fractional differencing order between 0 and 1; 0 fits log prices (default: 0.4)",
+		"window_size":   "Fixed-width fractional differencing window (default: 20)",
+		"entry_z":       "Deviation from the mean, in equilibrium standard deviations, that opens a position (default: 2.0)",
+		"exit_z":        "Deviation within which positions close (default: 0.5)",
+		"max_half_life": "Slowest half-life, in bars, worth trading (default: 20)",
+	}
+}
+
+// Metadata describes the algorithm and its default parameters
+func (o *MeanReversionOUAlgorithm) Metadata() AlgorithmMetadata {
+	return describe(o, map[string]interface{}{
+		"lookback":      60,
+		"d":             0.4,
+		"window_size":   20,
+		"entry_z":       2.0,
+		"exit_z":        0.5,
+		"max_half_life": 20.0,
+	})
+}
+
+// Configure configures the algorithm with the given parameters
+func (o *MeanReversionOUAlgorithm) Configure(config AlgorithmConfig) error {
+	if err := o.BaseAlgorithm.Configure(config); err != nil {
+		return err
+	}
+
+	// Set default values
+	o.lookback = 60
+	o.d = 0.4
+	o.window = 20
+	o.entryZ = 2.0
+	o.exitZ = 0.5
+	o.maxHalfLife = 20
+
+	// Override with provided values
+	if val, ok := config.AdditionalParams["lookback"]; ok {
+		if val < 10 {
+			return errors.New("lookback must be at least 10")
+		}
+		o.lookback = int(val)
+	}
+
+	if val, ok := config.AdditionalParams["d"]; ok {
+		if val < 0 || val > 1 {
+			return errors.New("d must be between 0 and 1")
+		}
+		o.d = val
+	}
+
+	if val, ok := config.AdditionalParams["window_size"]; ok {
+		if val < 1 {
+			return errors.New("window_size must be at least 1")
+		}
+		o.window = int(val)
+	}
+
+	if val, ok := config.AdditionalParams["entry_z"]; ok {
+		if val <= 0 {
+			return errors.New("entry_z must be positive")
+		}
+		o.entryZ = val
+	}
+
+	if val, ok := config.AdditionalParams["exit_z"]; ok {
+		if val < 0 {
+			return errors.New("exit_z must not be negative")
+		}
+		o.exitZ = val
+	}
+	if o.exitZ >= o.entryZ {
+		return errors.New("exit_z must be below entry_z")
+	}
+
+	if val, ok := config.AdditionalParams["max_half_life"]; ok {
+		if val <= 0 {
+			return errors.New("max_half_life must be positive")
+		}
+		o.maxHalfLife = val
+	}
+
+	return nil
+}
+
+// RequiredHistory returns the bars needed to difference and fit lookback
+// observations
+func (o *MeanReversionOUAlgorithm) RequiredHistory() int {
+	return maxInt(o.BaseAlgorithm.RequiredHistory(), o.lookback+o.window-1)
+}
+
+// Process fits the process to the recent differenced series and signals
+// on the latest deviation from its mean
+func (o *MeanReversionOUAlgorithm) Process(
+	symbol string,
+	currentData *types.MarketData,
+	historicalData []types.MarketData,
+) (*AlgorithmResult, error) {
+	if len(historicalData) < o.RequiredHistory() {
+		return nil, fmt.Errorf("insufficient historical data: got %d, need at least %d",
+			len(historicalData), o.RequiredHistory())
+	}
+
+	// The current bar is the latest observation unless it is the last
+	// historical one
+	bars := appendCurrent(historicalData, currentData)
+	logPrices := make([]float64, 0, len(bars))
+	for _, data := range bars {
+		if data.Price <= 0 {
+			return nil, errors.New("prices must be positive")
+		}
+		logPrices = append(logPrices, math.Log(data.Price))
+	}
+	series, err := FixedWidthFractionalDiff(logPrices, o.d, o.window)
+	if err != nil {
+		return nil, err
+	}
+	if len(series) > o.lookback {
+		series = series[len(series)-o.lookback:]
+	}
+
+	details := map[string]interface{}{
+		"d":            o.d,
+		"window_size":  o.window,
+		"entry_z":      o.entryZ,
+		"exit_z":       o.exitZ,
+		"observations": len(series),
+	}
+	hold := func(explanation string) *AlgorithmResult {
+		o.explanation = explanation
+		details["action"] = "none"
+		return &AlgorithmResult{Signal: "hold", OrderType: "none", Confidence: 0.5, Explanation: explanation, Details: details}
+	}
+
+	fit, err := FitOU(series)
+	details["b"] = fit.B
+	details["t_stat"] = fit.TStat
+	if err != nil {
+		return hold(fmt.Sprintf("No Ornstein-Uhlenbeck fit for %s: %v.", symbol, err)), nil
+	}
+	latest := series[len(series)-1]
+	z := (latest - fit.Mu) / fit.Sigma
+	quality := fit.Quality()
+	details["theta"] = fit.Theta
+	details["mu"] = fit.Mu
+	details["sigma"] = fit.Sigma
+	details["half_life"] = fit.HalfLife
+	details["z_score"] = z
+	details["fit_quality"] = quality
+
+	summary := fmt.Sprintf("OU fit on %d bars (d=%.2f): half-life %.1f bars, deviation %.2f standard deviations from the mean, fit quality %.2f.",
+		len(series), o.d, fit.HalfLife, z, quality)
+	if fit.HalfLife > o.maxHalfLife {
+		return hold(summary + fmt.Sprintf(" Reversion is slower than the %.0f-bar limit.", o.maxHalfLife)), nil
+	}
+
+	switch {
+	case math.Abs(z) >= o.entryZ:
+		signal, side := "buy", "below"
+		if z > 0 {
+			signal, side = "sell", "above"
+		}
+		confidence := 0.5 + 0.45*quality*math.Min(1, math.Abs(z)/(2*o.entryZ))
+		o.explanation = summary + fmt.Sprintf(" Price is %s the mean beyond %.1f standard deviations: %s, expecting reversion.", side, o.entryZ, signal)
+		details["action"] = "entry"
+		return &AlgorithmResult{Signal: signal, OrderType: "market", Confidence: confidence, Explanation: o.explanation, Details: details}, nil
+	case math.Abs(z) <= o.exitZ:
+		result := hold(summary + fmt.Sprintf(" Deviation is back within %.1f standard deviations: exit mean-reversion positions.", o.exitZ))
+		details["action"] = "exit"
+		return result, nil
+	}
+	return hold(summary + " Deviation is between the exit and entry bands."), nil
+}
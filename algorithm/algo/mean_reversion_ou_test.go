@@ -0,0 +1,92 @@
+package algo
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+// ouHistory is n daily bars whose log price follows an AR(1) with
+// coefficient b around log 100.
+func ouHistory(n int, b float64, seed int64) []types.MarketData {
+	rng := rand.New(rand.NewSource(seed))
+	start := time.Date(2025, 1, 2, 21, 0, 0, 0, time.UTC)
+	history := make([]types.MarketData, n)
+	x := 0.0
+	for i := range history {
+		x = b*x + 0.01*rng.NormFloat64()
+		history[i] = types.MarketData{Price: 100 * math.Exp(x), Timestamp: start.AddDate(0, 0, i)}
+	}
+	return history
+}
+
+func TestFitOU(t *testing.T) {
+	history := ouHistory(2000, 0.8, 1)
+	xs := make([]float64, len(history))
+	for i, h := range history {
+		xs[i] = math.Log(h.Price)
+	}
+	fit, err := FitOU(xs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(fit.B-0.8) > 0.03 || math.Abs(fit.Mu-math.Log(100)) > 0.002 || fit.Quality() != 1 {
+		t.Errorf("fit = %+v", fit)
+	}
+	if want := math.Ln2 / -math.Log(fit.B); math.Abs(fit.HalfLife-want) > 1e-9 {
+		t.Errorf("half-life = %v, want %v", fit.HalfLife, want)
+	}
+	if sigma := 0.01 / math.Sqrt(1-0.64); math.Abs(fit.Sigma-sigma) > 0.002 {
+		t.Errorf("sigma = %v, want about %v", fit.Sigma, sigma)
+	}
+
+	// A straight trend does not revert
+	if _, err := FitOU([]float64{1, 2, 3, 4, 5, 6.5}); err == nil {
+		t.Error("fitted a trend")
+	}
+}
+
+func TestMeanReversionOUSignals(t *testing.T) {
+	alg, err := Create(AlgorithmTypeMeanReversionOU)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := alg.Configure(AlgorithmConfig{AdditionalParams: map[string]float64{"d": 0}}); err != nil {
+		t.Fatal(err)
+	}
+	history := ouHistory(RequiredHistory(alg), 0.8, 2)
+	last := history[len(history)-1]
+
+	process := func(price float64) *AlgorithmResult {
+		current := types.MarketData{Price: price, Timestamp: last.Timestamp.AddDate(0, 0, 1)}
+		res, err := alg.Process("TEST", &current, history)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	// About 0.017 is one equilibrium standard deviation of the log price
+	if res := process(100 * math.Exp(-0.07)); res.Signal != "buy" || res.Confidence <= 0.5 || res.Details["action"] != "entry" {
+		t.Errorf("far below the mean: %+v", res)
+	}
+	if res := process(100 * math.Exp(0.07)); res.Signal != "sell" {
+		t.Errorf("far above the mean: %+v", res)
+	}
+	if res := process(100); res.Signal != "hold" || res.Details["action"] != "exit" {
+		t.Errorf("at the mean: %+v", res)
+	}
+	if _, err := alg.Process("TEST", &last, history[:10]); err == nil {
+		t.Error("processed too little history")
+	}
+
+	for _, params := range []map[string]float64{
+		{"d": 1.5}, {"lookback": 5}, {"entry_z": 1, "exit_z": 1}, {"max_half_life": 0},
+	} {
+		if err := alg.Configure(AlgorithmConfig{AdditionalParams: params}); err == nil {
+			t.Errorf("%v accepted", params)
+		}
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"time"
 
 	"github.com/rileyseaburg/go-trader/types"
 )
@@ -40,8 +41,21 @@ const (
 	FeatureTypeVolatility FeatureType = "volatility"
 	// FeatureTypeTechnical represents technical indicators
 	FeatureTypeTechnical FeatureType = "technical"
+	// FeatureTypePattern represents candlestick pattern flags
+	FeatureTypePattern FeatureType = "pattern"
 )
 
+// patternFeatureWeight is the simple-rules weight given to each candlestick
+// pattern flag. Doji signals indecision and counts against the trade.
+var patternFeatureWeight = map[CandlePattern]float64{
+	PatternDoji:                   -0.1,
+	PatternHammer:                 0.1,
+	PatternBullishEngulfing:       0.1,
+	PatternBearishEngulfing:       0.1,
+	PatternBullishThreeLineStrike: 0.15,
+	PatternBearishThreeLineStrike: 0.15,
+}
+
 // init registers the MetaLabeling algorithm with the factory
 func init() {
 	Register(AlgorithmTypeMetaLabeling, func() Algorithm {
@@ -63,8 +77,13 @@ type MetaLabelingAlgorithm struct {
 	weights             []float64              // Model weights (for simple models)
 	bias                float64                // Model bias term (for simple models)
 	featureRanges       map[string][2]float64  // Min/max ranges for feature normalization
+	labeling            LabelingMethod         // How the history trained on is labeled
+	trendScan           TrendScanConfig        // Trend windows for trend-scanning labels
 }
 
+// minTrainingLabels is the fewest labeled bars meta-labeling trains on
+const minTrainingLabels = 10
+
 // Name returns the name of the algorithm
 func (m *MetaLabelingAlgorithm) Name() string {
 	return "Meta-Labeling"
@@ -90,9 +109,28 @@ func (m *MetaLabelingAlgorithm) ParameterDescription() map[string]string {
 		"use_volume_features":  "Whether to use volume-based features (default: 1)",
 		"use_volatility_features": "Whether to use volatility-based features (default: 1)",
 		"use_technical_features": "Whether to use technical indicators (default: 1)",
+		"use_pattern_features": "Whether to use candlestick pattern flags (default: 0)",
+		"trend_min_span":       "Fewest bars in a trend when labeling_method is trend_scanning (default: 5)",
+		"trend_max_span":       "Most bars in a trend when labeling_method is trend_scanning (default: 20)",
 	}
 }
 
+// Metadata describes the algorithm and its default parameters
+func (m *MetaLabelingAlgorithm) Metadata() AlgorithmMetadata {
+	return describe(m, map[string]interface{}{
+		"confidence_threshold":    0.6,
+		"model_type":              string(ModelTypeSimpleRules),
+		"primary_algorithm":       string(AlgorithmTypeSequentialBootstrap),
+		"use_price_features":      1,
+		"use_volume_features":     1,
+		"use_volatility_features": 1,
+		"use_technical_features":  1,
+		"use_pattern_features":    0,
+		"trend_min_span":          5,
+		"trend_max_span":          20,
+	})
+}
+
 // Configure configures the algorithm with the given parameters
 func (m *MetaLabelingAlgorithm) Configure(config AlgorithmConfig) error {
 	if err := m.BaseAlgorithm.Configure(config); err != nil {
@@ -105,6 +143,8 @@ func (m *MetaLabelingAlgorithm) Configure(config AlgorithmConfig) error {
 	m.modelType = ModelTypeSimpleRules
 	m.primaryAlgorithm = AlgorithmTypeSequentialBootstrap
 	m.modelParams = make(map[string]interface{})
+	m.labeling = LabelingMethod(config.LabelingMethod)
+	m.trendScan = TrendScanConfig{MinSpan: 5, MaxSpan: 20}
 	
 	// Initialize feature ranges
 	m.featureRanges = map[string][2]float64{
@@ -148,15 +188,38 @@ func (m *MetaLabelingAlgorithm) Configure(config AlgorithmConfig) error {
 		}
 	}
 
+	if val, ok := config.AdditionalParams["use_pattern_features"]; ok {
+		if val > 0.5 {
+			m.features = append(m.features, FeatureTypePattern)
+		}
+	}
+
 	if len(m.features) == 0 {
 		return errors.New("at least one feature type must be enabled")
 	}
 
+	if val, ok := config.AdditionalParams["trend_min_span"]; ok {
+		m.trendScan.MinSpan = int(val)
+	}
+	if val, ok := config.AdditionalParams["trend_max_span"]; ok {
+		m.trendScan.MaxSpan = int(val)
+	}
+	if m.trendScan.MinSpan < 3 || m.trendScan.MaxSpan < m.trendScan.MinSpan {
+		return errors.New("trend spans must satisfy 3 <= trend_min_span <= trend_max_span")
+	}
+
 	// Set up a simple model with weights based on empirical observations
 	// In a real implementation, these would be trained on historical data
 	m.weights = []float64{0.2, 0.2, 0.3, 0.3}
 	m.bias = -0.1
 
+	// Pattern flags are always the trailing features, one per pattern
+	if containsFeatureType(m.features, FeatureTypePattern) {
+		for _, p := range PatternFeatureOrder {
+			m.weights = append(m.weights, patternFeatureWeight[p])
+		}
+	}
+
 	return nil
 }
 
@@ -170,6 +233,19 @@ func (m *MetaLabelingAlgorithm) removeFeature(featureType FeatureType) {
 	}
 }
 
+// RequiredHistory returns the bars needed for feature extraction, the
+// primary algorithm and, when labeling, enough labeled bars to train on
+func (m *MetaLabelingAlgorithm) RequiredHistory() int {
+	training := 0
+	switch m.labeling {
+	case LabelingTripleBarrier:
+		training = metaBarrierConfig.VolatilityLookback + minTrainingLabels
+	case LabelingTrendScanning:
+		training = m.trendScan.MaxSpan + minTrainingLabels - 1
+	}
+	return maxInt(m.BaseAlgorithm.RequiredHistory(), 10, primaryHistory(m.primaryAlgorithm), training)
+}
+
 // Process processes the market data and generates meta-labeled trading signals
 func (m *MetaLabelingAlgorithm) Process(
 	symbol string,
@@ -207,13 +283,25 @@ func (m *MetaLabelingAlgorithm) Process(
 	// Step 2: Extract features for meta-labeling
 	features := m.extractFeatures(currentData, historicalData)
 
-	// Step 3: Apply meta-labeling
-	metaLabelResult, err := m.applyMetaLabeling(primaryResult.Signal, features, primaryResult.Confidence)
+	// Step 3: Train the model's bias on the labeled history, if labeling
+	bias := m.bias
+	var hitRate float64
+	var trainingLabels int
+	if m.labeling != LabelingNone {
+		hitRate, trainingLabels, err = m.labelHitRate(primaryResult.Signal, historicalData)
+		if err != nil {
+			return nil, fmt.Errorf("error labeling history: %v", err)
+		}
+		bias = math.Log(hitRate / (1 - hitRate))
+	}
+
+	// Step 4: Apply meta-labeling
+	metaLabelResult, err := m.applyMetaLabeling(primaryResult.Signal, features, primaryResult.Confidence, bias)
 	if err != nil {
 		return nil, fmt.Errorf("error applying meta-labeling: %v", err)
 	}
 
-	// Step 4: Determine final signal based on meta-label
+	// Step 5: Determine final signal based on meta-label
 	var finalSignal, finalOrderType string
 	var finalConfidence float64
 
@@ -243,8 +331,28 @@ func (m *MetaLabelingAlgorithm) Process(
 
 	// Add feature importance information
 	m.explanation += "\nFeature importance:"
+	importance := make(map[string]float64, len(m.features))
 	for i, featType := range m.features {
 		m.explanation += fmt.Sprintf("\n - %s: %.2f", featType, m.weights[i])
+		importance[string(featType)] = m.weights[i]
+	}
+
+	details := map[string]interface{}{
+		"primary_algorithm":  primaryAlg.Name(),
+		"primary_signal":     primaryResult.Signal,
+		"primary_confidence": primaryResult.Confidence,
+		"meta_label":         metaLabelResult.MetaLabel,
+		"meta_confidence":    metaLabelResult.Confidence,
+		"suggested_size":     metaLabelResult.SuggestedSize,
+		"features":           features,
+		"feature_importance": importance,
+	}
+	if m.labeling != LabelingNone {
+		m.explanation += fmt.Sprintf("\nTrained on %d %s labels: the %s side was right %.0f%% of the time.",
+			trainingLabels, m.labeling, primaryResult.Signal, hitRate*100)
+		details["labeling_method"] = string(m.labeling)
+		details["training_labels"] = trainingLabels
+		details["label_hit_rate"] = hitRate
 	}
 
 	return &AlgorithmResult{
@@ -252,9 +360,75 @@ func (m *MetaLabelingAlgorithm) Process(
 		OrderType:   finalOrderType,
 		Confidence:  finalConfidence,
 		Explanation: m.explanation,
+		Details:     details,
 	}, nil
 }
 
+// metaBarrierConfig is the triple barrier, at its algorithm's defaults,
+// behind triple_barrier training labels
+var metaBarrierConfig = TripleBarrierConfig{ProfitTaking: 2, StopLoss: 1, TimeHorizon: 5, VolatilityLookback: 20}
+
+// labelHitRate labels the history with the configured method and returns
+// the share of directional labels on signal's side, smoothed by Laplace's
+// rule so it is never 0 or 1, and how many there were. It is the base rate
+// a meta-model trained on those labels would start from.
+func (m *MetaLabelingAlgorithm) labelHitRate(signal string, historicalData []types.MarketData) (float64, int, error) {
+	prices := make([]float64, len(historicalData))
+	times := make([]time.Time, len(historicalData))
+	stamped := true
+	for i, data := range historicalData {
+		prices[i] = data.Price
+		times[i] = data.Timestamp
+		stamped = stamped && !data.Timestamp.IsZero()
+	}
+	if !stamped {
+		times = sessionDates(currentCalendar(), time.Now(), len(historicalData))
+	}
+
+	var labels []BarrierLabel
+	switch m.labeling {
+	case LabelingTripleBarrier:
+		vol, err := DailyVolatility(prices, metaBarrierConfig.VolatilityLookback)
+		if err != nil {
+			return 0, 0, err
+		}
+		results, err := ApplyTripleBarrier(prices, times, vol, metaBarrierConfig)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, r := range results {
+			labels = append(labels, r.Label)
+		}
+	case LabelingTrendScanning:
+		results, err := ApplyTrendScanning(prices, times, m.trendScan)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, r := range results {
+			labels = append(labels, r.Label)
+		}
+	}
+
+	side := BarrierLabelBuy
+	if signal == "sell" {
+		side = BarrierLabelSell
+	}
+	right, directional := 0, 0
+	for _, l := range labels {
+		if l == BarrierLabelHold {
+			continue
+		}
+		directional++
+		if l == side {
+			right++
+		}
+	}
+	if directional < minTrainingLabels {
+		return 0, directional, fmt.Errorf("%d directional labels, need at least %d", directional, minTrainingLabels)
+	}
+	return float64(right+1) / float64(directional+2), directional, nil
+}
+
 // extractFeatures extracts features for meta-labeling from market data
 func (m *MetaLabelingAlgorithm) extractFeatures(
 	currentData *types.MarketData,
@@ -350,14 +524,22 @@ func (m *MetaLabelingAlgorithm) extractFeatures(
 		features = append(features, normalizeFeature(bPercent, "bollinger_pct_b", m.featureRanges))
 	}
 
+	// Candlestick pattern flags on the latest bar
+	if containsFeatureType(m.features, FeatureTypePattern) {
+		series := append(append([]types.MarketData{}, historicalData...), *currentData)
+		features = append(features, PatternFeatures(CandlesFromMarketData(series))...)
+	}
+
 	return features
 }
 
-// applyMetaLabeling applies the meta-labeling model to a primary signal
+// applyMetaLabeling applies the meta-labeling model, with the given bias,
+// to a primary signal
 func (m *MetaLabelingAlgorithm) applyMetaLabeling(
 	signal string,
 	features []float64,
 	primaryConfidence float64,
+	bias float64,
 ) (*MetaLabelResult, error) {
 	if len(features) == 0 {
 		return nil, errors.New("no features provided for meta-labeling")
@@ -377,7 +559,7 @@ func (m *MetaLabelingAlgorithm) applyMetaLabeling(
 			sum += feature * m.weights[i]
 		}
 		// Apply sigmoid to get a probability
-		metaConfidence = sigmoid(sum + m.bias)
+		metaConfidence = sigmoid(sum + bias)
 
 	case ModelTypeLogisticRegression:
 		// This would be a more sophisticated implementation
@@ -0,0 +1,37 @@
+package algo
+
+import "testing"
+
+func TestMetadataCoversRegistry(t *testing.T) {
+	all := AllMetadata()
+	if len(all) != len(GetRegisteredAlgorithms()) {
+		t.Fatalf("metadata for %d of %d algorithms", len(all), len(GetRegisteredAlgorithms()))
+	}
+	for i, m := range all {
+		if i > 0 && all[i-1].Type >= m.Type {
+			t.Errorf("metadata not ordered by type at %s", m.Type)
+		}
+		if m.Name == "" || m.Description == "" || len(m.Parameters) == 0 {
+			t.Errorf("%s: incomplete metadata %+v", m.Type, m)
+		}
+
+		// Every default documents a parameter, and the numeric defaults
+		// configure cleanly
+		params := make(map[string]float64)
+		for k, v := range m.Defaults {
+			if _, ok := m.Parameters[k]; !ok {
+				t.Errorf("%s: default %q has no parameter description", m.Type, k)
+			}
+			switch n := v.(type) {
+			case int:
+				params[k] = float64(n)
+			case float64:
+				params[k] = n
+			}
+		}
+		alg, _ := Create(m.Type)
+		if err := alg.Configure(AlgorithmConfig{AdditionalParams: params}); err != nil {
+			t.Errorf("%s: defaults rejected: %v", m.Type, err)
+		}
+	}
+}
@@ -52,6 +52,17 @@ func (m *MVOAlgorithm) ParameterDescription() map[string]string {
 	}
 }
 
+// Metadata describes the algorithm and its default parameters
+func (m *MVOAlgorithm) Metadata() AlgorithmMetadata {
+	return describe(m, map[string]interface{}{
+		"risk_aversion":       2.0,
+		"max_position_weight": 0.3,
+		"min_position_weight": 0.01,
+		"historical_days":     30,
+		"min_sharpe":          0.5,
+	})
+}
+
 // Process processes the market data and returns a trading signal
 func (m *MVOAlgorithm) Process(symbol string, data *types.MarketData, historicalData []types.MarketData) (*AlgorithmResult, error) {
 	if data == nil {
@@ -79,6 +90,7 @@ func (m *MVOAlgorithm) Process(symbol string, data *types.MarketData, historical
 	var limitPrice *float64
 	var confidence float64
 	var explanation string
+	details := map[string]interface{}{"observations": len(returns)}
 
 	if len(returns) > 0 {
 		// Calculate expected return (mean of historical returns)
@@ -107,6 +119,13 @@ func (m *MVOAlgorithm) Process(symbol string, data *types.MarketData, historical
 		
 		// Calculate utility (expected return - risk aversion * variance)
 		utility := expectedReturn - (riskAversion * risk * risk / 2)
+
+		details["expected_return"] = expectedReturn
+		details["risk"] = risk
+		details["sharpe_ratio"] = sharpeRatio
+		details["min_sharpe"] = minSharpe
+		details["risk_aversion"] = riskAversion
+		details["utility"] = utility
 		
 		// Decision logic based on Sharpe ratio and utility
 		if sharpeRatio > minSharpe && utility > 0 {
@@ -154,6 +173,7 @@ func (m *MVOAlgorithm) Process(symbol string, data *types.MarketData, historical
 		LimitPrice:  limitPrice,
 		Confidence:  confidence,
 		Explanation: explanation,
+		Details:     details,
 	}
 
 	return result, nil
@@ -0,0 +1,38 @@
+package algo
+
+import "github.com/rileyseaburg/go-trader/types"
+
+// PortfolioProcessor is implemented by algorithms that can decide a whole
+// basket of symbols together, sizing each position against the others, so
+// the basket trades as a unit rather than symbol by symbol.
+type PortfolioProcessor interface {
+	// ProcessPortfolio decides every symbol in histories, keyed by symbol
+	// with each symbol's history oldest first
+	ProcessPortfolio(histories map[string][]types.MarketData) (*PortfolioResult, error)
+}
+
+// PortfolioResult is a basket-level decision.
+type PortfolioResult struct {
+	// Weights is each symbol's target position as a signed fraction of
+	// equity: positive long, negative short, zero flat
+	Weights       map[string]float64 `json:"weights"`
+	GrossExposure float64            `json:"gross_exposure"` // sum of absolute weights
+	NetExposure   float64            `json:"net_exposure"`   // sum of weights
+	// Results is each symbol's own signal, holding its basket weight
+	Results map[string]*AlgorithmResult `json:"results"`
+	// Skipped maps symbols left out of the basket to the reason
+	Skipped     map[string]string `json:"skipped,omitempty"`
+	Explanation string            `json:"explanation"`
+}
+
+// appendCurrent returns history with current as its latest bar, unless
+// current is nil or not newer than the last historical bar.
+func appendCurrent(history []types.MarketData, current *types.MarketData) []types.MarketData {
+	if current == nil || len(history) == 0 {
+		return history
+	}
+	if last := history[len(history)-1]; current.Timestamp.IsZero() || current.Timestamp.After(last.Timestamp) {
+		return append(history[:len(history):len(history)], *current)
+	}
+	return history
+}
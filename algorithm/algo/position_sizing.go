@@ -68,6 +68,19 @@ func (p *PositionSizingAlgorithm) ParameterDescription() map[string]string {
 	}
 }
 
+// Metadata describes the algorithm and its default parameters
+func (p *PositionSizingAlgorithm) Metadata() AlgorithmMetadata {
+	return describe(p, map[string]interface{}{
+		"max_size":           0.2,
+		"risk_fraction":      0.3,
+		"use_vol_adjustment": 1,
+		"vol_lookback":       20,
+		"max_drawdown":       0.1,
+		"primary_algorithm":  string(AlgorithmTypeSequentialBootstrap),
+		"use_meta_labeling":  1,
+	})
+}
+
 // Configure configures the algorithm with the given parameters
 func (p *PositionSizingAlgorithm) Configure(config AlgorithmConfig) error {
 	if err := p.BaseAlgorithm.Configure(config); err != nil {
@@ -123,6 +136,12 @@ func (p *PositionSizingAlgorithm) Configure(config AlgorithmConfig) error {
 	return nil
 }
 
+// RequiredHistory returns the bars needed for the volatility lookback and
+// the primary algorithm
+func (p *PositionSizingAlgorithm) RequiredHistory() int {
+	return maxInt(p.BaseAlgorithm.RequiredHistory(), p.volLookback, primaryHistory(p.primaryAlgorithm))
+}
+
 // Process processes the market data and calculates the optimal position size
 func (p *PositionSizingAlgorithm) Process(
 	symbol string,
@@ -190,13 +209,11 @@ func (p *PositionSizingAlgorithm) Process(
 		confidence = metaLabelResult.Confidence
 	}
 
-	// Step 3: Calculate volatility for position sizing
-	prices := make([]float64, len(historicalData))
-	for i, data := range historicalData {
-		prices[i] = data.Price
-	}
-
-	volatility, err := calculateVolatility(prices, p.volLookback)
+	// Step 3: Calculate volatility for position sizing, by the configured
+	// estimator if any
+	volatility, estimator, err := p.estimateVolatility(historicalData, p.volLookback, func(closes []float64) (float64, error) {
+		return calculateVolatility(closes, p.volLookback)
+	}, "close_to_close")
 	if err != nil {
 		return nil, fmt.Errorf("error calculating volatility: %v", err)
 	}
@@ -229,6 +246,18 @@ func (p *PositionSizingAlgorithm) Process(
 		OrderType:   primaryResult.OrderType,
 		Confidence:  confidence,
 		Explanation: p.explanation,
+		Details: map[string]interface{}{
+			"primary_algorithm":  primaryAlg.Name(),
+			"primary_signal":     primaryResult.Signal,
+			"primary_confidence": primaryResult.Confidence,
+			"meta_labeling":      p.metaLabeling,
+			"confidence":         confidence,
+			"volatility":         volatility,
+			"estimator":          estimator,
+			"position_size":      positionResult.Size,
+			"vol_adjusted":       positionResult.VolAdjusted,
+			"risk_per_trade":     positionResult.RiskPerTrade,
+		},
 	}, nil
 }
 
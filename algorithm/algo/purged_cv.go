@@ -71,6 +71,15 @@ func (p *PurgedCVAlgorithm) ParameterDescription() map[string]string {
 	}
 }
 
+// Metadata describes the algorithm and its default parameters
+func (p *PurgedCVAlgorithm) Metadata() AlgorithmMetadata {
+	return describe(p, map[string]interface{}{
+		"num_folds":   5,
+		"embargo_pct": 0.01,
+		"test_size":   0.3,
+	})
+}
+
 // Configure configures the algorithm with the given parameters
 func (p *PurgedCVAlgorithm) Configure(config AlgorithmConfig) error {
 	if err := p.BaseAlgorithm.Configure(config); err != nil {
@@ -114,6 +123,11 @@ func (p *PurgedCVAlgorithm) Configure(config AlgorithmConfig) error {
 	return nil
 }
 
+// RequiredHistory returns the bars needed for two samples per fold
+func (p *PurgedCVAlgorithm) RequiredHistory() int {
+	return maxInt(p.BaseAlgorithm.RequiredHistory(), p.numFolds*2)
+}
+
 // Process processes the market data to generate CV folds
 // Note: This doesn't generate trading signals, but rather provides
 // a validation strategy for other algorithms
@@ -145,9 +159,15 @@ func (p *PurgedCVAlgorithm) Process(
 	explanation := fmt.Sprintf("Generated %d cross-validation folds with embargo=%.2f%% and test_size=%.2f%%.\n",
 		p.numFolds, p.embargoPct*100, p.testSize*100)
 		
+	foldStats := make([]map[string]interface{}, len(folds))
 	for i, fold := range folds {
 		explanation += fmt.Sprintf("Fold %d: %d training samples, %d test samples\n", 
 			i+1, len(fold.TrainIndices), len(fold.TestIndices))
+		foldStats[i] = map[string]interface{}{
+			"fold":          i + 1,
+			"train_samples": len(fold.TrainIndices),
+			"test_samples":  len(fold.TestIndices),
+		}
 	}
 
 	p.explanation = explanation
@@ -157,6 +177,13 @@ func (p *PurgedCVAlgorithm) Process(
 		OrderType:   "none",
 		Confidence:  0.5,
 		Explanation: p.explanation,
+		Details: map[string]interface{}{
+			"num_folds":   p.numFolds,
+			"embargo_pct": p.embargoPct,
+			"test_size":   p.testSize,
+			"samples":     len(historicalData),
+			"folds":       foldStats,
+		},
 	}, nil
 }
 
@@ -0,0 +1,35 @@
+package algo
+
+import "testing"
+
+func TestRequiredHistory(t *testing.T) {
+	ps, _ := Create(AlgorithmTypePositionSizing)
+	if err := ps.Configure(AlgorithmConfig{AdditionalParams: map[string]float64{"vol_lookback": 45}}); err != nil {
+		t.Fatal(err)
+	}
+	tb, _ := Create(AlgorithmTypeTripleBarrier)
+	if err := tb.Configure(AlgorithmConfig{HistoricalDays: 90}); err != nil {
+		t.Fatal(err)
+	}
+	cusum, _ := Create(AlgorithmTypeCUSUMFilter)
+	cusum.Configure(AlgorithmConfig{})
+
+	if got := RequiredHistory(ps); got != 45 {
+		t.Errorf("position sizing = %d, want 45", got)
+	}
+	if got := RequiredHistory(cusum); got != 0 {
+		t.Errorf("cusum = %d, want none stated", got)
+	}
+	if got := HistoryBars(cusum, AlgorithmConfig{}); got != DefaultRequiredHistory {
+		t.Errorf("cusum bars = %d, want default", got)
+	}
+	if got := HistoryBars(ps, AlgorithmConfig{BarCount: 200}); got != 200 {
+		t.Errorf("explicit bar count = %d, want 200", got)
+	}
+	if err := CheckBarCount(ps, AlgorithmConfig{BarCount: 20}); err == nil {
+		t.Error("expected error for bar_count below vol_lookback")
+	}
+	if got := RequiredHistory(tb); got != 90 {
+		t.Errorf("triple barrier = %d, want configured 90", got)
+	}
+}
@@ -0,0 +1,98 @@
+package algo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// CacheKey identifies one algorithm run: the same algorithm and
+// configuration over the same symbol's history up to the same bar always
+// produces the same result.
+type CacheKey struct {
+	// Instance names the configured algorithm instance, if any
+	Instance   string
+	Type       AlgorithmType
+	ConfigHash string
+	Symbol     string
+	// BarTime is the timestamp of the latest bar in the history processed.
+	BarTime time.Time
+}
+
+// CachedResult is a stored result and when it was computed.
+type CachedResult struct {
+	Result     *AlgorithmResult
+	ComputedAt time.Time
+}
+
+// ResultCache holds the latest result per algorithm instance and symbol. A
+// lookup only hits when the configuration and latest bar match, so a new
+// bar closing or a reconfiguration invalidates the entry, and the next Put
+// replaces it.
+type ResultCache struct {
+	mu      sync.RWMutex
+	entries map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	key CacheKey
+	CachedResult
+}
+
+// NewResultCache creates an empty cache
+func NewResultCache() *ResultCache {
+	return &ResultCache{entries: make(map[string]cacheEntry)}
+}
+
+// ConfigHash fingerprints a configuration for use in a CacheKey
+func ConfigHash(config AlgorithmConfig) string {
+	// encoding/json sorts map keys, so equal configs hash equally
+	b, _ := json.Marshal(config)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
+
+func slotKey(key CacheKey) string {
+	return key.Instance + "|" + string(key.Type) + "|" + key.Symbol
+}
+
+// Get returns the cached result for key, if any
+func (c *ResultCache) Get(key CacheKey) (CachedResult, bool) {
+	if key.BarTime.IsZero() {
+		return CachedResult{}, false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, ok := c.entries[slotKey(key)]
+	if !ok || e.key.ConfigHash != key.ConfigHash || !e.key.BarTime.Equal(key.BarTime) {
+		return CachedResult{}, false
+	}
+	return e.CachedResult, true
+}
+
+// Put stores result under key, replacing any earlier result for the same
+// instance, algorithm type and symbol. Histories without bar timestamps cannot be
+// keyed and are not cached.
+func (c *ResultCache) Put(key CacheKey, result *AlgorithmResult) CachedResult {
+	cached := CachedResult{Result: result, ComputedAt: time.Now()}
+	if key.BarTime.IsZero() {
+		return cached
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[slotKey(key)] = cacheEntry{key: key, CachedResult: cached}
+	return cached
+}
+
+// Invalidate drops every cached result for the named instance
+func (c *ResultCache) Invalidate(instance string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k, e := range c.entries {
+		if e.key.Instance == instance {
+			delete(c.entries, k)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package algo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestResultCacheInvalidatesOnNewBarAndConfig(t *testing.T) {
+	c := NewResultCache()
+	bar := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	cfg := AlgorithmConfig{AdditionalParams: map[string]float64{"a": 1, "b": 2}}
+	key := CacheKey{Instance: "tb", Type: AlgorithmTypeTripleBarrier, ConfigHash: ConfigHash(cfg), Symbol: "AAPL", BarTime: bar}
+
+	if _, ok := c.Get(key); ok {
+		t.Fatal("hit on empty cache")
+	}
+	c.Put(key, &AlgorithmResult{Signal: "buy"})
+	if got, ok := c.Get(key); !ok || got.Result.Signal != "buy" || got.ComputedAt.IsZero() {
+		t.Fatalf("get = %+v, %v", got, ok)
+	}
+
+	// Map ordering must not change the hash
+	same := AlgorithmConfig{AdditionalParams: map[string]float64{"b": 2, "a": 1}}
+	if ConfigHash(same) != key.ConfigHash {
+		t.Error("equal configs hash differently")
+	}
+
+	next := key
+	next.BarTime = bar.AddDate(0, 0, 1)
+	if _, ok := c.Get(next); ok {
+		t.Error("hit after a new bar")
+	}
+	reconfigured := key
+	reconfigured.ConfigHash = ConfigHash(AlgorithmConfig{TimeFrame: "1H"})
+	if _, ok := c.Get(reconfigured); ok {
+		t.Error("hit after reconfiguration")
+	}
+	if _, ok := c.Get(CacheKey{Instance: "tb", Type: AlgorithmTypeTripleBarrier, ConfigHash: key.ConfigHash, Symbol: "MSFT", BarTime: bar}); ok {
+		t.Error("hit for another symbol")
+	}
+
+	c.Put(CacheKey{Instance: "tb", Type: AlgorithmTypeTripleBarrier, Symbol: "AAPL"}, &AlgorithmResult{Signal: "sell"})
+	if got, _ := c.Get(key); got.Result == nil || got.Result.Signal != "buy" {
+		t.Error("untimestamped history replaced a keyed result")
+	}
+
+	c.Invalidate("tb")
+	if _, ok := c.Get(key); ok {
+		t.Error("hit after Invalidate")
+	}
+}
@@ -3,7 +3,6 @@ package algo
 import (
 	"errors"
 	"fmt"
-	"log"
 	"math/rand"
 
 	"github.com/rileyseaburg/go-trader/types"
@@ -56,6 +55,16 @@ func (s *SequentialBootstrapAlgorithm) ParameterDescription() map[string]string
 	}
 }
 
+// Metadata describes the algorithm and its default parameters
+func (s *SequentialBootstrapAlgorithm) Metadata() AlgorithmMetadata {
+	return describe(s, map[string]interface{}{
+		"lookback_period":      20,
+		"confidence_threshold": 0.65,
+		"use_sequential":       1,
+		"sample_size":          50,
+	})
+}
+
 // Configure configures the algorithm with the given parameters
 func (s *SequentialBootstrapAlgorithm) Configure(config AlgorithmConfig) error {
 	if err := s.BaseAlgorithm.Configure(config); err != nil {
@@ -97,6 +106,11 @@ func (s *SequentialBootstrapAlgorithm) Configure(config AlgorithmConfig) error {
 	return nil
 }
 
+// RequiredHistory returns the bars needed to fill the lookback period
+func (s *SequentialBootstrapAlgorithm) RequiredHistory() int {
+	return maxInt(s.BaseAlgorithm.RequiredHistory(), s.lookbackPeriod)
+}
+
 // Process processes the market data and generates a trading signal
 func (s *SequentialBootstrapAlgorithm) Process(
 	symbol string,
@@ -191,6 +205,8 @@ func (s *SequentialBootstrapAlgorithm) Process(
 		orderType = "NONE"
 	}
 
+	uniqueness := calculateAverageUniqueness(s.lastSamples)
+
 	// Generate explanation
 	s.explanation = fmt.Sprintf(
 		"Sequential Bootstrap analysis on %d samples with %d lookback period.\n"+
@@ -199,7 +215,7 @@ func (s *SequentialBootstrapAlgorithm) Process(
 			"Confidence threshold: %.2f",
 		s.sampleSize, s.lookbackPeriod,
 		upSignals, downSignals, confidence*100,
-		calculateAverageUniqueness(s.lastSamples),
+		uniqueness,
 		s.confidenceThreshold,
 	)
 
@@ -208,6 +224,15 @@ func (s *SequentialBootstrapAlgorithm) Process(
 		OrderType:   orderType,
 		Confidence:  confidence,
 		Explanation: s.explanation,
+		Details: map[string]interface{}{
+			"sample_size":          s.sampleSize,
+			"lookback_period":      s.lookbackPeriod,
+			"sequential":           s.useSequential,
+			"up_signals":           upSignals,
+			"down_signals":         downSignals,
+			"average_uniqueness":   uniqueness,
+			"confidence_threshold": s.confidenceThreshold,
+		},
 	}, nil
 }
 
@@ -412,7 +437,7 @@ func standardBootstrap(indM *mat.Dense, sLength int) []int {
 		sLength = c
 	}
 
-	log.Printf("DEBUG: Running standardBootstrap with dimensions: %d, sample length: %d", c, sLength)
+	logger().Debug("Running standard bootstrap", "dimensions", c, "sample_length", sLength)
 
 	// Simple random sampling with replacement
 	samples := make([]int, sLength)
@@ -0,0 +1,101 @@
+package algo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+// SymbolInstances keeps one configured instance of each named algorithm
+// per symbol. Algorithms carry state between Process calls — CUSUM sums,
+// the last explanation — so symbols must not share an instance, and calls
+// on one instance are serialized.
+type SymbolInstances struct {
+	mu      sync.Mutex
+	entries map[string]*symbolInstance
+}
+
+type symbolInstance struct {
+	mu         sync.Mutex
+	alg        Algorithm
+	configHash string
+}
+
+// NewSymbolInstances creates an empty instance set
+func NewSymbolInstances() *SymbolInstances {
+	return &SymbolInstances{entries: make(map[string]*symbolInstance)}
+}
+
+// instance returns the symbol's instance of the algorithm named name,
+// creating it from algType and config, and replacing it when the
+// configuration has changed
+func (s *SymbolInstances) instance(name string, algType AlgorithmType, config AlgorithmConfig, symbol string) (*symbolInstance, error) {
+	hash := ConfigHash(config)
+	key := name + "|" + string(algType) + "|" + symbol
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if e, ok := s.entries[key]; ok && e.configHash == hash {
+		return e, nil
+	}
+	alg, err := Create(algType)
+	if err != nil {
+		return nil, err
+	}
+	if err := alg.Configure(config); err != nil {
+		return nil, err
+	}
+	e := &symbolInstance{alg: alg, configHash: hash}
+	s.entries[key] = e
+	return e, nil
+}
+
+// Process runs the symbol's instance of the algorithm named name over data
+// and historicalData
+func (s *SymbolInstances) Process(name string, algType AlgorithmType, config AlgorithmConfig, symbol string, data *types.MarketData, historicalData []types.MarketData) (*AlgorithmResult, error) {
+	e, err := s.instance(name, algType, config, symbol)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.alg.Process(symbol, data, historicalData)
+}
+
+// ProcessPortfolio runs the basket's instance of the algorithm named name
+// over histories, keyed by symbol. The basket is identified by its sorted
+// symbols, so each distinct basket keeps its own instance. It fails for
+// algorithms that do not implement PortfolioProcessor.
+func (s *SymbolInstances) ProcessPortfolio(name string, algType AlgorithmType, config AlgorithmConfig, histories map[string][]types.MarketData) (*PortfolioResult, error) {
+	symbols := make([]string, 0, len(histories))
+	for symbol := range histories {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	e, err := s.instance(name, algType, config, strings.Join(symbols, ","))
+	if err != nil {
+		return nil, err
+	}
+	p, ok := e.alg.(PortfolioProcessor)
+	if !ok {
+		return nil, fmt.Errorf("algorithm type %s does not process baskets", algType)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return p.ProcessPortfolio(histories)
+}
+
+// Forget drops every symbol's instance of the algorithm named name
+func (s *SymbolInstances) Forget(name string) {
+	prefix := name + "|"
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k := range s.entries {
+		if strings.HasPrefix(k, prefix) {
+			delete(s.entries, k)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package algo
+
+import (
+	"testing"
+
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+func TestSymbolInstancesKeepStateApart(t *testing.T) {
+	s := NewSymbolInstances()
+	config := AlgorithmConfig{AdditionalParams: map[string]float64{"threshold": 5}}
+	history := []types.MarketData{{Price: 100}, {Price: 101}, {Price: 99}, {Price: 104}}
+	current := history[len(history)-1]
+
+	for _, sym := range []string{"AAPL", "MSFT"} {
+		if _, err := s.Process("cusum", AlgorithmTypeCUSUMFilter, config, sym, &current, history); err != nil {
+			t.Fatal(err)
+		}
+	}
+	aapl, _ := s.instance("cusum", AlgorithmTypeCUSUMFilter, config, "AAPL")
+	msft, _ := s.instance("cusum", AlgorithmTypeCUSUMFilter, config, "MSFT")
+	if aapl == msft {
+		t.Fatal("symbols share an instance")
+	}
+	if again, _ := s.instance("cusum", AlgorithmTypeCUSUMFilter, config, "AAPL"); again != aapl {
+		t.Error("instance not reused for the same configuration")
+	}
+
+	reconfigured := AlgorithmConfig{AdditionalParams: map[string]float64{"threshold": 2}}
+	if fresh, _ := s.instance("cusum", AlgorithmTypeCUSUMFilter, reconfigured, "AAPL"); fresh == aapl {
+		t.Error("instance kept after reconfiguration")
+	}
+	s.Forget("cusum")
+	if again, _ := s.instance("cusum", AlgorithmTypeCUSUMFilter, config, "MSFT"); again == msft {
+		t.Error("instance kept after Forget")
+	}
+	if _, err := s.Process("tb", AlgorithmTypeTripleBarrier, AlgorithmConfig{AdditionalParams: map[string]float64{"stop_loss": -1}}, "AAPL", &current, history); err == nil {
+		t.Error("invalid configuration accepted")
+	}
+}
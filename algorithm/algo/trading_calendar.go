@@ -0,0 +1,83 @@
+package algo
+
+import (
+	"sync"
+	"time"
+)
+
+// TradingCalendar reports which dates have an exchange session.
+// *calendar.Calendar satisfies it.
+type TradingCalendar interface {
+	IsTradingDay(t time.Time) bool
+}
+
+// weekdayCalendar treats every weekday as a session. It is the fallback
+// until a holiday-aware calendar is installed.
+type weekdayCalendar struct{}
+
+func (weekdayCalendar) IsTradingDay(t time.Time) bool {
+	wd := t.Weekday()
+	return wd != time.Saturday && wd != time.Sunday
+}
+
+var (
+	calendarMu      sync.RWMutex
+	tradingCalendar TradingCalendar = weekdayCalendar{}
+)
+
+// SetTradingCalendar installs the calendar used to count sessions for time
+// barriers. A nil calendar restores the weekday-only fallback.
+func SetTradingCalendar(cal TradingCalendar) {
+	calendarMu.Lock()
+	defer calendarMu.Unlock()
+	if cal == nil {
+		cal = weekdayCalendar{}
+	}
+	tradingCalendar = cal
+}
+
+func currentCalendar() TradingCalendar {
+	calendarMu.RLock()
+	defer calendarMu.RUnlock()
+	return tradingCalendar
+}
+
+// startOfDay truncates t to midnight in its own location.
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// isSession checks the date of day at midday, so calendars that convert to
+// the exchange zone see the same date whatever zone day is expressed in.
+func isSession(cal TradingCalendar, day time.Time) bool {
+	return cal.IsTradingDay(day.Add(12 * time.Hour))
+}
+
+// AddTradingDays returns the date n sessions after the date of t, at
+// midnight in t's location.
+func AddTradingDays(cal TradingCalendar, t time.Time, n int) time.Time {
+	day := startOfDay(t)
+	for n > 0 {
+		day = day.AddDate(0, 0, 1)
+		if isSession(cal, day) {
+			n--
+		}
+	}
+	return day
+}
+
+// sessionDates returns the dates of the n most recent sessions on or
+// before end, oldest first.
+func sessionDates(cal TradingCalendar, end time.Time, n int) []time.Time {
+	dates := make([]time.Time, n)
+	day := startOfDay(end)
+	for i := n - 1; i >= 0; {
+		if isSession(cal, day) {
+			dates[i] = day
+			i--
+		}
+		day = day.AddDate(0, 0, -1)
+	}
+	return dates
+}
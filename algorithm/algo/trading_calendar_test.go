@@ -0,0 +1,51 @@
+package algo
+
+import (
+	"testing"
+	"time"
+)
+
+// holidayCalendar is a weekday calendar with extra closed dates.
+type holidayCalendar map[string]bool
+
+func (h holidayCalendar) IsTradingDay(t time.Time) bool {
+	return weekdayCalendar{}.IsTradingDay(t) && !h[t.Format("2006-01-02")]
+}
+
+func TestAddTradingDaysSkipsWeekendsAndHolidays(t *testing.T) {
+	cal := holidayCalendar{"2024-07-04": true}
+	wed := time.Date(2024, 7, 3, 16, 0, 0, 0, time.UTC)
+	// Thu is a holiday, then Fri, Mon, Tue
+	if got := AddTradingDays(cal, wed, 3); got.Format("2006-01-02") != "2024-07-09" {
+		t.Errorf("AddTradingDays = %s, want 2024-07-09", got.Format("2006-01-02"))
+	}
+
+	dates := sessionDates(cal, time.Date(2024, 7, 8, 12, 0, 0, 0, time.UTC), 3)
+	want := []string{"2024-07-03", "2024-07-05", "2024-07-08"}
+	for i, d := range dates {
+		if d.Format("2006-01-02") != want[i] {
+			t.Errorf("sessionDates[%d] = %s, want %s", i, d.Format("2006-01-02"), want[i])
+		}
+	}
+}
+
+func TestTimeBarrierCountsSessions(t *testing.T) {
+	SetTradingCalendar(nil)
+	// Daily points Fri through Fri; a 2-session horizon from Friday ends on
+	// Tuesday, not Sunday
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	times := make([]time.Time, 8)
+	for i := range times {
+		times[i] = start.AddDate(0, 0, i)
+	}
+	prices := []float64{100, 100.1, 100.1, 100.2, 100.3, 100.2, 100.4, 100.5}
+
+	results, err := ApplyTripleBarrier(prices, times, 0.01, TripleBarrierConfig{ProfitTaking: 10, StopLoss: 10, TimeHorizon: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := results[0]
+	if first.BarrierHit != BarrierTypeTime || first.ExitTime.Weekday() != time.Tuesday {
+		t.Errorf("first exit = %s on %s, want time barrier on Tuesday", first.BarrierHit, first.ExitTime.Weekday())
+	}
+}
@@ -0,0 +1,128 @@
+package algo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// LabelingMethod names how training labels are drawn from a price series.
+type LabelingMethod string
+
+const (
+	// LabelingNone leaves history unlabeled
+	LabelingNone LabelingMethod = ""
+	// LabelingTripleBarrier labels each bar by the first of the profit,
+	// stop and time barriers its price path reaches
+	LabelingTripleBarrier LabelingMethod = "triple_barrier"
+	// LabelingTrendScanning labels each bar by the sign of its strongest
+	// forward linear trend
+	LabelingTrendScanning LabelingMethod = "trend_scanning"
+)
+
+// ParseLabelingMethod returns the labeling method called name; an empty
+// name is LabelingNone.
+func ParseLabelingMethod(name string) (LabelingMethod, error) {
+	switch m := LabelingMethod(name); m {
+	case LabelingNone, LabelingTripleBarrier, LabelingTrendScanning:
+		return m, nil
+	}
+	return LabelingNone, fmt.Errorf("unknown labeling method %q: use %s or %s", name, LabelingTripleBarrier, LabelingTrendScanning)
+}
+
+// maxTrendTValue caps the t-value of a trend fitting its prices exactly,
+// whose standard error is zero.
+const maxTrendTValue = 1e6
+
+// TrendScanConfig bounds the forward windows trend scanning fits.
+type TrendScanConfig struct {
+	MinSpan int // Fewest bars in a trend, entry included; at least 3
+	MaxSpan int // Most bars in a trend
+}
+
+// TrendScanResult is the trend-scanning label of one entry bar.
+type TrendScanResult struct {
+	Label      BarrierLabel `json:"label"`   // sign of the t-value
+	TValue     float64      `json:"t_value"` // slope t-value of the chosen trend
+	Span       int          `json:"span"`    // bars in the chosen trend
+	EntryTime  time.Time    `json:"entry_time"`
+	EntryPrice float64      `json:"entry_price"`
+	EndTime    time.Time    `json:"end_time"`
+	EndPrice   float64      `json:"end_price"`
+}
+
+// ApplyTrendScanning labels each bar with trend scanning, from de Prado's
+// "Machine Learning for Asset Managers" (2020), snippet 5.2: it fits a
+// linear trend to the prices over every forward window from MinSpan to
+// MaxSpan bars, keeps the one whose slope has the largest absolute
+// t-value, and labels the bar by that slope's sign. Bars too close to the
+// end for the longest window are left unlabeled.
+func ApplyTrendScanning(prices []float64, times []time.Time, config TrendScanConfig) ([]*TrendScanResult, error) {
+	if len(prices) != len(times) {
+		return nil, errors.New("prices and times must have the same length")
+	}
+	if config.MinSpan < 3 || config.MaxSpan < config.MinSpan {
+		return nil, errors.New("trend spans must satisfy 3 <= min_span <= max_span")
+	}
+	if len(prices) < config.MaxSpan {
+		return nil, fmt.Errorf("need at least %d price points to scan trends", config.MaxSpan)
+	}
+
+	results := make([]*TrendScanResult, 0, len(prices)-config.MaxSpan+1)
+	for i := 0; i+config.MaxSpan <= len(prices); i++ {
+		best := &TrendScanResult{EntryTime: times[i], EntryPrice: prices[i]}
+		for span := config.MinSpan; span <= config.MaxSpan; span++ {
+			if t := trendTValue(prices[i : i+span]); best.Span == 0 || math.Abs(t) > math.Abs(best.TValue) {
+				best.TValue, best.Span = t, span
+			}
+		}
+		end := i + best.Span - 1
+		best.EndTime, best.EndPrice = times[end], prices[end]
+		switch {
+		case best.TValue > 0:
+			best.Label = BarrierLabelBuy
+		case best.TValue < 0:
+			best.Label = BarrierLabelSell
+		default:
+			best.Label = BarrierLabelHold
+		}
+		results = append(results, best)
+	}
+	return results, nil
+}
+
+// trendTValue regresses ys on their index and returns the t-value of the
+// slope.
+func trendTValue(ys []float64) float64 {
+	n := float64(len(ys))
+	xMean, yMean := (n-1)/2, 0.0
+	for _, y := range ys {
+		yMean += y
+	}
+	yMean /= n
+
+	sxx, sxy := 0.0, 0.0
+	for i, y := range ys {
+		dx := float64(i) - xMean
+		sxx += dx * dx
+		sxy += dx * (y - yMean)
+	}
+	slope := sxy / sxx
+	sse := 0.0
+	for i, y := range ys {
+		r := y - yMean - slope*(float64(i)-xMean)
+		sse += r * r
+	}
+	se := math.Sqrt(sse / (n - 2) / sxx)
+	if se == 0 || slope/se > maxTrendTValue || slope/se < -maxTrendTValue {
+		switch {
+		case slope > 0:
+			return maxTrendTValue
+		case slope < 0:
+			return -maxTrendTValue
+		}
+		return 0
+	}
+	return slope / se
+}
@@ -0,0 +1,99 @@
+package algo
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+// tentPrices rise for half of n bars and fall for the rest, wobbling so no
+// window is an exact line.
+func tentPrices(n int) ([]float64, []time.Time) {
+	prices := make([]float64, n)
+	times := make([]time.Time, n)
+	start := time.Date(2025, 1, 2, 21, 0, 0, 0, time.UTC)
+	for i := range prices {
+		trend := float64(i)
+		if i >= n/2 {
+			trend = float64(n - i)
+		}
+		prices[i] = 100 + trend + 0.3*math.Sin(float64(i))
+		times[i] = start.AddDate(0, 0, i)
+	}
+	return prices, times
+}
+
+func TestApplyTrendScanning(t *testing.T) {
+	prices, times := tentPrices(60)
+	results, err := ApplyTrendScanning(prices, times, TrendScanConfig{MinSpan: 5, MaxSpan: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 51 {
+		t.Fatalf("labeled %d bars, want 51", len(results))
+	}
+	if r := results[0]; r.Label != BarrierLabelBuy || r.TValue < 10 || r.Span < 5 || r.Span > 10 || !r.EndTime.Equal(times[r.Span-1]) {
+		t.Errorf("rising bar = %+v", r)
+	}
+	if r := results[40]; r.Label != BarrierLabelSell || r.TValue > -10 {
+		t.Errorf("falling bar = %+v", r)
+	}
+
+	// An exact line's t-value is capped rather than infinite
+	if tv := trendTValue([]float64{1, 2, 3, 4}); tv != maxTrendTValue {
+		t.Errorf("straight line t-value = %v", tv)
+	}
+	if tv := trendTValue([]float64{5, 5, 5}); tv != 0 {
+		t.Errorf("flat t-value = %v", tv)
+	}
+
+	for _, c := range []TrendScanConfig{{MinSpan: 2, MaxSpan: 5}, {MinSpan: 6, MaxSpan: 5}, {MinSpan: 5, MaxSpan: 61}} {
+		if _, err := ApplyTrendScanning(prices, times, c); err == nil {
+			t.Errorf("%+v accepted", c)
+		}
+	}
+}
+
+func TestMetaLabelingTrainsOnLabels(t *testing.T) {
+	m := &MetaLabelingAlgorithm{}
+	if err := m.Configure(AlgorithmConfig{LabelingMethod: "trend_scanning"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.RequiredHistory(); got < 29 {
+		t.Errorf("RequiredHistory = %d", got)
+	}
+
+	prices, times := tentPrices(80)
+	history := make([]types.MarketData, len(prices))
+	for i := range prices {
+		history[i] = types.MarketData{Price: prices[i], Timestamp: times[i]}
+	}
+	buy, n, err := m.labelHitRate("buy", history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sell, _, _ := m.labelHitRate("sell", history)
+	if n != 61 || math.Abs(buy+sell-1) > 1e-12 || buy <= 0 || buy >= 1 {
+		t.Errorf("buy %v, sell %v over %d labels", buy, sell, n)
+	}
+
+	m.labeling = LabelingTripleBarrier
+	if _, n, err := m.labelHitRate("buy", history); err != nil || n == 0 {
+		t.Errorf("triple barrier labels: %d, %v", n, err)
+	}
+	if _, _, err := m.labelHitRate("buy", history[:8]); err == nil {
+		t.Error("trained on too few bars")
+	}
+
+	for _, c := range []AlgorithmConfig{
+		{LabelingMethod: "fixed_horizon"},
+		{LabelingMethod: "trend_scanning", AdditionalParams: map[string]float64{"trend_min_span": 2}},
+		{LabelingMethod: "trend_scanning", AdditionalParams: map[string]float64{"trend_min_span": 10, "trend_max_span": 8}},
+	} {
+		if err := (&MetaLabelingAlgorithm{}).Configure(c); err == nil {
+			t.Errorf("%+v accepted", c)
+		}
+	}
+}
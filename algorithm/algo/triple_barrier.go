@@ -3,9 +3,9 @@ package algo
 import (
 	"errors"
 	"fmt"
-	"math"
 	"time"
 
+	"github.com/rileyseaburg/go-trader/algorithm/algo/volatility"
 	"github.com/rileyseaburg/go-trader/types"
 )
 
@@ -47,7 +47,7 @@ type BarrierResult struct {
 type TripleBarrierConfig struct {
 	ProfitTaking       float64 // Multiple of volatility for upper barrier
 	StopLoss           float64 // Multiple of volatility for lower barrier
-	TimeHorizon        int     // Days for vertical barrier
+	TimeHorizon        int     // Trading sessions for vertical barrier
 	VolatilityLookback int     // Lookback window for volatility estimation
 }
 
@@ -67,7 +67,7 @@ type TripleBarrierAlgorithm struct {
 	config           TripleBarrierConfig
 	profitTaking     float64 // Multiple of volatility for upper barrier
 	stopLoss         float64 // Multiple of volatility for lower barrier
-	timeHorizon      int     // Days for vertical barrier
+	timeHorizon      int     // Trading sessions for vertical barrier
 	volatilityWindow int     // Lookback window for volatility estimation
 }
 
@@ -91,11 +91,21 @@ func (t *TripleBarrierAlgorithm) ParameterDescription() map[string]string {
 	return map[string]string{
 		"profit_taking":       "Multiple of volatility for upper/profit-taking barrier (default: 2.0)",
 		"stop_loss":           "Multiple of volatility for lower/stop-loss barrier (default: 1.0)",
-		"time_horizon":        "Number of days for vertical/time barrier (default: 5)",
+		"time_horizon":        "Number of trading sessions for vertical/time barrier (default: 5)",
 		"volatility_lookback": "Lookback window in days for volatility estimation (default: 20)",
 	}
 }
 
+// Metadata describes the algorithm and its default parameters
+func (t *TripleBarrierAlgorithm) Metadata() AlgorithmMetadata {
+	return describe(t, map[string]interface{}{
+		"profit_taking":       2.0,
+		"stop_loss":           1.0,
+		"time_horizon":        5,
+		"volatility_lookback": 20,
+	})
+}
+
 // Configure configures the algorithm with the given parameters
 func (t *TripleBarrierAlgorithm) Configure(config AlgorithmConfig) error {
 	if err := t.BaseAlgorithm.Configure(config); err != nil {
@@ -148,6 +158,11 @@ func (t *TripleBarrierAlgorithm) Configure(config AlgorithmConfig) error {
 	return nil
 }
 
+// RequiredHistory returns the bars needed for the volatility window
+func (t *TripleBarrierAlgorithm) RequiredHistory() int {
+	return maxInt(t.BaseAlgorithm.RequiredHistory(), t.volatilityWindow)
+}
+
 // Process processes the market data and generates labels using the triple barrier method
 func (t *TripleBarrierAlgorithm) Process(
 	symbol string,
@@ -158,20 +173,24 @@ func (t *TripleBarrierAlgorithm) Process(
 		return nil, fmt.Errorf("insufficient historical data: need at least %d data points for volatility estimation", t.volatilityWindow)
 	}
 
-	// Extract price series
+	// Extract price series, using bar timestamps when every point has one
 	prices := make([]float64, len(historicalData))
 	times := make([]time.Time, len(historicalData))
-	baseTime := time.Now().Add(-time.Duration(len(historicalData)) * 24 * time.Hour) // Start from current time - days of data
-	
+	stamped := true
 	for i, data := range historicalData {
 		prices[i] = data.Price
-		// Since MarketData doesn't have a timestamp, we'll create synthetic ones
-		// This assumes daily data for illustration purposes
-		times[i] = baseTime.Add(time.Duration(i) * 24 * time.Hour)
+		times[i] = data.Timestamp
+		stamped = stamped && !data.Timestamp.IsZero()
+	}
+	if !stamped {
+		// Without timestamps, assume one daily bar per session ending today
+		times = sessionDates(currentCalendar(), time.Now(), len(historicalData))
 	}
 
-	// Calculate daily volatility
-	vol, err := DailyVolatility(prices, t.volatilityWindow)
+	// Calculate daily volatility, by the configured estimator if any
+	vol, estimator, err := t.estimateVolatility(historicalData, t.volatilityWindow, func(closes []float64) (float64, error) {
+		return DailyVolatility(closes, t.volatilityWindow)
+	}, "ewma")
 	if err != nil {
 		return nil, fmt.Errorf("error calculating volatility: %v", err)
 	}
@@ -187,6 +206,14 @@ func (t *TripleBarrierAlgorithm) Process(
 	var signal string
 	var orderType string
 	var confidence float64
+	details := map[string]interface{}{
+		"profit_taking": t.profitTaking,
+		"stop_loss":     t.stopLoss,
+		"time_horizon":  t.timeHorizon,
+		"volatility":    vol,
+		"estimator":     estimator,
+		"labels":        len(result),
+	}
 
 	if result == nil || len(result) == 0 {
 		explanation = "Triple barrier method did not generate any labels"
@@ -196,8 +223,16 @@ func (t *TripleBarrierAlgorithm) Process(
 	} else {
 		// Use the most recent barrier result
 		latestResult := result[len(result)-1]
+		details["entry_price"] = latestResult.EntryPrice
+		details["entry_time"] = latestResult.EntryTime
+		details["exit_price"] = latestResult.ExitPrice
+		details["exit_time"] = latestResult.ExitTime
+		details["barrier_hit"] = latestResult.BarrierHit
+		details["label"] = latestResult.Label
+		details["upper_barrier"] = latestResult.EntryPrice * (1 + t.profitTaking*vol)
+		details["lower_barrier"] = latestResult.EntryPrice * (1 - t.stopLoss*vol)
 		
-		explanation = fmt.Sprintf("Triple barrier method applied with profit-taking=%.2f, stop-loss=%.2f, time-horizon=%d days.\n",
+		explanation = fmt.Sprintf("Triple barrier method applied with profit-taking=%.2f, stop-loss=%.2f, time-horizon=%d sessions.\n",
 			t.profitTaking, t.stopLoss, t.timeHorizon)
 			
 		explanation += fmt.Sprintf("Entry at %.2f on %s, exit at %.2f on %s.\n",
@@ -233,46 +268,14 @@ func (t *TripleBarrierAlgorithm) Process(
 		OrderType:   orderType,
 		Confidence:  confidence,
 		Explanation: explanation,
+		Details:     details,
 	}, nil
 }
 
 // DailyVolatility estimates the daily volatility using an exponentially weighted moving standard deviation
 // This corresponds to Snippet 3.1 in the book
 func DailyVolatility(prices []float64, span int) (float64, error) {
-	if len(prices) < 2 {
-		return 0, errors.New("need at least 2 price points to calculate volatility")
-	}
-
-	if span < 1 {
-		return 0, errors.New("span must be at least 1")
-	}
-
-	// Compute daily returns
-	returns := make([]float64, len(prices)-1)
-	for i := 1; i < len(prices); i++ {
-		returns[i-1] = math.Log(prices[i] / prices[i-1])
-	}
-
-	// Compute exponentially weighted moving standard deviation
-	// For simplicity, using a exponentially weighted moving average with decay factor
-	alpha := 2.0 / float64(span+1)
-	var ewma, ewmVar float64
-	
-	// Initialize with the first return
-	ewma = returns[0]
-	ewmVar = 0
-	
-	for i := 1; i < len(returns); i++ {
-		// Update the exponentially weighted moving average
-		ewma = alpha*returns[i] + (1-alpha)*ewma
-		
-		// Update the exponentially weighted moving variance
-		deviation := returns[i] - ewma
-		ewmVar = alpha*deviation*deviation + (1-alpha)*ewmVar
-	}
-	
-	// Return the square root of the variance (standard deviation)
-	return math.Sqrt(ewmVar), nil
+	return volatility.EWMA(prices, span)
 }
 
 // ApplyTripleBarrier implements the Triple Barrier method
@@ -302,8 +305,10 @@ func ApplyTripleBarrier(prices []float64, times []time.Time, volatility float64,
 		upperBarrier := entryPrice * (1 + config.ProfitTaking * volatility)
 		lowerBarrier := entryPrice * (1 - config.StopLoss * volatility)
 		
-		// Define time barrier
-		timeBarrierIdx := min(i + config.TimeHorizon, len(prices)-1)
+		// Define time barrier: the last point on or before the session
+		// TimeHorizon trading days after entry, so weekends and holidays
+		// do not count toward the horizon
+		timeBarrierIdx := timeBarrierIndex(times, i, config.TimeHorizon)
 		
 		// Simulate forward in time to determine which barrier is hit first
 		var hitBarrier BarrierType
@@ -386,6 +391,18 @@ func ApplyTripleBarrier(prices []float64, times []time.Time, volatility float64,
 	return results, nil
 }
 
+// timeBarrierIndex returns the index of the last point dated on or before
+// the session horizon trading days after times[entry]. It is never before
+// entry+1, so every entry has at least one forward point to exit on.
+func timeBarrierIndex(times []time.Time, entry, horizon int) int {
+	limit := AddTradingDays(currentCalendar(), times[entry], horizon).AddDate(0, 0, 1)
+	idx := entry + 1
+	for j := entry + 1; j < len(times) && times[j].Before(limit); j++ {
+		idx = j
+	}
+	return idx
+}
+
 // GetMetaLabels converts barrier results to meta-labels for secondary ML model
 // This is used in conjunction with meta-labeling approach
 func GetMetaLabels(barrierResults []*BarrierResult) []bool {
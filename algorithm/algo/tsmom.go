@@ -0,0 +1,381 @@
+package algo
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+// init registers the time-series momentum algorithm with the factory
+func init() {
+	Register(AlgorithmTypeTSMOM, func() Algorithm {
+		return &TSMOMAlgorithm{}
+	})
+}
+
+// TSMOMAlgorithm implements time-series momentum after Moskowitz, Ooi and
+// Pedersen, "Time Series Momentum" (2012): each symbol goes long after a
+// positive return over the lookback, skipping the most recent month, and
+// short after a negative one, sized so its volatility matches a target.
+// An optional shorter window votes alongside the 12-1 month return, and
+// the position is flat when the votes cancel.
+type TSMOMAlgorithm struct {
+	BaseAlgorithm
+	lookback       int     // Bars in the momentum window, 12 months by default
+	skip           int     // Most recent bars left out of the window
+	shortWindow    int     // Bars in the shorter window; 0 disables it
+	shortWeight    float64 // Share of the vote the shorter window has
+	flatBand       float64 // Vote magnitude at or below which the position is flat
+	targetVol      float64 // Annualized volatility each position is sized to
+	volWindow      int     // Span of the volatility estimate
+	maxLeverage    float64 // Largest absolute weight of one symbol, or of a basket's gross exposure
+	periodsPerYear float64 // Bars per year, to annualize volatility
+}
+
+// tsmomSignal is one symbol's momentum position before any basket scaling.
+type tsmomSignal struct {
+	momentum      float64 // return over the lookback, skipping the recent bars
+	shortMomentum float64 // return over the shorter window, when set
+	score         float64 // signed vote in [-1, 1]
+	volatility    float64 // annualized
+	estimator     string
+	weight        float64 // signed, volatility-scaled fraction of equity
+	tStat         float64 // momentum log return over its expected volatility
+}
+
+// Name returns the name of the algorithm
+func (m *TSMOMAlgorithm) Name() string {
+	return "Time-Series Momentum"
+}
+
+// Type returns the type of the algorithm
+func (m *TSMOMAlgorithm) Type() AlgorithmType {
+	return AlgorithmTypeTSMOM
+}
+
+// Description returns a brief description of the algorithm
+func (m *TSMOMAlgorithm) Description() string {
+	return "Goes long or short each symbol by the sign of its own 12-1 month return, and optionally a shorter window, with positions scaled to a volatility target"
+}
+
+// ParameterDescription returns a description of the parameters
+func (m *TSMOMAlgorithm) ParameterDescription() map[string]string {
+	return map[string]string{
+		"lookback":          "Bars in the momentum window (default: 252, 12 months of sessions)",
+		"skip":              "Most recent bars left out of the momentum window (default: 21, one month)",
+		"short_window":      "Bars in an additional shorter momentum window, not skipped; 0 disables it (default: 0)",
+		"short_weight":      "Share of the vote the shorter window has when set (default: 0.5)",
+		"flat_band":         "Vote magnitude at or below which the position is flat (default: 0)",
+		"target_volatility": "Annualized volatility each position is scaled to (default: 0.40)",
+		"vol_window":        "Span of the volatility estimate in bars (default: 60)",
+		"max_leverage":      "Largest absolute weight of one symbol, and gross exposure of a basket (default: 2.0)",
+		"periods_per_year":  "Bars per year, to annualize volatility (default: 252)",
+	}
+}
+
+// Metadata describes the algorithm and its default parameters
+func (m *TSMOMAlgorithm) Metadata() AlgorithmMetadata {
+	return describe(m, map[string]interface{}{
+		"lookback":          252,
+		"skip":              21,
+		"short_window":      0,
+		"short_weight":      0.5,
+		"flat_band":         0.0,
+		"target_volatility": 0.40,
+		"vol_window":        60,
+		"max_leverage":      2.0,
+		"periods_per_year":  252.0,
+	})
+}
+
+// Configure configures the algorithm with the given parameters
+func (m *TSMOMAlgorithm) Configure(config AlgorithmConfig) error {
+	if err := m.BaseAlgorithm.Configure(config); err != nil {
+		return err
+	}
+
+	// Set default values
+	m.lookback = 252
+	m.skip = 21
+	m.shortWindow = 0
+	m.shortWeight = 0.5
+	m.flatBand = 0
+	m.targetVol = 0.40
+	m.volWindow = 60
+	m.maxLeverage = 2.0
+	m.periodsPerYear = 252
+
+	// Override with provided values
+	if val, ok := config.AdditionalParams["lookback"]; ok {
+		if val < 2 {
+			return errors.New("lookback must be at least 2")
+		}
+		m.lookback = int(val)
+	}
+
+	if val, ok := config.AdditionalParams["skip"]; ok {
+		if val < 0 {
+			return errors.New("skip must not be negative")
+		}
+		m.skip = int(val)
+	}
+	if m.skip >= m.lookback {
+		return errors.New("skip must be shorter than lookback")
+	}
+
+	if val, ok := config.AdditionalParams["short_window"]; ok {
+		if val < 0 {
+			return errors.New("short_window must not be negative")
+		}
+		m.shortWindow = int(val)
+	}
+
+	if val, ok := config.AdditionalParams["short_weight"]; ok {
+		if val < 0 || val > 1 {
+			return errors.New("short_weight must be between 0 and 1")
+		}
+		m.shortWeight = val
+	}
+
+	if val, ok := config.AdditionalParams["flat_band"]; ok {
+		if val < 0 || val >= 1 {
+			return errors.New("flat_band must be at least 0 and below 1")
+		}
+		m.flatBand = val
+	}
+
+	if val, ok := config.AdditionalParams["target_volatility"]; ok {
+		if val <= 0 {
+			return errors.New("target_volatility must be positive")
+		}
+		m.targetVol = val
+	}
+
+	if val, ok := config.AdditionalParams["vol_window"]; ok {
+		if val < 2 {
+			return errors.New("vol_window must be at least 2")
+		}
+		m.volWindow = int(val)
+	}
+
+	if val, ok := config.AdditionalParams["max_leverage"]; ok {
+		if val <= 0 {
+			return errors.New("max_leverage must be positive")
+		}
+		m.maxLeverage = val
+	}
+
+	if val, ok := config.AdditionalParams["periods_per_year"]; ok {
+		if val < 1 {
+			return errors.New("periods_per_year must be at least 1")
+		}
+		m.periodsPerYear = val
+	}
+
+	return nil
+}
+
+// RequiredHistory returns the bars needed for the longest momentum window
+// and the volatility estimate
+func (m *TSMOMAlgorithm) RequiredHistory() int {
+	return maxInt(m.BaseAlgorithm.RequiredHistory(), m.lookback+1, m.shortWindow+1, m.volWindow+1)
+}
+
+// Process signals long, short or flat for symbol and reports the
+// volatility-scaled weight under Weights
+func (m *TSMOMAlgorithm) Process(
+	symbol string,
+	currentData *types.MarketData,
+	historicalData []types.MarketData,
+) (*AlgorithmResult, error) {
+	s, err := m.signal(appendCurrent(historicalData, currentData))
+	if err != nil {
+		return nil, err
+	}
+	result := m.result(symbol, s, s.weight)
+	m.explanation = result.Explanation
+	return result, nil
+}
+
+// ProcessPortfolio signals every symbol of a basket together. Each symbol
+// gets an equal share of the risk budget, its volatility-scaled weight
+// divided by the number of symbols, and the basket is scaled down when its
+// gross exposure exceeds max_leverage.
+func (m *TSMOMAlgorithm) ProcessPortfolio(histories map[string][]types.MarketData) (*PortfolioResult, error) {
+	symbols := make([]string, 0, len(histories))
+	for symbol := range histories {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	signals := make(map[string]tsmomSignal, len(symbols))
+	skipped := make(map[string]string)
+	for _, symbol := range symbols {
+		s, err := m.signal(histories[symbol])
+		if err != nil {
+			skipped[symbol] = err.Error()
+			continue
+		}
+		signals[symbol] = s
+	}
+	if len(signals) == 0 {
+		return nil, errors.New("no symbol in the basket has enough history for a momentum signal")
+	}
+
+	weights := make(map[string]float64, len(signals))
+	gross := 0.0
+	for symbol, s := range signals {
+		weights[symbol] = s.weight / float64(len(signals))
+		gross += math.Abs(weights[symbol])
+	}
+	scale := 1.0
+	if gross > m.maxLeverage {
+		scale = m.maxLeverage / gross
+	}
+
+	out := &PortfolioResult{
+		Weights: make(map[string]float64, len(weights)),
+		Results: make(map[string]*AlgorithmResult, len(signals)),
+		Skipped: skipped,
+	}
+	var long, short, flat []string
+	for _, symbol := range symbols {
+		s, ok := signals[symbol]
+		if !ok {
+			continue
+		}
+		w := weights[symbol] * scale
+		out.Weights[symbol] = w
+		out.GrossExposure += math.Abs(w)
+		out.NetExposure += w
+		out.Results[symbol] = m.result(symbol, s, w)
+		switch {
+		case w > 0:
+			long = append(long, symbol)
+		case w < 0:
+			short = append(short, symbol)
+		default:
+			flat = append(flat, symbol)
+		}
+	}
+	out.Explanation = fmt.Sprintf("Time-series momentum over %d symbols: long %s, short %s, flat %s; gross exposure %.2f, net %.2f.",
+		len(signals), listOrNone(long), listOrNone(short), listOrNone(flat), out.GrossExposure, out.NetExposure)
+	if scale < 1 {
+		out.Explanation += fmt.Sprintf(" Weights were scaled by %.2f to keep gross exposure within %.2f.", scale, m.maxLeverage)
+	}
+	if len(skipped) > 0 {
+		out.Explanation += fmt.Sprintf(" Skipped %d symbols without enough history.", len(skipped))
+	}
+	m.explanation = out.Explanation
+	return out, nil
+}
+
+// signal computes the momentum position of one symbol's history
+func (m *TSMOMAlgorithm) signal(history []types.MarketData) (tsmomSignal, error) {
+	if len(history) < m.RequiredHistory() {
+		return tsmomSignal{}, fmt.Errorf("insufficient historical data: got %d, need at least %d",
+			len(history), m.RequiredHistory())
+	}
+	prices := make([]float64, len(history))
+	for i, d := range history {
+		if d.Price <= 0 {
+			return tsmomSignal{}, errors.New("prices must be positive")
+		}
+		prices[i] = d.Price
+	}
+	last := len(prices) - 1
+
+	var s tsmomSignal
+	s.momentum = prices[last-m.skip]/prices[last-m.lookback] - 1
+	s.score = sign(s.momentum)
+	if m.shortWindow > 0 {
+		s.shortMomentum = prices[last]/prices[last-m.shortWindow] - 1
+		s.score = (1-m.shortWeight)*s.score + m.shortWeight*sign(s.shortMomentum)
+	}
+	if math.Abs(s.score) <= m.flatBand {
+		s.score = 0
+	}
+
+	daily, estimator, err := m.estimateVolatility(history, m.volWindow, func(closes []float64) (float64, error) {
+		return DailyVolatility(closes, m.volWindow)
+	}, "ewma")
+	if err != nil {
+		return tsmomSignal{}, fmt.Errorf("error calculating volatility: %v", err)
+	}
+	if daily <= 0 {
+		return tsmomSignal{}, errors.New("prices have no volatility to scale by")
+	}
+	s.estimator = estimator
+	s.volatility = daily * math.Sqrt(m.periodsPerYear)
+	s.weight = math.Max(-m.maxLeverage, math.Min(m.maxLeverage, s.score*m.targetVol/s.volatility))
+	s.tStat = math.Log(1+s.momentum) / (daily * math.Sqrt(float64(m.lookback-m.skip)))
+	return s, nil
+}
+
+// result renders s as symbol's signal, holding weight
+func (m *TSMOMAlgorithm) result(symbol string, s tsmomSignal, weight float64) *AlgorithmResult {
+	position, signal, orderType := "flat", types.SignalHold, "none"
+	switch {
+	case weight > 0:
+		position, signal, orderType = "long", types.SignalBuy, "market"
+	case weight < 0:
+		position, signal, orderType = "short", types.SignalSell, "market"
+	}
+	confidence := 0.5
+	if weight != 0 {
+		confidence += 0.45 * math.Abs(s.score) * math.Min(1, math.Abs(s.tStat)/2)
+	}
+
+	details := map[string]interface{}{
+		"position":          position,
+		"momentum":          s.momentum,
+		"score":             s.score,
+		"t_stat":            s.tStat,
+		"volatility":        s.volatility,
+		"estimator":         s.estimator,
+		"target_volatility": m.targetVol,
+		"weight":            weight,
+		"lookback":          m.lookback,
+		"skip":              m.skip,
+	}
+	explanation := fmt.Sprintf("%s returned %.1f%% over %d bars skipping the last %d", symbol, s.momentum*100, m.lookback, m.skip)
+	if m.shortWindow > 0 {
+		details["short_window"] = m.shortWindow
+		details["short_momentum"] = s.shortMomentum
+		explanation += fmt.Sprintf(" and %.1f%% over the last %d", s.shortMomentum*100, m.shortWindow)
+	}
+	explanation += fmt.Sprintf(": %s at weight %.2f, scaled from %.1f%% annualized volatility to a %.0f%% target.",
+		position, weight, s.volatility*100, m.targetVol*100)
+
+	return &AlgorithmResult{
+		Signal:      signal,
+		OrderType:   orderType,
+		Weights:     map[string]float64{symbol: weight},
+		Confidence:  confidence,
+		Explanation: explanation,
+		Details:     details,
+	}
+}
+
+// sign returns -1, 0 or 1 by the sign of x
+func sign(x float64) float64 {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return 0
+}
+
+// listOrNone joins symbols, or says none
+func listOrNone(symbols []string) string {
+	if len(symbols) == 0 {
+		return "none"
+	}
+	return strings.Join(symbols, ", ")
+}
@@ -0,0 +1,111 @@
+package algo
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+// driftHistory is n daily bars whose log price drifts by drift a bar with
+// 1% noise.
+func driftHistory(n int, drift float64, seed int64) []types.MarketData {
+	rng := rand.New(rand.NewSource(seed))
+	start := time.Date(2025, 1, 2, 21, 0, 0, 0, time.UTC)
+	history := make([]types.MarketData, n)
+	x := 0.0
+	for i := range history {
+		x += drift + 0.01*rng.NormFloat64()
+		history[i] = types.MarketData{Price: 100 * math.Exp(x), Timestamp: start.AddDate(0, 0, i)}
+	}
+	return history
+}
+
+func newTSMOM(t *testing.T, params map[string]float64) *TSMOMAlgorithm {
+	t.Helper()
+	m := &TSMOMAlgorithm{}
+	if err := m.Configure(AlgorithmConfig{AdditionalParams: params}); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestTSMOMSignals(t *testing.T) {
+	m := newTSMOM(t, nil)
+	n := m.RequiredHistory()
+
+	up, err := m.Process("UP", nil, driftHistory(n, 0.003, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	down, err := m.Process("DOWN", nil, driftHistory(n, -0.003, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if up.Signal != types.SignalBuy || down.Signal != types.SignalSell {
+		t.Fatalf("signals = %s, %s", up.Signal, down.Signal)
+	}
+	// 1% daily noise is about 16% a year; a 40% target levers it up
+	vol := up.Details["volatility"].(float64)
+	if w := up.Weights["UP"]; math.Abs(w-math.Min(2, 0.40/vol)) > 1e-12 || w <= 1 {
+		t.Errorf("weight %v at volatility %v", w, vol)
+	}
+	if down.Weights["DOWN"] >= 0 || up.Confidence <= 0.5 || up.Confidence > 0.95 {
+		t.Errorf("down weight %v, up confidence %v", down.Weights["DOWN"], up.Confidence)
+	}
+
+	// A year-long rise that reversed in the last quarter splits an even vote
+	m = newTSMOM(t, map[string]float64{"short_window": 63})
+	history := driftHistory(n, 0.004, 3)
+	for i := n - 63; i < n; i++ {
+		history[i].Price = history[n-64].Price * math.Exp(-0.004*float64(i-n+64))
+	}
+	flat, err := m.Process("MIXED", nil, history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flat.Signal != types.SignalHold || flat.Weights["MIXED"] != 0 || flat.Details["position"] != "flat" {
+		t.Errorf("mixed votes: %s at %v", flat.Signal, flat.Weights["MIXED"])
+	}
+
+	if _, err := m.Process("SHORT", nil, history[:10]); err == nil {
+		t.Error("processed too little history")
+	}
+	if err := m.Configure(AlgorithmConfig{AdditionalParams: map[string]float64{"skip": 252}}); err == nil {
+		t.Error("accepted a skip as long as the lookback")
+	}
+}
+
+func TestTSMOMPortfolio(t *testing.T) {
+	m := newTSMOM(t, map[string]float64{"max_leverage": 1})
+	n := m.RequiredHistory()
+	histories := map[string][]types.MarketData{
+		"UP":   driftHistory(n, 0.003, 1),
+		"DOWN": driftHistory(n, -0.003, 2),
+		"NEW":  driftHistory(20, 0.003, 3),
+	}
+
+	basket, err := m.ProcessPortfolio(histories)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := basket.Skipped["NEW"]; !ok || len(basket.Weights) != 2 {
+		t.Fatalf("weights %v, skipped %v", basket.Weights, basket.Skipped)
+	}
+	if basket.Weights["UP"] <= 0 || basket.Weights["DOWN"] >= 0 {
+		t.Errorf("weights = %v", basket.Weights)
+	}
+	// Each alone would hold more than 1; the basket is scaled to the cap
+	if math.Abs(basket.GrossExposure-1) > 1e-12 {
+		t.Errorf("gross exposure = %v", basket.GrossExposure)
+	}
+	if r := basket.Results["UP"]; r.Signal != types.SignalBuy || r.Weights["UP"] != basket.Weights["UP"] {
+		t.Errorf("UP result %s at %v", r.Signal, r.Weights["UP"])
+	}
+
+	if _, err := m.ProcessPortfolio(map[string][]types.MarketData{"NEW": histories["NEW"]}); err == nil {
+		t.Error("processed a basket without enough history")
+	}
+}
@@ -0,0 +1,33 @@
+package algo
+
+import (
+	"github.com/rileyseaburg/go-trader/algorithm/algo/volatility"
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+// VolatilityBars converts market data to the bars volatility estimators
+// take: the price is the close, the 24h high and low the bar's range.
+func VolatilityBars(data []types.MarketData) []volatility.Bar {
+	bars := make([]volatility.Bar, len(data))
+	for i, d := range data {
+		bars[i] = volatility.Bar{Open: d.Open, High: d.High24h, Low: d.Low24h, Close: d.Price}
+	}
+	return bars
+}
+
+// estimateVolatility estimates the volatility of the last window bars of
+// data with the configured estimator, or with fallback, the algorithm's
+// own close-based estimate, when none is configured. It returns the name
+// of the estimator used.
+func (b *BaseAlgorithm) estimateVolatility(data []types.MarketData, window int, fallback func([]float64) (float64, error), name string) (float64, string, error) {
+	if e := volatility.Estimator(b.config.Volatility); e != volatility.Default {
+		vol, used, err := volatility.Estimate(e, VolatilityBars(data), window)
+		return vol, string(used), err
+	}
+	prices := make([]float64, len(data))
+	for i, d := range data {
+		prices[i] = d.Price
+	}
+	vol, err := fallback(prices)
+	return vol, name, err
+}
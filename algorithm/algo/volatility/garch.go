@@ -0,0 +1,187 @@
+package volatility
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// MinGARCHReturns is the fewest returns a GARCH model is fitted to.
+const MinGARCHReturns = 50
+
+// maxPersistence keeps α + β below one, so the variance reverts to a
+// finite long-run level.
+const maxPersistence = 0.9999
+
+// GARCH is a GARCH(1,1) model of demeaned returns εₜ:
+//
+//	σ²ₜ = ω + α ε²ₜ₋₁ + β σ²ₜ₋₁
+//
+// Shocks raise next period's variance by α; β carries variance forward,
+// and α + β sets how slowly it reverts to ω / (1 − α − β).
+type GARCH struct {
+	Omega float64 `json:"omega"`
+	Alpha float64 `json:"alpha"`
+	Beta  float64 `json:"beta"`
+	// Mean is the average return taken out before fitting
+	Mean          float64 `json:"mean"`
+	LogLikelihood float64 `json:"log_likelihood"`
+	Observations  int     `json:"observations"`
+	// NextVariance is the variance forecast for the period after the last
+	// return fitted
+	NextVariance float64 `json:"next_variance"`
+}
+
+// Persistence is α + β.
+func (g GARCH) Persistence() float64 { return g.Alpha + g.Beta }
+
+// LongRunVariance is the level the variance reverts to.
+func (g GARCH) LongRunVariance() float64 { return g.Omega / (1 - g.Persistence()) }
+
+// Forecast returns the variance forecasts for the next h periods, the
+// first being NextVariance and each after it reverting toward the long
+// run variance at the rate α + β.
+func (g GARCH) Forecast(h int) []float64 {
+	out := make([]float64, h)
+	long, p := g.LongRunVariance(), g.Persistence()
+	for k := range out {
+		out[k] = long + math.Pow(p, float64(k))*(g.NextVariance-long)
+	}
+	return out
+}
+
+// FitGARCH fits a GARCH(1,1) model to returns by maximum likelihood under
+// normal innovations, with the variance started at the sample variance.
+func FitGARCH(returns []float64) (GARCH, error) {
+	n := len(returns)
+	if n < MinGARCHReturns {
+		return GARCH{}, fmt.Errorf("need at least %d returns to fit GARCH, have %d", MinGARCHReturns, n)
+	}
+	mean := 0.0
+	for _, r := range returns {
+		if math.IsNaN(r) || math.IsInf(r, 0) {
+			return GARCH{}, errors.New("returns must be finite")
+		}
+		mean += r
+	}
+	mean /= float64(n)
+	eps := make([]float64, n)
+	for i, r := range returns {
+		eps[i] = r - mean
+	}
+	v := sampleVariance(eps)
+	if v <= 0 {
+		return GARCH{}, errors.New("returns have no variance")
+	}
+
+	// Search unconstrained: ω is scaled by the sample variance, α + β and
+	// α's share of it go through the logistic function
+	params := func(x []float64) (omega, alpha, beta float64) {
+		p := maxPersistence * logistic(x[1])
+		alpha = p * logistic(x[2])
+		return v * math.Exp(x[0]), alpha, p - alpha
+	}
+	negLogLik := func(x []float64) float64 {
+		omega, alpha, beta := params(x)
+		ll, _ := garchLogLikelihood(eps, v, omega, alpha, beta)
+		if math.IsNaN(ll) || math.IsInf(ll, 0) {
+			return math.Inf(1)
+		}
+		return -ll
+	}
+
+	start := []float64{math.Log(0.05), logit(0.95 / maxPersistence), logit(0.05 / 0.95)}
+	x, f := nelderMead(negLogLik, start, 2000)
+	if math.IsInf(f, 1) {
+		return GARCH{}, errors.New("GARCH fit failed: no finite likelihood")
+	}
+
+	g := GARCH{Mean: mean, Observations: n}
+	g.Omega, g.Alpha, g.Beta = params(x)
+	g.LogLikelihood, g.NextVariance = garchLogLikelihood(eps, v, g.Omega, g.Alpha, g.Beta)
+	return g, nil
+}
+
+// garchLogLikelihood is the normal log likelihood of eps under the model,
+// with the variance started at v, and the variance forecast for the
+// period after.
+func garchLogLikelihood(eps []float64, v, omega, alpha, beta float64) (float64, float64) {
+	ll, variance := 0.0, v
+	for _, e := range eps {
+		ll -= 0.5 * (math.Log(2*math.Pi) + math.Log(variance) + e*e/variance)
+		variance = omega + alpha*e*e + beta*variance
+	}
+	return ll, variance
+}
+
+// nelderMead minimizes f from start with the Nelder-Mead simplex method,
+// stopping after maxIter iterations or once the simplex's values agree,
+// and returns the best point and its value.
+func nelderMead(f func([]float64) float64, start []float64, maxIter int) ([]float64, float64) {
+	dim := len(start)
+	type vertex struct {
+		x []float64
+		f float64
+	}
+	simplex := make([]vertex, dim+1)
+	for i := range simplex {
+		x := append([]float64(nil), start...)
+		if i > 0 {
+			x[i-1] += 0.5
+		}
+		simplex[i] = vertex{x, f(x)}
+	}
+	// along returns centroid + t·(centroid − worst)
+	along := func(centroid, worst []float64, t float64) vertex {
+		x := make([]float64, dim)
+		for j := range x {
+			x[j] = centroid[j] + t*(centroid[j]-worst[j])
+		}
+		return vertex{x, f(x)}
+	}
+
+	for iter := 0; iter < maxIter; iter++ {
+		sort.Slice(simplex, func(i, j int) bool { return simplex[i].f < simplex[j].f })
+		best, worst := simplex[0], simplex[dim]
+		if math.Abs(worst.f-best.f) <= 1e-10*(math.Abs(best.f)+1e-10) {
+			break
+		}
+		centroid := make([]float64, dim)
+		for _, v := range simplex[:dim] {
+			for j := range centroid {
+				centroid[j] += v.x[j] / float64(dim)
+			}
+		}
+
+		reflected := along(centroid, worst.x, 1)
+		switch {
+		case reflected.f < best.f:
+			if expanded := along(centroid, worst.x, 2); expanded.f < reflected.f {
+				simplex[dim] = expanded
+			} else {
+				simplex[dim] = reflected
+			}
+		case reflected.f < simplex[dim-1].f:
+			simplex[dim] = reflected
+		default:
+			if contracted := along(centroid, worst.x, -0.5); contracted.f < worst.f {
+				simplex[dim] = contracted
+				continue
+			}
+			// Shrink every vertex toward the best
+			for i := 1; i <= dim; i++ {
+				for j := range simplex[i].x {
+					simplex[i].x[j] = best.x[j] + 0.5*(simplex[i].x[j]-best.x[j])
+				}
+				simplex[i].f = f(simplex[i].x)
+			}
+		}
+	}
+	sort.Slice(simplex, func(i, j int) bool { return simplex[i].f < simplex[j].f })
+	return simplex[0].x, simplex[0].f
+}
+
+func logistic(x float64) float64 { return 1 / (1 + math.Exp(-x)) }
+
+func logit(p float64) float64 { return math.Log(p / (1 - p)) }
@@ -0,0 +1,69 @@
+package volatility
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// simulateGARCH draws n returns from a GARCH(1,1) process.
+func simulateGARCH(n int, omega, alpha, beta float64, seed int64) []float64 {
+	rng := rand.New(rand.NewSource(seed))
+	out := make([]float64, n)
+	variance := omega / (1 - alpha - beta)
+	for i := range out {
+		out[i] = math.Sqrt(variance) * rng.NormFloat64()
+		variance = omega + alpha*out[i]*out[i] + beta*variance
+	}
+	return out
+}
+
+func TestFitGARCHRecoversParameters(t *testing.T) {
+	returns := simulateGARCH(3000, 2e-6, 0.1, 0.85, 1)
+	g, err := FitGARCH(returns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(g.Alpha-0.1) > 0.04 || math.Abs(g.Beta-0.85) > 0.06 || g.Persistence() >= 1 {
+		t.Errorf("fit = %+v", g)
+	}
+	if long := math.Sqrt(g.LongRunVariance()); long < 0.005 || long > 0.008 {
+		t.Errorf("long run volatility = %v, want about %v", long, math.Sqrt(2e-6/0.05))
+	}
+
+	// Forecasts start at the next variance and revert to the long run
+	path := g.Forecast(500)
+	if path[0] != g.NextVariance || math.Abs(path[499]-g.LongRunVariance()) > 1e-3*g.LongRunVariance() {
+		t.Errorf("forecast from %v ends at %v, long run %v", path[0], path[499], g.LongRunVariance())
+	}
+}
+
+func TestFitGARCHNeedsData(t *testing.T) {
+	if _, err := FitGARCH(make([]float64, MinGARCHReturns-1)); err == nil {
+		t.Error("fitted too few returns")
+	}
+	if _, err := FitGARCH(make([]float64, MinGARCHReturns)); err == nil {
+		t.Error("fitted constant returns")
+	}
+}
+
+func TestEstimateGARCH(t *testing.T) {
+	returns := simulateGARCH(300, 2e-6, 0.1, 0.85, 2)
+	bars := make([]Bar, len(returns)+1)
+	bars[0].Close = 100
+	for i, r := range returns {
+		bars[i+1].Close = bars[i].Close * math.Exp(r)
+	}
+	g, err := FitGARCH(returns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The window does not limit the fit
+	vol, used, err := Estimate(GARCHForecast, bars, 20)
+	if err != nil || used != GARCHForecast || math.Abs(vol-math.Sqrt(g.NextVariance)) > 1e-9 {
+		t.Errorf("garch = %v by %s, err %v, want %v", vol, used, err, math.Sqrt(g.NextVariance))
+	}
+	if _, used, _ := Estimate(GARCHForecast, bars[:30], 20); used != CloseToClose {
+		t.Errorf("30 bars used %s", used)
+	}
+}
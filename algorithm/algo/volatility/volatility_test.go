@@ -0,0 +1,116 @@
+package volatility
+
+import (
+	"math"
+	"testing"
+)
+
+// flatBars are n bars opening at the prior close and closing where they
+// opened, trading 1% either side.
+func flatBars(n int) []Bar {
+	bars := make([]Bar, n)
+	for i := range bars {
+		bars[i] = Bar{Open: 100, High: 100 * math.Exp(0.01), Low: 100 * math.Exp(-0.01), Close: 100}
+	}
+	return bars
+}
+
+func TestEstimators(t *testing.T) {
+	bars := flatBars(30)
+	n := 20.0
+	k := 0.34 / (1.34 + (n+1)/(n-1))
+	for _, c := range []struct {
+		e    Estimator
+		want float64
+	}{
+		{Parkinson, 0.02 / math.Sqrt(4*math.Ln2)},
+		{GarmanKlass, math.Sqrt(0.5 * 0.02 * 0.02)},
+		// No gaps and no open-to-close moves leave only Rogers-Satchell
+		{YangZhang, math.Sqrt((1 - k) * 0.0002)},
+		// Closes never move
+		{CloseToClose, 0},
+		{Default, 0},
+	} {
+		got, used, err := Estimate(c.e, bars, 20)
+		if err != nil {
+			t.Fatalf("%s: %v", c.e, err)
+		}
+		if math.Abs(got-c.want) > 1e-12 {
+			t.Errorf("%s = %v, want %v", c.e, got, c.want)
+		}
+		if want := c.e; want != Default && used != want {
+			t.Errorf("%s used %s", c.e, used)
+		}
+	}
+}
+
+func TestRangeEstimatorsSeeIntradaySwings(t *testing.T) {
+	// Closes alternate 1% apart; the ranges are 4% wide
+	bars := make([]Bar, 40)
+	for i := range bars {
+		c := 100.0
+		if i%2 == 1 {
+			c = 101
+		}
+		bars[i] = Bar{Open: c, High: c * 1.02, Low: c / 1.02, Close: c}
+	}
+	cc, _, err := Estimate(CloseToClose, bars, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, _, _ := Estimate(Parkinson, bars, 20)
+	if cc < 0.009 || cc > 0.011 || pk <= cc {
+		t.Errorf("close to close %v, Parkinson %v", cc, pk)
+	}
+}
+
+func TestEstimateFallsBack(t *testing.T) {
+	bars := flatBars(10)
+	for i := range bars {
+		bars[i].Open = 0
+	}
+	if _, used, err := Estimate(YangZhang, bars, 5); err != nil || used != Parkinson {
+		t.Errorf("without opens: used %s, err %v", used, err)
+	}
+	for i := range bars {
+		bars[i].High, bars[i].Low = 0, 0
+	}
+	if _, used, err := Estimate(GarmanKlass, bars, 5); err != nil || used != CloseToClose {
+		t.Errorf("without ranges: used %s, err %v", used, err)
+	}
+	if _, _, err := Estimate(CloseToClose, bars[:2], 5); err == nil {
+		t.Error("two closes estimated")
+	}
+	if _, _, err := Estimate("atr", bars, 5); err == nil {
+		t.Error("unknown estimator accepted")
+	}
+	if _, err := Parse("yang_zhang"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestATR(t *testing.T) {
+	// Ranges of 2 around a flat close, then a gap up of 5 whose bar's
+	// range stays above the prior close
+	bars := []Bar{
+		{High: 101, Low: 99, Close: 100},
+		{High: 101, Low: 99, Close: 100},
+		{High: 101, Low: 99, Close: 100},
+		{High: 106, Low: 104, Close: 105},
+	}
+	got, err := ATR(bars, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (2 + 2 + 6) / 3.0; math.Abs(got-want) > 1e-12 {
+		t.Errorf("ATR = %v, want %v", got, want)
+	}
+	// Closes only: the moves between them
+	closes := []Bar{{Close: 100}, {Close: 102}, {Close: 101}}
+	if got, _ := ATR(closes, 2); got != 1.5 {
+		t.Errorf("close-only ATR = %v, want 1.5", got)
+	}
+	if _, err := ATR(bars, 4); err == nil {
+		t.Error("ATR without a prior close accepted")
+	}
+}
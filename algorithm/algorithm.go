@@ -1,590 +1,932 @@
-package algorithm
-
-import (
-	"context"
-	"errors"
-	"fmt"
-	"log"
-	"math"
-	"sync"
-	"time"
-
-	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
-	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
-)
-
-// Constants for signal types
-const (
-	SignalNone  = "none"
-	SignalBuy   = "buy"
-	SignalSell  = "sell"
-	SignalHold  = "hold"
-	SignalClose = "close"
-)
-
-// TradeSignal represents a trading signal from Claude
-type TradeSignal struct {
-	Symbol     string    `json:"symbol"`
-	Signal     string    `json:"signal"`      // buy, sell, hold, close
-	OrderType  string    `json:"order_type"`  // market, limit
-	LimitPrice *float64  `json:"limit_price"` // Only for limit orders
-	Timestamp  time.Time `json:"timestamp"`
-	Reasoning  string    `json:"reasoning"`
-	Confidence *float64  `json:"confidence,omitempty"` // Confidence score from 0-1, nil if not provided
-}
-
-// MarketData represents the current market data for a symbol
-type MarketData struct {
-	Symbol    string  `json:"symbol"`
-	Price     float64 `json:"price"`
-	High24h   float64 `json:"high_24h"`
-	Low24h    float64 `json:"low_24h"`
-	Volume24h float64 `json:"volume_24h"`
-	Change24h float64 `json:"change_24h"` // Percentage
-}
-
-// PositionData represents current position information
-type PositionData struct {
-	Symbol    string  `json:"symbol"`
-	Quantity  float64 `json:"quantity"`
-	AvgPrice  float64 `json:"avg_price"`
-	MarketVal float64 `json:"market_value"`
-	Profit    float64 `json:"profit"`
-	Return    float64 `json:"return"` // Percentage
-}
-
-// PortfolioData represents the current portfolio state
-type PortfolioData struct {
-	Balance     float64                 `json:"balance"`
-	Positions   map[string]PositionData `json:"positions"`
-	TotalValue  float64                 `json:"total_value"`
-	DailyPnL    float64                 `json:"daily_pnl"`
-	DailyReturn float64                 `json:"daily_return"` // Percentage
-}
-
-// ClaudeClientInterface defines the interface for the Claude client
-type ClaudeClientInterface interface {
-	GenerateTradeSignal(symbol string, marketData MarketData, portfolio PortfolioData) (*TradeSignal, error)
-}
-
-// TradingAlgorithm represents the main algorithm that processes market data and executes trades
-type TradingAlgorithm struct {
-	ctx              context.Context
-	claude           ClaudeClientInterface
-	client           *alpaca.Client
-	mdClient         *marketdata.Client
-	marketData       map[string]MarketData
-	signals          map[string]*TradeSignal
-	portfolio        PortfolioData
-	riskParameters   map[string]interface{}
-	signalCB         func(*TradeSignal)
-	tradingEnabled   bool
-	regimeMultiplier float64 // macro regime risk scalar — 1.0 means neutral
-	regimeName       string  // last regime name set by the cartography feeder
-	mu               sync.RWMutex
-}
-
-// NewTradingAlgorithm creates a new trading algorithm instance
-func NewTradingAlgorithm(ctx context.Context, claude ClaudeClientInterface, client *alpaca.Client, mdClient *marketdata.Client) *TradingAlgorithm {
-	return &TradingAlgorithm{
-		ctx:        ctx,
-		claude:     claude,
-		client:     client,
-		mdClient:   mdClient,
-		marketData: make(map[string]MarketData),
-		signals:    make(map[string]*TradeSignal),
-		portfolio: PortfolioData{
-			Positions: make(map[string]PositionData),
-		},
-		riskParameters: map[string]interface{}{
-			"max_position_size_percent": 5.0,  // Max 5% of portfolio per position
-			"max_daily_drawdown":        10.0, // Max 10% daily drawdown
-			"stop_loss_percent":         5.0,  // 5% stop loss
-			"take_profit_percent":       15.0, // 15% take profit
-			"max_trades_per_day":        10,   // Max 10 trades per day
-		},
-		tradingEnabled:   false,
-		regimeMultiplier: 1.0,
-	}
-}
-
-// SetRegimeMultiplier updates the macro-regime risk scalar applied to all
-// position sizing. Values < 1 shrink risk (defensive regimes); values > 1
-// expand it (favorable regimes). Callers (typically the cartography feeder
-// in main) are expected to clamp to a sane band.
-func (a *TradingAlgorithm) SetRegimeMultiplier(name string, m float64) {
-	if m < 0 {
-		m = 0
-	}
-	a.mu.Lock()
-	defer a.mu.Unlock()
-	a.regimeMultiplier = m
-	a.regimeName = name
-}
-
-// GetRegimeMultiplier returns the current regime name and multiplier.
-func (a *TradingAlgorithm) GetRegimeMultiplier() (string, float64) {
-	a.mu.RLock()
-	defer a.mu.RUnlock()
-	return a.regimeName, a.regimeMultiplier
-}
-
-// Start initializes the trading algorithm with the given symbols
-func (a *TradingAlgorithm) Start(symbols []string) error {
-	// Reset market data and signals
-	a.mu.Lock()
-	a.marketData = make(map[string]MarketData)
-	a.signals = make(map[string]*TradeSignal)
-	a.mu.Unlock()
-
-	// Initialize market data for each symbol
-	for _, symbol := range symbols {
-		a.marketData[symbol] = MarketData{
-			Symbol: symbol,
-		}
-	}
-
-	// Update account information
-	if err := a.updatePortfolio(); err != nil {
-		return fmt.Errorf("failed to update portfolio: %w", err)
-	}
-
-	log.Printf("Started trading algorithm with %d symbols", len(symbols))
-	a.tradingEnabled = true
-	return nil
-}
-
-// UpdateMarketData updates the market data for a symbol
-func (a *TradingAlgorithm) UpdateMarketData(symbol string, price, high24h, low24h, volume24h, change24h float64) {
-	a.mu.Lock()
-	defer a.mu.Unlock()
-
-	// Calculate change if not provided
-	currentData, exists := a.marketData[symbol]
-	if exists && change24h == 0 && currentData.Price > 0 {
-		change24h = (price - currentData.Price) / currentData.Price * 100
-	}
-
-	a.marketData[symbol] = MarketData{
-		Symbol:    symbol,
-		Price:     price,
-		High24h:   high24h,
-		Low24h:    low24h,
-		Volume24h: volume24h,
-		Change24h: change24h,
-	}
-}
-
-// GetMarketData returns the market data for a symbol
-func (a *TradingAlgorithm) GetMarketData(symbol string) MarketData {
-	a.mu.RLock()
-	defer a.mu.RUnlock()
-	return a.marketData[symbol]
-}
-
-// ProcessSymbol processes a single symbol to generate trading signals
-func (a *TradingAlgorithm) ProcessSymbol(symbol string) error {
-	if !a.tradingEnabled {
-		return errors.New("trading algorithm is not enabled")
-	}
-
-	// Check if claude client is initialized
-	if a.claude == nil {
-		log.Printf("Warning: Claude client is nil, skipping signal generation for %s", symbol)
-		// Create a default "hold" signal instead of failing
-		signal := &TradeSignal{
-			Symbol:    symbol,
-			Signal:    SignalHold,
-			OrderType: "market",
-			Timestamp: time.Now(),
-			Reasoning: "Signal generation skipped: Claude AI service not available.",
-		}
-
-		// Store the signal
-		a.mu.Lock()
-		a.signals[symbol] = signal
-		a.mu.Unlock()
-
-		// Notify callback if registered
-		if a.signalCB != nil {
-			a.signalCB(signal)
-		}
-
-		return nil
-	}
-
-	a.mu.RLock()
-	marketData, exists := a.marketData[symbol]
-	if !exists {
-		a.mu.RUnlock()
-		return fmt.Errorf("market data not found for symbol: %s", symbol)
-	}
-	portfolio := a.portfolio
-	a.mu.RUnlock()
-
-	// Generate trading signal from Claude
-	signal, err := a.claude.GenerateTradeSignal(symbol, marketData, portfolio)
-	if err != nil {
-		return fmt.Errorf("failed to generate trading signal: %w", err)
-	}
-
-	// Store the signal
-	a.mu.Lock()
-	a.signals[symbol] = signal
-	a.mu.Unlock()
-
-	// Execute the signal based on configuration
-	// In a real implementation, this would check if auto-trading is enabled
-	// and verify that the signal passes risk management checks
-	// For now, we'll just log the signal
-	log.Printf("Generated signal for %s: %s", symbol, signal.Signal)
-
-	// Notify callback if registered
-	if a.signalCB != nil {
-		a.signalCB(signal)
-	}
-
-	return nil
-}
-
-// GetSignal returns the current signal for a symbol
-func (a *TradingAlgorithm) GetSignal(symbol string) *TradeSignal {
-	a.mu.RLock()
-	defer a.mu.RUnlock()
-	return a.signals[symbol]
-}
-
-// GetAllSignals returns all current signals
-func (a *TradingAlgorithm) GetAllSignals() map[string]*TradeSignal {
-	a.mu.RLock()
-	defer a.mu.RUnlock()
-
-	// Create a copy to avoid race conditions
-	signals := make(map[string]*TradeSignal, len(a.signals))
-	for k, v := range a.signals {
-		signals[k] = v
-	}
-	return signals
-}
-
-// RegisterSignalCallback registers a callback function for new signals
-func (a *TradingAlgorithm) RegisterSignalCallback(callback func(*TradeSignal)) {
-	a.signalCB = callback
-}
-
-// updatePortfolio updates the portfolio data from Alpaca
-func (a *TradingAlgorithm) updatePortfolio() error {
-	// Get account information
-	account, err := a.client.GetAccount()
-	if err != nil {
-		return fmt.Errorf("failed to get account: %w", err)
-	}
-
-	// Get positions
-	positions, err := a.client.GetPositions()
-	if err != nil {
-		return fmt.Errorf("failed to get positions: %w", err)
-	}
-
-	// Update portfolio
-	a.mu.Lock()
-	defer a.mu.Unlock()
-
-	cashVal, _ := account.Cash.Float64()
-	equityVal, equityOk := account.Equity.Float64()
-
-	lastEquityVal, lastEquityOk := account.LastEquity.Float64()
-
-	// Calculate day change and return
-	dayChangeVal := 0.0
-	if equityOk && lastEquityOk {
-		dayChangeVal = equityVal - lastEquityVal
-	}
-	dayReturn := 0.0
-	if lastEquityVal > 0 {
-		dayReturn = dayChangeVal / lastEquityVal * 100
-	}
-
-	a.portfolio = PortfolioData{
-		Balance:     cashVal,
-		Positions:   make(map[string]PositionData),
-		TotalValue:  equityVal,
-		DailyPnL:    dayChangeVal,
-		DailyReturn: dayReturn,
-	}
-
-	// Process positions
-	for _, pos := range positions {
-		qty, _ := pos.Qty.Float64()
-		avgPrice, _ := pos.AvgEntryPrice.Float64()
-		marketValue, _ := pos.MarketValue.Float64()
-		profit, _ := pos.UnrealizedPL.Float64()
-
-		posReturn := 0.0
-		if avgPrice > 0 && qty > 0 {
-			currentPrice := marketValue / qty
-			posReturn = (currentPrice - avgPrice) / avgPrice * 100
-		}
-
-		a.portfolio.Positions[pos.Symbol] = PositionData{
-			Symbol:    pos.Symbol,
-			Quantity:  qty,
-			AvgPrice:  avgPrice,
-			MarketVal: marketValue,
-			Profit:    profit,
-			Return:    posReturn,
-		}
-	}
-
-	return nil
-}
-
-// GetPortfolio returns the current portfolio data
-func (a *TradingAlgorithm) GetPortfolio() PortfolioData {
-	a.mu.RLock()
-	defer a.mu.RUnlock()
-	return a.portfolio
-}
-
-// GetRiskParameters returns the current risk parameters
-func (a *TradingAlgorithm) GetRiskParameters() map[string]interface{} {
-	a.mu.RLock()
-	defer a.mu.RUnlock()
-
-	// Create a copy to avoid race conditions
-	params := make(map[string]interface{}, len(a.riskParameters))
-	for k, v := range a.riskParameters {
-		params[k] = v
-	}
-	return params
-}
-
-// UpdateRiskParameters updates the risk parameters
-func (a *TradingAlgorithm) UpdateRiskParameters(params map[string]interface{}) error {
-	a.mu.Lock()
-	defer a.mu.Unlock()
-
-	// Validate parameters
-	for k, v := range params {
-		switch k {
-		case "max_position_size_percent", "max_daily_drawdown", "stop_loss_percent", "take_profit_percent":
-			// These should be numeric
-			switch val := v.(type) {
-			case float64:
-				if val <= 0 {
-					return fmt.Errorf("parameter %s must be positive", k)
-				}
-			case int:
-				if val <= 0 {
-					return fmt.Errorf("parameter %s must be positive", k)
-				}
-				// Convert to float64
-				params[k] = float64(val)
-			default:
-				return fmt.Errorf("parameter %s must be numeric", k)
-			}
-		case "max_trades_per_day":
-			// This should be an integer
-			switch val := v.(type) {
-			case float64:
-				if val <= 0 || math.Floor(val) != val {
-					return fmt.Errorf("parameter %s must be a positive integer", k)
-				}
-				// Convert to int
-				params[k] = int(val)
-			case int:
-				if val <= 0 {
-					return fmt.Errorf("parameter %s must be positive", k)
-				}
-			default:
-				return fmt.Errorf("parameter %s must be an integer", k)
-			}
-		default:
-			// Unknown parameter
-			return fmt.Errorf("unknown parameter: %s", k)
-		}
-	}
-
-	// Update parameters
-	for k, v := range params {
-		a.riskParameters[k] = v
-	}
-
-	return nil
-}
-
-// ExecuteTrade executes a trade based on a signal
-func (a *TradingAlgorithm) ExecuteTrade(signal *TradeSignal) error {
-	if signal == nil {
-		return errors.New("signal is nil")
-	}
-
-	// Get current market data for the symbol
-	a.mu.RLock()
-	marketData, exists := a.marketData[signal.Symbol]
-	portfolio := a.portfolio
-	riskParams := a.riskParameters
-	a.mu.RUnlock()
-
-	if !exists {
-		return fmt.Errorf("market data not found for symbol: %s", signal.Symbol)
-	}
-
-	// Determine if we have an existing position
-	position, hasPosition := portfolio.Positions[signal.Symbol]
-
-	// Process the signal
-	var side, orderType string
-	var qty float64
-	var limitPrice float64
-
-	switch signal.Signal {
-	case SignalBuy:
-		// Only buy if we don't have a long position already
-		if hasPosition && position.Quantity > 0 {
-			log.Printf("Already have a long position in %s, skipping buy signal", signal.Symbol)
-			return nil
-		}
-		side = "buy"
-		orderType = signal.OrderType
-
-		// Handle limit price
-		if signal.LimitPrice != nil {
-			limitPrice = *signal.LimitPrice
-		}
-
-		// Calculate position size
-		maxPosSize, ok := riskParams["max_position_size_percent"].(float64)
-		if !ok {
-			maxPosSize = 5.0 // Default to 5% if not specified
-		}
-
-		// Calculate position value
-		positionValue := portfolio.TotalValue * (maxPosSize / 100.0)
-		qty = a.calculatePositionSize(positionValue, marketData.Price, true)
-
-	case SignalSell:
-		// If we have a long position, close it
-		if hasPosition && position.Quantity > 0 {
-			side = "sell"
-			orderType = signal.OrderType
-
-			// Handle limit price
-			if signal.LimitPrice != nil {
-				limitPrice = *signal.LimitPrice
-			}
-
-			qty = position.Quantity
-		} else {
-			// Otherwise, open a short position
-			side = "sell"
-			orderType = signal.OrderType
-
-			// Handle limit price
-			if signal.LimitPrice != nil {
-				limitPrice = *signal.LimitPrice
-			}
-
-			// Calculate position size
-			maxPosSize, ok := riskParams["max_position_size_percent"].(float64)
-			if !ok {
-				maxPosSize = 5.0 // Default to 5% if not specified
-			}
-
-			// Calculate position value
-			positionValue := portfolio.TotalValue * (maxPosSize / 100.0)
-			qty = a.calculatePositionSize(positionValue, marketData.Price, false)
-		}
-
-	case SignalClose:
-		// Close any existing position
-		if !hasPosition {
-			log.Printf("No position to close for %s", signal.Symbol)
-			return nil
-		}
-
-		if position.Quantity > 0 {
-			side = "sell"
-		} else {
-			side = "buy"
-		}
-		orderType = signal.OrderType
-
-		// Handle limit price
-		if signal.LimitPrice != nil {
-			limitPrice = *signal.LimitPrice
-		}
-
-		qty = math.Abs(position.Quantity)
-
-	case SignalHold:
-		// Do nothing
-		log.Printf("Hold signal for %s, no action taken", signal.Symbol)
-		return nil
-
-	default:
-		return fmt.Errorf("unknown signal type: %s", signal.Signal)
-	}
-
-	// Create order request - these will need to be adjusted to use decimal.Decimal in a real implementation
-	qtyStr := fmt.Sprintf("%.6f", qty)
-	limitPriceStr := ""
-	if limitPrice > 0 {
-		limitPriceStr = fmt.Sprintf("%.2f", limitPrice)
-	}
-
-	log.Printf("Order details: symbol=%s, side=%s, qty=%s, type=%s, limitPrice=%s",
-		signal.Symbol, side, qtyStr, orderType, limitPriceStr)
-
-	// Here we would normally place the order with Alpaca
-	// In a real implementation, this would be:
-	/*
-		req := alpaca.PlaceOrderRequest{
-			Symbol:      signal.Symbol,
-			Qty:         qtyStr,
-			Side:        alpaca.Side(side),
-			Type:        alpaca.OrderType(orderType),
-			TimeInForce: alpaca.Day,
-		}
-
-		if orderType == "limit" && limitPrice > 0 {
-			req.LimitPrice = limitPriceStr
-		}
-
-		order, err := a.client.PlaceOrder(req)
-		if err != nil {
-			return fmt.Errorf("failed to place order: %w", err)
-		}
-	*/
-
-	log.Printf("Order placed successfully for %s (%s)", signal.Symbol, side)
-	return nil
-}
-
-// calculatePositionSize calculates the position size in shares based on the position value and current price
-func (a *TradingAlgorithm) calculatePositionSize(positionValue, currentPrice float64, isBuy bool) float64 {
-	if currentPrice <= 0 {
-		log.Printf("Warning: Invalid current price %.2f, using 1.0", currentPrice)
-		currentPrice = 1.0
-	}
-
-	// Apply the macro-regime multiplier so position sizing tracks the
-	// economic-cartography reading (storm waters shrink risk; following
-	// seas expand it). Multiplier defaults to 1.0 if no feeder is wired.
-	a.mu.RLock()
-	mult := a.regimeMultiplier
-	a.mu.RUnlock()
-	if mult <= 0 {
-		mult = 1.0
-	}
-	positionValue *= mult
-
-	// Calculate position size in shares
-	qty := float64(int(positionValue / currentPrice))
-
-	// For sells (shorts), make it negative
-	if !isBuy {
-		qty = -qty
-	}
-	return qty
-}
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/rileyseaburg/go-trader/algorithm/algo/volatility"
+	"github.com/shopspring/decimal"
+)
+
+// logger tags the package's records with its module, for per-module levels.
+func logger() *slog.Logger { return slog.With("module", "algorithm") }
+
+// Constants for signal types
+const (
+	SignalNone  = "none"
+	SignalBuy   = "buy"
+	SignalSell  = "sell"
+	SignalHold  = "hold"
+	SignalClose = "close"
+)
+
+// TradeSignal represents a trading signal from Claude
+type TradeSignal struct {
+	Symbol     string     `json:"symbol"`
+	Signal     string     `json:"signal"`               // buy, sell, hold, close
+	OrderType  string     `json:"order_type"`           // market, limit, stop, stop_limit
+	LimitPrice *float64   `json:"limit_price"`          // Only for limit and stop-limit orders
+	StopPrice  *float64   `json:"stop_price,omitempty"` // Only for stop and stop-limit orders
+	Timestamp  time.Time  `json:"timestamp"`
+	Reasoning  string     `json:"reasoning"`
+	Analysis   *Analysis  `json:"analysis,omitempty"`   // structured reasoning, nil if not provided
+	Confidence *float64   `json:"confidence,omitempty"` // Confidence score from 0-1, nil if not provided
+	Source     string     `json:"source,omitempty"`     // What produced the signal: claude, system, algorithm:<type>
+	Execution  string     `json:"execution,omitempty"`  // passive (default), chase, aggressive, twap, vwap
+	Size       *TradeSize `json:"size,omitempty"`       // explicit size; nil for risk-based sizing
+	Tag        string     `json:"tag,omitempty"`        // strategy tag prefixed to client order IDs
+	// RiskReward is the expected risk and reward of the position the
+	// signal opens, nil when it opens none
+	RiskReward *RiskReward `json:"risk_reward,omitempty"`
+	// ValidUntil is when the signal expires unexecuted and GeneratedPrice
+	// the symbol's price when it was generated; see StampSignal
+	ValidUntil     *time.Time `json:"valid_until,omitempty"`
+	GeneratedPrice float64    `json:"generated_price,omitempty"`
+	// AIUsage is what generating the signal cost in Claude tokens, nil
+	// for signals Claude did not generate
+	AIUsage *AIUsage `json:"ai_usage,omitempty"`
+	// PromptVariant names the Claude prompt, chosen by the symbol's
+	// regime, the signal was generated with
+	PromptVariant string `json:"prompt_variant,omitempty"`
+}
+
+// AIUsage is the Claude tokens a signal consumed and, once priced, their
+// cost in dollars
+type AIUsage struct {
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	Estimated    bool    `json:"estimated,omitempty"` // counted from text length, not reported
+	Cost         float64 `json:"cost"`
+}
+
+// MarketData represents the current market data for a symbol
+type MarketData struct {
+	Symbol    string   `json:"symbol"`
+	Price     float64  `json:"price"`
+	High24h   float64  `json:"high_24h"`
+	Low24h    float64  `json:"low_24h"`
+	Volume24h float64  `json:"volume_24h"`
+	Change24h float64  `json:"change_24h"`         // Percentage, against PrevClose when known
+	Patterns  []string `json:"patterns,omitempty"` // Candlestick patterns on the latest bar
+	// PrevClose is the prior session's close from the daily bar cache, and
+	// ChangeSession the percentage move since the current session's open
+	PrevClose     float64 `json:"prev_close,omitempty"`
+	ChangeSession float64 `json:"change_session"`
+	// ImpliedMovePercent is the options market's expected move through
+	// the next event or expiry, when options data is available
+	ImpliedMovePercent float64 `json:"implied_move_percent,omitempty"`
+	// Breadth is the health of the wider market, when it is measured
+	Breadth *MarketBreadth `json:"breadth,omitempty"`
+	// Features are the symbol's stored features as of its latest closed
+	// bar, when a feature store is set
+	Features *Features `json:"features,omitempty"`
+	// Session is the symbol's open, range, VWAP, volume, volatility and
+	// spread this session, after its first bar
+	Session *SessionStats `json:"session,omitempty"`
+	// ContextID names the signal context this data was kept under, readable
+	// at /api/snapshot/context
+	ContextID string `json:"context_id,omitempty"`
+	// ContextToken reads the context once at /api/snapshot/context/redeem
+	// without an API token; it is never serialized
+	ContextToken string `json:"-"`
+}
+
+// PositionData represents current position information
+type PositionData struct {
+	Symbol    string  `json:"symbol"`
+	Quantity  float64 `json:"quantity"`
+	AvgPrice  float64 `json:"avg_price"`
+	MarketVal float64 `json:"market_value"`
+	Profit    float64 `json:"profit"`
+	Return    float64 `json:"return"` // Percentage
+	// CurrentPrice is the latest mark, from the broker or the ticker
+	// stream, and PricedAt when it was taken
+	CurrentPrice float64   `json:"current_price"`
+	PricedAt     time.Time `json:"priced_at"`
+}
+
+// PortfolioData represents the current portfolio state
+type PortfolioData struct {
+	Balance     float64                 `json:"balance"`
+	Positions   map[string]PositionData `json:"positions"`
+	TotalValue  float64                 `json:"total_value"`
+	DailyPnL    float64                 `json:"daily_pnl"`
+	DailyReturn float64                 `json:"daily_return"` // Percentage
+	// BuyingPower is what the broker lets new orders use; Multiplier is
+	// the account's margin multiplier, zero until the account is read
+	BuyingPower           float64 `json:"buying_power"`
+	RegTBuyingPower       float64 `json:"regt_buying_power"`
+	DaytradingBuyingPower float64 `json:"daytrading_buying_power"`
+	Multiplier            float64 `json:"multiplier"`
+	InitialMargin         float64 `json:"initial_margin"`
+	MaintenanceMargin     float64 `json:"maintenance_margin"`
+}
+
+// ClaudeClientInterface defines the interface for the Claude client
+type ClaudeClientInterface interface {
+	GenerateTradeSignal(symbol string, marketData MarketData, portfolio PortfolioData) (*TradeSignal, error)
+}
+
+// TradingAlgorithm represents the main algorithm that processes market data and executes trades
+type TradingAlgorithm struct {
+	ctx              context.Context
+	claude           ClaudeClientInterface
+	client           Broker
+	mdClient         *marketdata.Client
+	marketData       map[string]MarketData
+	signals          map[string]*TradeSignal
+	portfolio        PortfolioData
+	riskParameters   map[string]interface{}
+	signalCB         func(*TradeSignal)
+	tradingEnabled   bool
+	regimeMultiplier float64 // macro regime risk scalar — 1.0 means neutral
+	regimeName       string  // last regime name set by the cartography feeder
+	// positionScale shrinks max_position_size_percent while a de-risking
+	// policy is active — 1.0 means full size
+	positionScale       float64
+	positionScaleReason string
+	// dailyReturns holds recent daily log returns per symbol, fed by
+	// history fetches, for the portfolio volatility controller.
+	dailyReturns map[string][]float64
+	// patterns caches candlestick patterns on each symbol's latest bar
+	patterns map[string]patternCacheEntry
+	// guards vet signals before ExecuteTrade builds an order
+	guards []namedGuard
+	// guardObserver receives every decision CheckTradeGuards makes
+	guardObserver func(GuardDecision)
+	// outcomes supplies closed trade results to the cooldown rules
+	outcomes OutcomeSource
+	// barCache holds fetched bars per symbol/timeframe for the pre-market
+	// refresh and baseline computation
+	barCache map[string]barCacheEntry
+	// baselines holds the indicator baselines recomputed before each open
+	baselines map[string]SymbolBaseline
+	// timeFramePolicy chooses each baseline's signal timeframe
+	timeFramePolicy TimeFramePolicy
+	// signalContexts keeps the context each signal was generated from
+	signalContexts signalContexts
+	// portfolioAt is when the portfolio was last read from the broker
+	portfolioAt time.Time
+	// reservations holds the notional of open opening orders by order ID
+	reservations map[string]Reservation
+	// lastEquity is the prior close's equity, the base for live daily P&L
+	lastEquity float64
+	// portfolioCB receives the portfolio after every sync or live mark
+	portfolioCB func(PortfolioData, uint64)
+	// orderCB receives every order placed for a signal
+	orderCB OrderHandler
+	// slicer may work large orders as child orders over time
+	slicer OrderSlicer
+	// submitter places orders, retrying them when it takes them over
+	submitter OrderSubmitter
+	// impliedMoves looks up a symbol's options-implied move in percent
+	impliedMoves func(symbol string) (float64, bool)
+	// featureSource looks up a symbol's stored features
+	featureSource func(symbol string) (*Features, bool)
+	// sessionStats looks up a symbol's statistics for the session
+	sessionStats func(symbol string) (*SessionStats, bool)
+	// barFilter drops fetched bars unfit to use
+	barFilter BarFilter
+	// multiLegs holds the recent multi-leg orders, oldest first
+	multiLegs []MultiLegResult
+	// breadth returns the latest market breadth snapshot
+	breadth func() (MarketBreadth, bool)
+	// quotes looks up a symbol's latest bid and ask for its spread
+	quotes func(symbol string) (bid, ask float64, ok bool)
+	// sectors overrides the built-in symbol → sector map for position caps
+	sectors map[string]string
+	// capQueue holds signals refused by the position caps, oldest first
+	capQueue []QueuedSignal
+	// historyNeeds holds the bars each configured quant algorithm needs;
+	// Start preloads the largest per timeframe
+	historyNeeds map[string]historyNeed
+	// fetchLimiter paces historical bar requests under Alpaca's rate limit
+	fetchLimiter *tokenBucket
+	// fetches tracks recent chunked historical fetches for progress reports
+	fetches  []*FetchProgress
+	fetchSeq int
+	// clock overrides time.Now; replays drive it from recorded data
+	clock func() time.Time
+	// garchFits caches each symbol's GARCH fit to its cached daily bars
+	garchFits map[string]garchFit
+	garchMu   sync.Mutex
+	// cacheStats counts bar and pattern cache lookups for diagnostics
+	cacheStats map[string]*CacheStat
+	statsMu    sync.Mutex
+	// generation counts changes to signals, market data and the
+	// portfolio; every writer bumps it under mu
+	generation uint64
+	mu         sync.RWMutex
+}
+
+// NewTradingAlgorithm creates a new trading algorithm instance
+func NewTradingAlgorithm(ctx context.Context, claude ClaudeClientInterface, client *alpaca.Client, mdClient *marketdata.Client) *TradingAlgorithm {
+	a := &TradingAlgorithm{
+		ctx:        ctx,
+		claude:     claude,
+		mdClient:   mdClient,
+		marketData: make(map[string]MarketData),
+		signals:    make(map[string]*TradeSignal),
+		portfolio: PortfolioData{
+			Positions: make(map[string]PositionData),
+		},
+		riskParameters: map[string]interface{}{
+			"max_position_size_percent":    5.0,   // Max 5% of portfolio per position
+			"max_daily_drawdown":           10.0,  // Max 10% daily drawdown
+			"stop_loss_percent":            5.0,   // 5% stop loss
+			"take_profit_percent":          15.0,  // 15% take profit
+			"max_trades_per_day":           10,    // Max 10 trades per day
+			"target_annual_volatility":     0.0,   // Portfolio vol target in percent; 0 disables targeting
+			"max_open_positions":           10,    // Max concurrent open positions; 0 disables the cap
+			"max_positions_per_sector":     3,     // Max open positions per sector; 0 disables the cap
+			"queue_capped_signals":         false, // Hold capped signals until capacity frees up
+			"max_event_loss_percent":       0.5,   // Equity at risk to a position's implied move; 0 disables
+			"min_expected_r":               0.0,   // Minimum expected R multiple to open a position; 0 disables
+			"confidence_shrinkage":         0.25,  // Pull of stated confidence toward a coin flip, 0 to 1
+			"max_adv_percent":              1.0,   // Max position value as a percent of dollar ADV; 0 disables
+			"liquidity_full_adv":           5e8,   // Dollar ADV earning the full position size; 0 disables scoring
+			"liquidity_spread_bps":         10.0,  // Spreads wider than this shrink the liquidity score; 0 disables
+			"signal_ttl_minutes":           30.0,  // Minutes a signal stays executable after generation; 0 disables
+			"max_signal_deviation_percent": 2.0,   // Max price move since an opening signal was generated; 0 disables
+			"cooldown_losses":              3,     // Consecutive losses on a symbol or strategy before it cools down; 0 disables
+			"cooldown_minutes":             240.0, // Minutes a symbol or strategy cools down after its last loss
+			"cooldown_loss_percent":        2.0,   // A single loss of this percent of equity cools everything down; 0 disables
+			"global_cooldown_minutes":      120.0, // Minutes everything cools down after such a loss
+			"max_leverage":                 1.0,   // Max gross exposure, open orders included, as a multiple of equity; 0 disables
+			"garch_volatility":             false, // Volatility targeting uses each position's GARCH forecast over its realized volatility
+			"volatility_estimator":         "",    // Estimator behind volatility stops: close_to_close, parkinson, garman_klass, yang_zhang, garch; empty is an EWMA of closes
+			"sizing_method":                "",    // How opening positions are sized: percent, or atr to lose atr_risk_percent on a one-ATR move; empty is percent
+			"atr_risk_percent":             1.0,   // Percent of equity lost on a one-ATR adverse move under atr sizing
+			"atr_period":                   14,    // Daily bars in the ATR behind atr sizing
+		},
+		tradingEnabled:   false,
+		timeFramePolicy:  DefaultTimeFramePolicy(),
+		regimeMultiplier: 1.0,
+		positionScale:    1.0,
+		dailyReturns:     make(map[string][]float64),
+		patterns:         make(map[string]patternCacheEntry),
+		barCache:         make(map[string]barCacheEntry),
+		baselines:        make(map[string]SymbolBaseline),
+		sectors:          make(map[string]string),
+		historyNeeds:     make(map[string]historyNeed),
+		fetchLimiter:     newTokenBucket(alpacaRequestsPerMinute/60.0, fetchBurst),
+	}
+	if client != nil {
+		a.client = client
+	}
+	a.guards = []namedGuard{
+		{name: "freshness", guard: a.checkFreshness, dryRun: a.freshnessError, measure: a.measureFreshness},
+		{name: "position caps", guard: a.checkPositionCaps, dryRun: a.positionCapError, measure: a.measurePositionCaps},
+		{name: "risk/reward", guard: a.checkRiskReward, measure: a.measureRiskReward},
+		{name: "liquidity", guard: a.checkLiquidity, measure: a.measureLiquidity},
+		{name: "cooldown", guard: a.checkCooldown, measure: a.measureCooldown},
+	}
+	return a
+}
+
+// SetRegimeMultiplier updates the macro-regime risk scalar applied to all
+// position sizing. Values < 1 shrink risk (defensive regimes); values > 1
+// expand it (favorable regimes). Callers (typically the cartography feeder
+// in main) are expected to clamp to a sane band.
+func (a *TradingAlgorithm) SetRegimeMultiplier(name string, m float64) {
+	if m < 0 {
+		m = 0
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.regimeMultiplier = m
+	a.regimeName = name
+}
+
+// GetRegimeMultiplier returns the current regime name and multiplier.
+func (a *TradingAlgorithm) GetRegimeMultiplier() (string, float64) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.regimeName, a.regimeMultiplier
+}
+
+// Start initializes the trading algorithm with the given symbols
+func (a *TradingAlgorithm) Start(symbols []string) error {
+	// Reset market data and signals
+	a.mu.Lock()
+	a.marketData = make(map[string]MarketData)
+	a.signals = make(map[string]*TradeSignal)
+
+	// Initialize market data for each symbol
+	for _, symbol := range symbols {
+		a.marketData[symbol] = MarketData{
+			Symbol: symbol,
+		}
+	}
+	a.generation++
+	a.mu.Unlock()
+
+	// Update account information
+	if err := a.syncPortfolio(); err != nil {
+		return fmt.Errorf("failed to update portfolio: %w", err)
+	}
+
+	// Preload the lookback the quant algorithms need so they can run
+	// immediately instead of failing on insufficient history
+	a.warmStart(symbols)
+
+	logger().Info("Started trading algorithm", "symbols", len(symbols))
+	a.tradingEnabled = true
+	return nil
+}
+
+// AddSymbols starts tracking symbols alongside the current ones and
+// preloads their history, without resetting existing market data or
+// signals.
+func (a *TradingAlgorithm) AddSymbols(symbols []string) {
+	var added []string
+	a.mu.Lock()
+	for _, symbol := range symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if _, ok := a.marketData[symbol]; symbol != "" && !ok {
+			a.marketData[symbol] = MarketData{Symbol: symbol}
+			added = append(added, symbol)
+		}
+	}
+	if len(added) > 0 {
+		a.generation++
+	}
+	a.mu.Unlock()
+
+	if len(added) > 0 {
+		a.warmStart(added)
+		logger().Info("Added symbols to the trading algorithm", "symbols", added)
+	}
+}
+
+// UpdateMarketData updates the market data for a symbol
+func (a *TradingAlgorithm) UpdateMarketData(symbol string, price, high24h, low24h, volume24h, change24h float64) {
+	a.mu.Lock()
+	now := a.now()
+	a.rollDailyBarLocked(symbol, price, now)
+
+	// Measure change against the prior close and session open from the
+	// bar cache; without history the feed's own value is kept
+	prevClose, sessionOpen := a.sessionReferenceLocked(symbol, now)
+	var changeSession float64
+	if prevClose > 0 {
+		change24h = percentChange(prevClose, price)
+	}
+	if sessionOpen > 0 {
+		changeSession = percentChange(sessionOpen, price)
+	}
+
+	a.marketData[symbol] = MarketData{
+		Symbol:        symbol,
+		Price:         price,
+		High24h:       high24h,
+		Low24h:        low24h,
+		Volume24h:     volume24h,
+		Change24h:     change24h,
+		PrevClose:     prevClose,
+		ChangeSession: changeSession,
+	}
+
+	// Re-mark a held position and publish the live P&L outside the lock
+	marked := a.markPositionLocked(symbol, price, now)
+	a.generation++
+	cb, generation := a.portfolioCB, a.generation
+	var snapshot PortfolioData
+	if marked && cb != nil {
+		snapshot = a.portfolioSnapshotLocked()
+	}
+	a.mu.Unlock()
+	if marked && cb != nil {
+		cb(snapshot, generation)
+	}
+}
+
+// GetMarketData returns the market data for a symbol
+func (a *TradingAlgorithm) GetMarketData(symbol string) MarketData {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.marketData[symbol]
+}
+
+// ProcessSymbol processes a single symbol to generate trading signals
+func (a *TradingAlgorithm) ProcessSymbol(symbol string) error {
+	if !a.tradingEnabled {
+		return errors.New("trading algorithm is not enabled")
+	}
+
+	// Check if claude client is initialized
+	if a.claude == nil {
+		logger().Warn("Claude client is nil, skipping signal generation", "symbol", symbol)
+		// Create a default "hold" signal instead of failing
+		signal := &TradeSignal{
+			Symbol:    symbol,
+			Signal:    SignalHold,
+			OrderType: "market",
+			Timestamp: a.now(),
+			Reasoning: "Signal generation skipped: Claude AI service not available.",
+			Source:    "system",
+		}
+
+		// Store the signal
+		a.mu.Lock()
+		a.signals[symbol] = signal
+		a.generation++
+		a.mu.Unlock()
+
+		// Notify callback if registered
+		if a.signalCB != nil {
+			a.signalCB(signal)
+		}
+
+		return nil
+	}
+
+	// Keep the context under an ID the signal request carries, so the
+	// adapter can read exactly what the signal was generated from
+	sc, err := a.TakeSignalContext(symbol)
+	if err != nil {
+		return err
+	}
+
+	// Generate trading signal from Claude
+	signal, err := a.claude.GenerateTradeSignal(symbol, sc.MarketData, sc.Portfolio)
+	if err != nil {
+		return fmt.Errorf("failed to generate trading signal: %w", err)
+	}
+	if signal.Source == "" {
+		signal.Source = "claude"
+	}
+	signal.RiskReward = a.RiskReward(signal)
+	a.StampSignal(signal)
+
+	// Store the signal
+	a.mu.Lock()
+	a.signals[symbol] = signal
+	a.generation++
+	a.mu.Unlock()
+
+	// Execute the signal based on configuration
+	// In a real implementation, this would check if auto-trading is enabled
+	// and verify that the signal passes risk management checks
+	// For now, we'll just log the signal
+	logger().Info("Generated signal", "symbol", symbol, "signal", signal.Signal)
+
+	// Notify callback if registered
+	if a.signalCB != nil {
+		a.signalCB(signal)
+	}
+
+	return nil
+}
+
+// GetSignal returns the current signal for a symbol
+func (a *TradingAlgorithm) GetSignal(symbol string) *TradeSignal {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.signals[symbol]
+}
+
+// GetAllSignals returns all current signals
+func (a *TradingAlgorithm) GetAllSignals() map[string]*TradeSignal {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	// Create a copy to avoid race conditions
+	signals := make(map[string]*TradeSignal, len(a.signals))
+	for k, v := range a.signals {
+		signals[k] = v
+	}
+	return signals
+}
+
+// RegisterSignalCallback registers a callback function for new signals
+func (a *TradingAlgorithm) RegisterSignalCallback(callback func(*TradeSignal)) {
+	a.signalCB = callback
+}
+
+// updatePortfolio updates the portfolio data from Alpaca
+func (a *TradingAlgorithm) updatePortfolio() error {
+	// Get account information
+	account, err := a.client.GetAccount()
+	if err != nil {
+		return fmt.Errorf("failed to get account: %w", err)
+	}
+
+	// Get positions
+	positions, err := a.client.GetPositions()
+	if err != nil {
+		return fmt.Errorf("failed to get positions: %w", err)
+	}
+
+	// Update portfolio
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	cashVal, _ := account.Cash.Float64()
+	equityVal, equityOk := account.Equity.Float64()
+
+	lastEquityVal, lastEquityOk := account.LastEquity.Float64()
+
+	// Calculate day change and return
+	dayChangeVal := 0.0
+	if equityOk && lastEquityOk {
+		dayChangeVal = equityVal - lastEquityVal
+	}
+	dayReturn := 0.0
+	if lastEquityVal > 0 {
+		dayReturn = dayChangeVal / lastEquityVal * 100
+	}
+
+	a.lastEquity = lastEquityVal
+	a.portfolio = PortfolioData{
+		Balance:     cashVal,
+		Positions:   make(map[string]PositionData),
+		TotalValue:  equityVal,
+		DailyPnL:    dayChangeVal,
+		DailyReturn: dayReturn,
+	}
+	a.portfolio.BuyingPower, _ = account.BuyingPower.Float64()
+	a.portfolio.RegTBuyingPower, _ = account.RegTBuyingPower.Float64()
+	a.portfolio.DaytradingBuyingPower, _ = account.DaytradingBuyingPower.Float64()
+	a.portfolio.Multiplier, _ = account.Multiplier.Float64()
+	a.portfolio.InitialMargin, _ = account.InitialMargin.Float64()
+	a.portfolio.MaintenanceMargin, _ = account.MaintenanceMargin.Float64()
+	a.portfolioAt = a.now()
+	a.generation++
+
+	// Process positions
+	for _, pos := range positions {
+		qty, _ := pos.Qty.Float64()
+		avgPrice, _ := pos.AvgEntryPrice.Float64()
+		marketValue, _ := pos.MarketValue.Float64()
+		profit, _ := pos.UnrealizedPL.Float64()
+		lastPrice := 0.0
+		if pos.CurrentPrice != nil {
+			lastPrice, _ = pos.CurrentPrice.Float64()
+		}
+
+		posReturn := 0.0
+		if avgPrice > 0 && qty > 0 {
+			currentPrice := marketValue / qty
+			posReturn = (currentPrice - avgPrice) / avgPrice * 100
+		}
+
+		a.portfolio.Positions[pos.Symbol] = PositionData{
+			Symbol:       pos.Symbol,
+			Quantity:     qty,
+			AvgPrice:     avgPrice,
+			MarketVal:    marketValue,
+			Profit:       profit,
+			Return:       posReturn,
+			CurrentPrice: lastPrice,
+			PricedAt:     a.portfolioAt,
+		}
+	}
+
+	return nil
+}
+
+// GetPortfolio returns the current portfolio data
+func (a *TradingAlgorithm) GetPortfolio() PortfolioData {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.portfolio
+}
+
+// GetRiskParameters returns the current risk parameters
+func (a *TradingAlgorithm) GetRiskParameters() map[string]interface{} {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	// Create a copy to avoid race conditions
+	params := make(map[string]interface{}, len(a.riskParameters))
+	for k, v := range a.riskParameters {
+		params[k] = v
+	}
+	return params
+}
+
+// UpdateRiskParameters updates the risk parameters
+func (a *TradingAlgorithm) UpdateRiskParameters(params map[string]interface{}) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	// Validate parameters
+	for k, v := range params {
+		switch k {
+		case "max_position_size_percent", "max_daily_drawdown", "stop_loss_percent", "take_profit_percent", "atr_risk_percent":
+			// These should be numeric
+			switch val := v.(type) {
+			case float64:
+				if val <= 0 {
+					return fmt.Errorf("parameter %s must be positive", k)
+				}
+			case int:
+				if val <= 0 {
+					return fmt.Errorf("parameter %s must be positive", k)
+				}
+				// Convert to float64
+				params[k] = float64(val)
+			default:
+				return fmt.Errorf("parameter %s must be numeric", k)
+			}
+		case "max_trades_per_day", "atr_period":
+			// This should be an integer
+			switch val := v.(type) {
+			case float64:
+				if val <= 0 || math.Floor(val) != val {
+					return fmt.Errorf("parameter %s must be a positive integer", k)
+				}
+				// Convert to int
+				params[k] = int(val)
+			case int:
+				if val <= 0 {
+					return fmt.Errorf("parameter %s must be positive", k)
+				}
+			default:
+				return fmt.Errorf("parameter %s must be an integer", k)
+			}
+		case "max_open_positions", "max_positions_per_sector", "cooldown_losses":
+			// Zero is allowed and disables the cap
+			switch val := v.(type) {
+			case float64:
+				if val < 0 || math.Floor(val) != val {
+					return fmt.Errorf("parameter %s must be a non-negative integer", k)
+				}
+				params[k] = int(val)
+			case int:
+				if val < 0 {
+					return fmt.Errorf("parameter %s must not be negative", k)
+				}
+			default:
+				return fmt.Errorf("parameter %s must be an integer", k)
+			}
+		case "queue_capped_signals", "garch_volatility":
+			if _, ok := v.(bool); !ok {
+				return fmt.Errorf("parameter %s must be a boolean", k)
+			}
+		case "volatility_estimator":
+			name, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("parameter %s must be a string", k)
+			}
+			if _, err := volatility.Parse(name); err != nil {
+				return fmt.Errorf("parameter %s: %w", k, err)
+			}
+		case "sizing_method":
+			method, ok := v.(string)
+			if !ok {
+				return fmt.Errorf("parameter %s must be a string", k)
+			}
+			if method != "" && method != SizingPercent && method != SizingATR {
+				return fmt.Errorf("parameter %s must be %q or %q", k, SizingPercent, SizingATR)
+			}
+		case "target_annual_volatility", "max_event_loss_percent", "min_expected_r",
+			"max_adv_percent", "liquidity_full_adv", "liquidity_spread_bps",
+			"signal_ttl_minutes", "max_signal_deviation_percent",
+			"cooldown_minutes", "cooldown_loss_percent", "global_cooldown_minutes", "max_leverage":
+			// Zero is allowed and switches the control off
+			switch val := v.(type) {
+			case float64:
+				if val < 0 {
+					return fmt.Errorf("parameter %s must not be negative", k)
+				}
+			case int:
+				if val < 0 {
+					return fmt.Errorf("parameter %s must not be negative", k)
+				}
+				params[k] = float64(val)
+			default:
+				return fmt.Errorf("parameter %s must be numeric", k)
+			}
+		case "confidence_shrinkage":
+			switch val := v.(type) {
+			case float64:
+				if val < 0 || val > 1 {
+					return fmt.Errorf("parameter %s must be between 0 and 1", k)
+				}
+			case int:
+				if val < 0 || val > 1 {
+					return fmt.Errorf("parameter %s must be between 0 and 1", k)
+				}
+				params[k] = float64(val)
+			default:
+				return fmt.Errorf("parameter %s must be numeric", k)
+			}
+		default:
+			// Unknown parameter
+			return fmt.Errorf("unknown parameter: %s", k)
+		}
+	}
+
+	// Update parameters
+	for k, v := range params {
+		a.riskParameters[k] = v
+	}
+
+	return nil
+}
+
+// ExecuteTrade executes a trade based on a signal. With dryRun set, all
+// sizing and pricing still runs but the resulting order is returned as a
+// preview instead of being submitted. A nil preview with a nil error means
+// the signal required no order (hold, or nothing to close).
+func (a *TradingAlgorithm) ExecuteTrade(signal *TradeSignal, dryRun bool) (*OrderPreview, error) {
+	if signal == nil {
+		return nil, errors.New("signal is nil")
+	}
+	execution, err := NormalizeExecution(signal.Execution)
+	if err != nil {
+		return nil, err
+	}
+	signal.Execution = execution
+	if signal.Signal != SignalHold {
+		if err := a.CheckTradeGuards(signal); err != nil {
+			return nil, err
+		}
+	}
+
+	// Get current market data for the symbol
+	a.mu.RLock()
+	marketData, exists := a.marketData[signal.Symbol]
+	portfolio := a.portfolio
+	riskParams := a.sizingParamsLocked()
+	a.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("market data not found for symbol: %s", signal.Symbol)
+	}
+
+	preview, err := a.buildOrder(signal, marketData.Price, portfolio, riskParams)
+	if preview == nil || err != nil {
+		return nil, err
+	}
+	return a.submitPreview(signal, preview, dryRun)
+}
+
+// submitPreview slices or submits the order preview built for signal, or
+// returns it untouched with dryRun set.
+func (a *TradingAlgorithm) submitPreview(signal *TradeSignal, preview *OrderPreview, dryRun bool) (*OrderPreview, error) {
+	preview.DryRun = dryRun
+
+	req := preview.Request
+	logger().Debug("Order details", "symbol", signal.Symbol, "side", req.Side, "qty", req.Qty.String(),
+		"type", req.Type, "limit_price", req.LimitPrice, "estimated_cost", preview.EstimatedCost.String())
+
+	if dryRun {
+		return preview, nil
+	}
+
+	a.mu.RLock()
+	client := a.client
+	a.mu.RUnlock()
+	if client == nil {
+		return nil, errors.New("alpaca client not configured")
+	}
+
+	// Large orders are worked as child orders over time instead
+	if parentID, sliced, err := a.SliceOrder(signal, preview); err != nil {
+		return nil, fmt.Errorf("failed to slice order: %w", err)
+	} else if sliced {
+		preview.Submitted = true
+		preview.ParentID = parentID
+		logger().Info("Order sliced", "symbol", signal.Symbol, "parent", parentID)
+		return preview, nil
+	}
+
+	order, queued, err := a.SubmitOrder(req, func(order *alpaca.Order) { a.TrackOrder(signal, order) })
+	if err != nil {
+		return nil, fmt.Errorf("failed to place order: %w", err)
+	}
+	if queued {
+		preview.Queued = true
+		logger().Warn("Order queued for retry", "symbol", signal.Symbol, "side", req.Side)
+		return preview, nil
+	}
+	preview.Submitted = true
+	preview.OrderID = order.ID
+
+	logger().Info("Order placed", "symbol", signal.Symbol, "side", req.Side)
+	a.TrackOrder(signal, order)
+	return preview, nil
+}
+
+// buildOrder sizes and prices the order for signal at price against the
+// given portfolio and risk parameters. It never contacts the broker. A nil
+// preview with a nil error means the signal required no order.
+func (a *TradingAlgorithm) buildOrder(signal *TradeSignal, price float64, portfolio PortfolioData, riskParams map[string]interface{}) (*OrderPreview, error) {
+	// The position held decides the side and whether the order opens or
+	// closes
+	held := portfolio.Positions[signal.Symbol].Quantity
+	if signal.Signal == SignalBuy && held > 0 {
+		logger().Info("Already long, skipping buy signal", "symbol", signal.Symbol)
+		return nil, nil
+	}
+	intent, ok, err := ResolveIntent(signal.Signal, held)
+	if errors.Is(err, ErrNoPosition) {
+		logger().Info("No position to close", "symbol", signal.Symbol)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		logger().Debug("Hold signal, no action taken", "symbol", signal.Symbol)
+		return nil, nil
+	}
+
+	// Sizing works in decimal; Alpaca expects a positive quantity and the
+	// direction is carried by the side
+	priceDecimal := decimal.NewFromFloat(price)
+	equity := decimal.NewFromFloat(portfolio.TotalValue)
+
+	side := string(intent.Side)
+	var qty decimal.Decimal
+	var limitPrice float64
+	if signal.LimitPrice != nil {
+		limitPrice = *signal.LimitPrice
+	}
+	// opening orders must fit the buying power and leverage limit
+	opening := intent.Opens()
+
+	switch {
+	case !opening:
+		// Close the position, or reduce it by an explicit size; a close
+		// signal always flattens
+		qty = decimal.NewFromFloat(intent.Reduces)
+		if signal.Size != nil && signal.Signal != SignalClose {
+			if qty, err = a.sizeTrade(signal, priceDecimal, equity, qty, riskParams); err != nil {
+				return nil, err
+			}
+		}
+	case signal.Size != nil:
+		// An explicit size replaces the risk-based size
+		if qty, err = a.sizeTrade(signal, priceDecimal, equity, decimal.Zero, riskParams); err != nil {
+			return nil, err
+		}
+	default:
+		sized := a.riskSize(signal.Symbol, priceDecimal, equity, riskParams)
+		if !sized.Shares.IsPositive() {
+			return nil, fmt.Errorf("position size for %s is $%s, less than one share at $%s", signal.Symbol,
+				sized.Value.StringFixed(2), priceDecimal.StringFixed(2))
+		}
+		qty = sized.Shares
+	}
+
+	if err := signal.ValidateOrder(); err != nil {
+		return nil, err
+	}
+	orderType := signal.OrderType
+	if opening {
+		costPrice := priceDecimal
+		if limitPrice > 0 {
+			costPrice = decimal.NewFromFloat(limitPrice)
+		}
+		a.mu.RLock()
+		exposure := a.exposureLocked(portfolio, riskParams)
+		a.mu.RUnlock()
+		if qty, err = fitExposure(signal, costPrice, qty, exposure); err != nil {
+			return nil, err
+		}
+	}
+	if !qty.IsPositive() {
+		return nil, fmt.Errorf("order for %s has no quantity", signal.Symbol)
+	}
+	if IsStopOrder(orderType) {
+		if err := CheckStopPrices(side, *signal.StopPrice, limitPrice, price); err != nil {
+			return nil, err
+		}
+	}
+
+	// Build the broker payload
+	qty = qty.Round(6)
+	req := alpaca.PlaceOrderRequest{
+		Symbol:        signal.Symbol,
+		Qty:           &qty,
+		Side:          intent.Side,
+		Type:          alpaca.OrderType(orderType),
+		TimeInForce:   alpaca.Day,
+		ClientOrderID: NewClientOrderID(signal.OrderTag()),
+
+		PositionIntent: intent.Intent,
+	}
+
+	if (orderType == OrderTypeLimit || orderType == OrderTypeStopLimit) && limitPrice > 0 {
+		limitDecimal := decimal.NewFromFloat(limitPrice).Round(2)
+		req.LimitPrice = &limitDecimal
+	}
+	if IsStopOrder(orderType) {
+		stopDecimal := decimal.NewFromFloat(*signal.StopPrice).Round(2)
+		req.StopPrice = &stopDecimal
+	}
+
+	return NewOrderPreview(req, price), nil
+}
@@ -3,7 +3,6 @@ package algorithm
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"strings"
 
@@ -26,11 +25,7 @@ type AlgorithmExecutionResponse struct {
 }
 
 // AlgorithmMetadata represents metadata about an algorithm
-type AlgorithmMetadata struct {
-	Name                  string            `json:"name"`
-	Description           string            `json:"description"`
-	ParameterDescriptions map[string]string `json:"parameter_descriptions"`
-}
+type AlgorithmMetadata = algo.AlgorithmMetadata
 
 // HandleAlgorithmExecution handles requests to execute an algorithm
 func HandleAlgorithmExecution(w http.ResponseWriter, r *http.Request) {
@@ -70,7 +65,7 @@ func HandleAlgorithmExecution(w http.ResponseWriter, r *http.Request) {
 	// Process the data with the algorithm
 	result, err := algorithm.Process(req.Symbol, marketData, historicalData)
 	if err != nil {
-		log.Printf("Error processing algorithm: %v", err)
+		logger().Error("Failed to process algorithm", "error", err)
 
 		response := AlgorithmExecutionResponse{
 			Success: false,
@@ -127,7 +122,7 @@ func HandleAlgorithmMetadata(w http.ResponseWriter, r *http.Request) {
 	for _, algType := range algo.GetRegisteredAlgorithms() {
 		metadata, err := getAlgorithmMetadata(algType)
 		if err != nil {
-			log.Printf("Error getting metadata for %s: %v", algType, err)
+			logger().Warn("Failed to get algorithm metadata", "algorithm", algType, "error", err)
 			continue
 		}
 
@@ -145,11 +140,7 @@ func getAlgorithmMetadata(algorithmType algo.AlgorithmType) (AlgorithmMetadata,
 		return AlgorithmMetadata{}, fmt.Errorf("invalid algorithm type: %v", err)
 	}
 
-	return AlgorithmMetadata{
-		Name:                  algorithm.Name(),
-		Description:           algorithm.Description(),
-		ParameterDescriptions: algorithm.ParameterDescription(),
-	}, nil
+	return algorithm.Metadata(), nil
 }
 
 // getMarketDataForSymbol retrieves current and historical market data for a symbol
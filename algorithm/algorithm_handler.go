@@ -3,7 +3,6 @@ package algorithm
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"strconv"
 	"time"
@@ -48,12 +47,9 @@ func (h *AlgorithmHandler) RegisterRoutes(mux *http.ServeMux) {
 func (h *AlgorithmHandler) handleGetStatus(w http.ResponseWriter, r *http.Request) {
 	// Set common headers
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	// Handle OPTIONS for CORS
 	if r.Method == http.MethodOptions {
-		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		w.WriteHeader(http.StatusOK)
 		return
 	}
@@ -69,7 +65,7 @@ func (h *AlgorithmHandler) handleGetStatus(w http.ResponseWriter, r *http.Reques
 	// Return status
 	if err := json.NewEncoder(w).Encode(status); err != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-		log.Printf("Error encoding algorithm status: %v", err)
+		logger().Error("Failed to encode algorithm status", "error", err)
 		return
 	}
 }
@@ -78,12 +74,9 @@ func (h *AlgorithmHandler) handleGetStatus(w http.ResponseWriter, r *http.Reques
 func (h *AlgorithmHandler) handleStartAlgorithm(w http.ResponseWriter, r *http.Request) {
 	// Set common headers
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	// Handle OPTIONS for CORS
 	if r.Method == http.MethodOptions {
-		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		w.WriteHeader(http.StatusOK)
 		return
 	}
@@ -99,14 +92,14 @@ func (h *AlgorithmHandler) handleStartAlgorithm(w http.ResponseWriter, r *http.R
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		log.Printf("Error decoding start algorithm request: %v", err)
+		logger().Warn("Failed to decode start algorithm request", "error", err)
 		return
 	}
 
 	// Start algorithm
 	if err := h.algorithm.Start(req.Symbols); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to start algorithm: %v", err), http.StatusInternalServerError)
-		log.Printf("Error starting algorithm: %v", err)
+		logger().Error("Failed to start algorithm", "error", err)
 		return
 	}
 
@@ -116,7 +109,7 @@ func (h *AlgorithmHandler) handleStartAlgorithm(w http.ResponseWriter, r *http.R
 		"message": "Algorithm started successfully",
 		"symbols": req.Symbols,
 	}); err != nil {
-		log.Printf("Error encoding success response: %v", err)
+		logger().Error("Failed to encode response", "error", err)
 	}
 }
 
@@ -124,12 +117,9 @@ func (h *AlgorithmHandler) handleStartAlgorithm(w http.ResponseWriter, r *http.R
 func (h *AlgorithmHandler) handleStopAlgorithm(w http.ResponseWriter, r *http.Request) {
 	// Set common headers
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	// Handle OPTIONS for CORS
 	if r.Method == http.MethodOptions {
-		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		w.WriteHeader(http.StatusOK)
 		return
 	}
@@ -142,7 +132,7 @@ func (h *AlgorithmHandler) handleStopAlgorithm(w http.ResponseWriter, r *http.Re
 	// Stop algorithm
 	if err := h.algorithm.Stop(); err != nil {
 		http.Error(w, fmt.Sprintf("Failed to stop algorithm: %v", err), http.StatusInternalServerError)
-		log.Printf("Error stopping algorithm: %v", err)
+		logger().Error("Failed to stop algorithm", "error", err)
 		return
 	}
 
@@ -151,7 +141,7 @@ func (h *AlgorithmHandler) handleStopAlgorithm(w http.ResponseWriter, r *http.Re
 	if err := json.NewEncoder(w).Encode(map[string]interface{}{
 		"message": "Algorithm stopped successfully",
 	}); err != nil {
-		log.Printf("Error encoding success response: %v", err)
+		logger().Error("Failed to encode response", "error", err)
 	}
 }
 
@@ -159,12 +149,9 @@ func (h *AlgorithmHandler) handleStopAlgorithm(w http.ResponseWriter, r *http.Re
 func (h *AlgorithmHandler) handleGetHistoricalData(w http.ResponseWriter, r *http.Request) {
 	// Set common headers
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	// Handle OPTIONS for CORS
 	if r.Method == http.MethodOptions {
-		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		w.WriteHeader(http.StatusOK)
 		return
 	}
@@ -227,14 +214,14 @@ func (h *AlgorithmHandler) handleGetHistoricalData(w http.ResponseWriter, r *htt
 	data, err := h.algorithm.GetHistoricalDataV2(request)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get historical data: %v", err), http.StatusInternalServerError)
-		log.Printf("Error getting historical data: %v", err)
+		logger().Error("Failed to get historical data", "error", err)
 		return
 	}
 
 	// Return historical data
 	if err := json.NewEncoder(w).Encode(data); err != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-		log.Printf("Error encoding historical data: %v", err)
+		logger().Error("Failed to encode historical data", "error", err)
 		return
 	}
 }
@@ -243,12 +230,9 @@ func (h *AlgorithmHandler) handleGetHistoricalData(w http.ResponseWriter, r *htt
 func (h *AlgorithmHandler) handleAnalyzeData(w http.ResponseWriter, r *http.Request) {
 	// Set common headers
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	// Handle OPTIONS for CORS
 	if r.Method == http.MethodOptions {
-		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		w.WriteHeader(http.StatusOK)
 		return
 	}
@@ -310,7 +294,7 @@ func (h *AlgorithmHandler) handleAnalyzeData(w http.ResponseWriter, r *http.Requ
 	data, err := h.algorithm.GetHistoricalDataV2(request)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get historical data: %v", err), http.StatusInternalServerError)
-		log.Printf("Error getting historical data: %v", err)
+		logger().Error("Failed to get historical data", "error", err)
 		return
 	}
 
@@ -320,7 +304,7 @@ func (h *AlgorithmHandler) handleAnalyzeData(w http.ResponseWriter, r *http.Requ
 	// Return analysis
 	if err := json.NewEncoder(w).Encode(analysis); err != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-		log.Printf("Error encoding analysis: %v", err)
+		logger().Error("Failed to encode analysis", "error", err)
 		return
 	}
 }
@@ -329,12 +313,9 @@ func (h *AlgorithmHandler) handleAnalyzeData(w http.ResponseWriter, r *http.Requ
 func (h *AlgorithmHandler) handleGetRecommendations(w http.ResponseWriter, r *http.Request) {
 	// Set common headers
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	// Handle OPTIONS for CORS
 	if r.Method == http.MethodOptions {
-		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		w.WriteHeader(http.StatusOK)
 		return
 	}
@@ -360,7 +341,7 @@ func (h *AlgorithmHandler) handleGetRecommendations(w http.ResponseWriter, r *ht
 	recommendations, err := h.algorithm.RecommendTickersV2(sector, maxResults)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to get recommendations: %v", err), http.StatusInternalServerError)
-		log.Printf("Error getting recommendations: %v", err)
+		logger().Error("Failed to get recommendations", "error", err)
 		return
 	}
 
@@ -370,7 +351,7 @@ func (h *AlgorithmHandler) handleGetRecommendations(w http.ResponseWriter, r *ht
 		"recommendations": recommendations,
 	}); err != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
-		log.Printf("Error encoding recommendations: %v", err)
+		logger().Error("Failed to encode recommendations", "error", err)
 		return
 	}
 }
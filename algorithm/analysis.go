@@ -0,0 +1,31 @@
+package algorithm
+
+import "math"
+
+// Analysis is the structured reasoning behind a signal, when its source
+// gives one.
+type Analysis struct {
+	Thesis   string   `json:"thesis"`
+	KeyRisks []string `json:"key_risks"`
+	// InvalidationLevel is the price at which the thesis is wrong
+	InvalidationLevel *float64 `json:"invalidation_level,omitempty"`
+	TimeHorizon       string   `json:"time_horizon"` // intraday, swing or position
+}
+
+// invalidationStop returns the signal's invalidation level as the stop of
+// a position in direction entered at entry. It must sit on the losing
+// side of the entry, below for a long and above for a short, and no
+// further from it than a stop order may rest.
+func (s *TradeSignal) invalidationStop(direction string, entry float64) (float64, bool) {
+	if s.Analysis == nil || s.Analysis.InvalidationLevel == nil || entry <= 0 {
+		return 0, false
+	}
+	level := *s.Analysis.InvalidationLevel
+	if level <= 0 || math.Abs(level-entry)/entry > maxStopDistance {
+		return 0, false
+	}
+	if (direction == "long" && level < entry) || (direction == "short" && level > entry) {
+		return level, true
+	}
+	return 0, false
+}
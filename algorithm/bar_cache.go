@@ -0,0 +1,74 @@
+package algorithm
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// maxCachedBars bounds each symbol/timeframe series held in memory.
+const maxCachedBars = 1000
+
+// barCacheEntry is one cached symbol/timeframe series.
+type barCacheEntry struct {
+	bars      []BarData
+	fetchedAt time.Time
+}
+
+func barCacheKey(symbol, timeframe string) string { return symbol + "|" + timeframe }
+
+// cacheBars merges bars into the cache, de-duplicating on timestamp so
+// overlapping fetches extend rather than duplicate the series.
+func (a *TradingAlgorithm) cacheBars(symbol, timeframe string, bars []BarData) {
+	if len(bars) == 0 {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := barCacheKey(symbol, timeframe)
+	byTime := make(map[int64]BarData, len(a.barCache[key].bars)+len(bars))
+	for _, b := range a.barCache[key].bars {
+		byTime[b.Timestamp.UnixNano()] = b
+	}
+	for _, b := range bars {
+		byTime[b.Timestamp.UnixNano()] = b
+	}
+	merged := make([]BarData, 0, len(byTime))
+	for _, b := range byTime {
+		merged = append(merged, b)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Timestamp.Before(merged[j].Timestamp) })
+	if len(merged) > maxCachedBars {
+		merged = merged[len(merged)-maxCachedBars:]
+	}
+	a.barCache[key] = barCacheEntry{bars: merged, fetchedAt: time.Now()}
+}
+
+// CachedBars returns a copy of the cached series for symbol and timeframe
+// and when it was last refreshed. An empty slice means nothing is cached.
+func (a *TradingAlgorithm) CachedBars(symbol, timeframe string) ([]BarData, time.Time) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	entry := a.barCache[barCacheKey(symbol, timeframe)]
+	return append([]BarData(nil), entry.bars...), entry.fetchedAt
+}
+
+// RefreshHistoricalCache fetches lookbackDays of daily bars for every
+// symbol into the cache. The returned map holds per-symbol failures.
+func (a *TradingAlgorithm) RefreshHistoricalCache(symbols []string, lookbackDays int) map[string]error {
+	failures := make(map[string]error)
+	end := a.now()
+	start := end.AddDate(0, 0, -lookbackDays)
+	for _, sym := range symbols {
+		history, err := a.GetBarHistory(HistoryRequest{Symbol: sym, StartDate: start, EndDate: end, TimeFrame: "1D"})
+		if err != nil {
+			failures[sym] = err
+			continue
+		}
+		if len(history.Bars) == 0 {
+			failures[sym] = fmt.Errorf("no bars returned for %s", sym)
+		}
+	}
+	return failures
+}
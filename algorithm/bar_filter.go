@@ -0,0 +1,42 @@
+package algorithm
+
+import "github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+
+// BarFilter returns the bars of a fetched series that are fit to use,
+// in order, dropping corrupt ones.
+type BarFilter func(symbol, timeframe string, bars []BarData) []BarData
+
+// SetBarFilter sets the filter fetched bars pass through before they are
+// cached, analyzed or returned, so history, backtests and signals never
+// see the bars it rejects.
+func (a *TradingAlgorithm) SetBarFilter(fn BarFilter) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.barFilter = fn
+}
+
+// filterBars drops the bars the bar filter rejects.
+func (a *TradingAlgorithm) filterBars(symbol, timeframe string, bars []marketdata.Bar) []marketdata.Bar {
+	a.mu.RLock()
+	fn := a.barFilter
+	a.mu.RUnlock()
+	if fn == nil || len(bars) == 0 {
+		return bars
+	}
+	data := make([]BarData, len(bars))
+	for i, b := range bars {
+		data[i] = BarData{Symbol: symbol, Timestamp: b.Timestamp, Open: b.Open, High: b.High,
+			Low: b.Low, Close: b.Close, Volume: int64(b.Volume), VWAP: b.VWAP}
+	}
+	kept := make(map[int64]bool, len(bars))
+	for _, b := range fn(symbol, timeframe, data) {
+		kept[b.Timestamp.UnixNano()] = true
+	}
+	out := bars[:0:0]
+	for _, b := range bars {
+		if kept[b.Timestamp.UnixNano()] {
+			out = append(out, b)
+		}
+	}
+	return out
+}
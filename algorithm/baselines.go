@@ -0,0 +1,156 @@
+package algorithm
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+)
+
+// minBaselineBars is the fewest daily bars a baseline is computed from.
+const minBaselineBars = 20
+
+// SymbolBaseline holds slow-moving reference levels for a symbol, computed
+// from cached daily bars once a day before the open.
+type SymbolBaseline struct {
+	Symbol           string    `json:"symbol"`
+	AsOf             time.Time `json:"as_of"` // timestamp of the last bar used
+	Bars             int       `json:"bars"`
+	LastClose        float64   `json:"last_close"`
+	SMA20            float64   `json:"sma_20"`
+	SMA50            float64   `json:"sma_50,omitempty"` // zero with fewer than 50 bars
+	ATR14            float64   `json:"atr_14"`
+	RSI14            float64   `json:"rsi_14"`
+	AvgVolume20      float64   `json:"avg_volume_20"`
+	DollarVolume20   float64   `json:"dollar_volume_20"`  // average close × volume
+	AnnualVolatility float64   `json:"annual_volatility"` // percent, from daily log returns
+	// TimeFrame is the signal timeframe chosen for the symbol's liquidity
+	// and volatility; see TimeFramePolicy
+	TimeFrame string `json:"timeframe,omitempty"`
+}
+
+// ComputeBaseline derives a SymbolBaseline from daily bars in time order.
+func ComputeBaseline(symbol string, bars []BarData) (SymbolBaseline, error) {
+	if len(bars) < minBaselineBars {
+		return SymbolBaseline{}, fmt.Errorf("need at least %d daily bars for %s, have %d", minBaselineBars, symbol, len(bars))
+	}
+	n := len(bars)
+	last := bars[n-1]
+	b := SymbolBaseline{Symbol: symbol, AsOf: last.Timestamp, Bars: n, LastClose: last.Close}
+
+	closes := make([]float64, n)
+	for i, bar := range bars {
+		closes[i] = bar.Close
+	}
+	b.SMA20 = mean(closes[n-20:])
+	if n >= 50 {
+		b.SMA50 = mean(closes[n-50:])
+	}
+
+	var vol, dollars float64
+	for _, bar := range bars[n-20:] {
+		vol += float64(bar.Volume)
+		dollars += float64(bar.Volume) * bar.Close
+	}
+	b.AvgVolume20 = vol / 20
+	b.DollarVolume20 = dollars / 20
+
+	// Wilder's ATR and RSI over 14 periods
+	const period = 14
+	var atr, gain, loss float64
+	for i := n - period; i < n; i++ {
+		prev := bars[i-1].Close
+		tr := math.Max(bars[i].High-bars[i].Low, math.Max(math.Abs(bars[i].High-prev), math.Abs(bars[i].Low-prev)))
+		atr += tr
+		if d := bars[i].Close - prev; d > 0 {
+			gain += d
+		} else {
+			loss -= d
+		}
+	}
+	b.ATR14 = atr / period
+	if loss == 0 {
+		b.RSI14 = 100
+	} else {
+		b.RSI14 = 100 - 100/(1+gain/loss)
+	}
+
+	var returns []float64
+	for i := 1; i < n; i++ {
+		if closes[i-1] > 0 && closes[i] > 0 {
+			returns = append(returns, math.Log(closes[i]/closes[i-1]))
+		}
+	}
+	if len(returns) > 1 {
+		m := mean(returns)
+		var ss float64
+		for _, r := range returns {
+			ss += (r - m) * (r - m)
+		}
+		b.AnnualVolatility = math.Sqrt(ss/float64(len(returns)-1)) * math.Sqrt(tradingDaysPerYear) * 100
+	}
+	return b, nil
+}
+
+// RecomputeBaselines rebuilds baselines for symbols from the daily bar
+// cache, choosing each symbol's signal timeframe, and refreshes the
+// volatility controller's return series. The returned map holds
+// per-symbol failures.
+func (a *TradingAlgorithm) RecomputeBaselines(symbols []string) map[string]error {
+	failures := make(map[string]error)
+	for _, sym := range symbols {
+		bars, _ := a.CachedBars(sym, "1D")
+		baseline, err := ComputeBaseline(sym, bars)
+		if err != nil {
+			failures[sym] = err
+			continue
+		}
+
+		closes := make([]float64, len(bars))
+		for i, bar := range bars {
+			closes[i] = bar.Close
+		}
+		a.recordDailyCloses(sym, marketdata.OneDay, closes)
+
+		a.mu.Lock()
+		baseline.TimeFrame = a.timeFramePolicy.Choose(baseline)
+		if prev, ok := a.baselines[sym]; ok && prev.TimeFrame != baseline.TimeFrame {
+			logger().Info("Signal timeframe changed", "symbol", sym, "from", prev.TimeFrame, "to", baseline.TimeFrame,
+				"dollar_volume", baseline.DollarVolume20, "volatility", baseline.AnnualVolatility)
+		}
+		a.baselines[sym] = baseline
+		a.mu.Unlock()
+	}
+	return failures
+}
+
+// GetBaseline returns the last computed baseline for symbol.
+func (a *TradingAlgorithm) GetBaseline(symbol string) (SymbolBaseline, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	b, ok := a.baselines[symbol]
+	return b, ok
+}
+
+// GetBaselines returns all computed baselines keyed by symbol.
+func (a *TradingAlgorithm) GetBaselines() map[string]SymbolBaseline {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	out := make(map[string]SymbolBaseline, len(a.baselines))
+	for k, v := range a.baselines {
+		out[k] = v
+	}
+	return out
+}
+
+func mean(xs []float64) float64 {
+	if len(xs) == 0 {
+		return 0
+	}
+	var s float64
+	for _, x := range xs {
+		s += x
+	}
+	return s / float64(len(xs))
+}
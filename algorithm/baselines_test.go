@@ -0,0 +1,69 @@
+package algorithm
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestComputeBaseline(t *testing.T) {
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	var bars []BarData
+	for i := 0; i < 60; i++ {
+		c := 100 + float64(i)
+		bars = append(bars, BarData{
+			Timestamp: start.AddDate(0, 0, i),
+			Open:      c - 0.5,
+			High:      c + 1,
+			Low:       c - 1,
+			Close:     c,
+			Volume:    1000,
+		})
+	}
+
+	b, err := ComputeBaseline("AAPL", bars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.LastClose != 159 || b.SMA20 != 149.5 || b.SMA50 != 134.5 {
+		t.Errorf("closes/SMAs = %+v", b)
+	}
+	// Each bar's range is 2 and gaps up by 1 from the prior close
+	if math.Abs(b.ATR14-2) > 1e-9 {
+		t.Errorf("ATR14 = %v, want 2", b.ATR14)
+	}
+	if b.RSI14 != 100 || b.AvgVolume20 != 1000 {
+		t.Errorf("RSI/volume = %v / %v", b.RSI14, b.AvgVolume20)
+	}
+	if b.AnnualVolatility <= 0 {
+		t.Errorf("annual volatility = %v", b.AnnualVolatility)
+	}
+
+	if _, err := ComputeBaseline("AAPL", bars[:10]); err == nil {
+		t.Error("expected error with too few bars")
+	}
+}
+
+func TestCacheBarsMergesOverlaps(t *testing.T) {
+	a := &TradingAlgorithm{barCache: make(map[string]barCacheEntry)}
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	mk := func(from, n int) []BarData {
+		var out []BarData
+		for i := from; i < from+n; i++ {
+			out = append(out, BarData{Timestamp: start.AddDate(0, 0, i), Close: float64(i)})
+		}
+		return out
+	}
+	a.cacheBars("AAPL", "1D", mk(5, 5))
+	a.cacheBars("AAPL", "1D", mk(0, 7))
+
+	bars, fetched := a.CachedBars("AAPL", "1D")
+	if len(bars) != 10 || fetched.IsZero() {
+		t.Fatalf("cached %d bars, fetched %v", len(bars), fetched)
+	}
+	for i, b := range bars {
+		if b.Close != float64(i) {
+			t.Fatalf("bar %d close = %v, not in time order", i, b.Close)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package algorithm
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EquityPoint is one day of a hypothetical equity curve, starting at 1.
+type EquityPoint struct {
+	Date  time.Time `json:"date"`
+	Value float64   `json:"value"`
+}
+
+// BasketPerformance is an equal-weight, daily-rebalanced basket over the
+// dates every member has a close for. Percentages throughout.
+type BasketPerformance struct {
+	Start            time.Time     `json:"start"`
+	End              time.Time     `json:"end"`
+	Days             int           `json:"days"`
+	TotalReturn      float64       `json:"total_return"`
+	AnnualVolatility float64       `json:"annual_volatility"`
+	MaxDrawdown      float64       `json:"max_drawdown"`
+	Curve            []EquityPoint `json:"curve"`
+}
+
+// BasketCorrelation is a symmetric matrix of daily return correlations,
+// ordered like Symbols, ready to draw as a heatmap.
+type BasketCorrelation struct {
+	Symbols []string    `json:"symbols"`
+	Matrix  [][]float64 `json:"matrix"`
+	Average float64     `json:"average"` // mean off-diagonal correlation
+}
+
+// BasketSector is one sector's share of a basket.
+type BasketSector struct {
+	Sector  string   `json:"sector"`
+	Count   int      `json:"count"`
+	Weight  float64  `json:"weight"` // share of members, equal weight
+	Symbols []string `json:"symbols"`
+}
+
+// BasketMover is a member's move today.
+type BasketMover struct {
+	Symbol        string  `json:"symbol"`
+	Price         float64 `json:"price"`
+	ChangePercent float64 `json:"change_percent"`
+}
+
+// BasketAnalytics summarizes a basket from cached daily history so it can
+// be judged before trading it.
+type BasketAnalytics struct {
+	Symbols           []string           `json:"symbols"`
+	Missing           []string           `json:"missing,omitempty"` // no cached daily history
+	Performance       *BasketPerformance `json:"performance,omitempty"`
+	Correlation       *BasketCorrelation `json:"correlation,omitempty"`
+	Volatility        map[string]float64 `json:"volatility"` // annualized percent per symbol
+	AverageVolatility float64            `json:"average_volatility"`
+	Sectors           []BasketSector     `json:"sectors"`
+	TopMovers         []BasketMover      `json:"top_movers"` // largest moves first
+	ComputedAt        time.Time          `json:"computed_at"`
+}
+
+// maxBasketMovers bounds TopMovers.
+const maxBasketMovers = 5
+
+// BasketAnalytics computes analytics for symbols from the daily bar cache
+// and the latest streamed prices. Symbols without cached history are
+// listed in Missing and left out of the return statistics.
+func (a *TradingAlgorithm) BasketAnalytics(symbols []string) BasketAnalytics {
+	out := BasketAnalytics{
+		Volatility: make(map[string]float64),
+		Sectors:    []BasketSector{},
+		TopMovers:  []BasketMover{},
+		ComputedAt: time.Now(),
+	}
+	seen := make(map[string]bool)
+	for _, s := range symbols {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		if s != "" && !seen[s] {
+			seen[s] = true
+			out.Symbols = append(out.Symbols, s)
+		}
+	}
+	sort.Strings(out.Symbols)
+
+	// Daily closes keyed by session date
+	closes := make(map[string]map[string]float64)
+	var withHistory []string
+	for _, sym := range out.Symbols {
+		bars, _ := a.CachedBars(sym, warmStartTimeFrame)
+		if len(bars) < 2 {
+			out.Missing = append(out.Missing, sym)
+			continue
+		}
+		byDate := make(map[string]float64, len(bars))
+		series := make([]float64, 0, len(bars))
+		for _, b := range bars {
+			if b.Close > 0 {
+				byDate[b.Timestamp.Format("2006-01-02")] = b.Close
+				series = append(series, b.Close)
+			}
+		}
+		closes[sym] = byDate
+		withHistory = append(withHistory, sym)
+		out.Volatility[sym] = annualizedVolatility(logReturns(series)) * 100
+	}
+	if len(withHistory) > 0 {
+		total := 0.0
+		for _, v := range out.Volatility {
+			total += v
+		}
+		out.AverageVolatility = total / float64(len(out.Volatility))
+	}
+
+	dates, returns := alignedReturns(withHistory, closes)
+	if len(dates) > 0 {
+		out.Performance = equalWeightPerformance(dates, returns, len(withHistory))
+		out.Correlation = correlationMatrix(withHistory, returns)
+	}
+
+	out.Sectors = a.basketSectors(out.Symbols)
+	out.TopMovers = a.basketMovers(out.Symbols)
+	return out
+}
+
+// alignedReturns returns the dates (after the first) on which every symbol
+// has a close, and each symbol's simple return into those dates.
+func alignedReturns(symbols []string, closes map[string]map[string]float64) ([]time.Time, map[string][]float64) {
+	if len(symbols) == 0 {
+		return nil, nil
+	}
+	var common []string
+	for date := range closes[symbols[0]] {
+		all := true
+		for _, sym := range symbols[1:] {
+			if _, ok := closes[sym][date]; !ok {
+				all = false
+				break
+			}
+		}
+		if all {
+			common = append(common, date)
+		}
+	}
+	sort.Strings(common)
+	if len(common) < 2 {
+		return nil, nil
+	}
+
+	dates := make([]time.Time, 0, len(common)-1)
+	returns := make(map[string][]float64, len(symbols))
+	for i := 1; i < len(common); i++ {
+		d, _ := time.Parse("2006-01-02", common[i])
+		dates = append(dates, d)
+		for _, sym := range symbols {
+			prev, cur := closes[sym][common[i-1]], closes[sym][common[i]]
+			returns[sym] = append(returns[sym], cur/prev-1)
+		}
+	}
+	return dates, returns
+}
+
+// equalWeightPerformance compounds the average member return each day.
+func equalWeightPerformance(dates []time.Time, returns map[string][]float64, n int) *BasketPerformance {
+	p := &BasketPerformance{
+		Start: dates[0],
+		End:   dates[len(dates)-1],
+		Days:  len(dates),
+		Curve: make([]EquityPoint, 0, len(dates)),
+	}
+	daily := make([]float64, len(dates))
+	value, peak := 1.0, 1.0
+	for t := range dates {
+		sum := 0.0
+		for _, r := range returns {
+			sum += r[t]
+		}
+		daily[t] = sum / float64(n)
+		value *= 1 + daily[t]
+		peak = math.Max(peak, value)
+		p.MaxDrawdown = math.Max(p.MaxDrawdown, (1-value/peak)*100)
+		p.Curve = append(p.Curve, EquityPoint{Date: dates[t], Value: value})
+	}
+	p.TotalReturn = (value - 1) * 100
+	p.AnnualVolatility = annualizedVolatility(daily) * 100
+	return p
+}
+
+// correlationMatrix correlates the aligned daily returns of symbols.
+func correlationMatrix(symbols []string, returns map[string][]float64) *BasketCorrelation {
+	c := &BasketCorrelation{Symbols: symbols, Matrix: make([][]float64, len(symbols))}
+	sum, pairs := 0.0, 0
+	for i, si := range symbols {
+		c.Matrix[i] = make([]float64, len(symbols))
+		for j, sj := range symbols {
+			switch {
+			case i == j:
+				c.Matrix[i][j] = 1
+			case j < i:
+				c.Matrix[i][j] = c.Matrix[j][i]
+			default:
+				c.Matrix[i][j] = correlation(returns[si], returns[sj])
+				sum += c.Matrix[i][j]
+				pairs++
+			}
+		}
+	}
+	if pairs > 0 {
+		c.Average = sum / float64(pairs)
+	}
+	return c
+}
+
+// correlation is the Pearson correlation of two equal-length series, zero
+// when either is constant.
+func correlation(x, y []float64) float64 {
+	n := float64(len(x))
+	if n < 2 {
+		return 0
+	}
+	var mx, my float64
+	for i := range x {
+		mx += x[i]
+		my += y[i]
+	}
+	mx /= n
+	my /= n
+	var cov, vx, vy float64
+	for i := range x {
+		dx, dy := x[i]-mx, y[i]-my
+		cov += dx * dy
+		vx += dx * dx
+		vy += dy * dy
+	}
+	if vx == 0 || vy == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(vx*vy)
+}
+
+func logReturns(closes []float64) []float64 {
+	out := make([]float64, 0, len(closes))
+	for i := 1; i < len(closes); i++ {
+		out = append(out, math.Log(closes[i]/closes[i-1]))
+	}
+	return out
+}
+
+// annualizedVolatility is the sample standard deviation of daily returns
+// scaled to a year, as a fraction.
+func annualizedVolatility(returns []float64) float64 {
+	if len(returns) < 2 {
+		return 0
+	}
+	mean := 0.0
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	ss := 0.0
+	for _, r := range returns {
+		ss += (r - mean) * (r - mean)
+	}
+	return math.Sqrt(ss/float64(len(returns)-1)) * math.Sqrt(tradingDaysPerYear)
+}
+
+func (a *TradingAlgorithm) basketSectors(symbols []string) []BasketSector {
+	bySector := make(map[string]*BasketSector)
+	for _, sym := range symbols {
+		sector := a.SymbolSector(sym)
+		s, ok := bySector[sector]
+		if !ok {
+			s = &BasketSector{Sector: sector}
+			bySector[sector] = s
+		}
+		s.Count++
+		s.Symbols = append(s.Symbols, sym)
+	}
+	out := make([]BasketSector, 0, len(bySector))
+	for _, s := range bySector {
+		s.Weight = float64(s.Count) / float64(len(symbols))
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Sector < out[j].Sector
+	})
+	return out
+}
+
+// basketMovers ranks members by the size of today's move: the streamed
+// change from the prior close when there is one, otherwise the last two
+// cached closes.
+func (a *TradingAlgorithm) basketMovers(symbols []string) []BasketMover {
+	movers := make([]BasketMover, 0, len(symbols))
+	for _, sym := range symbols {
+		md := a.GetMarketData(sym)
+		if md.Price > 0 && md.PrevClose > 0 {
+			movers = append(movers, BasketMover{Symbol: sym, Price: md.Price, ChangePercent: md.Change24h})
+			continue
+		}
+		bars, _ := a.CachedBars(sym, warmStartTimeFrame)
+		if n := len(bars); n >= 2 && bars[n-2].Close > 0 {
+			movers = append(movers, BasketMover{
+				Symbol:        sym,
+				Price:         bars[n-1].Close,
+				ChangePercent: percentChange(bars[n-1].Close, bars[n-2].Close),
+			})
+		}
+	}
+	sort.Slice(movers, func(i, j int) bool {
+		return math.Abs(movers[i].ChangePercent) > math.Abs(movers[j].ChangePercent)
+	})
+	if len(movers) > maxBasketMovers {
+		movers = movers[:maxBasketMovers]
+	}
+	return movers
+}
@@ -0,0 +1,96 @@
+package algorithm
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestBasketAnalytics(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	day := func(i int) time.Time { return time.Date(2024, 5, 1+i, 20, 0, 0, 0, time.UTC) }
+	series := func(symbol string, closes ...float64) []BarData {
+		bars := make([]BarData, len(closes))
+		for i, c := range closes {
+			bars[i] = BarData{Symbol: symbol, Timestamp: day(i), Close: c}
+		}
+		return bars
+	}
+	a.cacheBars("AAA", warmStartTimeFrame, series("AAA", 100, 110, 99, 108.9))
+	a.cacheBars("BBB", warmStartTimeFrame, series("BBB", 50, 55, 49.5, 54.45))
+	// CCC has no bar on the second day, which drops out of the aligned range
+	ccc := series("CCC", 20, 0, 19, 19)
+	a.cacheBars("CCC", warmStartTimeFrame, append(ccc[:1:1], ccc[2:]...))
+	a.SetSymbolSector("AAA", "Technology")
+	a.SetSymbolSector("BBB", "Technology")
+	a.marketData["BBB"] = MarketData{Symbol: "BBB", Price: 60, PrevClose: 54.45, Change24h: 10.19}
+
+	got := a.BasketAnalytics([]string{"bbb", "AAA", "ccc", "ZZZ", "aaa"})
+
+	if len(got.Symbols) != 4 || len(got.Missing) != 1 || got.Missing[0] != "ZZZ" {
+		t.Fatalf("symbols = %v, missing = %v", got.Symbols, got.Missing)
+	}
+	p := got.Performance
+	if p == nil || p.Days != 2 || len(p.Curve) != 2 {
+		t.Fatalf("performance = %+v", p)
+	}
+	// Into day 3: AAA and BBB -1%, CCC -5%; into day 4: +10%, +10%, 0%
+	want := (1 - 7.0/300) * (1 + 20.0/300)
+	if math.Abs(p.TotalReturn-(want-1)*100) > 1e-9 || math.Abs(p.MaxDrawdown-7.0/3) > 1e-9 {
+		t.Errorf("total return = %v, drawdown = %v", p.TotalReturn, p.MaxDrawdown)
+	}
+
+	c := got.Correlation
+	if c == nil || len(c.Matrix) != 3 || math.Abs(c.Matrix[0][1]-1) > 1e-9 || c.Matrix[1][0] != c.Matrix[0][1] || c.Matrix[2][2] != 1 {
+		t.Errorf("correlation = %+v", c)
+	}
+	if got.Volatility["AAA"] <= 0 || got.AverageVolatility <= 0 {
+		t.Errorf("volatility = %v, average %v", got.Volatility, got.AverageVolatility)
+	}
+
+	if len(got.Sectors) == 0 || got.Sectors[0].Sector != "technology" || got.Sectors[0].Count != 2 || got.Sectors[0].Weight != 0.5 {
+		t.Errorf("sectors = %+v", got.Sectors)
+	}
+	if len(got.TopMovers) != 3 || got.TopMovers[0].Symbol != "BBB" || got.TopMovers[0].Price != 60 || got.TopMovers[len(got.TopMovers)-1].Symbol != "CCC" {
+		t.Errorf("movers = %+v", got.TopMovers)
+	}
+}
+
+func TestBasketWhatIf(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	day := func(i int) time.Time { return time.Date(2024, 5, 1+i, 20, 0, 0, 0, time.UTC) }
+	series := func(symbol string, closes ...float64) []BarData {
+		bars := make([]BarData, len(closes))
+		for i, c := range closes {
+			bars[i] = BarData{Symbol: symbol, Timestamp: day(i), Close: c}
+		}
+		return bars
+	}
+	a.cacheBars("AAA", warmStartTimeFrame, series("AAA", 100, 110, 99, 108.9, 110))
+	a.cacheBars("BBB", warmStartTimeFrame, series("BBB", 50, 55, 49.5, 54.45, 55))
+	a.cacheBars("CCC", warmStartTimeFrame, series("CCC", 20, 19, 21, 20, 19))
+	a.SetSymbolSector("AAA", "Technology")
+	a.SetSymbolSector("BBB", "Technology")
+	a.SetSymbolSector("CCC", "Energy")
+
+	got := a.BasketWhatIf([]string{"AAA", "CCC"}, []string{"bbb", "aaa"}, []string{"ccc", "ZZZ"})
+	if len(got.Added) != 1 || got.Added[0] != "BBB" || len(got.Removed) != 1 || len(got.Ignored) != 2 {
+		t.Fatalf("edit = %+v / %+v / %+v", got.Added, got.Removed, got.Ignored)
+	}
+	if len(got.After.Symbols) != 2 || got.After.Symbols[1] != "BBB" {
+		t.Fatalf("after = %v", got.After.Symbols)
+	}
+	c := got.Change
+	// AAA and CCC move against each other; AAA and BBB together
+	if c.AverageCorrelation == nil || *c.AverageCorrelation <= 1 || math.Abs(c.AddedCorrelation["BBB"]-1) > 1e-9 {
+		t.Errorf("correlation change = %v, added = %v", c.AverageCorrelation, c.AddedCorrelation)
+	}
+	if c.TotalReturn == nil || !c.SameWindow || c.AnnualVolatility == nil || *c.AnnualVolatility <= 0 {
+		t.Errorf("performance change = %+v", c)
+	}
+	if len(c.Sectors) != 2 || c.Sectors[0].Sector != "energy" || c.Sectors[0].CountAfter != 0 || c.Sectors[0].WeightChange != -0.5 ||
+		c.Sectors[1].CountBefore != 1 || c.Sectors[1].CountAfter != 2 {
+		t.Errorf("sectors = %+v", c.Sectors)
+	}
+}
@@ -0,0 +1,168 @@
+package algorithm
+
+import (
+	"sort"
+	"strings"
+)
+
+// BasketWhatIf is how a basket's analytics would change with a proposed
+// edit, before it is made.
+type BasketWhatIf struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	// Ignored are adds of members and removes of non-members
+	Ignored []string        `json:"ignored,omitempty"`
+	Before  BasketAnalytics `json:"before"`
+	After   BasketAnalytics `json:"after"`
+	Change  BasketChange    `json:"change"`
+}
+
+// BasketChange is after minus before for each basket-level figure.
+// Performance and correlation changes are nil when either side has none.
+type BasketChange struct {
+	AverageCorrelation *float64 `json:"average_correlation,omitempty"`
+	AverageVolatility  float64  `json:"average_volatility"`
+	TotalReturn        *float64 `json:"total_return,omitempty"`
+	AnnualVolatility   *float64 `json:"annual_volatility,omitempty"`
+	MaxDrawdown        *float64 `json:"max_drawdown,omitempty"`
+	// SameWindow reports whether both performances cover the same dates;
+	// an added member with shorter history narrows the window
+	SameWindow bool                 `json:"same_window"`
+	Sectors    []BasketSectorChange `json:"sectors"`
+	// AddedCorrelation is each added member's mean correlation with the
+	// rest of the edited basket
+	AddedCorrelation map[string]float64 `json:"added_correlation,omitempty"`
+}
+
+// BasketSectorChange is one sector's share of the basket before and after.
+type BasketSectorChange struct {
+	Sector       string  `json:"sector"`
+	CountBefore  int     `json:"count_before"`
+	CountAfter   int     `json:"count_after"`
+	WeightBefore float64 `json:"weight_before"`
+	WeightAfter  float64 `json:"weight_after"`
+	WeightChange float64 `json:"weight_change"`
+}
+
+// EditBasketSymbols applies add and remove to symbols, upper-casing them.
+// Adding a member or removing a non-member is ignored and reported.
+func EditBasketSymbols(symbols, add, remove []string) (after, added, removed, ignored []string) {
+	members := make(map[string]bool, len(symbols))
+	for _, s := range symbols {
+		if s = strings.ToUpper(strings.TrimSpace(s)); s != "" {
+			members[s] = true
+		}
+	}
+	added, removed, ignored = []string{}, []string{}, []string{}
+	for _, s := range remove {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		switch {
+		case s == "":
+		case members[s]:
+			delete(members, s)
+			removed = append(removed, s)
+		default:
+			ignored = append(ignored, s)
+		}
+	}
+	for _, s := range add {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		switch {
+		case s == "":
+		case members[s]:
+			ignored = append(ignored, s)
+		default:
+			members[s] = true
+			added = append(added, s)
+		}
+	}
+	after = make([]string, 0, len(members))
+	for s := range members {
+		after = append(after, s)
+	}
+	sort.Strings(after)
+	return after, added, removed, ignored
+}
+
+// BasketWhatIf computes analytics for symbols as they are and with the
+// proposed edit, from the daily bar cache, and the change between them.
+func (a *TradingAlgorithm) BasketWhatIf(symbols, add, remove []string) BasketWhatIf {
+	after, added, removed, ignored := EditBasketSymbols(symbols, add, remove)
+	w := BasketWhatIf{
+		Added:   added,
+		Removed: removed,
+		Before:  a.BasketAnalytics(symbols),
+		After:   a.BasketAnalytics(after),
+	}
+	if len(ignored) > 0 {
+		w.Ignored = ignored
+	}
+	w.Change = compareBaskets(w.Before, w.After, added)
+	return w
+}
+
+// compareBaskets is after minus before, with the mean correlation of each
+// added member to the others after the edit.
+func compareBaskets(before, after BasketAnalytics, added []string) BasketChange {
+	c := BasketChange{
+		AverageVolatility: after.AverageVolatility - before.AverageVolatility,
+		Sectors:           []BasketSectorChange{},
+	}
+	diff := func(x, y float64) *float64 { d := y - x; return &d }
+	if before.Correlation != nil && after.Correlation != nil {
+		c.AverageCorrelation = diff(before.Correlation.Average, after.Correlation.Average)
+	}
+	if p, q := before.Performance, after.Performance; p != nil && q != nil {
+		c.TotalReturn = diff(p.TotalReturn, q.TotalReturn)
+		c.AnnualVolatility = diff(p.AnnualVolatility, q.AnnualVolatility)
+		c.MaxDrawdown = diff(p.MaxDrawdown, q.MaxDrawdown)
+		c.SameWindow = p.Start.Equal(q.Start) && p.End.Equal(q.End) && p.Days == q.Days
+	}
+
+	bySector := make(map[string]*BasketSectorChange)
+	sector := func(name string) *BasketSectorChange {
+		s, ok := bySector[name]
+		if !ok {
+			s = &BasketSectorChange{Sector: name}
+			bySector[name] = s
+		}
+		return s
+	}
+	for _, s := range before.Sectors {
+		sc := sector(s.Sector)
+		sc.CountBefore, sc.WeightBefore = s.Count, s.Weight
+	}
+	for _, s := range after.Sectors {
+		sc := sector(s.Sector)
+		sc.CountAfter, sc.WeightAfter = s.Count, s.Weight
+	}
+	for _, s := range bySector {
+		s.WeightChange = s.WeightAfter - s.WeightBefore
+		c.Sectors = append(c.Sectors, *s)
+	}
+	sort.Slice(c.Sectors, func(i, j int) bool { return c.Sectors[i].Sector < c.Sectors[j].Sector })
+
+	if m := after.Correlation; m != nil && len(m.Symbols) > 1 {
+		index := make(map[string]int, len(m.Symbols))
+		for i, s := range m.Symbols {
+			index[s] = i
+		}
+		for _, s := range added {
+			i, ok := index[s]
+			if !ok {
+				continue
+			}
+			sum := 0.0
+			for j := range m.Symbols {
+				if j != i {
+					sum += m.Matrix[i][j]
+				}
+			}
+			if c.AddedCorrelation == nil {
+				c.AddedCorrelation = make(map[string]float64)
+			}
+			c.AddedCorrelation[s] = sum / float64(len(m.Symbols)-1)
+		}
+	}
+	return c
+}
@@ -0,0 +1,74 @@
+package algorithm
+
+import (
+	"fmt"
+	"time"
+)
+
+// Market breadth health, as reported by the breadth monitor.
+const (
+	BreadthStrong = "strong"
+	BreadthWeak   = "weak"
+)
+
+// breadthConfidenceScale is the factor applied to the confidence of quant
+// signals that go against the market's breadth.
+const breadthConfidenceScale = 0.8
+
+// MarketBreadth is the health of the wider market: how an index universe
+// moved on its latest session.
+type MarketBreadth struct {
+	Advancers      int     `json:"advancers"`
+	Decliners      int     `json:"decliners"`
+	PercentAboveMA float64 `json:"percent_above_ma"`
+	NewHighs       int     `json:"new_highs"`
+	NewLows        int     `json:"new_lows"`
+	// Score runs from -1, every measure bearish, to 1, every measure bullish
+	Score  float64   `json:"score"`
+	Health string    `json:"health"` // strong, neutral or weak
+	AsOf   time.Time `json:"as_of"`
+}
+
+// SetBreadthSource sets the lookup of the latest market breadth. Breadth
+// is passed to Claude with each symbol's market data, and quant signals
+// against it are given less confidence.
+func (a *TradingAlgorithm) SetBreadthSource(fn func() (MarketBreadth, bool)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.breadth = fn
+}
+
+// marketBreadth returns the latest breadth, nil when none is known.
+func (a *TradingAlgorithm) marketBreadth() *MarketBreadth {
+	a.mu.RLock()
+	fn := a.breadth
+	a.mu.RUnlock()
+	if fn == nil {
+		return nil
+	}
+	b, ok := fn()
+	if !ok {
+		return nil
+	}
+	return &b
+}
+
+// againstBreadth tempers signal when it buys into weak breadth or sells
+// into strong breadth, scaling its confidence and noting why.
+func againstBreadth(signal *TradeSignal, b *MarketBreadth) {
+	if b == nil {
+		return
+	}
+	switch {
+	case signal.Signal == "buy" && b.Health == BreadthWeak,
+		signal.Signal == "sell" && b.Health == BreadthStrong:
+	default:
+		return
+	}
+	if signal.Confidence != nil {
+		c := *signal.Confidence * breadthConfidenceScale
+		signal.Confidence = &c
+	}
+	signal.Reasoning += fmt.Sprintf(" Market breadth is %s (score %.2f, %d advancers to %d decliners, %.0f%% above the moving average), against this %s.",
+		b.Health, b.Score, b.Advancers, b.Decliners, b.PercentAboveMA, signal.Signal)
+}
@@ -0,0 +1,36 @@
+package algorithm
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAgainstBreadth(t *testing.T) {
+	weak := &MarketBreadth{Advancers: 5, Decliners: 25, PercentAboveMA: 20, Score: -0.6, Health: BreadthWeak}
+	confidence := 0.8
+	buy := &TradeSignal{Signal: "buy", Reasoning: "Momentum.", Confidence: &confidence}
+	againstBreadth(buy, weak)
+	if want := confidence * breadthConfidenceScale; *buy.Confidence != want || !strings.Contains(buy.Reasoning, "breadth is weak") {
+		t.Errorf("buy into weak breadth: %v, %q", *buy.Confidence, buy.Reasoning)
+	}
+	if confidence != 0.8 {
+		t.Error("caller's confidence changed")
+	}
+
+	sell := &TradeSignal{Signal: "sell", Reasoning: "Breakdown.", Confidence: &confidence}
+	againstBreadth(sell, weak)
+	againstBreadth(sell, nil)
+	if *sell.Confidence != 0.8 || sell.Reasoning != "Breakdown." {
+		t.Errorf("sell with weak breadth changed: %v, %q", *sell.Confidence, sell.Reasoning)
+	}
+
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	if a.marketBreadth() != nil {
+		t.Error("breadth without a source")
+	}
+	a.SetBreadthSource(func() (MarketBreadth, bool) { return *weak, true })
+	if b := a.marketBreadth(); b == nil || b.Health != BreadthWeak {
+		t.Errorf("breadth = %+v", b)
+	}
+}
@@ -0,0 +1,40 @@
+package algorithm
+
+import (
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+)
+
+// Broker is the part of the brokerage API the algorithm trades through.
+// *alpaca.Client satisfies it; replays swap in a simulated broker.
+type Broker interface {
+	GetAccount() (*alpaca.Account, error)
+	GetPositions() ([]alpaca.Position, error)
+	PlaceOrder(req alpaca.PlaceOrderRequest) (*alpaca.Order, error)
+}
+
+// SetBroker replaces the broker orders are placed with and the portfolio
+// is read from.
+func (a *TradingAlgorithm) SetBroker(b Broker) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.client = b
+}
+
+// SetClock replaces the clock used to stamp signals and marks, roll daily
+// bars and end history fetches, so a replay sees only data up to its
+// simulated time. Nil restores the wall clock.
+func (a *TradingAlgorithm) SetClock(now func() time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.clock = now
+}
+
+// now returns the current time on the algorithm's clock.
+func (a *TradingAlgorithm) now() time.Time {
+	if a.clock != nil {
+		return a.clock()
+	}
+	return time.Now()
+}
@@ -0,0 +1,45 @@
+package algorithm
+
+// Cache names reported by CacheStats.
+const (
+	CacheBars     = "bars"
+	CachePatterns = "patterns"
+)
+
+// CacheStat counts lookups in one of the algorithm's caches since start.
+type CacheStat struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"` // 0 to 1; zero before any lookup
+}
+
+// recordCacheLookup counts a hit or miss in the named cache.
+func (a *TradingAlgorithm) recordCacheLookup(cache string, hit bool) {
+	a.statsMu.Lock()
+	defer a.statsMu.Unlock()
+	if a.cacheStats == nil {
+		a.cacheStats = make(map[string]*CacheStat)
+	}
+	s, ok := a.cacheStats[cache]
+	if !ok {
+		s = &CacheStat{}
+		a.cacheStats[cache] = s
+	}
+	if hit {
+		s.Hits++
+	} else {
+		s.Misses++
+	}
+	s.HitRate = float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// CacheStats returns hit and miss counts for the bar and pattern caches.
+func (a *TradingAlgorithm) CacheStats() map[string]CacheStat {
+	a.statsMu.Lock()
+	defer a.statsMu.Unlock()
+	out := map[string]CacheStat{CacheBars: {}, CachePatterns: {}}
+	for name, s := range a.cacheStats {
+		out[name] = *s
+	}
+	return out
+}
@@ -0,0 +1,244 @@
+package algorithm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+)
+
+const (
+	// maxBarsPerChunk bounds how many bars one request may span, so long
+	// ranges are split into requests the provider answers in full.
+	maxBarsPerChunk = 10000
+	// alpacaRequestsPerMinute is Alpaca's basic-plan market data limit.
+	alpacaRequestsPerMinute = 200
+	// fetchBurst is how many chunk requests may go out back to back.
+	fetchBurst = 5
+	// recentFetchesKept bounds the fetch progress history.
+	recentFetchesKept = 20
+)
+
+// tokenBucket is a rate limiter: tokens refill continuously at rate per
+// second up to burst, and each request spends one.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newTokenBucket(perSecond float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: perSecond, burst: float64(burst), tokens: float64(burst), now: time.Now}
+}
+
+// reserve takes a token, returning how long the caller must wait before
+// using it.
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// Wait blocks until a request may be made or ctx is cancelled.
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	d := b.reserve()
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// FetchProgress reports the state of one chunked historical fetch.
+type FetchProgress struct {
+	ID         int       `json:"id"`
+	Symbol     string    `json:"symbol"`
+	TimeFrame  string    `json:"timeframe"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Chunks     int       `json:"chunks"`
+	ChunksDone int       `json:"chunks_done"`
+	Bars       int       `json:"bars"`
+	Done       bool      `json:"done"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// barsFetcher matches marketdata.Client.GetBars.
+type barsFetcher func(symbol string, req marketdata.GetBarsRequest) ([]marketdata.Bar, error)
+
+// barDuration is the span of one bar of tf.
+func barDuration(tf marketdata.TimeFrame) time.Duration {
+	n := time.Duration(tf.N)
+	if n <= 0 {
+		n = 1
+	}
+	switch tf.Unit {
+	case marketdata.Min:
+		return n * time.Minute
+	case marketdata.Hour:
+		return n * time.Hour
+	case marketdata.Week:
+		return n * 7 * 24 * time.Hour
+	case marketdata.Month:
+		return n * 31 * 24 * time.Hour
+	default:
+		return n * 24 * time.Hour
+	}
+}
+
+// chunkRanges splits [start, end] into consecutive ranges of at most
+// maxBarsPerChunk bars of tf. Counting every wall-clock bar, not just
+// trading hours, keeps chunks comfortably under the limit.
+func chunkRanges(tf marketdata.TimeFrame, start, end time.Time) [][2]time.Time {
+	span := barDuration(tf) * maxBarsPerChunk
+	var ranges [][2]time.Time
+	for from := start; from.Before(end); from = from.Add(span) {
+		to := from.Add(span)
+		if to.After(end) {
+			to = end
+		}
+		ranges = append(ranges, [2]time.Time{from, to})
+	}
+	if len(ranges) == 0 {
+		ranges = append(ranges, [2]time.Time{start, end})
+	}
+	return ranges
+}
+
+// stitchBars merges chunk results into one series ordered by time,
+// dropping the duplicates chunk boundaries can produce.
+func stitchBars(bars []marketdata.Bar) []marketdata.Bar {
+	seen := make(map[int64]bool, len(bars))
+	out := make([]marketdata.Bar, 0, len(bars))
+	for _, b := range bars {
+		key := b.Timestamp.UnixNano()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, b)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Timestamp.Before(out[j].Timestamp) })
+	return out
+}
+
+// fetchBarsChunked fetches bars for symbol from Alpaca, splitting long
+// ranges into rate-limited chunks and stitching the results. Bars the
+// bar filter rejects are dropped before anything sees them.
+func (a *TradingAlgorithm) fetchBarsChunked(symbol string, tf marketdata.TimeFrame, tfName string, start, end time.Time) ([]marketdata.Bar, error) {
+	if a.mdClient == nil {
+		return nil, errNoMarketData
+	}
+	bars, err := a.fetchChunks(a.mdClient.GetBars, symbol, tf, tfName, start, end)
+	if err != nil {
+		return nil, err
+	}
+	return a.filterBars(symbol, tfName, bars), nil
+}
+
+func (a *TradingAlgorithm) fetchChunks(fetch barsFetcher, symbol string, tf marketdata.TimeFrame, tfName string, start, end time.Time) ([]marketdata.Bar, error) {
+	ranges := chunkRanges(tf, start, end)
+	id := a.beginFetch(FetchProgress{Symbol: symbol, TimeFrame: tfName, Start: start, End: end, Chunks: len(ranges)})
+
+	var all []marketdata.Bar
+	for i, r := range ranges {
+		if err := a.fetchLimiter.Wait(a.ctx); err != nil {
+			a.finishFetch(id, err)
+			return nil, err
+		}
+		bars, err := fetch(symbol, marketdata.GetBarsRequest{TimeFrame: tf, Start: r[0], End: r[1]})
+		if err != nil {
+			err = fmt.Errorf("chunk %d/%d (%s to %s): %w", i+1, len(ranges),
+				r[0].Format(time.RFC3339), r[1].Format(time.RFC3339), err)
+			a.finishFetch(id, err)
+			return nil, err
+		}
+		all = append(all, bars...)
+		a.advanceFetch(id, len(bars))
+		if len(ranges) > 1 {
+			logger().Debug("Fetched chunk", "chunk", i+1, "chunks", len(ranges), "symbol", symbol, "bars", len(bars))
+		}
+	}
+	a.finishFetch(id, nil)
+	return stitchBars(all), nil
+}
+
+func (a *TradingAlgorithm) beginFetch(p FetchProgress) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.fetchSeq++
+	p.ID = a.fetchSeq
+	p.StartedAt = time.Now()
+	p.UpdatedAt = p.StartedAt
+	a.fetches = append(a.fetches, &p)
+	if len(a.fetches) > recentFetchesKept {
+		a.fetches = a.fetches[len(a.fetches)-recentFetchesKept:]
+	}
+	return p.ID
+}
+
+func (a *TradingAlgorithm) updateFetch(id int, fn func(*FetchProgress)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, p := range a.fetches {
+		if p.ID == id {
+			fn(p)
+			p.UpdatedAt = time.Now()
+			return
+		}
+	}
+}
+
+func (a *TradingAlgorithm) advanceFetch(id, bars int) {
+	a.updateFetch(id, func(p *FetchProgress) {
+		p.ChunksDone++
+		p.Bars += bars
+	})
+}
+
+func (a *TradingAlgorithm) finishFetch(id int, err error) {
+	a.updateFetch(id, func(p *FetchProgress) {
+		p.Done = true
+		if err != nil {
+			p.Error = err.Error()
+		}
+	})
+}
+
+// HistoricalFetches returns recent and in-flight historical fetches,
+// newest first.
+func (a *TradingAlgorithm) HistoricalFetches() []FetchProgress {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	out := make([]FetchProgress, 0, len(a.fetches))
+	for i := len(a.fetches) - 1; i >= 0; i-- {
+		out = append(out, *a.fetches[i])
+	}
+	return out
+}
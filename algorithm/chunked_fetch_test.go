@@ -0,0 +1,103 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+)
+
+func TestFetchChunksSplitsAndStitches(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	a.fetchLimiter = newTokenBucket(1000, 1000)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(1, 0, 0)
+	var requests int
+	fetch := func(symbol string, req marketdata.GetBarsRequest) ([]marketdata.Bar, error) {
+		requests++
+		// One bar at each chunk edge, so neighbouring chunks overlap
+		return []marketdata.Bar{{Timestamp: req.End, Close: 1}, {Timestamp: req.Start, Close: 1}}, nil
+	}
+
+	bars, err := a.fetchChunks(fetch, "SPY", marketdata.OneMin, "1Min", start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := len(chunkRanges(marketdata.OneMin, start, end))
+	if requests != want || want < 50 {
+		t.Fatalf("requests = %d, chunks = %d", requests, want)
+	}
+	if len(bars) != want+1 {
+		t.Errorf("stitched %d bars, want %d without duplicates", len(bars), want+1)
+	}
+	for i := 1; i < len(bars); i++ {
+		if !bars[i].Timestamp.After(bars[i-1].Timestamp) {
+			t.Fatalf("bars out of order at %d", i)
+		}
+	}
+
+	progress := a.HistoricalFetches()
+	if len(progress) != 1 || !progress[0].Done || progress[0].ChunksDone != want || progress[0].Error != "" {
+		t.Errorf("progress = %+v", progress)
+	}
+}
+
+func TestFetchChunksReportsFailedChunk(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	a.fetchLimiter = newTokenBucket(1000, 1000)
+	calls := 0
+	fetch := func(string, marketdata.GetBarsRequest) ([]marketdata.Bar, error) {
+		calls++
+		if calls == 2 {
+			return nil, errors.New("429 too many requests")
+		}
+		return nil, nil
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := a.fetchChunks(fetch, "SPY", marketdata.OneMin, "1Min", start, start.AddDate(0, 1, 0)); err == nil {
+		t.Fatal("expected chunk error")
+	}
+	if p := a.HistoricalFetches()[0]; p.ChunksDone != 1 || p.Error == "" {
+		t.Errorf("progress = %+v", p)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newTokenBucket(2, 2)
+	b.now = func() time.Time { return now }
+	if b.reserve() != 0 || b.reserve() != 0 {
+		t.Fatal("burst should be free")
+	}
+	if d := b.reserve(); d != 500*time.Millisecond {
+		t.Errorf("third request waits %v, want 500ms", d)
+	}
+	now = now.Add(2 * time.Second)
+	if d := b.reserve(); d != 0 {
+		t.Errorf("after refill wait = %v", d)
+	}
+}
+
+func TestFilterBarsDropsRejectedBars(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	start := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	bars := []marketdata.Bar{{Timestamp: start, Close: 10}, {Timestamp: start.AddDate(0, 0, 1), Close: -1}, {Timestamp: start.AddDate(0, 0, 2), Close: 11}}
+	if got := a.filterBars("AAPL", "1D", bars); len(got) != 3 {
+		t.Fatalf("no filter kept %d bars", len(got))
+	}
+	a.SetBarFilter(func(symbol, timeframe string, bars []BarData) []BarData {
+		var kept []BarData
+		for _, b := range bars {
+			if b.Symbol == "AAPL" && timeframe == "1D" && b.Close > 0 {
+				kept = append(kept, b)
+			}
+		}
+		return kept
+	})
+	if got := a.filterBars("AAPL", "1D", bars); len(got) != 2 || got[1].Close != 11 {
+		t.Errorf("filtered = %+v", got)
+	}
+}
@@ -0,0 +1,43 @@
+package algorithm
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ClaudeHealthHandler exposes the resilient Claude client's breaker over
+// HTTP.
+type ClaudeHealthHandler struct {
+	client *ResilientClaude
+}
+
+// NewClaudeHealthHandler creates a handler for client.
+func NewClaudeHealthHandler(client *ResilientClaude) *ClaudeHealthHandler {
+	return &ClaudeHealthHandler{client: client}
+}
+
+// RegisterRoutes registers the Claude health routes with mux.
+func (h *ClaudeHealthHandler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/claude/health - breaker state, failure streak, call counts
+	mux.HandleFunc("/api/claude/health", h.handleHealth)
+
+	// POST /api/claude/health/reset - close the breaker manually
+	mux.HandleFunc("/api/claude/health/reset", h.handleReset)
+}
+
+func (h *ClaudeHealthHandler) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(h.client.Health())
+}
+
+func (h *ClaudeHealthHandler) handleReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.client.ResetBreaker()
+	json.NewEncoder(w).Encode(h.client.Health())
+}
@@ -0,0 +1,212 @@
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrCooldown is wrapped by refusals of entries during a cooldown.
+var ErrCooldown = errors.New("cooling down after losses")
+
+// Cooldown scopes.
+const (
+	CooldownSymbol   = "symbol"
+	CooldownStrategy = "strategy"
+	CooldownGlobal   = "global"
+)
+
+// TradeOutcome is the realized result of a fill that closed part of a
+// position.
+type TradeOutcome struct {
+	Symbol        string    `json:"symbol"`
+	Strategy      string    `json:"strategy,omitempty"` // order tag
+	Qty           float64   `json:"qty"`
+	PL            float64   `json:"pl"`
+	ReturnPercent float64   `json:"return_percent"` // on the closed lots' cost
+	ClosedAt      time.Time `json:"closed_at"`
+}
+
+// OutcomeSource returns closed trade outcomes, oldest first, normally
+// paired from the fills journal.
+type OutcomeSource func() []TradeOutcome
+
+// Cooldown is a block on new entries after losing trades.
+type Cooldown struct {
+	Scope  string    `json:"scope"`         // symbol, strategy or global
+	Key    string    `json:"key,omitempty"` // the symbol or strategy
+	Reason string    `json:"reason"`
+	Losses int       `json:"losses,omitempty"` // consecutive losses behind it
+	Since  time.Time `json:"since"`
+	Until  time.Time `json:"until"`
+}
+
+// LossStreak is the run of losing trades at the end of a symbol's or
+// strategy's history.
+type LossStreak struct {
+	Scope  string    `json:"scope"`
+	Key    string    `json:"key"`
+	Losses int       `json:"losses"`
+	LastAt time.Time `json:"last_at"`
+}
+
+// SetOutcomeSource registers where the cooldown rules read trade outcomes.
+// Without one there are no cooldowns.
+func (a *TradingAlgorithm) SetOutcomeSource(fn OutcomeSource) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.outcomes = fn
+}
+
+// Cooldowns returns the cooldowns in force now.
+func (a *TradingAlgorithm) Cooldowns() []Cooldown {
+	cooldowns, _ := a.cooldownState()
+	return cooldowns
+}
+
+func (a *TradingAlgorithm) cooldownState() ([]Cooldown, []LossStreak) {
+	a.mu.RLock()
+	source := a.outcomes
+	equity := a.portfolio.TotalValue
+	riskParams := a.riskParameters
+	a.mu.RUnlock()
+	if source == nil {
+		return []Cooldown{}, []LossStreak{}
+	}
+	return ActiveCooldowns(source(), equity, a.now(),
+		int(riskParamFloat(riskParams, "cooldown_losses", 0)),
+		minutes(riskParamFloat(riskParams, "cooldown_minutes", 0)),
+		riskParamFloat(riskParams, "cooldown_loss_percent", 0),
+		minutes(riskParamFloat(riskParams, "global_cooldown_minutes", 0)))
+}
+
+func minutes(m float64) time.Duration { return time.Duration(m * float64(time.Minute)) }
+
+// ActiveCooldowns applies the cooldown rules to outcomes at now: after
+// losses consecutive losing trades on a symbol, or by a strategy across
+// its symbols, entries there are blocked for wait from the last loss; a
+// single loss of lossPercent of equity or more blocks every entry for
+// globalWait. A zero losses or lossPercent switches that rule off. It
+// also returns every current losing streak. Pure.
+func ActiveCooldowns(outcomes []TradeOutcome, equity float64, now time.Time, losses int, wait time.Duration, lossPercent float64, globalWait time.Duration) ([]Cooldown, []LossStreak) {
+	cooldowns, streaks := []Cooldown{}, []LossStreak{}
+	bySymbol := make(map[string]*LossStreak)
+	byStrategy := make(map[string]*LossStreak)
+	var global *Cooldown
+	step := func(m map[string]*LossStreak, scope, key string, o TradeOutcome) {
+		s, ok := m[key]
+		if !ok {
+			s = &LossStreak{Scope: scope, Key: key}
+			m[key] = s
+		}
+		if o.PL < 0 {
+			s.Losses++
+			s.LastAt = o.ClosedAt
+		} else {
+			s.Losses = 0
+		}
+	}
+	for _, o := range outcomes {
+		step(bySymbol, CooldownSymbol, strings.ToUpper(o.Symbol), o)
+		if o.Strategy != "" {
+			step(byStrategy, CooldownStrategy, o.Strategy, o)
+		}
+		if lossPercent <= 0 || equity <= 0 || o.PL >= 0 {
+			continue
+		}
+		if pct := -o.PL / equity * 100; pct >= lossPercent {
+			until := o.ClosedAt.Add(globalWait)
+			if until.After(now) && (global == nil || until.After(global.Until)) {
+				global = &Cooldown{Scope: CooldownGlobal, Since: o.ClosedAt, Until: until,
+					Reason: fmt.Sprintf("%s lost $%.2f, %.2f%% of equity, at least cooldown_loss_percent %.2f%%", o.Symbol, -o.PL, round4(pct), lossPercent)}
+			}
+		}
+	}
+	if global != nil {
+		cooldowns = append(cooldowns, *global)
+	}
+	for _, m := range []map[string]*LossStreak{bySymbol, byStrategy} {
+		for _, s := range m {
+			if s.Losses == 0 {
+				continue
+			}
+			streaks = append(streaks, *s)
+			if losses <= 0 || s.Losses < losses {
+				continue
+			}
+			if until := s.LastAt.Add(wait); until.After(now) {
+				cooldowns = append(cooldowns, Cooldown{Scope: s.Scope, Key: s.Key, Losses: s.Losses, Since: s.LastAt, Until: until,
+					Reason: fmt.Sprintf("%d consecutive losing trades on %s %s", s.Losses, s.Scope, s.Key)})
+			}
+		}
+	}
+	sort.Slice(cooldowns, func(i, j int) bool {
+		if cooldowns[i].Scope != cooldowns[j].Scope {
+			return cooldowns[i].Scope < cooldowns[j].Scope
+		}
+		return cooldowns[i].Key < cooldowns[j].Key
+	})
+	sort.Slice(streaks, func(i, j int) bool {
+		if streaks[i].Losses != streaks[j].Losses {
+			return streaks[i].Losses > streaks[j].Losses
+		}
+		if streaks[i].Scope != streaks[j].Scope {
+			return streaks[i].Scope < streaks[j].Scope
+		}
+		return streaks[i].Key < streaks[j].Key
+	})
+	return cooldowns, streaks
+}
+
+// checkCooldown refuses signals opening a position during a global
+// cooldown, or one on the signal's symbol or strategy. Closing and
+// reducing are never blocked.
+func (a *TradingAlgorithm) checkCooldown(signal *TradeSignal) error {
+	if !a.OpensPosition(signal) {
+		return nil
+	}
+	if c := a.blockingCooldown(signal); c != nil {
+		return fmt.Errorf("%w: %s; entries resume at %s", ErrCooldown, c.Reason, c.Until.Format(time.Kitchen))
+	}
+	return nil
+}
+
+// blockingCooldown is the cooldown that applies to signal, the longest
+// when several do.
+func (a *TradingAlgorithm) blockingCooldown(signal *TradeSignal) *Cooldown {
+	cooldowns, _ := a.cooldownState()
+	var block *Cooldown
+	for i, c := range cooldowns {
+		applies := c.Scope == CooldownGlobal ||
+			(c.Scope == CooldownSymbol && strings.EqualFold(c.Key, signal.Symbol)) ||
+			(c.Scope == CooldownStrategy && c.Key == signal.OrderTag())
+		if applies && (block == nil || c.Until.After(block.Until)) {
+			block = &cooldowns[i]
+		}
+	}
+	return block
+}
+
+// measureCooldown measures the longer of the losing streaks on an opening
+// signal's symbol and strategy against cooldown_losses.
+func (a *TradingAlgorithm) measureCooldown(signal *TradeSignal) *GuardMeasure {
+	a.mu.RLock()
+	limit := riskParamFloat(a.riskParameters, "cooldown_losses", 0)
+	a.mu.RUnlock()
+	if limit <= 0 || !a.OpensPosition(signal) {
+		return nil
+	}
+	_, streaks := a.cooldownState()
+	worst := 0
+	for _, s := range streaks {
+		if (s.Scope == CooldownSymbol && strings.EqualFold(s.Key, signal.Symbol)) ||
+			(s.Scope == CooldownStrategy && s.Key == signal.OrderTag()) {
+			if s.Losses > worst {
+				worst = s.Losses
+			}
+		}
+	}
+	return MeasureMax("consecutive_losses", float64(worst), limit)
+}
@@ -0,0 +1,84 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCooldownGuard(t *testing.T) {
+	now := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	a.SetClock(func() time.Time { return now })
+	a.portfolio.TotalValue = 100000
+	a.portfolio.Positions["TSLA"] = PositionData{Symbol: "TSLA", Quantity: 10}
+	if err := a.UpdateRiskParameters(map[string]interface{}{"signal_ttl_minutes": 0.0, "max_signal_deviation_percent": 0.0}); err != nil {
+		t.Fatal(err)
+	}
+
+	ago := func(m int) time.Time { return now.Add(-time.Duration(m) * time.Minute) }
+	outcomes := []TradeOutcome{
+		{Symbol: "AAPL", Strategy: "breakout", PL: -100, ClosedAt: ago(300)},
+		{Symbol: "AAPL", Strategy: "breakout", PL: -100, ClosedAt: ago(200)},
+		{Symbol: "AAPL", PL: -100, ClosedAt: ago(60)},
+		{Symbol: "NVDA", Strategy: "breakout", PL: -50, ClosedAt: ago(30)},
+		// A win ends the streak
+		{Symbol: "TSLA", PL: -100, ClosedAt: ago(50)},
+		{Symbol: "TSLA", PL: -100, ClosedAt: ago(40)},
+		{Symbol: "TSLA", PL: 10, ClosedAt: ago(35)},
+		{Symbol: "TSLA", PL: -100, ClosedAt: ago(20)},
+	}
+	a.SetOutcomeSource(func() []TradeOutcome { return outcomes })
+
+	for _, tc := range []struct {
+		signal *TradeSignal
+		scope  string
+	}{
+		{&TradeSignal{Symbol: "AAPL", Signal: SignalBuy}, CooldownSymbol},
+		{&TradeSignal{Symbol: "MSFT", Signal: SignalBuy, Tag: "breakout"}, CooldownStrategy},
+	} {
+		err := a.CheckTradeGuards(tc.signal)
+		if !errors.Is(err, ErrCooldown) {
+			t.Errorf("%s entry err = %v", tc.scope, err)
+		}
+		if c := a.blockingCooldown(tc.signal); c == nil || c.Scope != tc.scope || c.Losses != 3 {
+			t.Errorf("%s blocking cooldown = %+v", tc.scope, c)
+		}
+	}
+	if m := a.measureCooldown(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy}); m == nil || m.Value != 3 || m.Limit != 3 {
+		t.Errorf("measure = %+v", m)
+	}
+	for _, ok := range []*TradeSignal{
+		{Symbol: "MSFT", Signal: SignalBuy},  // no streak
+		{Symbol: "TSLA", Signal: SignalBuy},  // streak of one, adding to a long
+		{Symbol: "TSLA", Signal: SignalSell}, // closing is never blocked
+	} {
+		if err := a.CheckTradeGuards(ok); err != nil {
+			t.Errorf("%s %s blocked: %v", ok.Signal, ok.Symbol, err)
+		}
+	}
+
+	// 2.5% of equity in one trade cools everything down for two hours
+	outcomes = append(outcomes, TradeOutcome{Symbol: "META", PL: -2500, ClosedAt: ago(10)})
+	if err := a.CheckTradeGuards(&TradeSignal{Symbol: "MSFT", Signal: SignalBuy}); !errors.Is(err, ErrCooldown) {
+		t.Errorf("global cooldown err = %v", err)
+	}
+	metrics := a.GetRiskMetrics()
+	if len(metrics.Cooldowns) != 3 || metrics.Cooldowns[0].Scope != CooldownGlobal ||
+		!metrics.Cooldowns[0].Until.Equal(ago(10).Add(2*time.Hour)) {
+		t.Errorf("cooldowns = %+v", metrics.Cooldowns)
+	}
+	if len(metrics.LossStreaks) == 0 || metrics.LossStreaks[0].Losses != 3 {
+		t.Errorf("loss streaks = %+v", metrics.LossStreaks)
+	}
+
+	// Four hours after the last AAPL loss it may trade again
+	now = now.Add(3 * time.Hour)
+	if err := a.CheckTradeGuards(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy}); err != nil {
+		t.Errorf("AAPL after its cooldown: %v", err)
+	}
+	if err := a.UpdateRiskParameters(map[string]interface{}{"cooldown_losses": 1.5}); err == nil {
+		t.Error("fractional cooldown_losses accepted")
+	}
+}
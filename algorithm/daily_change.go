@@ -0,0 +1,35 @@
+package algorithm
+
+import "time"
+
+// percentChange is the move from ref to price, in percent.
+func percentChange(ref, price float64) float64 {
+	return (price - ref) / ref * 100
+}
+
+// sameSession reports whether a and b fall on the same exchange date.
+func sameSession(a, b time.Time) bool {
+	ay, am, ad := a.In(sessionZone).Date()
+	by, bm, bd := b.In(sessionZone).Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// sessionReferenceLocked returns symbol's prior session close and current
+// session open at time at, read from the cached daily bars. Either is zero
+// when the cache cannot tell. Live prices are rolled into the cache, so the
+// references move forward as new sessions begin. Callers must hold a.mu.
+func (a *TradingAlgorithm) sessionReferenceLocked(symbol string, at time.Time) (prevClose, sessionOpen float64) {
+	bars := a.barCache[barCacheKey(symbol, warmStartTimeFrame)].bars
+	if len(bars) == 0 {
+		return 0, 0
+	}
+	last := bars[len(bars)-1]
+	if !sameSession(last.Timestamp, at) {
+		// The current session has no bar yet: the last one is its prior
+		return last.Close, 0
+	}
+	if len(bars) > 1 {
+		prevClose = bars[len(bars)-2].Close
+	}
+	return prevClose, last.Open
+}
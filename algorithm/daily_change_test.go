@@ -0,0 +1,34 @@
+package algorithm
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestChangeMeasuredFromPriorClose(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+
+	// Without history the feed's value is kept, not derived from ticks
+	a.UpdateMarketData("AAPL", 100, 0, 0, 0, 0)
+	a.UpdateMarketData("AAPL", 101, 0, 0, 0, 0)
+	if md := a.GetMarketData("AAPL"); md.Change24h != 0 || md.PrevClose != 0 {
+		t.Errorf("uncached change = %+v", md)
+	}
+
+	now := time.Now().In(sessionZone)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, sessionZone)
+	a.cacheBars("AAPL", warmStartTimeFrame, []BarData{
+		{Symbol: "AAPL", Timestamp: today.AddDate(0, 0, -2), Open: 95, High: 96, Low: 94, Close: 95},
+		{Symbol: "AAPL", Timestamp: today.AddDate(0, 0, -1), Open: 96, High: 101, Low: 95, Close: 100},
+	})
+
+	// The first tick of the session opens today's bar
+	a.UpdateMarketData("AAPL", 102, 0, 0, 0, 0)
+	a.UpdateMarketData("AAPL", 103.02, 0, 0, 0, 0)
+	md := a.GetMarketData("AAPL")
+	if md.PrevClose != 100 || math.Abs(md.Change24h-3.02) > 1e-9 || math.Abs(md.ChangeSession-1) > 1e-9 {
+		t.Errorf("market data = %+v, want +3.02%% from the prior close and +1%% on the session", md)
+	}
+}
@@ -0,0 +1,158 @@
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
+)
+
+// Execution strategies. Passive, chase and aggressive only change what
+// happens after a limit order is submitted; market orders ignore them.
+// TWAP and VWAP slice the order into children over time whatever its
+// size; without them, orders above the slicing threshold are sliced by
+// the configured algorithm anyway.
+const (
+	ExecutionPassive    = "passive"    // rest at the limit until filled or the day ends
+	ExecutionChase      = "chase"      // reprice toward the market in steps up to a max distance
+	ExecutionAggressive = "aggressive" // chase, then convert to a market order after a timeout
+	ExecutionTWAP       = "twap"       // equal slices over a time window
+	ExecutionVWAP       = "vwap"       // slices weighted by intraday volume
+)
+
+// OrderHandler receives every order placed for a signal, for example to
+// hand limit orders with a chase strategy to order management.
+type OrderHandler func(signal *TradeSignal, order *alpaca.Order)
+
+// OrderSlicer may take over a built order and work it as child orders
+// over time instead of submitting it whole. It reports whether it did and
+// the parent order's ID.
+type OrderSlicer func(signal *TradeSignal, preview *OrderPreview) (parentID string, sliced bool, err error)
+
+// OrderSubmitter places an order with the broker. It may take over an
+// order whose submission failed transiently and report it queued instead,
+// handing the order to placed if a later attempt places it.
+type OrderSubmitter func(req alpaca.PlaceOrderRequest, placed func(*alpaca.Order)) (order *alpaca.Order, queued bool, err error)
+
+// NormalizeExecution validates an execution strategy and returns it in
+// canonical form; empty means passive.
+func NormalizeExecution(execution string) (string, error) {
+	switch e := strings.ToLower(strings.TrimSpace(execution)); e {
+	case "", ExecutionPassive:
+		return ExecutionPassive, nil
+	case ExecutionChase, ExecutionAggressive, ExecutionTWAP, ExecutionVWAP:
+		return e, nil
+	default:
+		return "", fmt.Errorf("unknown execution strategy %q (want passive, chase, aggressive, twap or vwap)", execution)
+	}
+}
+
+// SetOrderHandler registers fn to receive orders placed by ExecuteTrade or
+// reported through TrackOrder.
+func (a *TradingAlgorithm) SetOrderHandler(fn OrderHandler) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.orderCB = fn
+}
+
+// TrackOrder reports an order placed for signal outside ExecuteTrade so it
+// gets the same handling. An order opening a position reserves its
+// notional until ReleaseOrder is called for it.
+func (a *TradingAlgorithm) TrackOrder(signal *TradeSignal, order *alpaca.Order) {
+	a.mu.Lock()
+	if signal != nil && order != nil && opensPosition(signal, a.portfolio.Positions) {
+		a.reserveLocked(order, a.marketData[signal.Symbol].Price)
+	}
+	cb := a.orderCB
+	a.mu.Unlock()
+	if cb != nil && order != nil {
+		cb(signal, order)
+	}
+}
+
+// SetOrderSlicer registers fn to decide whether orders are sliced.
+func (a *TradingAlgorithm) SetOrderSlicer(fn OrderSlicer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.slicer = fn
+}
+
+// SliceOrder offers preview to the registered slicer. With none, nothing
+// is sliced.
+func (a *TradingAlgorithm) SliceOrder(signal *TradeSignal, preview *OrderPreview) (string, bool, error) {
+	a.mu.RLock()
+	slicer := a.slicer
+	a.mu.RUnlock()
+	if slicer == nil || preview == nil {
+		return "", false, nil
+	}
+	return slicer(signal, preview)
+}
+
+// CheckOrder runs an order the algorithm did not build, such as a parent
+// entered for slicing or one of its children, through the trade guards
+// and the limits on explicitly sized trades: an opening order may not
+// exceed max_position_size_percent of equity or the liquidity limit, and
+// a reducing one may not exceed the position. side is buy or sell and
+// price the order's expected price; zero uses the latest market price.
+func (a *TradingAlgorithm) CheckOrder(symbol, side string, qty, price float64, tag string) error {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	signal := &TradeSignal{
+		Symbol:    symbol,
+		Signal:    strings.ToLower(side),
+		OrderType: string(alpaca.Market),
+		Timestamp: a.now(),
+		Source:    "execution",
+		Size:      &TradeSize{Qty: qty},
+		Tag:       tag,
+	}
+	if signal.Signal != SignalBuy && signal.Signal != SignalSell {
+		return fmt.Errorf("side must be buy or sell, got %q", side)
+	}
+	if err := a.CheckTradeGuards(signal); err != nil {
+		return err
+	}
+
+	a.mu.RLock()
+	held := a.portfolio.Positions[symbol].Quantity
+	equity := a.portfolio.TotalValue
+	if price <= 0 {
+		price = a.marketData[symbol].Price
+	}
+	a.mu.RUnlock()
+	if price <= 0 {
+		return fmt.Errorf("no price for %s to check the order at", symbol)
+	}
+	intent, _, err := ResolveIntent(signal.Signal, held)
+	if err != nil {
+		return err
+	}
+	_, err = a.SizeTrade(signal, decimal.NewFromFloat(price), decimal.NewFromFloat(equity), decimal.NewFromFloat(intent.Reduces))
+	return err
+}
+
+// SetOrderSubmitter registers fn to place orders instead of the broker.
+func (a *TradingAlgorithm) SetOrderSubmitter(fn OrderSubmitter) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.submitter = fn
+}
+
+// SubmitOrder places req through the registered submitter, or with the
+// broker when there is none. A queued order is placed later, if at all,
+// and handed to placed then.
+func (a *TradingAlgorithm) SubmitOrder(req alpaca.PlaceOrderRequest, placed func(*alpaca.Order)) (*alpaca.Order, bool, error) {
+	a.mu.RLock()
+	submitter, client := a.submitter, a.client
+	a.mu.RUnlock()
+	if submitter != nil {
+		return submitter(req, placed)
+	}
+	if client == nil {
+		return nil, false, errors.New("alpaca client not configured")
+	}
+	order, err := client.PlaceOrder(req)
+	return order, false, err
+}
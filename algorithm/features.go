@@ -0,0 +1,36 @@
+package algorithm
+
+import "time"
+
+// Features are a symbol's stored features as of a closed bar, read from
+// the same store training and backtests use.
+type Features struct {
+	Timeframe string             `json:"timeframe"`
+	AsOf      time.Time          `json:"as_of"` // when the bar closed
+	Regime    string             `json:"regime,omitempty"`
+	Values    map[string]float64 `json:"values"`
+}
+
+// SetFeatureSource sets the lookup of a symbol's latest stored features,
+// passed to Claude with the market data.
+func (a *TradingAlgorithm) SetFeatureSource(fn func(symbol string) (*Features, bool)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.featureSource = fn
+}
+
+// features returns symbol's latest stored features, nil when there are
+// none.
+func (a *TradingAlgorithm) features(symbol string) *Features {
+	a.mu.RLock()
+	fn := a.featureSource
+	a.mu.RUnlock()
+	if fn == nil {
+		return nil
+	}
+	f, ok := fn(symbol)
+	if !ok {
+		return nil
+	}
+	return f
+}
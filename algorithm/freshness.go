@@ -0,0 +1,93 @@
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrStaleSignal is wrapped by refusals of signals past their validity,
+// or whose market has moved too far since they were generated.
+var ErrStaleSignal = errors.New("stale signal")
+
+// StampSignal records what the freshness guard needs of a new signal: the
+// symbol's current price as GeneratedPrice, and a ValidUntil
+// signal_ttl_minutes after its timestamp. Fields already set are kept, so
+// a signal carried from generation to execution keeps its original stamp.
+func (a *TradingAlgorithm) StampSignal(signal *TradeSignal) {
+	if signal == nil {
+		return
+	}
+	a.mu.RLock()
+	price := a.marketData[signal.Symbol].Price
+	ttl := riskParamFloat(a.riskParameters, "signal_ttl_minutes", 0)
+	a.mu.RUnlock()
+
+	if signal.Timestamp.IsZero() {
+		signal.Timestamp = a.now()
+	}
+	if signal.GeneratedPrice <= 0 && price > 0 {
+		signal.GeneratedPrice = price
+	}
+	if signal.ValidUntil == nil && ttl > 0 {
+		until := signal.Timestamp.Add(time.Duration(ttl * float64(time.Minute)))
+		signal.ValidUntil = &until
+	}
+}
+
+// checkFreshness refuses signals past their valid_until and opening
+// signals whose price has moved more than max_signal_deviation_percent
+// since they were generated. Signals reaching it unstamped, such as those
+// from webhooks or typed in by hand, are stamped first and so start their
+// validity here.
+func (a *TradingAlgorithm) checkFreshness(signal *TradeSignal) error {
+	a.StampSignal(signal)
+	return a.freshnessError(signal)
+}
+
+// freshnessError is checkFreshness without stamping the signal.
+// Closing and reducing signals are only held to their expiry: a market
+// that moved sharply is when getting out matters most.
+func (a *TradingAlgorithm) freshnessError(signal *TradeSignal) error {
+	now := a.now()
+	if signal.ValidUntil != nil && now.After(*signal.ValidUntil) {
+		return fmt.Errorf("%w: generated at %s, valid until %s, now %s", ErrStaleSignal,
+			signal.Timestamp.Format(time.Kitchen), signal.ValidUntil.Format(time.Kitchen), now.Format(time.Kitchen))
+	}
+	deviation, limit, ok := a.signalDeviation(signal)
+	if ok && deviation > limit {
+		return fmt.Errorf("%w: price moved %.2f%% since the signal at $%.2f, above max_signal_deviation_percent %.2f%%",
+			ErrStaleSignal, deviation, signal.GeneratedPrice, limit)
+	}
+	return nil
+}
+
+// signalDeviation is how far in percent the price of an opening signal's
+// symbol has moved since it was generated, and the limit on it. ok is
+// false when the check does not apply.
+func (a *TradingAlgorithm) signalDeviation(signal *TradeSignal) (deviation, limit float64, ok bool) {
+	a.mu.RLock()
+	price := a.marketData[signal.Symbol].Price
+	limit = riskParamFloat(a.riskParameters, "max_signal_deviation_percent", 0)
+	opens := opensPosition(signal, a.portfolio.Positions)
+	a.mu.RUnlock()
+	if limit <= 0 || !opens || price <= 0 || signal.GeneratedPrice <= 0 {
+		return 0, 0, false
+	}
+	return round4(math.Abs(price-signal.GeneratedPrice) / signal.GeneratedPrice * 100), limit, true
+}
+
+// measureFreshness measures an opening signal's price move against
+// max_signal_deviation_percent, or any other signal's age against its
+// validity.
+func (a *TradingAlgorithm) measureFreshness(signal *TradeSignal) *GuardMeasure {
+	if deviation, limit, ok := a.signalDeviation(signal); ok {
+		return MeasureMax("price_deviation_percent", deviation, limit)
+	}
+	if signal.ValidUntil == nil || !signal.ValidUntil.After(signal.Timestamp) {
+		return nil
+	}
+	age := a.now().Sub(signal.Timestamp).Minutes()
+	return MeasureMax("age_minutes", round4(age), round4(signal.ValidUntil.Sub(signal.Timestamp).Minutes()))
+}
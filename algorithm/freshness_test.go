@@ -0,0 +1,54 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFreshnessGuard(t *testing.T) {
+	now := time.Date(2026, 3, 2, 15, 45, 0, 0, time.UTC)
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	a.SetClock(func() time.Time { return now })
+	a.portfolio.Positions["MSFT"] = PositionData{Symbol: "MSFT", Quantity: 10}
+	a.marketData["AAPL"] = MarketData{Symbol: "AAPL", Price: 100}
+	a.marketData["MSFT"] = MarketData{Symbol: "MSFT", Price: 400}
+
+	// Unstamped signals start their 30 minutes here
+	fresh := &TradeSignal{Symbol: "AAPL", Signal: SignalBuy}
+	if err := a.CheckTradeGuards(fresh); err != nil {
+		t.Fatal(err)
+	}
+	if fresh.GeneratedPrice != 100 || !fresh.ValidUntil.Equal(now.Add(30*time.Minute)) {
+		t.Fatalf("stamped signal = %+v", fresh)
+	}
+
+	// Generated at 10:00, executed at 15:45
+	stale := &TradeSignal{Symbol: "MSFT", Signal: SignalSell, Timestamp: now.Add(-345 * time.Minute)}
+	a.StampSignal(stale)
+	if err := a.CheckTradeGuards(stale); !errors.Is(err, ErrStaleSignal) {
+		t.Fatalf("expired close err = %v", err)
+	}
+
+	// 3% above the price it was generated at
+	moved := &TradeSignal{Symbol: "AAPL", Signal: SignalBuy, Timestamp: now.Add(-5 * time.Minute), GeneratedPrice: 97}
+	if err := a.CheckTradeGuards(moved); !errors.Is(err, ErrStaleSignal) {
+		t.Fatalf("moved open err = %v", err)
+	}
+	if m := a.measureFreshness(moved); m == nil || m.Metric != "price_deviation_percent" || m.Value != 3.0928 || m.Margin >= 0 {
+		t.Errorf("moved measure = %+v", m)
+	}
+	// Closing is only held to the expiry
+	exit := &TradeSignal{Symbol: "MSFT", Signal: SignalSell, Timestamp: now.Add(-5 * time.Minute), GeneratedPrice: 440}
+	if err := a.CheckTradeGuards(exit); err != nil {
+		t.Errorf("moved close blocked: %v", err)
+	}
+
+	if err := a.UpdateRiskParameters(map[string]interface{}{"signal_ttl_minutes": 0.0, "max_signal_deviation_percent": 5.0}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.CheckTradeGuards(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy, Timestamp: now.Add(-6 * time.Hour), GeneratedPrice: 97}); err != nil {
+		t.Errorf("signal blocked with expiry off and within 5%%: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package algorithm
+
+import (
+	"fmt"
+	"time"
+)
+
+// TradeGuard vets a signal before any order is built for it. Returning an
+// error blocks the trade; the error is surfaced to the caller.
+type TradeGuard func(signal *TradeSignal) error
+
+// GuardMeasure reports how close a signal came to a guard's limit.
+type GuardMeasure struct {
+	Metric string  `json:"metric"`
+	Value  float64 `json:"value"`
+	Limit  float64 `json:"limit"`
+	// Margin is the room left before the limit as a fraction of the
+	// limit, negative once it is breached
+	Margin float64 `json:"margin"`
+}
+
+// GuardMeasurer measures signal against a guard's limit. It returns nil
+// when the limit does not apply, such as to a signal that opens nothing.
+type GuardMeasurer func(signal *TradeSignal) *GuardMeasure
+
+// MeasureMax measures value against a limit it must stay at or under.
+func MeasureMax(metric string, value, limit float64) *GuardMeasure {
+	return &GuardMeasure{Metric: metric, Value: value, Limit: limit, Margin: round4((limit - value) / limit)}
+}
+
+// MeasureMin measures value against a limit it must reach.
+func MeasureMin(metric string, value, limit float64) *GuardMeasure {
+	return &GuardMeasure{Metric: metric, Value: value, Limit: limit, Margin: round4((value - limit) / limit)}
+}
+
+// GuardDecision is one guard's verdict on a signal headed for execution.
+type GuardDecision struct {
+	At      time.Time     `json:"at"`
+	Symbol  string        `json:"symbol"`
+	Signal  string        `json:"signal"`
+	Source  string        `json:"source,omitempty"`
+	Guard   string        `json:"guard"`
+	Allowed bool          `json:"allowed"`
+	Reason  string        `json:"reason,omitempty"`
+	Measure *GuardMeasure `json:"measure,omitempty"`
+}
+
+// namedGuard keeps the registration name for error messages. dryRun,
+// when set, answers the same question as guard without side effects such
+// as queueing the signal; simulations use it. measure, when set, reports
+// the margin to the guard's limit with each decision.
+type namedGuard struct {
+	name    string
+	guard   TradeGuard
+	dryRun  TradeGuard
+	measure GuardMeasurer
+}
+
+// GuardResult is one guard's verdict on a signal.
+type GuardResult struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// AddTradeGuard registers a guard consulted by ExecuteTrade and by callers
+// of CheckTradeGuards. Guards run in registration order.
+func (a *TradingAlgorithm) AddTradeGuard(name string, guard TradeGuard) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.guards = append(a.guards, namedGuard{name: name, guard: guard})
+}
+
+// AddMeasuredTradeGuard registers a guard like AddTradeGuard, with measure
+// reporting the margin to its limit for the decision history.
+func (a *TradingAlgorithm) AddMeasuredTradeGuard(name string, guard TradeGuard, measure GuardMeasurer) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.guards = append(a.guards, namedGuard{name: name, guard: guard, measure: measure})
+}
+
+// SetGuardObserver registers fn to receive every decision CheckTradeGuards
+// makes: one per guard consulted, up to and including the first refusal.
+// Simulations through EvaluateTradeGuards are not observed.
+func (a *TradingAlgorithm) SetGuardObserver(fn func(GuardDecision)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.guardObserver = fn
+}
+
+// CheckTradeGuards runs every registered guard against signal and returns
+// the first refusal.
+func (a *TradingAlgorithm) CheckTradeGuards(signal *TradeSignal) error {
+	a.mu.RLock()
+	guards := append([]namedGuard(nil), a.guards...)
+	observe := a.guardObserver
+	a.mu.RUnlock()
+
+	for _, g := range guards {
+		err := g.guard(signal)
+		if observe != nil {
+			d := GuardDecision{At: a.now(), Symbol: signal.Symbol, Signal: signal.Signal, Source: signal.Source,
+				Guard: g.name, Allowed: err == nil}
+			if err != nil {
+				d.Reason = err.Error()
+			}
+			if g.measure != nil {
+				d.Measure = g.measure(signal)
+			}
+			observe(d)
+		}
+		if err != nil {
+			return fmt.Errorf("blocked by %s: %w", g.name, err)
+		}
+	}
+	return nil
+}
+
+// EvaluateTradeGuards runs every registered guard against signal without
+// side effects and reports each verdict, including guards after the first
+// refusal.
+func (a *TradingAlgorithm) EvaluateTradeGuards(signal *TradeSignal) []GuardResult {
+	a.mu.RLock()
+	guards := append([]namedGuard(nil), a.guards...)
+	a.mu.RUnlock()
+
+	results := make([]GuardResult, 0, len(guards))
+	for _, g := range guards {
+		check := g.guard
+		if g.dryRun != nil {
+			check = g.dryRun
+		}
+		r := GuardResult{Name: g.name, Passed: true}
+		if err := check(signal); err != nil {
+			r.Passed = false
+			r.Reason = err.Error()
+		}
+		results = append(results, r)
+	}
+	return results
+}
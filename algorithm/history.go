@@ -2,7 +2,6 @@ package algorithm
 
 import (
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
@@ -65,22 +64,17 @@ func (a *TradingAlgorithm) GetBarHistory(request HistoryRequest) (BarHistory, er
 		return BarHistory{}, err
 	}
 
-	// Fetch the historical bars from Alpaca
-	bars, err := a.mdClient.GetBars(
-		request.Symbol,
-		marketdata.GetBarsRequest{
-			TimeFrame: timeframe,
-			Start:     request.StartDate,
-			End:       request.EndDate,
-		},
-	)
+	// Fetch the historical bars from Alpaca, chunking long ranges
+	bars, err := a.fetchBarsChunked(request.Symbol, timeframe, request.TimeFrame, request.StartDate, request.EndDate)
 	if err != nil {
 		return BarHistory{}, fmt.Errorf("failed to fetch historical data: %w", err)
 	}
 
 	// Convert Alpaca bars to our BarData format
 	historicalBars := make([]BarData, len(bars))
+	closes := make([]float64, len(bars))
 	for i, bar := range bars {
+		closes[i] = bar.Close
 		historicalBars[i] = BarData{
 			Symbol:    request.Symbol,
 			Timestamp: bar.Timestamp,
@@ -93,9 +87,11 @@ func (a *TradingAlgorithm) GetBarHistory(request HistoryRequest) (BarHistory, er
 		}
 	}
 
-	log.Printf("Fetched %d historical bars for %s from %s to %s with timeframe %s",
-		len(historicalBars), request.Symbol, request.StartDate.Format("2006-01-02"),
-		request.EndDate.Format("2006-01-02"), request.TimeFrame)
+	a.recordDailyCloses(request.Symbol, timeframe, closes)
+	a.cacheBars(request.Symbol, request.TimeFrame, historicalBars)
+
+	logger().Info("Fetched historical bars", "symbol", request.Symbol, "bars", len(historicalBars),
+		"from", request.StartDate.Format("2006-01-02"), "to", request.EndDate.Format("2006-01-02"), "timeframe", request.TimeFrame)
 
 	// Create and return the BarHistory
 	return BarHistory{
@@ -119,7 +115,7 @@ func (a *TradingAlgorithm) AnalyzeBarHistory(data BarHistory) BarAnalysis {
 	}
 
 	if len(data.Bars) == 0 {
-		log.Printf("No bars to analyze for %s", data.Symbol)
+		logger().Warn("No bars to analyze", "symbol", data.Symbol)
 		return analysis
 	}
 
@@ -207,8 +203,8 @@ func (a *TradingAlgorithm) AnalyzeBarHistory(data BarHistory) BarAnalysis {
 		analysis.RecentVolatility = (recentReturnsSum / float64(recentBarCount-1)) * 100
 	}
 
-	log.Printf("Analyzed historical data for %s: trend=%s, strength=%.2f%%, volatility=%.2f%%",
-		data.Symbol, analysis.TrendDirection, analysis.TrendStrength, analysis.Volatility)
+	logger().Debug("Analyzed historical data", "symbol", data.Symbol, "trend", analysis.TrendDirection,
+		"strength", analysis.TrendStrength, "volatility", analysis.Volatility)
 
 	return analysis
 }
@@ -233,7 +229,7 @@ func parseTimeFrame(timeframe string) (marketdata.TimeFrame, error) {
 		return marketdata.OneDay, nil
 	default:
 		// Default to 1 day if not specified correctly
-		log.Printf("Unrecognized timeframe: %s, defaulting to 1D", timeframe)
+		logger().Warn("Unrecognized timeframe, defaulting to 1D", "timeframe", timeframe)
 		return marketdata.OneDay, nil
 	}
 }
@@ -3,7 +3,6 @@ package algorithm
 import (
 	"errors"
 	"fmt"
-	"log"
 
 	"github.com/rileyseaburg/go-trader/types"
 )
@@ -13,7 +12,7 @@ import (
 
 // GetHistoricalData provides backward compatibility with the handler API
 func (a *TradingAlgorithm) GetHistoricalData(request types.HistoricalDataRequest) (*types.HistoricalData, error) {
-	log.Printf("GetHistoricalData: converting request for %s", request.Symbol)
+	logger().Debug("Converting historical data request", "symbol", request.Symbol)
 
 	// Validate request
 	if request.Symbol == "" {
@@ -69,7 +68,7 @@ func convertBarHistoryToHistoricalData(data BarHistory) *types.HistoricalData {
 
 // AnalyzeHistoricalData provides backward compatibility with the handler API
 func (a *TradingAlgorithm) AnalyzeHistoricalData(data *types.HistoricalData) *types.HistoricalDataAnalysis {
-	log.Printf("AnalyzeHistoricalData: analyzing data for %s", data.Symbol)
+	logger().Debug("Analyzing historical data", "symbol", data.Symbol)
 
 	// Convert to BarHistory
 	barData := make([]BarData, len(data.Data))
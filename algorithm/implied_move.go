@@ -0,0 +1,51 @@
+package algorithm
+
+// SetImpliedMoveSource sets the lookup of a symbol's options-implied move
+// through its next event or expiry, as a percentage of price. The move is
+// passed to Claude with the market data, and risk-sized orders are shrunk
+// so the move costs at most max_event_loss_percent of equity.
+func (a *TradingAlgorithm) SetImpliedMoveSource(fn func(symbol string) (float64, bool)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.impliedMoves = fn
+}
+
+// impliedMove returns symbol's implied move in percent, if one is known.
+func (a *TradingAlgorithm) impliedMove(symbol string) (float64, bool) {
+	a.mu.RLock()
+	fn := a.impliedMoves
+	a.mu.RUnlock()
+	if fn == nil {
+		return 0, false
+	}
+	return fn(symbol)
+}
+
+// impliedMoveScale is the factor applied to a position of positionPct
+// percent of equity so that a move of movePct percent loses at most
+// maxLossPct percent of equity. It is 1 when the move fits or any input is
+// unset.
+func impliedMoveScale(positionPct, movePct, maxLossPct float64) float64 {
+	if positionPct <= 0 || movePct <= 0 || maxLossPct <= 0 {
+		return 1
+	}
+	loss := positionPct * movePct / 100
+	if loss <= maxLossPct {
+		return 1
+	}
+	return maxLossPct / loss
+}
+
+// eventScale returns the factor shrinking a risk-sized position of
+// maxPosSize percent of equity in symbol for its implied move.
+func (a *TradingAlgorithm) eventScale(symbol string, maxPosSize float64, riskParams map[string]interface{}) float64 {
+	move, ok := a.impliedMove(symbol)
+	if !ok {
+		return 1
+	}
+	scale := impliedMoveScale(maxPosSize, move, riskParamFloat(riskParams, "max_event_loss_percent", 0))
+	if scale < 1 {
+		logger().Debug("Position shrunk for implied move", "symbol", symbol, "implied_move_percent", move, "scale", scale)
+	}
+	return scale
+}
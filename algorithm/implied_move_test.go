@@ -0,0 +1,16 @@
+package algorithm
+
+import "testing"
+
+func TestImpliedMoveScale(t *testing.T) {
+	// 10% of equity through an 8% move risks 0.8%; capping at 0.5% shrinks it
+	if got := impliedMoveScale(10, 8, 0.5); got != 0.625 {
+		t.Fatalf("scale = %v, want 0.625", got)
+	}
+	if got := impliedMoveScale(5, 8, 0.5); got != 1 {
+		t.Fatalf("fitting move scaled: %v", got)
+	}
+	if got := impliedMoveScale(10, 8, 0); got != 1 {
+		t.Fatalf("disabled cap scaled: %v", got)
+	}
+}
@@ -0,0 +1,124 @@
+package algorithm
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// InstanceHandler serves CRUD for algorithm instances over HTTP.
+type InstanceHandler struct {
+	registry *InstanceRegistry
+}
+
+// NewInstanceHandler creates a handler for registry.
+func NewInstanceHandler(registry *InstanceRegistry) *InstanceHandler {
+	return &InstanceHandler{registry: registry}
+}
+
+// RegisterRoutes registers the instance routes with mux.
+func (h *InstanceHandler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/algorithms/instances?type=&symbol=&strategy= - List instances
+	// POST /api/algorithms/instances - Create an instance
+	mux.HandleFunc("/api/algorithms/instances", h.handleInstances)
+
+	// GET, PUT, DELETE /api/algorithms/instances/{id}
+	mux.HandleFunc("/api/algorithms/instances/", h.handleInstance)
+}
+
+func setInstanceHeaders(w http.ResponseWriter) {
+}
+
+// instanceErrorStatus maps registry errors to HTTP statuses.
+func instanceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInstanceNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrInstanceExists):
+		return http.StatusConflict
+	case errors.Is(err, ErrInvalidInstance):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeInstanceJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (h *InstanceHandler) handleInstances(w http.ResponseWriter, r *http.Request) {
+	setInstanceHeaders(w)
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		q := r.URL.Query()
+		writeInstanceJSON(w, http.StatusOK, h.registry.List(q.Get("type"), q.Get("symbol"), q.Get("strategy")))
+	case http.MethodPost:
+		var spec InstanceSpec
+		if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		inst, err := h.registry.Create(spec)
+		if err != nil {
+			http.Error(w, err.Error(), instanceErrorStatus(err))
+			return
+		}
+		writeInstanceJSON(w, http.StatusCreated, inst)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *InstanceHandler) handleInstance(w http.ResponseWriter, r *http.Request) {
+	setInstanceHeaders(w)
+	id := strings.TrimPrefix(r.URL.Path, "/api/algorithms/instances/")
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "Instance ID is required", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		inst, ok := h.registry.Get(id)
+		if !ok {
+			http.Error(w, ErrInstanceNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		writeInstanceJSON(w, http.StatusOK, inst)
+	case http.MethodPut:
+		var spec InstanceSpec
+		if err := json.NewDecoder(r.Body).Decode(&spec); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if spec.ID != "" && spec.ID != id {
+			http.Error(w, "Body id does not match the path", http.StatusBadRequest)
+			return
+		}
+		spec.ID = id
+		inst, err := h.registry.Put(spec)
+		if err != nil {
+			http.Error(w, err.Error(), instanceErrorStatus(err))
+			return
+		}
+		writeInstanceJSON(w, http.StatusOK, inst)
+	case http.MethodDelete:
+		if !h.registry.Delete(id) {
+			http.Error(w, ErrInstanceNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,495 @@
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm/algo"
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+var (
+	// ErrInstanceNotFound is returned for an unknown instance ID.
+	ErrInstanceNotFound = errors.New("algorithm instance not found")
+	// ErrInstanceExists is returned when creating an instance whose ID is
+	// taken.
+	ErrInstanceExists = errors.New("algorithm instance already exists")
+	// ErrInvalidInstance wraps every validation failure of an InstanceSpec.
+	ErrInvalidInstance = errors.New("invalid algorithm instance")
+)
+
+// InstanceSpec describes an algorithm instance to create or update.
+type InstanceSpec struct {
+	// ID names the instance; empty derives it from strategy, type and
+	// symbol.
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// Symbol scopes the instance to one symbol; empty runs it for any.
+	Symbol string `json:"symbol"`
+	// Strategy labels the strategy the instance serves.
+	Strategy   string                 `json:"strategy"`
+	Parameters map[string]interface{} `json:"parameters"`
+	TimeFrame  string                 `json:"timeframe"`
+	BarCount   int                    `json:"bar_count"`
+	// Volatility names the volatility estimator; see algo.AlgorithmConfig.
+	Volatility string `json:"volatility,omitempty"`
+	// LabelingMethod names how meta-labeling labels its training history;
+	// see algo.AlgorithmConfig.
+	LabelingMethod string `json:"labeling_method,omitempty"`
+}
+
+// AlgorithmInstance is a configured algorithm registered under an ID.
+type AlgorithmInstance struct {
+	ID        string               `json:"id"`
+	Type      algo.AlgorithmType   `json:"type"`
+	Symbol    string               `json:"symbol,omitempty"`
+	Strategy  string               `json:"strategy,omitempty"`
+	Config    algo.AlgorithmConfig `json:"config"`
+	Bars      int                  `json:"bars"` // history passed to each run
+	CreatedAt time.Time            `json:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at"`
+}
+
+// InstanceRun is the outcome of running an instance for a symbol.
+type InstanceRun struct {
+	Instance AlgorithmInstance
+	Symbol   string
+	// TimeFrame is the resolution the run used: the instance's, or the
+	// symbol's chosen timeframe when the instance names none
+	TimeFrame string
+	Bars      int // history actually processed
+	Result    algo.CachedResult
+	Cached    bool
+	// Shadow is the shadow configuration's result on the same history,
+	// set on fresh runs while one is set for the instance
+	Shadow *algo.AlgorithmResult
+}
+
+// RunHandler receives every fresh instance run with the market data it
+// was run on.
+type RunHandler func(run *InstanceRun, current *types.MarketData)
+
+// InstanceRegistry holds named algorithm instances, each with its own
+// parameters, optionally scoped to a symbol and strategy, so configuring
+// one never changes another.
+type InstanceRegistry struct {
+	algorithm *TradingAlgorithm
+
+	mu        sync.RWMutex
+	instances map[string]AlgorithmInstance
+	// shadows holds candidate configurations run alongside instances
+	shadows map[string]AlgorithmInstance
+	onRun   RunHandler
+
+	running *algo.SymbolInstances
+	results *algo.ResultCache
+}
+
+// NewInstanceRegistry creates an empty registry whose instances read
+// history from a.
+func NewInstanceRegistry(a *TradingAlgorithm) *InstanceRegistry {
+	return &InstanceRegistry{
+		algorithm: a,
+		instances: make(map[string]AlgorithmInstance),
+		shadows:   make(map[string]AlgorithmInstance),
+		running:   algo.NewSymbolInstances(),
+		results:   algo.NewResultCache(),
+	}
+}
+
+// Spec returns the spec that builds inst.
+func (inst AlgorithmInstance) Spec() InstanceSpec {
+	params := make(map[string]interface{}, len(inst.Config.AdditionalParams))
+	for k, v := range inst.Config.AdditionalParams {
+		params[k] = v
+	}
+	return InstanceSpec{
+		ID:             inst.ID,
+		Type:           string(inst.Type),
+		Symbol:         inst.Symbol,
+		Strategy:       inst.Strategy,
+		Parameters:     params,
+		TimeFrame:      inst.Config.TimeFrame,
+		BarCount:       inst.Config.BarCount,
+		Volatility:     inst.Config.Volatility,
+		LabelingMethod: inst.Config.LabelingMethod,
+	}
+}
+
+// instanceID derives an ID from the parts of spec that scope it.
+func instanceID(spec InstanceSpec) string {
+	parts := make([]string, 0, 3)
+	for _, p := range []string{spec.Strategy, spec.Type, spec.Symbol} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, ":")
+}
+
+// parseParameters converts JSON parameter values to the numeric form
+// algorithms are configured with.
+func parseParameters(raw map[string]interface{}) (map[string]float64, error) {
+	params := make(map[string]float64, len(raw))
+	for k, v := range raw {
+		switch val := v.(type) {
+		case float64:
+			params[k] = val
+		case int:
+			params[k] = float64(val)
+		case bool:
+			params[k] = 0
+			if val {
+				params[k] = 1
+			}
+		case string:
+			f, err := strconv.ParseFloat(val, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid parameter value for %s: %v", k, v)
+			}
+			params[k] = f
+		default:
+			return nil, fmt.Errorf("invalid parameter type for %s: %T", k, v)
+		}
+	}
+	return params, nil
+}
+
+// build validates spec and returns the instance it describes.
+func (r *InstanceRegistry) build(spec InstanceSpec) (AlgorithmInstance, error) {
+	invalid := func(err error) (AlgorithmInstance, error) {
+		return AlgorithmInstance{}, fmt.Errorf("%w: %v", ErrInvalidInstance, err)
+	}
+	spec.Symbol = strings.ToUpper(strings.TrimSpace(spec.Symbol))
+	if spec.ID == "" {
+		spec.ID = instanceID(spec)
+	}
+	if strings.ContainsAny(spec.ID, "/ ") {
+		return invalid(fmt.Errorf("id %q may not contain spaces or slashes", spec.ID))
+	}
+
+	params, err := parseParameters(spec.Parameters)
+	if err != nil {
+		return invalid(err)
+	}
+	alg, err := algo.Create(algo.AlgorithmType(spec.Type))
+	if err != nil {
+		return invalid(err)
+	}
+	config := algo.AlgorithmConfig{
+		AdditionalParams: params,
+		TimeFrame:        spec.TimeFrame,
+		BarCount:         spec.BarCount,
+		Volatility:       spec.Volatility,
+		LabelingMethod:   spec.LabelingMethod,
+	}
+	if err := alg.Configure(config); err != nil {
+		return invalid(err)
+	}
+	// Make sure its lookback can be served at the chosen resolution
+	if err := CheckAlgorithmHistory(alg, config); err != nil {
+		return invalid(err)
+	}
+
+	return AlgorithmInstance{
+		ID:       spec.ID,
+		Type:     alg.Type(),
+		Symbol:   spec.Symbol,
+		Strategy: spec.Strategy,
+		Config:   config,
+		Bars:     algo.HistoryBars(alg, config),
+	}, nil
+}
+
+// store registers inst, sizing the warm-start preload to its lookback and
+// dropping state and results left from an earlier configuration.
+func (r *InstanceRegistry) store(inst AlgorithmInstance) {
+	r.instances[inst.ID] = inst
+	r.running.Forget(inst.ID)
+	r.results.Invalidate(inst.ID)
+	if r.algorithm != nil {
+		r.algorithm.SetAlgorithmHistory(inst.ID, inst.Config.Resolution(), inst.Bars)
+	}
+}
+
+// Create registers a new instance, failing with ErrInstanceExists if the
+// ID is taken.
+func (r *InstanceRegistry) Create(spec InstanceSpec) (AlgorithmInstance, error) {
+	inst, err := r.build(spec)
+	if err != nil {
+		return inst, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.instances[inst.ID]; ok {
+		return AlgorithmInstance{}, fmt.Errorf("%w: %s", ErrInstanceExists, inst.ID)
+	}
+	inst.CreatedAt = time.Now()
+	inst.UpdatedAt = inst.CreatedAt
+	r.store(inst)
+	return inst, nil
+}
+
+// Put creates or replaces the instance spec describes.
+func (r *InstanceRegistry) Put(spec InstanceSpec) (AlgorithmInstance, error) {
+	inst, err := r.build(spec)
+	if err != nil {
+		return inst, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	inst.UpdatedAt = time.Now()
+	inst.CreatedAt = inst.UpdatedAt
+	if prev, ok := r.instances[inst.ID]; ok {
+		inst.CreatedAt = prev.CreatedAt
+	}
+	r.store(inst)
+	return inst, nil
+}
+
+// Get returns the instance registered under id.
+func (r *InstanceRegistry) Get(id string) (AlgorithmInstance, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	inst, ok := r.instances[id]
+	return inst, ok
+}
+
+// List returns instances matching the non-empty filters, ordered by ID.
+// An instance with no symbol scope matches any symbol.
+func (r *InstanceRegistry) List(algType, symbol, strategy string) []AlgorithmInstance {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]AlgorithmInstance, 0, len(r.instances))
+	for _, inst := range r.instances {
+		if algType != "" && string(inst.Type) != algType ||
+			symbol != "" && inst.Symbol != "" && !strings.EqualFold(inst.Symbol, symbol) ||
+			strategy != "" && inst.Strategy != strategy {
+			continue
+		}
+		out = append(out, inst)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Delete removes the instance registered under id, reporting whether it
+// existed.
+func (r *InstanceRegistry) Delete(id string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.instances[id]; !ok {
+		return false
+	}
+	delete(r.instances, id)
+	delete(r.shadows, id)
+	r.running.Forget(id)
+	r.running.Forget(shadowName(id))
+	r.results.Invalidate(id)
+	if r.algorithm != nil {
+		r.algorithm.SetAlgorithmHistory(id, "", 0)
+	}
+	return true
+}
+
+// shadowName is the name a shadow configuration of id runs under.
+func shadowName(id string) string {
+	return id + "~shadow"
+}
+
+// SetShadow runs instance id with params in place of its parameters
+// alongside it: every fresh run also runs the shadow on the same history
+// and reports its result in InstanceRun.Shadow. Only the instance's own
+// result is cached or acted on.
+func (r *InstanceRegistry) SetShadow(id string, params map[string]interface{}) (AlgorithmInstance, error) {
+	inst, ok := r.Get(id)
+	if !ok {
+		return AlgorithmInstance{}, fmt.Errorf("%w: %s", ErrInstanceNotFound, id)
+	}
+	spec := inst.Spec()
+	spec.Parameters = params
+	shadow, err := r.build(spec)
+	if err != nil {
+		return shadow, err
+	}
+	if shadow.Config.Resolution() != inst.Config.Resolution() {
+		return AlgorithmInstance{}, fmt.Errorf("%w: a shadow must run at the instance's resolution", ErrInvalidInstance)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	shadow.CreatedAt = time.Now()
+	shadow.UpdatedAt = shadow.CreatedAt
+	r.shadows[id] = shadow
+	r.running.Forget(shadowName(id))
+	return shadow, nil
+}
+
+// ClearShadow stops running id's shadow configuration, if any.
+func (r *InstanceRegistry) ClearShadow(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.shadows, id)
+	r.running.Forget(shadowName(id))
+}
+
+// Shadow returns the shadow configuration set for id.
+func (r *InstanceRegistry) Shadow(id string) (AlgorithmInstance, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	shadow, ok := r.shadows[id]
+	return shadow, ok
+}
+
+// SetRunHandler registers fn to receive every fresh run.
+func (r *InstanceRegistry) SetRunHandler(fn RunHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.onRun = fn
+}
+
+// Run executes instance id for symbol, or for the instance's own symbol
+// when symbol is empty. The result is served from cache while the history
+// ends on the same bar, unless refresh is set.
+func (r *InstanceRegistry) Run(id, symbol string, current *types.MarketData, refresh bool) (*InstanceRun, error) {
+	inst, ok := r.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInstanceNotFound, id)
+	}
+	switch {
+	case symbol == "":
+		symbol = inst.Symbol
+	case inst.Symbol != "" && !strings.EqualFold(symbol, inst.Symbol):
+		return nil, fmt.Errorf("%w: %s is scoped to %s, not %s", ErrInvalidInstance, id, inst.Symbol, symbol)
+	}
+	if symbol == "" {
+		return nil, fmt.Errorf("%w: symbol is required", ErrInvalidInstance)
+	}
+	symbol = strings.ToUpper(symbol)
+
+	r.mu.RLock()
+	shadow, shadowed := r.shadows[id]
+	onRun := r.onRun
+	r.mu.RUnlock()
+	bars := inst.Bars
+	if shadowed && shadow.Bars > bars {
+		bars = shadow.Bars
+	}
+	config, shadowConfig := inst.Config, shadow.Config
+	if tf := r.algorithm.SymbolTimeFrame(symbol); config.TimeFrame == "" && tf != algo.DefaultTimeFrame {
+		// Instances naming no timeframe run at the symbol's chosen one
+		config.TimeFrame, shadowConfig.TimeFrame = tf, tf
+	}
+
+	all, err := r.algorithm.AlgorithmHistory(symbol, config.Resolution(), bars)
+	if err != nil {
+		return nil, fmt.Errorf("get historical data: %w", err)
+	}
+	history := lastBars(all, inst.Bars)
+	run := &InstanceRun{Instance: inst, Symbol: symbol, TimeFrame: config.Resolution(), Bars: len(history)}
+
+	key := algo.CacheKey{
+		Instance:   inst.ID,
+		Type:       inst.Type,
+		ConfigHash: algo.ConfigHash(config),
+		Symbol:     symbol,
+		BarTime:    history[len(history)-1].Timestamp,
+	}
+	if cached, hit := r.results.Get(key); hit && !refresh {
+		run.Result, run.Cached = cached, true
+		return run, nil
+	}
+
+	result, err := r.running.Process(inst.ID, inst.Type, config, symbol, current, history)
+	if err != nil {
+		return nil, fmt.Errorf("execute %s: %w", inst.ID, err)
+	}
+	run.Result = r.results.Put(key, result)
+	if shadowed {
+		// A failing shadow never fails the instance's own run
+		shadowResult, err := r.running.Process(shadowName(id), shadow.Type, shadowConfig, symbol, current, lastBars(all, shadow.Bars))
+		if err != nil {
+			logger().Warn("Shadow configuration failed", "instance", id, "symbol", symbol, "error", err)
+		} else {
+			run.Shadow = shadowResult
+		}
+	}
+	if onRun != nil {
+		onRun(run, current)
+	}
+	return run, nil
+}
+
+// PortfolioRun is the outcome of running an instance over a basket.
+type PortfolioRun struct {
+	Instance AlgorithmInstance
+	Symbols  []string // the basket as requested, normalized
+	Result   *algo.PortfolioResult
+}
+
+// RunPortfolio executes instance id over a basket of symbols together,
+// for algorithms that size a basket as a unit. Symbols whose history
+// cannot be loaded are reported under the result's Skipped. Basket runs
+// are not cached.
+func (r *InstanceRegistry) RunPortfolio(id string, symbols []string) (*PortfolioRun, error) {
+	inst, ok := r.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInstanceNotFound, id)
+	}
+	if inst.Symbol != "" {
+		return nil, fmt.Errorf("%w: %s is scoped to %s and cannot run a basket", ErrInvalidInstance, id, inst.Symbol)
+	}
+	if alg, err := algo.Create(inst.Type); err != nil {
+		return nil, err
+	} else if _, ok := alg.(algo.PortfolioProcessor); !ok {
+		return nil, fmt.Errorf("%w: %s algorithms do not run baskets", ErrInvalidInstance, inst.Type)
+	}
+	symbols = BatchSymbols(symbols)
+	switch {
+	case len(symbols) == 0:
+		return nil, fmt.Errorf("%w: symbols are required", ErrInvalidInstance)
+	case len(symbols) > MaxBatchSymbols:
+		return nil, fmt.Errorf("%w: at most %d symbols per basket", ErrInvalidInstance, MaxBatchSymbols)
+	}
+
+	histories := make(map[string][]types.MarketData, len(symbols))
+	unavailable := make(map[string]string)
+	for _, symbol := range symbols {
+		history, err := r.algorithm.AlgorithmHistory(symbol, inst.Config.Resolution(), inst.Bars)
+		if err != nil {
+			unavailable[symbol] = err.Error()
+			continue
+		}
+		histories[symbol] = history
+	}
+	if len(histories) == 0 {
+		return nil, errors.New("get historical data: no symbol in the basket has history")
+	}
+
+	result, err := r.running.ProcessPortfolio(inst.ID, inst.Type, inst.Config, histories)
+	if err != nil {
+		return nil, fmt.Errorf("execute %s: %w", inst.ID, err)
+	}
+	if len(unavailable) > 0 {
+		if result.Skipped == nil {
+			result.Skipped = make(map[string]string, len(unavailable))
+		}
+		for symbol, reason := range unavailable {
+			result.Skipped[symbol] = reason
+		}
+	}
+	return &PortfolioRun{Instance: inst, Symbols: symbols, Result: result}, nil
+}
+
+// lastBars returns the last n of bars, or all of them when there are
+// fewer or n is not positive.
+func lastBars(bars []types.MarketData, n int) []types.MarketData {
+	if n <= 0 || len(bars) <= n {
+		return bars
+	}
+	return bars[len(bars)-n:]
+}
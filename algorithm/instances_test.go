@@ -0,0 +1,190 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+func TestInstanceRegistryScopesConfiguration(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	r := NewInstanceRegistry(a)
+
+	aapl, err := r.Create(InstanceSpec{Type: "triple_barrier", Symbol: "aapl", Strategy: "swing",
+		Parameters: map[string]interface{}{"stop_loss": 0.5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aapl.ID != "swing:triple_barrier:AAPL" || aapl.Symbol != "AAPL" {
+		t.Errorf("derived instance = %+v", aapl)
+	}
+	if _, err := r.Create(InstanceSpec{Type: "triple_barrier", Symbol: "AAPL", Strategy: "swing"}); !errors.Is(err, ErrInstanceExists) {
+		t.Errorf("duplicate create err = %v", err)
+	}
+	msft, err := r.Put(InstanceSpec{ID: "tb-msft", Type: "triple_barrier", Symbol: "MSFT",
+		Parameters: map[string]interface{}{"stop_loss": "2"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Configuring MSFT leaves AAPL's parameters alone
+	if got, _ := r.Get(aapl.ID); got.Config.AdditionalParams["stop_loss"] != 0.5 || msft.Config.AdditionalParams["stop_loss"] != 2 {
+		t.Errorf("parameters mixed: aapl %+v, msft %+v", got.Config, msft.Config)
+	}
+	if list := r.List("", "msft", ""); len(list) != 1 || list[0].ID != "tb-msft" {
+		t.Errorf("symbol filter = %+v", list)
+	}
+	if need := a.RequiredHistory()["1D"]; need != msft.Bars {
+		t.Errorf("preload need = %d, want %d", need, msft.Bars)
+	}
+
+	if _, err := r.Put(InstanceSpec{Type: "triple_barrier", Parameters: map[string]interface{}{"stop_loss": -1}}); !errors.Is(err, ErrInvalidInstance) {
+		t.Errorf("invalid parameters err = %v", err)
+	}
+	if _, err := r.Put(InstanceSpec{Type: "nope"}); !errors.Is(err, ErrInvalidInstance) {
+		t.Errorf("unknown type err = %v", err)
+	}
+
+	if !r.Delete("tb-msft") || r.Delete("tb-msft") {
+		t.Error("delete did not report existence")
+	}
+	if _, ok := r.Get("tb-msft"); ok {
+		t.Error("deleted instance still registered")
+	}
+}
+
+func TestInstanceRunUsesScopeAndCache(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	day0 := time.Date(2024, 1, 2, 0, 0, 0, 0, sessionZone)
+	bars := make([]BarData, 60)
+	for i := range bars {
+		p := 100 + 5*math.Sin(float64(i)/4)
+		bars[i] = BarData{Symbol: "AAPL", Timestamp: day0.AddDate(0, 0, i), High: p + 1, Low: p - 1, Close: p}
+	}
+	a.cacheBars("AAPL", warmStartTimeFrame, bars)
+	r := NewInstanceRegistry(a)
+	if _, err := r.Put(InstanceSpec{ID: "cusum-aapl", Type: "cusum_filter", Symbol: "AAPL"}); err != nil {
+		t.Fatal(err)
+	}
+
+	current := &types.MarketData{Symbol: "AAPL", Price: bars[59].Close}
+	if _, err := r.Run("cusum-aapl", "MSFT", current, false); !errors.Is(err, ErrInvalidInstance) {
+		t.Errorf("out-of-scope run err = %v", err)
+	}
+	if _, err := r.Run("missing", "AAPL", current, false); !errors.Is(err, ErrInstanceNotFound) {
+		t.Errorf("unknown instance err = %v", err)
+	}
+
+	first, err := r.Run("cusum-aapl", "", current, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Symbol != "AAPL" || first.Cached || first.Bars == 0 {
+		t.Errorf("first run = %+v", first)
+	}
+	if again, _ := r.Run("cusum-aapl", "aapl", current, false); !again.Cached {
+		t.Error("second run on the same bar not served from cache")
+	}
+	if forced, _ := r.Run("cusum-aapl", "AAPL", current, true); forced.Cached {
+		t.Error("refresh served from cache")
+	}
+}
+
+func TestInstanceShadowRunsOnSameHistory(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	day0 := time.Date(2024, 1, 2, 0, 0, 0, 0, sessionZone)
+	bars := make([]BarData, 60)
+	for i := range bars {
+		p := 100 + 5*math.Sin(float64(i)/4)
+		bars[i] = BarData{Symbol: "AAPL", Timestamp: day0.AddDate(0, 0, i), High: p + 1, Low: p - 1, Close: p}
+	}
+	a.cacheBars("AAPL", warmStartTimeFrame, bars)
+	r := NewInstanceRegistry(a)
+	if _, err := r.Put(InstanceSpec{ID: "tb-aapl", Type: "triple_barrier", Symbol: "AAPL",
+		Parameters: map[string]interface{}{"stop_loss": 1.0}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.SetShadow("missing", nil); !errors.Is(err, ErrInstanceNotFound) {
+		t.Errorf("shadow of unknown instance err = %v", err)
+	}
+	if _, err := r.SetShadow("tb-aapl", map[string]interface{}{"stop_loss": -1}); !errors.Is(err, ErrInvalidInstance) {
+		t.Errorf("invalid shadow err = %v", err)
+	}
+	shadow, err := r.SetShadow("tb-aapl", map[string]interface{}{"stop_loss": 2.0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shadow.Config.AdditionalParams["stop_loss"] != 2 {
+		t.Errorf("shadow = %+v", shadow)
+	}
+
+	var seen []*InstanceRun
+	r.SetRunHandler(func(run *InstanceRun, _ *types.MarketData) { seen = append(seen, run) })
+	current := &types.MarketData{Symbol: "AAPL", Price: bars[59].Close}
+	run, err := r.Run("tb-aapl", "", current, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Shadow == nil || len(seen) != 1 {
+		t.Fatalf("run = %+v, handler saw %d", run, len(seen))
+	}
+	if inst, _ := r.Get("tb-aapl"); inst.Config.AdditionalParams["stop_loss"] != 1 {
+		t.Errorf("shadow changed the instance: %+v", inst.Config)
+	}
+	// Cached runs are not fresh
+	r.Run("tb-aapl", "", current, false)
+	if len(seen) != 1 {
+		t.Errorf("handler saw a cached run")
+	}
+
+	r.ClearShadow("tb-aapl")
+	if run, _ := r.Run("tb-aapl", "", current, true); run.Shadow != nil {
+		t.Error("cleared shadow still runs")
+	}
+}
+
+func TestInstanceRunPortfolio(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	day0 := time.Date(2024, 1, 2, 0, 0, 0, 0, sessionZone)
+	for symbol, drift := range map[string]float64{"UP": 0.002, "DOWN": -0.002} {
+		bars := make([]BarData, 300)
+		for i := range bars {
+			// A steady drift with a 1% wobble, so volatility is never zero
+			p := 100 * math.Exp(drift*float64(i)+0.01*math.Sin(float64(i)))
+			bars[i] = BarData{Symbol: symbol, Timestamp: day0.AddDate(0, 0, i), High: p, Low: p, Close: p}
+		}
+		a.cacheBars(symbol, warmStartTimeFrame, bars)
+	}
+	r := NewInstanceRegistry(a)
+	if _, err := r.Put(InstanceSpec{ID: "mom", Type: "tsmom"}); err != nil {
+		t.Fatal(err)
+	}
+
+	run, err := r.RunPortfolio("mom", []string{"up", "DOWN", "NONE", "up"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(run.Symbols) != 3 || run.Result.Weights["UP"] <= 0 || run.Result.Weights["DOWN"] >= 0 {
+		t.Errorf("run = %+v", run.Result)
+	}
+	if _, ok := run.Result.Skipped["NONE"]; !ok {
+		t.Errorf("symbol without history not skipped: %v", run.Result.Skipped)
+	}
+
+	if _, err := r.Put(InstanceSpec{ID: "mom-up", Type: "tsmom", Symbol: "UP"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.RunPortfolio("mom-up", []string{"UP"}); !errors.Is(err, ErrInvalidInstance) {
+		t.Errorf("scoped basket run err = %v", err)
+	}
+	if _, err := r.Put(InstanceSpec{ID: "cusum", Type: "cusum_filter"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.RunPortfolio("cusum", []string{"UP"}); !errors.Is(err, ErrInvalidInstance) {
+		t.Errorf("basket run of a single-symbol algorithm err = %v", err)
+	}
+}
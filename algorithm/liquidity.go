@@ -0,0 +1,188 @@
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrLiquidity is wrapped by refusals of positions too large for a
+// symbol's trading volume.
+var ErrLiquidity = errors.New("position too large for liquidity")
+
+const (
+	// liquidityLookbackDays is the number of completed daily bars averaged
+	// for average daily volume
+	liquidityLookbackDays = 20
+	// liquidityMinDays is the fewest bars ADV is computed from; with fewer
+	// a symbol is not capped
+	liquidityMinDays = 5
+	// liquidityFloorADV is the dollar ADV at which the liquidity score is 0
+	liquidityFloorADV = 1e6
+)
+
+// Liquidity is a symbol's trading volume and spread and the sizing caps
+// they impose.
+type Liquidity struct {
+	Symbol     string  `json:"symbol"`
+	Days       int     `json:"days"`        // completed daily bars averaged
+	ADV        float64 `json:"adv"`         // average daily volume, shares
+	ADVDollars float64 `json:"adv_dollars"` // average daily close × volume
+	SpreadBps  float64 `json:"spread_bps,omitempty"`
+	// Score runs from 0 at $1M of daily volume to 1 at
+	// liquidity_full_adv, scaled down for spreads wider than
+	// liquidity_spread_bps. Risk-sized positions get
+	// max_position_size_percent × Score of equity.
+	Score float64 `json:"score"`
+	// MaxValue is max_adv_percent of ADVDollars, the most any position may
+	// be worth; 0 when the cap is off
+	MaxValue float64 `json:"max_value,omitempty"`
+}
+
+// SetQuoteSource sets the lookup of a symbol's latest bid and ask, used
+// for the spread in the liquidity score.
+func (a *TradingAlgorithm) SetQuoteSource(fn func(symbol string) (bid, ask float64, ok bool)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.quotes = fn
+}
+
+// Liquidity returns symbol's liquidity from the cached daily bars and the
+// latest quote. It returns nil when fewer than five completed sessions are
+// cached, in which case the symbol is not capped.
+func (a *TradingAlgorithm) Liquidity(symbol string) *Liquidity {
+	a.mu.RLock()
+	riskParams := a.sizingParamsLocked()
+	a.mu.RUnlock()
+	return a.liquidity(symbol, riskParams)
+}
+
+func (a *TradingAlgorithm) liquidity(symbol string, riskParams map[string]interface{}) *Liquidity {
+	symbol = strings.ToUpper(symbol)
+	bars, _ := a.CachedBars(symbol, warmStartTimeFrame)
+	// Live prices are rolled into today's bar, so its volume is partial
+	if n := len(bars); n > 0 && sameSession(bars[n-1].Timestamp, a.now()) {
+		bars = bars[:n-1]
+	}
+	if len(bars) > liquidityLookbackDays {
+		bars = bars[len(bars)-liquidityLookbackDays:]
+	}
+	if len(bars) < liquidityMinDays {
+		return nil
+	}
+
+	l := &Liquidity{Symbol: symbol, Days: len(bars)}
+	for _, b := range bars {
+		l.ADV += float64(b.Volume)
+		l.ADVDollars += b.Close * float64(b.Volume)
+	}
+	l.ADV = math.Round(l.ADV / float64(len(bars)))
+	l.ADVDollars = roundCents(l.ADVDollars / float64(len(bars)))
+
+	a.mu.RLock()
+	quotes := a.quotes
+	a.mu.RUnlock()
+	if quotes != nil {
+		if bid, ask, ok := quotes(symbol); ok && bid > 0 && ask >= bid {
+			l.SpreadBps = round4((ask - bid) / ((ask + bid) / 2) * 1e4)
+		}
+	}
+
+	l.Score = liquidityScore(l.ADVDollars, l.SpreadBps,
+		riskParamFloat(riskParams, "liquidity_full_adv", 5e8),
+		riskParamFloat(riskParams, "liquidity_spread_bps", 10))
+	if pct := riskParamFloat(riskParams, "max_adv_percent", 1); pct > 0 {
+		l.MaxValue = roundCents(l.ADVDollars * pct / 100)
+	}
+	return l
+}
+
+// liquidityScore rates dollar ADV on a log scale from 0 at $1M to 1 at
+// fullADV, then scales it by spreadBps/spread for spreads wider than
+// spreadBps. A non-positive fullADV or spreadBps switches that part off.
+func liquidityScore(advDollars, spread, fullADV, spreadBps float64) float64 {
+	score := 1.0
+	if fullADV > liquidityFloorADV {
+		score = math.Log(math.Max(advDollars, liquidityFloorADV)/liquidityFloorADV) / math.Log(fullADV/liquidityFloorADV)
+		score = math.Min(score, 1)
+	}
+	if spreadBps > 0 && spread > spreadBps {
+		score *= spreadBps / spread
+	}
+	return round4(score)
+}
+
+// checkLiquidityLimit refuses an explicitly sized opening trade of shares
+// at price worth more than the liquidity allows: max_position_size_percent
+// of equity scaled by the liquidity score, and max_adv_percent of dollar
+// ADV.
+func (a *TradingAlgorithm) checkLiquidityLimit(symbol string, shares, price, equity decimal.Decimal, riskParams map[string]interface{}) error {
+	l := a.liquidity(symbol, riskParams)
+	if l == nil {
+		return nil
+	}
+	value := shares.Mul(price)
+	maxPct := decimal.NewFromFloat(riskParamFloat(riskParams, "max_position_size_percent", 5.0))
+	if limit := equity.Mul(maxPct).Div(decimal.NewFromInt(100)).Mul(decimal.NewFromFloat(l.Score)); equity.IsPositive() && maxPct.IsPositive() && value.GreaterThan(limit) {
+		return fmt.Errorf("%w: $%s of %s is above the $%s its %.2f liquidity score allows",
+			ErrLiquidity, value.StringFixed(2), l.Symbol, limit.StringFixed(2), l.Score)
+	}
+	if limit := decimal.NewFromFloat(l.MaxValue); limit.IsPositive() && value.GreaterThan(limit) {
+		return fmt.Errorf("%w: $%s of %s is above the $%s max_adv_percent allows of its $%.0f average daily volume",
+			ErrLiquidity, value.StringFixed(2), l.Symbol, limit.StringFixed(2), l.ADVDollars)
+	}
+	return nil
+}
+
+// checkLiquidity refuses opening signals in symbols too illiquid to hold
+// any position, and explicitly sized opens above the liquidity limit.
+// Risk-sized opens are shrunk to the limit by RiskSize.
+func (a *TradingAlgorithm) checkLiquidity(signal *TradeSignal) error {
+	a.mu.RLock()
+	positions := a.portfolio.Positions
+	equity := a.portfolio.TotalValue
+	price := a.marketData[signal.Symbol].Price
+	riskParams := a.sizingParamsLocked()
+	a.mu.RUnlock()
+
+	if !opensPosition(signal, positions) {
+		return nil
+	}
+	if signal.LimitPrice != nil && *signal.LimitPrice > 0 {
+		price = *signal.LimitPrice
+	}
+	if signal.Size != nil && price > 0 {
+		p, e := decimal.NewFromFloat(price), decimal.NewFromFloat(equity)
+		shares, err := signal.Size.Shares(p, e)
+		if err != nil {
+			// Sizing reports its own errors when the order is built
+			return nil
+		}
+		return a.checkLiquidityLimit(signal.Symbol, shares, p, e, riskParams)
+	}
+	if l := a.liquidity(signal.Symbol, riskParams); l != nil && l.Score <= 0 {
+		return fmt.Errorf("%w: %s trades $%.0f a day, too little to size a position",
+			ErrLiquidity, l.Symbol, l.ADVDollars)
+	}
+	return nil
+}
+
+// measureLiquidity measures an opening signal's dollar ADV against the $1M
+// floor below which nothing is sized.
+func (a *TradingAlgorithm) measureLiquidity(signal *TradeSignal) *GuardMeasure {
+	a.mu.RLock()
+	positions := a.portfolio.Positions
+	riskParams := a.sizingParamsLocked()
+	a.mu.RUnlock()
+	if !opensPosition(signal, positions) {
+		return nil
+	}
+	l := a.liquidity(signal.Symbol, riskParams)
+	if l == nil {
+		return nil
+	}
+	return MeasureMin("adv_dollars", l.ADVDollars, liquidityFloorADV)
+}
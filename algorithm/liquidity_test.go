@@ -0,0 +1,89 @@
+package algorithm
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func liquidityBars(symbol string, price float64, volume int64) []BarData {
+	start := time.Date(2025, 3, 3, 21, 0, 0, 0, time.UTC)
+	bars := make([]BarData, 20)
+	for i := range bars {
+		bars[i] = BarData{Symbol: symbol, Timestamp: start.AddDate(0, 0, i), Open: price, High: price, Low: price, Close: price, Volume: volume}
+	}
+	return bars
+}
+
+func TestLiquidityScore(t *testing.T) {
+	cases := []struct {
+		adv, spread, want float64
+	}{
+		{5e8, 1, 1},
+		{5e9, 0, 1},
+		{1e6, 0, 0},
+		{1e5, 0, 0},
+		{math.Sqrt(1e6 * 5e8), 0, 0.5},
+		{5e8, 20, 0.5}, // twice the 10 bps allowance halves the score
+	}
+	for _, c := range cases {
+		if got := liquidityScore(c.adv, c.spread, 5e8, 10); math.Abs(got-c.want) > 1e-4 {
+			t.Errorf("score($%.0f, %.0f bps) = %v, want %v", c.adv, c.spread, got, c.want)
+		}
+	}
+}
+
+func TestLiquidityCapsPositionSize(t *testing.T) {
+	a := capTestAlgorithm()
+	a.portfolio.TotalValue = 100000
+	// $1B a day against $5M a day, both at $10 a share
+	a.cacheBars("BIG", warmStartTimeFrame, liquidityBars("BIG", 10, 100_000_000))
+	a.cacheBars("SMOL", warmStartTimeFrame, liquidityBars("SMOL", 10, 500_000))
+	a.SetQuoteSource(func(string) (float64, float64, bool) { return 9.995, 10.005, true })
+	riskParams := a.sizingParamsLocked()
+
+	big, err := a.buildOrder(&TradeSignal{Symbol: "BIG", Signal: SignalBuy, OrderType: "market"}, 10, a.portfolio, riskParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qty, _ := big.Request.Qty.Float64(); qty != 500 {
+		t.Errorf("liquid qty = %v, want the full 5%% of 500 shares", qty)
+	}
+	small, err := a.buildOrder(&TradeSignal{Symbol: "SMOL", Signal: SignalBuy, OrderType: "market"}, 10, a.portfolio, riskParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// log(5) / log(500) of the 5%
+	if qty, _ := small.Request.Qty.Float64(); qty != 129 {
+		t.Errorf("illiquid qty = %v, want 129", qty)
+	}
+
+	// At $10M of equity 1% of the $5M ADV binds first
+	a.portfolio.TotalValue = 10_000_000
+	small, err = a.buildOrder(&TradeSignal{Symbol: "SMOL", Signal: SignalSell, OrderType: "market"}, 10, a.portfolio, riskParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qty, _ := small.Request.Qty.Float64(); qty != 5000 {
+		t.Errorf("ADV-capped short qty = %v, want 5000", qty)
+	}
+	a.portfolio.TotalValue = 100000
+
+	a.UpdateMarketData("SMOL", 10, 10, 10, 1000, 0)
+	if err := a.CheckTradeGuards(&TradeSignal{Symbol: "SMOL", Signal: SignalBuy, Size: &TradeSize{Notional: 4000}}); !errors.Is(err, ErrLiquidity) {
+		t.Fatalf("oversized explicit buy err = %v", err)
+	}
+	if err := a.CheckTradeGuards(&TradeSignal{Symbol: "SMOL", Signal: SignalBuy, Size: &TradeSize{Notional: 1000}}); err != nil {
+		t.Fatalf("explicit buy within the limit blocked: %v", err)
+	}
+
+	// Under $1M a day there is nothing to size
+	a.cacheBars("DUST", warmStartTimeFrame, liquidityBars("DUST", 1, 500_000))
+	if err := a.CheckTradeGuards(&TradeSignal{Symbol: "DUST", Signal: SignalBuy}); !errors.Is(err, ErrLiquidity) {
+		t.Fatalf("illiquid open err = %v", err)
+	}
+	if l := a.Liquidity("NONE"); l != nil {
+		t.Errorf("liquidity without bars = %+v", l)
+	}
+}
@@ -0,0 +1,118 @@
+package algorithm
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// SetPortfolioHandler registers fn to receive the portfolio after every
+// broker sync and every live re-mark from the ticker stream, with the
+// generation it belongs to.
+func (a *TradingAlgorithm) SetPortfolioHandler(fn func(p PortfolioData, generation uint64)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.portfolioCB = fn
+}
+
+// syncPortfolio reads the portfolio from the broker and publishes it.
+func (a *TradingAlgorithm) syncPortfolio() error {
+	if err := a.updatePortfolio(); err != nil {
+		return err
+	}
+	a.mu.RLock()
+	cb, generation := a.portfolioCB, a.generation
+	snapshot := a.portfolioSnapshotLocked()
+	a.mu.RUnlock()
+	if cb != nil {
+		cb(snapshot, generation)
+	}
+	return nil
+}
+
+// RunPortfolioSync reconciles positions with the broker every interval
+// until ctx is cancelled. Prices between syncs come from the ticker stream.
+func (a *TradingAlgorithm) RunPortfolioSync(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := a.syncPortfolio(); err != nil {
+				logger().Warn("Portfolio sync failed", "error", err)
+			}
+		}
+	}
+}
+
+// PortfolioSyncedAt returns when positions were last read from the broker;
+// zero if they never have been.
+func (a *TradingAlgorithm) PortfolioSyncedAt() time.Time {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.portfolioAt
+}
+
+// HeldSymbols returns the symbols with an open position in p, sorted.
+func (p PortfolioData) HeldSymbols() []string {
+	symbols := make([]string, 0, len(p.Positions))
+	for sym, pos := range p.Positions {
+		if pos.Quantity != 0 {
+			symbols = append(symbols, sym)
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// markPositionLocked re-prices a held position at price and recomputes
+// portfolio value and daily P&L from it. It reports whether symbol is held.
+// The positions map is replaced rather than mutated, so callers holding an
+// earlier copy never race with the update. Callers must hold a.mu.
+func (a *TradingAlgorithm) markPositionLocked(symbol string, price float64, at time.Time) bool {
+	pos, held := a.portfolio.Positions[symbol]
+	if !held || pos.Quantity == 0 || price <= 0 {
+		return false
+	}
+
+	pos.CurrentPrice = price
+	pos.PricedAt = at
+	pos.MarketVal = pos.Quantity * price
+	pos.Profit = (price - pos.AvgPrice) * pos.Quantity
+	if pos.AvgPrice > 0 {
+		pos.Return = (price - pos.AvgPrice) / pos.AvgPrice * 100
+		if pos.Quantity < 0 {
+			pos.Return = -pos.Return
+		}
+	}
+
+	positions := make(map[string]PositionData, len(a.portfolio.Positions))
+	total := a.portfolio.Balance
+	for sym, p := range a.portfolio.Positions {
+		if sym == symbol {
+			p = pos
+		}
+		positions[sym] = p
+		total += p.MarketVal
+	}
+	a.portfolio.Positions = positions
+	a.portfolio.TotalValue = total
+	if a.lastEquity > 0 {
+		a.portfolio.DailyPnL = total - a.lastEquity
+		a.portfolio.DailyReturn = a.portfolio.DailyPnL / a.lastEquity * 100
+	}
+	return true
+}
+
+// portfolioSnapshotLocked returns a copy of the portfolio whose positions
+// map the caller may keep. Callers must hold a.mu.
+func (a *TradingAlgorithm) portfolioSnapshotLocked() PortfolioData {
+	p := a.portfolio
+	p.Positions = make(map[string]PositionData, len(a.portfolio.Positions))
+	for sym, pos := range a.portfolio.Positions {
+		p.Positions[sym] = pos
+	}
+	return p
+}
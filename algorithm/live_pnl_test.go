@@ -0,0 +1,53 @@
+package algorithm
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLiveMarksUpdatePnL(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	a.lastEquity = 10000
+	a.portfolio = PortfolioData{
+		Balance: 5000,
+		Positions: map[string]PositionData{
+			"AAPL": {Symbol: "AAPL", Quantity: 10, AvgPrice: 100, MarketVal: 1000},
+			"TSLA": {Symbol: "TSLA", Quantity: -5, AvgPrice: 200, MarketVal: -1000},
+		},
+	}
+	before := a.GetPortfolio().Positions
+
+	var published []PortfolioData
+	a.SetPortfolioHandler(func(p PortfolioData, _ uint64) { published = append(published, p) })
+
+	a.UpdateMarketData("AAPL", 110, 0, 0, 0, 0)
+	a.UpdateMarketData("TSLA", 180, 0, 0, 0, 0)
+	a.UpdateMarketData("MSFT", 400, 0, 0, 0, 0) // not held
+
+	if len(published) != 2 {
+		t.Fatalf("published %d updates, want 2", len(published))
+	}
+	p := published[1]
+	aapl, tsla := p.Positions["AAPL"], p.Positions["TSLA"]
+	if aapl.Profit != 100 || aapl.Return != 10 || aapl.CurrentPrice != 110 || aapl.PricedAt.IsZero() {
+		t.Errorf("AAPL = %+v", aapl)
+	}
+	if tsla.Profit != 100 || tsla.Return != 10 || tsla.MarketVal != -900 {
+		t.Errorf("short TSLA = %+v", tsla)
+	}
+	// 5000 cash + 1100 long - 900 short
+	if p.TotalValue != 5200 || p.DailyPnL != -4800 || math.Abs(p.DailyReturn+48) > 1e-9 {
+		t.Errorf("portfolio = %+v", p)
+	}
+	if before["AAPL"].CurrentPrice != 0 {
+		t.Error("earlier portfolio copy was mutated")
+	}
+	if got := p.HeldSymbols(); len(got) != 2 || got[0] != "AAPL" {
+		t.Errorf("held = %v", got)
+	}
+	if !a.PortfolioSyncedAt().Equal(time.Time{}) {
+		t.Error("marks must not count as a broker sync")
+	}
+}
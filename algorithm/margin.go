@@ -0,0 +1,179 @@
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/rileyseaburg/go-trader/algorithm/sizing"
+	"github.com/shopspring/decimal"
+)
+
+// ErrBuyingPower is wrapped by refusals of an explicitly sized opening
+// order that does not fit the buying power or the leverage limit.
+var ErrBuyingPower = errors.New("insufficient buying power")
+
+// reservationMaxAge bounds how long an open order's reservation is held
+// when its end is never reported. Orders are placed for the day.
+const reservationMaxAge = 24 * time.Hour
+
+// Reservation is the notional an open opening order will use once filled.
+type Reservation struct {
+	OrderID  string    `json:"order_id"`
+	Symbol   string    `json:"symbol"`
+	Side     string    `json:"side"`
+	Notional float64   `json:"notional"`
+	PlacedAt time.Time `json:"placed_at"`
+}
+
+// Exposure is the room left for new positions: the broker's buying power
+// less what orders placed since the account was read will use, and
+// max_leverage times equity less the gross value of positions and of
+// every open opening order.
+type Exposure struct {
+	Equity        float64 `json:"equity"`
+	BuyingPower   float64 `json:"buying_power"`
+	Multiplier    float64 `json:"multiplier"`
+	GrossExposure float64 `json:"gross_exposure"` // sum of absolute position values
+	Reserved      float64 `json:"reserved"`       // all open opening orders
+	// ReservedSinceSync is the part of Reserved placed after the account
+	// was read, which its buying power does not reflect yet
+	ReservedSinceSync float64       `json:"reserved_since_sync"`
+	MaxLeverage       float64       `json:"max_leverage"` // 0 means unlimited
+	Leverage          float64       `json:"leverage"`     // (gross + reserved) ÷ equity
+	BuyingPowerRoom   float64       `json:"buying_power_room"`
+	LeverageRoom      float64       `json:"leverage_room"`
+	Available         float64       `json:"available"` // the smaller room; -1 when neither applies
+	Reservations      []Reservation `json:"reservations"`
+	SyncedAt          time.Time     `json:"synced_at"`
+}
+
+// Exposure returns the current room for new positions.
+func (a *TradingAlgorithm) Exposure() Exposure {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.exposureLocked(a.portfolio, a.riskParameters)
+}
+
+// exposureLocked works out the room left against portfolio. Buying power
+// only applies once the account has been read, which sets its multiplier.
+func (a *TradingAlgorithm) exposureLocked(portfolio PortfolioData, riskParams map[string]interface{}) Exposure {
+	now := a.now()
+	e := Exposure{
+		Equity:       portfolio.TotalValue,
+		BuyingPower:  portfolio.BuyingPower,
+		Multiplier:   portfolio.Multiplier,
+		MaxLeverage:  riskParamFloat(riskParams, "max_leverage", 1.0),
+		Available:    -1,
+		Reservations: []Reservation{},
+		SyncedAt:     a.portfolioAt,
+	}
+	for _, pos := range portfolio.Positions {
+		e.GrossExposure += math.Abs(pos.MarketVal)
+	}
+	for _, r := range a.reservations {
+		if now.Sub(r.PlacedAt) > reservationMaxAge {
+			continue
+		}
+		e.Reserved += r.Notional
+		if r.PlacedAt.After(a.portfolioAt) {
+			e.ReservedSinceSync += r.Notional
+		}
+		e.Reservations = append(e.Reservations, r)
+	}
+	sort.Slice(e.Reservations, func(i, j int) bool { return e.Reservations[i].PlacedAt.Before(e.Reservations[j].PlacedAt) })
+
+	if e.Equity > 0 {
+		e.Leverage = (e.GrossExposure + e.Reserved) / e.Equity
+	}
+	if portfolio.Multiplier > 0 {
+		e.BuyingPowerRoom = math.Max(0, e.BuyingPower-e.ReservedSinceSync)
+		e.Available = e.BuyingPowerRoom
+	}
+	if e.MaxLeverage > 0 && e.Equity > 0 {
+		e.LeverageRoom = math.Max(0, e.MaxLeverage*e.Equity-e.GrossExposure-e.Reserved)
+		if e.Available < 0 || e.LeverageRoom < e.Available {
+			e.Available = e.LeverageRoom
+		}
+	}
+	return e
+}
+
+// FitExposure checks an opening order of qty shares for signal at price
+// against the cached account. See fitExposure.
+func (a *TradingAlgorithm) FitExposure(signal *TradeSignal, price, qty decimal.Decimal) (decimal.Decimal, error) {
+	a.mu.RLock()
+	e := a.exposureLocked(a.portfolio, a.sizingParamsLocked())
+	a.mu.RUnlock()
+	return fitExposure(signal, price, qty, e)
+}
+
+// fitExposure shrinks a risk-sized opening order to the whole shares the
+// room in e pays for at price, and refuses an explicitly sized one that
+// does not fit.
+func fitExposure(signal *TradeSignal, price, qty decimal.Decimal, e Exposure) (decimal.Decimal, error) {
+	if e.Available < 0 || !qty.IsPositive() || !price.IsPositive() {
+		return qty, nil
+	}
+	room := decimal.NewFromFloat(e.Available)
+	if qty.Mul(price).LessThanOrEqual(room) {
+		return qty, nil
+	}
+	if signal.Size != nil {
+		return decimal.Zero, fmt.Errorf("%w: $%s for %s is more than the $%s available", ErrBuyingPower,
+			qty.Mul(price).StringFixed(2), signal.Symbol, room.StringFixed(2))
+	}
+	shares := sizing.WholeShares(room, price)
+	if !shares.IsPositive() {
+		return decimal.Zero, fmt.Errorf("%w: $%s available is less than one share of %s at $%s", ErrBuyingPower,
+			room.StringFixed(2), signal.Symbol, price.StringFixed(2))
+	}
+	logger().Info("Position shrunk to the room left", "symbol", signal.Symbol, "shares", shares, "wanted", qty, "available", e.Available)
+	return shares, nil
+}
+
+// reserveLocked holds the notional of order, an opening order, until
+// ReleaseOrder is called for it. Limit orders are valued at their limit.
+func (a *TradingAlgorithm) reserveLocked(order *alpaca.Order, price float64) {
+	if order.LimitPrice != nil {
+		price, _ = order.LimitPrice.Float64()
+	}
+	qty := 0.0
+	if order.Qty != nil {
+		qty, _ = order.Qty.Float64()
+	}
+	notional := qty * price
+	if order.Notional != nil {
+		notional, _ = order.Notional.Float64()
+	}
+	if notional <= 0 {
+		return
+	}
+	now := a.now()
+	if a.reservations == nil {
+		a.reservations = make(map[string]Reservation)
+	}
+	for id, r := range a.reservations {
+		if now.Sub(r.PlacedAt) > reservationMaxAge {
+			delete(a.reservations, id)
+		}
+	}
+	a.reservations[order.ID] = Reservation{
+		OrderID:  order.ID,
+		Symbol:   order.Symbol,
+		Side:     string(order.Side),
+		Notional: notional,
+		PlacedAt: now,
+	}
+}
+
+// ReleaseOrder drops the reservation of an order that has finished,
+// filled or not. Orders without one are ignored.
+func (a *TradingAlgorithm) ReleaseOrder(orderID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.reservations, orderID)
+}
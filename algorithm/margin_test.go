@@ -0,0 +1,86 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
+)
+
+func TestOpeningOrdersFitBuyingPowerAndLeverage(t *testing.T) {
+	synced := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+	now := synced.Add(time.Minute)
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	a.SetClock(func() time.Time { return now })
+	a.portfolio = PortfolioData{
+		TotalValue:  10000,
+		BuyingPower: 4000,
+		Multiplier:  2,
+		Positions: map[string]PositionData{
+			"MSFT": {Symbol: "MSFT", Quantity: 10, MarketVal: 5000},
+		},
+	}
+	a.portfolioAt = synced
+	a.marketData["AAPL"] = MarketData{Symbol: "AAPL", Price: 100}
+	if err := a.UpdateRiskParameters(map[string]interface{}{"max_position_size_percent": 50.0}); err != nil {
+		t.Fatal(err)
+	}
+	build := func(signal *TradeSignal) (*OrderPreview, error) {
+		a.mu.RLock()
+		portfolio, params := a.portfolio, a.sizingParamsLocked()
+		a.mu.RUnlock()
+		return a.buildOrder(signal, 100, portfolio, params)
+	}
+
+	// $5,000 risk-sized, shrunk to the $4,000 of buying power
+	preview, err := build(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy, OrderType: "market"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !preview.Request.Qty.Equal(decimal.NewFromInt(40)) {
+		t.Fatalf("qty = %s, want 40", preview.Request.Qty)
+	}
+
+	signal := &TradeSignal{Symbol: "AAPL", Signal: SignalBuy, OrderType: "market"}
+	a.TrackOrder(signal, &alpaca.Order{ID: "o1", Symbol: "AAPL", Side: alpaca.Buy, Qty: preview.Request.Qty})
+	e := a.Exposure()
+	if e.Reserved != 4000 || e.ReservedSinceSync != 4000 || e.BuyingPowerRoom != 0 || e.LeverageRoom != 1000 || e.Available != 0 {
+		t.Errorf("exposure with o1 open = %+v", e)
+	}
+
+	// Nothing is left: explicit sizes are refused, risk sizes too
+	if _, err := build(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy, OrderType: "market", Size: &TradeSize{Qty: 1}}); !errors.Is(err, ErrBuyingPower) {
+		t.Errorf("explicit size err = %v", err)
+	}
+	if _, err := build(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy, OrderType: "market"}); !errors.Is(err, ErrBuyingPower) {
+		t.Errorf("risk size err = %v", err)
+	}
+
+	// A fresh account read counts o1 in the broker's buying power, but it
+	// still counts toward leverage until it finishes
+	a.portfolioAt = now
+	if e := a.Exposure(); e.ReservedSinceSync != 0 || e.BuyingPowerRoom != 4000 || e.Available != 1000 {
+		t.Errorf("exposure after sync = %+v", e)
+	}
+	a.ReleaseOrder("o1")
+	if e := a.Exposure(); e.Reserved != 0 || e.Available != 4000 || e.Leverage != 0.5 {
+		t.Errorf("exposure after release = %+v", e)
+	}
+
+	// Closing is never held back
+	a.portfolio.BuyingPower = 0
+	if preview, err := build(&TradeSignal{Symbol: "MSFT", Signal: SignalClose, OrderType: "market"}); err != nil || preview == nil {
+		t.Errorf("close = %v, %v", preview, err)
+	}
+
+	// Reservations lapse after a day when their end is never seen
+	a.portfolio.BuyingPower = 4000
+	a.TrackOrder(signal, &alpaca.Order{ID: "o2", Symbol: "AAPL", Side: alpaca.Buy, Qty: preview.Request.Qty})
+	now = now.Add(25 * time.Hour)
+	if e := a.Exposure(); e.Reserved != 0 || len(e.Reservations) != 0 {
+		t.Errorf("exposure a day on = %+v", e)
+	}
+}
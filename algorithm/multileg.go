@@ -0,0 +1,429 @@
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+)
+
+// Multi-leg orders are several orders that stand or fall together, such
+// as a pair trade (short A, long B) or a position and its hedge. Their
+// risk is evaluated as one order: every leg passes the trade guards, the
+// opening legs must fit the buying power and leverage room together, and
+// the net notional can be bounded against the gross. A broker that places
+// several orders atomically gets them in one request; otherwise the legs
+// are placed in turn and, when one fails, the legs already placed are
+// canceled and whatever of them filled is unwound.
+
+// ErrMultiLeg is wrapped by multi-leg orders refused before any leg was
+// placed.
+var ErrMultiLeg = errors.New("multi-leg order refused")
+
+// Multi-leg order statuses.
+const (
+	MultiLegPreview        = "preview"
+	MultiLegPlaced         = "placed"
+	MultiLegFailed         = "failed"          // atomic submission refused, nothing placed
+	MultiLegRolledBack     = "rolled_back"     // a leg failed; the others were canceled or unwound
+	MultiLegRollbackFailed = "rollback_failed" // a leg failed and some of the others could not be taken back
+)
+
+// maxLegs bounds the legs of one order.
+const maxLegs = 8
+
+// maxMultiLegHistory bounds the multi-leg orders kept in memory.
+const maxMultiLegHistory = 100
+
+// How long a rollback waits for a canceled leg to settle before reading
+// what of it filled.
+var (
+	settlePolls    = 10
+	settleInterval = 200 * time.Millisecond
+)
+
+// AtomicBroker places several orders at once, all of them or none.
+type AtomicBroker interface {
+	PlaceOrders(reqs []alpaca.PlaceOrderRequest) ([]*alpaca.Order, error)
+}
+
+// cancelingBroker takes back orders already placed, which coordinated
+// submission needs to roll back.
+type cancelingBroker interface {
+	GetOrder(orderID string) (*alpaca.Order, error)
+	CancelOrder(orderID string) error
+}
+
+// MultiLegOrder is one logical order made of a signal per leg. Legs buy,
+// sell or close; legs that do not close are sized explicitly, so the ratio
+// between them is the one intended.
+type MultiLegOrder struct {
+	Legs []*TradeSignal `json:"legs"`
+	Tag  string         `json:"tag,omitempty"` // strategy tag for legs without their own
+	// MaxNetPercent refuses the order when its net notional is more than
+	// this percent of its gross; 0 does not check
+	MaxNetPercent float64 `json:"max_net_percent,omitempty"`
+}
+
+// Validate checks the order's shape before anything is priced.
+func (o *MultiLegOrder) Validate() error {
+	if len(o.Legs) < 2 || len(o.Legs) > maxLegs {
+		return fmt.Errorf("%w: between 2 and %d legs are required", ErrMultiLeg, maxLegs)
+	}
+	if o.MaxNetPercent < 0 || o.MaxNetPercent > 100 {
+		return fmt.Errorf("%w: max_net_percent must be between 0 and 100", ErrMultiLeg)
+	}
+	seen := make(map[string]bool, len(o.Legs))
+	for i, leg := range o.Legs {
+		if leg == nil || leg.Symbol == "" {
+			return fmt.Errorf("%w: leg %d has no symbol", ErrMultiLeg, i+1)
+		}
+		leg.Symbol = strings.ToUpper(leg.Symbol)
+		if seen[leg.Symbol] {
+			return fmt.Errorf("%w: %s is in more than one leg", ErrMultiLeg, leg.Symbol)
+		}
+		seen[leg.Symbol] = true
+		switch leg.Signal {
+		case SignalBuy, SignalSell:
+			if leg.Size == nil {
+				return fmt.Errorf("%w: leg %d (%s) needs a qty, notional or percent_of_equity", ErrMultiLeg, i+1, leg.Symbol)
+			}
+			if err := leg.Size.Validate(); err != nil {
+				return fmt.Errorf("%w: leg %d (%s): %v", ErrMultiLeg, i+1, leg.Symbol, err)
+			}
+		case SignalClose:
+		default:
+			return fmt.Errorf("%w: leg %d (%s) must buy, sell or close", ErrMultiLeg, i+1, leg.Symbol)
+		}
+		if err := leg.ValidateOrder(); err != nil {
+			return fmt.Errorf("%w: leg %d (%s): %v", ErrMultiLeg, i+1, leg.Symbol, err)
+		}
+	}
+	return nil
+}
+
+// MultiLegRisk is a multi-leg order's risk taken as a whole.
+type MultiLegRisk struct {
+	GrossNotional   float64 `json:"gross_notional"`
+	NetNotional     float64 `json:"net_notional"` // bought less sold
+	NetPercent      float64 `json:"net_percent"`  // |net| ÷ gross
+	OpeningNotional float64 `json:"opening_notional"`
+	// Available is the buying power and leverage room the opening legs
+	// must fit together, -1 when neither applies
+	Available float64 `json:"available"`
+}
+
+// MultiLegResult is a multi-leg order's legs and what became of them.
+type MultiLegResult struct {
+	ID        string          `json:"id"`
+	Tag       string          `json:"tag,omitempty"`
+	Status    string          `json:"status"`
+	Legs      []*OrderPreview `json:"legs"`
+	Risk      MultiLegRisk    `json:"risk"`
+	DryRun    bool            `json:"dry_run"`
+	Atomic    bool            `json:"atomic,omitempty"`  // placed in one broker request
+	Error     string          `json:"error,omitempty"`   // why a leg failed
+	Unwound   []string        `json:"unwound,omitempty"` // orders placed to reverse filled legs
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// PreviewMultiLeg prices and checks order without placing anything.
+func (a *TradingAlgorithm) PreviewMultiLeg(order *MultiLegOrder) (*MultiLegResult, error) {
+	_, result, err := a.buildMultiLeg(order)
+	if err != nil {
+		return nil, err
+	}
+	for _, leg := range result.Legs {
+		leg.DryRun = true
+	}
+	result.DryRun = true
+	result.Status = MultiLegPreview
+	return result, nil
+}
+
+// ExecuteMultiLeg prices, checks and places order. A leg that fails after
+// others were placed rolls them back; the result, returned with the
+// error, says how that went.
+func (a *TradingAlgorithm) ExecuteMultiLeg(order *MultiLegOrder) (*MultiLegResult, error) {
+	legs, result, err := a.buildMultiLeg(order)
+	if err != nil {
+		return nil, err
+	}
+	a.mu.RLock()
+	broker := a.client
+	a.mu.RUnlock()
+	if broker == nil {
+		return nil, errors.New("alpaca client not configured")
+	}
+
+	err = a.placeLegs(broker, legs, result)
+	a.mu.Lock()
+	a.multiLegs = append(a.multiLegs, *result)
+	if over := len(a.multiLegs) - maxMultiLegHistory; over > 0 {
+		a.multiLegs = a.multiLegs[over:]
+	}
+	a.mu.Unlock()
+	return result, err
+}
+
+// MultiLegOrders returns the multi-leg orders placed or attempted, most
+// recent first.
+func (a *TradingAlgorithm) MultiLegOrders() []MultiLegResult {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	out := make([]MultiLegResult, len(a.multiLegs))
+	for i, r := range a.multiLegs {
+		out[len(a.multiLegs)-1-i] = r
+	}
+	return out
+}
+
+// buildMultiLeg runs each leg through the guards and the order builder
+// and checks the legs' combined risk.
+func (a *TradingAlgorithm) buildMultiLeg(order *MultiLegOrder) ([]*TradeSignal, *MultiLegResult, error) {
+	if order == nil {
+		return nil, nil, fmt.Errorf("%w: no order", ErrMultiLeg)
+	}
+	if err := order.Validate(); err != nil {
+		return nil, nil, err
+	}
+	tag, err := NormalizeTag(order.Tag)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The guards run first, since they may refresh the portfolio the legs
+	// are then sized against
+	legs := make([]*TradeSignal, len(order.Legs))
+	for i, in := range order.Legs {
+		leg := *in
+		if leg.Tag == "" {
+			leg.Tag = tag
+		}
+		if leg.Execution, err = NormalizeExecution(leg.Execution); err != nil {
+			return nil, nil, fmt.Errorf("%w: leg %d (%s): %v", ErrMultiLeg, i+1, leg.Symbol, err)
+		}
+		if err := a.CheckTradeGuards(&leg); err != nil {
+			return nil, nil, fmt.Errorf("%w: leg %d (%s): %v", ErrMultiLeg, i+1, leg.Symbol, err)
+		}
+		legs[i] = &leg
+	}
+
+	a.mu.RLock()
+	portfolio := a.portfolio
+	riskParams := a.sizingParamsLocked()
+	exposure := a.exposureLocked(portfolio, riskParams)
+	prices := make(map[string]float64, len(legs))
+	for _, leg := range legs {
+		if md, ok := a.marketData[leg.Symbol]; ok {
+			prices[leg.Symbol] = md.Price
+		}
+	}
+	a.mu.RUnlock()
+
+	result := &MultiLegResult{ID: newMultiLegID(), Tag: tag, CreatedAt: a.now()}
+	for i, leg := range legs {
+		price, ok := prices[leg.Symbol]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: market data not found for symbol: %s", ErrMultiLeg, leg.Symbol)
+		}
+		preview, err := a.buildOrder(leg, price, portfolio, riskParams)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: leg %d (%s): %v", ErrMultiLeg, i+1, leg.Symbol, err)
+		}
+		if preview == nil {
+			return nil, nil, fmt.Errorf("%w: leg %d (%s %s) needs no order against the position held", ErrMultiLeg, i+1, leg.Signal, leg.Symbol)
+		}
+		result.Legs = append(result.Legs, preview)
+	}
+
+	result.Risk = combinedRisk(result.Legs, exposure)
+	if r := result.Risk; r.Available >= 0 && r.OpeningNotional > r.Available {
+		return nil, nil, fmt.Errorf("%w: %w: $%.2f across the opening legs is more than the $%.2f available",
+			ErrMultiLeg, ErrBuyingPower, r.OpeningNotional, r.Available)
+	}
+	if r := result.Risk; order.MaxNetPercent > 0 && r.NetPercent > order.MaxNetPercent {
+		return nil, nil, fmt.Errorf("%w: net notional $%.2f is %.2f%% of the gross, over max_net_percent %.2f",
+			ErrMultiLeg, r.NetNotional, r.NetPercent, order.MaxNetPercent)
+	}
+	return legs, result, nil
+}
+
+// combinedRisk totals the legs' notional against the room left.
+func combinedRisk(legs []*OrderPreview, e Exposure) MultiLegRisk {
+	r := MultiLegRisk{Available: e.Available}
+	for _, leg := range legs {
+		cost := leg.EstimatedCost.InexactFloat64()
+		r.GrossNotional += cost
+		if leg.Request.Side == alpaca.Buy {
+			r.NetNotional += cost
+		} else {
+			r.NetNotional -= cost
+		}
+		if leg.Request.PositionIntent == alpaca.BuyToOpen || leg.Request.PositionIntent == alpaca.SellToOpen {
+			r.OpeningNotional += cost
+		}
+	}
+	if r.GrossNotional > 0 {
+		r.NetPercent = math.Round(math.Abs(r.NetNotional)/r.GrossNotional*10000) / 100
+	}
+	r.GrossNotional = math.Round(r.GrossNotional*100) / 100
+	r.NetNotional = math.Round(r.NetNotional*100) / 100
+	r.OpeningNotional = math.Round(r.OpeningNotional*100) / 100
+	return r
+}
+
+// placeLegs submits the legs, atomically when the broker can, and rolls
+// back on a failed leg when it cannot.
+func (a *TradingAlgorithm) placeLegs(broker Broker, legs []*TradeSignal, result *MultiLegResult) error {
+	reqs := make([]alpaca.PlaceOrderRequest, len(result.Legs))
+	for i, preview := range result.Legs {
+		reqs[i] = preview.Request
+	}
+
+	if atomic, ok := broker.(AtomicBroker); ok {
+		orders, err := atomic.PlaceOrders(reqs)
+		if err != nil {
+			result.Status, result.Error = MultiLegFailed, err.Error()
+			return fmt.Errorf("failed to place multi-leg order: %w", err)
+		}
+		result.Atomic = true
+		for i, order := range orders {
+			a.legPlaced(legs[i], result.Legs[i], order)
+		}
+		result.Status = MultiLegPlaced
+		logger().Info("Multi-leg order placed", "id", result.ID, "legs", len(orders), "atomic", true)
+		return nil
+	}
+
+	canceler, ok := broker.(cancelingBroker)
+	if !ok {
+		result.Status, result.Error = MultiLegFailed, "the broker can neither place orders together nor cancel them"
+		return errors.New("failed to place multi-leg order: " + result.Error)
+	}
+	var placed []*alpaca.Order
+	for i, req := range reqs {
+		order, err := broker.PlaceOrder(req)
+		if err != nil {
+			result.Error = fmt.Sprintf("leg %d (%s): %v", i+1, req.Symbol, err)
+			failures := a.rollBack(canceler, broker, legs, placed, result)
+			result.Status = MultiLegRolledBack
+			if failures > 0 {
+				result.Status = MultiLegRollbackFailed
+				logger().Error("Multi-leg rollback incomplete", "id", result.ID, "failed_legs", failures, "error", result.Error)
+				return fmt.Errorf("failed to place multi-leg order: %s; %d placed legs could not be rolled back", result.Error, failures)
+			}
+			logger().Warn("Multi-leg order rolled back", "id", result.ID, "placed", len(placed), "error", result.Error)
+			return fmt.Errorf("failed to place multi-leg order: %s; %d placed legs rolled back", result.Error, len(placed))
+		}
+		a.legPlaced(legs[i], result.Legs[i], order)
+		placed = append(placed, order)
+	}
+	result.Status = MultiLegPlaced
+	logger().Info("Multi-leg order placed", "id", result.ID, "legs", len(placed), "atomic", false)
+	return nil
+}
+
+// legPlaced records a placed leg and hands it on like any other order.
+func (a *TradingAlgorithm) legPlaced(leg *TradeSignal, preview *OrderPreview, order *alpaca.Order) {
+	preview.Submitted = true
+	preview.OrderID = order.ID
+	a.TrackOrder(leg, order)
+}
+
+// rollBack cancels the placed legs, newest first, and reverses what each
+// filled with a market order. It returns how many could not be taken
+// back.
+func (a *TradingAlgorithm) rollBack(canceler cancelingBroker, broker Broker, legs []*TradeSignal, placed []*alpaca.Order, result *MultiLegResult) int {
+	failures := 0
+	for i := len(placed) - 1; i >= 0; i-- {
+		order := placed[i]
+		if err := canceler.CancelOrder(order.ID); err != nil {
+			// Already filled, most likely; what filled is read next
+			logger().Debug("Multi-leg cancel refused", "order_id", order.ID, "error", err)
+		}
+		current, err := settled(canceler, order.ID)
+		if err != nil {
+			logger().Error("Failed to read rolled back leg", "order_id", order.ID, "error", err)
+			failures++
+			continue
+		}
+		if !current.FilledQty.IsPositive() {
+			continue
+		}
+		unwind := reverseLeg(legs[i], current, a.now())
+		req := alpaca.PlaceOrderRequest{
+			Symbol:         current.Symbol,
+			Qty:            &current.FilledQty,
+			Side:           alpaca.Side(unwind.Signal),
+			Type:           alpaca.Market,
+			TimeInForce:    alpaca.Day,
+			ClientOrderID:  NewClientOrderID(unwind.OrderTag()),
+			PositionIntent: reverseIntent[order.PositionIntent],
+		}
+		reversed, err := broker.PlaceOrder(req)
+		if err != nil {
+			logger().Error("Failed to unwind filled leg", "order_id", order.ID, "symbol", current.Symbol, "qty", current.FilledQty.String(), "error", err)
+			failures++
+			continue
+		}
+		result.Unwound = append(result.Unwound, reversed.ID)
+		a.TrackOrder(unwind, reversed)
+	}
+	return failures
+}
+
+// settled reads an order until it stops working, or gives up waiting and
+// returns the last read.
+func settled(canceler cancelingBroker, orderID string) (*alpaca.Order, error) {
+	var order *alpaca.Order
+	var err error
+	for i := 0; i < settlePolls; i++ {
+		if order, err = canceler.GetOrder(orderID); err != nil {
+			return nil, err
+		}
+		switch order.Status {
+		case "filled", "canceled", "expired", "rejected", "done_for_day":
+			return order, nil
+		}
+		time.Sleep(settleInterval)
+	}
+	return order, nil
+}
+
+// reverseIntent maps a leg's position intent to its unwind's.
+var reverseIntent = map[alpaca.PositionIntent]alpaca.PositionIntent{
+	alpaca.BuyToOpen:   alpaca.SellToClose,
+	alpaca.SellToClose: alpaca.BuyToOpen,
+	alpaca.SellToOpen:  alpaca.BuyToClose,
+	alpaca.BuyToClose:  alpaca.SellToOpen,
+}
+
+// reverseLeg is the signal for the market order that unwinds what leg
+// filled.
+func reverseLeg(leg *TradeSignal, order *alpaca.Order, now time.Time) *TradeSignal {
+	signal := SignalSell
+	if order.Side == alpaca.Sell {
+		signal = SignalBuy
+	}
+	qty, _ := order.FilledQty.Float64()
+	return &TradeSignal{
+		Symbol:    order.Symbol,
+		Signal:    signal,
+		OrderType: OrderTypeMarket,
+		Timestamp: now,
+		Reasoning: "unwinds a multi-leg order that failed",
+		Source:    leg.Source,
+		Tag:       leg.Tag,
+		Size:      &TradeSize{Qty: qty},
+	}
+}
+
+// newMultiLegID names a multi-leg order.
+func newMultiLegID() string {
+	return "mleg_" + strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatUint(clientOrderSeq.Add(1), 36)
+}
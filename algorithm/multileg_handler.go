@@ -0,0 +1,113 @@
+package algorithm
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// MultiLegHandler serves multi-leg orders over HTTP.
+type MultiLegHandler struct {
+	algo *TradingAlgorithm
+	// preflight may refuse an order after it is priced and before any leg
+	// is placed
+	preflight func(legs []*TradeSignal, previews []*OrderPreview) error
+}
+
+// NewMultiLegHandler creates a handler placing orders through algo.
+func NewMultiLegHandler(algo *TradingAlgorithm) *MultiLegHandler {
+	return &MultiLegHandler{algo: algo}
+}
+
+// SetPreflight sets a check every order must pass before it is placed.
+func (h *MultiLegHandler) SetPreflight(fn func(legs []*TradeSignal, previews []*OrderPreview) error) {
+	h.preflight = fn
+}
+
+// RegisterRoutes registers the multi-leg order routes with mux.
+func (h *MultiLegHandler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/orders/multileg - recent multi-leg orders, newest first
+	// POST /api/orders/multileg?dry_run=true - price, check and place one
+	mux.HandleFunc("/api/orders/multileg", h.handleMultiLeg)
+}
+
+// multiLegRequest is a multi-leg order as posted.
+type multiLegRequest struct {
+	MultiLegOrder
+	DryRun bool `json:"dry_run"`
+}
+
+func (h *MultiLegHandler) handleMultiLeg(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		writeInstanceJSON(w, http.StatusOK, h.algo.MultiLegOrders())
+	case http.MethodPost:
+		var req multiLegRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		order := &req.MultiLegOrder
+		if order.Tag == "" {
+			order.Tag = TagManual
+		}
+		now := h.algo.now()
+		for _, leg := range order.Legs {
+			if leg == nil {
+				continue
+			}
+			if leg.Source == "" {
+				leg.Source = "manual"
+			}
+			if leg.Timestamp.IsZero() {
+				leg.Timestamp = now
+			}
+		}
+
+		// Every order is previewed first, so the preflight sees the legs as
+		// they would be placed
+		preview, err := h.algo.PreviewMultiLeg(order)
+		if err != nil {
+			writeInstanceJSON(w, multiLegErrorStatus(err), map[string]interface{}{"success": false, "error": err.Error()})
+			return
+		}
+		if h.preflight != nil {
+			if err := h.preflight(order.Legs, preview.Legs); err != nil {
+				writeInstanceJSON(w, http.StatusConflict, map[string]interface{}{"success": false, "error": err.Error(), "blocked": true})
+				return
+			}
+		}
+		if req.DryRun || r.URL.Query().Get("dry_run") == "true" {
+			writeInstanceJSON(w, http.StatusOK, map[string]interface{}{"success": true, "order": preview})
+			return
+		}
+
+		result, err := h.algo.ExecuteMultiLeg(order)
+		if err != nil {
+			if result == nil {
+				writeInstanceJSON(w, multiLegErrorStatus(err), map[string]interface{}{"success": false, "error": err.Error()})
+				return
+			}
+			writeInstanceJSON(w, http.StatusBadGateway, map[string]interface{}{"success": false, "error": err.Error(), "order": result})
+			return
+		}
+		writeInstanceJSON(w, http.StatusCreated, map[string]interface{}{"success": true, "order": result})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// multiLegErrorStatus maps errors refusing an order before any leg was
+// placed to HTTP statuses.
+func multiLegErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrBuyingPower):
+		return http.StatusConflict
+	case errors.Is(err, ErrMultiLeg):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
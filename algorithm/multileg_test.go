@@ -0,0 +1,179 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
+)
+
+// legBroker places orders in turn, failing the symbols in fail and
+// filling fillQty of each order it places.
+type legBroker struct {
+	fail     map[string]bool
+	fillQty  decimal.Decimal
+	placed   []alpaca.PlaceOrderRequest
+	orders   map[string]*alpaca.Order
+	canceled []string
+}
+
+func (b *legBroker) GetAccount() (*alpaca.Account, error)     { return &alpaca.Account{}, nil }
+func (b *legBroker) GetPositions() ([]alpaca.Position, error) { return nil, nil }
+
+func (b *legBroker) PlaceOrder(req alpaca.PlaceOrderRequest) (*alpaca.Order, error) {
+	if b.fail[req.Symbol] {
+		return nil, errors.New("rejected")
+	}
+	b.placed = append(b.placed, req)
+	order := &alpaca.Order{ID: fmt.Sprintf("o%d", len(b.placed)), Symbol: req.Symbol, Side: req.Side, Qty: req.Qty,
+		PositionIntent: req.PositionIntent, Status: "new"}
+	if b.orders == nil {
+		b.orders = map[string]*alpaca.Order{}
+	}
+	b.orders[order.ID] = order
+	return order, nil
+}
+
+func (b *legBroker) GetOrder(orderID string) (*alpaca.Order, error) { return b.orders[orderID], nil }
+
+func (b *legBroker) CancelOrder(orderID string) error {
+	b.canceled = append(b.canceled, orderID)
+	order := b.orders[orderID]
+	order.Status, order.FilledQty = "canceled", b.fillQty
+	return nil
+}
+
+// atomicLegBroker places every order in one request.
+type atomicLegBroker struct {
+	legBroker
+	batches int
+}
+
+func (b *atomicLegBroker) PlaceOrders(reqs []alpaca.PlaceOrderRequest) ([]*alpaca.Order, error) {
+	b.batches++
+	var orders []*alpaca.Order
+	for _, req := range reqs {
+		order, err := b.PlaceOrder(req)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	return orders, nil
+}
+
+func newPairAlgorithm(broker Broker) *TradingAlgorithm {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	a.SetBroker(broker)
+	a.portfolio = PortfolioData{TotalValue: 100000, BuyingPower: 10000, Multiplier: 1, Positions: map[string]PositionData{}}
+	a.portfolioAt = time.Now()
+	a.marketData["KO"] = MarketData{Symbol: "KO", Price: 60}
+	a.marketData["PEP"] = MarketData{Symbol: "PEP", Price: 170}
+	return a
+}
+
+// pair shorts $1,200 of KO against $1,190 of PEP.
+func pair() *MultiLegOrder {
+	return &MultiLegOrder{Tag: "pairs", Legs: []*TradeSignal{
+		{Symbol: "ko", Signal: SignalSell, OrderType: OrderTypeMarket, Size: &TradeSize{Qty: 20}},
+		{Symbol: "PEP", Signal: SignalBuy, OrderType: OrderTypeMarket, Size: &TradeSize{Qty: 7}},
+	}}
+}
+
+func TestMultiLegPlacedAtomically(t *testing.T) {
+	broker := &atomicLegBroker{}
+	a := newPairAlgorithm(broker)
+	var tracked []string
+	a.SetOrderHandler(func(signal *TradeSignal, order *alpaca.Order) { tracked = append(tracked, order.Symbol) })
+
+	result, err := a.ExecuteMultiLeg(pair())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != MultiLegPlaced || !result.Atomic || broker.batches != 1 || len(broker.placed) != 2 {
+		t.Fatalf("result = %+v, %d batches", result, broker.batches)
+	}
+	if broker.placed[0].PositionIntent != alpaca.SellToOpen || broker.placed[1].PositionIntent != alpaca.BuyToOpen {
+		t.Errorf("intents = %s, %s", broker.placed[0].PositionIntent, broker.placed[1].PositionIntent)
+	}
+	if r := result.Risk; r.GrossNotional != 2390 || r.NetNotional != -10 || r.OpeningNotional != 2390 {
+		t.Errorf("risk = %+v", r)
+	}
+	if len(tracked) != 2 || result.Legs[1].OrderID != "o2" || len(a.MultiLegOrders()) != 1 {
+		t.Errorf("tracked = %v, legs = %+v", tracked, result.Legs)
+	}
+}
+
+func TestMultiLegRollsBackPlacedLegs(t *testing.T) {
+	settlePolls, settleInterval = 1, 0
+	broker := &legBroker{fail: map[string]bool{"PEP": true}, fillQty: decimal.NewFromInt(5)}
+	a := newPairAlgorithm(broker)
+
+	result, err := a.ExecuteMultiLeg(pair())
+	if err == nil || result == nil || result.Status != MultiLegRolledBack {
+		t.Fatalf("result = %+v, err = %v", result, err)
+	}
+	// The KO short was canceled and the 5 shares it sold bought back
+	if len(broker.canceled) != 1 || broker.canceled[0] != "o1" || len(broker.placed) != 2 {
+		t.Fatalf("canceled = %v, placed = %+v", broker.canceled, broker.placed)
+	}
+	unwind := broker.placed[1]
+	if unwind.Symbol != "KO" || unwind.Side != alpaca.Buy || !unwind.Qty.Equal(decimal.NewFromInt(5)) ||
+		unwind.PositionIntent != alpaca.BuyToClose || len(result.Unwound) != 1 {
+		t.Errorf("unwind = %+v, unwound = %v", unwind, result.Unwound)
+	}
+
+	// Without a way to cancel, nothing is placed
+	a.SetBroker(noCancelBroker{})
+	if result, err := a.ExecuteMultiLeg(pair()); err == nil || result == nil || result.Status != MultiLegFailed {
+		t.Errorf("without cancel = %+v, %v", result, err)
+	}
+}
+
+// noCancelBroker can only place orders.
+type noCancelBroker struct{}
+
+func (noCancelBroker) GetAccount() (*alpaca.Account, error)     { return &alpaca.Account{}, nil }
+func (noCancelBroker) GetPositions() ([]alpaca.Position, error) { return nil, nil }
+func (noCancelBroker) PlaceOrder(alpaca.PlaceOrderRequest) (*alpaca.Order, error) {
+	panic("placed an order it could not take back")
+}
+
+func TestMultiLegRiskTakenTogether(t *testing.T) {
+	broker := &atomicLegBroker{}
+	a := newPairAlgorithm(broker)
+
+	// Each leg fits the $2,000 of buying power on its own, not together
+	a.portfolio.BuyingPower = 2000
+	if _, err := a.PreviewMultiLeg(pair()); !errors.Is(err, ErrBuyingPower) || !errors.Is(err, ErrMultiLeg) {
+		t.Errorf("over buying power = %v", err)
+	}
+	a.portfolio.BuyingPower = 10000
+
+	order := pair()
+	order.Legs[1].Size = &TradeSize{Qty: 3}
+	order.MaxNetPercent = 10
+	if _, err := a.PreviewMultiLeg(order); !errors.Is(err, ErrMultiLeg) {
+		t.Errorf("unbalanced = %v", err)
+	}
+
+	for name, legs := range map[string][]*TradeSignal{
+		"one leg":    {{Symbol: "KO", Signal: SignalSell, Size: &TradeSize{Qty: 1}}},
+		"same twice": {{Symbol: "KO", Signal: SignalSell, Size: &TradeSize{Qty: 1}}, {Symbol: "ko", Signal: SignalBuy, Size: &TradeSize{Qty: 1}}},
+		"unsized":    {{Symbol: "KO", Signal: SignalSell}, {Symbol: "PEP", Signal: SignalBuy, Size: &TradeSize{Qty: 1}}},
+		"hold":       {{Symbol: "KO", Signal: SignalHold}, {Symbol: "PEP", Signal: SignalBuy, Size: &TradeSize{Qty: 1}}},
+	} {
+		if _, err := a.PreviewMultiLeg(&MultiLegOrder{Legs: legs}); !errors.Is(err, ErrMultiLeg) {
+			t.Errorf("%s = %v", name, err)
+		}
+	}
+
+	preview, err := a.PreviewMultiLeg(pair())
+	if err != nil || !preview.DryRun || preview.Status != MultiLegPreview || len(broker.placed) != 0 {
+		t.Errorf("preview = %+v, %v", preview, err)
+	}
+}
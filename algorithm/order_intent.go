@@ -0,0 +1,58 @@
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+)
+
+// ErrNoPosition is returned for close signals on a symbol with no
+// position.
+var ErrNoPosition = errors.New("no position to close")
+
+// OrderIntent is the side and position intent of the order that carries
+// out a signal against the position held. The intent tells the broker
+// whether the order opens or closes, so a buy against a short covers it
+// rather than being taken for a new long.
+type OrderIntent struct {
+	Side   alpaca.Side           `json:"side"`
+	Intent alpaca.PositionIntent `json:"position_intent"`
+	// Reduces is the size of the position a closing order reduces, and
+	// zero for an order that opens or adds to one
+	Reduces float64 `json:"reduces,omitempty"`
+}
+
+// Opens reports whether the order opens or adds to a position.
+func (i OrderIntent) Opens() bool { return i.Reduces == 0 }
+
+// ResolveIntent returns the order that carries out signal against held,
+// the signed quantity of the position in the symbol, negative for a short.
+// A buy covers a short and otherwise opens or adds to a long; a sell
+// closes a long and otherwise opens or adds to a short; a close flattens
+// either. ok is false for holds, which need no order.
+func ResolveIntent(signal string, held float64) (intent OrderIntent, ok bool, err error) {
+	switch signal {
+	case SignalBuy:
+		if held < 0 {
+			return OrderIntent{Side: alpaca.Buy, Intent: alpaca.BuyToClose, Reduces: -held}, true, nil
+		}
+		return OrderIntent{Side: alpaca.Buy, Intent: alpaca.BuyToOpen}, true, nil
+	case SignalSell:
+		if held > 0 {
+			return OrderIntent{Side: alpaca.Sell, Intent: alpaca.SellToClose, Reduces: held}, true, nil
+		}
+		return OrderIntent{Side: alpaca.Sell, Intent: alpaca.SellToOpen}, true, nil
+	case SignalClose:
+		switch {
+		case held > 0:
+			return OrderIntent{Side: alpaca.Sell, Intent: alpaca.SellToClose, Reduces: held}, true, nil
+		case held < 0:
+			return OrderIntent{Side: alpaca.Buy, Intent: alpaca.BuyToClose, Reduces: -held}, true, nil
+		}
+		return OrderIntent{}, false, ErrNoPosition
+	case SignalHold:
+		return OrderIntent{}, false, nil
+	}
+	return OrderIntent{}, false, fmt.Errorf("unknown signal type: %s", signal)
+}
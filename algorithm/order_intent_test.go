@@ -0,0 +1,144 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
+)
+
+func TestResolveIntent(t *testing.T) {
+	cases := []struct {
+		signal  string
+		held    float64
+		intent  alpaca.PositionIntent
+		reduces float64
+	}{
+		{SignalBuy, 0, alpaca.BuyToOpen, 0},
+		{SignalBuy, 10, alpaca.BuyToOpen, 0},
+		{SignalBuy, -10, alpaca.BuyToClose, 10},
+		{SignalSell, 10, alpaca.SellToClose, 10},
+		{SignalSell, 0, alpaca.SellToOpen, 0},
+		{SignalSell, -10, alpaca.SellToOpen, 0},
+		{SignalClose, 10, alpaca.SellToClose, 10},
+		{SignalClose, -10, alpaca.BuyToClose, 10},
+	}
+	for _, c := range cases {
+		got, ok, err := ResolveIntent(c.signal, c.held)
+		if err != nil || !ok || got.Intent != c.intent || got.Reduces != c.reduces {
+			t.Errorf("%s against %v = %+v, %v, %v; want %s reducing %v", c.signal, c.held, got, ok, err, c.intent, c.reduces)
+		}
+	}
+	if _, _, err := ResolveIntent(SignalClose, 0); !errors.Is(err, ErrNoPosition) {
+		t.Errorf("close while flat: %v", err)
+	}
+	if _, ok, err := ResolveIntent(SignalHold, 10); ok || err != nil {
+		t.Errorf("hold: %v, %v", ok, err)
+	}
+	if _, _, err := ResolveIntent("short", 0); err == nil {
+		t.Error("unknown signal accepted")
+	}
+}
+
+func TestBuildOrderCoversShorts(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	portfolio := PortfolioData{
+		TotalValue: 10000,
+		Positions: map[string]PositionData{
+			"AAPL": {Symbol: "AAPL", Quantity: -30},
+		},
+	}
+	params := a.sizingParamsLocked()
+
+	// A buy against the short covers all of it rather than opening a long
+	preview, err := a.buildOrder(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy, OrderType: "market"}, 100, portfolio, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := preview.Request
+	if req.Side != alpaca.Buy || req.PositionIntent != alpaca.BuyToClose || !req.Qty.Equal(decimal.NewFromInt(30)) {
+		t.Errorf("cover = %s %s %s", req.Side, req.PositionIntent, req.Qty)
+	}
+
+	// An explicit size covers part of it
+	preview, err = a.buildOrder(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy, OrderType: "market", Size: &TradeSize{Qty: 10}}, 100, portfolio, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !preview.Request.Qty.Equal(decimal.NewFromInt(10)) {
+		t.Errorf("partial cover qty = %s", preview.Request.Qty)
+	}
+
+	// A close signal flattens regardless of size
+	preview, err = a.buildOrder(&TradeSignal{Symbol: "AAPL", Signal: SignalClose, OrderType: "market", Size: &TradeSize{Qty: 10}}, 100, portfolio, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req := preview.Request; req.PositionIntent != alpaca.BuyToClose || !req.Qty.Equal(decimal.NewFromInt(30)) {
+		t.Errorf("close = %s %s", req.PositionIntent, req.Qty)
+	}
+
+	// A sell with nothing held opens a short
+	preview, err = a.buildOrder(&TradeSignal{Symbol: "MSFT", Signal: SignalSell, OrderType: "market"}, 100, portfolio, params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req := preview.Request; req.Side != alpaca.Sell || req.PositionIntent != alpaca.SellToOpen || !req.Qty.IsPositive() {
+		t.Errorf("short = %s %s %s", req.Side, req.PositionIntent, req.Qty)
+	}
+}
+
+func TestBuildOrderRefusesLessThanOneShare(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	portfolio := PortfolioData{TotalValue: 1000, Positions: map[string]PositionData{}}
+
+	// 5% of $1,000 does not buy one share at $500
+	_, err := a.buildOrder(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy, OrderType: "market"}, 500, portfolio, a.sizingParamsLocked())
+	if err == nil {
+		t.Fatal("zero-share order built")
+	}
+}
+
+func TestExecuteTradeSubmits(t *testing.T) {
+	broker := &legBroker{}
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	a.SetBroker(broker)
+	a.portfolio = PortfolioData{TotalValue: 100000, BuyingPower: 100000, Multiplier: 1, Positions: map[string]PositionData{}}
+	a.portfolioAt = time.Now()
+	a.marketData["AAPL"] = MarketData{Symbol: "AAPL", Price: 100}
+
+	preview, err := a.ExecuteTrade(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy, OrderType: "market", Source: "test"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !preview.Submitted || preview.DryRun || preview.OrderID != "o1" {
+		t.Fatalf("preview = %+v", preview)
+	}
+	if len(broker.placed) != 1 {
+		t.Fatalf("placed %d orders", len(broker.placed))
+	}
+	req := broker.placed[0]
+	if req.Side != alpaca.Buy || req.PositionIntent != alpaca.BuyToOpen || !req.Qty.Equal(decimal.NewFromInt(50)) || TagFromClientOrderID(req.ClientOrderID) != "test" {
+		t.Errorf("placed %s %s %s %q", req.Side, req.PositionIntent, req.Qty, req.ClientOrderID)
+	}
+
+	// A dry run builds the same order without placing it
+	if _, err := a.ExecuteTrade(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy, OrderType: "market"}, true); err != nil {
+		t.Fatal(err)
+	}
+	if len(broker.placed) != 1 {
+		t.Errorf("dry run placed an order")
+	}
+
+	// Less than one share is refused before the broker is asked
+	a.marketData["AAPL"] = MarketData{Symbol: "AAPL", Price: 10000}
+	if _, err := a.ExecuteTrade(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy, OrderType: "market"}, false); err == nil {
+		t.Error("zero-share order submitted")
+	}
+	if len(broker.placed) != 1 {
+		t.Errorf("placed %d orders", len(broker.placed))
+	}
+}
@@ -0,0 +1,45 @@
+package algorithm
+
+import (
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
+)
+
+// OrderPreview is the fully-formed broker payload for a signal together with
+// the estimates that went into building it. Dry-run executions return it
+// without submitting anything so the UI can show a confirm step.
+type OrderPreview struct {
+	Request       alpaca.PlaceOrderRequest `json:"request"`
+	MarketPrice   float64                  `json:"market_price"`
+	EstimatedCost decimal.Decimal          `json:"estimated_cost"`
+	DryRun        bool                     `json:"dry_run"`
+	Submitted     bool                     `json:"submitted"`
+	OrderID       string                   `json:"order_id,omitempty"`
+	ParentID      string                   `json:"parent_id,omitempty"` // set when sliced into child orders
+	Queued        bool                     `json:"queued,omitempty"`    // set when a transient failure queued it for retry
+}
+
+// NewOrderPreview wraps a PlaceOrderRequest and estimates its cost. Limit
+// and stop-limit orders are priced at the limit, stop orders at the stop
+// and everything else at the market price.
+func NewOrderPreview(req alpaca.PlaceOrderRequest, marketPrice float64) *OrderPreview {
+	price := decimal.NewFromFloat(marketPrice)
+	if req.LimitPrice != nil {
+		price = *req.LimitPrice
+	} else if req.StopPrice != nil {
+		price = *req.StopPrice
+	}
+
+	cost := decimal.Zero
+	if req.Qty != nil {
+		cost = req.Qty.Abs().Mul(price).Round(2)
+	} else if req.Notional != nil {
+		cost = req.Notional.Round(2)
+	}
+
+	return &OrderPreview{
+		Request:       req,
+		MarketPrice:   marketPrice,
+		EstimatedCost: cost,
+	}
+}
@@ -0,0 +1,91 @@
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// MaxTagLength bounds an order tag so the client order ID stays well under
+// Alpaca's 128 characters.
+const MaxTagLength = 48
+
+// TagSeparator ends the tag prefix of a client order ID. Tags never
+// contain it.
+const TagSeparator = ":"
+
+// TagManual tags trades placed from the UI without a tag of their own.
+const TagManual = "manual"
+
+// ErrInvalidTag is wrapped by tag validation failures.
+var ErrInvalidTag = errors.New("invalid order tag")
+
+// clientOrderSeq keeps client order IDs made in the same nanosecond apart.
+var clientOrderSeq atomic.Uint64
+
+// NormalizeTag trims tag and checks it is at most MaxTagLength letters,
+// digits, '-', '_' or '.'. An empty tag is valid.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.TrimSpace(tag)
+	if len(tag) > MaxTagLength {
+		return "", fmt.Errorf("%w: %q is longer than %d characters", ErrInvalidTag, tag, MaxTagLength)
+	}
+	for _, r := range tag {
+		if !isTagRune(r) {
+			return "", fmt.Errorf("%w: %q may only contain letters, digits, '-', '_' and '.'", ErrInvalidTag, tag)
+		}
+	}
+	return tag, nil
+}
+
+func isTagRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.'
+}
+
+// sanitizeTag turns free text such as a signal source into a valid tag.
+func sanitizeTag(s string) string {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(s) {
+		if b.Len() == MaxTagLength {
+			break
+		}
+		if isTagRune(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('-')
+		}
+	}
+	return b.String()
+}
+
+// OrderTag is the tag orders for the signal carry: its own tag, or else
+// its source, so algorithm and Claude orders are attributed too.
+func (s *TradeSignal) OrderTag() string {
+	if s.Tag != "" {
+		return s.Tag
+	}
+	return sanitizeTag(s.Source)
+}
+
+// NewClientOrderID returns a unique client order ID prefixed with tag, as
+// "<tag>:<unique>", or an empty string for no tag so Alpaca assigns one.
+func NewClientOrderID(tag string) string {
+	if tag == "" {
+		return ""
+	}
+	seq := clientOrderSeq.Add(1)
+	return tag + TagSeparator + strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatUint(seq, 36)
+}
+
+// TagFromClientOrderID returns the tag prefix of a client order ID made by
+// NewClientOrderID, or an empty string when it has none.
+func TagFromClientOrderID(id string) string {
+	tag, _, ok := strings.Cut(id, TagSeparator)
+	if !ok {
+		return ""
+	}
+	return tag
+}
@@ -0,0 +1,46 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestOrderTags(t *testing.T) {
+	for _, bad := range []string{"has space", "a:b", strings.Repeat("x", MaxTagLength+1)} {
+		if _, err := NormalizeTag(bad); !errors.Is(err, ErrInvalidTag) {
+			t.Errorf("NormalizeTag(%q) err = %v", bad, err)
+		}
+	}
+	if tag, err := NormalizeTag(" basket.tech-1 "); err != nil || tag != "basket.tech-1" {
+		t.Errorf("NormalizeTag = %q, %v", tag, err)
+	}
+
+	id1, id2 := NewClientOrderID("mean_rev"), NewClientOrderID("mean_rev")
+	if id1 == id2 || TagFromClientOrderID(id1) != "mean_rev" {
+		t.Errorf("client order IDs %q %q", id1, id2)
+	}
+	if NewClientOrderID("") != "" || TagFromClientOrderID("3f9a-uuid") != "" {
+		t.Error("untagged IDs should carry no tag")
+	}
+
+	// Without a tag of their own, orders are attributed to the source
+	if tag := (&TradeSignal{Source: "algorithm:momentum"}).OrderTag(); tag != "algorithm-momentum" {
+		t.Errorf("source tag = %q", tag)
+	}
+}
+
+func TestBuildOrderCarriesTag(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	a.marketData["AAPL"] = MarketData{Symbol: "AAPL", Price: 100}
+	a.portfolio.TotalValue = 100000
+
+	preview, err := a.ExecuteTrade(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy, OrderType: "market", Tag: "breakout"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if TagFromClientOrderID(preview.Request.ClientOrderID) != "breakout" {
+		t.Errorf("client order ID = %q", preview.Request.ClientOrderID)
+	}
+}
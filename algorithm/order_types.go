@@ -0,0 +1,102 @@
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Order types a signal may carry. Stop orders rest until the market trades
+// through the stop price and then go out as market orders; stop-limit
+// orders become limit orders at their limit price instead, so they never
+// fill worse than it but may not fill at all.
+const (
+	OrderTypeMarket    = "market"
+	OrderTypeLimit     = "limit"
+	OrderTypeStop      = "stop"
+	OrderTypeStopLimit = "stop_limit"
+)
+
+// ErrStopPrice is wrapped by stop and stop-limit prices that fail the
+// sanity checks.
+var ErrStopPrice = errors.New("invalid stop price")
+
+// maxStopDistance is how far from the market a stop may rest, as a
+// fraction of the price. Further away it is almost certainly a typo.
+const maxStopDistance = 0.5
+
+// NormalizeOrderType validates an order type and returns it in canonical
+// form; empty means market.
+func NormalizeOrderType(orderType string) (string, error) {
+	switch t := strings.ToLower(strings.TrimSpace(orderType)); t {
+	case "", OrderTypeMarket:
+		return OrderTypeMarket, nil
+	case OrderTypeLimit, OrderTypeStop, OrderTypeStopLimit:
+		return t, nil
+	case "stop-limit", "stoplimit":
+		return OrderTypeStopLimit, nil
+	default:
+		return "", fmt.Errorf("unknown order type %q (want market, limit, stop or stop_limit)", orderType)
+	}
+}
+
+// IsStopOrder reports whether orderType rests until a stop price trades.
+func IsStopOrder(orderType string) bool {
+	return orderType == OrderTypeStop || orderType == OrderTypeStopLimit
+}
+
+// ValidateOrder puts the signal's order type in canonical form and checks
+// that it carries the prices the type needs: a stop price for stop and
+// stop-limit orders, and a limit price as well for stop-limit orders.
+func (s *TradeSignal) ValidateOrder() error {
+	orderType, err := NormalizeOrderType(s.OrderType)
+	if err != nil {
+		return err
+	}
+	s.OrderType = orderType
+	switch {
+	case IsStopOrder(orderType) && (s.StopPrice == nil || *s.StopPrice <= 0):
+		return fmt.Errorf("%w: %s orders need a positive stop_price", ErrStopPrice, orderType)
+	case !IsStopOrder(orderType) && s.StopPrice != nil:
+		return fmt.Errorf("%w: stop_price is only for stop and stop_limit orders", ErrStopPrice)
+	case orderType == OrderTypeStopLimit && (s.LimitPrice == nil || *s.LimitPrice <= 0):
+		return fmt.Errorf("%w: stop_limit orders need a positive limit_price", ErrStopPrice)
+	}
+	return nil
+}
+
+// CheckStopPrices checks a stop or stop-limit order's prices for side
+// against the market price. A sell stop must rest below the market and a
+// buy stop above it; on the wrong side it would trigger at once. A sell
+// stop-limit's limit may not be above its stop, nor a buy's below it, or
+// it would become a limit the market has already passed when it
+// triggers. A non-positive market skips the checks against the market.
+func CheckStopPrices(side string, stop, limit, market float64) error {
+	if stop <= 0 {
+		return fmt.Errorf("%w: stop_price must be positive", ErrStopPrice)
+	}
+	switch side {
+	case SignalBuy:
+		if limit > 0 && limit < stop {
+			return fmt.Errorf("%w: buy limit $%.2f is below the stop $%.2f", ErrStopPrice, limit, stop)
+		}
+		if market > 0 && stop <= market {
+			return fmt.Errorf("%w: buy stop $%.2f is not above the market $%.2f", ErrStopPrice, stop, market)
+		}
+	case SignalSell:
+		if limit > 0 && limit > stop {
+			return fmt.Errorf("%w: sell limit $%.2f is above the stop $%.2f", ErrStopPrice, limit, stop)
+		}
+		if market > 0 && stop >= market {
+			return fmt.Errorf("%w: sell stop $%.2f is not below the market $%.2f", ErrStopPrice, stop, market)
+		}
+	default:
+		return fmt.Errorf("%w: unknown side %q", ErrStopPrice, side)
+	}
+	if market > 0 {
+		if distance := (stop - market) / market; distance > maxStopDistance || distance < -maxStopDistance {
+			return fmt.Errorf("%w: stop $%.2f is more than %.0f%% from the market $%.2f", ErrStopPrice, stop, maxStopDistance*100, market)
+		}
+	}
+	return nil
+}
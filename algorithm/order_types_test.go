@@ -0,0 +1,73 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func pricePtr(v float64) *float64 { return &v }
+
+func TestCheckStopPrices(t *testing.T) {
+	cases := []struct {
+		side                string
+		stop, limit, market float64
+		ok                  bool
+	}{
+		{SignalSell, 95, 0, 100, true},
+		{SignalSell, 95, 94.5, 100, true},
+		{SignalSell, 101, 0, 100, false}, // would trigger at once
+		{SignalSell, 95, 96, 100, false}, // limit above the stop
+		{SignalSell, 40, 0, 100, false},  // more than half away
+		{SignalBuy, 105, 105.5, 100, true},
+		{SignalBuy, 99, 0, 100, false},
+		{SignalBuy, 105, 104, 100, false},
+		{SignalBuy, 105, 0, 0, true}, // no market to check against
+		{SignalBuy, 0, 0, 100, false},
+	}
+	for _, c := range cases {
+		err := CheckStopPrices(c.side, c.stop, c.limit, c.market)
+		if (err == nil) != c.ok || (err != nil && !errors.Is(err, ErrStopPrice)) {
+			t.Errorf("%s stop %v limit %v at %v: err = %v", c.side, c.stop, c.limit, c.market, err)
+		}
+	}
+}
+
+func TestStopOrdersBuild(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	a.marketData["AAPL"] = MarketData{Symbol: "AAPL", Price: 100}
+	a.portfolio.TotalValue = 100000
+	a.portfolio.Positions["AAPL"] = PositionData{Symbol: "AAPL", Quantity: 10}
+
+	// A protective stop on the whole position, previewed at its stop
+	preview, err := a.ExecuteTrade(&TradeSignal{Symbol: "AAPL", Signal: SignalSell, OrderType: "STOP", StopPrice: pricePtr(94.999)}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Request.Type != "stop" || preview.Request.StopPrice.String() != "95" || preview.Request.LimitPrice != nil ||
+		preview.Request.Qty.String() != "10" || preview.EstimatedCost.String() != "950" {
+		t.Errorf("stop = %+v", preview.Request)
+	}
+
+	preview, err = a.ExecuteTrade(&TradeSignal{Symbol: "AAPL", Signal: SignalClose, OrderType: "stop-limit", StopPrice: pricePtr(95), LimitPrice: pricePtr(94)}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if preview.Request.Type != "stop_limit" || preview.Request.StopPrice.String() != "95" || preview.Request.LimitPrice.String() != "94" {
+		t.Errorf("stop-limit = %+v", preview.Request)
+	}
+
+	for _, s := range []*TradeSignal{
+		{Symbol: "AAPL", Signal: SignalSell, OrderType: "stop"},
+		{Symbol: "AAPL", Signal: SignalSell, OrderType: "stop", StopPrice: pricePtr(101)},
+		{Symbol: "AAPL", Signal: SignalSell, OrderType: "stop_limit", StopPrice: pricePtr(95)},
+		{Symbol: "AAPL", Signal: SignalSell, OrderType: "market", StopPrice: pricePtr(95)},
+	} {
+		if _, err := a.ExecuteTrade(s, true); !errors.Is(err, ErrStopPrice) {
+			t.Errorf("%s stop %v: err = %v", s.OrderType, s.StopPrice, err)
+		}
+	}
+	if _, err := a.ExecuteTrade(&TradeSignal{Symbol: "AAPL", Signal: SignalSell, OrderType: "trailing"}, true); err == nil {
+		t.Error("unknown order type accepted")
+	}
+}
@@ -0,0 +1,99 @@
+package algorithm
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm/algo"
+)
+
+const (
+	// patternLookbackDays is how much daily history pattern detection loads
+	patternLookbackDays = 30
+	// patternCacheTTL bounds how stale the patterns attached to Claude's
+	// market context may get before ProcessSymbol refreshes them.
+	patternCacheTTL = time.Hour
+)
+
+// PatternReport lists candlestick patterns found in a symbol's recent bars
+type PatternReport struct {
+	Symbol    string              `json:"symbol"`
+	TimeFrame string              `json:"timeframe"`
+	BarCount  int                 `json:"bar_count"`
+	Latest    []algo.PatternMatch `json:"latest"`
+	History   []algo.PatternMatch `json:"history"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// patternCacheEntry holds the latest-bar pattern names for a symbol
+type patternCacheEntry struct {
+	names     []string
+	fetchedAt time.Time
+}
+
+// GetPatterns loads recent bars for symbol and runs candlestick pattern
+// detection over them. The latest-bar patterns are cached for the Claude
+// market context.
+func (a *TradingAlgorithm) GetPatterns(symbol, timeframe string) (PatternReport, error) {
+	if timeframe == "" {
+		timeframe = "1D"
+	}
+	end := a.now()
+	history, err := a.GetBarHistory(HistoryRequest{
+		Symbol:    symbol,
+		StartDate: end.AddDate(0, 0, -patternLookbackDays),
+		EndDate:   end,
+		TimeFrame: timeframe,
+	})
+	if err != nil {
+		a.cachePatterns(symbol, nil)
+		return PatternReport{}, fmt.Errorf("failed to load bars for pattern detection: %w", err)
+	}
+
+	candles := make([]algo.Candle, len(history.Bars))
+	for i, bar := range history.Bars {
+		candles[i] = algo.Candle{
+			Timestamp: bar.Timestamp,
+			Open:      bar.Open,
+			High:      bar.High,
+			Low:       bar.Low,
+			Close:     bar.Close,
+		}
+	}
+
+	report := PatternReport{
+		Symbol:    symbol,
+		TimeFrame: timeframe,
+		BarCount:  len(candles),
+		Latest:    algo.LatestPatterns(candles),
+		History:   algo.DetectPatterns(candles),
+		UpdatedAt: end,
+	}
+
+	names := make([]string, len(report.Latest))
+	for i, m := range report.Latest {
+		names[i] = string(m.Pattern)
+	}
+	a.cachePatterns(symbol, names)
+	return report, nil
+}
+
+// cachedPatterns returns the cached latest-bar pattern names and whether
+// the entry is still fresh.
+func (a *TradingAlgorithm) cachedPatterns(symbol string) ([]string, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	entry, ok := a.patterns[symbol]
+	if !ok {
+		return nil, false
+	}
+	return entry.names, time.Since(entry.fetchedAt) < patternCacheTTL
+}
+
+// cachePatterns records the latest-bar patterns for symbol. Failed fetches
+// are cached as empty so they are not retried on every signal.
+func (a *TradingAlgorithm) cachePatterns(symbol string, names []string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.patterns[symbol] = patternCacheEntry{names: names, fetchedAt: time.Now()}
+}
@@ -0,0 +1,291 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	// portfolioMaxAge bounds how stale the positions used for cap checks
+	// may be before they are refreshed from the broker.
+	portfolioMaxAge = 30 * time.Second
+	// capQueueTTL is how long a capped signal waits for capacity.
+	capQueueTTL = 6 * time.Hour
+	// capQueueInterval is how often queued signals are retried.
+	capQueueInterval = time.Minute
+)
+
+// ErrPositionCap is wrapped by position-cap refusals.
+var ErrPositionCap = errors.New("position cap reached")
+
+// SectorUtilization is open-position usage within one sector.
+type SectorUtilization struct {
+	Sector    string   `json:"sector"`
+	Positions int      `json:"positions"`
+	Max       int      `json:"max"` // 0 means uncapped
+	Symbols   []string `json:"symbols"`
+}
+
+// QueuedSignal is a capped signal waiting for capacity.
+type QueuedSignal struct {
+	Signal    *TradeSignal `json:"signal"`
+	Reason    string       `json:"reason"`
+	QueuedAt  time.Time    `json:"queued_at"`
+	ExpiresAt time.Time    `json:"expires_at"`
+}
+
+// RiskMetrics reports current risk utilization.
+type RiskMetrics struct {
+	OpenPositions    int                 `json:"open_positions"`
+	MaxOpenPositions int                 `json:"max_open_positions"` // 0 means uncapped
+	Utilization      float64             `json:"utilization"`        // open ÷ max, 0 when uncapped
+	Sectors          []SectorUtilization `json:"sectors"`
+	QueueEnabled     bool                `json:"queue_enabled"`
+	Queued           []QueuedSignal      `json:"queued"`
+	Cooldowns        []Cooldown          `json:"cooldowns"`    // entries blocked after losses
+	LossStreaks      []LossStreak        `json:"loss_streaks"` // current runs of losing trades
+	Exposure         Exposure            `json:"exposure"`     // buying power and leverage room
+	UpdatedAt        time.Time           `json:"updated_at"`
+}
+
+// opensPosition reports whether executing signal would open a new
+// position: a buy or sell with no position, see ResolveIntent. A buy
+// against a short covers it.
+func opensPosition(signal *TradeSignal, positions map[string]PositionData) bool {
+	if signal.Signal != SignalBuy && signal.Signal != SignalSell {
+		return false
+	}
+	return positions[signal.Symbol].Quantity == 0
+}
+
+// refreshPortfolioIfStale re-reads positions from the broker when the
+// cached copy is older than portfolioMaxAge. Failures keep the cached copy.
+func (a *TradingAlgorithm) refreshPortfolioIfStale() {
+	a.mu.RLock()
+	stale := time.Since(a.portfolioAt) > portfolioMaxAge
+	a.mu.RUnlock()
+	if !stale || a.client == nil {
+		return
+	}
+	if err := a.syncPortfolio(); err != nil {
+		logger().Warn("Using cached positions for cap check", "error", err)
+	}
+}
+
+// checkPositionCaps is the built-in trade guard enforcing
+// max_open_positions and max_positions_per_sector on new opens.
+func (a *TradingAlgorithm) checkPositionCaps(signal *TradeSignal) error {
+	reason, queue := a.positionCapReason(signal)
+	if reason == "" {
+		return nil
+	}
+	if queue {
+		a.enqueueCapped(signal, reason)
+		return fmt.Errorf("%w: %s; signal queued until capacity frees up", ErrPositionCap, reason)
+	}
+	return fmt.Errorf("%w: %s", ErrPositionCap, reason)
+}
+
+// positionCapError is checkPositionCaps without queueing the signal.
+func (a *TradingAlgorithm) positionCapError(signal *TradeSignal) error {
+	if reason, _ := a.positionCapReason(signal); reason != "" {
+		return fmt.Errorf("%w: %s", ErrPositionCap, reason)
+	}
+	return nil
+}
+
+// positionCapReason explains why signal would exceed a cap, empty when it
+// fits, and reports whether capped signals are queued.
+func (a *TradingAlgorithm) positionCapReason(signal *TradeSignal) (string, bool) {
+	a.refreshPortfolioIfStale()
+
+	a.mu.RLock()
+	positions := a.portfolio.Positions
+	maxOpen := int(riskParamFloat(a.riskParameters, "max_open_positions", 0))
+	maxSector := int(riskParamFloat(a.riskParameters, "max_positions_per_sector", 0))
+	queue, _ := a.riskParameters["queue_capped_signals"].(bool)
+	a.mu.RUnlock()
+
+	if !opensPosition(signal, positions) {
+		return "", false
+	}
+
+	var reason string
+	open := countOpen(positions)
+	if maxOpen > 0 && open >= maxOpen {
+		reason = fmt.Sprintf("%d of %d open positions in use", open, maxOpen)
+	} else if sector := a.SymbolSector(signal.Symbol); maxSector > 0 && sector != SectorUnknown {
+		inSector := 0
+		for sym, pos := range positions {
+			if pos.Quantity != 0 && a.SymbolSector(sym) == sector {
+				inSector++
+			}
+		}
+		if inSector >= maxSector {
+			reason = fmt.Sprintf("%d of %d %s positions in use", inSector, maxSector, sector)
+		}
+	}
+	return reason, queue
+}
+
+// measurePositionCaps measures the positions signal would leave open
+// against the tighter of max_open_positions and max_positions_per_sector.
+func (a *TradingAlgorithm) measurePositionCaps(signal *TradeSignal) *GuardMeasure {
+	a.mu.RLock()
+	positions := a.portfolio.Positions
+	maxOpen := int(riskParamFloat(a.riskParameters, "max_open_positions", 0))
+	maxSector := int(riskParamFloat(a.riskParameters, "max_positions_per_sector", 0))
+	a.mu.RUnlock()
+
+	if !opensPosition(signal, positions) {
+		return nil
+	}
+	var m *GuardMeasure
+	if maxOpen > 0 {
+		m = MeasureMax("open_positions", float64(countOpen(positions)+1), float64(maxOpen))
+	}
+	if sector := a.SymbolSector(signal.Symbol); maxSector > 0 && sector != SectorUnknown {
+		inSector := 1
+		for sym, pos := range positions {
+			if pos.Quantity != 0 && a.SymbolSector(sym) == sector {
+				inSector++
+			}
+		}
+		if s := MeasureMax(sector+"_positions", float64(inSector), float64(maxSector)); m == nil || s.Margin < m.Margin {
+			m = s
+		}
+	}
+	return m
+}
+
+func countOpen(positions map[string]PositionData) int {
+	n := 0
+	for _, pos := range positions {
+		if pos.Quantity != 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// enqueueCapped queues signal, replacing any earlier queued signal for the
+// same symbol.
+func (a *TradingAlgorithm) enqueueCapped(signal *TradeSignal, reason string) {
+	now := a.now()
+	q := QueuedSignal{Signal: signal, Reason: reason, QueuedAt: now, ExpiresAt: now.Add(capQueueTTL)}
+	// A queued signal still expires with the signal itself
+	if signal.ValidUntil != nil && signal.ValidUntil.Before(q.ExpiresAt) {
+		q.ExpiresAt = *signal.ValidUntil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for i, existing := range a.capQueue {
+		if existing.Signal.Symbol == signal.Symbol {
+			q.QueuedAt = existing.QueuedAt
+			q.ExpiresAt = existing.ExpiresAt
+			a.capQueue[i] = q
+			return
+		}
+	}
+	a.capQueue = append(a.capQueue, q)
+	logger().Info("Queued signal", "symbol", signal.Symbol, "signal", signal.Signal, "reason", reason)
+}
+
+// ReleaseQueuedSignals retries queued signals oldest first, executing any
+// that now fit under the caps and dropping expired ones. Signals that are
+// still capped stay queued.
+func (a *TradingAlgorithm) ReleaseQueuedSignals() {
+	a.mu.Lock()
+	if len(a.capQueue) == 0 || !a.tradingEnabled {
+		a.mu.Unlock()
+		return
+	}
+	pending := a.capQueue
+	a.capQueue = nil
+	a.mu.Unlock()
+
+	now := a.now()
+	for _, q := range pending {
+		if now.After(q.ExpiresAt) {
+			logger().Info("Dropping expired queued signal", "symbol", q.Signal.Symbol, "signal", q.Signal.Signal)
+			continue
+		}
+		// A refused signal is re-queued by the cap guard itself
+		if _, err := a.ExecuteTrade(q.Signal, false); err != nil {
+			logger().Info("Queued signal not released", "symbol", q.Signal.Symbol, "reason", err)
+			continue
+		}
+		logger().Info("Released queued signal", "symbol", q.Signal.Symbol, "signal", q.Signal.Signal)
+	}
+}
+
+// RunCapQueue retries queued signals until ctx is cancelled.
+func (a *TradingAlgorithm) RunCapQueue(ctx context.Context) {
+	t := time.NewTicker(capQueueInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			a.ReleaseQueuedSignals()
+		}
+	}
+}
+
+// GetRiskMetrics returns position-cap utilization, queued signals and
+// cooldowns.
+func (a *TradingAlgorithm) GetRiskMetrics() RiskMetrics {
+	a.refreshPortfolioIfStale()
+
+	a.mu.RLock()
+	positions := a.portfolio.Positions
+	maxOpen := int(riskParamFloat(a.riskParameters, "max_open_positions", 0))
+	maxSector := int(riskParamFloat(a.riskParameters, "max_positions_per_sector", 0))
+	queue, _ := a.riskParameters["queue_capped_signals"].(bool)
+	queued := append([]QueuedSignal{}, a.capQueue...)
+	exposure := a.exposureLocked(a.portfolio, a.riskParameters)
+	a.mu.RUnlock()
+
+	m := RiskMetrics{
+		OpenPositions:    countOpen(positions),
+		MaxOpenPositions: maxOpen,
+		QueueEnabled:     queue,
+		Queued:           queued,
+		Exposure:         exposure,
+		UpdatedAt:        a.now(),
+		Sectors:          []SectorUtilization{},
+	}
+	m.Cooldowns, m.LossStreaks = a.cooldownState()
+	if maxOpen > 0 {
+		m.Utilization = float64(m.OpenPositions) / float64(maxOpen)
+	}
+
+	bySector := make(map[string]*SectorUtilization)
+	for sym, pos := range positions {
+		if pos.Quantity == 0 {
+			continue
+		}
+		sector := a.SymbolSector(sym)
+		su, ok := bySector[sector]
+		if !ok {
+			su = &SectorUtilization{Sector: sector}
+			if sector != SectorUnknown {
+				su.Max = maxSector
+			}
+			bySector[sector] = su
+		}
+		su.Positions++
+		su.Symbols = append(su.Symbols, sym)
+	}
+	for _, su := range bySector {
+		sort.Strings(su.Symbols)
+		m.Sectors = append(m.Sectors, *su)
+	}
+	sort.Slice(m.Sectors, func(i, j int) bool { return m.Sectors[i].Sector < m.Sectors[j].Sector })
+	return m
+}
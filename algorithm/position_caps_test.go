@@ -0,0 +1,110 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func capTestAlgorithm(positions ...string) *TradingAlgorithm {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	a.portfolioAt = time.Now()
+	for _, sym := range positions {
+		a.portfolio.Positions[sym] = PositionData{Symbol: sym, Quantity: 10}
+	}
+	return a
+}
+
+func TestPositionCaps(t *testing.T) {
+	a := capTestAlgorithm("AAPL", "MSFT", "JPM")
+	if err := a.UpdateRiskParameters(map[string]interface{}{
+		"max_open_positions":       4.0,
+		"max_positions_per_sector": 2.0,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A third technology name breaches the sector cap
+	err := a.CheckTradeGuards(&TradeSignal{Symbol: "NVDA", Signal: SignalBuy})
+	if !errors.Is(err, ErrPositionCap) {
+		t.Fatalf("sector cap err = %v", err)
+	}
+	// Closing or adding to a held name is never capped
+	if err := a.CheckTradeGuards(&TradeSignal{Symbol: "AAPL", Signal: SignalSell}); err != nil {
+		t.Errorf("close blocked: %v", err)
+	}
+	// Unknown sectors are only subject to the overall cap
+	if err := a.CheckTradeGuards(&TradeSignal{Symbol: "ZZZZ", Signal: SignalBuy}); err != nil {
+		t.Errorf("unknown-sector open blocked: %v", err)
+	}
+
+	a.portfolio.Positions["XOM"] = PositionData{Symbol: "XOM", Quantity: 5}
+	if err := a.CheckTradeGuards(&TradeSignal{Symbol: "BA", Signal: SignalBuy}); !errors.Is(err, ErrPositionCap) {
+		t.Errorf("overall cap err = %v", err)
+	}
+
+	m := a.GetRiskMetrics()
+	if m.OpenPositions != 4 || m.Utilization != 1 || len(m.Sectors) != 3 || m.Sectors[2].Sector != "technology" || m.Sectors[2].Positions != 2 {
+		t.Errorf("metrics = %+v", m)
+	}
+}
+
+func TestCappedSignalsQueue(t *testing.T) {
+	a := capTestAlgorithm("AAPL")
+	if err := a.UpdateRiskParameters(map[string]interface{}{
+		"max_open_positions":   1.0,
+		"queue_capped_signals": true,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	a.CheckTradeGuards(&TradeSignal{Symbol: "JPM", Signal: SignalBuy, Reasoning: "first"})
+	a.CheckTradeGuards(&TradeSignal{Symbol: "JPM", Signal: SignalBuy, Reasoning: "second"})
+	q := a.GetRiskMetrics().Queued
+	if len(q) != 1 || q[0].Signal.Reasoning != "second" {
+		t.Errorf("queue = %+v, want one replaced entry", q)
+	}
+
+	if err := a.UpdateRiskParameters(map[string]interface{}{"queue_capped_signals": "yes"}); err == nil {
+		t.Error("expected error for non-boolean queue flag")
+	}
+	if err := a.UpdateRiskParameters(map[string]interface{}{"max_open_positions": 2.5}); err == nil {
+		t.Error("expected error for fractional cap")
+	}
+}
+
+func TestGuardObserverSeesMargins(t *testing.T) {
+	a := capTestAlgorithm("AAPL", "MSFT", "JPM")
+	if err := a.UpdateRiskParameters(map[string]interface{}{
+		"max_open_positions":       4.0,
+		"max_positions_per_sector": 2.0,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var decisions []GuardDecision
+	a.SetGuardObserver(func(d GuardDecision) { decisions = append(decisions, d) })
+
+	// The fourth position fills the overall cap exactly
+	if err := a.CheckTradeGuards(&TradeSignal{Symbol: "ZZZZ", Signal: SignalBuy}); err != nil {
+		t.Fatal(err)
+	}
+	var caps *GuardDecision
+	for i := range decisions {
+		if decisions[i].Guard == "position caps" {
+			caps = &decisions[i]
+		}
+	}
+	if caps == nil || !caps.Allowed || caps.Measure == nil || caps.Measure.Metric != "open_positions" || caps.Measure.Margin != 0 {
+		t.Fatalf("open decision = %+v", caps)
+	}
+
+	// A third technology name is refused half over its sector limit, and
+	// no guard after it is consulted
+	decisions = nil
+	a.CheckTradeGuards(&TradeSignal{Symbol: "NVDA", Signal: SignalBuy})
+	last := decisions[len(decisions)-1]
+	if last.Guard != "position caps" || last.Allowed || last.Reason == "" || last.Measure.Metric != "technology_positions" || last.Measure.Margin != -0.5 {
+		t.Errorf("refusal = %+v %+v", last, last.Measure)
+	}
+}
@@ -0,0 +1,45 @@
+package algorithm
+
+// SetPositionScale shrinks max_position_size_percent by scale for every
+// order sized from now on, including explicitly sized ones, until it is set
+// back to 1. Scales outside (0, 1] reset it to 1; block entries with a
+// trade guard instead of a zero scale. reason names who asked, for the
+// status endpoints. The risk parameters themselves are left untouched.
+func (a *TradingAlgorithm) SetPositionScale(reason string, scale float64) {
+	if scale <= 0 || scale > 1 {
+		scale = 1
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.positionScale = scale
+	a.positionScaleReason = reason
+}
+
+// PositionScale returns the current position scale and who set it.
+func (a *TradingAlgorithm) PositionScale() (string, float64) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.positionScaleReason, a.positionScale
+}
+
+// sizingParamsLocked returns a copy of the risk parameters with
+// max_position_size_percent scaled by the position scale.
+func (a *TradingAlgorithm) sizingParamsLocked() map[string]interface{} {
+	params := make(map[string]interface{}, len(a.riskParameters)+1)
+	for k, v := range a.riskParameters {
+		params[k] = v
+	}
+	if a.positionScale < 1 {
+		params["max_position_size_percent"] = riskParamFloat(params, "max_position_size_percent", 5.0) * a.positionScale
+	}
+	return params
+}
+
+// OpensPosition reports whether executing signal against the cached
+// portfolio would open a new position rather than add to, reduce or close
+// an existing one.
+func (a *TradingAlgorithm) OpensPosition(signal *TradeSignal) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return opensPosition(signal, a.portfolio.Positions)
+}
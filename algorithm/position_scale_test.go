@@ -0,0 +1,36 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestPositionScaleShrinksSizing(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	a.marketData["AAPL"] = MarketData{Symbol: "AAPL", Price: 100}
+	a.portfolio.TotalValue = 100000
+
+	a.SetPositionScale("drawdown tier 1", 0.5)
+	preview, err := a.ExecuteTrade(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy, OrderType: "market"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qty, _ := preview.Request.Qty.Float64(); qty != 25 { // 5% × 0.5 of $100,000 at $100
+		t.Errorf("qty = %v", qty)
+	}
+
+	// Explicit sizes are held to the scaled limit too
+	_, err = a.ExecuteTrade(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy, OrderType: "market", Size: &TradeSize{Qty: 40}}, true)
+	if !errors.Is(err, ErrTradeSize) {
+		t.Errorf("err = %v", err)
+	}
+
+	a.SetPositionScale("", 0)
+	if _, scale := a.PositionScale(); scale != 1 {
+		t.Errorf("scale = %v", scale)
+	}
+	if !a.OpensPosition(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy}) {
+		t.Error("buy with no position should open one")
+	}
+}
@@ -0,0 +1,82 @@
+package algorithm
+
+import (
+	"fmt"
+
+	"github.com/rileyseaburg/go-trader/algorithm/algo"
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+// quantFallbackLookbackDays is how much daily history the quant fallback
+// loads when the bar cache is too thin.
+const quantFallbackLookbackDays = 120
+
+// QuantFallback produces signals from the combined quant algorithms using
+// cached daily bars. It stands in for Claude while the breaker is open.
+type QuantFallback struct {
+	algorithm *TradingAlgorithm
+	adapter   *algo.ClaudeAlgorithmAdapter
+}
+
+// NewQuantFallback creates a fallback over a's bar cache.
+func NewQuantFallback(a *TradingAlgorithm) *QuantFallback {
+	return &QuantFallback{algorithm: a, adapter: algo.NewClaudeAlgorithmAdapter()}
+}
+
+// GenerateTradeSignal implements ClaudeClientInterface.
+func (q *QuantFallback) GenerateTradeSignal(symbol string, marketData MarketData, portfolio PortfolioData) (*TradeSignal, error) {
+	bars, _ := q.algorithm.CachedBars(symbol, "1D")
+	q.algorithm.recordCacheLookup(CacheBars, len(bars) >= minBaselineBars)
+	if len(bars) < minBaselineBars {
+		end := q.algorithm.now()
+		history, err := q.algorithm.GetBarHistory(HistoryRequest{
+			Symbol:    symbol,
+			StartDate: end.AddDate(0, 0, -quantFallbackLookbackDays),
+			EndDate:   end,
+			TimeFrame: "1D",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("no history for quant fallback: %w", err)
+		}
+		bars = history.Bars
+	}
+
+	historical := make([]types.MarketData, len(bars))
+	for i, bar := range bars {
+		historical[i] = types.MarketData{
+			Symbol:    symbol,
+			Price:     bar.Close,
+			Open:      bar.Open,
+			High24h:   bar.High,
+			Low24h:    bar.Low,
+			Volume24h: float64(bar.Volume),
+		}
+	}
+	current := &types.MarketData{
+		Symbol:    symbol,
+		Price:     marketData.Price,
+		High24h:   marketData.High24h,
+		Low24h:    marketData.Low24h,
+		Volume24h: marketData.Volume24h,
+		Change24h: marketData.Change24h,
+	}
+
+	signal, err := q.adapter.GenerateTradeSignal(symbol, current, historical)
+	if err != nil {
+		return nil, err
+	}
+	if signal == nil {
+		return nil, fmt.Errorf("quant algorithms produced no signal for %s", symbol)
+	}
+	out := &TradeSignal{
+		Symbol:     symbol,
+		Signal:     signal.Signal,
+		OrderType:  signal.OrderType,
+		LimitPrice: signal.LimitPrice,
+		Timestamp:  signal.Timestamp,
+		Reasoning:  signal.Reasoning,
+		Confidence: signal.Confidence,
+	}
+	againstBreadth(out, marketData.Breadth)
+	return out, nil
+}
@@ -1,7 +1,6 @@
 package algorithm
 
 import (
-	"log"
 	"math/rand"
 	"time"
 )
@@ -15,7 +14,7 @@ type RecommendedTicker struct {
 
 // RecommendTickers uses the AI to recommend tickers to watch based on market conditions
 func (a *TradingAlgorithm) RecommendTickersList(sector string, maxResults int) ([]RecommendedTicker, error) {
-	log.Printf("Generating ticker recommendations for sector: %s", sector)
+	logger().Info("Generating ticker recommendations", "sector", sector)
 
 	// This would typically involve making a request to Claude to analyze market conditions
 	// and recommend tickers in the specified sector. For now, we'll provide sample responses
@@ -54,7 +53,7 @@ func (a *TradingAlgorithm) RecommendTickersList(sector string, maxResults int) (
 		recommendations[i], recommendations[j] = recommendations[j], recommendations[i]
 	})
 
-	log.Printf("Generated %d ticker recommendations", len(recommendations))
+	logger().Info("Generated ticker recommendations", "count", len(recommendations))
 	return recommendations[:minInt(len(recommendations), maxResults)], nil
 }
 
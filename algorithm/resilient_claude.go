@@ -0,0 +1,311 @@
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BreakerState is the circuit breaker position.
+type BreakerState string
+
+const (
+	// BreakerClosed sends every call to Claude
+	BreakerClosed BreakerState = "closed"
+	// BreakerOpen skips Claude and answers from the fallback chain
+	BreakerOpen BreakerState = "open"
+	// BreakerHalfOpen lets a single probe call through after the cool-off
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// errClaudeTimeout is returned when a call exceeds the per-call timeout.
+var errClaudeTimeout = errors.New("claude call timed out")
+
+// ResilienceConfig tunes timeouts, retries and the circuit breaker.
+type ResilienceConfig struct {
+	Timeout          time.Duration `json:"timeout"`           // per attempt
+	MaxRetries       int           `json:"max_retries"`       // extra attempts on transient errors
+	BaseBackoff      time.Duration `json:"base_backoff"`      // doubled each retry
+	MaxBackoff       time.Duration `json:"max_backoff"`       // backoff ceiling
+	FailureThreshold int           `json:"failure_threshold"` // consecutive failed calls that trip the breaker
+	OpenDuration     time.Duration `json:"open_duration"`     // cool-off before a half-open probe
+}
+
+// DefaultResilienceConfig returns conservative defaults: a 20s timeout,
+// two retries, and a breaker that trips after five failed calls for a
+// minute.
+func DefaultResilienceConfig() ResilienceConfig {
+	return ResilienceConfig{
+		Timeout:          20 * time.Second,
+		MaxRetries:       2,
+		BaseBackoff:      500 * time.Millisecond,
+		MaxBackoff:       5 * time.Second,
+		FailureThreshold: 5,
+		OpenDuration:     time.Minute,
+	}
+}
+
+// ClaudeHealth reports breaker state and call statistics.
+type ClaudeHealth struct {
+	State               BreakerState     `json:"state"`
+	ConsecutiveFailures int              `json:"consecutive_failures"`
+	Calls               int              `json:"calls"`
+	Failures            int              `json:"failures"`
+	Retries             int              `json:"retries"`
+	Timeouts            int              `json:"timeouts"`
+	FallbackSignals     int              `json:"fallback_signals"`
+	LastError           string           `json:"last_error,omitempty"`
+	LastFailure         *time.Time       `json:"last_failure,omitempty"`
+	LastSuccess         *time.Time       `json:"last_success,omitempty"`
+	OpenedAt            *time.Time       `json:"opened_at,omitempty"`
+	RetryAt             *time.Time       `json:"retry_at,omitempty"` // when the next probe is allowed
+	Fallbacks           []string         `json:"fallbacks"`
+	Config              ResilienceConfig `json:"config"`
+}
+
+type namedFallback struct {
+	name   string
+	client ClaudeClientInterface
+}
+
+// ResilientClaude wraps a Claude client with per-call timeouts, retries
+// with exponential backoff on transient failures, and a circuit breaker.
+// While the breaker is open, signals come from the fallback chain — each
+// fallback is tried in order and a hold signal ends the chain.
+type ResilientClaude struct {
+	primary ClaudeClientInterface
+	cfg     ResilienceConfig
+	sleep   func(time.Duration)
+	now     func() time.Time
+
+	mu        sync.Mutex
+	fallbacks []namedFallback
+	state     BreakerState
+	probing   bool
+	health    ClaudeHealth
+	openedAt  time.Time
+}
+
+// NewResilientClaude wraps primary.
+func NewResilientClaude(primary ClaudeClientInterface, cfg ResilienceConfig) *ResilientClaude {
+	return &ResilientClaude{
+		primary: primary,
+		cfg:     cfg,
+		sleep:   time.Sleep,
+		now:     time.Now,
+		state:   BreakerClosed,
+	}
+}
+
+// AddFallback appends a signal source to the fallback chain.
+func (r *ResilientClaude) AddFallback(name string, client ClaudeClientInterface) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallbacks = append(r.fallbacks, namedFallback{name: name, client: client})
+}
+
+// GenerateTradeSignal implements ClaudeClientInterface. With the breaker
+// closed, failures are returned to the caller after retries; once it is
+// open the fallback chain answers instead.
+func (r *ResilientClaude) GenerateTradeSignal(symbol string, marketData MarketData, portfolio PortfolioData) (*TradeSignal, error) {
+	if !r.allow() {
+		return r.fallback(symbol, marketData, portfolio, "Claude circuit breaker is open")
+	}
+
+	var err error
+	for attempt := 0; attempt <= r.cfg.MaxRetries; attempt++ {
+		if attempt > 0 {
+			r.mu.Lock()
+			r.health.Retries++
+			r.mu.Unlock()
+			r.sleep(r.backoff(attempt))
+		}
+		var signal *TradeSignal
+		signal, err = r.callWithTimeout(symbol, marketData, portfolio)
+		if err == nil {
+			r.recordSuccess()
+			return signal, nil
+		}
+		if !isTransient(err) {
+			break
+		}
+		logger().Warn("Claude call failed", "symbol", symbol, "attempt", attempt+1, "attempts", r.cfg.MaxRetries+1, "error", err)
+	}
+
+	if r.recordFailure(err) {
+		return r.fallback(symbol, marketData, portfolio, fmt.Sprintf("Claude unavailable: %v", err))
+	}
+	return nil, err
+}
+
+func (r *ResilientClaude) callWithTimeout(symbol string, marketData MarketData, portfolio PortfolioData) (*TradeSignal, error) {
+	type result struct {
+		signal *TradeSignal
+		err    error
+	}
+	// Buffered so an abandoned call can still complete and exit
+	done := make(chan result, 1)
+	go func() {
+		signal, err := r.primary.GenerateTradeSignal(symbol, marketData, portfolio)
+		done <- result{signal, err}
+	}()
+
+	timer := time.NewTimer(r.cfg.Timeout)
+	defer timer.Stop()
+	select {
+	case res := <-done:
+		if res.err == nil && res.signal == nil {
+			return nil, errors.New("claude returned no signal")
+		}
+		return res.signal, res.err
+	case <-timer.C:
+		r.mu.Lock()
+		r.health.Timeouts++
+		r.mu.Unlock()
+		return nil, fmt.Errorf("%w after %s", errClaudeTimeout, r.cfg.Timeout)
+	}
+}
+
+func (r *ResilientClaude) backoff(attempt int) time.Duration {
+	d := r.cfg.BaseBackoff << uint(attempt-1)
+	if d > r.cfg.MaxBackoff || d <= 0 {
+		d = r.cfg.MaxBackoff
+	}
+	return d
+}
+
+// allow reports whether a call may go to Claude, moving an open breaker
+// to half-open once the cool-off has passed. Only one probe runs at a time.
+func (r *ResilientClaude) allow() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.health.Calls++
+	switch r.state {
+	case BreakerOpen:
+		if r.now().Sub(r.openedAt) < r.cfg.OpenDuration {
+			return false
+		}
+		r.state = BreakerHalfOpen
+		r.probing = true
+		logger().Info("Claude circuit breaker half-open, probing")
+		return true
+	case BreakerHalfOpen:
+		if r.probing {
+			return false
+		}
+		r.probing = true
+	}
+	return true
+}
+
+func (r *ResilientClaude) recordSuccess() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state != BreakerClosed {
+		logger().Info("Claude circuit breaker closed after successful probe")
+	}
+	now := r.now()
+	r.state = BreakerClosed
+	r.probing = false
+	r.health.ConsecutiveFailures = 0
+	r.health.LastSuccess = &now
+}
+
+// recordFailure counts a failed call and reports whether the breaker is
+// now open.
+func (r *ResilientClaude) recordFailure(err error) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	r.health.Failures++
+	r.health.ConsecutiveFailures++
+	r.health.LastError = err.Error()
+	r.health.LastFailure = &now
+	r.probing = false
+
+	if r.state == BreakerHalfOpen || r.health.ConsecutiveFailures >= r.cfg.FailureThreshold {
+		if r.state != BreakerOpen {
+			logger().Error("Claude circuit breaker open", "consecutive_failures", r.health.ConsecutiveFailures, "error", err)
+		}
+		r.state = BreakerOpen
+		r.openedAt = now
+		return true
+	}
+	return false
+}
+
+func (r *ResilientClaude) fallback(symbol string, marketData MarketData, portfolio PortfolioData, reason string) (*TradeSignal, error) {
+	r.mu.Lock()
+	r.health.FallbackSignals++
+	chain := append([]namedFallback(nil), r.fallbacks...)
+	r.mu.Unlock()
+
+	for _, fb := range chain {
+		signal, err := fb.client.GenerateTradeSignal(symbol, marketData, portfolio)
+		if err != nil || signal == nil {
+			logger().Warn("Fallback failed", "fallback", fb.name, "symbol", symbol, "error", err)
+			continue
+		}
+		signal.Source = "fallback:" + fb.name
+		signal.Reasoning = fmt.Sprintf("[%s; using %s fallback] %s", reason, fb.name, signal.Reasoning)
+		return signal, nil
+	}
+
+	return &TradeSignal{
+		Symbol:    symbol,
+		Signal:    SignalHold,
+		OrderType: "market",
+		Timestamp: r.now(),
+		Reasoning: reason + "; no fallback produced a signal, holding.",
+		Source:    "system",
+	}, nil
+}
+
+// Health returns breaker state and call statistics.
+func (r *ResilientClaude) Health() ClaudeHealth {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h := r.health
+	h.State = r.state
+	h.Config = r.cfg
+	h.Fallbacks = make([]string, len(r.fallbacks))
+	for i, fb := range r.fallbacks {
+		h.Fallbacks[i] = fb.name
+	}
+	if r.state != BreakerClosed {
+		opened, retry := r.openedAt, r.openedAt.Add(r.cfg.OpenDuration)
+		h.OpenedAt, h.RetryAt = &opened, &retry
+	}
+	return h
+}
+
+// ResetBreaker closes the breaker and clears the failure streak.
+func (r *ResilientClaude) ResetBreaker() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.state = BreakerClosed
+	r.probing = false
+	r.health.ConsecutiveFailures = 0
+}
+
+// isTransient reports whether err is worth retrying: timeouts, network
+// errors and upstream overload. Malformed requests and responses are not.
+func isTransient(err error) bool {
+	if errors.Is(err, errClaudeTimeout) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"http request failed", "timeout", "connection", "eof", "429", "502", "503", "504", "overloaded", "rate limit"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package algorithm
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type stubClaude struct {
+	calls int
+	err   error
+	delay time.Duration
+}
+
+func (s *stubClaude) GenerateTradeSignal(symbol string, _ MarketData, _ PortfolioData) (*TradeSignal, error) {
+	s.calls++
+	if s.delay > 0 {
+		time.Sleep(s.delay)
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &TradeSignal{Symbol: symbol, Signal: SignalBuy, Reasoning: "stub"}, nil
+}
+
+func testResilience() ResilienceConfig {
+	return ResilienceConfig{
+		Timeout:          50 * time.Millisecond,
+		MaxRetries:       2,
+		BaseBackoff:      time.Millisecond,
+		MaxBackoff:       time.Millisecond,
+		FailureThreshold: 2,
+		OpenDuration:     time.Minute,
+	}
+}
+
+func TestResilientClaudeRetriesAndTrips(t *testing.T) {
+	primary := &stubClaude{err: errors.New("HTTP request failed: connection refused")}
+	fallback := &stubClaude{}
+	r := NewResilientClaude(primary, testResilience())
+	r.sleep = func(time.Duration) {}
+	r.AddFallback("quant", fallback)
+
+	// First failed call: retried, then surfaced as an error
+	if _, err := r.GenerateTradeSignal("AAPL", MarketData{}, PortfolioData{}); err == nil {
+		t.Fatal("expected error before breaker trips")
+	}
+	if primary.calls != 3 {
+		t.Errorf("primary calls = %d, want 3 (1 + 2 retries)", primary.calls)
+	}
+
+	// Second failed call trips the breaker and answers from the fallback
+	signal, err := r.GenerateTradeSignal("AAPL", MarketData{}, PortfolioData{})
+	if err != nil || signal.Source != "fallback:quant" {
+		t.Fatalf("tripping call = %+v, %v", signal, err)
+	}
+	if h := r.Health(); h.State != BreakerOpen || h.RetryAt == nil {
+		t.Errorf("health = %+v", h)
+	}
+
+	// While open, Claude is not called at all
+	before := primary.calls
+	if _, err := r.GenerateTradeSignal("AAPL", MarketData{}, PortfolioData{}); err != nil {
+		t.Fatal(err)
+	}
+	if primary.calls != before {
+		t.Error("open breaker still called Claude")
+	}
+
+	// After the cool-off a successful probe closes it
+	r.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	primary.err = nil
+	signal, err = r.GenerateTradeSignal("AAPL", MarketData{}, PortfolioData{})
+	if err != nil || signal.Source != "" {
+		t.Fatalf("probe = %+v, %v", signal, err)
+	}
+	if h := r.Health(); h.State != BreakerClosed || h.ConsecutiveFailures != 0 {
+		t.Errorf("after probe health = %+v", h)
+	}
+}
+
+func TestResilientClaudeTimeoutAndPermanentErrors(t *testing.T) {
+	slow := &stubClaude{delay: 200 * time.Millisecond}
+	cfg := testResilience()
+	cfg.MaxRetries = 0
+	r := NewResilientClaude(slow, cfg)
+	if _, err := r.GenerateTradeSignal("AAPL", MarketData{}, PortfolioData{}); !errors.Is(err, errClaudeTimeout) {
+		t.Errorf("err = %v, want timeout", err)
+	}
+
+	bad := &stubClaude{err: errors.New("error parsing response: invalid character")}
+	r = NewResilientClaude(bad, testResilience())
+	r.sleep = func(time.Duration) {}
+	r.GenerateTradeSignal("AAPL", MarketData{}, PortfolioData{})
+	if bad.calls != 1 {
+		t.Errorf("permanent error retried: %d calls", bad.calls)
+	}
+}
+
+func TestFallbackChainEndsInHold(t *testing.T) {
+	r := NewResilientClaude(&stubClaude{}, testResilience())
+	r.AddFallback("broken", &stubClaude{err: errors.New("no history")})
+	signal, err := r.fallback("AAPL", MarketData{}, PortfolioData{}, "test")
+	if err != nil || signal.Signal != SignalHold || signal.Source != "system" {
+		t.Errorf("chain end = %+v, %v", signal, err)
+	}
+}
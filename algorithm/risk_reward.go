@@ -0,0 +1,127 @@
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrRiskReward is wrapped by refusals for too little expected reward.
+var ErrRiskReward = errors.New("expected reward below minimum")
+
+// RiskReward is the expected risk and reward of the position a signal
+// opens. Stop and target come from the triple barrier volatility levels
+// when there are enough cached daily bars, otherwise from the
+// stop_loss_percent and take_profit_percent risk parameters. A usable
+// invalidation level in the signal's analysis replaces the stop. Amounts
+// are per share.
+type RiskReward struct {
+	Direction   string  `json:"direction"` // long or short
+	Entry       float64 `json:"entry"`
+	Stop        float64 `json:"stop"`
+	Target      float64 `json:"target"`
+	LevelSource string  `json:"level_source"`          // volatility or fixed
+	StopSource  string  `json:"stop_source,omitempty"` // invalidation when the analysis set the stop
+	Risk        float64 `json:"risk"`
+	Reward      float64 `json:"reward"`
+	RMultiple   float64 `json:"r_multiple"` // reward over risk
+	// Probability of reaching the target before the stop: the signal's
+	// confidence shrunk toward 0.5 by the confidence_shrinkage risk
+	// parameter, 0.5 when the signal states none
+	Probability   float64 `json:"probability"`
+	ExpectedR     float64 `json:"expected_r"`     // probability × R − (1 − probability)
+	ExpectedValue float64 `json:"expected_value"` // probability × reward − (1 − probability) × risk
+}
+
+// RiskReward computes the expected risk and reward of signal at its limit
+// price or the last price. It returns nil for signals that open no
+// position and when there is no price.
+func (a *TradingAlgorithm) RiskReward(signal *TradeSignal) *RiskReward {
+	if signal == nil {
+		return nil
+	}
+	a.mu.RLock()
+	positions := a.portfolio.Positions
+	entry := a.marketData[signal.Symbol].Price
+	riskParams := a.sizingParamsLocked()
+	a.mu.RUnlock()
+
+	if !opensPosition(signal, positions) {
+		return nil
+	}
+	if signal.LimitPrice != nil && *signal.LimitPrice > 0 {
+		entry = *signal.LimitPrice
+	}
+	if entry <= 0 {
+		return nil
+	}
+
+	qty := 1.0
+	if signal.Signal == SignalSell {
+		qty = -1
+	}
+	levels := a.simulateBarriers(signal.Symbol, qty, entry, riskParams, nil)
+	rr := &RiskReward{
+		Direction:   levels.Direction,
+		Entry:       entry,
+		Stop:        levels.StopLoss,
+		Target:      levels.TakeProfit,
+		LevelSource: "fixed",
+	}
+	if levels.DailyVolatility > 0 {
+		rr.Stop, rr.Target, rr.LevelSource = levels.VolatilityStopLoss, levels.VolatilityTakeProfit, "volatility"
+	}
+	if stop, ok := signal.invalidationStop(levels.Direction, entry); ok {
+		rr.Stop, rr.StopSource = stop, "invalidation"
+	}
+	rr.Risk = roundCents(math.Abs(entry - rr.Stop))
+	rr.Reward = roundCents(math.Abs(rr.Target - entry))
+	if rr.Risk <= 0 {
+		return nil
+	}
+
+	confidence := 0.5
+	if signal.Confidence != nil {
+		confidence = math.Max(0, math.Min(1, *signal.Confidence))
+	}
+	shrinkage := riskParamFloat(riskParams, "confidence_shrinkage", 0.25)
+	p := 0.5 + (confidence-0.5)*(1-shrinkage)
+
+	rr.RMultiple = round4(rr.Reward / rr.Risk)
+	rr.Probability = round4(p)
+	rr.ExpectedR = round4(p*rr.Reward/rr.Risk - (1 - p))
+	rr.ExpectedValue = roundCents(p*rr.Reward - (1-p)*rr.Risk)
+	return rr
+}
+
+// checkRiskReward refuses opening signals whose expected R is below the
+// min_expected_r risk parameter. Signals without an annotation, such as
+// those arriving from webhooks, are annotated first.
+func (a *TradingAlgorithm) checkRiskReward(signal *TradeSignal) error {
+	a.mu.RLock()
+	minR := riskParamFloat(a.riskParameters, "min_expected_r", 0)
+	a.mu.RUnlock()
+	if minR <= 0 {
+		return nil
+	}
+	if signal.RiskReward == nil {
+		signal.RiskReward = a.RiskReward(signal)
+	}
+	rr := signal.RiskReward
+	if rr == nil || rr.ExpectedR >= minR {
+		return nil
+	}
+	return fmt.Errorf("%w: expected %.2fR (%.0f%% to reach %.2f, stop %.2f) under the %.2fR minimum",
+		ErrRiskReward, rr.ExpectedR, rr.Probability*100, rr.Target, rr.Stop, minR)
+}
+
+// measureRiskReward measures signal's expected R against min_expected_r.
+func (a *TradingAlgorithm) measureRiskReward(signal *TradeSignal) *GuardMeasure {
+	a.mu.RLock()
+	minR := riskParamFloat(a.riskParameters, "min_expected_r", 0)
+	a.mu.RUnlock()
+	if minR <= 0 || signal.RiskReward == nil {
+		return nil
+	}
+	return MeasureMin("expected_r", signal.RiskReward.ExpectedR, minR)
+}
@@ -0,0 +1,72 @@
+package algorithm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRiskRewardAnnotationAndMinimum(t *testing.T) {
+	a := capTestAlgorithm("MSFT")
+	a.UpdateMarketData("AAPL", 100, 101, 99, 1000, 0)
+	a.UpdateMarketData("MSFT", 400, 401, 399, 1000, 0)
+
+	// Without enough daily bars the levels come from the 5% stop and 15% target
+	confidence := 0.6
+	signal := &TradeSignal{Symbol: "AAPL", Signal: SignalBuy, Confidence: &confidence}
+	rr := a.RiskReward(signal)
+	if rr == nil || rr.LevelSource != "fixed" || rr.Stop != 95 || rr.Target != 115 || rr.RMultiple != 3 {
+		t.Fatalf("risk/reward = %+v", rr)
+	}
+	// 0.6 shrunk a quarter of the way to 0.5 is 0.575; 0.575 × 3 − 0.425
+	if rr.Probability != 0.575 || rr.ExpectedR != 1.3 || rr.ExpectedValue != 6.5 {
+		t.Fatalf("expectation = %+v", rr)
+	}
+
+	short := a.RiskReward(&TradeSignal{Symbol: "AAPL", Signal: SignalSell})
+	if short == nil || short.Direction != "short" || short.Stop != 105 || short.Probability != 0.5 {
+		t.Fatalf("short = %+v", short)
+	}
+	// Closing a held position opens nothing to measure
+	if rr := a.RiskReward(&TradeSignal{Symbol: "MSFT", Signal: SignalSell}); rr != nil {
+		t.Fatalf("close annotated: %+v", rr)
+	}
+
+	if err := a.UpdateRiskParameters(map[string]interface{}{"min_expected_r": 1.5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.CheckTradeGuards(signal); !errors.Is(err, ErrRiskReward) {
+		t.Fatalf("err = %v", err)
+	}
+	confident := 0.9
+	if err := a.CheckTradeGuards(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy, Confidence: &confident}); err != nil {
+		t.Fatalf("confident signal blocked: %v", err)
+	}
+	if err := a.CheckTradeGuards(&TradeSignal{Symbol: "MSFT", Signal: SignalSell}); err != nil {
+		t.Fatalf("close blocked: %v", err)
+	}
+	if err := a.UpdateRiskParameters(map[string]interface{}{"confidence_shrinkage": 1.5}); err == nil {
+		t.Fatal("expected an error for shrinkage above 1")
+	}
+}
+
+func TestRiskRewardStopsAtTheInvalidationLevel(t *testing.T) {
+	a := capTestAlgorithm()
+	a.UpdateMarketData("AAPL", 100, 101, 99, 1000, 0)
+
+	level := func(v float64) *Analysis {
+		return &Analysis{Thesis: "breakout", TimeHorizon: "swing", InvalidationLevel: &v}
+	}
+	rr := a.RiskReward(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy, Analysis: level(97.5)})
+	if rr == nil || rr.Stop != 97.5 || rr.StopSource != "invalidation" || rr.Risk != 2.5 || rr.RMultiple != 6 {
+		t.Fatalf("risk/reward = %+v", rr)
+	}
+	// Above a long's entry, or absurdly far from it, the level is no stop
+	for _, v := range []float64{101, 40} {
+		if rr := a.RiskReward(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy, Analysis: level(v)}); rr == nil || rr.Stop != 95 || rr.StopSource != "" {
+			t.Errorf("level %v: risk/reward = %+v", v, rr)
+		}
+	}
+	if rr := a.RiskReward(&TradeSignal{Symbol: "AAPL", Signal: SignalSell, Analysis: level(103)}); rr == nil || rr.Stop != 103 {
+		t.Errorf("short risk/reward = %+v", rr)
+	}
+}
@@ -0,0 +1,71 @@
+package algorithm
+
+import "strings"
+
+// SectorUnknown groups symbols with no sector mapping. It is never capped.
+const SectorUnknown = "unknown"
+
+// defaultSectors maps widely traded symbols to their GICS sector. Anything
+// else can be mapped at runtime with SetSymbolSector.
+var defaultSectors = map[string]string{
+	// Information Technology
+	"AAPL": "technology", "MSFT": "technology", "NVDA": "technology", "AMD": "technology",
+	"INTC": "technology", "AVGO": "technology", "ORCL": "technology", "CRM": "technology",
+	"ADBE": "technology", "CSCO": "technology", "QCOM": "technology", "TXN": "technology",
+	"IBM": "technology", "MU": "technology", "NOW": "technology", "PLTR": "technology",
+	// Communication Services
+	"GOOGL": "communication", "GOOG": "communication", "META": "communication", "NFLX": "communication",
+	"DIS": "communication", "T": "communication", "VZ": "communication", "TMUS": "communication",
+	// Consumer Discretionary
+	"AMZN": "consumer_discretionary", "TSLA": "consumer_discretionary", "HD": "consumer_discretionary",
+	"MCD": "consumer_discretionary", "NKE": "consumer_discretionary", "SBUX": "consumer_discretionary",
+	"LOW": "consumer_discretionary", "BKNG": "consumer_discretionary",
+	// Consumer Staples
+	"WMT": "consumer_staples", "PG": "consumer_staples", "KO": "consumer_staples", "PEP": "consumer_staples",
+	"COST": "consumer_staples", "PM": "consumer_staples",
+	// Health Care
+	"JNJ": "healthcare", "UNH": "healthcare", "PFE": "healthcare", "ABBV": "healthcare",
+	"MRK": "healthcare", "LLY": "healthcare", "TMO": "healthcare", "ABT": "healthcare",
+	// Financials
+	"JPM": "financials", "BAC": "financials", "WFC": "financials", "GS": "financials",
+	"MS": "financials", "C": "financials", "V": "financials", "MA": "financials",
+	"BRK.B": "financials", "AXP": "financials",
+	// Energy
+	"XOM": "energy", "CVX": "energy", "COP": "energy", "SLB": "energy", "OXY": "energy",
+	// Industrials
+	"BA": "industrials", "CAT": "industrials", "GE": "industrials", "HON": "industrials",
+	"UPS": "industrials", "LMT": "industrials", "RTX": "industrials", "DE": "industrials",
+	// Utilities, Real Estate, Materials
+	"NEE": "utilities", "DUK": "utilities", "SO": "utilities",
+	"AMT": "real_estate", "PLD": "real_estate", "O": "real_estate",
+	"LIN": "materials", "FCX": "materials", "NEM": "materials",
+}
+
+// SymbolSector returns the sector for symbol, or SectorUnknown.
+func (a *TradingAlgorithm) SymbolSector(symbol string) string {
+	symbol = strings.ToUpper(symbol)
+	a.mu.RLock()
+	sector, ok := a.sectors[symbol]
+	a.mu.RUnlock()
+	if ok {
+		return sector
+	}
+	if sector, ok := defaultSectors[symbol]; ok {
+		return sector
+	}
+	return SectorUnknown
+}
+
+// SetSymbolSector overrides the sector for symbol. An empty sector removes
+// the override.
+func (a *TradingAlgorithm) SetSymbolSector(symbol, sector string) {
+	symbol = strings.ToUpper(symbol)
+	sector = strings.ToLower(strings.TrimSpace(sector))
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if sector == "" {
+		delete(a.sectors, symbol)
+		return
+	}
+	a.sectors[symbol] = sector
+}
@@ -0,0 +1,46 @@
+package algorithm
+
+import "time"
+
+// SessionStats are a symbol's statistics for the current regular session,
+// as /api/symbols/{symbol}/session-stats reports them.
+type SessionStats struct {
+	Open        float64   `json:"open"`
+	High        float64   `json:"high"`
+	Low         float64   `json:"low"`
+	VWAP        float64   `json:"vwap"`
+	Volume      float64   `json:"volume"`
+	AvgVolume20 float64   `json:"avg_volume_20,omitempty"`
+	VolumeRatio float64   `json:"volume_ratio,omitempty"` // today's volume over AvgVolume20
+	VolumePace  float64   `json:"volume_pace,omitempty"`  // against the share of AvgVolume20 due by now
+	AsOf        time.Time `json:"as_of"`
+	// RealizedVolatility is annualized, in percent, from the session's
+	// minute returns
+	RealizedVolatility float64 `json:"realized_volatility"`
+	Spread             float64 `json:"spread,omitempty"`
+	SpreadBps          float64 `json:"spread_bps,omitempty"`
+}
+
+// SetSessionStatsSource sets the lookup of a symbol's session stats,
+// passed to Claude with the market data.
+func (a *TradingAlgorithm) SetSessionStatsSource(fn func(symbol string) (*SessionStats, bool)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sessionStats = fn
+}
+
+// session returns symbol's session stats, nil before the session's first
+// bar or without a source.
+func (a *TradingAlgorithm) session(symbol string) *SessionStats {
+	a.mu.RLock()
+	fn := a.sessionStats
+	a.mu.RUnlock()
+	if fn == nil {
+		return nil
+	}
+	s, ok := fn(symbol)
+	if !ok {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,135 @@
+package algorithm
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Batch signal statuses.
+const (
+	BatchOK      = "ok"
+	BatchError   = "error"
+	BatchTimeout = "timeout"
+)
+
+// Batch generation limits.
+const (
+	DefaultBatchConcurrency = 4
+	MaxBatchConcurrency     = 16
+	MaxBatchSymbols         = 100
+)
+
+// BatchSignal is the outcome of generating one symbol's signal in a batch.
+type BatchSignal struct {
+	Symbol string       `json:"symbol"`
+	Status string       `json:"status"` // ok, error or timeout
+	Signal *TradeSignal `json:"signal,omitempty"`
+	Error  string       `json:"error,omitempty"`
+	// DurationMS is how long generation took; zero for symbols never started
+	DurationMS float64 `json:"duration_ms,omitempty"`
+}
+
+// SignalBatch is every symbol's outcome, in request order, with counts.
+type SignalBatch struct {
+	Signals   []BatchSignal `json:"signals"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	TimedOut  int           `json:"timed_out"`
+	// Partial reports whether the deadline passed before every symbol
+	// finished; generations still running complete in the background
+	Partial bool `json:"partial"`
+}
+
+// BatchSymbols upper-cases symbols and drops blanks and duplicates,
+// keeping the first occurrence's position.
+func BatchSymbols(symbols []string) []string {
+	seen := make(map[string]bool, len(symbols))
+	out := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		if s != "" && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// GenerateSignals generates a signal for each of symbols, normalized by
+// BatchSymbols, as ProcessSymbol does, at most concurrency at a time,
+// until ctx is done. Symbols still generating or not yet started by then
+// are reported as timed out.
+func (a *TradingAlgorithm) GenerateSignals(ctx context.Context, symbols []string, concurrency int) SignalBatch {
+	symbols = BatchSymbols(symbols)
+	if concurrency < 1 {
+		concurrency = DefaultBatchConcurrency
+	}
+	if concurrency > MaxBatchConcurrency {
+		concurrency = MaxBatchConcurrency
+	}
+	batch := SignalBatch{Signals: make([]BatchSignal, len(symbols))}
+	for i, s := range symbols {
+		batch.Signals[i] = BatchSignal{Symbol: s, Status: BatchTimeout, Error: "not started before the deadline"}
+	}
+
+	queue := make(chan int)
+	// Buffered so workers finishing after the deadline never block
+	results := make(chan BatchSignal, len(symbols))
+	for w := 0; w < concurrency && w < len(symbols); w++ {
+		go func() {
+			for i := range queue {
+				results <- a.generateBatchSignal(symbols[i])
+			}
+		}()
+	}
+
+	index := make(map[string]int, len(symbols))
+	for i, s := range symbols {
+		index[s] = i
+	}
+	next, pending := 0, len(symbols)
+	for pending > 0 {
+		// Feed the workers while waiting for results; nil disables the send
+		var send chan int
+		if next < len(symbols) {
+			send = queue
+		}
+		select {
+		case send <- next:
+			batch.Signals[next].Error = "still generating at the deadline"
+			next++
+		case r := <-results:
+			batch.Signals[index[r.Symbol]] = r
+			pending--
+		case <-ctx.Done():
+			batch.Partial = true
+			pending = 0
+		}
+	}
+	close(queue)
+
+	for _, s := range batch.Signals {
+		switch s.Status {
+		case BatchOK:
+			batch.Succeeded++
+		case BatchError:
+			batch.Failed++
+		default:
+			batch.TimedOut++
+		}
+	}
+	return batch
+}
+
+func (a *TradingAlgorithm) generateBatchSignal(symbol string) BatchSignal {
+	start := time.Now()
+	err := a.ProcessSymbol(symbol)
+	r := BatchSignal{Symbol: symbol, Status: BatchOK, DurationMS: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		r.Status, r.Error = BatchError, err.Error()
+		return r
+	}
+	r.Signal = a.GetSignal(symbol)
+	return r
+}
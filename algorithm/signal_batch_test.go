@@ -0,0 +1,75 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowClaude answers after a per-symbol delay, tracking peak concurrency.
+type slowClaude struct {
+	mu      sync.Mutex
+	active  int
+	peak    int
+	delays  map[string]time.Duration
+	failing map[string]bool
+}
+
+func (s *slowClaude) GenerateTradeSignal(symbol string, _ MarketData, _ PortfolioData) (*TradeSignal, error) {
+	s.mu.Lock()
+	s.active++
+	if s.active > s.peak {
+		s.peak = s.active
+	}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.active--
+		s.mu.Unlock()
+	}()
+	time.Sleep(s.delays[symbol])
+	if s.failing[symbol] {
+		return nil, errors.New("upstream error")
+	}
+	return &TradeSignal{Symbol: symbol, Signal: SignalBuy, Reasoning: "stub"}, nil
+}
+
+func TestGenerateSignalsBoundsConcurrencyAndReturnsPartialResults(t *testing.T) {
+	claude := &slowClaude{
+		delays:  map[string]time.Duration{"AAA": 10 * time.Millisecond, "BBB": 10 * time.Millisecond, "CCC": 10 * time.Millisecond, "SLOW": time.Second},
+		failing: map[string]bool{"BBB": true},
+	}
+	a := NewTradingAlgorithm(context.Background(), claude, nil, nil)
+	a.tradingEnabled = true
+	for _, s := range []string{"AAA", "BBB", "CCC", "SLOW"} {
+		a.marketData[s] = MarketData{Symbol: s, Price: 100}
+		a.cachePatterns(s, nil)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	batch := a.GenerateSignals(ctx, []string{"aaa", "SLOW", "BBB", "AAA", "CCC", "NONE"}, 2)
+
+	if len(batch.Signals) != 5 || batch.Signals[0].Symbol != "AAA" || batch.Signals[4].Symbol != "NONE" {
+		t.Fatalf("signals = %+v", batch.Signals)
+	}
+	want := map[string]string{"AAA": BatchOK, "SLOW": BatchTimeout, "BBB": BatchError, "CCC": BatchOK, "NONE": BatchError}
+	for _, s := range batch.Signals {
+		if s.Status != want[s.Symbol] {
+			t.Errorf("%s: status %s (%s)", s.Symbol, s.Status, s.Error)
+		}
+	}
+	if batch.Signals[0].Signal == nil || batch.Signals[0].Signal.Signal != SignalBuy {
+		t.Errorf("AAA signal = %+v", batch.Signals[0].Signal)
+	}
+	if !batch.Partial || batch.Succeeded != 2 || batch.Failed != 2 || batch.TimedOut != 1 {
+		t.Errorf("batch = %+v", batch)
+	}
+	claude.mu.Lock()
+	defer claude.mu.Unlock()
+	if claude.peak > 2 {
+		t.Errorf("peak concurrency %d over the limit of 2", claude.peak)
+	}
+}
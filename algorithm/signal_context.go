@@ -0,0 +1,161 @@
+package algorithm
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxSignalContexts bounds how many signal contexts are kept for lookup by
+// ID.
+const maxSignalContexts = 500
+
+// signalContextTTL is how long a context read by symbol is served before
+// it is rebuilt from the current state.
+var signalContextTTL = 5 * time.Second
+
+// SignalContext is the market data and portfolio a signal was, or would
+// be, generated from, exactly as it is sent to Claude.
+type SignalContext struct {
+	ID         string        `json:"id"`
+	Symbol     string        `json:"symbol"`
+	At         time.Time     `json:"at"`
+	MarketData MarketData    `json:"market_data"`
+	Portfolio  PortfolioData `json:"portfolio"`
+}
+
+// signalContexts keeps recent signal contexts by ID and the latest per
+// symbol.
+type signalContexts struct {
+	mu     sync.Mutex
+	seq    int
+	byID   map[string]SignalContext
+	order  []string // IDs, oldest first
+	latest map[string]string
+	tokens map[string]string // by ID, until redeemed
+}
+
+func (s *signalContexts) put(c SignalContext) SignalContext {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.byID == nil {
+		s.byID = make(map[string]SignalContext)
+		s.latest = make(map[string]string)
+		s.tokens = make(map[string]string)
+	}
+	s.seq++
+	c.ID = fmt.Sprintf("%s-%d", c.Symbol, s.seq)
+	c.MarketData.ContextID = c.ID
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err == nil {
+		c.MarketData.ContextToken = hex.EncodeToString(token)
+		s.tokens[c.ID] = c.MarketData.ContextToken
+	}
+	s.byID[c.ID] = c
+	s.latest[c.Symbol] = c.ID
+	s.order = append(s.order, c.ID)
+	if len(s.order) > maxSignalContexts {
+		evicted := s.order[0]
+		s.order = s.order[1:]
+		if old := s.byID[evicted]; s.latest[old.Symbol] == evicted {
+			delete(s.latest, old.Symbol)
+		}
+		delete(s.byID, evicted)
+		delete(s.tokens, evicted)
+	}
+	return c
+}
+
+// redeem returns the context id names if token is the one issued with it,
+// and spends the token.
+func (s *signalContexts) redeem(id, token string) (SignalContext, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	want, ok := s.tokens[id]
+	if !ok || token == "" || subtle.ConstantTimeCompare([]byte(want), []byte(token)) != 1 {
+		return SignalContext{}, false
+	}
+	delete(s.tokens, id)
+	return s.byID[id], true
+}
+
+func (s *signalContexts) get(id string) (SignalContext, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.byID[id]
+	return c, ok
+}
+
+func (s *signalContexts) latestFor(symbol string) (SignalContext, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.byID[s.latest[symbol]]
+	return c, ok
+}
+
+// buildSignalContext builds the market data, with its patterns, implied
+// move, breadth, features and session stats, and a copy of the portfolio a signal for
+// symbol is generated from.
+func (a *TradingAlgorithm) buildSignalContext(symbol string) (MarketData, PortfolioData, error) {
+	a.mu.RLock()
+	marketData, exists := a.marketData[symbol]
+	if !exists {
+		a.mu.RUnlock()
+		return MarketData{}, PortfolioData{}, fmt.Errorf("market data not found for symbol: %s", symbol)
+	}
+	portfolio := a.portfolioSnapshotLocked()
+	a.mu.RUnlock()
+
+	// Attach candlestick patterns so Claude sees them as context
+	patterns, fresh := a.cachedPatterns(symbol)
+	a.recordCacheLookup(CachePatterns, fresh)
+	if !fresh {
+		if report, err := a.GetPatterns(symbol, "1D"); err == nil {
+			patterns = make([]string, len(report.Latest))
+			for i, m := range report.Latest {
+				patterns[i] = string(m.Pattern)
+			}
+		}
+	}
+	marketData.Patterns = patterns
+	marketData.ImpliedMovePercent, _ = a.impliedMove(symbol)
+	marketData.Breadth = a.marketBreadth()
+	marketData.Features = a.features(symbol)
+	marketData.Session = a.session(symbol)
+	return marketData, portfolio, nil
+}
+
+// TakeSignalContext builds symbol's signal context now and keeps it under
+// a new ID.
+func (a *TradingAlgorithm) TakeSignalContext(symbol string) (SignalContext, error) {
+	marketData, portfolio, err := a.buildSignalContext(symbol)
+	if err != nil {
+		return SignalContext{}, err
+	}
+	return a.signalContexts.put(SignalContext{Symbol: symbol, At: a.now(), MarketData: marketData, Portfolio: portfolio}), nil
+}
+
+// SignalContext returns symbol's latest signal context while it is
+// younger than signalContextTTL, and otherwise takes a new one.
+func (a *TradingAlgorithm) SignalContext(symbol string) (SignalContext, error) {
+	if c, ok := a.signalContexts.latestFor(symbol); ok && a.now().Sub(c.At) < signalContextTTL {
+		return c, nil
+	}
+	return a.TakeSignalContext(symbol)
+}
+
+// SignalContextByID returns a kept signal context, such as the one a
+// signal request names.
+func (a *TradingAlgorithm) SignalContextByID(id string) (SignalContext, bool) {
+	return a.signalContexts.get(id)
+}
+
+// RedeemSignalContext returns the kept signal context id names when token
+// is its MarketData.ContextToken, once; the Claude adapter reads its
+// request's context this way without an API token.
+func (a *TradingAlgorithm) RedeemSignalContext(id, token string) (SignalContext, bool) {
+	return a.signalContexts.redeem(id, token)
+}
@@ -0,0 +1,75 @@
+package algorithm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// recordingClaude records the market data it is asked about.
+type recordingClaude struct {
+	seen []MarketData
+}
+
+func (c *recordingClaude) GenerateTradeSignal(symbol string, md MarketData, _ PortfolioData) (*TradeSignal, error) {
+	c.seen = append(c.seen, md)
+	return &TradeSignal{Symbol: symbol, Signal: SignalHold, OrderType: "market"}, nil
+}
+
+func TestSignalContextIsWhatClaudeSaw(t *testing.T) {
+	claude := &recordingClaude{}
+	a := NewTradingAlgorithm(context.Background(), claude, nil, nil)
+	a.tradingEnabled = true
+	now := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	a.SetClock(func() time.Time { return now })
+	a.portfolio = PortfolioData{
+		Balance:   1000,
+		Positions: map[string]PositionData{"AAPL": {Symbol: "AAPL", Quantity: 10, AvgPrice: 100}},
+	}
+	a.SetSessionStatsSource(func(symbol string) (*SessionStats, bool) {
+		return &SessionStats{Open: 108, VWAP: 109.5, VolumeRatio: 0.4}, symbol == "AAPL"
+	})
+	a.UpdateMarketData("AAPL", 110, 111, 108, 5000, 1)
+	if err := a.ProcessSymbol("AAPL"); err != nil {
+		t.Fatal(err)
+	}
+	sent := claude.seen[0]
+	if sent.ContextID == "" {
+		t.Fatal("signal request carries no context ID")
+	}
+	if sent.Session == nil || sent.Session.VWAP != 109.5 {
+		t.Errorf("session stats sent = %+v", sent.Session)
+	}
+	sc, ok := a.SignalContextByID(sent.ContextID)
+	if !ok || sc.MarketData.Price != 110 || sc.MarketData.ContextID != sent.ContextID || sc.Portfolio.Positions["AAPL"].Quantity != 10 {
+		t.Fatalf("context = %+v, %v", sc, ok)
+	}
+	// The adapter reads it once with the token the request carries
+	if _, ok := a.RedeemSignalContext(sent.ContextID, "guess"); ok || sent.ContextToken == "" {
+		t.Errorf("redeemed with a wrong token, or none was issued")
+	}
+	if sc, ok := a.RedeemSignalContext(sent.ContextID, sent.ContextToken); !ok || sc.ID != sent.ContextID {
+		t.Errorf("redeemed %+v, %v", sc, ok)
+	}
+	if _, ok := a.RedeemSignalContext(sent.ContextID, sent.ContextToken); ok {
+		t.Error("token redeemed twice")
+	}
+
+	// The kept context does not follow later updates...
+	a.UpdateMarketData("AAPL", 115, 116, 108, 6000, 1)
+	if sc, _ := a.SignalContextByID(sent.ContextID); sc.MarketData.Price != 110 {
+		t.Errorf("kept context moved to %v", sc.MarketData.Price)
+	}
+	// ...and is served by symbol until it is stale, then read through
+	if sc, _ := a.SignalContext("AAPL"); sc.ID != sent.ContextID {
+		t.Errorf("fresh read took a new context %s", sc.ID)
+	}
+	now = now.Add(signalContextTTL)
+	sc, err := a.SignalContext("AAPL")
+	if err != nil || sc.ID == sent.ContextID || sc.MarketData.Price != 115 {
+		t.Errorf("stale read = %+v, %v", sc, err)
+	}
+	if _, err := a.SignalContext("MSFT"); err == nil {
+		t.Error("context for a symbol without market data")
+	}
+}
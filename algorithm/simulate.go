@@ -0,0 +1,375 @@
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm/algo"
+	"github.com/rileyseaburg/go-trader/algorithm/algo/volatility"
+	"github.com/rileyseaburg/go-trader/algorithm/sizing"
+	"github.com/shopspring/decimal"
+)
+
+const (
+	// defaultSlippageBps is the simulated slippage when a request does not
+	// set one: half a spread on a liquid large cap.
+	defaultSlippageBps = 5.0
+)
+
+// BarrierSettings are the triple barrier multiples used to project exit
+// levels. Zero fields take the triple barrier algorithm's defaults.
+type BarrierSettings struct {
+	ProfitTaking       float64 `json:"profit_taking,omitempty"`       // multiple of daily volatility
+	StopLoss           float64 `json:"stop_loss,omitempty"`           // multiple of daily volatility
+	TimeHorizon        int     `json:"time_horizon,omitempty"`        // sessions
+	VolatilityLookback int     `json:"volatility_lookback,omitempty"` // span of the volatility estimate
+	// Volatility names the estimator; empty takes the volatility_estimator
+	// risk parameter
+	Volatility string `json:"volatility,omitempty"`
+}
+
+func (b BarrierSettings) withDefaults() BarrierSettings {
+	if b.ProfitTaking <= 0 {
+		b.ProfitTaking = 2.0
+	}
+	if b.StopLoss <= 0 {
+		b.StopLoss = 1.0
+	}
+	if b.TimeHorizon <= 0 {
+		b.TimeHorizon = 5
+	}
+	if b.VolatilityLookback <= 0 {
+		b.VolatilityLookback = 20
+	}
+	return b
+}
+
+// SimulationRequest is a hypothetical signal and the assumptions to price
+// it under.
+type SimulationRequest struct {
+	Symbol     string   `json:"symbol"`
+	Signal     string   `json:"signal"`     // buy, sell, close, hold
+	OrderType  string   `json:"order_type"` // market (default), limit, stop or stop_limit
+	LimitPrice *float64 `json:"limit_price,omitempty"`
+	StopPrice  *float64 `json:"stop_price,omitempty"`
+	Confidence *float64 `json:"confidence,omitempty"`
+	// Price overrides the symbol's last streamed price
+	Price              float64          `json:"price,omitempty"`
+	SlippageBps        *float64         `json:"slippage_bps,omitempty"`
+	CommissionPerShare float64          `json:"commission_per_share,omitempty"`
+	Barrier            *BarrierSettings `json:"barrier,omitempty"`
+}
+
+// SimulatedSizing shows how the position size was reached.
+type SimulatedSizing struct {
+	Equity             float64 `json:"equity"`
+	MaxPositionPercent float64 `json:"max_position_percent"` // after any position scale
+	PositionScale      float64 `json:"position_scale"`
+	BaseValue          float64 `json:"base_value"` // equity × max position percent
+	RegimeMultiplier   float64 `json:"regime_multiplier"`
+	VolTargetScale     float64 `json:"vol_target_scale"`
+	// Factors are every multiplier risk sizing applied to a new position,
+	// including the implied move and liquidity score when they shrink it
+	Factors  []sizing.Factor `json:"factors,omitempty"`
+	Quantity float64         `json:"quantity"` // signed: negative sells
+}
+
+// CostEstimate is the expected cost of filling the order.
+type CostEstimate struct {
+	Notional    float64 `json:"notional"`
+	SlippageBps float64 `json:"slippage_bps"`
+	Slippage    float64 `json:"slippage"`
+	Commission  float64 `json:"commission"`
+	Total       float64 `json:"total"` // slippage + commission
+}
+
+// BarrierLevels are projected exits for the resulting position. Fixed
+// levels come from the stop_loss_percent and take_profit_percent risk
+// parameters; volatility levels from the triple barrier method on cached
+// daily bars, when there are enough, by the estimator named in
+// VolatilityEstimator.
+type BarrierLevels struct {
+	Direction            string          `json:"direction"` // long or short
+	Entry                float64         `json:"entry"`
+	StopLoss             float64         `json:"stop_loss"`
+	TakeProfit           float64         `json:"take_profit"`
+	DailyVolatility      float64         `json:"daily_volatility,omitempty"`
+	VolatilityEstimator  string          `json:"volatility_estimator,omitempty"` // ewma when none is configured
+	VolatilityStopLoss   float64         `json:"volatility_stop_loss,omitempty"`
+	VolatilityTakeProfit float64         `json:"volatility_take_profit,omitempty"`
+	Settings             BarrierSettings `json:"settings"`
+}
+
+// PortfolioImpact compares the portfolio before and after the order.
+// Volatilities are annualized percentages, zero without return history.
+type PortfolioImpact struct {
+	QuantityBefore      float64 `json:"quantity_before"`
+	QuantityAfter       float64 `json:"quantity_after"`
+	CashBefore          float64 `json:"cash_before"`
+	CashAfter           float64 `json:"cash_after"`
+	WeightBefore        float64 `json:"weight_before"` // position value ÷ equity
+	WeightAfter         float64 `json:"weight_after"`
+	OpenPositionsBefore int     `json:"open_positions_before"`
+	OpenPositionsAfter  int     `json:"open_positions_after"`
+	VolatilityBefore    float64 `json:"volatility_before,omitempty"`
+	VolatilityAfter     float64 `json:"volatility_after,omitempty"`
+}
+
+// TradeSimulation is what the system would do with a signal.
+type TradeSimulation struct {
+	Signal   *TradeSignal     `json:"signal"`
+	Price    float64          `json:"price"`
+	Allowed  bool             `json:"allowed"` // every guard passed
+	Guards   []GuardResult    `json:"guards"`
+	Sizing   SimulatedSizing  `json:"sizing"`
+	Order    *OrderPreview    `json:"order"` // nil when the signal needs no order
+	Note     string           `json:"note,omitempty"`
+	Costs    *CostEstimate    `json:"costs,omitempty"`
+	Barriers *BarrierLevels   `json:"barriers,omitempty"`
+	Impact   *PortfolioImpact `json:"impact,omitempty"`
+	At       time.Time        `json:"simulated_at"`
+}
+
+// SimulateTrade runs req through the same guards, sizing and pricing as
+// ExecuteTrade and reports the outcome. Nothing is submitted and guards
+// that would queue a capped signal do not.
+func (a *TradingAlgorithm) SimulateTrade(req SimulationRequest) (*TradeSimulation, error) {
+	symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+	if symbol == "" {
+		return nil, errors.New("symbol is required")
+	}
+	orderType, err := NormalizeOrderType(req.OrderType)
+	if err != nil {
+		return nil, err
+	}
+	slippageBps := defaultSlippageBps
+	if req.SlippageBps != nil {
+		slippageBps = *req.SlippageBps
+	}
+	if slippageBps < 0 || req.CommissionPerShare < 0 {
+		return nil, errors.New("slippage_bps and commission_per_share must not be negative")
+	}
+	if req.Barrier != nil {
+		if _, err := volatility.Parse(req.Barrier.Volatility); err != nil {
+			return nil, err
+		}
+	}
+
+	signal := &TradeSignal{
+		Symbol:     symbol,
+		Signal:     strings.ToLower(req.Signal),
+		OrderType:  orderType,
+		LimitPrice: req.LimitPrice,
+		StopPrice:  req.StopPrice,
+		Confidence: req.Confidence,
+		Timestamp:  time.Now(),
+		Reasoning:  "simulation",
+		Source:     "simulation",
+	}
+
+	// Guards read the same cached portfolio the sizing below uses
+	guards := a.EvaluateTradeGuards(signal)
+
+	a.mu.RLock()
+	price := req.Price
+	if price <= 0 {
+		price = a.marketData[symbol].Price
+	}
+	portfolio := a.portfolioSnapshotLocked()
+	riskParams := a.sizingParamsLocked()
+	regime := a.regimeMultiplier
+	scale := a.positionScale
+	volState := a.volTargetStateLocked()
+	a.mu.RUnlock()
+
+	if price <= 0 {
+		return nil, fmt.Errorf("no price for %s; pass price to simulate", symbol)
+	}
+	if regime <= 0 {
+		regime = 1.0
+	}
+
+	sim := &TradeSimulation{
+		Signal:  signal,
+		Price:   price,
+		Allowed: true,
+		Guards:  guards,
+		At:      signal.Timestamp,
+	}
+	for _, g := range guards {
+		sim.Allowed = sim.Allowed && g.Passed
+	}
+
+	maxPct, ok := riskParams["max_position_size_percent"].(float64)
+	if !ok {
+		maxPct = 5.0
+	}
+	sim.Sizing = SimulatedSizing{
+		Equity:             portfolio.TotalValue,
+		MaxPositionPercent: maxPct,
+		BaseValue:          portfolio.TotalValue * maxPct / 100.0,
+		PositionScale:      scale,
+		RegimeMultiplier:   regime,
+		VolTargetScale:     volState.Scale,
+		Factors:            a.riskSize(symbol, decimal.NewFromFloat(price), decimal.NewFromFloat(portfolio.TotalValue), riskParams).Factors,
+	}
+
+	order, err := a.buildOrder(signal, price, portfolio, riskParams)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		sim.Note = "signal requires no order"
+		return sim, nil
+	}
+	order.DryRun = true
+	sim.Order = order
+
+	qty, _ := order.Request.Qty.Float64()
+	if order.Request.Side == "sell" {
+		qty = -qty
+	}
+	sim.Sizing.Quantity = qty
+
+	notional, _ := order.EstimatedCost.Float64()
+	costs := &CostEstimate{
+		Notional:    notional,
+		SlippageBps: slippageBps,
+		Slippage:    roundCents(notional * slippageBps / 10000),
+		Commission:  roundCents(math.Abs(qty) * req.CommissionPerShare),
+	}
+	costs.Total = roundCents(costs.Slippage + costs.Commission)
+	sim.Costs = costs
+
+	// Limit orders fill at their limit and stops at their stop
+	fill := price
+	if order.Request.LimitPrice != nil {
+		fill, _ = order.Request.LimitPrice.Float64()
+	} else if order.Request.StopPrice != nil {
+		fill, _ = order.Request.StopPrice.Float64()
+	}
+	sim.Impact = a.simulateImpact(symbol, qty, fill, price, costs.Total, portfolio, volState.Weights)
+	sim.Barriers = a.simulateBarriers(symbol, sim.Impact.QuantityAfter, fill, riskParams, req.Barrier)
+	return sim, nil
+}
+
+// simulateImpact applies a fill of qty at fill to portfolio. The symbol is
+// marked at price afterwards.
+func (a *TradingAlgorithm) simulateImpact(symbol string, qty, fill, price, costs float64, portfolio PortfolioData, weights map[string]float64) *PortfolioImpact {
+	before := portfolio.Positions[symbol].Quantity
+	after := before + qty
+	equity := portfolio.TotalValue
+	impact := &PortfolioImpact{
+		QuantityBefore:      before,
+		QuantityAfter:       after,
+		CashBefore:          portfolio.Balance,
+		CashAfter:           roundCents(portfolio.Balance - qty*fill - costs),
+		OpenPositionsBefore: countOpen(portfolio.Positions),
+	}
+	impact.OpenPositionsAfter = impact.OpenPositionsBefore
+	switch {
+	case before == 0 && after != 0:
+		impact.OpenPositionsAfter++
+	case before != 0 && after == 0:
+		impact.OpenPositionsAfter--
+	}
+
+	if equity <= 0 {
+		return impact
+	}
+	impact.WeightBefore = portfolio.Positions[symbol].MarketVal / equity
+	if before < 0 && impact.WeightBefore > 0 {
+		impact.WeightBefore = -impact.WeightBefore
+	}
+	equityAfter := equity - costs - qty*(fill-price)
+	if equityAfter > 0 {
+		impact.WeightAfter = after * price / equityAfter
+	}
+
+	next := make(map[string]float64, len(weights)+1)
+	for sym, w := range weights {
+		next[sym] = w
+	}
+	next[symbol] = impact.WeightAfter
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if vol, err := PortfolioVolatility(weights, a.dailyReturns); err == nil {
+		impact.VolatilityBefore = vol * 100
+	}
+	if vol, err := PortfolioVolatility(next, a.dailyReturns); err == nil {
+		impact.VolatilityAfter = vol * 100
+	}
+	return impact
+}
+
+// simulateBarriers projects exits for a position of qty entered at entry.
+// A flat result has nothing to protect and returns nil.
+func (a *TradingAlgorithm) simulateBarriers(symbol string, qty, entry float64, riskParams map[string]interface{}, settings *BarrierSettings) *BarrierLevels {
+	if qty == 0 || entry <= 0 {
+		return nil
+	}
+	s := BarrierSettings{}
+	if settings != nil {
+		s = *settings
+	}
+	s = s.withDefaults()
+	if s.Volatility == "" {
+		s.Volatility, _ = riskParams["volatility_estimator"].(string)
+	}
+
+	dir, direction := 1.0, "long"
+	if qty < 0 {
+		dir, direction = -1.0, "short"
+	}
+	stopPct := riskParamFloat(riskParams, "stop_loss_percent", 5.0)
+	takePct := riskParamFloat(riskParams, "take_profit_percent", 15.0)
+	levels := &BarrierLevels{
+		Direction:  direction,
+		Entry:      entry,
+		StopLoss:   roundCents(entry * (1 - dir*stopPct/100)),
+		TakeProfit: roundCents(entry * (1 + dir*takePct/100)),
+		Settings:   s,
+	}
+
+	bars, _ := a.CachedBars(symbol, warmStartTimeFrame)
+	if len(bars) <= s.VolatilityLookback {
+		return levels
+	}
+	vol, estimator, err := estimateBarVolatility(bars, s.VolatilityLookback, s.Volatility)
+	if err != nil || vol <= 0 {
+		return levels
+	}
+	levels.DailyVolatility, levels.VolatilityEstimator = vol, estimator
+	levels.VolatilityStopLoss = roundCents(entry * (1 - dir*s.StopLoss*vol))
+	levels.VolatilityTakeProfit = roundCents(entry * (1 + dir*s.ProfitTaking*vol))
+	return levels
+}
+
+// estimateBarVolatility estimates the volatility of bars with the named
+// estimator, or as the triple barrier algorithm does, an EWMA of close to
+// close returns over span, when none is named. It returns the estimator
+// used.
+func estimateBarVolatility(bars []BarData, span int, estimator string) (float64, string, error) {
+	if estimator != "" {
+		ohlc := make([]volatility.Bar, len(bars))
+		for i, b := range bars {
+			ohlc[i] = volatility.Bar{Open: b.Open, High: b.High, Low: b.Low, Close: b.Close}
+		}
+		vol, used, err := volatility.Estimate(volatility.Estimator(estimator), ohlc, span)
+		return vol, string(used), err
+	}
+	closes := make([]float64, len(bars))
+	for i, b := range bars {
+		closes[i] = b.Close
+	}
+	vol, err := algo.DailyVolatility(closes, span)
+	return vol, "ewma", err
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
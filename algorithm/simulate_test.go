@@ -0,0 +1,94 @@
+package algorithm
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSimulateTradeSizesWithoutSideEffects(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	a.portfolio = PortfolioData{
+		Balance:    90000,
+		TotalValue: 100000,
+		Positions: map[string]PositionData{
+			"MSFT": {Symbol: "MSFT", Quantity: 25, MarketVal: 10000},
+		},
+	}
+	a.portfolioAt = time.Now()
+	a.marketData["AAPL"] = MarketData{Symbol: "AAPL", Price: 100}
+
+	sim, err := a.SimulateTrade(SimulationRequest{Symbol: "aapl", Signal: "buy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sim.Allowed || sim.Order == nil || sim.Sizing.Quantity != 50 {
+		t.Fatalf("simulation = %+v", sim)
+	}
+	if sim.Costs.Notional != 5000 || sim.Costs.Slippage != 2.5 {
+		t.Errorf("costs = %+v", sim.Costs)
+	}
+	if b := sim.Barriers; b.Direction != "long" || b.StopLoss != 95 || b.TakeProfit != 115 {
+		t.Errorf("barriers = %+v", b)
+	}
+	if im := sim.Impact; im.QuantityAfter != 50 || im.CashAfter != 84997.5 || im.OpenPositionsAfter != 2 {
+		t.Errorf("impact = %+v", im)
+	}
+
+	// A capped signal is reported, not queued
+	if err := a.UpdateRiskParameters(map[string]interface{}{"max_open_positions": 1, "queue_capped_signals": true}); err != nil {
+		t.Fatal(err)
+	}
+	sim, err = a.SimulateTrade(SimulationRequest{Symbol: "AAPL", Signal: "buy", Price: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sim.Allowed || sim.Guards[1].Name != "position caps" || sim.Guards[1].Passed || sim.Sizing.Quantity != 25 {
+		t.Errorf("capped simulation = %+v", sim)
+	}
+	if len(a.GetRiskMetrics().Queued) != 0 {
+		t.Error("simulation queued the capped signal")
+	}
+
+	if sim, _ := a.SimulateTrade(SimulationRequest{Symbol: "AAPL", Signal: "hold"}); sim.Order != nil || sim.Note == "" {
+		t.Errorf("hold simulation = %+v", sim)
+	}
+	if _, err := a.SimulateTrade(SimulationRequest{Symbol: "TSLA", Signal: "buy"}); err == nil {
+		t.Error("simulated without a price")
+	}
+}
+
+func TestBarrierLevelsUseTheConfiguredEstimator(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	// Closes alternate 1% apart while every bar trades 4% wide
+	bars := make([]BarData, 40)
+	for i := range bars {
+		c := 100.0
+		if i%2 == 1 {
+			c = 101
+		}
+		bars[i] = BarData{Open: c, High: c * 1.02, Low: c / 1.02, Close: c}
+	}
+	a.barCache[barCacheKey("AAPL", warmStartTimeFrame)] = barCacheEntry{bars: bars}
+
+	ewma := a.simulateBarriers("AAPL", 10, 100, a.GetRiskParameters(), nil)
+	if ewma.VolatilityEstimator != "ewma" || ewma.DailyVolatility <= 0 {
+		t.Fatalf("default levels = %+v", ewma)
+	}
+	if err := a.UpdateRiskParameters(map[string]interface{}{"volatility_estimator": "parkinson"}); err != nil {
+		t.Fatal(err)
+	}
+	ranged := a.simulateBarriers("AAPL", 10, 100, a.GetRiskParameters(), nil)
+	if ranged.VolatilityEstimator != "parkinson" || ranged.DailyVolatility <= ewma.DailyVolatility || ranged.VolatilityStopLoss >= ewma.VolatilityStopLoss {
+		t.Errorf("parkinson levels = %+v, ewma %+v", ranged, ewma)
+	}
+	// A request's own estimator wins
+	yz := a.simulateBarriers("AAPL", 10, 100, a.GetRiskParameters(), &BarrierSettings{Volatility: "yang_zhang"})
+	if yz.VolatilityEstimator != "yang_zhang" {
+		t.Errorf("yang-zhang levels = %+v", yz)
+	}
+
+	if err := a.UpdateRiskParameters(map[string]interface{}{"volatility_estimator": "atr"}); err == nil {
+		t.Error("unknown estimator accepted")
+	}
+}
@@ -0,0 +1,160 @@
+// Package sizing turns risk parameters and explicit trade sizes into share
+// quantities. The algorithm and the HTTP trade path both size through it,
+// in decimal, so dollar amounts and share counts round the same way
+// wherever an order is built.
+package sizing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrTradeSize is wrapped by refusals of a caller-chosen trade size.
+var ErrTradeSize = errors.New("invalid trade size")
+
+var hundred = decimal.NewFromInt(100)
+
+// Factor is a named multiplier on a risk-sized position's value, such as
+// the macro regime or the volatility target scale.
+type Factor struct {
+	Name  string          `json:"name"`
+	Value decimal.Decimal `json:"value"`
+}
+
+// Request describes a risk-sized position: MaxPercent of Equity, scaled
+// by each factor, capped at MaxValue and bought at Price.
+type Request struct {
+	Equity     decimal.Decimal
+	Price      decimal.Decimal
+	MaxPercent decimal.Decimal // max_position_size_percent
+	Factors    []Factor
+	MaxValue   decimal.Decimal // zero for no cap
+}
+
+// Result is a risk-sized position.
+type Result struct {
+	BaseValue decimal.Decimal `json:"base_value"` // Equity × MaxPercent
+	Value     decimal.Decimal `json:"value"`      // after factors and the cap
+	Shares    decimal.Decimal `json:"shares"`     // whole shares, never negative
+	Factors   []Factor        `json:"factors,omitempty"`
+	Capped    bool            `json:"capped"` // MaxValue bound
+}
+
+// Risk sizes r in whole shares, rounding down. Negative factors count as
+// zero. A non-positive price or equity sizes nothing.
+func Risk(r Request) Result {
+	res := Result{Factors: r.Factors}
+	if !r.Equity.IsPositive() || !r.MaxPercent.IsPositive() {
+		return res
+	}
+	res.BaseValue = r.Equity.Mul(r.MaxPercent).Div(hundred)
+	res.Value = res.BaseValue
+	for _, f := range r.Factors {
+		if f.Value.IsNegative() {
+			res.Value = decimal.Zero
+			continue
+		}
+		res.Value = res.Value.Mul(f.Value)
+	}
+	if r.MaxValue.IsPositive() && res.Value.GreaterThan(r.MaxValue) {
+		res.Value, res.Capped = r.MaxValue, true
+	}
+	res.Value = res.Value.Round(2)
+	res.Shares = WholeShares(res.Value, r.Price)
+	return res
+}
+
+// ATRFactor is the factor that scales a MaxPercent position to the
+// shares losing riskPercent of equity on a one-ATR adverse move, equity ×
+// riskPercent ÷ atr, at most 1 so maxPercent stays the cap. Equity cancels
+// out. It is 1 when any input is not positive.
+func ATRFactor(price, atr, riskPercent, maxPercent decimal.Decimal) decimal.Decimal {
+	one := decimal.NewFromInt(1)
+	if !price.IsPositive() || !atr.IsPositive() || !riskPercent.IsPositive() || !maxPercent.IsPositive() {
+		return one
+	}
+	return decimal.Min(one, riskPercent.Mul(price).Div(atr.Mul(maxPercent)))
+}
+
+// WholeShares is the whole shares value buys at price, rounded down.
+func WholeShares(value, price decimal.Decimal) decimal.Decimal {
+	if !price.IsPositive() || !value.IsPositive() {
+		return decimal.Zero
+	}
+	return value.Div(price).Floor()
+}
+
+// TradeSize is an explicit size for a trade, set by the user instead of the
+// default risk-based sizing. Exactly one field is set.
+type TradeSize struct {
+	Qty             float64 `json:"qty,omitempty"`               // shares, fractional allowed
+	Notional        float64 `json:"notional,omitempty"`          // dollars
+	PercentOfEquity float64 `json:"percent_of_equity,omitempty"` // 0-100
+}
+
+// Validate checks that exactly one positive size is given.
+func (s *TradeSize) Validate() error {
+	set := 0
+	for name, v := range map[string]float64{"qty": s.Qty, "notional": s.Notional, "percent_of_equity": s.PercentOfEquity} {
+		if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("%w: %s must be positive", ErrTradeSize, name)
+		}
+		if v > 0 {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("%w: set exactly one of qty, notional or percent_of_equity", ErrTradeSize)
+	}
+	if s.PercentOfEquity > 100 {
+		return fmt.Errorf("%w: percent_of_equity %.2f is above 100", ErrTradeSize, s.PercentOfEquity)
+	}
+	return nil
+}
+
+// Shares converts the size to a quantity at price. An explicit qty is used
+// as given, to six places; dollar sizes are rounded down to whole shares.
+func (s *TradeSize) Shares(price, equity decimal.Decimal) (decimal.Decimal, error) {
+	if err := s.Validate(); err != nil {
+		return decimal.Zero, err
+	}
+	if s.Qty > 0 {
+		return decimal.NewFromFloat(s.Qty).Round(6), nil
+	}
+	if !price.IsPositive() {
+		return decimal.Zero, fmt.Errorf("%w: no price to convert a dollar size", ErrTradeSize)
+	}
+	value := decimal.NewFromFloat(s.Notional)
+	if s.PercentOfEquity > 0 {
+		if !equity.IsPositive() {
+			return decimal.Zero, fmt.Errorf("%w: account equity unknown", ErrTradeSize)
+		}
+		value = equity.Mul(decimal.NewFromFloat(s.PercentOfEquity)).Div(hundred)
+	}
+	shares := WholeShares(value, price)
+	if shares.LessThan(decimal.NewFromInt(1)) {
+		return decimal.Zero, fmt.Errorf("%w: $%s buys no whole shares at $%s", ErrTradeSize, value.StringFixed(2), price.StringFixed(2))
+	}
+	return shares, nil
+}
+
+// CheckLimit refuses an explicitly sized opening trade of shares at price
+// worth more than maxPercent of equity, the cap risk sizing stays under. A
+// non-positive maxPercent switches the check off.
+func CheckLimit(shares, price, equity, maxPercent decimal.Decimal) error {
+	if !maxPercent.IsPositive() {
+		return nil
+	}
+	if !equity.IsPositive() {
+		return fmt.Errorf("%w: account equity unknown, cannot check max_position_size_percent", ErrTradeSize)
+	}
+	value := shares.Mul(price)
+	if limit := equity.Mul(maxPercent).Div(hundred); value.GreaterThan(limit) {
+		return fmt.Errorf("%w: $%s is %s%% of equity, above max_position_size_percent %s%%", ErrTradeSize,
+			value.StringFixed(2), value.Div(equity).Mul(hundred).StringFixed(2), maxPercent.StringFixed(2))
+	}
+	return nil
+}
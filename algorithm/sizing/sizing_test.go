@@ -0,0 +1,64 @@
+package sizing
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func d(v float64) decimal.Decimal { return decimal.NewFromFloat(v) }
+
+func TestRisk(t *testing.T) {
+	req := Request{Equity: d(100000), Price: d(30), MaxPercent: d(5)}
+	// $5,000 at $30 is 166.67 shares, rounded down
+	if res := Risk(req); !res.BaseValue.Equal(d(5000)) || !res.Shares.Equal(d(166)) || res.Capped {
+		t.Fatalf("plain = %+v", res)
+	}
+
+	req.Factors = []Factor{{Name: "regime", Value: d(1.2)}, {Name: "liquidity", Value: d(0.5)}}
+	if res := Risk(req); !res.Value.Equal(d(3000)) || !res.Shares.Equal(d(100)) {
+		t.Fatalf("scaled = %+v", res)
+	}
+
+	req.MaxValue = d(1500)
+	if res := Risk(req); !res.Value.Equal(d(1500)) || !res.Shares.Equal(d(50)) || !res.Capped {
+		t.Fatalf("capped = %+v", res)
+	}
+
+	req.Price = decimal.Zero
+	if res := Risk(req); !res.Shares.IsZero() {
+		t.Fatalf("no price sized %s shares", res.Shares)
+	}
+}
+
+func TestATRFactor(t *testing.T) {
+	// Risking 1% of $100k on a $2 ATR is 500 shares, $25,000 at $50: half
+	// of a 50% cap, but above a 5% one
+	req := Request{Equity: d(100000), Price: d(50), MaxPercent: d(50)}
+	req.Factors = []Factor{{Name: "atr", Value: ATRFactor(req.Price, d(2), d(1), req.MaxPercent)}}
+	if res := Risk(req); !res.Shares.Equal(d(500)) {
+		t.Fatalf("atr sized = %+v", res)
+	}
+	if f := ATRFactor(d(50), d(2), d(1), d(5)); !f.Equal(d(1)) {
+		t.Errorf("factor above the cap = %s", f)
+	}
+	if f := ATRFactor(d(50), decimal.Zero, d(1), d(5)); !f.Equal(d(1)) {
+		t.Errorf("no ATR factor = %s", f)
+	}
+}
+
+func TestCheckLimit(t *testing.T) {
+	if err := CheckLimit(d(40), d(100), d(100000), d(5)); err != nil {
+		t.Fatalf("$4,000 of $100,000 refused: %v", err)
+	}
+	if err := CheckLimit(d(60), d(100), d(100000), d(5)); !errors.Is(err, ErrTradeSize) {
+		t.Fatalf("$6,000 of $100,000 err = %v", err)
+	}
+	if err := CheckLimit(d(60), d(100), decimal.Zero, d(5)); !errors.Is(err, ErrTradeSize) {
+		t.Fatalf("unknown equity err = %v", err)
+	}
+	if err := CheckLimit(d(60), d(100), d(100000), decimal.Zero); err != nil {
+		t.Fatalf("disabled limit refused: %v", err)
+	}
+}
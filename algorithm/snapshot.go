@@ -0,0 +1,46 @@
+package algorithm
+
+import "time"
+
+// Snapshot is the algorithm's signals, market data and portfolio as of
+// one generation, read under a single lock so they agree with each other.
+type Snapshot struct {
+	// Generation increases with every change to signals, market data or
+	// the portfolio; an equal generation means nothing changed between
+	// two reads
+	Generation uint64                  `json:"generation"`
+	TakenAt    time.Time               `json:"taken_at"`
+	Signals    map[string]*TradeSignal `json:"signals"`
+	MarketData map[string]MarketData   `json:"market_data"`
+	Portfolio  PortfolioData           `json:"portfolio"`
+}
+
+// Snapshot returns copies of the signals, market data and portfolio taken
+// together with their generation.
+func (a *TradingAlgorithm) Snapshot() Snapshot {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	s := Snapshot{
+		Generation: a.generation,
+		TakenAt:    a.now(),
+		Signals:    make(map[string]*TradeSignal, len(a.signals)),
+		MarketData: make(map[string]MarketData, len(a.marketData)),
+		Portfolio:  a.portfolioSnapshotLocked(),
+	}
+	for k, v := range a.signals {
+		s.Signals[k] = v
+	}
+	for k, v := range a.marketData {
+		s.MarketData[k] = v
+	}
+	return s
+}
+
+// Generation returns the current generation of signals, market data and
+// portfolio.
+func (a *TradingAlgorithm) Generation() uint64 {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.generation
+}
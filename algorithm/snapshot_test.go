@@ -0,0 +1,75 @@
+package algorithm
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestSnapshotGenerations(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	a.tradingEnabled = true
+	a.portfolio = PortfolioData{
+		Balance:   1000,
+		Positions: map[string]PositionData{"AAPL": {Symbol: "AAPL", Quantity: 10, AvgPrice: 100}},
+	}
+
+	var published []uint64
+	a.SetPortfolioHandler(func(_ PortfolioData, generation uint64) { published = append(published, generation) })
+
+	start := a.Snapshot().Generation
+	a.UpdateMarketData("AAPL", 110, 0, 0, 0, 0)
+	if err := a.ProcessSymbol("AAPL"); err != nil {
+		t.Fatal(err)
+	}
+
+	s := a.Snapshot()
+	if s.Generation != start+2 || a.Generation() != s.Generation {
+		t.Errorf("generation = %d after two changes from %d", s.Generation, start)
+	}
+	if len(published) != 1 || published[0] != start+1 {
+		t.Errorf("published generations %v, want [%d]", published, start+1)
+	}
+	if s.Signals["AAPL"] == nil || s.MarketData["AAPL"].Price != 110 || s.Portfolio.Positions["AAPL"].CurrentPrice != 110 {
+		t.Errorf("snapshot = %+v", s)
+	}
+	if again := a.Snapshot(); again.Generation != s.Generation {
+		t.Errorf("generation moved from %d to %d without a change", s.Generation, again.Generation)
+	}
+
+	// Snapshots are copies the caller may keep while updates continue
+	s.MarketData["AAPL"] = MarketData{}
+	if a.GetMarketData("AAPL").Price != 110 {
+		t.Error("snapshot shares market data with the algorithm")
+	}
+}
+
+func TestSnapshotIsConsistentUnderUpdates(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	a.portfolio = PortfolioData{
+		Positions: map[string]PositionData{"AAPL": {Symbol: "AAPL", Quantity: 1}},
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 500; i++ {
+			a.UpdateMarketData("AAPL", float64(i), 0, 0, 0, 0)
+		}
+	}()
+
+	var last uint64
+	for i := 0; i < 500; i++ {
+		s := a.Snapshot()
+		if s.Generation < last {
+			t.Fatalf("generation went back from %d to %d", last, s.Generation)
+		}
+		last = s.Generation
+		if s.MarketData["AAPL"].Price != s.Portfolio.Positions["AAPL"].CurrentPrice {
+			t.Fatalf("market price %v and position mark %v disagree at generation %d",
+				s.MarketData["AAPL"].Price, s.Portfolio.Positions["AAPL"].CurrentPrice, s.Generation)
+		}
+	}
+	wg.Wait()
+}
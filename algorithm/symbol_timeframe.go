@@ -0,0 +1,172 @@
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/rileyseaburg/go-trader/algorithm/algo"
+)
+
+// timeFrameOrder lists the supported timeframes from finest to coarsest.
+var timeFrameOrder = []string{"1Min", "5Min", "15Min", "1H", "1D"}
+
+// TimeFrameTier gives symbols trading at least MinDollarVolume a day the
+// signal timeframe TimeFrame.
+type TimeFrameTier struct {
+	MinDollarVolume float64 `json:"min_dollar_volume"`
+	TimeFrame       string  `json:"timeframe"`
+}
+
+// TimeFramePolicy chooses each symbol's signal timeframe from its
+// baseline: the first tier its 20-day average dollar volume reaches, one
+// step coarser when it is more volatile than NoisyVolatility, and daily
+// bars below every tier. Deep books like SPY trade on minute bars, where
+// thin small caps would be all spread and gaps.
+type TimeFramePolicy struct {
+	// Enabled chooses timeframes; disabled, every symbol gets the default
+	Enabled bool `json:"enabled"`
+	// Tiers run from the most liquid down, in decreasing MinDollarVolume
+	Tiers []TimeFrameTier `json:"tiers"`
+	// NoisyVolatility is the annualized volatility, in percent, above which
+	// a symbol gets the next coarser timeframe; 0 disables
+	NoisyVolatility float64 `json:"noisy_volatility"`
+}
+
+// DefaultTimeFramePolicy gives 1-minute bars from $1B a day, 5-minute from
+// $200M, 15-minute from $20M and hourly from $2M, one step coarser above
+// 80% annualized volatility.
+func DefaultTimeFramePolicy() TimeFramePolicy {
+	return TimeFramePolicy{
+		Enabled: true,
+		Tiers: []TimeFrameTier{
+			{MinDollarVolume: 1e9, TimeFrame: "1Min"},
+			{MinDollarVolume: 2e8, TimeFrame: "5Min"},
+			{MinDollarVolume: 2e7, TimeFrame: "15Min"},
+			{MinDollarVolume: 2e6, TimeFrame: "1H"},
+		},
+		NoisyVolatility: 80,
+	}
+}
+
+// Validate checks the policy for usable values.
+func (p TimeFramePolicy) Validate() error {
+	for i, t := range p.Tiers {
+		if !ValidTimeFrame(t.TimeFrame) {
+			return fmt.Errorf("tier %d: unsupported timeframe %q", i, t.TimeFrame)
+		}
+		if t.MinDollarVolume <= 0 {
+			return fmt.Errorf("tier %d: min_dollar_volume must be positive", i)
+		}
+		if i > 0 && t.MinDollarVolume >= p.Tiers[i-1].MinDollarVolume {
+			return errors.New("tiers must be in decreasing min_dollar_volume")
+		}
+	}
+	if p.NoisyVolatility < 0 {
+		return errors.New("noisy_volatility must not be negative")
+	}
+	return nil
+}
+
+// Choose returns the timeframe for a symbol with baseline b.
+func (p TimeFramePolicy) Choose(b SymbolBaseline) string {
+	if !p.Enabled {
+		return algo.DefaultTimeFrame
+	}
+	tf := algo.DefaultTimeFrame
+	for _, t := range p.Tiers {
+		if b.DollarVolume20 >= t.MinDollarVolume {
+			tf = t.TimeFrame
+			break
+		}
+	}
+	if p.NoisyVolatility > 0 && b.AnnualVolatility > p.NoisyVolatility {
+		tf = coarserTimeFrame(tf)
+	}
+	return tf
+}
+
+// coarserTimeFrame returns the timeframe one step coarser than tf, or tf
+// when it is already the coarsest.
+func coarserTimeFrame(tf string) string {
+	for i, name := range timeFrameOrder[:len(timeFrameOrder)-1] {
+		if name == tf {
+			return timeFrameOrder[i+1]
+		}
+	}
+	return tf
+}
+
+// SetTimeFramePolicy replaces the policy symbols' timeframes are chosen
+// by and rechooses them for the baselines already computed.
+func (a *TradingAlgorithm) SetTimeFramePolicy(p TimeFramePolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.timeFramePolicy = p
+	for sym, b := range a.baselines {
+		b.TimeFrame = p.Choose(b)
+		a.baselines[sym] = b
+	}
+	return nil
+}
+
+// TimeFramePolicy returns the policy symbols' timeframes are chosen by.
+func (a *TradingAlgorithm) TimeFramePolicy() TimeFramePolicy {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.timeFramePolicy
+}
+
+// SymbolTimeFrame returns the signal timeframe chosen for symbol at its
+// last baseline, or the default before one is computed.
+func (a *TradingAlgorithm) SymbolTimeFrame(symbol string) string {
+	if b, ok := a.GetBaseline(symbol); ok && b.TimeFrame != "" {
+		return b.TimeFrame
+	}
+	return algo.DefaultTimeFrame
+}
+
+// PreloadSymbolTimeFrames caches history at each symbol's chosen
+// timeframe, as many bars as the daily requirement, for the instances that
+// will run at it. It returns the choices and per-symbol failures.
+func (a *TradingAlgorithm) PreloadSymbolTimeFrames(symbols []string) (map[string]string, map[string]error) {
+	bars := a.RequiredHistory()[warmStartTimeFrame]
+	chosen := make(map[string]string, len(symbols))
+	failures := make(map[string]error)
+	for _, sym := range symbols {
+		tf := a.SymbolTimeFrame(sym)
+		chosen[sym] = tf
+		if tf == warmStartTimeFrame {
+			continue
+		}
+		if err := a.PreloadHistory([]string{sym}, tf, bars)[sym]; err != nil {
+			failures[sym] = err
+		}
+	}
+	return chosen, failures
+}
+
+// SymbolTimeFrameChoice is a symbol's chosen timeframe with the profile it
+// was chosen from.
+type SymbolTimeFrameChoice struct {
+	Symbol           string  `json:"symbol"`
+	TimeFrame        string  `json:"timeframe"`
+	DollarVolume20   float64 `json:"dollar_volume_20"`
+	AnnualVolatility float64 `json:"annual_volatility"`
+}
+
+// SymbolTimeFrames returns the timeframe chosen for every symbol with a
+// baseline, ordered by symbol.
+func (a *TradingAlgorithm) SymbolTimeFrames() []SymbolTimeFrameChoice {
+	baselines := a.GetBaselines()
+	out := make([]SymbolTimeFrameChoice, 0, len(baselines))
+	for _, b := range baselines {
+		out = append(out, SymbolTimeFrameChoice{Symbol: b.Symbol, TimeFrame: b.TimeFrame,
+			DollarVolume20: b.DollarVolume20, AnnualVolatility: b.AnnualVolatility})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Symbol < out[j].Symbol })
+	return out
+}
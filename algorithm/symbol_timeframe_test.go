@@ -0,0 +1,94 @@
+package algorithm
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+func TestTimeFramePolicyChooses(t *testing.T) {
+	p := DefaultTimeFramePolicy()
+	cases := []struct {
+		dollars, vol float64
+		want         string
+	}{
+		{3e10, 15, "1Min"}, // SPY
+		{5e8, 30, "5Min"},
+		{5e7, 40, "15Min"},
+		{5e7, 120, "1H"}, // a volatile small cap steps coarser
+		{5e6, 50, "1H"},
+		{5e5, 50, "1D"},
+		{5e5, 150, "1D"},
+	}
+	for _, c := range cases {
+		if got := p.Choose(SymbolBaseline{DollarVolume20: c.dollars, AnnualVolatility: c.vol}); got != c.want {
+			t.Errorf("$%.0f at %.0f%% = %s, want %s", c.dollars, c.vol, got, c.want)
+		}
+	}
+	p.Enabled = false
+	if got := p.Choose(SymbolBaseline{DollarVolume20: 3e10}); got != "1D" {
+		t.Errorf("disabled = %s", got)
+	}
+	if err := (TimeFramePolicy{Tiers: []TimeFrameTier{{2e6, "1H"}, {1e9, "1Min"}}}).Validate(); err == nil {
+		t.Error("accepted tiers out of order")
+	}
+	if err := (TimeFramePolicy{Tiers: []TimeFrameTier{{1e9, "2Min"}}}).Validate(); err == nil {
+		t.Error("accepted an unsupported timeframe")
+	}
+}
+
+func TestInstancesRunAtSymbolTimeFrame(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	day0 := time.Date(2024, 1, 2, 0, 0, 0, 0, sessionZone)
+	series := func(symbol string, step time.Duration, volume int64) []BarData {
+		bars := make([]BarData, 60)
+		for i := range bars {
+			p := 100 + 5*math.Sin(float64(i)/4)
+			bars[i] = BarData{Symbol: symbol, Timestamp: day0.Add(time.Duration(i) * step), High: p + 1, Low: p - 1, Close: p, Volume: volume}
+		}
+		return bars
+	}
+	// $2B a day against $100k a day
+	a.cacheBars("SPY", warmStartTimeFrame, series("SPY", 24*time.Hour, 20_000_000))
+	a.cacheBars("SPY", "1Min", series("SPY", time.Minute, 50_000))
+	a.cacheBars("TINY", warmStartTimeFrame, series("TINY", 24*time.Hour, 1_000))
+	if failed := a.RecomputeBaselines([]string{"SPY", "TINY"}); len(failed) != 0 {
+		t.Fatal(failed)
+	}
+	if a.SymbolTimeFrame("SPY") != "1Min" || a.SymbolTimeFrame("TINY") != "1D" || a.SymbolTimeFrame("NEW") != "1D" {
+		t.Errorf("timeframes = %+v", a.SymbolTimeFrames())
+	}
+
+	r := NewInstanceRegistry(a)
+	if _, err := r.Put(InstanceSpec{ID: "cusum", Type: "cusum_filter"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Put(InstanceSpec{ID: "cusum-daily", Type: "cusum_filter", TimeFrame: "1D"}); err != nil {
+		t.Fatal(err)
+	}
+	current := &types.MarketData{Symbol: "SPY", Price: 100}
+	for _, c := range []struct{ id, symbol, want string }{
+		{"cusum", "SPY", "1Min"},
+		{"cusum", "TINY", "1D"},
+		{"cusum-daily", "SPY", "1D"}, // an explicit timeframe wins
+	} {
+		run, err := r.Run(c.id, c.symbol, current, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run.TimeFrame != c.want {
+			t.Errorf("%s on %s ran at %s, want %s", c.id, c.symbol, run.TimeFrame, c.want)
+		}
+	}
+
+	// A policy change rechooses the computed baselines
+	if err := a.SetTimeFramePolicy(TimeFramePolicy{}); err != nil {
+		t.Fatal(err)
+	}
+	if tf := a.SymbolTimeFrame("SPY"); tf != "1D" {
+		t.Errorf("disabled policy left SPY at %s", tf)
+	}
+}
@@ -22,11 +22,21 @@ func ParseTimeFrame(timeframe string) (marketdata.TimeFrame, error) {
 		return marketdata.OneDay, nil
 	default:
 		// Default to 1 day if not specified correctly
-		fmt.Printf("Unrecognized timeframe: %s, defaulting to 1D\n", timeframe)
+		logger().Warn("Unrecognized timeframe, defaulting to 1D", "timeframe", timeframe)
 		return marketdata.OneDay, nil
 	}
 }
 
+// ValidTimeFrame reports whether timeframe is one ParseTimeFrame recognizes
+// rather than defaulting.
+func ValidTimeFrame(timeframe string) bool {
+	switch timeframe {
+	case "1Min", "5Min", "15Min", "1H", "1D":
+		return true
+	}
+	return false
+}
+
 // GetTimeFrameName returns a human-readable name for the timeframe
 func GetTimeFrameName(tf marketdata.TimeFrame) string {
 	switch tf {
@@ -0,0 +1,58 @@
+package algorithm
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// TimeFrameHandler exposes each symbol's chosen signal timeframe over
+// HTTP.
+type TimeFrameHandler struct {
+	algorithm *TradingAlgorithm
+}
+
+// NewTimeFrameHandler creates a handler for a.
+func NewTimeFrameHandler(a *TradingAlgorithm) *TimeFrameHandler {
+	return &TimeFrameHandler{algorithm: a}
+}
+
+// RegisterRoutes registers the timeframe routes with mux.
+func (h *TimeFrameHandler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/symbols/timeframes - each baseline symbol's timeframe and profile
+	mux.HandleFunc("/api/symbols/timeframes", h.handleStatus)
+
+	// GET, PUT /api/symbols/timeframes/policy - liquidity tiers and the volatility step
+	mux.HandleFunc("/api/symbols/timeframes/policy", h.handlePolicy)
+}
+
+func (h *TimeFrameHandler) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"policy":  h.algorithm.TimeFramePolicy(),
+		"symbols": h.algorithm.SymbolTimeFrames(),
+	})
+}
+
+func (h *TimeFrameHandler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.algorithm.TimeFramePolicy())
+	case http.MethodPost, http.MethodPut:
+		// Start from the current policy so partial updates work
+		policy := h.algorithm.TimeFramePolicy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.algorithm.SetTimeFramePolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(policy)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
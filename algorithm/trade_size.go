@@ -0,0 +1,135 @@
+package algorithm
+
+import (
+	"fmt"
+
+	"github.com/rileyseaburg/go-trader/algorithm/algo/volatility"
+	"github.com/rileyseaburg/go-trader/algorithm/sizing"
+	"github.com/shopspring/decimal"
+)
+
+// ErrTradeSize is wrapped by refusals of a caller-chosen trade size.
+var ErrTradeSize = sizing.ErrTradeSize
+
+// Sizing methods, the values of the sizing_method risk parameter.
+const (
+	// SizingPercent sizes positions at max_position_size_percent of equity
+	SizingPercent = "percent"
+	// SizingATR sizes positions so a one-ATR adverse move loses
+	// atr_risk_percent of equity, within max_position_size_percent
+	SizingATR = "atr"
+)
+
+// TradeSize is an explicit size for a trade, set by the user instead of the
+// default risk-based sizing. Exactly one field is set.
+type TradeSize = sizing.TradeSize
+
+// RiskSize sizes a new position in symbol at price for an account of
+// equity from the risk parameters: max_position_size_percent of equity,
+// or with sizing_method atr the smaller position losing atr_risk_percent
+// of equity on a one-ATR move, scaled by the macro regime, the volatility
+// target, the implied move and the liquidity score, then capped at
+// max_adv_percent of dollar ADV. It is the default sizing for every
+// opening order.
+func (a *TradingAlgorithm) RiskSize(symbol string, price, equity decimal.Decimal) sizing.Result {
+	a.mu.RLock()
+	riskParams := a.sizingParamsLocked()
+	a.mu.RUnlock()
+	return a.riskSize(symbol, price, equity, riskParams)
+}
+
+func (a *TradingAlgorithm) riskSize(symbol string, price, equity decimal.Decimal, riskParams map[string]interface{}) sizing.Result {
+	a.mu.RLock()
+	regime := a.regimeMultiplier
+	volScale := a.volTargetStateLocked().Scale
+	a.mu.RUnlock()
+	if regime <= 0 {
+		regime = 1.0
+	}
+
+	maxPct := riskParamFloat(riskParams, "max_position_size_percent", 5.0)
+	req := sizing.Request{
+		Equity:     equity,
+		Price:      price,
+		MaxPercent: decimal.NewFromFloat(maxPct),
+		Factors: []sizing.Factor{
+			// The economic-cartography reading: storm waters shrink risk,
+			// following seas expand it
+			{Name: "regime", Value: decimal.NewFromFloat(regime)},
+			// Neutral unless target_annual_volatility is set and returns
+			// are cached for holdings
+			{Name: "volatility_target", Value: decimal.NewFromFloat(volScale)},
+		},
+	}
+	if method, _ := riskParams["sizing_method"].(string); method == SizingATR {
+		period := int(riskParamFloat(riskParams, "atr_period", 14))
+		if atr, err := a.symbolATR(symbol, period); err != nil {
+			logger().Debug("ATR unavailable, sizing by percent of equity", "symbol", symbol, "error", err)
+		} else {
+			risk := decimal.NewFromFloat(riskParamFloat(riskParams, "atr_risk_percent", 1.0))
+			req.Factors = append(req.Factors, sizing.Factor{Name: "atr", Value: sizing.ATRFactor(price, decimal.NewFromFloat(atr), risk, req.MaxPercent)})
+		}
+	}
+	if scale := a.eventScale(symbol, maxPct, riskParams); scale < 1 {
+		req.Factors = append(req.Factors, sizing.Factor{Name: "implied_move", Value: decimal.NewFromFloat(scale)})
+	}
+	if l := a.liquidity(symbol, riskParams); l != nil {
+		if l.Score < 1 {
+			req.Factors = append(req.Factors, sizing.Factor{Name: "liquidity", Value: decimal.NewFromFloat(l.Score)})
+		}
+		req.MaxValue = decimal.NewFromFloat(l.MaxValue)
+	}
+
+	res := sizing.Risk(req)
+	if res.Capped {
+		logger().Info("Position capped by average daily volume", "symbol", symbol, "value", res.Value, "shares", res.Shares)
+	}
+	return res
+}
+
+// symbolATR is the average true range of symbol's last period cached
+// daily bars.
+func (a *TradingAlgorithm) symbolATR(symbol string, period int) (float64, error) {
+	bars, _ := a.CachedBars(symbol, warmStartTimeFrame)
+	ohlc := make([]volatility.Bar, len(bars))
+	for i, b := range bars {
+		ohlc[i] = volatility.Bar{Open: b.Open, High: b.High, Low: b.Low, Close: b.Close}
+	}
+	return volatility.ATR(ohlc, period)
+}
+
+// SizeTrade returns the shares for an explicitly sized signal at price,
+// checked against the risk parameters. held is the quantity of the position
+// the trade reduces, zero for an opening trade. Opening trades may not
+// exceed max_position_size_percent of equity or the symbol's liquidity
+// limit; reducing trades may not exceed held.
+func (a *TradingAlgorithm) SizeTrade(signal *TradeSignal, price, equity, held decimal.Decimal) (decimal.Decimal, error) {
+	a.mu.RLock()
+	riskParams := a.sizingParamsLocked()
+	a.mu.RUnlock()
+	return a.sizeTrade(signal, price, equity, held, riskParams)
+}
+
+func (a *TradingAlgorithm) sizeTrade(signal *TradeSignal, price, equity, held decimal.Decimal, riskParams map[string]interface{}) (decimal.Decimal, error) {
+	if signal.Size == nil {
+		return decimal.Zero, fmt.Errorf("%w: signal has no size", ErrTradeSize)
+	}
+	shares, err := signal.Size.Shares(price, equity)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if held.IsPositive() {
+		if shares.GreaterThan(held) {
+			return decimal.Zero, fmt.Errorf("%w: %s shares is more than the %s held", ErrTradeSize, shares, held)
+		}
+		return shares, nil
+	}
+	maxPct := decimal.NewFromFloat(riskParamFloat(riskParams, "max_position_size_percent", 5.0))
+	if err := sizing.CheckLimit(shares, price, equity, maxPct); err != nil {
+		return decimal.Zero, err
+	}
+	if err := a.checkLiquidityLimit(signal.Symbol, shares, price, equity, riskParams); err != nil {
+		return decimal.Zero, err
+	}
+	return shares, nil
+}
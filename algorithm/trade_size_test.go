@@ -0,0 +1,119 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestTradeSizeShares(t *testing.T) {
+	cases := []struct {
+		size   TradeSize
+		shares float64
+		ok     bool
+	}{
+		{TradeSize{Qty: 2.5}, 2.5, true},
+		{TradeSize{Notional: 1050}, 10, true},
+		{TradeSize{PercentOfEquity: 2}, 20, true}, // $2,000 of $100,000
+		{TradeSize{Notional: 50}, 0, false},       // less than one share
+		{TradeSize{}, 0, false},
+		{TradeSize{Qty: 1, Notional: 100}, 0, false},
+		{TradeSize{Qty: -1}, 0, false},
+		{TradeSize{PercentOfEquity: 150}, 0, false},
+	}
+	for _, c := range cases {
+		shares, err := c.size.Shares(decimal.NewFromInt(100), decimal.NewFromInt(100000))
+		if (err == nil) != c.ok || !shares.Equal(decimal.NewFromFloat(c.shares)) {
+			t.Errorf("%+v: shares = %v, err = %v", c.size, shares, err)
+		}
+		if err != nil && !errors.Is(err, ErrTradeSize) {
+			t.Errorf("%+v: err %v does not wrap ErrTradeSize", c.size, err)
+		}
+	}
+}
+
+func TestATRSizingMethod(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	// $50 closes trading $2 a day on $5B of volume, liquid enough to score 1
+	bars := liquidityBars("XYZ", 50, 100_000_000)
+	for i := range bars {
+		bars[i].High, bars[i].Low = 51, 49
+	}
+	a.cacheBars("XYZ", warmStartTimeFrame, bars)
+	price, equity := decimal.NewFromInt(50), decimal.NewFromInt(100000)
+
+	if err := a.UpdateRiskParameters(map[string]interface{}{"sizing_method": "atr", "max_position_size_percent": 50.0}); err != nil {
+		t.Fatal(err)
+	}
+	// 1% of $100k over a $2 ATR is 500 shares, half the 50% cap
+	if res := a.RiskSize("XYZ", price, equity); res.Shares.String() != "500" {
+		t.Errorf("atr sized = %+v", res)
+	}
+	// The percent cap still binds a quiet symbol
+	a.UpdateRiskParameters(map[string]interface{}{"max_position_size_percent": 5.0})
+	if res := a.RiskSize("XYZ", price, equity); res.Shares.String() != "100" {
+		t.Errorf("capped atr size = %+v", res)
+	}
+	// Without bars for an ATR it falls back to percent sizing
+	if res := a.RiskSize("NEW", price, equity); res.Shares.String() != "100" {
+		t.Errorf("fallback size = %+v", res)
+	}
+
+	for _, bad := range []map[string]interface{}{{"sizing_method": "kelly"}, {"atr_risk_percent": 0.0}, {"atr_period": 2.5}} {
+		if err := a.UpdateRiskParameters(bad); err == nil {
+			t.Errorf("accepted %v", bad)
+		}
+	}
+}
+
+func TestExplicitSizeRespectsRiskLimits(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	a.marketData["AAPL"] = MarketData{Symbol: "AAPL", Price: 100}
+	a.portfolio.TotalValue = 100000
+	a.portfolio.Positions["MSFT"] = PositionData{Symbol: "MSFT", Quantity: 10}
+	a.marketData["MSFT"] = MarketData{Symbol: "MSFT", Price: 400}
+
+	// 40 shares is $4,000, under the default 5% cap
+	preview, err := a.ExecuteTrade(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy, OrderType: "market", Size: &TradeSize{Qty: 40}}, true)
+	if err != nil || preview.Request.Qty.String() != "40" {
+		t.Fatalf("sized buy = %+v, %v", preview, err)
+	}
+
+	// 10% of equity breaches it
+	_, err = a.ExecuteTrade(&TradeSignal{Symbol: "AAPL", Signal: SignalBuy, OrderType: "market", Size: &TradeSize{PercentOfEquity: 10}}, true)
+	if !errors.Is(err, ErrTradeSize) {
+		t.Fatalf("oversized buy err = %v", err)
+	}
+
+	// A partial sell is sized; selling more than is held is refused
+	preview, err = a.ExecuteTrade(&TradeSignal{Symbol: "MSFT", Signal: SignalSell, OrderType: "market", Size: &TradeSize{Qty: 4}}, true)
+	if err != nil || preview.Request.Qty.String() != "4" {
+		t.Fatalf("partial sell = %+v, %v", preview, err)
+	}
+	if _, err := a.ExecuteTrade(&TradeSignal{Symbol: "MSFT", Signal: SignalSell, OrderType: "market", Size: &TradeSize{Qty: 11}}, true); !errors.Is(err, ErrTradeSize) {
+		t.Fatalf("oversold err = %v", err)
+	}
+}
+
+func TestCheckOrder(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	a.portfolio = PortfolioData{TotalValue: 100000, Positions: map[string]PositionData{"AAPL": {Symbol: "AAPL", Quantity: 20}}}
+	a.UpdateMarketData("MSFT", 100, 101, 99, 1000, 0)
+
+	// 5% of $100k is 50 shares at $100
+	if err := a.CheckOrder("msft", "buy", 40, 0, ""); err != nil {
+		t.Errorf("within the limit: %v", err)
+	}
+	if err := a.CheckOrder("MSFT", "buy", 60, 0, ""); !errors.Is(err, ErrTradeSize) {
+		t.Errorf("over the limit: %v", err)
+	}
+	if err := a.CheckOrder("AAPL", "sell", 30, 100, ""); !errors.Is(err, ErrTradeSize) {
+		t.Errorf("sold more than held: %v", err)
+	}
+	a.AddTradeGuard("halt", func(*TradeSignal) error { return errors.New("halted") })
+	if err := a.CheckOrder("MSFT", "buy", 1, 0, ""); err == nil {
+		t.Error("guard not consulted")
+	}
+}
@@ -0,0 +1,152 @@
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm/algo/volatility"
+)
+
+const (
+	// garchLookback is the most daily returns a GARCH model is fitted to,
+	// about two years of sessions.
+	garchLookback = 504
+	// DefaultForecastHorizon is the sessions a volatility forecast covers
+	// when no horizon is asked for.
+	DefaultForecastHorizon = 5
+	// maxForecastHorizon bounds the forecast path at a year of sessions.
+	maxForecastHorizon = tradingDaysPerYear
+)
+
+// VolatilityForecast is a GARCH(1,1) forecast of a symbol's daily
+// volatility. Volatilities are daily percentages of price unless named
+// annualized.
+type VolatilityForecast struct {
+	Symbol string           `json:"symbol"`
+	Model  volatility.GARCH `json:"model"`
+	// OneStep is the next session's forecast volatility
+	OneStep           float64 `json:"one_step"`
+	AnnualizedOneStep float64 `json:"annualized_one_step"`
+	Horizon           int     `json:"horizon"`
+	// Path is the forecast for each of the next Horizon sessions
+	Path []float64 `json:"path"`
+	// HorizonVolatility is the volatility of the return over all Horizon
+	// sessions together
+	HorizonVolatility float64 `json:"horizon_volatility"`
+	LongRunVolatility float64 `json:"long_run_volatility"`
+	// HalfLifeSessions is how long a volatility shock takes to decay by half
+	HalfLifeSessions float64 `json:"half_life_sessions"`
+	// RealizedVolatility is the trailing close-to-close volatility over the
+	// last quarter of sessions, for comparison
+	RealizedVolatility float64 `json:"realized_volatility"`
+	// AsOf is the last daily bar fitted
+	AsOf time.Time `json:"as_of"`
+}
+
+// garchFit is a GARCH model fitted to a symbol's cached daily bars, kept
+// until the series changes.
+type garchFit struct {
+	model  volatility.GARCH
+	closes []float64
+	asOf   time.Time
+	bars   int
+}
+
+// ForecastVolatility fits a GARCH(1,1) model to symbol's cached daily
+// returns and forecasts its volatility over the next horizon sessions.
+// Daily history is fetched first when too little is cached to fit.
+func (a *TradingAlgorithm) ForecastVolatility(symbol string, horizon int) (*VolatilityForecast, error) {
+	if horizon < 1 || horizon > maxForecastHorizon {
+		return nil, fmt.Errorf("horizon must be between 1 and %d sessions", maxForecastHorizon)
+	}
+	bars, _ := a.CachedBars(symbol, warmStartTimeFrame)
+	if len(bars) <= volatility.MinGARCHReturns {
+		end := a.now()
+		start := end.AddDate(0, 0, -garchLookback*7/5-5)
+		if _, err := a.GetBarHistory(HistoryRequest{Symbol: symbol, StartDate: start, EndDate: end, TimeFrame: warmStartTimeFrame}); err != nil {
+			return nil, err
+		}
+		bars, _ = a.CachedBars(symbol, warmStartTimeFrame)
+	}
+	fit, err := a.garchModel(symbol, bars)
+	if err != nil {
+		return nil, err
+	}
+
+	g := fit.model
+	forecast := &VolatilityForecast{
+		Symbol:            symbol,
+		Model:             g,
+		OneStep:           math.Sqrt(g.NextVariance) * 100,
+		AnnualizedOneStep: math.Sqrt(g.NextVariance*tradingDaysPerYear) * 100,
+		Horizon:           horizon,
+		Path:              make([]float64, horizon),
+		LongRunVolatility: math.Sqrt(g.LongRunVariance()) * 100,
+		AsOf:              fit.asOf,
+	}
+	total := 0.0
+	for i, v := range g.Forecast(horizon) {
+		forecast.Path[i] = math.Sqrt(v) * 100
+		total += v
+	}
+	forecast.HorizonVolatility = math.Sqrt(total) * 100
+	if p := g.Persistence(); p > 0 {
+		forecast.HalfLifeSessions = math.Log(0.5) / math.Log(p)
+	}
+	recent := fit.closes[max(len(fit.closes)-volTargetLookback-1, 0):]
+	if vol, err := volatility.StdDev(recent); err == nil {
+		forecast.RealizedVolatility = vol * 100
+	}
+	return forecast, nil
+}
+
+// ForecastedVolatility returns symbol's GARCH forecast of the next
+// session's volatility, as a fraction of price, from the daily bars
+// already cached. It never fetches.
+func (a *TradingAlgorithm) ForecastedVolatility(symbol string) (float64, bool) {
+	bars, _ := a.CachedBars(symbol, warmStartTimeFrame)
+	fit, err := a.garchModel(symbol, bars)
+	if err != nil {
+		return 0, false
+	}
+	return math.Sqrt(fit.model.NextVariance), true
+}
+
+// garchModel returns the GARCH fit to bars, symbol's cached daily series,
+// fitting it again only when the series has changed since the last fit.
+// It does not take mu, so callers may hold it.
+func (a *TradingAlgorithm) garchModel(symbol string, bars []BarData) (garchFit, error) {
+	if len(bars) == 0 {
+		return garchFit{}, errors.New("no daily bars cached for " + symbol)
+	}
+	asOf, n := bars[len(bars)-1].Timestamp, len(bars)
+	a.garchMu.Lock()
+	fit, ok := a.garchFits[symbol]
+	a.garchMu.Unlock()
+	if ok && fit.bars == n && fit.asOf.Equal(asOf) {
+		return fit, nil
+	}
+
+	if len(bars) > garchLookback+1 {
+		bars = bars[len(bars)-garchLookback-1:]
+	}
+	closes := make([]float64, len(bars))
+	for i, b := range bars {
+		closes[i] = b.Close
+	}
+	model, err := volatility.FitGARCH(volatility.LogReturns(closes))
+	if err != nil {
+		return garchFit{}, fmt.Errorf("%s: %w", symbol, err)
+	}
+	fit = garchFit{model: model, closes: closes, asOf: asOf, bars: n}
+
+	a.garchMu.Lock()
+	defer a.garchMu.Unlock()
+	if a.garchFits == nil {
+		a.garchFits = make(map[string]garchFit)
+	}
+	a.garchFits[symbol] = fit
+	return fit, nil
+}
@@ -0,0 +1,87 @@
+package algorithm
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+)
+
+// turbulentBars are n daily bars moving 1% a day, then 4% a day for the
+// last ten.
+func turbulentBars(symbol string, n int) []BarData {
+	rng := rand.New(rand.NewSource(7))
+	start := time.Date(2024, 1, 2, 21, 0, 0, 0, time.UTC)
+	bars := make([]BarData, n)
+	price := 100.0
+	for i := range bars {
+		sigma := 0.01
+		if i >= n-10 {
+			sigma = 0.04
+		}
+		price *= math.Exp(sigma * rng.NormFloat64())
+		bars[i] = BarData{Symbol: symbol, Timestamp: start.AddDate(0, 0, i), Open: price, High: price, Low: price, Close: price}
+	}
+	return bars
+}
+
+func TestForecastVolatility(t *testing.T) {
+	a := capTestAlgorithm()
+	a.cacheBars("AAPL", warmStartTimeFrame, turbulentBars("AAPL", 300))
+
+	f, err := a.ForecastVolatility("AAPL", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The recent turbulence lifts the forecast above both the long run
+	// and the trailing quarter, and it decays from there
+	if f.OneStep <= f.RealizedVolatility || f.OneStep <= f.LongRunVolatility {
+		t.Errorf("one step %v, realized %v, long run %v", f.OneStep, f.RealizedVolatility, f.LongRunVolatility)
+	}
+	if len(f.Path) != 10 || math.Abs(f.Path[0]-f.OneStep) > 1e-9 || f.Path[9] >= f.Path[0] {
+		t.Errorf("path = %v", f.Path)
+	}
+	if f.HorizonVolatility <= f.OneStep || f.HorizonVolatility > f.OneStep*math.Sqrt(10) {
+		t.Errorf("horizon volatility = %v", f.HorizonVolatility)
+	}
+	if vol, ok := a.ForecastedVolatility("AAPL"); !ok || math.Abs(vol*100-f.OneStep) > 1e-9 {
+		t.Errorf("cached forecast = %v, %v", vol, ok)
+	}
+	if _, ok := a.ForecastedVolatility("MSFT"); ok {
+		t.Error("forecast without bars")
+	}
+	if _, err := a.ForecastVolatility("AAPL", 0); err == nil {
+		t.Error("zero horizon accepted")
+	}
+}
+
+func TestVolTargetUsesGARCHForecast(t *testing.T) {
+	a := capTestAlgorithm()
+	a.portfolio.TotalValue = 10000
+	a.portfolio.Positions["AAPL"] = PositionData{Symbol: "AAPL", Quantity: 100, MarketVal: 10000}
+	bars := turbulentBars("AAPL", 300)
+	a.cacheBars("AAPL", warmStartTimeFrame, bars)
+	closes := make([]float64, len(bars))
+	for i, b := range bars {
+		closes[i] = b.Close
+	}
+	a.recordDailyCloses("AAPL", marketdata.OneDay, closes)
+
+	realized := a.GetVolTargetState()
+	if err := a.UpdateRiskParameters(map[string]interface{}{"garch_volatility": true}); err != nil {
+		t.Fatal(err)
+	}
+	forecast := a.GetVolTargetState()
+	f, _ := a.ForecastVolatility("AAPL", 1)
+	if len(forecast.Forecasted) != 1 || math.Abs(forecast.CurrentVolatility-f.AnnualizedOneStep) > 1e-6 {
+		t.Errorf("forecast state = %+v, want %v", forecast, f.AnnualizedOneStep)
+	}
+	if forecast.CurrentVolatility <= realized.CurrentVolatility {
+		t.Errorf("forecast %v not above realized %v", forecast.CurrentVolatility, realized.CurrentVolatility)
+	}
+	if err := a.UpdateRiskParameters(map[string]interface{}{"garch_volatility": 1.0}); err == nil {
+		t.Error("numeric garch_volatility accepted")
+	}
+}
@@ -0,0 +1,348 @@
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+)
+
+const (
+	// tradingDaysPerYear annualizes daily return statistics.
+	tradingDaysPerYear = 252
+	// volTargetLookback is the number of daily closes kept per symbol for
+	// the covariance estimate — roughly one quarter of sessions.
+	volTargetLookback = 63
+	// volTargetMaxScale caps how far a quiet portfolio may scale new
+	// positions up. Scaling down is unbounded (to zero).
+	volTargetMaxScale = 1.5
+)
+
+// VolTrim is a suggested reduction of an existing position that brings
+// portfolio volatility back toward target.
+type VolTrim struct {
+	Symbol      string  `json:"symbol"`
+	CurrentQty  float64 `json:"current_qty"`
+	TrimQty     float64 `json:"trim_qty"`
+	TargetQty   float64 `json:"target_qty"`
+	MarketValue float64 `json:"market_value"`
+}
+
+// VolTargetState describes the portfolio-level volatility controller.
+// Volatilities are annualized percentages.
+type VolTargetState struct {
+	Enabled           bool               `json:"enabled"`
+	TargetVolatility  float64            `json:"target_volatility"`
+	CurrentVolatility float64            `json:"current_volatility"`
+	Scale             float64            `json:"scale"`
+	Weights           map[string]float64 `json:"weights"`
+	MissingReturns    []string           `json:"missing_returns,omitempty"`
+	// Forecasted lists the symbols whose volatility is their GARCH forecast
+	// rather than trailing realized volatility (garch_volatility)
+	Forecasted []string  `json:"forecasted,omitempty"`
+	Trims      []VolTrim `json:"trims,omitempty"`
+}
+
+// PortfolioVolatility returns the annualized volatility (as a fraction) of
+// a portfolio with the given signed weights, using the sample covariance of
+// daily returns. Return series are aligned on their most recent common
+// window; symbols without returns are ignored.
+func PortfolioVolatility(weights map[string]float64, returns map[string][]float64) (float64, error) {
+	symbols := make([]string, 0, len(weights))
+	window := 0
+	for sym, w := range weights {
+		r := returns[sym]
+		if w == 0 || len(r) < 2 {
+			continue
+		}
+		if window == 0 || len(r) < window {
+			window = len(r)
+		}
+		symbols = append(symbols, sym)
+	}
+	if len(symbols) == 0 {
+		return 0, errors.New("no positions with return history")
+	}
+	sort.Strings(symbols)
+
+	// Tail-align every series and de-mean it.
+	series := make([][]float64, len(symbols))
+	for i, sym := range symbols {
+		r := returns[sym]
+		tail := r[len(r)-window:]
+		mean := 0.0
+		for _, v := range tail {
+			mean += v
+		}
+		mean /= float64(window)
+		centered := make([]float64, window)
+		for t, v := range tail {
+			centered[t] = v - mean
+		}
+		series[i] = centered
+	}
+
+	variance := 0.0
+	for i := range symbols {
+		for j := range symbols {
+			cov := 0.0
+			for t := 0; t < window; t++ {
+				cov += series[i][t] * series[j][t]
+			}
+			cov /= float64(window - 1)
+			variance += weights[symbols[i]] * weights[symbols[j]] * cov
+		}
+	}
+	if variance < 0 {
+		variance = 0
+	}
+	return math.Sqrt(variance) * math.Sqrt(tradingDaysPerYear), nil
+}
+
+// volTargetScale converts current vs target volatility into the multiplier
+// applied to new position sizes. Without a target or a usable estimate the
+// controller is neutral.
+func volTargetScale(target, current float64) float64 {
+	if target <= 0 || current <= 0 {
+		return 1.0
+	}
+	return math.Min(volTargetMaxScale, target/current)
+}
+
+// recordDailyCloses stores log returns from a daily close series for the
+// volatility controller. Shorter timeframes are ignored.
+func (a *TradingAlgorithm) recordDailyCloses(symbol string, tf marketdata.TimeFrame, closes []float64) {
+	if tf != marketdata.OneDay || len(closes) < 2 {
+		return
+	}
+	returns := make([]float64, 0, len(closes)-1)
+	for i := 1; i < len(closes); i++ {
+		if closes[i-1] <= 0 || closes[i] <= 0 {
+			continue
+		}
+		returns = append(returns, math.Log(closes[i]/closes[i-1]))
+	}
+	if len(returns) > volTargetLookback {
+		returns = returns[len(returns)-volTargetLookback:]
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.dailyReturns[symbol] = returns
+}
+
+// RefreshVolatilityInputs fetches daily history for held symbols that have
+// no cached returns yet so the controller can see every position.
+func (a *TradingAlgorithm) RefreshVolatilityInputs() {
+	a.mu.RLock()
+	var missing []string
+	for sym := range a.portfolio.Positions {
+		if len(a.dailyReturns[sym]) < 2 {
+			missing = append(missing, sym)
+		}
+	}
+	a.mu.RUnlock()
+
+	end := a.now()
+	start := end.AddDate(0, 0, -volTargetLookback*7/5-5)
+	for _, sym := range missing {
+		if _, err := a.GetBarHistory(HistoryRequest{Symbol: sym, StartDate: start, EndDate: end, TimeFrame: "1D"}); err != nil {
+			logger().Warn("Volatility targeting: no daily history", "symbol", sym, "error", err)
+		}
+	}
+}
+
+// GetVolTargetState estimates current portfolio volatility against the
+// configured target and, when over target, the trims that would bring it
+// back.
+func (a *TradingAlgorithm) GetVolTargetState() VolTargetState {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.volTargetStateLocked()
+}
+
+func (a *TradingAlgorithm) volTargetStateLocked() VolTargetState {
+	target := riskParamFloat(a.riskParameters, "target_annual_volatility", 0)
+	state := VolTargetState{
+		Enabled:          target > 0,
+		TargetVolatility: target,
+		Scale:            1.0,
+		Weights:          make(map[string]float64),
+	}
+
+	equity := a.portfolio.TotalValue
+	if equity <= 0 {
+		return state
+	}
+	for sym, pos := range a.portfolio.Positions {
+		w := pos.MarketVal / equity
+		if pos.Quantity < 0 && w > 0 {
+			w = -w
+		}
+		state.Weights[sym] = w
+		if len(a.dailyReturns[sym]) < 2 {
+			state.MissingReturns = append(state.MissingReturns, sym)
+		}
+	}
+	sort.Strings(state.MissingReturns)
+
+	returns := a.dailyReturns
+	if garch, _ := a.riskParameters["garch_volatility"].(bool); garch {
+		returns, state.Forecasted = a.forecastScaledReturnsLocked(state.Weights)
+	}
+	vol, err := PortfolioVolatility(state.Weights, returns)
+	if err != nil {
+		return state
+	}
+	state.CurrentVolatility = vol * 100
+	if !state.Enabled {
+		return state
+	}
+
+	state.Scale = volTargetScale(target, state.CurrentVolatility)
+	if state.Scale < 1 {
+		for sym, pos := range a.portfolio.Positions {
+			trim := math.Floor(math.Abs(pos.Quantity) * (1 - state.Scale))
+			if trim <= 0 {
+				continue
+			}
+			state.Trims = append(state.Trims, VolTrim{
+				Symbol:      sym,
+				CurrentQty:  pos.Quantity,
+				TrimQty:     trim,
+				TargetQty:   math.Copysign(math.Abs(pos.Quantity)-trim, pos.Quantity),
+				MarketValue: pos.MarketVal,
+			})
+		}
+		sort.Slice(state.Trims, func(i, j int) bool { return state.Trims[i].Symbol < state.Trims[j].Symbol })
+	}
+	return state
+}
+
+// forecastScaledReturnsLocked returns the daily returns with each
+// weighted symbol's series rescaled so its volatility is the GARCH forecast
+// for the next session, keeping the correlations between them, and the
+// symbols rescaled. Symbols without enough cached daily bars to fit keep
+// their realized returns.
+func (a *TradingAlgorithm) forecastScaledReturnsLocked(weights map[string]float64) (map[string][]float64, []string) {
+	out := make(map[string][]float64, len(a.dailyReturns))
+	for sym, r := range a.dailyReturns {
+		out[sym] = r
+	}
+	var forecasted []string
+	for sym := range weights {
+		r := a.dailyReturns[sym]
+		if len(r) < 2 {
+			continue
+		}
+		fit, err := a.garchModel(sym, a.barCache[barCacheKey(sym, warmStartTimeFrame)].bars)
+		if err != nil {
+			continue
+		}
+		mean, variance := 0.0, 0.0
+		for _, v := range r {
+			mean += v
+		}
+		mean /= float64(len(r))
+		for _, v := range r {
+			variance += (v - mean) * (v - mean)
+		}
+		variance /= float64(len(r) - 1)
+		if variance <= 0 {
+			continue
+		}
+		k := math.Sqrt(fit.model.NextVariance / variance)
+		scaled := make([]float64, len(r))
+		for i, v := range r {
+			scaled[i] = v * k
+		}
+		out[sym] = scaled
+		forecasted = append(forecasted, sym)
+	}
+	sort.Strings(forecasted)
+	return out, forecasted
+}
+
+// TrimToVolTarget reduces existing positions pro rata so the portfolio's
+// estimated volatility returns to target. Each trim goes through the
+// normal order path; with dryRun set only previews are returned.
+func (a *TradingAlgorithm) TrimToVolTarget(dryRun bool) ([]*OrderPreview, error) {
+	state := a.GetVolTargetState()
+	if !state.Enabled {
+		return nil, errors.New("volatility targeting is disabled (target_annual_volatility is 0)")
+	}
+
+	var previews []*OrderPreview
+	var failed []string
+	for _, trim := range state.Trims {
+		side := SignalSell
+		if trim.CurrentQty < 0 {
+			side = SignalBuy
+		}
+		// Trims are whole shares, so short covers stay whole too
+		signal := &TradeSignal{
+			Symbol:    trim.Symbol,
+			Signal:    side,
+			OrderType: OrderTypeMarket,
+			Execution: ExecutionPassive,
+			Timestamp: a.now(),
+			Reasoning: "trims the portfolio to its volatility target",
+			Source:    "vol_target",
+			Size:      &TradeSize{Qty: trim.TrimQty},
+		}
+		preview, err := a.submitReduction(signal, dryRun)
+		if err != nil {
+			failed = append(failed, trim.Symbol+": "+err.Error())
+			continue
+		}
+		if preview != nil {
+			previews = append(previews, preview)
+		}
+	}
+	if len(failed) > 0 {
+		return previews, errors.New("some trims failed: " + strings.Join(failed, "; "))
+	}
+	return previews, nil
+}
+
+// riskParamFloat reads a numeric risk parameter regardless of whether it
+// was stored as an int or a float64.
+func riskParamFloat(params map[string]interface{}, key string, def float64) float64 {
+	switch v := params[key].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	}
+	return def
+}
+
+// submitReduction previews or submits signal, an order reducing an
+// existing position, through the trade guards and the normal order path.
+// Without a market price the position's mark is used.
+func (a *TradingAlgorithm) submitReduction(signal *TradeSignal, dryRun bool) (*OrderPreview, error) {
+	if err := a.CheckTradeGuards(signal); err != nil {
+		return nil, err
+	}
+
+	a.mu.RLock()
+	price := a.marketData[signal.Symbol].Price
+	if pos, ok := a.portfolio.Positions[signal.Symbol]; ok && price <= 0 && pos.Quantity != 0 {
+		price = math.Abs(pos.MarketVal / pos.Quantity)
+	}
+	portfolio := a.portfolio
+	riskParams := a.sizingParamsLocked()
+	a.mu.RUnlock()
+	if price <= 0 {
+		return nil, fmt.Errorf("no price for %s", signal.Symbol)
+	}
+
+	preview, err := a.buildOrder(signal, price, portfolio, riskParams)
+	if preview == nil || err != nil {
+		return nil, err
+	}
+	return a.submitPreview(signal, preview, dryRun)
+}
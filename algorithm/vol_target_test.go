@@ -0,0 +1,162 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+)
+
+func TestPortfolioVolatilitySingleAsset(t *testing.T) {
+	// Alternating ±1% daily returns: sample stdev is ~1.0%.
+	returns := make([]float64, 20)
+	for i := range returns {
+		if i%2 == 0 {
+			returns[i] = 0.01
+		} else {
+			returns[i] = -0.01
+		}
+	}
+	sd := 0.01 * math.Sqrt(20.0/19.0)
+
+	vol, err := PortfolioVolatility(map[string]float64{"AAA": 0.5}, map[string][]float64{"AAA": returns})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := 0.5 * sd * math.Sqrt(tradingDaysPerYear)
+	if math.Abs(vol-want) > 1e-9 {
+		t.Fatalf("vol = %v, want %v", vol, want)
+	}
+}
+
+func TestPortfolioVolatilityHedgedPair(t *testing.T) {
+	// A long and an equal short in perfectly correlated assets cancel out.
+	r := []float64{0.02, -0.01, 0.015, -0.03, 0.005}
+	vol, err := PortfolioVolatility(
+		map[string]float64{"AAA": 0.3, "BBB": -0.3},
+		map[string][]float64{"AAA": r, "BBB": r},
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if vol > 1e-12 {
+		t.Fatalf("hedged vol = %v, want ~0", vol)
+	}
+}
+
+func TestPortfolioVolatilityAlignsTails(t *testing.T) {
+	long := []float64{0.5, -0.5, 0.01, -0.01, 0.01}
+	short := []float64{0.01, -0.01, 0.01}
+	vol, err := PortfolioVolatility(
+		map[string]float64{"AAA": 1},
+		map[string][]float64{"AAA": long},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	both, err := PortfolioVolatility(
+		map[string]float64{"AAA": 1, "BBB": 0},
+		map[string][]float64{"AAA": long, "BBB": short},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Zero-weight symbols are skipped, so the window is not shortened.
+	if math.Abs(vol-both) > 1e-12 {
+		t.Fatalf("zero-weight symbol changed vol: %v vs %v", vol, both)
+	}
+}
+
+func TestPortfolioVolatilityNoData(t *testing.T) {
+	if _, err := PortfolioVolatility(map[string]float64{"AAA": 1}, nil); err == nil {
+		t.Fatal("expected error without return history")
+	}
+}
+
+func TestVolTargetScale(t *testing.T) {
+	cases := []struct {
+		target, current, want float64
+	}{
+		{0, 30, 1},
+		{15, 0, 1},
+		{15, 30, 0.5},
+		{15, 5, volTargetMaxScale},
+	}
+	for _, c := range cases {
+		if got := volTargetScale(c.target, c.current); math.Abs(got-c.want) > 1e-12 {
+			t.Errorf("volTargetScale(%v, %v) = %v, want %v", c.target, c.current, got, c.want)
+		}
+	}
+}
+
+func TestVolTargetStateTrims(t *testing.T) {
+	a := &TradingAlgorithm{
+		riskParameters: map[string]interface{}{"target_annual_volatility": 10.0},
+		portfolio: PortfolioData{
+			TotalValue: 10000,
+			Positions: map[string]PositionData{
+				"AAA": {Symbol: "AAA", Quantity: 100, MarketVal: 10000},
+			},
+		},
+		dailyReturns: map[string][]float64{},
+	}
+	a.recordDailyCloses("AAA", marketdata.OneDay, []float64{100, 102, 100, 102, 100, 102, 100})
+
+	state := a.GetVolTargetState()
+	if !state.Enabled || state.CurrentVolatility <= state.TargetVolatility {
+		t.Fatalf("expected portfolio over target, got %+v", state)
+	}
+	if state.Scale >= 1 {
+		t.Fatalf("scale = %v, want < 1", state.Scale)
+	}
+	if len(state.Trims) != 1 || state.Trims[0].Symbol != "AAA" {
+		t.Fatalf("trims = %+v", state.Trims)
+	}
+	if tr := state.Trims[0]; tr.TargetQty+tr.TrimQty != 100 {
+		t.Fatalf("trim does not reconcile: %+v", tr)
+	}
+}
+
+func TestTrimToVolTargetSubmits(t *testing.T) {
+	broker := &legBroker{}
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	a.SetBroker(broker)
+	a.riskParameters["target_annual_volatility"] = 10.0
+	a.portfolio = PortfolioData{
+		TotalValue: 10000,
+		Positions: map[string]PositionData{
+			"AAA": {Symbol: "AAA", Quantity: -100, MarketVal: -10000},
+		},
+	}
+	a.portfolioAt = time.Now()
+	a.recordDailyCloses("AAA", marketdata.OneDay, []float64{100, 102, 100, 102, 100, 102, 100})
+
+	// Safe mode, or any other guard, stops the trim
+	a.AddTradeGuard("safe_mode", func(*TradeSignal) error { return errors.New("safe mode") })
+	if _, err := a.TrimToVolTarget(false); err == nil || len(broker.placed) != 0 {
+		t.Fatalf("guarded trim = %v, placed %d", err, len(broker.placed))
+	}
+
+	a.guards = nil
+	var tracked []string
+	a.SetOrderHandler(func(_ *TradeSignal, order *alpaca.Order) { tracked = append(tracked, order.ID) })
+	previews, err := a.TrimToVolTarget(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(previews) != 1 || !previews[0].Submitted || len(broker.placed) != 1 {
+		t.Fatalf("previews = %+v, placed %d", previews, len(broker.placed))
+	}
+	req := broker.placed[0]
+	if req.Side != alpaca.Buy || req.PositionIntent != alpaca.BuyToClose || !req.Qty.Equal(req.Qty.Floor()) ||
+		TagFromClientOrderID(req.ClientOrderID) != "vol_target" {
+		t.Errorf("trim = %s %s %s %q", req.Side, req.PositionIntent, req.Qty, req.ClientOrderID)
+	}
+	if len(tracked) != 1 || tracked[0] != previews[0].OrderID {
+		t.Errorf("tracked %v", tracked)
+	}
+}
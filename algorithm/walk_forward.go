@@ -0,0 +1,433 @@
+package algorithm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm/algo"
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+const (
+	// defaultInSampleBars is the optimization window of each fold.
+	defaultInSampleBars = 120
+	// defaultOutOfSampleBars is how many bars each fold trades.
+	defaultOutOfSampleBars = 20
+	// defaultWalkForwardEquity is the stitched curve's starting equity.
+	defaultWalkForwardEquity = 100000.0
+	// maxParamCombinations bounds the optimizer's grid per fold.
+	maxParamCombinations = 256
+)
+
+// Walk-forward objectives the optimizer ranks candidates by.
+const (
+	ObjectiveSharpe = "sharpe"
+	ObjectiveReturn = "return"
+)
+
+// WalkForwardRequest describes a walk-forward backtest. Each fold picks
+// the best parameters from ParamGrid on its in-sample window and trades
+// only the bars after it with them.
+type WalkForwardRequest struct {
+	Symbol        string               `json:"symbol"`
+	AlgorithmType algo.AlgorithmType   `json:"algorithm_type"`
+	Config        algo.AlgorithmConfig `json:"config"` // base config; the grid overrides its additional params
+	// ParamGrid lists candidate values per additional param; every
+	// combination is tried. Empty runs the base config in every fold.
+	ParamGrid       map[string][]float64 `json:"param_grid,omitempty"`
+	InSampleBars    int                  `json:"in_sample_bars,omitempty"`
+	OutOfSampleBars int                  `json:"out_of_sample_bars,omitempty"`
+	// Anchored grows the in-sample window from the first bar instead of
+	// rolling it.
+	Anchored       bool     `json:"anchored,omitempty"`
+	Objective      string   `json:"objective,omitempty"`   // sharpe (default) or return
+	AllowShort     bool     `json:"allow_short,omitempty"` // sell goes short rather than flat
+	CostBps        *float64 `json:"cost_bps,omitempty"`    // per unit of turnover
+	StartingEquity float64  `json:"starting_equity,omitempty"`
+	LookbackDays   int      `json:"lookback_days,omitempty"` // history fetched when the bars are not given
+}
+
+// withDefaults fills unset fields and validates the rest.
+func (r WalkForwardRequest) withDefaults() (WalkForwardRequest, error) {
+	r.Symbol = strings.ToUpper(strings.TrimSpace(r.Symbol))
+	if r.Symbol == "" {
+		return r, errors.New("symbol is required")
+	}
+	if r.AlgorithmType == "" {
+		return r, errors.New("algorithm_type is required")
+	}
+	if r.InSampleBars == 0 {
+		r.InSampleBars = defaultInSampleBars
+	}
+	if r.OutOfSampleBars == 0 {
+		r.OutOfSampleBars = defaultOutOfSampleBars
+	}
+	if r.InSampleBars < 2 || r.OutOfSampleBars < 1 {
+		return r, errors.New("in_sample_bars must be at least 2 and out_of_sample_bars at least 1")
+	}
+	switch r.Objective {
+	case "":
+		r.Objective = ObjectiveSharpe
+	case ObjectiveSharpe, ObjectiveReturn:
+	default:
+		return r, fmt.Errorf("objective must be %s or %s, got %q", ObjectiveSharpe, ObjectiveReturn, r.Objective)
+	}
+	if r.CostBps == nil {
+		cost := defaultSlippageBps
+		r.CostBps = &cost
+	}
+	if *r.CostBps < 0 || r.StartingEquity < 0 {
+		return r, errors.New("cost_bps and starting_equity must not be negative")
+	}
+	if r.StartingEquity == 0 {
+		r.StartingEquity = defaultWalkForwardEquity
+	}
+	if _, err := ParseTimeFrame(r.Config.Resolution()); err != nil {
+		return r, err
+	}
+	return r, nil
+}
+
+// BacktestPerformance summarizes the per-bar returns of a backtest.
+// Returns and drawdowns are fractions; the Sharpe ratio is annualized.
+type BacktestPerformance struct {
+	Bars        int     `json:"bars"`
+	TotalReturn float64 `json:"total_return"`
+	Sharpe      float64 `json:"sharpe"`
+	MaxDrawdown float64 `json:"max_drawdown"`
+	Trades      int     `json:"trades"`   // position changes
+	Exposure    float64 `json:"exposure"` // share of bars in a position
+}
+
+// WalkForwardFold is one fold: the parameters chosen in sample and how
+// they did out of sample.
+type WalkForwardFold struct {
+	Fold             int                 `json:"fold"`
+	InSampleStart    time.Time           `json:"in_sample_start"`
+	InSampleEnd      time.Time           `json:"in_sample_end"`
+	OutOfSampleStart time.Time           `json:"out_of_sample_start"`
+	OutOfSampleEnd   time.Time           `json:"out_of_sample_end"`
+	Params           map[string]float64  `json:"params"`
+	Candidates       int                 `json:"candidates"` // parameter sets that ran in sample
+	InSample         BacktestPerformance `json:"in_sample"`  // of the chosen parameters
+	OutOfSample      BacktestPerformance `json:"out_of_sample"`
+	Errors           int                 `json:"errors,omitempty"` // out-of-sample bars the algorithm failed on, held as before
+	StartEquity      float64             `json:"start_equity"`
+	EndEquity        float64             `json:"end_equity"`
+}
+
+// StitchedPoint is the walk-forward equity at the close of a bar.
+type StitchedPoint struct {
+	Time     time.Time `json:"time"`
+	Equity   float64   `json:"equity"`
+	Position float64   `json:"position"` // held into the next bar: 1 long, -1 short, 0 flat
+	Fold     int       `json:"fold"`
+}
+
+// WalkForwardResult is the out-of-sample record of a walk-forward
+// backtest. Equity and OutOfSample cover only bars traded with parameters
+// chosen before them.
+type WalkForwardResult struct {
+	Request     WalkForwardRequest  `json:"request"`
+	TimeFrame   string              `json:"timeframe"`
+	Folds       []WalkForwardFold   `json:"folds"`
+	Equity      []StitchedPoint     `json:"equity"`
+	OutOfSample BacktestPerformance `json:"out_of_sample"`
+	// InSampleSharpe is the mean of the folds' chosen in-sample Sharpe
+	// ratios; Efficiency is the out-of-sample Sharpe over it, zero when it
+	// is not positive.
+	InSampleSharpe float64   `json:"in_sample_sharpe"`
+	Efficiency     float64   `json:"efficiency"`
+	GeneratedAt    time.Time `json:"generated_at"`
+}
+
+// backtestRun is one pass of an algorithm over a range of bars.
+type backtestRun struct {
+	returns   []float64
+	positions []float64 // held into the next bar
+	trades    int
+	exposed   int
+	errors    int
+	position  float64 // at the end
+}
+
+// WalkForward runs req over bars, oldest first. progress, when set, is
+// told the fraction of folds done.
+func WalkForward(ctx context.Context, bars []BarData, req WalkForwardRequest, progress func(fraction float64, message string)) (*WalkForwardResult, error) {
+	req, err := req.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := expandParamGrid(req.Config.AdditionalParams, req.ParamGrid)
+	if err != nil {
+		return nil, err
+	}
+	alg, err := algo.Create(req.AlgorithmType)
+	if err != nil {
+		return nil, err
+	}
+	if err := alg.Configure(req.Config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	history := algo.HistoryBars(alg, req.Config)
+
+	// The first in-sample bar has a full lookback behind it
+	warmup := max(history-1, 1)
+	n := len(bars)
+	if n < warmup+req.InSampleBars+1 {
+		return nil, fmt.Errorf("walk-forward needs at least %d bars of %s, got %d",
+			warmup+req.InSampleBars+1, req.Symbol, n)
+	}
+	data := marketDataFromBars(req.Symbol, bars)
+	ppy := periodsPerYear(req.Config.Resolution())
+
+	var starts []int
+	for start := warmup + req.InSampleBars; start < n; start += req.OutOfSampleBars {
+		starts = append(starts, start)
+	}
+
+	result := &WalkForwardResult{
+		Request:     req,
+		TimeFrame:   req.Config.Resolution(),
+		Folds:       make([]WalkForwardFold, 0, len(starts)),
+		Equity:      make([]StitchedPoint, 0, n-starts[0]),
+		GeneratedAt: time.Now(),
+	}
+	equity, position := req.StartingEquity, 0.0
+	var oosReturns []float64
+	var trades, exposed int
+	var isSharpe float64
+	for k, oosStart := range starts {
+		oosEnd := minInt(oosStart+req.OutOfSampleBars, n)
+		isStart := oosStart - req.InSampleBars
+		if req.Anchored {
+			isStart = warmup
+		}
+
+		best, bestRun, tried := -1, backtestRun{}, 0
+		for i, params := range candidates {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			run, err := backtestRange(data, isStart, oosStart, history, req, params, 0)
+			if err != nil || run.errors == len(run.returns) {
+				continue
+			}
+			tried++
+			if best < 0 || score(run, req.Objective, ppy) > score(bestRun, req.Objective, ppy) {
+				best, bestRun = i, run
+			}
+		}
+		if best < 0 {
+			return nil, fmt.Errorf("fold %d: no parameter set ran in sample", k+1)
+		}
+
+		run, err := backtestRange(data, oosStart, oosEnd, history, req, candidates[best], position)
+		if err != nil {
+			return nil, fmt.Errorf("fold %d: %w", k+1, err)
+		}
+		fold := WalkForwardFold{
+			Fold:             k + 1,
+			InSampleStart:    bars[isStart].Timestamp,
+			InSampleEnd:      bars[oosStart-1].Timestamp,
+			OutOfSampleStart: bars[oosStart].Timestamp,
+			OutOfSampleEnd:   bars[oosEnd-1].Timestamp,
+			Params:           candidates[best],
+			Candidates:       tried,
+			InSample:         bestRun.performance(ppy),
+			OutOfSample:      run.performance(ppy),
+			Errors:           run.errors,
+			StartEquity:      roundCents(equity),
+		}
+		for i, r := range run.returns {
+			equity *= 1 + r
+			result.Equity = append(result.Equity, StitchedPoint{
+				Time:     bars[oosStart+i].Timestamp,
+				Equity:   roundCents(equity),
+				Position: run.positions[i],
+				Fold:     k + 1,
+			})
+		}
+		fold.EndEquity = roundCents(equity)
+		result.Folds = append(result.Folds, fold)
+
+		oosReturns = append(oosReturns, run.returns...)
+		trades += run.trades
+		exposed += run.exposed
+		isSharpe += fold.InSample.Sharpe
+		position = run.position
+		if progress != nil {
+			progress(float64(k+1)/float64(len(starts)), fmt.Sprintf("fold %d of %d", k+1, len(starts)))
+		}
+	}
+
+	result.OutOfSample = performance(oosReturns, trades, exposed, ppy)
+	result.InSampleSharpe = round4(isSharpe / float64(len(result.Folds)))
+	if result.InSampleSharpe > 0 {
+		result.Efficiency = round4(result.OutOfSample.Sharpe / result.InSampleSharpe)
+	}
+	return result, nil
+}
+
+// backtestRange trades bars [from, to) with a fresh algorithm configured
+// with params, starting with position. The position decided at a bar's
+// close earns the next bar's return, so no bar trades on its own close.
+func backtestRange(data []types.MarketData, from, to, history int, req WalkForwardRequest, params map[string]float64, position float64) (backtestRun, error) {
+	alg, err := algo.Create(req.AlgorithmType)
+	if err != nil {
+		return backtestRun{}, err
+	}
+	config := req.Config
+	config.AdditionalParams = params
+	if err := alg.Configure(config); err != nil {
+		return backtestRun{}, err
+	}
+
+	run := backtestRun{
+		returns:   make([]float64, 0, to-from),
+		positions: make([]float64, 0, to-from),
+	}
+	cost := *req.CostBps / 10000
+	for i := from; i < to; i++ {
+		r := 0.0
+		if prev := data[i-1].Price; prev > 0 {
+			r = position * (data[i].Price/prev - 1)
+		}
+		if position != 0 {
+			run.exposed++
+		}
+
+		next := position
+		current := data[i]
+		result, err := alg.Process(req.Symbol, &current, data[max(i-history+1, 0):i+1])
+		if err != nil || result == nil {
+			run.errors++
+		} else {
+			next = targetPosition(result.Signal, position, req.AllowShort)
+		}
+		if next != position {
+			r -= math.Abs(next-position) * cost
+			run.trades++
+			position = next
+		}
+		run.returns = append(run.returns, r)
+		run.positions = append(run.positions, position)
+	}
+	run.position = position
+	return run, nil
+}
+
+// targetPosition maps a signal to the position to hold: buy is long, sell
+// short or flat, close flat, and anything else keeps current.
+func targetPosition(signal string, current float64, allowShort bool) float64 {
+	switch strings.ToLower(signal) {
+	case "buy":
+		return 1
+	case "sell":
+		if allowShort {
+			return -1
+		}
+		return 0
+	case "close":
+		return 0
+	}
+	return current
+}
+
+func (r backtestRun) performance(ppy float64) BacktestPerformance {
+	return performance(r.returns, r.trades, r.exposed, ppy)
+}
+
+func score(r backtestRun, objective string, ppy float64) float64 {
+	p := r.performance(ppy)
+	if objective == ObjectiveReturn {
+		return p.TotalReturn
+	}
+	return p.Sharpe
+}
+
+func performance(returns []float64, trades, exposed int, ppy float64) BacktestPerformance {
+	p := BacktestPerformance{Bars: len(returns), Trades: trades}
+	if len(returns) == 0 {
+		return p
+	}
+	equity, peak := 1.0, 1.0
+	for _, r := range returns {
+		equity *= 1 + r
+		peak = math.Max(peak, equity)
+		p.MaxDrawdown = math.Max(p.MaxDrawdown, 1-equity/peak)
+	}
+	p.TotalReturn = round4(equity - 1)
+	p.MaxDrawdown = round4(p.MaxDrawdown)
+	p.Exposure = round4(float64(exposed) / float64(len(returns)))
+
+	if len(returns) > 1 {
+		m := mean(returns)
+		var ss float64
+		for _, r := range returns {
+			ss += (r - m) * (r - m)
+		}
+		if sd := math.Sqrt(ss / float64(len(returns)-1)); sd > 0 {
+			p.Sharpe = round4(m / sd * math.Sqrt(ppy))
+		}
+	}
+	return p
+}
+
+// expandParamGrid returns every combination of grid's values over base,
+// in a fixed order so ties go to the same candidate.
+func expandParamGrid(base map[string]float64, grid map[string][]float64) ([]map[string]float64, error) {
+	keys := make([]string, 0, len(grid))
+	total := 1
+	for k, values := range grid {
+		if len(values) == 0 {
+			return nil, fmt.Errorf("param_grid %s has no values", k)
+		}
+		keys = append(keys, k)
+		total *= len(values)
+		if total > maxParamCombinations {
+			return nil, fmt.Errorf("param_grid has more than %d combinations", maxParamCombinations)
+		}
+	}
+	sort.Strings(keys)
+
+	out := make([]map[string]float64, 0, total)
+	for i := 0; i < total; i++ {
+		params := make(map[string]float64, len(base)+len(keys))
+		for k, v := range base {
+			params[k] = v
+		}
+		rest := i
+		for j := len(keys) - 1; j >= 0; j-- {
+			values := grid[keys[j]]
+			params[keys[j]] = values[rest%len(values)]
+			rest /= len(values)
+		}
+		out = append(out, params)
+	}
+	return out, nil
+}
+
+// periodsPerYear is how many bars of timeframe a year of regular sessions
+// holds.
+func periodsPerYear(timeframe string) float64 {
+	const sessions, session = 252.0, 390 * time.Minute
+	tf, err := ParseTimeFrame(timeframe)
+	if err != nil {
+		return sessions
+	}
+	d := barDuration(tf)
+	if d >= 24*time.Hour {
+		return sessions * float64(24*time.Hour) / float64(d)
+	}
+	return sessions * math.Ceil(float64(session)/float64(d))
+}
+
+func round4(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
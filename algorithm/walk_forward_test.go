@@ -0,0 +1,88 @@
+package algorithm
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm/algo"
+)
+
+func walkForwardBars(n int) []BarData {
+	start := time.Date(2025, 1, 2, 21, 0, 0, 0, time.UTC)
+	bars := make([]BarData, n)
+	for i := range bars {
+		price := 100 + 10*math.Sin(float64(i)/7) + float64(i)/10
+		bars[i] = BarData{Symbol: "AAPL", Timestamp: start.AddDate(0, 0, i), Open: price, High: price, Low: price, Close: price, Volume: 1000}
+	}
+	return bars
+}
+
+func TestWalkForwardStitchesOnlyOutOfSampleBars(t *testing.T) {
+	bars := walkForwardBars(200)
+	req := WalkForwardRequest{
+		Symbol:          "aapl",
+		AlgorithmType:   algo.AlgorithmTypeCUSUMFilter,
+		ParamGrid:       map[string][]float64{"threshold": {0.5, 1, 2}, "drift": {0, 0.5}},
+		InSampleBars:    60,
+		OutOfSampleBars: 25,
+	}
+	var calls int
+	res, err := WalkForward(context.Background(), bars, req, func(float64, string) { calls++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 30 bars of lookback, then 60 in sample, leaves 111 bars to trade
+	first := 29 + 60
+	if len(res.Equity) != len(bars)-first || len(res.Folds) != 5 || calls != 5 {
+		t.Fatalf("equity %d points, %d folds, %d progress calls", len(res.Equity), len(res.Folds), calls)
+	}
+	if !res.Equity[0].Time.Equal(bars[first].Timestamp) {
+		t.Fatalf("stitched curve starts %v, want the first out-of-sample bar %v", res.Equity[0].Time, bars[first].Timestamp)
+	}
+	equity := res.Request.StartingEquity
+	for i, f := range res.Folds {
+		if !f.InSampleEnd.Before(f.OutOfSampleStart) {
+			t.Fatalf("fold %d trades bars it was optimized on", f.Fold)
+		}
+		if f.Candidates != 6 || f.Params["threshold"] == 0 {
+			t.Fatalf("fold %d: %d candidates, params %v", f.Fold, f.Candidates, f.Params)
+		}
+		if f.StartEquity != roundCents(equity) {
+			t.Fatalf("fold %d starts at %v, previous ended at %v", i+1, f.StartEquity, equity)
+		}
+		equity = f.EndEquity
+	}
+	if last := res.Equity[len(res.Equity)-1].Equity; last != equity {
+		t.Fatalf("curve ends at %v, folds at %v", last, equity)
+	}
+	if got := res.OutOfSample.Bars; got != len(res.Equity) {
+		t.Fatalf("out-of-sample performance covers %d bars, want %d", got, len(res.Equity))
+	}
+}
+
+func TestWalkForwardRejectsShortHistory(t *testing.T) {
+	req := WalkForwardRequest{Symbol: "AAPL", AlgorithmType: algo.AlgorithmTypeCUSUMFilter}
+	if _, err := WalkForward(context.Background(), walkForwardBars(100), req, nil); err == nil {
+		t.Fatal("expected an error for fewer bars than the lookback and in-sample window")
+	}
+}
+
+func TestExpandParamGrid(t *testing.T) {
+	got, err := expandParamGrid(map[string]float64{"drift": 0.1, "threshold": 1},
+		map[string][]float64{"threshold": {1, 2}, "window": {5, 10, 20}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 6 || got[0]["threshold"] != 1 || got[0]["window"] != 5 || got[5]["threshold"] != 2 || got[5]["window"] != 20 {
+		t.Fatalf("grid = %v", got)
+	}
+	if got[3]["drift"] != 0.1 {
+		t.Fatalf("base params lost: %v", got[3])
+	}
+	if _, err := expandParamGrid(nil, map[string][]float64{"threshold": {}}); err == nil {
+		t.Fatal("expected an error for an empty value list")
+	}
+}
@@ -0,0 +1,251 @@
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/rileyseaburg/go-trader/algorithm/algo"
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+// warmStartTimeFrame is the bar resolution always preloaded for the quant
+// algorithms; other resolutions are preloaded once an algorithm is
+// configured for them.
+const warmStartTimeFrame = algo.DefaultTimeFrame
+
+// sessionMinutes is the length of a regular trading session.
+const sessionMinutes = 390
+
+// historyNeed is one configured algorithm's history requirement.
+type historyNeed struct {
+	timeframe string
+	bars      int
+}
+
+// errNoMarketData is returned when bars must be fetched but no market data
+// client is configured.
+var errNoMarketData = errors.New("no market data client configured")
+
+// sessionZone is the exchange time zone used to decide which daily bar a
+// live price belongs to.
+var sessionZone = func() *time.Location {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}()
+
+// SetAlgorithmHistory records how many bars of timeframe the algorithm
+// configured under name needs. Start preloads the largest requirement per
+// timeframe; a non-positive bars removes the entry.
+func (a *TradingAlgorithm) SetAlgorithmHistory(name, timeframe string, bars int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if bars <= 0 {
+		delete(a.historyNeeds, name)
+		return
+	}
+	a.historyNeeds[name] = historyNeed{timeframe: timeframe, bars: bars}
+}
+
+// RequiredHistory returns the number of bars preloaded per symbol for each
+// timeframe: the largest configured algorithm requirement. Daily bars are
+// always preloaded, never fewer than algo.DefaultRequiredHistory.
+func (a *TradingAlgorithm) RequiredHistory() map[string]int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	needs := map[string]int{warmStartTimeFrame: algo.DefaultRequiredHistory}
+	for _, n := range a.historyNeeds {
+		if n.bars > needs[n.timeframe] {
+			needs[n.timeframe] = n.bars
+		}
+	}
+	return needs
+}
+
+// CheckAlgorithmHistory validates that config names a supported timeframe
+// and that the history alg needs at that resolution can be served: an
+// explicit bar count must cover the algorithm's lookback, and the total
+// must fit in the bar cache.
+func CheckAlgorithmHistory(alg algo.Algorithm, config algo.AlgorithmConfig) error {
+	if !ValidTimeFrame(config.Resolution()) {
+		return fmt.Errorf("unsupported timeframe %q", config.TimeFrame)
+	}
+	if err := algo.CheckBarCount(alg, config); err != nil {
+		return err
+	}
+	if bars := algo.HistoryBars(alg, config); bars > maxCachedBars {
+		return fmt.Errorf("%s needs %d %s bars, more than the %d bars of history kept per symbol",
+			alg.Name(), bars, config.Resolution(), maxCachedBars)
+	}
+	return nil
+}
+
+// PreloadHistory makes sure at least bars bars of timeframe are cached for
+// each symbol. Symbols already covered by the cache are skipped; the rest
+// are fetched from Alpaca. The returned map holds per-symbol failures.
+func (a *TradingAlgorithm) PreloadHistory(symbols []string, timeframe string, bars int) map[string]error {
+	failures := make(map[string]error)
+	for _, sym := range symbols {
+		if cached, _ := a.CachedBars(sym, timeframe); len(cached) >= bars {
+			continue
+		}
+		if err := a.fetchBars(sym, timeframe, bars); err != nil {
+			failures[sym] = err
+		}
+	}
+	return failures
+}
+
+// lookbackDays is how many calendar days to request to cover bars bars of
+// tf, allowing for weekends, holidays and regular-session hours only.
+func lookbackDays(tf marketdata.TimeFrame, bars int) int {
+	var sessions int
+	if d := barDuration(tf); d >= 24*time.Hour {
+		sessions = bars * int(d/(24*time.Hour))
+	} else {
+		perSession := sessionMinutes / int(d/time.Minute)
+		if perSession < 1 {
+			perSession = 1
+		}
+		sessions = (bars + perSession - 1) / perSession
+	}
+	return sessions*7/5 + 10
+}
+
+// fetchBars fetches enough history to cover bars bars of timeframe into
+// the bar cache.
+func (a *TradingAlgorithm) fetchBars(symbol, timeframe string, bars int) error {
+	if a.mdClient == nil {
+		return errNoMarketData
+	}
+	tf, err := ParseTimeFrame(timeframe)
+	if err != nil {
+		return err
+	}
+	end := a.now()
+	start := end.AddDate(0, 0, -lookbackDays(tf, bars))
+	history, err := a.GetBarHistory(HistoryRequest{Symbol: symbol, StartDate: start, EndDate: end, TimeFrame: timeframe})
+	if err != nil {
+		return err
+	}
+	if len(history.Bars) < bars {
+		return fmt.Errorf("only %d of %d %s bars available for %s", len(history.Bars), bars, timeframe, symbol)
+	}
+	return nil
+}
+
+// AlgorithmHistory returns the latest bars bars of timeframe for symbol in
+// the form the quant algorithms consume. It reads the bar cache, fetching
+// when the cache is short or, for intraday timeframes, a bar behind. Fewer
+// bars than requested are returned, with no error, when the provider has
+// no more history.
+func (a *TradingAlgorithm) AlgorithmHistory(symbol, timeframe string, bars int) ([]types.MarketData, error) {
+	if timeframe == "" {
+		timeframe = warmStartTimeFrame
+	}
+	cached, fetchedAt := a.CachedBars(symbol, timeframe)
+	stale := false
+	if timeframe != warmStartTimeFrame {
+		tf, _ := ParseTimeFrame(timeframe)
+		stale = time.Since(fetchedAt) > barDuration(tf)
+	}
+	miss := len(cached) < bars || stale
+	a.recordCacheLookup(CacheBars, !miss)
+	if miss {
+		if err := a.fetchBars(symbol, timeframe, bars); err != nil && len(cached) == 0 {
+			return nil, err
+		}
+		cached, _ = a.CachedBars(symbol, timeframe)
+	}
+	if len(cached) == 0 {
+		return nil, fmt.Errorf("no %s bars available for %s", timeframe, symbol)
+	}
+	if len(cached) > bars {
+		cached = cached[len(cached)-bars:]
+	}
+
+	return marketDataFromBars(symbol, cached), nil
+}
+
+// marketDataFromBars converts bars to the form the quant algorithms
+// consume, with each bar's change from the one before.
+func marketDataFromBars(symbol string, bars []BarData) []types.MarketData {
+	out := make([]types.MarketData, len(bars))
+	for i, b := range bars {
+		out[i] = types.MarketData{
+			Symbol:    symbol,
+			Price:     b.Close,
+			Open:      b.Open,
+			High24h:   b.High,
+			Low24h:    b.Low,
+			Volume24h: float64(b.Volume),
+			Timestamp: b.Timestamp,
+		}
+		if i > 0 && bars[i-1].Close > 0 {
+			out[i].Change24h = (b.Close - bars[i-1].Close) / bars[i-1].Close * 100
+		}
+	}
+	return out
+}
+
+// rollDailyBarLocked folds a live price into the cached daily series for
+// symbol so preloaded history keeps pace with the stream: the current
+// session's bar is extended, or a new one is opened on the first price of
+// a new session. Symbols with nothing cached are left alone. Callers must
+// hold a.mu.
+func (a *TradingAlgorithm) rollDailyBarLocked(symbol string, price float64, at time.Time) {
+	if price <= 0 {
+		return
+	}
+	key := barCacheKey(symbol, warmStartTimeFrame)
+	entry, ok := a.barCache[key]
+	if !ok || len(entry.bars) == 0 {
+		return
+	}
+
+	at = at.In(sessionZone)
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, sessionZone)
+	last := &entry.bars[len(entry.bars)-1]
+	lastDay := last.Timestamp.In(sessionZone)
+	switch {
+	case lastDay.Year() == day.Year() && lastDay.YearDay() == day.YearDay():
+		last.Close = price
+		if price > last.High {
+			last.High = price
+		}
+		if price < last.Low {
+			last.Low = price
+		}
+	case day.After(lastDay):
+		entry.bars = append(entry.bars, BarData{
+			Symbol:    symbol,
+			Timestamp: day,
+			Open:      price,
+			High:      price,
+			Low:       price,
+			Close:     price,
+		})
+		if len(entry.bars) > maxCachedBars {
+			entry.bars = entry.bars[len(entry.bars)-maxCachedBars:]
+		}
+	default:
+		return
+	}
+	a.barCache[key] = entry
+}
+
+// warmStart preloads history for symbols at every configured timeframe,
+// logging rather than failing so a partial preload never blocks Start.
+func (a *TradingAlgorithm) warmStart(symbols []string) {
+	for timeframe, need := range a.RequiredHistory() {
+		failures := a.PreloadHistory(symbols, timeframe, need)
+		for sym, err := range failures {
+			logger().Warn("Warm start incomplete", "timeframe", timeframe, "symbol", sym, "error", err)
+		}
+		logger().Info("Preloaded bars", "bars", need, "timeframe", timeframe, "loaded", len(symbols)-len(failures), "symbols", len(symbols))
+	}
+}
@@ -0,0 +1,90 @@
+package algorithm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/rileyseaburg/go-trader/algorithm/algo"
+)
+
+func TestAlgorithmHistoryFromCacheAndRolling(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	today := time.Now().In(sessionZone)
+	day0 := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, sessionZone)
+	bars := make([]BarData, 40)
+	for i := range bars {
+		p := 100 + float64(i)
+		bars[i] = BarData{Symbol: "AAPL", Timestamp: day0.AddDate(0, 0, i-40), Open: p, High: p + 1, Low: p - 1, Close: p}
+	}
+	a.cacheBars("AAPL", warmStartTimeFrame, bars)
+
+	if failed := a.PreloadHistory([]string{"AAPL"}, "1D", 40); len(failed) != 0 {
+		t.Fatalf("cached symbol refetched: %v", failed)
+	}
+	if failed := a.PreloadHistory([]string{"MSFT"}, "1D", 40); failed["MSFT"] != errNoMarketData {
+		t.Errorf("uncached symbol failures = %v", failed)
+	}
+
+	hist, err := a.AlgorithmHistory("AAPL", "1D", 30)
+	if err != nil || len(hist) != 30 || hist[29].Price != 139 {
+		t.Fatalf("history = %d bars, last %+v, err %v", len(hist), hist[len(hist)-1], err)
+	}
+	if hist[29].Change24h <= 0 {
+		t.Errorf("change not derived from prior close: %+v", hist[29])
+	}
+
+	// A live price opens today's bar, later prices extend it
+	a.UpdateMarketData("AAPL", 150, 0, 0, 0, 0)
+	a.UpdateMarketData("AAPL", 148, 0, 0, 0, 0)
+	cached, _ := a.CachedBars("AAPL", warmStartTimeFrame)
+	last := cached[len(cached)-1]
+	if len(cached) != 41 || last.Open != 150 || last.High != 150 || last.Close != 148 {
+		t.Errorf("rolled bar = %+v (of %d)", last, len(cached))
+	}
+}
+
+func TestRequiredHistoryTracksConfiguredAlgorithms(t *testing.T) {
+	a := NewTradingAlgorithm(context.Background(), nil, nil, nil)
+	a.SetAlgorithmHistory("position_sizing", "1D", 60)
+	a.SetAlgorithmHistory("cusum_filter", "1D", 2)
+	a.SetAlgorithmHistory("triple_barrier", "15Min", 200)
+	needs := a.RequiredHistory()
+	if needs["1D"] != 60 || needs["15Min"] != 200 || len(needs) != 2 {
+		t.Errorf("required = %v", needs)
+	}
+	a.SetAlgorithmHistory("position_sizing", "1D", 0)
+	if got := a.RequiredHistory()["1D"]; got != 30 {
+		t.Errorf("required after removal = %d, want default 30", got)
+	}
+}
+
+func TestCheckAlgorithmHistory(t *testing.T) {
+	ps, _ := algo.Create(algo.AlgorithmTypePositionSizing)
+	cfg := algo.AlgorithmConfig{TimeFrame: "5Min", AdditionalParams: map[string]float64{"vol_lookback": 50}}
+	ps.Configure(cfg)
+	if err := CheckAlgorithmHistory(ps, cfg); err != nil {
+		t.Errorf("valid config rejected: %v", err)
+	}
+
+	for name, bad := range map[string]algo.AlgorithmConfig{
+		"unknown timeframe":  {TimeFrame: "2D"},
+		"bar count too low":  {BarCount: 10},
+		"beyond cached bars": {BarCount: maxCachedBars + 1},
+	} {
+		bad.AdditionalParams = cfg.AdditionalParams
+		ps.Configure(bad)
+		if err := CheckAlgorithmHistory(ps, bad); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLookbackDaysScalesWithResolution(t *testing.T) {
+	daily := lookbackDays(marketdata.OneDay, 100)
+	minute := lookbackDays(marketdata.OneMin, 780) // two sessions
+	if daily != 150 || minute != 12 {
+		t.Errorf("lookback days = %d daily, %d minute", daily, minute)
+	}
+}
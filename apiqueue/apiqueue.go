@@ -0,0 +1,460 @@
+// Package apiqueue paces the REST requests every subsystem makes to
+// Alpaca. Each API gets a token bucket under its rate limit and a priority
+// queue in front of it, so order placement goes ahead of portfolio reads
+// and portfolio reads ahead of data fetches. Identical GETs already in
+// flight are answered by the first one's response, and a 429 pauses the
+// API until Alpaca's limit resets.
+package apiqueue
+
+import (
+	"bytes"
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func logger() *slog.Logger { return slog.With("module", "apiqueue") }
+
+// Priority orders queued requests; lower values are sent first.
+type Priority int
+
+const (
+	PriorityOrder     Priority = iota // placing, replacing and cancelling orders, closing positions
+	PriorityPortfolio                 // account and position reads
+	PriorityData                      // market data, assets, calendar and everything else
+	numPriorities
+)
+
+// String names the priority.
+func (p Priority) String() string {
+	switch p {
+	case PriorityOrder:
+		return "order"
+	case PriorityPortfolio:
+		return "portfolio"
+	default:
+		return "data"
+	}
+}
+
+// Classify picks a request's priority from its Alpaca endpoint.
+func Classify(req *http.Request) Priority {
+	path := req.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/v2/orders"):
+		return PriorityOrder
+	case strings.HasPrefix(path, "/v2/positions"):
+		// DELETE closes positions
+		if req.Method == http.MethodDelete {
+			return PriorityOrder
+		}
+		return PriorityPortfolio
+	case strings.HasPrefix(path, "/v2/account"):
+		return PriorityPortfolio
+	}
+	return PriorityData
+}
+
+// Policy configures the pacing of each API.
+type Policy struct {
+	// RequestsPerMinute is sent to each API at most, on average; Alpaca
+	// allows 200 per account
+	RequestsPerMinute int `json:"requests_per_minute"`
+	// Burst is the requests an idle API may send at once
+	Burst int `json:"burst"`
+	// Coalesce answers a GET identical to one in flight with its response
+	Coalesce bool `json:"coalesce"`
+	// PauseSeconds holds an API after a 429 that names no reset time
+	PauseSeconds int `json:"pause_seconds"`
+}
+
+// DefaultPolicy leaves headroom under Alpaca's 200 requests a minute.
+func DefaultPolicy() Policy {
+	return Policy{RequestsPerMinute: 180, Burst: 10, Coalesce: true, PauseSeconds: 5}
+}
+
+// Validate checks the policy for internally consistent values.
+func (p Policy) Validate() error {
+	if p.RequestsPerMinute < 1 || p.RequestsPerMinute > 10000 {
+		return errors.New("requests_per_minute must be between 1 and 10000")
+	}
+	if p.Burst < 1 || p.Burst > p.RequestsPerMinute {
+		return errors.New("burst must be between 1 and requests_per_minute")
+	}
+	if p.PauseSeconds < 1 || p.PauseSeconds > 300 {
+		return errors.New("pause_seconds must be between 1 and 300")
+	}
+	return nil
+}
+
+// maxPause caps how long a reset time read from a 429 holds an API.
+const maxPause = time.Minute
+
+// Stats describes one API's queue.
+type Stats struct {
+	API string `json:"api"`
+	// Queued is the requests waiting now, by priority
+	Queued    map[string]int `json:"queued"`
+	MaxQueued int            `json:"max_queued"` // deepest the queue has been
+	InFlight  int            `json:"in_flight"`
+	// Sent counts requests sent to Alpaca, by priority
+	Sent        map[string]int64 `json:"sent"`
+	Coalesced   int64            `json:"coalesced"`    // answered by an identical request in flight
+	Cancelled   int64            `json:"cancelled"`    // given up while queued
+	RateLimited int64            `json:"rate_limited"` // 429 responses
+	// LastRateLimitedAt is the last 429, and PausedUntil when the API
+	// sends again after it
+	LastRateLimitedAt time.Time `json:"last_rate_limited_at,omitempty"`
+	PausedUntil       time.Time `json:"paused_until,omitempty"`
+	AverageWaitMs     float64   `json:"average_wait_ms"` // time queued per sent request
+	MaxWaitMs         float64   `json:"max_wait_ms"`
+}
+
+// Queue paces requests per API. It is safe for concurrent use.
+type Queue struct {
+	mu     sync.Mutex
+	policy Policy
+	apis   map[string]*lane
+}
+
+// lane is one API's bucket, queue and counters, guarded by Queue.mu.
+type lane struct {
+	api      string
+	tokens   float64
+	last     time.Time
+	paused   time.Time
+	waiting  waiters
+	seq      uint64
+	timer    *time.Timer
+	inflight map[string]*call
+	sending  int
+
+	maxQueued   int
+	sent        [numPriorities]int64
+	coalesced   int64
+	cancelled   int64
+	rateLimited int64
+	lastLimited time.Time
+	waitTotal   time.Duration
+	waitMax     time.Duration
+}
+
+// New creates a queue pacing each API under policy.
+func New(policy Policy) (*Queue, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &Queue{policy: policy, apis: make(map[string]*lane)}, nil
+}
+
+// Policy returns the current policy.
+func (q *Queue) Policy() Policy {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.policy
+}
+
+// SetPolicy validates and replaces the policy. Queued requests are
+// released at the new rate.
+func (q *Queue) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.policy = p
+	for _, l := range q.apis {
+		l.tokens = math.Min(l.tokens, float64(p.Burst))
+		if l.timer != nil {
+			l.timer.Stop()
+			l.timer = nil
+		}
+		q.dispatch(l)
+	}
+	return nil
+}
+
+// Client returns an HTTP client with timeout whose requests are queued
+// under api before going through base. The timeout covers the time
+// queued.
+func (q *Queue) Client(api string, timeout time.Duration, base http.RoundTripper) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: q.Transport(api, base)}
+}
+
+// Transport wraps base, or the default transport when nil, queuing every
+// request under api.
+func (q *Queue) Transport(api string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	q.mu.Lock()
+	l, ok := q.apis[api]
+	if !ok {
+		l = &lane{api: api, tokens: float64(q.policy.Burst), last: time.Now(), inflight: make(map[string]*call)}
+		q.apis[api] = l
+	}
+	q.mu.Unlock()
+	return roundTripper{queue: q, lane: l, base: base}
+}
+
+// Stats describes each API's queue, sorted by name.
+func (q *Queue) Stats() []Stats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]Stats, 0, len(q.apis))
+	for _, l := range q.apis {
+		s := Stats{
+			API:               l.api,
+			Queued:            make(map[string]int, numPriorities),
+			MaxQueued:         l.maxQueued,
+			InFlight:          l.sending,
+			Sent:              make(map[string]int64, numPriorities),
+			Coalesced:         l.coalesced,
+			Cancelled:         l.cancelled,
+			RateLimited:       l.rateLimited,
+			LastRateLimitedAt: l.lastLimited,
+			MaxWaitMs:         float64(l.waitMax) / float64(time.Millisecond),
+		}
+		if time.Now().Before(l.paused) {
+			s.PausedUntil = l.paused
+		}
+		total := int64(0)
+		for p := Priority(0); p < numPriorities; p++ {
+			s.Queued[p.String()] = 0
+			s.Sent[p.String()] = l.sent[p]
+			total += l.sent[p]
+		}
+		for _, w := range l.waiting {
+			s.Queued[w.priority.String()]++
+		}
+		if total > 0 {
+			s.AverageWaitMs = float64(l.waitTotal) / float64(total) / float64(time.Millisecond)
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].API < out[j].API })
+	return out
+}
+
+// waiter is a request queued for a token.
+type waiter struct {
+	priority Priority
+	seq      uint64
+	queued   time.Time
+	ready    chan struct{}
+	granted  bool
+	index    int
+}
+
+// waiters is a heap of queued requests by priority, then arrival.
+type waiters []*waiter
+
+func (w waiters) Len() int { return len(w) }
+func (w waiters) Less(i, j int) bool {
+	if w[i].priority != w[j].priority {
+		return w[i].priority < w[j].priority
+	}
+	return w[i].seq < w[j].seq
+}
+func (w waiters) Swap(i, j int) {
+	w[i], w[j] = w[j], w[i]
+	w[i].index = i
+	w[j].index = j
+}
+func (w *waiters) Push(x interface{}) {
+	item := x.(*waiter)
+	item.index = len(*w)
+	*w = append(*w, item)
+}
+func (w *waiters) Pop() interface{} {
+	old := *w
+	item := old[len(old)-1]
+	*w = old[:len(old)-1]
+	return item
+}
+
+// wait queues a request at priority on l until it may be sent or ctx is
+// done.
+func (q *Queue) wait(ctx context.Context, l *lane, priority Priority) error {
+	q.mu.Lock()
+	l.seq++
+	w := &waiter{priority: priority, seq: l.seq, queued: time.Now(), ready: make(chan struct{})}
+	heap.Push(&l.waiting, w)
+	if n := l.waiting.Len(); n > l.maxQueued {
+		l.maxQueued = n
+	}
+	q.dispatch(l)
+	q.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if w.granted {
+			return nil
+		}
+		heap.Remove(&l.waiting, w.index)
+		l.cancelled++
+		return ctx.Err()
+	}
+}
+
+// dispatch releases queued requests while l has tokens and is not
+// paused, and otherwise arranges to try again when it next will.
+// q.mu must be held.
+func (q *Queue) dispatch(l *lane) {
+	now := time.Now()
+	rate := float64(q.policy.RequestsPerMinute) / 60
+	l.tokens = math.Min(float64(q.policy.Burst), l.tokens+now.Sub(l.last).Seconds()*rate)
+	l.last = now
+
+	for l.waiting.Len() > 0 && !now.Before(l.paused) && l.tokens >= 1 {
+		w := heap.Pop(&l.waiting).(*waiter)
+		w.granted = true
+		close(w.ready)
+		l.tokens--
+		l.sent[w.priority]++
+		waited := now.Sub(w.queued)
+		l.waitTotal += waited
+		if waited > l.waitMax {
+			l.waitMax = waited
+		}
+	}
+	if l.waiting.Len() == 0 || l.timer != nil {
+		return
+	}
+	delay := l.paused.Sub(now)
+	if delay <= 0 {
+		delay = time.Duration((1 - l.tokens) / rate * float64(time.Second))
+	}
+	l.timer = time.AfterFunc(delay, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		l.timer = nil
+		q.dispatch(l)
+	})
+}
+
+// rateLimited pauses l after resp, a 429, until Alpaca's limit resets.
+func (q *Queue) rateLimited(l *lane, req *http.Request, resp *http.Response) {
+	now := time.Now()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	until := now.Add(time.Duration(q.policy.PauseSeconds) * time.Second)
+	if after, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && after > 0 {
+		until = now.Add(time.Duration(after) * time.Second)
+	} else if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil && time.Unix(reset, 0).After(now) {
+		until = time.Unix(reset, 0)
+	}
+	if until.After(now.Add(maxPause)) {
+		until = now.Add(maxPause)
+	}
+	if until.After(l.paused) {
+		l.paused = until
+	}
+	l.rateLimited++
+	l.lastLimited = now
+	logger().Warn("Alpaca rate limit hit; pausing requests", "api", l.api, "path", req.URL.Path, "until", l.paused)
+}
+
+// call is a GET in flight that identical requests wait on.
+type call struct {
+	done chan struct{}
+	resp *http.Response
+	body []byte
+	err  error
+}
+
+// response gives req its own copy of the call's response.
+func (c *call) response(req *http.Request) (*http.Response, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	resp := *c.resp
+	resp.Header = c.resp.Header.Clone()
+	resp.Body = io.NopCloser(bytes.NewReader(c.body))
+	resp.Request = req
+	return &resp, nil
+}
+
+type roundTripper struct {
+	queue *Queue
+	lane  *lane
+	base  http.RoundTripper
+}
+
+func (t roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	q, l := t.queue, t.lane
+	if req.Method != http.MethodGet || req.Body != nil && req.Body != http.NoBody {
+		return t.send(req)
+	}
+
+	key := req.URL.String()
+	q.mu.Lock()
+	coalesce := q.policy.Coalesce
+	if c, ok := l.inflight[key]; ok && coalesce {
+		l.coalesced++
+		q.mu.Unlock()
+		select {
+		case <-c.done:
+			return c.response(req)
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+	if !coalesce {
+		q.mu.Unlock()
+		return t.send(req)
+	}
+	c := &call{done: make(chan struct{})}
+	l.inflight[key] = c
+	q.mu.Unlock()
+
+	// Requests joining this one share its outcome, including a
+	// cancellation of its own context
+	c.resp, c.err = t.send(req)
+	if c.err == nil {
+		c.body, c.err = io.ReadAll(c.resp.Body)
+		c.resp.Body.Close()
+		if c.err != nil {
+			c.err = fmt.Errorf("read response body: %w", c.err)
+		}
+	}
+	q.mu.Lock()
+	delete(l.inflight, key)
+	q.mu.Unlock()
+	close(c.done)
+	return c.response(req)
+}
+
+// send queues req at its priority, then sends it through the base
+// transport.
+func (t roundTripper) send(req *http.Request) (*http.Response, error) {
+	q, l := t.queue, t.lane
+	if err := q.wait(req.Context(), l, Classify(req)); err != nil {
+		return nil, err
+	}
+	q.mu.Lock()
+	l.sending++
+	q.mu.Unlock()
+	resp, err := t.base.RoundTrip(req)
+	q.mu.Lock()
+	l.sending--
+	q.mu.Unlock()
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		q.rateLimited(l, req, resp)
+	}
+	return resp, err
+}
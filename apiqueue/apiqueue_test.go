@@ -0,0 +1,178 @@
+package apiqueue
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeAlpaca records the paths it is sent and answers each with its path,
+// after release is closed when it is set.
+type fakeAlpaca struct {
+	mu      sync.Mutex
+	paths   []string
+	release chan struct{}
+	status  int
+}
+
+func (f *fakeAlpaca) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	f.paths = append(f.paths, req.Method+" "+req.URL.Path)
+	release, status := f.release, f.status
+	f.mu.Unlock()
+	if release != nil {
+		<-release
+	}
+	if status == 0 {
+		status = http.StatusOK
+	}
+	header := http.Header{}
+	if status == http.StatusTooManyRequests {
+		header.Set("Retry-After", "30")
+	}
+	return &http.Response{StatusCode: status, Header: header, Body: io.NopCloser(strings.NewReader(req.URL.Path)), Request: req}, nil
+}
+
+func (f *fakeAlpaca) sent() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.paths...)
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	cases := map[string]Priority{
+		"POST /v2/orders":                     PriorityOrder,
+		"DELETE /v2/positions/AAPL":           PriorityOrder,
+		"GET /v2/positions":                   PriorityPortfolio,
+		"GET /v2/account":                     PriorityPortfolio,
+		"GET /v2/assets/AAPL":                 PriorityData,
+		"GET /v2/stocks/AAPL/bars":            PriorityData,
+		"GET /v2/account/activities/FILL":     PriorityPortfolio,
+		"PATCH /v2/orders/0e3a9b51-1f2c-4d3b": PriorityOrder,
+	}
+	for route, want := range cases {
+		method, path, _ := strings.Cut(route, " ")
+		req, _ := http.NewRequest(method, "https://paper-api.alpaca.markets"+path, nil)
+		if got := Classify(req); got != want {
+			t.Errorf("%s = %s, want %s", route, got, want)
+		}
+	}
+}
+
+func TestQueueSendsByPriority(t *testing.T) {
+	q, err := New(Policy{RequestsPerMinute: 600, Burst: 1, PauseSeconds: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := &fakeAlpaca{}
+	client := q.Client("trading", 10*time.Second, base)
+
+	// The first request takes the only token; the rest queue behind it
+	var wg sync.WaitGroup
+	send := func(method, path string) {
+		defer wg.Done()
+		req, _ := http.NewRequest(method, "https://paper-api.alpaca.markets"+path, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		resp.Body.Close()
+	}
+	routes := [][2]string{{"GET", "/v2/clock"}, {"GET", "/v2/assets/AAPL"}, {"GET", "/v2/positions"}, {"POST", "/v2/orders"}}
+	for i, r := range routes {
+		wg.Add(1)
+		go send(r[0], r[1])
+		queued := i
+		waitFor(t, func() bool {
+			s := q.Stats()[0]
+			return len(base.sent()) == 1 && s.Queued["order"]+s.Queued["portfolio"]+s.Queued["data"] == queued
+		})
+	}
+	wg.Wait()
+
+	want := []string{"GET /v2/clock", "POST /v2/orders", "GET /v2/positions", "GET /v2/assets/AAPL"}
+	if got := base.sent(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("sent %v, want %v", got, want)
+	}
+	s := q.Stats()[0]
+	if s.MaxQueued != 3 || s.Sent["data"] != 2 || s.Sent["order"] != 1 || s.AverageWaitMs <= 0 {
+		t.Errorf("stats = %+v", s)
+	}
+}
+
+func TestQueueCoalescesIdenticalGets(t *testing.T) {
+	q, err := New(DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := &fakeAlpaca{release: make(chan struct{})}
+	client := q.Client("market_data", 10*time.Second, base)
+
+	bodies := make(chan string, 3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			resp, err := client.Get("https://data.alpaca.markets/v2/stocks/AAPL/bars?timeframe=1Day")
+			if err != nil {
+				bodies <- err.Error()
+				return
+			}
+			defer resp.Body.Close()
+			b, _ := io.ReadAll(resp.Body)
+			bodies <- string(b)
+		}()
+	}
+	waitFor(t, func() bool { return q.Stats()[0].Coalesced == 2 })
+	close(base.release)
+	for i := 0; i < 3; i++ {
+		if b := <-bodies; b != "/v2/stocks/AAPL/bars" {
+			t.Errorf("body = %q", b)
+		}
+	}
+	if n := len(base.sent()); n != 1 {
+		t.Errorf("sent %d requests", n)
+	}
+}
+
+func TestQueuePausesAfterRateLimit(t *testing.T) {
+	q, err := New(DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := q.Client("trading", 10*time.Second, &fakeAlpaca{status: http.StatusTooManyRequests})
+	resp, err := client.Get("https://paper-api.alpaca.markets/v2/account")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	s := q.Stats()[0]
+	if s.RateLimited != 1 || time.Until(s.PausedUntil) < 25*time.Second {
+		t.Fatalf("stats = %+v", s)
+	}
+
+	// Requests wait out the pause, and give up when their context does
+	if _, err := (&http.Client{Transport: client.Transport, Timeout: 50 * time.Millisecond}).Get("https://paper-api.alpaca.markets/v2/orders"); err == nil {
+		t.Fatal("sent during the pause")
+	}
+	waitFor(t, func() bool { return q.Stats()[0].Cancelled == 1 })
+	if err := q.SetPolicy(Policy{RequestsPerMinute: 10, Burst: 20, PauseSeconds: 1}); err == nil {
+		t.Error("accepted a burst above the rate")
+	}
+}
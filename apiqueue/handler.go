@@ -0,0 +1,57 @@
+package apiqueue
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Handler exposes the request queues over HTTP.
+type Handler struct {
+	queue *Queue
+}
+
+// NewHandler creates a handler for queue.
+func NewHandler(queue *Queue) *Handler {
+	return &Handler{queue: queue}
+}
+
+// RegisterRoutes registers the queue routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/alpaca/queue - queue depth, requests sent and 429s per API
+	mux.HandleFunc("/api/alpaca/queue", h.handleQueue)
+
+	// GET/POST /api/alpaca/queue/policy - read or update the pacing
+	mux.HandleFunc("/api/alpaca/queue/policy", h.handlePolicy)
+}
+
+func (h *Handler) handleQueue(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"policy": h.queue.Policy(),
+		"apis":   h.queue.Stats(),
+	})
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.queue.Policy())
+	case http.MethodPost, http.MethodPut:
+		// Start from the current policy so partial updates work
+		policy := h.queue.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.queue.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(h.queue.Policy())
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,441 @@
+// Package approvals holds trade signals from outside sources, such as
+// TradingView alerts, until someone approves or rejects them. Signals are
+// checked against the trade guards when they arrive and again when they
+// are approved, pending ones expire after the policy's TTL, and sources
+// the policy trusts are executed without review.
+package approvals
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+)
+
+func logger() *slog.Logger { return slog.With("module", "approvals") }
+
+// Item statuses.
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved" // the order is being placed
+	StatusExecuted = "executed"
+	StatusFailed   = "failed" // approved, but the order could not be placed
+	StatusRejected = "rejected"
+	StatusBlocked  = "blocked" // refused by a trade guard
+	StatusExpired  = "expired"
+)
+
+// DecidedByAuto marks items the policy approved.
+const DecidedByAuto = "auto"
+
+// maxDecided bounds the decided items kept for review.
+const maxDecided = 500
+
+var (
+	// ErrNotFound is returned for an unknown item ID.
+	ErrNotFound = errors.New("approval not found")
+	// ErrDecided is wrapped when an item is no longer pending.
+	ErrDecided = errors.New("signal is no longer pending")
+)
+
+// Policy configures the queue.
+type Policy struct {
+	TTLMinutes  int      `json:"ttl_minutes"`  // pending signals expire after this
+	AutoApprove []string `json:"auto_approve"` // sources executed without review
+}
+
+// DefaultPolicy expires pending signals after 15 minutes and reviews
+// every source.
+func DefaultPolicy() Policy {
+	return Policy{TTLMinutes: 15, AutoApprove: []string{}}
+}
+
+// Validate checks the policy for internally consistent values.
+func (p Policy) Validate() error {
+	if p.TTLMinutes < 1 || p.TTLMinutes > 24*60 {
+		return errors.New("ttl_minutes must be between 1 and 1440")
+	}
+	for _, s := range p.AutoApprove {
+		if strings.TrimSpace(s) == "" {
+			return errors.New("auto_approve must not contain empty sources")
+		}
+	}
+	return nil
+}
+
+func (p Policy) autoApproves(source string) bool {
+	for _, s := range p.AutoApprove {
+		if strings.EqualFold(s, source) {
+			return true
+		}
+	}
+	return false
+}
+
+// Item is one queued signal and what became of it.
+type Item struct {
+	ID        string                 `json:"id"`
+	Signal    *algorithm.TradeSignal `json:"signal"`
+	Status    string                 `json:"status"`
+	CreatedAt time.Time              `json:"created_at"`
+	ExpiresAt time.Time              `json:"expires_at"`
+	DecidedAt *time.Time             `json:"decided_at,omitempty"`
+	DecidedBy string                 `json:"decided_by,omitempty"`
+	Reason    string                 `json:"reason,omitempty"` // why it was rejected
+	Result    string                 `json:"result,omitempty"` // what executing it did
+	Error     string                 `json:"error,omitempty"`  // guard refusal or execution error
+}
+
+// state is what the queue persists.
+type state struct {
+	Policy Policy  `json:"policy"`
+	Items  []*Item `json:"items"` // oldest first
+}
+
+// Queue holds signals awaiting approval. It is safe for concurrent use.
+type Queue struct {
+	path string
+
+	mu      sync.Mutex
+	state   state
+	seq     int
+	now     func() time.Time
+	guard   func(*algorithm.TradeSignal) error
+	execute func(*algorithm.TradeSignal) (string, error)
+	notify  func(Item)
+}
+
+// New opens the queue saved at path, using policy unless one was saved.
+func New(path string, policy Policy) (*Queue, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create approvals directory: %w", err)
+	}
+	q := &Queue{path: path, state: state{Policy: policy}, now: time.Now}
+	if data, err := os.ReadFile(path); err == nil {
+		var saved state
+		if err := json.Unmarshal(data, &saved); err != nil {
+			return nil, fmt.Errorf("failed to decode approval queue: %w", err)
+		}
+		if saved.Policy.Validate() == nil {
+			q.state.Policy = saved.Policy
+		}
+		for _, it := range saved.Items {
+			if it.Status == StatusApproved {
+				// The process stopped while placing the order; whether it
+				// reached the broker is unknown
+				it.Status = StatusFailed
+				it.Error = "interrupted while placing the order; check the broker before resubmitting"
+			}
+			q.state.Items = append(q.state.Items, it)
+			// Keep IDs unique when the clock repeats, as in replays
+			if _, seq, ok := strings.Cut(strings.TrimPrefix(it.ID, "apr_"), "_"); ok {
+				if n, err := strconv.Atoi(seq); err == nil && n > q.seq {
+					q.seq = n
+				}
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read approval queue: %w", err)
+	}
+	return q, nil
+}
+
+// SetClock replaces the clock, for replays and tests.
+func (q *Queue) SetClock(now func() time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.now = now
+}
+
+// SetGuard sets the check a signal must pass on arrival and on approval,
+// normally the algorithm's trade guards.
+func (q *Queue) SetGuard(fn func(*algorithm.TradeSignal) error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.guard = fn
+}
+
+// SetExecutor sets the function that places an approved signal's order
+// and describes what it did.
+func (q *Queue) SetExecutor(fn func(*algorithm.TradeSignal) (string, error)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.execute = fn
+}
+
+// SetNotifier sets a function called with every submitted item, after the
+// guard check.
+func (q *Queue) SetNotifier(fn func(Item)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.notify = fn
+}
+
+// Policy returns the current policy.
+func (q *Queue) Policy() Policy {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.state.Policy
+}
+
+// SetPolicy validates, replaces and saves the policy. The TTL applies to
+// signals submitted from now on.
+func (q *Queue) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if p.AutoApprove == nil {
+		p.AutoApprove = []string{}
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.state.Policy = p
+	q.saveLocked()
+	return nil
+}
+
+// Submit queues signal. A signal the guard refuses is recorded as blocked;
+// one from an auto-approved source is executed before Submit returns.
+func (q *Queue) Submit(signal *algorithm.TradeSignal) (Item, error) {
+	if signal == nil {
+		return Item{}, errors.New("signal is nil")
+	}
+	q.mu.Lock()
+	now := q.now()
+	q.expireLocked(now)
+	q.seq++
+	it := &Item{
+		ID:        fmt.Sprintf("apr_%d_%d", now.UnixNano(), q.seq),
+		Signal:    signal,
+		Status:    StatusPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Duration(q.state.Policy.TTLMinutes) * time.Minute),
+	}
+	guard, auto := q.guard, q.state.Policy.autoApproves(signal.Source)
+	q.mu.Unlock()
+
+	if guard != nil {
+		if err := guard(signal); err != nil {
+			it.Status = StatusBlocked
+			it.Error = err.Error()
+			it.DecidedAt = &now
+		}
+	}
+	if it.Status == StatusPending && auto {
+		it.Status = StatusApproved
+		it.DecidedAt = &now
+		it.DecidedBy = DecidedByAuto
+	}
+
+	q.mu.Lock()
+	q.state.Items = append(q.state.Items, it)
+	q.trimLocked()
+	q.saveLocked()
+	out, notify := *it, q.notify
+	q.mu.Unlock()
+	logger().Info("Signal submitted for approval", "id", out.ID, "symbol", signal.Symbol, "signal", signal.Signal,
+		"source", signal.Source, "status", out.Status, "error", out.Error)
+
+	if notify != nil {
+		notify(out)
+	}
+	if out.Status == StatusApproved {
+		return q.run(it.ID, signal, false), nil
+	}
+	return out, nil
+}
+
+// Approve executes a pending signal, after checking the guard again.
+func (q *Queue) Approve(id, by string) (Item, error) {
+	q.mu.Lock()
+	now := q.now()
+	q.expireLocked(now)
+	it, err := q.pendingLocked(id)
+	if err != nil {
+		q.mu.Unlock()
+		return Item{}, err
+	}
+	it.Status = StatusApproved
+	it.DecidedAt = &now
+	it.DecidedBy = by
+	q.saveLocked()
+	signal := it.Signal
+	q.mu.Unlock()
+	logger().Info("Signal approved", "id", id, "symbol", signal.Symbol, "signal", signal.Signal, "by", by)
+	return q.run(id, signal, true), nil
+}
+
+// Reject drops a pending signal.
+func (q *Queue) Reject(id, by, reason string) (Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.now()
+	q.expireLocked(now)
+	it, err := q.pendingLocked(id)
+	if err != nil {
+		return Item{}, err
+	}
+	it.Status = StatusRejected
+	it.DecidedAt = &now
+	it.DecidedBy = by
+	it.Reason = reason
+	q.saveLocked()
+	logger().Info("Signal rejected", "id", id, "symbol", it.Signal.Symbol, "by", by, "reason", reason)
+	return *it, nil
+}
+
+// run places an approved signal's order and records the outcome. A
+// manual approval re-checks the guard, since conditions may have changed
+// while the signal waited.
+func (q *Queue) run(id string, signal *algorithm.TradeSignal, recheck bool) Item {
+	q.mu.Lock()
+	guard, execute := q.guard, q.execute
+	q.mu.Unlock()
+
+	status, result, errText := StatusExecuted, "", ""
+	var err error
+	if recheck && guard != nil {
+		err = guard(signal)
+		if err != nil {
+			status = StatusBlocked
+		}
+	}
+	if err == nil {
+		if execute == nil {
+			err = errors.New("no executor configured")
+		} else {
+			result, err = execute(signal)
+		}
+		if err != nil {
+			status = StatusFailed
+		}
+	}
+	if err != nil {
+		errText = err.Error()
+		logger().Warn("Approved signal was not executed", "id", id, "symbol", signal.Symbol, "status", status, "error", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, it := range q.state.Items {
+		if it.ID == id {
+			it.Status, it.Result, it.Error = status, result, errText
+			q.saveLocked()
+			return *it
+		}
+	}
+	return Item{ID: id, Signal: signal, Status: status, Result: result, Error: errText}
+}
+
+// Get returns one item.
+func (q *Queue) Get(id string) (Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expireLocked(q.now())
+	for _, it := range q.state.Items {
+		if it.ID == id {
+			return *it, nil
+		}
+	}
+	return Item{}, ErrNotFound
+}
+
+// List returns items with status, or every item when status is empty,
+// newest first, at most limit of them when limit is positive.
+func (q *Queue) List(status string, limit int) []Item {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expireLocked(q.now())
+	out := []Item{}
+	for i := len(q.state.Items) - 1; i >= 0; i-- {
+		it := q.state.Items[i]
+		if status != "" && it.Status != status {
+			continue
+		}
+		out = append(out, *it)
+		if limit > 0 && len(out) >= limit {
+			break
+		}
+	}
+	return out
+}
+
+func (q *Queue) pendingLocked(id string) (*Item, error) {
+	for _, it := range q.state.Items {
+		if it.ID != id {
+			continue
+		}
+		if it.Status != StatusPending {
+			return nil, fmt.Errorf("%w: %s", ErrDecided, it.Status)
+		}
+		return it, nil
+	}
+	return nil, ErrNotFound
+}
+
+// expireLocked marks pending items past their TTL as expired.
+func (q *Queue) expireLocked(now time.Time) {
+	changed := false
+	for _, it := range q.state.Items {
+		if it.Status == StatusPending && !now.Before(it.ExpiresAt) {
+			it.Status = StatusExpired
+			at := it.ExpiresAt
+			it.DecidedAt = &at
+			changed = true
+			logger().Info("Signal expired unapproved", "id", it.ID, "symbol", it.Signal.Symbol)
+		}
+	}
+	if changed {
+		q.saveLocked()
+	}
+}
+
+// trimLocked drops the oldest decided items beyond maxDecided.
+func (q *Queue) trimLocked() {
+	decided := 0
+	for _, it := range q.state.Items {
+		if it.Status != StatusPending && it.Status != StatusApproved {
+			decided++
+		}
+	}
+	if decided <= maxDecided {
+		return
+	}
+	drop := decided - maxDecided
+	kept := q.state.Items[:0]
+	for _, it := range q.state.Items {
+		if drop > 0 && it.Status != StatusPending && it.Status != StatusApproved {
+			drop--
+			continue
+		}
+		kept = append(kept, it)
+	}
+	q.state.Items = kept
+}
+
+func (q *Queue) saveLocked() {
+	data, err := json.MarshalIndent(q.state, "", "  ")
+	if err != nil {
+		logger().Error("Failed to encode approval queue", "error", err)
+		return
+	}
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		logger().Error("Failed to write approval queue", "error", err)
+		return
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		logger().Error("Failed to save approval queue", "error", err)
+	}
+}
@@ -0,0 +1,141 @@
+package approvals
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+	"github.com/rileyseaburg/go-trader/users"
+)
+
+func TestQueueApprovesRejectsAndExpires(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	q, err := New(path, DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	q.SetClock(func() time.Time { return now })
+	blocked := map[string]bool{"TSLA": true}
+	q.SetGuard(func(s *algorithm.TradeSignal) error {
+		if blocked[s.Symbol] {
+			return errors.New("symbol paused")
+		}
+		return nil
+	})
+	var executed []string
+	q.SetExecutor(func(s *algorithm.TradeSignal) (string, error) {
+		executed = append(executed, s.Symbol)
+		return "placed " + s.Symbol, nil
+	})
+	var notified []string
+	q.SetNotifier(func(it Item) { notified = append(notified, it.Status) })
+
+	submit := func(symbol, source string) Item {
+		t.Helper()
+		it, err := q.Submit(&algorithm.TradeSignal{Symbol: symbol, Signal: algorithm.SignalBuy, OrderType: "market", Source: source})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return it
+	}
+
+	aapl := submit("AAPL", "tradingview")
+	if aapl.Status != StatusPending || len(executed) != 0 {
+		t.Fatalf("aapl = %+v, executed %v", aapl, executed)
+	}
+	if it := submit("TSLA", "tradingview"); it.Status != StatusBlocked || it.Error != "symbol paused" {
+		t.Fatalf("tsla = %+v", it)
+	}
+
+	// Conditions change while the signal waits
+	blocked["AAPL"] = true
+	if it, _ := q.Approve(aapl.ID, "ops"); it.Status != StatusBlocked || len(executed) != 0 {
+		t.Fatalf("approve while paused = %+v", it)
+	}
+	if _, err := q.Approve(aapl.ID, "ops"); !errors.Is(err, ErrDecided) {
+		t.Fatalf("second approve: %v", err)
+	}
+
+	msft := submit("MSFT", "tradingview")
+	if it, err := q.Approve(msft.ID, "ops"); err != nil || it.Status != StatusExecuted || it.Result != "placed MSFT" || it.DecidedBy != "ops" {
+		t.Fatalf("approve = %+v, %v", it, err)
+	}
+	nvda := submit("NVDA", "tradingview")
+	if it, err := q.Reject(nvda.ID, "ops", "chasing"); err != nil || it.Status != StatusRejected || it.Reason != "chasing" {
+		t.Fatalf("reject = %+v, %v", it, err)
+	}
+
+	amd := submit("AMD", "tradingview")
+	now = now.Add(15 * time.Minute)
+	if it, _ := q.Get(amd.ID); it.Status != StatusExpired {
+		t.Fatalf("amd after the TTL = %+v", it)
+	}
+	if _, err := q.Approve(amd.ID, "ops"); !errors.Is(err, ErrDecided) {
+		t.Fatalf("approve expired: %v", err)
+	}
+
+	// A trusted source skips review
+	if err := q.SetPolicy(Policy{TTLMinutes: 5, AutoApprove: []string{"TradingView"}}); err != nil {
+		t.Fatal(err)
+	}
+	if it := submit("META", "tradingview"); it.Status != StatusExecuted || it.DecidedBy != DecidedByAuto {
+		t.Fatalf("auto-approved = %+v", it)
+	}
+	if len(executed) != 2 || executed[1] != "META" {
+		t.Fatalf("executed = %v", executed)
+	}
+	if len(notified) != 6 || notified[1] != StatusBlocked || notified[5] != StatusApproved {
+		t.Fatalf("notified = %v", notified)
+	}
+	if got := q.List(StatusExecuted, 0); len(got) != 2 || got[0].Signal.Symbol != "META" {
+		t.Fatalf("executed list = %+v", got)
+	}
+
+	// The queue and policy survive a restart
+	pending := submit("AMZN", "manual")
+	q, err = New(path, DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.SetClock(func() time.Time { return now })
+	if p := q.Policy(); p.TTLMinutes != 5 || len(p.AutoApprove) != 1 {
+		t.Fatalf("policy = %+v", p)
+	}
+	if got := q.List(StatusPending, 0); len(got) != 1 || got[0].ID != pending.ID {
+		t.Fatalf("pending after restart = %+v", got)
+	}
+	if it := submit("GOOG", "manual"); it.ID == pending.ID {
+		t.Fatalf("ID %s reused after restart", it.ID)
+	}
+	if len(q.List("", 0)) != 8 {
+		t.Fatalf("items after restart = %d", len(q.List("", 0)))
+	}
+}
+
+func TestOnlyAdminsChangePolicy(t *testing.T) {
+	q, err := New(filepath.Join(t.TempDir(), "approvals.json"), DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	NewHandler(q).RegisterRoutes(mux)
+
+	trader := users.WithUser(context.Background(), users.User{ID: "trader", Role: users.RoleUser})
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/approvals/policy", strings.NewReader(`{}`)).WithContext(trader))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("user POST = %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/approvals/policy", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusOK {
+		t.Errorf("admin POST = %d", rec.Code)
+	}
+}
@@ -0,0 +1,153 @@
+package approvals
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/rileyseaburg/go-trader/paging"
+	"github.com/rileyseaburg/go-trader/users"
+)
+
+// Handler exposes the approval queue over HTTP.
+type Handler struct {
+	queue *Queue
+}
+
+// NewHandler creates a handler for queue.
+func NewHandler(queue *Queue) *Handler {
+	return &Handler{queue: queue}
+}
+
+// RegisterRoutes registers the approval routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/approvals?status=&limit=&cursor=&sort=&filter= - queued signals and what became of them, newest first
+	mux.HandleFunc("/api/approvals", h.handleList)
+
+	// GET /api/approvals/{id} - one queued signal
+	// POST /api/approvals/{id}/approve - execute a pending signal, {"by"} optional
+	// POST /api/approvals/{id}/reject - drop a pending signal, {"by", "reason"} optional
+	mux.HandleFunc("/api/approvals/", h.handleItem)
+
+	// GET/POST /api/approvals/policy - read or update the TTL and auto-approved sources
+	mux.HandleFunc("/api/approvals/policy", h.handlePolicy)
+}
+
+// itemList pages the queue, newest first by default.
+var itemList = paging.List[Item]{
+	Fields: []paging.Field[Item]{
+		{Name: "created_at", Sort: func(it Item) paging.Key { return paging.Time(it.CreatedAt) }},
+		{Name: "symbol", Sort: func(it Item) paging.Key { return paging.Text(it.Signal.Symbol) }, Filter: func(it Item) string { return it.Signal.Symbol }},
+		{Name: "source", Filter: func(it Item) string { return it.Signal.Source }},
+		{Name: "status", Filter: func(it Item) string { return it.Status }},
+		{Name: "decided_by", Filter: func(it Item) string { return it.DecidedBy }},
+	},
+	ID:   func(it Item) string { return it.ID },
+	Sort: "-created_at",
+}
+
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	itemList.Serve(w, r, h.queue.List(r.URL.Query().Get("status"), 0))
+}
+
+func (h *Handler) handleItem(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/approvals/"), "/")
+	if id == "" || (action != "" && action != "approve" && action != "reject") {
+		http.NotFound(w, r)
+		return
+	}
+	if action == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		item, err := h.queue.Get(id)
+		if err != nil {
+			http.Error(w, "Approval not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(item)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		By     string `json:"by"`
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	if req.By == "" {
+		req.By = r.RemoteAddr
+	}
+	var (
+		item Item
+		err  error
+	)
+	if action == "approve" {
+		item, err = h.queue.Approve(id, req.By)
+	} else {
+		item, err = h.queue.Reject(id, req.By, req.Reason)
+	}
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "Approval not found", http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(StatusCode(item))
+	json.NewEncoder(w).Encode(item)
+}
+
+// StatusCode is the HTTP status reporting item: a guard refusal is a
+// conflict and a failed execution unprocessable.
+func StatusCode(item Item) int {
+	switch item.Status {
+	case StatusPending:
+		return http.StatusAccepted
+	case StatusBlocked:
+		return http.StatusConflict
+	case StatusFailed:
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusOK
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.queue.Policy())
+	case http.MethodPost, http.MethodPut:
+		if !users.FromContext(r.Context()).IsAdmin() {
+			http.Error(w, users.ErrForbidden.Error(), http.StatusForbidden)
+			return
+		}
+		// Start from the current policy so partial updates work
+		policy := h.queue.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.queue.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(h.queue.Policy())
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,264 @@
+// Package audit records every API request — who made it, what it touched,
+// how it ended and how long it took — to a size-rotated JSONL log, and
+// keeps the most recent entries in memory for querying. Requests that can
+// move money are flagged so they stand out in compliance reviews.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+func logger() *slog.Logger { return slog.With("module", "audit") }
+
+// Defaults for rotation and the in-memory window.
+const (
+	DefaultMaxBytes = 10 << 20 // rotate the active file past 10 MiB
+	DefaultMaxFiles = 5        // rotated files kept alongside the active one
+	DefaultRecent   = 1000     // entries kept in memory for Query
+	DefaultLimit    = 100
+)
+
+// TradingPaths are endpoints whose mutating requests can place, cancel or
+// resize orders or change how the system trades. A trailing slash matches
+// the whole subtree.
+var TradingPaths = []string{
+	"/api/executeTrade",
+	"/api/algorithm/start",
+	"/api/algorithm/stop",
+	"/api/baskets/trade/",
+	"/api/algorithms/execute",
+	"/api/algorithms/configure",
+	"/api/algorithms/instances",
+	"/api/algorithms/instances/",
+	"/api/risk-parameters",
+	"/api/risk/volatility/trim",
+	"/api/gaps/policy",
+	"/api/gaps/resume",
+	"/api/calendar/earnings/policy",
+	"/api/risk/drawdown/policy",
+	"/api/risk/drawdown/override",
+	"/api/symbols/",
+	"/api/orders/execution",
+	"/api/execution/",
+	"/api/settings/manual-control",
+	"/api/signals/reject",
+	"/api/approvals/",
+	"/api/confirmations/",
+	"/api/webhooks/tradingview",
+	"/api/chatops/",
+	"/api/scheduler/run",
+}
+
+// Entry is one audited request.
+type Entry struct {
+	Time       time.Time `json:"time"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	Query      string    `json:"query,omitempty"`
+	Caller     string    `json:"caller,omitempty"`
+	RemoteAddr string    `json:"remote_addr"`
+	BodySHA256 string    `json:"body_sha256,omitempty"` // mutations only
+	Status     int       `json:"status"`
+	LatencyMS  float64   `json:"latency_ms"`
+	Mutation   bool      `json:"mutation"`
+	Trading    bool      `json:"trading"` // mutation of a TradingPaths endpoint
+}
+
+// Filter selects entries from the recent window. Zero values match
+// everything.
+type Filter struct {
+	Method      string
+	PathPrefix  string
+	Caller      string
+	TradingOnly bool
+	MinStatus   int
+	Since       time.Time
+	Limit       int
+}
+
+// Log is the audit sink. It is safe for concurrent use.
+type Log struct {
+	path     string
+	maxBytes int64
+	maxFiles int
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	recent []Entry // ring buffer, oldest first once full
+	next   int
+	full   bool
+}
+
+// Open opens (or creates) the active audit file at path and loads its tail
+// into the recent window.
+func Open(path string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+	l := &Log{
+		path:     path,
+		maxBytes: DefaultMaxBytes,
+		maxFiles: DefaultMaxFiles,
+		recent:   make([]Entry, DefaultRecent),
+	}
+
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var e Entry
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				logger().Warn("Skipping malformed audit line", "error", err)
+				continue
+			}
+			l.remember(e)
+		}
+		f.Close()
+	}
+
+	if err := l.openActive(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// SetRotation overrides the rotation size and the number of files kept.
+func (l *Log) SetRotation(maxBytes int64, maxFiles int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if maxBytes > 0 {
+		l.maxBytes = maxBytes
+	}
+	if maxFiles > 0 {
+		l.maxFiles = maxFiles
+	}
+}
+
+func (l *Log) openActive() error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to stat audit log: %w", err)
+	}
+	l.file, l.size = f, info.Size()
+	return nil
+}
+
+// rotate shifts audit.log → audit.log.1 → … → audit.log.N, dropping the
+// oldest. Caller holds l.mu.
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	os.Remove(fmt.Sprintf("%s.%d", l.path, l.maxFiles))
+	for i := l.maxFiles - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1))
+	}
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return err
+	}
+	return l.openActive()
+}
+
+// Record appends e to the log, rotating first if the active file is full.
+// Write failures are logged rather than returned so auditing never fails
+// a request.
+func (l *Log) Record(e Entry) {
+	line, err := json.Marshal(e)
+	if err != nil {
+		logger().Error("Failed to encode audit entry", "error", err)
+		return
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.remember(e)
+	if l.size+int64(len(line)) > l.maxBytes && l.size > 0 {
+		if err := l.rotate(); err != nil {
+			logger().Error("Failed to rotate audit log", "error", err)
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		logger().Error("Failed to write audit entry", "error", err)
+	}
+}
+
+func (l *Log) remember(e Entry) {
+	l.recent[l.next] = e
+	l.next = (l.next + 1) % len(l.recent)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Query returns matching entries from the recent window, newest first.
+func (l *Log) Query(f Filter) []Entry {
+	if f.Limit <= 0 {
+		f.Limit = DefaultLimit
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	count := l.next
+	if l.full {
+		count = len(l.recent)
+	}
+	out := []Entry{}
+	for i := 0; i < count && len(out) < f.Limit; i++ {
+		idx := (l.next - 1 - i + len(l.recent)) % len(l.recent)
+		e := l.recent[idx]
+		if f.Method != "" && !strings.EqualFold(e.Method, f.Method) {
+			continue
+		}
+		if f.PathPrefix != "" && !strings.HasPrefix(e.Path, f.PathPrefix) {
+			continue
+		}
+		if f.Caller != "" && e.Caller != f.Caller {
+			continue
+		}
+		if f.TradingOnly && !e.Trading {
+			continue
+		}
+		if f.MinStatus > 0 && e.Status < f.MinStatus {
+			continue
+		}
+		if !f.Since.IsZero() && e.Time.Before(f.Since) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// Close closes the active file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// IsTrading reports whether path is one of TradingPaths.
+func IsTrading(path string) bool {
+	for _, p := range TradingPaths {
+		if path == p || (strings.HasSuffix(p, "/") && strings.HasPrefix(path, p)) {
+			return true
+		}
+	}
+	return false
+}
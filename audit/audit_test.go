@@ -0,0 +1,99 @@
+package audit
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareRecordsAndFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit", "audit.log")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	var seenBody string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/executeTrade", func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		seenBody = string(b)
+		w.WriteHeader(http.StatusConflict)
+	})
+	mux.HandleFunc("/api/account", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	})
+	h := l.Middleware(mux, func(r *http.Request) string { return r.Header.Get("X-User") })
+
+	req := httptest.NewRequest(http.MethodPost, "/api/executeTrade", strings.NewReader(`{"symbol":"AAPL"}`))
+	req.Header.Set("X-User", "riley")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/account", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ws", nil))
+
+	if seenBody != `{"symbol":"AAPL"}` {
+		t.Errorf("handler saw body %q", seenBody)
+	}
+
+	all := l.Query(Filter{})
+	if len(all) != 2 || all[0].Path != "/api/account" {
+		t.Fatalf("entries = %+v", all)
+	}
+	trade := all[1]
+	if !trade.Trading || !trade.Mutation || trade.Status != http.StatusConflict || trade.Caller != "riley" || len(trade.BodySHA256) != 64 {
+		t.Errorf("trade entry = %+v", trade)
+	}
+	if all[0].Trading || all[0].BodySHA256 != "" || all[0].Status != http.StatusOK {
+		t.Errorf("read entry = %+v", all[0])
+	}
+
+	if got := l.Query(Filter{TradingOnly: true}); len(got) != 1 {
+		t.Errorf("trading filter returned %d", len(got))
+	}
+
+	l.Close()
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if got := reopened.Query(Filter{}); len(got) != 2 {
+		t.Errorf("reloaded %d entries", len(got))
+	}
+}
+
+func TestRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.SetRotation(200, 2)
+
+	for i := 0; i < 10; i++ {
+		l.Record(Entry{Method: "GET", Path: "/api/account", Status: 200})
+	}
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("expected %s: %v", p, err)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("kept more rotated files than configured")
+	}
+	if got := l.Query(Filter{Limit: 50}); len(got) != 10 {
+		t.Errorf("recent window has %d entries", len(got))
+	}
+}
+
+func TestIsTrading(t *testing.T) {
+	if !IsTrading("/api/baskets/trade/tech") || IsTrading("/api/baskets") || !IsTrading("/api/executeTrade") {
+		t.Error("trading path matching is wrong")
+	}
+}
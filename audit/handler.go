@@ -0,0 +1,86 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/paging"
+)
+
+// Handler serves recent audit entries over HTTP.
+type Handler struct {
+	log *Log
+}
+
+// NewHandler creates a handler for l.
+func NewHandler(l *Log) *Handler {
+	return &Handler{log: l}
+}
+
+// RegisterRoutes registers the audit route with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/audit?method=&path=&caller=&trading=true&min_status=&since=&limit=&cursor=&sort=&filter=
+	mux.HandleFunc("/api/audit", h.handleAudit)
+}
+
+// entryList pages the recent entries, newest first by default.
+var entryList = paging.List[Entry]{
+	Fields: []paging.Field[Entry]{
+		{Name: "time", Sort: func(e Entry) paging.Key { return paging.Time(e.Time) }},
+		{Name: "latency_ms", Sort: func(e Entry) paging.Key { return paging.Number(e.LatencyMS) }},
+		{Name: "status", Sort: func(e Entry) paging.Key { return paging.Number(float64(e.Status)) }, Filter: func(e Entry) string { return strconv.Itoa(e.Status) }},
+		{Name: "method", Filter: func(e Entry) string { return e.Method }},
+		{Name: "caller", Filter: func(e Entry) string { return e.Caller }},
+		{Name: "trading", Filter: func(e Entry) string { return strconv.FormatBool(e.Trading) }},
+	},
+	ID: func(e Entry) string {
+		return e.Time.UTC().Format(time.RFC3339Nano) + "/" + e.Method + "/" + e.Path + "/" + e.RemoteAddr
+	},
+	Sort: "-time",
+}
+
+func (h *Handler) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	f := Filter{
+		Method:      params.Get("method"),
+		PathPrefix:  params.Get("path"),
+		Caller:      params.Get("caller"),
+		TradingOnly: params.Get("trading") == "true",
+		Limit:       DefaultRecent,
+	}
+	var err error
+	if v := params.Get("min_status"); v != "" {
+		if f.MinStatus, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "min_status must be an integer", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := params.Get("since"); v != "" {
+		if f.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "since must be RFC3339", http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, ok := entryList.Page(w, r, h.log.Query(f))
+	if !ok {
+		return
+	}
+	paging.SetHeaders(w, page.Total, page.NextCursor)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"entries": page.Items,
+		"count":   len(page.Items),
+	})
+}
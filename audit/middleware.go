@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CallerFunc identifies who made a request, e.g. from an auth token.
+type CallerFunc func(r *http.Request) string
+
+// Middleware audits /api/ requests handled by next. Other paths, such as
+// the WebSocket feed and static files, pass through untouched.
+func (l *Log) Middleware(next http.Handler, caller CallerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		e := Entry{
+			Time:       start,
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Query:      r.URL.RawQuery,
+			RemoteAddr: r.RemoteAddr,
+			Mutation:   isMutation(r.Method),
+		}
+		e.Trading = e.Mutation && IsTrading(r.URL.Path)
+
+		if e.Mutation && r.Body != nil {
+			body, err := io.ReadAll(r.Body)
+			r.Body.Close()
+			if err == nil {
+				sum := sha256.Sum256(body)
+				e.BodySHA256 = hex.EncodeToString(sum[:])
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		// Resolve the caller after the handler so auth layers can annotate
+		// the request on the way through.
+		if caller != nil {
+			e.Caller = caller(r)
+		}
+		e.Status = rec.status
+		e.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+		l.Record(e)
+	})
+}
+
+func isMutation(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// statusRecorder captures the response status while still supporting
+// streaming and connection upgrades.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if !s.wroteHeader {
+		s.status, s.wroteHeader = code, true
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(b)
+}
+
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	s.status, s.wroteHeader = http.StatusSwitchingProtocols, true
+	return h.Hijack()
+}
@@ -0,0 +1,36 @@
+package breadth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+)
+
+// Alpaca reads daily bars from Alpaca market data.
+type Alpaca struct {
+	Client *marketdata.Client
+}
+
+// DailyBars returns each symbol's daily bars between start and end.
+func (a Alpaca) DailyBars(ctx context.Context, symbols []string, start, end time.Time) (map[string][]Bar, error) {
+	bars, err := a.Client.GetMultiBars(symbols, marketdata.GetBarsRequest{
+		TimeFrame: marketdata.OneDay,
+		Start:     start,
+		End:       end,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch daily bars: %w", err)
+	}
+	out := make(map[string][]Bar, len(bars))
+	for sym, series := range bars {
+		converted := make([]Bar, len(series))
+		for i, b := range series {
+			converted[i] = Bar{Time: b.Timestamp, High: b.High, Low: b.Low, Close: b.Close}
+		}
+		out[strings.ToUpper(sym)] = converted
+	}
+	return out, nil
+}
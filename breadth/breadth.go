@@ -0,0 +1,417 @@
+// Package breadth measures the health of the market as a whole from the
+// daily bars of an index universe: how many members advanced and declined,
+// the share trading above their moving average, and how many set new
+// highs or lows. Snapshots are refreshed periodically and feed the Claude
+// context and the regime multiplier, so single-symbol decisions account for
+// the market around them.
+package breadth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+func logger() *slog.Logger { return slog.With("module", "breadth") }
+
+// Health classifications.
+const (
+	HealthStrong  = "strong"
+	HealthNeutral = "neutral"
+	HealthWeak    = "weak"
+)
+
+// maxUniverse bounds the universe so a refresh stays a handful of requests.
+const maxUniverse = 500
+
+// Bar is one daily bar of a universe member.
+type Bar struct {
+	Time  time.Time
+	High  float64
+	Low   float64
+	Close float64
+}
+
+// Source fetches daily bars.
+type Source interface {
+	// DailyBars returns the daily bars of each of symbols between start and
+	// end, oldest first. Symbols without bars may be missing.
+	DailyBars(ctx context.Context, symbols []string, start, end time.Time) (map[string][]Bar, error)
+}
+
+// Policy configures the universe and the measures.
+type Policy struct {
+	// Universe is the index whose members are measured
+	Universe []string `json:"universe"`
+	// UniverseName names a universe file whose constituents, when it
+	// exists, are measured in place of Universe
+	UniverseName   string `json:"universe_name,omitempty"`
+	RefreshMinutes int    `json:"refresh_minutes"`
+	// MovingAverage is the simple moving average, in sessions, members are
+	// compared with
+	MovingAverage int `json:"moving_average"`
+	// HighLowLookback is the sessions a new high or low must exceed
+	HighLowLookback int `json:"high_low_lookback"`
+	// WeakScore is the score at or below which breadth is weak, and its
+	// negative the score at or above which it is strong
+	WeakScore float64 `json:"weak_score"`
+	// WeakMultiplier scales position sizes while breadth is weak
+	WeakMultiplier float64 `json:"weak_multiplier"`
+}
+
+// DefaultUniverse is the Dow Jones Industrial Average.
+var DefaultUniverse = []string{
+	"AAPL", "AMGN", "AMZN", "AXP", "BA", "CAT", "CRM", "CSCO", "CVX", "DIS",
+	"GS", "HD", "HON", "IBM", "JNJ", "JPM", "KO", "MCD", "MMM", "MRK",
+	"MSFT", "NKE", "NVDA", "PG", "SHW", "TRV", "UNH", "V", "VZ", "WMT",
+}
+
+// DefaultPolicy measures the Dow, as the dow universe file lists it, every
+// 30 minutes against the 50-day average and 52-week highs and lows, and
+// trims sizes by a quarter while breadth is weak.
+func DefaultPolicy() Policy {
+	return Policy{
+		Universe:        append([]string(nil), DefaultUniverse...),
+		UniverseName:    "dow",
+		RefreshMinutes:  30,
+		MovingAverage:   50,
+		HighLowLookback: 252,
+		WeakScore:       -0.3,
+		WeakMultiplier:  0.75,
+	}
+}
+
+// Validate checks the policy for internally consistent values.
+func (p Policy) Validate() error {
+	if n := len(normalize(p.Universe)); (n == 0 && p.UniverseName == "") || n > maxUniverse {
+		return fmt.Errorf("universe must have between 1 and %d symbols", maxUniverse)
+	}
+	if p.RefreshMinutes < 1 || p.RefreshMinutes > 24*60 {
+		return errors.New("refresh_minutes must be between 1 and 1440")
+	}
+	if p.MovingAverage < 2 || p.MovingAverage > 200 {
+		return errors.New("moving_average must be between 2 and 200")
+	}
+	if p.HighLowLookback < 20 || p.HighLowLookback > 504 {
+		return errors.New("high_low_lookback must be between 20 and 504")
+	}
+	if p.WeakScore <= -1 || p.WeakScore >= 0 {
+		return errors.New("weak_score must be between -1 and 0")
+	}
+	if p.WeakMultiplier <= 0 || p.WeakMultiplier > 1 {
+		return errors.New("weak_multiplier must be above 0 and at most 1")
+	}
+	return nil
+}
+
+// normalize upper-cases symbols and drops blanks and duplicates.
+func normalize(symbols []string) []string {
+	seen := make(map[string]bool, len(symbols))
+	out := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		if s != "" && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Snapshot is the breadth of the universe on its latest session.
+type Snapshot struct {
+	AsOf     time.Time `json:"as_of"`    // the latest session's bar
+	Universe int       `json:"universe"` // members configured
+	Counted  int       `json:"counted"`  // members with a bar on the latest session
+	// Missing lists members without a bar on the latest session
+	Missing   []string `json:"missing,omitempty"`
+	Advancers int      `json:"advancers"`
+	Decliners int      `json:"decliners"`
+	Unchanged int      `json:"unchanged"`
+	// AdvanceDeclineRatio is advancers over decliners, zero without
+	// decliners
+	AdvanceDeclineRatio float64 `json:"advance_decline_ratio"`
+	AboveMA             int     `json:"above_ma"`
+	PercentAboveMA      float64 `json:"percent_above_ma"` // of members with enough history
+	NewHighs            int     `json:"new_highs"`
+	NewLows             int     `json:"new_lows"`
+	// HighLowIndex is new highs as a percentage of new highs and lows, 50
+	// when there are neither
+	HighLowIndex float64 `json:"high_low_index"`
+	// Score averages the advance-decline spread, the share above the
+	// average and the high-low index, each scaled to [-1, 1]
+	Score      float64   `json:"score"`
+	Health     string    `json:"health"`     // strong, neutral or weak
+	Multiplier float64   `json:"multiplier"` // position size scalar the health implies
+	ComputedAt time.Time `json:"computed_at"`
+}
+
+// Compute measures the breadth of the policy's universe from bars, keyed
+// by symbol and oldest first. Only members with a bar on the latest
+// session any member has are counted.
+func Compute(bars map[string][]Bar, p Policy, now time.Time) (Snapshot, error) {
+	universe := normalize(p.Universe)
+	snap := Snapshot{Universe: len(universe), ComputedAt: now}
+	for _, symbol := range universe {
+		if series := bars[symbol]; len(series) > 0 && series[len(series)-1].Time.After(snap.AsOf) {
+			snap.AsOf = series[len(series)-1].Time
+		}
+	}
+	if snap.AsOf.IsZero() {
+		return Snapshot{}, errors.New("no bars for any member of the universe")
+	}
+
+	maCounted, hlCounted := 0, 0
+	for _, symbol := range universe {
+		series := bars[symbol]
+		if len(series) < 2 || !series[len(series)-1].Time.Equal(snap.AsOf) {
+			snap.Missing = append(snap.Missing, symbol)
+			continue
+		}
+		snap.Counted++
+		last := series[len(series)-1]
+		switch prev := series[len(series)-2].Close; {
+		case last.Close > prev:
+			snap.Advancers++
+		case last.Close < prev:
+			snap.Decliners++
+		default:
+			snap.Unchanged++
+		}
+
+		if len(series) >= p.MovingAverage {
+			maCounted++
+			sum := 0.0
+			for _, b := range series[len(series)-p.MovingAverage:] {
+				sum += b.Close
+			}
+			if last.Close > sum/float64(p.MovingAverage) {
+				snap.AboveMA++
+			}
+		}
+
+		if len(series) >= p.HighLowLookback {
+			hlCounted++
+			high, low := math.Inf(-1), math.Inf(1)
+			for _, b := range series[len(series)-p.HighLowLookback : len(series)-1] {
+				h, l := barRange(b)
+				high, low = math.Max(high, h), math.Min(low, l)
+			}
+			h, l := barRange(last)
+			if h > high {
+				snap.NewHighs++
+			}
+			if l < low {
+				snap.NewLows++
+			}
+		}
+	}
+	if snap.Counted == 0 {
+		return Snapshot{}, errors.New("no member of the universe has two sessions of bars")
+	}
+
+	if snap.Decliners > 0 {
+		snap.AdvanceDeclineRatio = float64(snap.Advancers) / float64(snap.Decliners)
+	}
+	components := []float64{float64(snap.Advancers-snap.Decliners) / float64(snap.Counted)}
+	if maCounted > 0 {
+		snap.PercentAboveMA = 100 * float64(snap.AboveMA) / float64(maCounted)
+		components = append(components, snap.PercentAboveMA/50-1)
+	}
+	snap.HighLowIndex = 50
+	if extremes := snap.NewHighs + snap.NewLows; extremes > 0 {
+		snap.HighLowIndex = 100 * float64(snap.NewHighs) / float64(extremes)
+	}
+	if hlCounted > 0 {
+		components = append(components, snap.HighLowIndex/50-1)
+	}
+	for _, c := range components {
+		snap.Score += c
+	}
+	snap.Score /= float64(len(components))
+
+	snap.Health, snap.Multiplier = HealthNeutral, 1
+	switch {
+	case snap.Score <= p.WeakScore:
+		snap.Health, snap.Multiplier = HealthWeak, p.WeakMultiplier
+	case snap.Score >= -p.WeakScore:
+		snap.Health = HealthStrong
+	}
+	return snap, nil
+}
+
+// barRange is the bar's high and low, or its close where they are unset.
+func barRange(b Bar) (float64, float64) {
+	high, low := b.High, b.Low
+	if high <= 0 {
+		high = b.Close
+	}
+	if low <= 0 {
+		low = b.Close
+	}
+	return high, low
+}
+
+// Monitor refreshes the breadth snapshot periodically. It is safe for
+// concurrent use.
+type Monitor struct {
+	source Source
+
+	mu        sync.Mutex
+	policy    Policy
+	universes func(name string) []string
+	latest    *Snapshot
+	lastErr   error
+	onUpdate  func(Snapshot)
+	now       func() time.Time
+}
+
+// New creates a monitor reading bars from source.
+func New(source Source, policy Policy) (*Monitor, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	policy.Universe = normalize(policy.Universe)
+	return &Monitor{source: source, policy: policy, now: time.Now}, nil
+}
+
+// SetClock replaces the clock, for tests.
+func (m *Monitor) SetClock(now func() time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = now
+}
+
+// SetUniverses sets how a policy's UniverseName is resolved to symbols.
+func (m *Monitor) SetUniverses(fn func(name string) []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.universes = fn
+}
+
+// SetOnUpdate registers fn to receive every new snapshot.
+func (m *Monitor) SetOnUpdate(fn func(Snapshot)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onUpdate = fn
+}
+
+// Policy returns the current policy.
+func (m *Monitor) Policy() Policy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p := m.policy
+	p.Universe = append([]string(nil), p.Universe...)
+	return p
+}
+
+// SetPolicy validates and replaces the policy. The next refresh measures
+// the new universe.
+func (m *Monitor) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	p.Universe = normalize(p.Universe)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policy = p
+	return nil
+}
+
+// Refresh fetches the universe's bars and computes a new snapshot. On
+// failure the last snapshot is kept.
+func (m *Monitor) Refresh(ctx context.Context) (Snapshot, error) {
+	if m.source == nil {
+		return Snapshot{}, errors.New("refresh market breadth: no bar source configured")
+	}
+	m.mu.Lock()
+	policy, now, universes := m.policy, m.now(), m.universes
+	m.mu.Unlock()
+	if policy.UniverseName != "" && universes != nil {
+		if named := normalize(universes(policy.UniverseName)); len(named) > maxUniverse {
+			return Snapshot{}, fmt.Errorf("refresh market breadth: universe %s has %d symbols, more than %d", policy.UniverseName, len(named), maxUniverse)
+		} else if len(named) > 0 {
+			policy.Universe = named
+		}
+	}
+	if len(policy.Universe) == 0 {
+		return Snapshot{}, fmt.Errorf("refresh market breadth: universe %s not found", policy.UniverseName)
+	}
+
+	// Enough calendar days for the longer window, with room for holidays
+	sessions := policy.HighLowLookback
+	if policy.MovingAverage > sessions {
+		sessions = policy.MovingAverage
+	}
+	start := now.AddDate(0, 0, -(sessions*7/5 + 10))
+	bars, err := m.source.DailyBars(ctx, policy.Universe, start, now)
+	if err == nil {
+		var snap Snapshot
+		if snap, err = Compute(bars, policy, now); err == nil {
+			m.mu.Lock()
+			m.latest, m.lastErr = &snap, nil
+			onUpdate := m.onUpdate
+			m.mu.Unlock()
+			if onUpdate != nil {
+				onUpdate(snap)
+			}
+			return snap, nil
+		}
+	}
+	m.mu.Lock()
+	m.lastErr = err
+	m.mu.Unlock()
+	return Snapshot{}, fmt.Errorf("refresh market breadth: %w", err)
+}
+
+// Latest returns the last snapshot. Snapshots older than four refresh
+// intervals are not returned, so a source that stopped answering does not
+// keep feeding a stale picture into decisions.
+func (m *Monitor) Latest() (Snapshot, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.latest == nil || m.now().Sub(m.latest.ComputedAt) > 4*time.Duration(m.policy.RefreshMinutes)*time.Minute {
+		return Snapshot{}, false
+	}
+	return *m.latest, true
+}
+
+// Status is the last snapshot, fresh or not, with the last refresh error.
+type Status struct {
+	Snapshot  *Snapshot `json:"snapshot"`
+	Stale     bool      `json:"stale"`
+	LastError string    `json:"last_error,omitempty"`
+	Policy    Policy    `json:"policy"`
+}
+
+// Status reports the last snapshot and refresh error.
+func (m *Monitor) Status() Status {
+	_, fresh := m.Latest()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := Status{Snapshot: m.latest, Stale: m.latest != nil && !fresh, Policy: m.policy}
+	s.Policy.Universe = append([]string(nil), m.policy.Universe...)
+	if m.lastErr != nil {
+		s.LastError = m.lastErr.Error()
+	}
+	return s
+}
+
+// Run refreshes the snapshot every refresh interval until ctx is done.
+func (m *Monitor) Run(ctx context.Context) {
+	for {
+		if _, err := m.Refresh(ctx); err != nil && ctx.Err() == nil {
+			logger().Warn("Market breadth refresh failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(m.Policy().RefreshMinutes) * time.Minute):
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package breadth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+var day0 = time.Date(2025, 1, 2, 5, 0, 0, 0, time.UTC)
+
+// trend is n daily bars closing at start and moving by step a session,
+// trading 1 either side of the close.
+func trend(n int, start, step float64) []Bar {
+	bars := make([]Bar, n)
+	for i := range bars {
+		c := start + step*float64(i)
+		bars[i] = Bar{Time: day0.AddDate(0, 0, i), High: c + 1, Low: c - 1, Close: c}
+	}
+	return bars
+}
+
+func testPolicy(universe ...string) Policy {
+	p := DefaultPolicy()
+	p.Universe = universe
+	p.UniverseName = ""
+	p.MovingAverage = 10
+	p.HighLowLookback = 20
+	return p
+}
+
+func TestCompute(t *testing.T) {
+	bars := map[string][]Bar{
+		"UP":   trend(30, 100, 1),
+		"UP2":  trend(30, 50, 0.5),
+		"DOWN": trend(30, 100, -1),
+		"FLAT": trend(30, 100, 0),
+		// Stopped trading a session early
+		"HALT": trend(29, 100, 1),
+	}
+	snap, err := Compute(bars, testPolicy("up", "UP2", "DOWN", "FLAT", "HALT", "NONE"), day0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Universe != 6 || snap.Counted != 4 || len(snap.Missing) != 2 {
+		t.Errorf("universe %d, counted %d, missing %v", snap.Universe, snap.Counted, snap.Missing)
+	}
+	if snap.Advancers != 2 || snap.Decliners != 1 || snap.Unchanged != 1 || snap.AdvanceDeclineRatio != 2 {
+		t.Errorf("advance/decline = %+v", snap)
+	}
+	if snap.AboveMA != 2 || snap.PercentAboveMA != 50 {
+		t.Errorf("above MA %d (%v%%)", snap.AboveMA, snap.PercentAboveMA)
+	}
+	if snap.NewHighs != 2 || snap.NewLows != 1 {
+		t.Errorf("new highs %d, lows %d", snap.NewHighs, snap.NewLows)
+	}
+	// (2-1)/4, 50% above, 2 of 3 extremes highs
+	want := (0.25 + 0 + (200.0/3/50 - 1)) / 3
+	if d := snap.Score - want; d > 1e-12 || d < -1e-12 || snap.Health != HealthNeutral || snap.Multiplier != 1 {
+		t.Errorf("score %v (want %v), health %s, multiplier %v", snap.Score, want, snap.Health, snap.Multiplier)
+	}
+
+	weak, err := Compute(map[string][]Bar{"A": trend(30, 100, -1), "B": trend(30, 90, -2)}, testPolicy("A", "B"), day0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if weak.Score != -1 || weak.Health != HealthWeak || weak.Multiplier != 0.75 {
+		t.Errorf("falling market: score %v, health %s, multiplier %v", weak.Score, weak.Health, weak.Multiplier)
+	}
+
+	if _, err := Compute(map[string][]Bar{}, testPolicy("A"), day0); err == nil {
+		t.Error("computed breadth without bars")
+	}
+}
+
+type fakeSource struct {
+	bars map[string][]Bar
+	err  error
+	got  []string
+}
+
+func (f *fakeSource) DailyBars(_ context.Context, symbols []string, _, _ time.Time) (map[string][]Bar, error) {
+	f.got = symbols
+	return f.bars, f.err
+}
+
+func TestMonitorRefresh(t *testing.T) {
+	src := &fakeSource{bars: map[string][]Bar{"A": trend(30, 100, 1)}}
+	m, err := New(src, testPolicy("a", "A"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := day0.AddDate(0, 1, 0)
+	m.SetClock(func() time.Time { return now })
+	var updates int
+	m.SetOnUpdate(func(Snapshot) { updates++ })
+
+	if _, ok := m.Latest(); ok {
+		t.Error("snapshot before the first refresh")
+	}
+	if _, err := m.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if snap, ok := m.Latest(); !ok || snap.Advancers != 1 || updates != 1 || len(src.got) != 1 {
+		t.Errorf("snapshot %+v, updates %d, fetched %v", snap, updates, src.got)
+	}
+
+	// A failed refresh keeps the last snapshot until it goes stale
+	src.err = errors.New("down")
+	if _, err := m.Refresh(context.Background()); err == nil {
+		t.Error("refresh error swallowed")
+	}
+	if st := m.Status(); st.LastError == "" || st.Snapshot == nil || st.Stale {
+		t.Errorf("status = %+v", st)
+	}
+	now = now.Add(3 * time.Hour)
+	if _, ok := m.Latest(); ok || !m.Status().Stale {
+		t.Error("stale snapshot served")
+	}
+
+	bad := testPolicy()
+	if err := m.SetPolicy(bad); err == nil {
+		t.Error("accepted an empty universe")
+	}
+
+	// A named universe is measured in place of the list once it exists
+	src.err = nil
+	named := testPolicy()
+	named.UniverseName = "tech"
+	if err := m.SetPolicy(named); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Refresh(context.Background()); err == nil {
+		t.Error("refreshed an unknown universe")
+	}
+	m.SetUniverses(func(name string) []string {
+		if name == "tech" {
+			return []string{"a", "B"}
+		}
+		return nil
+	})
+	if _, err := m.Refresh(context.Background()); err != nil || len(src.got) != 2 || src.got[0] != "A" {
+		t.Errorf("refresh = %v, fetched %v", err, src.got)
+	}
+}
@@ -0,0 +1,60 @@
+package breadth
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Handler exposes market breadth over HTTP.
+type Handler struct {
+	monitor *Monitor
+}
+
+// NewHandler creates a handler for monitor.
+func NewHandler(monitor *Monitor) *Handler {
+	return &Handler{monitor: monitor}
+}
+
+// RegisterRoutes registers the breadth routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/market/breadth?refresh=true - the latest snapshot with the last refresh error
+	mux.HandleFunc("/api/market/breadth", h.handleBreadth)
+
+	// GET/POST /api/market/breadth/policy - read or update the universe and measures
+	mux.HandleFunc("/api/market/breadth/policy", h.handlePolicy)
+}
+
+func (h *Handler) handleBreadth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if r.URL.Query().Get("refresh") == "true" {
+		if _, err := h.monitor.Refresh(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+	json.NewEncoder(w).Encode(h.monitor.Status())
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.monitor.Policy())
+	case http.MethodPost, http.MethodPut:
+		// Start from the current policy so partial updates work
+		policy := h.monitor.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.monitor.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(h.monitor.Policy())
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,389 @@
+// Package budgets caps how much each strategy may lose. Every strategy tag
+// has a daily and a total loss budget, measured on the realized P&L the
+// fills journal attributes to it. A strategy that spends either budget is
+// disabled — it may close positions but not open them — and stays
+// disabled until an admin re-enables it, which starts its budgets afresh.
+// The rest of the system keeps trading.
+package budgets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/fills"
+)
+
+func logger() *slog.Logger { return slog.With("module", "budgets") }
+
+// ErrDisabled is wrapped by refusals of entries for a disabled strategy.
+var ErrDisabled = errors.New("strategy disabled by its loss budget")
+
+// errNotDisabled is wrapped when re-enabling a strategy that is enabled.
+var errNotDisabled = errors.New("not disabled")
+
+// Budget periods.
+const (
+	PeriodDaily = "daily"
+	PeriodTotal = "total"
+)
+
+// Budget is how much a strategy may lose, in dollars of realized P&L. A
+// zero field is not checked.
+type Budget struct {
+	DailyMaxLoss float64 `json:"daily_max_loss"` // since midnight market time
+	TotalMaxLoss float64 `json:"total_max_loss"` // since the strategy was last enabled
+}
+
+// Validate reports the first invalid field.
+func (b Budget) Validate() error {
+	switch {
+	case b.DailyMaxLoss < 0:
+		return errors.New("daily_max_loss must not be negative")
+	case b.TotalMaxLoss < 0:
+		return errors.New("total_max_loss must not be negative")
+	}
+	return nil
+}
+
+// Policy holds the budgets.
+type Policy struct {
+	Budget     Budget            `json:"budget"`               // for every strategy without its own
+	Strategies map[string]Budget `json:"strategies,omitempty"` // by strategy tag
+}
+
+// DefaultPolicy sets no budgets; strategies are given theirs through the
+// API.
+func DefaultPolicy() Policy {
+	return Policy{}
+}
+
+// Validate reports the first invalid field.
+func (p Policy) Validate() error {
+	if err := p.Budget.Validate(); err != nil {
+		return err
+	}
+	for tag, b := range p.Strategies {
+		if strings.TrimSpace(tag) == "" {
+			return errors.New("strategy budgets need a tag")
+		}
+		if err := b.Validate(); err != nil {
+			return fmt.Errorf("strategy %s: %w", tag, err)
+		}
+	}
+	return nil
+}
+
+// BudgetFor returns the budget that applies to strategy.
+func (p Policy) BudgetFor(strategy string) Budget {
+	if b, ok := p.Strategies[strategy]; ok {
+		return b
+	}
+	return p.Budget
+}
+
+// Disablement records why a strategy was disabled.
+type Disablement struct {
+	Period string    `json:"period"` // daily or total
+	Loss   float64   `json:"loss"`
+	Limit  float64   `json:"limit"`
+	Reason string    `json:"reason"`
+	At     time.Time `json:"at"`
+}
+
+// Strategy is one strategy's spending against its budget.
+type Strategy struct {
+	Strategy string       `json:"strategy"`
+	Budget   Budget       `json:"budget"`
+	DailyPL  float64      `json:"daily_pl"`
+	TotalPL  float64      `json:"total_pl"`
+	Disabled *Disablement `json:"disabled,omitempty"`
+	// EnabledAt is when an admin last re-enabled the strategy, the start
+	// of its total
+	EnabledAt *time.Time `json:"enabled_at,omitempty"`
+	EnabledBy string     `json:"enabled_by,omitempty"`
+}
+
+// Status is every strategy with realized P&L, a budget or a disablement.
+type Status struct {
+	Policy     Policy     `json:"policy"`
+	Day        string     `json:"day"` // YYYY-MM-DD in market time
+	Strategies []Strategy `json:"strategies"`
+}
+
+// enablement is an admin's re-enable of a strategy.
+type enablement struct {
+	At time.Time `json:"at"`
+	By string    `json:"by,omitempty"`
+}
+
+// state is what survives a restart.
+type state struct {
+	Disabled map[string]Disablement `json:"disabled"`
+	Enabled  map[string]enablement  `json:"enabled"`
+}
+
+// Source returns the finished fill records submitted at or after since.
+type Source func(since time.Time) []fills.Record
+
+// Notifier raises a high-priority notification.
+type Notifier func(title, message string, metadata map[string]interface{})
+
+// Manager keeps the budgets, measures strategies against them from the
+// fills journal and disables those that overspend. It is safe for
+// concurrent use.
+type Manager struct {
+	dir    string
+	source Source
+	loc    *time.Location
+
+	mu     sync.Mutex
+	policy Policy
+	state  state
+	notify Notifier
+	now    func() time.Time
+}
+
+// New opens the policy and state saved in dir, starting from DefaultPolicy
+// when there is none. Fills come from source; days roll over at midnight
+// in loc.
+func New(dir string, source Source, loc *time.Location) (*Manager, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create budgets directory: %w", err)
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	m := &Manager{
+		dir:    dir,
+		source: source,
+		loc:    loc,
+		policy: DefaultPolicy(),
+		state:  state{Disabled: make(map[string]Disablement), Enabled: make(map[string]enablement)},
+		now:    time.Now,
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "policy.json")); err == nil {
+		policy := DefaultPolicy()
+		if err := json.Unmarshal(data, &policy); err != nil {
+			return nil, fmt.Errorf("failed to decode budget policy: %w", err)
+		}
+		if err := policy.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid budget policy: %w", err)
+		}
+		m.policy = policy
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read budget policy: %w", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "state.json")); err == nil {
+		if err := json.Unmarshal(data, &m.state); err != nil {
+			return nil, fmt.Errorf("failed to decode budget state: %w", err)
+		}
+		if m.state.Disabled == nil {
+			m.state.Disabled = make(map[string]Disablement)
+		}
+		if m.state.Enabled == nil {
+			m.state.Enabled = make(map[string]enablement)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read budget state: %w", err)
+	}
+	return m, nil
+}
+
+// SetClock replaces the clock, for replays and tests.
+func (m *Manager) SetClock(now func() time.Time) { m.mu.Lock(); m.now = now; m.mu.Unlock() }
+
+// SetNotifier sets where disablements are raised.
+func (m *Manager) SetNotifier(fn Notifier) { m.mu.Lock(); m.notify = fn; m.mu.Unlock() }
+
+// Policy returns the current policy.
+func (m *Manager) Policy() Policy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.policy
+}
+
+// SetPolicy validates, applies and saves p, then checks every strategy
+// against it. Lifting a budget does not re-enable a strategy.
+func (m *Manager) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.policy = p
+	err := m.saveLocked("policy.json", m.policy)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	m.Check()
+	return nil
+}
+
+// CheckEntry refuses entries for strategy while it is disabled.
+func (m *Manager) CheckEntry(strategy string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if d, ok := m.state.Disabled[strategy]; ok {
+		return fmt.Errorf("%w: %s; an admin must re-enable it", ErrDisabled, d.Reason)
+	}
+	return nil
+}
+
+// Enable re-enables strategy on behalf of by, starting its daily and total
+// budgets from now.
+func (m *Manager) Enable(strategy, by string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.state.Disabled[strategy]; !ok {
+		return fmt.Errorf("strategy %s: %w", strategy, errNotDisabled)
+	}
+	delete(m.state.Disabled, strategy)
+	m.state.Enabled[strategy] = enablement{At: m.now(), By: by}
+	logger().Info("Strategy re-enabled", "strategy", strategy, "by", by)
+	return m.saveLocked("state.json", m.state)
+}
+
+// Status measures every strategy against its budget.
+func (m *Manager) Status() Status {
+	m.mu.Lock()
+	now, policy := m.now(), m.policy
+	enabled := make(map[string]enablement, len(m.state.Enabled))
+	for k, v := range m.state.Enabled {
+		enabled[k] = v
+	}
+	disabled := make(map[string]Disablement, len(m.state.Disabled))
+	for k, v := range m.state.Disabled {
+		disabled[k] = v
+	}
+	m.mu.Unlock()
+
+	daily, total := m.spend(now, enabled)
+	names := make(map[string]bool)
+	for k := range total {
+		names[k] = true
+	}
+	for k := range policy.Strategies {
+		names[k] = true
+	}
+	for k := range disabled {
+		names[k] = true
+	}
+	s := Status{Policy: policy, Day: now.In(m.loc).Format("2006-01-02"), Strategies: make([]Strategy, 0, len(names))}
+	for name := range names {
+		st := Strategy{Strategy: name, Budget: policy.BudgetFor(name), DailyPL: round(daily[name]), TotalPL: round(total[name])}
+		if d, ok := disabled[name]; ok {
+			st.Disabled = &d
+		}
+		if e, ok := enabled[name]; ok {
+			at := e.At
+			st.EnabledAt, st.EnabledBy = &at, e.By
+		}
+		s.Strategies = append(s.Strategies, st)
+	}
+	sort.Slice(s.Strategies, func(i, j int) bool { return s.Strategies[i].Strategy < s.Strategies[j].Strategy })
+	return s
+}
+
+// Check disables every strategy that has spent its daily or total budget
+// and is not already disabled, saving and notifying each. It returns the
+// strategies it disabled.
+func (m *Manager) Check() []string {
+	status := m.Status()
+	var disabled []string
+	for _, st := range status.Strategies {
+		if st.Disabled != nil {
+			continue
+		}
+		d := breach(st)
+		if d == nil {
+			continue
+		}
+		m.mu.Lock()
+		if _, ok := m.state.Disabled[st.Strategy]; ok {
+			m.mu.Unlock()
+			continue
+		}
+		d.At = m.now()
+		m.state.Disabled[st.Strategy] = *d
+		err := m.saveLocked("state.json", m.state)
+		notify := m.notify
+		m.mu.Unlock()
+		if err != nil {
+			logger().Error("Failed to save budget state", "error", err)
+		}
+		disabled = append(disabled, st.Strategy)
+		logger().Warn("Strategy disabled by its loss budget", "strategy", st.Strategy, "period", d.Period, "loss", d.Loss, "limit", d.Limit)
+		if notify != nil {
+			notify("Strategy disabled: "+st.Strategy, d.Reason+". New entries are refused until an admin re-enables it.", map[string]interface{}{
+				"strategy": st.Strategy,
+				"period":   d.Period,
+				"loss":     d.Loss,
+				"limit":    d.Limit,
+			})
+		}
+	}
+	return disabled
+}
+
+// breach returns the disablement st's spending calls for, if any; the
+// total budget is checked first.
+func breach(st Strategy) *Disablement {
+	if b := st.Budget.TotalMaxLoss; b > 0 && -st.TotalPL >= b {
+		return &Disablement{Period: PeriodTotal, Loss: -st.TotalPL, Limit: b,
+			Reason: fmt.Sprintf("strategy %s has lost $%.2f in total, at least its total_max_loss of $%.2f", st.Strategy, -st.TotalPL, b)}
+	}
+	if b := st.Budget.DailyMaxLoss; b > 0 && -st.DailyPL >= b {
+		return &Disablement{Period: PeriodDaily, Loss: -st.DailyPL, Limit: b,
+			Reason: fmt.Sprintf("strategy %s has lost $%.2f today, at least its daily_max_loss of $%.2f", st.Strategy, -st.DailyPL, b)}
+	}
+	return nil
+}
+
+// spend sums each tagged strategy's realized P&L today and in total, both
+// from when it was last enabled.
+func (m *Manager) spend(now time.Time, enabled map[string]enablement) (daily, total map[string]float64) {
+	day := now.In(m.loc)
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, m.loc)
+	daily, total = make(map[string]float64), make(map[string]float64)
+	// Earlier fills open the positions later fills close
+	for _, o := range fills.TradeOutcomes(m.source(time.Time{})) {
+		if o.Strategy == "" {
+			continue
+		}
+		if e, ok := enabled[o.Strategy]; ok && o.ClosedAt.Before(e.At) {
+			continue
+		}
+		total[o.Strategy] += o.PL
+		if !o.ClosedAt.Before(midnight) {
+			daily[o.Strategy] += o.PL
+		}
+	}
+	return daily, total
+}
+
+func round(v float64) float64 { return math.Round(v*100) / 100 }
+
+func (m *Manager) saveLocked(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	path := filepath.Join(m.dir, name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to save %s: %w", name, err)
+	}
+	return nil
+}
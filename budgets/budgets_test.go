@@ -0,0 +1,89 @@
+package budgets
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/fills"
+)
+
+func fill(tag, side string, qty, price float64, at time.Time) fills.Record {
+	return fills.Record{Tag: tag, Symbol: "AAPL", Side: side, Qty: qty, FilledQty: qty, FillPrice: price, SubmittedAt: at, FilledAt: &at, FinishedAt: at}
+}
+
+func TestCheckDisablesOverspentStrategy(t *testing.T) {
+	dir := t.TempDir()
+	yesterday := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	now := yesterday.Add(24 * time.Hour)
+	records := []fills.Record{
+		// -$300 yesterday, -$250 today for momo; breakout only gains
+		fill("momo", "buy", 100, 10, yesterday),
+		fill("momo", "sell", 100, 7, yesterday.Add(time.Hour)),
+		fill("momo", "buy", 50, 20, now.Add(-2*time.Hour)),
+		fill("momo", "sell", 50, 15, now.Add(-time.Hour)),
+		fill("breakout", "buy", 10, 10, now.Add(-2*time.Hour)),
+		fill("breakout", "sell", 10, 12, now.Add(-time.Hour)),
+	}
+	m, err := New(dir, func(time.Time) []fills.Record { return records }, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetClock(func() time.Time { return now })
+	var notified []string
+	m.SetNotifier(func(title, message string, metadata map[string]interface{}) { notified = append(notified, title) })
+
+	// The daily budget holds; the total does not
+	if err := m.SetPolicy(Policy{Budget: Budget{DailyMaxLoss: 300}, Strategies: map[string]Budget{"momo": {DailyMaxLoss: 300, TotalMaxLoss: 500}}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(notified) != 1 || notified[0] != "Strategy disabled: momo" {
+		t.Fatalf("notified %v", notified)
+	}
+	if err := m.CheckEntry("momo"); !errors.Is(err, ErrDisabled) {
+		t.Fatalf("momo entry: %v", err)
+	}
+	if err := m.CheckEntry("breakout"); err != nil {
+		t.Fatalf("breakout entry: %v", err)
+	}
+	if disabled := m.Check(); len(disabled) != 0 || len(notified) != 1 {
+		t.Errorf("disabled again: %v", disabled)
+	}
+
+	// Disablement survives a restart, and only re-enabling lifts it
+	reopened, err := New(dir, func(time.Time) []fills.Record { return records }, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened.SetClock(func() time.Time { return now })
+	status := reopened.Status()
+	if len(status.Strategies) != 2 || status.Strategies[1].Strategy != "momo" {
+		t.Fatalf("strategies = %+v", status.Strategies)
+	}
+	momo := status.Strategies[1]
+	if momo.DailyPL != -250 || momo.TotalPL != -550 || momo.Disabled == nil || momo.Disabled.Period != PeriodTotal || momo.Disabled.Limit != 500 {
+		t.Fatalf("momo = %+v", momo)
+	}
+	if err := reopened.Enable("breakout", "admin"); err == nil {
+		t.Error("enabled a strategy that was not disabled")
+	}
+	if err := reopened.Enable("momo", "admin"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Budgets start afresh from the re-enable
+	if reopened.Check(); reopened.CheckEntry("momo") != nil {
+		t.Fatal("re-enabled strategy disabled by losses before it was re-enabled")
+	}
+	if momo := reopened.Status().Strategies[1]; momo.TotalPL != 0 || momo.EnabledBy != "admin" {
+		t.Errorf("after re-enable = %+v", momo)
+	}
+	now = now.Add(time.Hour)
+	records = append(records, fill("momo", "buy", 100, 20, now.Add(-time.Minute)), fill("momo", "sell", 100, 16.5, now))
+	if disabled := reopened.Check(); len(disabled) != 1 {
+		t.Fatal("daily budget not enforced after re-enable")
+	}
+	if d := reopened.Status().Strategies[1].Disabled; d == nil || d.Period != PeriodDaily {
+		t.Errorf("disabled = %+v", d)
+	}
+}
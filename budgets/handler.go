@@ -0,0 +1,89 @@
+package budgets
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/rileyseaburg/go-trader/users"
+)
+
+// Handler exposes strategy loss budgets over HTTP.
+type Handler struct {
+	manager *Manager
+}
+
+// NewHandler creates a handler for manager.
+func NewHandler(manager *Manager) *Handler {
+	return &Handler{manager: manager}
+}
+
+// RegisterRoutes registers the budget routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/risk/strategy-budgets - each strategy's realized P&L against its budget
+	mux.HandleFunc("/api/risk/strategy-budgets", h.handleStatus)
+
+	// GET/POST /api/risk/strategy-budgets/policy - read or update the budgets
+	mux.HandleFunc("/api/risk/strategy-budgets/policy", h.handlePolicy)
+
+	// POST /api/risk/strategy-budgets/enable - re-enable a disabled strategy; admins only
+	mux.HandleFunc("/api/risk/strategy-budgets/enable", h.handleEnable)
+}
+
+func (h *Handler) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(h.manager.Status())
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.manager.Policy())
+	case http.MethodPost, http.MethodPut:
+		// Start from the current policy so partial updates work
+		policy := h.manager.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.manager.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(h.manager.Policy())
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleEnable(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	caller := users.FromContext(r.Context())
+	if !caller.IsAdmin() {
+		http.Error(w, users.ErrForbidden.Error(), http.StatusForbidden)
+		return
+	}
+	var req struct {
+		Strategy string `json:"strategy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Strategy) == "" {
+		http.Error(w, "strategy is required", http.StatusBadRequest)
+		return
+	}
+	if err := h.manager.Enable(req.Strategy, caller.ID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, errNotDisabled) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	json.NewEncoder(w).Encode(h.manager.Status())
+}
@@ -0,0 +1,272 @@
+// Package calendar knows when US equity markets trade: weekends, NYSE
+// holidays, and the 1 p.m. early closes around Independence Day,
+// Thanksgiving and Christmas. Rules are computed, not fetched, so the
+// calendar works offline and for any year.
+package calendar
+
+import (
+	"sort"
+	"sync"
+	"time"
+	_ "time/tzdata" // containers often ship without zoneinfo
+)
+
+// Regular and early session times, Eastern.
+const (
+	openHour, openMinute = 9, 30
+	closeHour            = 16
+	earlyCloseHour       = 13
+)
+
+// Session is one trading day's regular hours.
+type Session struct {
+	Date       time.Time `json:"date"` // midnight Eastern
+	Open       time.Time `json:"open"`
+	Close      time.Time `json:"close"`
+	EarlyClose bool      `json:"early_close"`
+}
+
+// Holiday is a full-day market closure.
+type Holiday struct {
+	Date time.Time `json:"date"`
+	Name string    `json:"name"`
+}
+
+// Calendar answers trading-day questions in exchange time. It is safe for
+// concurrent use; holiday tables are built lazily per year.
+type Calendar struct {
+	loc *time.Location
+
+	mu          sync.Mutex
+	holidays    map[string]string // "2006-01-02" → name
+	earlyCloses map[string]bool
+	years       map[int]bool
+}
+
+// New returns an NYSE calendar.
+func New() *Calendar {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		loc = time.FixedZone("EST", -5*3600)
+	}
+	return &Calendar{
+		loc:         loc,
+		holidays:    make(map[string]string),
+		earlyCloses: make(map[string]bool),
+		years:       make(map[int]bool),
+	}
+}
+
+// Location is the exchange time zone.
+func (c *Calendar) Location() *time.Location { return c.loc }
+
+// IsTradingDay reports whether the exchange-local date of t has a session.
+func (c *Calendar) IsTradingDay(t time.Time) bool {
+	t = t.In(c.loc)
+	if wd := t.Weekday(); wd == time.Saturday || wd == time.Sunday {
+		return false
+	}
+	_, holiday := c.Holiday(t)
+	return !holiday
+}
+
+// Holiday returns the holiday name if the exchange-local date of t is a
+// market holiday.
+func (c *Calendar) Holiday(t time.Time) (string, bool) {
+	t = t.In(c.loc)
+	c.ensureYear(t.Year())
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	name, ok := c.holidays[dayKey(t)]
+	return name, ok
+}
+
+// SessionFor returns the session on the exchange-local date of t, if any.
+func (c *Calendar) SessionFor(t time.Time) (Session, bool) {
+	if !c.IsTradingDay(t) {
+		return Session{}, false
+	}
+	t = t.In(c.loc)
+	c.mu.Lock()
+	early := c.earlyCloses[dayKey(t)]
+	c.mu.Unlock()
+
+	y, m, d := t.Date()
+	closeAt := closeHour
+	if early {
+		closeAt = earlyCloseHour
+	}
+	return Session{
+		Date:       time.Date(y, m, d, 0, 0, 0, 0, c.loc),
+		Open:       time.Date(y, m, d, openHour, openMinute, 0, 0, c.loc),
+		Close:      time.Date(y, m, d, closeAt, 0, 0, 0, c.loc),
+		EarlyClose: early,
+	}, true
+}
+
+// IsOpen reports whether the regular session is in progress at t.
+func (c *Calendar) IsOpen(t time.Time) bool {
+	s, ok := c.SessionFor(t)
+	return ok && !t.Before(s.Open) && t.Before(s.Close)
+}
+
+// NextSession returns the first session that has not yet closed at t —
+// today's if it is still running or yet to open.
+func (c *Calendar) NextSession(t time.Time) Session {
+	day := t.In(c.loc)
+	for i := 0; i < 15; i++ {
+		if s, ok := c.SessionFor(day); ok && t.Before(s.Close) {
+			return s
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return Session{}
+}
+
+// PreviousSession returns the last session that closed strictly before the
+// exchange-local date of t.
+func (c *Calendar) PreviousSession(t time.Time) Session {
+	day := t.In(c.loc).AddDate(0, 0, -1)
+	for i := 0; i < 15; i++ {
+		if s, ok := c.SessionFor(day); ok {
+			return s
+		}
+		day = day.AddDate(0, 0, -1)
+	}
+	return Session{}
+}
+
+// BreakAfter returns the number of calendar days with no session between
+// the date of t and the next session. Zero means the next day trades; one
+// or more means a weekend or holiday follows.
+func (c *Calendar) BreakAfter(t time.Time) int {
+	day := t.In(c.loc)
+	gap := 0
+	for i := 0; i < 15; i++ {
+		day = day.AddDate(0, 0, 1)
+		if c.IsTradingDay(day) {
+			return gap
+		}
+		gap++
+	}
+	return gap
+}
+
+// TradingDaysBetween counts sessions whose date falls in (from, to].
+func (c *Calendar) TradingDaysBetween(from, to time.Time) int {
+	if !to.After(from) {
+		return 0
+	}
+	from, to = from.In(c.loc), to.In(c.loc)
+	n := 0
+	for day := from.AddDate(0, 0, 1); !dateAfter(day, to); day = day.AddDate(0, 0, 1) {
+		if c.IsTradingDay(day) {
+			n++
+		}
+	}
+	return n
+}
+
+// Holidays lists the market holidays in a calendar year, in date order.
+func (c *Calendar) Holidays(year int) []Holiday {
+	c.ensureYear(year)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []Holiday
+	for key, name := range c.holidays {
+		d, _ := time.ParseInLocation("2006-01-02", key, c.loc)
+		if d.Year() == year {
+			out = append(out, Holiday{Date: d, Name: name})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Date.Before(out[j].Date) })
+	return out
+}
+
+// ensureYear populates holiday and early-close tables for year.
+func (c *Calendar) ensureYear(year int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.years[year] {
+		return
+	}
+	c.years[year] = true
+
+	add := func(d time.Time, name string) { c.holidays[dayKey(d)] = name }
+	date := func(m time.Month, d int) time.Time { return time.Date(year, m, d, 0, 0, 0, 0, c.loc) }
+
+	// New Year's Day is not moved back into the prior year when it falls
+	// on a Saturday.
+	if ny := date(time.January, 1); ny.Weekday() != time.Saturday {
+		add(observed(ny), "New Year's Day")
+	}
+	add(nthWeekday(year, time.January, time.Monday, 3, c.loc), "Martin Luther King Jr. Day")
+	add(nthWeekday(year, time.February, time.Monday, 3, c.loc), "Washington's Birthday")
+	add(easter(year, c.loc).AddDate(0, 0, -2), "Good Friday")
+	add(lastWeekday(year, time.May, time.Monday, c.loc), "Memorial Day")
+	if year >= 2022 {
+		add(observed(date(time.June, 19)), "Juneteenth")
+	}
+	add(observed(date(time.July, 4)), "Independence Day")
+	add(nthWeekday(year, time.September, time.Monday, 1, c.loc), "Labor Day")
+	thanksgiving := nthWeekday(year, time.November, time.Thursday, 4, c.loc)
+	add(thanksgiving, "Thanksgiving Day")
+	add(observed(date(time.December, 25)), "Christmas Day")
+
+	// Early closes apply only when the day is otherwise a full session.
+	for _, d := range []time.Time{date(time.July, 3), thanksgiving.AddDate(0, 0, 1), date(time.December, 24)} {
+		if wd := d.Weekday(); wd == time.Saturday || wd == time.Sunday {
+			continue
+		}
+		if _, closed := c.holidays[dayKey(d)]; closed {
+			continue
+		}
+		c.earlyCloses[dayKey(d)] = true
+	}
+}
+
+// observed moves a Saturday holiday to Friday and a Sunday one to Monday.
+func observed(d time.Time) time.Time {
+	switch d.Weekday() {
+	case time.Saturday:
+		return d.AddDate(0, 0, -1)
+	case time.Sunday:
+		return d.AddDate(0, 0, 1)
+	}
+	return d
+}
+
+// nthWeekday returns the nth (1-based) given weekday of a month.
+func nthWeekday(year int, m time.Month, wd time.Weekday, n int, loc *time.Location) time.Time {
+	d := time.Date(year, m, 1, 0, 0, 0, 0, loc)
+	offset := (int(wd) - int(d.Weekday()) + 7) % 7
+	return d.AddDate(0, 0, offset+7*(n-1))
+}
+
+// lastWeekday returns the last given weekday of a month.
+func lastWeekday(year int, m time.Month, wd time.Weekday, loc *time.Location) time.Time {
+	d := time.Date(year, m+1, 0, 0, 0, 0, 0, loc)
+	offset := (int(d.Weekday()) - int(wd) + 7) % 7
+	return d.AddDate(0, 0, -offset)
+}
+
+// easter returns Western Easter Sunday (anonymous Gregorian algorithm).
+func easter(year int, loc *time.Location) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, loc)
+}
+
+func dayKey(t time.Time) string { return t.Format("2006-01-02") }
+
+// dateAfter reports whether a's date is after b's date.
+func dateAfter(a, b time.Time) bool { return dayKey(a) > dayKey(b) }
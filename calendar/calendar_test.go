@@ -0,0 +1,108 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+)
+
+func et(c *Calendar, y int, m time.Month, d, h, min int) time.Time {
+	return time.Date(y, m, d, h, min, 0, 0, c.Location())
+}
+
+func TestHolidays2024(t *testing.T) {
+	c := New()
+	want := map[string]string{
+		"2024-01-01": "New Year's Day",
+		"2024-01-15": "Martin Luther King Jr. Day",
+		"2024-02-19": "Washington's Birthday",
+		"2024-03-29": "Good Friday",
+		"2024-05-27": "Memorial Day",
+		"2024-06-19": "Juneteenth",
+		"2024-07-04": "Independence Day",
+		"2024-09-02": "Labor Day",
+		"2024-11-28": "Thanksgiving Day",
+		"2024-12-25": "Christmas Day",
+	}
+	got := c.Holidays(2024)
+	if len(got) != len(want) {
+		t.Fatalf("Holidays(2024) returned %d entries, want %d: %+v", len(got), len(want), got)
+	}
+	for _, h := range got {
+		if want[dayKey(h.Date)] != h.Name {
+			t.Errorf("unexpected holiday %s %q", dayKey(h.Date), h.Name)
+		}
+	}
+}
+
+func TestObservedHolidays(t *testing.T) {
+	c := New()
+	cases := []struct {
+		day  time.Time
+		want bool
+	}{
+		{et(c, 2026, time.July, 3, 12, 0), false},     // July 4 on Saturday → Friday closed
+		{et(c, 2022, time.January, 17, 12, 0), false}, // MLK
+		{et(c, 2022, time.December, 26, 12, 0), false},
+		{et(c, 2021, time.December, 31, 12, 0), true}, // New Year's on Saturday is not observed
+		{et(c, 2023, time.January, 2, 12, 0), false},  // New Year's on Sunday → Monday
+		{et(c, 2025, time.April, 18, 12, 0), false},   // Good Friday
+		{et(c, 2024, time.June, 18, 12, 0), true},
+	}
+	for _, tc := range cases {
+		if got := c.IsTradingDay(tc.day); got != tc.want {
+			t.Errorf("IsTradingDay(%s) = %v, want %v", dayKey(tc.day), got, tc.want)
+		}
+	}
+}
+
+func TestEarlyCloses(t *testing.T) {
+	c := New()
+	for _, d := range []time.Time{
+		et(c, 2024, time.July, 3, 10, 0),
+		et(c, 2024, time.November, 29, 10, 0),
+		et(c, 2024, time.December, 24, 10, 0),
+	} {
+		s, ok := c.SessionFor(d)
+		if !ok || !s.EarlyClose || s.Close.Hour() != 13 {
+			t.Errorf("SessionFor(%s) = %+v, %v; want 13:00 early close", dayKey(d), s, ok)
+		}
+	}
+	// July 3, 2026 is the observed holiday, so no early close on July 2.
+	if s, _ := c.SessionFor(et(c, 2026, time.July, 2, 10, 0)); s.EarlyClose {
+		t.Errorf("2026-07-02 should be a full session")
+	}
+}
+
+func TestSessionNavigation(t *testing.T) {
+	c := New()
+	fri := et(c, 2024, time.March, 28, 17, 0) // Thursday after close; Good Friday follows
+	next := c.NextSession(fri)
+	if dayKey(next.Date) != "2024-04-01" {
+		t.Errorf("NextSession = %s, want 2024-04-01", dayKey(next.Date))
+	}
+	if prev := c.PreviousSession(next.Date); dayKey(prev.Date) != "2024-03-28" {
+		t.Errorf("PreviousSession = %s, want 2024-03-28", dayKey(prev.Date))
+	}
+	if gap := c.BreakAfter(fri); gap != 3 {
+		t.Errorf("BreakAfter = %d, want 3", gap)
+	}
+	if gap := c.BreakAfter(et(c, 2024, time.April, 2, 10, 0)); gap != 0 {
+		t.Errorf("BreakAfter midweek = %d, want 0", gap)
+	}
+	if n := c.TradingDaysBetween(et(c, 2024, time.March, 27, 0, 0), et(c, 2024, time.April, 2, 0, 0)); n != 3 {
+		t.Errorf("TradingDaysBetween = %d, want 3", n)
+	}
+}
+
+func TestIsOpen(t *testing.T) {
+	c := New()
+	if !c.IsOpen(et(c, 2024, time.April, 2, 9, 30)) {
+		t.Error("expected open at 09:30")
+	}
+	if c.IsOpen(et(c, 2024, time.April, 2, 16, 0)) {
+		t.Error("expected closed at 16:00")
+	}
+	if c.IsOpen(et(c, 2024, time.December, 24, 14, 0)) {
+		t.Error("expected closed after early close")
+	}
+}
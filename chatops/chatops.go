@@ -0,0 +1,483 @@
+// Package chatops supervises the trader from Slack or Discord. Signals
+// and fills are posted to a channel through incoming webhooks, and slash
+// commands — positions, pnl, pending, approve, reject, halt and resume —
+// are answered from the same state the HTTP API serves. Every command
+// needs a role: viewers can read, traders can decide approvals and admins
+// can halt and resume automated trading.
+package chatops
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+	"github.com/rileyseaburg/go-trader/approvals"
+	"github.com/rileyseaburg/go-trader/fills"
+)
+
+func logger() *slog.Logger { return slog.With("module", "chatops") }
+
+// Platforms.
+const (
+	PlatformSlack   = "slack"
+	PlatformDiscord = "discord"
+)
+
+// Roles, each allowed everything the ones before it are.
+const (
+	RoleViewer = "viewer"
+	RoleTrader = "trader"
+	RoleAdmin  = "admin"
+)
+
+var roleRank = map[string]int{RoleViewer: 1, RoleTrader: 2, RoleAdmin: 3}
+
+// outboxSize bounds the messages waiting to be posted; more are dropped.
+const outboxSize = 100
+
+// Policy configures who may do what and what is posted.
+type Policy struct {
+	Users       map[string]string `json:"users"` // "slack:<user id>" or "discord:<user id>" to role
+	PostSignals bool              `json:"post_signals"`
+	PostFills   bool              `json:"post_fills"`
+}
+
+// DefaultPolicy posts signals and fills and lets nobody run commands
+// until users are given roles.
+func DefaultPolicy() Policy {
+	return Policy{Users: map[string]string{}, PostSignals: true, PostFills: true}
+}
+
+// Validate checks the policy for internally consistent values.
+func (p Policy) Validate() error {
+	for user, role := range p.Users {
+		platform, id, ok := strings.Cut(user, ":")
+		if !ok || id == "" || (platform != PlatformSlack && platform != PlatformDiscord) {
+			return fmt.Errorf("user %q must be slack:<id> or discord:<id>", user)
+		}
+		if roleRank[role] == 0 {
+			return fmt.Errorf("user %q: role must be %s, %s or %s", user, RoleViewer, RoleTrader, RoleAdmin)
+		}
+	}
+	return nil
+}
+
+// Actions are what commands act on.
+type Actions struct {
+	Portfolio func() algorithm.PortfolioData
+	Halt      func() error // stop automated trading
+	Resume    func() error
+	Approvals *approvals.Queue
+	// Execution refuses approvals while orders may not be placed, such as
+	// in safe mode; nil allows them
+	Execution func() error
+}
+
+// command is one chat command.
+type command struct {
+	role  string
+	usage string
+	loud  bool // the reply is shown to the whole channel
+	run   func(b *Bot, by string, args []string) (string, error)
+}
+
+// commands is filled in init, since help refers back to it.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"help":      {RoleViewer, "help", false, (*Bot).help},
+		"positions": {RoleViewer, "positions", false, (*Bot).positions},
+		"pnl":       {RoleViewer, "pnl today", false, (*Bot).pnl},
+		"pending":   {RoleViewer, "pending", false, (*Bot).pending},
+		"approve":   {RoleTrader, "approve <signal-id>", true, (*Bot).approve},
+		"reject":    {RoleTrader, "reject <signal-id> [reason]", true, (*Bot).reject},
+		"halt":      {RoleAdmin, "halt", true, (*Bot).halt},
+		"resume":    {RoleAdmin, "resume", true, (*Bot).resume},
+	}
+}
+
+// channel is an incoming webhook posts go to.
+type channel struct {
+	platform, url string
+}
+
+// Bot answers commands and posts announcements. It is safe for
+// concurrent use.
+type Bot struct {
+	path     string
+	actions  Actions
+	client   *http.Client
+	outbox   chan string
+	inFlight sync.WaitGroup // approvals placing orders
+
+	mu          sync.Mutex
+	policy      Policy
+	channels    []channel
+	slackSecret string
+	discordKey  ed25519.PublicKey
+}
+
+// New opens the bot with the policy saved at path, or policy when none
+// was saved.
+func New(path string, policy Policy, actions Actions) (*Bot, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create chatops directory: %w", err)
+	}
+	b := &Bot{
+		path:    path,
+		actions: actions,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		outbox:  make(chan string, outboxSize),
+		policy:  policy,
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		var saved Policy
+		if err := json.Unmarshal(data, &saved); err != nil {
+			return nil, fmt.Errorf("failed to decode chatops policy: %w", err)
+		}
+		if err := saved.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid saved chatops policy: %w", err)
+		}
+		b.policy = saved
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read chatops policy: %w", err)
+	}
+	if b.policy.Users == nil {
+		b.policy.Users = map[string]string{}
+	}
+	return b, nil
+}
+
+// AddChannel posts announcements to an incoming webhook URL on platform.
+func (b *Bot) AddChannel(platform, url string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.channels = append(b.channels, channel{platform: platform, url: url})
+}
+
+// Policy returns the current policy.
+func (b *Bot) Policy() Policy {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p := b.policy
+	p.Users = make(map[string]string, len(b.policy.Users))
+	for k, v := range b.policy.Users {
+		p.Users[k] = v
+	}
+	return p
+}
+
+// SetPolicy validates, replaces and saves the policy.
+func (b *Bot) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	if p.Users == nil {
+		p.Users = map[string]string{}
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write chatops policy: %w", err)
+	}
+	if err := os.Rename(tmp, b.path); err != nil {
+		return fmt.Errorf("failed to save chatops policy: %w", err)
+	}
+	b.policy = p
+	return nil
+}
+
+// Execute runs a command for user on platform and returns the reply and
+// whether the whole channel should see it.
+func (b *Bot) Execute(platform, user, name string, args []string) (string, bool) {
+	name = strings.ToLower(strings.TrimPrefix(name, "/"))
+	cmd, ok := commands[name]
+	if !ok {
+		return fmt.Sprintf("Unknown command %q. Try help.", name), false
+	}
+	who := platform + ":" + user
+	b.mu.Lock()
+	role := b.policy.Users[who]
+	b.mu.Unlock()
+	if roleRank[role] < roleRank[cmd.role] {
+		logger().Warn("Refused chat command", "user", who, "command", name, "role", role)
+		if role == "" {
+			return fmt.Sprintf("You have no role here. An admin can add %s to the chatops policy.", who), false
+		}
+		return fmt.Sprintf("%s needs the %s role; you are a %s.", name, cmd.role, role), false
+	}
+	logger().Info("Chat command", "user", who, "command", name, "args", args)
+	reply, err := cmd.run(b, who, args)
+	if err != nil {
+		return fmt.Sprintf("%s failed: %v", name, err), false
+	}
+	return reply, cmd.loud
+}
+
+// parse splits a slash command into a command name and arguments. A
+// single catch-all command such as /trader takes the name from its text.
+func parse(name, text string) (string, []string) {
+	name = strings.ToLower(strings.TrimPrefix(name, "/"))
+	args := strings.Fields(text)
+	if _, ok := commands[name]; !ok && len(args) > 0 {
+		return strings.ToLower(args[0]), args[1:]
+	}
+	return name, args
+}
+
+func (b *Bot) help(by string, _ []string) (string, error) {
+	b.mu.Lock()
+	rank := roleRank[b.policy.Users[by]]
+	b.mu.Unlock()
+	var lines []string
+	for _, c := range commands {
+		if roleRank[c.role] <= rank {
+			lines = append(lines, "/"+c.usage)
+		}
+	}
+	sort.Strings(lines)
+	return "Commands:\n" + strings.Join(lines, "\n"), nil
+}
+
+func (b *Bot) positions(string, []string) (string, error) {
+	if b.actions.Portfolio == nil {
+		return "", errors.New("portfolio unavailable")
+	}
+	p := b.actions.Portfolio()
+	held := p.HeldSymbols()
+	if len(held) == 0 {
+		return fmt.Sprintf("No open positions. Equity %.2f, cash %.2f.", p.TotalValue, p.Balance), nil
+	}
+	lines := make([]string, 0, len(held)+1)
+	for _, sym := range held {
+		pos := p.Positions[sym]
+		lines = append(lines, fmt.Sprintf("%s %g @ %.2f, now %.2f, P&L %+.2f (%+.2f%%)",
+			sym, pos.Quantity, pos.AvgPrice, pos.CurrentPrice, pos.Profit, pos.Return))
+	}
+	lines = append(lines, fmt.Sprintf("Equity %.2f, cash %.2f.", p.TotalValue, p.Balance))
+	return strings.Join(lines, "\n"), nil
+}
+
+func (b *Bot) pnl(_ string, args []string) (string, error) {
+	if len(args) > 0 && !strings.EqualFold(args[0], "today") {
+		return "Only /pnl today is supported.", nil
+	}
+	if b.actions.Portfolio == nil {
+		return "", errors.New("portfolio unavailable")
+	}
+	p := b.actions.Portfolio()
+	return fmt.Sprintf("Today: %+.2f (%+.2f%%). Equity %.2f.", p.DailyPnL, p.DailyReturn, p.TotalValue), nil
+}
+
+func (b *Bot) pending(string, []string) (string, error) {
+	if b.actions.Approvals == nil {
+		return "", errors.New("no approval queue")
+	}
+	items := b.actions.Approvals.List(approvals.StatusPending, 20)
+	if len(items) == 0 {
+		return "Nothing awaiting approval.", nil
+	}
+	lines := make([]string, len(items))
+	for i, it := range items {
+		lines[i] = fmt.Sprintf("%s %s, expires %s", it.ID, describe(it.Signal), it.ExpiresAt.Format("15:04"))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// approve places the order in the background, since chat platforms give
+// a command only a few seconds to answer, and posts the outcome.
+func (b *Bot) approve(by string, args []string) (string, error) {
+	if b.actions.Approvals == nil {
+		return "", errors.New("no approval queue")
+	}
+	if len(args) != 1 {
+		return "Usage: /approve <signal-id>", nil
+	}
+	it, err := b.actions.Approvals.Get(args[0])
+	if err != nil {
+		return "", err
+	}
+	if it.Status != approvals.StatusPending {
+		return "", fmt.Errorf("%w: %s", approvals.ErrDecided, it.Status)
+	}
+	if b.actions.Execution != nil {
+		if err := b.actions.Execution(); err != nil {
+			return "", err
+		}
+	}
+	b.inFlight.Add(1)
+	go func() {
+		defer b.inFlight.Done()
+		it, err := b.actions.Approvals.Approve(it.ID, by)
+		switch {
+		case err != nil:
+			b.Announce(fmt.Sprintf("Approving %s failed: %v", args[0], err))
+		case it.Error != "":
+			b.Announce(fmt.Sprintf("%s %s: %s (%s)", it.ID, it.Status, describe(it.Signal), it.Error))
+		default:
+			b.Announce(fmt.Sprintf("%s %s by %s: %s", it.ID, it.Status, by, it.Result))
+		}
+	}()
+	return fmt.Sprintf("Approving %s: %s", it.ID, describe(it.Signal)), nil
+}
+
+func (b *Bot) reject(by string, args []string) (string, error) {
+	if b.actions.Approvals == nil {
+		return "", errors.New("no approval queue")
+	}
+	if len(args) < 1 {
+		return "Usage: /reject <signal-id> [reason]", nil
+	}
+	it, err := b.actions.Approvals.Reject(args[0], by, strings.Join(args[1:], " "))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Rejected %s: %s", it.ID, describe(it.Signal)), nil
+}
+
+func (b *Bot) halt(by string, _ []string) (string, error) {
+	if b.actions.Halt == nil {
+		return "", errors.New("halt unavailable")
+	}
+	if err := b.actions.Halt(); err != nil {
+		return "", err
+	}
+	logger().Warn("Automated trading halted from chat", "user", by)
+	return fmt.Sprintf("Automated trading halted by %s. Open orders and positions are untouched.", by), nil
+}
+
+func (b *Bot) resume(by string, _ []string) (string, error) {
+	if b.actions.Resume == nil {
+		return "", errors.New("resume unavailable")
+	}
+	if err := b.actions.Resume(); err != nil {
+		return "", err
+	}
+	logger().Info("Automated trading resumed from chat", "user", by)
+	return fmt.Sprintf("Automated trading resumed by %s.", by), nil
+}
+
+func describe(s *algorithm.TradeSignal) string {
+	if s == nil {
+		return "no signal"
+	}
+	text := fmt.Sprintf("%s %s %s", strings.ToUpper(s.Signal), s.Symbol, s.OrderType)
+	if s.LimitPrice != nil {
+		text += fmt.Sprintf(" @ %.2f", *s.LimitPrice)
+	}
+	if s.Size != nil && s.Size.Qty != 0 {
+		text += fmt.Sprintf(" x%g", s.Size.Qty)
+	}
+	if s.Source != "" {
+		text += " from " + s.Source
+	}
+	return text
+}
+
+// AnnounceSignal posts a signal, with the approval ID when it awaits
+// approval, if the policy posts signals.
+func (b *Bot) AnnounceSignal(s *algorithm.TradeSignal, approvalID string) {
+	b.mu.Lock()
+	post := b.policy.PostSignals
+	b.mu.Unlock()
+	if !post || s == nil {
+		return
+	}
+	text := "Signal: " + describe(s)
+	if s.Confidence != nil {
+		text += fmt.Sprintf(", confidence %.2f", *s.Confidence)
+	}
+	if s.Reasoning != "" {
+		text += "\n" + s.Reasoning
+	}
+	if approvalID != "" {
+		text += fmt.Sprintf("\nAwaiting approval: /approve %s or /reject %s", approvalID, approvalID)
+	}
+	b.Announce(text)
+}
+
+// AnnounceFill posts a finished order if the policy posts fills.
+func (b *Bot) AnnounceFill(r fills.Record) {
+	b.mu.Lock()
+	post := b.policy.PostFills
+	b.mu.Unlock()
+	if !post {
+		return
+	}
+	b.Announce(fmt.Sprintf("Fill: %s %g/%g %s @ %.2f (%s, %s)", strings.ToUpper(r.Side), r.FilledQty, r.Qty, r.Symbol, r.FillPrice, r.Type, r.Outcome))
+}
+
+// Announce queues text for every channel.
+func (b *Bot) Announce(text string) {
+	select {
+	case b.outbox <- text:
+	default:
+		logger().Warn("Chat outbox full; dropping message")
+	}
+}
+
+// Run posts queued announcements until ctx is done.
+func (b *Bot) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case text := <-b.outbox:
+			b.mu.Lock()
+			channels := append([]channel{}, b.channels...)
+			b.mu.Unlock()
+			for _, c := range channels {
+				if err := b.post(ctx, c, text); err != nil {
+					logger().Warn("Failed to post to chat", "platform", c.platform, "error", err)
+				}
+			}
+		}
+	}
+}
+
+// post sends text to an incoming webhook in the platform's format.
+func (b *Bot) post(ctx context.Context, c channel, text string) error {
+	payload := map[string]string{"text": text}
+	if c.platform == PlatformDiscord {
+		payload = map[string]string{"content": text}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package chatops
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+	"github.com/rileyseaburg/go-trader/approvals"
+)
+
+func newBot(t *testing.T) (*Bot, *approvals.Queue, *bool) {
+	t.Helper()
+	dir := t.TempDir()
+	queue, err := approvals.New(filepath.Join(dir, "approvals.json"), approvals.DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue.SetExecutor(func(s *algorithm.TradeSignal) (string, error) { return "placed " + s.Symbol, nil })
+	halted := false
+	bot, err := New(filepath.Join(dir, "chatops.json"), DefaultPolicy(), Actions{
+		Portfolio: func() algorithm.PortfolioData {
+			return algorithm.PortfolioData{
+				Balance: 5000, TotalValue: 10000, DailyPnL: 125.5, DailyReturn: 1.27,
+				Positions: map[string]algorithm.PositionData{"AAPL": {Symbol: "AAPL", Quantity: 10, AvgPrice: 190, CurrentPrice: 195, Profit: 50, Return: 2.63}},
+			}
+		},
+		Halt:      func() error { halted = true; return nil },
+		Resume:    func() error { halted = false; return nil },
+		Approvals: queue,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bot.SetPolicy(Policy{Users: map[string]string{"slack:UV": RoleViewer, "slack:UT": RoleTrader, "discord:42": RoleAdmin}, PostSignals: true, PostFills: true}); err != nil {
+		t.Fatal(err)
+	}
+	return bot, queue, &halted
+}
+
+func TestCommandsRespectRoles(t *testing.T) {
+	bot, queue, halted := newBot(t)
+
+	if reply, _ := bot.Execute(PlatformSlack, "UX", "positions", nil); !strings.Contains(reply, "no role") {
+		t.Errorf("unknown user: %q", reply)
+	}
+	if reply, _ := bot.Execute(PlatformSlack, "UV", "positions", nil); !strings.Contains(reply, "AAPL 10 @ 190.00, now 195.00, P&L +50.00") {
+		t.Errorf("positions: %q", reply)
+	}
+	if reply, _ := bot.Execute(PlatformSlack, "UV", "pnl", []string{"today"}); reply != "Today: +125.50 (+1.27%). Equity 10000.00." {
+		t.Errorf("pnl: %q", reply)
+	}
+	if reply, _ := bot.Execute(PlatformSlack, "UV", "halt", nil); !strings.Contains(reply, "needs the admin role") || *halted {
+		t.Errorf("viewer halt: %q", reply)
+	}
+	if reply, loud := bot.Execute(PlatformDiscord, "42", "halt", nil); !*halted || !loud {
+		t.Errorf("admin halt: %q", reply)
+	}
+	if reply, _ := bot.Execute(PlatformSlack, "UV", "help", nil); strings.Contains(reply, "approve") || !strings.Contains(reply, "/pnl today") {
+		t.Errorf("viewer help: %q", reply)
+	}
+
+	item, err := queue.Submit(&algorithm.TradeSignal{Symbol: "MSFT", Signal: algorithm.SignalBuy, OrderType: "market", Source: "tradingview"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reply, _ := bot.Execute(PlatformSlack, "UV", "pending", nil); !strings.Contains(reply, item.ID+" BUY MSFT market from tradingview") {
+		t.Errorf("pending: %q", reply)
+	}
+	if reply, _ := bot.Execute(PlatformSlack, "UV", "approve", []string{item.ID}); !strings.Contains(reply, "needs the trader role") {
+		t.Errorf("viewer approve: %q", reply)
+	}
+	bot.actions.Execution = func() error { return errors.New("execution is disabled in safe mode") }
+	if reply, _ := bot.Execute(PlatformSlack, "UT", "approve", []string{item.ID}); !strings.Contains(reply, "safe mode") {
+		t.Errorf("approve in safe mode: %q", reply)
+	}
+	bot.actions.Execution = nil
+	if reply, _ := bot.Execute(PlatformSlack, "UT", "approve", []string{item.ID}); !strings.HasPrefix(reply, "Approving "+item.ID) {
+		t.Errorf("trader approve: %q", reply)
+	}
+	bot.inFlight.Wait()
+	if got, _ := queue.Get(item.ID); got.Status != approvals.StatusExecuted || got.DecidedBy != "slack:UT" {
+		t.Errorf("approved item = %+v", got)
+	}
+	if msg := <-bot.outbox; !strings.Contains(msg, "executed by slack:UT: placed MSFT") {
+		t.Errorf("announcement: %q", msg)
+	}
+	if reply, _ := bot.Execute(PlatformSlack, "UT", "approve", []string{item.ID}); !strings.Contains(reply, "no longer pending") {
+		t.Errorf("second approve: %q", reply)
+	}
+}
+
+func TestSlackAndDiscordRequests(t *testing.T) {
+	bot, _, halted := newBot(t)
+	bot.SetSlack("slack-secret")
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	if err := bot.SetDiscord(hex.EncodeToString(pub)); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	mux := http.NewServeMux()
+	h := NewHandler(bot)
+	h.now = func() time.Time { return now }
+	h.RegisterRoutes(mux)
+
+	slack := func(form url.Values, sign string) *httptest.ResponseRecorder {
+		body := form.Encode()
+		ts := fmt.Sprint(now.Unix())
+		mac := hmac.New(sha256.New, []byte(sign))
+		fmt.Fprintf(mac, "v0:%s:%s", ts, body)
+		req := httptest.NewRequest(http.MethodPost, "/api/chatops/slack", strings.NewReader(body))
+		req.Header.Set("X-Slack-Request-Timestamp", ts)
+		req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	cmd := url.Values{"command": {"/trader"}, "text": {"pnl today"}, "user_id": {"UV"}}
+	if rec := slack(cmd, "wrong"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("bad signature: %d", rec.Code)
+	}
+	rec := slack(cmd, "slack-secret")
+	var out map[string]string
+	json.NewDecoder(rec.Body).Decode(&out)
+	if rec.Code != http.StatusOK || out["response_type"] != "ephemeral" || !strings.HasPrefix(out["text"], "Today: +125.50") {
+		t.Fatalf("slack: %d %+v", rec.Code, out)
+	}
+
+	discord := func(body string) *httptest.ResponseRecorder {
+		ts := fmt.Sprint(now.Unix())
+		req := httptest.NewRequest(http.MethodPost, "/api/chatops/discord", strings.NewReader(body))
+		req.Header.Set("X-Signature-Timestamp", ts)
+		req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(ed25519.Sign(priv, []byte(ts+body))))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := discord(`{"type": 1}`); rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"type":1}` {
+		t.Fatalf("ping: %d %s", rec.Code, rec.Body)
+	}
+	rec = discord(`{"type": 2, "data": {"name": "trader", "options": [{"name": "halt", "type": 1}]}, "member": {"user": {"id": "42"}}}`)
+	var resp struct {
+		Type int `json:"type"`
+		Data struct {
+			Content string `json:"content"`
+			Flags   int    `json:"flags"`
+		} `json:"data"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Type != 4 || resp.Data.Flags != 0 || !strings.Contains(resp.Data.Content, "halted by discord:42") || !*halted {
+		t.Fatalf("discord halt: %+v", resp)
+	}
+}
+
+func TestAnnouncementsArePosted(t *testing.T) {
+	bot, _, _ := newBot(t)
+	got := make(chan map[string]string, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m map[string]string
+		json.NewDecoder(r.Body).Decode(&m)
+		got <- m
+	}))
+	defer srv.Close()
+	bot.AddChannel(PlatformSlack, srv.URL)
+	bot.AddChannel(PlatformDiscord, srv.URL)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go bot.Run(ctx)
+
+	bot.AnnounceSignal(&algorithm.TradeSignal{Symbol: "AAPL", Signal: algorithm.SignalBuy, OrderType: "market", Source: "tradingview"}, "apr_1")
+	first, second := <-got, <-got
+	if !strings.Contains(first["text"], "/approve apr_1") || !strings.HasPrefix(second["content"], "Signal: BUY AAPL market") {
+		t.Fatalf("posted %+v and %+v", first, second)
+	}
+}
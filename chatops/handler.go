@@ -0,0 +1,268 @@
+package chatops
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// maxSkew is how old a signed request may be before it is refused as a
+// possible replay.
+const maxSkew = 5 * time.Minute
+
+// maxBody bounds a command request.
+const maxBody = 64 * 1024
+
+// SetSlack enables Slack commands signed with signingSecret.
+func (b *Bot) SetSlack(signingSecret string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.slackSecret = signingSecret
+}
+
+// SetDiscord enables Discord interactions signed with the application's
+// hex-encoded public key.
+func (b *Bot) SetDiscord(publicKey string) error {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return errors.New("discord public key must be 64 hex characters")
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.discordKey = ed25519.PublicKey(key)
+	return nil
+}
+
+// VerifySlack checks Slack's request signature: "v0=" and the hex
+// HMAC-SHA256 of "v0:<timestamp>:<body>" keyed with the signing secret.
+func VerifySlack(secret, timestamp, signature string, body []byte, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("missing request timestamp")
+	}
+	if d := now.Sub(time.Unix(ts, 0)); d > maxSkew || d < -maxSkew {
+		return errors.New("stale request timestamp")
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	if !hmac.Equal([]byte(signature), []byte("v0="+hex.EncodeToString(mac.Sum(nil)))) {
+		return errors.New("bad signature")
+	}
+	return nil
+}
+
+// VerifyDiscord checks Discord's Ed25519 signature of the timestamp and
+// body.
+func VerifyDiscord(key ed25519.PublicKey, timestamp, signature string, body []byte) error {
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return errors.New("bad signature")
+	}
+	if !ed25519.Verify(key, append([]byte(timestamp), body...), sig) {
+		return errors.New("bad signature")
+	}
+	return nil
+}
+
+// Handler exposes the bot over HTTP.
+type Handler struct {
+	bot *Bot
+	now func() time.Time
+}
+
+// NewHandler creates a handler for bot.
+func NewHandler(bot *Bot) *Handler {
+	return &Handler{bot: bot, now: time.Now}
+}
+
+// RegisterRoutes registers the chat routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// POST /api/chatops/slack - Slack slash commands
+	mux.HandleFunc("/api/chatops/slack", h.handleSlack)
+
+	// POST /api/chatops/discord - Discord interactions
+	mux.HandleFunc("/api/chatops/discord", h.handleDiscord)
+
+	// GET/POST /api/chatops/policy - user roles and what is posted
+	mux.HandleFunc("/api/chatops/policy", h.handlePolicy)
+}
+
+// readBody reads a command request, bounded.
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return nil, false
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBody+1))
+	if err != nil || len(body) > maxBody {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return nil, false
+	}
+	return body, true
+}
+
+func (h *Handler) handleSlack(w http.ResponseWriter, r *http.Request) {
+	h.bot.mu.Lock()
+	secret := h.bot.slackSecret
+	h.bot.mu.Unlock()
+	if secret == "" {
+		http.Error(w, "Slack commands are disabled: no SLACK_SIGNING_SECRET is configured", http.StatusServiceUnavailable)
+		return
+	}
+	body, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+	if err := VerifySlack(secret, r.Header.Get("X-Slack-Request-Timestamp"), r.Header.Get("X-Slack-Signature"), body, h.now()); err != nil {
+		logger().Warn("Refused Slack command", "remote_addr", r.RemoteAddr, "error", err)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	name, args := parse(form.Get("command"), form.Get("text"))
+	reply, loud := h.bot.Execute(PlatformSlack, form.Get("user_id"), name, args)
+	responseType := "ephemeral"
+	if loud {
+		responseType = "in_channel"
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"response_type": responseType, "text": reply})
+}
+
+// Discord interaction, option and response types, and message limits.
+const (
+	discordPing           = 1
+	discordCommand        = 2
+	discordSubcommand     = 1
+	discordPong           = 1
+	discordChannelMessage = 4
+	discordEphemeral      = 1 << 6
+	discordMaxContent     = 2000
+)
+
+type discordOption struct {
+	Name    string          `json:"name"`
+	Type    int             `json:"type"`
+	Value   interface{}     `json:"value"`
+	Options []discordOption `json:"options"`
+}
+
+type discordUser struct {
+	ID string `json:"id"`
+}
+
+type discordInteraction struct {
+	Type int `json:"type"`
+	Data struct {
+		Name    string          `json:"name"`
+		Options []discordOption `json:"options"`
+	} `json:"data"`
+	Member *struct {
+		User discordUser `json:"user"`
+	} `json:"member"`
+	User *discordUser `json:"user"`
+}
+
+func (h *Handler) handleDiscord(w http.ResponseWriter, r *http.Request) {
+	h.bot.mu.Lock()
+	key := h.bot.discordKey
+	h.bot.mu.Unlock()
+	if key == nil {
+		http.Error(w, "Discord commands are disabled: no DISCORD_PUBLIC_KEY is configured", http.StatusServiceUnavailable)
+		return
+	}
+	body, ok := readBody(w, r)
+	if !ok {
+		return
+	}
+	if err := VerifyDiscord(key, r.Header.Get("X-Signature-Timestamp"), r.Header.Get("X-Signature-Ed25519"), body); err != nil {
+		logger().Warn("Refused Discord interaction", "remote_addr", r.RemoteAddr, "error", err)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+	var in discordInteraction
+	if err := json.Unmarshal(body, &in); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	switch in.Type {
+	case discordPing:
+		json.NewEncoder(w).Encode(map[string]int{"type": discordPong})
+		return
+	case discordCommand:
+	default:
+		http.Error(w, "Unsupported interaction type", http.StatusBadRequest)
+		return
+	}
+
+	// A catch-all command such as /trader carries the real one as a
+	// subcommand
+	name, options := in.Data.Name, in.Data.Options
+	if len(options) == 1 && options[0].Type == discordSubcommand {
+		name, options = options[0].Name, options[0].Options
+	}
+	args := make([]string, 0, len(options))
+	for _, o := range options {
+		args = append(args, fmt.Sprint(o.Value))
+	}
+	user := ""
+	if in.Member != nil {
+		user = in.Member.User.ID
+	} else if in.User != nil {
+		user = in.User.ID
+	}
+
+	reply, loud := h.bot.Execute(PlatformDiscord, user, name, args)
+	if len(reply) > discordMaxContent {
+		reply = reply[:discordMaxContent-3] + "..."
+	}
+	data := map[string]interface{}{"content": reply}
+	if !loud {
+		data["flags"] = discordEphemeral
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"type": discordChannelMessage, "data": data})
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.bot.Policy())
+	case http.MethodPost, http.MethodPut:
+		// Start from the current policy so partial updates work
+		policy := h.bot.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		// Users merge into the current ones; an empty role removes one
+		for user, role := range policy.Users {
+			if role == "" {
+				delete(policy.Users, user)
+			}
+		}
+		if err := h.bot.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(h.bot.Policy())
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,281 @@
+// Package circuit trips per-symbol circuit breakers on abnormal market
+// conditions — a trading halt, a blown-out spread, a sudden price gap or
+// quotes that have stopped updating — and keeps signal execution on a
+// tripped symbol suspended until someone resumes it.
+package circuit
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/calendar"
+)
+
+func logger() *slog.Logger { return slog.With("module", "circuit") }
+
+// Trip reasons.
+const (
+	ReasonHalt   = "halt"         // the quote went dark: no bid and no ask
+	ReasonSpread = "spread"       // bid/ask spread beyond MaxSpreadPercent
+	ReasonGap    = "gap"          // trade-to-trade move beyond MaxGapPercent
+	ReasonStale  = "stale_quotes" // quote older than StaleAfterSeconds
+)
+
+// maxTripHistory bounds the resumed-trip log kept in memory.
+const maxTripHistory = 200
+
+// Policy configures what counts as abnormal. Checks only run during the
+// regular session; extended hours are thin enough to trip constantly.
+type Policy struct {
+	Enabled           bool    `json:"enabled"`
+	MaxSpreadPercent  float64 `json:"max_spread_percent"`  // (ask-bid)/mid that trips
+	MaxGapPercent     float64 `json:"max_gap_percent"`     // move between consecutive trades that trips
+	StaleAfterSeconds int     `json:"stale_after_seconds"` // quote age that trips
+}
+
+// DefaultPolicy trips on a 2% spread, a 5% jump between polled trades or
+// a quote more than two minutes old.
+func DefaultPolicy() Policy {
+	return Policy{
+		Enabled:           true,
+		MaxSpreadPercent:  2.0,
+		MaxGapPercent:     5.0,
+		StaleAfterSeconds: 120,
+	}
+}
+
+// Validate checks the policy for usable values.
+func (p Policy) Validate() error {
+	if p.MaxSpreadPercent <= 0 {
+		return errors.New("max_spread_percent must be positive")
+	}
+	if p.MaxGapPercent <= 0 {
+		return errors.New("max_gap_percent must be positive")
+	}
+	if p.StaleAfterSeconds < 1 {
+		return errors.New("stale_after_seconds must be at least 1")
+	}
+	return nil
+}
+
+// Observation is one polled quote and trade for a symbol. Zero fields are
+// not checked.
+type Observation struct {
+	Symbol    string
+	Bid       float64
+	Ask       float64
+	QuoteTime time.Time
+	Price     float64 // last trade
+}
+
+// Trip records a symbol whose breaker tripped.
+type Trip struct {
+	Symbol    string     `json:"symbol"`
+	Reason    string     `json:"reason"`
+	Detail    string     `json:"detail"`
+	Value     float64    `json:"value"` // spread or gap percent, quote age in seconds
+	TrippedAt time.Time  `json:"tripped_at"`
+	ResumedAt *time.Time `json:"resumed_at,omitempty"`
+}
+
+// Notifier is told about every new trip.
+type Notifier func(title, message string, metadata map[string]interface{})
+
+// lastTrade is the previous trade seen for a symbol and its session.
+type lastTrade struct {
+	price   float64
+	session string
+}
+
+// Manager evaluates observations against the policy and tracks tripped
+// symbols.
+type Manager struct {
+	cal *calendar.Calendar
+
+	mu      sync.RWMutex
+	policy  Policy
+	notify  Notifier
+	last    map[string]lastTrade
+	tripped map[string]*Trip
+	history []Trip
+}
+
+// NewManager returns a manager with the given policy.
+func NewManager(cal *calendar.Calendar, policy Policy) *Manager {
+	return &Manager{
+		cal:     cal,
+		policy:  policy,
+		last:    make(map[string]lastTrade),
+		tripped: make(map[string]*Trip),
+	}
+}
+
+// SetNotifier registers a callback for new trips.
+func (m *Manager) SetNotifier(n Notifier) { m.mu.Lock(); m.notify = n; m.mu.Unlock() }
+
+// Policy returns the current policy.
+func (m *Manager) Policy() Policy {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.policy
+}
+
+// SetPolicy validates and replaces the policy.
+func (m *Manager) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policy = p
+	return nil
+}
+
+// Check evaluates one observation against p and returns the first
+// abnormal condition found. prev is the symbol's previous trade price in
+// the same session, zero when there is none. Pure; Observe applies trips.
+func Check(p Policy, obs Observation, prev float64, now time.Time) (Trip, bool) {
+	trip := Trip{Symbol: strings.ToUpper(obs.Symbol), TrippedAt: now}
+	hasQuote := !obs.QuoteTime.IsZero()
+
+	switch {
+	case hasQuote && obs.Bid <= 0 && obs.Ask <= 0:
+		trip.Reason = ReasonHalt
+		trip.Detail = "no bid or ask during the regular session"
+		return trip, true
+	case hasQuote && now.Sub(obs.QuoteTime) > time.Duration(p.StaleAfterSeconds)*time.Second:
+		age := now.Sub(obs.QuoteTime).Seconds()
+		trip.Reason = ReasonStale
+		trip.Value = math.Round(age*100) / 100
+		trip.Detail = fmt.Sprintf("last quote is %.0fs old", age)
+		return trip, true
+	}
+
+	if obs.Bid > 0 && obs.Ask > 0 {
+		mid := (obs.Bid + obs.Ask) / 2
+		spread := (obs.Ask - obs.Bid) / mid * 100
+		if spread > p.MaxSpreadPercent {
+			trip.Reason = ReasonSpread
+			trip.Value = math.Round(spread*100) / 100
+			trip.Detail = fmt.Sprintf("spread %.2f%% (bid %.2f, ask %.2f)", spread, obs.Bid, obs.Ask)
+			return trip, true
+		}
+	}
+
+	if prev > 0 && obs.Price > 0 {
+		gap := (obs.Price/prev - 1) * 100
+		if gap > p.MaxGapPercent || gap < -p.MaxGapPercent {
+			trip.Reason = ReasonGap
+			trip.Value = math.Round(gap*100) / 100
+			trip.Detail = fmt.Sprintf("price moved %+.2f%% between trades (%.2f to %.2f)", gap, prev, obs.Price)
+			return trip, true
+		}
+	}
+	return Trip{}, false
+}
+
+// Observe checks obs at now and trips the symbol's breaker on an abnormal
+// condition. The new trip, if any, is returned; an already tripped symbol
+// is not re-evaluated.
+func (m *Manager) Observe(obs Observation, now time.Time) (Trip, bool) {
+	symbol := strings.ToUpper(obs.Symbol)
+	session, open := m.cal.SessionFor(now)
+	open = open && m.cal.IsOpen(now)
+	sessionKey := session.Date.Format("2006-01-02")
+
+	m.mu.Lock()
+	policy := m.policy
+	prev := m.last[symbol]
+	if obs.Price > 0 {
+		m.last[symbol] = lastTrade{price: obs.Price, session: sessionKey}
+	}
+	if _, already := m.tripped[symbol]; already || !policy.Enabled || !open {
+		m.mu.Unlock()
+		return Trip{}, false
+	}
+	// Overnight moves are the gap-risk controls' business
+	prevPrice := 0.0
+	if prev.session == sessionKey {
+		prevPrice = prev.price
+	}
+	trip, abnormal := Check(policy, obs, prevPrice, now)
+	if !abnormal {
+		m.mu.Unlock()
+		return Trip{}, false
+	}
+	m.tripped[symbol] = &trip
+	notify := m.notify
+	m.mu.Unlock()
+
+	logger().Warn("Circuit breaker tripped, suspending symbol", "symbol", symbol, "reason", trip.Reason, "detail", trip.Detail)
+	if notify != nil {
+		notify(fmt.Sprintf("%s circuit breaker tripped — execution suspended", symbol),
+			fmt.Sprintf("%s: %s. Signal execution is suspended until resumed via /api/symbols/%s/resume.",
+				symbol, trip.Detail, symbol),
+			map[string]interface{}{"symbol": symbol, "reason": trip.Reason, "value": trip.Value})
+	}
+	return trip, true
+}
+
+// CheckSymbol returns an error if symbol's breaker is tripped. It has the
+// shape of an algorithm trade guard.
+func (m *Manager) CheckSymbol(symbol string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if t, ok := m.tripped[strings.ToUpper(symbol)]; ok {
+		return fmt.Errorf("%s circuit breaker tripped (%s: %s); resume via /api/symbols/%s/resume",
+			t.Symbol, t.Reason, t.Detail, t.Symbol)
+	}
+	return nil
+}
+
+// Tripped lists symbols with a tripped breaker, sorted by symbol.
+func (m *Manager) Tripped() []Trip {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]Trip, 0, len(m.tripped))
+	for _, t := range m.tripped {
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Symbol < out[j].Symbol })
+	return out
+}
+
+// History returns resumed trips, most recent first.
+func (m *Manager) History() []Trip {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]Trip, len(m.history))
+	for i, t := range m.history {
+		out[len(m.history)-1-i] = t
+	}
+	return out
+}
+
+// Resume resets a tripped breaker and re-enables execution on symbol. The
+// last trade is forgotten so the next one is not measured against a price
+// from before the trip.
+func (m *Manager) Resume(symbol string) (Trip, error) {
+	symbol = strings.ToUpper(symbol)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tripped[symbol]
+	if !ok {
+		return Trip{}, fmt.Errorf("%s is not tripped", symbol)
+	}
+	now := time.Now()
+	t.ResumedAt = &now
+	delete(m.tripped, symbol)
+	delete(m.last, symbol)
+	m.history = append(m.history, *t)
+	if len(m.history) > maxTripHistory {
+		m.history = m.history[len(m.history)-maxTripHistory:]
+	}
+	return *t, nil
+}
@@ -0,0 +1,93 @@
+package circuit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/calendar"
+)
+
+func TestCheck(t *testing.T) {
+	p := DefaultPolicy()
+	now := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	fresh := now.Add(-5 * time.Second)
+
+	cases := []struct {
+		name   string
+		obs    Observation
+		prev   float64
+		reason string
+	}{
+		{"normal", Observation{Bid: 99.99, Ask: 100.01, QuoteTime: fresh, Price: 100}, 99.5, ""},
+		{"halt", Observation{QuoteTime: fresh, Price: 100}, 0, ReasonHalt},
+		{"stale", Observation{Bid: 99.99, Ask: 100.01, QuoteTime: now.Add(-3 * time.Minute)}, 0, ReasonStale},
+		{"spread", Observation{Bid: 97, Ask: 100, QuoteTime: fresh}, 0, ReasonSpread},
+		{"gap down", Observation{Bid: 93.99, Ask: 94.01, QuoteTime: fresh, Price: 94}, 100, ReasonGap},
+		{"no quote", Observation{Price: 100}, 100, ""},
+	}
+	for _, c := range cases {
+		trip, tripped := Check(p, c.obs, c.prev, now)
+		if tripped != (c.reason != "") || trip.Reason != c.reason {
+			t.Errorf("%s: trip = %+v, %v; want %q", c.name, trip, tripped, c.reason)
+		}
+	}
+}
+
+func TestManagerTripsAndResumes(t *testing.T) {
+	cal := calendar.New()
+	loc := cal.Location()
+	m := NewManager(cal, DefaultPolicy())
+	var notified []string
+	m.SetNotifier(func(title, message string, metadata map[string]interface{}) {
+		notified = append(notified, metadata["reason"].(string))
+	})
+
+	// Wednesday 1 May 2024, 10:00 New York
+	open := time.Date(2024, 5, 1, 10, 0, 0, 0, loc)
+	quote := func(price float64, at time.Time) Observation {
+		return Observation{Symbol: "aapl", Bid: price - 0.01, Ask: price + 0.01, QuoteTime: at, Price: price}
+	}
+
+	// The prior evening's price is not a base for an intraday gap
+	m.Observe(quote(100, time.Date(2024, 4, 30, 17, 0, 0, 0, loc)), time.Date(2024, 4, 30, 17, 0, 0, 0, loc))
+	if _, tripped := m.Observe(quote(110, open), open); tripped {
+		t.Fatal("overnight move tripped the breaker")
+	}
+	if err := m.CheckSymbol("AAPL"); err != nil {
+		t.Fatal(err)
+	}
+
+	trip, tripped := m.Observe(quote(100, open.Add(5*time.Second)), open.Add(5*time.Second))
+	if !tripped || trip.Reason != ReasonGap || trip.Symbol != "AAPL" {
+		t.Fatalf("intraday gap trip = %+v, %v", trip, tripped)
+	}
+	if m.CheckSymbol("aapl") == nil {
+		t.Error("tripped symbol passes the guard")
+	}
+	if _, again := m.Observe(Observation{Symbol: "AAPL", QuoteTime: open}, open.Add(10*time.Second)); again {
+		t.Error("tripped symbol re-evaluated")
+	}
+	if len(notified) != 1 || notified[0] != ReasonGap {
+		t.Errorf("notifications = %v", notified)
+	}
+
+	if _, err := m.Resume("AAPL"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Resume("AAPL"); err == nil {
+		t.Error("resumed an untripped symbol")
+	}
+	if m.CheckSymbol("AAPL") != nil || len(m.History()) != 1 {
+		t.Error("resume did not re-enable the symbol")
+	}
+	// After a resume the next trade is not measured against the old price
+	if _, tripped := m.Observe(quote(120, open.Add(time.Minute)), open.Add(time.Minute)); tripped {
+		t.Error("tripped against the pre-resume price")
+	}
+
+	// Outside the regular session nothing trips
+	evening := time.Date(2024, 5, 1, 18, 0, 0, 0, loc)
+	if _, tripped := m.Observe(Observation{Symbol: "MSFT", QuoteTime: evening}, evening); tripped {
+		t.Error("after-hours halt tripped the breaker")
+	}
+}
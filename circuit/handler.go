@@ -0,0 +1,83 @@
+package circuit
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Handler exposes the circuit breakers over HTTP.
+type Handler struct {
+	manager *Manager
+}
+
+// NewHandler creates a handler for manager.
+func NewHandler(manager *Manager) *Handler {
+	return &Handler{manager: manager}
+}
+
+// RegisterRoutes registers the circuit breaker routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/symbols/breakers - policy, tripped symbols, resumed history
+	mux.HandleFunc("/api/symbols/breakers", h.handleStatus)
+
+	// GET/POST /api/symbols/breakers/policy - read or update the policy
+	mux.HandleFunc("/api/symbols/breakers/policy", h.handlePolicy)
+
+	// POST /api/symbols/{symbol}/resume - reset a tripped breaker
+	mux.HandleFunc("/api/symbols/", h.handleResume)
+}
+
+func (h *Handler) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"policy":  h.manager.Policy(),
+		"tripped": h.manager.Tripped(),
+		"history": h.manager.History(),
+	})
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.manager.Policy())
+	case http.MethodPost, http.MethodPut:
+		// Start from the current policy so partial updates work
+		policy := h.manager.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.manager.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(policy)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleResume(w http.ResponseWriter, r *http.Request) {
+	symbol, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/symbols/"), "/")
+	if symbol == "" || action != "resume" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	trip, err := h.manager.Resume(symbol)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"resumed": trip,
+	})
+}
@@ -13,6 +13,12 @@ func NewWebSocketAdapterWrapper(serverURL string) *WebSocketAdapterWrapper {
 	}
 }
 
+// SetContextURL sets the endpoint the adapter reads signal contexts back
+// from.
+func (w *WebSocketAdapterWrapper) SetContextURL(contextURL string) {
+	w.adapter.SetContextURL(contextURL)
+}
+
 // GenerateTradeSignal implements the algorithm.ClaudeClientInterface method
 // with the exact signature the algorithm package expects
 func (w *WebSocketAdapterWrapper) GenerateTradeSignal(
@@ -28,6 +34,16 @@ func (w *WebSocketAdapterWrapper) GenerateTradeSignal(
 		Low24h:    marketData.Low24h,
 		Volume24h: marketData.Volume24h,
 		Change24h: marketData.Change24h,
+		Patterns:  marketData.Patterns,
+
+		PrevClose:          marketData.PrevClose,
+		ChangeSession:      marketData.ChangeSession,
+		ImpliedMovePercent: marketData.ImpliedMovePercent,
+		Breadth:            marketData.Breadth,
+		Features:           marketData.Features,
+		Session:            marketData.Session,
+		ContextID:          marketData.ContextID,
+		ContextToken:       marketData.ContextToken,
 	}
 	
 	claudePositions := make(map[string]PositionData)
@@ -71,6 +87,10 @@ func (w *WebSocketAdapterWrapper) GenerateTradeSignal(
 		Timestamp:  claudeSignal.Timestamp,
 		Reasoning:  claudeSignal.Reasoning,
 		Confidence: confidence,
+		Analysis:   claudeSignal.Analysis,
+		Usage:      claudeSignal.Usage,
+
+		PromptVariant: claudeSignal.PromptVariant,
 	}, nil
 }
 
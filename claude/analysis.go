@@ -0,0 +1,246 @@
+package claude
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// ErrNoAnalysis is returned by ParseAnalysis for responses carrying no
+// JSON object.
+var ErrNoAnalysis = errors.New("no structured analysis in response")
+
+// Time horizons an analysis may state.
+const (
+	HorizonIntraday = "intraday"
+	HorizonSwing    = "swing"    // days to a few weeks
+	HorizonPosition = "position" // weeks to months
+)
+
+// Analysis is the structured reasoning behind a signal.
+type Analysis struct {
+	Thesis   string   `json:"thesis"`
+	KeyRisks []string `json:"key_risks"`
+	// InvalidationLevel is the price at which the thesis is wrong: below
+	// the entry for a long, above it for a short
+	InvalidationLevel *float64 `json:"invalidation_level,omitempty"`
+	TimeHorizon       string   `json:"time_horizon"`
+}
+
+// AnalysisSchema is the JSON schema analyses are validated against. It is
+// sent with each signal request so the server can ask Claude for it.
+const AnalysisSchema = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "Trade signal analysis",
+  "type": "object",
+  "required": ["thesis", "key_risks", "time_horizon"],
+  "properties": {
+    "thesis": {"type": "string", "minLength": 1, "maxLength": 2000},
+    "key_risks": {
+      "type": "array",
+      "items": {"type": "string", "minLength": 1},
+      "maxItems": 10
+    },
+    "invalidation_level": {"type": ["number", "null"], "exclusiveMinimum": 0},
+    "time_horizon": {"type": "string", "enum": ["intraday", "swing", "position"]}
+  }
+}`
+
+var analysisSchema = mustSchema(AnalysisSchema)
+
+func mustSchema(s string) map[string]interface{} {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &schema); err != nil {
+		panic(fmt.Sprintf("claude: invalid schema: %v", err))
+	}
+	return schema
+}
+
+// ParseAnalysis extracts the analysis from a response's text: the first
+// JSON object in it, possibly in a code fence, or that object's "analysis"
+// member when it has one. The analysis must satisfy AnalysisSchema.
+func ParseAnalysis(text string) (*Analysis, error) {
+	raw, ok := firstJSONObject(text)
+	if !ok {
+		return nil, ErrNoAnalysis
+	}
+	var envelope struct {
+		Analysis json.RawMessage `json:"analysis"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err == nil && len(envelope.Analysis) > 0 && string(envelope.Analysis) != "null" {
+		raw = envelope.Analysis
+	}
+	return ValidateAnalysis(raw)
+}
+
+// ValidateAnalysis checks raw against AnalysisSchema and decodes it.
+func ValidateAnalysis(raw []byte) (*Analysis, error) {
+	var doc interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("invalid analysis JSON: %w", err)
+	}
+	if err := validateSchema(analysisSchema, doc, ""); err != nil {
+		return nil, fmt.Errorf("analysis does not match the schema: %w", err)
+	}
+	var a Analysis
+	if err := json.Unmarshal(raw, &a); err != nil {
+		return nil, fmt.Errorf("invalid analysis: %w", err)
+	}
+	return &a, nil
+}
+
+// firstJSONObject returns the first balanced, valid JSON object in text.
+func firstJSONObject(text string) ([]byte, bool) {
+	for start := strings.IndexByte(text, '{'); start >= 0; {
+		if end, ok := objectEnd(text, start); ok && json.Valid([]byte(text[start:end])) {
+			return []byte(text[start:end]), true
+		}
+		next := strings.IndexByte(text[start+1:], '{')
+		if next < 0 {
+			break
+		}
+		start += next + 1
+	}
+	return nil, false
+}
+
+// objectEnd returns the end of the object opening at text[start],
+// ignoring braces inside strings.
+func objectEnd(text string, start int) (int, bool) {
+	depth, inString, escaped := 0, false, false
+	for i := start; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return i + 1, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// validateSchema checks v against the subset of JSON schema the package's
+// schemas use: type, enum, required, properties, items, minLength,
+// maxLength, maxItems and exclusiveMinimum.
+func validateSchema(schema map[string]interface{}, v interface{}, path string) error {
+	at := path
+	if at == "" {
+		at = "/"
+	}
+	if t, ok := schema["type"]; ok && !matchesType(t, v) {
+		return fmt.Errorf("%s: want %v, got %s", at, t, jsonType(v))
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if e == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not one of %v", at, v, enum)
+		}
+	}
+	switch val := v.(type) {
+	case string:
+		if n, ok := schema["minLength"].(float64); ok && float64(len(strings.TrimSpace(val))) < n {
+			return fmt.Errorf("%s: shorter than %.0f characters", at, n)
+		}
+		if n, ok := schema["maxLength"].(float64); ok && float64(len(val)) > n {
+			return fmt.Errorf("%s: longer than %.0f characters", at, n)
+		}
+	case float64:
+		if n, ok := schema["exclusiveMinimum"].(float64); ok && val <= n {
+			return fmt.Errorf("%s: %v is not above %v", at, val, n)
+		}
+	case []interface{}:
+		if n, ok := schema["maxItems"].(float64); ok && float64(len(val)) > n {
+			return fmt.Errorf("%s: more than %.0f items", at, n)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range val {
+				if err := validateSchema(items, item, fmt.Sprintf("%s/%d", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case map[string]interface{}:
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, r := range required {
+				if _, ok := val[r.(string)]; !ok {
+					return fmt.Errorf("%s: missing %q", at, r)
+				}
+			}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		names := make([]string, 0, len(props))
+		for name := range props {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			member, ok := val[name]
+			if !ok {
+				continue
+			}
+			sub, _ := props[name].(map[string]interface{})
+			if err := validateSchema(sub, member, path+"/"+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// matchesType reports whether v is of the schema type t, a name or a list
+// of names.
+func matchesType(t, v interface{}) bool {
+	switch t := t.(type) {
+	case string:
+		got := jsonType(v)
+		return got == t || (t == "number" && got == "integer")
+	case []interface{}:
+		for _, name := range t {
+			if matchesType(name, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jsonType(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
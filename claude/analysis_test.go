@@ -0,0 +1,62 @@
+package claude
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadSignalStreamAggregatesAnalysis(t *testing.T) {
+	stream := strings.Join([]string{
+		`{"id":"req_1","status":"stream","chunk":"Here is my view.\n` + "```json" + `\n{\"signal\": \"buy\", \"analysis\": {\"thesis\": \"Breakout above {resistance}"}`,
+		`{"id":"req_1","status":"stream","chunk":" on volume\", \"key_risks\": [\"earnings\", \"rates\"], \"invalidation_level\": 182.5, \"time_horizon\": \"swing\"}}\n` + "```" + `"}`,
+		`{"id":"req_1","status":"success","signal":{"symbol":"AAPL","signal":"buy","order_type":"market"}}`,
+	}, "\n")
+	var chunks []string
+	signal, err := readSignalStream(strings.NewReader(stream), "AAPL", func(symbol, chunk string) { chunks = append(chunks, chunk) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chunks) != 2 {
+		t.Errorf("chunks = %q", chunks)
+	}
+	a := signal.Analysis
+	if a == nil || a.Thesis != "Breakout above {resistance} on volume" || len(a.KeyRisks) != 2 ||
+		a.InvalidationLevel == nil || *a.InvalidationLevel != 182.5 || a.TimeHorizon != HorizonSwing {
+		t.Fatalf("analysis = %+v", a)
+	}
+	if signal.Reasoning != a.Thesis {
+		t.Errorf("reasoning = %q", signal.Reasoning)
+	}
+
+	// A single message, with free text reasoning
+	plain, err := readSignalStream(strings.NewReader(`{"status":"success","signal":{"symbol":"AAPL","signal":"hold","reasoning":"Range bound"}}`), "AAPL", nil)
+	if err != nil || plain.Analysis != nil || plain.Reasoning != "Range bound" {
+		t.Fatalf("plain = %+v, %v", plain, err)
+	}
+	if _, err := readSignalStream(strings.NewReader(`{"status":"stream","chunk":"partial"}`), "AAPL", nil); err == nil {
+		t.Error("truncated stream accepted")
+	}
+}
+
+func TestValidateAnalysisAgainstSchema(t *testing.T) {
+	for name, raw := range map[string]string{
+		"missing thesis":     `{"key_risks": [], "time_horizon": "swing"}`,
+		"blank thesis":       `{"thesis": "  ", "key_risks": [], "time_horizon": "swing"}`,
+		"unknown horizon":    `{"thesis": "x", "key_risks": [], "time_horizon": "forever"}`,
+		"risk not a string":  `{"thesis": "x", "key_risks": [3], "time_horizon": "swing"}`,
+		"negative level":     `{"thesis": "x", "key_risks": [], "time_horizon": "swing", "invalidation_level": -1}`,
+		"level not a number": `{"thesis": "x", "key_risks": [], "time_horizon": "swing", "invalidation_level": "180"}`,
+	} {
+		if _, err := ValidateAnalysis([]byte(raw)); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+	a, err := ValidateAnalysis([]byte(`{"thesis": "x", "key_risks": [], "time_horizon": "intraday", "invalidation_level": null}`))
+	if err != nil || a.InvalidationLevel != nil {
+		t.Fatalf("analysis = %+v, %v", a, err)
+	}
+	if _, err := ParseAnalysis("No JSON here"); !errors.Is(err, ErrNoAnalysis) {
+		t.Errorf("err = %v", err)
+	}
+}
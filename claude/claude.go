@@ -2,11 +2,14 @@ package claude
 
 import (
 	"net/http"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 )
 
+// logger tags the package's records with its module, for per-module levels.
+func logger() *slog.Logger { return slog.With("module", "claude") }
+
 // Client is a client for the Claude API
 type Client struct {
 	apiKey      string
@@ -20,12 +23,74 @@ type Client struct {
 
 // MarketData represents market data for a symbol
 type MarketData struct {
-	Symbol    string  `json:"symbol"`
-	Price     float64 `json:"price"`
-	High24h   float64 `json:"high_24h"`
-	Low24h    float64 `json:"low_24h"`
-	Volume24h float64 `json:"volume_24h"`
-	Change24h float64 `json:"change_24h"` // Percentage
+	Symbol    string   `json:"symbol"`
+	Price     float64  `json:"price"`
+	High24h   float64  `json:"high_24h"`
+	Low24h    float64  `json:"low_24h"`
+	Volume24h float64  `json:"volume_24h"`
+	Change24h float64  `json:"change_24h"`         // Percentage, against PrevClose when known
+	Patterns  []string `json:"patterns,omitempty"` // Candlestick patterns on the latest bar
+	// PrevClose is the prior session's close and ChangeSession the
+	// percentage move since the current session's open
+	PrevClose     float64 `json:"prev_close,omitempty"`
+	ChangeSession float64 `json:"change_session"`
+	// ImpliedMovePercent is the options market's expected move through
+	// the next event or expiry
+	ImpliedMovePercent float64 `json:"implied_move_percent,omitempty"`
+	// Breadth is the health of the wider market, when it is measured
+	Breadth *MarketBreadth `json:"breadth,omitempty"`
+	// Features are the symbol's stored features as of its latest closed
+	// daily bar
+	Features *SymbolFeatures `json:"features,omitempty"`
+	// Session is the symbol's statistics this session, after its first bar
+	Session *SessionStats `json:"session,omitempty"`
+	// ContextID names the signal context kept by the Go process, readable
+	// at the request's context URL
+	ContextID string `json:"context_id,omitempty"`
+	// ContextToken lets the context URL be read once without an API
+	// token; it is never serialized
+	ContextToken string `json:"-"`
+}
+
+// SymbolFeatures are a symbol's indicators, volatility, regime and bar
+// microstructure as of a closed bar
+type SymbolFeatures struct {
+	Timeframe string             `json:"timeframe"`
+	AsOf      time.Time          `json:"as_of"` // when the bar closed
+	Regime    string             `json:"regime,omitempty"`
+	Values    map[string]float64 `json:"values"`
+}
+
+// SessionStats are a symbol's open, range, VWAP, volume, realized
+// volatility and spread for the current session
+type SessionStats struct {
+	Open        float64   `json:"open"`
+	High        float64   `json:"high"`
+	Low         float64   `json:"low"`
+	VWAP        float64   `json:"vwap"`
+	Volume      float64   `json:"volume"`
+	AvgVolume20 float64   `json:"avg_volume_20,omitempty"`
+	VolumeRatio float64   `json:"volume_ratio,omitempty"`
+	VolumePace  float64   `json:"volume_pace,omitempty"`
+	AsOf        time.Time `json:"as_of"`
+	// RealizedVolatility is annualized, in percent
+	RealizedVolatility float64 `json:"realized_volatility"`
+	Spread             float64 `json:"spread,omitempty"`
+	SpreadBps          float64 `json:"spread_bps,omitempty"`
+}
+
+// MarketBreadth is the health of the wider market: how an index universe
+// moved on its latest session
+type MarketBreadth struct {
+	Advancers      int     `json:"advancers"`
+	Decliners      int     `json:"decliners"`
+	PercentAboveMA float64 `json:"percent_above_ma"`
+	NewHighs       int     `json:"new_highs"`
+	NewLows        int     `json:"new_lows"`
+	// Score runs from -1, every measure bearish, to 1, every measure bullish
+	Score  float64   `json:"score"`
+	Health string    `json:"health"` // strong, neutral or weak
+	AsOf   time.Time `json:"as_of"`
 }
 
 // TradeSignal represents a trading signal with reasoning
@@ -37,6 +102,14 @@ type TradeSignal struct {
 	Timestamp  time.Time `json:"timestamp"`
 	Reasoning  string    `json:"reasoning"`   // explanation for the decision
 	Margin     float64   `json:"margin"`      // leverage margin
+	// Analysis is the structured reasoning, nil when the response had
+	// none that satisfies AnalysisSchema
+	Analysis *Analysis `json:"analysis,omitempty"`
+	// Usage is the tokens the request consumed, reported or estimated
+	Usage *Usage `json:"usage,omitempty"`
+	// PromptVariant names the prompt the request was sent with, so the
+	// variants' signals can be compared later
+	PromptVariant string `json:"prompt_variant,omitempty"`
 }
 
 // PositionData represents a trading position
@@ -75,10 +148,14 @@ type SignalAdapter interface {
 	GenerateSignal(symbol string) (*TradeSignal, error)
 }
 
+// DefaultAdapterURL is the frontend server signal requests go to unless
+// one is configured.
+const DefaultAdapterURL = "http://localhost:3000"
+
 // NewClient creates a new Claude client
 func NewClient(apiKey, apiURL string, adapter SignalAdapter) *Client {
 	if adapter == nil {
-		adapter = NewWebSocketAdapter("http://localhost:3000")
+		adapter = NewWebSocketAdapter(DefaultAdapterURL)
 	}
 	
 	return &Client{
@@ -97,7 +174,7 @@ func (c *Client) RegisterStreamCallback(callback StreamCallback) {
 
 // GenerateSignal generates a trading signal for a symbol
 func (c *Client) GenerateSignal(symbol string) (*TradeSignal, error) {
-	log.Printf("[DEBUG] GenerateSignal called for symbol: %s", symbol)
+	logger().Debug("GenerateSignal called", "symbol", symbol)
 
 	// Check if we have a cached signal
 	c.signalMutex.RLock()
@@ -106,17 +183,16 @@ func (c *Client) GenerateSignal(symbol string) (*TradeSignal, error) {
 
 	// If we have a recent cached signal (less than 10 minutes old), return it
 	if exists && time.Since(cachedSignal.Timestamp) < 10*time.Minute {
-		log.Printf("[DEBUG] Using cached signal for %s (age: %v)", symbol, time.Since(cachedSignal.Timestamp))
+		logger().Debug("Using cached signal", "symbol", symbol, "age", time.Since(cachedSignal.Timestamp))
 		return cachedSignal, nil
 	}
 
-	log.Printf("[INFO] No recent cached signal found, generating new signal from algorithm for %s", symbol)
+	logger().Info("No recent cached signal, generating a new one", "symbol", symbol)
 	
 	// Use the adapter to generate a real signal
 	signal, err := c.adapter.GenerateSignal(symbol)
 	if err != nil {
-		log.Printf("[ERROR] Error generating signal via adapter: %v", err)
-		log.Printf("[ERROR] Failed to generate signal for %s: %v", symbol, err)
+		logger().Error("Failed to generate signal via adapter", "symbol", symbol, "error", err)
 		return nil, err
 	}
 
@@ -124,7 +200,7 @@ func (c *Client) GenerateSignal(symbol string) (*TradeSignal, error) {
 	c.signalMutex.Lock()
 	c.signals[symbol] = signal
 	c.signalMutex.Unlock()
-	log.Printf("[DEBUG] Generated and cached algorithmic signal for %s: %s", symbol, signal.Signal)
+	logger().Debug("Generated and cached algorithmic signal", "symbol", symbol, "signal", signal.Signal)
 
 	return signal, nil
 }
@@ -132,16 +208,15 @@ func (c *Client) GenerateSignal(symbol string) (*TradeSignal, error) {
 // GenerateTradeSignal generates a trading signal with full market data
 func (c *Client) GenerateTradeSignal(symbol string, marketData MarketData, portfolioData PortfolioData, opts *StreamOptions) (*TradeSignal, error) {
 	// Enhanced version of GenerateSignal that takes additional context
-	log.Printf("[DEBUG] GenerateTradeSignal called for %s with market data: price=%.2f, change=%.2f%%", 
-		symbol, marketData.Price, marketData.Change24h)
+	logger().Debug("GenerateTradeSignal called", "symbol", symbol, "price", marketData.Price, "change_24h", marketData.Change24h)
 	
-	log.Printf("[INFO] Generating real trading signal using algorithm analysis for %s", symbol)
+	logger().Info("Generating trading signal from algorithm analysis", "symbol", symbol)
 	// If real API is specified, use the adapter directly
 	if opts != nil && opts.UseRealAPI {
-		log.Printf("[DEBUG] Using real API for %s", symbol)
+		logger().Debug("Using real API", "symbol", symbol)
 		signal, err := c.adapter.GenerateTradeSignal(symbol, marketData, portfolioData)
 		if err != nil {
-			log.Printf("[ERROR] Error calling real API: %v", err)
+			logger().Error("Failed to call real API", "symbol", symbol, "error", err)
 			return nil, err
 		}
 		
@@ -4,12 +4,30 @@ import "time"
 
 // AlgorithmMarketData represents market data with the same structure as algorithm.MarketData
 type AlgorithmMarketData struct {
-	Symbol    string  `json:"symbol"`
-	Price     float64 `json:"price"`
-	High24h   float64 `json:"high_24h"`
-	Low24h    float64 `json:"low_24h"`
-	Volume24h float64 `json:"volume_24h"`
-	Change24h float64 `json:"change_24h"` // Percentage
+	Symbol    string   `json:"symbol"`
+	Price     float64  `json:"price"`
+	High24h   float64  `json:"high_24h"`
+	Low24h    float64  `json:"low_24h"`
+	Volume24h float64  `json:"volume_24h"`
+	Change24h float64  `json:"change_24h"`         // Percentage, against PrevClose when known
+	Patterns  []string `json:"patterns,omitempty"` // Candlestick patterns on the latest bar
+	// PrevClose is the prior session's close and ChangeSession the
+	// percentage move since the current session's open
+	PrevClose     float64 `json:"prev_close,omitempty"`
+	ChangeSession float64 `json:"change_session"`
+	// ImpliedMovePercent is the options market's expected move through
+	// the next event or expiry
+	ImpliedMovePercent float64 `json:"implied_move_percent,omitempty"`
+	// Breadth is the health of the wider market, when it is measured
+	Breadth *MarketBreadth `json:"breadth,omitempty"`
+	// Features are the symbol's stored features, when there are any
+	Features *SymbolFeatures `json:"features,omitempty"`
+	// Session is the symbol's statistics this session, when it has begun
+	Session *SessionStats `json:"session,omitempty"`
+	// ContextID names the kept signal context this data came from
+	ContextID string `json:"context_id,omitempty"`
+	// ContextToken reads that context once; it is never serialized
+	ContextToken string `json:"-"`
 }
 
 // AlgorithmPositionData represents position data with the same structure as algorithm.PositionData
@@ -40,6 +58,10 @@ type AlgorithmTradeSignal struct {
 	Timestamp  time.Time `json:"timestamp"`
 	Reasoning  string    `json:"reasoning"`
 	Confidence *float64  `json:"confidence,omitempty"` // Confidence score from 0-1, nil if not provided
+	Analysis   *Analysis `json:"analysis,omitempty"`   // Structured reasoning, nil if not provided
+	Usage      *Usage    `json:"usage,omitempty"`      // Tokens the request consumed
+	// PromptVariant names the prompt the request was sent with
+	PromptVariant string `json:"prompt_variant,omitempty"`
 }
 
 // GenerateTradeSignalForAlgorithm adapts the WebSocketAdapter for the algorithm package
@@ -56,6 +78,13 @@ func (a *WebSocketAdapter) GenerateTradeSignalForAlgorithm(
 		Low24h:    marketData.Low24h,
 		Volume24h: marketData.Volume24h,
 		Change24h: marketData.Change24h,
+		Patterns:  marketData.Patterns,
+
+		PrevClose:          marketData.PrevClose,
+		ChangeSession:      marketData.ChangeSession,
+		ImpliedMovePercent: marketData.ImpliedMovePercent,
+		Breadth:            marketData.Breadth,
+		Features:           marketData.Features,
 	}
 	
 	claudePositions := make(map[string]PositionData)
@@ -99,5 +128,9 @@ func (a *WebSocketAdapter) GenerateTradeSignalForAlgorithm(
 		Timestamp:  claudeSignal.Timestamp,
 		Reasoning:  claudeSignal.Reasoning,
 		Confidence: confidence,
+		Analysis:   claudeSignal.Analysis,
+		Usage:      claudeSignal.Usage,
+
+		PromptVariant: claudeSignal.PromptVariant,
 	}, nil
 }
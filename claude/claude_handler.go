@@ -3,7 +3,6 @@ package claude
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"sync"
 
@@ -42,20 +41,19 @@ func NewClaudeHandler(claudeClient *Client) *ClaudeHandler {
 
 // HandleWebSocket handles WebSocket connections for Claude trading signals
 func (h *ClaudeHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
-	log.Printf("HandleWebSocket called with URL: %s", r.URL.String())
+	logger().Debug("HandleWebSocket called", "url", r.URL.String())
 	// Get symbol from query parameter
 	symbol := r.URL.Query().Get("symbol")
 	if symbol == "" {
 		http.Error(w, "Symbol parameter is required", http.StatusBadRequest)
-		log.Printf("WebSocket connection rejected: missing symbol parameter")
+		logger().Warn("WebSocket connection rejected: missing symbol parameter")
 		return
 	}
 
 	// Upgrade HTTP connection to WebSocket
 	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("Error upgrading to WebSocket: %v", err)
-		log.Printf("Connection headers: %v", r.Header)
+		logger().Error("Failed to upgrade to WebSocket", "error", err, "headers", r.Header)
 		http.Error(w, "Failed to upgrade connection", http.StatusInternalServerError)
 		return
 	}
@@ -65,7 +63,7 @@ func (h *ClaudeHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request)
 	h.activeConns[conn] = symbol
 	h.connMutex.Unlock()
 
-	log.Printf("New WebSocket connection for %s", symbol)
+	logger().Info("New WebSocket connection", "symbol", symbol)
 
 	// Handle the connection
 	go h.handleConnection(conn, symbol)
@@ -79,7 +77,7 @@ func (h *ClaudeHandler) handleConnection(conn *websocket.Conn, symbol string) {
 		delete(h.activeConns, conn)
 		h.connMutex.Unlock()
 		conn.Close()
-		log.Printf("WebSocket connection closed for %s", symbol)
+		logger().Info("WebSocket connection closed", "symbol", symbol)
 	}()
 
 	for {
@@ -87,7 +85,7 @@ func (h *ClaudeHandler) handleConnection(conn *websocket.Conn, symbol string) {
 		_, message, err := conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket error: %v", err)
+				logger().Warn("WebSocket error", "error", err)
 			}
 			break
 		}
@@ -95,12 +93,12 @@ func (h *ClaudeHandler) handleConnection(conn *websocket.Conn, symbol string) {
 		// Parse the message
 		var request map[string]interface{}
 		if err := json.Unmarshal(message, &request); err != nil {
-			log.Printf("Error parsing WebSocket message: %v", err)
+			logger().Warn("Failed to parse WebSocket message", "error", err)
 			h.sendError(conn, "Invalid message format")
 			continue
 		}
 
-		log.Printf("Received WebSocket message: %s", string(message))
+		logger().Debug("Received WebSocket message", "message", string(message))
 		action, ok := request["action"].(string)
 		if !ok {
 			h.sendError(conn, "Missing action field")
@@ -128,7 +126,7 @@ func (h *ClaudeHandler) handleConnection(conn *websocket.Conn, symbol string) {
 
 // generateAndSendSignal generates a trading signal and sends it to the client
 func (h *ClaudeHandler) generateAndSendSignal(conn *websocket.Conn, symbol string) {
-	log.Printf("Generating signal for %s", symbol)
+	logger().Info("Generating signal", "symbol", symbol)
 
 	h.sendStream(conn, "Analyzing market data with active trading algorithms...")
 
@@ -139,7 +137,7 @@ func (h *ClaudeHandler) generateAndSendSignal(conn *websocket.Conn, symbol strin
 		return
 	}
 
-	log.Printf("Generated signal for %s: %+v", symbol, signal)
+	logger().Info("Generated signal", "symbol", symbol, "signal", signal.Signal)
 
 	// Send the signal
 	h.sendSignal(conn, signal)
@@ -152,7 +150,7 @@ func (h *ClaudeHandler) sendStream(conn *websocket.Conn, message string) {
 		Data: message,
 	}
 	if err := conn.WriteJSON(msg); err != nil {
-		log.Printf("Error sending stream message: %v", err)
+		logger().Warn("Failed to send stream message", "error", err)
 	}
 }
 
@@ -165,12 +163,10 @@ func (h *ClaudeHandler) sendSignal(conn *websocket.Conn, signal *TradeSignal) {
  
 	}
 
-	jsonData, _ := json.Marshal(msg)
-	log.Printf("Sending signal message: %s", string(jsonData))
-	log.Printf("Signal field specifically: %s", signal.Signal)
+	logger().Debug("Sending signal message", "symbol", signal.Symbol, "signal", signal.Signal)
 	
 	if err := conn.WriteJSON(msg); err != nil {
-		log.Printf("Error sending signal: %v", err)
+		logger().Warn("Failed to send signal", "error", err)
 	}
 }
 
@@ -181,7 +177,7 @@ func (h *ClaudeHandler) sendError(conn *websocket.Conn, message string) {
 		Data: message,
 	}
 	if err := conn.WriteJSON(msg); err != nil {
-		log.Printf("Error sending error message: %v", err)
+		logger().Warn("Failed to send error message", "error", err)
 	}
 }
 
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"time"
 )
@@ -62,6 +61,7 @@ type SignalResponse struct {
 		LimitPrice *float64  `json:"limit_price,omitempty"`
 		Reasoning  string    `json:"reasoning"`
 		Confidence float64   `json:"confidence,omitempty"`
+		Analysis   *Analysis `json:"analysis,omitempty"`
 	} `json:"signal"`
 	RawResponse string `json:"rawResponse"`
 }
@@ -108,7 +108,7 @@ func (a *HTTPAdapter) GenerateTradeSignal(symbol string, marketData MarketData,
 	}
 
 	// Use real algorithm data rather than defaults
-	log.Printf("[INFO] Using active trading algorithms for signal generation")
+	logger().Info("Using active trading algorithms for signal generation")
 	
 	// Get algorithm list from the server
 	algorithmsData := []map[string]interface{}{}
@@ -162,14 +162,16 @@ func (a *HTTPAdapter) GenerateTradeSignal(symbol string, marketData MarketData,
 		Timestamp:  time.Now(),
 		Reasoning:  signalResp.Signal.Reasoning,
 		Margin:     1.0, // Default margin
+		Analysis:   signalResp.Signal.Analysis,
 	}
+	attachAnalysis(signal, signalResp.RawResponse)
 
 	return signal, nil
 }
 
 // Generate simulates the GenerateSignal method for backward compatibility
 func (a *HTTPAdapter) GenerateSignal(symbol string) (*TradeSignal, error) {
-	log.Printf("[INFO] Fetching real-time market data for %s to generate signal", symbol)
+	logger().Info("Fetching real-time market data to generate signal", "symbol", symbol)
 	
 	// Create real market data based on the symbol
 	marketData := MarketData{
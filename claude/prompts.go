@@ -0,0 +1,113 @@
+package claude
+
+import (
+	"strings"
+	"text/template"
+)
+
+// Prompt variants, named for the strategy each asks Claude to take.
+const (
+	PromptTrendFollowing = "trend_following"
+	PromptMeanReversion  = "mean_reversion"
+	PromptDefensive      = "high_volatility_defensive"
+	// PromptGeneral is for symbols with no detected regime
+	PromptGeneral = "general"
+)
+
+// Regimes as the feature store's regime detector labels them.
+const (
+	RegimeTrendingUp   = "trending_up"
+	RegimeTrendingDown = "trending_down"
+	RegimeRanging      = "ranging"
+	RegimeVolatile     = "volatile"
+)
+
+// PromptVariant is a named prompt template and the regimes it is chosen
+// for.
+type PromptVariant struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Regimes     []string `json:"regimes,omitempty"`
+	template    *template.Template
+}
+
+// promptData is what prompt templates are filled from: the market data
+// and the symbol's regime, empty when none is detected.
+type promptData struct {
+	MarketData
+	Regime string
+}
+
+var promptVariants = []PromptVariant{
+	newPromptVariant(PromptTrendFollowing, "Trade with the trend, entering on pullbacks",
+		[]string{RegimeTrendingUp, RegimeTrendingDown},
+		`{{.Symbol}} is trending {{if eq .Regime "trending_down"}}down{{else}}up{{end}} at {{printf "%.2f" .Price}}. `+
+			`Trade with the trend: favour entries in its direction on pullbacks toward support or the session VWAP`+
+			`{{with .Session}} ({{printf "%.2f" .VWAP}}){{end}}, and hold while the trend's structure holds. `+
+			`Do not fade the move or pick tops and bottoms. Set the invalidation level where the trend would be broken, `+
+			`such as below the last higher low for a long, and prefer a swing or position horizon.`),
+	newPromptVariant(PromptMeanReversion, "Trade the range, fading its extremes",
+		[]string{RegimeRanging},
+		`{{.Symbol}} is ranging at {{printf "%.2f" .Price}}. `+
+			`Trade the range: buy near its support and sell near its resistance`+
+			`{{with .Session}}, with the session VWAP ({{printf "%.2f" .VWAP}}) as the mean price reverts to{{end}}, `+
+			`and hold in the middle of it. Treat a close outside the range as invalidation, not a breakout to chase. `+
+			`Keep targets inside the range and prefer an intraday or swing horizon.`),
+	newPromptVariant(PromptDefensive, "Protect capital: hold or reduce, small positions only",
+		[]string{RegimeVolatile},
+		`{{.Symbol}} is volatile at {{printf "%.2f" .Price}}`+
+			`{{with .Session}}, with {{printf "%.1f" .RealizedVolatility}}% annualized realized volatility this session{{end}}. `+
+			`Protect capital first: hold unless the setup is exceptional, and prefer reducing or closing exposure to adding it. `+
+			`Favour limit orders over market orders while spreads are wide. Any new position should be small, with an `+
+			`invalidation level far enough out to survive normal swings, and an intraday horizon. `+
+			`Name the volatility among the key risks.`),
+	newPromptVariant(PromptGeneral, "No regime detected: weigh trend and range evidence alike",
+		nil,
+		`Generate a trade signal for {{.Symbol}} at {{printf "%.2f" .Price}} from the market data and portfolio provided. `+
+			`No regime has been detected for it, so weigh trend and range evidence alike, and hold when neither is clear.`),
+}
+
+func newPromptVariant(name, description string, regimes []string, text string) PromptVariant {
+	return PromptVariant{
+		Name:        name,
+		Description: description,
+		Regimes:     regimes,
+		template:    template.Must(template.New(name).Option("missingkey=error").Parse(text)),
+	}
+}
+
+// PromptVariants returns the prompt variants.
+func PromptVariants() []PromptVariant {
+	return append([]PromptVariant(nil), promptVariants...)
+}
+
+// SelectPrompt returns the prompt variant for md's regime: trend following
+// for a trending symbol, mean reversion for a ranging one and the defensive
+// variant for a volatile one, or the general variant without a regime.
+func SelectPrompt(md MarketData) PromptVariant {
+	regime := marketRegime(md)
+	for _, v := range promptVariants {
+		for _, r := range v.Regimes {
+			if r == regime {
+				return v
+			}
+		}
+	}
+	return promptVariants[len(promptVariants)-1]
+}
+
+// Render fills the variant's template from md.
+func (v PromptVariant) Render(md MarketData) (string, error) {
+	var b strings.Builder
+	if err := v.template.Execute(&b, promptData{MarketData: md, Regime: marketRegime(md)}); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func marketRegime(md MarketData) string {
+	if md.Features == nil {
+		return ""
+	}
+	return md.Features.Regime
+}
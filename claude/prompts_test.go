@@ -0,0 +1,65 @@
+package claude
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSelectPrompt(t *testing.T) {
+	cases := map[string]string{
+		RegimeTrendingUp:   PromptTrendFollowing,
+		RegimeTrendingDown: PromptTrendFollowing,
+		RegimeRanging:      PromptMeanReversion,
+		RegimeVolatile:     PromptDefensive,
+		"":                 PromptGeneral,
+	}
+	for regime, want := range cases {
+		md := MarketData{Symbol: "AAPL", Price: 187.5}
+		if regime != "" {
+			md.Features = &SymbolFeatures{Regime: regime}
+		}
+		if got := SelectPrompt(md).Name; got != want {
+			t.Errorf("SelectPrompt(%q) = %s, want %s", regime, got, want)
+		}
+	}
+
+	md := MarketData{Symbol: "AAPL", Price: 187.5, Features: &SymbolFeatures{Regime: RegimeTrendingDown},
+		Session: &SessionStats{VWAP: 189.25}}
+	prompt, err := SelectPrompt(md).Render(md)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(prompt, "AAPL is trending down at 187.50") || !strings.Contains(prompt, "(189.25)") {
+		t.Errorf("prompt = %q", prompt)
+	}
+	// Every variant renders without session stats
+	for _, v := range PromptVariants() {
+		if _, err := v.Render(MarketData{Symbol: "AAPL"}); err != nil {
+			t.Errorf("%s: %v", v.Name, err)
+		}
+	}
+}
+
+func TestSignalRecordsPromptVariant(t *testing.T) {
+	var sent WSSignalRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&sent)
+		w.Write([]byte(`{"status":"success","signal":{"symbol":"AAPL","signal":"hold","reasoning":"Inside the range"}}`))
+	}))
+	defer server.Close()
+
+	md := MarketData{Symbol: "AAPL", Price: 187.5, Features: &SymbolFeatures{Regime: RegimeRanging}}
+	signal, err := NewWebSocketAdapter(server.URL).GenerateTradeSignal("AAPL", md, PortfolioData{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent.PromptVariant != PromptMeanReversion || !strings.HasPrefix(sent.Prompt, "AAPL is ranging") {
+		t.Errorf("request sent variant %q, prompt %q", sent.PromptVariant, sent.Prompt)
+	}
+	if signal.PromptVariant != PromptMeanReversion {
+		t.Errorf("signal variant = %q", signal.PromptVariant)
+	}
+}
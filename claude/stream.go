@@ -0,0 +1,85 @@
+package claude
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Statuses of the messages in a signal response.
+const (
+	statusStream = "stream" // a chunk of Claude's response text
+	statusError  = "error"
+)
+
+// readSignalStream reads a signal response: one message, or a stream of
+// messages, one JSON value each, whose "stream" messages carry chunks of
+// Claude's response text and whose last message carries the signal. The
+// chunks are aggregated and the signal's analysis taken from them, see
+// attachAnalysis. onChunk, when set, receives each chunk as it arrives.
+func readSignalStream(body io.Reader, symbol string, onChunk StreamCallback) (*TradeSignal, error) {
+	dec := json.NewDecoder(body)
+	var text strings.Builder
+	for {
+		var msg WSSignalResponse
+		if err := dec.Decode(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("response ended before the signal after %d characters", text.Len())
+			}
+			return nil, fmt.Errorf("error parsing response: %w", err)
+		}
+		switch msg.Status {
+		case statusStream:
+			text.WriteString(msg.Chunk)
+			if onChunk != nil {
+				onChunk(symbol, msg.Chunk)
+			}
+		case statusError:
+			return nil, fmt.Errorf("server returned error: %s", msg.Error)
+		default:
+			signal := msg.Signal
+			signal.Usage = responseUsage(msg.Usage, &signal, text.String())
+			attachAnalysis(&signal, text.String())
+			return &signal, nil
+		}
+	}
+}
+
+// attachAnalysis gives signal a validated analysis: its own when it
+// carries one that satisfies AnalysisSchema, otherwise one parsed from the
+// response text or, failing that, from the reasoning. A signal left
+// without reasoning gets the thesis, or the response text.
+func attachAnalysis(signal *TradeSignal, text string) {
+	if signal.Analysis != nil {
+		raw, err := json.Marshal(signal.Analysis)
+		if err == nil {
+			signal.Analysis, err = ValidateAnalysis(raw)
+		}
+		if err != nil {
+			logger().Warn("Dropping invalid signal analysis", "symbol", signal.Symbol, "error", err)
+			signal.Analysis = nil
+		}
+	}
+	for _, source := range []string{text, signal.Reasoning} {
+		if signal.Analysis != nil || source == "" {
+			break
+		}
+		analysis, err := ParseAnalysis(source)
+		if err != nil {
+			if !errors.Is(err, ErrNoAnalysis) {
+				logger().Warn("Response analysis is invalid", "symbol", signal.Symbol, "error", err)
+			}
+			continue
+		}
+		signal.Analysis = analysis
+	}
+	if strings.TrimSpace(signal.Reasoning) == "" {
+		if signal.Analysis != nil {
+			signal.Reasoning = signal.Analysis.Thesis
+		} else {
+			signal.Reasoning = strings.TrimSpace(text)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package claude
+
+import "encoding/json"
+
+// Usage is the tokens one signal request consumed.
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+	// Estimated is set when the server did not report usage and the
+	// tokens were counted from text length instead; the estimate misses
+	// the server's own system prompt
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// charsPerToken approximates Claude's tokenizer on English and JSON text.
+const charsPerToken = 4
+
+// estimateTokens returns the tokens chars characters of text come to.
+func estimateTokens(chars int) int {
+	return (chars + charsPerToken - 1) / charsPerToken
+}
+
+// responseUsage returns the usage a response reported, preferring the
+// final message's over the signal's, or else an estimate of the output
+// from the response text, or from the signal when nothing was streamed.
+func responseUsage(reported *Usage, signal *TradeSignal, text string) *Usage {
+	if reported == nil {
+		reported = signal.Usage
+	}
+	if reported != nil {
+		u := *reported
+		return &u
+	}
+	chars := len(text)
+	if chars == 0 {
+		if data, err := json.Marshal(signal); err == nil {
+			chars = len(data)
+		}
+	}
+	return &Usage{OutputTokens: estimateTokens(chars), Estimated: true}
+}
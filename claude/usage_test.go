@@ -0,0 +1,30 @@
+package claude
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadSignalStreamUsage(t *testing.T) {
+	reported, err := readSignalStream(strings.NewReader(
+		`{"status":"success","signal":{"symbol":"AAPL","signal":"hold","reasoning":"Range bound"},"usage":{"input_tokens":1200,"output_tokens":300}}`), "AAPL", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u := reported.Usage; u == nil || u.InputTokens != 1200 || u.OutputTokens != 300 || u.Estimated {
+		t.Errorf("reported usage = %+v", u)
+	}
+
+	stream := strings.Join([]string{
+		`{"status":"stream","chunk":"` + strings.Repeat("a", 398) + `"}`,
+		`{"status":"success","signal":{"symbol":"AAPL","signal":"hold","reasoning":"Range bound"}}`,
+	}, "\n")
+	estimated, err := readSignalStream(strings.NewReader(stream), "AAPL", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 398 characters round up to 100 tokens
+	if u := estimated.Usage; u == nil || u.OutputTokens != 100 || u.InputTokens != 0 || !u.Estimated {
+		t.Errorf("estimated usage = %+v", u)
+	}
+}
@@ -3,7 +3,6 @@ package claude
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
 	"net/url"
 	"strings"
@@ -24,6 +23,10 @@ type WebSocketAdapter struct {
 	httpClient  *http.Client
 	reqIDMutex  sync.Mutex
 	reqIDCount  int
+	callback    StreamCallback
+	// contextURL is where the adapter reads a request's signal context
+	// back from the Go process, empty when it is not served
+	contextURL string
 }
 
 // WSSignalRequest represents a WebSocket request for a signal
@@ -33,6 +36,15 @@ type WSSignalRequest struct {
 	Symbol        string        `json:"symbol"`
 	MarketData    MarketData    `json:"marketData"`
 	PortfolioData PortfolioData `json:"portfolioData"`
+	// AnalysisSchema is the schema the response's analysis must satisfy
+	AnalysisSchema json.RawMessage `json:"analysisSchema,omitempty"`
+	// ContextURL returns the exact market and portfolio context of this
+	// request from the Go process, so the adapter need not keep its own
+	ContextURL string `json:"contextUrl,omitempty"`
+	// Prompt is the strategy instructions for the symbol's regime, from
+	// the variant PromptVariant names
+	Prompt        string `json:"prompt,omitempty"`
+	PromptVariant string `json:"promptVariant,omitempty"`
 }
 
 // WSSignalResponse represents a WebSocket response with a signal
@@ -41,6 +53,8 @@ type WSSignalResponse struct {
 	Status  string      `json:"status"`
 	Signal  TradeSignal `json:"signal,omitempty"`
 	Error   string      `json:"error,omitempty"`
+	Chunk   string      `json:"chunk,omitempty"` // response text, for stream messages
+	Usage   *Usage      `json:"usage,omitempty"` // tokens consumed, on the signal message
 }
 
 // NewWebSocketAdapter creates a new WebSocket adapter
@@ -57,6 +71,23 @@ func NewWebSocketAdapter(serverURL string) *WebSocketAdapter {
 	}
 }
 
+// SetStreamCallback registers a callback for each chunk of streamed
+// responses.
+func (a *WebSocketAdapter) SetStreamCallback(callback StreamCallback) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.callback = callback
+}
+
+// SetContextURL sets the endpoint signal contexts are read back from, such
+// as http://localhost:8080/api/snapshot/context/redeem. Each request's URL
+// names its context and carries the one-time token that reads it.
+func (a *WebSocketAdapter) SetContextURL(contextURL string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.contextURL = contextURL
+}
+
 // Connect establishes a WebSocket connection to the Next.js server
 // or sets up for HTTP fallback if WebSockets aren't available
 func (a *WebSocketAdapter) Connect() error {
@@ -70,7 +101,7 @@ func (a *WebSocketAdapter) Connect() error {
 	// For Next.js App Router, we'll use HTTP instead of WebSockets
 	// as WebSocket support in App Router is limited
 	a.isConnected = true
-	log.Printf("Using HTTP fallback mode for WebSocket communication with %s", a.serverURL)
+	logger().Info("Using HTTP fallback mode for WebSocket communication", "server", a.serverURL)
 	return nil
 }
 
@@ -78,7 +109,7 @@ func (a *WebSocketAdapter) Connect() error {
 func (a *WebSocketAdapter) GenerateTradeSignal(symbol string, marketData MarketData, portfolio PortfolioData) (*TradeSignal, error) {
 	// Ensure connection is established
 	if err := a.Connect(); err != nil {
-		log.Printf("Warning: Failed to connect to server: %v", err)
+		logger().Warn("Failed to connect to server", "error", err)
 		// Return a default hold signal as fallback
 		return &TradeSignal{
 			Symbol:    symbol,
@@ -103,7 +134,21 @@ func (a *WebSocketAdapter) GenerateTradeSignal(symbol string, marketData MarketD
 		Symbol:        symbol,
 		MarketData:    marketData,
 		PortfolioData: portfolio,
+
+		AnalysisSchema: json.RawMessage(AnalysisSchema),
 	}
+	variant := SelectPrompt(marketData)
+	if prompt, err := variant.Render(marketData); err != nil {
+		logger().Warn("Failed to render prompt, sending the request without one", "symbol", symbol, "variant", variant.Name, "error", err)
+	} else {
+		request.Prompt, request.PromptVariant = prompt, variant.Name
+	}
+	a.mutex.Lock()
+	if a.contextURL != "" && marketData.ContextID != "" {
+		request.ContextURL = a.contextURL + "?id=" + url.QueryEscape(marketData.ContextID) +
+			"&token=" + url.QueryEscape(marketData.ContextToken)
+	}
+	a.mutex.Unlock()
 
 	// Marshal request
 	reqData, err := json.Marshal(request)
@@ -132,18 +177,20 @@ func (a *WebSocketAdapter) GenerateTradeSignal(symbol string, marketData MarketD
 	}
 	defer resp.Body.Close()
 
-	// Parse the response
-	var response WSSignalResponse
-	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return nil, fmt.Errorf("error parsing response: %w", err)
+	// Parse the response, aggregating a streamed one
+	a.mutex.Lock()
+	callback := a.callback
+	a.mutex.Unlock()
+	signal, err := readSignalStream(resp.Body, symbol, callback)
+	if err != nil {
+		return nil, err
 	}
-
-	// Handle error responses
-	if response.Status == "error" {
-		return nil, fmt.Errorf("server returned error: %s", response.Error)
+	// Without reported usage, estimate the input from the request sent
+	if signal.Usage.Estimated && signal.Usage.InputTokens == 0 {
+		signal.Usage.InputTokens = estimateTokens(len(reqData))
 	}
-
-	return &response.Signal, nil
+	signal.PromptVariant = request.PromptVariant
+	return signal, nil
 }
 
 // Disconnect closes any connection
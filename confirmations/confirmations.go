@@ -0,0 +1,462 @@
+// Package confirmations holds orders that need a second confirmation
+// before they are submitted. The policy marks orders above a notional
+// threshold, or for signals below a confidence floor, and such orders wait
+// until a different user confirms them or the requester types back the
+// order's confirmation phrase. Confirmed orders are checked against the
+// trade guards again before they are placed.
+package confirmations
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+)
+
+func logger() *slog.Logger { return slog.With("module", "confirmations") }
+
+// Item statuses.
+const (
+	StatusPending   = "pending"
+	StatusConfirmed = "confirmed" // the order is being placed
+	StatusExecuted  = "executed"
+	StatusFailed    = "failed" // confirmed, but the order could not be placed
+	StatusRejected  = "rejected"
+	StatusBlocked   = "blocked" // refused by a trade guard on confirmation
+	StatusExpired   = "expired"
+)
+
+// maxDecided bounds the decided items kept for review.
+const maxDecided = 500
+
+var (
+	// ErrNotFound is returned for an unknown item ID.
+	ErrNotFound = errors.New("confirmation not found")
+	// ErrDecided is wrapped when an item is no longer pending.
+	ErrDecided = errors.New("order is no longer pending")
+	// ErrNotConfirmed is wrapped when a confirmation attempt is refused:
+	// the requester gave the wrong phrase, or the policy wants another user.
+	ErrNotConfirmed = errors.New("order not confirmed")
+)
+
+// Policy selects the orders that need a second confirmation.
+type Policy struct {
+	Enabled        bool    `json:"enabled"`
+	MinNotional    float64 `json:"min_notional"`     // orders costing at least this; 0 turns the check off
+	MinConfidence  float64 `json:"min_confidence"`   // signals less confident than this; 0 turns the check off
+	SecondUserOnly bool    `json:"second_user_only"` // refuse the typed phrase; only another user confirms
+	TTLMinutes     int     `json:"ttl_minutes"`      // pending orders expire after this
+}
+
+// DefaultPolicy asks for confirmation of live orders of $10,000 or more and
+// of signals under 60% confidence, and expires them after 10 minutes.
+// Paper and mock trading place orders directly.
+func DefaultPolicy(live bool) Policy {
+	return Policy{Enabled: live, MinNotional: 10000, MinConfidence: 0.6, TTLMinutes: 10}
+}
+
+// Validate checks the policy for internally consistent values.
+func (p Policy) Validate() error {
+	if p.MinNotional < 0 {
+		return errors.New("min_notional must not be negative")
+	}
+	if p.MinConfidence < 0 || p.MinConfidence > 1 {
+		return errors.New("min_confidence must be between 0 and 1")
+	}
+	if p.TTLMinutes < 1 || p.TTLMinutes > 24*60 {
+		return errors.New("ttl_minutes must be between 1 and 1440")
+	}
+	return nil
+}
+
+// reasons lists why an order for signal costing notional needs confirming.
+func (p Policy) reasons(signal *algorithm.TradeSignal, notional float64) []string {
+	if !p.Enabled || signal == nil || signal.Signal == algorithm.SignalHold {
+		return nil
+	}
+	var out []string
+	if p.MinNotional > 0 && notional >= p.MinNotional {
+		out = append(out, fmt.Sprintf("notional $%.2f is at or above $%.2f", notional, p.MinNotional))
+	}
+	if p.MinConfidence > 0 && signal.Confidence != nil && *signal.Confidence < p.MinConfidence {
+		out = append(out, fmt.Sprintf("confidence %.2f is below %.2f", *signal.Confidence, p.MinConfidence))
+	}
+	return out
+}
+
+// Item is one order awaiting confirmation and what became of it. The
+// signal's size is pinned to the previewed quantity, so the order placed
+// is the one that was confirmed.
+type Item struct {
+	ID          string                 `json:"id"`
+	Signal      *algorithm.TradeSignal `json:"signal"`
+	Notional    float64                `json:"notional"`
+	Reasons     []string               `json:"reasons"`
+	Phrase      string                 `json:"phrase"` // typed back by the requester to confirm
+	RequestedBy string                 `json:"requested_by"`
+	Status      string                 `json:"status"`
+	CreatedAt   time.Time              `json:"created_at"`
+	ExpiresAt   time.Time              `json:"expires_at"`
+	DecidedAt   *time.Time             `json:"decided_at,omitempty"`
+	DecidedBy   string                 `json:"decided_by,omitempty"`
+	Reason      string                 `json:"reason,omitempty"` // why it was rejected
+	Result      string                 `json:"result,omitempty"` // what executing it did
+	Error       string                 `json:"error,omitempty"`  // guard refusal or execution error
+}
+
+// state is what the queue persists.
+type state struct {
+	Policy Policy  `json:"policy"`
+	Items  []*Item `json:"items"` // oldest first
+}
+
+// Queue holds orders awaiting confirmation. It is safe for concurrent use.
+type Queue struct {
+	path string
+
+	mu      sync.Mutex
+	state   state
+	seq     int
+	now     func() time.Time
+	guard   func(*algorithm.TradeSignal) error
+	execute func(*algorithm.TradeSignal) (string, error)
+}
+
+// New opens the queue saved at path, using policy unless one was saved.
+func New(path string, policy Policy) (*Queue, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create confirmations directory: %w", err)
+	}
+	q := &Queue{path: path, state: state{Policy: policy}, now: time.Now}
+	if data, err := os.ReadFile(path); err == nil {
+		var saved state
+		if err := json.Unmarshal(data, &saved); err != nil {
+			return nil, fmt.Errorf("failed to decode confirmation queue: %w", err)
+		}
+		if saved.Policy.Validate() == nil {
+			q.state.Policy = saved.Policy
+		}
+		for _, it := range saved.Items {
+			if it.Status == StatusConfirmed {
+				// The process stopped while placing the order; whether it
+				// reached the broker is unknown
+				it.Status = StatusFailed
+				it.Error = "interrupted while placing the order; check the broker before resubmitting"
+			}
+			q.state.Items = append(q.state.Items, it)
+			if _, seq, ok := strings.Cut(strings.TrimPrefix(it.ID, "cfm_"), "_"); ok {
+				if n, err := strconv.Atoi(seq); err == nil && n > q.seq {
+					q.seq = n
+				}
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read confirmation queue: %w", err)
+	}
+	return q, nil
+}
+
+// SetClock replaces the clock, for tests.
+func (q *Queue) SetClock(now func() time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.now = now
+}
+
+// SetGuard sets the check a confirmed order must pass before it is placed,
+// normally the algorithm's trade guards.
+func (q *Queue) SetGuard(fn func(*algorithm.TradeSignal) error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.guard = fn
+}
+
+// SetExecutor sets the function that places a confirmed signal's order
+// and describes what it did.
+func (q *Queue) SetExecutor(fn func(*algorithm.TradeSignal) (string, error)) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.execute = fn
+}
+
+// Policy returns the current policy.
+func (q *Queue) Policy() Policy {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.state.Policy
+}
+
+// SetPolicy validates, replaces and saves the policy. It applies to orders
+// requested from now on.
+func (q *Queue) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.state.Policy = p
+	q.saveLocked()
+	return nil
+}
+
+// Required lists why the previewed order for signal needs confirming, or
+// returns nil when it may be placed directly.
+func (q *Queue) Required(signal *algorithm.TradeSignal, preview *algorithm.OrderPreview) []string {
+	if preview == nil {
+		return nil
+	}
+	notional, _ := preview.EstimatedCost.Float64()
+	return q.Policy().reasons(signal, notional)
+}
+
+// Hold queues the previewed order for signal, requested by requestedBy,
+// until it is confirmed. reasons are those Required gave.
+func (q *Queue) Hold(signal *algorithm.TradeSignal, preview *algorithm.OrderPreview, reasons []string, requestedBy string) (Item, error) {
+	if signal == nil || preview == nil {
+		return Item{}, errors.New("signal and preview are required")
+	}
+	held := *signal
+	req := preview.Request
+	if req.Qty != nil {
+		qty, _ := req.Qty.Float64()
+		held.Size = &algorithm.TradeSize{Qty: qty}
+	}
+	notional, _ := preview.EstimatedCost.Float64()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.now()
+	q.expireLocked(now)
+	q.seq++
+	it := &Item{
+		ID:          fmt.Sprintf("cfm_%d_%d", now.UnixNano(), q.seq),
+		Signal:      &held,
+		Notional:    notional,
+		Reasons:     reasons,
+		Phrase:      phrase(&held, preview),
+		RequestedBy: requestedBy,
+		Status:      StatusPending,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(time.Duration(q.state.Policy.TTLMinutes) * time.Minute),
+	}
+	q.state.Items = append(q.state.Items, it)
+	q.trimLocked()
+	q.saveLocked()
+	logger().Info("Order held for confirmation", "id", it.ID, "symbol", held.Symbol, "signal", held.Signal,
+		"notional", notional, "reasons", reasons, "requested_by", requestedBy)
+	return *it, nil
+}
+
+// phrase is what the requester types to confirm an order, e.g.
+// "BUY 120 AAPL".
+func phrase(signal *algorithm.TradeSignal, preview *algorithm.OrderPreview) string {
+	size := "$" + preview.EstimatedCost.StringFixed(2)
+	if preview.Request.Qty != nil {
+		size = preview.Request.Qty.String()
+	}
+	return strings.ToUpper(fmt.Sprintf("%s %s %s", signal.Signal, size, signal.Symbol))
+}
+
+// Confirm places a pending order, after checking the guard again. A user
+// other than the requester confirms outright; the requester must type the
+// item's phrase, unless the policy accepts only another user.
+func (q *Queue) Confirm(id, by, typed string) (Item, error) {
+	q.mu.Lock()
+	now := q.now()
+	q.expireLocked(now)
+	it, err := q.pendingLocked(id)
+	if err != nil {
+		q.mu.Unlock()
+		return Item{}, err
+	}
+	if by == "" || by == it.RequestedBy {
+		if q.state.Policy.SecondUserOnly {
+			q.mu.Unlock()
+			return Item{}, fmt.Errorf("%w: another user must confirm it", ErrNotConfirmed)
+		}
+		if !strings.EqualFold(strings.Join(strings.Fields(typed), " "), it.Phrase) {
+			q.mu.Unlock()
+			return Item{}, fmt.Errorf("%w: type %q to confirm it", ErrNotConfirmed, it.Phrase)
+		}
+	}
+	it.Status = StatusConfirmed
+	it.DecidedAt = &now
+	it.DecidedBy = by
+	q.saveLocked()
+	signal := it.Signal
+	q.mu.Unlock()
+	logger().Info("Order confirmed", "id", id, "symbol", signal.Symbol, "signal", signal.Signal, "by", by)
+	return q.run(id, signal), nil
+}
+
+// Reject drops a pending order.
+func (q *Queue) Reject(id, by, reason string) (Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	now := q.now()
+	q.expireLocked(now)
+	it, err := q.pendingLocked(id)
+	if err != nil {
+		return Item{}, err
+	}
+	it.Status = StatusRejected
+	it.DecidedAt = &now
+	it.DecidedBy = by
+	it.Reason = reason
+	q.saveLocked()
+	logger().Info("Order rejected", "id", id, "symbol", it.Signal.Symbol, "by", by, "reason", reason)
+	return *it, nil
+}
+
+// run places a confirmed order and records the outcome.
+func (q *Queue) run(id string, signal *algorithm.TradeSignal) Item {
+	q.mu.Lock()
+	guard, execute := q.guard, q.execute
+	q.mu.Unlock()
+
+	status, result, errText := StatusExecuted, "", ""
+	var err error
+	if guard != nil {
+		if err = guard(signal); err != nil {
+			status = StatusBlocked
+		}
+	}
+	if err == nil {
+		if execute == nil {
+			err = errors.New("no executor configured")
+		} else {
+			result, err = execute(signal)
+		}
+		if err != nil {
+			status = StatusFailed
+		}
+	}
+	if err != nil {
+		errText = err.Error()
+		logger().Warn("Confirmed order was not placed", "id", id, "symbol", signal.Symbol, "status", status, "error", err)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, it := range q.state.Items {
+		if it.ID == id {
+			it.Status, it.Result, it.Error = status, result, errText
+			q.saveLocked()
+			return *it
+		}
+	}
+	return Item{ID: id, Signal: signal, Status: status, Result: result, Error: errText}
+}
+
+// Get returns one item.
+func (q *Queue) Get(id string) (Item, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expireLocked(q.now())
+	for _, it := range q.state.Items {
+		if it.ID == id {
+			return *it, nil
+		}
+	}
+	return Item{}, ErrNotFound
+}
+
+// List returns items with status, or every item when status is empty,
+// newest first, at most limit of them when limit is positive.
+func (q *Queue) List(status string, limit int) []Item {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.expireLocked(q.now())
+	out := []Item{}
+	for i := len(q.state.Items) - 1; i >= 0; i-- {
+		it := q.state.Items[i]
+		if status != "" && it.Status != status {
+			continue
+		}
+		out = append(out, *it)
+		if limit > 0 && len(out) >= limit {
+			break
+		}
+	}
+	return out
+}
+
+func (q *Queue) pendingLocked(id string) (*Item, error) {
+	for _, it := range q.state.Items {
+		if it.ID != id {
+			continue
+		}
+		if it.Status != StatusPending {
+			return nil, fmt.Errorf("%w: %s", ErrDecided, it.Status)
+		}
+		return it, nil
+	}
+	return nil, ErrNotFound
+}
+
+// expireLocked marks pending items past their TTL as expired.
+func (q *Queue) expireLocked(now time.Time) {
+	changed := false
+	for _, it := range q.state.Items {
+		if it.Status == StatusPending && !now.Before(it.ExpiresAt) {
+			it.Status = StatusExpired
+			at := it.ExpiresAt
+			it.DecidedAt = &at
+			changed = true
+			logger().Info("Order expired unconfirmed", "id", it.ID, "symbol", it.Signal.Symbol)
+		}
+	}
+	if changed {
+		q.saveLocked()
+	}
+}
+
+// trimLocked drops the oldest decided items beyond maxDecided.
+func (q *Queue) trimLocked() {
+	decided := 0
+	for _, it := range q.state.Items {
+		if it.Status != StatusPending && it.Status != StatusConfirmed {
+			decided++
+		}
+	}
+	if decided <= maxDecided {
+		return
+	}
+	drop := decided - maxDecided
+	kept := q.state.Items[:0]
+	for _, it := range q.state.Items {
+		if drop > 0 && it.Status != StatusPending && it.Status != StatusConfirmed {
+			drop--
+			continue
+		}
+		kept = append(kept, it)
+	}
+	q.state.Items = kept
+}
+
+func (q *Queue) saveLocked() {
+	data, err := json.MarshalIndent(q.state, "", "  ")
+	if err != nil {
+		logger().Error("Failed to encode confirmation queue", "error", err)
+		return
+	}
+	tmp := q.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		logger().Error("Failed to write confirmation queue", "error", err)
+		return
+	}
+	if err := os.Rename(tmp, q.path); err != nil {
+		logger().Error("Failed to save confirmation queue", "error", err)
+	}
+}
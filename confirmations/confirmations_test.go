@@ -0,0 +1,115 @@
+package confirmations
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/rileyseaburg/go-trader/algorithm"
+	"github.com/shopspring/decimal"
+)
+
+func preview(qty, price float64) *algorithm.OrderPreview {
+	q := decimal.NewFromFloat(qty)
+	return algorithm.NewOrderPreview(alpaca.PlaceOrderRequest{Symbol: "AAPL", Side: alpaca.Buy, Qty: &q}, price)
+}
+
+func TestRequiredMarksLargeAndLowConfidenceOrders(t *testing.T) {
+	q, err := New(filepath.Join(t.TempDir(), "queue.json"), DefaultPolicy(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	low, high := 0.4, 0.9
+	buy := func(conf *float64) *algorithm.TradeSignal {
+		return &algorithm.TradeSignal{Symbol: "AAPL", Signal: algorithm.SignalBuy, Confidence: conf}
+	}
+
+	if r := q.Required(buy(&high), preview(10, 150)); r != nil {
+		t.Fatalf("small confident order marked: %v", r)
+	}
+	if r := q.Required(buy(nil), preview(100, 150)); len(r) != 1 {
+		t.Fatalf("$15,000 order: %v", r)
+	}
+	if r := q.Required(buy(&low), preview(100, 150)); len(r) != 2 {
+		t.Fatalf("large low-confidence order: %v", r)
+	}
+	hold := &algorithm.TradeSignal{Symbol: "AAPL", Signal: algorithm.SignalHold, Confidence: &low}
+	if r := q.Required(hold, preview(100, 150)); r != nil {
+		t.Fatalf("hold marked: %v", r)
+	}
+
+	paper, _ := New(filepath.Join(t.TempDir(), "queue.json"), DefaultPolicy(false))
+	if r := paper.Required(buy(&low), preview(100, 150)); r != nil {
+		t.Fatalf("paper order marked: %v", r)
+	}
+}
+
+func TestConfirmByPhraseOrSecondUser(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.json")
+	q, err := New(path, DefaultPolicy(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	q.SetClock(func() time.Time { return now })
+	var placed []*algorithm.TradeSignal
+	q.SetExecutor(func(s *algorithm.TradeSignal) (string, error) {
+		placed = append(placed, s)
+		return "placed", nil
+	})
+
+	signal := &algorithm.TradeSignal{Symbol: "AAPL", Signal: algorithm.SignalBuy, OrderType: "market"}
+	p := preview(100, 150)
+	it, err := q.Hold(signal, p, q.Required(signal, p), "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if it.Phrase != "BUY 100 AAPL" || it.Signal.Size == nil || it.Signal.Size.Qty != 100 || signal.Size != nil {
+		t.Fatalf("held = %+v", it)
+	}
+
+	if _, err := q.Confirm(it.ID, "alice", "buy 10 AAPL"); !errors.Is(err, ErrNotConfirmed) {
+		t.Fatalf("wrong phrase: %v", err)
+	}
+	if _, err := q.Confirm(it.ID, "alice", ""); !errors.Is(err, ErrNotConfirmed) || len(placed) != 0 {
+		t.Fatalf("requester without phrase: %v", err)
+	}
+	done, err := q.Confirm(it.ID, "alice", " buy  100 aapl ")
+	if err != nil || done.Status != StatusExecuted || len(placed) != 1 || placed[0].Size.Qty != 100 {
+		t.Fatalf("confirm by phrase = %+v, %v", done, err)
+	}
+	if _, err := q.Confirm(it.ID, "bob", ""); !errors.Is(err, ErrDecided) {
+		t.Fatalf("second confirm: %v", err)
+	}
+
+	// Only another user may confirm under SecondUserOnly
+	policy := q.Policy()
+	policy.SecondUserOnly = true
+	if err := q.SetPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	it, _ = q.Hold(signal, p, []string{"large"}, "alice")
+	if _, err := q.Confirm(it.ID, "alice", it.Phrase); !errors.Is(err, ErrNotConfirmed) {
+		t.Fatalf("phrase under second_user_only: %v", err)
+	}
+	if done, err := q.Confirm(it.ID, "bob", ""); err != nil || done.DecidedBy != "bob" || len(placed) != 2 {
+		t.Fatalf("confirm by bob = %+v, %v", done, err)
+	}
+
+	// Unconfirmed orders expire, and survive a restart
+	stale, _ := q.Hold(signal, p, []string{"large"}, "alice")
+	now = now.Add(11 * time.Minute)
+	reopened, err := New(path, DefaultPolicy(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened.SetClock(func() time.Time { return now })
+	if got, err := reopened.Get(stale.ID); err != nil || got.Status != StatusExpired {
+		t.Fatalf("stale = %+v, %v", got, err)
+	}
+	if !reopened.Policy().SecondUserOnly {
+		t.Fatal("saved policy not restored")
+	}
+}
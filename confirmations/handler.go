@@ -0,0 +1,157 @@
+package confirmations
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/rileyseaburg/go-trader/paging"
+	"github.com/rileyseaburg/go-trader/users"
+)
+
+// Handler exposes the confirmation queue over HTTP.
+type Handler struct {
+	queue *Queue
+}
+
+// NewHandler creates a handler for queue.
+func NewHandler(queue *Queue) *Handler {
+	return &Handler{queue: queue}
+}
+
+// RegisterRoutes registers the confirmation routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/confirmations?status=&limit=&cursor=&sort=&filter= - held orders and what became of them, newest first
+	mux.HandleFunc("/api/confirmations", h.handleList)
+
+	// GET /api/confirmations/{id} - one held order
+	// POST /api/confirmations/{id}/confirm - place a held order, {"phrase"} when confirming your own
+	// POST /api/confirmations/{id}/reject - drop a held order, {"reason"} optional
+	mux.HandleFunc("/api/confirmations/", h.handleItem)
+
+	// GET/POST /api/confirmations/policy - read or update the thresholds
+	mux.HandleFunc("/api/confirmations/policy", h.handlePolicy)
+}
+
+// itemList pages the held orders, newest first by default.
+var itemList = paging.List[Item]{
+	Fields: []paging.Field[Item]{
+		{Name: "created_at", Sort: func(it Item) paging.Key { return paging.Time(it.CreatedAt) }},
+		{Name: "notional", Sort: func(it Item) paging.Key { return paging.Number(it.Notional) }},
+		{Name: "symbol", Sort: func(it Item) paging.Key { return paging.Text(it.Signal.Symbol) }, Filter: func(it Item) string { return it.Signal.Symbol }},
+		{Name: "status", Filter: func(it Item) string { return it.Status }},
+		{Name: "requested_by", Filter: func(it Item) string { return it.RequestedBy }},
+	},
+	ID:   func(it Item) string { return it.ID },
+	Sort: "-created_at",
+}
+
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	itemList.Serve(w, r, h.queue.List(r.URL.Query().Get("status"), 0))
+}
+
+func (h *Handler) handleItem(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/confirmations/"), "/")
+	if id == "" || (action != "" && action != "confirm" && action != "reject") {
+		http.NotFound(w, r)
+		return
+	}
+	if action == "" {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		item, err := h.queue.Get(id)
+		if err != nil {
+			http.Error(w, "Confirmation not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(item)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Phrase string `json:"phrase"`
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+	// Who confirms comes from the caller's token, never the body, so a
+	// second user cannot be claimed
+	by := users.FromContext(r.Context()).ID
+	var (
+		item Item
+		err  error
+	)
+	if action == "confirm" {
+		item, err = h.queue.Confirm(id, by, req.Phrase)
+	} else {
+		item, err = h.queue.Reject(id, by, req.Reason)
+	}
+	switch {
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, "Confirmation not found", http.StatusNotFound)
+		return
+	case errors.Is(err, ErrNotConfirmed):
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(StatusCode(item))
+	json.NewEncoder(w).Encode(item)
+}
+
+// StatusCode is the HTTP status reporting item: a held order is accepted,
+// a guard refusal is a conflict and a failed execution unprocessable.
+func StatusCode(item Item) int {
+	switch item.Status {
+	case StatusPending:
+		return http.StatusAccepted
+	case StatusBlocked:
+		return http.StatusConflict
+	case StatusFailed:
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusOK
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.queue.Policy())
+	case http.MethodPost, http.MethodPut:
+		// Loosening the policy would let orders skip confirmation
+		if !users.FromContext(r.Context()).IsAdmin() {
+			http.Error(w, users.ErrForbidden.Error(), http.StatusForbidden)
+			return
+		}
+		// Start from the current policy so partial updates work
+		policy := h.queue.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.queue.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(h.queue.Policy())
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,40 @@
+package dashboard
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandlerServesOnlyEmbeddedFiles(t *testing.T) {
+	h := Handler()
+	get := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+
+	rec := get(http.MethodGet, "/")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `id="kill-switch"`) {
+		t.Fatalf("index: %d %s", rec.Code, rec.Body)
+	}
+	if rec.Header().Get("Content-Security-Policy") == "" {
+		t.Error("no Content-Security-Policy header")
+	}
+	for _, path := range []string{"/app.js", "/style.css"} {
+		if rec := get(http.MethodGet, path); rec.Code != http.StatusOK {
+			t.Errorf("%s: %d", path, rec.Code)
+		}
+	}
+
+	// Source files and data in the working directory are not reachable
+	for _, path := range []string{"/main.go", "/go.mod", "/data/live/baskets.json", "/../dashboard.go", "/static/app.js"} {
+		if rec := get(http.MethodGet, path); rec.Code != http.StatusNotFound {
+			t.Errorf("%s: %d, want 404", path, rec.Code)
+		}
+	}
+	if rec := get(http.MethodPost, "/"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /: %d", rec.Code)
+	}
+}
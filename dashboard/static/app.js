@@ -0,0 +1,188 @@
+// Go Trader dashboard: polls the API and renders positions, the latest
+// signal per symbol, notifications and the automated trading state.
+
+const API = {
+    account: '/api/account',
+    positions: '/api/positions',
+    signals: '/api/signals',
+    notifications: '/api/notifications',
+    status: '/api/algorithm/status',
+    stop: '/api/algorithm/stop'
+};
+
+const REFRESH_MS = 5000;
+const MAX_NOTIFICATIONS = 20;
+const TOKEN_KEY = 'go-trader-token';
+
+// AuthError marks a request refused for want of a valid API token.
+class AuthError extends Error {}
+
+let tokenDeclined = false;
+
+function el(tag, text, className) {
+    const node = document.createElement(tag);
+    if (text !== undefined && text !== null) {
+        node.textContent = text;
+    }
+    if (className) {
+        node.className = className;
+    }
+    return node;
+}
+
+// table builds a table from headers and rows of cells; a cell is text or
+// {text, className}. Everything goes through textContent, never HTML.
+function table(headers, rows, rowClass) {
+    const t = el('table');
+    const head = t.createTHead().insertRow();
+    headers.forEach(h => head.appendChild(el('th', h)));
+    const body = t.createTBody();
+    rows.forEach((cells, i) => {
+        const tr = body.insertRow();
+        if (rowClass) {
+            tr.className = rowClass(i);
+        }
+        cells.forEach(c => {
+            const cell = typeof c === 'object' && c !== null ? c : { text: c };
+            tr.appendChild(el('td', cell.text, cell.className));
+        });
+    });
+    return t;
+}
+
+function render(id, content) {
+    const target = document.getElementById(id);
+    target.replaceChildren(typeof content === 'string' ? el('p', content, 'empty') : content);
+}
+
+function money(v) {
+    const n = Number(v);
+    return Number.isFinite(n) ? n.toLocaleString(undefined, { style: 'currency', currency: 'USD' }) : '-';
+}
+
+function signed(v) {
+    const n = Number(v);
+    return { text: money(v), className: n > 0 ? 'positive' : n < 0 ? 'negative' : '' };
+}
+
+async function getJSON(url, options = {}) {
+    const token = localStorage.getItem(TOKEN_KEY);
+    const headers = token ? { ...options.headers, Authorization: `Bearer ${token}` } : options.headers;
+    const resp = await fetch(url, { ...options, headers });
+    if (resp.status === 401) {
+        throw new AuthError(`${url}: a valid API token is required`);
+    }
+    if (!resp.ok) {
+        throw new Error(`${url}: ${resp.status} ${(await resp.text()).trim()}`);
+    }
+    return resp.json();
+}
+
+async function loadAccount() {
+    const a = await getJSON(API.account);
+    const dayPL = Number(a.equity) - Number(a.last_equity);
+    render('account-data', table(['Equity', 'Cash', 'Buying power', 'Today'], [[
+        money(a.equity), money(a.cash), money(a.buying_power), signed(dayPL)
+    ]]));
+}
+
+async function loadPositions() {
+    const positions = await getJSON(API.positions) || [];
+    if (positions.length === 0) {
+        render('positions-data', 'No open positions');
+        return;
+    }
+    render('positions-data', table(
+        ['Symbol', 'Side', 'Qty', 'Avg price', 'Price', 'Market value', 'Unrealized P&L'],
+        positions.map(p => [p.symbol, p.side, p.qty, money(p.avg_entry_price),
+            money(p.current_price), money(p.market_value), signed(p.unrealized_pl)])
+    ));
+}
+
+async function loadSignals() {
+    const signals = Object.values(await getJSON(API.signals) || {})
+        .filter(s => s)
+        .sort((a, b) => new Date(b.timestamp) - new Date(a.timestamp));
+    if (signals.length === 0) {
+        render('signals-data', 'No signals yet');
+        return;
+    }
+    render('signals-data', table(
+        ['Time', 'Symbol', 'Signal', 'Confidence', 'Source', 'Reasoning'],
+        signals.map(s => [
+            new Date(s.timestamp).toLocaleString(),
+            s.symbol,
+            { text: s.signal.toUpperCase(), className: s.signal },
+            s.confidence != null ? `${Math.round(s.confidence * 100)}%` : '-',
+            s.source || '-',
+            s.reasoning
+        ])
+    ));
+}
+
+async function loadNotifications() {
+    const notifications = (await getJSON(API.notifications) || []).slice(0, MAX_NOTIFICATIONS);
+    if (notifications.length === 0) {
+        render('notifications-data', 'No notifications');
+        return;
+    }
+    render('notifications-data', table(
+        ['Time', 'Priority', 'Title', 'Message'],
+        notifications.map(n => [new Date(n.timestamp).toLocaleString(), n.priority, n.title, n.message]),
+        i => notifications[i].read ? '' : 'unread'
+    ));
+}
+
+async function loadStatus() {
+    const status = await getJSON(API.status);
+    const state = document.getElementById('trading-state');
+    state.textContent = status.is_running ? 'Automated trading: running' : 'Automated trading: stopped';
+    state.className = 'state ' + (status.is_running ? 'running' : 'stopped');
+    document.getElementById('kill-switch').disabled = !status.is_running;
+}
+
+// askToken prompts for an API token once the server starts requiring one,
+// keeping it in local storage for later visits.
+function askToken() {
+    if (tokenDeclined) {
+        return false;
+    }
+    const token = (prompt('This server requires an API token') || '').trim();
+    if (!token) {
+        tokenDeclined = true;
+        return false;
+    }
+    localStorage.setItem(TOKEN_KEY, token);
+    return true;
+}
+
+async function refresh() {
+    const results = await Promise.allSettled([
+        loadStatus(), loadAccount(), loadPositions(), loadSignals(), loadNotifications()
+    ]);
+    if (results.some(r => r.status === 'rejected' && r.reason instanceof AuthError) && askToken()) {
+        return refresh();
+    }
+    const errors = results.filter(r => r.status === 'rejected').map(r => r.reason.message);
+    const box = document.getElementById('error');
+    box.textContent = errors.join('\n');
+    box.hidden = errors.length === 0;
+}
+
+async function stopTrading() {
+    if (!confirm('Stop automated trading? Open positions and orders are left as they are.')) {
+        return;
+    }
+    const button = document.getElementById('kill-switch');
+    button.disabled = true;
+    try {
+        await getJSON(API.stop, { method: 'POST' });
+    } catch (err) {
+        alert(`Failed to stop trading: ${err.message}`);
+    }
+    refresh();
+}
+
+document.getElementById('kill-switch').addEventListener('click', stopTrading);
+refresh();
+setInterval(refresh, REFRESH_MS);
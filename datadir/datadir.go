@@ -0,0 +1,83 @@
+// Package datadir decides where persistent state lives. Each trading mode
+// gets its own directory under a shared root, so baskets, signal history
+// and audit logs written by paper runs never mix with live ones.
+package datadir
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Trading modes, used as directory names under the root.
+const (
+	ModePaper  = "paper"
+	ModeLive   = "live"
+	ModeMock   = "mock"
+	ModeReplay = "replay"
+)
+
+// Legacy lists the entries written directly under the root before data was
+// namespaced by mode.
+var Legacy = []string{"baskets", "signals", "audit"}
+
+// Mode names the directory for a run's trading mode.
+func Mode(paper, mock bool) string {
+	switch {
+	case mock:
+		return ModeMock
+	case paper:
+		return ModePaper
+	default:
+		return ModeLive
+	}
+}
+
+// Resolve returns root/mode, creating it if needed. The first time a paper
+// or live directory is created under root, legacy entries found directly
+// in root are moved into it, so an existing install keeps its baskets and
+// history in the environment it next runs in. Mock and replay runs neither
+// adopt legacy data nor count as that first run. The names of moved
+// entries are returned.
+func Resolve(root, mode string) (string, []string, error) {
+	dir := filepath.Join(root, mode)
+	firstRun := true
+	for _, m := range []string{ModePaper, ModeLive} {
+		if _, err := os.Stat(filepath.Join(root, m)); err == nil {
+			firstRun = false
+			break
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", nil, fmt.Errorf("create data directory: %w", err)
+	}
+	if !firstRun || mode == ModeMock || mode == ModeReplay {
+		return dir, nil, nil
+	}
+
+	var moved []string
+	for _, name := range Legacy {
+		from := filepath.Join(root, name)
+		if _, err := os.Stat(from); err != nil {
+			continue
+		}
+		if err := os.Rename(from, filepath.Join(dir, name)); err != nil {
+			return "", moved, fmt.Errorf("migrate %s: %w", name, err)
+		}
+		moved = append(moved, name)
+	}
+	return dir, moved, nil
+}
+
+// Stranded reports legacy entries still sitting directly under root, which
+// happens when data was written by an older build after the mode
+// directories already existed.
+func Stranded(root string) []string {
+	var out []string
+	for _, name := range Legacy {
+		if _, err := os.Stat(filepath.Join(root, name)); err == nil {
+			out = append(out, name)
+		}
+	}
+	return out
+}
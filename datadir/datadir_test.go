@@ -0,0 +1,57 @@
+package datadir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolveMigratesLegacyDataOnce(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"baskets", "audit"} {
+		if err := os.MkdirAll(filepath.Join(root, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "baskets", "tech.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, moved, err := Resolve(root, ModePaper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir != filepath.Join(root, "paper") || len(moved) != 2 {
+		t.Fatalf("dir = %s, moved = %v", dir, moved)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "baskets", "tech.json")); err != nil {
+		t.Errorf("basket not migrated: %v", err)
+	}
+	if s := Stranded(root); len(s) != 0 {
+		t.Errorf("stranded after migration: %v", s)
+	}
+
+	// Later legacy writes are not adopted by another mode.
+	os.MkdirAll(filepath.Join(root, "signals"), 0755)
+	live, moved, err := Resolve(root, ModeLive)
+	if err != nil || len(moved) != 0 {
+		t.Fatalf("live resolve moved %v, err %v", moved, err)
+	}
+	if _, err := os.Stat(filepath.Join(live, "baskets")); !os.IsNotExist(err) {
+		t.Error("live directory shares paper baskets")
+	}
+	if s := Stranded(root); len(s) != 1 || s[0] != "signals" {
+		t.Errorf("stranded = %v", s)
+	}
+}
+
+func TestMockNeverAdoptsLegacyData(t *testing.T) {
+	root := t.TempDir()
+	os.MkdirAll(filepath.Join(root, "baskets"), 0755)
+	if _, moved, err := Resolve(root, Mode(true, true)); err != nil || len(moved) != 0 {
+		t.Fatalf("moved %v, err %v", moved, err)
+	}
+	if _, moved, _ := Resolve(root, Mode(true, false)); len(moved) != 1 {
+		t.Errorf("paper moved %v, want legacy baskets after a mock run", moved)
+	}
+}
@@ -0,0 +1,87 @@
+package dataquality
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/rileyseaburg/go-trader/paging"
+)
+
+// Handler exposes the data quality monitor over HTTP.
+type Handler struct {
+	monitor *Monitor
+}
+
+// NewHandler creates a handler for monitor.
+func NewHandler(monitor *Monitor) *Handler {
+	return &Handler{monitor: monitor}
+}
+
+// RegisterRoutes registers the data quality routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/data-quality - policy, per-symbol metrics and degraded symbols
+	mux.HandleFunc("/api/data-quality", h.handleStatus)
+
+	// GET /api/data-quality/issues?limit=&cursor=&sort=&filter= - quarantined points and gaps
+	mux.HandleFunc("/api/data-quality/issues", h.handleIssues)
+
+	// GET/POST /api/data-quality/policy - read or update the policy
+	mux.HandleFunc("/api/data-quality/policy", h.handlePolicy)
+}
+
+func (h *Handler) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"policy":   h.monitor.Policy(),
+		"symbols":  h.monitor.Symbols(),
+		"degraded": h.monitor.Degraded(),
+	})
+}
+
+// issueList pages the issues, newest first.
+var issueList = paging.List[Issue]{
+	Fields: []paging.Field[Issue]{
+		{Name: "detected_at", Sort: func(i Issue) paging.Key { return paging.Time(i.DetectedAt) }},
+		{Name: "at", Sort: func(i Issue) paging.Key { return paging.Time(i.At) }},
+		{Name: "symbol", Sort: func(i Issue) paging.Key { return paging.Text(i.Symbol) }, Filter: func(i Issue) string { return i.Symbol }},
+		{Name: "kind", Filter: func(i Issue) string { return i.Kind }},
+		{Name: "timeframe", Filter: func(i Issue) string { return i.Timeframe }},
+		{Name: "reason", Filter: func(i Issue) string { return i.Reason }},
+		{Name: "quarantined", Filter: func(i Issue) string { return strconv.FormatBool(i.Quarantined) }},
+	},
+	ID:   func(i Issue) string { return i.key() },
+	Sort: "-detected_at",
+}
+
+func (h *Handler) handleIssues(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	issueList.Serve(w, r, h.monitor.Issues())
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.monitor.Policy())
+	case http.MethodPost, http.MethodPut:
+		// Start from the current policy so partial updates work
+		policy := h.monitor.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.monitor.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(policy)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
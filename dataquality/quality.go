@@ -0,0 +1,448 @@
+// Package dataquality checks bars and quotes before anything uses them —
+// prices that are not positive, highs below lows, crossed quotes, moves
+// far outside a symbol's recent distribution, duplicate or out-of-order
+// timestamps and gaps — quarantines the bad ones so they are never cached,
+// trained or traded on, keeps quality metrics per symbol and alerts when a
+// symbol's feed degrades.
+package dataquality
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/calendar"
+)
+
+func logger() *slog.Logger { return slog.With("module", "dataquality") }
+
+// Kinds of data checked.
+const (
+	KindBar   = "bar"
+	KindQuote = "quote"
+)
+
+// Issue reasons. Every reason but a gap quarantines the point.
+const (
+	ReasonPrice      = "bad_price"     // a price that is not positive, or negative volume
+	ReasonRange      = "bad_range"     // high below low, or open or close outside them
+	ReasonCrossed    = "crossed_quote" // bid above ask
+	ReasonOutlier    = "outlier"       // a move beyond MaxZScore of recent moves
+	ReasonDuplicate  = "duplicate"     // a timestamp already seen, with other values
+	ReasonOutOfOrder = "out_of_order"  // older than the last point accepted
+	ReasonGap        = "gap"           // bars missing before this one, which is kept
+)
+
+// maxIssues bounds the issues kept in memory.
+const maxIssues = 2000
+
+// minAlertPoints is how many points a symbol needs checked before its feed
+// can be judged degraded.
+const minAlertPoints = 20
+
+// Policy configures the checks and when a feed counts as degraded.
+type Policy struct {
+	Enabled            bool    `json:"enabled"`
+	MaxZScore          float64 `json:"max_z_score"`          // move, in standard deviations of recent moves, that is an outlier
+	ZWindow            int     `json:"z_window"`             // recent moves the z-score is measured against
+	MinSamples         int     `json:"min_samples"`          // moves needed before outliers are judged
+	MaxMissingMinutes  int     `json:"max_missing_minutes"`  // intraday bars missing within a session before a gap counts
+	MaxMissingSessions int     `json:"max_missing_sessions"` // daily bars missing before a gap counts
+	AlertWindow        int     `json:"alert_window"`         // recent points per symbol the degraded test looks at
+	MaxBadPercent      float64 `json:"max_bad_percent"`      // share of them quarantined or after a gap that degrades the feed
+}
+
+// DefaultPolicy quarantines moves beyond 8 standard deviations of the last
+// 100 once 20 are seen, counts 15 missing minutes or any missing session as
+// a gap, and alerts when more than 5% of a symbol's last 100 points were
+// bad.
+func DefaultPolicy() Policy {
+	return Policy{
+		Enabled:            true,
+		MaxZScore:          8,
+		ZWindow:            100,
+		MinSamples:         20,
+		MaxMissingMinutes:  15,
+		MaxMissingSessions: 0,
+		AlertWindow:        100,
+		MaxBadPercent:      5,
+	}
+}
+
+// Validate checks the policy for usable values.
+func (p Policy) Validate() error {
+	if p.MaxZScore <= 0 {
+		return errors.New("max_z_score must be positive")
+	}
+	if p.ZWindow < 2 {
+		return errors.New("z_window must be at least 2")
+	}
+	if p.MinSamples < 2 || p.MinSamples > p.ZWindow {
+		return errors.New("min_samples must be between 2 and z_window")
+	}
+	if p.MaxMissingMinutes < 0 || p.MaxMissingSessions < 0 {
+		return errors.New("max_missing_minutes and max_missing_sessions must not be negative")
+	}
+	if p.AlertWindow < minAlertPoints {
+		return fmt.Errorf("alert_window must be at least %d", minAlertPoints)
+	}
+	if p.MaxBadPercent <= 0 || p.MaxBadPercent >= 100 {
+		return errors.New("max_bad_percent must be between 0 and 100")
+	}
+	return nil
+}
+
+// Bar is one price bar.
+type Bar struct {
+	Time   time.Time `json:"time"` // start of the bar
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Volume float64   `json:"volume"`
+}
+
+// Quote is one top-of-book quote.
+type Quote struct {
+	Time time.Time `json:"time"`
+	Bid  float64   `json:"bid"`
+	Ask  float64   `json:"ask"`
+}
+
+// Issue is a problem found with a bar or quote.
+type Issue struct {
+	Symbol      string    `json:"symbol"`
+	Kind        string    `json:"kind"`
+	Timeframe   string    `json:"timeframe,omitempty"` // bars only
+	At          time.Time `json:"at"`                  // the point's timestamp
+	Reason      string    `json:"reason"`
+	Detail      string    `json:"detail"`
+	Value       float64   `json:"value,omitempty"` // z-score, or minutes or sessions missing
+	Quarantined bool      `json:"quarantined"`
+	Bar         *Bar      `json:"bar,omitempty"`
+	Quote       *Quote    `json:"quote,omitempty"`
+	DetectedAt  time.Time `json:"detected_at"`
+}
+
+// key identifies the issue so a point fetched again is not counted twice.
+func (i Issue) key() string {
+	return i.Symbol + "|" + i.Kind + "|" + i.Timeframe + "|" + i.At.UTC().Format(time.RFC3339Nano) + "|" + i.Reason
+}
+
+// Metrics are a symbol's data quality since start.
+type Metrics struct {
+	Symbol        string         `json:"symbol"`
+	Bars          int            `json:"bars"`   // checked
+	Quotes        int            `json:"quotes"` // checked
+	Quarantined   int            `json:"quarantined"`
+	Gaps          int            `json:"gaps"`
+	Reasons       map[string]int `json:"reasons,omitempty"`
+	BadPercent    float64        `json:"bad_percent"` // of the recent alert window
+	Degraded      bool           `json:"degraded"`
+	DegradedSince *time.Time     `json:"degraded_since,omitempty"`
+	LastIssue     *Issue         `json:"last_issue,omitempty"`
+}
+
+// Notifier is told when a symbol's feed degrades or recovers.
+type Notifier func(title, message string, metadata map[string]interface{})
+
+// symbolState is a symbol's metrics and recent outcomes.
+type symbolState struct {
+	metrics Metrics
+	recent  []bool // bad or not, oldest first, at most AlertWindow
+}
+
+// Monitor checks bars and quotes against the policy, per symbol and, for
+// bars, per timeframe.
+type Monitor struct {
+	cal *calendar.Calendar
+
+	mu      sync.Mutex
+	policy  Policy
+	now     func() time.Time
+	notify  Notifier
+	series  map[string]*series // by symbol|kind|timeframe, for streamed points
+	symbols map[string]*symbolState
+	issues  []Issue // oldest first
+	seen    map[string]bool
+}
+
+// New returns a monitor with the given policy.
+func New(cal *calendar.Calendar, policy Policy) *Monitor {
+	return &Monitor{
+		cal:     cal,
+		policy:  policy,
+		now:     time.Now,
+		series:  make(map[string]*series),
+		symbols: make(map[string]*symbolState),
+		seen:    make(map[string]bool),
+	}
+}
+
+// SetClock overrides the clock, for replays and tests.
+func (m *Monitor) SetClock(now func() time.Time) { m.mu.Lock(); m.now = now; m.mu.Unlock() }
+
+// SetNotifier registers a callback for feeds degrading and recovering.
+func (m *Monitor) SetNotifier(n Notifier) { m.mu.Lock(); m.notify = n; m.mu.Unlock() }
+
+// Policy returns the current policy.
+func (m *Monitor) Policy() Policy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.policy
+}
+
+// SetPolicy validates and replaces the policy.
+func (m *Monitor) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policy = p
+	return nil
+}
+
+// CheckBar checks a streamed bar against the symbol's earlier bars in
+// timeframe and reports whether it may be used. A bar seen before with
+// the same values gets the same answer without being counted again.
+func (m *Monitor) CheckBar(symbol, timeframe string, b Bar) bool {
+	symbol = strings.ToUpper(symbol)
+	m.mu.Lock()
+	if !m.policy.Enabled {
+		m.mu.Unlock()
+		return true
+	}
+	s := m.seriesLocked(symbol, KindBar, timeframe)
+	if seen, ok := s.repeat(b.Time, barValues(b)); seen {
+		m.mu.Unlock()
+		return ok
+	}
+	issues, ok := s.checkBar(m.policy, m.cal, symbol, timeframe, b, nil)
+	alert := m.recordLocked(symbol, KindBar, issues, ok)
+	m.mu.Unlock()
+	alert()
+	return ok
+}
+
+// CheckQuote checks a streamed quote against the symbol's earlier quotes
+// and reports whether it may be used.
+func (m *Monitor) CheckQuote(symbol string, q Quote) bool {
+	symbol = strings.ToUpper(symbol)
+	m.mu.Lock()
+	if !m.policy.Enabled {
+		m.mu.Unlock()
+		return true
+	}
+	s := m.seriesLocked(symbol, KindQuote, "")
+	if seen, ok := s.repeat(q.Time, []float64{q.Bid, q.Ask}); seen {
+		m.mu.Unlock()
+		return ok
+	}
+	issues, ok := s.checkQuote(m.policy, symbol, q)
+	alert := m.recordLocked(symbol, KindQuote, issues, ok)
+	m.mu.Unlock()
+	alert()
+	return ok
+}
+
+// Quarantined reports whether q is the symbol's last quote and was
+// quarantined, for readers of the latest quote that bypass CheckQuote.
+func (m *Monitor) Quarantined(symbol string, q Quote) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.series[strings.ToUpper(symbol)+"|"+KindQuote+"|"]
+	if !ok {
+		return false
+	}
+	seen, accepted := s.repeat(q.Time, []float64{q.Bid, q.Ask})
+	return seen && !accepted
+}
+
+// FilterBars checks a fetched series of bars, oldest first, on its own and
+// returns the ones that may be used. An outlier the next bar confirms, a
+// level the series moved to rather than a spike it came back from, is kept.
+// Issues already recorded for the same bars are not counted again.
+func (m *Monitor) FilterBars(symbol, timeframe string, bars []Bar) []Bar {
+	symbol = strings.ToUpper(symbol)
+	m.mu.Lock()
+	if !m.policy.Enabled || len(bars) == 0 {
+		m.mu.Unlock()
+		return bars
+	}
+	s := newSeries()
+	kept := make([]Bar, 0, len(bars))
+	var alerts []func()
+	for i, b := range bars {
+		var next *Bar
+		if i+1 < len(bars) {
+			next = &bars[i+1]
+		}
+		issues, ok := s.checkBar(m.policy, m.cal, symbol, timeframe, b, next)
+		issues = m.unseenLocked(issues)
+		if len(issues) > 0 || ok {
+			alerts = append(alerts, m.recordLocked(symbol, KindBar, issues, ok))
+		}
+		if ok {
+			kept = append(kept, b)
+		}
+	}
+	m.mu.Unlock()
+	for _, alert := range alerts {
+		alert()
+	}
+	if dropped := len(bars) - len(kept); dropped > 0 {
+		logger().Warn("Quarantined fetched bars", "symbol", symbol, "timeframe", timeframe, "dropped", dropped, "of", len(bars))
+	}
+	return kept
+}
+
+// unseenLocked drops issues already recorded.
+func (m *Monitor) unseenLocked(issues []Issue) []Issue {
+	out := issues[:0]
+	for _, i := range issues {
+		if !m.seen[i.key()] {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+func (m *Monitor) seriesLocked(symbol, kind, timeframe string) *series {
+	key := symbol + "|" + kind + "|" + timeframe
+	s, ok := m.series[key]
+	if !ok {
+		s = newSeries()
+		m.series[key] = s
+	}
+	return s
+}
+
+// recordLocked counts one checked point and its issues and returns the
+// alert to send, if the symbol's feed changed state, once the lock is
+// released.
+func (m *Monitor) recordLocked(symbol, kind string, issues []Issue, accepted bool) func() {
+	st, ok := m.symbols[symbol]
+	if !ok {
+		st = &symbolState{metrics: Metrics{Symbol: symbol}}
+		m.symbols[symbol] = st
+	}
+	now := m.now()
+	mt := &st.metrics
+	if kind == KindBar {
+		mt.Bars++
+	} else {
+		mt.Quotes++
+	}
+	for _, i := range issues {
+		i.DetectedAt = now
+		m.seen[i.key()] = true
+		m.issues = append(m.issues, i)
+		if mt.Reasons == nil {
+			mt.Reasons = make(map[string]int)
+		}
+		mt.Reasons[i.Reason]++
+		if i.Reason == ReasonGap {
+			mt.Gaps++
+		}
+		last := i
+		mt.LastIssue = &last
+	}
+	if !accepted {
+		mt.Quarantined++
+	}
+	if len(m.issues) > maxIssues {
+		for _, i := range m.issues[:len(m.issues)-maxIssues] {
+			delete(m.seen, i.key())
+		}
+		m.issues = append([]Issue(nil), m.issues[len(m.issues)-maxIssues:]...)
+	}
+
+	st.recent = append(st.recent, len(issues) > 0)
+	if over := len(st.recent) - m.policy.AlertWindow; over > 0 {
+		st.recent = st.recent[over:]
+	}
+	bad := 0
+	for _, b := range st.recent {
+		if b {
+			bad++
+		}
+	}
+	mt.BadPercent = math.Round(float64(bad)/float64(len(st.recent))*10000) / 100
+	judged := len(st.recent) >= minAlertPoints
+
+	notify := m.notify
+	switch {
+	case !mt.Degraded && judged && mt.BadPercent > m.policy.MaxBadPercent:
+		mt.Degraded = true
+		mt.DegradedSince = &now
+		logger().Warn("Market data degraded", "symbol", symbol, "bad_percent", mt.BadPercent, "reasons", mt.Reasons)
+		title := fmt.Sprintf("%s market data degraded", symbol)
+		message := fmt.Sprintf("%.1f%% of the last %d points for %s were quarantined or followed a gap (last: %s). Bad points are kept out of the cache, features and signals.",
+			mt.BadPercent, len(st.recent), symbol, mt.LastIssue.Detail)
+		meta := map[string]interface{}{"symbol": symbol, "bad_percent": mt.BadPercent, "reasons": copyReasons(mt.Reasons)}
+		if notify != nil {
+			return func() { notify(title, message, meta) }
+		}
+	case mt.Degraded && mt.BadPercent <= m.policy.MaxBadPercent/2:
+		mt.Degraded = false
+		mt.DegradedSince = nil
+		logger().Info("Market data recovered", "symbol", symbol, "bad_percent", mt.BadPercent)
+		title := fmt.Sprintf("%s market data recovered", symbol)
+		message := fmt.Sprintf("%.1f%% of the last %d points for %s were bad.", mt.BadPercent, len(st.recent), symbol)
+		meta := map[string]interface{}{"symbol": symbol, "bad_percent": mt.BadPercent}
+		if notify != nil {
+			return func() { notify(title, message, meta) }
+		}
+	}
+	return func() {}
+}
+
+func copyReasons(r map[string]int) map[string]int {
+	out := make(map[string]int, len(r))
+	for k, v := range r {
+		out[k] = v
+	}
+	return out
+}
+
+// Symbols returns every checked symbol's metrics, sorted by symbol.
+func (m *Monitor) Symbols() []Metrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Metrics, 0, len(m.symbols))
+	for _, st := range m.symbols {
+		mt := st.metrics
+		mt.Reasons = copyReasons(mt.Reasons)
+		out = append(out, mt)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Symbol < out[j].Symbol })
+	return out
+}
+
+// Degraded lists the symbols whose feed is degraded, sorted.
+func (m *Monitor) Degraded() []string {
+	var out []string
+	for _, mt := range m.Symbols() {
+		if mt.Degraded {
+			out = append(out, mt.Symbol)
+		}
+	}
+	return out
+}
+
+// Issues returns the recorded issues, newest first.
+func (m *Monitor) Issues() []Issue {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Issue, len(m.issues))
+	for i, issue := range m.issues {
+		out[len(m.issues)-1-i] = issue
+	}
+	return out
+}
@@ -0,0 +1,161 @@
+package dataquality
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/calendar"
+)
+
+var cal = calendar.New()
+
+// minute is 10:00 Eastern on Tuesday 2026-09-15 plus n minutes.
+func minute(n int) time.Time {
+	return time.Date(2026, 9, 15, 10, n, 0, 0, cal.Location())
+}
+
+// bar is a bar closing at c with a range around it.
+func bar(t time.Time, c float64) Bar {
+	return Bar{Time: t, Open: c, High: c + 0.05, Low: c - 0.05, Close: c, Volume: 1000}
+}
+
+// warm feeds 30 minute bars closing at 100 and 100.1 in turn.
+func warm(t *testing.T, m *Monitor) {
+	t.Helper()
+	for i := 0; i < 30; i++ {
+		if !m.CheckBar("aapl", "1Min", bar(minute(i), 100+0.1*float64(i%2))) {
+			t.Fatalf("normal bar %d quarantined", i)
+		}
+	}
+}
+
+func TestStreamedBarsQuarantined(t *testing.T) {
+	m := New(cal, DefaultPolicy())
+	warm(t, m)
+
+	cases := []struct {
+		name string
+		bar  Bar
+		ok   bool
+	}{
+		{"zero close", Bar{Time: minute(30), Open: 100, High: 100, Low: 100}, false},
+		{"high below low", Bar{Time: minute(30), Open: 100, High: 99, Low: 101, Close: 100}, false},
+		{"spike", bar(minute(30), 110), false},
+		{"spike polled again", bar(minute(30), 110), false},
+		{"back to normal", bar(minute(31), 100.05), true},
+		{"polled again", bar(minute(31), 100.05), true},
+		{"out of order", bar(minute(20), 100), false},
+		{"same minute, other values", bar(minute(31), 100.1), false},
+		{"after a gap", bar(minute(52), 100.1), true},
+	}
+	for _, c := range cases {
+		if got := m.CheckBar("AAPL", "1Min", c.bar); got != c.ok {
+			t.Errorf("%s: accepted = %v", c.name, got)
+		}
+	}
+
+	mt := m.Symbols()[0]
+	if mt.Symbol != "AAPL" || mt.Bars != 30+7 || mt.Quarantined != 5 || mt.Gaps != 1 {
+		t.Errorf("metrics = %+v", mt)
+	}
+	for reason, want := range map[string]int{ReasonPrice: 1, ReasonRange: 1, ReasonOutlier: 1, ReasonOutOfOrder: 1, ReasonDuplicate: 1, ReasonGap: 1} {
+		if mt.Reasons[reason] != want {
+			t.Errorf("%s = %d, want %d", reason, mt.Reasons[reason], want)
+		}
+	}
+	if issues := m.Issues(); len(issues) != 6 || issues[0].Reason != ReasonGap || issues[0].Quarantined || issues[0].Value != 20 {
+		t.Errorf("issues = %+v", issues)
+	}
+}
+
+func TestFilterBarsKeepsNewLevels(t *testing.T) {
+	m := New(cal, DefaultPolicy())
+	// Daily bars on consecutive sessions from Monday 2026-08-03, drifting
+	// around 100, then a spike that reverts and a jump that holds
+	var bars []Bar
+	day := time.Date(2026, 8, 3, 0, 0, 0, 0, cal.Location())
+	for len(bars) < 30 {
+		if cal.IsTradingDay(day) {
+			bars = append(bars, bar(day, 100+0.5*float64(len(bars)%3)))
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	next := func(c float64) {
+		for !cal.IsTradingDay(day) {
+			day = day.AddDate(0, 0, 1)
+		}
+		bars = append(bars, bar(day, c))
+		day = day.AddDate(0, 0, 1)
+	}
+	next(150) // spike
+	next(100.5)
+	next(130) // new level
+	next(130.5)
+	day = day.AddDate(0, 0, 7) // a missing week
+	next(131)
+
+	kept := m.FilterBars("MSFT", "1D", bars)
+	if len(kept) != len(bars)-1 || kept[30].Close != 100.5 || kept[31].Close != 130 {
+		t.Fatalf("kept %d of %d", len(kept), len(bars))
+	}
+	mt := m.Symbols()[0]
+	if mt.Quarantined != 1 || mt.Gaps != 1 || mt.Reasons[ReasonOutlier] != 1 {
+		t.Errorf("metrics = %+v", mt)
+	}
+
+	// Fetching the same bars again records nothing new
+	m.FilterBars("MSFT", "1D", bars)
+	if got := m.Symbols()[0]; got.Quarantined != 1 || len(m.Issues()) != 2 {
+		t.Errorf("after refetch = %+v, %d issues", got, len(m.Issues()))
+	}
+}
+
+func TestDegradedFeedAlerts(t *testing.T) {
+	m := New(cal, DefaultPolicy())
+	var alerts []string
+	m.SetNotifier(func(title, message string, _ map[string]interface{}) { alerts = append(alerts, title) })
+
+	quote := func(n int, bid, ask float64) bool {
+		return m.CheckQuote("NVDA", Quote{Time: minute(0).Add(time.Duration(n) * time.Second), Bid: bid, Ask: ask})
+	}
+	n := 0
+	for ; n < 25; n++ {
+		if n%4 == 0 {
+			quote(n, 100.02, 100) // crossed
+		} else {
+			quote(n, 100, 100.02)
+		}
+	}
+	if len(alerts) != 1 || alerts[0] != "NVDA market data degraded" || len(m.Degraded()) != 1 {
+		t.Fatalf("alerts = %v", alerts)
+	}
+	for ; n < 200 && len(alerts) == 1; n++ {
+		quote(n, 100, 100.02)
+	}
+	if len(alerts) != 2 || alerts[1] != "NVDA market data recovered" || len(m.Degraded()) != 0 {
+		t.Errorf("alerts = %v after %d quotes", alerts, n)
+	}
+}
+
+func TestHandlerPagesIssues(t *testing.T) {
+	m := New(cal, DefaultPolicy())
+	warm(t, m)
+	m.CheckBar("AAPL", "1Min", bar(minute(30), 0))
+	m.CheckQuote("AAPL", Quote{Time: minute(30), Bid: 101, Ask: 100})
+	mux := http.NewServeMux()
+	NewHandler(m).RegisterRoutes(mux)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/data-quality/issues?filter=kind:quote", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("X-Total-Count") != "1" || !strings.Contains(rec.Body.String(), `"reason":"crossed_quote"`) {
+		t.Errorf("issues = %d %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/data-quality/policy", strings.NewReader(`{"min_samples": 500}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("min_samples beyond z_window = %d", rec.Code)
+	}
+}
@@ -0,0 +1,231 @@
+package dataquality
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/calendar"
+)
+
+// minStdDev floors the spread of recent moves, so a symbol whose price
+// has not moved does not turn its first tick into an outlier.
+const minStdDev = 0.0005
+
+// intervals are the intraday bar timeframes gaps are measured in.
+var intervals = map[string]time.Duration{
+	"1Min":  time.Minute,
+	"5Min":  5 * time.Minute,
+	"15Min": 15 * time.Minute,
+	"1H":    time.Hour,
+}
+
+// series is the state one symbol's bars in a timeframe, or its quotes,
+// are checked against.
+type series struct {
+	last    time.Time // timestamp of the last point accepted
+	values  []float64 // its values
+	price   float64   // its close or mid
+	moves   []float64 // log moves between accepted points, oldest first
+	suspect float64   // price of the last outlier quarantined, 0 for none
+
+	rejected       time.Time // timestamp of the last point quarantined
+	rejectedValues []float64
+}
+
+func newSeries() *series { return &series{} }
+
+func barValues(b Bar) []float64 { return []float64{b.Open, b.High, b.Low, b.Close, b.Volume} }
+
+func sameValues(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// repeat reports whether a point is the last one accepted or quarantined
+// polled again, and if so whether it was accepted.
+func (s *series) repeat(t time.Time, values []float64) (seen, ok bool) {
+	switch {
+	case !s.last.IsZero() && t.Equal(s.last) && sameValues(values, s.values):
+		return true, true
+	case !s.rejected.IsZero() && t.Equal(s.rejected) && sameValues(values, s.rejectedValues):
+		return true, false
+	}
+	return false, false
+}
+
+// checkBar checks b after the bars already accepted. next, when known,
+// is the bar after it, which can confirm an outlier as a new level.
+func (s *series) checkBar(p Policy, cal *calendar.Calendar, symbol, timeframe string, b Bar, next *Bar) ([]Issue, bool) {
+	issue := Issue{Symbol: symbol, Kind: KindBar, Timeframe: timeframe, At: b.Time, Quarantined: true, Bar: &b}
+	reject := func(reason, detail string, value float64) ([]Issue, bool) {
+		issue.Reason, issue.Detail, issue.Value = reason, detail, value
+		s.rejected, s.rejectedValues = b.Time, barValues(b)
+		return []Issue{issue}, false
+	}
+
+	switch {
+	case b.Open <= 0 || b.High <= 0 || b.Low <= 0 || b.Close <= 0:
+		return reject(ReasonPrice, fmt.Sprintf("price not positive (open %.4g, high %.4g, low %.4g, close %.4g)", b.Open, b.High, b.Low, b.Close), 0)
+	case b.Volume < 0:
+		return reject(ReasonPrice, fmt.Sprintf("negative volume %.0f", b.Volume), 0)
+	case b.High < b.Low:
+		return reject(ReasonRange, fmt.Sprintf("high %.4g below low %.4g", b.High, b.Low), 0)
+	case b.Open > b.High || b.Open < b.Low || b.Close > b.High || b.Close < b.Low:
+		return reject(ReasonRange, fmt.Sprintf("open %.4g or close %.4g outside low %.4g to high %.4g", b.Open, b.Close, b.Low, b.High), 0)
+	}
+	if reason, detail, bad := s.order(b.Time); bad {
+		return reject(reason, detail, 0)
+	}
+	confirm := 0.0
+	if next != nil {
+		confirm = next.Close
+	}
+	z, outlier, shift := s.outlier(p, b.Close, confirm)
+	if outlier {
+		return reject(ReasonOutlier, fmt.Sprintf("close %.4g moved %.1f standard deviations from %.4g", b.Close, z, s.price), round2(z))
+	}
+
+	// A quarantined bar was not missing, so gaps run from the later of the
+	// last bar accepted and the last quarantined
+	since := s.last
+	if s.rejected.After(since) {
+		since = s.rejected
+	}
+	var issues []Issue
+	if missing, unit, gap := gapBefore(p, cal, timeframe, since, b.Time); gap {
+		issue.Reason, issue.Value, issue.Quarantined = ReasonGap, missing, false
+		issue.Detail = fmt.Sprintf("%.0f %s missing before this bar", missing, unit)
+		issues = append(issues, issue)
+	}
+	s.accept(p, b.Time, barValues(b), b.Close, !shift)
+	return issues, true
+}
+
+// checkQuote checks q after the quotes already accepted.
+func (s *series) checkQuote(p Policy, symbol string, q Quote) ([]Issue, bool) {
+	issue := Issue{Symbol: symbol, Kind: KindQuote, At: q.Time, Quarantined: true, Quote: &q}
+	reject := func(reason, detail string, value float64) ([]Issue, bool) {
+		issue.Reason, issue.Detail, issue.Value = reason, detail, value
+		s.rejected, s.rejectedValues = q.Time, []float64{q.Bid, q.Ask}
+		return []Issue{issue}, false
+	}
+
+	switch {
+	case q.Bid <= 0 || q.Ask <= 0:
+		return reject(ReasonPrice, fmt.Sprintf("bid %.4g or ask %.4g not positive", q.Bid, q.Ask), 0)
+	case q.Bid > q.Ask:
+		return reject(ReasonCrossed, fmt.Sprintf("bid %.4g above ask %.4g", q.Bid, q.Ask), 0)
+	}
+	if reason, detail, bad := s.order(q.Time); bad {
+		return reject(reason, detail, 0)
+	}
+	mid := (q.Bid + q.Ask) / 2
+	z, outlier, shift := s.outlier(p, mid, 0)
+	if outlier {
+		return reject(ReasonOutlier, fmt.Sprintf("mid %.4g moved %.1f standard deviations from %.4g", mid, z, s.price), round2(z))
+	}
+	s.accept(p, q.Time, []float64{q.Bid, q.Ask}, mid, !shift)
+	return nil, true
+}
+
+// order checks a timestamp against the last point accepted.
+func (s *series) order(t time.Time) (reason, detail string, bad bool) {
+	switch {
+	case s.last.IsZero():
+		return "", "", false
+	case t.Before(s.last):
+		return ReasonOutOfOrder, fmt.Sprintf("timestamp %s before the last accepted %s", t.UTC().Format(time.RFC3339), s.last.UTC().Format(time.RFC3339)), true
+	case t.Equal(s.last):
+		return ReasonDuplicate, fmt.Sprintf("timestamp %s already seen with other values", t.UTC().Format(time.RFC3339)), true
+	}
+	return "", "", false
+}
+
+// outlier measures the move to price in standard deviations of recent
+// moves. A move beyond MaxZScore is an outlier unless the series holds
+// near the new price: confirm, the next price when known, or the next
+// price after an outlier was quarantined, stays within MaxZScore of it.
+// shift reports a new level accepted that way.
+func (s *series) outlier(p Policy, price, confirm float64) (z float64, outlier, shift bool) {
+	if s.price <= 0 {
+		return 0, false, false
+	}
+	z = s.z(p, math.Log(price/s.price))
+	if z <= p.MaxZScore {
+		return z, false, false
+	}
+	if confirm > 0 && s.z(p, math.Log(confirm/price)) <= p.MaxZScore {
+		return z, false, true
+	}
+	if s.suspect > 0 && s.z(p, math.Log(price/s.suspect)) <= p.MaxZScore {
+		return z, false, true
+	}
+	s.suspect = price
+	return z, true, false
+}
+
+// z is how many standard deviations of recent moves move is from their
+// mean, 0 until MinSamples moves are known.
+func (s *series) z(p Policy, move float64) float64 {
+	n := len(s.moves)
+	if n < p.MinSamples {
+		return 0
+	}
+	var sum, sq float64
+	for _, m := range s.moves {
+		sum += m
+	}
+	mean := sum / float64(n)
+	for _, m := range s.moves {
+		sq += (m - mean) * (m - mean)
+	}
+	std := math.Max(math.Sqrt(sq/float64(n-1)), minStdDev)
+	return math.Abs(move-mean) / std
+}
+
+// accept makes a point the one later points are checked against. A move
+// to a new level is not added to the recent moves, where it would widen
+// what counts as normal.
+func (s *series) accept(p Policy, t time.Time, values []float64, price float64, addMove bool) {
+	if s.price > 0 && addMove {
+		s.moves = append(s.moves, math.Log(price/s.price))
+		if over := len(s.moves) - p.ZWindow; over > 0 {
+			s.moves = s.moves[over:]
+		}
+	}
+	s.last, s.values, s.price, s.suspect = t, values, price, 0
+}
+
+// gapBefore measures the bars missing between last and t: sessions for
+// daily bars, minutes within one regular session for intraday bars.
+// Overnight and weekend breaks are not gaps.
+func gapBefore(p Policy, cal *calendar.Calendar, timeframe string, last, t time.Time) (missing float64, unit string, gap bool) {
+	if last.IsZero() {
+		return 0, "", false
+	}
+	if timeframe == "1D" {
+		n := cal.TradingDaysBetween(last, t) - 1
+		return float64(n), "sessions", n > p.MaxMissingSessions
+	}
+	step, ok := intervals[timeframe]
+	if !ok || !cal.IsOpen(last) || !cal.IsOpen(t) {
+		return 0, "", false
+	}
+	a, _ := cal.SessionFor(last)
+	b, _ := cal.SessionFor(t)
+	if !a.Date.Equal(b.Date) {
+		return 0, "", false
+	}
+	minutes := (t.Sub(last) - step).Minutes()
+	return minutes, "minutes", minutes > float64(p.MaxMissingMinutes)
+}
+
+func round2(v float64) float64 { return math.Round(v*100) / 100 }
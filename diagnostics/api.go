@@ -0,0 +1,182 @@
+package diagnostics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// API error-rate thresholds for the traffic light. Rates are only judged
+// once a window holds minRequests.
+const (
+	yellowErrorRate = 0.05
+	redErrorRate    = 0.25
+	minRequests     = 5
+)
+
+// APIStats summarizes one API's requests over the monitor's window.
+type APIStats struct {
+	API          string    `json:"api"`
+	Requests     int       `json:"requests"`
+	Errors       int       `json:"errors"`        // transport failures, 5xx and 429
+	RateLimited  int       `json:"rate_limited"`  // 429s, also counted as errors
+	ClientErrors int       `json:"client_errors"` // other 4xx, such as no position to sell
+	ErrorRate    float64   `json:"error_rate"`    // errors over requests
+	LastError    string    `json:"last_error,omitempty"`
+	LastErrorAt  time.Time `json:"last_error_at,omitempty"`
+}
+
+type apiCall struct {
+	at     time.Time
+	status int // zero for a transport failure
+}
+
+type apiLog struct {
+	calls       []apiCall
+	lastError   string
+	lastErrorAt time.Time
+}
+
+// APIMonitor counts the outcome of outgoing API requests per API over a
+// rolling window. Its transports wrap the HTTP clients handed to the
+// Alpaca SDK.
+type APIMonitor struct {
+	window time.Duration
+
+	mu   sync.Mutex
+	apis map[string]*apiLog
+}
+
+// NewAPIMonitor returns a monitor keeping window of history.
+func NewAPIMonitor(window time.Duration) *APIMonitor {
+	return &APIMonitor{window: window, apis: make(map[string]*apiLog)}
+}
+
+// Client returns an HTTP client with timeout whose requests are counted
+// under api.
+func (m *APIMonitor) Client(api string, timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: m.Transport(api, nil)}
+}
+
+// Transport wraps base, or the default transport when nil, counting every
+// request under api.
+func (m *APIMonitor) Transport(api string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	m.mu.Lock()
+	if _, ok := m.apis[api]; !ok {
+		m.apis[api] = &apiLog{}
+	}
+	m.mu.Unlock()
+	return roundTripper{monitor: m, api: api, base: base}
+}
+
+type roundTripper struct {
+	monitor *APIMonitor
+	api     string
+	base    http.RoundTripper
+}
+
+func (t roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	status := 0
+	var failure string
+	switch {
+	case err != nil:
+		failure = err.Error()
+	default:
+		status = resp.StatusCode
+		if status >= 400 {
+			failure = fmt.Sprintf("%s %s: %s", req.Method, req.URL.Path, resp.Status)
+		}
+	}
+	t.monitor.record(t.api, time.Now(), status, failure)
+	return resp, err
+}
+
+func (m *APIMonitor) record(api string, at time.Time, status int, failure string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l, ok := m.apis[api]
+	if !ok {
+		l = &apiLog{}
+		m.apis[api] = l
+	}
+	l.calls = append(pruneCalls(l.calls, at.Add(-m.window)), apiCall{at: at, status: status})
+	if failure != "" && isError(status) {
+		l.lastError = failure
+		l.lastErrorAt = at
+	}
+}
+
+func isError(status int) bool {
+	return status == 0 || status == http.StatusTooManyRequests || status >= 500
+}
+
+func pruneCalls(calls []apiCall, cutoff time.Time) []apiCall {
+	i := 0
+	for i < len(calls) && calls[i].at.Before(cutoff) {
+		i++
+	}
+	return calls[i:]
+}
+
+// Stats summarizes each API's window, sorted by name.
+func (m *APIMonitor) Stats() []APIStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cutoff := time.Now().Add(-m.window)
+	out := make([]APIStats, 0, len(m.apis))
+	for api, l := range m.apis {
+		l.calls = pruneCalls(l.calls, cutoff)
+		s := APIStats{API: api, Requests: len(l.calls), LastError: l.lastError, LastErrorAt: l.lastErrorAt}
+		for _, c := range l.calls {
+			switch {
+			case isError(c.status):
+				s.Errors++
+				if c.status == http.StatusTooManyRequests {
+					s.RateLimited++
+				}
+			case c.status >= 400:
+				s.ClientErrors++
+			}
+		}
+		if s.Requests > 0 {
+			s.ErrorRate = float64(s.Errors) / float64(s.Requests)
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].API < out[j].API })
+	return out
+}
+
+// Check reports the worst API's error rate over the window.
+func (m *APIMonitor) Check() Check {
+	stats := m.Stats()
+	c := Check{Status: Green, Details: map[string]interface{}{"window": m.window.String(), "apis": stats}}
+	total, errors := 0, 0
+	for _, s := range stats {
+		total += s.Requests
+		errors += s.Errors
+		if s.Requests < minRequests {
+			continue
+		}
+		switch {
+		case s.ErrorRate >= redErrorRate:
+			c.Status = Red
+			c.Warnings = append(c.Warnings, fmt.Sprintf("%s: %.0f%% of requests failing (last: %s)", s.API, s.ErrorRate*100, s.LastError))
+		case s.ErrorRate >= yellowErrorRate:
+			c.Status = Worst(c.Status, Yellow)
+			c.Warnings = append(c.Warnings, fmt.Sprintf("%s: %.0f%% of requests failing (last: %s)", s.API, s.ErrorRate*100, s.LastError))
+		}
+		if s.RateLimited > 0 {
+			c.Status = Worst(c.Status, Yellow)
+			c.Warnings = append(c.Warnings, fmt.Sprintf("%s: rate limited %d times", s.API, s.RateLimited))
+		}
+	}
+	c.Message = fmt.Sprintf("%d requests, %d errors in the last %s", total, errors, m.window)
+	return c
+}
@@ -0,0 +1,188 @@
+package diagnostics
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+	"github.com/rileyseaburg/go-trader/dataquality"
+	"github.com/rileyseaburg/go-trader/scheduler"
+	"github.com/rileyseaburg/go-trader/ticker"
+)
+
+// Feed thresholds. A poll is overdue after this many poll intervals, and the
+// feed is red after this many consecutive polls in which every symbol failed.
+const (
+	overduePolls   = 3
+	failedPollsRed = 3
+)
+
+// minCacheLookups is how many lookups a cache needs before its hit rate is
+// judged.
+const minCacheLookups = 50
+
+// lowHitRate is the cache hit rate below which the cache check turns yellow.
+const lowHitRate = 0.2
+
+// SymbolFeed is one symbol's market data freshness.
+type SymbolFeed struct {
+	Symbol     string    `json:"symbol"`
+	LastUpdate time.Time `json:"last_update"`
+	Age        string    `json:"age"`                  // since the poller last refreshed it
+	TradeTime  time.Time `json:"trade_time,omitempty"` // the exchange timestamp of its last trade
+	TradeAge   string    `json:"trade_age,omitempty"`
+	Stale      bool      `json:"stale"`
+}
+
+// FeedCheck judges the market data poller and each symbol's last message.
+// Staleness is only a warning while the market is open; outside regular
+// hours quiet symbols are expected.
+func FeedCheck(health ticker.Health, last map[string]ticker.TickerData, marketOpen bool, now time.Time, staleAfter time.Duration) Check {
+	symbols := make([]SymbolFeed, 0, len(last))
+	for sym, d := range last {
+		f := SymbolFeed{Symbol: sym, LastUpdate: d.LastUpdated, Age: age(now, d.LastUpdated)}
+		if d.Trade != nil {
+			f.TradeTime = d.Trade.Timestamp
+			f.TradeAge = age(now, d.Trade.Timestamp)
+		}
+		f.Stale = now.Sub(d.LastUpdated) > staleAfter || (!f.TradeTime.IsZero() && now.Sub(f.TradeTime) > staleAfter)
+		symbols = append(symbols, f)
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i].Symbol < symbols[j].Symbol })
+
+	c := Check{Status: Green, Details: map[string]interface{}{
+		"health":      health,
+		"market_open": marketOpen,
+		"stale_after": staleAfter.String(),
+		"symbols":     symbols,
+	}}
+	if health.Mock {
+		c.Message = fmt.Sprintf("mock feed, %d symbols", len(symbols))
+		return c
+	}
+	if !health.Polling && health.LastPoll.IsZero() {
+		c.Status = Yellow
+		c.Message = "market data poller has not started"
+		return c
+	}
+
+	if health.ConsecutiveFailures >= failedPollsRed {
+		c.Status = Red
+		c.Warnings = append(c.Warnings, fmt.Sprintf("%d consecutive polls failed: %s", health.ConsecutiveFailures, health.LastError))
+	} else if health.ConsecutiveFailures > 0 {
+		c.Status = Yellow
+		c.Warnings = append(c.Warnings, fmt.Sprintf("last poll failed: %s", health.LastError))
+	}
+
+	if !marketOpen {
+		c.Message = fmt.Sprintf("market closed, %d symbols, last poll %s ago", len(symbols), age(now, health.LastPoll))
+		return c
+	}
+
+	if interval, err := time.ParseDuration(health.PollInterval); err == nil && health.Polling {
+		if now.Sub(health.LastPoll) > overduePolls*interval {
+			c.Status = Red
+			c.Warnings = append(c.Warnings, fmt.Sprintf("no successful poll for %s", age(now, health.LastPoll)))
+		}
+	}
+	stale := 0
+	for _, f := range symbols {
+		if f.Stale {
+			stale++
+			c.Warnings = append(c.Warnings, fmt.Sprintf("%s data is stale: last trade %s ago", f.Symbol, f.TradeAge))
+		}
+	}
+	if stale > 0 {
+		c.Status = Worst(c.Status, Yellow)
+	}
+	c.Message = fmt.Sprintf("%d symbols, %d stale, last poll %s ago", len(symbols), stale, age(now, health.LastPoll))
+	return c
+}
+
+// ClaudeCheck is red while the breaker is open and signals come from the
+// fallback chain, and yellow while it is probing.
+func ClaudeCheck(h algorithm.ClaudeHealth) Check {
+	c := Check{Status: Green, Details: h,
+		Message: fmt.Sprintf("breaker %s, %d calls, %d failures", h.State, h.Calls, h.Failures)}
+	switch h.State {
+	case algorithm.BreakerOpen:
+		c.Status = Red
+		c.Warnings = append(c.Warnings, "breaker open, signals come from fallbacks: "+h.LastError)
+	case algorithm.BreakerHalfOpen:
+		c.Status = Yellow
+		c.Warnings = append(c.Warnings, "breaker half open, probing the primary client")
+	default:
+		if h.ConsecutiveFailures > 0 {
+			c.Status = Yellow
+			c.Warnings = append(c.Warnings, fmt.Sprintf("%d consecutive failures: %s", h.ConsecutiveFailures, h.LastError))
+		}
+	}
+	return c
+}
+
+// CacheCheck reports cache hit rates. A cache with a low hit rate after
+// enough lookups is only a warning: it costs API calls, not correctness.
+func CacheCheck(stats map[string]algorithm.CacheStat) Check {
+	c := Check{Status: Green, Details: stats}
+	names := make([]string, 0, len(stats))
+	for name := range stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var hits, lookups int64
+	for _, name := range names {
+		s := stats[name]
+		hits += s.Hits
+		lookups += s.Hits + s.Misses
+		if s.Hits+s.Misses >= minCacheLookups && s.HitRate < lowHitRate {
+			c.Status = Yellow
+			c.Warnings = append(c.Warnings, fmt.Sprintf("%s cache hit rate %.0f%%", name, s.HitRate*100))
+		}
+	}
+	if lookups == 0 {
+		c.Message = "no cache lookups yet"
+		return c
+	}
+	c.Message = fmt.Sprintf("%.0f%% hit rate over %d lookups", float64(hits)/float64(lookups)*100, lookups)
+	return c
+}
+
+// SchedulerCheck is yellow while any job's last run failed.
+func SchedulerCheck(jobs []scheduler.JobStatus) Check {
+	c := Check{Status: Green, Details: jobs}
+	running := 0
+	for _, j := range jobs {
+		if j.Running {
+			running++
+		}
+		if j.LastError != "" {
+			c.Status = Yellow
+			c.Warnings = append(c.Warnings, fmt.Sprintf("%s failed: %s", j.Name, j.LastError))
+		}
+	}
+	c.Message = fmt.Sprintf("%d jobs, %d running", len(jobs), running)
+	return c
+}
+
+// DataQualityCheck is yellow while any symbol's market data is degraded.
+func DataQualityCheck(symbols []dataquality.Metrics) Check {
+	c := Check{Status: Green, Details: symbols}
+	quarantined := 0
+	for _, s := range symbols {
+		quarantined += s.Quarantined
+		if s.Degraded {
+			c.Status = Yellow
+			c.Warnings = append(c.Warnings, fmt.Sprintf("%s data degraded: %.1f%% of recent points bad", s.Symbol, s.BadPercent))
+		}
+	}
+	c.Message = fmt.Sprintf("%d symbols, %d points quarantined", len(symbols), quarantined)
+	return c
+}
+
+func age(now, t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return now.Sub(t).Round(time.Second).String()
+}
@@ -0,0 +1,188 @@
+// Package diagnostics gathers the health of every subsystem — the market
+// data feed and its quality, the Claude breaker, Alpaca API error rates,
+// caches, the job scheduler and the Go runtime — into one report with an overall
+// traffic-light status.
+package diagnostics
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync"
+	"time"
+)
+
+func logger() *slog.Logger { return slog.With("module", "diagnostics") }
+
+// Traffic-light statuses, from best to worst.
+const (
+	Green  = "green"
+	Yellow = "yellow"
+	Red    = "red"
+)
+
+// severity orders statuses so the worst can be picked.
+var severity = map[string]int{Green: 0, Yellow: 1, Red: 2}
+
+// Worst returns the most severe of statuses, green for none.
+func Worst(statuses ...string) string {
+	worst := Green
+	for _, s := range statuses {
+		if severity[s] > severity[worst] {
+			worst = s
+		}
+	}
+	return worst
+}
+
+// Check is one subsystem's health.
+type Check struct {
+	Name     string      `json:"name"`
+	Status   string      `json:"status"`
+	Message  string      `json:"message"`
+	Warnings []string    `json:"warnings,omitempty"`
+	Details  interface{} `json:"details,omitempty"`
+}
+
+// CheckFunc produces a subsystem's current health.
+type CheckFunc func() Check
+
+// Report is the health of every registered subsystem.
+type Report struct {
+	Status      string    `json:"status"` // the worst check's status
+	Checks      []Check   `json:"checks"`
+	Runtime     Runtime   `json:"runtime"`
+	StartedAt   time.Time `json:"started_at"`
+	Uptime      string    `json:"uptime"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+type namedCheck struct {
+	name string
+	fn   CheckFunc
+}
+
+// Monitor runs registered checks on demand.
+type Monitor struct {
+	started time.Time
+
+	mu     sync.Mutex
+	checks []namedCheck
+}
+
+// New returns a monitor with no checks, counting uptime from now.
+func New() *Monitor {
+	return &Monitor{started: time.Now()}
+}
+
+// Register adds a check, run in registration order. A check registered
+// again under the same name replaces the earlier one.
+func (m *Monitor) Register(name string, fn CheckFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, c := range m.checks {
+		if c.name == name {
+			m.checks[i].fn = fn
+			return
+		}
+	}
+	m.checks = append(m.checks, namedCheck{name: name, fn: fn})
+}
+
+// Report runs every check. A check that panics is reported red rather
+// than taking the endpoint down.
+func (m *Monitor) Report() Report {
+	m.mu.Lock()
+	checks := append([]namedCheck(nil), m.checks...)
+	m.mu.Unlock()
+
+	now := time.Now()
+	rt := ReadRuntime()
+	r := Report{
+		Runtime:     rt,
+		StartedAt:   m.started,
+		Uptime:      now.Sub(m.started).Round(time.Second).String(),
+		GeneratedAt: now,
+	}
+	for _, c := range checks {
+		r.Checks = append(r.Checks, run(c))
+	}
+	r.Checks = append(r.Checks, RuntimeCheck(rt))
+
+	statuses := make([]string, len(r.Checks))
+	for i, c := range r.Checks {
+		statuses[i] = c.Status
+	}
+	r.Status = Worst(statuses...)
+	return r
+}
+
+func run(c namedCheck) (check Check) {
+	defer func() {
+		if p := recover(); p != nil {
+			logger().Error("Diagnostics check panicked", "check", c.name, "panic", p)
+			check = Check{Name: c.name, Status: Red, Message: fmt.Sprintf("check failed: %v", p)}
+		}
+	}()
+	check = c.fn()
+	check.Name = c.name
+	if check.Status == "" {
+		check.Status = Green
+	}
+	return check
+}
+
+// Runtime thresholds past which the runtime check turns yellow.
+const (
+	maxGoroutines = 5000
+	maxHeapMB     = 1024
+)
+
+// Runtime is a snapshot of Go runtime statistics.
+type Runtime struct {
+	GoVersion   string  `json:"go_version"`
+	Goroutines  int     `json:"goroutines"`
+	HeapAllocMB float64 `json:"heap_alloc_mb"`
+	HeapSysMB   float64 `json:"heap_sys_mb"`
+	SysMB       float64 `json:"sys_mb"`
+	NumGC       uint32  `json:"num_gc"`
+	LastPauseMs float64 `json:"last_gc_pause_ms"`
+}
+
+// ReadRuntime samples the runtime.
+func ReadRuntime() Runtime {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	const mb = 1 << 20
+	rt := Runtime{
+		GoVersion:   runtime.Version(),
+		Goroutines:  runtime.NumGoroutine(),
+		HeapAllocMB: round1(float64(ms.HeapAlloc) / mb),
+		HeapSysMB:   round1(float64(ms.HeapSys) / mb),
+		SysMB:       round1(float64(ms.Sys) / mb),
+		NumGC:       ms.NumGC,
+	}
+	if ms.NumGC > 0 {
+		rt.LastPauseMs = float64(ms.PauseNs[(ms.NumGC+255)%256]) / 1e6
+	}
+	return rt
+}
+
+// RuntimeCheck flags goroutine leaks and runaway heap growth.
+func RuntimeCheck(rt Runtime) Check {
+	c := Check{Name: "runtime", Status: Green, Details: rt,
+		Message: fmt.Sprintf("%d goroutines, %.1f MB heap", rt.Goroutines, rt.HeapAllocMB)}
+	if rt.Goroutines > maxGoroutines {
+		c.Status = Yellow
+		c.Warnings = append(c.Warnings, fmt.Sprintf("%d goroutines exceeds %d; possible leak", rt.Goroutines, maxGoroutines))
+	}
+	if rt.HeapAllocMB > maxHeapMB {
+		c.Status = Yellow
+		c.Warnings = append(c.Warnings, fmt.Sprintf("heap of %.0f MB exceeds %d MB", rt.HeapAllocMB, maxHeapMB))
+	}
+	return c
+}
+
+func round1(v float64) float64 {
+	return float64(int64(v*10+0.5)) / 10
+}
@@ -0,0 +1,130 @@
+package diagnostics
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/rileyseaburg/go-trader/algorithm"
+	"github.com/rileyseaburg/go-trader/dataquality"
+	"github.com/rileyseaburg/go-trader/scheduler"
+	"github.com/rileyseaburg/go-trader/ticker"
+)
+
+func TestReportTakesWorstStatusAndRecoversPanics(t *testing.T) {
+	m := New()
+	m.Register("ok", func() Check { return Check{Message: "fine"} })
+	m.Register("warn", func() Check { return Check{Status: Yellow} })
+	r := m.Report()
+	if r.Status != Yellow {
+		t.Fatalf("status = %s, want yellow", r.Status)
+	}
+	if len(r.Checks) != 3 || r.Checks[0].Name != "ok" || r.Checks[0].Status != Green || r.Checks[2].Name != "runtime" {
+		t.Fatalf("checks = %+v", r.Checks)
+	}
+
+	m.Register("broken", func() Check { panic("boom") })
+	r = m.Report()
+	if r.Status != Red || r.Checks[2].Name != "broken" || r.Checks[2].Status != Red {
+		t.Fatalf("panicking check not reported red: %+v", r)
+	}
+}
+
+type stubTransport struct {
+	status int
+	err    error
+}
+
+func (s stubTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &http.Response{StatusCode: s.status, Status: http.StatusText(s.status), Body: http.NoBody}, nil
+}
+
+func TestAPIMonitorCountsErrorsAndRateLimits(t *testing.T) {
+	m := NewAPIMonitor(time.Minute)
+	send := func(rt stubTransport, n int) {
+		client := &http.Client{Transport: m.Transport("trading", rt)}
+		for i := 0; i < n; i++ {
+			resp, err := client.Get("http://alpaca.test/v2/orders")
+			if err == nil {
+				resp.Body.Close()
+			}
+		}
+	}
+	send(stubTransport{status: http.StatusOK}, 6)
+	send(stubTransport{status: http.StatusForbidden}, 2)
+	send(stubTransport{status: http.StatusTooManyRequests}, 1)
+	send(stubTransport{err: errors.New("connection reset")}, 1)
+
+	stats := m.Stats()
+	if len(stats) != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	s := stats[0]
+	if s.Requests != 10 || s.Errors != 2 || s.RateLimited != 1 || s.ClientErrors != 2 {
+		t.Fatalf("stats = %+v", s)
+	}
+	if s.ErrorRate != 0.2 {
+		t.Fatalf("error rate = %v, want 0.2", s.ErrorRate)
+	}
+	if c := m.Check(); c.Status != Yellow {
+		t.Fatalf("check = %+v, want yellow", c)
+	}
+
+	send(stubTransport{status: http.StatusBadGateway}, 5)
+	if c := m.Check(); c.Status != Red {
+		t.Fatalf("check = %+v, want red", c)
+	}
+}
+
+func TestFeedCheckFlagsStaleSymbolsOnlyWhileOpen(t *testing.T) {
+	now := time.Date(2025, 3, 3, 15, 0, 0, 0, time.UTC)
+	health := ticker.Health{Polling: true, LastPoll: now.Add(-2 * time.Second), PollInterval: "5s"}
+	last := map[string]ticker.TickerData{
+		"AAPL": {Symbol: "AAPL", LastUpdated: now.Add(-2 * time.Second), Trade: &marketdata.Trade{Timestamp: now.Add(-time.Second)}},
+		"TSLA": {Symbol: "TSLA", LastUpdated: now.Add(-2 * time.Second), Trade: &marketdata.Trade{Timestamp: now.Add(-10 * time.Minute)}},
+	}
+
+	c := FeedCheck(health, last, true, now, 2*time.Minute)
+	if c.Status != Yellow || len(c.Warnings) != 1 {
+		t.Fatalf("open market check = %+v", c)
+	}
+	if c = FeedCheck(health, last, false, now, 2*time.Minute); c.Status != Green {
+		t.Fatalf("closed market check = %+v", c)
+	}
+
+	health.LastPoll = now.Add(-time.Minute)
+	if c = FeedCheck(health, last, true, now, 2*time.Minute); c.Status != Red {
+		t.Fatalf("overdue poll check = %+v", c)
+	}
+	health.LastPoll = now
+	health.ConsecutiveFailures = 3
+	if c = FeedCheck(health, last, false, now, 2*time.Minute); c.Status != Red {
+		t.Fatalf("failing poller check = %+v", c)
+	}
+}
+
+func TestSubsystemChecks(t *testing.T) {
+	if c := ClaudeCheck(algorithm.ClaudeHealth{State: algorithm.BreakerOpen}); c.Status != Red {
+		t.Fatalf("open breaker = %+v", c)
+	}
+	if c := ClaudeCheck(algorithm.ClaudeHealth{State: algorithm.BreakerClosed}); c.Status != Green {
+		t.Fatalf("closed breaker = %+v", c)
+	}
+	if c := CacheCheck(map[string]algorithm.CacheStat{"bars": {Hits: 5, Misses: 95, HitRate: 0.05}}); c.Status != Yellow {
+		t.Fatalf("cold cache = %+v", c)
+	}
+	if c := CacheCheck(map[string]algorithm.CacheStat{"bars": {Hits: 1, Misses: 9, HitRate: 0.1}}); c.Status != Green {
+		t.Fatalf("cache with few lookups = %+v", c)
+	}
+	if c := SchedulerCheck([]scheduler.JobStatus{{Name: "eod"}, {Name: "sync", LastError: "timeout"}}); c.Status != Yellow {
+		t.Fatalf("failed job = %+v", c)
+	}
+	if c := DataQualityCheck([]dataquality.Metrics{{Symbol: "AAPL", Quarantined: 2}, {Symbol: "MSFT", Quarantined: 9, Degraded: true}}); c.Status != Yellow || c.Message != "2 symbols, 11 points quarantined" {
+		t.Fatalf("degraded feed = %+v", c)
+	}
+}
@@ -0,0 +1,30 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Handler exposes the monitor's report over HTTP.
+type Handler struct {
+	monitor *Monitor
+}
+
+// NewHandler creates a handler for monitor.
+func NewHandler(monitor *Monitor) *Handler {
+	return &Handler{monitor: monitor}
+}
+
+// RegisterRoutes registers the diagnostics route with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/diagnostics - subsystem checks and the overall traffic light
+	mux.HandleFunc("/api/diagnostics", h.handleReport)
+}
+
+func (h *Handler) handleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(h.monitor.Report())
+}
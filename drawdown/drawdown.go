@@ -0,0 +1,705 @@
+// Package drawdown de-risks the account as equity falls from its high.
+// Each policy tier names a drawdown, measured from the day's high or the
+// trailing high over recent days, and what happens once it is reached:
+// position sizes shrink, new entries are blocked and, at the last tier,
+// every position is flattened. Every tier change is journaled and
+// notified, and an operator can override the tier for a while or re-base
+// the highs once losses are accepted.
+package drawdown
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/gaprisk"
+)
+
+func logger() *slog.Logger { return slog.With("module", "drawdown") }
+
+// Drawdown bases.
+const (
+	BasisDaily    = "daily"    // from today's high
+	BasisTrailing = "trailing" // from the highest daily high in the window
+)
+
+// Journal entry actions.
+const (
+	ActionEscalate      = "escalate"
+	ActionRecover       = "recover"
+	ActionFlatten       = "flatten"
+	ActionOverride      = "override"
+	ActionClearOverride = "clear_override"
+	ActionRebase        = "rebase"
+)
+
+// maxDays bounds the daily highs kept; the trailing window cannot exceed it.
+const maxDays = 260
+
+// maxRecent bounds the journal entries kept in memory; the file keeps
+// everything.
+const maxRecent = 500
+
+// Tier is one step of de-risking. Tiers are numbered from 1 in policy
+// order; tier 0 is normal trading.
+type Tier struct {
+	DrawdownPercent float64 `json:"drawdown_percent"` // reached at this drawdown from the high
+	PositionScale   float64 `json:"position_scale"`   // multiplier on max_position_size_percent, (0, 1]
+	BlockEntries    bool    `json:"block_entries"`    // refuse trades that open new positions
+	Flatten         bool    `json:"flatten"`          // close every position on reaching the tier
+}
+
+// Policy configures the tiers.
+type Policy struct {
+	Enabled           bool    `json:"enabled"`
+	Basis             string  `json:"basis"`               // daily or trailing
+	TrailingDays      int     `json:"trailing_days"`       // days in the trailing high, today included
+	RecoveryBufferPct float64 `json:"recovery_buffer_pct"` // drawdown must fall this far below a tier to leave it
+	Tiers             []Tier  `json:"tiers"`               // ascending drawdown
+}
+
+// DefaultPolicy halves position sizes at a 3% drawdown from the 20-day
+// high, blocks new entries at 5% and flattens at 8%.
+func DefaultPolicy() Policy {
+	return Policy{
+		Enabled:           true,
+		Basis:             BasisTrailing,
+		TrailingDays:      20,
+		RecoveryBufferPct: 0.5,
+		Tiers: []Tier{
+			{DrawdownPercent: 3, PositionScale: 0.5},
+			{DrawdownPercent: 5, PositionScale: 0.5, BlockEntries: true},
+			{DrawdownPercent: 8, PositionScale: 0.5, BlockEntries: true, Flatten: true},
+		},
+	}
+}
+
+// Validate checks the policy for internally consistent values.
+func (p Policy) Validate() error {
+	if p.Basis != BasisDaily && p.Basis != BasisTrailing {
+		return fmt.Errorf("basis must be %s or %s", BasisDaily, BasisTrailing)
+	}
+	if p.TrailingDays < 1 || p.TrailingDays > maxDays {
+		return fmt.Errorf("trailing_days must be between 1 and %d", maxDays)
+	}
+	if p.RecoveryBufferPct < 0 {
+		return errors.New("recovery_buffer_pct must not be negative")
+	}
+	prev := 0.0
+	for i, t := range p.Tiers {
+		if t.DrawdownPercent <= prev || t.DrawdownPercent >= 100 {
+			return fmt.Errorf("tier %d: drawdown_percent must be above the previous tier's and below 100", i+1)
+		}
+		if t.PositionScale <= 0 || t.PositionScale > 1 {
+			return fmt.Errorf("tier %d: position_scale must be in (0, 1]", i+1)
+		}
+		if t.Flatten && !t.BlockEntries {
+			return fmt.Errorf("tier %d: a flatten tier must also block entries", i+1)
+		}
+		prev = t.DrawdownPercent
+	}
+	return nil
+}
+
+// tier returns tier n (1-based), or the normal-trading zero tier.
+func (p Policy) tier(n int) Tier {
+	if n < 1 || n > len(p.Tiers) {
+		return Tier{PositionScale: 1}
+	}
+	return p.Tiers[n-1]
+}
+
+// TierFor returns the tier the policy calls for at drawdownPct given the
+// tier currently in force. Escalation happens as soon as a threshold is
+// reached; leaving a tier needs the drawdown to fall RecoveryBufferPct
+// below its threshold, so equity hovering at a threshold does not flap.
+func (p Policy) TierFor(drawdownPct float64, current int) int {
+	target := 0
+	for i, t := range p.Tiers {
+		if drawdownPct >= t.DrawdownPercent {
+			target = i + 1
+		}
+	}
+	if target < current && current <= len(p.Tiers) && drawdownPct > p.Tiers[current-1].DrawdownPercent-p.RecoveryBufferPct {
+		return current
+	}
+	return target
+}
+
+// DayHigh is the highest equity seen on a day.
+type DayHigh struct {
+	Date string  `json:"date"` // YYYY-MM-DD in the manager's location
+	High float64 `json:"high"`
+}
+
+// Override pins the tier regardless of drawdown.
+type Override struct {
+	Tier   int        `json:"tier"` // 0 resumes normal trading
+	Reason string     `json:"reason"`
+	SetAt  time.Time  `json:"set_at"`
+	Until  *time.Time `json:"until,omitempty"` // nil until cleared
+}
+
+// active reports whether the override is in force at now.
+func (o *Override) active(now time.Time) bool {
+	return o != nil && (o.Until == nil || now.Before(*o.Until))
+}
+
+// Entry is one journaled action.
+type Entry struct {
+	Time            time.Time           `json:"time"`
+	Action          string              `json:"action"`
+	FromTier        int                 `json:"from_tier"`
+	ToTier          int                 `json:"to_tier"`
+	DrawdownPercent float64             `json:"drawdown_percent"`
+	Equity          float64             `json:"equity,omitempty"`
+	High            float64             `json:"high,omitempty"`
+	Reason          string              `json:"reason,omitempty"`
+	Orders          []gaprisk.Reduction `json:"orders,omitempty"` // flatten orders submitted
+	Error           string              `json:"error,omitempty"`
+}
+
+// Status is the current drawdown and what is in force.
+type Status struct {
+	Policy                  Policy    `json:"policy"`
+	Equity                  float64   `json:"equity"`
+	DailyHigh               float64   `json:"daily_high"`
+	TrailingHigh            float64   `json:"trailing_high"`
+	DailyDrawdownPercent    float64   `json:"daily_drawdown_percent"`
+	TrailingDrawdownPercent float64   `json:"trailing_drawdown_percent"`
+	DrawdownPercent         float64   `json:"drawdown_percent"` // on the policy's basis
+	PolicyTier              int       `json:"policy_tier"`      // what the drawdown calls for
+	Tier                    int       `json:"tier"`             // in force, after any override
+	PositionScale           float64   `json:"position_scale"`
+	BlockEntries            bool      `json:"block_entries"`
+	Override                *Override `json:"override,omitempty"`
+	UpdatedAt               time.Time `json:"updated_at"`
+}
+
+// state is what survives a restart.
+type state struct {
+	Highs      []DayHigh `json:"highs"` // oldest first
+	PolicyTier int       `json:"policy_tier"`
+	Tier       int       `json:"tier"`
+	Override   *Override `json:"override,omitempty"`
+}
+
+// Notifier is told about tier changes, flattens and overrides.
+type Notifier func(title, message string, metadata map[string]interface{})
+
+// Manager tracks the highs and applies the tiers. It is safe for
+// concurrent use.
+type Manager struct {
+	loc       *time.Location
+	statePath string
+	// act serializes tier changes so a flatten runs once
+	act sync.Mutex
+
+	mu      sync.Mutex
+	policy  Policy
+	state   state
+	equity  func() float64
+	broker  gaprisk.Broker
+	scaler  func(reason string, scale float64)
+	notify  Notifier
+	journal *os.File
+	recent  []Entry
+	last    Status
+	dirty   bool
+}
+
+// New opens the journal and state in dir and returns a manager with the
+// given policy. Days roll over at midnight in loc.
+func New(dir string, loc *time.Location, policy Policy) (*Manager, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	if loc == nil {
+		loc = time.Local
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create drawdown directory: %w", err)
+	}
+	m := &Manager{
+		loc:       loc,
+		statePath: filepath.Join(dir, "state.json"),
+		policy:    policy,
+	}
+
+	if data, err := os.ReadFile(m.statePath); err == nil {
+		if err := json.Unmarshal(data, &m.state); err != nil {
+			return nil, fmt.Errorf("failed to decode drawdown state: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read drawdown state: %w", err)
+	}
+
+	path := filepath.Join(dir, "journal.jsonl")
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			var e Entry
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				logger().Warn("Skipping malformed drawdown journal line", "error", err)
+				continue
+			}
+			m.remember(e)
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read drawdown journal: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to open drawdown journal: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open drawdown journal for writing: %w", err)
+	}
+	m.journal = f
+	m.last = m.statusLocked(0, time.Time{})
+	return m, nil
+}
+
+// SetEquity wires the account equity source read on every tick.
+func (m *Manager) SetEquity(fn func() float64) { m.mu.Lock(); m.equity = fn; m.mu.Unlock() }
+
+// SetBroker wires the broker used to flatten positions.
+func (m *Manager) SetBroker(b gaprisk.Broker) { m.mu.Lock(); m.broker = b; m.mu.Unlock() }
+
+// SetNotifier registers a callback for tier changes.
+func (m *Manager) SetNotifier(n Notifier) { m.mu.Lock(); m.notify = n; m.mu.Unlock() }
+
+// SetScaler registers the callback that applies a tier's position scale
+// and calls it at once with the tier in force, so a restart restores it.
+func (m *Manager) SetScaler(fn func(reason string, scale float64)) {
+	m.mu.Lock()
+	m.scaler = fn
+	tier := m.state.Tier
+	scale := m.policy.tier(tier).PositionScale
+	m.mu.Unlock()
+	if fn != nil {
+		fn(scaleReason(tier), scale)
+	}
+}
+
+// Policy returns the current policy.
+func (m *Manager) Policy() Policy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.policy
+}
+
+// SetPolicy validates and replaces the policy. The new tiers apply on the
+// next tick.
+func (m *Manager) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policy = p
+	return nil
+}
+
+// CheckEntry returns an error if the tier in force blocks new entries. It
+// is meant for a trade guard that first checks the trade opens a position.
+func (m *Manager) CheckEntry() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.policy.tier(m.state.Tier).BlockEntries {
+		return nil
+	}
+	return fmt.Errorf("new entries blocked at drawdown tier %d (%.2f%% from the high); override via /api/risk/drawdown/override",
+		m.state.Tier, m.last.DrawdownPercent)
+}
+
+// Status returns the drawdown as of the last tick.
+func (m *Manager) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.last
+	s.Policy = m.policy
+	s.Override = m.state.Override
+	return s
+}
+
+// Journal returns recent entries, newest first.
+func (m *Manager) Journal(limit int) []Entry {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := []Entry{}
+	for i := len(m.recent) - 1; i >= 0 && (limit <= 0 || len(out) < limit); i-- {
+		out = append(out, m.recent[i])
+	}
+	return out
+}
+
+// Tick reads equity, updates the highs and moves to the tier the drawdown
+// or override calls for. Run calls it every minute; tests and manual
+// triggers can call it directly.
+func (m *Manager) Tick(ctx context.Context, now time.Time) {
+	m.mu.Lock()
+	equityFn := m.equity
+	m.mu.Unlock()
+	if equityFn == nil {
+		return
+	}
+	equity := equityFn()
+	if equity <= 0 || math.IsNaN(equity) || math.IsInf(equity, 0) {
+		return
+	}
+
+	m.mu.Lock()
+	m.markLocked(equity, now)
+	s := m.statusLocked(equity, now)
+	m.last = s
+	from := m.state.Tier
+	if m.state.Override != nil && !m.state.Override.active(now) {
+		m.writeLocked(Entry{Time: now, Action: ActionClearOverride, FromTier: from, ToTier: s.Tier,
+			DrawdownPercent: s.DrawdownPercent, Equity: equity, Reason: "override expired"})
+		m.state.Override = nil
+		m.dirty = true
+	}
+	m.state.PolicyTier = s.PolicyTier
+	m.mu.Unlock()
+
+	m.moveTo(ctx, s, now, "")
+
+	m.persist()
+}
+
+// markLocked records equity in today's high.
+func (m *Manager) markLocked(equity float64, now time.Time) {
+	day := now.In(m.loc).Format("2006-01-02")
+	n := len(m.state.Highs)
+	if n == 0 || m.state.Highs[n-1].Date != day {
+		m.state.Highs = append(m.state.Highs, DayHigh{Date: day, High: equity})
+		if len(m.state.Highs) > maxDays {
+			m.state.Highs = m.state.Highs[len(m.state.Highs)-maxDays:]
+		}
+		m.dirty = true
+		return
+	}
+	if equity > m.state.Highs[n-1].High {
+		m.state.Highs[n-1].High = equity
+		m.dirty = true
+	}
+}
+
+// statusLocked measures equity against the highs and works out the tier.
+func (m *Manager) statusLocked(equity float64, now time.Time) Status {
+	s := Status{Policy: m.policy, Equity: round2(equity), UpdatedAt: now, PolicyTier: m.state.PolicyTier, Tier: m.state.Tier}
+	if n := len(m.state.Highs); n > 0 && equity > 0 {
+		daily := m.state.Highs[n-1].High
+		trailing := 0.0
+		for i := n - 1; i >= 0 && i >= n-m.policy.TrailingDays; i-- {
+			trailing = math.Max(trailing, m.state.Highs[i].High)
+		}
+		s.DailyHigh, s.TrailingHigh = round2(daily), round2(trailing)
+		s.DailyDrawdownPercent = percentBelow(equity, daily)
+		s.TrailingDrawdownPercent = percentBelow(equity, trailing)
+		s.DrawdownPercent = s.TrailingDrawdownPercent
+		if m.policy.Basis == BasisDaily {
+			s.DrawdownPercent = s.DailyDrawdownPercent
+		}
+		s.PolicyTier = 0
+		if m.policy.Enabled {
+			s.PolicyTier = m.policy.TierFor(s.DrawdownPercent, m.state.PolicyTier)
+		}
+		s.Tier = s.PolicyTier
+	}
+	if o := m.state.Override; o.active(now) {
+		s.Tier = o.Tier
+	}
+	t := m.policy.tier(s.Tier)
+	s.PositionScale, s.BlockEntries = t.PositionScale, t.BlockEntries
+	return s
+}
+
+// moveTo puts s.Tier in force if it is not already: the position scale is
+// applied, the change journaled and notified and, on reaching a flatten
+// tier from below, every position closed.
+func (m *Manager) moveTo(ctx context.Context, s Status, now time.Time, reason string) {
+	m.act.Lock()
+	defer m.act.Unlock()
+	m.mu.Lock()
+	from := m.state.Tier
+	if s.Tier == from {
+		m.mu.Unlock()
+		return
+	}
+	m.state.Tier = s.Tier
+	m.dirty = true
+	policy, scaler, notify, broker := m.policy, m.scaler, m.notify, m.broker
+	m.mu.Unlock()
+
+	to := policy.tier(s.Tier)
+	if scaler != nil {
+		scaler(scaleReason(s.Tier), to.PositionScale)
+	}
+
+	action := ActionEscalate
+	if s.Tier < from {
+		action = ActionRecover
+	}
+	if reason == "" {
+		reason = fmt.Sprintf("%.2f%% drawdown from the %s high", s.DrawdownPercent, policy.Basis)
+	}
+	high := s.TrailingHigh
+	if policy.Basis == BasisDaily {
+		high = s.DailyHigh
+	}
+	e := Entry{Time: now, Action: action, FromTier: from, ToTier: s.Tier, DrawdownPercent: s.DrawdownPercent,
+		Equity: s.Equity, High: high, Reason: reason}
+	m.mu.Lock()
+	m.writeLocked(e)
+	m.mu.Unlock()
+
+	logger().Warn("Drawdown tier changed", "from", from, "to", s.Tier, "drawdown_percent", s.DrawdownPercent,
+		"position_scale", to.PositionScale, "block_entries", to.BlockEntries, "reason", reason)
+	if notify != nil {
+		notify(fmt.Sprintf("Drawdown tier %d → %d", from, s.Tier),
+			fmt.Sprintf("%s. Position size scale %.2f; new entries %s.", capitalize(reason), to.PositionScale, blockedWord(to.BlockEntries)),
+			map[string]interface{}{"from_tier": from, "to_tier": s.Tier, "drawdown_percent": s.DrawdownPercent,
+				"position_scale": to.PositionScale, "block_entries": to.BlockEntries})
+	}
+
+	if to.Flatten && !policy.tier(from).Flatten && s.Tier > from {
+		m.flatten(ctx, s, now, broker, notify)
+	}
+}
+
+// flatten closes every position and journals the orders.
+func (m *Manager) flatten(ctx context.Context, s Status, now time.Time, broker gaprisk.Broker, notify Notifier) {
+	e := Entry{Time: now, Action: ActionFlatten, FromTier: s.Tier, ToTier: s.Tier,
+		DrawdownPercent: s.DrawdownPercent, Equity: s.Equity, Reason: fmt.Sprintf("drawdown tier %d", s.Tier)}
+	var failed []string
+	if broker == nil {
+		failed = append(failed, "no broker configured")
+	} else if positions, err := broker.Positions(ctx); err != nil {
+		failed = append(failed, "failed to list positions: "+err.Error())
+	} else {
+		plan := gaprisk.PlanReductions(gaprisk.Policy{Mode: gaprisk.ModeFlatten}, positions, e.Reason)
+		for _, r := range plan {
+			if err := broker.Reduce(ctx, r); err != nil {
+				failed = append(failed, r.Symbol+": "+err.Error())
+				continue
+			}
+			e.Orders = append(e.Orders, r)
+		}
+	}
+	if len(failed) > 0 {
+		e.Error = strings.Join(failed, "; ")
+		logger().Error("Drawdown flatten incomplete", "orders", len(e.Orders), "error", e.Error)
+	} else {
+		logger().Warn("Drawdown flatten submitted", "orders", len(e.Orders))
+	}
+
+	m.mu.Lock()
+	m.writeLocked(e)
+	m.mu.Unlock()
+
+	if notify != nil {
+		msg := fmt.Sprintf("Submitted %d order(s) to close every position at a %.2f%% drawdown.", len(e.Orders), s.DrawdownPercent)
+		if e.Error != "" {
+			msg += " Failures: " + e.Error
+		}
+		notify("Drawdown flatten", msg, map[string]interface{}{"tier": s.Tier, "orders": len(e.Orders), "error": e.Error})
+	}
+}
+
+// SetOverride pins the tier for d (until cleared when d is zero) and puts
+// it in force at once. Overriding to a flatten tier flattens.
+func (m *Manager) SetOverride(ctx context.Context, tier int, d time.Duration, reason string, now time.Time) (Status, error) {
+	m.mu.Lock()
+	if tier < 0 || tier > len(m.policy.Tiers) {
+		m.mu.Unlock()
+		return Status{}, fmt.Errorf("tier must be between 0 and %d", len(m.policy.Tiers))
+	}
+	if d < 0 {
+		m.mu.Unlock()
+		return Status{}, errors.New("duration must not be negative")
+	}
+	if strings.TrimSpace(reason) == "" {
+		m.mu.Unlock()
+		return Status{}, errors.New("reason is required")
+	}
+	o := &Override{Tier: tier, Reason: reason, SetAt: now}
+	if d > 0 {
+		until := now.Add(d)
+		o.Until = &until
+	}
+	m.state.Override = o
+	m.dirty = true
+	s := m.refreshLocked(now)
+	m.writeLocked(Entry{Time: now, Action: ActionOverride, FromTier: m.state.Tier, ToTier: tier,
+		DrawdownPercent: s.DrawdownPercent, Equity: s.Equity, Reason: reason})
+	notify := m.notify
+	m.mu.Unlock()
+
+	logger().Warn("Drawdown tier overridden", "tier", tier, "until", o.Until, "reason", reason)
+	if notify != nil {
+		notify(fmt.Sprintf("Drawdown tier overridden to %d", tier), reason, map[string]interface{}{"tier": tier})
+	}
+	m.moveTo(ctx, s, now, "override: "+reason)
+	m.persist()
+	return m.Status(), nil
+}
+
+// ClearOverride returns control to the policy.
+func (m *Manager) ClearOverride(ctx context.Context, reason string, now time.Time) (Status, error) {
+	m.mu.Lock()
+	if m.state.Override == nil {
+		m.mu.Unlock()
+		return Status{}, errors.New("no override is set")
+	}
+	m.state.Override = nil
+	m.dirty = true
+	s := m.refreshLocked(now)
+	m.writeLocked(Entry{Time: now, Action: ActionClearOverride, FromTier: m.state.Tier, ToTier: s.Tier,
+		DrawdownPercent: s.DrawdownPercent, Equity: s.Equity, Reason: reason})
+	m.mu.Unlock()
+
+	m.moveTo(ctx, s, now, "override cleared")
+	m.persist()
+	return m.Status(), nil
+}
+
+// Rebase resets the highs to the last equity, so the drawdown is measured
+// from here on. Use it once a loss has been accepted.
+func (m *Manager) Rebase(ctx context.Context, reason string, now time.Time) (Status, error) {
+	m.mu.Lock()
+	equity := m.last.Equity
+	if equity <= 0 {
+		m.mu.Unlock()
+		return Status{}, errors.New("no equity observed yet")
+	}
+	m.state.Highs = []DayHigh{{Date: now.In(m.loc).Format("2006-01-02"), High: equity}}
+	m.state.PolicyTier = 0
+	m.dirty = true
+	s := m.refreshLocked(now)
+	m.writeLocked(Entry{Time: now, Action: ActionRebase, FromTier: m.state.Tier, ToTier: s.Tier,
+		Equity: equity, High: equity, Reason: reason})
+	m.mu.Unlock()
+
+	m.moveTo(ctx, s, now, "highs re-based")
+	m.persist()
+	return m.Status(), nil
+}
+
+// refreshLocked recomputes the status from the last equity.
+func (m *Manager) refreshLocked(now time.Time) Status {
+	s := m.statusLocked(m.last.Equity, now)
+	m.state.PolicyTier = s.PolicyTier
+	m.last = s
+	return s
+}
+
+// persist saves the state if it changed.
+func (m *Manager) persist() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saveLocked()
+}
+
+func (m *Manager) saveLocked() {
+	if !m.dirty {
+		return
+	}
+	data, err := json.MarshalIndent(m.state, "", "  ")
+	if err != nil {
+		logger().Error("Failed to encode drawdown state", "error", err)
+		return
+	}
+	tmp := m.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		logger().Error("Failed to write drawdown state", "error", err)
+		return
+	}
+	if err := os.Rename(tmp, m.statePath); err != nil {
+		logger().Error("Failed to save drawdown state", "error", err)
+		return
+	}
+	m.dirty = false
+}
+
+func (m *Manager) writeLocked(e Entry) {
+	m.remember(e)
+	if line, err := json.Marshal(e); err != nil {
+		logger().Error("Failed to encode drawdown journal entry", "action", e.Action, "error", err)
+	} else if _, err := m.journal.Write(append(line, '\n')); err != nil {
+		logger().Error("Failed to write drawdown journal entry", "action", e.Action, "error", err)
+	}
+}
+
+func (m *Manager) remember(e Entry) {
+	m.recent = append(m.recent, e)
+	if len(m.recent) > maxRecent {
+		m.recent = m.recent[len(m.recent)-maxRecent:]
+	}
+}
+
+// Close saves the state and closes the journal.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.saveLocked()
+	return m.journal.Close()
+}
+
+// Run ticks every minute until ctx is done.
+func (m *Manager) Run(ctx context.Context) {
+	t := time.NewTicker(time.Minute)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			m.Tick(ctx, now)
+		}
+	}
+}
+
+func scaleReason(tier int) string {
+	if tier == 0 {
+		return ""
+	}
+	return fmt.Sprintf("drawdown tier %d", tier)
+}
+
+func percentBelow(v, high float64) float64 {
+	if high <= 0 || v >= high {
+		return 0
+	}
+	return round2((high - v) / high * 100)
+}
+
+func blockedWord(blocked bool) string {
+	if blocked {
+		return "blocked"
+	}
+	return "allowed"
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,172 @@
+package drawdown
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/gaprisk"
+	"github.com/rileyseaburg/go-trader/users"
+)
+
+type stubBroker struct {
+	positions []gaprisk.Position
+	reduced   []gaprisk.Reduction
+}
+
+func (b *stubBroker) Positions(context.Context) ([]gaprisk.Position, error) {
+	return b.positions, nil
+}
+
+func (b *stubBroker) Reduce(_ context.Context, r gaprisk.Reduction) error {
+	b.reduced = append(b.reduced, r)
+	return nil
+}
+
+func TestTierForEscalatesAtOnceAndRecoversPastTheBuffer(t *testing.T) {
+	p := DefaultPolicy()
+	for _, tc := range []struct {
+		dd            float64
+		current, want int
+	}{
+		{2.9, 0, 0},
+		{3, 0, 1},
+		{9, 0, 3},   // straight to the top tier
+		{2.8, 1, 1}, // inside the 0.5 buffer below 3%
+		{2.4, 1, 0},
+		{4.6, 2, 2},
+		{4.4, 2, 1},
+	} {
+		if got := p.TierFor(tc.dd, tc.current); got != tc.want {
+			t.Errorf("TierFor(%v, %d) = %d, want %d", tc.dd, tc.current, got, tc.want)
+		}
+	}
+
+	bad := DefaultPolicy()
+	bad.Tiers[2].BlockEntries = false
+	if bad.Validate() == nil {
+		t.Error("flatten tier that allows entries accepted")
+	}
+}
+
+func TestManagerDeRisksFlattensAndRestores(t *testing.T) {
+	dir := t.TempDir()
+	m, err := New(dir, time.UTC, DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	equity := 100000.0
+	m.SetEquity(func() float64 { return equity })
+	broker := &stubBroker{positions: []gaprisk.Position{{Symbol: "AAPL", Qty: 10}, {Symbol: "TSLA", Qty: -5}}}
+	m.SetBroker(broker)
+	var scale float64
+	m.SetScaler(func(_ string, s float64) { scale = s })
+	var notes []string
+	m.SetNotifier(func(title, _ string, _ map[string]interface{}) { notes = append(notes, title) })
+	ctx := context.Background()
+
+	day1 := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	m.Tick(ctx, day1)
+	if s := m.Status(); s.Tier != 0 || scale != 1 || m.CheckEntry() != nil {
+		t.Fatalf("status = %+v", s)
+	}
+
+	// Next day the trailing high carries over although the daily high resets
+	equity = 96000
+	day2 := day1.Add(24 * time.Hour)
+	m.Tick(ctx, day2)
+	s := m.Status()
+	if s.Tier != 1 || s.DailyDrawdownPercent != 0 || s.TrailingDrawdownPercent != 4 || scale != 0.5 || m.CheckEntry() != nil {
+		t.Fatalf("tier 1 status = %+v scale %v", s, scale)
+	}
+
+	equity = 91500
+	m.Tick(ctx, day2.Add(time.Minute))
+	if s := m.Status(); s.Tier != 3 || len(broker.reduced) != 2 || m.CheckEntry() == nil {
+		t.Fatalf("tier 3 status = %+v reduced %+v", s, broker.reduced)
+	}
+	if broker.reduced[1].Symbol != "TSLA" || broker.reduced[1].Side != "buy" {
+		t.Errorf("reductions = %+v", broker.reduced)
+	}
+
+	// Staying at the flatten tier does not flatten again
+	m.Tick(ctx, day2.Add(2*time.Minute))
+	if len(broker.reduced) != 2 {
+		t.Errorf("flattened twice: %+v", broker.reduced)
+	}
+
+	// An operator resumes trading for an hour
+	if _, err := m.SetOverride(ctx, 0, time.Hour, "", day2); err == nil {
+		t.Error("override without a reason accepted")
+	}
+	if s, err := m.SetOverride(ctx, 0, time.Hour, "reviewed, resuming", day2.Add(3*time.Minute)); err != nil || s.Tier != 0 || s.PolicyTier != 3 {
+		t.Fatalf("override = %+v, %v", s, err)
+	}
+	if m.CheckEntry() != nil || scale != 1 {
+		t.Error("override did not lift the block")
+	}
+	m.Close()
+
+	// A restart restores the tier and override; expiry hands control back
+	m, err = New(dir, time.UTC, DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	m.SetEquity(func() float64 { return equity })
+	m.SetBroker(broker)
+	m.SetScaler(func(_ string, s float64) { scale = s })
+	if s := m.Status(); s.Tier != 0 || s.Override == nil {
+		t.Fatalf("restored = %+v", s)
+	}
+	m.Tick(ctx, day2.Add(2*time.Hour))
+	if s := m.Status(); s.Tier != 3 || s.Override != nil || m.CheckEntry() == nil {
+		t.Fatalf("after expiry = %+v", s)
+	}
+	if len(broker.reduced) != 4 {
+		t.Errorf("re-entering the flatten tier should flatten: %+v", broker.reduced)
+	}
+
+	if s, err := m.Rebase(ctx, "loss accepted", day2.Add(2*time.Hour)); err != nil || s.Tier != 0 || s.TrailingHigh != 91500 {
+		t.Fatalf("rebase = %+v, %v", s, err)
+	}
+
+	j := m.Journal(0)
+	actions := make([]string, len(j))
+	for i, e := range j {
+		actions[len(j)-1-i] = e.Action
+	}
+	want := "escalate escalate flatten override recover clear_override escalate flatten rebase recover"
+	if got := strings.Join(actions, " "); got != want {
+		t.Errorf("journal = %s", got)
+	}
+	if len(notes) == 0 {
+		t.Error("no notifications")
+	}
+}
+
+func TestOnlyAdminsChangePolicyOrOverride(t *testing.T) {
+	m, err := New(t.TempDir(), time.UTC, DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	NewHandler(m).RegisterRoutes(mux)
+	trader := users.WithUser(context.Background(), users.User{ID: "trader", Role: users.RoleUser})
+
+	for _, path := range []string{"/api/risk/drawdown/policy", "/api/risk/drawdown/override"} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"rebase":true}`)).WithContext(trader))
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("user POST %s = %d", path, rec.Code)
+		}
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/risk/drawdown/policy", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusOK {
+		t.Errorf("admin POST policy = %d", rec.Code)
+	}
+}
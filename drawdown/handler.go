@@ -0,0 +1,139 @@
+package drawdown
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/paging"
+	"github.com/rileyseaburg/go-trader/users"
+)
+
+// Handler exposes the drawdown controls over HTTP.
+type Handler struct {
+	manager *Manager
+}
+
+// NewHandler creates a handler for manager.
+func NewHandler(manager *Manager) *Handler {
+	return &Handler{manager: manager}
+}
+
+// RegisterRoutes registers the drawdown routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/risk/drawdown - drawdown from the highs and the tier in force
+	mux.HandleFunc("/api/risk/drawdown", h.handleStatus)
+
+	// GET/POST /api/risk/drawdown/policy - read or update the tiers
+	mux.HandleFunc("/api/risk/drawdown/policy", h.handlePolicy)
+
+	// GET /api/risk/drawdown/journal?limit=&cursor=&sort=&filter= - tier changes, flattens and overrides, newest first
+	mux.HandleFunc("/api/risk/drawdown/journal", h.handleJournal)
+
+	// POST /api/risk/drawdown/override - pin the tier, clear the pin or re-base the highs
+	mux.HandleFunc("/api/risk/drawdown/override", h.handleOverride)
+}
+
+func (h *Handler) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(h.manager.Status())
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.manager.Policy())
+	case http.MethodPost, http.MethodPut:
+		if !users.FromContext(r.Context()).IsAdmin() {
+			http.Error(w, users.ErrForbidden.Error(), http.StatusForbidden)
+			return
+		}
+		// Start from the current policy so partial updates work
+		policy := h.manager.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.manager.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(policy)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// journalList pages the journal, newest first by default.
+var journalList = paging.List[Entry]{
+	Fields: []paging.Field[Entry]{
+		{Name: "time", Sort: func(e Entry) paging.Key { return paging.Time(e.Time) }},
+		{Name: "drawdown_percent", Sort: func(e Entry) paging.Key { return paging.Number(e.DrawdownPercent) }},
+		{Name: "action", Filter: func(e Entry) string { return e.Action }},
+		{Name: "to_tier", Filter: func(e Entry) string { return strconv.Itoa(e.ToTier) }},
+	},
+	ID:   func(e Entry) string { return e.Time.UTC().Format(time.RFC3339Nano) + "/" + e.Action },
+	Sort: "-time",
+}
+
+func (h *Handler) handleJournal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	journalList.Serve(w, r, h.manager.Journal(0))
+}
+
+// overrideRequest sets a tier for minutes (until cleared when zero), or
+// clears the override, or re-bases the highs to the current equity. A tier
+// override needs a reason.
+type overrideRequest struct {
+	Tier    *int   `json:"tier"`
+	Minutes int    `json:"minutes"`
+	Clear   bool   `json:"clear"`
+	Rebase  bool   `json:"rebase"`
+	Reason  string `json:"reason"`
+}
+
+func (h *Handler) handleOverride(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !users.FromContext(r.Context()).IsAdmin() {
+		http.Error(w, users.ErrForbidden.Error(), http.StatusForbidden)
+		return
+	}
+	var req overrideRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var status Status
+	var err error
+	now := time.Now()
+	switch {
+	case req.Rebase:
+		status, err = h.manager.Rebase(r.Context(), req.Reason, now)
+	case req.Clear:
+		status, err = h.manager.ClearOverride(r.Context(), req.Reason, now)
+	case req.Tier != nil:
+		if req.Minutes < 0 {
+			http.Error(w, "minutes must not be negative", http.StatusBadRequest)
+			return
+		}
+		status, err = h.manager.SetOverride(r.Context(), *req.Tier, time.Duration(req.Minutes)*time.Minute, req.Reason, now)
+	default:
+		http.Error(w, "Set tier, clear or rebase", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(status)
+}
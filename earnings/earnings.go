@@ -0,0 +1,611 @@
+// Package earnings keeps a calendar of upcoming earnings reports for held
+// and watched symbols, polled from a provider into a cache under the data
+// directory, and applies the pre-earnings risk rules: new entries can be
+// blocked and positions reduced or flattened before the close a set number
+// of sessions ahead of a report.
+package earnings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/calendar"
+	"github.com/rileyseaburg/go-trader/gaprisk"
+)
+
+func logger() *slog.Logger { return slog.With("module", "earnings") }
+
+// ErrNoProvider is returned by Refresh when no provider is configured.
+var ErrNoProvider = errors.New("no earnings provider configured")
+
+// Report times, as providers give them.
+const (
+	BeforeOpen  = "bmo"
+	AfterClose  = "amc"
+	DuringHours = "dmh"
+)
+
+// Event is one scheduled earnings report.
+type Event struct {
+	Symbol      string    `json:"symbol"`
+	Date        string    `json:"date"`           // YYYY-MM-DD, exchange local
+	Hour        string    `json:"hour,omitempty"` // bmo, amc, dmh or empty when unknown
+	EPSEstimate *float64  `json:"eps_estimate,omitempty"`
+	Source      string    `json:"source"`
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// Upcoming is an event with the sessions left to trade before it.
+type Upcoming struct {
+	Event
+	// SessionsBefore counts the closes after today before the report; 0
+	// means today's close is the last one ahead of it.
+	SessionsBefore int  `json:"sessions_before"`
+	Held           bool `json:"held"`
+}
+
+// Provider fetches scheduled reports.
+type Provider interface {
+	// Name identifies the provider in events and logs.
+	Name() string
+	// Earnings returns the reports of symbols dated from from to to,
+	// inclusive. Failures for some symbols may be returned alongside the
+	// events of the rest.
+	Earnings(ctx context.Context, symbols []string, from, to time.Time) ([]Event, error)
+}
+
+// Policy configures polling and the risk rules. Reductions reuse the gap
+// controls' modes.
+type Policy struct {
+	LookaheadDays      int     `json:"lookahead_days"` // how far ahead reports are fetched
+	PollHours          int     `json:"poll_hours"`
+	BlockEntries       bool    `json:"block_entries"`
+	BlockSessions      int     `json:"block_sessions"` // block new entries with this many sessions or fewer left
+	ReduceMode         string  `json:"reduce_mode"`    // off, reduce or flatten
+	ReduceFraction     float64 `json:"reduce_fraction"`
+	ReduceSessions     int     `json:"reduce_sessions"` // act on holdings with this many sessions or fewer left
+	MinutesBeforeClose int     `json:"minutes_before_close"`
+}
+
+// DefaultPolicy looks three weeks ahead every six hours and blocks new
+// entries on the last session before a report, but leaves positions alone
+// until an operator opts in to reductions.
+func DefaultPolicy() Policy {
+	return Policy{
+		LookaheadDays:      21,
+		PollHours:          6,
+		BlockEntries:       true,
+		BlockSessions:      0,
+		ReduceMode:         gaprisk.ModeOff,
+		ReduceFraction:     0.5,
+		ReduceSessions:     0,
+		MinutesBeforeClose: 15,
+	}
+}
+
+// Validate checks the policy for internally consistent values.
+func (p Policy) Validate() error {
+	if p.LookaheadDays < 1 || p.LookaheadDays > 90 {
+		return errors.New("lookahead_days must be between 1 and 90")
+	}
+	if p.PollHours < 1 || p.PollHours > 24*7 {
+		return errors.New("poll_hours must be between 1 and 168")
+	}
+	if p.BlockSessions < 0 || p.ReduceSessions < 0 {
+		return errors.New("block_sessions and reduce_sessions must not be negative")
+	}
+	switch p.ReduceMode {
+	case gaprisk.ModeOff, gaprisk.ModeReduce, gaprisk.ModeFlatten:
+	default:
+		return fmt.Errorf("reduce_mode must be one of %s, %s, %s", gaprisk.ModeOff, gaprisk.ModeReduce, gaprisk.ModeFlatten)
+	}
+	if p.ReduceMode == gaprisk.ModeReduce && (p.ReduceFraction <= 0 || p.ReduceFraction > 1) {
+		return errors.New("reduce_fraction must be in (0, 1]")
+	}
+	if p.MinutesBeforeClose < 1 || p.MinutesBeforeClose > 240 {
+		return errors.New("minutes_before_close must be between 1 and 240")
+	}
+	return nil
+}
+
+// Notifier is told about reductions.
+type Notifier func(title, message string, metadata map[string]interface{})
+
+// state is what the manager saves.
+type state struct {
+	Policy      Policy             `json:"policy"`
+	Events      map[string][]Event `json:"events"` // by symbol, in date order
+	RefreshedAt time.Time          `json:"refreshed_at"`
+	// Reduced records the reports holdings were already cut for, as
+	// SYMBOL/date, so a restart does not cut them again
+	Reduced map[string]time.Time `json:"reduced"`
+}
+
+// Manager caches the calendar and applies the policy. It is safe for
+// concurrent use.
+type Manager struct {
+	path string
+	cal  *calendar.Calendar
+
+	mu          sync.Mutex
+	state       state
+	provider    Provider
+	broker      gaprisk.Broker
+	symbols     func() []string
+	notify      Notifier
+	now         func() time.Time
+	lastSession string // session date of the last pre-close check
+	lastAttempt time.Time
+	lastError   string
+}
+
+// New opens the calendar saved at path, using policy unless one was saved.
+// Without a provider the cache is only what was saved; without a broker
+// nothing is reduced.
+func New(path string, cal *calendar.Calendar, policy Policy) (*Manager, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create earnings directory: %w", err)
+	}
+	m := &Manager{
+		path:  path,
+		cal:   cal,
+		state: state{Policy: policy, Events: make(map[string][]Event), Reduced: make(map[string]time.Time)},
+		now:   time.Now,
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		var saved state
+		if err := json.Unmarshal(data, &saved); err != nil {
+			return nil, fmt.Errorf("failed to decode earnings calendar: %w", err)
+		}
+		if saved.Policy.Validate() == nil {
+			m.state.Policy = saved.Policy
+		}
+		for sym, events := range saved.Events {
+			m.state.Events[sym] = events
+		}
+		for key, at := range saved.Reduced {
+			m.state.Reduced[key] = at
+		}
+		m.state.RefreshedAt = saved.RefreshedAt
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read earnings calendar: %w", err)
+	}
+	return m, nil
+}
+
+// SetClock replaces the clock, for replays and tests.
+func (m *Manager) SetClock(now func() time.Time) { m.mu.Lock(); m.now = now; m.mu.Unlock() }
+
+// SetProvider sets where reports are fetched from.
+func (m *Manager) SetProvider(p Provider) { m.mu.Lock(); m.provider = p; m.mu.Unlock() }
+
+// SetBroker wires the broker used for positions and reductions.
+func (m *Manager) SetBroker(b gaprisk.Broker) { m.mu.Lock(); m.broker = b; m.mu.Unlock() }
+
+// SetSymbols supplies the watchlist fetched in addition to held positions.
+func (m *Manager) SetSymbols(fn func() []string) { m.mu.Lock(); m.symbols = fn; m.mu.Unlock() }
+
+// SetNotifier registers a callback for reductions.
+func (m *Manager) SetNotifier(n Notifier) { m.mu.Lock(); m.notify = n; m.mu.Unlock() }
+
+// Policy returns the current policy.
+func (m *Manager) Policy() Policy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.Policy
+}
+
+// SetPolicy validates, replaces and saves the policy.
+func (m *Manager) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state.Policy = p
+	m.saveLocked()
+	return nil
+}
+
+// Status summarises the cache.
+type Status struct {
+	Provider    string    `json:"provider,omitempty"`
+	Symbols     int       `json:"symbols"`
+	RefreshedAt time.Time `json:"refreshed_at"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// Status returns the provider, cache size and last refresh.
+func (m *Manager) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := Status{Symbols: len(m.state.Events), RefreshedAt: m.state.RefreshedAt, LastError: m.lastError}
+	if m.provider != nil {
+		s.Provider = m.provider.Name()
+	}
+	return s
+}
+
+// Refresh fetches reports for watched and held symbols through the
+// lookahead and replaces their cached events.
+func (m *Manager) Refresh(ctx context.Context) error {
+	m.mu.Lock()
+	provider, now, policy := m.provider, m.now(), m.state.Policy
+	if provider != nil {
+		m.lastAttempt = now
+	}
+	m.mu.Unlock()
+	if provider == nil {
+		return ErrNoProvider
+	}
+	symbols := m.watched(ctx)
+	if len(symbols) == 0 {
+		return nil
+	}
+
+	today := m.day(now)
+	events, err := provider.Earnings(ctx, symbols, today, today.AddDate(0, 0, policy.LookaheadDays))
+	if err != nil && len(events) == 0 {
+		m.mu.Lock()
+		m.lastError = err.Error()
+		m.mu.Unlock()
+		return err
+	}
+	bySymbol := make(map[string][]Event)
+	for _, ev := range events {
+		ev.Symbol = strings.ToUpper(ev.Symbol)
+		ev.FetchedAt = now
+		bySymbol[ev.Symbol] = append(bySymbol[ev.Symbol], ev)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, sym := range symbols {
+		list := bySymbol[sym]
+		if len(list) == 0 {
+			// A partial failure may be why nothing came back
+			if err == nil {
+				delete(m.state.Events, sym)
+			}
+			continue
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].Date < list[j].Date })
+		m.state.Events[sym] = list
+	}
+	// Forget cuts for reports that are long past
+	for key, at := range m.state.Reduced {
+		if now.Sub(at) > 30*24*time.Hour {
+			delete(m.state.Reduced, key)
+		}
+	}
+	m.state.RefreshedAt = now
+	m.lastError = ""
+	if err != nil {
+		m.lastError = err.Error()
+	}
+	m.saveLocked()
+	logger().Info("Earnings calendar refreshed", "symbols", len(symbols), "events", len(events), "provider", provider.Name())
+	return err
+}
+
+// watched returns the watchlist and held symbols, upper-cased and sorted.
+func (m *Manager) watched(ctx context.Context) []string {
+	m.mu.Lock()
+	symbolsFn, broker := m.symbols, m.broker
+	m.mu.Unlock()
+	set := make(map[string]bool)
+	if symbolsFn != nil {
+		for _, s := range symbolsFn() {
+			set[strings.ToUpper(s)] = true
+		}
+	}
+	if broker != nil {
+		if positions, err := broker.Positions(ctx); err == nil {
+			for _, p := range positions {
+				set[strings.ToUpper(p.Symbol)] = true
+			}
+		}
+	}
+	out := make([]string, 0, len(set))
+	for s := range set {
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// day is the exchange-local date of t at midnight.
+func (m *Manager) day(t time.Time) time.Time {
+	y, mo, d := t.In(m.cal.Location()).Date()
+	return time.Date(y, mo, d, 0, 0, 0, 0, m.cal.Location())
+}
+
+// lastClose is the date of the last session that closes before ev is
+// reported. Reports of unknown time are assumed to come before the open.
+func (m *Manager) lastClose(ev Event) (time.Time, bool) {
+	date, err := time.ParseInLocation("2006-01-02", ev.Date, m.cal.Location())
+	if err != nil {
+		return time.Time{}, false
+	}
+	if ev.Hour == AfterClose && m.cal.IsTradingDay(date) {
+		return date, true
+	}
+	return m.cal.PreviousSession(date).Date, true
+}
+
+// sessionsBefore counts the closes after the date of now up to the last
+// close before ev, or returns -1 once that close is past.
+func (m *Manager) sessionsBefore(ev Event, now time.Time) int {
+	last, ok := m.lastClose(ev)
+	if !ok {
+		return -1
+	}
+	today := m.day(now)
+	if last.Before(today) {
+		return -1
+	}
+	return m.cal.TradingDaysBetween(today, last)
+}
+
+// Next returns symbol's next report that still has a close ahead of it.
+func (m *Manager) Next(symbol string) (Upcoming, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.nextLocked(strings.ToUpper(symbol), m.now())
+}
+
+func (m *Manager) nextLocked(symbol string, now time.Time) (Upcoming, bool) {
+	for _, ev := range m.state.Events[symbol] {
+		if n := m.sessionsBefore(ev, now); n >= 0 {
+			return Upcoming{Event: ev, SessionsBefore: n}, true
+		}
+	}
+	return Upcoming{}, false
+}
+
+// NextReaction returns the first session date that trades on symbol's next
+// report: the report date, or the session after it for reports after the
+// close. It has the shape the implied move estimator takes for events.
+func (m *Manager) NextReaction(symbol string) (time.Time, bool) {
+	next, ok := m.Next(symbol)
+	if !ok {
+		return time.Time{}, false
+	}
+	date, err := time.ParseInLocation("2006-01-02", next.Date, m.cal.Location())
+	if err != nil {
+		return time.Time{}, false
+	}
+	if next.Hour == AfterClose {
+		return m.cal.NextSession(date.Add(24 * time.Hour)).Date, true
+	}
+	return date, true
+}
+
+// Upcoming lists the next report of each cached symbol within days
+// calendar days, soonest first. Held marks symbols in held, which may be
+// nil.
+func (m *Manager) Upcoming(days int, held map[string]bool) []Upcoming {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	until := m.day(now).AddDate(0, 0, days).Format("2006-01-02")
+	out := []Upcoming{}
+	for sym := range m.state.Events {
+		next, ok := m.nextLocked(sym, now)
+		if !ok || next.Date > until {
+			continue
+		}
+		next.Held = held[sym]
+		out = append(out, next)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Date != out[j].Date {
+			return out[i].Date < out[j].Date
+		}
+		return out[i].Symbol < out[j].Symbol
+	})
+	return out
+}
+
+// Held returns the symbols currently held at the broker, or nil without
+// one.
+func (m *Manager) Held(ctx context.Context) map[string]bool {
+	m.mu.Lock()
+	broker := m.broker
+	m.mu.Unlock()
+	if broker == nil {
+		return nil
+	}
+	positions, err := broker.Positions(ctx)
+	if err != nil {
+		return nil
+	}
+	held := make(map[string]bool, len(positions))
+	for _, p := range positions {
+		held[strings.ToUpper(p.Symbol)] = true
+	}
+	return held
+}
+
+// CheckEntry returns an error when the policy blocks new entries in symbol
+// ahead of its report. It has the shape of an algorithm trade guard.
+func (m *Manager) CheckEntry(symbol string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	policy := m.state.Policy
+	if !policy.BlockEntries {
+		return nil
+	}
+	next, ok := m.nextLocked(strings.ToUpper(symbol), m.now())
+	if !ok || next.SessionsBefore > policy.BlockSessions {
+		return nil
+	}
+	return fmt.Errorf("%s reports earnings %s; new entries are blocked within %d session(s) of a report", next.Symbol, next.When(), policy.BlockSessions)
+}
+
+// When describes the report's timing, e.g. "after the close on 2026-01-29".
+func (ev Event) When() string {
+	switch ev.Hour {
+	case BeforeOpen:
+		return "before the open on " + ev.Date
+	case AfterClose:
+		return "after the close on " + ev.Date
+	case DuringHours:
+		return "during market hours on " + ev.Date
+	}
+	return "on " + ev.Date
+}
+
+// Tick runs the pre-close reduction when it is due at now, at most once per
+// session. Run calls it every minute.
+func (m *Manager) Tick(ctx context.Context, now time.Time) {
+	session, ok := m.cal.SessionFor(now)
+	if !ok {
+		return
+	}
+	policy := m.Policy()
+	reduceAt := session.Close.Add(-time.Duration(policy.MinutesBeforeClose) * time.Minute)
+	if policy.ReduceMode == gaprisk.ModeOff || now.Before(reduceAt) || !now.Before(session.Close) {
+		return
+	}
+	key := session.Date.Format("2006-01-02")
+	m.mu.Lock()
+	if m.lastSession == key {
+		m.mu.Unlock()
+		return
+	}
+	m.lastSession = key
+	m.mu.Unlock()
+	if _, err := m.ReduceBeforeClose(ctx); err != nil {
+		logger().Error("Pre-earnings reduction failed", "error", err)
+	}
+}
+
+// ReduceBeforeClose cuts holdings whose report is within the policy's
+// sessions, once per report.
+func (m *Manager) ReduceBeforeClose(ctx context.Context) ([]gaprisk.Reduction, error) {
+	m.mu.Lock()
+	broker, policy, notify := m.broker, m.state.Policy, m.notify
+	m.mu.Unlock()
+	if broker == nil {
+		return nil, errors.New("no broker configured")
+	}
+	if policy.ReduceMode == gaprisk.ModeOff {
+		return nil, nil
+	}
+	positions, err := broker.Positions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list positions: %w", err)
+	}
+
+	m.mu.Lock()
+	now := m.now()
+	var plan []gaprisk.Reduction
+	for _, pos := range positions {
+		next, ok := m.nextLocked(strings.ToUpper(pos.Symbol), now)
+		if !ok || next.SessionsBefore > policy.ReduceSessions {
+			continue
+		}
+		if _, done := m.state.Reduced[reducedKey(next.Event)]; done {
+			continue
+		}
+		plan = append(plan, gaprisk.PlanReductions(gaprisk.Policy{Mode: policy.ReduceMode, ReduceFraction: policy.ReduceFraction},
+			[]gaprisk.Position{pos}, "earnings "+next.When())...)
+	}
+	m.mu.Unlock()
+
+	var done []gaprisk.Reduction
+	var failed []string
+	for _, r := range plan {
+		if err := broker.Reduce(ctx, r); err != nil {
+			failed = append(failed, r.Symbol+": "+err.Error())
+			continue
+		}
+		done = append(done, r)
+	}
+
+	m.mu.Lock()
+	for _, r := range done {
+		if next, ok := m.nextLocked(strings.ToUpper(r.Symbol), now); ok {
+			m.state.Reduced[reducedKey(next.Event)] = now
+		}
+	}
+	if len(done) > 0 {
+		m.saveLocked()
+	}
+	m.mu.Unlock()
+
+	for _, r := range done {
+		logger().Warn("Position cut ahead of earnings", "symbol", r.Symbol, "side", r.Side, "qty", r.Qty, "reason", r.Reason)
+	}
+	if notify != nil && len(done) > 0 {
+		symbols := make([]string, len(done))
+		for i, r := range done {
+			symbols[i] = r.Symbol
+		}
+		notify("Pre-earnings risk reduction",
+			fmt.Sprintf("%s %d position(s) before the close ahead of earnings: %s.", policy.ReduceMode, len(done), strings.Join(symbols, ", ")),
+			map[string]interface{}{"mode": policy.ReduceMode, "symbols": symbols})
+	}
+	if len(failed) > 0 {
+		return done, errors.New("some reductions failed: " + strings.Join(failed, "; "))
+	}
+	return done, nil
+}
+
+func reducedKey(ev Event) string { return ev.Symbol + "/" + ev.Date }
+
+// Run refreshes the calendar every PollHours and ticks the risk rules every
+// minute until ctx is done.
+func (m *Manager) Run(ctx context.Context) {
+	refresh := func() {
+		if err := m.Refresh(ctx); err != nil && !errors.Is(err, ErrNoProvider) {
+			logger().Warn("Failed to refresh earnings calendar", "error", err)
+		}
+	}
+	refresh()
+	t := time.NewTicker(time.Minute)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			m.mu.Lock()
+			now, due := m.now(), m.lastAttempt.Add(time.Duration(m.state.Policy.PollHours)*time.Hour)
+			m.mu.Unlock()
+			if !now.Before(due) {
+				refresh()
+			}
+			m.Tick(ctx, now)
+		}
+	}
+}
+
+func (m *Manager) saveLocked() {
+	data, err := json.MarshalIndent(m.state, "", "  ")
+	if err != nil {
+		logger().Error("Failed to encode earnings calendar", "error", err)
+		return
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		logger().Error("Failed to write earnings calendar", "error", err)
+		return
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		logger().Error("Failed to save earnings calendar", "error", err)
+	}
+}
@@ -0,0 +1,128 @@
+package earnings
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/calendar"
+	"github.com/rileyseaburg/go-trader/gaprisk"
+)
+
+type fakeProvider []Event
+
+func (f fakeProvider) Name() string { return "fake" }
+func (f fakeProvider) Earnings(ctx context.Context, symbols []string, from, to time.Time) ([]Event, error) {
+	return f, nil
+}
+
+type fakeBroker struct {
+	positions []gaprisk.Position
+	reduced   []gaprisk.Reduction
+}
+
+func (f *fakeBroker) Positions(ctx context.Context) ([]gaprisk.Position, error) {
+	return f.positions, nil
+}
+func (f *fakeBroker) Reduce(ctx context.Context, r gaprisk.Reduction) error {
+	f.reduced = append(f.reduced, r)
+	return nil
+}
+
+func TestRulesCountSessionsBeforeReport(t *testing.T) {
+	cal := calendar.New()
+	path := filepath.Join(t.TempDir(), "calendar.json")
+	m, err := New(path, cal, DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Monday 2 March 2026, ten minutes before the close
+	now := time.Date(2026, 3, 2, 15, 50, 0, 0, cal.Location())
+	m.SetClock(func() time.Time { return now })
+	m.SetSymbols(func() []string { return []string{"aapl", "msft", "nvda"} })
+	m.SetProvider(fakeProvider{
+		{Symbol: "AAPL", Date: "2026-03-04", Hour: AfterClose},
+		{Symbol: "MSFT", Date: "2026-03-03", Hour: BeforeOpen},
+		{Symbol: "NVDA", Date: "2026-03-09"},
+	})
+	broker := &fakeBroker{positions: []gaprisk.Position{{Symbol: "MSFT", Qty: 10}, {Symbol: "AAPL", Qty: 5}}}
+	m.SetBroker(broker)
+	if err := m.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	up := m.Upcoming(14, m.Held(context.Background()))
+	var got []string
+	for _, u := range up {
+		got = append(got, fmt.Sprintf("%s:%d:%v", u.Symbol, u.SessionsBefore, u.Held))
+	}
+	if strings.Join(got, ",") != "MSFT:0:true,AAPL:2:true,NVDA:4:false" {
+		t.Fatalf("upcoming = %v", got)
+	}
+
+	if err := m.CheckEntry("msft"); err == nil {
+		t.Fatal("entry allowed on the last session before a report")
+	}
+	if err := m.CheckEntry("AAPL"); err != nil {
+		t.Fatalf("entry two sessions out blocked: %v", err)
+	}
+	if d, ok := m.NextReaction("AAPL"); !ok || d.Format("2006-01-02") != "2026-03-05" {
+		t.Fatalf("AAPL reacts %v, %v", d, ok)
+	}
+
+	// Reductions are off by default; flatten once the policy asks for it
+	m.Tick(context.Background(), now)
+	if len(broker.reduced) != 0 {
+		t.Fatalf("reduced with mode off: %+v", broker.reduced)
+	}
+	policy := m.Policy()
+	policy.ReduceMode = gaprisk.ModeFlatten
+	if err := m.SetPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	m.Tick(context.Background(), now)
+	m.Tick(context.Background(), now)
+	if len(broker.reduced) != 1 || broker.reduced[0].Symbol != "MSFT" || broker.reduced[0].Qty != 10 {
+		t.Fatalf("reduced = %+v", broker.reduced)
+	}
+
+	// The cut is remembered across a restart
+	reopened, err := New(path, cal, DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened.SetClock(func() time.Time { return now })
+	reopened.SetBroker(broker)
+	if done, err := reopened.ReduceBeforeClose(context.Background()); err != nil || len(done) != 0 {
+		t.Fatalf("second cut = %+v, %v", done, err)
+	}
+
+	// Once the report is out the rules let go
+	now = time.Date(2026, 3, 3, 10, 0, 0, 0, cal.Location())
+	if err := reopened.CheckEntry("MSFT"); err != nil {
+		t.Fatalf("entry after the report: %v", err)
+	}
+}
+
+func TestFinnhubEarnings(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("token") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprintf(w, `{"earningsCalendar":[{"date":"2026-03-04","hour":"amc","symbol":"%s","epsEstimate":1.5}]}`, r.URL.Query().Get("symbol"))
+	}))
+	defer srv.Close()
+
+	f := NewFinnhub("key")
+	f.Base = srv.URL
+	events, err := f.Earnings(context.Background(), []string{"AAPL", "MSFT"}, time.Now(), time.Now())
+	if err != nil || len(events) != 2 || events[1].Symbol != "MSFT" || events[0].Hour != AfterClose || *events[0].EPSEstimate != 1.5 {
+		t.Fatalf("events = %+v, %v", events, err)
+	}
+}
@@ -0,0 +1,107 @@
+package earnings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const finnhubAPIBase = "https://finnhub.io/api/v1/calendar/earnings"
+
+// Finnhub reads the earnings calendar from Finnhub, one request per
+// symbol. Set APIKey from the FINNHUB_API_KEY secret.
+type Finnhub struct {
+	APIKey string
+	HTTP   *http.Client
+	Base   string // overrides the API URL, for tests
+}
+
+// NewFinnhub builds a provider with a sensible default HTTP timeout.
+func NewFinnhub(apiKey string) *Finnhub {
+	return &Finnhub{APIKey: apiKey, HTTP: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// Name implements Provider.
+func (f *Finnhub) Name() string { return "finnhub" }
+
+type finnhubResponse struct {
+	EarningsCalendar []struct {
+		Date        string   `json:"date"`
+		Hour        string   `json:"hour"`
+		Symbol      string   `json:"symbol"`
+		EPSEstimate *float64 `json:"epsEstimate"`
+	} `json:"earningsCalendar"`
+}
+
+// Earnings implements Provider. Symbols that fail are reported together
+// after the rest are fetched.
+func (f *Finnhub) Earnings(ctx context.Context, symbols []string, from, to time.Time) ([]Event, error) {
+	if f.APIKey == "" {
+		return nil, errors.New("Finnhub API key not configured")
+	}
+	var events []Event
+	var failed []string
+	for _, sym := range symbols {
+		if ctx.Err() != nil {
+			return events, ctx.Err()
+		}
+		got, err := f.fetch(ctx, sym, from, to)
+		if err != nil {
+			failed = append(failed, sym+": "+err.Error())
+			continue
+		}
+		events = append(events, got...)
+	}
+	if len(failed) > 0 {
+		return events, fmt.Errorf("Finnhub earnings failed for %d symbol(s): %s", len(failed), strings.Join(failed, "; "))
+	}
+	return events, nil
+}
+
+func (f *Finnhub) fetch(ctx context.Context, symbol string, from, to time.Time) ([]Event, error) {
+	base := f.Base
+	if base == "" {
+		base = finnhubAPIBase
+	}
+	q := url.Values{}
+	q.Set("symbol", symbol)
+	q.Set("from", from.Format("2006-01-02"))
+	q.Set("to", to.Format("2006-01-02"))
+	q.Set("token", f.APIKey)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := f.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	var fr finnhubResponse
+	if err := json.NewDecoder(resp.Body).Decode(&fr); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	events := make([]Event, 0, len(fr.EarningsCalendar))
+	for _, e := range fr.EarningsCalendar {
+		if _, err := time.Parse("2006-01-02", e.Date); err != nil {
+			continue
+		}
+		events = append(events, Event{
+			Symbol:      strings.ToUpper(e.Symbol),
+			Date:        e.Date,
+			Hour:        strings.ToLower(e.Hour),
+			EPSEstimate: e.EPSEstimate,
+			Source:      f.Name(),
+		})
+	}
+	return events, nil
+}
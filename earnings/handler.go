@@ -0,0 +1,91 @@
+package earnings
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// Handler exposes the earnings calendar over HTTP.
+type Handler struct {
+	manager *Manager
+}
+
+// NewHandler creates a handler for manager.
+func NewHandler(manager *Manager) *Handler {
+	return &Handler{manager: manager}
+}
+
+// RegisterRoutes registers the earnings routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/calendar/earnings?days=14 - upcoming reports for held and watched symbols
+	mux.HandleFunc("/api/calendar/earnings", h.handleUpcoming)
+
+	// GET/POST /api/calendar/earnings/policy - read or update polling and the pre-earnings rules
+	mux.HandleFunc("/api/calendar/earnings/policy", h.handlePolicy)
+
+	// POST /api/calendar/earnings/refresh - poll the provider now
+	mux.HandleFunc("/api/calendar/earnings/refresh", h.handleRefresh)
+}
+
+func (h *Handler) handleUpcoming(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	days := h.manager.Policy().LookaheadDays
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "days must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   h.manager.Status(),
+		"policy":   h.manager.Policy(),
+		"upcoming": h.manager.Upcoming(days, h.manager.Held(r.Context())),
+	})
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.manager.Policy())
+	case http.MethodPost, http.MethodPut:
+		// Start from the current policy so partial updates work
+		policy := h.manager.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.manager.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(policy)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	err := h.manager.Refresh(r.Context())
+	if errors.Is(err, ErrNoProvider) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		// Partial failures still refreshed the other symbols
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error(), "status": h.manager.Status()})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"status": h.manager.Status()})
+}
@@ -0,0 +1,224 @@
+// Package execution slices large orders into child orders worked over a
+// time window — evenly for TWAP, or weighted by streamed intraday volume
+// for VWAP — tracks each parent and its children, and journals the
+// implementation shortfall of every finished parent.
+package execution
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+)
+
+// Execution algorithms.
+const (
+	AlgoTWAP = "twap"
+	AlgoVWAP = "vwap"
+)
+
+// Parent states.
+const (
+	ParentWorking   = "working"
+	ParentCompleted = "completed"
+	ParentCanceled  = "canceled"
+)
+
+// Child states.
+const (
+	ChildPending   = "pending"
+	ChildSubmitted = "submitted"
+	ChildFilled    = "filled"
+	ChildCanceled  = "canceled" // by us or the broker; may be partly filled
+	ChildFailed    = "failed"
+)
+
+// Policy decides which orders are sliced and how.
+type Policy struct {
+	Enabled         bool    `json:"enabled"`
+	MinNotional     float64 `json:"min_notional"` // orders at or above this are sliced
+	Algo            string  `json:"algo"`         // twap or vwap
+	DurationMinutes int     `json:"duration_minutes"`
+	Slices          int     `json:"slices"`
+}
+
+// DefaultPolicy slices orders of $25,000 or more into ten TWAP children
+// over 30 minutes.
+func DefaultPolicy() Policy {
+	return Policy{
+		Enabled:         true,
+		MinNotional:     25000,
+		Algo:            AlgoTWAP,
+		DurationMinutes: 30,
+		Slices:          10,
+	}
+}
+
+// Validate checks the policy for usable values.
+func (p Policy) Validate() error {
+	if p.MinNotional <= 0 {
+		return errors.New("min_notional must be positive")
+	}
+	if p.Algo != AlgoTWAP && p.Algo != AlgoVWAP {
+		return errors.New("algo must be twap or vwap")
+	}
+	if p.DurationMinutes < 1 {
+		return errors.New("duration_minutes must be at least 1")
+	}
+	if p.Slices < 1 || p.Slices > 500 {
+		return errors.New("slices must be between 1 and 500")
+	}
+	return nil
+}
+
+// Child is one slice of a parent order.
+type Child struct {
+	Seq         int       `json:"seq"`
+	ScheduledAt time.Time `json:"scheduled_at"`
+	Qty         float64   `json:"qty"`
+	Status      string    `json:"status"`
+	OrderID     string    `json:"order_id,omitempty"`
+	FilledQty   float64   `json:"filled_qty"`
+	FillPrice   float64   `json:"fill_price,omitempty"` // average
+	Error       string    `json:"error,omitempty"`
+}
+
+// Shortfall is a parent's implementation shortfall against its arrival
+// price. Costs are in dollars and positive when execution did worse than
+// trading everything at arrival.
+type Shortfall struct {
+	ExecutionCost   float64 `json:"execution_cost"`   // fills vs arrival on the filled quantity
+	OpportunityCost float64 `json:"opportunity_cost"` // last price vs arrival on the unfilled quantity
+	Total           float64 `json:"total"`
+	Bps             float64 `json:"bps"` // total over the parent's arrival notional
+	// VWAPBps is the average fill against the market VWAP over the
+	// parent's life, positive when it did worse, the usual VWAP benchmark
+	VWAPBps float64 `json:"vwap_bps,omitempty"`
+}
+
+// Parent is a sliced order and its children.
+type Parent struct {
+	ID           string     `json:"id"`
+	Symbol       string     `json:"symbol"`
+	Side         string     `json:"side"`
+	Qty          float64    `json:"qty"`
+	OrderType    string     `json:"order_type"`
+	LimitPrice   float64    `json:"limit_price,omitempty"`
+	Algo         string     `json:"algo"`
+	ArrivalPrice float64    `json:"arrival_price"`
+	Tag          string     `json:"tag,omitempty"`
+	StartAt      time.Time  `json:"start_at"`
+	EndAt        time.Time  `json:"end_at"`
+	Status       string     `json:"status"`
+	Note         string     `json:"note,omitempty"`
+	Children     []Child    `json:"children"`
+	FilledQty    float64    `json:"filled_qty"`
+	AvgFillPrice float64    `json:"avg_fill_price,omitempty"`
+	MarketVWAP   float64    `json:"market_vwap,omitempty"` // the market's VWAP while the parent worked
+	Shortfall    *Shortfall `json:"shortfall,omitempty"`   // set when finished
+	CreatedAt    time.Time  `json:"created_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty"`
+}
+
+// Schedule splits qty whole shares over duration from start, one child per
+// weight, sized in proportion to the weights. Equal weights give TWAP.
+// Shares lost to rounding go to the slices that lost the most; slices that
+// round to nothing are dropped.
+func Schedule(qty float64, start time.Time, duration time.Duration, weights []float64) []Child {
+	n := len(weights)
+	total := 0.0
+	for _, w := range weights {
+		total += math.Max(w, 0)
+	}
+	shares := math.Floor(qty)
+	if n == 0 || total == 0 || shares <= 0 {
+		return nil
+	}
+
+	sizes := make([]float64, n)
+	rem := make([]float64, n)
+	assigned := 0.0
+	for i, w := range weights {
+		exact := shares * math.Max(w, 0) / total
+		sizes[i] = math.Floor(exact)
+		rem[i] = exact - sizes[i]
+		assigned += sizes[i]
+	}
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return rem[order[a]] > rem[order[b]] })
+	for k := 0; assigned < shares; k++ {
+		sizes[order[k%n]]++
+		assigned++
+	}
+
+	interval := duration / time.Duration(n)
+	var children []Child
+	for i, size := range sizes {
+		if size <= 0 {
+			continue
+		}
+		children = append(children, Child{
+			Seq:         len(children) + 1,
+			ScheduledAt: start.Add(time.Duration(i) * interval),
+			Qty:         size,
+			Status:      ChildPending,
+		})
+	}
+	return children
+}
+
+// EqualWeights returns n equal weights.
+func EqualWeights(n int) []float64 {
+	w := make([]float64, n)
+	for i := range w {
+		w[i] = 1
+	}
+	return w
+}
+
+// sideSign is +1 for buys and -1 for sells, so costs come out positive
+// when the price moved against the order.
+func sideSign(side string) float64 {
+	if side == "sell" {
+		return -1
+	}
+	return 1
+}
+
+// ComputeShortfall measures a parent's implementation shortfall. Filled
+// shares are charged the gap between their average fill and arrival;
+// unfilled shares the gap between lastPrice and arrival, what waiting
+// cost. Zero lastPrice skips the opportunity cost.
+func ComputeShortfall(side string, qty, arrival, filled, avgFill, lastPrice float64) Shortfall {
+	s := sideSign(side)
+	var sf Shortfall
+	if filled > 0 && avgFill > 0 {
+		sf.ExecutionCost = s * (avgFill - arrival) * filled
+	}
+	if unfilled := qty - filled; unfilled > 0 && lastPrice > 0 {
+		sf.OpportunityCost = s * (lastPrice - arrival) * unfilled
+	}
+	sf.Total = sf.ExecutionCost + sf.OpportunityCost
+	if arrival > 0 && qty > 0 {
+		sf.Bps = sf.Total / (arrival * qty) * 10000
+	}
+	sf.ExecutionCost = round2(sf.ExecutionCost)
+	sf.OpportunityCost = round2(sf.OpportunityCost)
+	sf.Total = round2(sf.Total)
+	sf.Bps = round2(sf.Bps)
+	return sf
+}
+
+// VWAPSlippageBps is avgFill against the market vwap in basis points,
+// positive when the fills did worse.
+func VWAPSlippageBps(side string, avgFill, vwap float64) float64 {
+	if avgFill <= 0 || vwap <= 0 {
+		return 0
+	}
+	return round2(sideSign(side) * (avgFill - vwap) / vwap * 10000)
+}
+
+func round2(x float64) float64 { return math.Round(x*100) / 100 }
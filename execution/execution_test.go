@@ -0,0 +1,203 @@
+package execution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/rileyseaburg/go-trader/users"
+	"github.com/shopspring/decimal"
+)
+
+func TestSchedule(t *testing.T) {
+	start := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+
+	twap := Schedule(103, start, 10*time.Minute, EqualWeights(4))
+	if len(twap) != 4 || twap[0].Qty != 26 || twap[3].Qty != 25 || !twap[3].ScheduledAt.Equal(start.Add(7*time.Minute+30*time.Second)) {
+		t.Errorf("twap = %+v", twap)
+	}
+
+	// The empty slice is dropped; shares follow the weights
+	vwap := Schedule(100, start, 3*time.Minute, []float64{3, 0, 1})
+	if len(vwap) != 2 || vwap[0].Qty != 75 || vwap[1].Qty != 25 || vwap[1].Seq != 2 || !vwap[1].ScheduledAt.Equal(start.Add(2*time.Minute)) {
+		t.Errorf("vwap = %+v", vwap)
+	}
+
+	if Schedule(0.5, start, time.Minute, EqualWeights(2)) != nil {
+		t.Error("scheduled a fractional share")
+	}
+}
+
+func TestComputeShortfall(t *testing.T) {
+	// Bought 80 of 100 at 100.50 against a 100 arrival; the rest went to 101
+	sf := ComputeShortfall("buy", 100, 100, 80, 100.5, 101)
+	if sf.ExecutionCost != 40 || sf.OpportunityCost != 20 || sf.Total != 60 || sf.Bps != 60 {
+		t.Errorf("buy shortfall = %+v", sf)
+	}
+	// Selling above arrival is a gain
+	if sf := ComputeShortfall("sell", 10, 50, 10, 50.2, 0); sf.Total != -2 {
+		t.Errorf("sell shortfall = %+v", sf)
+	}
+}
+
+func TestVolumeProfileWeights(t *testing.T) {
+	loc := time.UTC
+	v := NewVolumeProfile(loc)
+	day := time.Date(2024, 5, 1, 14, 0, 0, 0, loc)
+	for d := 0; d < 2; d++ {
+		at := day.AddDate(0, 0, d)
+		v.Observe("aapl", at, 300)
+		v.Observe("AAPL", at.Add(time.Minute), 100)
+		v.Observe("AAPL", at.Add(time.Minute), 100) // re-polled bar
+	}
+	w, ok := v.Weights("AAPL", day.AddDate(0, 0, 2), 2*time.Minute, 2)
+	if !ok || w[0] != 300 || w[1] != 100 {
+		t.Errorf("weights = %v, %v", w, ok)
+	}
+	if _, ok := v.Weights("MSFT", day, time.Minute, 1); ok {
+		t.Error("weights for an unseen symbol")
+	}
+}
+
+type fakeBroker struct {
+	orders map[string]*alpaca.Order
+	placed []alpaca.PlaceOrderRequest
+}
+
+func (b *fakeBroker) PlaceOrder(req alpaca.PlaceOrderRequest) (*alpaca.Order, error) {
+	b.placed = append(b.placed, req)
+	id := fmt.Sprintf("o%d", len(b.placed))
+	b.orders[id] = &alpaca.Order{ID: id, Status: "new"}
+	return b.orders[id], nil
+}
+
+func (b *fakeBroker) GetOrder(id string) (*alpaca.Order, error) { return b.orders[id], nil }
+
+func (b *fakeBroker) CancelOrder(id string) error {
+	b.orders[id].Status = "canceled"
+	return nil
+}
+
+func fill(o *alpaca.Order, qty, price float64) {
+	o.Status = "filled"
+	o.FilledQty = decimal.NewFromFloat(qty)
+	p := decimal.NewFromFloat(price)
+	o.FilledAvgPrice = &p
+}
+
+func TestManagerWorksParentAndJournals(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	broker := &fakeBroker{orders: make(map[string]*alpaca.Order)}
+	m, err := NewManager(path, broker, func(string) float64 { return 101 }, NewVolumeProfile(time.UTC), DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	qty := decimal.NewFromInt(100)
+	if _, sliced, _ := m.Slice(alpaca.PlaceOrderRequest{Symbol: "AAPL", Qty: &qty, Side: alpaca.Buy, Type: alpaca.Market}, 100, ""); sliced {
+		t.Fatal("sliced an order under the notional threshold")
+	}
+	qty = decimal.NewFromInt(300)
+	p, sliced, err := m.Slice(alpaca.PlaceOrderRequest{Symbol: "AAPL", Qty: &qty, Side: alpaca.Buy, Type: alpaca.Market}, 100, "")
+	if err != nil || !sliced || len(p.Children) != 10 {
+		t.Fatalf("slice = %+v, %v, %v", p, sliced, err)
+	}
+
+	start := p.StartAt
+	m.Step(start)
+	if len(broker.placed) != 1 || broker.placed[0].Qty.String() != "30" || broker.placed[0].ClientOrderID != p.ID+"-1" {
+		t.Fatalf("placed = %+v", broker.placed)
+	}
+	fill(broker.orders["o1"], 30, 100.5)
+	m.Step(start.Add(3 * time.Minute))
+	got, _ := m.Get(p.ID)
+	if len(broker.placed) != 2 || got.Children[0].Status != ChildFilled || got.Children[1].Status != ChildSubmitted {
+		t.Fatalf("after second step: %+v", got.Children[:2])
+	}
+
+	// Canceling keeps the fill and charges the rest at the last price
+	if _, err := m.Cancel(p.ID); err != nil {
+		t.Fatal(err)
+	}
+	got, _ = m.Get(p.ID)
+	if got.Status != ParentCanceled || got.FilledQty != 30 || got.Shortfall == nil || got.Shortfall.Total != 15+270 {
+		t.Errorf("canceled parent = %+v, shortfall %+v", got, got.Shortfall)
+	}
+	if _, err := m.Cancel(p.ID); err == nil {
+		t.Error("canceled a finished parent")
+	}
+	m.Close()
+
+	reopened, err := NewManager(path, broker, nil, NewVolumeProfile(time.UTC), DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	if _, finished := reopened.List(); len(finished) != 1 || finished[0].Shortfall.Total != 285 {
+		t.Errorf("journal = %+v", finished)
+	}
+}
+
+func TestManagerGuardsParentsAndChildren(t *testing.T) {
+	broker := &fakeBroker{orders: make(map[string]*alpaca.Order)}
+	m, err := NewManager(filepath.Join(t.TempDir(), "journal.jsonl"), broker, func(string) float64 { return 101 },
+		NewVolumeProfile(time.UTC), DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	safeMode := false
+	m.SetGuard(func(symbol, side string, qty, price float64, _ string) error {
+		if safeMode {
+			return errors.New("safe mode")
+		}
+		if qty*price > 50000 {
+			return errors.New("over the position limit")
+		}
+		return nil
+	})
+
+	if _, err := m.Submit(Request{Symbol: "AAPL", Side: "buy", Qty: 1000, Algo: AlgoTWAP}, time.Now()); !errors.Is(err, ErrRefused) {
+		t.Fatalf("oversized parent: %v", err)
+	}
+	p, err := m.Submit(Request{Symbol: "AAPL", Side: "buy", Qty: 100, Algo: AlgoTWAP, Slices: 2}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Step(p.StartAt)
+	safeMode = true
+	m.Step(p.EndAt)
+	got, _ := m.Get(p.ID)
+	if len(broker.placed) != 1 || got.Children[1].Status != ChildFailed || got.Children[1].Error != "safe mode" {
+		t.Errorf("placed %d, children %+v", len(broker.placed), got.Children)
+	}
+}
+
+func TestOnlyAdminsChangePolicy(t *testing.T) {
+	m, err := NewManager(filepath.Join(t.TempDir(), "journal.jsonl"), &fakeBroker{orders: make(map[string]*alpaca.Order)}, nil,
+		NewVolumeProfile(time.UTC), DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	NewHandler(m).RegisterRoutes(mux)
+
+	trader := users.WithUser(context.Background(), users.User{ID: "trader", Role: users.RoleUser})
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/execution/policy", strings.NewReader(`{}`)).WithContext(trader))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("user POST = %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/execution/policy", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusOK {
+		t.Errorf("admin POST = %d", rec.Code)
+	}
+}
@@ -0,0 +1,138 @@
+package execution
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/paging"
+	"github.com/rileyseaburg/go-trader/users"
+)
+
+// Handler exposes the execution algorithms over HTTP.
+type Handler struct {
+	manager *Manager
+}
+
+// NewHandler creates a handler for manager.
+func NewHandler(manager *Manager) *Handler {
+	return &Handler{manager: manager}
+}
+
+// RegisterRoutes registers the execution routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/execution/parents?limit=&cursor=&sort=&filter= - working parent orders and a page of journaled ones
+	// POST /api/execution/parents - slice an order: {"symbol", "side", "qty", "algo", ...}
+	mux.HandleFunc("/api/execution/parents", h.handleParents)
+
+	// GET /api/execution/parents/{id} - one parent with its children
+	// POST /api/execution/parents/{id}/cancel - stop a working parent
+	mux.HandleFunc("/api/execution/parents/", h.handleParent)
+
+	// GET/POST /api/execution/policy - read or update the slicing policy
+	mux.HandleFunc("/api/execution/policy", h.handlePolicy)
+}
+
+// finishedList pages the journaled parents, newest first by default.
+var finishedList = paging.List[Parent]{
+	Fields: []paging.Field[Parent]{
+		{Name: "created_at", Sort: func(p Parent) paging.Key { return paging.Time(p.CreatedAt) }},
+		{Name: "symbol", Sort: func(p Parent) paging.Key { return paging.Text(p.Symbol) }, Filter: func(p Parent) string { return p.Symbol }},
+		{Name: "side", Filter: func(p Parent) string { return p.Side }},
+		{Name: "algo", Filter: func(p Parent) string { return p.Algo }},
+		{Name: "status", Filter: func(p Parent) string { return p.Status }},
+		{Name: "tag", Filter: func(p Parent) string { return p.Tag }},
+	},
+	ID:   func(p Parent) string { return p.ID },
+	Sort: "-created_at",
+}
+
+func (h *Handler) handleParents(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		working, finished := h.manager.List()
+		page, ok := finishedList.Page(w, r, finished)
+		if !ok {
+			return
+		}
+		paging.SetHeaders(w, page.Total, page.NextCursor)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"working":  working,
+			"finished": page.Items,
+		})
+	case http.MethodPost:
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		p, err := h.manager.Submit(req, time.Now())
+		if errors.Is(err, ErrRefused) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(p)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleParent(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/execution/parents/"), "/")
+	switch {
+	case id == "":
+		http.NotFound(w, r)
+	case action == "" && r.Method == http.MethodGet:
+		p, err := h.manager.Get(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(p)
+	case action == "cancel" && r.Method == http.MethodPost:
+		p, err := h.manager.Cancel(id)
+		if errors.Is(err, ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		json.NewEncoder(w).Encode(p)
+	case action == "" || action == "cancel":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.manager.Policy())
+	case http.MethodPost, http.MethodPut:
+		if !users.FromContext(r.Context()).IsAdmin() {
+			http.Error(w, users.ErrForbidden.Error(), http.StatusForbidden)
+			return
+		}
+		// Start from the current policy so partial updates work
+		policy := h.manager.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.manager.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(policy)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
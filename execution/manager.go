@@ -0,0 +1,587 @@
+package execution
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
+)
+
+func logger() *slog.Logger { return slog.With("module", "execution") }
+
+// maxHistory bounds the finished parents kept in memory; the journal on
+// disk keeps everything.
+const maxHistory = 500
+
+// tagSeparator ends the strategy tag prefix of a client order ID, as
+// algorithm.NewClientOrderID writes it.
+const tagSeparator = ":"
+
+// ErrNotFound is returned for an unknown parent ID.
+var ErrNotFound = errors.New("parent order not found")
+
+// ErrRefused is wrapped by the guard's refusals of parents.
+var ErrRefused = errors.New("order refused")
+
+// Broker is the part of the Alpaca client the slicer needs.
+type Broker interface {
+	PlaceOrder(req alpaca.PlaceOrderRequest) (*alpaca.Order, error)
+	GetOrder(orderID string) (*alpaca.Order, error)
+	CancelOrder(orderID string) error
+}
+
+// PriceSource returns the latest price for symbol, zero if unknown.
+type PriceSource func(symbol string) float64
+
+// Guard checks an order before it is placed, normally against the
+// algorithm's trade guards and risk limits. side is buy or sell and price
+// the order's expected price.
+type Guard func(symbol, side string, qty, price float64, tag string) error
+
+// MarketVWAP measures the market's VWAP over a window, such as a parent's
+// life, from streamed bars.
+type MarketVWAP interface {
+	OpenWindow(symbol, id string)
+	CloseWindow(symbol, id string) (float64, bool)
+}
+
+// Request describes an order to slice. Zero Algo, DurationMinutes and
+// Slices take the policy's values.
+type Request struct {
+	Symbol          string  `json:"symbol"`
+	Side            string  `json:"side"`
+	Qty             float64 `json:"qty"`
+	OrderType       string  `json:"order_type"`
+	LimitPrice      float64 `json:"limit_price,omitempty"`
+	Algo            string  `json:"algo,omitempty"`
+	ArrivalPrice    float64 `json:"arrival_price"`
+	DurationMinutes int     `json:"duration_minutes,omitempty"`
+	Slices          int     `json:"slices,omitempty"`
+	Tag             string  `json:"tag,omitempty"` // strategy tag for the children's client order IDs
+}
+
+// Manager works parent orders and journals them when they finish.
+type Manager struct {
+	broker  Broker
+	prices  PriceSource
+	profile *VolumeProfile
+	vwap    MarketVWAP
+
+	// stepMu serializes Step and Cancel, the only writers of working
+	// parents, so they can talk to the broker without holding mu
+	stepMu sync.Mutex
+
+	mu      sync.RWMutex
+	policy  Policy
+	working map[string]*Parent
+	history []Parent
+	journal *os.File
+	seq     int
+	placed  func(order *alpaca.Order, algo string)
+	guard   Guard
+}
+
+// NewManager opens the journal at path, loading recent finished parents,
+// and returns a manager with the given policy. Parents still working when
+// the process stopped are not resumed; their submitted children stay at
+// the broker.
+func NewManager(path string, broker Broker, prices PriceSource, profile *VolumeProfile, policy Policy) (*Manager, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create execution journal directory: %w", err)
+	}
+	m := &Manager{
+		broker:  broker,
+		prices:  prices,
+		profile: profile,
+		policy:  policy,
+		working: make(map[string]*Parent),
+	}
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			var p Parent
+			if err := json.Unmarshal(scanner.Bytes(), &p); err != nil {
+				logger().Warn("Skipping malformed execution journal line", "error", err)
+				continue
+			}
+			m.history = append(m.history, p)
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read execution journal: %w", err)
+		}
+		if len(m.history) > maxHistory {
+			m.history = m.history[len(m.history)-maxHistory:]
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to open execution journal: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open execution journal for writing: %w", err)
+	}
+	m.journal = f
+	return m, nil
+}
+
+// SetOrderHandler registers fn to receive every child order placed.
+func (m *Manager) SetOrderHandler(fn func(order *alpaca.Order, algo string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.placed = fn
+}
+
+// SetGuard sets the check every parent entered through Submit and every
+// child must pass before it is placed. A refused child fails; the rest of
+// its parent carries on.
+func (m *Manager) SetGuard(fn Guard) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.guard = fn
+}
+
+// SetMarketVWAP sets where parents are benchmarked against the market
+// VWAP over their life.
+func (m *Manager) SetMarketVWAP(v MarketVWAP) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.vwap = v
+}
+
+// Policy returns the current policy.
+func (m *Manager) Policy() Policy {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.policy
+}
+
+// SetPolicy validates and replaces the policy.
+func (m *Manager) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policy = p
+	return nil
+}
+
+// Slice takes over req if it should be sliced: algo names an algorithm to
+// use regardless of size, otherwise the policy's notional threshold
+// decides. It reports whether the order was sliced. Stop orders rest
+// whole until triggered and are never sliced.
+func (m *Manager) Slice(req alpaca.PlaceOrderRequest, arrival float64, algo string) (*Parent, bool, error) {
+	if req.Qty == nil {
+		return nil, false, nil
+	}
+	if req.StopPrice != nil {
+		if algo != "" {
+			return nil, false, fmt.Errorf("%s orders cannot be worked by %s", req.Type, algo)
+		}
+		return nil, false, nil
+	}
+	qty, _ := req.Qty.Float64()
+	price := arrival
+	if req.LimitPrice != nil {
+		price, _ = req.LimitPrice.Float64()
+	}
+	policy := m.Policy()
+	if algo == "" && (!policy.Enabled || qty*price < policy.MinNotional) {
+		return nil, false, nil
+	}
+
+	r := Request{
+		Symbol:       req.Symbol,
+		Side:         string(req.Side),
+		Qty:          qty,
+		OrderType:    string(req.Type),
+		Algo:         algo,
+		ArrivalPrice: arrival,
+	}
+	if req.LimitPrice != nil {
+		r.LimitPrice, _ = req.LimitPrice.Float64()
+	}
+	// Keep the order's strategy tag, the prefix of its client order ID
+	if tag, _, ok := strings.Cut(req.ClientOrderID, tagSeparator); ok {
+		r.Tag = tag
+	}
+	// The order was built, guarded and sized by the caller; its children
+	// are still checked as they go out
+	p, err := m.submit(r, time.Now())
+	if err != nil {
+		return nil, false, err
+	}
+	return p, true, nil
+}
+
+// Submit checks req with the guard and schedules it as a parent order
+// starting at now. The first child goes out on the next Step.
+func (m *Manager) Submit(req Request, now time.Time) (*Parent, error) {
+	m.mu.RLock()
+	guard := m.guard
+	m.mu.RUnlock()
+	if guard != nil {
+		symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+		if err := guard(symbol, req.Side, req.Qty, m.orderPrice(symbol, req.LimitPrice, req.ArrivalPrice), req.Tag); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrRefused, err)
+		}
+	}
+	return m.submit(req, now)
+}
+
+// orderPrice is the price an order is checked at: its limit, else its
+// arrival price, else the latest price.
+func (m *Manager) orderPrice(symbol string, limit, arrival float64) float64 {
+	switch {
+	case limit > 0:
+		return limit
+	case arrival > 0:
+		return arrival
+	case m.prices != nil:
+		return m.prices(symbol)
+	}
+	return 0
+}
+
+func (m *Manager) submit(req Request, now time.Time) (*Parent, error) {
+	policy := m.Policy()
+	if req.Algo == "" {
+		req.Algo = policy.Algo
+	}
+	if req.DurationMinutes <= 0 {
+		req.DurationMinutes = policy.DurationMinutes
+	}
+	if req.Slices <= 0 {
+		req.Slices = policy.Slices
+	}
+	req.Symbol = strings.ToUpper(strings.TrimSpace(req.Symbol))
+	switch {
+	case req.Symbol == "":
+		return nil, errors.New("symbol is required")
+	case req.Side != "buy" && req.Side != "sell":
+		return nil, fmt.Errorf("side must be buy or sell, got %q", req.Side)
+	case req.Algo != AlgoTWAP && req.Algo != AlgoVWAP:
+		return nil, fmt.Errorf("unknown execution algorithm %q", req.Algo)
+	case req.Qty < 1:
+		return nil, errors.New("qty must be at least one share")
+	}
+	if req.OrderType == "" {
+		req.OrderType = string(alpaca.Market)
+	}
+
+	duration := time.Duration(req.DurationMinutes) * time.Minute
+	p := &Parent{
+		Symbol:       req.Symbol,
+		Side:         req.Side,
+		Qty:          req.Qty,
+		OrderType:    req.OrderType,
+		LimitPrice:   req.LimitPrice,
+		Algo:         req.Algo,
+		ArrivalPrice: req.ArrivalPrice,
+		Tag:          req.Tag,
+		StartAt:      now,
+		EndAt:        now.Add(duration),
+		Status:       ParentWorking,
+		CreatedAt:    now,
+	}
+
+	weights := EqualWeights(req.Slices)
+	if req.Algo == AlgoVWAP {
+		if w, ok := m.profile.Weights(req.Symbol, now, duration, req.Slices); ok {
+			weights = w
+		} else {
+			p.Note = "no streamed volume for this window yet; sliced evenly"
+		}
+	}
+	p.Children = Schedule(req.Qty, now, duration, weights)
+	if len(p.Children) == 0 {
+		return nil, errors.New("nothing to schedule")
+	}
+
+	m.mu.Lock()
+	m.seq++
+	p.ID = fmt.Sprintf("exe_%d_%d", now.UnixNano(), m.seq)
+	m.working[p.ID] = p
+	vwap := m.vwap
+	m.mu.Unlock()
+	if vwap != nil {
+		vwap.OpenWindow(p.Symbol, p.ID)
+	}
+
+	logger().Info("Started execution", "id", p.ID, "algo", p.Algo, "side", p.Side, "qty", p.Qty,
+		"symbol", p.Symbol, "minutes", req.DurationMinutes, "children", len(p.Children))
+	return m.Get(p.ID)
+}
+
+// Get returns a copy of the parent with id, working or finished.
+func (m *Manager) Get(id string) (*Parent, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if p, ok := m.working[id]; ok {
+		c := copyParent(p)
+		return &c, nil
+	}
+	for i := len(m.history) - 1; i >= 0; i-- {
+		if m.history[i].ID == id {
+			c := copyParent(&m.history[i])
+			return &c, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// List returns working parents, oldest first, and finished ones, newest
+// first.
+func (m *Manager) List() (working, finished []Parent) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	working = make([]Parent, 0, len(m.working))
+	for _, p := range m.working {
+		working = append(working, copyParent(p))
+	}
+	sort.Slice(working, func(i, j int) bool { return working[i].CreatedAt.Before(working[j].CreatedAt) })
+	finished = make([]Parent, len(m.history))
+	for i, p := range m.history {
+		finished[len(m.history)-1-i] = p
+	}
+	return working, finished
+}
+
+func copyParent(p *Parent) Parent {
+	c := *p
+	c.Children = append([]Child(nil), p.Children...)
+	return c
+}
+
+// Run steps working parents every interval until ctx is cancelled.
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			m.Step(time.Now())
+		}
+	}
+}
+
+// Step submits children that are due, reads fills for submitted ones and
+// finishes parents whose children are all done. Limit children still open
+// one slice after the window ends are canceled.
+func (m *Manager) Step(now time.Time) {
+	m.stepMu.Lock()
+	defer m.stepMu.Unlock()
+
+	m.mu.RLock()
+	parents := make([]*Parent, 0, len(m.working))
+	for _, p := range m.working {
+		parents = append(parents, p)
+	}
+	m.mu.RUnlock()
+
+	for _, p := range parents {
+		overdue := now.After(p.EndAt.Add(p.EndAt.Sub(p.StartAt) / time.Duration(len(p.Children))))
+		done := true
+		for i := range p.Children {
+			c := p.Children[i] // only Step and Cancel write, under stepMu
+			switch c.Status {
+			case ChildPending:
+				if overdue {
+					c.Status = ChildCanceled
+				} else if !now.Before(c.ScheduledAt) {
+					m.submitChild(p, &c)
+				}
+			case ChildSubmitted:
+				m.refreshChild(&c, overdue)
+			}
+			if c.Status == ChildPending || c.Status == ChildSubmitted {
+				done = false
+			}
+			m.mu.Lock()
+			p.Children[i] = c
+			m.mu.Unlock()
+		}
+		if done {
+			m.finish(p, ParentCompleted, now)
+		}
+	}
+}
+
+func (m *Manager) submitChild(p *Parent, c *Child) {
+	qty := decimal.NewFromFloat(c.Qty)
+	req := alpaca.PlaceOrderRequest{
+		Symbol:        p.Symbol,
+		Qty:           &qty,
+		Side:          alpaca.Side(p.Side),
+		Type:          alpaca.OrderType(p.OrderType),
+		TimeInForce:   alpaca.Day,
+		ClientOrderID: fmt.Sprintf("%s-%d", p.ID, c.Seq),
+	}
+	if p.Tag != "" {
+		req.ClientOrderID = p.Tag + tagSeparator + req.ClientOrderID
+	}
+	if p.LimitPrice > 0 && req.Type == alpaca.Limit {
+		limit := decimal.NewFromFloat(p.LimitPrice).Round(2)
+		req.LimitPrice = &limit
+	}
+	m.mu.RLock()
+	guard := m.guard
+	m.mu.RUnlock()
+	if guard != nil {
+		if err := guard(p.Symbol, p.Side, c.Qty, m.orderPrice(p.Symbol, p.LimitPrice, p.ArrivalPrice), p.Tag); err != nil {
+			c.Status = ChildFailed
+			c.Error = err.Error()
+			logger().Warn("Child order refused", "id", p.ID, "seq", c.Seq, "error", err)
+			return
+		}
+	}
+	order, err := m.broker.PlaceOrder(req)
+	if err != nil {
+		c.Status = ChildFailed
+		c.Error = err.Error()
+		logger().Error("Child order failed", "id", p.ID, "seq", c.Seq, "error", err)
+		return
+	}
+	c.Status = ChildSubmitted
+	c.OrderID = order.ID
+	m.mu.RLock()
+	placed := m.placed
+	m.mu.RUnlock()
+	if placed != nil {
+		placed(order, p.Algo)
+	}
+}
+
+// refreshChild reads a submitted child's fills, canceling it when the
+// parent's window is over.
+func (m *Manager) refreshChild(c *Child, overdue bool) {
+	order, err := m.broker.GetOrder(c.OrderID)
+	if err != nil {
+		logger().Error("Failed to read child order", "order_id", c.OrderID, "error", err)
+		return
+	}
+	c.FilledQty, _ = order.FilledQty.Float64()
+	if order.FilledAvgPrice != nil {
+		c.FillPrice, _ = order.FilledAvgPrice.Float64()
+	}
+	switch strings.ToLower(order.Status) {
+	case "filled":
+		c.Status = ChildFilled
+	case "canceled", "expired", "rejected", "done_for_day", "stopped", "suspended":
+		c.Status = ChildCanceled
+	default:
+		if overdue {
+			if err := m.broker.CancelOrder(c.OrderID); err != nil {
+				logger().Error("Failed to cancel overdue child order", "order_id", c.OrderID, "error", err)
+				return
+			}
+			c.Status = ChildCanceled
+		}
+	}
+}
+
+// Cancel stops a working parent: pending children are dropped and open
+// ones canceled at the broker. Fills so far are kept and journaled.
+func (m *Manager) Cancel(id string) (*Parent, error) {
+	m.stepMu.Lock()
+	defer m.stepMu.Unlock()
+
+	m.mu.RLock()
+	p, ok := m.working[id]
+	m.mu.RUnlock()
+	if !ok {
+		if _, err := m.Get(id); err == nil {
+			return nil, fmt.Errorf("parent order %s already finished", id)
+		}
+		return nil, ErrNotFound
+	}
+
+	for i := range p.Children {
+		c := p.Children[i]
+		switch c.Status {
+		case ChildPending:
+			c.Status = ChildCanceled
+		case ChildSubmitted:
+			m.refreshChild(&c, true)
+		}
+		m.mu.Lock()
+		p.Children[i] = c
+		m.mu.Unlock()
+	}
+	m.finish(p, ParentCanceled, time.Now())
+	return m.Get(id)
+}
+
+// finish totals p's fills, measures its shortfall, journals it and moves
+// it to history.
+func (m *Manager) finish(p *Parent, status string, now time.Time) {
+	filled, cost := 0.0, 0.0
+	for _, c := range p.Children {
+		filled += c.FilledQty
+		cost += c.FilledQty * c.FillPrice
+	}
+	avg := 0.0
+	if filled > 0 {
+		avg = cost / filled
+	}
+	last := 0.0
+	if m.prices != nil {
+		last = m.prices(p.Symbol)
+	}
+	sf := ComputeShortfall(p.Side, p.Qty, p.ArrivalPrice, filled, avg, last)
+	m.mu.RLock()
+	vwap := m.vwap
+	m.mu.RUnlock()
+	var marketVWAP float64
+	if vwap != nil {
+		if v, ok := vwap.CloseWindow(p.Symbol, p.ID); ok {
+			marketVWAP = math.Round(v*10000) / 10000
+			sf.VWAPBps = VWAPSlippageBps(p.Side, avg, v)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p.MarketVWAP = marketVWAP
+	p.Status = status
+	p.FilledQty = filled
+	p.AvgFillPrice = math.Round(avg*10000) / 10000
+	p.Shortfall = &sf
+	p.FinishedAt = &now
+	delete(m.working, p.ID)
+	m.history = append(m.history, copyParent(p))
+	if len(m.history) > maxHistory {
+		m.history = m.history[len(m.history)-maxHistory:]
+	}
+
+	if line, err := json.Marshal(p); err != nil {
+		logger().Error("Failed to encode execution journal entry", "id", p.ID, "error", err)
+	} else if _, err := m.journal.Write(append(line, '\n')); err != nil {
+		logger().Error("Failed to write execution journal entry", "id", p.ID, "error", err)
+	}
+	logger().Info("Execution finished", "id", p.ID, "status", status, "symbol", p.Symbol, "filled", filled,
+		"qty", p.Qty, "avg_price", avg, "shortfall", sf.Total, "shortfall_bps", sf.Bps, "vwap_bps", sf.VWAPBps)
+}
+
+// Close closes the journal.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.journal.Close()
+}
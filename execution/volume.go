@@ -0,0 +1,92 @@
+package execution
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// profileDays is how many sessions of streamed volume a profile keeps.
+const profileDays = 10
+
+// VolumeProfile learns each symbol's intraday volume curve from streamed
+// minute bars, for VWAP weights.
+type VolumeProfile struct {
+	loc *time.Location
+
+	mu   sync.Mutex
+	days map[string]map[string]map[int]float64 // symbol → date → minute of day → volume
+}
+
+// NewVolumeProfile returns an empty profile keyed by dates and minutes in
+// loc, the exchange's time zone.
+func NewVolumeProfile(loc *time.Location) *VolumeProfile {
+	return &VolumeProfile{loc: loc, days: make(map[string]map[string]map[int]float64)}
+}
+
+// Observe records volume for the minute bar starting at at. A bar seen
+// again on a later poll replaces rather than adds to the earlier reading.
+func (v *VolumeProfile) Observe(symbol string, at time.Time, volume float64) {
+	if volume <= 0 || at.IsZero() {
+		return
+	}
+	symbol = strings.ToUpper(symbol)
+	at = at.In(v.loc)
+	date := at.Format("2006-01-02")
+	minute := at.Hour()*60 + at.Minute()
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	byDate, ok := v.days[symbol]
+	if !ok {
+		byDate = make(map[string]map[int]float64)
+		v.days[symbol] = byDate
+	}
+	minutes, ok := byDate[date]
+	if !ok {
+		minutes = make(map[int]float64)
+		byDate[date] = minutes
+		if len(byDate) > profileDays {
+			dates := make([]string, 0, len(byDate))
+			for d := range byDate {
+				dates = append(dates, d)
+			}
+			sort.Strings(dates)
+			delete(byDate, dates[0])
+		}
+	}
+	minutes[minute] = volume
+}
+
+// Weights returns the average volume traded in each of n equal slices of
+// duration from start, by time of day across the sessions seen. ok is
+// false when there is no volume for the window at all.
+func (v *VolumeProfile) Weights(symbol string, start time.Time, duration time.Duration, n int) ([]float64, bool) {
+	symbol = strings.ToUpper(symbol)
+	start = start.In(v.loc)
+	interval := duration / time.Duration(n)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	byDate := v.days[symbol]
+	if len(byDate) == 0 {
+		return nil, false
+	}
+
+	weights := make([]float64, n)
+	any := false
+	for i := range weights {
+		from := start.Add(time.Duration(i) * interval)
+		to := from.Add(interval)
+		total := 0.0
+		for _, minutes := range byDate {
+			for t := from; t.Before(to); t = t.Add(time.Minute) {
+				total += minutes[t.Hour()*60+t.Minute()]
+			}
+		}
+		weights[i] = total / float64(len(byDate))
+		any = any || weights[i] > 0
+	}
+	return weights, any
+}
@@ -0,0 +1,252 @@
+// Package featurestore computes features — returns, indicators,
+// volatility, regime and bar microstructure — per symbol per bar and keeps
+// them, so model training, backtests and live inference read the same
+// values.
+//
+// Features are point in time. A bar's features are computed from that bar
+// and the ones before it only, and the row is stamped with when the bar
+// closed; reads never return a row before then, against the store's clock.
+// Rows are written once: a stored bar is never recomputed, so what live
+// inference saw is what a later backtest reads.
+package featurestore
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Version identifies the feature definitions. Rows of each version are
+// kept apart, so changing a definition never mixes old and new values.
+const Version = 1
+
+// Lookback is the bars, including the current one, the longest feature
+// needs. Rows with less history omit the features they lack.
+const Lookback = 61
+
+// Regimes.
+const (
+	RegimeTrendingUp   = "trending_up"
+	RegimeTrendingDown = "trending_down"
+	RegimeRanging      = "ranging"
+	RegimeVolatile     = "volatile"
+)
+
+const (
+	// trendEfficiency is the efficiency ratio at or above which a symbol
+	// is trending
+	trendEfficiency = 0.3
+	// volatileRatio is the short to long realized volatility ratio above
+	// which a symbol is volatile, whatever its trend
+	volatileRatio = 1.5
+)
+
+// Bar is one bar a row is computed from.
+type Bar struct {
+	Time   time.Time `json:"time"` // the bar's start
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Volume float64   `json:"volume"`
+	VWAP   float64   `json:"vwap,omitempty"`
+}
+
+// Definition describes a feature.
+type Definition struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Bars        int    `json:"bars"` // history needed, including the current bar
+}
+
+// Catalog lists the features of the current Version.
+var Catalog = []Definition{
+	{"return_1", "Close over the previous close, less one", 2},
+	{"return_5", "Close over the close 5 bars back, less one", 6},
+	{"return_20", "Close over the close 20 bars back, less one", 21},
+	{"sma_20_gap", "Close over its 20-bar simple average, less one", 20},
+	{"rsi_14", "Relative strength index over 14 bars", 15},
+	{"atr_14_pct", "Average true range over 14 bars, percent of close", 15},
+	{"realized_vol_20", "Annualized volatility of 20 bars of log returns, percent", 21},
+	{"realized_vol_60", "Annualized volatility of 60 bars of log returns, percent", 61},
+	{"vol_ratio", "20-bar over 60-bar realized volatility", 61},
+	{"efficiency_ratio_20", "Net move over the sum of absolute moves across 20 bars", 21},
+	{"volume_z_20", "Volume's z-score against the 20 bars before it", 21},
+	{"range_pct", "High less low, percent of close", 1},
+	{"close_location", "Where the close sits in the bar's range, 0 at the low and 1 at the high", 1},
+	{"gap_pct", "Open over the previous close, less one, percent", 2},
+	{"vwap_gap_pct", "Close over the bar's VWAP, less one, percent", 1},
+}
+
+// compute returns the features of the last bar of window, which holds it
+// and the bars before it, oldest first, and its regime. It is only ever
+// given bars up to the one it describes.
+func compute(window []Bar, periodsPerYear float64) (map[string]float64, string) {
+	n := len(window)
+	values := make(map[string]float64)
+	if n == 0 {
+		return values, ""
+	}
+	cur := window[n-1]
+	closes := make([]float64, n)
+	for i, b := range window {
+		closes[i] = b.Close
+	}
+	ret := func(k int) {
+		if n > k && closes[n-1-k] > 0 {
+			values["return_"+strconv.Itoa(k)] = closes[n-1]/closes[n-1-k] - 1
+		}
+	}
+	ret(1)
+	ret(5)
+	ret(20)
+	if n >= 20 {
+		if sma := mean(closes[n-20:]); sma > 0 {
+			values["sma_20_gap"] = cur.Close/sma - 1
+		}
+	}
+
+	// RSI and ATR over 14 periods, as the baselines compute them
+	const period = 14
+	if n > period {
+		var atr, gain, loss float64
+		for i := n - period; i < n; i++ {
+			prev := window[i-1].Close
+			atr += math.Max(window[i].High-window[i].Low, math.Max(math.Abs(window[i].High-prev), math.Abs(window[i].Low-prev)))
+			if d := window[i].Close - prev; d > 0 {
+				gain += d
+			} else {
+				loss -= d
+			}
+		}
+		if cur.Close > 0 {
+			values["atr_14_pct"] = atr / period / cur.Close * 100
+		}
+		if loss == 0 {
+			values["rsi_14"] = 100
+		} else {
+			values["rsi_14"] = 100 - 100/(1+gain/loss)
+		}
+	}
+
+	vol20, ok20 := realizedVol(closes, 20, periodsPerYear)
+	if ok20 {
+		values["realized_vol_20"] = vol20
+	}
+	vol60, ok60 := realizedVol(closes, 60, periodsPerYear)
+	if ok60 {
+		values["realized_vol_60"] = vol60
+		if ok20 && vol60 > 0 {
+			values["vol_ratio"] = vol20 / vol60
+		}
+	}
+	efficiency, okER := efficiencyRatio(closes, 20)
+	if okER {
+		values["efficiency_ratio_20"] = efficiency
+	}
+
+	if n > 20 {
+		vols := make([]float64, 20)
+		for i, b := range window[n-21 : n-1] {
+			vols[i] = b.Volume
+		}
+		if sd := stddev(vols); sd > 0 {
+			values["volume_z_20"] = (cur.Volume - mean(vols)) / sd
+		}
+	}
+
+	// Microstructure of the bar itself
+	if cur.Close > 0 {
+		values["range_pct"] = (cur.High - cur.Low) / cur.Close * 100
+	}
+	values["close_location"] = 0.5
+	if cur.High > cur.Low {
+		values["close_location"] = (cur.Close - cur.Low) / (cur.High - cur.Low)
+	}
+	if n > 1 && closes[n-2] > 0 && cur.Open > 0 {
+		values["gap_pct"] = (cur.Open/closes[n-2] - 1) * 100
+	}
+	if cur.VWAP > 0 {
+		values["vwap_gap_pct"] = (cur.Close/cur.VWAP - 1) * 100
+	}
+
+	regime := ""
+	switch {
+	case ok20 && ok60 && vol60 > 0 && vol20/vol60 > volatileRatio:
+		regime = RegimeVolatile
+	case okER && efficiency >= trendEfficiency:
+		regime = RegimeTrendingUp
+		if closes[n-1] < closes[n-21] {
+			regime = RegimeTrendingDown
+		}
+	case okER:
+		regime = RegimeRanging
+	}
+	return values, regime
+}
+
+// realizedVol is the annualized standard deviation, in percent, of the
+// last k log returns of closes.
+func realizedVol(closes []float64, k int, periodsPerYear float64) (float64, bool) {
+	n := len(closes)
+	if n <= k {
+		return 0, false
+	}
+	rets := make([]float64, 0, k)
+	for i := n - k; i < n; i++ {
+		if closes[i-1] <= 0 || closes[i] <= 0 {
+			return 0, false
+		}
+		rets = append(rets, math.Log(closes[i]/closes[i-1]))
+	}
+	return stddev(rets) * math.Sqrt(periodsPerYear) * 100, true
+}
+
+// efficiencyRatio is the net move over the last k bars as a fraction of
+// the distance travelled, 0 for a flat series.
+func efficiencyRatio(closes []float64, k int) (float64, bool) {
+	n := len(closes)
+	if n <= k {
+		return 0, false
+	}
+	var path float64
+	for i := n - k; i < n; i++ {
+		path += math.Abs(closes[i] - closes[i-1])
+	}
+	if path == 0 {
+		return 0, true
+	}
+	return math.Abs(closes[n-1]-closes[n-1-k]) / path, true
+}
+
+func mean(xs []float64) float64 {
+	var sum float64
+	for _, x := range xs {
+		sum += x
+	}
+	return sum / float64(len(xs))
+}
+
+// stddev is the sample standard deviation of xs.
+func stddev(xs []float64) float64 {
+	if len(xs) < 2 {
+		return 0
+	}
+	m := mean(xs)
+	var ss float64
+	for _, x := range xs {
+		ss += (x - m) * (x - m)
+	}
+	return math.Sqrt(ss / float64(len(xs)-1))
+}
+
+// Names returns the catalog's feature names, sorted.
+func Names() []string {
+	names := make([]string, len(Catalog))
+	for i, d := range Catalog {
+		names[i] = d.Name
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,223 @@
+package featurestore
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/calendar"
+)
+
+// dailyBars returns n daily bars on consecutive sessions ending on the
+// session before last, closing at close(i).
+func dailyBars(cal *calendar.Calendar, last time.Time, n int, close func(i int) float64) []Bar {
+	days := make([]time.Time, n)
+	d := last
+	for i := n - 1; i >= 0; i-- {
+		d = cal.PreviousSession(d).Date
+		days[i] = d
+	}
+	bars := make([]Bar, n)
+	for i, day := range days {
+		c := close(i)
+		bars[i] = Bar{Time: day, Open: c - 0.5, High: c + 1, Low: c - 1, Close: c, Volume: 1000 + float64(i%3)*100}
+	}
+	return bars
+}
+
+func encode(t *testing.T, rows []Row) string {
+	t.Helper()
+	data, err := json.Marshal(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func newStore(t *testing.T, now time.Time) (*Store, *calendar.Calendar, string) {
+	t.Helper()
+	cal := calendar.New()
+	dir := t.TempDir()
+	s, err := New(dir, cal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetClock(func() time.Time { return now })
+	return s, cal, dir
+}
+
+func TestFeaturesUseOnlyPastBars(t *testing.T) {
+	cal := calendar.New()
+	// Wednesday 2026-09-16 at noon, mid-session
+	now := time.Date(2026, 9, 16, 12, 0, 0, 0, cal.Location())
+	bars := dailyBars(cal, now, 80, func(i int) float64 { return 100 + float64(i) })
+
+	whole, _, _ := newStore(t, now)
+	if added, err := whole.Append("AAPL", "1D", bars); err != nil || added != 80 {
+		t.Fatalf("append = %d, %v", added, err)
+	}
+	// The same bars one at a time, as they stream in, give the same rows
+	streamed, _, _ := newStore(t, now)
+	for _, b := range bars {
+		streamed.Append("aapl", "1D", []Bar{b})
+	}
+	a, _ := whole.Range("AAPL", "1D", time.Time{}, time.Time{}, time.Time{})
+	b, _ := streamed.Range("AAPL", "1D", time.Time{}, time.Time{}, time.Time{})
+	for i := range a {
+		a[i].ComputedAt, b[i].ComputedAt = time.Time{}, time.Time{}
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Fatal("streamed rows differ from rows appended at once")
+	}
+
+	row := a[len(a)-1]
+	if got := row.Values["return_1"]; math.Abs(got-(179.0/178-1)) > 1e-12 {
+		t.Errorf("return_1 = %v", got)
+	}
+	if row.Values["rsi_14"] != 100 || row.Regime != RegimeTrendingUp {
+		t.Errorf("steady rise: rsi %v, regime %s", row.Values["rsi_14"], row.Regime)
+	}
+	if len(row.Values) != len(Catalog)-1 { // no VWAP on these bars
+		t.Errorf("values = %v", row.Values)
+	}
+	// The first bar has no history: only the bar's own features
+	if first := a[0].Values; len(first) != 2 || first["close_location"] != 0.5 {
+		t.Errorf("first row = %v", first)
+	}
+	for _, name := range Names() {
+		found := false
+		for _, d := range Catalog {
+			found = found || d.Name == name
+		}
+		if !found {
+			t.Errorf("%s not in the catalog", name)
+		}
+	}
+}
+
+func TestReadsArePointInTime(t *testing.T) {
+	cal := calendar.New()
+	now := time.Date(2026, 9, 16, 12, 0, 0, 0, cal.Location())
+	s, _, _ := newStore(t, now)
+
+	// Today's bar is still forming at noon and is not stored
+	bars := dailyBars(cal, now, 30, func(i int) float64 { return 50 + float64(i%5) })
+	today := Bar{Time: time.Date(2026, 9, 16, 0, 0, 0, 0, cal.Location()), Open: 60, High: 61, Low: 59, Close: 60, Volume: 900}
+	if added, _ := s.Append("MSFT", "1D", append(bars, today)); added != 30 {
+		t.Fatalf("added %d, want 30", added)
+	}
+
+	latest, ok := s.Latest("MSFT", "1D")
+	if !ok || !latest.Bar.Time.Equal(bars[29].Time) {
+		t.Fatalf("latest = %v, %v", latest.Bar.Time, ok)
+	}
+	// Before yesterday's close only the day before is known
+	yesterday := bars[29].Time
+	row, ok, _ := s.AsOf("MSFT", "1D", yesterday.Add(15*time.Hour))
+	if !ok || !row.Bar.Time.Equal(bars[28].Time) {
+		t.Errorf("as of yesterday 15:00 = %v", row.Bar.Time)
+	}
+	if row, _, _ := s.AsOf("MSFT", "1D", yesterday.Add(16*time.Hour)); !row.Bar.Time.Equal(yesterday) {
+		t.Errorf("as of yesterday's close = %v", row.Bar.Time)
+	}
+	// A read in the future is a read now
+	if row, _, _ := s.AsOf("MSFT", "1D", now.AddDate(0, 0, 7)); !row.Bar.Time.Equal(yesterday) {
+		t.Errorf("as of next week = %v", row.Bar.Time)
+	}
+	if _, ok, _ := s.AsOf("MSFT", "1D", bars[0].Time); ok {
+		t.Error("row read before its bar closed")
+	}
+
+	rows, _ := s.Range("MSFT", "1D", bars[10].Time, time.Time{}, yesterday.Add(15*time.Hour))
+	if len(rows) != 19 || !rows[0].Bar.Time.Equal(bars[10].Time) {
+		t.Errorf("range as of yesterday 15:00 = %d rows", len(rows))
+	}
+
+	// Once the session closes the bar is stored
+	s.SetClock(func() time.Time { return now.Add(5 * time.Hour) })
+	if added, _ := s.Append("MSFT", "1D", []Bar{today}); added != 1 {
+		t.Errorf("closed bar added %d", added)
+	}
+
+	if _, _, err := s.AsOf("../etc", "1D", time.Time{}); err == nil {
+		t.Error("path in symbol accepted")
+	}
+	if _, _, err := s.AsOf("MSFT", "1W", time.Time{}); err == nil {
+		t.Error("unknown timeframe accepted")
+	}
+}
+
+func TestRowsAreWrittenOnce(t *testing.T) {
+	cal := calendar.New()
+	now := time.Date(2026, 9, 16, 12, 0, 0, 0, cal.Location())
+	s, _, dir := newStore(t, now)
+	bars := dailyBars(cal, now, 25, func(i int) float64 { return 20 + float64(i) })
+	s.Append("SPY", "1D", bars[:20])
+
+	// A revised bar already stored is ignored, however it differs
+	revised := bars[19]
+	revised.Close = 999
+	if added, _ := s.Append("SPY", "1D", append([]Bar{revised}, bars[20:]...)); added != 5 {
+		t.Fatalf("added %d, want 5", added)
+	}
+	before, _ := s.Range("SPY", "1D", time.Time{}, time.Time{}, time.Time{})
+	if before[19].Bar.Close != bars[19].Close {
+		t.Errorf("stored bar rewritten: %v", before[19].Bar.Close)
+	}
+
+	// A line cut short by a crash is dropped and the bar recomputed
+	path := s.path(Series{Symbol: "SPY", Timeframe: "1D"})
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString(`{"symbol":"SPY","timef`)
+	f.Close()
+
+	reopened, err := New(dir, cal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened.SetClock(func() time.Time { return now })
+	after, _ := reopened.Range("SPY", "1D", time.Time{}, time.Time{}, time.Time{})
+	if a, b := encode(t, before), encode(t, after); a != b {
+		t.Errorf("rows differ after reopening:\n%s\n%s", a, b)
+	}
+	if got := reopened.Stored(); len(got) != 1 || got[0] != (Series{Symbol: "SPY", Timeframe: "1D"}) {
+		t.Errorf("stored = %v", got)
+	}
+}
+
+func TestBackfillResumesFromLastRow(t *testing.T) {
+	cal := calendar.New()
+	now := time.Date(2026, 9, 16, 12, 0, 0, 0, cal.Location())
+	s, _, _ := newStore(t, now)
+	bars := dailyBars(cal, now, 40, func(i int) float64 { return 10 + float64(i%7) })
+
+	if _, err := s.Backfill(context.Background(), "QQQ", "1D", bars[0].Time); err != ErrNoHistory {
+		t.Errorf("backfill without history: %v", err)
+	}
+	var fetchedFrom []time.Time
+	s.SetHistory(func(ctx context.Context, symbol, tf string, from, to time.Time) ([]Bar, error) {
+		fetchedFrom = append(fetchedFrom, from)
+		var out []Bar
+		for _, b := range bars {
+			if !b.Time.Before(from) && b.Time.Before(to) {
+				out = append(out, b)
+			}
+		}
+		return out, nil
+	})
+	s.Append("QQQ", "1D", bars[:25])
+	added, err := s.Backfill(context.Background(), "QQQ", "1D", bars[0].Time)
+	if err != nil || added != 15 {
+		t.Fatalf("backfill = %d, %v", added, err)
+	}
+	if !fetchedFrom[0].Equal(bars[24].Time) {
+		t.Errorf("fetched from %v, want the last stored bar", fetchedFrom[0])
+	}
+	if err := s.Sync(context.Background(), []string{"QQQ"}, 90); err != nil {
+		t.Error(err)
+	}
+}
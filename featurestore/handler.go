@@ -0,0 +1,175 @@
+package featurestore
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Handler exposes the feature store over HTTP.
+type Handler struct {
+	store *Store
+}
+
+// NewHandler creates a handler for store.
+func NewHandler(store *Store) *Handler {
+	return &Handler{store: store}
+}
+
+// RegisterRoutes registers the feature routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/features - the feature catalog, its version and the series stored
+	mux.HandleFunc("/api/features", h.handleCatalog)
+
+	// GET /api/features/{symbol}?timeframe=1D&as_of= - the row of the latest bar closed by as_of (RFC3339, default now)
+	mux.HandleFunc("/api/features/{symbol}", h.handleRow)
+
+	// GET /api/features/{symbol}/history?timeframe=1D&from=&to=&as_of=&format=csv - rows of bars from from to to, as known at as_of
+	mux.HandleFunc("/api/features/{symbol}/history", h.handleHistory)
+
+	// POST /api/features/{symbol}/backfill - fetch and store rows, {"timeframe", "from"}
+	mux.HandleFunc("/api/features/{symbol}/backfill", h.handleBackfill)
+}
+
+func (h *Handler) handleCatalog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"version":  Version,
+		"lookback": Lookback,
+		"features": Catalog,
+		"regimes":  []string{RegimeTrendingUp, RegimeTrendingDown, RegimeRanging, RegimeVolatile},
+		"series":   h.store.Stored(),
+	})
+}
+
+// timeParam parses an optional RFC3339 or YYYY-MM-DD query parameter.
+func timeParam(r *http.Request, name string) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.New(name + " must be RFC3339 or YYYY-MM-DD")
+}
+
+// timeframeParam is the timeframe query parameter, daily by default.
+func timeframeParam(r *http.Request) string {
+	if tf := r.URL.Query().Get("timeframe"); tf != "" {
+		return tf
+	}
+	return "1D"
+}
+
+func (h *Handler) handleRow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	asOf, err := timeParam(r, "as_of")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	row, ok, err := h.store.AsOf(r.PathValue("symbol"), timeframeParam(r), asOf)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !ok {
+		http.Error(w, "no features stored for "+r.PathValue("symbol"), http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(row)
+}
+
+func (h *Handler) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var times [3]time.Time
+	for i, name := range []string{"from", "to", "as_of"} {
+		t, err := timeParam(r, name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		times[i] = t
+	}
+	rows, err := h.store.Range(r.PathValue("symbol"), timeframeParam(r), times[0], times[1], times[2])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if r.URL.Query().Get("format") == "csv" {
+		writeCSV(w, rows)
+		return
+	}
+	json.NewEncoder(w).Encode(rows)
+}
+
+// writeCSV writes rows one per line for training, the features in name
+// order and blank where a row lacks one.
+func writeCSV(w http.ResponseWriter, rows []Row) {
+	w.Header().Set("Content-Type", "text/csv")
+	names := Names()
+	out := csv.NewWriter(w)
+	out.Write(append([]string{"symbol", "timeframe", "time", "available_at", "close", "regime"}, names...))
+	for _, row := range rows {
+		record := []string{row.Symbol, row.Timeframe, row.Bar.Time.UTC().Format(time.RFC3339), row.AvailableAt.UTC().Format(time.RFC3339),
+			strconv.FormatFloat(row.Bar.Close, 'f', -1, 64), row.Regime}
+		for _, name := range names {
+			v, ok := row.Values[name]
+			if !ok {
+				record = append(record, "")
+				continue
+			}
+			record = append(record, strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		out.Write(record)
+	}
+	out.Flush()
+}
+
+func (h *Handler) handleBackfill(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Timeframe string    `json:"timeframe"`
+		From      time.Time `json:"from"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Timeframe == "" {
+		req.Timeframe = "1D"
+	}
+	if req.From.IsZero() {
+		http.Error(w, "from is required", http.StatusBadRequest)
+		return
+	}
+	added, err := h.store.Backfill(r.Context(), r.PathValue("symbol"), req.Timeframe, req.From)
+	switch {
+	case errors.Is(err, ErrNoHistory):
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "added": added})
+}
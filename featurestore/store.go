@@ -0,0 +1,410 @@
+package featurestore
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/calendar"
+)
+
+func logger() *slog.Logger { return slog.With("module", "featurestore") }
+
+// ErrNoHistory is returned by Backfill without a history source.
+var ErrNoHistory = errors.New("no history source")
+
+// timeframe is a bar length rows are kept for.
+type timeframe struct {
+	length time.Duration // zero for sessions
+	// perYear is the bars in a year, to annualize volatility
+	perYear float64
+}
+
+// timeframes are the bar lengths, named as the algorithm's history names
+// them.
+var timeframes = map[string]timeframe{
+	"1Min":  {time.Minute, 252 * 390},
+	"5Min":  {5 * time.Minute, 252 * 78},
+	"15Min": {15 * time.Minute, 252 * 26},
+	"1H":    {time.Hour, 252 * 6.5},
+	"1D":    {0, 252},
+}
+
+var symbolPattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9.\-]{0,15}$`)
+
+// History fetches bars of timeframe between from and to, used to backfill.
+type History func(ctx context.Context, symbol, timeframe string, from, to time.Time) ([]Bar, error)
+
+// Row is the features of one bar.
+type Row struct {
+	Symbol    string `json:"symbol"`
+	Timeframe string `json:"timeframe"`
+	Bar       Bar    `json:"bar"`
+	// AvailableAt is when the bar closed. No read before it returns the
+	// row.
+	AvailableAt time.Time          `json:"available_at"`
+	Values      map[string]float64 `json:"values"` // by Catalog name; features lacking history are absent
+	Regime      string             `json:"regime,omitempty"`
+	Version     int                `json:"version"`
+	ComputedAt  time.Time          `json:"computed_at"`
+}
+
+// Series names a symbol's rows of one timeframe.
+type Series struct {
+	Symbol    string `json:"symbol"`
+	Timeframe string `json:"timeframe"`
+}
+
+// Store keeps rows in a JSON-lines file per symbol and timeframe under a
+// directory per Version. Files are loaded when first read. It is safe for
+// concurrent use.
+type Store struct {
+	dir string
+	cal *calendar.Calendar
+
+	mu      sync.Mutex
+	series  map[Series][]Row // in bar order
+	history History
+	now     func() time.Time
+}
+
+// New opens the store in dir.
+func New(dir string, cal *calendar.Calendar) (*Store, error) {
+	dir = filepath.Join(dir, fmt.Sprintf("v%d", Version))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create features directory: %w", err)
+	}
+	return &Store{dir: dir, cal: cal, series: make(map[Series][]Row), now: time.Now}, nil
+}
+
+// SetClock replaces the clock, for replays and tests. Rows whose bars
+// close after it are neither stored nor read.
+func (s *Store) SetClock(now func() time.Time) { s.mu.Lock(); s.now = now; s.mu.Unlock() }
+
+// SetHistory sets where Backfill fetches bars.
+func (s *Store) SetHistory(h History) { s.mu.Lock(); s.history = h; s.mu.Unlock() }
+
+// key validates symbol and tf and returns their series.
+func key(symbol, tf string) (Series, timeframe, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if !symbolPattern.MatchString(symbol) || strings.Contains(symbol, "..") {
+		return Series{}, timeframe{}, fmt.Errorf("invalid symbol %q", symbol)
+	}
+	t, ok := timeframes[tf]
+	if !ok {
+		return Series{}, timeframe{}, fmt.Errorf("unknown timeframe %q: use 1Min, 5Min, 15Min, 1H or 1D", tf)
+	}
+	return Series{Symbol: symbol, Timeframe: tf}, t, nil
+}
+
+func (s *Store) path(k Series) string {
+	return filepath.Join(s.dir, k.Symbol, k.Timeframe+".jsonl")
+}
+
+// rowsLocked returns k's rows, loading them on first use.
+func (s *Store) rowsLocked(k Series) ([]Row, error) {
+	if rows, ok := s.series[k]; ok {
+		return rows, nil
+	}
+	rows := []Row{}
+	f, err := os.Open(s.path(k))
+	if os.IsNotExist(err) {
+		s.series[k] = rows
+		return rows, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open features: %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var row Row
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			// A line cut short by a crash is dropped; the bar is
+			// recomputed from the rows before it
+			logger().Warn("Skipping unreadable feature row", "symbol", k.Symbol, "timeframe", k.Timeframe, "error", err)
+			continue
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read features: %w", err)
+	}
+	s.series[k] = rows
+	return rows, nil
+}
+
+// availableAt is when a bar of t starting at start closes: the session's
+// close for daily bars.
+func (s *Store) availableAt(start time.Time, t timeframe) time.Time {
+	if t.length > 0 {
+		return start.Add(t.length)
+	}
+	if session, ok := s.cal.SessionFor(start); ok {
+		return session.Close
+	}
+	return start.Add(24 * time.Hour)
+}
+
+// Append computes and stores rows for bars after symbol's last stored
+// bar, returning how many were added. Bars at or before it are ignored, as
+// are bars that have not closed by the store's clock, so a row is never
+// rewritten or computed from a forming bar.
+func (s *Store) Append(symbol, tf string, bars []Bar) (int, error) {
+	k, t, err := key(symbol, tf)
+	if err != nil {
+		return 0, err
+	}
+	bars = append([]Bar(nil), bars...)
+	sort.Slice(bars, func(i, j int) bool { return bars[i].Time.Before(bars[j].Time) })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows, err := s.rowsLocked(k)
+	if err != nil {
+		return 0, err
+	}
+	now := s.now()
+	stored := len(rows)
+	for _, b := range bars {
+		if b.Time.IsZero() || b.Close <= 0 {
+			continue
+		}
+		if n := len(rows); n > 0 && !b.Time.After(rows[n-1].Bar.Time) {
+			continue
+		}
+		available := s.availableAt(b.Time, t)
+		if available.After(now) {
+			continue
+		}
+		// The bar and the ones before it, never anything later
+		from := len(rows) - (Lookback - 1)
+		if from < 0 {
+			from = 0
+		}
+		window := make([]Bar, 0, Lookback)
+		for _, r := range rows[from:] {
+			window = append(window, r.Bar)
+		}
+		window = append(window, b)
+		values, regime := compute(window, t.perYear)
+		rows = append(rows, Row{
+			Symbol:      k.Symbol,
+			Timeframe:   k.Timeframe,
+			Bar:         b,
+			AvailableAt: available,
+			Values:      values,
+			Regime:      regime,
+			Version:     Version,
+			ComputedAt:  now,
+		})
+	}
+	added := rows[stored:]
+	if len(added) == 0 {
+		return 0, nil
+	}
+	if err := s.writeLocked(k, added); err != nil {
+		return 0, err
+	}
+	s.series[k] = rows
+	return len(added), nil
+}
+
+// writeLocked appends rows to k's file.
+func (s *Store) writeLocked(k Series, rows []Row) error {
+	path := s.path(k)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create features directory: %w", err)
+	}
+	var buf []byte
+	for _, r := range rows {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return fmt.Errorf("failed to encode features: %w", err)
+		}
+		buf = append(append(buf, line...), '\n')
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open features: %w", err)
+	}
+	// Start a fresh line after one a crash cut short
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err := f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			buf = append([]byte{'\n'}, buf...)
+		}
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write features: %w", err)
+	}
+	return f.Close()
+}
+
+// Backfill fetches symbol's bars from from, or from its last stored bar
+// when that is later, up to the store's clock and appends them. Rows are
+// only ever added after the last one; to start a series earlier, remove
+// its file.
+func (s *Store) Backfill(ctx context.Context, symbol, tf string, from time.Time) (int, error) {
+	k, _, err := key(symbol, tf)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	history, now := s.history, s.now()
+	rows, err := s.rowsLocked(k)
+	s.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	if history == nil {
+		return 0, ErrNoHistory
+	}
+	if n := len(rows); n > 0 && rows[n-1].Bar.Time.After(from) {
+		from = rows[n-1].Bar.Time
+	}
+	if !from.Before(now) {
+		return 0, nil
+	}
+	bars, err := history(ctx, k.Symbol, tf, from, now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch %s history: %w", k.Symbol, err)
+	}
+	return s.Append(k.Symbol, tf, bars)
+}
+
+// Sync backfills each of symbols' daily rows over the last lookbackDays,
+// for the scheduled refresh after the close. Failures are logged and
+// returned together.
+func (s *Store) Sync(ctx context.Context, symbols []string, lookbackDays int) error {
+	s.mu.Lock()
+	from := s.now().AddDate(0, 0, -lookbackDays)
+	s.mu.Unlock()
+	var failed []string
+	for _, symbol := range symbols {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		added, err := s.Backfill(ctx, symbol, "1D", from)
+		if err != nil {
+			logger().Warn("Failed to backfill features", "symbol", symbol, "error", err)
+			failed = append(failed, symbol)
+			continue
+		}
+		logger().Debug("Backfilled features", "symbol", symbol, "rows", added)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to backfill features for %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// AsOf returns symbol's row for the latest bar closed by at, or by the
+// store's clock when at is later or zero.
+func (s *Store) AsOf(symbol, tf string, at time.Time) (Row, bool, error) {
+	k, _, err := key(symbol, tf)
+	if err != nil {
+		return Row{}, false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows, err := s.rowsLocked(k)
+	if err != nil {
+		return Row{}, false, err
+	}
+	cutoff := s.cutoffLocked(at)
+	i := sort.Search(len(rows), func(i int) bool { return rows[i].AvailableAt.After(cutoff) })
+	if i == 0 {
+		return Row{}, false, nil
+	}
+	return rows[i-1], true, nil
+}
+
+// Latest returns symbol's row for the latest bar closed by the store's
+// clock.
+func (s *Store) Latest(symbol, tf string) (Row, bool) {
+	row, ok, err := s.AsOf(symbol, tf, time.Time{})
+	if err != nil {
+		logger().Warn("Failed to read features", "symbol", symbol, "timeframe", tf, "error", err)
+	}
+	return row, ok
+}
+
+// Range returns symbol's rows for bars starting between from and to,
+// inclusive, that had closed by asOf: the rows as they could have been
+// read at asOf. A zero to or asOf, or one after the store's clock, means
+// the clock.
+func (s *Store) Range(symbol, tf string, from, to, asOf time.Time) ([]Row, error) {
+	k, _, err := key(symbol, tf)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows, err := s.rowsLocked(k)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := s.cutoffLocked(asOf)
+	if to.IsZero() || to.After(cutoff) {
+		to = cutoff
+	}
+	out := []Row{}
+	for i := sort.Search(len(rows), func(i int) bool { return !rows[i].Bar.Time.Before(from) }); i < len(rows); i++ {
+		r := rows[i]
+		if r.Bar.Time.After(to) || r.AvailableAt.After(cutoff) {
+			break
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// cutoffLocked is the latest time a read at at may see: at, but never
+// past the clock.
+func (s *Store) cutoffLocked(at time.Time) time.Time {
+	now := s.now()
+	if at.IsZero() || at.After(now) {
+		return now
+	}
+	return at
+}
+
+// Stored lists the series with rows on disk, by symbol and timeframe.
+func (s *Store) Stored() []Series {
+	out := []Series{}
+	symbols, err := os.ReadDir(s.dir)
+	if err != nil {
+		return out
+	}
+	for _, sym := range symbols {
+		if !sym.IsDir() {
+			continue
+		}
+		files, _ := os.ReadDir(filepath.Join(s.dir, sym.Name()))
+		for _, f := range files {
+			if tf, ok := strings.CutSuffix(f.Name(), ".jsonl"); ok {
+				out = append(out, Series{Symbol: sym.Name(), Timeframe: tf})
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Symbol != out[j].Symbol {
+			return out[i].Symbol < out[j].Symbol
+		}
+		return out[i].Timeframe < out[j].Timeframe
+	})
+	return out
+}
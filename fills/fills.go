@@ -0,0 +1,356 @@
+// Package fills measures execution quality. Every order placed is tracked
+// from submission — with the bid and ask at that moment — until the broker
+// reports it finished, following replacements as chased limits are
+// repriced, and the outcome is journaled. Reports over the journal compare
+// market and limit orders by effective spread paid, price improvement,
+// fill rate and time to fill, per symbol and time of day.
+package fills
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/rileyseaburg/go-trader/algorithm"
+)
+
+func logger() *slog.Logger { return slog.With("module", "fills") }
+
+// Outcomes of a tracked order.
+const (
+	OutcomeFilled   = "filled"
+	OutcomePartial  = "partial"  // finished with some but not all filled
+	OutcomeUnfilled = "unfilled" // canceled, expired or rejected with nothing filled
+)
+
+// maxRecords bounds the records kept in memory for reports; the journal
+// keeps everything.
+const maxRecords = 20000
+
+// maxPendingAge is how long an order is followed before it is given up on.
+const maxPendingAge = 7 * 24 * time.Hour
+
+// Broker reads orders.
+type Broker interface {
+	GetOrder(orderID string) (*alpaca.Order, error)
+}
+
+// QuoteSource returns the latest bid and ask for symbol.
+type QuoteSource func(symbol string) (bid, ask float64, ok bool)
+
+// Record is one order from submission to finish.
+type Record struct {
+	OrderID     string     `json:"order_id"` // as first submitted
+	FinalID     string     `json:"final_id,omitempty"`
+	Symbol      string     `json:"symbol"`
+	Side        string     `json:"side"`
+	Type        string     `json:"type"`
+	Execution   string     `json:"execution,omitempty"`
+	Tag         string     `json:"tag,omitempty"`
+	Qty         float64    `json:"qty"`
+	LimitPrice  float64    `json:"limit_price,omitempty"` // as first submitted
+	StopPrice   float64    `json:"stop_price,omitempty"`
+	Bid         float64    `json:"bid,omitempty"` // at submission
+	Ask         float64    `json:"ask,omitempty"` // at submission
+	Replaces    int        `json:"replaces,omitempty"`
+	Resubmits   int        `json:"resubmits,omitempty"` // remainders placed again after a partial fill
+	SubmittedAt time.Time  `json:"submitted_at"`
+	FilledQty   float64    `json:"filled_qty"`
+	FillPrice   float64    `json:"fill_price,omitempty"` // average over every replacement
+	FilledAt    *time.Time `json:"filled_at,omitempty"`
+	Outcome     string     `json:"outcome"`
+	Status      string     `json:"status"` // the broker's final status
+	FinishedAt  time.Time  `json:"finished_at"`
+
+	// fill value of replaced and resubmitted orders, folded into FillPrice
+	doneQty, doneValue float64
+}
+
+// Tracker follows orders until they finish and journals them. It is safe
+// for concurrent use.
+type Tracker struct {
+	broker Broker
+	quotes QuoteSource
+	loc    *time.Location
+
+	mu      sync.Mutex
+	pending map[string]*Record // by current order ID
+	records []Record
+	journal *os.File
+	onFill  func(Record)
+	onDone  func(Record)
+}
+
+// New opens the journal at path, loading it for reports. Times of day are
+// reported in loc.
+func New(path string, broker Broker, quotes QuoteSource, loc *time.Location) (*Tracker, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create fills journal directory: %w", err)
+	}
+	t := &Tracker{broker: broker, quotes: quotes, loc: loc, pending: make(map[string]*Record)}
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			var r Record
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				logger().Warn("Skipping malformed fills journal line", "error", err)
+				continue
+			}
+			t.remember(r)
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read fills journal: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to open fills journal: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open fills journal for writing: %w", err)
+	}
+	t.journal = f
+	return t, nil
+}
+
+// Track starts following order, placed with the given execution strategy,
+// and notes the quote at submission. Call it right after placing.
+func (t *Tracker) Track(order *alpaca.Order, execution string) {
+	if order == nil || order.ID == "" {
+		return
+	}
+	r := &Record{
+		OrderID:     order.ID,
+		Symbol:      strings.ToUpper(order.Symbol),
+		Side:        string(order.Side),
+		Type:        string(order.Type),
+		Execution:   execution,
+		Tag:         algorithm.TagFromClientOrderID(order.ClientOrderID),
+		SubmittedAt: order.SubmittedAt,
+	}
+	if order.Qty != nil {
+		r.Qty, _ = order.Qty.Float64()
+	}
+	if order.LimitPrice != nil {
+		r.LimitPrice, _ = order.LimitPrice.Float64()
+	}
+	if order.StopPrice != nil {
+		r.StopPrice, _ = order.StopPrice.Float64()
+	}
+	if r.SubmittedAt.IsZero() {
+		r.SubmittedAt = time.Now()
+	}
+	if t.quotes != nil {
+		if bid, ask, ok := t.quotes(r.Symbol); ok && bid > 0 && ask >= bid {
+			r.Bid, r.Ask = bid, ask
+		}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.pending[order.ID] = r
+}
+
+// Resubmitted carries the record of prevID, an order that ended with
+// filledQty filled at avgPrice, over to order, placed for its remainder, so
+// the journal keeps one record for the whole quantity as it does for
+// replacements. When prevID is no longer pending order is tracked on its
+// own.
+func (t *Tracker) Resubmitted(prevID string, filledQty, avgPrice float64, order *alpaca.Order, execution string) {
+	if order == nil || order.ID == "" {
+		return
+	}
+	t.mu.Lock()
+	r, ok := t.pending[prevID]
+	if !ok {
+		t.mu.Unlock()
+		t.Track(order, execution)
+		return
+	}
+	r.doneQty += filledQty
+	r.doneValue += filledQty * avgPrice
+	r.Resubmits++
+	delete(t.pending, prevID)
+	t.pending[order.ID] = r
+	t.mu.Unlock()
+}
+
+// Pending returns the orders still being followed, oldest first.
+func (t *Tracker) Pending() []Record {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]Record, 0, len(t.pending))
+	for _, r := range t.pending {
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].SubmittedAt.Before(out[j].SubmittedAt) })
+	return out
+}
+
+// SetFillHandler sets a function called with every journaled record that
+// filled at least partly.
+func (t *Tracker) SetFillHandler(fn func(Record)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onFill = fn
+}
+
+// SetDoneHandler sets a function called with every journaled record,
+// filled or not.
+func (t *Tracker) SetDoneHandler(fn func(Record)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onDone = fn
+}
+
+// Step reads every pending order from the broker once, following
+// replacements and journaling the ones that finished.
+func (t *Tracker) Step(now time.Time) {
+	t.mu.Lock()
+	ids := make([]string, 0, len(t.pending))
+	for id := range t.pending {
+		ids = append(ids, id)
+	}
+	t.mu.Unlock()
+
+	for _, id := range ids {
+		order, err := t.broker.GetOrder(id)
+		if err != nil {
+			logger().Warn("Failed to read tracked order", "order_id", id, "error", err)
+			t.mu.Lock()
+			if r, ok := t.pending[id]; ok && now.Sub(r.SubmittedAt) > maxPendingAge {
+				delete(t.pending, id)
+			}
+			t.mu.Unlock()
+			continue
+		}
+		r, done := t.update(id, order, now)
+		if !done {
+			continue
+		}
+		t.mu.Lock()
+		onFill, onDone := t.onFill, t.onDone
+		t.mu.Unlock()
+		if onFill != nil && r.FilledQty > 0 {
+			onFill(r)
+		}
+		if onDone != nil {
+			onDone(r)
+		}
+	}
+}
+
+// update applies the broker's view of order, tracked as id, and returns the
+// record if the order finished.
+func (t *Tracker) update(id string, order *alpaca.Order, now time.Time) (Record, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r, ok := t.pending[id]
+	if !ok {
+		return Record{}, false
+	}
+	filled, _ := order.FilledQty.Float64()
+	price := 0.0
+	if order.FilledAvgPrice != nil {
+		price, _ = order.FilledAvgPrice.Float64()
+	}
+
+	status := strings.ToLower(order.Status)
+	if status == "replaced" && order.ReplacedBy != nil {
+		// The replacement carries the rest of the order
+		r.doneQty += filled
+		r.doneValue += filled * price
+		r.Replaces++
+		delete(t.pending, id)
+		t.pending[*order.ReplacedBy] = r
+		return Record{}, false
+	}
+	switch status {
+	case "filled", "canceled", "expired", "rejected", "done_for_day", "replaced", "stopped", "suspended":
+	default:
+		return Record{}, false
+	}
+
+	delete(t.pending, id)
+	r.FinalID = order.ID
+	r.FilledQty = r.doneQty + filled
+	if r.FilledQty > 0 {
+		r.FillPrice = (r.doneValue + filled*price) / r.FilledQty
+	}
+	r.FilledAt = order.FilledAt
+	if r.FilledAt == nil && r.FilledQty > 0 {
+		r.FilledAt = &order.UpdatedAt
+	}
+	r.Status = status
+	r.FinishedAt = now
+	switch {
+	case r.FilledQty <= 0:
+		r.Outcome = OutcomeUnfilled
+	case r.Qty > 0 && r.FilledQty < r.Qty:
+		r.Outcome = OutcomePartial
+	default:
+		r.Outcome = OutcomeFilled
+	}
+	t.remember(*r)
+	if line, err := json.Marshal(r); err != nil {
+		logger().Error("Failed to encode fill record", "order_id", r.OrderID, "error", err)
+	} else if _, err := t.journal.Write(append(line, '\n')); err != nil {
+		logger().Error("Failed to write fill record", "order_id", r.OrderID, "error", err)
+	}
+	return *r, true
+}
+
+func (t *Tracker) remember(r Record) {
+	t.records = append(t.records, r)
+	if len(t.records) > maxRecords {
+		t.records = t.records[len(t.records)-maxRecords:]
+	}
+}
+
+// Records returns finished records submitted at or after since, oldest
+// first.
+func (t *Tracker) Records(since time.Time) []Record {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := []Record{}
+	for _, r := range t.records {
+		if !r.SubmittedAt.Before(since) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Run reads pending orders every interval until ctx is cancelled.
+func (t *Tracker) Run(ctx context.Context, interval time.Duration) {
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-tick.C:
+			t.Step(now)
+		}
+	}
+}
+
+// Close closes the journal.
+func (t *Tracker) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.journal.Close()
+}
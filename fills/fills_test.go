@@ -0,0 +1,181 @@
+package fills
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
+)
+
+type fakeBroker struct {
+	orders map[string]*alpaca.Order
+}
+
+func (b *fakeBroker) GetOrder(id string) (*alpaca.Order, error) {
+	return b.orders[id], nil
+}
+
+func dec(v float64) *decimal.Decimal {
+	d := decimal.NewFromFloat(v)
+	return &d
+}
+
+func order(id, side string, typ alpaca.OrderType, qty float64, at time.Time) *alpaca.Order {
+	return &alpaca.Order{ID: id, Symbol: "AAPL", Side: alpaca.Side(side), Type: typ, Qty: dec(qty), Status: "new", SubmittedAt: at}
+}
+
+func fill(o *alpaca.Order, qty, price float64, at time.Time, status string) {
+	o.FilledQty = *dec(qty)
+	o.FilledAvgPrice = dec(price)
+	o.FilledAt = &at
+	o.Status = status
+}
+
+func TestTrackerFollowsReplacementsAndReports(t *testing.T) {
+	ny, _ := time.LoadLocation("America/New_York")
+	open := time.Date(2026, 3, 2, 9, 35, 0, 0, ny)
+	broker := &fakeBroker{orders: map[string]*alpaca.Order{}}
+	path := filepath.Join(t.TempDir(), "fills.jsonl")
+	tr, err := New(path, broker, func(string) (float64, float64, bool) { return 99.9, 100.1, true }, ny)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var filled, done []string
+	tr.SetFillHandler(func(r Record) { filled = append(filled, r.OrderID) })
+	tr.SetDoneHandler(func(r Record) { done = append(done, r.OrderID) })
+
+	// A market buy that paid the ask
+	mkt := order("m1", "buy", alpaca.Market, 10, open)
+	broker.orders["m1"] = mkt
+	tr.Track(mkt, "")
+
+	// A chased limit buy, repriced once: half filled at 99.95, the rest at 100.05
+	lim := order("l1", "buy", alpaca.Limit, 10, open)
+	broker.orders["l1"] = lim
+	tr.Track(lim, "chase")
+
+	// A limit sell that never filled
+	rest := order("l2", "sell", alpaca.Limit, 5, open.Add(2*time.Hour))
+	broker.orders["l2"] = rest
+	tr.Track(rest, "")
+
+	tr.Step(open.Add(time.Second))
+	if len(tr.Pending()) != 3 {
+		t.Fatalf("pending = %+v", tr.Pending())
+	}
+
+	fill(mkt, 10, 100.1, open.Add(time.Second), "filled")
+	fill(lim, 5, 99.95, open.Add(10*time.Second), "replaced")
+	next := "l1b"
+	lim.ReplacedBy = &next
+	lim2 := order("l1b", "buy", alpaca.Limit, 10, open.Add(15*time.Second))
+	broker.orders["l1b"] = lim2
+	rest.Status = "canceled"
+	tr.Step(open.Add(20 * time.Second))
+	if p := tr.Pending(); len(p) != 1 || p[0].OrderID != "l1" || p[0].Replaces != 1 {
+		t.Fatalf("pending = %+v", p)
+	}
+	fill(lim2, 5, 100.05, open.Add(30*time.Second), "filled")
+	tr.Step(open.Add(40 * time.Second))
+	tr.Close()
+	if len(filled) != 2 || filled[0] != "m1" || filled[1] != "l1" {
+		t.Errorf("fill handler saw %v", filled)
+	}
+	if len(done) != 3 || done[2] != "l1" {
+		t.Errorf("done handler saw %v", done)
+	}
+
+	// Reports are rebuilt from the journal
+	tr, err = New(path, broker, nil, ny)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+	r := tr.Report(Query{})
+	if r.Orders != 3 || len(r.ByType) != 3 {
+		t.Fatalf("report = %+v", r)
+	}
+	chase, passive, market := r.ByType[0].Stats, r.ByType[1].Stats, r.ByType[2].Stats
+	if r.ByType[0].Execution != "chase" || chase.Filled != 1 || chase.FillRate != 1 || chase.EffectiveSpreadBps != 0 ||
+		chase.PriceImprovementBps != 10 || chase.MedianTimeToFillSec != 30 {
+		t.Errorf("chase = %+v", r.ByType[0])
+	}
+	if r.ByType[2].Type != "market" || market.EffectiveSpreadBps != 20 || market.QuotedSpreadBps != 20 || market.PriceImprovementBps != 0 {
+		t.Errorf("market = %+v", r.ByType[2])
+	}
+	if passive.Unfilled != 1 || passive.FillRate != 0 || passive.Quoted != 0 {
+		t.Errorf("unfilled limit = %+v", r.ByType[1])
+	}
+	if len(r.ByTimeOfDay) != 3 || r.ByTimeOfDay[0].TimeOfDay != "09:30" || r.ByTimeOfDay[2].TimeOfDay != "11:30" {
+		t.Errorf("by time of day = %+v", r.ByTimeOfDay)
+	}
+	if got := tr.Report(Query{Type: "market"}); got.Orders != 1 {
+		t.Errorf("market only = %+v", got)
+	}
+}
+
+func TestTrackerCarriesResubmittedRemainder(t *testing.T) {
+	start := time.Date(2026, 3, 2, 15, 55, 0, 0, time.UTC)
+	broker := &fakeBroker{orders: map[string]*alpaca.Order{}}
+	tr, err := New(filepath.Join(t.TempDir(), "fills.jsonl"), broker, nil, time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+
+	// 4 of 10 fill before the day order expires; the other 6 go again
+	first := order("d1", "buy", alpaca.Limit, 10, start)
+	broker.orders["d1"] = first
+	tr.Track(first, "passive")
+	fill(first, 4, 100, start.Add(time.Minute), "expired")
+	rest := order("d2", "buy", alpaca.Limit, 6, start.Add(2*time.Minute))
+	broker.orders["d2"] = rest
+	tr.Resubmitted("d1", 4, 100, rest, "passive")
+
+	fill(rest, 6, 101, start.Add(3*time.Minute), "filled")
+	tr.Step(start.Add(4 * time.Minute))
+	got := tr.Records(start)
+	if len(got) != 1 || got[0].OrderID != "d1" || got[0].FinalID != "d2" || got[0].Resubmits != 1 {
+		t.Fatalf("records = %+v", got)
+	}
+	if r := got[0]; r.FilledQty != 10 || r.Outcome != OutcomeFilled || r.FillPrice < 100.59 || r.FillPrice > 100.61 {
+		t.Errorf("record = %+v", r)
+	}
+}
+
+func TestTradeOutcomesPairFirstInFirstOut(t *testing.T) {
+	t0 := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+	rec := func(tag, symbol, side string, qty, price float64, minutes int) Record {
+		at := t0.Add(time.Duration(minutes) * time.Minute)
+		return Record{Tag: tag, Symbol: symbol, Side: side, FilledQty: qty, FillPrice: price, FilledAt: &at, SubmittedAt: at}
+	}
+	outcomes := TradeOutcomes([]Record{
+		rec("momo", "aapl", "buy", 10, 100, 0),
+		rec("momo", "AAPL", "buy", 10, 110, 1),
+		// Out of order: closes the first lot and half the second
+		rec("momo", "AAPL", "sell", 15, 104, 3),
+		rec("momo", "AAPL", "sell", 5, 120, 2),
+		// Another strategy's lots pair separately
+		rec("", "AAPL", "sell", 5, 105, 4),
+		rec("", "AAPL", "buy", 5, 107, 5),
+		rec("momo", "MSFT", "buy", 3, 400, 6), // still open
+		{Tag: "momo", Symbol: "MSFT", Side: "sell", FilledQty: 0},
+	})
+	if len(outcomes) != 3 {
+		t.Fatalf("outcomes = %+v", outcomes)
+	}
+	// 5 × (120 − 100)
+	if o := outcomes[0]; o.PL != 100 || o.Qty != 5 || o.Strategy != "momo" || o.ReturnPercent != 20 {
+		t.Errorf("first close = %+v", o)
+	}
+	// 5 × (104 − 100) + 10 × (104 − 110)
+	if o := outcomes[1]; o.PL != -40 || o.Qty != 15 || o.ReturnPercent != -2.5 || !o.ClosedAt.Equal(t0.Add(3*time.Minute)) {
+		t.Errorf("second close = %+v", o)
+	}
+	// A covered short: 5 × (105 − 107)
+	if o := outcomes[2]; o.PL != -10 || o.Strategy != "" || o.Symbol != "AAPL" {
+		t.Errorf("short cover = %+v", o)
+	}
+}
@@ -0,0 +1,98 @@
+package fills
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/paging"
+)
+
+// Handler exposes execution quality reports over HTTP.
+type Handler struct {
+	tracker *Tracker
+}
+
+// NewHandler creates a handler for tracker.
+func NewHandler(tracker *Tracker) *Handler {
+	return &Handler{tracker: tracker}
+}
+
+// RegisterRoutes registers the execution quality routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/reports/execution-quality?days=&symbol=&type=&bucket_minutes= - fill quality by order type, symbol and time of day
+	mux.HandleFunc("/api/reports/execution-quality", h.handleReport)
+
+	// GET /api/reports/execution-quality/orders?days=&limit=&cursor=&sort=&filter= - journaled fill records, newest first, and orders still being followed
+	mux.HandleFunc("/api/reports/execution-quality/orders", h.handleOrders)
+}
+
+// positiveParam reads an optional positive integer query parameter.
+func positiveParam(r *http.Request, name string, def int) (int, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(v)
+	return n, err == nil && n > 0
+}
+
+func (h *Handler) handleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	days, ok := positiveParam(r, "days", 30)
+	if !ok {
+		http.Error(w, "days must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	bucket, ok := positiveParam(r, "bucket_minutes", 30)
+	if !ok || bucket > 24*60 {
+		http.Error(w, "bucket_minutes must be between 1 and 1440", http.StatusBadRequest)
+		return
+	}
+	q := r.URL.Query()
+	json.NewEncoder(w).Encode(h.tracker.Report(Query{
+		Since:         time.Now().AddDate(0, 0, -days),
+		Symbol:        q.Get("symbol"),
+		Type:          q.Get("type"),
+		BucketMinutes: bucket,
+	}))
+}
+
+// recordList pages the journaled fill records, newest first by default.
+var recordList = paging.List[Record]{
+	Fields: []paging.Field[Record]{
+		{Name: "submitted_at", Sort: func(r Record) paging.Key { return paging.Time(r.SubmittedAt) }},
+		{Name: "symbol", Sort: func(r Record) paging.Key { return paging.Text(r.Symbol) }, Filter: func(r Record) string { return r.Symbol }},
+		{Name: "side", Filter: func(r Record) string { return r.Side }},
+		{Name: "type", Filter: func(r Record) string { return r.Type }},
+		{Name: "outcome", Filter: func(r Record) string { return r.Outcome }},
+		{Name: "tag", Filter: func(r Record) string { return r.Tag }},
+	},
+	ID:   func(r Record) string { return r.OrderID },
+	Sort: "-submitted_at",
+}
+
+func (h *Handler) handleOrders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	days, ok := positiveParam(r, "days", 7)
+	if !ok {
+		http.Error(w, "days must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	page, ok := recordList.Page(w, r, h.tracker.Records(time.Now().AddDate(0, 0, -days)))
+	if !ok {
+		return
+	}
+	paging.SetHeaders(w, page.Total, page.NextCursor)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"finished": page.Items,
+		"pending":  h.tracker.Pending(),
+	})
+}
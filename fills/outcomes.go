@@ -0,0 +1,77 @@
+package fills
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+)
+
+// TradeOutcomes pairs filled records first in first out per strategy tag
+// and symbol and returns the realized result of every fill that closed
+// part of a position, oldest first. Pure.
+func TradeOutcomes(records []Record) []algorithm.TradeOutcome {
+	filled := make([]Record, 0, len(records))
+	for _, r := range records {
+		if r.FilledQty > 0 && r.FillPrice > 0 {
+			filled = append(filled, r)
+		}
+	}
+	sort.SliceStable(filled, func(i, j int) bool { return filledAt(filled[i]).Before(filledAt(filled[j])) })
+
+	type key struct{ tag, symbol string }
+	// open lots per key; qty is negative for a short
+	type lot struct{ qty, price float64 }
+	lots := make(map[key][]lot)
+	var out []algorithm.TradeOutcome
+	for _, r := range filled {
+		k := key{r.Tag, strings.ToUpper(r.Symbol)}
+		sign := 1.0
+		if strings.EqualFold(r.Side, "sell") {
+			sign = -1
+		}
+		open := lots[k]
+		remaining, closed, pl, cost := r.FilledQty, 0.0, 0.0, 0.0
+		for remaining > 0 && len(open) > 0 && open[0].qty*sign < 0 {
+			l := &open[0]
+			take := math.Min(remaining, math.Abs(l.qty))
+			// A closing sell gains on a long lot; a closing buy on a short one
+			pl += take * (r.FillPrice - l.price) * -sign
+			cost += take * l.price
+			closed += take
+			remaining -= take
+			l.qty += take * sign
+			if math.Abs(l.qty) < 1e-9 {
+				open = open[1:]
+			}
+		}
+		if remaining > 0 {
+			open = append(open, lot{qty: remaining * sign, price: r.FillPrice})
+		}
+		lots[k] = open
+		if closed > 0 {
+			o := algorithm.TradeOutcome{
+				Symbol:   k.symbol,
+				Strategy: r.Tag,
+				Qty:      closed,
+				PL:       math.Round(pl*100) / 100,
+				ClosedAt: filledAt(r),
+			}
+			if cost > 0 {
+				o.ReturnPercent = math.Round(pl/cost*10000) / 100
+			}
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// filledAt is when r filled.
+func filledAt(r Record) time.Time {
+	if r.FilledAt != nil {
+		return *r.FilledAt
+	}
+	return r.FinishedAt
+}
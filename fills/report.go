@@ -0,0 +1,202 @@
+package fills
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Query selects the records a report covers.
+type Query struct {
+	Since         time.Time
+	Symbol        string
+	Type          string // market, limit, ...
+	BucketMinutes int    // time-of-day bucket width; 30 if zero
+}
+
+// Stats is the fill quality of a group of orders. Spreads and improvement
+// are in basis points of the mid at submission, weighted by filled shares,
+// over fills whose quote was known.
+type Stats struct {
+	Orders              int     `json:"orders"`
+	Filled              int     `json:"filled"`
+	Partial             int     `json:"partial"`
+	Unfilled            int     `json:"unfilled"`
+	FillRate            float64 `json:"fill_rate"` // filled over ordered shares
+	Quoted              int     `json:"quoted"`    // fills behind the spread figures
+	QuotedSpreadBps     float64 `json:"quoted_spread_bps"`
+	EffectiveSpreadBps  float64 `json:"effective_spread_bps"`  // 2 × distance from the mid, positive when paid
+	PriceImprovementBps float64 `json:"price_improvement_bps"` // better than the far quote, positive when improved
+	AvgTimeToFillSec    float64 `json:"avg_time_to_fill_sec"`
+	MedianTimeToFillSec float64 `json:"median_time_to_fill_sec"`
+}
+
+// Group is one slice of the report. Execution is the strategy the order
+// was worked with, passive unless chased, made aggressive or sliced.
+type Group struct {
+	Type      string `json:"type"`
+	Execution string `json:"execution"`
+	Symbol    string `json:"symbol,omitempty"`
+	TimeOfDay string `json:"time_of_day,omitempty"` // bucket start, HH:MM market time
+	Stats
+}
+
+// Report compares fill quality by order type overall, per symbol and per
+// time of day.
+type Report struct {
+	Since         time.Time `json:"since"`
+	BucketMinutes int       `json:"bucket_minutes"`
+	Orders        int       `json:"orders"`
+	ByType        []Group   `json:"by_type"`
+	BySymbol      []Group   `json:"by_symbol"`
+	ByTimeOfDay   []Group   `json:"by_time_of_day"`
+}
+
+// Report summarizes the journal for q.
+func (t *Tracker) Report(q Query) Report {
+	return Summarize(t.Records(q.Since), q, t.loc)
+}
+
+// Summarize groups records matching q. Pure; times of day are in loc.
+func Summarize(records []Record, q Query, loc *time.Location) Report {
+	if q.BucketMinutes <= 0 {
+		q.BucketMinutes = 30
+	}
+	r := Report{Since: q.Since, BucketMinutes: q.BucketMinutes, ByType: []Group{}, BySymbol: []Group{}, ByTimeOfDay: []Group{}}
+	byType := make(map[Group]*accumulator)
+	bySymbol := make(map[Group]*accumulator)
+	byTime := make(map[Group]*accumulator)
+	add := func(m map[Group]*accumulator, g Group, rec Record) {
+		a, ok := m[g]
+		if !ok {
+			a = &accumulator{}
+			m[g] = a
+		}
+		a.add(rec)
+	}
+	for _, rec := range records {
+		if rec.SubmittedAt.Before(q.Since) ||
+			(q.Symbol != "" && !strings.EqualFold(rec.Symbol, q.Symbol)) ||
+			(q.Type != "" && !strings.EqualFold(rec.Type, q.Type)) {
+			continue
+		}
+		r.Orders++
+		key := Group{Type: rec.Type, Execution: rec.Execution}
+		if key.Execution == "" {
+			key.Execution = "passive"
+		}
+		add(byType, key, rec)
+		sym := key
+		sym.Symbol = rec.Symbol
+		add(bySymbol, sym, rec)
+		tod := key
+		tod.TimeOfDay = bucket(rec.SubmittedAt.In(loc), q.BucketMinutes)
+		add(byTime, tod, rec)
+	}
+	r.ByType = collect(byType)
+	r.BySymbol = collect(bySymbol)
+	r.ByTimeOfDay = collect(byTime)
+	return r
+}
+
+// bucket returns the HH:MM start of the bucket holding t.
+func bucket(t time.Time, minutes int) string {
+	m := t.Hour()*60 + t.Minute()
+	m -= m % minutes
+	return time.Date(0, 1, 1, m/60, m%60, 0, 0, time.UTC).Format("15:04")
+}
+
+func collect(m map[Group]*accumulator) []Group {
+	out := make([]Group, 0, len(m))
+	for g, a := range m {
+		g.Stats = a.stats()
+		out = append(out, g)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Symbol != b.Symbol {
+			return a.Symbol < b.Symbol
+		}
+		if a.TimeOfDay != b.TimeOfDay {
+			return a.TimeOfDay < b.TimeOfDay
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Execution < b.Execution
+	})
+	return out
+}
+
+type accumulator struct {
+	s                                      Stats
+	ordered, filled                        float64
+	quotedQty, quoted, effective, improved float64
+	waits                                  []float64
+}
+
+func (a *accumulator) add(r Record) {
+	a.s.Orders++
+	switch r.Outcome {
+	case OutcomeFilled:
+		a.s.Filled++
+	case OutcomePartial:
+		a.s.Partial++
+	default:
+		a.s.Unfilled++
+	}
+	if r.Qty > 0 {
+		a.ordered += r.Qty
+		a.filled += math.Min(r.FilledQty, r.Qty)
+	}
+	if r.FilledQty <= 0 || r.FillPrice <= 0 {
+		return
+	}
+	if r.FilledAt != nil {
+		a.waits = append(a.waits, math.Max(r.FilledAt.Sub(r.SubmittedAt).Seconds(), 0))
+	}
+	if r.Bid <= 0 || r.Ask < r.Bid {
+		return
+	}
+	mid := (r.Bid + r.Ask) / 2
+	sign, far := 1.0, r.Ask
+	if r.Side == "sell" {
+		sign, far = -1, r.Bid
+	}
+	a.s.Quoted++
+	a.quotedQty += r.FilledQty
+	a.quoted += r.FilledQty * (r.Ask - r.Bid) / mid * 1e4
+	a.effective += r.FilledQty * 2 * sign * (r.FillPrice - mid) / mid * 1e4
+	a.improved += r.FilledQty * sign * (far - r.FillPrice) / mid * 1e4
+}
+
+func (a *accumulator) stats() Stats {
+	s := a.s
+	if a.ordered > 0 {
+		s.FillRate = round4(a.filled / a.ordered)
+	}
+	if a.quotedQty > 0 {
+		s.QuotedSpreadBps = round2(a.quoted / a.quotedQty)
+		s.EffectiveSpreadBps = round2(a.effective / a.quotedQty)
+		s.PriceImprovementBps = round2(a.improved / a.quotedQty)
+	}
+	if n := len(a.waits); n > 0 {
+		sort.Float64s(a.waits)
+		sum := 0.0
+		for _, w := range a.waits {
+			sum += w
+		}
+		s.AvgTimeToFillSec = round2(sum / float64(n))
+		s.MedianTimeToFillSec = a.waits[n/2]
+		if n%2 == 0 {
+			s.MedianTimeToFillSec = (a.waits[n/2-1] + a.waits[n/2]) / 2
+		}
+		s.MedianTimeToFillSec = round2(s.MedianTimeToFillSec)
+	}
+	return s
+}
+
+func round2(v float64) float64 { return math.Round(v*100) / 100 }
+
+func round4(v float64) float64 { return math.Round(v*10000) / 10000 }
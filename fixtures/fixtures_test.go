@@ -0,0 +1,100 @@
+package fixtures
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	var calls int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v2/account":
+			io.WriteString(w, `{"id":"b3a1c7e0-uuid","account_number":"PA1234567","equity":"100000.123456789012345","note":"key AKSECRET"}`)
+		case "/v2/positions":
+			if n == 2 {
+				io.WriteString(w, `[]`)
+				return
+			}
+			io.WriteString(w, `[{"symbol":"AAPL","qty":"10","asset_id":"904837e3"}]`)
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, "forbidden for AKSECRET")
+		}
+	}))
+	defer upstream.Close()
+
+	dir := t.TempDir()
+	rec, err := NewRecorder(dir, "AKSECRET", "shh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: rec.Transport("trading", nil)}
+	get := func(c *http.Client, path string) (int, string) {
+		req, _ := http.NewRequest(http.MethodGet, upstream.URL+path, nil)
+		req.Header.Set("APCA-API-SECRET-KEY", "shh")
+		resp, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+	get(client, "/v2/positions")
+	get(client, "/v2/positions")
+	if _, body := get(client, "/v2/account"); !strings.Contains(body, "PA1234567") {
+		t.Fatalf("recording changed the live response: %s", body)
+	}
+	get(client, "/v2/stocks/AAPL/bars?timeframe=1Day&start=2026-01-01&end=2026-02-01&key=AKSECRET")
+
+	// Nothing secret reaches the files
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if info.IsDir() {
+			return nil
+		}
+		data, _ := os.ReadFile(path)
+		for _, secret := range []string{"AKSECRET", "shh", "PA1234567", "b3a1c7e0"} {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("%s contains %q:\n%s", path, secret, data)
+			}
+		}
+		return nil
+	})
+
+	p, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := p.Client()
+	// Repeats replay in order, the last answering further repeats
+	for i, want := range []string{"AAPL", "[]", "[]"} {
+		if _, body := get(replay, "/v2/positions"); !strings.Contains(body, want) {
+			t.Errorf("positions %d = %s, want %s", i, body, want)
+		}
+	}
+	_, body := get(replay, "/v2/account")
+	if !strings.Contains(body, `"account_number": "REDACTED"`) || !strings.Contains(body, `"equity": "100000.123456789012345"`) ||
+		!strings.Contains(body, "key REDACTED") {
+		t.Errorf("account = %s", body)
+	}
+	// start and end are not matched; other parameters are
+	if status, body := get(replay, "/v2/stocks/AAPL/bars?end=2026-03-01&start=2026-02-01&timeframe=1Day&key=REDACTED"); status != http.StatusForbidden || body != "forbidden for REDACTED" {
+		t.Errorf("bars = %d %q", status, body)
+	}
+	if status, _ := get(replay, "/v2/stocks/AAPL/bars?timeframe=1Hour&key=REDACTED"); status != http.StatusNotFound {
+		t.Errorf("unrecorded request answered %d", status)
+	}
+	if misses := p.Misses(); len(misses) != 1 || !strings.Contains(misses[0], "timeframe=1Hour") {
+		t.Errorf("misses = %v", misses)
+	}
+}
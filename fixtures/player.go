@@ -0,0 +1,127 @@
+package fixtures
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Player answers requests from recorded fixtures. Requests without one get
+// a 404 in Alpaca's error format and are listed by Misses. It is safe for
+// concurrent use.
+type Player struct {
+	mu       sync.Mutex
+	fixtures map[string][]Fixture // by match key, in recorded order
+	served   map[string]int       // responses served per key
+	misses   []string
+}
+
+// Load reads every fixture under dir.
+func Load(dir string) (*Player, error) {
+	p := &Player{fixtures: make(map[string][]Fixture), served: make(map[string]int)}
+	var names []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(path, ".json") {
+			names = append(names, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fixtures: %w", err)
+	}
+	var all []Fixture
+	for _, name := range names {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read fixture: %w", err)
+		}
+		var f Fixture
+		if err := json.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("failed to decode fixture %s: %w", name, err)
+		}
+		all = append(all, f)
+	}
+	// Repeats replay in the order they were recorded
+	sort.SliceStable(all, func(i, j int) bool { return all[i].RecordedAt.Before(all[j].RecordedAt) })
+	for _, f := range all {
+		p.fixtures[f.key()] = append(p.fixtures[f.key()], f)
+	}
+	return p, nil
+}
+
+// Add registers f, after any fixtures recorded for the same request.
+func (p *Player) Add(f Fixture) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.fixtures[f.key()] = append(p.fixtures[f.key()], f)
+}
+
+// Client returns an HTTP client that is answered by p.
+func (p *Player) Client() *http.Client {
+	return &http.Client{Transport: p}
+}
+
+// Misses returns the requests no fixture answered, in order.
+func (p *Player) Misses() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.misses...)
+}
+
+// RoundTrip answers req from the fixtures.
+func (p *Player) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	key := matchKey(req.Method, req.URL.Path, req.URL.RawQuery)
+	p.mu.Lock()
+	recorded := p.fixtures[key]
+	if len(recorded) == 0 {
+		p.misses = append(p.misses, key)
+		p.mu.Unlock()
+		logger().Warn("No fixture for request", "request", key)
+		body, _ := json.Marshal(map[string]interface{}{"code": 40410000, "message": "no fixture for " + key})
+		return response(req, http.StatusNotFound, http.Header{"Content-Type": {"application/json"}}, body), nil
+	}
+	n := p.served[key]
+	p.served[key]++
+	p.mu.Unlock()
+	if n >= len(recorded) {
+		n = len(recorded) - 1
+	}
+	f := recorded[n]
+	body := []byte(f.Body)
+	if f.Text {
+		var text string
+		if err := json.Unmarshal(f.Body, &text); err != nil {
+			return nil, fmt.Errorf("fixture for %s: %w", key, err)
+		}
+		body = []byte(text)
+	}
+	return response(req, f.Status, f.header(), body), nil
+}
+
+func response(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,115 @@
+package fixtures
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Recorder writes the requests made through its transports, and Alpaca's
+// responses, as fixture files. Secrets are replaced with Redacted wherever
+// they appear, along with account numbers and the account ID; request
+// headers, which carry the keys, are not recorded at all. It is safe for
+// concurrent use.
+type Recorder struct {
+	dir      string
+	sanitize sanitizer
+
+	mu     sync.Mutex
+	counts map[string]int // recordings of each key this run
+	now    func() time.Time
+}
+
+// NewRecorder records into dir, redacting secrets.
+func NewRecorder(dir string, secrets ...string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create fixtures directory: %w", err)
+	}
+	return &Recorder{dir: dir, sanitize: sanitizer{secrets: secrets}, counts: make(map[string]int), now: time.Now}, nil
+}
+
+// Transport wraps base, or the default transport when nil, recording
+// every request under api.
+func (r *Recorder) Transport(api string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return recordingTransport{recorder: r, api: api, base: base}
+}
+
+type recordingTransport struct {
+	recorder *Recorder
+	api      string
+	base     http.RoundTripper
+}
+
+func (t recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			reqBody, _ = io.ReadAll(body)
+			body.Close()
+		}
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err := t.recorder.record(t.api, req, reqBody, resp, body); err != nil {
+		logger().Warn("Failed to record fixture", "api", t.api, "path", req.URL.Path, "error", err)
+	}
+	return resp, nil
+}
+
+// record writes one fixture for req and its response.
+func (r *Recorder) record(api string, req *http.Request, reqBody []byte, resp *http.Response, body []byte) error {
+	s := r.sanitize
+	f := Fixture{
+		API:         api,
+		Method:      req.Method,
+		Path:        req.URL.Path,
+		Query:       s.text(req.URL.RawQuery),
+		RequestBody: s.text(string(reqBody)),
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if clean, ok := s.body(f.Path, body); ok {
+		f.Body = clean
+	} else if len(body) > 0 {
+		f.Body, _ = json.Marshal(s.text(string(body)))
+		f.Text = true
+	}
+
+	r.mu.Lock()
+	f.RecordedAt = r.now().UTC()
+	key := f.key()
+	r.counts[key]++
+	name := fileName(api, f.Method, f.Path, key, r.counts[key])
+	r.mu.Unlock()
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(r.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,85 @@
+package gaprisk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/rileyseaburg/go-trader/calendar"
+	"github.com/shopspring/decimal"
+)
+
+// AlpacaBroker adapts an Alpaca trading client to Broker. Reductions are
+// market day orders.
+type AlpacaBroker struct {
+	Client *alpaca.Client
+	// Execution, when set, refuses reductions while it returns an error,
+	// as it does in safe mode
+	Execution func() error
+}
+
+// Positions lists open positions.
+func (b AlpacaBroker) Positions(ctx context.Context) ([]Position, error) {
+	positions, err := b.Client.GetPositions()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Position, 0, len(positions))
+	for _, p := range positions {
+		qty, _ := p.Qty.Float64()
+		out = append(out, Position{Symbol: p.Symbol, Qty: qty})
+	}
+	return out, nil
+}
+
+// Reduce submits a market order for the reduction.
+func (b AlpacaBroker) Reduce(ctx context.Context, r Reduction) error {
+	if b.Execution != nil {
+		if err := b.Execution(); err != nil {
+			return err
+		}
+	}
+	qty := decimal.NewFromFloat(r.Qty)
+	_, err := b.Client.PlaceOrder(alpaca.PlaceOrderRequest{
+		Symbol:      r.Symbol,
+		Qty:         &qty,
+		Side:        alpaca.Side(r.Side),
+		Type:        alpaca.Market,
+		TimeInForce: alpaca.Day,
+	})
+	return err
+}
+
+// AlpacaPrices reads session opens and prior closes from daily bars.
+type AlpacaPrices struct {
+	Client *marketdata.Client
+	Cal    *calendar.Calendar
+}
+
+// OpenAndPrevClose returns the open of session and the close of the bar
+// before it for each symbol that has both.
+func (p AlpacaPrices) OpenAndPrevClose(ctx context.Context, symbols []string, session calendar.Session) (map[string]OpenClose, error) {
+	bars, err := p.Client.GetMultiBars(symbols, marketdata.GetBarsRequest{
+		TimeFrame: marketdata.OneDay,
+		Start:     session.Date.AddDate(0, 0, -10),
+		End:       time.Now(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch daily bars: %w", err)
+	}
+
+	want := session.Date.Format("2006-01-02")
+	out := make(map[string]OpenClose, len(bars))
+	for sym, series := range bars {
+		for i := 1; i < len(series); i++ {
+			if series[i].Timestamp.In(p.Cal.Location()).Format("2006-01-02") == want {
+				out[strings.ToUpper(sym)] = OpenClose{Open: series[i].Open, PrevClose: series[i-1].Close}
+				break
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,497 @@
+// Package gaprisk controls exposure to overnight and weekend gaps. Before
+// the close it can reduce or flatten positions (every night, or only ahead
+// of weekends and holidays), and shortly after the open it compares each
+// symbol's opening price with the prior close, pausing automated trading
+// on anything that gapped further than the policy allows until a human
+// reviews it.
+package gaprisk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/calendar"
+)
+
+func logger() *slog.Logger { return slog.With("module", "gaprisk") }
+
+// Pre-close modes.
+const (
+	ModeOff     = "off"
+	ModeReduce  = "reduce"
+	ModeFlatten = "flatten"
+)
+
+// Policy configures both halves of the gap controls.
+type Policy struct {
+	Mode                   string  `json:"mode"`                      // off, reduce or flatten
+	ReduceFraction         float64 `json:"reduce_fraction"`           // share of each position to cut in reduce mode (0-1]
+	MinutesBeforeClose     int     `json:"minutes_before_close"`      // when the pre-close action fires
+	BreaksOnly             bool    `json:"breaks_only"`               // act only before weekends/holidays
+	GapThresholdPercent    float64 `json:"gap_threshold_percent"`     // |open/prev close - 1| that pauses a symbol
+	GapThresholdSigmas     float64 `json:"gap_threshold_sigmas"`      // gap in forecast daily volatilities that pauses a symbol instead; 0 disables
+	AssessAfterOpenMinutes int     `json:"assess_after_open_minutes"` // delay before the morning gap check
+}
+
+// DefaultPolicy assesses gaps above 4% but leaves positions alone at the
+// close until an operator opts in.
+func DefaultPolicy() Policy {
+	return Policy{
+		Mode:                   ModeOff,
+		ReduceFraction:         0.5,
+		MinutesBeforeClose:     15,
+		BreaksOnly:             true,
+		GapThresholdPercent:    4.0,
+		AssessAfterOpenMinutes: 1,
+	}
+}
+
+// Validate checks the policy for internally consistent values.
+func (p Policy) Validate() error {
+	switch p.Mode {
+	case ModeOff, ModeReduce, ModeFlatten:
+	default:
+		return fmt.Errorf("mode must be one of %s, %s, %s", ModeOff, ModeReduce, ModeFlatten)
+	}
+	if p.Mode == ModeReduce && (p.ReduceFraction <= 0 || p.ReduceFraction > 1) {
+		return errors.New("reduce_fraction must be in (0, 1]")
+	}
+	if p.MinutesBeforeClose < 1 || p.MinutesBeforeClose > 240 {
+		return errors.New("minutes_before_close must be between 1 and 240")
+	}
+	if p.GapThresholdPercent <= 0 {
+		return errors.New("gap_threshold_percent must be positive")
+	}
+	if p.GapThresholdSigmas < 0 {
+		return errors.New("gap_threshold_sigmas must not be negative")
+	}
+	if p.AssessAfterOpenMinutes < 0 || p.AssessAfterOpenMinutes > 120 {
+		return errors.New("assess_after_open_minutes must be between 0 and 120")
+	}
+	return nil
+}
+
+// Position is the part of a broker position the controls need.
+type Position struct {
+	Symbol string  `json:"symbol"`
+	Qty    float64 `json:"qty"` // negative for shorts
+}
+
+// Reduction is an order that shrinks a position ahead of the close.
+type Reduction struct {
+	Symbol string  `json:"symbol"`
+	Side   string  `json:"side"` // sell for longs, buy to cover shorts
+	Qty    float64 `json:"qty"`
+	Reason string  `json:"reason"`
+}
+
+// OpenClose pairs a session's opening price with the prior session's close.
+type OpenClose struct {
+	Open      float64
+	PrevClose float64
+	// ForecastVolPercent is the symbol's forecast daily volatility, zero
+	// when unknown
+	ForecastVolPercent float64
+}
+
+// GapEvent records a symbol whose open gapped beyond the threshold.
+type GapEvent struct {
+	Symbol     string  `json:"symbol"`
+	PrevClose  float64 `json:"prev_close"`
+	Open       float64 `json:"open"`
+	GapPercent float64 `json:"gap_percent"`
+	// ThresholdPercent is the gap the symbol was held to: the policy's
+	// percent, or its sigmas times the forecast volatility
+	ThresholdPercent   float64    `json:"threshold_percent"`
+	ForecastVolPercent float64    `json:"forecast_vol_percent,omitempty"`
+	Session            time.Time  `json:"session"`
+	DetectedAt         time.Time  `json:"detected_at"`
+	ReviewedAt         *time.Time `json:"reviewed_at,omitempty"`
+}
+
+// Broker lists positions and submits reductions.
+type Broker interface {
+	Positions(ctx context.Context) ([]Position, error)
+	Reduce(ctx context.Context, r Reduction) error
+}
+
+// PriceSource supplies today's open and the prior close for symbols.
+type PriceSource interface {
+	OpenAndPrevClose(ctx context.Context, symbols []string, session calendar.Session) (map[string]OpenClose, error)
+}
+
+// Notifier is told about reductions and new gap pauses.
+type Notifier func(title, message string, metadata map[string]interface{})
+
+// VolatilityForecast returns a symbol's forecast daily volatility as a
+// fraction of price, and whether there is one.
+type VolatilityForecast func(symbol string) (float64, bool)
+
+// Manager runs the pre-close policy and the morning gap assessment and
+// tracks which symbols are paused.
+type Manager struct {
+	cal *calendar.Calendar
+
+	mu          sync.RWMutex
+	policy      Policy
+	broker      Broker
+	prices      PriceSource
+	symbols     func() []string
+	notify      Notifier
+	forecast    VolatilityForecast
+	paused      map[string]*GapEvent
+	history     []GapEvent
+	lastReduced string // session date of the last pre-close run
+	lastAssess  string // session date of the last gap assessment
+	lastActions []Reduction
+}
+
+// maxGapHistory bounds the reviewed-gap log kept in memory.
+const maxGapHistory = 200
+
+// NewManager returns a manager with the given policy. Broker, price source
+// and symbol list are optional; without them the corresponding half of the
+// controls is inert.
+func NewManager(cal *calendar.Calendar, policy Policy) *Manager {
+	return &Manager{
+		cal:    cal,
+		policy: policy,
+		paused: make(map[string]*GapEvent),
+	}
+}
+
+// SetBroker wires the broker used for pre-close reductions.
+func (m *Manager) SetBroker(b Broker) { m.mu.Lock(); m.broker = b; m.mu.Unlock() }
+
+// SetPriceSource wires the open/prior-close source for gap assessment.
+func (m *Manager) SetPriceSource(p PriceSource) { m.mu.Lock(); m.prices = p; m.mu.Unlock() }
+
+// SetVolatilityForecast wires the forecasts gap_threshold_sigmas scales.
+func (m *Manager) SetVolatilityForecast(fn VolatilityForecast) {
+	m.mu.Lock()
+	m.forecast = fn
+	m.mu.Unlock()
+}
+
+// SetSymbols supplies the watchlist assessed each morning in addition to
+// held positions.
+func (m *Manager) SetSymbols(fn func() []string) { m.mu.Lock(); m.symbols = fn; m.mu.Unlock() }
+
+// SetNotifier registers a callback for reductions and pauses.
+func (m *Manager) SetNotifier(n Notifier) { m.mu.Lock(); m.notify = n; m.mu.Unlock() }
+
+// Policy returns the current policy.
+func (m *Manager) Policy() Policy {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.policy
+}
+
+// SetPolicy validates and replaces the policy.
+func (m *Manager) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policy = p
+	return nil
+}
+
+// CheckSymbol returns an error if automated trading on symbol is paused
+// pending gap review. It has the shape of an algorithm trade guard.
+func (m *Manager) CheckSymbol(symbol string) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if ev, ok := m.paused[strings.ToUpper(symbol)]; ok {
+		return fmt.Errorf("%s paused after a %.2f%% opening gap; review and resume via /api/gaps/resume", ev.Symbol, ev.GapPercent)
+	}
+	return nil
+}
+
+// Paused lists symbols awaiting gap review, sorted by symbol.
+func (m *Manager) Paused() []GapEvent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]GapEvent, 0, len(m.paused))
+	for _, ev := range m.paused {
+		out = append(out, *ev)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Symbol < out[j].Symbol })
+	return out
+}
+
+// History returns reviewed gap events, most recent first.
+func (m *Manager) History() []GapEvent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]GapEvent, len(m.history))
+	for i, ev := range m.history {
+		out[len(m.history)-1-i] = ev
+	}
+	return out
+}
+
+// LastReductions returns the orders generated by the most recent pre-close
+// run.
+func (m *Manager) LastReductions() []Reduction {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]Reduction(nil), m.lastActions...)
+}
+
+// Resume marks a paused symbol as reviewed and re-enables trading on it.
+func (m *Manager) Resume(symbol string) (GapEvent, error) {
+	symbol = strings.ToUpper(symbol)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ev, ok := m.paused[symbol]
+	if !ok {
+		return GapEvent{}, fmt.Errorf("%s is not paused", symbol)
+	}
+	now := time.Now()
+	ev.ReviewedAt = &now
+	delete(m.paused, symbol)
+	m.history = append(m.history, *ev)
+	if len(m.history) > maxGapHistory {
+		m.history = m.history[len(m.history)-maxGapHistory:]
+	}
+	return *ev, nil
+}
+
+// PlanReductions returns the pre-close orders the policy calls for given
+// the current positions. It does not check the time of day.
+func PlanReductions(p Policy, positions []Position, reason string) []Reduction {
+	if p.Mode == ModeOff {
+		return nil
+	}
+	var out []Reduction
+	for _, pos := range positions {
+		abs := math.Abs(pos.Qty)
+		qty := abs
+		if p.Mode == ModeReduce {
+			qty = math.Floor(abs * p.ReduceFraction)
+		}
+		if qty <= 0 {
+			continue
+		}
+		side := "sell"
+		if pos.Qty < 0 {
+			side = "buy"
+		}
+		out = append(out, Reduction{Symbol: pos.Symbol, Side: side, Qty: qty, Reason: reason})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Symbol < out[j].Symbol })
+	return out
+}
+
+// AssessGaps compares opens with prior closes and returns the symbols that
+// gapped beyond the threshold: gap_threshold_sigmas forecast volatilities
+// where set and a forecast is known, else gap_threshold_percent. Pure;
+// Manager.Assess applies the pauses.
+func AssessGaps(p Policy, quotes map[string]OpenClose, session calendar.Session, now time.Time) []GapEvent {
+	var out []GapEvent
+	for sym, q := range quotes {
+		if q.Open <= 0 || q.PrevClose <= 0 {
+			continue
+		}
+		gap := (q.Open/q.PrevClose - 1) * 100
+		threshold := p.GapThresholdPercent
+		if p.GapThresholdSigmas > 0 && q.ForecastVolPercent > 0 {
+			threshold = p.GapThresholdSigmas * q.ForecastVolPercent
+		}
+		if math.Abs(gap) < threshold {
+			continue
+		}
+		out = append(out, GapEvent{
+			Symbol:             strings.ToUpper(sym),
+			PrevClose:          q.PrevClose,
+			Open:               q.Open,
+			GapPercent:         math.Round(gap*100) / 100,
+			ThresholdPercent:   math.Round(threshold*100) / 100,
+			ForecastVolPercent: math.Round(q.ForecastVolPercent*100) / 100,
+			Session:            session.Date,
+			DetectedAt:         now,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Symbol < out[j].Symbol })
+	return out
+}
+
+// Tick runs whichever control is due at now. Each runs at most once per
+// session. Run calls it every minute; tests and manual triggers can call
+// it directly.
+func (m *Manager) Tick(ctx context.Context, now time.Time) {
+	session, ok := m.cal.SessionFor(now)
+	if !ok {
+		return
+	}
+	policy := m.Policy()
+	key := session.Date.Format("2006-01-02")
+
+	assessAt := session.Open.Add(time.Duration(policy.AssessAfterOpenMinutes) * time.Minute)
+	if !now.Before(assessAt) && now.Before(session.Close) && m.claim(&m.lastAssess, key) {
+		if _, err := m.Assess(ctx, session); err != nil {
+			logger().Error("Gap assessment failed", "error", err)
+		}
+	}
+
+	reduceAt := session.Close.Add(-time.Duration(policy.MinutesBeforeClose) * time.Minute)
+	if policy.Mode != ModeOff && !now.Before(reduceAt) && now.Before(session.Close) && m.claim(&m.lastReduced, key) {
+		if policy.BreaksOnly && m.cal.BreakAfter(now) == 0 {
+			return
+		}
+		if _, err := m.ReduceBeforeClose(ctx, now); err != nil {
+			logger().Error("Pre-close gap reduction failed", "error", err)
+		}
+	}
+}
+
+// claim marks key as done in *slot and reports whether it was not already.
+func (m *Manager) claim(slot *string, key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if *slot == key {
+		return false
+	}
+	*slot = key
+	return true
+}
+
+// Assess fetches opens for held and watched symbols, pauses those that
+// gapped too far, and returns the new pauses.
+func (m *Manager) Assess(ctx context.Context, session calendar.Session) ([]GapEvent, error) {
+	m.mu.RLock()
+	prices, broker, symbolsFn, policy, forecast := m.prices, m.broker, m.symbols, m.policy, m.forecast
+	m.mu.RUnlock()
+	if prices == nil {
+		return nil, errors.New("no price source configured")
+	}
+
+	set := make(map[string]bool)
+	if symbolsFn != nil {
+		for _, s := range symbolsFn() {
+			set[strings.ToUpper(s)] = true
+		}
+	}
+	if broker != nil {
+		if positions, err := broker.Positions(ctx); err == nil {
+			for _, p := range positions {
+				set[strings.ToUpper(p.Symbol)] = true
+			}
+		}
+	}
+	if len(set) == 0 {
+		return nil, nil
+	}
+	symbols := make([]string, 0, len(set))
+	for s := range set {
+		symbols = append(symbols, s)
+	}
+	sort.Strings(symbols)
+
+	quotes, err := prices.OpenAndPrevClose(ctx, symbols, session)
+	if err != nil {
+		return nil, err
+	}
+	if policy.GapThresholdSigmas > 0 && forecast != nil {
+		for sym, q := range quotes {
+			if vol, ok := forecast(sym); ok && vol > 0 {
+				q.ForecastVolPercent = vol * 100
+				quotes[sym] = q
+			}
+		}
+	}
+	events := AssessGaps(policy, quotes, session, time.Now())
+
+	m.mu.Lock()
+	var fresh []GapEvent
+	for i := range events {
+		ev := events[i]
+		if _, already := m.paused[ev.Symbol]; already {
+			continue
+		}
+		m.paused[ev.Symbol] = &ev
+		fresh = append(fresh, ev)
+	}
+	notify := m.notify
+	m.mu.Unlock()
+
+	for _, ev := range fresh {
+		logger().Warn("Gap risk: pausing symbol after opening gap",
+			"symbol", ev.Symbol, "gap_percent", ev.GapPercent, "open", ev.Open, "prev_close", ev.PrevClose)
+		if notify != nil {
+			notify(fmt.Sprintf("%s gapped %+.2f%% — trading paused", ev.Symbol, ev.GapPercent),
+				fmt.Sprintf("%s opened at $%.2f vs prior close $%.2f, beyond the %.2f%% threshold. Automated trading is paused until reviewed.",
+					ev.Symbol, ev.Open, ev.PrevClose, ev.ThresholdPercent),
+				map[string]interface{}{"symbol": ev.Symbol, "gap_percent": ev.GapPercent})
+		}
+	}
+	return fresh, nil
+}
+
+// ReduceBeforeClose applies the pre-close policy to current positions.
+func (m *Manager) ReduceBeforeClose(ctx context.Context, now time.Time) ([]Reduction, error) {
+	m.mu.RLock()
+	broker, policy, notify := m.broker, m.policy, m.notify
+	m.mu.RUnlock()
+	if broker == nil {
+		return nil, errors.New("no broker configured")
+	}
+
+	positions, err := broker.Positions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list positions: %w", err)
+	}
+
+	reason := "overnight gap risk"
+	if gap := m.cal.BreakAfter(now); gap > 0 {
+		reason = fmt.Sprintf("%d-day market break ahead", gap+1)
+	}
+	plan := PlanReductions(policy, positions, reason)
+
+	var done []Reduction
+	var failed []string
+	for _, r := range plan {
+		if err := broker.Reduce(ctx, r); err != nil {
+			failed = append(failed, r.Symbol+": "+err.Error())
+			continue
+		}
+		done = append(done, r)
+	}
+
+	m.mu.Lock()
+	m.lastActions = done
+	m.mu.Unlock()
+
+	if notify != nil && len(done) > 0 {
+		notify("Pre-close gap risk reduction",
+			fmt.Sprintf("%s %d position(s) before the close (%s).", policy.Mode, len(done), reason),
+			map[string]interface{}{"mode": policy.Mode, "orders": len(done)})
+	}
+	if len(failed) > 0 {
+		return done, errors.New("some reductions failed: " + strings.Join(failed, "; "))
+	}
+	return done, nil
+}
+
+// Run ticks the controls every minute until ctx is done.
+func (m *Manager) Run(ctx context.Context) {
+	t := time.NewTicker(time.Minute)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			m.Tick(ctx, now)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package gaprisk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/rileyseaburg/go-trader/calendar"
+)
+
+type fakeBroker struct {
+	positions []Position
+	reduced   []Reduction
+}
+
+func (f *fakeBroker) Positions(ctx context.Context) ([]Position, error) { return f.positions, nil }
+func (f *fakeBroker) Reduce(ctx context.Context, r Reduction) error {
+	f.reduced = append(f.reduced, r)
+	return nil
+}
+
+type fakePrices map[string]OpenClose
+
+func (f fakePrices) OpenAndPrevClose(ctx context.Context, symbols []string, s calendar.Session) (map[string]OpenClose, error) {
+	return f, nil
+}
+
+func TestPlanReductions(t *testing.T) {
+	positions := []Position{{Symbol: "MSFT", Qty: 9}, {Symbol: "AAPL", Qty: -10}, {Symbol: "TINY", Qty: 1}}
+
+	p := DefaultPolicy()
+	p.Mode = ModeReduce
+	p.ReduceFraction = 0.5
+	got := PlanReductions(p, positions, "test")
+	if len(got) != 2 {
+		t.Fatalf("reduce plan = %+v, want 2 orders (TINY rounds to zero)", got)
+	}
+	if got[0].Symbol != "AAPL" || got[0].Side != "buy" || got[0].Qty != 5 {
+		t.Errorf("short reduction = %+v", got[0])
+	}
+	if got[1].Symbol != "MSFT" || got[1].Side != "sell" || got[1].Qty != 4 {
+		t.Errorf("long reduction = %+v", got[1])
+	}
+
+	p.Mode = ModeFlatten
+	if got := PlanReductions(p, positions, "test"); len(got) != 3 || got[2].Qty != 1 {
+		t.Errorf("flatten plan = %+v", got)
+	}
+
+	p.Mode = ModeOff
+	if got := PlanReductions(p, positions, "test"); got != nil {
+		t.Errorf("off plan = %+v, want nil", got)
+	}
+}
+
+func TestAssessGaps(t *testing.T) {
+	p := DefaultPolicy()
+	p.GapThresholdPercent = 3
+	quotes := map[string]OpenClose{
+		"up":   {Open: 105, PrevClose: 100},
+		"down": {Open: 96, PrevClose: 100},
+		"flat": {Open: 101, PrevClose: 100},
+		"bad":  {Open: 0, PrevClose: 100},
+	}
+	got := AssessGaps(p, quotes, calendar.Session{}, time.Now())
+	if len(got) != 2 || got[0].Symbol != "DOWN" || got[1].Symbol != "UP" {
+		t.Fatalf("AssessGaps = %+v", got)
+	}
+	if got[0].GapPercent != -4 || got[1].GapPercent != 5 {
+		t.Errorf("gap percents = %v, %v", got[0].GapPercent, got[1].GapPercent)
+	}
+}
+
+func TestGapThresholdSigmas(t *testing.T) {
+	cal := calendar.New()
+	p := DefaultPolicy()
+	p.GapThresholdSigmas = 2
+	m := NewManager(cal, p)
+	// Quiet KO's 3% gap is over two 1% sigmas; volatile TSLA's 5% is not
+	// over two 4% sigmas; NVDA has no forecast and is held to 4%
+	m.SetPriceSource(fakePrices{
+		"KO":   {Open: 103, PrevClose: 100},
+		"TSLA": {Open: 105, PrevClose: 100},
+		"NVDA": {Open: 105, PrevClose: 100},
+	})
+	m.SetSymbols(func() []string { return []string{"KO", "TSLA", "NVDA"} })
+	m.SetVolatilityForecast(func(symbol string) (float64, bool) {
+		vol, ok := map[string]float64{"KO": 0.01, "TSLA": 0.04}[symbol]
+		return vol, ok
+	})
+
+	session, _ := cal.SessionFor(time.Date(2024, time.April, 2, 12, 0, 0, 0, cal.Location()))
+	got, err := m.Assess(context.Background(), session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Symbol != "KO" || got[1].Symbol != "NVDA" {
+		t.Fatalf("paused = %+v", got)
+	}
+	if got[0].ThresholdPercent != 2 || got[0].ForecastVolPercent != 1 || got[1].ThresholdPercent != 4 {
+		t.Errorf("thresholds = %+v", got)
+	}
+	p.GapThresholdSigmas = -1
+	if p.Validate() == nil {
+		t.Error("negative gap_threshold_sigmas accepted")
+	}
+}
+
+func TestPauseAndResume(t *testing.T) {
+	cal := calendar.New()
+	m := NewManager(cal, DefaultPolicy())
+	m.SetPriceSource(fakePrices{"AAPL": {Open: 90, PrevClose: 100}})
+	m.SetSymbols(func() []string { return []string{"AAPL"} })
+
+	session, _ := cal.SessionFor(time.Date(2024, time.April, 2, 12, 0, 0, 0, cal.Location()))
+	if _, err := m.Assess(context.Background(), session); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.CheckSymbol("aapl"); err == nil {
+		t.Fatal("expected AAPL to be paused")
+	}
+	if _, err := m.Resume("AAPL"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.CheckSymbol("AAPL"); err != nil {
+		t.Fatalf("expected AAPL resumed, got %v", err)
+	}
+	if h := m.History(); len(h) != 1 || h[0].ReviewedAt == nil {
+		t.Errorf("history = %+v", h)
+	}
+	if _, err := m.Resume("AAPL"); err == nil {
+		t.Error("expected error resuming a symbol that is not paused")
+	}
+}
+
+func TestTickBreaksOnly(t *testing.T) {
+	cal := calendar.New()
+	p := DefaultPolicy()
+	p.Mode = ModeFlatten
+	p.BreaksOnly = true
+	broker := &fakeBroker{positions: []Position{{Symbol: "MSFT", Qty: 3}}}
+	m := NewManager(cal, p)
+	m.SetBroker(broker)
+
+	// Tuesday 15:50 — next day trades, nothing happens.
+	m.Tick(context.Background(), time.Date(2024, time.April, 2, 15, 50, 0, 0, cal.Location()))
+	if len(broker.reduced) != 0 {
+		t.Fatalf("reduced midweek: %+v", broker.reduced)
+	}
+
+	// Friday 15:50 — weekend ahead, flatten once.
+	fri := time.Date(2024, time.April, 5, 15, 50, 0, 0, cal.Location())
+	m.Tick(context.Background(), fri)
+	m.Tick(context.Background(), fri.Add(time.Minute))
+	if len(broker.reduced) != 1 || broker.reduced[0].Qty != 3 {
+		t.Fatalf("reduced = %+v, want one flatten order", broker.reduced)
+	}
+
+	// Early-close session: window is relative to 13:00.
+	broker.reduced = nil
+	m.Tick(context.Background(), time.Date(2024, time.July, 3, 12, 50, 0, 0, cal.Location()))
+	if len(broker.reduced) != 1 {
+		t.Fatalf("expected reduction before the July 3 early close, got %+v", broker.reduced)
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	p := DefaultPolicy()
+	if err := p.Validate(); err != nil {
+		t.Fatalf("default policy invalid: %v", err)
+	}
+	p.Mode = "sometimes"
+	if p.Validate() == nil {
+		t.Error("expected invalid mode to fail")
+	}
+	p = DefaultPolicy()
+	p.Mode = ModeReduce
+	p.ReduceFraction = 1.5
+	if p.Validate() == nil {
+		t.Error("expected reduce_fraction > 1 to fail")
+	}
+}
+
+func TestAlpacaBrokerReduceChecksExecution(t *testing.T) {
+	var orders int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		orders++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"o1","symbol":"AAPL","status":"new"}`))
+	}))
+	defer server.Close()
+	client := alpaca.NewClient(alpaca.ClientOpts{APIKey: "TEST_KEY", APISecret: "TEST_SECRET", BaseURL: server.URL})
+	safe := errors.New("safe mode")
+	var blocked error = safe
+	b := AlpacaBroker{Client: client, Execution: func() error { return blocked }}
+	r := Reduction{Symbol: "AAPL", Side: "sell", Qty: 5}
+
+	if err := b.Reduce(context.Background(), r); !errors.Is(err, safe) || orders != 0 {
+		t.Fatalf("reduce in safe mode = %v, %d orders", err, orders)
+	}
+	blocked = nil
+	if err := b.Reduce(context.Background(), r); err != nil || orders != 1 {
+		t.Fatalf("reduce = %v, %d orders", err, orders)
+	}
+}
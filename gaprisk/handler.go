@@ -0,0 +1,108 @@
+package gaprisk
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Handler exposes the gap-risk controls over HTTP.
+type Handler struct {
+	manager *Manager
+}
+
+// NewHandler creates a handler for manager.
+func NewHandler(manager *Manager) *Handler {
+	return &Handler{manager: manager}
+}
+
+// RegisterRoutes registers the gap-risk routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/gaps - policy, paused symbols, last reductions, review history
+	mux.HandleFunc("/api/gaps", h.handleStatus)
+
+	// GET/POST /api/gaps/policy - read or replace the policy
+	mux.HandleFunc("/api/gaps/policy", h.handlePolicy)
+
+	// POST /api/gaps/resume?symbol=AAPL - mark a gap reviewed and resume trading
+	mux.HandleFunc("/api/gaps/resume", h.handleResume)
+
+	// POST /api/gaps/assess - run the morning gap assessment now
+	mux.HandleFunc("/api/gaps/assess", h.handleAssess)
+}
+
+func (h *Handler) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"policy":          h.manager.Policy(),
+		"paused":          h.manager.Paused(),
+		"last_reductions": h.manager.LastReductions(),
+		"history":         h.manager.History(),
+	})
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.manager.Policy())
+	case http.MethodPost, http.MethodPut:
+		// Start from the current policy so partial updates work
+		policy := h.manager.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.manager.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(policy)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleResume(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	symbol := strings.TrimSpace(r.URL.Query().Get("symbol"))
+	if symbol == "" {
+		http.Error(w, "Symbol is required", http.StatusBadRequest)
+		return
+	}
+	ev, err := h.manager.Resume(symbol)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"resumed": ev,
+	})
+}
+
+func (h *Handler) handleAssess(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	session, ok := h.manager.cal.SessionFor(time.Now())
+	if !ok {
+		http.Error(w, "Market is closed today", http.StatusConflict)
+		return
+	}
+	events, err := h.manager.Assess(r.Context(), session)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"paused": events,
+	})
+}
@@ -0,0 +1,94 @@
+package impliedmove
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/civil"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+)
+
+// Alpaca reads option chains from Alpaca's options market data. The
+// indicative feed is used unless the account subscribes to OPRA.
+type Alpaca struct {
+	client *marketdata.Client
+	feed   marketdata.OptionFeed
+}
+
+// NewAlpaca creates a source using client. feed is "opra" or "indicative";
+// empty means indicative.
+func NewAlpaca(client *marketdata.Client, feed string) *Alpaca {
+	if feed == "" {
+		feed = marketdata.Indicative
+	}
+	return &Alpaca{client: client, feed: feed}
+}
+
+// Chain returns the contracts on underlying expiring between from and to.
+func (a *Alpaca) Chain(ctx context.Context, underlying string, from, to time.Time) ([]Contract, error) {
+	snapshots, err := a.client.GetOptionChain(underlying, marketdata.GetOptionChainRequest{
+		Feed:              a.feed,
+		ExpirationDateGte: civil.DateOf(from),
+		ExpirationDateLte: civil.DateOf(to),
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Contract, 0, len(snapshots))
+	for symbol, snap := range snapshots {
+		c, err := ParseOCC(symbol)
+		if err != nil {
+			continue
+		}
+		if q := snap.LatestQuote; q != nil {
+			c.Bid, c.Ask = q.BidPrice, q.AskPrice
+		}
+		if t := snap.LatestTrade; t != nil {
+			c.Last = t.Price
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// Price returns the underlying's latest trade price.
+func (a *Alpaca) Price(ctx context.Context, underlying string) (float64, error) {
+	trade, err := a.client.GetLatestTrade(underlying, marketdata.GetLatestTradeRequest{})
+	if err != nil {
+		return 0, err
+	}
+	if trade == nil {
+		return 0, fmt.Errorf("no trade for %s", underlying)
+	}
+	return trade.Price, nil
+}
+
+// ParseOCC reads an OCC option symbol: the root, the expiry as YYMMDD, C or
+// P, and the strike in thousandths padded to eight digits, e.g.
+// AAPL260116C00190000 for the $190 call expiring 16 January 2026.
+func ParseOCC(symbol string) (Contract, error) {
+	if len(symbol) < 16 {
+		return Contract{}, fmt.Errorf("not an OCC symbol: %q", symbol)
+	}
+	tail := symbol[len(symbol)-15:]
+	expiry, err := time.Parse("060102", tail[:6])
+	if err != nil {
+		return Contract{}, fmt.Errorf("bad expiry in %q", symbol)
+	}
+	var kind string
+	switch tail[6] {
+	case 'C':
+		kind = Call
+	case 'P':
+		kind = Put
+	default:
+		return Contract{}, fmt.Errorf("bad option type in %q", symbol)
+	}
+	strike, err := strconv.Atoi(tail[7:])
+	if err != nil {
+		return Contract{}, fmt.Errorf("bad strike in %q", symbol)
+	}
+	return Contract{Symbol: symbol, Type: kind, Strike: float64(strike) / 1000, Expiry: expiry}, nil
+}
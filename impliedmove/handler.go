@@ -0,0 +1,78 @@
+package impliedmove
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Handler exposes the estimates over HTTP.
+type Handler struct {
+	estimator *Estimator
+}
+
+// NewHandler creates a handler for estimator.
+func NewHandler(estimator *Estimator) *Handler {
+	return &Handler{estimator: estimator}
+}
+
+// RegisterRoutes registers the implied move routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/symbols/{symbol}/implied-move?refresh=true - the expected move through the next event or expiry
+	mux.HandleFunc("/api/symbols/{symbol}/implied-move", h.handleSymbol)
+
+	// GET /api/implied-moves - every cached estimate with the policy
+	mux.HandleFunc("/api/implied-moves", h.handleList)
+
+	// GET/POST /api/implied-moves/policy - read or update the TTL and expiry window
+	mux.HandleFunc("/api/implied-moves/policy", h.handlePolicy)
+}
+
+func (h *Handler) handleSymbol(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	est, err := h.estimator.Get(r.Context(), r.PathValue("symbol"), r.URL.Query().Get("refresh") == "true")
+	switch {
+	case errors.Is(err, ErrNoStraddle):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	json.NewEncoder(w).Encode(est)
+}
+
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"policy":    h.estimator.Policy(),
+		"estimates": h.estimator.Snapshot(),
+	})
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.estimator.Policy())
+	case http.MethodPost, http.MethodPut:
+		// Start from the current policy so partial updates work
+		policy := h.estimator.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.estimator.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(policy)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
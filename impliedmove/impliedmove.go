@@ -0,0 +1,372 @@
+// Package impliedmove estimates the move the options market expects in a
+// symbol through a coming expiry, from the price of the at-the-money
+// straddle. No volatility surface or pricing model is involved: a straddle
+// pays the absolute move at expiry, so its price is the market's expected
+// move. Estimates are made for the first expiry on or after the symbol's
+// next event, such as earnings, or the nearest expiry when none is known,
+// and are cached for risk sizing and the Claude context.
+package impliedmove
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+func logger() *slog.Logger { return slog.With("module", "impliedmove") }
+
+// Option types.
+const (
+	Call = "call"
+	Put  = "put"
+)
+
+// ErrNoStraddle is returned when the chain has no expiry with call and put
+// quotes around the price.
+var ErrNoStraddle = errors.New("no at-the-money straddle in the option chain")
+
+// Contract is one option's latest quote.
+type Contract struct {
+	Symbol string    `json:"symbol"` // OCC symbol, e.g. AAPL260116C00190000
+	Type   string    `json:"type"`   // call or put
+	Strike float64   `json:"strike"`
+	Expiry time.Time `json:"expiry"` // the expiration date, midnight UTC
+	Bid    float64   `json:"bid"`
+	Ask    float64   `json:"ask"`
+	Last   float64   `json:"last"`
+}
+
+// Mid is the contract's bid/ask midpoint, or its last trade when the quote
+// is one-sided or crossed.
+func (c Contract) Mid() float64 {
+	if c.Bid > 0 && c.Ask >= c.Bid {
+		return (c.Bid + c.Ask) / 2
+	}
+	return c.Last
+}
+
+// Estimate is the expected move through one expiry.
+type Estimate struct {
+	Symbol       string     `json:"symbol"`
+	Price        float64    `json:"price"`
+	Expiry       time.Time  `json:"expiry"`
+	DaysToExpiry float64    `json:"days_to_expiry"`
+	Event        *time.Time `json:"event,omitempty"` // the event the expiry was chosen for
+	// The straddle is interpolated between the strikes either side of the
+	// price; both are the same when one is at the money.
+	LowerStrike float64 `json:"lower_strike"`
+	UpperStrike float64 `json:"upper_strike"`
+	Straddle    float64 `json:"straddle"`
+	// Move is the expected absolute move in price and MovePercent the same
+	// as a percentage of the price. DailyPercent spreads it over the
+	// sessions to expiry as a one-day move.
+	Move         float64   `json:"move"`
+	MovePercent  float64   `json:"move_percent"`
+	DailyPercent float64   `json:"daily_percent"`
+	AsOf         time.Time `json:"as_of"`
+}
+
+// Compute estimates the expected move for symbol at price from contracts,
+// using the first expiry on or after from that has call and put quotes for
+// a strike at or either side of the price.
+func Compute(symbol string, price float64, contracts []Contract, from, now time.Time) (Estimate, error) {
+	if price <= 0 {
+		return Estimate{}, fmt.Errorf("no price for %s", symbol)
+	}
+	type pair struct{ call, put float64 }
+	byExpiry := make(map[time.Time]map[float64]*pair)
+	for _, c := range contracts {
+		mid := c.Mid()
+		if mid <= 0 || c.Strike <= 0 || c.Expiry.Before(dayOf(from)) {
+			continue
+		}
+		strikes := byExpiry[c.Expiry]
+		if strikes == nil {
+			strikes = make(map[float64]*pair)
+			byExpiry[c.Expiry] = strikes
+		}
+		p := strikes[c.Strike]
+		if p == nil {
+			p = &pair{}
+			strikes[c.Strike] = p
+		}
+		switch c.Type {
+		case Call:
+			p.call = mid
+		case Put:
+			p.put = mid
+		}
+	}
+
+	expiries := make([]time.Time, 0, len(byExpiry))
+	for e := range byExpiry {
+		expiries = append(expiries, e)
+	}
+	sort.Slice(expiries, func(i, j int) bool { return expiries[i].Before(expiries[j]) })
+
+	for _, expiry := range expiries {
+		// Straddle prices by strike, for strikes quoted on both sides
+		var strikes []float64
+		straddles := make(map[float64]float64)
+		for k, p := range byExpiry[expiry] {
+			if p.call > 0 && p.put > 0 {
+				strikes = append(strikes, k)
+				straddles[k] = p.call + p.put
+			}
+		}
+		sort.Float64s(strikes)
+		i := sort.SearchFloat64s(strikes, price)
+		var lo, hi float64
+		switch {
+		case i < len(strikes) && strikes[i] == price:
+			lo, hi = price, price
+		case i > 0 && i < len(strikes):
+			lo, hi = strikes[i-1], strikes[i]
+		default:
+			continue // the price is outside the quoted strikes
+		}
+		straddle := straddles[lo]
+		if hi != lo {
+			straddle += (straddles[hi] - straddles[lo]) * (price - lo) / (hi - lo)
+		}
+
+		// Options expire at the close, 16:00 New York, roughly 20:00 UTC
+		days := expiry.Add(20*time.Hour).Sub(now).Hours() / 24
+		sessions := math.Max(1, math.Round(days*5/7))
+		est := Estimate{
+			Symbol:       symbol,
+			Price:        price,
+			Expiry:       expiry,
+			DaysToExpiry: math.Max(0, days),
+			LowerStrike:  lo,
+			UpperStrike:  hi,
+			Straddle:     straddle,
+			Move:         straddle,
+			MovePercent:  100 * straddle / price,
+			AsOf:         now,
+		}
+		est.DailyPercent = est.MovePercent / math.Sqrt(sessions)
+		return est, nil
+	}
+	return Estimate{}, ErrNoStraddle
+}
+
+func dayOf(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Source fetches option chains and underlying prices.
+type Source interface {
+	// Chain returns the contracts on underlying expiring between from and
+	// to, inclusive.
+	Chain(ctx context.Context, underlying string, from, to time.Time) ([]Contract, error)
+	// Price returns the underlying's latest price.
+	Price(ctx context.Context, underlying string) (float64, error)
+}
+
+// Policy configures the estimator.
+type Policy struct {
+	TTLMinutes      int `json:"ttl_minutes"`        // estimates are refreshed after this
+	MaxDaysToExpiry int `json:"max_days_to_expiry"` // expiries further out are not fetched
+}
+
+// DefaultPolicy refreshes estimates every 15 minutes and looks at most 45
+// days out.
+func DefaultPolicy() Policy {
+	return Policy{TTLMinutes: 15, MaxDaysToExpiry: 45}
+}
+
+// Validate checks the policy for internally consistent values.
+func (p Policy) Validate() error {
+	if p.TTLMinutes < 1 || p.TTLMinutes > 24*60 {
+		return errors.New("ttl_minutes must be between 1 and 1440")
+	}
+	if p.MaxDaysToExpiry < 1 || p.MaxDaysToExpiry > 365 {
+		return errors.New("max_days_to_expiry must be between 1 and 365")
+	}
+	return nil
+}
+
+// entry is a cached estimate, or the error of the last attempt.
+type entry struct {
+	estimate Estimate
+	err      error
+	at       time.Time
+}
+
+// Estimator computes and caches estimates. It is safe for concurrent use.
+type Estimator struct {
+	source Source
+
+	mu     sync.Mutex
+	policy Policy
+	cache  map[string]entry
+	events func(symbol string) (time.Time, bool)
+	prices func(symbol string) float64
+	now    func() time.Time
+}
+
+// New creates an estimator reading from source.
+func New(source Source, policy Policy) (*Estimator, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &Estimator{source: source, policy: policy, cache: make(map[string]entry), now: time.Now}, nil
+}
+
+// SetClock replaces the clock, for tests.
+func (e *Estimator) SetClock(now func() time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.now = now
+}
+
+// SetEvents sets the lookup of a symbol's next event date, such as its
+// earnings. Estimates are then made through the event.
+func (e *Estimator) SetEvents(fn func(symbol string) (time.Time, bool)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = fn
+}
+
+// SetPrices sets the lookup of a symbol's latest streamed price, used
+// instead of asking the source when it is positive.
+func (e *Estimator) SetPrices(fn func(symbol string) float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.prices = fn
+}
+
+// Policy returns the current policy.
+func (e *Estimator) Policy() Policy {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.policy
+}
+
+// SetPolicy validates and replaces the policy.
+func (e *Estimator) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.policy = p
+	return nil
+}
+
+// Get returns symbol's estimate, from the cache unless it is older than the
+// TTL or refresh is set.
+func (e *Estimator) Get(ctx context.Context, symbol string, refresh bool) (Estimate, error) {
+	symbol = strings.ToUpper(symbol)
+	e.mu.Lock()
+	cached, ok := e.cache[symbol]
+	fresh := ok && e.now().Sub(cached.at) < time.Duration(e.policy.TTLMinutes)*time.Minute
+	e.mu.Unlock()
+	if fresh && !refresh {
+		return cached.estimate, cached.err
+	}
+	est, err := e.fetch(ctx, symbol)
+	if err != nil && ok && cached.err == nil {
+		// Keep serving the last good estimate; Cached stops handing it to
+		// sizing once it is too old
+		logger().Warn("Failed to refresh implied move", "symbol", symbol, "error", err)
+		est, err = cached.estimate, nil
+	} else if err != nil {
+		logger().Debug("Implied move unavailable", "symbol", symbol, "error", err)
+	}
+	e.mu.Lock()
+	e.cache[symbol] = entry{estimate: est, err: err, at: e.now()}
+	e.mu.Unlock()
+	return est, err
+}
+
+func (e *Estimator) fetch(ctx context.Context, symbol string) (Estimate, error) {
+	e.mu.Lock()
+	now, policy, events, prices := e.now(), e.policy, e.events, e.prices
+	e.mu.Unlock()
+
+	from := now
+	var event *time.Time
+	if events != nil {
+		if at, ok := events(symbol); ok && !at.Before(dayOf(now)) {
+			from, event = at, &at
+		}
+	}
+	to := now.AddDate(0, 0, policy.MaxDaysToExpiry)
+	if from.After(to) {
+		return Estimate{}, fmt.Errorf("event on %s is beyond max_days_to_expiry", from.Format("2006-01-02"))
+	}
+
+	var price float64
+	if prices != nil {
+		price = prices(symbol)
+	}
+	if price <= 0 {
+		p, err := e.source.Price(ctx, symbol)
+		if err != nil {
+			return Estimate{}, fmt.Errorf("failed to get price: %w", err)
+		}
+		price = p
+	}
+	contracts, err := e.source.Chain(ctx, symbol, from, to)
+	if err != nil {
+		return Estimate{}, fmt.Errorf("failed to get option chain: %w", err)
+	}
+	est, err := Compute(symbol, price, contracts, from, now)
+	if err != nil {
+		return Estimate{}, err
+	}
+	est.Event = event
+	return est, nil
+}
+
+// Cached returns symbol's cached move as a percentage of price without
+// fetching. Estimates older than four TTLs are not returned, so a source
+// that stopped answering does not feed stale values into sizing.
+func (e *Estimator) Cached(symbol string) (float64, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	c, ok := e.cache[strings.ToUpper(symbol)]
+	if !ok || c.estimate.MovePercent <= 0 || e.now().Sub(c.estimate.AsOf) > 4*time.Duration(e.policy.TTLMinutes)*time.Minute {
+		return 0, false
+	}
+	return c.estimate.MovePercent, true
+}
+
+// Snapshot returns every cached estimate by symbol.
+func (e *Estimator) Snapshot() map[string]Estimate {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := make(map[string]Estimate, len(e.cache))
+	for sym, c := range e.cache {
+		if c.estimate.MovePercent > 0 {
+			out[sym] = c.estimate
+		}
+	}
+	return out
+}
+
+// Run refreshes the estimates of symbols() every TTL until ctx is done.
+func (e *Estimator) Run(ctx context.Context, symbols func() []string) {
+	for {
+		for _, sym := range symbols() {
+			if ctx.Err() != nil {
+				return
+			}
+			e.Get(ctx, sym, false)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Duration(e.Policy().TTLMinutes) * time.Minute):
+		}
+	}
+}
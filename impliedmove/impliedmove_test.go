@@ -0,0 +1,111 @@
+package impliedmove
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func day(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+
+func quotes(expiry time.Time, strike, call, put float64) []Contract {
+	return []Contract{
+		{Type: Call, Strike: strike, Expiry: expiry, Bid: call - 0.05, Ask: call + 0.05},
+		{Type: Put, Strike: strike, Expiry: expiry, Bid: put - 0.05, Ask: put + 0.05},
+	}
+}
+
+func TestComputeInterpolatesStraddleThroughEvent(t *testing.T) {
+	now := time.Date(2026, 1, 5, 15, 0, 0, 0, time.UTC)
+	weekly, monthly := day(2026, 1, 9), day(2026, 1, 16)
+	var chain []Contract
+	chain = append(chain, quotes(weekly, 100, 2, 2)...)
+	chain = append(chain, quotes(weekly, 105, 1, 4)...)
+	chain = append(chain, quotes(monthly, 100, 5, 4)...)
+	chain = append(chain, quotes(monthly, 105, 3, 7)...)
+
+	// With no event the nearest expiry is used, halfway between strikes
+	est, err := Compute("AAPL", 102.5, chain, now, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !est.Expiry.Equal(weekly) || est.LowerStrike != 100 || est.UpperStrike != 105 || math.Abs(est.Straddle-4.5) > 1e-9 {
+		t.Fatalf("weekly = %+v", est)
+	}
+	if math.Abs(est.MovePercent-100*4.5/102.5) > 1e-9 || est.DailyPercent >= est.MovePercent {
+		t.Fatalf("percentages = %v, %v", est.MovePercent, est.DailyPercent)
+	}
+
+	// Earnings after the weekly moves to the monthly expiry
+	est, err = Compute("AAPL", 100, chain, day(2026, 1, 12), now)
+	if err != nil || !est.Expiry.Equal(monthly) || est.Straddle != 9 {
+		t.Fatalf("monthly = %+v, %v", est, err)
+	}
+
+	if _, err := Compute("AAPL", 120, chain, now, now); !errors.Is(err, ErrNoStraddle) {
+		t.Fatalf("price outside the strikes: %v", err)
+	}
+}
+
+func TestParseOCC(t *testing.T) {
+	c, err := ParseOCC("AAPL260116C00192500")
+	if err != nil || c.Type != Call || c.Strike != 192.5 || !c.Expiry.Equal(day(2026, 1, 16)) {
+		t.Fatalf("ParseOCC = %+v, %v", c, err)
+	}
+	if _, err := ParseOCC("AAPL260116X00192500"); err == nil {
+		t.Fatal("bad type accepted")
+	}
+}
+
+type fakeSource struct {
+	chain []Contract
+	err   error
+	calls int
+}
+
+func (f *fakeSource) Chain(ctx context.Context, underlying string, from, to time.Time) ([]Contract, error) {
+	f.calls++
+	return f.chain, f.err
+}
+
+func (f *fakeSource) Price(ctx context.Context, underlying string) (float64, error) {
+	return 100, nil
+}
+
+func TestEstimatorCachesAndKeepsLastGoodEstimate(t *testing.T) {
+	now := time.Date(2026, 1, 5, 15, 0, 0, 0, time.UTC)
+	src := &fakeSource{chain: quotes(day(2026, 1, 9), 100, 2, 2)}
+	e, err := New(src, DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.SetClock(func() time.Time { return now })
+
+	if _, ok := e.Cached("AAPL"); ok {
+		t.Fatal("cached before any fetch")
+	}
+	est, err := e.Get(context.Background(), "aapl", false)
+	if err != nil || est.Straddle != 4 {
+		t.Fatalf("Get = %+v, %v", est, err)
+	}
+	e.Get(context.Background(), "AAPL", false)
+	if src.calls != 1 {
+		t.Fatalf("fetched %d times within the TTL", src.calls)
+	}
+
+	// A failed refresh keeps the last good estimate until it is too old
+	src.err = errors.New("feed down")
+	now = now.Add(20 * time.Minute)
+	if est, err := e.Get(context.Background(), "AAPL", false); err != nil || est.Straddle != 4 {
+		t.Fatalf("refresh failure = %+v, %v", est, err)
+	}
+	if pct, ok := e.Cached("AAPL"); !ok || pct != 4 {
+		t.Fatalf("Cached = %v, %v", pct, ok)
+	}
+	now = now.Add(time.Hour)
+	if _, ok := e.Cached("AAPL"); ok {
+		t.Fatal("stale estimate handed to sizing")
+	}
+}
@@ -0,0 +1,89 @@
+package indicators
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Handler exposes the VWAPs over HTTP.
+type Handler struct {
+	tracker *Tracker
+}
+
+// NewHandler creates a handler for tracker.
+func NewHandler(tracker *Tracker) *Handler {
+	return &Handler{tracker: tracker}
+}
+
+// RegisterRoutes registers the VWAP routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/symbols/{symbol}/vwap - the session VWAP and every anchored VWAP
+	mux.HandleFunc("/api/symbols/{symbol}/vwap", h.handleSnapshot)
+
+	// GET /api/symbols/{symbol}/session-stats - today's open, high, low, VWAP, volume, volatility and spread
+	mux.HandleFunc("/api/symbols/{symbol}/session-stats", h.handleSessionStats)
+
+	// POST /api/symbols/{symbol}/vwap/anchors - anchor a VWAP, {"name", "at"} with at in RFC3339
+	mux.HandleFunc("/api/symbols/{symbol}/vwap/anchors", h.handleAnchors)
+
+	// DELETE /api/symbols/{symbol}/vwap/anchors/{name} - remove an anchor
+	mux.HandleFunc("/api/symbols/{symbol}/vwap/anchors/{name}", h.handleAnchor)
+}
+
+func (h *Handler) handleSnapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(h.tracker.Snapshot(r.PathValue("symbol")))
+}
+
+func (h *Handler) handleSessionStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	stats, ok := h.tracker.SessionStats(r.PathValue("symbol"))
+	if !ok {
+		http.Error(w, "No bars yet this session", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(stats)
+}
+
+func (h *Handler) handleAnchors(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Name string    `json:"name"`
+		At   time.Time `json:"at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	v, err := h.tracker.Anchor(r.Context(), r.PathValue("symbol"), req.Name, req.At)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (h *Handler) handleAnchor(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	err := h.tracker.RemoveAnchor(r.PathValue("symbol"), r.PathValue("name"))
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+}
@@ -0,0 +1,142 @@
+package indicators
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// minutesPerYear annualizes minute return variance: 390 regular-session
+// minutes on each of 252 trading days.
+const minutesPerYear = 390 * 252
+
+// SessionStats are a symbol's statistics for the current regular session,
+// from the streamed minute bars and the latest quote.
+type SessionStats struct {
+	Symbol  string    `json:"symbol"`
+	Session string    `json:"session"` // exchange-local date
+	Open    float64   `json:"open"`
+	High    float64   `json:"high"`
+	Low     float64   `json:"low"`
+	Last    float64   `json:"last"`
+	VWAP    float64   `json:"vwap"`
+	Volume  float64   `json:"volume"`
+	Bars    int       `json:"bars"`
+	AsOf    time.Time `json:"as_of"` // the latest bar's start
+	// AvgVolume20 is the average daily volume over 20 sessions, and
+	// VolumeRatio today's volume over it. VolumePace compares today's
+	// volume with the share of that average due by now, so 2 is twice
+	// the usual volume for the time of day
+	AvgVolume20 float64 `json:"avg_volume_20,omitempty"`
+	VolumeRatio float64 `json:"volume_ratio,omitempty"`
+	VolumePace  float64 `json:"volume_pace,omitempty"`
+	// RealizedVolatility is annualized, in percent, from the session's
+	// minute log returns
+	RealizedVolatility float64 `json:"realized_volatility"`
+	Bid                float64 `json:"bid,omitempty"`
+	Ask                float64 `json:"ask,omitempty"`
+	Spread             float64 `json:"spread,omitempty"`
+	SpreadBps          float64 `json:"spread_bps,omitempty"` // of the mid
+}
+
+// SetAverageVolume sets the lookup of a symbol's 20-session average daily
+// volume for the session stats.
+func (t *Tracker) SetAverageVolume(fn func(symbol string) (float64, bool)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.avgVolume = fn
+}
+
+// SetQuotes sets the lookup of a symbol's latest bid and ask for the
+// session stats' spread.
+func (t *Tracker) SetQuotes(fn func(symbol string) (bid, ask float64, ok bool)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.quotes = fn
+}
+
+// addSessionBar keeps b among the session's bars, replacing the forming
+// bar when the feed revises it. Callers hold mu.
+func (s *symbolState) addSessionBar(b Bar) {
+	if n := len(s.bars); n > 0 {
+		switch last := s.bars[n-1].Time; {
+		case b.Time.Before(last):
+			return
+		case b.Time.Equal(last):
+			s.bars[n-1] = b
+			return
+		}
+	}
+	s.bars = append(s.bars, b)
+}
+
+// SessionStats returns symbol's statistics for the current session, false
+// before the session's first bar.
+func (t *Tracker) SessionStats(symbol string) (SessionStats, bool) {
+	symbol = strings.ToUpper(symbol)
+	vwap, ok := t.Session(symbol)
+	if !ok {
+		return SessionStats{}, false
+	}
+	t.mu.RLock()
+	s := t.symbols[symbol]
+	bars := append([]Bar(nil), s.bars...)
+	key := s.session
+	avgVolume, quotes := t.avgVolume, t.quotes
+	session, _ := t.cal.SessionFor(t.now())
+	t.mu.RUnlock()
+	if len(bars) == 0 {
+		return SessionStats{}, false
+	}
+
+	st := SessionStats{Symbol: symbol, Session: key, VWAP: vwap.VWAP, Bars: len(bars), AsOf: bars[len(bars)-1].Time}
+	st.Open = bars[0].Open
+	if st.Open <= 0 {
+		st.Open = bars[0].Close
+	}
+	st.Last = bars[len(bars)-1].Close
+	st.High, st.Low = math.Inf(-1), math.Inf(1)
+	var sumSq float64
+	returns := 0
+	for i, b := range bars {
+		st.Volume += b.Volume
+		high, low := b.High, b.Low
+		if high <= 0 || low <= 0 {
+			high, low = b.Close, b.Close
+		}
+		st.High = math.Max(st.High, high)
+		st.Low = math.Min(st.Low, low)
+		if i > 0 && bars[i-1].Close > 0 && b.Close > 0 {
+			r := math.Log(b.Close / bars[i-1].Close)
+			sumSq += r * r
+			returns++
+		}
+	}
+	if returns > 0 {
+		st.RealizedVolatility = round4(math.Sqrt(sumSq/float64(returns)*minutesPerYear) * 100)
+	}
+
+	if avgVolume != nil {
+		if avg, ok := avgVolume(symbol); ok && avg > 0 {
+			st.AvgVolume20 = avg
+			st.VolumeRatio = round4(st.Volume / avg)
+			// The share of the session elapsed by the end of the latest bar
+			if length := session.Close.Sub(session.Open); length > 0 {
+				elapsed := math.Min(float64(st.AsOf.Add(time.Minute).Sub(session.Open))/float64(length), 1)
+				if elapsed > 0 {
+					st.VolumePace = round4(st.Volume / (avg * elapsed))
+				}
+			}
+		}
+	}
+	if quotes != nil {
+		if bid, ask, ok := quotes(symbol); ok && bid > 0 && ask >= bid {
+			st.Bid, st.Ask = bid, ask
+			st.Spread = round4(ask - bid)
+			st.SpreadBps = round4((ask - bid) / ((ask + bid) / 2) * 10000)
+		}
+	}
+	return st, true
+}
+
+func round4(x float64) float64 { return math.Round(x*10000) / 10000 }
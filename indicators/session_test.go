@@ -0,0 +1,58 @@
+package indicators
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/calendar"
+)
+
+func TestSessionStats(t *testing.T) {
+	cal := calendar.New()
+	tr, err := NewTracker(filepath.Join(t.TempDir(), "anchors.json"), cal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := time.Date(2026, 3, 2, 9, 30, 0, 0, cal.Location())
+	now := open.Add(3 * time.Minute)
+	tr.SetClock(func() time.Time { return now })
+	tr.SetAverageVolume(func(string) (float64, bool) { return 39000, true })
+	tr.SetQuotes(func(string) (float64, float64, bool) { return 101.9, 102.1, true })
+
+	if _, ok := tr.SessionStats("AAPL"); ok {
+		t.Fatal("stats before the first bar")
+	}
+	// A pre-market bar is not part of the session
+	tr.Observe("AAPL", Bar{Time: open.Add(-time.Minute), Open: 90, High: 95, Low: 90, Close: 94, Volume: 500})
+	tr.Observe("AAPL", Bar{Time: open, Open: 100, High: 101, Low: 99, Close: 100, Volume: 100})
+	tr.Observe("AAPL", Bar{Time: open.Add(time.Minute), Open: 100, High: 103, Low: 100, Close: 101, Volume: 100})
+	// The forming bar is revised
+	tr.Observe("AAPL", Bar{Time: open.Add(2 * time.Minute), Open: 101, High: 102, Low: 100, Close: 101, Volume: 50})
+	tr.Observe("AAPL", Bar{Time: open.Add(2 * time.Minute), Open: 101, High: 102.5, Low: 98, Close: 102, Volume: 100})
+
+	st, ok := tr.SessionStats("aapl")
+	if !ok {
+		t.Fatal("no stats")
+	}
+	if st.Open != 100 || st.High != 103 || st.Low != 98 || st.Last != 102 || st.Volume != 300 || st.Bars != 3 {
+		t.Errorf("stats = %+v", st)
+	}
+	if st.VolumeRatio != round4(300.0/39000) || st.VolumePace != round4(300/(39000*3.0/390)) {
+		t.Errorf("volume ratio %v, pace %v", st.VolumeRatio, st.VolumePace)
+	}
+	r1, r2 := math.Log(101/100.0), math.Log(102/101.0)
+	if want := round4(math.Sqrt((r1*r1+r2*r2)/2*minutesPerYear) * 100); st.RealizedVolatility != want {
+		t.Errorf("realized volatility = %v, want %v", st.RealizedVolatility, want)
+	}
+	if st.Spread != 0.2 || st.SpreadBps != round4(0.2/102*10000) {
+		t.Errorf("spread = %v (%v bps)", st.Spread, st.SpreadBps)
+	}
+
+	// The next session starts afresh
+	now = now.Add(24 * time.Hour)
+	if _, ok := tr.SessionStats("AAPL"); ok {
+		t.Error("yesterday's stats served")
+	}
+}
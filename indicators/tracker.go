@@ -0,0 +1,347 @@
+package indicators
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/calendar"
+)
+
+func logger() *slog.Logger { return slog.With("module", "indicators") }
+
+// ErrNotFound is returned for an unknown anchor.
+var ErrNotFound = errors.New("anchor not found")
+
+// History fetches minute bars from from to to, used to backfill anchors
+// set in the past.
+type History func(ctx context.Context, symbol string, from, to time.Time) ([]Bar, error)
+
+// AnchorSpec is a saved anchor.
+type AnchorSpec struct {
+	Symbol    string    `json:"symbol"`
+	Name      string    `json:"name"`
+	At        time.Time `json:"at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type anchor struct {
+	spec AnchorSpec
+	vwap VWAP
+}
+
+type symbolState struct {
+	session string // exchange-local date of the session VWAP
+	vwap    VWAP
+	bars    []Bar // the session's bars, for its stats
+	anchors map[string]*anchor
+	windows map[string]*VWAP // unsaved anchors such as execution windows
+}
+
+// Tracker keeps the session and anchored VWAPs of every symbol. Anchors
+// are saved so they survive a restart; Backfill rebuilds them from
+// history. It is safe for concurrent use.
+type Tracker struct {
+	path string
+	cal  *calendar.Calendar
+
+	mu      sync.RWMutex
+	symbols map[string]*symbolState
+	history History
+	now     func() time.Time
+	// avgVolume and quotes complete the session stats
+	avgVolume func(symbol string) (float64, bool)
+	quotes    func(symbol string) (bid, ask float64, ok bool)
+}
+
+// NewTracker opens the anchors saved at path.
+func NewTracker(path string, cal *calendar.Calendar) (*Tracker, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create indicators directory: %w", err)
+	}
+	t := &Tracker{path: path, cal: cal, symbols: make(map[string]*symbolState), now: time.Now}
+	if data, err := os.ReadFile(path); err == nil {
+		var specs []AnchorSpec
+		if err := json.Unmarshal(data, &specs); err != nil {
+			return nil, fmt.Errorf("failed to decode anchors: %w", err)
+		}
+		for _, spec := range specs {
+			t.stateLocked(spec.Symbol).anchors[spec.Name] = &anchor{spec: spec}
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read anchors: %w", err)
+	}
+	return t, nil
+}
+
+// SetClock replaces the clock, for replays and tests.
+func (t *Tracker) SetClock(now func() time.Time) { t.mu.Lock(); t.now = now; t.mu.Unlock() }
+
+// SetHistory sets where anchors in the past are backfilled from.
+func (t *Tracker) SetHistory(h History) { t.mu.Lock(); t.history = h; t.mu.Unlock() }
+
+func (t *Tracker) stateLocked(symbol string) *symbolState {
+	s, ok := t.symbols[symbol]
+	if !ok {
+		s = &symbolState{anchors: make(map[string]*anchor), windows: make(map[string]*VWAP)}
+		t.symbols[symbol] = s
+	}
+	return s
+}
+
+// Observe folds a streamed minute bar into symbol's VWAPs. Only bars in
+// the regular session count toward the session VWAP; anchors take every
+// bar from their start.
+func (t *Tracker) Observe(symbol string, b Bar) {
+	if b.Time.IsZero() {
+		return
+	}
+	symbol = strings.ToUpper(symbol)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s := t.stateLocked(symbol)
+	if session, ok := t.cal.SessionFor(b.Time); ok && !b.Time.Before(session.Open) && b.Time.Before(session.Close) {
+		if key := session.Date.Format("2006-01-02"); key != s.session {
+			s.session, s.vwap, s.bars = key, VWAP{}, nil
+		}
+		s.vwap.Add(b)
+		s.addSessionBar(b)
+	}
+	for _, a := range s.anchors {
+		if !b.Time.Before(a.spec.At) {
+			a.vwap.Add(b)
+		}
+	}
+	for _, w := range s.windows {
+		w.Add(b)
+	}
+}
+
+// Session returns symbol's VWAP for the current session, false before the
+// session's first bar.
+func (t *Tracker) Session(symbol string) (Value, bool) {
+	symbol = strings.ToUpper(symbol)
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	s, ok := t.symbols[symbol]
+	if !ok {
+		return Value{}, false
+	}
+	session, open := t.cal.SessionFor(t.now())
+	if !open || session.Date.Format("2006-01-02") != s.session {
+		// Yesterday's VWAP is no guide to today's prices
+		return Value{}, false
+	}
+	return s.vwap.reading()
+}
+
+// SessionPrice is the session VWAP alone, zero and false when unknown. It
+// has the shape order management and execution take.
+func (t *Tracker) SessionPrice(symbol string) (float64, bool) {
+	v, ok := t.Session(symbol)
+	return v.VWAP, ok
+}
+
+// Anchor starts an anchored VWAP for symbol at at under name, replacing
+// one of the same name. An anchor in the past is backfilled from history
+// when one is set; otherwise it counts from the next streamed bar.
+func (t *Tracker) Anchor(ctx context.Context, symbol, name string, at time.Time) (Value, error) {
+	symbol, name = strings.ToUpper(strings.TrimSpace(symbol)), strings.TrimSpace(name)
+	switch {
+	case symbol == "":
+		return Value{}, errors.New("symbol is required")
+	case name == "":
+		return Value{}, errors.New("name is required")
+	case at.IsZero():
+		return Value{}, errors.New("at is required")
+	}
+	t.mu.RLock()
+	now := t.now()
+	t.mu.RUnlock()
+	if at.After(now) {
+		return Value{}, errors.New("at must not be in the future")
+	}
+
+	a := &anchor{spec: AnchorSpec{Symbol: symbol, Name: name, At: at, CreatedAt: now}}
+	if err := t.backfill(ctx, a, now); err != nil {
+		return Value{}, err
+	}
+	t.mu.Lock()
+	t.stateLocked(symbol).anchors[name] = a
+	t.saveLocked()
+	t.mu.Unlock()
+	logger().Info("Anchored VWAP", "symbol", symbol, "name", name, "at", at)
+	v, _ := t.Anchored(symbol, name)
+	return v, nil
+}
+
+// backfill rebuilds a's VWAP from history up to now. Without a history
+// source it is left to count from the next streamed bar.
+func (t *Tracker) backfill(ctx context.Context, a *anchor, now time.Time) error {
+	t.mu.RLock()
+	history := t.history
+	t.mu.RUnlock()
+	if history == nil || now.Sub(a.spec.At) < time.Minute {
+		return nil
+	}
+	bars, err := history(ctx, a.spec.Symbol, a.spec.At, now)
+	if err != nil {
+		return fmt.Errorf("failed to backfill %s from %s: %w", a.spec.Symbol, a.spec.At.Format(time.RFC3339), err)
+	}
+	sort.Slice(bars, func(i, j int) bool { return bars[i].Time.Before(bars[j].Time) })
+	var w VWAP
+	for _, b := range bars {
+		if !b.Time.Before(a.spec.At) {
+			w.Add(b)
+		}
+	}
+	t.mu.Lock()
+	// Keep a streamed bar that arrived after the history ends
+	if a.vwap.bars > 0 && a.vwap.last.Time.After(w.last.Time) {
+		w.Add(a.vwap.last)
+	}
+	a.vwap = w
+	t.mu.Unlock()
+	return nil
+}
+
+// Backfill rebuilds every saved anchor from history, for use at startup.
+// Failures are logged and leave the anchor counting from live bars.
+func (t *Tracker) Backfill(ctx context.Context) {
+	t.mu.RLock()
+	now := t.now()
+	var all []*anchor
+	for _, s := range t.symbols {
+		for _, a := range s.anchors {
+			all = append(all, a)
+		}
+	}
+	t.mu.RUnlock()
+	for _, a := range all {
+		if err := t.backfill(ctx, a, now); err != nil {
+			logger().Warn("Failed to backfill anchored VWAP", "symbol", a.spec.Symbol, "name", a.spec.Name, "error", err)
+		}
+	}
+}
+
+// Anchored returns the named anchored VWAP of symbol, false when there is
+// no such anchor or it has no volume yet.
+func (t *Tracker) Anchored(symbol, name string) (Value, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	s, ok := t.symbols[strings.ToUpper(symbol)]
+	if !ok {
+		return Value{}, false
+	}
+	a, ok := s.anchors[name]
+	if !ok {
+		return Value{}, false
+	}
+	v, ok := a.vwap.reading()
+	v.Name, v.Anchor = a.spec.Name, a.spec.At
+	return v, ok
+}
+
+// RemoveAnchor deletes the named anchor of symbol.
+func (t *Tracker) RemoveAnchor(symbol, name string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.symbols[strings.ToUpper(symbol)]
+	if !ok {
+		return ErrNotFound
+	}
+	if _, ok := s.anchors[name]; !ok {
+		return ErrNotFound
+	}
+	delete(s.anchors, name)
+	t.saveLocked()
+	return nil
+}
+
+// Snapshot is a symbol's session VWAP and anchors.
+type Snapshot struct {
+	Symbol  string  `json:"symbol"`
+	Session *Value  `json:"session,omitempty"`
+	Anchors []Value `json:"anchors"` // by anchor time; those without volume have a zero vwap
+}
+
+// Snapshot returns symbol's VWAPs.
+func (t *Tracker) Snapshot(symbol string) Snapshot {
+	symbol = strings.ToUpper(symbol)
+	out := Snapshot{Symbol: symbol, Anchors: []Value{}}
+	if v, ok := t.Session(symbol); ok {
+		out.Session = &v
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if s, ok := t.symbols[symbol]; ok {
+		for _, a := range s.anchors {
+			v, _ := a.vwap.reading()
+			v.Name, v.Anchor = a.spec.Name, a.spec.At
+			out.Anchors = append(out.Anchors, v)
+		}
+	}
+	sort.Slice(out.Anchors, func(i, j int) bool { return out.Anchors[i].Anchor.Before(out.Anchors[j].Anchor) })
+	return out
+}
+
+// OpenWindow starts an unsaved VWAP of symbol from the next bar, for
+// measuring an order against the market over its life.
+func (t *Tracker) OpenWindow(symbol, id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stateLocked(strings.ToUpper(symbol)).windows[id] = &VWAP{}
+}
+
+// CloseWindow ends a window and returns its VWAP, false when it saw no
+// volume.
+func (t *Tracker) CloseWindow(symbol, id string) (float64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	s, ok := t.symbols[strings.ToUpper(symbol)]
+	if !ok {
+		return 0, false
+	}
+	w, ok := s.windows[id]
+	if !ok {
+		return 0, false
+	}
+	delete(s.windows, id)
+	return w.Value()
+}
+
+func (t *Tracker) saveLocked() {
+	specs := []AnchorSpec{}
+	for _, s := range t.symbols {
+		for _, a := range s.anchors {
+			specs = append(specs, a.spec)
+		}
+	}
+	sort.Slice(specs, func(i, j int) bool {
+		if specs[i].Symbol != specs[j].Symbol {
+			return specs[i].Symbol < specs[j].Symbol
+		}
+		return specs[i].Name < specs[j].Name
+	})
+	data, err := json.MarshalIndent(specs, "", "  ")
+	if err != nil {
+		logger().Error("Failed to encode anchors", "error", err)
+		return
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		logger().Error("Failed to write anchors", "error", err)
+		return
+	}
+	if err := os.Rename(tmp, t.path); err != nil {
+		logger().Error("Failed to save anchors", "error", err)
+	}
+}
@@ -0,0 +1,123 @@
+// Package indicators computes intraday indicators incrementally from the
+// streamed minute bars. It provides the session VWAP, reset at each
+// regular session, and anchored VWAPs that start at a chosen time such as
+// an earnings report or a swing low.
+package indicators
+
+import (
+	"math"
+	"time"
+)
+
+// Bar is a minute bar, as a VWAP and the session stats take it.
+type Bar struct {
+	Time   time.Time // the bar's start
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume float64
+	VWAP   float64 // the bar's own VWAP when the feed reports one
+}
+
+// price is the bar's representative price: its own VWAP, or the typical
+// price when the feed does not report one.
+func (b Bar) price() float64 {
+	if b.VWAP > 0 {
+		return b.VWAP
+	}
+	if b.High > 0 && b.Low > 0 {
+		return (b.High + b.Low + b.Close) / 3
+	}
+	return b.Close
+}
+
+// VWAP accumulates a volume-weighted average price bar by bar. A polled
+// feed revises the bar still forming, so observing the latest bar again
+// replaces its earlier contribution. The zero value is ready to use.
+type VWAP struct {
+	pv, v, p2v float64 // Σ price·volume, Σ volume, Σ price²·volume
+	bars       int
+	first      time.Time
+	last       Bar
+}
+
+// Add folds b into the average. Bars older than the latest are ignored
+// and reported false.
+func (w *VWAP) Add(b Bar) bool {
+	if b.Volume <= 0 || b.price() <= 0 {
+		return false
+	}
+	if w.bars > 0 {
+		switch {
+		case b.Time.Before(w.last.Time):
+			return false
+		case b.Time.Equal(w.last.Time):
+			w.remove(w.last)
+			w.bars--
+		}
+	}
+	p := b.price()
+	w.pv += p * b.Volume
+	w.v += b.Volume
+	w.p2v += p * p * b.Volume
+	if w.bars == 0 {
+		w.first = b.Time
+	}
+	w.bars++
+	w.last = b
+	return true
+}
+
+func (w *VWAP) remove(b Bar) {
+	p := b.price()
+	w.pv -= p * b.Volume
+	w.v -= b.Volume
+	w.p2v -= p * p * b.Volume
+}
+
+// Value returns the average, false before any volume.
+func (w *VWAP) Value() (float64, bool) {
+	if w.v <= 0 {
+		return 0, false
+	}
+	return w.pv / w.v, true
+}
+
+// StdDev is the volume-weighted standard deviation of price around the
+// average, the width of the usual VWAP bands.
+func (w *VWAP) StdDev() float64 {
+	vwap, ok := w.Value()
+	if !ok {
+		return 0
+	}
+	return math.Sqrt(math.Max(0, w.p2v/w.v-vwap*vwap))
+}
+
+// Value is a VWAP reading.
+type Value struct {
+	Name    string    `json:"name,omitempty"`   // the anchor's name; empty for the session VWAP
+	Anchor  time.Time `json:"anchor,omitempty"` // where an anchored VWAP starts
+	VWAP    float64   `json:"vwap"`
+	StdDev  float64   `json:"std_dev"`
+	Volume  float64   `json:"volume"`
+	Bars    int       `json:"bars"`
+	From    time.Time `json:"from"`    // the first bar included
+	Updated time.Time `json:"updated"` // the latest bar included
+}
+
+// reading returns w as a Value, false before any volume.
+func (w *VWAP) reading() (Value, bool) {
+	vwap, ok := w.Value()
+	if !ok {
+		return Value{}, false
+	}
+	return Value{
+		VWAP:    math.Round(vwap*10000) / 10000,
+		StdDev:  math.Round(w.StdDev()*10000) / 10000,
+		Volume:  w.v,
+		Bars:    w.bars,
+		From:    w.first,
+		Updated: w.last.Time,
+	}, true
+}
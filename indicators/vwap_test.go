@@ -0,0 +1,75 @@
+package indicators
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/calendar"
+)
+
+func TestVWAPReplacesRevisedBar(t *testing.T) {
+	at := time.Date(2026, 3, 2, 14, 31, 0, 0, time.UTC)
+	var w VWAP
+	w.Add(Bar{Time: at, Close: 100, Volume: 100})
+	w.Add(Bar{Time: at.Add(time.Minute), Close: 102, Volume: 100})
+	// The forming bar is polled again with more volume
+	w.Add(Bar{Time: at.Add(time.Minute), Close: 103, Volume: 300})
+	if w.Add(Bar{Time: at, Close: 500, Volume: 1}) {
+		t.Fatal("older bar accepted")
+	}
+	v, ok := w.Value()
+	if want := (100*100 + 103*300) / 400.0; !ok || math.Abs(v-want) > 1e-9 {
+		t.Fatalf("vwap = %v, want %v", v, want)
+	}
+	if sd := w.StdDev(); math.Abs(sd-math.Sqrt(0.75*0.25)*3) > 1e-9 {
+		t.Fatalf("std dev = %v", sd)
+	}
+}
+
+func TestTrackerSessionAnchorsAndWindows(t *testing.T) {
+	cal := calendar.New()
+	path := filepath.Join(t.TempDir(), "anchors.json")
+	tr, err := NewTracker(path, cal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := time.Date(2026, 3, 2, 9, 30, 0, 0, cal.Location())
+	now := open.Add(10 * time.Minute)
+	tr.SetClock(func() time.Time { return now })
+	tr.SetHistory(func(ctx context.Context, symbol string, from, to time.Time) ([]Bar, error) {
+		return []Bar{{Time: open.Add(-15 * time.Minute), VWAP: 90, Volume: 100}, {Time: open, VWAP: 100, Volume: 100}}, nil
+	})
+
+	// Pre-market bars count toward anchors but not the session
+	if _, err := tr.Anchor(context.Background(), "aapl", "swing-low", open.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	tr.OpenWindow("AAPL", "exe_1")
+	tr.Observe("AAPL", Bar{Time: open.Add(time.Minute), VWAP: 110, Volume: 100})
+
+	if v, ok := tr.Session("AAPL"); !ok || v.VWAP != 110 {
+		t.Fatalf("session = %+v, %v", v, ok)
+	}
+	if v, ok := tr.Anchored("AAPL", "swing-low"); !ok || v.VWAP != 100 || v.Bars != 3 {
+		t.Fatalf("anchored = %+v, %v", v, ok)
+	}
+	if v, ok := tr.CloseWindow("AAPL", "exe_1"); !ok || v != 110 {
+		t.Fatalf("window = %v, %v", v, ok)
+	}
+
+	// The next session starts afresh; anchors survive a restart
+	now = now.Add(24 * time.Hour)
+	if _, ok := tr.Session("AAPL"); ok {
+		t.Fatal("yesterday's session VWAP served")
+	}
+	reopened, err := NewTracker(path, cal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap := reopened.Snapshot("AAPL"); len(snap.Anchors) != 1 || snap.Anchors[0].Name != "swing-low" {
+		t.Fatalf("reopened = %+v", snap)
+	}
+}
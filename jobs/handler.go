@@ -0,0 +1,119 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/rileyseaburg/go-trader/paging"
+)
+
+// Handler exposes the job queue over HTTP.
+type Handler struct {
+	manager *Manager
+}
+
+// NewHandler creates a handler for manager.
+func NewHandler(manager *Manager) *Handler {
+	return &Handler{manager: manager}
+}
+
+// RegisterRoutes registers the job routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/jobs?kind=&status=&limit=&cursor=&sort=&filter= - jobs, newest first, and the registered kinds
+	// POST /api/jobs - {"kind": "...", "params": {...}} queue a job
+	mux.HandleFunc("/api/jobs", h.handleJobs)
+
+	// GET /api/jobs/{id} - one job
+	// POST /api/jobs/{id}/cancel - cancel a queued or running job
+	mux.HandleFunc("/api/jobs/", h.handleJob)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// jobList pages the jobs, newest first by default.
+var jobList = paging.List[Job]{
+	Fields: []paging.Field[Job]{
+		{Name: "created_at", Sort: func(j Job) paging.Key { return paging.Time(j.CreatedAt) }},
+		{Name: "kind", Sort: func(j Job) paging.Key { return paging.Text(j.Kind) }, Filter: func(j Job) string { return j.Kind }},
+		{Name: "status", Filter: func(j Job) string { return j.Status }},
+	},
+	ID:   func(j Job) string { return j.ID },
+	Sort: "-created_at",
+}
+
+func (h *Handler) handleJobs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		q := r.URL.Query()
+		page, ok := jobList.Page(w, r, h.manager.List(q.Get("kind"), q.Get("status")))
+		if !ok {
+			return
+		}
+		paging.SetHeaders(w, page.Total, page.NextCursor)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"jobs":  page.Items,
+			"kinds": h.manager.Kinds(),
+		})
+	case http.MethodPost:
+		var request struct {
+			Kind   string          `json:"kind"`
+			Params json.RawMessage `json:"params,omitempty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		job, err := h.manager.Submit(request.Kind, request.Params)
+		switch {
+		case errors.Is(err, ErrUnknownKind):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, ErrQueueFull):
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			writeJSON(w, http.StatusAccepted, job)
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleJob(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/api/jobs/")
+	id, action, _ := strings.Cut(rest, "/")
+	if id == "" {
+		http.Error(w, "Job ID is required", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		job, ok := h.manager.Get(id)
+		if !ok {
+			http.Error(w, ErrNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, job)
+	case action == "cancel" && r.Method == http.MethodPost:
+		job, err := h.manager.Cancel(id)
+		switch {
+		case errors.Is(err, ErrNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, ErrFinished):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			writeJSON(w, http.StatusOK, job)
+		}
+	case action == "" || action == "cancel":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,395 @@
+// Package jobs runs long-running tasks — backtests, optimizations, model
+// training, data downloads — on a bounded worker pool. Every job's status,
+// progress, result location and error is persisted so it can be inspected
+// after the fact, and queued or running jobs can be cancelled.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+func logger() *slog.Logger { return slog.With("module", "jobs") }
+
+// Job statuses.
+const (
+	StatusQueued    = "queued"
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCanceled  = "canceled"
+)
+
+// maxFinished bounds how many finished jobs are kept on disk.
+const maxFinished = 500
+
+var (
+	// ErrNotFound is returned for unknown job IDs.
+	ErrNotFound = errors.New("job not found")
+	// ErrUnknownKind is returned when no runner is registered for a kind.
+	ErrUnknownKind = errors.New("unknown job kind")
+	// ErrQueueFull is returned when the queue cannot take another job.
+	ErrQueueFull = errors.New("job queue is full")
+	// ErrFinished is returned when cancelling a job that already ended.
+	ErrFinished = errors.New("job already finished")
+)
+
+// Job is the persisted record of one task.
+type Job struct {
+	ID         string          `json:"id"`
+	Kind       string          `json:"kind"`
+	Params     json.RawMessage `json:"params,omitempty"`
+	Status     string          `json:"status"`
+	Progress   float64         `json:"progress"` // 0 to 1
+	Message    string          `json:"message,omitempty"`
+	Result     string          `json:"result,omitempty"` // where the output can be found
+	Error      string          `json:"error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+}
+
+// Finished reports whether the job has reached a terminal status.
+func (j Job) Finished() bool {
+	switch j.Status {
+	case StatusSucceeded, StatusFailed, StatusCanceled:
+		return true
+	}
+	return false
+}
+
+// Progress lets a running task report how far along it is.
+type Progress struct {
+	m  *Manager
+	id string
+}
+
+// Report records progress as a fraction between 0 and 1 with an optional
+// status message.
+func (p *Progress) Report(fraction float64, message string) {
+	if fraction < 0 {
+		fraction = 0
+	} else if fraction > 1 {
+		fraction = 1
+	}
+	p.m.update(p.id, func(j *Job) {
+		j.Progress = fraction
+		if message != "" {
+			j.Message = message
+		}
+	}, false)
+}
+
+// Runner performs a job of one kind. It should return promptly once ctx
+// is cancelled. The returned result is the location of the job's output,
+// empty if it has none.
+type Runner func(ctx context.Context, params json.RawMessage, progress *Progress) (result string, err error)
+
+// Manager queues jobs, runs them on a fixed number of workers and
+// persists their records to a JSON file.
+type Manager struct {
+	path    string
+	workers int
+	queue   chan string
+
+	mu      sync.Mutex
+	runners map[string]Runner
+	jobs    map[string]*Job
+	cancels map[string]context.CancelFunc
+	seq     int
+}
+
+// New loads job records from path and returns a manager with the given
+// number of workers and queue capacity. Jobs that were queued or running
+// when the process last stopped are marked failed.
+func New(path string, workers, queueSize int) (*Manager, error) {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	m := &Manager{
+		path:    path,
+		workers: workers,
+		queue:   make(chan string, queueSize),
+		runners: make(map[string]Runner),
+		jobs:    make(map[string]*Job),
+		cancels: make(map[string]context.CancelFunc),
+	}
+	if path == "" {
+		return m, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create jobs directory: %w", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read jobs: %w", err)
+	}
+	if len(data) > 0 {
+		var saved []Job
+		if err := json.Unmarshal(data, &saved); err != nil {
+			return nil, fmt.Errorf("failed to parse jobs: %w", err)
+		}
+		now := time.Now()
+		for i := range saved {
+			j := saved[i]
+			if !j.Finished() {
+				j.Status = StatusFailed
+				j.Error = "interrupted by restart"
+				j.FinishedAt = &now
+			}
+			m.jobs[j.ID] = &j
+		}
+		m.saveLocked()
+	}
+	return m, nil
+}
+
+// Register adds the runner for kind, replacing any earlier one.
+func (m *Manager) Register(kind string, run Runner) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.runners[kind] = run
+}
+
+// Kinds lists the registered job kinds, sorted.
+func (m *Manager) Kinds() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	kinds := make([]string, 0, len(m.runners))
+	for k := range m.runners {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// Submit queues a job of kind with params.
+func (m *Manager) Submit(kind string, params json.RawMessage) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.runners[kind]; !ok {
+		return Job{}, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+
+	now := time.Now()
+	m.seq++
+	j := &Job{
+		ID:        fmt.Sprintf("job_%d_%d", now.UnixNano(), m.seq),
+		Kind:      kind,
+		Params:    params,
+		Status:    StatusQueued,
+		CreatedAt: now,
+	}
+	select {
+	case m.queue <- j.ID:
+	default:
+		return Job{}, ErrQueueFull
+	}
+	m.jobs[j.ID] = j
+	m.saveLocked()
+	return *j, nil
+}
+
+// Get returns the job with id.
+func (m *Manager) Get(id string) (Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *j, true
+}
+
+// List returns jobs matching kind and status, newest first. Empty filters
+// match everything.
+func (m *Manager) List(kind, status string) []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		if (kind == "" || j.Kind == kind) && (status == "" || j.Status == status) {
+			out = append(out, *j)
+		}
+	}
+	sortNewestFirst(out)
+	return out
+}
+
+// Cancel stops a queued or running job. A running job is marked cancelled
+// once its runner returns.
+func (m *Manager) Cancel(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	if j.Finished() {
+		return *j, ErrFinished
+	}
+	if cancel, running := m.cancels[id]; running {
+		cancel()
+		j.Message = "cancelling"
+		return *j, nil
+	}
+	now := time.Now()
+	j.Status = StatusCanceled
+	j.FinishedAt = &now
+	m.saveLocked()
+	return *j, nil
+}
+
+// Run starts the workers and blocks until ctx is cancelled. Running jobs
+// are cancelled on the way out.
+func (m *Manager) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < m.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-m.queue:
+					m.execute(ctx, id)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// execute runs one queued job, skipping it if it was cancelled while
+// waiting.
+func (m *Manager) execute(parent context.Context, id string) {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	if !ok || j.Status != StatusQueued {
+		m.mu.Unlock()
+		return
+	}
+	kind, params := j.Kind, j.Params
+	run := m.runners[kind]
+	now := time.Now()
+	j.Status = StatusRunning
+	j.StartedAt = &now
+	m.cancels[id] = cancel
+	m.saveLocked()
+	m.mu.Unlock()
+
+	result, err := runSafely(ctx, run, params, &Progress{m: m, id: id})
+
+	m.mu.Lock()
+	delete(m.cancels, id)
+	m.mu.Unlock()
+	m.update(id, func(j *Job) {
+		done := time.Now()
+		j.FinishedAt = &done
+		j.Result = result
+		switch {
+		case ctx.Err() != nil:
+			j.Status = StatusCanceled
+			j.Message = ""
+		case err != nil:
+			j.Status = StatusFailed
+			j.Error = err.Error()
+		default:
+			j.Status = StatusSucceeded
+			j.Progress = 1
+		}
+	}, true)
+	if err != nil && ctx.Err() == nil {
+		logger().Error("Job failed", "job", id, "kind", kind, "error", err)
+	}
+}
+
+// runSafely turns a runner panic into a job failure.
+func runSafely(ctx context.Context, run Runner, params json.RawMessage, p *Progress) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return run(ctx, params, p)
+}
+
+// update applies fn to the job with id, persisting the change when save is
+// set. Progress reports are kept in memory and written with the next
+// status change.
+func (m *Manager) update(id string, fn func(*Job), save bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return
+	}
+	fn(j)
+	if save {
+		m.saveLocked()
+	}
+}
+
+// saveLocked prunes the oldest finished jobs beyond maxFinished and writes
+// the records to disk. Callers must hold m.mu.
+func (m *Manager) saveLocked() {
+	all := make([]Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		all = append(all, *j)
+	}
+	sortNewestFirst(all)
+	finished := 0
+	kept := all[:0]
+	for _, j := range all {
+		if j.Finished() {
+			finished++
+			if finished > maxFinished {
+				delete(m.jobs, j.ID)
+				continue
+			}
+		}
+		kept = append(kept, j)
+	}
+
+	if m.path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(kept, "", "  ")
+	if err != nil {
+		logger().Error("Failed to encode jobs", "error", err)
+		return
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		logger().Error("Failed to write jobs", "error", err)
+		return
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		logger().Error("Failed to save jobs", "error", err)
+	}
+}
+
+func sortNewestFirst(jobs []Job) {
+	sort.Slice(jobs, func(i, k int) bool {
+		if !jobs[i].CreatedAt.Equal(jobs[k].CreatedAt) {
+			return jobs[i].CreatedAt.After(jobs[k].CreatedAt)
+		}
+		return jobs[i].ID > jobs[k].ID
+	})
+}
@@ -0,0 +1,99 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitFor polls the job until it finishes or the test times out.
+func waitFor(t *testing.T, m *Manager, id string) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if j, _ := m.Get(id); j.Finished() {
+			return j
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %s did not finish", id)
+	return Job{}
+}
+
+func TestJobsRunCancelAndPersist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	m, err := New(path, 1, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	m.Register("download", func(ctx context.Context, params json.RawMessage, p *Progress) (string, error) {
+		p.Report(0.5, "halfway")
+		return "data/" + string(params), nil
+	})
+	m.Register("block", func(ctx context.Context, params json.RawMessage, p *Progress) (string, error) {
+		close(started)
+		<-ctx.Done()
+		return "", ctx.Err()
+	})
+	m.Register("fail", func(ctx context.Context, params json.RawMessage, p *Progress) (string, error) {
+		return "", errors.New("boom")
+	})
+
+	if _, err := m.Submit("nope", nil); !errors.Is(err, ErrUnknownKind) {
+		t.Errorf("unknown kind err = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go m.Run(ctx)
+
+	ok, _ := m.Submit("download", json.RawMessage(`"bars"`))
+	if j := waitFor(t, m, ok.ID); j.Status != StatusSucceeded || j.Result != `data/"bars"` || j.Progress != 1 {
+		t.Errorf("succeeded job = %+v", j)
+	}
+	failed, _ := m.Submit("fail", nil)
+	if j := waitFor(t, m, failed.ID); j.Status != StatusFailed || j.Error != "boom" {
+		t.Errorf("failed job = %+v", j)
+	}
+
+	// One worker: the queued job behind the blocking one can be cancelled
+	// before it starts, and the running one stops when cancelled
+	running, _ := m.Submit("block", nil)
+	<-started
+	queued, _ := m.Submit("download", nil)
+	if j, err := m.Cancel(queued.ID); err != nil || j.Status != StatusCanceled {
+		t.Errorf("cancel queued = %+v, %v", j, err)
+	}
+	if _, err := m.Cancel(running.ID); err != nil {
+		t.Fatal(err)
+	}
+	if j := waitFor(t, m, running.ID); j.Status != StatusCanceled {
+		t.Errorf("cancelled running job = %+v", j)
+	}
+	if _, err := m.Cancel(running.ID); !errors.Is(err, ErrFinished) {
+		t.Errorf("second cancel err = %v", err)
+	}
+	cancel()
+
+	// A job left queued by a shutdown is failed on reload
+	stopped, _ := New(path, 1, 1)
+	stopped.Register("download", nil)
+	left, _ := stopped.Submit("download", nil)
+
+	reloaded, err := New(path, 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j, _ := reloaded.Get(left.ID); j.Status != StatusFailed || j.Error != "interrupted by restart" {
+		t.Errorf("interrupted job = %+v", j)
+	}
+	if got := len(reloaded.List("", "")); got != 5 {
+		t.Errorf("reloaded %d jobs, want 5", got)
+	}
+	if got := len(reloaded.List("download", StatusCanceled)); got != 1 {
+		t.Errorf("filtered %d jobs, want 1", got)
+	}
+}
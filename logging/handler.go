@@ -0,0 +1,67 @@
+package logging
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Handler exposes the logging configuration over HTTP.
+type Handler struct {
+	manager *Manager
+}
+
+// NewHandler creates a handler for manager.
+func NewHandler(manager *Manager) *Handler {
+	return &Handler{manager: manager}
+}
+
+// RegisterRoutes registers the logging route with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET|POST /api/logging - levels, per-module overrides and known modules
+	mux.HandleFunc("/api/logging", h.handleConfig)
+}
+
+type configResponse struct {
+	Config
+	KnownModules []string `json:"known_modules"`
+}
+
+func (h *Handler) handleConfig(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost, http.MethodPut:
+		// Start from the current config so partial updates work; a module
+		// override set to "" is removed
+		cfg := h.manager.Config()
+		var req struct {
+			Level   *string           `json:"level"`
+			Format  *string           `json:"format"`
+			Modules map[string]string `json:"modules"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Level != nil {
+			cfg.Level = *req.Level
+		}
+		if req.Format != nil {
+			cfg.Format = *req.Format
+		}
+		for module, level := range req.Modules {
+			if level == "" {
+				delete(cfg.Modules, module)
+			} else {
+				cfg.Modules[module] = level
+			}
+		}
+		if err := h.manager.SetConfig(cfg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(configResponse{Config: h.manager.Config(), KnownModules: h.manager.Modules()})
+}
@@ -0,0 +1,300 @@
+// Package logging configures the process-wide structured logger. Packages
+// log through log/slog with a "module" attribute naming themselves; the
+// handler installed here filters records by a global level with per-module
+// overrides that can change at runtime, writes text or JSON, and redacts
+// API keys and other secrets before anything reaches the output. Output
+// from the standard log package is routed through the same handler.
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ModuleKey is the attribute naming the package a record came from.
+const ModuleKey = "module"
+
+// Output formats.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Config is the logging configuration. Format is fixed at startup; levels
+// can change at runtime.
+type Config struct {
+	Level   string            `json:"level"`   // debug, info, warn or error
+	Format  string            `json:"format"`  // text or json
+	Modules map[string]string `json:"modules"` // per-module level overrides
+}
+
+// DefaultConfig logs info and above as text.
+func DefaultConfig() Config {
+	return Config{Level: "info", Format: FormatText, Modules: map[string]string{}}
+}
+
+// Validate checks every level and the format.
+func (c Config) Validate() error {
+	if _, err := ParseLevel(c.Level); err != nil {
+		return err
+	}
+	if c.Format != FormatText && c.Format != FormatJSON {
+		return fmt.Errorf("format must be %s or %s", FormatText, FormatJSON)
+	}
+	for module, level := range c.Modules {
+		if module == "" {
+			return errors.New("module names must not be empty")
+		}
+		if _, err := ParseLevel(level); err != nil {
+			return fmt.Errorf("module %s: %w", module, err)
+		}
+	}
+	return nil
+}
+
+// ParseLevel parses debug, info, warn (or warning) or error.
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("invalid level %q: use debug, info, warn or error", s)
+}
+
+// ParseModules parses per-module overrides such as "ticker=debug,claude=warn".
+func ParseModules(s string) (map[string]string, error) {
+	modules := make(map[string]string)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		module, level, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid module level %q: use module=level", part)
+		}
+		module = strings.TrimSpace(module)
+		if _, err := ParseLevel(level); err != nil {
+			return nil, fmt.Errorf("module %s: %w", module, err)
+		}
+		modules[module] = strings.ToLower(strings.TrimSpace(level))
+	}
+	return modules, nil
+}
+
+// Manager owns the installed handler's configuration.
+type Manager struct {
+	out      slog.Handler
+	redactor *Redactor
+
+	mu      sync.RWMutex
+	cfg     Config
+	level   slog.Level
+	modules map[string]slog.Level
+	seen    map[string]bool
+}
+
+// New returns a manager writing to w.
+func New(w io.Writer, cfg Config) (*Manager, error) {
+	if cfg.Format == "" {
+		cfg.Format = FormatText
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	// The inner handler passes everything; the manager does the filtering
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var out slog.Handler = slog.NewTextHandler(w, opts)
+	if cfg.Format == FormatJSON {
+		out = slog.NewJSONHandler(w, opts)
+	}
+	m := &Manager{out: out, redactor: NewRedactor(), seen: make(map[string]bool)}
+	m.apply(cfg)
+	return m, nil
+}
+
+// Install makes the manager's handler the default slog and log output.
+func (m *Manager) Install() {
+	slog.SetDefault(slog.New(m.Handler()))
+}
+
+// Handler returns the filtering, redacting handler.
+func (m *Manager) Handler() slog.Handler {
+	return &handler{m: m, inner: m.out}
+}
+
+// Redactor returns the manager's redactor, for registering secrets.
+func (m *Manager) Redactor() *Redactor {
+	return m.redactor
+}
+
+// Config returns the current configuration.
+func (m *Manager) Config() Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	cfg := m.cfg
+	cfg.Modules = make(map[string]string, len(m.cfg.Modules))
+	for k, v := range m.cfg.Modules {
+		cfg.Modules[k] = v
+	}
+	return cfg
+}
+
+// SetConfig changes the levels. The format cannot change once output has
+// started.
+func (m *Manager) SetConfig(cfg Config) error {
+	if cfg.Modules == nil {
+		cfg.Modules = map[string]string{}
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	m.mu.RLock()
+	format := m.cfg.Format
+	m.mu.RUnlock()
+	if cfg.Format != format {
+		return fmt.Errorf("format is fixed at startup as %s", format)
+	}
+	m.apply(cfg)
+	return nil
+}
+
+func (m *Manager) apply(cfg Config) {
+	level, _ := ParseLevel(cfg.Level)
+	modules := make(map[string]slog.Level, len(cfg.Modules))
+	normalized := make(map[string]string, len(cfg.Modules))
+	for module, l := range cfg.Modules {
+		modules[module], _ = ParseLevel(l)
+		normalized[module] = strings.ToLower(strings.TrimSpace(l))
+	}
+	cfg.Level = strings.ToLower(strings.TrimSpace(cfg.Level))
+	cfg.Modules = normalized
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cfg = cfg
+	m.level = level
+	m.modules = modules
+}
+
+// Modules returns the modules that have logged or created a logger, sorted.
+func (m *Manager) Modules() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]string, 0, len(m.seen))
+	for module := range m.seen {
+		out = append(out, module)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (m *Manager) enabled(module string, level slog.Level) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	min, ok := m.modules[module]
+	if !ok {
+		min = m.level
+	}
+	return level >= min
+}
+
+func (m *Manager) see(module string) {
+	m.mu.RLock()
+	seen := m.seen[module]
+	m.mu.RUnlock()
+	if !seen {
+		m.mu.Lock()
+		m.seen[module] = true
+		m.mu.Unlock()
+	}
+}
+
+// Fatal logs msg at error level and exits.
+func Fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+// handler filters by module level and redacts before the inner handler.
+type handler struct {
+	m      *Manager
+	inner  slog.Handler
+	module string
+}
+
+func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
+	// A record may still name its module in its own attributes, so a
+	// handler without one only rules out what no override could allow
+	if h.module == "" {
+		return h.m.anyEnabled(level)
+	}
+	return h.m.enabled(h.module, level)
+}
+
+func (m *Manager) anyEnabled(level slog.Level) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if level >= m.level {
+		return true
+	}
+	for _, min := range m.modules {
+		if level >= min {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	module := h.module
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		if module == "" && a.Key == ModuleKey {
+			module = a.Value.String()
+		}
+		attrs = append(attrs, h.m.redactor.Attr(a))
+		return true
+	})
+	if module != "" {
+		h.m.see(module)
+	}
+	if !h.m.enabled(module, r.Level) {
+		return nil
+	}
+	out := slog.NewRecord(r.Time, r.Level, h.m.redactor.String(r.Message), r.PC)
+	out.AddAttrs(attrs...)
+	return h.inner.Handle(ctx, out)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	module := h.module
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		if a.Key == ModuleKey {
+			module = a.Value.String()
+		}
+		redacted[i] = h.m.redactor.Attr(a)
+	}
+	if module != h.module {
+		h.m.see(module)
+	}
+	return &handler{m: h.m, inner: h.inner.WithAttrs(redacted), module: module}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return &handler{m: h.m, inner: h.inner.WithGroup(name), module: h.module}
+}
@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func newTestLogger(t *testing.T, cfg Config) (*Manager, *slog.Logger, *bytes.Buffer) {
+	t.Helper()
+	var buf bytes.Buffer
+	m, err := New(&buf, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, slog.New(m.Handler()), &buf
+}
+
+func TestModuleLevelOverrides(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Modules = map[string]string{"ticker": "debug", "claude": "error"}
+	m, logger, buf := newTestLogger(t, cfg)
+
+	logger.With(ModuleKey, "ticker").Debug("polled")
+	logger.With(ModuleKey, "claude").Warn("retrying")
+	logger.With(ModuleKey, "orders").Debug("hidden")
+	logger.Info("attribute module", ModuleKey, "claude")
+	logger.Info("no module")
+
+	out := buf.String()
+	if !strings.Contains(out, "polled") || !strings.Contains(out, "no module") {
+		t.Fatalf("missing enabled records:\n%s", out)
+	}
+	if strings.Contains(out, "retrying") || strings.Contains(out, "hidden") || strings.Contains(out, "attribute module") {
+		t.Fatalf("records below their module level were written:\n%s", out)
+	}
+
+	// Levels change at runtime, including for loggers already created
+	claude := logger.With(ModuleKey, "claude")
+	cfg = m.Config()
+	cfg.Modules["claude"] = "info"
+	if err := m.SetConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+	claude.Info("now visible")
+	if !strings.Contains(buf.String(), "now visible") {
+		t.Fatal("override change did not apply to an existing logger")
+	}
+	if got := m.Modules(); len(got) != 3 {
+		t.Fatalf("known modules = %v", got)
+	}
+}
+
+func TestSetConfigRejectsInvalidLevelsAndFormatChanges(t *testing.T) {
+	m, _, _ := newTestLogger(t, DefaultConfig())
+	cfg := m.Config()
+	cfg.Modules["ticker"] = "verbose"
+	if err := m.SetConfig(cfg); err == nil {
+		t.Fatal("invalid module level accepted")
+	}
+	cfg = m.Config()
+	cfg.Format = FormatJSON
+	if err := m.SetConfig(cfg); err == nil {
+		t.Fatal("format change accepted")
+	}
+}
+
+func TestJSONOutputRedactsSecrets(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Format = FormatJSON
+	m, logger, buf := newTestLogger(t, cfg)
+	m.Redactor().AddSecret("my-secret-value", "short")
+
+	logger.Info("connecting with my-secret-value and PKABCDEFGHIJKLMNOPQR",
+		"api_key", "anything",
+		"err", errors.New("401: Authorization: Bearer abc.def.ghi"),
+		"note", "short",
+		slog.Group("claude", "key", "sk-ant-api03-xyz"),
+	)
+
+	var rec map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("output is not JSON: %v\n%s", err, buf.String())
+	}
+	out := buf.String()
+	for _, leak := range []string{"my-secret-value", "PKABCDEFGHIJKLMNOPQR", "anything", "abc.def.ghi", "sk-ant-api03-xyz"} {
+		if strings.Contains(out, leak) {
+			t.Errorf("%q leaked:\n%s", leak, out)
+		}
+	}
+	if rec["note"] != "short" {
+		t.Errorf("short registered value was redacted: %v", rec["note"])
+	}
+	if !strings.Contains(rec["err"].(string), "Bearer "+Redacted) {
+		t.Errorf("err = %v", rec["err"])
+	}
+}
+
+func TestParseModules(t *testing.T) {
+	got, err := ParseModules("ticker=debug, claude=WARN,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["ticker"] != "debug" || got["claude"] != "warn" {
+		t.Fatalf("modules = %v", got)
+	}
+	if _, err := ParseModules("ticker"); err == nil {
+		t.Fatal("missing level accepted")
+	}
+}
@@ -0,0 +1,115 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces secrets in log output.
+const Redacted = "[REDACTED]"
+
+// minSecretLen keeps short registered values such as "true" from being
+// redacted everywhere they appear.
+const minSecretLen = 8
+
+// sensitiveKeys are attribute key fragments whose values are always
+// redacted.
+var sensitiveKeys = []string{"secret", "password", "passwd", "token", "api_key", "apikey", "authorization", "credential"}
+
+// secretPatterns match credentials that were never registered: Alpaca key
+// IDs, Anthropic keys, bearer tokens and Alpaca auth headers.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b[AP]K[A-Z0-9]{16,}\b`),
+	regexp.MustCompile(`sk-ant-[A-Za-z0-9_\-]+`),
+	regexp.MustCompile(`(?i)\b(bearer\s+)[A-Za-z0-9._~+/\-]+=*`),
+	regexp.MustCompile(`(?i)\b(apca-api-(?:key-id|secret-key)["']?\s*[:=]\s*["']?)[^\s"',}]+`),
+}
+
+// Redactor strips secrets from messages and attributes.
+type Redactor struct {
+	mu      sync.RWMutex
+	secrets []string
+}
+
+// NewRedactor returns a redactor with only the built-in patterns.
+func NewRedactor() *Redactor {
+	return &Redactor{}
+}
+
+// AddSecret registers values, such as API keys read from the environment,
+// to redact wherever they appear. Empty and short values are ignored.
+func (r *Redactor) AddSecret(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, v := range values {
+		if len(v) < minSecretLen {
+			continue
+		}
+		dup := false
+		for _, s := range r.secrets {
+			dup = dup || s == v
+		}
+		if !dup {
+			r.secrets = append(r.secrets, v)
+		}
+	}
+}
+
+// String redacts registered secrets and known credential patterns in s.
+func (r *Redactor) String(s string) string {
+	r.mu.RLock()
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, Redacted)
+	}
+	r.mu.RUnlock()
+	for _, p := range secretPatterns {
+		if p.NumSubexp() > 0 {
+			s = p.ReplaceAllString(s, "${1}"+Redacted)
+		} else {
+			s = p.ReplaceAllString(s, Redacted)
+		}
+	}
+	return s
+}
+
+// Attr redacts a whole value under a sensitive key, and secrets within any
+// other string, error or Stringer value. Groups are redacted recursively.
+func (r *Redactor) Attr(a slog.Attr) slog.Attr {
+	if sensitiveKey(a.Key) && a.Value.Kind() != slog.KindGroup {
+		return slog.String(a.Key, Redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, r.String(a.Value.String()))
+	case slog.KindGroup:
+		group := a.Value.Group()
+		attrs := make([]any, len(group))
+		for i, g := range group {
+			attrs[i] = r.Attr(g)
+		}
+		return slog.Group(a.Key, attrs...)
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case error:
+			return slog.String(a.Key, r.String(v.Error()))
+		case fmt.Stringer:
+			return slog.String(a.Key, r.String(v.String()))
+		}
+	case slog.KindLogValuer:
+		return r.Attr(slog.Attr{Key: a.Key, Value: a.Value.Resolve()})
+	}
+	return a
+}
+
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range sensitiveKeys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,13 +2,16 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -18,12 +21,61 @@ import (
 
 	// Ensure algorithm package is imported first, before algorithm/algo,
 	// to avoid any import conflict or shadowing issues
+	"github.com/rileyseaburg/go-trader/activity"
+	"github.com/rileyseaburg/go-trader/aging"
+	"github.com/rileyseaburg/go-trader/aicost"
+	"github.com/rileyseaburg/go-trader/aieval"
 	"github.com/rileyseaburg/go-trader/algorithm"
 	"github.com/rileyseaburg/go-trader/algorithm/algo"
+	"github.com/rileyseaburg/go-trader/apiqueue"
+	"github.com/rileyseaburg/go-trader/approvals"
+	"github.com/rileyseaburg/go-trader/audit"
+	"github.com/rileyseaburg/go-trader/breadth"
+	"github.com/rileyseaburg/go-trader/budgets"
+	"github.com/rileyseaburg/go-trader/calendar"
 	"github.com/rileyseaburg/go-trader/cartography"
+	"github.com/rileyseaburg/go-trader/chatops"
+	"github.com/rileyseaburg/go-trader/circuit"
 	"github.com/rileyseaburg/go-trader/claude"
+	"github.com/rileyseaburg/go-trader/confirmations"
+	"github.com/rileyseaburg/go-trader/dashboard"
+	"github.com/rileyseaburg/go-trader/datadir"
+	"github.com/rileyseaburg/go-trader/dataquality"
+	"github.com/rileyseaburg/go-trader/diagnostics"
+	"github.com/rileyseaburg/go-trader/drawdown"
+	"github.com/rileyseaburg/go-trader/earnings"
+	"github.com/rileyseaburg/go-trader/execution"
+	"github.com/rileyseaburg/go-trader/featurestore"
+	"github.com/rileyseaburg/go-trader/fills"
+	"github.com/rileyseaburg/go-trader/fixtures"
+	"github.com/rileyseaburg/go-trader/gaprisk"
+	"github.com/rileyseaburg/go-trader/impliedmove"
+	"github.com/rileyseaburg/go-trader/indicators"
+	"github.com/rileyseaburg/go-trader/jobs"
+	"github.com/rileyseaburg/go-trader/logging"
 	"github.com/rileyseaburg/go-trader/notification"
+	"github.com/rileyseaburg/go-trader/orders"
+	"github.com/rileyseaburg/go-trader/paging"
+	"github.com/rileyseaburg/go-trader/pdt"
+	"github.com/rileyseaburg/go-trader/portfoliostream"
+	"github.com/rileyseaburg/go-trader/premarket"
+	"github.com/rileyseaburg/go-trader/replay"
+	"github.com/rileyseaburg/go-trader/restrictions"
+	"github.com/rileyseaburg/go-trader/riskhistory"
+	"github.com/rileyseaburg/go-trader/router"
+	"github.com/rileyseaburg/go-trader/scheduler"
+	"github.com/rileyseaburg/go-trader/secrets"
+	"github.com/rileyseaburg/go-trader/shadow"
+	"github.com/rileyseaburg/go-trader/signalstore"
 	"github.com/rileyseaburg/go-trader/ticker"
+	"github.com/rileyseaburg/go-trader/ticks"
+	"github.com/rileyseaburg/go-trader/tradingmode"
+	"github.com/rileyseaburg/go-trader/tradingview"
+	"github.com/rileyseaburg/go-trader/tsdb"
+	"github.com/rileyseaburg/go-trader/tuning"
+	"github.com/rileyseaburg/go-trader/universe"
+	"github.com/rileyseaburg/go-trader/users"
+	"github.com/rileyseaburg/go-trader/webhooks"
 
 	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
@@ -31,24 +83,136 @@ import (
 	"github.com/shopspring/decimal"
 )
 
-// convertHistoricalDataToMarketData converts from types.HistoricalData to []types.MarketData
-func convertHistoricalDataToMarketData(data *types.HistoricalData) []types.MarketData {
-	if data == nil || len(data.Data) == 0 {
-		return []types.MarketData{}
+func logger() *slog.Logger { return slog.With("module", "main") }
+
+// newSecretLoader chains the Alpaca key providers: the -alpaca-key and
+// -alpaca-secret flags, the environment, the secrets directory and Vault.
+// Every key found is registered with the log redactor.
+func newSecretLoader(lm *logging.Manager, flagKey, flagSecret, dir string, vl *cartography.VaultLoader) *secrets.Loader {
+	var providers []secrets.Provider
+	if flagKey != "" || flagSecret != "" {
+		providers = append(providers, secrets.Static{Name: "flag", Values: map[string]string{
+			"PAPER_ALPACA_API_KEY":    flagKey,
+			"LIVE_ALPACA_API_KEY":     flagKey,
+			"PAPER_ALPACA_SECRET_KEY": flagSecret,
+			"LIVE_ALPACA_SECRET_KEY":  flagSecret,
+		}})
 	}
+	providers = append(providers, secrets.Env{})
+	if dir != "" {
+		providers = append(providers, secrets.File{Dir: dir})
+	}
+	if vl != nil {
+		path := os.Getenv("GO_TRADER_VAULT_PATH")
+		if path == "" {
+			path = "secret/go-trader/alpaca"
+		}
+		providers = append(providers, secrets.Vault{Reader: vl, Path: path})
+	}
+	return secrets.NewLoader(lm.Redactor().AddSecret, providers...)
+}
+
+// livePositions renders the algorithm's live-marked positions in Alpaca's
+// position format, decimals as strings, so clients of /api/positions see
+// the same shape whichever source answered. Positions the fills journal
+// shows opening also carry their age against their strategy's horizon.
+func livePositions(p algorithm.PortfolioData, ages map[string]aging.Age) []map[string]interface{} {
+	format := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+	out := make([]map[string]interface{}, 0, len(p.Positions))
+	for _, sym := range p.HeldSymbols() {
+		pos := p.Positions[sym]
+		side := "long"
+		if pos.Quantity < 0 {
+			side = "short"
+		}
+		row := map[string]interface{}{
+			"symbol":          pos.Symbol,
+			"qty":             format(pos.Quantity),
+			"side":            side,
+			"avg_entry_price": format(pos.AvgPrice),
+			"current_price":   format(pos.CurrentPrice),
+			"market_value":    format(pos.MarketVal),
+			"unrealized_pl":   format(pos.Profit),
+			"unrealized_plpc": format(pos.Return / 100),
+			"priced_at":       pos.PricedAt,
+		}
+		if age, ok := ages[strings.ToUpper(sym)]; ok && age.Side == side && age.OpenedAt != nil {
+			row["opened_at"] = age.OpenedAt
+			row["age_hours"] = age.AgeHours
+			row["age_sessions"] = age.Sessions
+			row["horizon_sessions"] = age.Horizon
+			row["stale"] = age.Stale
+		}
+		out = append(out, row)
+	}
+	return out
+}
+
+// alpacaOrdersPage is the most orders Alpaca returns to one request.
+const alpacaOrdersPage = 500
+
+// orderList pages GET /api/orders. Symbol filters are passed to Alpaca;
+// the rest apply to the orders it returns.
+var orderList = paging.List[alpaca.Order]{
+	Fields: []paging.Field[alpaca.Order]{
+		{Name: "submitted_at", Sort: func(o alpaca.Order) paging.Key { return paging.Time(o.SubmittedAt) }},
+		{Name: "symbol", Sort: func(o alpaca.Order) paging.Key { return paging.Text(o.Symbol) }, Filter: func(o alpaca.Order) string { return o.Symbol }},
+		{Name: "status", Filter: func(o alpaca.Order) string { return o.Status }},
+		{Name: "side", Filter: func(o alpaca.Order) string { return string(o.Side) }},
+		{Name: "type", Filter: func(o alpaca.Order) string { return string(o.Type) }},
+	},
+	ID:   func(o alpaca.Order) string { return o.ID },
+	Sort: "-submitted_at",
+}
 
-	result := make([]types.MarketData, len(data.Data))
-	for i, point := range data.Data {
-		result[i] = types.MarketData{
-			Symbol:    point.Symbol,
-			Price:     point.Close,           // Using Close price as the current price
-			High24h:   point.High,            // Using daily high
-			Low24h:    point.Low,             // Using daily low
-			Volume24h: float64(point.Volume), // Converting volume
-			Change24h: 0,                     // Not available in historical data
+// fetchOrders returns every order query matches, asking Alpaca for one
+// page after another, each older than the last, so lists count and sort
+// all of them rather than the newest page.
+func fetchOrders(client *alpaca.Client, query alpaca.GetOrdersRequest) ([]alpaca.Order, error) {
+	query.Limit = alpacaOrdersPage
+	seen := make(map[string]bool)
+	var all []alpaca.Order
+	for {
+		orders, err := client.GetOrders(query)
+		if err != nil {
+			return nil, err
+		}
+		added := 0
+		for _, o := range orders {
+			if !seen[o.ID] {
+				seen[o.ID] = true
+				all = append(all, o)
+				added++
+			}
 		}
+		if len(orders) < alpacaOrdersPage || added == 0 {
+			return all, nil
+		}
+		// Until excludes its own time, so orders submitted with the
+		// oldest are asked for again and skipped as seen
+		query.Until = orders[len(orders)-1].SubmittedAt.Add(time.Nanosecond)
 	}
-	return result
+}
+
+// writeAlgorithmResult writes an /api/algorithms/execute response.
+func writeAlgorithmResult(w http.ResponseWriter, run *algorithm.InstanceRun) {
+	result := run.Result.Result
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":      "success",
+		"instance":    run.Instance.ID,
+		"type":        run.Instance.Type,
+		"symbol":      run.Symbol,
+		"timeframe":   run.TimeFrame,
+		"bars":        run.Bars,
+		"signal":      result.Signal,
+		"order_type":  result.OrderType,
+		"confidence":  result.Confidence,
+		"explanation": result.Explanation,
+		"details":     result.Details,
+		"cached":      run.Cached,
+		"computed_at": run.Result.ComputedAt,
+	})
 }
 
 const (
@@ -57,47 +221,16 @@ const (
 	paperTradingURL  = "https://paper-api.alpaca.markets"
 	liveTradingURL   = "https://api.alpaca.markets"
 	paperKeyPrefix   = "PK" // Paper API keys usually start with PK
-	liveKeyPrefix    = "AK" // Live API keys usually start with AK
 	maxNotifications = 100  // Maximum notifications to store
 )
-const dataDir = "./data" // Directory for storing persistent data like ticker baskets
-
-// PriceTracker tracks previous prices for market event detection
-type PriceTracker struct {
-	prices map[string]float64
-	mu     sync.RWMutex
-}
-
-// NewPriceTracker creates a new price tracker
-func NewPriceTracker() *PriceTracker {
-	return &PriceTracker{
-		prices: make(map[string]float64),
-	}
-}
-
-// GetPreviousPrice returns the previous price for a symbol
-func (pt *PriceTracker) GetPreviousPrice(symbol string) float64 {
-	pt.mu.RLock()
-	defer pt.mu.RUnlock()
-	return pt.prices[symbol]
-}
-
-// UpdatePrice updates the price for a symbol
-func (pt *PriceTracker) UpdatePrice(symbol string, price float64) {
-	pt.mu.Lock()
-	defer pt.mu.Unlock()
-	pt.prices[symbol] = price
-}
+const defaultDataDir = "./data" // Root for persistent data like ticker baskets, one subdirectory per trading mode
 
 func main() {
 	// Load .env file
-	// Define global API key variables that will be used throughout the application
-	var alpacaAPIKey, alpacaSecretKey string
-
 	if err := godotenv.Load(); err != nil {
 		// If .env file doesn't exist, log a warning but continue
 		if !errors.Is(err, os.ErrNotExist) {
-			log.Printf("Warning: Error loading .env file: %v", err)
+			slog.Warn("Failed to load .env file", "error", err)
 		}
 	}
 
@@ -106,85 +239,136 @@ func main() {
 	symbols := flag.String("symbols", defaultSymbols, "Comma-separated list of ticker symbols")
 	usePaperTrading := flag.Bool("paper", true, "Use paper trading (true) or live trading (false)")
 	mockMode := flag.Bool("mock", strings.EqualFold(os.Getenv("GO_TRADER_MOCK"), "true"), "Run with deterministic mock market/account data instead of Alpaca credentials")
+	defaultRoot := os.Getenv("GO_TRADER_DATA_DIR")
+	if defaultRoot == "" {
+		defaultRoot = defaultDataDir
+	}
+	maxSymbols := flag.Int("max-symbols", ticker.DefaultMaxSymbols, "Maximum symbols polled for market data; idle watch-list symbols are evicted beyond it (0 for no limit)")
+	recordTicks := flag.Bool("record-ticks", false, "Record the raw trade and quote stream to compressed per-symbol daily files for replay")
+	replayDay := flag.String("replay", "", "Replay a past session (YYYY-MM-DD) through the engine against a simulated broker instead of trading live")
+	replaySpeed := flag.String("replay-speed", "10x", "Replay speed: a multiple of real time such as 1x or 10x, or max")
+	replaySource := flag.String("replay-source", replay.SourceTicks, "Replay from recorded ticks (ticks) or historical minute bars (bars)")
+	recordFixtures := flag.String("record-fixtures", "", "Record Alpaca's REST responses, sanitized, as fixtures for the handler tests into this directory")
+	dataRoot := flag.String("data-dir", defaultRoot, "Root directory for persistent data; each trading mode (paper, live, mock) uses its own subdirectory")
 
 	// Add flags for API keys that can be used instead of environment variables
+	defaultAdapterURL := os.Getenv("GO_TRADER_CLAUDE_ADAPTER_URL")
+	if defaultAdapterURL == "" {
+		defaultAdapterURL = claude.DefaultAdapterURL
+	}
+	adapterURL := flag.String("claude-adapter-url", defaultAdapterURL, "Frontend server Claude signal requests go to (default: GO_TRADER_CLAUDE_ADAPTER_URL or http://localhost:3000)")
+	contextURL := flag.String("claude-context-url", "", "URL the Claude adapter reads each request's signal context back from (default: this server's /api/snapshot/context/redeem on localhost)")
 	alpacaKey := flag.String("alpaca-key", "", "Alpaca API key (overrides env var)")
 	alpacaSecret := flag.String("alpaca-secret", "", "Alpaca secret key (overrides env var)")
-
-	// Log to verify that the environment variables are being loaded
-	log.Printf("DEBUG: Checking for Alpaca API Keys in environment...")
+	secretsDir := flag.String("secrets-dir", os.Getenv("GO_TRADER_SECRETS_DIR"), "Directory of secret files, one per key (e.g. paper_alpaca_api_key), as Docker and Kubernetes mount them")
+
+	logLevel := flag.String("log-level", "info", "Log level: debug, info, warn or error")
+	logFormat := flag.String("log-format", logging.FormatText, "Log output format: text or json")
+	logModules := flag.String("log-modules", "", "Per-module log levels overriding -log-level, e.g. ticker=debug,claude=warn")
+	basketStore := flag.String("basket-store", os.Getenv("GO_TRADER_BASKET_STORE"), "Basket persistence: file (one JSON file per basket, default) or sqlite (baskets.db, importing existing files)")
+	liveSafeMode := flag.Bool("live-safe-mode", true, "In live trading, start with order execution disabled until an admin enables it at /api/trading-mode/enable")
+	digestMinutes := flag.Int("notification-digest-minutes", 0, "Batch low and medium priority notifications into one digest per type every N minutes (0 emits them at once); per-type intervals at /api/notifications/digest")
+	liveConfirmMinutes := flag.Int("live-confirm-minutes", 30, "In live trading, minutes after startup during which order requests need confirm_live=true (0 to turn off)")
+	corsConfig := flag.String("cors-config", os.Getenv("GO_TRADER_CORS_CONFIG"), "JSON file with the CORS policy: allowed origins, methods and headers, and credentials (default: same origin only)")
 	flag.Parse()
 
-	// Get appropriate API keys from environment based on trading mode
-
-	if *usePaperTrading {
-		alpacaAPIKey = os.Getenv("PAPER_ALPACA_API_KEY")
-		// Check for both correct spelling and potential typo
-		alpacaSecretKey = os.Getenv("PAPER_ALPACA_SECRET_KEY")
-		if alpacaSecretKey == "" {
-			alpacaSecretKey = os.Getenv("PAPAER_ALPACA_SECRET_KEY") // Handle potential typo
-		}
-	} else {
-		alpacaAPIKey = os.Getenv("LIVE_ALPACA_API_KEY")
-		alpacaSecretKey = os.Getenv("LIVE_ALPACA_SECRET_KEY")
+	// Structured logging with per-module levels; API keys are redacted
+	// from every record once registered below
+	logConfig := logging.Config{Level: *logLevel, Format: *logFormat}
+	var err error
+	if logConfig.Modules, err = logging.ParseModules(*logModules); err != nil {
+		logging.Fatal("Invalid -log-modules", "error", err)
 	}
-
-	// Override with command line flags if provided
-	if *alpacaKey != "" {
-		alpacaAPIKey = *alpacaKey
-		log.Printf("Using Alpaca API Key from command line")
+	logManager, err := logging.New(os.Stderr, logConfig)
+	if err != nil {
+		logging.Fatal("Invalid logging configuration", "error", err)
+	}
+	logManager.Install()
+
+	// A replay never trades: orders go to a simulated broker, and the
+	// account endpoints serve mock data
+	replaying := *replayDay != ""
+	var replayDate time.Time
+	var replaySpeedX float64
+	if replaying {
+		if replayDate, err = time.ParseInLocation("2006-01-02", *replayDay, calendar.New().Location()); err != nil {
+			logging.Fatal("Invalid -replay date: use YYYY-MM-DD", "date", *replayDay)
+		}
+		if replaySpeedX, err = replay.ParseSpeed(*replaySpeed); err != nil {
+			logging.Fatal("Invalid -replay-speed", "error", err)
+		}
+		if *replaySource != replay.SourceTicks && *replaySource != replay.SourceBars {
+			logging.Fatal("Invalid -replay-source: use ticks or bars", "source", *replaySource)
+		}
+		logger().Info("Replaying session", "day", *replayDay, "source", *replaySource, "speed", replay.FormatSpeed(replaySpeedX))
 	}
-	if *alpacaSecret != "" {
-		alpacaSecretKey = *alpacaSecret
-		log.Printf("Using Alpaca Secret Key from command line")
+	if err := settleMode(mockMode, replaying, *recordFixtures); err != nil {
+		logging.Fatal("Invalid flags", "error", err)
 	}
 
+	// Alpaca keys come from the command line, then the environment, then
+	// the secrets directory, then Vault. Whatever is found is redacted from
+	// the logs before it is returned.
+	vaultLoader := cartography.NewVaultLoaderFromEnv()
+	secretLoader := newSecretLoader(logManager, *alpacaKey, *alpacaSecret, *secretsDir, vaultLoader)
+	loadCtx, loadCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	creds, err := secretLoader.Alpaca(loadCtx, *usePaperTrading)
+	loadCancel()
 	if *mockMode {
-		log.Println("Running in mock mode: Alpaca credentials are not required and no live trades will be placed")
+		logger().Info("Running in mock mode: Alpaca credentials are not required and no live trades will be placed")
 		os.Setenv("GO_TRADER_MOCK", "true")
-		if alpacaAPIKey == "" {
-			alpacaAPIKey = "MOCK_ALPACA_API_KEY"
-		}
-		if alpacaSecretKey == "" {
-			alpacaSecretKey = "MOCK_ALPACA_SECRET_KEY"
+		if err != nil {
+			creds, err = secrets.MockCredentials(*usePaperTrading), nil
 		}
 	}
-
-	// Log the key being used (first few characters only)
-	if alpacaAPIKey != "" {
-		prefixLen := 5
-		if len(alpacaAPIKey) < prefixLen {
-			prefixLen = len(alpacaAPIKey)
-		}
-		log.Printf("DEBUG: Using Alpaca API Key: %s...", alpacaAPIKey[:prefixLen])
+	if err == nil {
+		logger().Info("Loaded Alpaca credentials", "alpaca", creds)
 	}
 
 	// Validate required API keys
-	if alpacaAPIKey == "" || alpacaSecretKey == "" {
+	if err != nil {
 		if *usePaperTrading {
-			log.Fatal("PAPER_ALPACA_API_KEY and PAPER_ALPACA_SECRET_KEY environment variables are required for paper trading. For local development without credentials, run with -mock or set GO_TRADER_MOCK=true")
+			logging.Fatal("PAPER_ALPACA_API_KEY and PAPER_ALPACA_SECRET_KEY are required for paper trading, from the environment, -secrets-dir or Vault. For local development without credentials, run with -mock or set GO_TRADER_MOCK=true", "error", err)
 		} else {
-			log.Fatal("LIVE_ALPACA_API_KEY and LIVE_ALPACA_SECRET_KEY environment variables are required for live trading. For local development without credentials, run with -mock or set GO_TRADER_MOCK=true")
+			logging.Fatal("LIVE_ALPACA_API_KEY and LIVE_ALPACA_SECRET_KEY are required for live trading, from the environment, -secrets-dir or Vault. For local development without credentials, run with -mock or set GO_TRADER_MOCK=true", "error", err)
 		}
 	}
 
 	var baseURL string
 	if *usePaperTrading {
 		baseURL = paperTradingURL
-		log.Println("Using PAPER trading environment")
+		logger().Info("Using PAPER trading environment")
 	} else {
 		// Safety check: Only allow live trading if the API key has the correct prefix
-		if !*mockMode && !strings.HasPrefix(alpacaAPIKey, liveKeyPrefix) {
-			log.Println("WARNING: Cannot use live trading - live API keys not detected (keys should start with AK)")
-			log.Println("Falling back to paper trading mode")
+		if !*mockMode && !creds.IsLiveKey() {
+			logger().Warn("Cannot use live trading: live API keys not detected (keys should start with AK), falling back to paper trading")
 			*usePaperTrading = true
+			creds.Paper = true
 			baseURL = paperTradingURL
 		} else {
 			baseURL = liveTradingURL
-			log.Println("Using LIVE trading environment")
+			logger().Info("Using LIVE trading environment")
 		}
 	}
 
+	// Keep persistent state separate per trading mode
+	mode := datadir.Mode(*usePaperTrading, *mockMode)
+	if replaying {
+		mode = datadir.ModeReplay
+	}
+	dataDir, migrated, err := datadir.Resolve(*dataRoot, mode)
+	if err != nil {
+		logging.Fatal("Failed to prepare data directory", "error", err)
+	}
+	if len(migrated) > 0 {
+		logger().Info("Moved existing data into the mode directory", "moved", migrated, "dir", dataDir)
+	}
+	if stranded := datadir.Stranded(*dataRoot); len(stranded) > 0 {
+		logger().Warn("Data predates per-mode data directories and is not used; move it into the mode directory to keep it",
+			"stranded", stranded, "root", *dataRoot, "dir", dataDir)
+	}
+	logger().Info("Using data directory", "dir", dataDir)
+
 	// Split symbols into a slice
 	symbolsSlice := strings.Split(*symbols, ",")
 	for i, s := range symbolsSlice {
@@ -195,71 +379,321 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// Initialize Alpaca clients
+	// Initialize Alpaca clients. A replay's trading client never gets real
+	// keys; market data keys are kept for history and bar replays.
+	tradingCreds := creds
+	if replaying {
+		tradingCreds = secrets.MockCredentials(*usePaperTrading)
+	}
+
+	// Every Alpaca request is counted for the diagnostics' API error rates.
+	// Requests are queued under Alpaca's rate limit first, orders ahead of
+	// portfolio reads ahead of data, so the timeouts cover time queued.
+	apiMonitor := diagnostics.NewAPIMonitor(15 * time.Minute)
+	apiQueue, err := apiqueue.New(apiqueue.DefaultPolicy())
+	if err != nil {
+		logging.Fatal("Failed to create Alpaca request queue", "error", err)
+	}
+	// With -record-fixtures, responses are also written as fixtures, with
+	// the keys redacted
+	var tradingBase, dataBase http.RoundTripper
+	if *recordFixtures != "" {
+		recorder, err := fixtures.NewRecorder(*recordFixtures, creds.KeyID, creds.Secret)
+		if err != nil {
+			logging.Fatal("Failed to start recording fixtures", "error", err)
+		}
+		tradingBase, dataBase = recorder.Transport("trading", nil), recorder.Transport("market_data", nil)
+		logger().Info("Recording Alpaca fixtures", "dir", *recordFixtures)
+	}
 	client := alpaca.NewClient(alpaca.ClientOpts{
-		APIKey:    alpacaAPIKey,
-		APISecret: alpacaSecretKey,
-		BaseURL:   baseURL,
+		APIKey:     tradingCreds.KeyID,
+		APISecret:  tradingCreds.Secret,
+		BaseURL:    baseURL,
+		HTTPClient: apiQueue.Client("trading", 30*time.Second, apiMonitor.Transport("trading", tradingBase)),
 	})
 
 	mdClient := marketdata.NewClient(marketdata.ClientOpts{
-		APIKey:    alpacaAPIKey,
-		APISecret: alpacaSecretKey,
+		APIKey:     creds.KeyID,
+		APISecret:  creds.Secret,
+		HTTPClient: apiQueue.Client("market_data", 30*time.Second, apiMonitor.Transport("market_data", dataBase)),
 	})
+	// Routes are registered as their subsystems start; the server is
+	// started with the router once everything is in place
+	rt := router.New()
+	corsPolicy, err := router.LoadCORSPolicy(*corsConfig)
+	if err != nil {
+		logging.Fatal("Failed to load CORS config", "error", err)
+	}
+	secrets.NewHandler(tradingCreds, secrets.NewValidator(tradingCreds, baseURL, "")).RegisterRoutes(rt.Mux())
+	apiqueue.NewHandler(apiQueue).RegisterRoutes(rt.Mux())
 
 	// Initialize tading algorithm
 	// Create Claude WebSocket adapter for communication with the Next.js frontend
-	claudeAdapter := claude.NewWebSocketAdapterWrapper("http://localhost:3000")
+	claudeAdapter := claude.NewWebSocketAdapterWrapper(*adapterURL)
+	if *contextURL == "" {
+		*contextURL = "http://localhost:" + *port + "/api/snapshot/context/redeem"
+	}
+	claudeAdapter.SetContextURL(*contextURL)
 
 	// Adapt Claude adapter to the algorithm's ClaudeClientInterface
 	adaptedClaudeAdapter := &adaptedClaudeClient{claudeAdapter}
 
+	// Guard Claude with timeouts, retries and a circuit breaker. While the
+	// breaker is open, signals come from the quant algorithms instead.
+	resilientClaude := algorithm.NewResilientClaude(adaptedClaudeAdapter, algorithm.DefaultResilienceConfig())
+
 	// Initialize algorithm with the Claude adapter
-	tradingAlgorithm := algorithm.NewTradingAlgorithm(ctx, adaptedClaudeAdapter, client, mdClient)
+	tradingAlgorithm := algorithm.NewTradingAlgorithm(ctx, resilientClaude, client, mdClient)
+
+	// A replay runs on the replayed day's clock, from midnight, and fills
+	// orders against the replayed market
+	var replayClock *replay.Clock
+	var simBroker *replay.Broker
+	var tradingBroker orders.Broker = client
+	if replaying {
+		replayClock = replay.NewClock(replayDate)
+		simBroker = replay.NewBroker(replayClock, replay.DefaultCash)
+		tradingBroker = simBroker
+		tradingAlgorithm.SetBroker(simBroker)
+		tradingAlgorithm.SetClock(replayClock.Now)
+	}
+	quantFallback := algorithm.NewQuantFallback(tradingAlgorithm)
+	resilientClaude.AddFallback("quant", quantFallback)
+	algorithm.NewClaudeHealthHandler(resilientClaude).RegisterRoutes(rt.Mux())
+
+	// Shadow trading — whenever the live source signals, the other source
+	// is asked on the same market data, and both are booked against their
+	// own virtual portfolios for comparison.
+	shadowTracker, err := shadow.New(filepath.Join(dataDir, "shadow", "journal.jsonl"), shadow.DefaultPolicy(),
+		map[string]algorithm.ClaudeClientInterface{shadow.SourceClaude: resilientClaude, shadow.SourceQuant: quantFallback})
+	if err != nil {
+		logging.Fatal("Failed to open shadow journal", "error", err)
+	}
+	defer shadowTracker.Close()
+	if replaying {
+		shadowTracker.SetClock(replayClock.Now)
+	}
+	shadow.NewHandler(shadowTracker).RegisterRoutes(rt.Mux())
+
+	// Outbound webhooks — signals, fills and risk breaches are POSTed,
+	// signed, to the registered URLs, retried with backoff and
+	// dead-lettered at /api/webhooks/deliveries when they keep failing.
+	hooks, err := webhooks.New(filepath.Join(dataDir, "webhooks"), webhooks.DefaultPolicy())
+	if err != nil {
+		logging.Fatal("Failed to open webhooks", "error", err)
+	}
+	defer hooks.Close()
+	go hooks.Run(ctx)
+	webhooks.NewHandler(hooks).RegisterRoutes(rt.Mux())
 
 	// Initialize basket manager
-	basketManager, err := ticker.NewBasketManager(dataDir)
+	basketBackend, err := ticker.OpenBasketStore(*basketStore, dataDir)
 	if err != nil {
-		log.Fatalf("Failed to initialize basket manager: %v", err)
+		logging.Fatal("Failed to initialize basket manager", "error", err)
 	}
+	basketManager := ticker.NewBasketManagerWithStore(basketBackend)
 
-	// Initialize notification manager
+	// Initialize notification manager. With -notification-digest-minutes
+	// low and medium priority notifications are batched per type into
+	// digests; high priority ones are always emitted at once.
 	notificationService := notification.NewNotificationManager(maxNotifications)
+	if *digestMinutes > 0 {
+		digest := notification.DefaultDigestPolicy()
+		digest.Enabled, digest.DefaultMinutes = true, *digestMinutes
+		if err := notificationService.SetDigestPolicy(digest); err != nil {
+			logging.Fatal("Invalid -notification-digest-minutes", "error", err)
+		}
+	}
+	if replaying {
+		notificationService.SetClock(replayClock.Now)
+	}
+	go notificationService.RunDigests(ctx)
+	notification.NewDigestHandler(notificationService).RegisterRoutes(rt.Mux())
+
+	// Open the persistent signal history
+	signalHistory, err := signalstore.Open(filepath.Join(dataDir, "signals", "history.jsonl"))
+	if err != nil {
+		logging.Fatal("Failed to open signal history", "error", err)
+	}
+	defer signalHistory.Close()
+
+	// Open the rotating API audit log
+	auditLog, err := audit.Open(filepath.Join(dataDir, "audit", "audit.log"))
+	if err != nil {
+		logging.Fatal("Failed to open audit log", "error", err)
+	}
+	defer auditLog.Close()
+	audit.NewHandler(auditLog).RegisterRoutes(rt.Mux())
+	logging.NewHandler(logManager).RegisterRoutes(rt.Mux())
+
+	// API users and their watchlists, notification preferences and manual
+	// control setting. Authentication turns on when the first user is
+	// created at POST /api/users.
+	userStore, err := users.Open(filepath.Join(dataDir, "users"))
+	if err != nil {
+		logging.Fatal("Failed to open users", "error", err)
+	}
+	users.NewHandler(userStore).RegisterRoutes(rt.Mux())
+
+	// Trading mode — every response names it in X-Trading-Mode. Live
+	// trading starts in safe mode, refusing orders until an admin enables
+	// execution, and asks order requests for confirm_live=true during the
+	// first minutes after startup.
+	tradingMode, err := tradingmode.New(mode, tradingmode.Policy{SafeMode: *liveSafeMode, ConfirmLiveMinutes: *liveConfirmMinutes})
+	if err != nil {
+		logging.Fatal("Invalid -live-confirm-minutes", "error", err)
+	}
+	tradingMode.SetNotifier(func(title, message string, metadata map[string]interface{}) {
+		notificationService.AddNotification(notification.CreateSystemAlertNotification(title, message, metadata))
+	})
+	tradingmode.NewHandler(tradingMode).RegisterRoutes(rt.Mux())
+	tradingAlgorithm.AddTradeGuard("safe mode", func(*algorithm.TradeSignal) error {
+		return tradingMode.CheckExecution()
+	})
+
+	// Trade restrictions — blocklisted symbols, and everything off the
+	// allowlist when it is strict, can be neither traded nor added to the
+	// watch list. Changes are journaled with the user who made them.
+	restrictionList, err := restrictions.New(filepath.Join(dataDir, "restrictions"), restrictions.DefaultPolicy())
+	if err != nil {
+		logging.Fatal("Failed to open trade restrictions", "error", err)
+	}
+	defer restrictionList.Close()
+	if replaying {
+		restrictionList.SetClock(replayClock.Now)
+	}
+	restrictions.NewHandler(restrictionList, userStore.Caller).RegisterRoutes(rt.Mux())
+	tradingAlgorithm.AddTradeGuard("restrictions", func(signal *algorithm.TradeSignal) error {
+		if restrictionList.Policy().AllowClosing && !tradingAlgorithm.OpensPosition(signal) {
+			return nil
+		}
+		return restrictionList.Check(signal.Symbol)
+	})
 
 	// Create system startup notification
-	log.Println("Initializing system with notification service")
+	logger().Info("Initializing system with notification service")
 	notificationService.AddNotification(notification.CreateSystemAlertNotification("System Started", "Trading system successfully initialized", nil))
 
-	// Initialize price tracker
-	priceTracker := NewPriceTracker()
+	// Price-move alerts, with per-symbol thresholds and cooldowns
+	priceAlerts := notification.NewPriceAlerts(notificationService)
+	notification.NewPriceAlertHandler(priceAlerts).RegisterRoutes(rt.Mux())
 
 	// Start the ticker server
-	// Get API keys for ticker server
-	var tickerAPIKey, tickerAPISecret string
-	if *usePaperTrading {
-		tickerAPIKey = alpacaAPIKey
-		tickerAPISecret = alpacaSecretKey
-	} else {
-		tickerAPIKey = alpacaAPIKey
-		tickerAPISecret = alpacaSecretKey
+	tickerServer := ticker.NewTickerServer(ctx, *usePaperTrading, creds.KeyID, creds.Secret)
+	tickerServer.SetMarketDataClient(mdClient)
+	// A replay feeds the ticker recorded data instead of polling
+	if !replaying {
+		if err := tickerServer.Start(); err != nil {
+			logging.Fatal("Failed to start ticker server", "error", err)
+		}
 	}
 
-	// Create ticker server with the correct API keys
-	tickerServer := ticker.NewTickerServer(ctx, *usePaperTrading, tickerAPIKey, tickerAPISecret)
-	if err := tickerServer.Start(); err != nil {
-		log.Fatalf("Failed to start ticker server: %v", err)
+	// Set the initial symbols, leaving out restricted ones
+	tickerServer.SetMaxSymbols(*maxSymbols)
+	tickerServer.SetSymbolFilter(restrictionList.Check)
+	allowed := symbolsSlice[:0]
+	for _, sym := range symbolsSlice {
+		if err := restrictionList.Check(sym); err != nil {
+			logger().Warn("Not watching restricted symbol", "symbol", sym, "error", err)
+			continue
+		}
+		allowed = append(allowed, sym)
 	}
-
-	// Set the initial symbols
+	symbolsSlice = allowed
 	if err := tickerServer.UpdateSymbols(symbolsSlice); err != nil {
-		log.Fatalf("Failed to set initial symbols: %v", err)
+		logging.Fatal("Failed to set initial symbols", "error", err)
 	}
 
+	// Live P&L — held positions are re-marked from the ticker stream and
+	// pushed to /ws/portfolio. Held symbols are polled even when they are
+	// not on the watch list, and positions are reconciled with the broker
+	// once a minute rather than on every request.
+	portfolioHub := portfoliostream.NewHub()
+	tradingAlgorithm.SetPortfolioHandler(func(p algorithm.PortfolioData, generation uint64) {
+		tickerServer.SetPinnedSymbols(ticker.PinPositions, p.HeldSymbols())
+		portfolioHub.Publish(generation, p)
+	})
+	portfolioHub.SetCheckOrigin(corsPolicy.CheckOrigin)
+	portfolioHub.RegisterRoutes(rt.Mux())
+
+	// Signals, market data and portfolio together on /ws/state, checked
+	// for a new generation every second while anyone is listening. Both
+	// streams send patches between keyframes.
+	stateHub := portfoliostream.NewStreamHub("state", "/ws/state")
+	stateHub.SetCheckOrigin(corsPolicy.CheckOrigin)
+	stateHub.RegisterRoutes(rt.Mux())
+	go func() {
+		tick := time.NewTicker(time.Second)
+		defer tick.Stop()
+		var published uint64
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-tick.C:
+				if stateHub.Clients() == 0 || tradingAlgorithm.Generation() == published {
+					continue
+				}
+				s := tradingAlgorithm.Snapshot()
+				stateHub.Publish(s.Generation, s)
+				published = s.Generation
+			}
+		}
+	}()
+
 	// Start the trading algorithm
 	// Initialize but don't enable automatic trading - only symbols will be processed
 	// when explicitly triggered from the frontend UI
 	tradingAlgorithm.Start(symbolsSlice)
-	log.Println("Trading algorithm initialized but not auto-running - waiting for UI trigger")
+	logger().Info("Trading algorithm initialized but not auto-running, waiting for UI trigger")
+	if !*mockMode {
+		go tradingAlgorithm.RunPortfolioSync(ctx, time.Minute)
+		// Symbols with working orders stay subscribed too
+		go pinOpenOrderSymbols(ctx, client, tickerServer, time.Minute)
+	} else if replaying {
+		go tradingAlgorithm.RunPortfolioSync(ctx, 5*time.Second)
+	}
+
+	// Market breadth — advancers against decliners, the share of an index
+	// universe above its moving average and new highs against new lows.
+	// Breadth goes into the Claude context, and weak breadth caps the
+	// regime multiplier below.
+	breadthMonitor, err := breadth.New(breadth.Alpaca{Client: mdClient}, breadth.DefaultPolicy())
+	if err != nil {
+		logging.Fatal("Failed to create breadth monitor", "error", err)
+	}
+	// Universes — index constituents and sector lists shared by every
+	// mode, refreshed from their sources before the open. Breadth measures
+	// the universe its policy names.
+	universes, err := universe.New(filepath.Join(*dataRoot, "universes"))
+	if err != nil {
+		logging.Fatal("Failed to load universes", "error", err)
+	}
+	if err := universes.Seed(universe.Universe{Name: "dow", Description: "Dow Jones Industrial Average", Symbols: breadth.DefaultUniverse}); err != nil {
+		logger().Warn("Failed to seed the dow universe", "error", err)
+	}
+	if !*mockMode {
+		universes.SetFetcher(universe.NewHTTP())
+	}
+	breadthMonitor.SetUniverses(universes.Symbols)
+	universe.NewHandler(universes).RegisterRoutes(rt.Mux())
+	tradingAlgorithm.SetBreadthSource(func() (algorithm.MarketBreadth, bool) {
+		b, ok := breadthMonitor.Latest()
+		if !ok {
+			return algorithm.MarketBreadth{}, false
+		}
+		return algorithm.MarketBreadth{
+			Advancers:      b.Advancers,
+			Decliners:      b.Decliners,
+			PercentAboveMA: b.PercentAboveMA,
+			NewHighs:       b.NewHighs,
+			NewLows:        b.NewLows,
+			Score:          b.Score,
+			Health:         b.Health,
+			AsOf:           b.AsOf,
+		}, true
+	})
+	breadth.NewHandler(breadthMonitor).RegisterRoutes(rt.Mux())
 
 	// Cartography — formula provides a slow-moving prior; FRED feed provides
 	// a coincident veto. The applied multiplier is the more cautious of the
@@ -270,8 +704,6 @@ func main() {
 	// formula-only. Vault path/field are overridable but default to a
 	// sensible convention so a vanilla install just works once the secret
 	// is written to the standard location.
-	fredKey := ""
-	fredKeySource := ""
 	vaultPath := os.Getenv("CARTOGRAPHY_VAULT_PATH")
 	if vaultPath == "" {
 		vaultPath = "secret/go-trader/fred"
@@ -280,30 +712,25 @@ func main() {
 	if vaultField == "" {
 		vaultField = "api_key"
 	}
-	if vl := cartography.NewVaultLoaderFromEnv(); vl != nil {
-		vctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-		if k, err := vl.Field(vctx, vaultPath, vaultField); err == nil {
-			fredKey = k
-			fredKeySource = "vault:" + vaultPath
-		} else {
-			log.Printf("Vault FRED key load skipped (%s): %v", vaultPath, err)
-		}
-		cancel()
+	var fredProviders []secrets.Provider
+	if vaultLoader != nil {
+		fredProviders = append(fredProviders, secrets.Vault{Reader: vaultLoader, Path: vaultPath, Fields: map[string]string{"FRED_API_KEY": vaultField}})
 	}
-	if fredKey == "" {
-		if k := os.Getenv("FRED_API_KEY"); k != "" {
-			fredKey = k
-			fredKeySource = "env:FRED_API_KEY"
-		}
+	fredProviders = append(fredProviders, secrets.Env{})
+	fredCtx, fredCancel := context.WithTimeout(ctx, 5*time.Second)
+	fredKey, fredKeySource, err := secrets.NewLoader(logManager.Redactor().AddSecret, fredProviders...).Lookup(fredCtx, "FRED_API_KEY")
+	fredCancel()
+	if err != nil && vaultLoader != nil {
+		logger().Info("Vault FRED key load skipped", "path", vaultPath, "error", err)
 	}
 
 	var feedCache *cartography.FeedCache
 	signalWatcher := cartography.NewSignalWatcher()
 	if fredKey != "" {
 		feedCache = cartography.NewFeedCache(cartography.NewFREDClient(fredKey), 6*time.Hour)
-		log.Printf("Cartography live-data overlay enabled (key from %s)", fredKeySource)
+		logger().Info("Cartography live-data overlay enabled", "key_source", fredKeySource)
 	} else {
-		log.Println("Cartography running formula-only — no FRED key in Vault (" + vaultPath + ") or env. Sahm/yield-curve/NFCI/HY-spread overrides disabled.")
+		logger().Info("Cartography running formula-only: no FRED key in Vault or env, Sahm/yield-curve/NFCI/HY-spread overrides disabled", "vault_path", vaultPath)
 	}
 
 	emitSignalChange := func(ev cartography.ChangeEvent) {
@@ -339,8 +766,7 @@ func main() {
 			},
 		}
 		notificationService.AddNotification(notif)
-		log.Printf("Cartography %s: %s (%.2f vs threshold %.2f)",
-			ev.Kind, s.Name, s.Value, s.Threshold)
+		logger().Info("Cartography signal change", "kind", ev.Kind, "signal", s.Name, "value", s.Value, "threshold", s.Threshold)
 	}
 
 	applyCartography := func() {
@@ -354,16 +780,20 @@ func main() {
 		if feed != nil && feed.Multiplier < r.Regime.Multiplier && len(feed.Triggers) > 0 {
 			regimeLabel = r.Regime.Name + " | DATA OVERRIDE"
 		}
+		if b, ok := breadthMonitor.Latest(); ok && b.Multiplier < applied {
+			applied = b.Multiplier
+			regimeLabel += " | WEAK BREADTH"
+		}
 		tradingAlgorithm.SetRegimeMultiplier(regimeLabel, applied)
 		if feed != nil {
-			log.Printf("Cartography formula=%s ×%.2f | data ×%.2f triggers=%v | applied ×%.2f",
-				r.Regime.Name, r.Regime.Multiplier, feed.Multiplier, feed.Triggers, applied)
+			logger().Info("Cartography regime", "formula", r.Regime.Name, "formula_multiplier", r.Regime.Multiplier,
+				"data_multiplier", feed.Multiplier, "triggers", feed.Triggers, "applied", applied)
 			for _, ev := range signalWatcher.Observe(feed) {
 				emitSignalChange(ev)
 			}
 		} else {
-			log.Printf("Cartography formula=%s ×%.2f composite=%+.2fσ (no live data)",
-				r.Regime.Name, r.Regime.Multiplier, r.Composite)
+			logger().Info("Cartography regime (no live data)", "formula", r.Regime.Name,
+				"formula_multiplier", r.Regime.Multiplier, "composite", r.Composite)
 		}
 	}
 
@@ -388,11 +818,15 @@ func main() {
 			rctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 			defer cancel()
 			if _, err := refreshAndApply(rctx); err != nil {
-				log.Printf("Cartography FRED initial refresh failed: %v", err)
+				logger().Warn("Cartography FRED initial refresh failed", "error", err)
 			}
 		}()
 	}
 	applyCartography()
+	breadthMonitor.SetOnUpdate(func(breadth.Snapshot) { applyCartography() })
+	if !*mockMode {
+		go breadthMonitor.Run(ctx)
+	}
 
 	go func() {
 		formulaTick := time.NewTicker(time.Hour)
@@ -411,15 +845,159 @@ func main() {
 				}
 				rctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 				if _, err := refreshAndApply(rctx); err != nil {
-					log.Printf("Cartography FRED refresh failed: %v", err)
+					logger().Warn("Cartography FRED refresh failed", "error", err)
 				}
 				cancel()
 			}
 		}
 	}()
 
-	// Register signal callback for notifications
+	// Confirmation queue — in live trading, orders at or above the policy's
+	// notional threshold or for signals of low confidence wait until
+	// another user confirms them or the requester types back their phrase.
+	confirmQueue, err := confirmations.New(filepath.Join(dataDir, "confirmations", "queue.json"),
+		confirmations.DefaultPolicy(mode == datadir.ModeLive))
+	if err != nil {
+		logging.Fatal("Failed to open confirmation queue", "error", err)
+	}
+	confirmQueue.SetGuard(tradingAlgorithm.CheckTradeGuards)
+	confirmQueue.SetExecutor(func(signal *algorithm.TradeSignal) (string, error) {
+		_, result, err := executeSignal(client, tradingAlgorithm, signal, mdClient)
+		return result, err
+	})
+	confirmations.NewHandler(confirmQueue).RegisterRoutes(rt.Mux())
+
+	// Multi-leg orders (pairs, hedges) are placed together or not at all,
+	// so they cannot wait in the confirmation queue leg by leg; one that
+	// would need confirming is refused instead
+	multiLegHandler := algorithm.NewMultiLegHandler(tradingAlgorithm)
+	multiLegHandler.SetPreflight(func(legs []*algorithm.TradeSignal, previews []*algorithm.OrderPreview) error {
+		if !confirmQueue.Policy().Enabled {
+			return nil
+		}
+		for i, leg := range legs {
+			if reasons := confirmQueue.Required(leg, previews[i]); len(reasons) > 0 {
+				return fmt.Errorf("leg %s needs confirmation (%s); place its legs as single orders instead", leg.Symbol, strings.Join(reasons, "; "))
+			}
+		}
+		return nil
+	})
+	multiLegHandler.RegisterRoutes(rt.Mux())
+
+	// Approval queue — signals from outside the engine, such as TradingView
+	// alerts, pass the trade guards on arrival and wait for someone to
+	// approve them unless the policy auto-approves their source.
+	approvalQueue, err := approvals.New(filepath.Join(dataDir, "approvals", "queue.json"), approvals.DefaultPolicy())
+	if err != nil {
+		logging.Fatal("Failed to open approval queue", "error", err)
+	}
+	if replaying {
+		approvalQueue.SetClock(replayClock.Now)
+	}
+	approvalQueue.SetGuard(tradingAlgorithm.CheckTradeGuards)
+	approvalQueue.SetExecutor(func(signal *algorithm.TradeSignal) (string, error) {
+		if replaying {
+			preview, err := tradingAlgorithm.ExecuteTrade(signal, false)
+			if err != nil || preview == nil {
+				return "No order needed", err
+			}
+			return fmt.Sprintf("%s order for %s %s placed with the replay broker", preview.Request.Side, preview.Request.Qty, signal.Symbol), nil
+		}
+		// An approved signal's order still needs confirming when the
+		// confirmation policy marks it
+		item, held, err := holdForConfirmation(client, tradingAlgorithm, confirmQueue, signal, mdClient, signal.Source)
+		if err != nil {
+			return "", err
+		}
+		if held {
+			return fmt.Sprintf("Held for confirmation as %s: %s", item.ID, strings.Join(item.Reasons, "; ")), nil
+		}
+		_, result, err := executeSignal(client, tradingAlgorithm, signal, mdClient)
+		return result, err
+	})
+	approvals.NewHandler(approvalQueue).RegisterRoutes(rt.Mux())
+
+	// TradingView alerts carry a shared secret in the body, looked up like
+	// the Alpaca keys; without one the endpoint refuses every alert.
+	tvCtx, tvCancel := context.WithTimeout(ctx, 10*time.Second)
+	tvSecret, tvSource, err := secretLoader.Lookup(tvCtx, "TRADINGVIEW_WEBHOOK_SECRET")
+	tvCancel()
+	if err != nil {
+		logger().Info("TradingView alerts disabled: no TRADINGVIEW_WEBHOOK_SECRET", "error", err)
+	} else {
+		logger().Info("TradingView alerts enabled", "from", tvSource)
+	}
+	tradingview.NewHandler(tvSecret, approvalQueue).RegisterRoutes(rt.Mux())
+
+	// Chat ops — signals and fills are posted to Slack or Discord through
+	// incoming webhooks, and slash commands read positions and P&L, decide
+	// approvals and halt or resume automated trading, each needing the
+	// role the policy gives the chat user.
+	chatBot, err := chatops.New(filepath.Join(dataDir, "chatops", "policy.json"), chatops.DefaultPolicy(), chatops.Actions{
+		Portfolio: tradingAlgorithm.GetPortfolio,
+		Halt:      tradingAlgorithm.Stop,
+		Resume:    func() error { return tradingAlgorithm.Start(tickerServer.GetSymbols()) },
+		Approvals: approvalQueue,
+		Execution: tradingMode.CheckExecution,
+	})
+	if err != nil {
+		logging.Fatal("Failed to open chatops policy", "error", err)
+	}
+	chatCtx, chatCancel := context.WithTimeout(ctx, 10*time.Second)
+	if v, _, err := secretLoader.Lookup(chatCtx, "SLACK_SIGNING_SECRET"); err == nil {
+		chatBot.SetSlack(v)
+	}
+	if v, _, err := secretLoader.Lookup(chatCtx, "SLACK_WEBHOOK_URL"); err == nil {
+		chatBot.AddChannel(chatops.PlatformSlack, v)
+	}
+	if v, _, err := secretLoader.Lookup(chatCtx, "DISCORD_PUBLIC_KEY"); err == nil {
+		if err := chatBot.SetDiscord(v); err != nil {
+			logger().Warn("Discord commands disabled", "error", err)
+		}
+	}
+	if v, _, err := secretLoader.Lookup(chatCtx, "DISCORD_WEBHOOK_URL"); err == nil {
+		chatBot.AddChannel(chatops.PlatformDiscord, v)
+	}
+	chatCancel()
+	go chatBot.Run(ctx)
+	chatops.NewHandler(chatBot).RegisterRoutes(rt.Mux())
+
+	// Queued signals are recorded and announced like the engine's own
+	approvalQueue.SetNotifier(func(item approvals.Item) {
+		signal := item.Signal
+		if signal.RiskReward == nil {
+			signal.RiskReward = tradingAlgorithm.RiskReward(signal)
+		}
+		recordSignal(signalHistory, signal, tradingAlgorithm.GetMarketData(signal.Symbol))
+		hooks.Emit(webhooks.EventSignalGenerated, signal)
+		priority := notification.PriorityMedium
+		if item.Status == approvals.StatusPending {
+			priority = notification.PriorityHigh
+			chatBot.AnnounceSignal(signal, item.ID)
+		}
+		notificationService.AddNotification(notification.CreateSignalGeneratedNotification(signal.Symbol, signal.Signal, signal.Reasoning,
+			priority, map[string]interface{}{"approval_id": item.ID, "approval_status": item.Status, "source": signal.Source}))
+	})
+
+	// The market calendar's time zone is where days and months roll over
+	marketCalendar := calendar.New()
+
+	// AI cost — each Claude signal's tokens are priced and journaled with
+	// its strategy before it is recorded, so the history carries its cost
+	aiCosts, err := aicost.New(filepath.Join(dataDir, "aicost"), marketCalendar.Location())
+	if err != nil {
+		logging.Fatal("Failed to open AI cost journal", "error", err)
+	}
+	defer aiCosts.Close()
+
+	// Register signal callback for notifications and history
 	tradingAlgorithm.RegisterSignalCallback(func(signal *algorithm.TradeSignal) {
+		aiCosts.Record(signal)
+		recordSignal(signalHistory, signal, tradingAlgorithm.GetMarketData(signal.Symbol))
+		go shadowTracker.Observe(signal, tradingAlgorithm.GetMarketData(signal.Symbol), tradingAlgorithm.GetPortfolio())
+		hooks.Emit(webhooks.EventSignalGenerated, signal)
+		chatBot.AnnounceSignal(signal, "")
+
 		// Convert signal priority based on type
 		var priority notification.NotificationPriority
 		if signal.Signal == algorithm.SignalBuy || signal.Signal == algorithm.SignalSell {
@@ -434,146 +1012,1217 @@ func main() {
 		notificationService.AddNotification(notif)
 	})
 
-	// Set up market data handler to forward data from ticker to algorithm
-	tickerServer.SetDataHandler(func(symbol string, trade ticker.TickerData) {
-		tradingAlgorithm.UpdateMarketData(
-			symbol,
-			trade.Trade.Price,
-			trade.Trade.Price*1.05,         // Placeholder for 24h high
-			trade.Trade.Price*0.95,         // Placeholder for 24h low
-			float64(trade.Trade.Size)*1000, // Convert uint32 to float64
-			0,                              // Placeholder for 24h change percentage
-		)
-
-		// Process the symbol to generate trading signals
-		// Only process if explicitly triggered by UI (don't auto-process for all data updates)
-		go func(s string) {
-			// Market data is updated, but signals are only generated
-			// when requested from the frontend to avoid excessive Claude API calls
-			// if err := tradingAlgorithm.ProcessSymbol(s); err != nil {
-			//    log.Printf("Error processing symbol %s: %v", s, err)
-			// }
-		}(symbol)
-
-		// Create market event notification for significant price changes (>2%)
-		currentPrice := trade.Trade.Price
-		prevPrice := priceTracker.GetPreviousPrice(symbol)
-
-		if prevPrice > 0 {
-			priceChange := (currentPrice - prevPrice) / prevPrice
-			if priceChange > 0.02 {
-				notif := notification.CreateMarketEventNotification(
-					symbol,
-					"Significant Price Increase",
-					fmt.Sprintf("%s price increased by %.2f%% to %s",
-						symbol,
-						priceChange*100,
-						fmt.Sprintf("$%.2f", currentPrice)),
-				)
-				notificationService.AddNotification(notif)
-			} else if priceChange < -0.02 {
-				notif := notification.CreateMarketEventNotification(
-					symbol,
-					"Significant Price Decrease",
-					fmt.Sprintf("%s price decreased by %.2f%% to %s",
-						symbol,
-						-priceChange*100,
-						fmt.Sprintf("$%.2f", currentPrice)),
-				)
-				notificationService.AddNotification(notif)
-			}
-		}
-
-		// Update the previous price for next comparison
-		priceTracker.UpdatePrice(symbol, currentPrice)
-	})
-
-	// Set up HTTP handlers, passing API keys for order handlers to use
-	setupHTTPHandlers(client, tradingAlgorithm, tickerServer, basketManager, notificationService,
-		feedCache, refreshAndApply, alpacaAPIKey, alpacaSecretKey)
-
-	log.Printf("Starting HTTP server on port %s", *port)
-	if err := http.ListenAndServe(":"+*port, nil); err != nil {
-		log.Fatalf("Failed to start HTTP server: %v", err)
+	// Risk alerts from gap risk, drawdown and the circuit breakers go to the
+	// notifications and out as risk_breach webhooks
+	riskAlert := func(source string) func(title, message string, metadata map[string]interface{}) {
+		return func(title, message string, metadata map[string]interface{}) {
+			notificationService.AddNotification(notification.CreateSystemAlertNotification(title, message, metadata))
+			hooks.Emit(webhooks.EventRiskBreach, map[string]interface{}{
+				"source":   source,
+				"title":    title,
+				"message":  message,
+				"metadata": metadata,
+			})
+		}
 	}
-}
-
-type SignalGeneratorFunc func(string) (*algorithm.TradeSignal, error)
-
-// adaptedClaudeClient adapts the claude.WebSocketAdapterWrapper to the algorithm.ClaudeClientInterface
-type adaptedClaudeClient struct {
-	*claude.WebSocketAdapterWrapper
-}
 
-// GenerateTradeSignal adapts the method signature
-func (a *adaptedClaudeClient) GenerateTradeSignal(symbol string, marketData algorithm.MarketData, portfolioData algorithm.PortfolioData) (*algorithm.TradeSignal, error) {
-	// Convert algorithm types to claude types
-	claudeMarketData := claude.AlgorithmMarketData{
-		Price:     marketData.Price,
-		High24h:   marketData.High24h,
-		Low24h:    marketData.Low24h,
-		Volume24h: marketData.Volume24h,
-		Change24h: marketData.Change24h,
+	// Gap risk — pre-close reduction policy plus the morning gap check. A
+	// symbol that gaps past the threshold is paused for automated trading
+	// until someone reviews it. In mock mode there is no broker or price
+	// feed, so the controls stay inert but the API still works.
+	// Triple barrier time horizons count sessions on the same calendar
+	algo.SetTradingCalendar(marketCalendar)
+	gapManager := gaprisk.NewManager(marketCalendar, gaprisk.DefaultPolicy())
+	gapManager.SetSymbols(tickerServer.GetSymbols)
+	gapManager.SetNotifier(riskAlert("gap_risk"))
+	gapManager.SetBroker(riskBroker(*mockMode, client, tradingMode.CheckExecution))
+	if !*mockMode {
+		gapManager.SetPriceSource(gaprisk.AlpacaPrices{Client: mdClient, Cal: marketCalendar})
 	}
-
-	claudePortfolioData := claude.AlgorithmPortfolioData{
-		Balance:     portfolioData.Balance,
-		TotalValue:  portfolioData.TotalValue,
-		DailyPnL:    portfolioData.DailyPnL,
-		DailyReturn: portfolioData.DailyReturn,
-		Positions:   make(map[string]claude.AlgorithmPositionData),
+	// gap_threshold_sigmas scales with each symbol's GARCH forecast
+	gapManager.SetVolatilityForecast(tradingAlgorithm.ForecastedVolatility)
+	// Risk decision history — every guard's allow or deny, with the margin
+	// to its limit where it measures one, for /api/risk/history
+	riskHistory, err := riskhistory.New(filepath.Join(dataDir, "risk", "decisions.jsonl"), marketCalendar.Location())
+	if err != nil {
+		logging.Fatal("Failed to open risk history", "error", err)
 	}
+	defer riskHistory.Close()
+	tradingAlgorithm.SetGuardObserver(riskHistory.Record)
+	riskhistory.NewHandler(riskHistory).RegisterRoutes(rt.Mux())
+	tradingAlgorithm.AddTradeGuard("gap risk", func(signal *algorithm.TradeSignal) error {
+		return gapManager.CheckSymbol(signal.Symbol)
+	})
+	go gapManager.Run(ctx)
 
-	// Convert positions from algorithm to claude types
-	for symbol, pos := range portfolioData.Positions {
-		claudePortfolioData.Positions[symbol] = claude.AlgorithmPositionData{
-			Symbol:    pos.Symbol,
-			Quantity:  pos.Quantity,
-			AvgPrice:  pos.AvgPrice,
-			MarketVal: pos.MarketVal,
-			Profit:    pos.Profit,
-			Return:    pos.Return,
+	// Earnings calendar — reports for held and watched symbols are polled
+	// from Finnhub into a cache under the data directory. New entries are
+	// blocked and holdings optionally cut before the close ahead of a
+	// report.
+	earningsCalendar, err := earnings.New(filepath.Join(dataDir, "earnings", "calendar.json"), marketCalendar, earnings.DefaultPolicy())
+	if err != nil {
+		logging.Fatal("Failed to open earnings calendar", "error", err)
+	}
+	earningsCalendar.SetSymbols(tickerServer.GetSymbols)
+	earningsCalendar.SetNotifier(riskAlert("earnings"))
+	if replaying {
+		earningsCalendar.SetClock(replayClock.Now)
+	}
+	finnhubCtx, finnhubCancel := context.WithTimeout(ctx, 10*time.Second)
+	finnhubKey, finnhubSource, err := secretLoader.Lookup(finnhubCtx, "FINNHUB_API_KEY")
+	finnhubCancel()
+	if err != nil {
+		logger().Info("Earnings calendar polling disabled: no FINNHUB_API_KEY", "error", err)
+	} else {
+		logger().Info("Earnings calendar enabled", "from", finnhubSource)
+		earningsCalendar.SetProvider(earnings.NewFinnhub(finnhubKey))
+	}
+	earningsCalendar.SetBroker(riskBroker(*mockMode, client, tradingMode.CheckExecution))
+	tradingAlgorithm.AddTradeGuard("earnings", func(signal *algorithm.TradeSignal) error {
+		if !tradingAlgorithm.OpensPosition(signal) {
+			return nil
+		}
+		return earningsCalendar.CheckEntry(signal.Symbol)
+	})
+	go earningsCalendar.Run(ctx)
+	earnings.NewHandler(earningsCalendar).RegisterRoutes(rt.Mux())
+
+	// Drawdown de-risking — as equity falls from its daily or trailing
+	// high, the policy tiers shrink position sizes, block new entries and
+	// finally flatten. Tier changes are journaled under the data directory
+	// and an operator can override the tier or re-base the highs.
+	drawdownManager, err := drawdown.New(filepath.Join(dataDir, "drawdown"), marketCalendar.Location(), drawdown.DefaultPolicy())
+	if err != nil {
+		logging.Fatal("Failed to open drawdown state", "error", err)
+	}
+	defer drawdownManager.Close()
+	drawdownManager.SetEquity(func() float64 { return tradingAlgorithm.GetPortfolio().TotalValue })
+	drawdownManager.SetScaler(tradingAlgorithm.SetPositionScale)
+	drawdownManager.SetNotifier(riskAlert("drawdown"))
+	drawdownManager.SetBroker(riskBroker(*mockMode, client, tradingMode.CheckExecution))
+	tradingAlgorithm.AddTradeGuard("drawdown", func(signal *algorithm.TradeSignal) error {
+		if !tradingAlgorithm.OpensPosition(signal) {
+			return nil
 		}
+		return drawdownManager.CheckEntry()
+	})
+	go drawdownManager.Run(ctx)
+	drawdown.NewHandler(drawdownManager).RegisterRoutes(rt.Mux())
+
+	// Per-symbol circuit breakers suspend execution on a halt, spread
+	// blowout, intraday price gap or stale quotes until someone resumes
+	// the symbol.
+	breakers := circuit.NewManager(marketCalendar, circuit.DefaultPolicy())
+	breakers.SetNotifier(riskAlert("circuit_breaker"))
+	tradingAlgorithm.AddTradeGuard("circuit breaker", func(signal *algorithm.TradeSignal) error {
+		return breakers.CheckSymbol(signal.Symbol)
+	})
+	circuit.NewHandler(breakers).RegisterRoutes(rt.Mux())
+	go tradingAlgorithm.RunCapQueue(ctx)
+
+	// Data quality — fetched and streamed bars and streamed quotes are
+	// checked before use. Bad ones are quarantined: fetched bars never
+	// reach the bar cache, history or backtests, streamed ones never reach
+	// the indicators, features or quote lookups. A symbol whose feed
+	// degrades raises an alert.
+	dataQuality := dataquality.New(marketCalendar, dataquality.DefaultPolicy())
+	dataQuality.SetNotifier(func(title, message string, metadata map[string]interface{}) {
+		notificationService.AddNotification(notification.CreateSystemAlertNotification(title, message, metadata))
+	})
+	if replaying {
+		dataQuality.SetClock(replayClock.Now)
 	}
+	tradingAlgorithm.SetBarFilter(func(symbol, timeframe string, bars []algorithm.BarData) []algorithm.BarData {
+		checked := make([]dataquality.Bar, len(bars))
+		for i, b := range bars {
+			checked[i] = qualityBar(b)
+		}
+		keep := make(map[int64]bool, len(bars))
+		for _, b := range dataQuality.FilterBars(symbol, timeframe, checked) {
+			keep[b.Time.UnixNano()] = true
+		}
+		kept := make([]algorithm.BarData, 0, len(keep))
+		for _, b := range bars {
+			if keep[b.Timestamp.UnixNano()] {
+				kept = append(kept, b)
+			}
+		}
+		return kept
+	})
+	dataquality.NewHandler(dataQuality).RegisterRoutes(rt.Mux())
 
-	// Call the original GenerateTradeSignal method
-	claudeSignal, err := a.WebSocketAdapterWrapper.GenerateTradeSignal(symbol, claudeMarketData, claudePortfolioData)
+	// Implied moves — the at-the-money straddle on the first expiry through
+	// a symbol's next event prices the move the options market expects.
+	// Cached estimates cap position sizes and go into the Claude context.
+	impliedMoves, err := impliedmove.New(impliedmove.NewAlpaca(mdClient, os.Getenv("ALPACA_OPTIONS_FEED")), impliedmove.DefaultPolicy())
 	if err != nil {
-		return nil, err
+		logging.Fatal("Failed to create implied move estimator", "error", err)
 	}
-
-	// Convert claude types to algorithm types
-	signal := &algorithm.TradeSignal{
-		Symbol:    claudeSignal.Symbol,
-		Signal:    claudeSignal.Signal,
-		OrderType: claudeSignal.OrderType,
-		Timestamp: claudeSignal.Timestamp,
-		Reasoning: claudeSignal.Reasoning,
+	impliedMoves.SetPrices(func(symbol string) float64 { return tradingAlgorithm.GetMarketData(symbol).Price })
+	impliedMoves.SetEvents(earningsCalendar.NextReaction)
+	tradingAlgorithm.SetImpliedMoveSource(impliedMoves.Cached)
+	if !*mockMode {
+		go impliedMoves.Run(ctx, tickerServer.GetSymbols)
 	}
+	impliedmove.NewHandler(impliedMoves).RegisterRoutes(rt.Mux())
+	gaprisk.NewHandler(gapManager).RegisterRoutes(rt.Mux())
 
-	if claudeSignal.LimitPrice != nil {
-		limitPrice := *claudeSignal.LimitPrice
-		signal.LimitPrice = &limitPrice
+	// Session and anchored VWAPs, built from streamed minute bars. Anchors
+	// set in the past are backfilled from minute history.
+	vwapTracker, err := indicators.NewTracker(filepath.Join(dataDir, "indicators", "anchors.json"), marketCalendar)
+	if err != nil {
+		logging.Fatal("Failed to open VWAP anchors", "error", err)
 	}
-
+	if replaying {
+		vwapTracker.SetClock(replayClock.Now)
+	}
+	if !*mockMode {
+		vwapTracker.SetHistory(func(ctx context.Context, symbol string, from, to time.Time) ([]indicators.Bar, error) {
+			history, err := tradingAlgorithm.GetBarHistory(algorithm.HistoryRequest{Symbol: symbol, StartDate: from, EndDate: to, TimeFrame: "1Min"})
+			if err != nil {
+				return nil, err
+			}
+			bars := make([]indicators.Bar, len(history.Bars))
+			for i, b := range history.Bars {
+				bars[i] = vwapBar(b)
+			}
+			return bars, nil
+		})
+		go vwapTracker.Backfill(ctx)
+	}
+	indicators.NewHandler(vwapTracker).RegisterRoutes(rt.Mux())
+
+	// Point-in-time features per symbol and bar. Streamed minute bars are
+	// stored as they close and daily rows are backfilled after each close;
+	// signals read the same rows training and backtests do.
+	featureStore, err := featurestore.New(filepath.Join(dataDir, "features"), marketCalendar)
+	if err != nil {
+		logging.Fatal("Failed to open feature store", "error", err)
+	}
+	if replaying {
+		featureStore.SetClock(replayClock.Now)
+	}
+	if !*mockMode {
+		featureStore.SetHistory(func(ctx context.Context, symbol, timeframe string, from, to time.Time) ([]featurestore.Bar, error) {
+			history, err := tradingAlgorithm.GetBarHistory(algorithm.HistoryRequest{Symbol: symbol, StartDate: from, EndDate: to, TimeFrame: timeframe})
+			if err != nil {
+				return nil, err
+			}
+			bars := make([]featurestore.Bar, len(history.Bars))
+			for i, b := range history.Bars {
+				bars[i] = featureBar(b)
+			}
+			return bars, nil
+		})
+	}
+	tradingAlgorithm.SetFeatureSource(func(symbol string) (*algorithm.Features, bool) {
+		row, ok := featureStore.Latest(symbol, "1D")
+		if !ok {
+			return nil, false
+		}
+		return &algorithm.Features{Timeframe: row.Timeframe, AsOf: row.AvailableAt, Regime: row.Regime, Values: row.Values}, true
+	})
+	featurestore.NewHandler(featureStore).RegisterRoutes(rt.Mux())
+
+	// Order management — limit orders with a chase or aggressive execution
+	// strategy are repriced toward the market from the ticker's quotes and,
+	// for aggressive ones, sent to market after a timeout. Chases can be
+	// capped at a band around the session VWAP.
+	lastQuote := func(symbol string) (float64, float64, bool) {
+		data, err := tickerServer.GetLastData(symbol)
+		if err != nil || data.Quote == nil || dataQuality.Quarantined(symbol, qualityQuote(data.Quote)) {
+			return 0, 0, false
+		}
+		return data.Quote.BidPrice, data.Quote.AskPrice, true
+	}
+	tradingAlgorithm.SetQuoteSource(lastQuote)
+
+	// Session stats — today's range, VWAP, volume against the 20-day
+	// average, realized volatility and spread, from the streamed minute
+	// bars and the quote cache; the UI and Claude read the same numbers
+	vwapTracker.SetQuotes(lastQuote)
+	vwapTracker.SetAverageVolume(func(symbol string) (float64, bool) {
+		b, ok := tradingAlgorithm.GetBaseline(symbol)
+		return b.AvgVolume20, ok
+	})
+	tradingAlgorithm.SetSessionStatsSource(func(symbol string) (*algorithm.SessionStats, bool) {
+		st, ok := vwapTracker.SessionStats(symbol)
+		if !ok {
+			return nil, false
+		}
+		return &algorithm.SessionStats{Open: st.Open, High: st.High, Low: st.Low, VWAP: st.VWAP, Volume: st.Volume,
+			AvgVolume20: st.AvgVolume20, VolumeRatio: st.VolumeRatio, VolumePace: st.VolumePace, AsOf: st.AsOf,
+			RealizedVolatility: st.RealizedVolatility, Spread: st.Spread, SpreadBps: st.SpreadBps}, true
+	})
+	orderManager := orders.NewManager(tradingBroker, lastQuote, orders.DefaultPolicy())
+	orderManager.SetVWAP(vwapTracker.SessionPrice)
+
+	// Execution quality — every order placed is followed to its fill with
+	// the quote at submission, and the journal feeds the market vs limit
+	// comparison at /api/reports/execution-quality.
+	fillTracker, err := fills.New(filepath.Join(dataDir, "fills", "journal.jsonl"), tradingBroker, lastQuote, marketCalendar.Location())
+	if err != nil {
+		logging.Fatal("Failed to open fills journal", "error", err)
+	}
+	defer fillTracker.Close()
+	// Order lifecycles — the trade updates stream moves each order from
+	// new through partial fills to filled, canceled or expired. Orders of
+	// ours the broker expires partly filled have their remainder placed
+	// again, carried in the fills journal as the same order.
+	orderBook := orders.NewBook(tradingBroker, lastQuote, orders.DefaultRemainderPolicy())
+	tradingAlgorithm.SetOrderHandler(func(signal *algorithm.TradeSignal, order *alpaca.Order) {
+		fillTracker.Track(order, signal.Execution)
+		orderBook.Watch(order, signal.Execution)
+		if err := orderManager.Track(order, signal.Execution); err != nil {
+			logger().Warn("Order is not managed", "order_id", order.ID, "symbol", order.Symbol, "error", err)
+		}
+	})
+	orderManager.SetOrderHandler(func(order *alpaca.Order, execution string) {
+		fillTracker.Track(order, execution)
+		orderBook.Watch(order, execution)
+	})
+	orderBook.SetOrderHandler(func(prev orders.OrderState, order *alpaca.Order) {
+		fillTracker.Resubmitted(prev.ID, prev.FilledQty, prev.AvgFillPrice, order, prev.Execution)
+		if err := orderManager.Track(order, prev.Execution); err != nil {
+			logger().Warn("Order is not managed", "order_id", order.ID, "symbol", order.Symbol, "error", err)
+		}
+	})
+	// Trade frequency — every fill is checked against the strategy's
+	// norms for trades, holding time and churn per symbol per day, with an
+	// advisory notification when it looks to be overtrading.
+	activityMonitor, err := activity.New(filepath.Join(dataDir, "activity", "policy.json"), fillTracker.Records, marketCalendar.Location())
+	if err != nil {
+		logging.Fatal("Failed to load activity policy", "error", err)
+	}
+	activityMonitor.SetNotifier(func(title, message string, metadata map[string]interface{}) {
+		notificationService.AddNotification(notification.CreateSystemAlertNotification(title, message, metadata))
+	})
+	activity.NewHandler(activityMonitor).RegisterRoutes(rt.Mux())
+	// Cooldowns — losing streaks on a symbol or strategy, and single large
+	// losses, are read from the fills journal's round trips and block new
+	// entries for a while; /api/risk/metrics lists those in force.
+	tradingAlgorithm.SetOutcomeSource(func() []algorithm.TradeOutcome {
+		return fills.TradeOutcomes(fillTracker.Records(time.Time{}))
+	})
+	// Strategy loss budgets — a strategy whose realized P&L from the fills
+	// journal spends its daily or total budget is disabled for entries
+	// until an admin re-enables it; other strategies keep trading.
+	strategyBudgets, err := budgets.New(filepath.Join(dataDir, "budgets"), fillTracker.Records, marketCalendar.Location())
+	if err != nil {
+		logging.Fatal("Failed to load strategy budgets", "error", err)
+	}
+	strategyBudgets.SetNotifier(riskAlert("strategy_budget"))
+	strategyBudgets.Check()
+	tradingAlgorithm.AddTradeGuard("strategy budget", func(signal *algorithm.TradeSignal) error {
+		if !tradingAlgorithm.OpensPosition(signal) {
+			return nil
+		}
+		return strategyBudgets.CheckEntry(signal.OrderTag())
+	})
+	budgets.NewHandler(strategyBudgets).RegisterRoutes(rt.Mux())
+	// AI cost is reported per strategy net of its realized P&L, and
+	// reaching the monthly cap raises a risk alert once a month
+	aiCosts.SetOutcomeSource(func() []algorithm.TradeOutcome {
+		return fills.TradeOutcomes(fillTracker.Records(time.Time{}))
+	})
+	aiCosts.SetNotifier(riskAlert("ai_cost"))
+	aiCosts.Check()
+	aicost.NewHandler(aiCosts).RegisterRoutes(rt.Mux())
+	// Claude's signals are evaluated out of sample each month from the
+	// signal log, against the quant signals journaled beside them
+	aiEvaluator := aieval.New(aieval.Sources{
+		Claude: func(since time.Time) []aieval.Signal {
+			var out []aieval.Signal
+			for offset := 0; ; offset += signalstore.MaxLimit {
+				page := signalHistory.Query(signalstore.Query{Source: shadow.SourceClaude, From: since, Limit: signalstore.MaxLimit, Offset: offset})
+				for _, r := range page.Items {
+					s := aieval.Signal{Time: r.Timestamp, Symbol: r.Symbol, Signal: r.Signal, Confidence: r.Confidence}
+					if r.Market != nil {
+						s.Price = r.Market.Price
+					}
+					out = append(out, s)
+				}
+				if offset+len(page.Items) >= page.Total || len(page.Items) == 0 {
+					return out
+				}
+			}
+		},
+		Quant: func(since time.Time) ([]aieval.Signal, error) {
+			entries, err := shadowTracker.Entries(shadow.SourceQuant, since)
+			if err != nil {
+				return nil, err
+			}
+			out := make([]aieval.Signal, len(entries))
+			for i, e := range entries {
+				out[i] = aieval.Signal{Time: e.Time, Symbol: e.Symbol, Signal: e.Signal, Confidence: e.Confidence, Price: e.Price}
+			}
+			return out, nil
+		},
+		Bars: func(ctx context.Context, symbol string, from, to time.Time) ([]aieval.Bar, error) {
+			history, err := tradingAlgorithm.GetBarHistory(algorithm.HistoryRequest{Symbol: symbol, StartDate: from, EndDate: to, TimeFrame: "1D"})
+			if err != nil {
+				return nil, err
+			}
+			bars := make([]aieval.Bar, len(history.Bars))
+			for i, b := range history.Bars {
+				bars[i] = aieval.Bar{Time: b.Timestamp, Close: b.Close}
+			}
+			return bars, nil
+		},
+	}, marketCalendar.Location())
+	if replaying {
+		aiEvaluator.SetClock(replayClock.Now)
+	}
+	aieval.NewHandler(aiEvaluator).RegisterRoutes(rt.Mux())
+	fillTracker.SetFillHandler(func(r fills.Record) {
+		hooks.Emit(webhooks.EventOrderFilled, r)
+		chatBot.AnnounceFill(r)
+		activityMonitor.Observe(r)
+		strategyBudgets.Check()
+	})
+	// Buying power — opening orders hold their notional against buying
+	// power and max_leverage until they finish, filled or not
+	fillTracker.SetDoneHandler(func(r fills.Record) {
+		tradingAlgorithm.ReleaseOrder(r.OrderID)
+	})
+	if !*mockMode || replaying {
+		go orderManager.Run(ctx, 2*time.Second)
+		go fillTracker.Run(ctx, 5*time.Second)
+	}
+	if !*mockMode && !replaying {
+		client.StreamTradeUpdatesInBackground(ctx, orderBook.Apply)
+	}
+	orders.NewHandler(orderManager, orderBook).RegisterRoutes(rt.Mux())
+	fills.NewHandler(fillTracker).RegisterRoutes(rt.Mux())
+
+	// Order retries — a submission that fails transiently (a timeout, a
+	// dropped connection, a 5xx) is retried with jittered backoff under
+	// the same client order ID, passing the trade guards again each time;
+	// one out of attempts waits for someone to retry or dismiss it at
+	// /api/orders/failed.
+	retryQueue, err := orders.NewRetryQueue(tradingBroker, filepath.Join(dataDir, "orders", "retries.json"), orders.DefaultRetryPolicy())
+	if err != nil {
+		logging.Fatal("Failed to open order retry queue", "error", err)
+	}
+	if replaying {
+		retryQueue.SetClock(replayClock.Now)
+	}
+	retryQueue.SetNotifier(func(title, message string, metadata map[string]interface{}) {
+		notificationService.AddNotification(notification.CreateSystemAlertNotification(title, message, metadata))
+	})
+	retryQueue.SetGuard(tradingAlgorithm.CheckTradeGuards)
+	tradingAlgorithm.SetOrderSubmitter(retryQueue.Submit)
+	if !*mockMode || replaying {
+		go retryQueue.Run(ctx, time.Second)
+	}
+	orders.NewRetryHandler(retryQueue).RegisterRoutes(rt.Mux())
+
+	// Pattern day trader rule — under $25,000 of equity, day trades in the
+	// last five sessions are counted from the fills journal and the
+	// broker, and a close that would make one too many is refused or, in
+	// warn mode, notified.
+	dayTrades, err := pdt.New(filepath.Join(dataDir, "pdt", "policy.json"), fillTracker.Records, marketCalendar)
+	if err != nil {
+		logging.Fatal("Failed to load day trade policy", "error", err)
+	}
+	dayTrades.SetNotifier(riskAlert("pdt"))
+	if replaying {
+		dayTrades.SetClock(replayClock.Now)
+	}
+	var accountBroker algorithm.Broker = client
+	if replaying {
+		accountBroker = simBroker
+	}
+	if !*mockMode || replaying {
+		dayTrades.SetAccountSource(func() (pdt.Account, error) {
+			acct, err := accountBroker.GetAccount()
+			if err != nil {
+				return pdt.Account{}, err
+			}
+			equity, _ := acct.Equity.Float64()
+			return pdt.Account{Equity: equity, DayTradeCount: int(acct.DaytradeCount), PatternDayTrader: acct.PatternDayTrader}, nil
+		})
+	}
+	tradingAlgorithm.AddTradeGuard("pattern day trader", func(signal *algorithm.TradeSignal) error {
+		if tradingAlgorithm.OpensPosition(signal) {
+			return nil
+		}
+		return dayTrades.CheckClose(signal.Symbol)
+	})
+	pdt.NewHandler(dayTrades).RegisterRoutes(rt.Mux())
+
+	// Position aging — each position's age runs from the fill that opened
+	// it, and one held past its strategy's horizon in sessions is notified
+	// once a session as stale or, under a close policy, closed at market.
+	positionAges, err := aging.New(filepath.Join(dataDir, "aging", "policy.json"), fillTracker.Records, marketCalendar)
+	if err != nil {
+		logging.Fatal("Failed to load position aging policy", "error", err)
+	}
+	positionAges.SetNotifier(riskAlert("position_aging"))
+	if replaying {
+		positionAges.SetClock(replayClock.Now)
+	}
+	positionAges.SetBroker(riskBroker(*mockMode, client, tradingMode.CheckExecution))
+	go positionAges.Run(ctx)
+	aging.NewHandler(positionAges).RegisterRoutes(rt.Mux())
+
+	// Execution algorithms — orders above the policy's notional, or with a
+	// twap/vwap execution, are sliced into child orders over a window.
+	// VWAP weights come from the volume in streamed minute bars, and every
+	// finished parent's implementation shortfall, with its slippage against
+	// the market VWAP over its life, goes to the journal.
+	volumeProfile := execution.NewVolumeProfile(marketCalendar.Location())
+	execManager, err := execution.NewManager(filepath.Join(dataDir, "execution", "journal.jsonl"), tradingBroker,
+		func(symbol string) float64 { return tradingAlgorithm.GetMarketData(symbol).Price },
+		volumeProfile, execution.DefaultPolicy())
+	if err != nil {
+		logging.Fatal("Failed to open execution journal", "error", err)
+	}
+	defer execManager.Close()
+	execManager.SetOrderHandler(fillTracker.Track)
+	execManager.SetMarketVWAP(vwapTracker)
+	// Parents entered over the API, and every child, face the same guards
+	// and risk limits as the algorithm's own orders
+	execManager.SetGuard(tradingAlgorithm.CheckOrder)
+	tradingAlgorithm.SetOrderSlicer(func(signal *algorithm.TradeSignal, preview *algorithm.OrderPreview) (string, bool, error) {
+		algo := ""
+		if signal.Execution == algorithm.ExecutionTWAP || signal.Execution == algorithm.ExecutionVWAP {
+			algo = signal.Execution
+		}
+		parent, sliced, err := execManager.Slice(preview.Request, preview.MarketPrice, algo)
+		if err != nil || !sliced {
+			return "", false, err
+		}
+		return parent.ID, true, nil
+	})
+	if !*mockMode || replaying {
+		go execManager.Run(ctx, time.Second)
+	}
+	execution.NewHandler(execManager).RegisterRoutes(rt.Mux())
+
+	// Raw tick recording for replay and research. Off unless -record-ticks
+	// is set or the policy is switched on over the API.
+	tickPolicy := ticks.DefaultPolicy()
+	tickPolicy.Enabled = *recordTicks
+	tickStore, err := ticks.Open(filepath.Join(dataDir, "ticks"), marketCalendar.Location(), tickPolicy)
+	if err != nil {
+		logging.Fatal("Failed to open tick store", "error", err)
+	}
+	go tickStore.Run(ctx, 5*time.Second)
+	ticks.NewHandler(tickStore).RegisterRoutes(rt.Mux())
+
+	// Time-series export of bars, baselines and portfolio metrics to
+	// InfluxDB or TimescaleDB, for Grafana and long-horizon analytics.
+	// Off unless a database is configured; replays are never exported.
+	var tsSink tsdb.Sink
+	if !replaying {
+		tsSink = openTimeSeriesSink(ctx, secretLoader)
+	}
+	tsWriter, err := tsdb.New(tsSink, tsdb.DefaultPolicy())
+	if err != nil {
+		logging.Fatal("Failed to create time-series writer", "error", err)
+	}
+	tsWriter.SetSources(tsdb.Sources{Portfolio: tradingAlgorithm.GetPortfolio, Baselines: tradingAlgorithm.GetBaselines})
+	go tsWriter.Run(ctx)
+	tsdb.NewHandler(tsWriter).RegisterRoutes(rt.Mux())
+
+	// Calendar-driven jobs. The pre-market routine refreshes history and
+	// baselines for the watchlist 45 minutes before each open, checks every
+	// symbol is still tradable and posts a readiness notification.
+	jobScheduler := scheduler.New(30 * time.Second)
+	if replaying {
+		jobScheduler.SetClock(replayClock.Now)
+	}
+	premarketRoutine := premarket.New(tickerServer.GetSymbols, tradingAlgorithm)
+	premarketRoutine.SetRearm(jobScheduler.Rearm)
+	premarketRoutine.SetNotifier(func(title, message string, metadata map[string]interface{}) {
+		notificationService.AddNotification(notification.CreateSystemAlertNotification(title, message, metadata))
+	})
+	if !*mockMode {
+		premarketRoutine.SetAssetChecker(premarket.AlpacaAssets{Client: client})
+	}
+	if earningsCalendar.Status().Provider != "" {
+		premarketRoutine.SetEarnings(earningsCalendar)
+	}
+	jobScheduler.Add(scheduler.Job{
+		Name:        "premarket",
+		Description: "Refresh history and baselines, check tradability, notify when ready",
+		Schedule:    scheduler.BeforeOpen(marketCalendar, 45*time.Minute),
+		Run: func(ctx context.Context) error {
+			_, err := premarketRoutine.Run(ctx)
+			return err
+		},
+	})
+	jobScheduler.Add(scheduler.Job{
+		Name:        "universes",
+		Description: "Reload universe files and refresh those whose source is due",
+		Schedule:    scheduler.BeforeOpen(marketCalendar, 2*time.Hour),
+		Run:         universes.RefreshDue,
+	})
+	if !*mockMode {
+		jobScheduler.Add(scheduler.Job{
+			Name:        "features",
+			Description: "Store the watchlist's daily features once the session's bars are final",
+			Schedule:    scheduler.AfterClose(marketCalendar, 30*time.Minute),
+			Run: func(ctx context.Context) error {
+				return featureStore.Sync(ctx, tickerServer.GetSymbols(), premarket.DefaultLookbackDays)
+			},
+		})
+	}
+	if !replaying {
+		go jobScheduler.Run(ctx)
+	}
+	scheduler.NewHandler(jobScheduler).RegisterRoutes(rt.Mux())
+	premarket.NewHandler(premarketRoutine).RegisterRoutes(rt.Mux())
+	// The pre-market baselines choose each symbol's signal timeframe from
+	// its liquidity; instances naming no timeframe run at it
+	algorithm.NewTimeFrameHandler(tradingAlgorithm).RegisterRoutes(rt.Mux())
+
+	// Long-running work (downloads, backtests, optimizations) goes through
+	// the job queue so requests return at once with a job to poll.
+	jobQueue, err := jobs.New(filepath.Join(dataDir, "jobs", "jobs.json"), 2, 64)
+	if err != nil {
+		logging.Fatal("Failed to open job store", "error", err)
+	}
+	jobQueue.Register("history_download", historyDownloadJob(tradingAlgorithm, tickerServer.GetSymbols))
+	backtestDir := filepath.Join(dataDir, "backtests")
+	jobQueue.Register("walk_forward", walkForwardJob(tradingAlgorithm, backtestDir))
+	// GET /api/backtests/ - saved backtest results, by the name a job returned
+	rt.Handle("/api/backtests/", http.StripPrefix("/api/backtests/", http.FileServer(http.Dir(backtestDir))))
+	go jobQueue.Run(ctx)
+	jobs.NewHandler(jobQueue).RegisterRoutes(rt.Mux())
+
+	// Subsystem health for the UI's traffic light. Symbol data is stale
+	// once two minutes pass without a trade during regular hours.
+	diagnosticsNow := time.Now
+	if replaying {
+		diagnosticsNow = replayClock.Now
+	}
+	monitor := diagnostics.New()
+	monitor.Register("market_data", func() diagnostics.Check {
+		now := diagnosticsNow()
+		return diagnostics.FeedCheck(tickerServer.Health(), tickerServer.GetAllLastData(), marketCalendar.IsOpen(now), now, 2*time.Minute)
+	})
+	monitor.Register("claude", func() diagnostics.Check { return diagnostics.ClaudeCheck(resilientClaude.Health()) })
+	monitor.Register("alpaca_api", apiMonitor.Check)
+	monitor.Register("caches", func() diagnostics.Check { return diagnostics.CacheCheck(tradingAlgorithm.CacheStats()) })
+	monitor.Register("scheduler", func() diagnostics.Check { return diagnostics.SchedulerCheck(jobScheduler.Status()) })
+	monitor.Register("data_quality", func() diagnostics.Check { return diagnostics.DataQualityCheck(dataQuality.Symbols()) })
+	diagnostics.NewHandler(monitor).RegisterRoutes(rt.Mux())
+
+	// Set up market data handler to forward data from ticker to algorithm
+	tickerServer.SetDataHandler(func(symbol string, trade ticker.TickerData) {
+		tradingAlgorithm.UpdateMarketData(
+			symbol,
+			trade.Trade.Price,
+			trade.Trade.Price*1.05,         // Placeholder for 24h high
+			trade.Trade.Price*0.95,         // Placeholder for 24h low
+			float64(trade.Trade.Size)*1000, // Convert uint32 to float64
+			0,                              // Placeholder for 24h change percentage
+		)
+		shadowTracker.Mark(symbol, trade.Trade.Price)
+
+		// Process the symbol to generate trading signals
+		// Only process if explicitly triggered by UI (don't auto-process for all data updates)
+		go func(s string) {
+			// Market data is updated, but signals are only generated
+			// when requested from the frontend to avoid excessive Claude API calls
+			// if err := tradingAlgorithm.ProcessSymbol(s); err != nil {
+			//    logger().Error("Failed to process symbol", "symbol", s, "error", err)
+			// }
+		}(symbol)
+
+		if err := tickStore.Record(tickerTicks(symbol, trade)...); err != nil {
+			logger().Error("Failed to record ticks", "symbol", symbol, "error", err)
+		}
+
+		// A quarantined quote is kept from quote lookups
+		if trade.Quote != nil {
+			dataQuality.CheckQuote(symbol, qualityQuote(trade.Quote))
+		}
+
+		// Minute bar volume feeds the VWAP profile and indicators, unless
+		// the bar is quarantined
+		if trade.Bar != nil {
+			bar := algorithm.BarData{
+				Symbol:    symbol,
+				Timestamp: trade.Bar.Timestamp,
+				Open:      trade.Bar.Open,
+				High:      trade.Bar.High,
+				Low:       trade.Bar.Low,
+				Close:     trade.Bar.Close,
+				Volume:    int64(trade.Bar.Volume),
+				VWAP:      trade.Bar.VWAP,
+			}
+			if dataQuality.CheckBar(symbol, "1Min", qualityBar(bar)) {
+				volumeProfile.Observe(symbol, trade.Bar.Timestamp, float64(trade.Bar.Volume))
+				vwapTracker.Observe(symbol, vwapBar(bar))
+				if _, err := featureStore.Append(symbol, "1Min", []featurestore.Bar{featureBar(bar)}); err != nil {
+					logger().Error("Failed to store features", "symbol", symbol, "error", err)
+				}
+				tsWriter.Bar("1Min", bar)
+			}
+		}
+
+		// Trip the symbol's circuit breaker on abnormal quotes or trades
+		obs := circuit.Observation{Symbol: symbol}
+		if trade.Trade != nil {
+			obs.Price = trade.Trade.Price
+		}
+		if trade.Quote != nil {
+			obs.Bid, obs.Ask, obs.QuoteTime = trade.Quote.BidPrice, trade.Quote.AskPrice, trade.Quote.Timestamp
+		}
+		breakers.Observe(obs, time.Now())
+
+		// Raise a market event notification if the move crosses the
+		// symbol's alert threshold
+		md := tradingAlgorithm.GetMarketData(symbol)
+		priceAlerts.Observe(symbol, md.Price, md.PrevClose, time.Now())
+	})
+
+	// Play the replayed day through the ticker, advancing the scheduler on
+	// the simulated clock
+	if replaying {
+		from, to := replayDate, replayDate.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		var events []replay.Event
+		if *replaySource == replay.SourceBars {
+			events, err = replay.LoadBars(mdClient, tickerServer.GetSymbols(), from, to)
+		} else {
+			var recorded *ticks.Store
+			recorded, err = ticks.Open(filepath.Join(*dataRoot, datadir.Mode(*usePaperTrading, false), "ticks"), marketCalendar.Location(), ticks.DefaultPolicy())
+			if err == nil {
+				events, err = replay.LoadTicks(recorded, tickerServer.GetSymbols(), from, to)
+			}
+		}
+		if err != nil {
+			logging.Fatal("Failed to load replay data", "error", err)
+		}
+		if len(events) == 0 {
+			logging.Fatal("No replay data recorded", "source", *replaySource, "symbols", tickerServer.GetSymbols(), "day", *replayDay)
+		}
+		replayRunner := replay.NewRunner(replayClock, simBroker, events, *replaySource, *replayDay, replaySpeedX,
+			tickerServer.Inject, func(now time.Time) { jobScheduler.Tick(ctx, now) })
+		replay.NewHandler(replayRunner).RegisterRoutes(rt.Mux())
+		go func() {
+			replayRunner.Run(ctx)
+			logger().Info("Replay finished", "day", *replayDay, "events", len(events), "fills", simBroker.Fills())
+		}()
+	}
+
+	// Registry of configured algorithm instances, each with its own
+	// parameters and optionally scoped to a symbol and strategy
+	algoInstances := algorithm.NewInstanceRegistry(tradingAlgorithm)
+	algorithm.NewInstanceHandler(algoInstances).RegisterRoutes(rt.Mux())
+
+	// Parameter tuning — new parameters for an instance run as its shadow
+	// until enough signals are scored against the current ones, then are
+	// committed or rolled back on a paired t-test.
+	tuner, err := tuning.New(filepath.Join(dataDir, "tuning", "trials.json"), algoInstances)
+	if err != nil {
+		logging.Fatal("Failed to load parameter trials", "error", err)
+	}
+	tuner.SetNotifier(func(title, message string, metadata map[string]interface{}) {
+		notificationService.AddNotification(notification.CreateSystemAlertNotification(title, message, metadata))
+	})
+	if replaying {
+		tuner.SetClock(replayClock.Now)
+	}
+	algoInstances.SetRunHandler(tuner.Observe)
+	tuning.NewHandler(tuner).RegisterRoutes(rt.Mux())
+
+	// Set up HTTP handlers, passing the market data client for order handlers to quote with
+	setupHTTPHandlers(rt, client, tradingAlgorithm, tickerServer, userBaskets(userStore, basketManager, *basketStore), userStore,
+		notificationService, feedCache, refreshAndApply, signalHistory, confirmQueue, algoInstances, positionAges, mdClient)
+
+	// Every response names the trading mode, and /api/ responses are JSON
+	// unless the handler says otherwise. Every /api/ request is audited
+	// with the user its token belongs to. Once users exist,
+	// requests without a valid token are refused, except the inbound hooks
+	// that verify their own signatures and the Claude adapter's one-time
+	// context reads. CORS applies before authentication
+	// so refusals reach the browser. The trading mode guards the order
+	// routes of authenticated requests; only the routes whose handlers
+	// honor dry_run=true let it through. Every /api/ route is also served
+	// under /api/v1/.
+	rt.Use(tradingMode.Announce, router.Recover, router.Log, router.CORS(corsPolicy), router.JSON("/api/"),
+		func(next http.Handler) http.Handler { return auditLog.Middleware(next, userStore.Caller) },
+		func(next http.Handler) http.Handler {
+			return userStore.Middleware(next, "/api/webhooks/tradingview", "/api/chatops/slack", "/api/chatops/discord",
+				"/api/snapshot/context/redeem")
+		},
+		func(next http.Handler) http.Handler {
+			return tradingMode.Middleware(next,
+				[]string{"POST /api/confirmations/{id}/confirm", "POST /api/approvals/{id}/approve",
+					"POST /api/algorithms/execute", "POST /api/execution/parents", "POST /api/orders/failed"},
+				[]string{"POST /api/executeTrade", "POST /api/orders/multileg", "POST /api/risk/volatility/trim"})
+		})
+	rt.Alias("/api/v1/{path...}", "/api/{path...}")
+	logger().Info("Starting HTTP server", "port", *port, "auth_enabled", userStore.Enabled(),
+		"cors_origins", corsPolicy.AllowedOrigins)
+	if userStore.Enabled() && corsPolicy.AnyOrigin() {
+		logging.Fatal("Refusing to let any origin call the authenticated API; list the allowed_origins in the -cors-config file")
+	}
+	if err := http.ListenAndServe(":"+*port, rt); err != nil {
+		logging.Fatal("Failed to start HTTP server", "error", err)
+	}
+}
+
+type SignalGeneratorFunc func(string) (*algorithm.TradeSignal, error)
+
+// userBaskets returns a lookup of the basket manager for the user a request
+// acts for. The default user keeps the baskets in the data directory root;
+// other users' managers are opened under their own directory on first use,
+// with the backend named kind.
+func userBaskets(store *users.Store, defaultManager *ticker.BasketManager, kind string) func(*http.Request) (*ticker.BasketManager, error) {
+	var mu sync.Mutex
+	managers := map[string]*ticker.BasketManager{users.DefaultID: defaultManager}
+	return func(r *http.Request) (*ticker.BasketManager, error) {
+		id, err := store.Scope(r)
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if m, ok := managers[id]; ok {
+			return m, nil
+		}
+		backend, err := ticker.OpenBasketStore(kind, store.Dir(id))
+		if err != nil {
+			return nil, err
+		}
+		m := ticker.NewBasketManagerWithStore(backend)
+		managers[id] = m
+		return m, nil
+	}
+}
+
+// featureBar converts a bar for the feature store.
+func featureBar(b algorithm.BarData) featurestore.Bar {
+	return featurestore.Bar{Time: b.Timestamp, Open: b.Open, High: b.High, Low: b.Low, Close: b.Close, Volume: float64(b.Volume), VWAP: b.VWAP}
+}
+
+// vwapBar converts a bar for the VWAP indicators.
+func vwapBar(b algorithm.BarData) indicators.Bar {
+	return indicators.Bar{Time: b.Timestamp, Open: b.Open, High: b.High, Low: b.Low, Close: b.Close, Volume: float64(b.Volume), VWAP: b.VWAP}
+}
+
+// qualityBar converts a bar for the data quality checks.
+func qualityBar(b algorithm.BarData) dataquality.Bar {
+	return dataquality.Bar{Time: b.Timestamp, Open: b.Open, High: b.High, Low: b.Low, Close: b.Close, Volume: float64(b.Volume)}
+}
+
+// qualityQuote converts a polled quote for the data quality checks.
+func qualityQuote(q *marketdata.Quote) dataquality.Quote {
+	return dataquality.Quote{Time: q.Timestamp, Bid: q.BidPrice, Ask: q.AskPrice}
+}
+
+// tickerTicks converts a polled trade and quote into ticks for recording.
+func tickerTicks(symbol string, data ticker.TickerData) []ticks.Tick {
+	var out []ticks.Tick
+	if t := data.Trade; t != nil {
+		out = append(out, ticks.Tick{
+			Time:     t.Timestamp,
+			Symbol:   symbol,
+			Kind:     ticks.KindTrade,
+			Price:    t.Price,
+			Size:     float64(t.Size),
+			Exchange: t.Exchange,
+			ID:       t.ID,
+		})
+	}
+	if q := data.Quote; q != nil {
+		out = append(out, ticks.Tick{
+			Time:    q.Timestamp,
+			Symbol:  symbol,
+			Kind:    ticks.KindQuote,
+			Bid:     q.BidPrice,
+			BidSize: float64(q.BidSize),
+			Ask:     q.AskPrice,
+			AskSize: float64(q.AskSize),
+		})
+	}
+	return out
+}
+
+// openTimeSeriesSink returns the configured time-series database:
+// InfluxDB when INFLUXDB_URL is set, else TimescaleDB when TIMESCALE_DSN
+// is, else nil. A database that cannot be reached or prepared is logged
+// and left off.
+func openTimeSeriesSink(ctx context.Context, loader *secrets.Loader) tsdb.Sink {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	lookup := func(name string) string {
+		v, _, _ := loader.Lookup(ctx, name)
+		return v
+	}
+
+	if u := lookup("INFLUXDB_URL"); u != "" {
+		sink, err := tsdb.NewInflux(u, lookup("INFLUXDB_ORG"), lookup("INFLUXDB_BUCKET"), lookup("INFLUXDB_TOKEN"))
+		if err != nil {
+			logger().Warn("Time-series export disabled", "error", err)
+			return nil
+		}
+		logger().Info("Exporting time series to InfluxDB", "url", u)
+		return sink
+	}
+
+	if dsn := lookup("TIMESCALE_DSN"); dsn != "" {
+		db, err := sql.Open("pgx", dsn)
+		if err != nil {
+			logger().Warn("Time-series export disabled: build with -tags timescale for the Postgres driver", "error", err)
+			return nil
+		}
+		table := lookup("TIMESCALE_TABLE")
+		if table == "" {
+			table = "trader_metrics"
+		}
+		sink, err := tsdb.NewTimescale(db, table)
+		if err == nil {
+			err = sink.Init(ctx)
+		}
+		if err != nil {
+			db.Close()
+			logger().Warn("Time-series export disabled", "error", err)
+			return nil
+		}
+		logger().Info("Exporting time series to TimescaleDB", "table", table)
+		return sink
+	}
+	return nil
+}
+
+// recordSignal appends a signal and the market snapshot it was generated
+// against to the persistent history. Failures are logged, never fatal.
+func recordSignal(store *signalstore.Store, signal *algorithm.TradeSignal, md algorithm.MarketData) {
+	if store == nil || signal == nil {
+		return
+	}
+	var rr *signalstore.RiskReward
+	if r := signal.RiskReward; r != nil {
+		rr = &signalstore.RiskReward{
+			Direction:     r.Direction,
+			Entry:         r.Entry,
+			Stop:          r.Stop,
+			Target:        r.Target,
+			LevelSource:   r.LevelSource,
+			StopSource:    r.StopSource,
+			Risk:          r.Risk,
+			Reward:        r.Reward,
+			RMultiple:     r.RMultiple,
+			Probability:   r.Probability,
+			ExpectedR:     r.ExpectedR,
+			ExpectedValue: r.ExpectedValue,
+		}
+	}
+	var analysis *signalstore.Analysis
+	if a := signal.Analysis; a != nil {
+		analysis = &signalstore.Analysis{
+			Thesis:            a.Thesis,
+			KeyRisks:          a.KeyRisks,
+			InvalidationLevel: a.InvalidationLevel,
+			TimeHorizon:       a.TimeHorizon,
+		}
+	}
+	var usage *signalstore.AIUsage
+	if u := signal.AIUsage; u != nil {
+		usage = &signalstore.AIUsage{
+			InputTokens:  u.InputTokens,
+			OutputTokens: u.OutputTokens,
+			Estimated:    u.Estimated,
+			Cost:         u.Cost,
+		}
+	}
+	_, err := store.Append(signalstore.Record{
+		Symbol:     signal.Symbol,
+		Signal:     signal.Signal,
+		OrderType:  signal.OrderType,
+		LimitPrice: signal.LimitPrice,
+		StopPrice:  signal.StopPrice,
+		Confidence: signal.Confidence,
+		Reasoning:  signal.Reasoning,
+		Analysis:   analysis,
+		Source:     signal.Source,
+		Tag:        signal.OrderTag(),
+		Timestamp:  signal.Timestamp,
+		Market: &signalstore.Snapshot{
+			Price:     md.Price,
+			High24h:   md.High24h,
+			Low24h:    md.Low24h,
+			Volume24h: md.Volume24h,
+			Change24h: md.Change24h,
+			Patterns:  md.Patterns,
+		},
+		RiskReward: rr,
+		AIUsage:    usage,
+
+		PromptVariant: signal.PromptVariant,
+	})
+	if err != nil {
+		logger().Warn("Failed to record signal", "symbol", signal.Symbol, "error", err)
+	}
+}
+
+// historyDownloadJob refreshes cached daily bars for the requested symbols
+// (the watchlist when none are given), one symbol at a time so progress
+// and cancellation are per symbol.
+func historyDownloadJob(a *algorithm.TradingAlgorithm, watchlist func() []string) jobs.Runner {
+	return func(ctx context.Context, params json.RawMessage, progress *jobs.Progress) (string, error) {
+		var request struct {
+			Symbols      []string `json:"symbols"`
+			LookbackDays int      `json:"lookback_days"`
+		}
+		if len(params) > 0 {
+			if err := json.Unmarshal(params, &request); err != nil {
+				return "", fmt.Errorf("invalid params: %w", err)
+			}
+		}
+		if len(request.Symbols) == 0 {
+			request.Symbols = watchlist()
+		}
+		if request.LookbackDays <= 0 {
+			request.LookbackDays = premarket.DefaultLookbackDays
+		}
+
+		var failed []string
+		for i, symbol := range request.Symbols {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+			if errs := a.RefreshHistoricalCache([]string{symbol}, request.LookbackDays); len(errs) > 0 {
+				failed = append(failed, symbol)
+			}
+			progress.Report(float64(i+1)/float64(len(request.Symbols)), fmt.Sprintf("%s (%d of %d)", symbol, i+1, len(request.Symbols)))
+		}
+		if len(failed) > 0 {
+			return "", fmt.Errorf("failed to refresh %s", strings.Join(failed, ", "))
+		}
+		return "", nil
+	}
+}
+
+// walkForwardJob runs a walk-forward backtest over daily or intraday
+// history fetched for the request and saves the stitched result to dir as
+// JSON. The result is the file's name under /api/backtests/.
+func walkForwardJob(a *algorithm.TradingAlgorithm, dir string) jobs.Runner {
+	return func(ctx context.Context, params json.RawMessage, progress *jobs.Progress) (string, error) {
+		var request algorithm.WalkForwardRequest
+		if err := json.Unmarshal(params, &request); err != nil {
+			return "", fmt.Errorf("invalid params: %w", err)
+		}
+		if request.LookbackDays <= 0 {
+			request.LookbackDays = 730
+		}
+		end := time.Now()
+		history, err := a.GetBarHistory(algorithm.HistoryRequest{
+			Symbol:    strings.ToUpper(request.Symbol),
+			StartDate: end.AddDate(0, 0, -request.LookbackDays),
+			EndDate:   end,
+			TimeFrame: request.Config.Resolution(),
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch history: %w", err)
+		}
+		progress.Report(0, fmt.Sprintf("%d bars fetched", len(history.Bars)))
+
+		result, err := algorithm.WalkForward(ctx, history.Bars, request, progress.Report)
+		if err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create backtests directory: %w", err)
+		}
+		name := fmt.Sprintf("walk_forward_%s_%s_%d.json", result.Request.Symbol, result.Request.AlgorithmType, result.GeneratedAt.Unix())
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to encode result: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			return "", fmt.Errorf("failed to save result: %w", err)
+		}
+		return name, nil
+	}
+}
+
+// settleMode checks the mode flags and puts a replay into mock mode: it
+// never trades, so nothing is wired to Alpaca's trading API and its orders
+// go to the simulated broker. Recording fixtures needs Alpaca itself.
+func settleMode(mockMode *bool, replaying bool, recordFixtures string) error {
+	if recordFixtures != "" && (replaying || *mockMode) {
+		return errors.New("-record-fixtures records Alpaca itself and cannot be used with -mock or -replay")
+	}
+	if replaying {
+		*mockMode = true
+		os.Setenv("GO_TRADER_REPLAY", "true")
+	}
+	return nil
+}
+
+// riskBroker is the broker the risk controls reduce positions through, or
+// none in mock mode. Reductions are refused while execution returns an
+// error, as in safe mode.
+func riskBroker(mockMode bool, client *alpaca.Client, execution func() error) gaprisk.Broker {
+	if mockMode {
+		return nil
+	}
+	return gaprisk.AlpacaBroker{Client: client, Execution: execution}
+}
+
+// pinOpenOrderSymbols pins the symbols of open broker orders on ts every
+// interval until ctx is cancelled, so a pending order keeps getting prices
+// even after its symbol leaves the watch list.
+func pinOpenOrderSymbols(ctx context.Context, client *alpaca.Client, ts *ticker.TickerServer, interval time.Duration) {
+	pin := func() {
+		orders, err := client.GetOrders(alpaca.GetOrdersRequest{Status: "open", Limit: 500})
+		if err != nil {
+			logger().Warn("Failed to list open orders", "error", err)
+			return
+		}
+		symbols := make([]string, 0, len(orders))
+		for _, o := range orders {
+			symbols = append(symbols, o.Symbol)
+		}
+		ts.SetPinnedSymbols(ticker.PinOrders, symbols)
+	}
+
+	pin()
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			pin()
+		}
+	}
+}
+
+// adaptedClaudeClient adapts the claude.WebSocketAdapterWrapper to the algorithm.ClaudeClientInterface
+type adaptedClaudeClient struct {
+	*claude.WebSocketAdapterWrapper
+}
+
+// GenerateTradeSignal adapts the method signature
+func (a *adaptedClaudeClient) GenerateTradeSignal(symbol string, marketData algorithm.MarketData, portfolioData algorithm.PortfolioData) (*algorithm.TradeSignal, error) {
+	// Convert algorithm types to claude types
+	claudeMarketData := claude.AlgorithmMarketData{
+		Price:     marketData.Price,
+		High24h:   marketData.High24h,
+		Low24h:    marketData.Low24h,
+		Volume24h: marketData.Volume24h,
+		Change24h: marketData.Change24h,
+		Patterns:  marketData.Patterns,
+
+		PrevClose:          marketData.PrevClose,
+		ChangeSession:      marketData.ChangeSession,
+		ImpliedMovePercent: marketData.ImpliedMovePercent,
+		ContextID:          marketData.ContextID,
+		ContextToken:       marketData.ContextToken,
+	}
+	if b := marketData.Breadth; b != nil {
+		claudeMarketData.Breadth = &claude.MarketBreadth{
+			Advancers:      b.Advancers,
+			Decliners:      b.Decliners,
+			PercentAboveMA: b.PercentAboveMA,
+			NewHighs:       b.NewHighs,
+			NewLows:        b.NewLows,
+			Score:          b.Score,
+			Health:         b.Health,
+			AsOf:           b.AsOf,
+		}
+	}
+	if f := marketData.Features; f != nil {
+		claudeMarketData.Features = &claude.SymbolFeatures{Timeframe: f.Timeframe, AsOf: f.AsOf, Regime: f.Regime, Values: f.Values}
+	}
+	if st := marketData.Session; st != nil {
+		claudeMarketData.Session = &claude.SessionStats{Open: st.Open, High: st.High, Low: st.Low, VWAP: st.VWAP, Volume: st.Volume,
+			AvgVolume20: st.AvgVolume20, VolumeRatio: st.VolumeRatio, VolumePace: st.VolumePace, AsOf: st.AsOf,
+			RealizedVolatility: st.RealizedVolatility, Spread: st.Spread, SpreadBps: st.SpreadBps}
+	}
+
+	claudePortfolioData := claude.AlgorithmPortfolioData{
+		Balance:     portfolioData.Balance,
+		TotalValue:  portfolioData.TotalValue,
+		DailyPnL:    portfolioData.DailyPnL,
+		DailyReturn: portfolioData.DailyReturn,
+		Positions:   make(map[string]claude.AlgorithmPositionData),
+	}
+
+	// Convert positions from algorithm to claude types
+	for symbol, pos := range portfolioData.Positions {
+		claudePortfolioData.Positions[symbol] = claude.AlgorithmPositionData{
+			Symbol:    pos.Symbol,
+			Quantity:  pos.Quantity,
+			AvgPrice:  pos.AvgPrice,
+			MarketVal: pos.MarketVal,
+			Profit:    pos.Profit,
+			Return:    pos.Return,
+		}
+	}
+
+	// Call the original GenerateTradeSignal method
+	claudeSignal, err := a.WebSocketAdapterWrapper.GenerateTradeSignal(symbol, claudeMarketData, claudePortfolioData)
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert claude types to algorithm types
+	signal := &algorithm.TradeSignal{
+		Symbol:    claudeSignal.Symbol,
+		Signal:    claudeSignal.Signal,
+		OrderType: claudeSignal.OrderType,
+		Timestamp: claudeSignal.Timestamp,
+		Reasoning: claudeSignal.Reasoning,
+
+		PromptVariant: claudeSignal.PromptVariant,
+	}
+
+	if claudeSignal.LimitPrice != nil {
+		limitPrice := *claudeSignal.LimitPrice
+		signal.LimitPrice = &limitPrice
+	}
+
 	if claudeSignal.Confidence != nil {
 		confidence := *claudeSignal.Confidence
 		signal.Confidence = &confidence
 	}
 
+	if a := claudeSignal.Analysis; a != nil {
+		signal.Analysis = &algorithm.Analysis{
+			Thesis:            a.Thesis,
+			KeyRisks:          a.KeyRisks,
+			InvalidationLevel: a.InvalidationLevel,
+			TimeHorizon:       a.TimeHorizon,
+		}
+	}
+
+	if u := claudeSignal.Usage; u != nil {
+		signal.AIUsage = &algorithm.AIUsage{
+			InputTokens:  u.InputTokens,
+			OutputTokens: u.OutputTokens,
+			Estimated:    u.Estimated,
+		}
+	}
+
 	return signal, nil
 }
 
-func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgorithm, tickerServer *ticker.TickerServer,
-	basketManager *ticker.BasketManager, notificationManager *notification.NotificationManager,
+func setupHTTPHandlers(rt *router.Router, client *alpaca.Client, tradingAlgo *algorithm.TradingAlgorithm, tickerServer *ticker.TickerServer,
+	basketsFor func(*http.Request) (*ticker.BasketManager, error), userStore *users.Store,
+	notificationManager *notification.NotificationManager,
 	feedCache *cartography.FeedCache,
 	refreshCartography func(context.Context) (*cartography.DataFeed, error),
-	apiKey, apiSecret string) {
-	// Create a registry for the Lopez de Prado algorithms
-	var algoRegistry = make(map[string]interface{})
-
+	signalHistory *signalstore.Store, confirmQueue *confirmations.Queue,
+	algoInstances *algorithm.InstanceRegistry, positionAges *aging.Monitor, mdClient *marketdata.Client) {
 	// Create notification handler to register routes
 	notificationHandler := notification.NewNotificationHandler(notificationManager)
+	notificationHandler.SetFilter(func(r *http.Request, n notification.Notification) bool {
+		userID, err := userStore.Scope(r)
+		if err != nil {
+			userID = users.FromContext(r.Context()).ID
+		}
+		return userStore.Settings(userID).Notifications.Allows(string(n.Priority), string(n.Type))
+	})
 
 	// Function to generate signal without execution
 	generateSignalWithoutExecution := func(algo *algorithm.TradingAlgorithm, symbol string) (*algorithm.TradeSignal, error) {
@@ -586,25 +2235,13 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 		return signal, nil
 	}
 
-	corsMiddleware := func(next http.HandlerFunc) http.HandlerFunc {
-		return func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-
-			if r.Method == "OPTIONS" {
-				w.WriteHeader(http.StatusOK)
-				return
-			}
-
-			next(w, r)
-		}
-	}
+	api := rt.Group("/api")
 
 	mockMode := strings.EqualFold(os.Getenv("GO_TRADER_MOCK"), "true")
+	replayMode := strings.EqualFold(os.Getenv("GO_TRADER_REPLAY"), "true")
 
 	// Account Handler
-	http.HandleFunc("/api/account", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/account", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		if mockMode {
 			json.NewEncoder(w).Encode(map[string]interface{}{
@@ -629,10 +2266,10 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 		}
 
 		json.NewEncoder(w).Encode(acct)
-	}))
+	})
 
 	// Positions Handler
-	http.HandleFunc("/api/positions", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/positions", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		if mockMode {
 			json.NewEncoder(w).Encode([]map[string]interface{}{
@@ -641,17 +2278,24 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 			return
 		}
 
-		positions, err := client.GetPositions()
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		// Serve live-marked positions once the algorithm has synced with
+		// the broker; fall back to asking Alpaca directly until then
+		if tradingAlgo.PortfolioSyncedAt().IsZero() {
+			positions, err := client.GetPositions()
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			json.NewEncoder(w).Encode(positions)
 			return
 		}
+		json.NewEncoder(w).Encode(livePositions(tradingAlgo.GetPortfolio(), positionAges.Ages()))
+	})
 
-		json.NewEncoder(w).Encode(positions)
-	}))
-
-	// Orders Handler
-	http.HandleFunc("/api/orders", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	// Orders Handler - GET ?limit=&cursor=&sort=&filter=, newest first by
+	// default. Every matching order is fetched from Alpaca, past its page
+	// size, so X-Total-Count is the real total.
+	api.HandleFunc("/orders", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		if mockMode {
 			json.NewEncoder(w).Encode([]map[string]interface{}{
@@ -660,35 +2304,57 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 			return
 		}
 
-		orders, err := client.GetOrders(alpaca.GetOrdersRequest{
-			Status: "all",
-			Limit:  100,
-		})
+		req, err := orderList.Parse(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		query := alpaca.GetOrdersRequest{Status: "all"}
+		for _, symbol := range req.Filter("symbol") {
+			query.Symbols = append(query.Symbols, strings.ToUpper(symbol))
+		}
+		orders, err := fetchOrders(client, query)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-
-		json.NewEncoder(w).Encode(orders)
-	}))
+		page := orderList.Apply(orders, req)
+		paging.SetHeaders(w, page.Total, page.NextCursor)
+		json.NewEncoder(w).Encode(page.Items)
+	})
 
 	// Tickers Handler - GET current tickers, POST to update
-	http.HandleFunc("/api/tickers", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/tickers", func(w http.ResponseWriter, r *http.Request) {
+		// The polled symbols are shared; each user's watchlist records the
+		// ones they asked for
+		userID, err := userStore.Scope(r)
+		if err != nil {
+			http.Error(w, err.Error(), users.HTTPStatus(err))
+			return
+		}
+
 		if r.Method == http.MethodGet {
 			// Get current symbols
 			symbols := tickerServer.GetSymbols()
 
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
-				"symbols": symbols,
+				"symbols":       symbols,
+				"watchlist":     userStore.Settings(userID).Watchlist,
+				"subscriptions": tickerServer.Subscriptions(),
+				"max_symbols":   tickerServer.MaxSymbols(),
 			})
 			return
 		}
 
 		if r.Method == http.MethodPost {
-			// Update symbols
+			// Replace the watch list with symbols, or change it
+			// incrementally with add and remove. Symbols on another user's
+			// watchlist keep being polled.
 			var request struct {
 				Symbols []string `json:"symbols"`
+				Add     []string `json:"add"`
+				Remove  []string `json:"remove"`
 			}
 
 			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -696,16 +2362,80 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 				return
 			}
 
-			if err := tickerServer.UpdateSymbols(request.Symbols); err != nil {
-				http.Error(w, fmt.Sprintf("Failed to update symbols: %v", err), http.StatusInternalServerError)
+			others := userStore.WatchedByOthers(userID)
+
+			if len(request.Add) > 0 || len(request.Remove) > 0 {
+				var remove []string
+				for _, sym := range request.Remove {
+					if !others[strings.ToUpper(sym)] {
+						remove = append(remove, sym)
+					}
+				}
+				tickerServer.RemoveSymbols(remove)
+				evicted, err := tickerServer.AddSymbols(request.Add)
+				if err != nil {
+					status := http.StatusConflict
+					if errors.Is(err, ticker.ErrSymbolRefused) {
+						status = http.StatusForbidden
+					}
+					http.Error(w, fmt.Sprintf("Failed to add symbols: %v", err), status)
+					return
+				}
+				tradingAlgo.AddSymbols(request.Add)
+				if _, err := userStore.UpdateSettings(userID, func(s *users.Settings) {
+					dropped := make(map[string]bool)
+					for _, sym := range request.Remove {
+						dropped[strings.ToUpper(sym)] = true
+					}
+					watchlist := s.Watchlist[:0]
+					for _, sym := range s.Watchlist {
+						if !dropped[sym] {
+							watchlist = append(watchlist, sym)
+						}
+					}
+					s.Watchlist = append(watchlist, request.Add...)
+				}); err != nil {
+					logger().Warn("Failed to save watchlist", "user", userID, "error", err)
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"message": "Symbols updated successfully",
+					"symbols": tickerServer.GetSymbols(),
+					"evicted": evicted,
+				})
+				return
+			}
+
+			// Another user's symbol restricted since they added it is
+			// dropped rather than refusing this user's list
+			symbols := append([]string{}, request.Symbols...)
+			for sym := range others {
+				if tickerServer.CheckSymbol(sym) == nil {
+					symbols = append(symbols, sym)
+				}
+			}
+			if err := tickerServer.UpdateSymbols(symbols); err != nil {
+				status := http.StatusInternalServerError
+				if errors.Is(err, ticker.ErrTooManySymbols) {
+					status = http.StatusConflict
+				} else if errors.Is(err, ticker.ErrSymbolRefused) {
+					status = http.StatusForbidden
+				}
+				http.Error(w, fmt.Sprintf("Failed to update symbols: %v", err), status)
 				return
 			}
 
 			// Also update the algorithm's symbols
-			if err := tradingAlgo.Start(request.Symbols); err != nil {
+			if err := tradingAlgo.Start(tickerServer.GetSymbols()); err != nil {
 				http.Error(w, fmt.Sprintf("Failed to update algorithm symbols: %v", err), http.StatusInternalServerError)
 				return
 			}
+			if _, err := userStore.UpdateSettings(userID, func(s *users.Settings) {
+				s.Watchlist = request.Symbols
+			}); err != nil {
+				logger().Warn("Failed to save watchlist", "user", userID, "error", err)
+			}
 
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]interface{}{
@@ -716,33 +2446,79 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 		}
 
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}))
+	})
 
 	// Trading Signals Handler
-	http.HandleFunc("/api/signals", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/signals", func(w http.ResponseWriter, r *http.Request) {
 		// Get symbol from query string
 		symbol := r.URL.Query().Get("symbol")
 
 		var signals interface{}
 		if symbol != "" {
+			tickerServer.Touch(symbol)
 			// Get signal for specific symbol
 			signal := tradingAlgo.GetSignal(symbol)
 			if signal == nil {
 				http.Error(w, "No signal available for symbol", http.StatusNotFound)
 				return
 			}
-			signals = signal
+			signals = signal
+		} else {
+			// Get all signals
+			signals = tradingAlgo.GetAllSignals()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(signals)
+	})
+
+	// Signals, market data and portfolio read together, tagged with the
+	// generation the portfolio stream also carries
+	api.With(router.Methods(http.MethodGet)).HandleFunc("/snapshot", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tradingAlgo.Snapshot())
+	})
+
+	// The market data and portfolio a signal request was generated from, by
+	// the id the request carries, or a symbol's current context read through
+	// to the live state
+	api.With(router.Methods(http.MethodGet)).HandleFunc("/snapshot/context", func(w http.ResponseWriter, r *http.Request) {
+		var sc algorithm.SignalContext
+		if id := r.URL.Query().Get("id"); id != "" {
+			var ok bool
+			if sc, ok = tradingAlgo.SignalContextByID(id); !ok {
+				http.Error(w, "Signal context not found", http.StatusNotFound)
+				return
+			}
+		} else if symbol := strings.ToUpper(r.URL.Query().Get("symbol")); symbol != "" {
+			var err error
+			if sc, err = tradingAlgo.SignalContext(symbol); err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
 		} else {
-			// Get all signals
-			signals = tradingAlgo.GetAllSignals()
+			http.Error(w, "id or symbol is required", http.StatusBadRequest)
+			return
 		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sc)
+	})
 
+	// A signal request's context, read once by the Claude adapter with the
+	// token the request carries. It needs no API token, since the adapter
+	// has none; the context's own token is the credential.
+	api.With(router.Methods(http.MethodGet)).HandleFunc("/snapshot/context/redeem", func(w http.ResponseWriter, r *http.Request) {
+		sc, ok := tradingAlgo.RedeemSignalContext(r.URL.Query().Get("id"), r.URL.Query().Get("token"))
+		if !ok {
+			http.Error(w, "Signal context not found or already read", http.StatusNotFound)
+			return
+		}
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(signals)
-	}))
+		json.NewEncoder(w).Encode(sc)
+	})
 
 	// Ticker Recommendations Handler
-	http.HandleFunc("/api/recommendations", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/recommendations", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -768,10 +2544,10 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(recommendations)
-	}))
+	})
 
 	// Risk Parameters Handler
-	http.HandleFunc("/api/risk-parameters", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/risk-parameters", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodGet {
 			// Get current risk parameters
 			params := tradingAlgo.GetRiskParameters()
@@ -804,10 +2580,142 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 		}
 
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}))
+	})
+
+	// Portfolio volatility targeting - GET current estimate vs target
+	api.HandleFunc("/risk/volatility", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !mockMode {
+			tradingAlgo.RefreshVolatilityInputs()
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tradingAlgo.GetVolTargetState())
+	})
+
+	// Portfolio volatility targeting - POST to trim positions back to target
+	api.HandleFunc("/risk/volatility/trim", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var request struct {
+			DryRun bool `json:"dry_run,omitempty"`
+		}
+		if r.ContentLength > 0 {
+			if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+		dryRun := request.DryRun || r.URL.Query().Get("dry_run") == "true" || mockMode
+
+		previews, err := tradingAlgo.TrimToVolTarget(dryRun)
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": false,
+				"error":   err.Error(),
+				"dry_run": dryRun,
+				"orders":  previews,
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"dry_run": dryRun,
+			"orders":  previews,
+		})
+	})
+
+	// Position caps - GET open-position and per-sector utilization plus
+	// signals queued behind the caps
+	api.HandleFunc("/risk/metrics", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tradingAlgo.GetRiskMetrics())
+	})
+
+	// Liquidity - GET ?symbol= for average daily volume, spread, liquidity
+	// score and the position value cap they set
+	api.HandleFunc("/risk/liquidity", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		symbol := strings.ToUpper(r.URL.Query().Get("symbol"))
+		if symbol == "" {
+			http.Error(w, "symbol is required", http.StatusBadRequest)
+			return
+		}
+		liquidity := tradingAlgo.Liquidity(symbol)
+		if liquidity == nil {
+			http.Error(w, "Not enough cached daily bars for "+symbol, http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(liquidity)
+	})
+
+	// Trade simulation - POST a hypothetical signal to see the sizing, guard
+	// verdicts, costs, barriers and portfolio impact. Never places an order.
+	api.HandleFunc("/simulate/trade", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var request algorithm.SimulationRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		sim, err := tradingAlgo.SimulateTrade(request)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(sim)
+	})
+
+	// Position caps - POST {"SYMBOL": "sector"} to override sector mappings;
+	// an empty sector restores the default
+	api.HandleFunc("/risk/sectors", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var overrides map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&overrides); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		sectors := make(map[string]string, len(overrides))
+		for symbol, sector := range overrides {
+			tradingAlgo.SetSymbolSector(symbol, sector)
+			sectors[strings.ToUpper(symbol)] = tradingAlgo.SymbolSector(symbol)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": true,
+			"sectors": sectors,
+		})
+	})
 
 	// Baskets Handler - List and Create
-	http.HandleFunc("/api/baskets", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/baskets", func(w http.ResponseWriter, r *http.Request) {
+		basketManager, err := basketsFor(r)
+		if err != nil {
+			http.Error(w, err.Error(), users.HTTPStatus(err))
+			return
+		}
 		if r.Method == http.MethodGet {
 			// List all baskets
 			baskets := basketManager.ListBaskets()
@@ -830,7 +2738,13 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 			basket.UpdatedAt = now
 
 			if err := basketManager.SaveBasket(&basket); err != nil {
-				http.Error(w, fmt.Sprintf("Failed to save basket: %v", err), http.StatusInternalServerError)
+				status := http.StatusInternalServerError
+				if errors.Is(err, ticker.ErrVersionConflict) {
+					status = http.StatusConflict
+				} else if errors.Is(err, ticker.ErrVersionRequired) {
+					status = http.StatusPreconditionRequired
+				}
+				http.Error(w, fmt.Sprintf("Failed to save basket: %v", err), status)
 				return
 			}
 
@@ -840,58 +2754,151 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 		}
 
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}))
-
-	// Individual Basket Handler - Get, Update, Delete
-	http.HandleFunc("/api/baskets/", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		// Extract path to determine if it's for a specific basket or symbols
-		path := strings.TrimPrefix(r.URL.Path, "/api/baskets/")
-		parts := strings.Split(path, "/")
+	})
 
-		// Handle /api/baskets/{id}/symbols endpoint
-		if len(parts) == 2 && parts[1] == "symbols" {
-			basketID := parts[0]
+	// Basket sub-resources resolve the caller's basket manager first
+	baskets := api.Group("/baskets")
+	basketRoute := func(h func(http.ResponseWriter, *http.Request, *ticker.BasketManager)) func(http.ResponseWriter, *http.Request) {
+		return func(w http.ResponseWriter, r *http.Request) {
+			basketManager, err := basketsFor(r)
+			if err != nil {
+				http.Error(w, err.Error(), users.HTTPStatus(err))
+				return
+			}
+			h(w, r, basketManager)
+		}
+	}
 
-			if r.Method == http.MethodPost {
-				// Add symbol to basket
-				var request struct {
-					Symbol string `json:"symbol"`
-				}
-				if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-					http.Error(w, "Invalid request body", http.StatusBadRequest)
-					return
-				}
+	// POST /api/baskets/import - a JSON or CSV export body; ?overwrite=true
+	// replaces baskets whose IDs already exist
+	baskets.With(router.Methods(http.MethodPost)).HandleFunc("/import", basketRoute(func(w http.ResponseWriter, r *http.Request, basketManager *ticker.BasketManager) {
+		format := strings.ToLower(r.URL.Query().Get("format"))
+		if format == "" && strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+			format = ticker.FormatCSV
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 10<<20))
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		baskets, err := ticker.ParseBasketImport(body, format)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		result, err := basketManager.ImportBaskets(baskets, r.URL.Query().Get("overwrite") == "true")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}))
 
-				if err := basketManager.AddSymbolToBasket(basketID, request.Symbol); err != nil {
-					http.Error(w, fmt.Sprintf("Failed to add symbol to basket: %v", err), http.StatusInternalServerError)
-					return
-				}
+	// GET /api/baskets/{id}/export?format=json|csv
+	baskets.With(router.Methods(http.MethodGet)).HandleFunc("/{id}/export", basketRoute(func(w http.ResponseWriter, r *http.Request, basketManager *ticker.BasketManager) {
+		basket, err := basketManager.GetBasket(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		format := strings.ToLower(r.URL.Query().Get("format"))
+		if format == "" {
+			format = ticker.FormatJSON
+		}
+		data, err := ticker.ExportBaskets([]ticker.TickerBasket{*basket}, format)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		contentType := "application/json"
+		if format == ticker.FormatCSV {
+			contentType = "text/csv"
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", basket.ID+"."+format))
+		w.Write(data)
+	}))
 
-				// Get updated basket
-				basket, err := basketManager.GetBasket(basketID)
-				if err != nil {
-					http.Error(w, fmt.Sprintf("Failed to get updated basket: %v", err), http.StatusInternalServerError)
-					return
-				}
+	// GET /api/baskets/{id}/analytics - ?refresh=true pulls daily history
+	// for the members first
+	baskets.With(router.Methods(http.MethodGet)).HandleFunc("/{id}/analytics", basketRoute(func(w http.ResponseWriter, r *http.Request, basketManager *ticker.BasketManager) {
+		basket, err := basketManager.GetBasket(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("refresh") == "true" {
+			for symbol, err := range tradingAlgo.RefreshHistoricalCache(basket.Symbols, premarket.DefaultLookbackDays) {
+				logger().Warn("Basket analytics: failed to refresh symbol", "symbol", symbol, "error", err)
+			}
+		}
+		json.NewEncoder(w).Encode(tradingAlgo.BasketAnalytics(basket.Symbols))
+	}))
 
-				w.Header().Set("Content-Type", "application/json")
-				json.NewEncoder(w).Encode(basket)
-				return
+	// POST /api/baskets/{id}/whatif {"add": [...], "remove": [...]} - how
+	// the analytics would change without editing the basket; ?refresh=true
+	// pulls daily history first
+	baskets.With(router.Methods(http.MethodPost)).HandleFunc("/{id}/whatif", basketRoute(func(w http.ResponseWriter, r *http.Request, basketManager *ticker.BasketManager) {
+		basket, err := basketManager.GetBasket(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		var request struct {
+			Add    []string `json:"add"`
+			Remove []string `json:"remove"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if len(request.Add) == 0 && len(request.Remove) == 0 {
+			http.Error(w, "add or remove is required", http.StatusBadRequest)
+			return
+		}
+		if r.URL.Query().Get("refresh") == "true" {
+			edited, _, _, _ := algorithm.EditBasketSymbols(basket.Symbols, request.Add, nil)
+			for symbol, err := range tradingAlgo.RefreshHistoricalCache(edited, premarket.DefaultLookbackDays) {
+				logger().Warn("Basket what-if: failed to refresh symbol", "symbol", symbol, "error", err)
 			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tradingAlgo.BasketWhatIf(basket.Symbols, request.Add, request.Remove))
+	}))
 
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	// POST /api/baskets/{id}/symbols {"symbol": "AAPL"} - add a symbol
+	baskets.With(router.Methods(http.MethodPost)).HandleFunc("/{id}/symbols", basketRoute(func(w http.ResponseWriter, r *http.Request, basketManager *ticker.BasketManager) {
+		basketID := r.PathValue("id")
+		var request struct {
+			Symbol string `json:"symbol"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
 
-		// Handle /api/baskets/{id} endpoint
-		basketID := parts[0]
-		if basketID == "" {
-			http.Error(w, "Invalid basket ID", http.StatusBadRequest)
+		if err := basketManager.AddSymbolToBasket(basketID, request.Symbol); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to add symbol to basket: %v", err), http.StatusInternalServerError)
 			return
 		}
 
-		if r.Method == http.MethodGet {
-			// Get basket details
+		// Get updated basket
+		basket, err := basketManager.GetBasket(basketID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get updated basket: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(basket)
+	}))
+
+	// GET/PUT/DELETE /api/baskets/{id}
+	baskets.With(router.Methods(http.MethodGet, http.MethodPut, http.MethodDelete)).HandleFunc("/{id}", basketRoute(func(w http.ResponseWriter, r *http.Request, basketManager *ticker.BasketManager) {
+		basketID := r.PathValue("id")
+		switch r.Method {
+		case http.MethodGet:
 			basket, err := basketManager.GetBasket(basketID)
 			if err != nil {
 				http.Error(w, fmt.Sprintf("Failed to get basket: %v", err), http.StatusNotFound)
@@ -900,93 +2907,84 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(basket)
-			return
-		}
 
-		if r.Method == http.MethodPut {
-			// Update basket
+		case http.MethodPut:
+			// The update must carry the version it read: 428 without one,
+			// 409 if someone else saved the basket since
 			var basket ticker.TickerBasket
 			if err := json.NewDecoder(r.Body).Decode(&basket); err != nil {
 				http.Error(w, "Invalid request body", http.StatusBadRequest)
 				return
 			}
-
-			// Set updated timestamp
-			basket.UpdatedAt = time.Now().Format(time.RFC3339)
+			basket.ID = basketID
 
 			if err := basketManager.SaveBasket(&basket); err != nil {
-				http.Error(w, fmt.Sprintf("Failed to update basket: %v", err), http.StatusInternalServerError)
+				status := http.StatusInternalServerError
+				if errors.Is(err, ticker.ErrVersionConflict) {
+					status = http.StatusConflict
+				} else if errors.Is(err, ticker.ErrVersionRequired) {
+					status = http.StatusPreconditionRequired
+				}
+				http.Error(w, fmt.Sprintf("Failed to update basket: %v", err), status)
 				return
 			}
 
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(basket)
-			return
-		}
 
-		if r.Method == http.MethodDelete {
-			// Delete basket
+		case http.MethodDelete:
 			if err := basketManager.DeleteBasket(basketID); err != nil {
 				http.Error(w, fmt.Sprintf("Failed to delete basket: %v", err), http.StatusInternalServerError)
 				return
 			}
 
-			w.WriteHeader(http.StatusOK)
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(map[string]string{"message": "Basket deleted successfully"})
-			return
 		}
-
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}))
 
-	// Trade Basket Handler - Trade all symbols in a basket
-	http.HandleFunc("/api/baskets/trade/", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		basketID := strings.TrimPrefix(r.URL.Path, "/api/baskets/trade/")
-		if basketID == "" {
-			http.Error(w, "Invalid basket ID", http.StatusBadRequest)
+	// POST /api/baskets/{id}/trade - trade all symbols in a basket; the
+	// original /api/baskets/trade/{id} path is kept as an alias
+	baskets.With(router.Methods(http.MethodPost)).HandleFunc("/{id}/trade", basketRoute(func(w http.ResponseWriter, r *http.Request, basketManager *ticker.BasketManager) {
+		basket, err := basketManager.GetBasket(r.PathValue("id"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to get basket: %v", err), http.StatusNotFound)
 			return
 		}
 
-		if r.Method == http.MethodPost {
-			// Get the basket
-			basket, err := basketManager.GetBasket(basketID)
-			if err != nil {
-				http.Error(w, fmt.Sprintf("Failed to get basket: %v", err), http.StatusNotFound)
-				return
-			}
-
-			// Check if basket has symbols
-			if len(basket.Symbols) == 0 {
-				http.Error(w, "Basket has no symbols", http.StatusBadRequest)
-				return
-			}
-
-			// Update the ticker server with the basket symbols
-			if err := tickerServer.UpdateSymbols(basket.Symbols); err != nil {
-				http.Error(w, fmt.Sprintf("Failed to update symbols: %v", err), http.StatusInternalServerError)
-				return
-			}
+		// Check if basket has symbols
+		if len(basket.Symbols) == 0 {
+			http.Error(w, "Basket has no symbols", http.StatusBadRequest)
+			return
+		}
 
-			// Also update the trading algorithm
-			if err := tradingAlgo.Start(basket.Symbols); err != nil {
-				http.Error(w, fmt.Sprintf("Failed to update algorithm symbols: %v", err), http.StatusInternalServerError)
-				return
+		// Subscribe to the basket alongside the current symbols;
+		// idle ones are evicted if that goes over the cap
+		if _, err := tickerServer.AddSymbols(basket.Symbols); err != nil {
+			status := http.StatusConflict
+			if errors.Is(err, ticker.ErrSymbolRefused) {
+				status = http.StatusForbidden
 			}
+			http.Error(w, fmt.Sprintf("Failed to update symbols: %v", err), status)
+			return
+		}
 
-			w.Header().Set("Content-Type", "application/json")
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"message": fmt.Sprintf("Started trading %d symbols from basket '%s'", len(basket.Symbols), basket.Name),
-				"symbols": basket.Symbols,
-			})
+		// Also update the trading algorithm
+		if err := tradingAlgo.Start(tickerServer.GetSymbols()); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to update algorithm symbols: %v", err), http.StatusInternalServerError)
 			return
 		}
 
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": fmt.Sprintf("Started trading %d symbols from basket '%s'", len(basket.Symbols), basket.Name),
+			"symbols": basket.Symbols,
+		})
 	}))
+	baskets.Alias("/trade/{id}", "/{id}/trade")
 
 	// Historical Data and Analysis Handler
-	http.HandleFunc("/api/historical", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/historical", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method == http.MethodGet {
 			// Get query parameters
 			symbol := r.URL.Query().Get("symbol")
@@ -1058,8 +3056,75 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 			return
 		}
 
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}))
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	})
+
+	// Progress of recent and in-flight chunked historical fetches
+	api.HandleFunc("/historical/progress", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tradingAlgo.HistoricalFetches())
+	})
+
+	// Candlestick Patterns Handler
+	api.HandleFunc("/patterns", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		symbol := strings.ToUpper(r.URL.Query().Get("symbol"))
+		if symbol == "" {
+			http.Error(w, "Symbol is required", http.StatusBadRequest)
+			return
+		}
+
+		report, err := tradingAlgo.GetPatterns(symbol, r.URL.Query().Get("timeframe"))
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to detect patterns: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	})
+
+	// GARCH(1,1) volatility forecast for one symbol, fitted to its cached
+	// daily returns
+	api.HandleFunc("/indicators/volatility-forecast", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		symbol := strings.ToUpper(r.URL.Query().Get("symbol"))
+		if symbol == "" {
+			http.Error(w, "Symbol is required", http.StatusBadRequest)
+			return
+		}
+		horizon := algorithm.DefaultForecastHorizon
+		if v := r.URL.Query().Get("horizon"); v != "" {
+			h, err := strconv.Atoi(v)
+			if err != nil {
+				http.Error(w, "Invalid horizon", http.StatusBadRequest)
+				return
+			}
+			horizon = h
+		}
+
+		forecast, err := tradingAlgo.ForecastVolatility(symbol, horizon)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to forecast volatility: %v", err), http.StatusUnprocessableEntity)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(forecast)
+	})
 
 	// Claude WebSocket endpoint for streaming responses
 	// Note: This route is already registered by claudeHandler.RegisterRoutes in the main function
@@ -1068,7 +3133,7 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 	// claudeHandler handles this endpoint correctly
 
 	// Signal generation with manual approval
-	http.HandleFunc("/api/signals/generate", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/signals/generate", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -1080,6 +3145,9 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 			return
 		}
 
+		// Asking for a signal keeps the symbol from being evicted as idle
+		tickerServer.Touch(symbol)
+
 		// Generate the signal but don't execute it yet
 		signal, err := generateSignalWithoutExecution(tradingAlgo, symbol)
 		if err != nil {
@@ -1089,23 +3157,101 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(signal)
-	}))
+	})
+
+	// POST /api/signals/generate-batch {"basket_id": "..."} or {"symbols":
+	// [...]} - generate signals for many symbols at once, a few at a time,
+	// returning whatever finished within timeout_seconds with a status per
+	// symbol
+	api.With(router.Methods(http.MethodPost)).HandleFunc("/signals/generate-batch", func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			BasketID       string   `json:"basket_id"`
+			Symbols        []string `json:"symbols"`
+			Concurrency    int      `json:"concurrency"`
+			TimeoutSeconds float64  `json:"timeout_seconds"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		symbols := request.Symbols
+		if request.BasketID != "" {
+			basketManager, err := basketsFor(r)
+			if err != nil {
+				http.Error(w, err.Error(), users.HTTPStatus(err))
+				return
+			}
+			basket, err := basketManager.GetBasket(request.BasketID)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			symbols = append(symbols, basket.Symbols...)
+		}
+		symbols = algorithm.BatchSymbols(symbols)
+		switch {
+		case len(symbols) == 0:
+			http.Error(w, "basket_id or symbols is required", http.StatusBadRequest)
+			return
+		case len(symbols) > algorithm.MaxBatchSymbols:
+			http.Error(w, fmt.Sprintf("at most %d symbols per batch", algorithm.MaxBatchSymbols), http.StatusBadRequest)
+			return
+		case request.Concurrency < 0 || request.Concurrency > algorithm.MaxBatchConcurrency:
+			http.Error(w, fmt.Sprintf("concurrency must be between 1 and %d", algorithm.MaxBatchConcurrency), http.StatusBadRequest)
+			return
+		case request.TimeoutSeconds < 0 || request.TimeoutSeconds > 120:
+			http.Error(w, "timeout_seconds must be between 1 and 120", http.StatusBadRequest)
+			return
+		}
+		timeout := 30 * time.Second
+		if request.TimeoutSeconds > 0 {
+			timeout = time.Duration(request.TimeoutSeconds * float64(time.Second))
+		}
+
+		// Asking for signals keeps the symbols from being evicted as idle
+		for _, symbol := range symbols {
+			tickerServer.Touch(symbol)
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tradingAlgo.GenerateSignals(ctx, symbols, request.Concurrency))
+	})
 
 	// Execute trade endpoint - receives signals from the frontend AI integration
-	http.HandleFunc("/api/executeTrade", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/executeTrade", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		log.Printf("Received request to execute trade from frontend")
+		logger().Info("Received request to execute trade from frontend")
 		var request struct {
 			Symbol     string  `json:"symbol"`
 			Signal     string  `json:"signal"`
 			OrderType  string  `json:"order_type"`
 			LimitPrice float64 `json:"limit_price,omitempty"`
+			StopPrice  float64 `json:"stop_price,omitempty"` // stop and stop_limit orders
 			Reasoning  string  `json:"reasoning,omitempty"`
 			Confidence float64 `json:"confidence,omitempty"`
+			DryRun     bool    `json:"dry_run,omitempty"`
+			Execution  string  `json:"execution,omitempty"`
+			// Optional explicit size, at most one of them; without one the
+			// trade is sized by the risk parameters
+			Qty             float64 `json:"qty,omitempty"`
+			Notional        float64 `json:"notional,omitempty"`
+			PercentOfEquity float64 `json:"percent_of_equity,omitempty"`
+			// Strategy tag prefixed to the client order ID for attribution;
+			// strategy_id is accepted as an alias. Defaults to manual.
+			Tag        string `json:"tag,omitempty"`
+			StrategyID string `json:"strategy_id,omitempty"`
+			// A generated signal executed from the UI carries when it was
+			// generated, at what price and until when it is valid, so the
+			// freshness guard judges it from generation. Without them the
+			// trade is fresh as of now.
+			GeneratedAt    time.Time  `json:"generated_at,omitempty"`
+			GeneratedPrice float64    `json:"generated_price,omitempty"`
+			ValidUntil     *time.Time `json:"valid_until,omitempty"`
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
@@ -1126,6 +3272,23 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 			limitPricePtr = &limitPrice
 		}
 
+		execution, err := algorithm.NormalizeExecution(request.Execution)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if request.Tag == "" {
+			request.Tag = request.StrategyID
+		}
+		tag, err := algorithm.NormalizeTag(request.Tag)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if tag == "" {
+			tag = algorithm.TagManual
+		}
+
 		// Convert the request to a trade signal
 		signal := &algorithm.TradeSignal{
 			Symbol:     request.Symbol,
@@ -1134,35 +3297,134 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 			LimitPrice: limitPricePtr,
 			Timestamp:  time.Now(),
 			Reasoning:  request.Reasoning,
+			Source:     "manual",
+			Execution:  execution,
+			Tag:        tag,
+		}
+		if request.StopPrice != 0 {
+			stopPrice := request.StopPrice
+			signal.StopPrice = &stopPrice
+		}
+		if !request.GeneratedAt.IsZero() {
+			signal.Timestamp = request.GeneratedAt
+		}
+		signal.GeneratedPrice = request.GeneratedPrice
+		signal.ValidUntil = request.ValidUntil
+		if err := signal.ValidateOrder(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if request.Qty != 0 || request.Notional != 0 || request.PercentOfEquity != 0 {
+			signal.Size = &algorithm.TradeSize{Qty: request.Qty, Notional: request.Notional, PercentOfEquity: request.PercentOfEquity}
+			if err := signal.Size.Validate(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 		}
 
 		// Add confidence if provided
 		if request.Confidence > 0 {
 			confidenceVal := request.Confidence
 			signal.Confidence = &confidenceVal
-			log.Printf("Signal confidence: %.2f", *signal.Confidence)
 		}
+		signal.RiskReward = tradingAlgo.RiskReward(signal)
 
-		// Log the signal
-		log.Printf("Received trade signal: %+v", signal)
+		logger().Info("Received trade signal", "symbol", signal.Symbol, "signal", signal.Signal, "order_type", signal.OrderType,
+			"limit_price", signal.LimitPrice, "stop_price", signal.StopPrice, "confidence", signal.Confidence, "execution", signal.Execution, "size", signal.Size, "tag", signal.Tag)
 
-		// Execute the trade based on the signal
-		var result string
-		var err error
-
-		// Execute different actions based on the signal type
-		switch signal.Signal {
-		case "buy":
-			result, err = executeBuyOrder(client, signal, apiKey, apiSecret)
-		case "sell":
-			result, err = executeSellOrder(client, signal, apiKey, apiSecret)
-		case "hold":
-			result = "No trade executed for hold signal"
-			err = nil
-		default:
-			err = fmt.Errorf("invalid signal type: %s", signal.Signal)
+		// Trade guards (gap pauses, etc.) apply to dry runs too, so a
+		// preview never promises an order that would be refused.
+		if signal.Signal != "hold" {
+			if err := tradingAlgo.CheckTradeGuards(signal); err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusConflict)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"error":   err.Error(),
+					"success": false,
+					"blocked": true,
+				})
+				return
+			}
+		}
+
+		// In a replay the algorithm sizes and places the order with the
+		// simulated broker; nothing reaches Alpaca
+		if replayMode {
+			preview, err := tradingAlgo.ExecuteTrade(signal, request.DryRun || r.URL.Query().Get("dry_run") == "true")
+			w.Header().Set("Content-Type", "application/json")
+			if err != nil {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"error":   fmt.Sprintf("Error executing trade: %v", err),
+					"success": false,
+					"replay":  true,
+				})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"replay":  true,
+				"symbol":  signal.Symbol,
+				"signal":  signal.Signal,
+				"preview": preview, // null for hold
+			})
+			return
+		}
+
+		// Dry run: do all the sizing and pricing, hand back the exact payload
+		// that would go to the broker, and stop there.
+		if request.DryRun || r.URL.Query().Get("dry_run") == "true" {
+			preview, err := previewSignal(client, tradingAlgo, signal, mdClient)
+
+			w.Header().Set("Content-Type", "application/json")
+			if err != nil {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				json.NewEncoder(w).Encode(map[string]interface{}{
+					"error":   fmt.Sprintf("Error preparing trade: %v", err),
+					"success": false,
+					"dry_run": true,
+				})
+				return
+			}
+			if preview != nil {
+				preview.DryRun = true
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success": true,
+				"dry_run": true,
+				"symbol":  signal.Symbol,
+				"signal":  signal.Signal,
+				"preview": preview, // null for hold — nothing would be sent
+			})
+			return
+		}
+
+		// Orders the confirmation policy marks wait for a second step
+		// at /api/confirmations instead of being placed
+		item, held, err := holdForConfirmation(client, tradingAlgo, confirmQueue, signal, mdClient, users.FromContext(r.Context()).ID)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"error":   fmt.Sprintf("Error executing trade: %v", err),
+				"success": false,
+			})
+			return
+		}
+		if held {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusAccepted)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"success":               false,
+				"confirmation_required": true,
+				"message":               fmt.Sprintf("Order held for confirmation: %s", strings.Join(item.Reasons, "; ")),
+				"confirmation":          item,
+			})
+			return
 		}
 
+		// Execute the trade based on the signal
+		order, result, err := executeSignal(client, tradingAlgo, signal, mdClient)
 		if err != nil {
 			// Return error as JSON instead of plain text
 			w.Header().Set("Content-Type", "application/json")
@@ -1173,23 +3435,32 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 			})
 			return
 		}
+		if signal.Signal != "hold" {
+			recordSignal(signalHistory, signal, tradingAlgo.GetMarketData(signal.Symbol))
+		}
 
+		clientOrderID := ""
+		if order != nil {
+			clientOrderID = order.ClientOrderID
+		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"success": true,
 			"message": result,
 
-			"symbol":     signal.Symbol,
-			"signal":     signal.Signal,
-			"confidence": signal.Confidence,
-			"reasoning":  signal.Reasoning,
-			"order_id":   fmt.Sprintf("ord_%s", time.Now().Format("20060102150405")),
-			"timestamp":  time.Now().Format(time.RFC3339),
+			"symbol":          signal.Symbol,
+			"signal":          signal.Signal,
+			"confidence":      signal.Confidence,
+			"reasoning":       signal.Reasoning,
+			"tag":             signal.Tag,
+			"client_order_id": clientOrderID, // empty when sliced or held
+			"order_id":        fmt.Sprintf("ord_%s", time.Now().Format("20060102150405")),
+			"timestamp":       time.Now().Format(time.RFC3339),
 		})
-	}))
+	})
 
 	// Reject signal (don't execute)
-	http.HandleFunc("/api/signals/reject", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/signals/reject", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -1207,11 +3478,11 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"message": fmt.Sprintf("Signal for %s rejected", request.Symbol),
 		})
-	}))
+	})
 
 	// Economic Cartography — current reading, optional chart series, and
 	// the regime-derived risk multiplier currently being applied to sizing.
-	http.HandleFunc("/api/cartography", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/cartography", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -1269,12 +3540,12 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
-	}))
+	})
 
 	// Manual FRED refresh — triggers an out-of-band fetch and re-applies
 	// the multiplier. Useful after market-moving releases (NFP, CPI, etc.)
 	// when waiting for the next 6h tick is too slow.
-	http.HandleFunc("/api/cartography/refresh", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/cartography/refresh", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -1302,198 +3573,60 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 			"feed":    feed,
 			"applied": map[string]interface{}{"regime": appliedRegime, "multiplier": appliedMult},
 		})
-	}))
+	})
 
 	// Lopez de Prado Algorithms API
-	http.HandleFunc("/api/algorithms/metadata", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/algorithms/metadata", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
-		// Define metadata for all Lopez de Prado algorithms
-		metadata := []map[string]interface{}{
-			{
-				"type":        "fractional_diff",
-				"name":        "Fractional Differentiation",
-				"description": "Makes time series stationary while preserving memory",
-				"parameters": map[string]string{
-					"d_value":     "Differencing parameter (0-1), typically 0.3-0.5",
-					"threshold":   "Threshold for FFD method weight truncation",
-					"window_size": "Window size for fixed-width window method",
-				},
-				"defaults": map[string]interface{}{
-					"d_value":     0.4,
-					"threshold":   1e-4,
-					"window_size": 20,
-				},
-			},
-			{
-				"type":        "triple_barrier",
-				"name":        "Triple Barrier Method",
-				"description": "Implements realistic profit-taking, stop-loss, and time exit conditions",
-				"parameters": map[string]string{
-					"profit_taking":       "Multiple of volatility for profit target",
-					"stop_loss":           "Multiple of volatility for stop loss",
-					"time_horizon":        "Days for time barrier",
-					"volatility_lookback": "Lookback window for volatility estimation",
-				},
-				"defaults": map[string]interface{}{
-					"profit_taking":       2.0,
-					"stop_loss":           1.0,
-					"time_horizon":        5,
-					"volatility_lookback": 20,
-				},
-			},
-			{
-				"type":        "meta_labeling",
-				"name":        "Meta-Labeling",
-				"description": "Secondary model to filter primary trading signals",
-				"parameters": map[string]string{
-					"confidence_threshold":    "Minimum confidence to execute trade",
-					"use_price_features":      "Whether to use price-based features",
-					"use_volume_features":     "Whether to use volume-based features",
-					"use_volatility_features": "Whether to use volatility-based features",
-					"use_technical_features":  "Whether to use technical indicators",
-				},
-				"defaults": map[string]interface{}{
-					"confidence_threshold":    0.6,
-					"use_price_features":      1.0,
-					"use_volume_features":     1.0,
-					"use_volatility_features": 1.0,
-					"use_technical_features":  1.0,
-				},
-			},
-			{
-				"type":        "purged_cv",
-				"name":        "Purged Cross-Validation",
-				"description": "Prevents information leakage in model validation",
-				"parameters": map[string]string{
-					"num_folds":   "Number of folds for cross-validation",
-					"embargo_pct": "Percentage of observations to embargo",
-					"test_size":   "Size of test set as percentage of total",
-				},
-				"defaults": map[string]interface{}{
-					"num_folds":   5,
-					"embargo_pct": 0.01,
-					"test_size":   0.3,
-				},
-			},
-			{
-				"type":        "position_sizing",
-				"name":        "Advanced Position Sizing",
-				"description": "Dynamic allocation based on confidence and volatility",
-				"parameters": map[string]string{
-					"max_size":           "Maximum position size as percentage of capital",
-					"risk_fraction":      "Kelly fraction (typically 0.2-0.5)",
-					"use_vol_adjustment": "Whether to adjust for volatility",
-					"vol_lookback":       "Volatility lookback period",
-					"max_drawdown":       "Maximum acceptable drawdown",
-				},
-				"defaults": map[string]interface{}{
-					"max_size":           0.2,
-					"risk_fraction":      0.3,
-					"use_vol_adjustment": 1.0,
-					"vol_lookback":       20,
-					"max_drawdown":       0.1,
-				},
-			},
-			{
-				"type":        "sequential_bootstrap",
-				"name":        "Sequential Bootstrap",
-				"description": "Creates unbiased training sets by addressing overlapping returns",
-				"parameters": map[string]string{
-					"sample_pct":        "Percentage of data to include in each bootstrap",
-					"num_bootstraps":    "Number of bootstrap samples to create",
-					"sequential_blocks": "Whether to use sequential blocks (true) or random sampling (false)",
-				},
-				"defaults": map[string]interface{}{
-					"sample_pct":        0.5,
-					"num_bootstraps":    100,
-					"sequential_blocks": true,
-				},
-			},
-		}
+		// Describe every registered algorithm, so new ones appear without
+		// changes here
+		metadata := algo.AllMetadata()
 
 		// Return metadata
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(metadata)
-	}))
+	})
 
 	// Configure an algorithm
-	http.HandleFunc("/api/algorithms/configure", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/algorithms/configure", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
 
 		// Parse request body
-		var req struct {
-			Type       string                 `json:"type"`
-			Parameters map[string]interface{} `json:"parameters"`
-		}
+		var req algorithm.InstanceSpec
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
-			log.Printf("Error decoding algorithm configuration request: %v", err)
-			return
-		}
-
-		// Create algorithm config
-		params := make(map[string]float64)
-		for k, v := range req.Parameters {
-			// Convert to float64
-			switch val := v.(type) {
-			case float64:
-				params[k] = val
-			case int:
-				params[k] = float64(val)
-			case string:
-				// Try to parse as float
-				floatVal, err := strconv.ParseFloat(val, 64)
-				if err != nil {
-					http.Error(w, fmt.Sprintf("Invalid parameter value for %s: %v", k, v), http.StatusBadRequest)
-					return
-				}
-				params[k] = floatVal
-			default:
-				http.Error(w, fmt.Sprintf("Invalid parameter type for %s: %T", k, v), http.StatusBadRequest)
-				return
-			}
-		}
-
-		// Create algorithm
-		algType := algo.AlgorithmType(req.Type)
-		algorithm, err := algo.Create(algType)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to create algorithm: %v", err), http.StatusBadRequest)
-			log.Printf("Error creating algorithm: %v", err)
+			logger().Warn("Failed to decode algorithm configuration request", "error", err)
 			return
 		}
 
-		// Configure algorithm
-		config := algo.AlgorithmConfig{
-			AdditionalParams: params,
-		}
-		if err := algorithm.Configure(config); err != nil {
+		// Register the algorithm under its type, or the ID given, for
+		// future use
+		instance, err := algoInstances.Put(req)
+		if err != nil {
 			http.Error(w, fmt.Sprintf("Failed to configure algorithm: %v", err), http.StatusBadRequest)
-			log.Printf("Error configuring algorithm: %v", err)
+			logger().Error("Failed to configure algorithm", "error", err)
 			return
 		}
 
-		// Register the algorithm for future use
-		algoRegistry[req.Type] = algorithm
-
 		// Return success
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":  "success",
-			"message": fmt.Sprintf("Algorithm %s configured successfully", req.Type),
-			"type":    req.Type,
+			"status":   "success",
+			"message":  fmt.Sprintf("Algorithm %s configured successfully", instance.ID),
+			"type":     instance.Type,
+			"instance": instance,
 		})
-	}))
+	})
 
 	// Execute an algorithm
-	http.HandleFunc("/api/algorithms/execute", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/algorithms/execute", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -1501,42 +3634,103 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 
 		// Parse request body
 		var req struct {
-			Type   string `json:"type"`
-			Symbol string `json:"symbol"`
+			Instance string `json:"instance"`
+			Type     string `json:"type"`
+			Symbol   string `json:"symbol"`
+			Refresh  bool   `json:"refresh"`
+			// Symbols or Basket run the instance over a basket as a unit
+			Symbols []string `json:"symbols"`
+			Basket  string   `json:"basket"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
-			log.Printf("Error decoding algorithm execution request: %v", err)
+			logger().Warn("Failed to decode algorithm execution request", "error", err)
 			return
 		}
+		refresh := req.Refresh || r.URL.Query().Get("refresh") == "true"
 
-		// Get the algorithm
-		algorithm, exists := algoRegistry[req.Type]
+		// Get the algorithm instance; configuring by type registers one
+		// under the type name
+		id := req.Instance
+		if id == "" {
+			id = req.Type
+		}
+		registered, exists := algoInstances.Get(id)
 		if !exists {
-			http.Error(w, fmt.Sprintf("Algorithm of type %s not found. Configure it first.", req.Type), http.StatusBadRequest)
+			http.Error(w, fmt.Sprintf("Algorithm instance %s not found. Configure it first.", id), http.StatusBadRequest)
 			return
 		}
 
-		// Get current market data
-		marketData := tradingAlgo.GetMarketData(req.Symbol)
-		if marketData.Price == 0 {
-			http.Error(w, fmt.Sprintf("No market data available for symbol %s", req.Symbol), http.StatusBadRequest)
+		// A basket is decided together, each member sized against the rest
+		if len(req.Symbols) > 0 || req.Basket != "" {
+			symbols := req.Symbols
+			if req.Basket != "" {
+				basketManager, err := basketsFor(r)
+				if err != nil {
+					http.Error(w, err.Error(), users.HTTPStatus(err))
+					return
+				}
+				basket, err := basketManager.GetBasket(req.Basket)
+				if err != nil {
+					http.Error(w, fmt.Sprintf("Failed to get basket: %v", err), http.StatusNotFound)
+					return
+				}
+				symbols = append(append([]string(nil), symbols...), basket.Symbols...)
+			}
+			run, err := algoInstances.RunPortfolio(id, symbols)
+			if err != nil {
+				status := http.StatusInternalServerError
+				if errors.Is(err, algorithm.ErrInvalidInstance) {
+					status = http.StatusBadRequest
+				}
+				http.Error(w, fmt.Sprintf("Failed to execute algorithm: %v", err), status)
+				logger().Error("Failed to execute algorithm on basket", "error", err)
+				return
+			}
+			now := time.Now()
+			for symbol, result := range run.Result.Results {
+				confidence := result.Confidence
+				recordSignal(signalHistory, &algorithm.TradeSignal{
+					Symbol:     symbol,
+					Signal:     result.Signal,
+					OrderType:  result.OrderType,
+					LimitPrice: result.LimitPrice,
+					Timestamp:  now,
+					Reasoning:  result.Explanation,
+					Confidence: &confidence,
+					Source:     "algorithm:" + run.Instance.ID,
+				}, tradingAlgo.GetMarketData(symbol))
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"status":         "success",
+				"instance":       run.Instance.ID,
+				"type":           run.Instance.Type,
+				"symbols":        run.Symbols,
+				"timeframe":      run.Instance.Config.Resolution(),
+				"weights":        run.Result.Weights,
+				"gross_exposure": run.Result.GrossExposure,
+				"net_exposure":   run.Result.NetExposure,
+				"results":        run.Result.Results,
+				"skipped":        run.Result.Skipped,
+				"explanation":    run.Result.Explanation,
+			})
 			return
 		}
 
-		// Get historical data
-		request := types.HistoricalDataRequest{
-			Symbol:    req.Symbol,                    // Symbol to get data for
-			StartDate: time.Now().AddDate(0, 0, -30), // Last 30 days
-			EndDate:   time.Now(),                    // Current time
-			TimeFrame: "1D",                          // Daily timeframe
+		symbol := req.Symbol
+		if symbol == "" {
+			symbol = registered.Symbol
+		}
+		if symbol == "" {
+			http.Error(w, "Symbol is required", http.StatusBadRequest)
+			return
 		}
 
-		// Use the correctly imported algorithm package and function
-		historicalData, err := tradingAlgo.GetHistoricalDataV2(request)
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to get historical data: %v", err), http.StatusInternalServerError)
-			log.Printf("Error getting historical data: %v", err)
+		// Get current market data
+		marketData := tradingAlgo.GetMarketData(symbol)
+		if marketData.Price == 0 {
+			http.Error(w, fmt.Sprintf("No market data available for symbol %s", symbol), http.StatusBadRequest)
 			return
 		}
 
@@ -1550,50 +3744,56 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 			Change24h: marketData.Change24h,
 		}
 
-		// Execute the algorithm
-		var result *algo.AlgorithmResult
-		var algErr error
-
-		// Type assertion to the correct algorithm type
-		switch a := algorithm.(type) {
-		case *algo.FractionalDiffAlgorithm:
-			result, algErr = a.Process(req.Symbol, typesMarketData, convertHistoricalDataToMarketData(historicalData))
-		case *algo.TripleBarrierAlgorithm:
-			result, algErr = a.Process(req.Symbol, typesMarketData, convertHistoricalDataToMarketData(historicalData))
-		case *algo.MetaLabelingAlgorithm:
-			result, algErr = a.Process(req.Symbol, typesMarketData, convertHistoricalDataToMarketData(historicalData))
-		case *algo.PurgedCVAlgorithm:
-			result, algErr = a.Process(req.Symbol, typesMarketData, convertHistoricalDataToMarketData(historicalData))
-		case *algo.PositionSizingAlgorithm:
-			result, algErr = a.Process(req.Symbol, typesMarketData, convertHistoricalDataToMarketData(historicalData))
-		case *algo.SequentialBootstrapAlgorithm:
-			result, algErr = a.Process(req.Symbol, typesMarketData, convertHistoricalDataToMarketData(historicalData))
-		default:
-			http.Error(w, fmt.Sprintf("Unsupported algorithm type: %T", a), http.StatusBadRequest)
+		// Run the instance on the symbol's own state over its lookback at
+		// the configured resolution, or serve the last result while the
+		// history ends on the same bar
+		run, err := algoInstances.Run(id, symbol, typesMarketData, refresh)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, algorithm.ErrInvalidInstance) {
+				status = http.StatusBadRequest
+			}
+			http.Error(w, fmt.Sprintf("Failed to execute algorithm: %v", err), status)
+			logger().Error("Failed to execute algorithm", "error", err)
 			return
 		}
-
-		if algErr != nil {
-			http.Error(w, fmt.Sprintf("Failed to execute algorithm: %v", algErr), http.StatusInternalServerError)
-			log.Printf("Error executing algorithm: %v", algErr)
+		if run.Cached {
+			writeAlgorithmResult(w, run)
 			return
 		}
 
-		// Return the result
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":      "success",
-			"type":        req.Type,
-			"symbol":      req.Symbol,
-			"signal":      result.Signal,
-			"order_type":  result.OrderType,
-			"confidence":  result.Confidence,
-			"explanation": result.Explanation,
-		})
-	}))
+		result := run.Result.Result
+		confidence := result.Confidence
+		recordSignal(signalHistory, &algorithm.TradeSignal{
+			Symbol:     run.Symbol,
+			Signal:     result.Signal,
+			OrderType:  result.OrderType,
+			LimitPrice: result.LimitPrice,
+			Timestamp:  time.Now(),
+			Reasoning:  result.Explanation,
+			Confidence: &confidence,
+			Source:     "algorithm:" + run.Instance.ID,
+		}, marketData)
+
+		writeAlgorithmResult(w, run)
+	})
 
 	// Toggle manual control setting
-	http.HandleFunc("/api/settings/manual-control", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+	api.HandleFunc("/settings/manual-control", func(w http.ResponseWriter, r *http.Request) {
+		userID, err := userStore.Scope(r)
+		if err != nil {
+			http.Error(w, err.Error(), users.HTTPStatus(err))
+			return
+		}
+
+		if r.Method == http.MethodGet {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"enabled": userStore.Settings(userID).ManualControl,
+			})
+			return
+		}
+
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -1607,216 +3807,290 @@ func setupHTTPHandlers(client *alpaca.Client, tradingAlgo *algorithm.TradingAlgo
 			return
 		}
 
-		// Update application settings - in a real app, this would update a settings store
-		log.Printf("Setting manual trading control to: %v", request.Enabled)
+		// Manual control is kept per user
+		if _, err := userStore.UpdateSettings(userID, func(s *users.Settings) {
+			s.ManualControl = request.Enabled
+		}); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to save setting: %v", err), http.StatusInternalServerError)
+			return
+		}
+		logger().Info("Setting manual trading control", "user", userID, "enabled", request.Enabled)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"message": fmt.Sprintf("Manual control set to %v", request.Enabled),
 			"enabled": request.Enabled,
 		})
-	}))
+	})
 
 	// Register notification routes
-	notificationHandler.RegisterRoutes(http.DefaultServeMux)
+	notificationHandler.RegisterRoutes(rt.Mux())
+	signalstore.NewHandler(signalHistory).RegisterRoutes(rt.Mux())
+	algorithm.NewAlgorithmHandler(tradingAlgo).RegisterRoutes(rt.Mux())
 
-	// Static File Server - Must be last to avoid conflicts with API routes
-	fs := http.FileServer(http.Dir("."))
-	http.Handle("/", fs)
+	// The embedded dashboard takes every path no API route claimed
+	rt.Handle("/", dashboard.Handler())
 }
 
-// executeBuyOrder executes a buy order using the Alpaca API
-func executeBuyOrder(client *alpaca.Client, signal *algorithm.TradeSignal, apiKey, apiSecret string) (string, error) {
-	log.Printf("Starting executeBuyOrder for symbol: %s", signal.Symbol)
-	// Create order request
-	// Initialize order request with only required fields to avoid potential API issues
-	orderRequest := alpaca.PlaceOrderRequest{}
-
-	// Set basic order properties
-	orderRequest.Symbol = signal.Symbol
-	orderRequest.Side = alpaca.Side("buy")
-	orderRequest.Type = alpaca.OrderType(strings.ToLower(signal.OrderType))
-	orderRequest.TimeInForce = alpaca.Day
-
-	// Set PositionIntent explicitly to prevent 422 API error
-	// Use correct PositionIntent value from Alpaca SDK
-	log.Printf("DEBUG: Setting PositionIntent to BuyToOpen (value: %s)", string(alpaca.BuyToOpen))
-	orderRequest.PositionIntent = alpaca.BuyToOpen
-
-	// Log Alpaca client version info if available
-	log.Printf("DEBUG: Alpaca API Version Info - Using SDK go/v3")
-
-	// Qty will be set later after position sizing
+// previewSignal sizes and prices the order for signal without placing it.
+// A hold needs no order and gives a nil preview.
+func previewSignal(client *alpaca.Client, a *algorithm.TradingAlgorithm, signal *algorithm.TradeSignal, md *marketdata.Client) (*algorithm.OrderPreview, error) {
+	if signal.Signal == algorithm.SignalHold {
+		return nil, nil
+	}
+	return prepareOrder(client, a, signal, md)
+}
 
-	// Calculate position size (simple example - in a real system this would use risk management)
-	// Get account information
-	account, err := client.GetAccount()
-	log.Printf("GetAccount call result: %v", err)
+// holdForConfirmation previews signal's order and, when the confirmation
+// policy marks it, holds it in q instead of placing it. held reports
+// whether it was held.
+func holdForConfirmation(client *alpaca.Client, a *algorithm.TradingAlgorithm, q *confirmations.Queue, signal *algorithm.TradeSignal,
+	md *marketdata.Client, requestedBy string) (item confirmations.Item, held bool, err error) {
+	if !q.Policy().Enabled || signal.Signal == algorithm.SignalHold {
+		return confirmations.Item{}, false, nil
+	}
+	preview, err := previewSignal(client, a, signal, md)
 	if err != nil {
-		return "", fmt.Errorf("failed to get account info: %w", err)
+		return confirmations.Item{}, false, err
 	}
+	reasons := q.Required(signal, preview)
+	if len(reasons) == 0 {
+		return confirmations.Item{}, false, nil
+	}
+	item, err = q.Hold(signal, preview, reasons, requestedBy)
+	return item, err == nil, err
+}
 
-	log.Printf("Account cash available: %s", account.Cash)
-	// Simple position sizing: use 5% of available cash
-	cashAvailable, _ := account.Cash.Float64()
-	positionSize := cashAvailable * 0.05
-
-	// Get latest quote for the symbol
-	// Using marketdata client instead of direct client for quotes
-	mdClient := marketdata.NewClient(marketdata.ClientOpts{
-		APIKey:    apiKey,
-		APISecret: apiSecret,
-	})
-	log.Printf("Created marketdata client for quote lookup")
-
-	// Get the quote
-	quote, err := mdClient.GetLatestQuote(signal.Symbol, marketdata.GetLatestQuoteRequest{})
-	log.Printf("GetLatestQuote call result for %s: %v", signal.Symbol, err)
+// executeSignal places the order for signal and starts managing it,
+// returning a summary of what was done
+func executeSignal(client *alpaca.Client, a *algorithm.TradingAlgorithm, signal *algorithm.TradeSignal, md *marketdata.Client) (*alpaca.Order, string, error) {
+	if signal.Signal == algorithm.SignalHold {
+		return nil, "No trade executed for hold signal", nil
+	}
+	order, result, err := executeOrder(client, a, signal, md)
 	if err != nil {
-		return "", fmt.Errorf("failed to get quote for %s: %w", signal.Symbol, err)
+		return nil, "", err
 	}
+	// Chase and aggressive limit orders are worked from here on
+	a.TrackOrder(signal, order)
+	return order, result, nil
+}
 
-	// Calculate number of shares
-	latestPrice := quote.BidPrice
-	log.Printf("Latest price for %s: %v", signal.Symbol, latestPrice)
-	if latestPrice == 0 {
+// intentActions names each position intent in order summaries.
+var intentActions = map[alpaca.PositionIntent]string{
+	alpaca.BuyToOpen:   "Buy",
+	alpaca.BuyToClose:  "Buy to cover",
+	alpaca.SellToOpen:  "Short sell",
+	alpaca.SellToClose: "Sell",
+}
 
-		return "", fmt.Errorf("invalid price (0) for %s", signal.Symbol)
+// executeOrder places the order for a buy, sell or close signal, see
+// prepareOrder, or hands it to the execution algorithms when it is large
+// enough to slice
+func executeOrder(client *alpaca.Client, a *algorithm.TradingAlgorithm, signal *algorithm.TradeSignal, md *marketdata.Client) (*alpaca.Order, string, error) {
+	preview, err := prepareOrder(client, a, signal, md)
+	if err != nil {
+		return nil, "", err
 	}
-
-	shares := positionSize / float64(latestPrice)
-	// Convert to decimal format for Alpaca API
-	qtyValue := fmt.Sprintf("%.0f", shares) // Round to whole shares
-	qtyDecimal, _ := decimal.NewFromString(qtyValue)
-	orderRequest.Qty = &qtyDecimal
-
-	// For limit orders, set the limit price
-	var priceDecimal decimal.Decimal
-	marketPrice := float64(latestPrice)
-
-	if strings.ToLower(signal.OrderType) == "limit" {
-		if signal.LimitPrice == nil || *signal.LimitPrice <= 0 {
-			// If no limit price provided, use current price
-			priceDecimal = decimal.NewFromFloat(marketPrice)
-		} else {
-			proposedPrice := *signal.LimitPrice
-
-			// Validate that the limit price is within a reasonable range of current market price
-			// For buy orders, limit price should normally be at or below market price
-			minReasonablePrice := marketPrice * 0.70 // 30% below market price (allowing more room for strategy-based limit orders)
-			maxReasonablePrice := marketPrice * 1.05 // 5% above market price
-
-			if proposedPrice < minReasonablePrice || proposedPrice > maxReasonablePrice {
-				log.Printf("WARNING: Proposed limit price ($%.2f) for %s is outside reasonable range of market price ($%.2f)",
-					proposedPrice, signal.Symbol, marketPrice)
-				log.Printf("Adjusting limit price to 99%% of market price")
-				proposedPrice = marketPrice * 0.99 // 1% below market price as default fallback
-			}
-
-			priceDecimal = decimal.NewFromFloat(proposedPrice)
-			// Round to 2 decimal places to avoid sub-penny increments
-			priceDecimal = priceDecimal.Round(2)
-		}
-		orderRequest.LimitPrice = &priceDecimal
+	orderRequest := preview.Request
+	action := intentActions[orderRequest.PositionIntent]
+	if parentID, sliced, err := a.SliceOrder(signal, preview); err != nil {
+		return nil, "", fmt.Errorf("failed to slice %s order: %w", orderRequest.Side, err)
+	} else if sliced {
+		return nil, fmt.Sprintf("%s order for %s shares of %s sliced as %s", action, orderRequest.Qty.String(), signal.Symbol, parentID), nil
 	}
 
 	// Place the order
-	log.Printf("Attempting to place order: %+v", orderRequest)
-	order, err := client.PlaceOrder(orderRequest)
+	logger().Debug("Placing order", "request", fmt.Sprintf("%+v", orderRequest))
+	order, queued, err := a.SubmitOrder(orderRequest, func(order *alpaca.Order) { a.TrackOrder(signal, order) })
 	if err != nil {
-		log.Printf("Full request details: %#v", orderRequest)
+		logger().Error("Failed to place order", "symbol", signal.Symbol, "request", fmt.Sprintf("%#v", orderRequest), "error", err)
+		return nil, "", fmt.Errorf("failed to place %s order: %w", orderRequest.Side, err)
 	}
-	log.Printf("PlaceOrder call result: %v", err)
-
-	if err != nil {
-		log.Printf("Error details: %#v", err)
-		return "", fmt.Errorf("failed to place buy order: %w", err)
+	if queued {
+		return nil, fmt.Sprintf("%s order for %s shares of %s failed transiently and is queued for retry as %s", action, orderRequest.Qty.String(), signal.Symbol, orderRequest.ClientOrderID), nil
 	}
-	log.Printf("Order placed successfully: %+v", order)
+	logger().Info("Order placed", "symbol", order.Symbol, "order_id", order.ID, "side", order.Side, "intent", orderRequest.PositionIntent, "type", order.Type)
 
-	return fmt.Sprintf("Buy order placed for %s shares of %s at %s", orderRequest.Qty.String(), signal.Symbol, order.FilledAvgPrice), nil
+	return order, fmt.Sprintf("%s order placed for %s shares of %s at %s", action, orderRequest.Qty.String(), signal.Symbol, order.FilledAvgPrice), nil
 }
 
-// executeSellOrder executes a sell order using the Alpaca API
-func executeSellOrder(client *alpaca.Client, signal *algorithm.TradeSignal, apiKey, apiSecret string) (string, error) {
-	// Check if we have a position in this symbol
+// prepareOrder builds the order for a buy, sell or close signal against
+// the position held in its symbol, which decides the side and position
+// intent, see algorithm.ResolveIntent: a buy covers a short, a sell closes
+// a long and otherwise goes short, and a close flattens either. An opening
+// order is risk-sized, or checked against a's risk parameters when the
+// signal has an explicit size, and must fit the buying power; a closing
+// order takes the whole position, or the signal's explicit size of it.
+// Nothing is sent to the broker.
+func prepareOrder(client *alpaca.Client, a *algorithm.TradingAlgorithm, signal *algorithm.TradeSignal, md *marketdata.Client) (*algorithm.OrderPreview, error) {
+	// The position's current price is the fallback estimate when no quote
+	// is fetched (closing market orders)
+	held := decimal.Zero
+	marketPrice := 0.0
 	position, err := client.GetPosition(signal.Symbol)
+	if err == nil {
+		held = position.Qty
+		if position.CurrentPrice != nil {
+			marketPrice, _ = position.CurrentPrice.Float64()
+		}
+	} else if !positionNotFound(err) {
+		return nil, fmt.Errorf("failed to get position for %s: %w", signal.Symbol, err)
+	}
+	intent, _, err := algorithm.ResolveIntent(signal.Signal, held.InexactFloat64())
 	if err != nil {
-		// If no position, return an error
-		return "", fmt.Errorf("no position found for %s: %w", signal.Symbol, err)
+		return nil, fmt.Errorf("%s: %w", signal.Symbol, err)
 	}
+	buying := intent.Side == alpaca.Buy
 
 	// Initialize order request with only required fields to avoid potential API issues
 	orderRequest := alpaca.PlaceOrderRequest{}
-
-	// Get position quantity to sell
-	qtyDecimal := position.Qty // Already a decimal in the Alpaca API
-
-	// Set basic order properties
 	orderRequest.Symbol = signal.Symbol
-	orderRequest.Qty = &qtyDecimal // Sell entire position
-	orderRequest.Side = alpaca.Side("sell")
+	orderRequest.Side = intent.Side
 	orderRequest.Type = alpaca.OrderType(strings.ToLower(signal.OrderType))
 	orderRequest.TimeInForce = alpaca.Day
+	orderRequest.ClientOrderID = algorithm.NewClientOrderID(signal.OrderTag())
 
 	// Set PositionIntent explicitly to prevent 422 API error
-	// Use correct PositionIntent value from Alpaca SDK
-	log.Printf("DEBUG: Setting PositionIntent to SellToClose (value: %s)", string(alpaca.SellToClose))
-	orderRequest.PositionIntent = alpaca.SellToClose
-
-	// Log Alpaca client version info if available
-	log.Printf("DEBUG: Alpaca API Version Info - Using SDK go/v3")
-
-	// For limit orders, set the limit price
-	if strings.ToLower(signal.OrderType) == "limit" {
-		if signal.LimitPrice == nil || *signal.LimitPrice <= 0 {
-			// Using marketdata client for quote
-			mdClient := marketdata.NewClient(marketdata.ClientOpts{
-				APIKey:    apiKey,
-				APISecret: apiSecret,
-			})
-
-			// Get latest quote
-			askQuote, err := mdClient.GetLatestQuote(signal.Symbol, marketdata.GetLatestQuoteRequest{})
-			if err != nil {
-
-				return "", fmt.Errorf("failed to get quote for %s: %w", signal.Symbol, err)
+	orderRequest.PositionIntent = intent.Intent
+
+	// Quote buys at the bid and sells at the ask. Opening orders and limit
+	// orders without a limit price need the quote; a closing limit order
+	// is only checked against it when one is available.
+	limit := strings.ToLower(signal.OrderType) == "limit"
+	hasLimit := limit && signal.LimitPrice != nil && *signal.LimitPrice > 0
+	quoted := false
+	if intent.Opens() || limit {
+		quote, err := md.GetLatestQuote(signal.Symbol, marketdata.GetLatestQuoteRequest{})
+		switch {
+		case err == nil:
+			marketPrice = quote.AskPrice
+			if buying {
+				marketPrice = quote.BidPrice
 			}
-
-			marketPrice := float64(askQuote.AskPrice)
-			priceDecimal := decimal.NewFromFloat(marketPrice)
-			orderRequest.LimitPrice = &priceDecimal
-			// Round to 2 decimal places to avoid sub-penny increments
-			*orderRequest.LimitPrice = orderRequest.LimitPrice.Round(2)
-		} else {
-			// Using marketdata client for quote to get current price for validation
-			mdClient := marketdata.NewClient(marketdata.ClientOpts{
-				APIKey:    apiKey,
-				APISecret: apiSecret,
-			})
-			askQuote, err := mdClient.GetLatestQuote(signal.Symbol, marketdata.GetLatestQuoteRequest{})
-			if err == nil { // Only validate if we can get the current price
-				marketPrice := float64(askQuote.AskPrice)
-				proposedPrice := *signal.LimitPrice
-				if proposedPrice < marketPrice*0.70 || proposedPrice > marketPrice*1.30 {
-					log.Printf("WARNING: Proposed sell limit price ($%.2f) for %s is outside reasonable range of market price ($%.2f)",
-						proposedPrice, signal.Symbol, marketPrice)
-					log.Printf("Adjusting limit price to 101%% of market price")
-					*signal.LimitPrice = marketPrice * 1.01 // 1% above market price as default fallback
+			quoted = true
+		case intent.Opens() || !hasLimit:
+			return nil, fmt.Errorf("failed to get quote for %s: %w", signal.Symbol, err)
+		}
+	}
+	logger().Debug("Latest price", "symbol", signal.Symbol, "price", marketPrice)
+	if intent.Opens() && marketPrice == 0 {
+		return nil, fmt.Errorf("invalid price (0) for %s", signal.Symbol)
+	}
+	price := decimal.NewFromFloat(marketPrice)
+
+	// Size the order: opening orders against account equity with the same
+	// risk parameters, scales and liquidity caps ExecuteTrade uses
+	var qtyDecimal decimal.Decimal
+	switch {
+	case !intent.Opens():
+		qtyDecimal = decimal.NewFromFloat(intent.Reduces)
+		if signal.Size != nil && signal.Signal != algorithm.SignalClose {
+			equity := decimal.Zero
+			if signal.Size.PercentOfEquity > 0 {
+				account, err := client.GetAccount()
+				if err != nil {
+					return nil, fmt.Errorf("failed to get account info: %w", err)
 				}
+				equity = account.Equity
+			}
+			if qtyDecimal, err = a.SizeTrade(signal, price, equity, qtyDecimal); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		account, err := client.GetAccount()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get account info: %w", err)
+		}
+		equity := account.Equity
+		if signal.Size != nil {
+			if qtyDecimal, err = a.SizeTrade(signal, price, equity, decimal.Zero); err != nil {
+				return nil, err
 			}
-			priceDecimal := decimal.NewFromFloat(*signal.LimitPrice)
-			// Round to 2 decimal places to avoid sub-penny increments
-			priceDecimal = priceDecimal.Round(2)
-			orderRequest.LimitPrice = &priceDecimal
+			break
 		}
+		sized := a.RiskSize(signal.Symbol, price, equity)
+		logger().Debug("Risk-sized position", "symbol", signal.Symbol, "equity", equity, "value", sized.Value, "shares", sized.Shares)
+		if !sized.Shares.IsPositive() {
+			return nil, fmt.Errorf("position size for %s is $%s, less than one share at $%s", signal.Symbol, sized.Value.StringFixed(2), price.StringFixed(2))
+		}
+		qtyDecimal = sized.Shares
 	}
+	orderRequest.Qty = &qtyDecimal
 
-	// Place the order
-	order, err := client.PlaceOrder(orderRequest)
-	if err != nil {
-		return "", fmt.Errorf("failed to place sell order: %w", err)
+	// For limit orders, set the limit price: the quote when none is
+	// given, otherwise the signal's, checked against a reasonable range of
+	// the market. Buys may sit further below the market than above it.
+	if limit {
+		limitPrice := marketPrice
+		if hasLimit {
+			limitPrice = *signal.LimitPrice
+			low, high, fallback := 0.70, 1.30, 1.01
+			if buying {
+				high, fallback = 1.05, 0.99
+			}
+			if quoted && (limitPrice < marketPrice*low || limitPrice > marketPrice*high) {
+				logger().Warn("Proposed limit price is outside a reasonable range of the market price, adjusting",
+					"symbol", signal.Symbol, "side", intent.Side, "limit_price", limitPrice, "market_price", marketPrice, "adjusted", marketPrice*fallback)
+				limitPrice = marketPrice * fallback
+			}
+		}
+		// Round to 2 decimal places to avoid sub-penny increments
+		priceDecimal := decimal.NewFromFloat(limitPrice).Round(2)
+		orderRequest.LimitPrice = &priceDecimal
 	}
+	if err := applyStopPrices(&orderRequest, signal, marketPrice); err != nil {
+		return nil, err
+	}
+
+	// An opening order must fit the buying power and leverage limit, open
+	// orders included; a risk-sized one shrinks to fit
+	if intent.Opens() {
+		costPrice := price
+		if orderRequest.LimitPrice != nil {
+			costPrice = *orderRequest.LimitPrice
+		}
+		if qtyDecimal, err = a.FitExposure(signal, costPrice, qtyDecimal); err != nil {
+			return nil, err
+		}
+	}
+
+	return algorithm.NewOrderPreview(orderRequest, marketPrice), nil
+}
+
+// positionNotFound reports whether err is Alpaca's answer for a symbol
+// with no position.
+func positionNotFound(err error) bool {
+	var apiErr *alpaca.APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
 
-	return fmt.Sprintf("Sell order placed for %s shares of %s at %s", qtyDecimal.String(), signal.Symbol, order.FilledAvgPrice), nil
+// applyStopPrices validates signal's order type and, for a stop or
+// stop-limit order, sets the stop and a stop-limit's limit on req after
+// checking them against the market. Unlike limit prices, stop prices out
+// of range are refused rather than adjusted: a protective stop moved
+// somewhere else is not the one asked for.
+func applyStopPrices(req *alpaca.PlaceOrderRequest, signal *algorithm.TradeSignal, marketPrice float64) error {
+	if err := signal.ValidateOrder(); err != nil {
+		return err
+	}
+	req.Type = alpaca.OrderType(signal.OrderType)
+	if !algorithm.IsStopOrder(signal.OrderType) {
+		return nil
+	}
+	limit := 0.0
+	if req.Type == alpaca.StopLimit {
+		limit = *signal.LimitPrice
+	}
+	if err := algorithm.CheckStopPrices(string(req.Side), *signal.StopPrice, limit, marketPrice); err != nil {
+		return err
+	}
+	stop := decimal.NewFromFloat(*signal.StopPrice).Round(2)
+	req.StopPrice = &stop
+	if limit > 0 {
+		limitDecimal := decimal.NewFromFloat(limit).Round(2)
+		req.LimitPrice = &limitDecimal
+	}
+	return nil
 }
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/rileyseaburg/go-trader/aging"
+	"github.com/rileyseaburg/go-trader/algorithm"
+	"github.com/rileyseaburg/go-trader/calendar"
+	"github.com/rileyseaburg/go-trader/confirmations"
+	"github.com/rileyseaburg/go-trader/fills"
+	"github.com/rileyseaburg/go-trader/fixtures"
+	"github.com/rileyseaburg/go-trader/notification"
+	"github.com/rileyseaburg/go-trader/router"
+	"github.com/rileyseaburg/go-trader/signalstore"
+	"github.com/rileyseaburg/go-trader/ticker"
+	"github.com/rileyseaburg/go-trader/users"
+)
+
+// newFixtureServer serves the HTTP API with Alpaca answered from the
+// fixtures in testdata/fixtures, recorded with -record-fixtures.
+func newFixtureServer(t *testing.T) (*httptest.Server, *fixtures.Player) {
+	t.Helper()
+	player, err := fixtures.Load(filepath.Join("testdata", "fixtures"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := alpaca.NewClient(alpaca.ClientOpts{
+		APIKey:     "TEST_KEY",
+		APISecret:  "TEST_SECRET",
+		BaseURL:    "https://paper-api.alpaca.markets",
+		HTTPClient: player.Client(),
+	})
+	mdClient := marketdata.NewClient(marketdata.ClientOpts{
+		APIKey:     "TEST_KEY",
+		APISecret:  "TEST_SECRET",
+		HTTPClient: player.Client(),
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	dir := t.TempDir()
+	tradingAlgo := algorithm.NewTradingAlgorithm(ctx, nil, client, mdClient)
+	tickerServer := ticker.NewTickerServer(ctx, true, "TEST_KEY", "TEST_SECRET")
+	tickerServer.SetMarketDataClient(mdClient)
+	basketManager, err := ticker.NewBasketManager(filepath.Join(dir, "baskets"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	userStore, err := users.Open(filepath.Join(dir, "users"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signalHistory, err := signalstore.Open(filepath.Join(dir, "signals.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirmQueue, err := confirmations.New(filepath.Join(dir, "confirmations.json"), confirmations.DefaultPolicy(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	positionAges, err := aging.New(filepath.Join(dir, "aging.json"), func(time.Time) []fills.Record { return nil }, calendar.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rt := router.New()
+	setupHTTPHandlers(rt, client, tradingAlgo, tickerServer, userBaskets(userStore, basketManager, ""), userStore,
+		notification.NewNotificationManager(100), nil, nil, signalHistory, confirmQueue,
+		algorithm.NewInstanceRegistry(tradingAlgo), positionAges, mdClient)
+	server := httptest.NewServer(rt)
+	t.Cleanup(server.Close)
+	t.Cleanup(func() {
+		if misses := player.Misses(); len(misses) > 0 {
+			t.Errorf("requests without fixtures: %v", misses)
+		}
+	})
+	return server, player
+}
+
+// getJSON decodes the response to a GET of path into v.
+func getJSON(t *testing.T, server *httptest.Server, path string, v interface{}) {
+	t.Helper()
+	resp, err := http.Get(server.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: %s", path, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+}
+
+func TestAccountAndPositionsFromFixtures(t *testing.T) {
+	server, _ := newFixtureServer(t)
+
+	var account alpaca.Account
+	getJSON(t, server, "/api/account", &account)
+	if account.Status != "ACTIVE" || account.Equity.String() == "0" || account.AccountNumber != fixtures.Redacted {
+		t.Errorf("account = %+v", account)
+	}
+
+	var positions []alpaca.Position
+	getJSON(t, server, "/api/positions", &positions)
+	if len(positions) != 1 || positions[0].Symbol != "AAPL" || positions[0].Qty.String() != "10" {
+		t.Errorf("positions = %+v", positions)
+	}
+}
+
+func TestHistoricalFromFixtures(t *testing.T) {
+	server, _ := newFixtureServer(t)
+
+	var data struct {
+		Symbol string                   `json:"symbol"`
+		Data   []map[string]interface{} `json:"data"`
+	}
+	getJSON(t, server, "/api/historical?symbol=AAPL&start=2026-09-01&end=2026-09-05", &data)
+	if len(data.Data) == 0 {
+		t.Errorf("historical = %+v", data)
+	}
+}
+
+func TestExecuteTradeDryRunFromFixtures(t *testing.T) {
+	server, _ := newFixtureServer(t)
+
+	// MSFT is not held: a buy opens a position sized against the account
+	body := `{"symbol":"MSFT","signal":"buy","order_type":"market","dry_run":true}`
+	resp, err := http.Post(server.URL+"/api/executeTrade", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Success bool                    `json:"success"`
+		DryRun  bool                    `json:"dry_run"`
+		Error   string                  `json:"error"`
+		Preview *algorithm.OrderPreview `json:"preview"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if !result.Success || !result.DryRun || result.Preview == nil {
+		t.Fatalf("dry run = %d %+v", resp.StatusCode, result)
+	}
+	req := result.Preview.Request
+	if req.Side != alpaca.Buy || req.PositionIntent != alpaca.BuyToOpen || req.Qty == nil || !req.Qty.IsPositive() {
+		t.Errorf("order = %+v", req)
+	}
+}
+
+func TestReplayNeverBuildsLiveBroker(t *testing.T) {
+	t.Setenv("GO_TRADER_REPLAY", "")
+	client := alpaca.NewClient(alpaca.ClientOpts{APIKey: "TEST_KEY", APISecret: "TEST_SECRET"})
+
+	mock := false
+	if err := settleMode(&mock, true, ""); err != nil {
+		t.Fatal(err)
+	}
+	if !mock || os.Getenv("GO_TRADER_REPLAY") != "true" {
+		t.Fatalf("replay mock = %v, GO_TRADER_REPLAY = %q", mock, os.Getenv("GO_TRADER_REPLAY"))
+	}
+	if b := riskBroker(mock, client, nil); b != nil {
+		t.Errorf("replay built a live broker: %T", b)
+	}
+
+	live := false
+	if err := settleMode(&live, false, ""); err != nil || live {
+		t.Fatalf("live run mock = %v, %v", live, err)
+	}
+	if riskBroker(live, client, nil) == nil {
+		t.Error("live run has no broker")
+	}
+
+	for _, mock := range []bool{false, true} {
+		m := mock
+		if err := settleMode(&m, !mock, "fixtures"); err == nil {
+			t.Errorf("-record-fixtures accepted with mock %v", mock)
+		}
+	}
+}
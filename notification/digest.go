@@ -0,0 +1,225 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxDigestLines bounds how many held notifications a digest lists before
+// counting the rest.
+const maxDigestLines = 10
+
+// digestTick is how often RunDigests looks for batches that are due.
+var digestTick = 15 * time.Second
+
+// DigestPolicy batches low and medium priority notifications into one
+// summary per type every few minutes, so a volatile session does not
+// bury the user in alerts. High priority notifications are never held.
+type DigestPolicy struct {
+	Enabled bool `json:"enabled"`
+	// DefaultMinutes is how often a type's digest is emitted unless
+	// Minutes names the type
+	DefaultMinutes int `json:"default_minutes"`
+	// Minutes overrides the interval per type; 0 emits the type at once
+	Minutes map[NotificationType]int `json:"minutes,omitempty"`
+}
+
+// DefaultDigestPolicy emits every notification at once; enabled, it
+// batches each type for 15 minutes.
+func DefaultDigestPolicy() DigestPolicy {
+	return DigestPolicy{DefaultMinutes: 15, Minutes: map[NotificationType]int{}}
+}
+
+// Validate checks the policy for usable values.
+func (p DigestPolicy) Validate() error {
+	if p.DefaultMinutes < 0 || p.DefaultMinutes > 24*60 {
+		return errors.New("default_minutes must be between 0 and 1440")
+	}
+	for t, m := range p.Minutes {
+		if t == "" {
+			return errors.New("minutes needs a notification type")
+		}
+		if m < 0 || m > 24*60 {
+			return fmt.Errorf("minutes for %s must be between 0 and 1440", t)
+		}
+	}
+	return nil
+}
+
+// interval returns how long notifications of type t are held, zero for
+// not at all.
+func (p DigestPolicy) interval(t NotificationType) time.Duration {
+	if !p.Enabled {
+		return 0
+	}
+	m, ok := p.Minutes[t]
+	if !ok {
+		m = p.DefaultMinutes
+	}
+	return time.Duration(m) * time.Minute
+}
+
+// DigestBatch is the notifications of one type held for its next digest.
+type DigestBatch struct {
+	Type          NotificationType `json:"type"`
+	Due           time.Time        `json:"due"`
+	Notifications []Notification   `json:"notifications"` // oldest first
+}
+
+// SetDigestPolicy replaces the digest policy. Batches held under the old
+// policy are emitted at once.
+func (nm *NotificationManager) SetDigestPolicy(p DigestPolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	nm.mutex.Lock()
+	defer nm.mutex.Unlock()
+	nm.flushLocked(true)
+	nm.digest = p
+	return nil
+}
+
+// DigestPolicy returns the digest policy.
+func (nm *NotificationManager) DigestPolicy() DigestPolicy {
+	nm.mutex.RLock()
+	defer nm.mutex.RUnlock()
+	p := nm.digest
+	p.Minutes = make(map[NotificationType]int, len(nm.digest.Minutes))
+	for t, m := range nm.digest.Minutes {
+		p.Minutes[t] = m
+	}
+	return p
+}
+
+// SetClock replaces the clock digests are timed by.
+func (nm *NotificationManager) SetClock(now func() time.Time) {
+	nm.mutex.Lock()
+	nm.now = now
+	nm.mutex.Unlock()
+}
+
+// PendingDigests returns the batches held for their next digest, soonest
+// due first.
+func (nm *NotificationManager) PendingDigests() []DigestBatch {
+	nm.mutex.RLock()
+	defer nm.mutex.RUnlock()
+	out := make([]DigestBatch, 0, len(nm.batches))
+	for _, b := range nm.batches {
+		out = append(out, DigestBatch{Type: b.Type, Due: b.Due, Notifications: append([]Notification(nil), b.Notifications...)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Due.Before(out[j].Due) })
+	return out
+}
+
+// FlushDigests emits the batches that are due, or every batch when all is
+// set, and returns how many digests it emitted.
+func (nm *NotificationManager) FlushDigests(all bool) int {
+	nm.mutex.Lock()
+	defer nm.mutex.Unlock()
+	return nm.flushLocked(all)
+}
+
+// RunDigests emits batches as they come due until ctx is done.
+func (nm *NotificationManager) RunDigests(ctx context.Context) {
+	ticker := time.NewTicker(digestTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			nm.FlushDigests(false)
+		}
+	}
+}
+
+// holdLocked adds n to its type's batch and reports whether it was held.
+func (nm *NotificationManager) holdLocked(n Notification) bool {
+	if n.Priority == PriorityHigh {
+		return false
+	}
+	wait := nm.digest.interval(n.Type)
+	if wait <= 0 {
+		return false
+	}
+	if nm.batches == nil {
+		nm.batches = make(map[NotificationType]*DigestBatch)
+	}
+	b, ok := nm.batches[n.Type]
+	if !ok {
+		b = &DigestBatch{Type: n.Type, Due: nm.now().Add(wait)}
+		nm.batches[n.Type] = b
+	}
+	b.Notifications = append(b.Notifications, n)
+	return true
+}
+
+func (nm *NotificationManager) flushLocked(all bool) int {
+	now := nm.now()
+	emitted := 0
+	for t, b := range nm.batches {
+		if !all && now.Before(b.Due) {
+			continue
+		}
+		delete(nm.batches, t)
+		nm.storeLocked(summarize(b, now))
+		emitted++
+	}
+	return emitted
+}
+
+// summarize turns a batch into one notification: its only member as it
+// was, or a digest listing the members at the highest priority among
+// them.
+func summarize(b *DigestBatch, now time.Time) Notification {
+	items := b.Notifications
+	if len(items) == 1 {
+		return items[0]
+	}
+	priority := PriorityLow
+	ids := make([]string, len(items))
+	seen := make(map[string]bool)
+	var symbols []string
+	var lines []string
+	for i, n := range items {
+		if n.Priority == PriorityMedium {
+			priority = PriorityMedium
+		}
+		ids[i] = n.ID
+		if sym, ok := n.Metadata["symbol"].(string); ok && sym != "" && !seen[sym] {
+			seen[sym] = true
+			symbols = append(symbols, sym)
+		}
+		if i < maxDigestLines {
+			lines = append(lines, "- "+n.Title+": "+n.Message)
+		}
+	}
+	if more := len(items) - maxDigestLines; more > 0 {
+		lines = append(lines, fmt.Sprintf("...and %d more", more))
+	}
+	sort.Strings(symbols)
+	metadata := map[string]interface{}{
+		"digest":  true,
+		"count":   len(items),
+		"from":    items[0].Timestamp,
+		"to":      items[len(items)-1].Timestamp,
+		"ids":     ids,
+		"symbols": symbols,
+	}
+	if len(symbols) == 1 {
+		metadata["symbol"] = symbols[0]
+	}
+	return Notification{
+		ID:        generateID(),
+		Type:      b.Type,
+		Title:     fmt.Sprintf("Digest: %d %s notifications", len(items), strings.ReplaceAll(string(b.Type), "_", " ")),
+		Message:   strings.Join(lines, "\n"),
+		Priority:  priority,
+		Timestamp: now,
+		Metadata:  metadata,
+	}
+}
@@ -0,0 +1,72 @@
+package notification
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// DigestHandler serves the notification digest over HTTP.
+type DigestHandler struct {
+	manager *NotificationManager
+}
+
+// NewDigestHandler creates a handler for manager's digests.
+func NewDigestHandler(manager *NotificationManager) *DigestHandler {
+	return &DigestHandler{manager: manager}
+}
+
+// RegisterRoutes registers the digest routes with mux.
+func (h *DigestHandler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/notifications/digest - policy and the batches held for the next digests
+	// POST /api/notifications/digest - update the policy; omitted fields are kept
+	mux.HandleFunc("/api/notifications/digest", h.handleDigest)
+
+	// POST /api/notifications/digest/flush - emit every held batch now
+	mux.HandleFunc("/api/notifications/digest/flush", h.handleFlush)
+}
+
+func (h *DigestHandler) handleDigest(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusOK)
+		return
+	case http.MethodGet:
+	case http.MethodPost, http.MethodPut:
+		// Start from the current policy so partial updates work
+		policy := h.manager.DigestPolicy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.manager.SetDigestPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"policy":  h.manager.DigestPolicy(),
+		"pending": h.manager.PendingDigests(),
+	})
+}
+
+func (h *DigestHandler) handleFlush(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusOK)
+		return
+	case http.MethodPost:
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"emitted": h.manager.FlushDigests(true),
+	})
+}
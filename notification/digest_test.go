@@ -0,0 +1,61 @@
+package notification
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDigestBatchesLowAndMediumPriority(t *testing.T) {
+	manager := NewNotificationManager(100)
+	now := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	manager.SetClock(func() time.Time { return now })
+	if err := manager.SetDigestPolicy(DigestPolicy{Enabled: true, DefaultMinutes: 15,
+		Minutes: map[NotificationType]int{TypeMarketEvent: 5, TypeSignalGenerated: 0}}); err != nil {
+		t.Fatal(err)
+	}
+
+	event := func(symbol, message string, priority NotificationPriority) Notification {
+		n := CreateMarketEventNotification(symbol, "Gap", message)
+		n.Priority = priority
+		return n
+	}
+	manager.AddNotification(event("AAPL", "AAPL opened 2% up", PriorityMedium))
+	manager.AddNotification(event("MSFT", "MSFT opened 1% down", PriorityLow))
+	manager.AddNotification(event("TSLA", "TSLA halted", PriorityHigh))
+	manager.AddNotification(CreateSignalGeneratedNotification("AAPL", "buy", "momentum", PriorityLow, nil))
+	if got := len(manager.GetNotifications()); got != 2 {
+		t.Fatalf("emitted %d at once, want the high priority and the undigested type", got)
+	}
+	pending := manager.PendingDigests()
+	if len(pending) != 1 || len(pending[0].Notifications) != 2 || !pending[0].Due.Equal(now.Add(5*time.Minute)) {
+		t.Fatalf("pending = %+v", pending)
+	}
+
+	now = now.Add(4 * time.Minute)
+	if n := manager.FlushDigests(false); n != 0 {
+		t.Errorf("flushed %d before due", n)
+	}
+	now = now.Add(time.Minute)
+	if n := manager.FlushDigests(false); n != 1 {
+		t.Fatalf("flushed %d when due", n)
+	}
+	digest := manager.GetNotifications()[0]
+	if digest.Title != "Digest: 2 market event notifications" || digest.Priority != PriorityMedium ||
+		digest.Metadata["count"] != 2 || !strings.Contains(digest.Message, "- Gap: MSFT: MSFT opened 1% down") {
+		t.Errorf("digest = %+v", digest)
+	}
+	if len(manager.PendingDigests()) != 0 {
+		t.Error("batch kept after flush")
+	}
+
+	// A batch of one is emitted as it was
+	manager.AddNotification(event("NVDA", "NVDA opened 3% up", PriorityLow))
+	if n := manager.FlushDigests(true); n != 1 || manager.GetNotifications()[0].Title != "Gap: NVDA" {
+		t.Errorf("single batch = %+v", manager.GetNotifications()[0])
+	}
+
+	if err := manager.SetDigestPolicy(DigestPolicy{Enabled: true, DefaultMinutes: -1}); err == nil {
+		t.Error("accepted negative minutes")
+	}
+}
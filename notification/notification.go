@@ -44,6 +44,10 @@ type NotificationManager struct {
 	notifications   []Notification
 	maxNotifications int
 	mutex           sync.RWMutex
+	// digest holds low and medium priority notifications in batches
+	digest  DigestPolicy
+	batches map[NotificationType]*DigestBatch
+	now     func() time.Time
 }
 
 // NewNotificationManager creates a new notification manager
@@ -51,10 +55,14 @@ func NewNotificationManager(maxNotifications int) *NotificationManager {
 	return &NotificationManager{
 		notifications:   []Notification{},
 		maxNotifications: maxNotifications,
+		digest:          DefaultDigestPolicy(),
+		now:             time.Now,
 	}
 }
 
-// AddNotification adds a notification to the manager
+// AddNotification adds a notification to the manager. With digests
+// enabled, low and medium priority notifications are held for their
+// type's next digest instead.
 func (nm *NotificationManager) AddNotification(notification Notification) {
 	nm.mutex.Lock()
 	defer nm.mutex.Unlock()
@@ -63,7 +71,14 @@ func (nm *NotificationManager) AddNotification(notification Notification) {
 	if notification.Timestamp.IsZero() {
 		notification.Timestamp = time.Now()
 	}
+	if nm.holdLocked(notification) {
+		return
+	}
+	nm.storeLocked(notification)
+}
 
+// storeLocked lists notification, newest first.
+func (nm *NotificationManager) storeLocked(notification Notification) {
 	// Add to the beginning of the list for reverse chronological order
 	nm.notifications = append([]Notification{notification}, nm.notifications...)
 
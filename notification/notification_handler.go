@@ -2,15 +2,35 @@ package notification
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/rileyseaburg/go-trader/paging"
 )
 
+func logger() *slog.Logger { return slog.With("module", "notification") }
+
+// notificationList pages GET /api/notifications, newest first by default.
+var notificationList = paging.List[Notification]{
+	Fields: []paging.Field[Notification]{
+		{Name: "timestamp", Sort: func(n Notification) paging.Key { return paging.Time(n.Timestamp) }},
+		{Name: "type", Sort: func(n Notification) paging.Key { return paging.Text(string(n.Type)) }, Filter: func(n Notification) string { return string(n.Type) }},
+		{Name: "priority", Filter: func(n Notification) string { return string(n.Priority) }},
+		{Name: "read", Filter: func(n Notification) string { return strconv.FormatBool(n.Read) }},
+		{Name: "symbol", Filter: func(n Notification) string { return fmt.Sprint(n.Metadata["symbol"]) }},
+	},
+	ID:   func(n Notification) string { return n.ID },
+	Sort: "-timestamp",
+}
+
 // NotificationHandler implements HTTP handlers for notification API endpoints
 type NotificationHandler struct {
 	manager *NotificationManager
+	filter  func(r *http.Request, n Notification) bool
 }
 
 // NewNotificationHandler creates a new notification handler
@@ -20,9 +40,15 @@ func NewNotificationHandler(manager *NotificationManager) *NotificationHandler {
 	}
 }
 
+// SetFilter sets a check each listed notification must pass for the
+// request, such as the caller's notification preferences
+func (h *NotificationHandler) SetFilter(filter func(r *http.Request, n Notification) bool) {
+	h.filter = filter
+}
+
 // RegisterRoutes registers notification routes with the provided HTTP mux
 func (h *NotificationHandler) RegisterRoutes(mux *http.ServeMux) {
-	// GET /api/notifications - List all notifications
+	// GET /api/notifications?limit=&cursor=&sort=&filter= - List notifications, a page at a time
 	// POST /api/notifications - Create a new notification
 	mux.HandleFunc("/api/notifications", h.handleNotifications)
 
@@ -44,16 +70,10 @@ func (h *NotificationHandler) handleNotifications(w http.ResponseWriter, r *http
 
 	// Handle OPTIONS for CORS
 	if r.Method == http.MethodOptions {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 
-	// Add CORS headers
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-
 	if r.Method == http.MethodGet {
 		// Get query parameters for filtering
 		unreadOnly := r.URL.Query().Get("unread") == "true"
@@ -74,13 +94,17 @@ func (h *NotificationHandler) handleNotifications(w http.ResponseWriter, r *http
 			// If no filters, get all notifications
 			notifications = h.manager.GetNotifications()
 		}
-
-		// Serialize and return
-		if err := json.NewEncoder(w).Encode(notifications); err != nil {
-			http.Error(w, "Failed to encode notifications", http.StatusInternalServerError)
-			log.Printf("Error encoding notifications: %v", err)
-			return
+		if h.filter != nil {
+			kept := make([]Notification, 0, len(notifications))
+			for _, n := range notifications {
+				if h.filter(r, n) {
+					kept = append(kept, n)
+				}
+			}
+			notifications = kept
 		}
+
+		notificationList.Serve(w, r, notifications)
 		return
 	}
 
@@ -89,7 +113,7 @@ func (h *NotificationHandler) handleNotifications(w http.ResponseWriter, r *http
 		var notif Notification
 		if err := json.NewDecoder(r.Body).Decode(&notif); err != nil {
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
-			log.Printf("Error decoding notification request: %v", err)
+			logger().Warn("Failed to decode notification request", "error", err)
 			return
 		}
 
@@ -110,7 +134,7 @@ func (h *NotificationHandler) handleNotifications(w http.ResponseWriter, r *http
 			"message": "Notification created successfully",
 			"id":      notif.ID,
 		}); err != nil {
-			log.Printf("Error encoding success response: %v", err)
+			logger().Error("Failed to encode response", "error", err)
 		}
 		return
 	}
@@ -137,12 +161,9 @@ func (h *NotificationHandler) filterByType(notificationType NotificationType) []
 func (h *NotificationHandler) handleNotificationActions(w http.ResponseWriter, r *http.Request) {
 	// Set common headers
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	// Handle OPTIONS for CORS
 	if r.Method == http.MethodOptions {
-		w.Header().Set("Access-Control-Allow-Methods", "POST, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		w.WriteHeader(http.StatusOK)
 		return
 	}
@@ -198,12 +219,9 @@ func (h *NotificationHandler) handleNotificationActions(w http.ResponseWriter, r
 func (h *NotificationHandler) handleReadAllNotifications(w http.ResponseWriter, r *http.Request) {
 	// Set common headers
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
 
 	// Handle OPTIONS for CORS
 	if r.Method == http.MethodOptions {
-		w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 		w.WriteHeader(http.StatusOK)
 		return
 	}
@@ -213,7 +231,7 @@ func (h *NotificationHandler) handleReadAllNotifications(w http.ResponseWriter,
 		if err := json.NewEncoder(w).Encode(map[string]string{
 			"message": "All notifications marked as read",
 		}); err != nil {
-			log.Printf("Error encoding success response: %v", err)
+			logger().Error("Failed to encode response", "error", err)
 		}
 		return
 	}
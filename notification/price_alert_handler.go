@@ -0,0 +1,64 @@
+package notification
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// PriceAlertHandler serves price alert configuration over HTTP.
+type PriceAlertHandler struct {
+	alerts *PriceAlerts
+}
+
+// NewPriceAlertHandler creates a handler for alerts.
+func NewPriceAlertHandler(alerts *PriceAlerts) *PriceAlertHandler {
+	return &PriceAlertHandler{alerts: alerts}
+}
+
+// RegisterRoutes registers the price alert routes with mux.
+func (h *PriceAlertHandler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/notifications/price-alerts - current default and per-symbol rules
+	// POST /api/notifications/price-alerts - update rules; omitted fields are kept
+	// DELETE /api/notifications/price-alerts?symbol=TSLA - drop a symbol override
+	mux.HandleFunc("/api/notifications/price-alerts", h.handlePriceAlerts)
+}
+
+func (h *PriceAlertHandler) handlePriceAlerts(w http.ResponseWriter, r *http.Request) {
+
+	switch r.Method {
+	case http.MethodOptions:
+		w.WriteHeader(http.StatusOK)
+		return
+	case http.MethodGet:
+	case http.MethodPost:
+		// Start from the current config so partial updates work
+		config := h.alerts.Config()
+		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.alerts.SetConfig(config); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		symbol := strings.ToUpper(r.URL.Query().Get("symbol"))
+		config := h.alerts.Config()
+		if _, ok := config.Symbols[symbol]; !ok {
+			http.Error(w, "No override for symbol", http.StatusNotFound)
+			return
+		}
+		delete(config.Symbols, symbol)
+		if err := h.alerts.SetConfig(config); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.alerts.Config())
+}
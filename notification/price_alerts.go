@@ -0,0 +1,238 @@
+package notification
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Reference prices a move is measured against.
+const (
+	BasisPrevClose = "prev_close" // the prior session's close
+	BasisRolling   = "rolling"    // the price WindowMinutes ago
+)
+
+// PriceAlertRule configures when a price move raises a market event
+// notification.
+type PriceAlertRule struct {
+	ThresholdPercent float64 `json:"threshold_percent"` // |move| that raises an alert
+	Basis            string  `json:"basis"`             // prev_close or rolling
+	WindowMinutes    int     `json:"window_minutes"`    // lookback for the rolling basis
+	CooldownMinutes  int     `json:"cooldown_minutes"`  // quiet period after an alert for the symbol
+}
+
+// DefaultPriceAlertRule alerts on a 2% move from the prior close, at most
+// once every 30 minutes per symbol.
+func DefaultPriceAlertRule() PriceAlertRule {
+	return PriceAlertRule{
+		ThresholdPercent: 2.0,
+		Basis:            BasisPrevClose,
+		WindowMinutes:    15,
+		CooldownMinutes:  30,
+	}
+}
+
+// Validate checks the rule for usable values.
+func (r PriceAlertRule) Validate() error {
+	switch r.Basis {
+	case BasisPrevClose, BasisRolling:
+	default:
+		return fmt.Errorf("basis must be %s or %s", BasisPrevClose, BasisRolling)
+	}
+	if r.ThresholdPercent <= 0 {
+		return errors.New("threshold_percent must be positive")
+	}
+	if r.WindowMinutes < 1 || r.WindowMinutes > 24*60 {
+		return errors.New("window_minutes must be between 1 and 1440")
+	}
+	if r.CooldownMinutes < 0 {
+		return errors.New("cooldown_minutes must not be negative")
+	}
+	return nil
+}
+
+// withDefaults fills the rule's unset fields from def.
+func (r PriceAlertRule) withDefaults(def PriceAlertRule) PriceAlertRule {
+	if r.ThresholdPercent == 0 {
+		r.ThresholdPercent = def.ThresholdPercent
+	}
+	if r.Basis == "" {
+		r.Basis = def.Basis
+	}
+	if r.WindowMinutes == 0 {
+		r.WindowMinutes = def.WindowMinutes
+	}
+	if r.CooldownMinutes == 0 {
+		r.CooldownMinutes = def.CooldownMinutes
+	}
+	return r
+}
+
+// PriceAlertConfig is the rule applied to every symbol, with per-symbol
+// overrides.
+type PriceAlertConfig struct {
+	Default PriceAlertRule            `json:"default"`
+	Symbols map[string]PriceAlertRule `json:"symbols,omitempty"`
+}
+
+type priceTick struct {
+	at    time.Time
+	price float64
+}
+
+// alertState is one symbol's recent prices and alert history.
+type alertState struct {
+	ticks     []priceTick
+	side      int // direction of the move last alerted, 0 once back inside
+	lastAlert time.Time
+}
+
+// PriceAlerts turns a symbol's price stream into market event
+// notifications. An alert fires when the move crosses the symbol's
+// threshold, not on every tick beyond it, and no more than once per
+// cooldown.
+type PriceAlerts struct {
+	manager *NotificationManager
+
+	mu     sync.Mutex
+	config PriceAlertConfig
+	state  map[string]*alertState
+}
+
+// NewPriceAlerts creates price alerts that post to manager, using
+// DefaultPriceAlertRule for every symbol.
+func NewPriceAlerts(manager *NotificationManager) *PriceAlerts {
+	return &PriceAlerts{
+		manager: manager,
+		config:  PriceAlertConfig{Default: DefaultPriceAlertRule(), Symbols: map[string]PriceAlertRule{}},
+		state:   make(map[string]*alertState),
+	}
+}
+
+// Config returns the current configuration.
+func (p *PriceAlerts) Config() PriceAlertConfig {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c := PriceAlertConfig{Default: p.config.Default, Symbols: make(map[string]PriceAlertRule, len(p.config.Symbols))}
+	for sym, r := range p.config.Symbols {
+		c.Symbols[sym] = r
+	}
+	return c
+}
+
+// SetConfig validates and replaces the configuration. Fields a symbol
+// override leaves unset take the default rule's values.
+func (p *PriceAlerts) SetConfig(c PriceAlertConfig) error {
+	if err := c.Default.Validate(); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+	symbols := make(map[string]PriceAlertRule, len(c.Symbols))
+	for sym, r := range c.Symbols {
+		r = r.withDefaults(c.Default)
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("%s: %w", sym, err)
+		}
+		symbols[strings.ToUpper(sym)] = r
+	}
+	c.Symbols = symbols
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config = c
+	return nil
+}
+
+// Rule returns the rule applied to symbol.
+func (p *PriceAlerts) Rule(symbol string) PriceAlertRule {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.ruleLocked(strings.ToUpper(symbol))
+}
+
+func (p *PriceAlerts) ruleLocked(symbol string) PriceAlertRule {
+	if r, ok := p.config.Symbols[symbol]; ok {
+		return r
+	}
+	return p.config.Default
+}
+
+// Observe records a price for symbol at time at and posts an alert if the
+// move from the rule's reference crosses its threshold. prevClose is the
+// prior session's close, zero when unknown. The alert, if any, is
+// returned.
+func (p *PriceAlerts) Observe(symbol string, price, prevClose float64, at time.Time) (Notification, bool) {
+	if price <= 0 {
+		return Notification{}, false
+	}
+	symbol = strings.ToUpper(symbol)
+
+	p.mu.Lock()
+	rule := p.ruleLocked(symbol)
+	st, ok := p.state[symbol]
+	if !ok {
+		st = &alertState{}
+		p.state[symbol] = st
+	}
+
+	// Keep just enough history to know the price a window ago
+	window := time.Duration(rule.WindowMinutes) * time.Minute
+	st.ticks = append(st.ticks, priceTick{at: at, price: price})
+	for len(st.ticks) > 1 && !st.ticks[1].at.After(at.Add(-window)) {
+		st.ticks = st.ticks[1:]
+	}
+
+	ref := prevClose
+	if rule.Basis == BasisRolling {
+		ref = st.ticks[0].price
+	}
+	if ref <= 0 {
+		p.mu.Unlock()
+		return Notification{}, false
+	}
+	change := (price - ref) / ref * 100
+
+	side := 0
+	switch {
+	case change >= rule.ThresholdPercent:
+		side = 1
+	case change <= -rule.ThresholdPercent:
+		side = -1
+	}
+	cooldown := time.Duration(rule.CooldownMinutes) * time.Minute
+	if side == 0 || side == st.side || (!st.lastAlert.IsZero() && at.Sub(st.lastAlert) < cooldown) {
+		if side == 0 {
+			st.side = 0
+		}
+		p.mu.Unlock()
+		return Notification{}, false
+	}
+	st.side = side
+	st.lastAlert = at
+	p.mu.Unlock()
+
+	n := priceAlertNotification(symbol, price, change, rule)
+	if p.manager != nil {
+		p.manager.AddNotification(n)
+	}
+	return n, true
+}
+
+// priceAlertNotification describes a move of change percent to price.
+func priceAlertNotification(symbol string, price, change float64, rule PriceAlertRule) Notification {
+	event, direction, move := "Significant Price Increase", "up", change
+	if change < 0 {
+		event, direction, move = "Significant Price Decrease", "down", -change
+	}
+	since := "from the prior close"
+	if rule.Basis == BasisRolling {
+		since = fmt.Sprintf("in the last %d minutes", rule.WindowMinutes)
+	}
+	n := CreateMarketEventNotification(symbol, event,
+		fmt.Sprintf("%s is %s %.2f%% %s at $%.2f", symbol, direction, move, since, price))
+	n.Metadata["change_percent"] = change
+	n.Metadata["basis"] = rule.Basis
+	n.Metadata["threshold_percent"] = rule.ThresholdPercent
+	return n
+}
@@ -0,0 +1,73 @@
+package notification
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPriceAlertsCrossThresholdWithCooldown(t *testing.T) {
+	manager := NewNotificationManager(100)
+	p := NewPriceAlerts(manager)
+	t0 := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+
+	if _, fired := p.Observe("AAPL", 101, 100, t0); fired {
+		t.Fatal("alert inside threshold")
+	}
+	n, fired := p.Observe("AAPL", 102.5, 100, t0.Add(time.Minute))
+	if !fired || n.Metadata["basis"] != BasisPrevClose || n.Title != "Significant Price Increase: AAPL" {
+		t.Fatalf("crossing alert = %+v, %v", n, fired)
+	}
+	// Ticks beyond the threshold do not repeat the alert
+	if _, fired := p.Observe("AAPL", 103, 100, t0.Add(2*time.Minute)); fired {
+		t.Error("repeated alert while beyond threshold")
+	}
+	// Falling back and crossing again within the cooldown stays quiet...
+	p.Observe("AAPL", 101, 100, t0.Add(3*time.Minute))
+	if _, fired := p.Observe("AAPL", 102.5, 100, t0.Add(4*time.Minute)); fired {
+		t.Error("alert inside cooldown")
+	}
+	// ...until the cooldown has passed
+	if _, fired := p.Observe("AAPL", 102.6, 100, t0.Add(31*time.Minute)); !fired {
+		t.Error("no alert after cooldown")
+	}
+	if got := len(manager.GetNotifications()); got != 2 {
+		t.Errorf("posted %d notifications, want 2", got)
+	}
+}
+
+func TestPriceAlertsRollingBasisAndOverrides(t *testing.T) {
+	p := NewPriceAlerts(nil)
+	err := p.SetConfig(PriceAlertConfig{
+		Default: DefaultPriceAlertRule(),
+		Symbols: map[string]PriceAlertRule{"tsla": {ThresholdPercent: 1, Basis: BasisRolling, WindowMinutes: 5}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := p.Rule("TSLA"); r.CooldownMinutes != 30 || r.ThresholdPercent != 1 {
+		t.Errorf("override = %+v, want cooldown filled from default", r)
+	}
+
+	t0 := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	p.Observe("TSLA", 200, 0, t0)
+	p.Observe("TSLA", 201, 0, t0.Add(3*time.Minute))
+	// 10 minutes in, the reference is the 3-minute tick, not the first
+	if _, fired := p.Observe("TSLA", 202.5, 0, t0.Add(10*time.Minute)); fired {
+		t.Error("rolling alert measured from outside the window")
+	}
+	n, fired := p.Observe("TSLA", 198, 0, t0.Add(11*time.Minute))
+	if !fired || n.Metadata["change_percent"].(float64) > -1 {
+		t.Errorf("rolling drop alert = %+v, %v", n, fired)
+	}
+
+	// prev_close alerts need a prior close
+	if _, fired := p.Observe("AAPL", 150, 0, t0); fired {
+		t.Error("alert without a reference price")
+	}
+
+	bad := p.Config()
+	bad.Default.Basis = "vwap"
+	if err := p.SetConfig(bad); err == nil {
+		t.Error("invalid basis accepted")
+	}
+}
@@ -0,0 +1,126 @@
+package orders
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/rileyseaburg/go-trader/users"
+)
+
+// Handler exposes order management over HTTP.
+type Handler struct {
+	manager *Manager
+	book    *Book
+}
+
+// NewHandler creates a handler for manager and the lifecycle book.
+func NewHandler(manager *Manager, book *Book) *Handler {
+	return &Handler{manager: manager, book: book}
+}
+
+// RegisterRoutes registers the order management routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/orders/working - orders being chased and recently finished ones
+	mux.HandleFunc("/api/orders/working", h.handleWorking)
+
+	// GET/POST /api/orders/execution - read or update the chase policy
+	mux.HandleFunc("/api/orders/execution", h.handlePolicy)
+
+	// GET /api/orders/states?all=true - open orders' lifecycle states, and finished ones with all
+	mux.HandleFunc("/api/orders/states", h.handleStates)
+
+	// GET/POST /api/orders/remainders - read or update the partial fill remainder policy
+	mux.HandleFunc("/api/orders/remainders", h.handleRemainders)
+
+	// GET /api/orders/{id} - one order's lifecycle state, fills and transitions
+	mux.HandleFunc("/api/orders/", h.handleOrder)
+}
+
+func (h *Handler) handleWorking(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"working": h.manager.Working(),
+		"history": h.manager.History(),
+	})
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.manager.Policy())
+	case http.MethodPost, http.MethodPut:
+		if !users.FromContext(r.Context()).IsAdmin() {
+			http.Error(w, users.ErrForbidden.Error(), http.StatusForbidden)
+			return
+		}
+		// Start from the current policy so partial updates work
+		policy := h.manager.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.manager.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(policy)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleStates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(h.book.List(r.URL.Query().Get("all") == "true"))
+}
+
+func (h *Handler) handleRemainders(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.book.Policy())
+	case http.MethodPost, http.MethodPut:
+		policy := h.book.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.book.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(policy)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleOrder answers from the book, or from the broker for orders the
+// book has not seen an update for.
+func (h *Handler) handleOrder(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/orders/"), "/")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "Order ID required", http.StatusBadRequest)
+		return
+	}
+	if state, ok := h.book.Get(id); ok {
+		json.NewEncoder(w).Encode(state)
+		return
+	}
+	order, err := h.book.broker.GetOrder(id)
+	if err != nil || order == nil {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(StateFromOrder(order))
+}
@@ -0,0 +1,507 @@
+package orders
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/rileyseaburg/go-trader/algorithm"
+	"github.com/shopspring/decimal"
+)
+
+// Order lifecycle states. An order starts new, may be partially filled
+// any number of times, and ends filled, canceled, expired, rejected or
+// replaced.
+const (
+	LifecycleNew             = "new"
+	LifecyclePartiallyFilled = "partially_filled"
+	LifecycleFilled          = "filled"
+	LifecycleCanceled        = "canceled"
+	LifecycleExpired         = "expired"
+	LifecycleRejected        = "rejected"
+	LifecycleReplaced        = "replaced"
+)
+
+// maxOrderStates bounds the order states kept in memory.
+const maxOrderStates = 1000
+
+// ErrInvalidTransition is returned for an event the order's state does
+// not allow, such as a fill after a cancel.
+var ErrInvalidTransition = errors.New("invalid order state transition")
+
+// transitions lists the states each live state may move to.
+var transitions = map[string][]string{
+	LifecycleNew:             {LifecyclePartiallyFilled, LifecycleFilled, LifecycleCanceled, LifecycleExpired, LifecycleRejected, LifecycleReplaced},
+	LifecyclePartiallyFilled: {LifecyclePartiallyFilled, LifecycleFilled, LifecycleCanceled, LifecycleExpired, LifecycleReplaced},
+}
+
+// EventState maps a trade-update event to the state it moves an order to,
+// empty for events that change nothing, such as pending_cancel.
+func EventState(event string) string {
+	switch strings.ToLower(event) {
+	case "partial_fill":
+		return LifecyclePartiallyFilled
+	case "fill":
+		return LifecycleFilled
+	case "canceled":
+		return LifecycleCanceled
+	case "expired", "done_for_day":
+		return LifecycleExpired
+	case "rejected":
+		return LifecycleRejected
+	case "replaced":
+		return LifecycleReplaced
+	}
+	return ""
+}
+
+// Next returns the state an order in state moves to on event. Events that
+// change nothing return state unchanged.
+func Next(state, event string) (string, error) {
+	to := EventState(event)
+	if to == "" {
+		return state, nil
+	}
+	for _, allowed := range transitions[state] {
+		if allowed == to {
+			return to, nil
+		}
+	}
+	return state, fmt.Errorf("%w: %s on %s", ErrInvalidTransition, event, state)
+}
+
+// Terminal reports whether state is final.
+func Terminal(state string) bool {
+	_, live := transitions[state]
+	return !live
+}
+
+// Fill is one execution against an order.
+type Fill struct {
+	ID    string    `json:"id,omitempty"`
+	Qty   float64   `json:"qty"`
+	Price float64   `json:"price"`
+	At    time.Time `json:"at"`
+}
+
+// Transition is one change of state.
+type Transition struct {
+	From  string    `json:"from"`
+	To    string    `json:"to"`
+	Event string    `json:"event"`
+	At    time.Time `json:"at"`
+}
+
+// OrderState is an order's lifecycle as the trade-updates stream reports
+// it. FilledQty and AvgFillPrice count only executions, so a partially
+// filled order contributes exactly what it filled to a position.
+type OrderState struct {
+	ID            string       `json:"id"`
+	ClientOrderID string       `json:"client_order_id,omitempty"`
+	Symbol        string       `json:"symbol"`
+	Side          string       `json:"side"`
+	Type          string       `json:"type"`
+	TimeInForce   string       `json:"time_in_force,omitempty"`
+	Execution     string       `json:"execution,omitempty"`
+	Tag           string       `json:"tag,omitempty"`
+	Qty           float64      `json:"qty"`
+	LimitPrice    float64      `json:"limit_price,omitempty"`
+	StopPrice     float64      `json:"stop_price,omitempty"`
+	FilledQty     float64      `json:"filled_qty"`
+	AvgFillPrice  float64      `json:"avg_fill_price,omitempty"`
+	State         string       `json:"state"`
+	Fills         []Fill       `json:"fills"`
+	Transitions   []Transition `json:"transitions"`
+	// Managed orders were placed by this process and may have their
+	// remainder resubmitted
+	Managed       bool      `json:"managed"`
+	Replaces      string    `json:"replaces,omitempty"`
+	ReplacedBy    string    `json:"replaced_by,omitempty"`
+	ResubmitOf    string    `json:"resubmit_of,omitempty"` // the order whose remainder this is
+	ResubmittedAs string    `json:"resubmitted_as,omitempty"`
+	Resubmits     int       `json:"resubmits"` // remainders before this one in the chain
+	Note          string    `json:"note,omitempty"`
+	SubmittedAt   time.Time `json:"submitted_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Remaining is the quantity not yet filled.
+func (s OrderState) Remaining() float64 {
+	return math.Max(s.Qty-s.FilledQty, 0)
+}
+
+// RemainderPolicy decides whether the unfilled part of a partly filled
+// order the broker ended is placed again.
+type RemainderPolicy struct {
+	Resubmit     bool    `json:"resubmit"`
+	MaxResubmits int     `json:"max_resubmits"` // per original order
+	MinNotional  float64 `json:"min_notional"`  // smallest remainder worth placing, in dollars
+}
+
+// DefaultRemainderPolicy places a remainder worth at least $100 once.
+func DefaultRemainderPolicy() RemainderPolicy {
+	return RemainderPolicy{Resubmit: true, MaxResubmits: 1, MinNotional: 100}
+}
+
+// Validate checks the policy for usable values.
+func (p RemainderPolicy) Validate() error {
+	if p.MaxResubmits < 0 {
+		return errors.New("max_resubmits must not be negative")
+	}
+	if p.MinNotional < 0 {
+		return errors.New("min_notional must not be negative")
+	}
+	return nil
+}
+
+// DecideRemainder reports whether to place the remainder of s at price
+// and why. Only managed orders that expired partly filled qualify: a
+// cancel was asked for by someone, and an order that filled nothing is
+// left to lapse with its signal. Pure.
+func DecideRemainder(p RemainderPolicy, s OrderState, price float64) (bool, string) {
+	remaining := s.Remaining()
+	switch {
+	case s.State != LifecycleExpired:
+		return false, fmt.Sprintf("order %s, not expired", s.State)
+	case s.FilledQty <= 0:
+		return false, "nothing filled"
+	case remaining <= 0:
+		return false, "nothing remaining"
+	case !s.Managed:
+		return false, "not placed by go-trader"
+	case !p.Resubmit:
+		return false, "remainder resubmission is off"
+	case s.Resubmits >= p.MaxResubmits:
+		return false, fmt.Sprintf("already resubmitted %d times", s.Resubmits)
+	case price <= 0:
+		return false, "no price for the remainder"
+	case remaining*price < p.MinNotional:
+		return false, fmt.Sprintf("remainder of $%.2f is under the $%.2f minimum", remaining*price, p.MinNotional)
+	}
+	return true, fmt.Sprintf("resubmitting %g unfilled", remaining)
+}
+
+// Book follows orders through their lifecycle from trade updates and
+// places the remainder of partly filled orders as its policy allows. It
+// is safe for concurrent use.
+type Book struct {
+	broker Broker
+	quotes QuoteSource
+
+	mu     sync.RWMutex
+	policy RemainderPolicy
+	orders map[string]*OrderState
+	placed func(prev OrderState, order *alpaca.Order)
+}
+
+// NewBook returns a book that prices remainders from quotes.
+func NewBook(broker Broker, quotes QuoteSource, policy RemainderPolicy) *Book {
+	return &Book{broker: broker, quotes: quotes, policy: policy, orders: make(map[string]*OrderState)}
+}
+
+// Policy returns the remainder policy.
+func (b *Book) Policy() RemainderPolicy {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.policy
+}
+
+// SetPolicy validates and replaces the remainder policy.
+func (b *Book) SetPolicy(p RemainderPolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.policy = p
+	return nil
+}
+
+// SetOrderHandler registers fn to receive resubmitted remainders along
+// with the state of the order they continue.
+func (b *Book) SetOrderHandler(fn func(prev OrderState, order *alpaca.Order)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.placed = fn
+}
+
+// Watch records order, placed by this process with the given execution
+// strategy, as managed. Call it right after placing.
+func (b *Book) Watch(order *alpaca.Order, execution string) {
+	if order == nil || order.ID == "" {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := b.stateLocked(order)
+	s.Managed = true
+	s.Execution = execution
+}
+
+// Get returns the state of the order with id, which may also be a client
+// order ID.
+func (b *Book) Get(id string) (OrderState, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if s, ok := b.orders[id]; ok {
+		return copyState(s), true
+	}
+	for _, s := range b.orders {
+		if s.ClientOrderID == id {
+			return copyState(s), true
+		}
+	}
+	return OrderState{}, false
+}
+
+// List returns the orders still open and, with all, finished ones too,
+// newest first.
+func (b *Book) List(all bool) []OrderState {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	out := []OrderState{}
+	for _, s := range b.orders {
+		if all || !Terminal(s.State) {
+			out = append(out, copyState(s))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].SubmittedAt.After(out[j].SubmittedAt) })
+	return out
+}
+
+// Apply moves the update's order through its lifecycle. Events the order's
+// state does not allow are logged and dropped.
+func (b *Book) Apply(u alpaca.TradeUpdate) {
+	at := u.At
+	if u.Timestamp != nil {
+		at = *u.Timestamp
+	}
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	b.mu.Lock()
+	s := b.stateLocked(&u.Order)
+	next, err := Next(s.State, u.Event)
+	if err != nil {
+		b.mu.Unlock()
+		logger().Warn("Dropping order update", "order_id", s.ID, "event", u.Event, "error", err)
+		return
+	}
+	if next == LifecyclePartiallyFilled || next == LifecycleFilled {
+		s.addFill(u, at)
+	}
+	if next != s.State {
+		s.Transitions = append(s.Transitions, Transition{From: s.State, To: next, Event: u.Event, At: at})
+		s.State = next
+	}
+	s.UpdatedAt = at
+	if next == LifecycleReplaced && u.Order.ReplacedBy != nil {
+		s.ReplacedBy = *u.Order.ReplacedBy
+	}
+	state := copyState(s)
+	b.pruneLocked()
+	b.mu.Unlock()
+
+	if state.State == LifecycleExpired {
+		b.remainder(state)
+	}
+}
+
+// addFill records the execution in u once and folds it into the filled
+// quantity and average price. The broker's cumulative figures win when
+// executions were missed.
+func (s *OrderState) addFill(u alpaca.TradeUpdate, at time.Time) {
+	if u.ExecutionID != "" {
+		for _, f := range s.Fills {
+			if f.ID == u.ExecutionID {
+				return
+			}
+		}
+	}
+	if u.Qty != nil && u.Price != nil {
+		qty, _ := u.Qty.Float64()
+		price, _ := u.Price.Float64()
+		if qty > 0 {
+			s.Fills = append(s.Fills, Fill{ID: u.ExecutionID, Qty: qty, Price: price, At: at})
+			value := s.FilledQty*s.AvgFillPrice + qty*price
+			s.FilledQty += qty
+			s.AvgFillPrice = value / s.FilledQty
+		}
+	}
+	if total, _ := u.Order.FilledQty.Float64(); total > s.FilledQty+1e-9 {
+		s.FilledQty = total
+		if u.Order.FilledAvgPrice != nil {
+			s.AvgFillPrice, _ = u.Order.FilledAvgPrice.Float64()
+		}
+	}
+}
+
+// remainder places what s left unfilled when the policy allows it.
+func (b *Book) remainder(s OrderState) {
+	price := s.LimitPrice
+	if price <= 0 && b.quotes != nil {
+		if bid, ask, ok := b.quotes(s.Symbol); ok && bid > 0 && ask > 0 {
+			price = (bid + ask) / 2
+		}
+	}
+	ok, why := DecideRemainder(b.Policy(), s, price)
+	if !ok {
+		b.note(s.ID, why)
+		return
+	}
+
+	qty := decimal.NewFromFloat(s.Remaining()).Round(6)
+	req := alpaca.PlaceOrderRequest{
+		Symbol:        s.Symbol,
+		Qty:           &qty,
+		Side:          alpaca.Side(s.Side),
+		Type:          alpaca.OrderType(s.Type),
+		TimeInForce:   alpaca.TimeInForce(s.TimeInForce),
+		ClientOrderID: algorithm.NewClientOrderID(s.Tag),
+	}
+	if req.TimeInForce == "" {
+		req.TimeInForce = alpaca.Day
+	}
+	// A stop that filled in part has triggered; its remainder goes out as
+	// the order it became
+	switch req.Type {
+	case alpaca.Stop:
+		req.Type = alpaca.Market
+	case alpaca.StopLimit:
+		req.Type = alpaca.Limit
+	}
+	if s.LimitPrice > 0 {
+		limit := decimal.NewFromFloat(s.LimitPrice).Round(2)
+		req.LimitPrice = &limit
+	}
+	order, err := b.broker.PlaceOrder(req)
+	if err != nil {
+		logger().Error("Failed to resubmit order remainder", "order_id", s.ID, "symbol", s.Symbol, "qty", qty, "error", err)
+		b.note(s.ID, "resubmission failed: "+err.Error())
+		return
+	}
+	logger().Info("Resubmitted order remainder", "order_id", s.ID, "new_order_id", order.ID, "symbol", s.Symbol, "qty", qty)
+
+	b.mu.Lock()
+	if prev, ok := b.orders[s.ID]; ok {
+		prev.ResubmittedAs = order.ID
+		prev.Note = why
+	}
+	next := b.stateLocked(order)
+	next.Managed = true
+	next.Execution = s.Execution
+	next.ResubmitOf = s.ID
+	next.Resubmits = s.Resubmits + 1
+	placed := b.placed
+	b.mu.Unlock()
+	if placed != nil {
+		placed(s, order)
+	}
+}
+
+func (b *Book) note(id, note string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s, ok := b.orders[id]; ok {
+		s.Note = note
+	}
+}
+
+// stateLocked returns the state for order, starting one if it is new. A
+// replacement inherits what the order it replaces was managed with.
+func (b *Book) stateLocked(order *alpaca.Order) *OrderState {
+	if s, ok := b.orders[order.ID]; ok {
+		return s
+	}
+	s := newState(order)
+	if prev, ok := b.orders[s.Replaces]; ok && s.Replaces != "" {
+		s.Managed, s.Execution, s.Resubmits = prev.Managed, prev.Execution, prev.Resubmits
+	}
+	b.orders[order.ID] = s
+	return s
+}
+
+// newState starts a new order's lifecycle.
+func newState(order *alpaca.Order) *OrderState {
+	s := &OrderState{
+		ID:            order.ID,
+		ClientOrderID: order.ClientOrderID,
+		Symbol:        strings.ToUpper(order.Symbol),
+		Side:          string(order.Side),
+		Type:          string(order.Type),
+		TimeInForce:   string(order.TimeInForce),
+		Tag:           algorithm.TagFromClientOrderID(order.ClientOrderID),
+		State:         LifecycleNew,
+		Fills:         []Fill{},
+		Transitions:   []Transition{},
+		SubmittedAt:   order.SubmittedAt,
+		UpdatedAt:     order.UpdatedAt,
+	}
+	if order.Qty != nil {
+		s.Qty, _ = order.Qty.Float64()
+	}
+	if order.LimitPrice != nil {
+		s.LimitPrice, _ = order.LimitPrice.Float64()
+	}
+	if order.StopPrice != nil {
+		s.StopPrice, _ = order.StopPrice.Float64()
+	}
+	if order.Replaces != nil {
+		s.Replaces = *order.Replaces
+	}
+	if s.SubmittedAt.IsZero() {
+		s.SubmittedAt = time.Now()
+	}
+	return s
+}
+
+// pruneLocked drops the oldest finished orders beyond maxOrderStates.
+func (b *Book) pruneLocked() {
+	if len(b.orders) <= maxOrderStates {
+		return
+	}
+	finished := make([]*OrderState, 0, len(b.orders))
+	for _, s := range b.orders {
+		if Terminal(s.State) {
+			finished = append(finished, s)
+		}
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].UpdatedAt.Before(finished[j].UpdatedAt) })
+	for _, s := range finished[:min(len(finished), len(b.orders)-maxOrderStates)] {
+		delete(b.orders, s.ID)
+	}
+}
+
+// StateFromOrder derives a state from a broker snapshot, for orders the
+// book never saw updates for. It has no individual fills.
+func StateFromOrder(order *alpaca.Order) OrderState {
+	s := newState(order)
+	s.FilledQty, _ = order.FilledQty.Float64()
+	if order.FilledAvgPrice != nil {
+		s.AvgFillPrice, _ = order.FilledAvgPrice.Float64()
+	}
+	switch status := strings.ToLower(order.Status); status {
+	case "filled":
+		s.State = LifecycleFilled
+	case "partially_filled":
+		s.State = LifecyclePartiallyFilled
+	default:
+		if to := EventState(status); to != "" {
+			s.State = to
+		}
+	}
+	return copyState(s)
+}
+
+func copyState(s *OrderState) OrderState {
+	out := *s
+	out.Fills = append([]Fill{}, s.Fills...)
+	out.Transitions = append([]Transition{}, s.Transitions...)
+	return out
+}
@@ -0,0 +1,124 @@
+package orders
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
+)
+
+func TestNext(t *testing.T) {
+	cases := []struct {
+		state, event, want string
+		ok                 bool
+	}{
+		{LifecycleNew, "partial_fill", LifecyclePartiallyFilled, true},
+		{LifecyclePartiallyFilled, "partial_fill", LifecyclePartiallyFilled, true},
+		{LifecyclePartiallyFilled, "fill", LifecycleFilled, true},
+		{LifecyclePartiallyFilled, "expired", LifecycleExpired, true},
+		{LifecycleNew, "pending_new", LifecycleNew, true},
+		{LifecycleCanceled, "fill", LifecycleCanceled, false},
+		{LifecycleFilled, "canceled", LifecycleFilled, false},
+	}
+	for _, c := range cases {
+		got, err := Next(c.state, c.event)
+		if (err == nil) != c.ok || got != c.want {
+			t.Errorf("%s on %s: got %q, %v", c.event, c.state, got, err)
+		}
+	}
+}
+
+func update(order *alpaca.Order, event, execID string, qty, price float64, filled float64) alpaca.TradeUpdate {
+	order.FilledQty = decimal.NewFromFloat(filled)
+	u := alpaca.TradeUpdate{Event: event, ExecutionID: execID, Order: *order, At: time.Now()}
+	if qty > 0 {
+		q, p := decimal.NewFromFloat(qty), decimal.NewFromFloat(price)
+		u.Qty, u.Price = &q, &p
+	}
+	return u
+}
+
+func TestBookResubmitsExpiredRemainder(t *testing.T) {
+	qty, limit := decimal.NewFromInt(10), decimal.NewFromFloat(100)
+	order := &alpaca.Order{ID: "o1", Symbol: "aapl", Side: alpaca.Buy, Type: alpaca.Limit, TimeInForce: alpaca.Day,
+		Qty: &qty, LimitPrice: &limit, SubmittedAt: time.Now()}
+	broker := &fakeBroker{orders: map[string]*alpaca.Order{"o1": order}}
+	b := NewBook(broker, nil, DefaultRemainderPolicy())
+	var prev OrderState
+	b.SetOrderHandler(func(p OrderState, _ *alpaca.Order) { prev = p })
+	b.Watch(order, "passive")
+
+	b.Apply(update(order, "partial_fill", "e1", 3, 99.9, 3))
+	b.Apply(update(order, "partial_fill", "e1", 3, 99.9, 3)) // redelivered
+	b.Apply(update(order, "partial_fill", "e2", 1, 100, 4))
+	s, ok := b.Get("o1")
+	if !ok || s.State != LifecyclePartiallyFilled || s.FilledQty != 4 || len(s.Fills) != 2 {
+		t.Fatalf("state = %+v", s)
+	}
+	if s.AvgFillPrice < 99.924 || s.AvgFillPrice > 99.926 {
+		t.Errorf("average fill price = %v, want 99.925", s.AvgFillPrice)
+	}
+
+	b.Apply(update(order, "expired", "", 0, 0, 4))
+	if len(broker.placed) != 1 || !broker.placed[0].Qty.Equal(decimal.NewFromInt(6)) || !broker.placed[0].LimitPrice.Equal(limit) {
+		t.Fatalf("placed = %+v", broker.placed)
+	}
+	if prev.ID != "o1" || prev.FilledQty != 4 || prev.Execution != "passive" {
+		t.Errorf("handler saw %+v", prev)
+	}
+	if s, _ := b.Get("o1"); s.State != LifecycleExpired || s.ResubmittedAs != "m1" || len(s.Transitions) != 2 {
+		t.Errorf("expired state = %+v", s)
+	}
+	next, ok := b.Get("m1")
+	if !ok || !next.Managed || next.ResubmitOf != "o1" || next.Resubmits != 1 {
+		t.Fatalf("remainder state = %+v", next)
+	}
+
+	// A fill after the order ended is dropped
+	b.Apply(update(order, "fill", "e3", 6, 100, 10))
+	if s, _ := b.Get("o1"); s.FilledQty != 4 {
+		t.Errorf("fill applied after expiry: %+v", s)
+	}
+}
+
+func TestBookLeavesCanceledAndUnmanagedOrders(t *testing.T) {
+	qty, limit := decimal.NewFromInt(10), decimal.NewFromFloat(100)
+	canceled := &alpaca.Order{ID: "c1", Symbol: "AAPL", Side: alpaca.Buy, Type: alpaca.Limit, Qty: &qty, LimitPrice: &limit}
+	manual := &alpaca.Order{ID: "x1", Symbol: "AAPL", Side: alpaca.Buy, Type: alpaca.Limit, Qty: &qty, LimitPrice: &limit}
+	broker := &fakeBroker{orders: map[string]*alpaca.Order{}}
+	b := NewBook(broker, nil, DefaultRemainderPolicy())
+	b.Watch(canceled, "")
+
+	b.Apply(update(canceled, "partial_fill", "e1", 5, 100, 5))
+	b.Apply(update(canceled, "canceled", "", 0, 0, 5))
+	b.Apply(update(manual, "partial_fill", "e2", 5, 100, 5))
+	b.Apply(update(manual, "expired", "", 0, 0, 5))
+	if len(broker.placed) != 0 {
+		t.Fatalf("placed = %+v", broker.placed)
+	}
+	if s, _ := b.Get("x1"); s.Note != "not placed by go-trader" {
+		t.Errorf("note = %q", s.Note)
+	}
+	if got := b.List(false); len(got) != 0 {
+		t.Errorf("open orders = %+v", got)
+	}
+}
+
+func TestDecideRemainder(t *testing.T) {
+	p := DefaultRemainderPolicy()
+	s := OrderState{State: LifecycleExpired, Managed: true, Qty: 10, FilledQty: 9}
+	if ok, why := DecideRemainder(p, s, 50); ok {
+		t.Errorf("placed a $50 remainder: %s", why)
+	}
+	if ok, why := DecideRemainder(p, s, 150); !ok {
+		t.Errorf("refused a $150 remainder: %s", why)
+	}
+	s.Resubmits = 1
+	if ok, _ := DecideRemainder(p, s, 150); ok {
+		t.Error("resubmitted past max_resubmits")
+	}
+	if err := (RemainderPolicy{MaxResubmits: -1}).Validate(); err == nil {
+		t.Error("expected an error for negative max_resubmits")
+	}
+}
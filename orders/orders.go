@@ -0,0 +1,443 @@
+// Package orders manages working limit orders after submission. Orders
+// with a chase execution strategy are repriced toward the market while
+// they sit unfilled, optionally no further than a band around the session
+// VWAP, and aggressive ones are converted to market orders once they have
+// waited long enough.
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/rileyseaburg/go-trader/algorithm"
+	"github.com/shopspring/decimal"
+)
+
+func logger() *slog.Logger { return slog.With("module", "orders") }
+
+// Actions Decide can return.
+const (
+	ActionWait    = "wait"
+	ActionReprice = "reprice"
+	ActionMarket  = "market"
+)
+
+// Working order states.
+const (
+	StateWorking   = "working"
+	StateFilled    = "filled"
+	StateConverted = "converted" // replaced by a market order
+	StateClosed    = "closed"    // canceled, expired or rejected at the broker
+)
+
+// maxHistory bounds the finished orders kept in memory.
+const maxHistory = 200
+
+// Policy configures how chase and aggressive orders are worked.
+type Policy struct {
+	RepriceAfterSeconds int     `json:"reprice_after_seconds"` // unfilled time before each reprice
+	StepPercent         float64 `json:"step_percent"`          // reprice step, percent of the original limit
+	MaxChasePercent     float64 `json:"max_chase_percent"`     // furthest the limit may move from the original
+	MarketAfterSeconds  int     `json:"market_after_seconds"`  // aggressive orders go to market after this long
+	// VWAPCapBps stops a chase this far past the session VWAP: buys are
+	// not repriced above VWAP plus the cap nor sells below VWAP minus it.
+	// Zero disables the cap.
+	VWAPCapBps float64 `json:"vwap_cap_bps"`
+}
+
+// DefaultPolicy reprices every 15 seconds in 0.1% steps, at most 1% from
+// the original limit, and sends aggressive orders to market after two
+// minutes.
+func DefaultPolicy() Policy {
+	return Policy{
+		RepriceAfterSeconds: 15,
+		StepPercent:         0.1,
+		MaxChasePercent:     1.0,
+		MarketAfterSeconds:  120,
+	}
+}
+
+// Validate checks the policy for usable values.
+func (p Policy) Validate() error {
+	if p.RepriceAfterSeconds < 1 {
+		return errors.New("reprice_after_seconds must be at least 1")
+	}
+	if p.StepPercent <= 0 {
+		return errors.New("step_percent must be positive")
+	}
+	if p.MaxChasePercent < 0 {
+		return errors.New("max_chase_percent must not be negative")
+	}
+	if p.MarketAfterSeconds < 1 {
+		return errors.New("market_after_seconds must be at least 1")
+	}
+	if p.VWAPCapBps < 0 {
+		return errors.New("vwap_cap_bps must not be negative")
+	}
+	return nil
+}
+
+// Working is a limit order under management. ID follows the order through
+// replacements.
+type Working struct {
+	ID            string    `json:"id"`
+	Symbol        string    `json:"symbol"`
+	Side          string    `json:"side"`
+	Execution     string    `json:"execution"`
+	Tag           string    `json:"tag,omitempty"` // strategy tag from the client order ID
+	Qty           float64   `json:"qty"`
+	OriginalLimit float64   `json:"original_limit"`
+	LimitPrice    float64   `json:"limit_price"`
+	Reprices      int       `json:"reprices"`
+	State         string    `json:"state"`
+	SubmittedAt   time.Time `json:"submitted_at"`
+	LastActionAt  time.Time `json:"last_action_at"`
+	FinishedAt    time.Time `json:"finished_at,omitempty"`
+}
+
+// Decision is what Decide wants done with a working order.
+type Decision struct {
+	Action     string  `json:"action"`
+	LimitPrice float64 `json:"limit_price,omitempty"` // for ActionReprice
+}
+
+// Decide works out the next step for w at now against the current bid and
+// ask and the session VWAP, zero when unknown. Pure; Manager carries
+// decisions out. A chase reprices one step toward the far side of the
+// spread each time RepriceAfterSeconds passes unfilled, never past
+// MaxChasePercent from the original limit or VWAPCapBps past the VWAP,
+// then rests. An aggressive order chases the same way and goes to market
+// once MarketAfterSeconds have passed since submission.
+func Decide(p Policy, w Working, bid, ask, vwap float64, now time.Time) Decision {
+	wait := Decision{Action: ActionWait}
+	if w.Execution != algorithm.ExecutionChase && w.Execution != algorithm.ExecutionAggressive {
+		return wait
+	}
+	if w.Execution == algorithm.ExecutionAggressive &&
+		now.Sub(w.SubmittedAt) >= time.Duration(p.MarketAfterSeconds)*time.Second {
+		return Decision{Action: ActionMarket}
+	}
+	if now.Sub(w.LastActionAt) < time.Duration(p.RepriceAfterSeconds)*time.Second {
+		return wait
+	}
+
+	// At least a cent, or rounding could leave the price where it is
+	step := math.Max(w.OriginalLimit*p.StepPercent/100, 0.01)
+	reach := w.OriginalLimit * p.MaxChasePercent / 100
+	var next float64
+	switch w.Side {
+	case string(alpaca.Buy):
+		if ask <= 0 {
+			return wait
+		}
+		next = math.Min(math.Min(w.LimitPrice+step, ask), w.OriginalLimit+reach)
+		if p.VWAPCapBps > 0 && vwap > 0 {
+			next = math.Min(next, vwap*(1+p.VWAPCapBps/10000))
+		}
+		next = math.Floor(next*100+1e-9) / 100
+		if next <= w.LimitPrice {
+			return wait
+		}
+	case string(alpaca.Sell):
+		if bid <= 0 {
+			return wait
+		}
+		next = math.Max(math.Max(w.LimitPrice-step, bid), w.OriginalLimit-reach)
+		if p.VWAPCapBps > 0 && vwap > 0 {
+			next = math.Max(next, vwap*(1-p.VWAPCapBps/10000))
+		}
+		next = math.Ceil(next*100-1e-9) / 100
+		if next >= w.LimitPrice {
+			return wait
+		}
+	default:
+		return wait
+	}
+	return Decision{Action: ActionReprice, LimitPrice: next}
+}
+
+// Broker is the part of the Alpaca client order management needs.
+type Broker interface {
+	GetOrder(orderID string) (*alpaca.Order, error)
+	ReplaceOrder(orderID string, req alpaca.ReplaceOrderRequest) (*alpaca.Order, error)
+	CancelOrder(orderID string) error
+	PlaceOrder(req alpaca.PlaceOrderRequest) (*alpaca.Order, error)
+}
+
+// QuoteSource returns the latest bid and ask for symbol.
+type QuoteSource func(symbol string) (bid, ask float64, ok bool)
+
+// VWAPSource returns symbol's session VWAP.
+type VWAPSource func(symbol string) (float64, bool)
+
+// Manager works chase and aggressive limit orders until they finish.
+type Manager struct {
+	broker Broker
+	quotes QuoteSource
+	vwap   VWAPSource
+
+	mu      sync.RWMutex
+	policy  Policy
+	working map[string]*Working // by current order ID
+	history []Working
+	placed  func(order *alpaca.Order, execution string)
+}
+
+// NewManager returns a manager with the given policy.
+func NewManager(broker Broker, quotes QuoteSource, policy Policy) *Manager {
+	return &Manager{
+		broker:  broker,
+		quotes:  quotes,
+		policy:  policy,
+		working: make(map[string]*Working),
+	}
+}
+
+// Policy returns the current policy.
+func (m *Manager) Policy() Policy {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.policy
+}
+
+// SetPolicy validates and replaces the policy.
+func (m *Manager) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policy = p
+	return nil
+}
+
+// SetVWAP sets where the session VWAP for the chase cap comes from.
+func (m *Manager) SetVWAP(fn VWAPSource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.vwap = fn
+}
+
+// SetOrderHandler registers fn to receive the market orders placed when
+// aggressive orders are converted.
+func (m *Manager) SetOrderHandler(fn func(order *alpaca.Order, execution string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.placed = fn
+}
+
+// Track starts working order with the given execution strategy. Only
+// chase and aggressive limit orders are managed; anything else is left
+// alone.
+func (m *Manager) Track(order *alpaca.Order, execution string) error {
+	execution, err := algorithm.NormalizeExecution(execution)
+	if err != nil {
+		return err
+	}
+	if order == nil || order.Type != alpaca.Limit ||
+		(execution != algorithm.ExecutionChase && execution != algorithm.ExecutionAggressive) {
+		return nil
+	}
+	if order.LimitPrice == nil || order.Qty == nil {
+		return fmt.Errorf("order %s has no limit price or quantity", order.ID)
+	}
+	limit, _ := order.LimitPrice.Float64()
+	qty, _ := order.Qty.Float64()
+	submitted := order.SubmittedAt
+	if submitted.IsZero() {
+		submitted = time.Now()
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.working[order.ID] = &Working{
+		ID:            order.ID,
+		Symbol:        order.Symbol,
+		Side:          string(order.Side),
+		Execution:     execution,
+		Tag:           algorithm.TagFromClientOrderID(order.ClientOrderID),
+		Qty:           qty,
+		OriginalLimit: limit,
+		LimitPrice:    limit,
+		State:         StateWorking,
+		SubmittedAt:   submitted,
+		LastActionAt:  submitted,
+	}
+	logger().Info("Working limit order", "side", order.Side, "symbol", order.Symbol, "order_id", order.ID, "limit", limit, "execution", execution)
+	return nil
+}
+
+// Working lists orders under management, oldest first.
+func (m *Manager) Working() []Working {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]Working, 0, len(m.working))
+	for _, w := range m.working {
+		out = append(out, *w)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].SubmittedAt.Before(out[j].SubmittedAt) })
+	return out
+}
+
+// History returns finished orders, most recent first.
+func (m *Manager) History() []Working {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]Working, len(m.history))
+	for i, w := range m.history {
+		out[len(m.history)-1-i] = w
+	}
+	return out
+}
+
+// Run works orders every interval until ctx is cancelled.
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			m.Step(time.Now())
+		}
+	}
+}
+
+// Step checks every working order with the broker once and carries out
+// whatever Decide asks for.
+func (m *Manager) Step(now time.Time) {
+	m.mu.RLock()
+	policy, vwapOf := m.policy, m.vwap
+	working := make([]Working, 0, len(m.working))
+	for _, w := range m.working {
+		working = append(working, *w)
+	}
+	m.mu.RUnlock()
+
+	for _, w := range working {
+		order, err := m.broker.GetOrder(w.ID)
+		if err != nil {
+			logger().Error("Failed to read order", "order_id", w.ID, "error", err)
+			continue
+		}
+		if state, done := finalState(order.Status); done {
+			m.finish(w.ID, state, now)
+			continue
+		}
+
+		bid, ask, _ := m.quotes(w.Symbol)
+		var vwap float64
+		if vwapOf != nil {
+			vwap, _ = vwapOf(w.Symbol)
+		}
+		switch d := Decide(policy, w, bid, ask, vwap, now); d.Action {
+		case ActionReprice:
+			m.reprice(w, d.LimitPrice, now)
+		case ActionMarket:
+			m.toMarket(w, now)
+		}
+	}
+}
+
+// finalState maps a broker order status to a finished state.
+func finalState(status string) (string, bool) {
+	switch strings.ToLower(status) {
+	case "filled":
+		return StateFilled, true
+	case "canceled", "expired", "rejected", "done_for_day", "replaced", "stopped", "suspended":
+		return StateClosed, true
+	}
+	return "", false
+}
+
+func (m *Manager) reprice(w Working, price float64, now time.Time) {
+	limit := decimal.NewFromFloat(price).Round(2)
+	replaced, err := m.broker.ReplaceOrder(w.ID, alpaca.ReplaceOrderRequest{LimitPrice: &limit})
+	if err != nil {
+		logger().Error("Failed to reprice order", "symbol", w.Symbol, "order_id", w.ID, "limit", price, "error", err)
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cur, ok := m.working[w.ID]
+	if !ok {
+		return
+	}
+	delete(m.working, w.ID)
+	cur.ID = replaced.ID
+	cur.LimitPrice = price
+	cur.Reprices++
+	cur.LastActionAt = now
+	m.working[cur.ID] = cur
+	logger().Info("Repriced order", "side", w.Side, "symbol", w.Symbol, "from", w.LimitPrice, "to", price)
+}
+
+// toMarket cancels the limit order and sends whatever is unfilled as a
+// market order. The fill is re-read after the cancel so a late partial
+// fill is not doubled.
+func (m *Manager) toMarket(w Working, now time.Time) {
+	if err := m.broker.CancelOrder(w.ID); err != nil {
+		logger().Error("Failed to cancel order for market conversion", "symbol", w.Symbol, "order_id", w.ID, "error", err)
+		return
+	}
+	order, err := m.broker.GetOrder(w.ID)
+	if err != nil {
+		logger().Error("Failed to read order after cancel", "symbol", w.Symbol, "order_id", w.ID, "error", err)
+		m.finish(w.ID, StateClosed, now)
+		return
+	}
+	filled, _ := order.FilledQty.Float64()
+	remaining := decimal.NewFromFloat(w.Qty - filled).Round(6)
+	if !remaining.IsPositive() {
+		m.finish(w.ID, StateFilled, now)
+		return
+	}
+
+	market, err := m.broker.PlaceOrder(alpaca.PlaceOrderRequest{
+		Symbol:        w.Symbol,
+		Qty:           &remaining,
+		Side:          alpaca.Side(w.Side),
+		Type:          alpaca.Market,
+		TimeInForce:   alpaca.Day,
+		ClientOrderID: algorithm.NewClientOrderID(w.Tag),
+	})
+	if err != nil {
+		logger().Error("Failed to place market order after cancel", "symbol", w.Symbol, "order_id", w.ID, "error", err)
+		m.finish(w.ID, StateClosed, now)
+		return
+	}
+	logger().Info("Converted limit order to market", "side", w.Side, "symbol", w.Symbol, "order_id", w.ID, "market_order_id", market.ID, "qty", remaining)
+	m.finish(w.ID, StateConverted, now)
+	m.mu.RLock()
+	placed := m.placed
+	m.mu.RUnlock()
+	if placed != nil {
+		placed(market, w.Execution)
+	}
+}
+
+func (m *Manager) finish(id, state string, now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	w, ok := m.working[id]
+	if !ok {
+		return
+	}
+	delete(m.working, id)
+	w.State = state
+	w.FinishedAt = now
+	m.history = append(m.history, *w)
+	if len(m.history) > maxHistory {
+		m.history = m.history[len(m.history)-maxHistory:]
+	}
+}
@@ -0,0 +1,145 @@
+package orders
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/rileyseaburg/go-trader/users"
+	"github.com/shopspring/decimal"
+)
+
+func TestDecide(t *testing.T) {
+	p := DefaultPolicy()
+	start := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	buy := Working{Side: "buy", Execution: "chase", OriginalLimit: 100, LimitPrice: 100, SubmittedAt: start, LastActionAt: start}
+
+	cases := []struct {
+		name     string
+		w        Working
+		bid, ask float64
+		at       time.Duration
+		want     Decision
+	}{
+		{"too soon", buy, 100.2, 100.5, 10 * time.Second, Decision{Action: ActionWait}},
+		{"step toward ask", buy, 100.2, 100.5, 15 * time.Second, Decision{Action: ActionReprice, LimitPrice: 100.1}},
+		{"stop at the ask", buy, 100.02, 100.05, 20 * time.Second, Decision{Action: ActionReprice, LimitPrice: 100.05}},
+		{"max chase reached", func() Working { w := buy; w.LimitPrice = 101; return w }(), 102, 103, time.Minute, Decision{Action: ActionWait}},
+		{"no quote", buy, 0, 0, time.Minute, Decision{Action: ActionWait}},
+		{"passive", func() Working { w := buy; w.Execution = "passive"; return w }(), 100.2, 100.5, time.Hour, Decision{Action: ActionWait}},
+		{"sell steps toward bid", Working{Side: "sell", Execution: "chase", OriginalLimit: 50, LimitPrice: 50, SubmittedAt: start, LastActionAt: start},
+			49.5, 49.6, time.Minute, Decision{Action: ActionReprice, LimitPrice: 49.95}},
+		{"aggressive times out", func() Working { w := buy; w.Execution = "aggressive"; return w }(), 100.2, 100.5, 2 * time.Minute, Decision{Action: ActionMarket}},
+	}
+	for _, c := range cases {
+		if got := Decide(p, c.w, c.bid, c.ask, 0, start.Add(c.at)); got != c.want {
+			t.Errorf("%s: got %+v, want %+v", c.name, got, c.want)
+		}
+	}
+
+	// A 5 bps VWAP cap stops the buy at 100.05 however far the ask runs
+	p.VWAPCapBps = 5
+	w := buy
+	w.LimitPrice = 100.05
+	if got := Decide(p, w, 100.5, 100.6, 100, start.Add(time.Minute)); got.Action != ActionWait {
+		t.Errorf("vwap cap: got %+v, want wait", got)
+	}
+	w.LimitPrice = 100
+	if got := Decide(p, w, 100.5, 100.6, 100, start.Add(time.Minute)); got != (Decision{Action: ActionReprice, LimitPrice: 100.05}) {
+		t.Errorf("vwap cap step: got %+v", got)
+	}
+}
+
+type fakeBroker struct {
+	orders map[string]*alpaca.Order
+	placed []alpaca.PlaceOrderRequest
+	seq    int
+}
+
+func (b *fakeBroker) GetOrder(id string) (*alpaca.Order, error) {
+	o, ok := b.orders[id]
+	if !ok {
+		return nil, fmt.Errorf("no order %s", id)
+	}
+	return o, nil
+}
+
+func (b *fakeBroker) ReplaceOrder(id string, req alpaca.ReplaceOrderRequest) (*alpaca.Order, error) {
+	old := b.orders[id]
+	old.Status = "replaced"
+	b.seq++
+	o := *old
+	o.ID = fmt.Sprintf("r%d", b.seq)
+	o.Status = "new"
+	o.LimitPrice = req.LimitPrice
+	b.orders[o.ID] = &o
+	return &o, nil
+}
+
+func (b *fakeBroker) CancelOrder(id string) error {
+	b.orders[id].Status = "canceled"
+	return nil
+}
+
+func (b *fakeBroker) PlaceOrder(req alpaca.PlaceOrderRequest) (*alpaca.Order, error) {
+	b.placed = append(b.placed, req)
+	return &alpaca.Order{ID: "m1", Status: "new"}, nil
+}
+
+func TestManagerChasesThenGoesToMarket(t *testing.T) {
+	start := time.Now()
+	qty, limit := decimal.NewFromInt(10), decimal.NewFromFloat(100)
+	order := &alpaca.Order{ID: "o1", Symbol: "AAPL", Side: alpaca.Buy, Type: alpaca.Limit, Status: "new",
+		Qty: &qty, LimitPrice: &limit, SubmittedAt: start}
+	broker := &fakeBroker{orders: map[string]*alpaca.Order{"o1": order}}
+	m := NewManager(broker, func(string) (float64, float64, bool) { return 100.4, 100.5, true }, DefaultPolicy())
+
+	if err := m.Track(order, "sideways"); err == nil {
+		t.Error("tracked an unknown execution strategy")
+	}
+	if err := m.Track(order, "aggressive"); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Step(start.Add(15 * time.Second))
+	w := m.Working()
+	if len(w) != 1 || w[0].ID != "r1" || w[0].LimitPrice != 100.1 || w[0].Reprices != 1 {
+		t.Fatalf("after reprice: %+v", w)
+	}
+
+	// Half fills, then the timeout sends the rest to market
+	broker.orders["r1"].FilledQty = decimal.NewFromInt(4)
+	m.Step(start.Add(2 * time.Minute))
+	if len(m.Working()) != 0 || len(broker.placed) != 1 {
+		t.Fatalf("working = %+v, placed = %+v", m.Working(), broker.placed)
+	}
+	if req := broker.placed[0]; req.Type != alpaca.Market || !req.Qty.Equal(decimal.NewFromInt(6)) {
+		t.Errorf("market order = %+v", req)
+	}
+	if h := m.History(); len(h) != 1 || h[0].State != StateConverted {
+		t.Errorf("history = %+v", h)
+	}
+}
+
+func TestOnlyAdminsChangeExecutionPolicy(t *testing.T) {
+	m := NewManager(&fakeBroker{}, nil, DefaultPolicy())
+	mux := http.NewServeMux()
+	NewHandler(m, nil).RegisterRoutes(mux)
+
+	trader := users.WithUser(context.Background(), users.User{ID: "trader", Role: users.RoleUser})
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/orders/execution", strings.NewReader(`{}`)).WithContext(trader))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("user POST = %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/orders/execution", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusOK {
+		t.Errorf("admin POST = %d", rec.Code)
+	}
+}
@@ -0,0 +1,509 @@
+package orders
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/rileyseaburg/go-trader/algorithm"
+)
+
+// Submission states
+const (
+	SubmissionRetrying = "retrying" // waiting for its next attempt
+	SubmissionFailed   = "failed"   // out of attempts, awaiting manual resolution
+)
+
+// RetryPolicy bounds how order submissions that fail transiently — a
+// timeout, a dropped connection or a 5xx from Alpaca — are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the submissions made in all, the first included;
+	// 1 disables retrying
+	MaxAttempts int `json:"max_attempts"`
+	// BaseDelaySeconds is the wait before the first retry, doubled after
+	// each one up to MaxDelaySeconds
+	BaseDelaySeconds float64 `json:"base_delay_seconds"`
+	MaxDelaySeconds  float64 `json:"max_delay_seconds"`
+	// Jitter randomizes each wait by up to this fraction either way, so
+	// orders failed together do not retry together
+	Jitter float64 `json:"jitter"`
+	// MaxAgeSeconds fails an order still unplaced this long after it was
+	// first submitted, since the market it was sized for has moved on
+	MaxAgeSeconds float64 `json:"max_age_seconds"`
+}
+
+// DefaultRetryPolicy makes four attempts, 2, 4 and 8 seconds apart with
+// half of each wait randomized, within two minutes.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 4, BaseDelaySeconds: 2, MaxDelaySeconds: 30, Jitter: 0.5, MaxAgeSeconds: 120}
+}
+
+// Validate checks the policy for usable values.
+func (p RetryPolicy) Validate() error {
+	if p.MaxAttempts < 1 || p.MaxAttempts > 20 {
+		return errors.New("max_attempts must be between 1 and 20")
+	}
+	if p.BaseDelaySeconds <= 0 || p.MaxDelaySeconds < p.BaseDelaySeconds {
+		return errors.New("base_delay_seconds must be positive and at most max_delay_seconds")
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return errors.New("jitter must be between 0 and 1")
+	}
+	if p.MaxAgeSeconds <= 0 {
+		return errors.New("max_age_seconds must be positive")
+	}
+	return nil
+}
+
+// delay returns the wait after attempt n, before jitter.
+func (p RetryPolicy) delay(n int) time.Duration {
+	d := math.Min(p.BaseDelaySeconds*math.Pow(2, float64(n-1)), p.MaxDelaySeconds)
+	return time.Duration(d * float64(time.Second))
+}
+
+// httpStatus matches the status Alpaca errors without a JSON body end in.
+var httpStatus = regexp.MustCompile(`\(HTTP (\d{3})\)$`)
+
+// Transient reports whether err from placing an order may succeed when
+// retried: a timeout, a dropped or refused connection, a 429 or a 5xx.
+// Rejections such as insufficient buying power are not.
+func Transient(err error) bool {
+	if err == nil {
+		return false
+	}
+	var apiErr *alpaca.APIError
+	if errors.As(err, &apiErr) {
+		return transientStatus(apiErr.StatusCode)
+	}
+	if m := httpStatus.FindStringSubmatch(err.Error()); m != nil {
+		var status int
+		fmt.Sscan(m[1], &status)
+		return transientStatus(status)
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+func transientStatus(status int) bool {
+	return status >= 500 || status == http.StatusTooManyRequests || status == http.StatusRequestTimeout
+}
+
+// Submission is an order whose submission failed transiently, retrying or
+// out of attempts. Its client order ID stays the same on every attempt, so
+// Alpaca never takes it twice.
+type Submission struct {
+	ClientOrderID string                   `json:"client_order_id"`
+	Symbol        string                   `json:"symbol"`
+	Request       alpaca.PlaceOrderRequest `json:"request"`
+	State         string                   `json:"state"`
+	Attempts      int                      `json:"attempts"`
+	FirstAt       time.Time                `json:"first_at"`
+	LastAt        time.Time                `json:"last_at"`
+	NextAt        time.Time                `json:"next_at,omitempty"`
+	LastError     string                   `json:"last_error"`
+
+	placed   func(*alpaca.Order)
+	inFlight bool // an attempt is out; Step, Retry and Dismiss leave it alone
+}
+
+// Submitter is the part of the broker the retry queue places orders with.
+type Submitter interface {
+	PlaceOrder(req alpaca.PlaceOrderRequest) (*alpaca.Order, error)
+}
+
+// clientOrderLookup finds an order by its client order ID, to tell an
+// attempt that timed out after reaching the broker from one that did not.
+type clientOrderLookup interface {
+	GetOrderByClientOrderID(clientOrderID string) (*alpaca.Order, error)
+}
+
+// RetryQueue retries order submissions that failed transiently, with
+// bounded, jittered backoff, and keeps those that run out of attempts for
+// someone to retry or dismiss. Retrying and failed submissions are saved,
+// and after a restart all of them await manual resolution.
+type RetryQueue struct {
+	broker Submitter
+	path   string
+
+	mu     sync.Mutex
+	policy RetryPolicy
+	subs   map[string]*Submission // by client order ID
+	now    func() time.Time
+	jitter func() float64
+	notify func(title, message string, metadata map[string]interface{})
+	guard  func(*algorithm.TradeSignal) error
+}
+
+// NewRetryQueue returns a queue placing orders with broker and saving to
+// path, loading the submissions saved there.
+func NewRetryQueue(broker Submitter, path string, policy RetryPolicy) (*RetryQueue, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	q := &RetryQueue{
+		broker: broker,
+		path:   path,
+		policy: policy,
+		subs:   make(map[string]*Submission),
+		now:    time.Now,
+		jitter: rand.Float64,
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return q, nil
+		}
+		return nil, fmt.Errorf("failed to read order retries: %w", err)
+	}
+	var saved []*Submission
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, fmt.Errorf("failed to decode order retries: %w", err)
+	}
+	for _, s := range saved {
+		// Whoever was waiting on a retry is gone, and the order is stale
+		s.State = SubmissionFailed
+		s.NextAt = time.Time{}
+		q.subs[s.ClientOrderID] = s
+	}
+	return q, nil
+}
+
+// SetNotifier registers fn to be told when an order fails for good.
+func (q *RetryQueue) SetNotifier(fn func(title, message string, metadata map[string]interface{})) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.notify = fn
+}
+
+// SetGuard sets the check a held order must pass before each retry,
+// normally the algorithm's trade guards.
+func (q *RetryQueue) SetGuard(fn func(*algorithm.TradeSignal) error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.guard = fn
+}
+
+// SetClock replaces the clock retries are timed by.
+func (q *RetryQueue) SetClock(now func() time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.now = now
+}
+
+// Policy returns the retry policy.
+func (q *RetryQueue) Policy() RetryPolicy {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.policy
+}
+
+// SetPolicy replaces the retry policy.
+func (q *RetryQueue) SetPolicy(p RetryPolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.policy = p
+	return nil
+}
+
+// Submit places req. When the attempt fails transiently the order is
+// queued for retry and Submit reports it queued; placed receives the
+// order if a retry places it. Other failures are returned as they are.
+// Submit has the signature of algorithm.OrderSubmitter.
+func (q *RetryQueue) Submit(req alpaca.PlaceOrderRequest, placed func(*alpaca.Order)) (*alpaca.Order, bool, error) {
+	// Every attempt must carry the same client order ID, so an untagged
+	// order gets one now
+	if req.ClientOrderID == "" {
+		req.ClientOrderID = algorithm.NewClientOrderID(algorithm.TagManual)
+	}
+	order, err := q.broker.PlaceOrder(req)
+	if err == nil {
+		return order, false, nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !Transient(err) || q.policy.MaxAttempts <= 1 {
+		return nil, false, err
+	}
+	now := q.now()
+	s := &Submission{
+		ClientOrderID: req.ClientOrderID,
+		Symbol:        req.Symbol,
+		Request:       req,
+		State:         SubmissionRetrying,
+		Attempts:      1,
+		FirstAt:       now,
+		LastAt:        now,
+		LastError:     err.Error(),
+		placed:        placed,
+	}
+	s.NextAt = now.Add(q.waitLocked(1))
+	q.subs[s.ClientOrderID] = s
+	q.saveLogged()
+	logger().Warn("Order submission failed, retrying", "symbol", req.Symbol, "client_order_id", req.ClientOrderID,
+		"retry_at", s.NextAt, "error", err)
+	return nil, true, nil
+}
+
+// waitLocked returns the jittered wait after attempt n.
+func (q *RetryQueue) waitLocked(n int) time.Duration {
+	d := float64(q.policy.delay(n))
+	return time.Duration(d * (1 + q.policy.Jitter*(2*q.jitter()-1)))
+}
+
+// Run retries due submissions every interval until ctx is done.
+func (q *RetryQueue) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			q.Step(q.clock())
+		}
+	}
+}
+
+func (q *RetryQueue) clock() time.Time {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.now()
+}
+
+// Step retries the submissions due at now.
+func (q *RetryQueue) Step(now time.Time) {
+	q.mu.Lock()
+	var due []*Submission
+	for _, s := range q.subs {
+		if s.State == SubmissionRetrying && !s.inFlight && !now.Before(s.NextAt) {
+			s.inFlight = true
+			due = append(due, s)
+		}
+	}
+	q.mu.Unlock()
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAt.Before(due[j].NextAt) })
+	for _, s := range due {
+		q.attempt(s, now, false)
+	}
+}
+
+// attempt submits s, which the caller marked in flight, again, first
+// checking whether an earlier attempt reached the broker after all. A
+// manual attempt is made whatever the policy, and failing leaves a failed
+// order failed. An order the guard refuses fails for good.
+func (q *RetryQueue) attempt(s *Submission, now time.Time, manual bool) (*alpaca.Order, error) {
+	order, err := q.place(s.Request)
+
+	q.mu.Lock()
+	s.inFlight = false
+	s.Attempts++
+	s.LastAt = now
+	if err == nil {
+		delete(q.subs, s.ClientOrderID)
+		placed := s.placed
+		q.saveLogged()
+		q.mu.Unlock()
+		logger().Info("Order placed on retry", "symbol", s.Symbol, "client_order_id", s.ClientOrderID,
+			"order_id", order.ID, "attempts", s.Attempts)
+		if placed != nil {
+			placed(order)
+		}
+		return order, nil
+	}
+	s.LastError = err.Error()
+	age := now.Sub(s.FirstAt).Seconds()
+	if (!manual || s.State == SubmissionRetrying) && Transient(err) && s.Attempts < q.policy.MaxAttempts && age < q.policy.MaxAgeSeconds {
+		s.NextAt = now.Add(q.waitLocked(s.Attempts))
+		q.saveLogged()
+		q.mu.Unlock()
+		logger().Warn("Order retry failed", "symbol", s.Symbol, "client_order_id", s.ClientOrderID,
+			"attempts", s.Attempts, "retry_at", s.NextAt, "error", err)
+		return nil, err
+	}
+	wasFailed := s.State == SubmissionFailed
+	s.State = SubmissionFailed
+	s.NextAt = time.Time{}
+	q.saveLogged()
+	notify := q.notify
+	q.mu.Unlock()
+	if wasFailed {
+		return nil, err
+	}
+	logger().Error("Order submission failed for good", "symbol", s.Symbol, "client_order_id", s.ClientOrderID,
+		"attempts", s.Attempts, "error", err)
+	if notify != nil {
+		notify("Order failed: "+s.Symbol, fmt.Sprintf("%s %s %s was not placed after %d attempts: %s. Retry or dismiss it at /api/orders/failed.",
+			s.Request.Side, quantity(s.Request), s.Symbol, s.Attempts, s.LastError), map[string]interface{}{
+			"symbol":          s.Symbol,
+			"client_order_id": s.ClientOrderID,
+			"attempts":        s.Attempts,
+			"error":           s.LastError,
+		})
+	}
+	return nil, err
+}
+
+// place submits req unless an earlier attempt with its client order ID
+// already reached the broker, or the guard refuses it.
+func (q *RetryQueue) place(req alpaca.PlaceOrderRequest) (*alpaca.Order, error) {
+	if lookup, ok := q.broker.(clientOrderLookup); ok {
+		if order, err := lookup.GetOrderByClientOrderID(req.ClientOrderID); err == nil && order != nil {
+			return order, nil
+		}
+	}
+	q.mu.Lock()
+	guard, now := q.guard, q.now()
+	q.mu.Unlock()
+	if guard != nil {
+		if err := guard(retrySignal(req, now)); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrRefused, err)
+		}
+	}
+	return q.broker.PlaceOrder(req)
+}
+
+// retrySignal describes req as a signal for the trade guards.
+func retrySignal(req alpaca.PlaceOrderRequest, now time.Time) *algorithm.TradeSignal {
+	signal := &algorithm.TradeSignal{
+		Symbol:    req.Symbol,
+		Signal:    string(req.Side),
+		OrderType: string(req.Type),
+		Timestamp: now,
+		Source:    "retry",
+		Tag:       algorithm.TagFromClientOrderID(req.ClientOrderID),
+		Size:      &algorithm.TradeSize{},
+	}
+	if req.Qty != nil {
+		signal.Size.Qty = req.Qty.InexactFloat64()
+	} else if req.Notional != nil {
+		signal.Size.Notional = req.Notional.InexactFloat64()
+	}
+	if req.LimitPrice != nil {
+		limit := req.LimitPrice.InexactFloat64()
+		signal.LimitPrice = &limit
+	}
+	if req.StopPrice != nil {
+		stop := req.StopPrice.InexactFloat64()
+		signal.StopPrice = &stop
+	}
+	return signal
+}
+
+// quantity describes an order request's size.
+func quantity(req alpaca.PlaceOrderRequest) string {
+	if req.Qty != nil {
+		return req.Qty.String()
+	}
+	if req.Notional != nil {
+		return "$" + req.Notional.String() + " of"
+	}
+	return ""
+}
+
+// List returns the submissions awaiting a retry or manual resolution,
+// oldest first.
+func (q *RetryQueue) List() []Submission {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make([]Submission, 0, len(q.subs))
+	for _, s := range q.subs {
+		c := *s
+		c.placed = nil
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].FirstAt.Before(out[j].FirstAt) })
+	return out
+}
+
+var (
+	// ErrSubmissionNotFound is returned for a client order ID the queue
+	// does not hold.
+	ErrSubmissionNotFound = errors.New("no failed or retrying order with that client order ID")
+	// ErrSubmissionInFlight is returned for an order an attempt is out
+	// for.
+	ErrSubmissionInFlight = errors.New("the order is being submitted")
+	// ErrRefused wraps the guard's refusal of a retry.
+	ErrRefused = errors.New("order refused")
+)
+
+// Retry submits a held order again now, with the same client order ID.
+func (q *RetryQueue) Retry(clientOrderID string) (*alpaca.Order, error) {
+	q.mu.Lock()
+	s, ok := q.subs[clientOrderID]
+	if !ok {
+		q.mu.Unlock()
+		return nil, ErrSubmissionNotFound
+	}
+	if s.inFlight {
+		q.mu.Unlock()
+		return nil, ErrSubmissionInFlight
+	}
+	s.inFlight = true
+	now := q.now()
+	q.mu.Unlock()
+	return q.attempt(s, now, true)
+}
+
+// Dismiss drops a held order without placing it.
+func (q *RetryQueue) Dismiss(clientOrderID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	s, ok := q.subs[clientOrderID]
+	if !ok {
+		return ErrSubmissionNotFound
+	}
+	if s.inFlight {
+		return ErrSubmissionInFlight
+	}
+	delete(q.subs, clientOrderID)
+	q.saveLogged()
+	logger().Info("Failed order dismissed", "symbol", s.Symbol, "client_order_id", clientOrderID)
+	return nil
+}
+
+// saveLogged writes the held submissions to the queue's file, logging a
+// failure. Callers hold mu.
+func (q *RetryQueue) saveLogged() {
+	if q.path == "" {
+		return
+	}
+	subs := make([]*Submission, 0, len(q.subs))
+	for _, s := range q.subs {
+		subs = append(subs, s)
+	}
+	sort.Slice(subs, func(i, j int) bool { return subs[i].FirstAt.Before(subs[j].FirstAt) })
+	data, err := json.MarshalIndent(subs, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(q.path), 0755)
+	}
+	if err == nil {
+		tmp := q.path + ".tmp"
+		if err = os.WriteFile(tmp, data, 0644); err == nil {
+			err = os.Rename(tmp, q.path)
+		}
+	}
+	if err != nil {
+		logger().Error("Failed to save order retries", "error", err)
+	}
+}
@@ -0,0 +1,126 @@
+package orders
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/rileyseaburg/go-trader/paging"
+	"github.com/rileyseaburg/go-trader/users"
+)
+
+// RetryHandler exposes the order retry queue over HTTP.
+type RetryHandler struct {
+	queue *RetryQueue
+}
+
+// NewRetryHandler creates a handler for queue.
+func NewRetryHandler(queue *RetryQueue) *RetryHandler {
+	return &RetryHandler{queue: queue}
+}
+
+// RegisterRoutes registers the retry queue routes with mux.
+func (h *RetryHandler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/orders/failed?limit=&cursor=&sort=&filter= - orders awaiting a retry or manual resolution
+	// POST /api/orders/failed?id=&action=retry|dismiss - resolve one by its client order ID
+	mux.HandleFunc("/api/orders/failed", h.handleFailed)
+
+	// GET/POST /api/orders/retries - read or update the retry policy
+	mux.HandleFunc("/api/orders/retries", h.handlePolicy)
+}
+
+// submissionList pages the held orders, newest first by default.
+var submissionList = paging.List[Submission]{
+	Fields: []paging.Field[Submission]{
+		{Name: "first_at", Sort: func(s Submission) paging.Key { return paging.Time(s.FirstAt) }},
+		{Name: "last_at", Sort: func(s Submission) paging.Key { return paging.Time(s.LastAt) }},
+		{Name: "symbol", Sort: func(s Submission) paging.Key { return paging.Text(s.Symbol) }, Filter: func(s Submission) string { return s.Symbol }},
+		{Name: "state", Filter: func(s Submission) string { return s.State }},
+	},
+	ID:   func(s Submission) string { return s.ClientOrderID },
+	Sort: "-first_at",
+}
+
+func (h *RetryHandler) handleFailed(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		page, ok := submissionList.Page(w, r, h.queue.List())
+		if !ok {
+			return
+		}
+		paging.SetHeaders(w, page.Total, page.NextCursor)
+		failed, retrying := []Submission{}, []Submission{}
+		for _, s := range page.Items {
+			if s.State == SubmissionFailed {
+				failed = append(failed, s)
+			} else {
+				retrying = append(retrying, s)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"failed":   failed,
+			"retrying": retrying,
+		})
+	case http.MethodPost:
+		id := r.URL.Query().Get("id")
+		if id == "" {
+			http.Error(w, "id is required", http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Query().Get("action") {
+		case "retry":
+			order, err := h.queue.Retry(id)
+			if errors.Is(err, ErrSubmissionNotFound) {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			if errors.Is(err, ErrSubmissionInFlight) || errors.Is(err, ErrRefused) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			if err != nil {
+				http.Error(w, "Retry failed: "+err.Error(), http.StatusBadGateway)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "order": order})
+		case "dismiss":
+			if err := h.queue.Dismiss(id); errors.Is(err, ErrSubmissionInFlight) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			} else if err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+		default:
+			http.Error(w, "action must be retry or dismiss", http.StatusBadRequest)
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *RetryHandler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.queue.Policy())
+	case http.MethodPost, http.MethodPut:
+		if !users.FromContext(r.Context()).IsAdmin() {
+			http.Error(w, users.ErrForbidden.Error(), http.StatusForbidden)
+			return
+		}
+		// Start from the current policy so partial updates work
+		policy := h.queue.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.queue.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(policy)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
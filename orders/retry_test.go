@@ -0,0 +1,242 @@
+package orders
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/rileyseaburg/go-trader/algorithm"
+	"github.com/rileyseaburg/go-trader/users"
+	"github.com/shopspring/decimal"
+)
+
+// flakyBroker fails each placement with the next queued error, then
+// accepts; a timed-out placement still reaches it when landed is set.
+type flakyBroker struct {
+	errs   []error
+	landed bool
+	byID   map[string]*alpaca.Order
+	calls  int
+}
+
+func (b *flakyBroker) PlaceOrder(req alpaca.PlaceOrderRequest) (*alpaca.Order, error) {
+	b.calls++
+	if _, dup := b.byID[req.ClientOrderID]; dup {
+		return nil, &alpaca.APIError{StatusCode: http.StatusUnprocessableEntity, Message: "client_order_id must be unique"}
+	}
+	order := &alpaca.Order{ID: fmt.Sprintf("o%d", b.calls), ClientOrderID: req.ClientOrderID, Symbol: req.Symbol}
+	if len(b.errs) > 0 {
+		err := b.errs[0]
+		b.errs = b.errs[1:]
+		if b.landed {
+			b.byID[req.ClientOrderID] = order
+		}
+		return nil, err
+	}
+	b.byID[req.ClientOrderID] = order
+	return order, nil
+}
+
+func (b *flakyBroker) GetOrderByClientOrderID(id string) (*alpaca.Order, error) {
+	if o, ok := b.byID[id]; ok {
+		return o, nil
+	}
+	return nil, &alpaca.APIError{StatusCode: http.StatusNotFound, Message: "order not found"}
+}
+
+func TestTransient(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&alpaca.APIError{StatusCode: 503}, true},
+		{fmt.Errorf("place: %w", &alpaca.APIError{StatusCode: 429}), true},
+		{&alpaca.APIError{StatusCode: 403, Message: "insufficient buying power"}, false},
+		{errors.New("<html>Bad Gateway</html> (HTTP 502)"), true},
+		{errors.New("not found (HTTP 404)"), false},
+		{fmt.Errorf("post: %w", errTimeout{}), true},
+	}
+	for _, c := range cases {
+		if got := Transient(c.err); got != c.want {
+			t.Errorf("Transient(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+type errTimeout struct{}
+
+func (errTimeout) Error() string   { return "i/o timeout" }
+func (errTimeout) Timeout() bool   { return true }
+func (errTimeout) Temporary() bool { return true }
+
+func TestRetryQueueRetriesThenFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "retries.json")
+	unavailable := &alpaca.APIError{StatusCode: 503, Message: "service unavailable"}
+	broker := &flakyBroker{errs: []error{unavailable, unavailable}, byID: map[string]*alpaca.Order{}}
+	q, err := NewRetryQueue(broker, path, DefaultRetryPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	q.SetClock(func() time.Time { return now })
+	q.jitter = func() float64 { return 0.5 } // no jitter
+	var notified []string
+	q.SetNotifier(func(title, _ string, _ map[string]interface{}) { notified = append(notified, title) })
+
+	qty := decimal.NewFromInt(10)
+	var placed *alpaca.Order
+	order, queued, err := q.Submit(alpaca.PlaceOrderRequest{Symbol: "AAPL", Side: alpaca.Buy, Qty: &qty, ClientOrderID: "momo:1"},
+		func(o *alpaca.Order) { placed = o })
+	if order != nil || !queued || err != nil {
+		t.Fatalf("Submit = %v, %v, %v", order, queued, err)
+	}
+	q.Step(now.Add(time.Second))
+	if broker.calls != 1 {
+		t.Error("retried before the backoff")
+	}
+	q.Step(now.Add(2 * time.Second)) // fails again, next in 4s
+	q.Step(now.Add(6 * time.Second))
+	if placed == nil || placed.ClientOrderID != "momo:1" || len(q.List()) != 0 {
+		t.Fatalf("placed = %+v, held %+v", placed, q.List())
+	}
+
+	// A rejection is returned, not queued
+	broker.errs = []error{&alpaca.APIError{StatusCode: 403, Message: "insufficient buying power"}}
+	if _, queued, err := q.Submit(alpaca.PlaceOrderRequest{Symbol: "MSFT", ClientOrderID: "momo:2"}, nil); queued || err == nil {
+		t.Errorf("rejection queued = %v, err %v", queued, err)
+	}
+
+	// Out of attempts, an order waits for manual resolution, saved
+	broker.errs = []error{unavailable, unavailable, unavailable, unavailable}
+	q.Submit(alpaca.PlaceOrderRequest{Symbol: "TSLA", ClientOrderID: "momo:3"}, nil)
+	for s := 2; s <= 14; s += 2 {
+		q.Step(now.Add(time.Duration(s) * time.Second))
+	}
+	held := q.List()
+	if len(held) != 1 || held[0].State != SubmissionFailed || held[0].Attempts != 4 || len(notified) != 1 {
+		t.Fatalf("held = %+v, notified %v", held, notified)
+	}
+	reopened, err := NewRetryQueue(broker, path, DefaultRetryPolicy())
+	if err != nil || len(reopened.List()) != 1 {
+		t.Fatalf("reopened = %+v, %v", reopened.List(), err)
+	}
+	if order, err := q.Retry("momo:3"); err != nil || order == nil {
+		t.Errorf("manual retry = %v, %v", order, err)
+	}
+	if err := q.Dismiss("momo:3"); !errors.Is(err, ErrSubmissionNotFound) {
+		t.Errorf("dismissed a placed order: %v", err)
+	}
+}
+
+func TestRetryQueueFindsTimedOutOrder(t *testing.T) {
+	// The first attempt reached the broker but its answer was lost
+	broker := &flakyBroker{errs: []error{errTimeout{}}, landed: true, byID: map[string]*alpaca.Order{}}
+	q, err := NewRetryQueue(broker, "", DefaultRetryPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	q.SetClock(func() time.Time { return now })
+	if _, queued, _ := q.Submit(alpaca.PlaceOrderRequest{Symbol: "AAPL", ClientOrderID: "momo:1"}, nil); !queued {
+		t.Fatal("timeout not queued")
+	}
+	q.Step(now.Add(time.Minute))
+	if broker.calls != 1 || len(q.List()) != 0 {
+		t.Errorf("placed twice (%d calls) or still held %+v", broker.calls, q.List())
+	}
+}
+
+func TestRetryQueueGuardsRetries(t *testing.T) {
+	unavailable := &alpaca.APIError{StatusCode: 503, Message: "service unavailable"}
+	broker := &flakyBroker{errs: []error{unavailable}, byID: map[string]*alpaca.Order{}}
+	q, err := NewRetryQueue(broker, "", DefaultRetryPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	q.SetClock(func() time.Time { return now })
+	var checked *algorithm.TradeSignal
+	refuse := errors.New("execution is disabled in safe mode")
+	q.SetGuard(func(s *algorithm.TradeSignal) error { checked = s; return refuse })
+
+	qty := decimal.NewFromInt(10)
+	q.Submit(alpaca.PlaceOrderRequest{Symbol: "AAPL", Side: alpaca.Sell, Type: alpaca.Market, Qty: &qty, ClientOrderID: "momo:1"}, nil)
+	q.Step(now.Add(time.Minute))
+	if broker.calls != 1 || checked == nil || checked.Signal != "sell" || checked.Size.Qty != 10 || checked.Tag != "momo" {
+		t.Fatalf("guard saw %+v; %d broker calls", checked, broker.calls)
+	}
+	if held := q.List(); len(held) != 1 || held[0].State != SubmissionFailed {
+		t.Fatalf("refused retry left %+v", held)
+	}
+	if _, err := q.Retry("momo:1"); !errors.Is(err, ErrRefused) || broker.calls != 1 {
+		t.Errorf("manual retry past the guard: %v", err)
+	}
+	q.SetGuard(func(*algorithm.TradeSignal) error { return nil })
+	if order, err := q.Retry("momo:1"); err != nil || order == nil {
+		t.Errorf("allowed retry = %v, %v", order, err)
+	}
+}
+
+func TestRetryQueueLeavesAttemptsInFlightAlone(t *testing.T) {
+	broker := &flakyBroker{errs: []error{errTimeout{}}, byID: map[string]*alpaca.Order{}}
+	q, err := NewRetryQueue(broker, "", DefaultRetryPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	q.SetClock(func() time.Time { return now })
+	entered, release := make(chan struct{}), make(chan struct{})
+	q.SetGuard(func(*algorithm.TradeSignal) error {
+		entered <- struct{}{}
+		<-release
+		return nil
+	})
+	q.Submit(alpaca.PlaceOrderRequest{Symbol: "AAPL", ClientOrderID: "momo:1"}, nil)
+
+	done := make(chan struct{})
+	go func() {
+		q.Step(now.Add(time.Minute))
+		close(done)
+	}()
+	<-entered
+	if _, err := q.Retry("momo:1"); !errors.Is(err, ErrSubmissionInFlight) {
+		t.Errorf("retry during an attempt: %v", err)
+	}
+	if err := q.Dismiss("momo:1"); !errors.Is(err, ErrSubmissionInFlight) {
+		t.Errorf("dismiss during an attempt: %v", err)
+	}
+	q.Step(now.Add(2 * time.Minute)) // would block on the guard if it attempted again
+	close(release)
+	<-done
+	if broker.calls != 2 || len(q.List()) != 0 {
+		t.Errorf("%d broker calls, still held %+v", broker.calls, q.List())
+	}
+}
+
+func TestOnlyAdminsChangeRetryPolicy(t *testing.T) {
+	q, err := NewRetryQueue(&flakyBroker{byID: map[string]*alpaca.Order{}}, "", DefaultRetryPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	NewRetryHandler(q).RegisterRoutes(mux)
+
+	trader := users.WithUser(context.Background(), users.User{ID: "trader", Role: users.RoleUser})
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/orders/retries", strings.NewReader(`{}`)).WithContext(trader))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("user POST = %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/orders/retries", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusOK {
+		t.Errorf("admin POST = %d", rec.Code)
+	}
+}
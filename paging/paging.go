@@ -0,0 +1,308 @@
+// Package paging gives list endpoints one set of query parameters and one
+// way to page through them:
+//
+//	limit=50                at most this many items, up to MaxLimit
+//	cursor=<next_cursor>    the page after the one that returned the cursor
+//	sort=-time              a field to order by, descending with a leading -
+//	filter=symbol:AAPL      only items whose field equals the value, ignoring
+//	                        case; repeat for more fields, separate values
+//	                        with | to match any of them
+//
+// Cursors name the last item of a page by its sort key and ID rather than
+// by position, so they stay valid when items are added, and across
+// restarts for lists whose items outlive the process.
+package paging
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits on items per page.
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// Response headers set on lists whose body is a bare array.
+const (
+	HeaderTotal      = "X-Total-Count"
+	HeaderNextCursor = "X-Next-Cursor"
+)
+
+// timeLayout formats times at a fixed width so they sort as text.
+const timeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// Key is an item's value of a sort field: a number, or text compared
+// byte by byte.
+type Key struct {
+	Num float64 `json:"n,omitempty"`
+	Str string  `json:"s,omitempty"`
+}
+
+// Time is the key of a time.
+func Time(t time.Time) Key { return Key{Str: t.UTC().Format(timeLayout)} }
+
+// Number is the key of a number.
+func Number(v float64) Key { return Key{Num: v} }
+
+// Text is the key of a string, ignoring case.
+func Text(s string) Key { return Key{Str: strings.ToLower(s)} }
+
+// Time returns the time a Time key holds.
+func (k Key) Time() (time.Time, bool) {
+	t, err := time.Parse(timeLayout, k.Str)
+	return t, err == nil
+}
+
+func (k Key) compare(o Key) int {
+	switch {
+	case k.Num < o.Num:
+		return -1
+	case k.Num > o.Num:
+		return 1
+	}
+	return strings.Compare(k.Str, o.Str)
+}
+
+// Field is a field of the items a list can be sorted or filtered by.
+type Field[T any] struct {
+	Name string
+	// Sort is the item's key; nil when the field cannot be sorted by
+	Sort func(T) Key
+	// Filter is the item's value filters match; nil when the field cannot
+	// be filtered on
+	Filter func(T) string
+}
+
+// List describes a list endpoint's items.
+type List[T any] struct {
+	Fields []Field[T]
+	// ID identifies an item uniquely and stably; it orders items with
+	// equal keys
+	ID func(T) string
+	// Sort is the default order, such as -time
+	Sort string
+	// Limit is the default page size, DefaultLimit when 0
+	Limit int
+}
+
+// Filter is one filter parameter.
+type Filter struct {
+	Field  string
+	Values []string // any of them matches
+}
+
+// Request is a parsed page request.
+type Request struct {
+	Limit   int
+	Sort    string // field, with a leading - for descending
+	Filters []Filter
+	after   *cursor
+}
+
+// Filter returns the values the request filters field by.
+func (r Request) Filter(field string) []string {
+	for _, f := range r.Filters {
+		if f.Field == field {
+			return f.Values
+		}
+	}
+	return nil
+}
+
+// After returns the sort key of the item the request's cursor follows.
+func (r Request) After() (Key, bool) {
+	if r.after == nil {
+		return Key{}, false
+	}
+	return r.after.Key, true
+}
+
+// cursor is the last item of a page.
+type cursor struct {
+	Sort string `json:"sort"`
+	Key  Key    `json:"key"`
+	ID   string `json:"id"`
+}
+
+func (c cursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	var c cursor
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil {
+		return nil, errors.New("invalid cursor")
+	}
+	return &c, nil
+}
+
+// Page is one page of items.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	Total      int    `json:"total"` // items matching the filters
+	Limit      int    `json:"limit"`
+	Sort       string `json:"sort"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+func (l List[T]) field(name string) (Field[T], bool) {
+	for _, f := range l.Fields {
+		if f.Name == name {
+			return f, true
+		}
+	}
+	return Field[T]{}, false
+}
+
+// names lists the fields for which has is true.
+func (l List[T]) names(has func(Field[T]) bool) string {
+	var out []string
+	for _, f := range l.Fields {
+		if has(f) {
+			out = append(out, f.Name)
+		}
+	}
+	return strings.Join(out, ", ")
+}
+
+// Parse reads and checks the paging parameters of r.
+func (l List[T]) Parse(r *http.Request) (Request, error) {
+	q := r.URL.Query()
+	req := Request{Limit: l.Limit, Sort: l.Sort}
+	if req.Limit == 0 {
+		req.Limit = DefaultLimit
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxLimit {
+			return Request{}, fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+		}
+		req.Limit = n
+	}
+	if v := q.Get("sort"); v != "" {
+		req.Sort = v
+	}
+	if f, ok := l.field(strings.TrimPrefix(req.Sort, "-")); !ok || f.Sort == nil {
+		return Request{}, fmt.Errorf("sort must be one of %s, with a leading - for descending", l.names(func(f Field[T]) bool { return f.Sort != nil }))
+	}
+	for _, v := range q["filter"] {
+		name, value, ok := strings.Cut(v, ":")
+		if f, known := l.field(name); !ok || !known || f.Filter == nil {
+			return Request{}, fmt.Errorf("filter must be field:value, on one of %s", l.names(func(f Field[T]) bool { return f.Filter != nil }))
+		}
+		req.Filters = append(req.Filters, Filter{Field: name, Values: strings.Split(value, "|")})
+	}
+	if v := q.Get("cursor"); v != "" {
+		c, err := decodeCursor(v)
+		if err != nil {
+			return Request{}, err
+		}
+		if c.Sort != req.Sort {
+			return Request{}, errors.New("cursor belongs to another sort order")
+		}
+		req.after = c
+	}
+	return req, nil
+}
+
+// Apply filters and orders items and returns the page req asks for.
+func (l List[T]) Apply(items []T, req Request) Page[T] {
+	kept := make([]T, 0, len(items))
+	for _, item := range items {
+		if l.matches(item, req.Filters) {
+			kept = append(kept, item)
+		}
+	}
+
+	f, _ := l.field(strings.TrimPrefix(req.Sort, "-"))
+	desc := strings.HasPrefix(req.Sort, "-")
+	// less orders a before b, on the key and then the ID
+	less := func(a Key, aID string, b Key, bID string) bool {
+		c := a.compare(b)
+		if c == 0 {
+			c = strings.Compare(aID, bID)
+		}
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	}
+	sort.SliceStable(kept, func(i, j int) bool {
+		return less(f.Sort(kept[i]), l.ID(kept[i]), f.Sort(kept[j]), l.ID(kept[j]))
+	})
+
+	start := 0
+	if c := req.after; c != nil {
+		start = sort.Search(len(kept), func(i int) bool { return less(c.Key, c.ID, f.Sort(kept[i]), l.ID(kept[i])) })
+	}
+	end := start + req.Limit
+	if end > len(kept) {
+		end = len(kept)
+	}
+	page := Page[T]{Items: kept[start:end], Total: len(kept), Limit: req.Limit, Sort: req.Sort}
+	if end < len(kept) && end > start {
+		last := kept[end-1]
+		page.NextCursor = cursor{Sort: req.Sort, Key: f.Sort(last), ID: l.ID(last)}.encode()
+	}
+	return page
+}
+
+func (l List[T]) matches(item T, filters []Filter) bool {
+	for _, filter := range filters {
+		f, _ := l.field(filter.Field)
+		v := f.Filter(item)
+		ok := false
+		for _, want := range filter.Values {
+			ok = ok || strings.EqualFold(v, want)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Page parses r and returns the page of items it asks for, writing a bad
+// request and returning false when its parameters are invalid.
+func (l List[T]) Page(w http.ResponseWriter, r *http.Request, items []T) (Page[T], bool) {
+	req, err := l.Parse(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return Page[T]{}, false
+	}
+	return l.Apply(items, req), true
+}
+
+// Serve writes the page of items r asks for as a bare array, for lists
+// that have always returned one, with the total and next cursor in the
+// X-Total-Count and X-Next-Cursor headers.
+func (l List[T]) Serve(w http.ResponseWriter, r *http.Request, items []T) {
+	page, ok := l.Page(w, r, items)
+	if !ok {
+		return
+	}
+	SetHeaders(w, page.Total, page.NextCursor)
+	json.NewEncoder(w).Encode(page.Items)
+}
+
+// SetHeaders sets the paging headers of a bare array response.
+func SetHeaders(w http.ResponseWriter, total int, next string) {
+	w.Header().Set(HeaderTotal, strconv.Itoa(total))
+	if next != "" {
+		w.Header().Set(HeaderNextCursor, next)
+	}
+}
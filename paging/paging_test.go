@@ -0,0 +1,109 @@
+package paging
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+type item struct {
+	ID     string
+	Time   time.Time
+	Symbol string
+	Score  float64
+}
+
+var items = List[item]{
+	Fields: []Field[item]{
+		{Name: "time", Sort: func(i item) Key { return Time(i.Time) }},
+		{Name: "score", Sort: func(i item) Key { return Number(i.Score) }},
+		{Name: "symbol", Sort: func(i item) Key { return Text(i.Symbol) }, Filter: func(i item) string { return i.Symbol }},
+	},
+	ID:   func(i item) string { return i.ID },
+	Sort: "-time",
+}
+
+func request(t *testing.T, query url.Values) Request {
+	t.Helper()
+	req, err := items.Parse(httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func ids(list []item) string {
+	out := ""
+	for _, i := range list {
+		out += i.ID
+	}
+	return out
+}
+
+func TestCursorWalksEveryItemOnce(t *testing.T) {
+	start := time.Date(2026, 9, 16, 9, 30, 0, 0, time.UTC)
+	var all []item
+	for n, symbol := range []string{"AAPL", "MSFT", "aapl", "NVDA", "AAPL", "MSFT", "AAPL"} {
+		// b and c share a time, so their IDs order them
+		at := start.Add(time.Duration(n) * time.Minute)
+		if n == 2 {
+			at = start.Add(time.Minute)
+		}
+		all = append(all, item{ID: string(rune('a' + n)), Time: at, Symbol: symbol, Score: float64(n % 3)})
+	}
+
+	req := request(t, url.Values{"limit": {"2"}})
+	var seen string
+	for pages := 0; ; pages++ {
+		page := items.Apply(all, req)
+		if page.Total != 7+pages || pages > 4 {
+			t.Fatalf("page %d = %+v", pages, page)
+		}
+		seen += ids(page.Items)
+		if page.NextCursor == "" {
+			break
+		}
+		// An item added between pages does not shift the next one
+		all = append(all, item{ID: fmt.Sprint("z", pages), Time: start.Add(time.Hour)})
+		req = request(t, url.Values{"limit": {"2"}, "cursor": {page.NextCursor}})
+	}
+	if seen != "gfedcba" {
+		t.Errorf("newest first = %q", seen)
+	}
+
+	page := items.Apply(all[:7], request(t, url.Values{"sort": {"score"}, "filter": {"symbol:aapl|nvda"}}))
+	if ids(page.Items) != "adgec" || page.Total != 5 {
+		t.Errorf("filtered by score = %q of %d", ids(page.Items), page.Total)
+	}
+}
+
+func TestParseRejectsBadParameters(t *testing.T) {
+	page := items.Apply([]item{{ID: "a"}, {ID: "b"}}, Request{Limit: 1, Sort: "time"})
+	for _, query := range []url.Values{
+		{"limit": {"0"}},
+		{"limit": {"5000"}},
+		{"sort": {"price"}},
+		{"filter": {"score:1"}},
+		{"filter": {"symbol"}},
+		{"cursor": {"%%%"}},
+		{"cursor": {page.NextCursor}}, // from another sort order
+	} {
+		if _, err := items.Parse(httptest.NewRequest(http.MethodGet, "/?"+query.Encode(), nil)); err == nil {
+			t.Errorf("%v accepted", query)
+		}
+	}
+}
+
+func TestServeSetsHeaders(t *testing.T) {
+	rec := httptest.NewRecorder()
+	items.Serve(rec, httptest.NewRequest(http.MethodGet, "/?limit=1", nil), []item{{ID: "a"}, {ID: "b"}})
+	if rec.Code != http.StatusOK || rec.Header().Get(HeaderTotal) != "2" || rec.Header().Get(HeaderNextCursor) == "" {
+		t.Errorf("response = %d %v", rec.Code, rec.Header())
+	}
+	if body := rec.Body.String(); body != "[{\"ID\":\"b\",\"Time\":\"0001-01-01T00:00:00Z\",\"Symbol\":\"\",\"Score\":0}]\n" {
+		t.Errorf("body = %s", body)
+	}
+}
@@ -0,0 +1,416 @@
+// Package pdt keeps the account clear of the pattern day trader rule:
+// a margin account under $25,000 that makes four or more day trades in
+// five business days is flagged and restricted. Day trades are counted
+// from the fills journal and compared with the broker's own count, and
+// trades that would be one too many are warned about or refused.
+package pdt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/calendar"
+	"github.com/rileyseaburg/go-trader/fills"
+)
+
+func logger() *slog.Logger { return slog.With("module", "pdt") }
+
+// Enforcement modes.
+const (
+	ModeOff   = "off"
+	ModeWarn  = "warn"  // notify, but let the trade through
+	ModeBlock = "block" // refuse the trade
+)
+
+// WindowSessions is how many business days, today included, day trades
+// are counted over.
+const WindowSessions = 5
+
+// accountTTL is how long a broker account read is reused.
+const accountTTL = time.Minute
+
+// ErrDayTradeLimit is wrapped by refusals of trades over the limit.
+var ErrDayTradeLimit = errors.New("day trade limit reached")
+
+// Policy configures when the rule applies and what happens at the limit.
+type Policy struct {
+	Mode string `json:"mode"` // off, warn or block
+	// MinEquity is the equity at or above which day trades are unlimited
+	MinEquity float64 `json:"min_equity"`
+	// MaxDayTrades is how many day trades the window may hold below
+	// MinEquity; the next one would flag the account
+	MaxDayTrades int `json:"max_day_trades"`
+	// Reserve holds back day trades from automated trading, so some are
+	// left for exits by hand
+	Reserve int `json:"reserve"`
+}
+
+// DefaultPolicy refuses a fourth day trade in five business days while
+// equity is under $25,000.
+func DefaultPolicy() Policy {
+	return Policy{Mode: ModeBlock, MinEquity: 25000, MaxDayTrades: 3, Reserve: 0}
+}
+
+// Validate reports the first invalid field.
+func (p Policy) Validate() error {
+	switch p.Mode {
+	case ModeOff, ModeWarn, ModeBlock:
+	default:
+		return fmt.Errorf("mode must be one of %s, %s, %s", ModeOff, ModeWarn, ModeBlock)
+	}
+	switch {
+	case p.MinEquity < 0:
+		return errors.New("min_equity must not be negative")
+	case p.MaxDayTrades < 0:
+		return errors.New("max_day_trades must not be negative")
+	case p.Reserve < 0 || p.Reserve > p.MaxDayTrades:
+		return errors.New("reserve must be between 0 and max_day_trades")
+	}
+	return nil
+}
+
+// Account is what the broker reports about day trading.
+type Account struct {
+	Equity           float64 `json:"equity"`
+	DayTradeCount    int     `json:"day_trade_count"`
+	PatternDayTrader bool    `json:"pattern_day_trader"`
+}
+
+// AccountSource reads the account from the broker.
+type AccountSource func() (Account, error)
+
+// Source returns the finished fill records submitted at or after since.
+type Source func(since time.Time) []fills.Record
+
+// Notifier is told about trades at the limit.
+type Notifier func(title, message string, metadata map[string]interface{})
+
+// DayTrade is a position opened and closed on the same session.
+type DayTrade struct {
+	Symbol   string    `json:"symbol"`
+	Date     string    `json:"date"` // session, YYYY-MM-DD
+	ClosedAt time.Time `json:"closed_at"`
+}
+
+// Count is what the journal says about day trading as of a time.
+type Count struct {
+	// DayTrades are those in the window, oldest first
+	DayTrades []DayTrade `json:"day_trades"`
+	// OpenedToday lists symbols opened or added to today since their last
+	// counted close; closing any of them now would be a day trade
+	OpenedToday []string `json:"opened_today"`
+}
+
+// CountDayTrades walks records, oldest first, and counts the day trades
+// of the sessions on or after since. A day trade is a closing fill that
+// follows an opening fill of the same symbol that session with no counted
+// close in between, so buying twice and selling once is one day trade and
+// buying, selling, buying and selling is two. Positions are taken as flat
+// at the start of the journal. Pure.
+func CountDayTrades(records []fills.Record, since, now time.Time, loc *time.Location) Count {
+	filled := make([]fills.Record, 0, len(records))
+	for _, r := range records {
+		if r.FilledQty > 0 {
+			filled = append(filled, r)
+		}
+	}
+	sort.SliceStable(filled, func(i, j int) bool { return filledAt(filled[i]).Before(filledAt(filled[j])) })
+
+	type book struct {
+		position float64 // negative for a short
+		day      string  // session of the last fill
+		opened   bool    // opened since the last counted close, on day
+	}
+	books := make(map[string]*book)
+	today := day(now, loc)
+	count := Count{DayTrades: []DayTrade{}, OpenedToday: []string{}}
+	for _, r := range filled {
+		at := filledAt(r)
+		if at.After(now) {
+			break
+		}
+		sym := strings.ToUpper(r.Symbol)
+		b, ok := books[sym]
+		if !ok {
+			b = &book{}
+			books[sym] = b
+		}
+		if d := day(at, loc); d != b.day {
+			b.day, b.opened = d, false
+		}
+
+		qty := r.FilledQty
+		if strings.EqualFold(r.Side, "sell") {
+			qty = -qty
+		}
+		// The part against the position closes; any rest opens
+		closing := 0.0
+		if b.position*qty < 0 {
+			closing = math.Min(math.Abs(qty), math.Abs(b.position))
+		}
+		if closing > 0 && b.opened {
+			if !at.Before(since) {
+				count.DayTrades = append(count.DayTrades, DayTrade{Symbol: sym, Date: b.day, ClosedAt: at})
+			}
+			b.opened = false
+		}
+		if math.Abs(qty)-closing > 1e-9 {
+			b.opened = true
+		}
+		b.position += qty
+		if math.Abs(b.position) < 1e-9 {
+			b.position = 0
+		}
+	}
+	for sym, b := range books {
+		if b.day == today && b.opened && b.position != 0 {
+			count.OpenedToday = append(count.OpenedToday, sym)
+		}
+	}
+	sort.Strings(count.OpenedToday)
+	return count
+}
+
+// Status is the day trading picture at /api/account/daytrades.
+type Status struct {
+	Policy Policy `json:"policy"`
+	// Applies is whether the limit is enforced: the mode is not off and
+	// equity is under min_equity, or unknown
+	Applies bool `json:"applies"`
+	// Account is nil when the broker could not be read
+	Account      *Account `json:"account,omitempty"`
+	AccountError string   `json:"account_error,omitempty"`
+	// JournalDayTrades and BrokerDayTrades are counted separately; the
+	// higher is used, as the broker sees trades made elsewhere and the
+	// journal sees fills the broker has not counted yet
+	JournalDayTrades int `json:"journal_day_trades"`
+	BrokerDayTrades  int `json:"broker_day_trades"`
+	DayTrades        int `json:"day_trades"`
+	// Remaining is how many more day trades automated trading may make;
+	// -1 when unlimited
+	Remaining   int        `json:"remaining"`
+	WindowStart string     `json:"window_start"` // first session counted, YYYY-MM-DD
+	Trades      []DayTrade `json:"trades"`
+	OpenedToday []string   `json:"opened_today"`
+}
+
+// Manager counts day trades and checks trades against the limit. It is
+// safe for concurrent use.
+type Manager struct {
+	path   string
+	source Source
+	cal    *calendar.Calendar
+
+	mu        sync.Mutex
+	policy    Policy
+	account   AccountSource
+	cached    *Account
+	cachedAt  time.Time
+	cachedErr error
+	notify    Notifier
+	notified  map[string]string // symbol → session last notified
+	now       func() time.Time
+}
+
+// New opens the policy saved at path, starting from DefaultPolicy when
+// there is none. Fills come from source; sessions from cal.
+func New(path string, source Source, cal *calendar.Calendar) (*Manager, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create pdt directory: %w", err)
+	}
+	m := &Manager{path: path, source: source, cal: cal, policy: DefaultPolicy(), notified: make(map[string]string), now: time.Now}
+	if data, err := os.ReadFile(path); err == nil {
+		policy := DefaultPolicy()
+		if err := json.Unmarshal(data, &policy); err != nil {
+			return nil, fmt.Errorf("failed to decode pdt policy: %w", err)
+		}
+		if err := policy.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid pdt policy: %w", err)
+		}
+		m.policy = policy
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read pdt policy: %w", err)
+	}
+	return m, nil
+}
+
+// SetClock replaces the clock, for replays and tests.
+func (m *Manager) SetClock(now func() time.Time) { m.mu.Lock(); m.now = now; m.mu.Unlock() }
+
+// SetAccountSource wires the broker account. Without one, equity is
+// unknown and the limit always applies.
+func (m *Manager) SetAccountSource(fn AccountSource) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.account, m.cached, m.cachedErr = fn, nil, nil
+}
+
+// SetNotifier sets where warnings and refusals go.
+func (m *Manager) SetNotifier(fn Notifier) { m.mu.Lock(); m.notify = fn; m.mu.Unlock() }
+
+// Policy returns the current policy.
+func (m *Manager) Policy() Policy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.policy
+}
+
+// SetPolicy validates, applies and saves p.
+func (m *Manager) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.policy = p
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode pdt policy: %w", err)
+	}
+	if err := os.WriteFile(m.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to save pdt policy: %w", err)
+	}
+	return nil
+}
+
+// Status counts the day trades in the window and reads the account.
+func (m *Manager) Status() Status {
+	m.mu.Lock()
+	now, policy := m.now(), m.policy
+	m.mu.Unlock()
+	acct, acctErr := m.readAccount(now)
+
+	start := m.windowStart(now)
+	count := CountDayTrades(m.source(time.Time{}), start, now, m.cal.Location())
+	s := Status{
+		Policy:           policy,
+		JournalDayTrades: len(count.DayTrades),
+		WindowStart:      start.Format("2006-01-02"),
+		Trades:           count.DayTrades,
+		OpenedToday:      count.OpenedToday,
+		Remaining:        -1,
+	}
+	if acctErr != nil {
+		s.AccountError = acctErr.Error()
+	} else {
+		s.Account = &acct
+		s.BrokerDayTrades = acct.DayTradeCount
+	}
+	s.DayTrades = max(s.JournalDayTrades, s.BrokerDayTrades)
+	s.Applies = policy.Mode != ModeOff && (acctErr != nil || acct.Equity < policy.MinEquity)
+	if s.Applies {
+		s.Remaining = max(policy.MaxDayTrades-policy.Reserve-s.DayTrades, 0)
+	}
+	return s
+}
+
+// CheckClose checks a trade that closes or reduces a position in symbol.
+// If a position in symbol was opened this session, the trade is a day
+// trade; when none remain it is refused with an error wrapping
+// ErrDayTradeLimit, or in warn mode notified and let through.
+func (m *Manager) CheckClose(symbol string) error {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	s := m.Status()
+	if !s.Applies || s.Remaining != 0 {
+		return nil
+	}
+	opened := false
+	for _, sym := range s.OpenedToday {
+		if sym == symbol {
+			opened = true
+			break
+		}
+	}
+	if !opened {
+		return nil
+	}
+
+	err := fmt.Errorf("%w: closing %s today would be day trade %d of %d allowed in %d sessions under $%.0f equity",
+		ErrDayTradeLimit, symbol, s.DayTrades+1, s.Policy.MaxDayTrades-s.Policy.Reserve, WindowSessions, s.Policy.MinEquity)
+	m.alert(symbol, s, err)
+	if s.Policy.Mode == ModeWarn {
+		logger().Warn("Day trade over the limit allowed in warn mode", "symbol", symbol, "day_trades", s.DayTrades)
+		return nil
+	}
+	return err
+}
+
+// alert notifies err once per symbol per session.
+func (m *Manager) alert(symbol string, s Status, err error) {
+	m.mu.Lock()
+	session := day(m.now(), m.cal.Location())
+	notify := m.notify
+	seen := m.notified[symbol] == session
+	m.notified[symbol] = session
+	m.mu.Unlock()
+	if notify == nil || seen {
+		return
+	}
+	title := "Day trade refused"
+	if s.Policy.Mode == ModeWarn {
+		title = "Day trade over the PDT limit"
+	}
+	notify(title, err.Error(), map[string]interface{}{
+		"symbol":     symbol,
+		"day_trades": s.DayTrades,
+		"mode":       s.Policy.Mode,
+	})
+}
+
+// readAccount returns the account, reading the broker at most once per
+// accountTTL.
+func (m *Manager) readAccount(now time.Time) (Account, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.account == nil {
+		return Account{}, errors.New("no broker account")
+	}
+	if !m.cachedAt.IsZero() && now.Sub(m.cachedAt) < accountTTL && !now.Before(m.cachedAt) {
+		if m.cachedErr != nil {
+			return Account{}, m.cachedErr
+		}
+		return *m.cached, nil
+	}
+	acct, err := m.account()
+	m.cachedAt, m.cachedErr = now, err
+	if err != nil {
+		logger().Warn("Failed to read account for day trade count", "error", err)
+		return Account{}, err
+	}
+	m.cached = &acct
+	return acct, nil
+}
+
+// windowStart is the first of the WindowSessions sessions ending with
+// today's, or with the last one before now on a day without a session.
+func (m *Manager) windowStart(now time.Time) time.Time {
+	s, ok := m.cal.SessionFor(now)
+	if !ok {
+		s = m.cal.PreviousSession(now)
+	}
+	for i := 1; i < WindowSessions; i++ {
+		s = m.cal.PreviousSession(s.Date)
+	}
+	return s.Date
+}
+
+// filledAt is when r filled.
+func filledAt(r fills.Record) time.Time {
+	if r.FilledAt != nil {
+		return *r.FilledAt
+	}
+	return r.FinishedAt
+}
+
+func day(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format("2006-01-02")
+}
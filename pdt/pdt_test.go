@@ -0,0 +1,119 @@
+package pdt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/calendar"
+	"github.com/rileyseaburg/go-trader/fills"
+)
+
+func fill(symbol, side string, qty float64, at time.Time) fills.Record {
+	return fills.Record{Symbol: symbol, Side: side, FilledQty: qty, FillPrice: 100, FilledAt: &at, Outcome: fills.OutcomeFilled}
+}
+
+func TestCountDayTrades(t *testing.T) {
+	cal := calendar.New()
+	loc := cal.Location()
+	at := func(day, hour int) time.Time { return time.Date(2025, 3, day, hour, 0, 0, 0, loc) }
+	records := []fills.Record{
+		// Monday, outside the window: a round trip
+		fill("AAPL", "buy", 10, at(3, 10)), fill("AAPL", "sell", 10, at(3, 11)),
+		// Tuesday: bought twice and sold in two orders is one day trade
+		fill("MSFT", "buy", 5, at(4, 10)), fill("MSFT", "buy", 5, at(4, 11)),
+		fill("MSFT", "sell", 5, at(4, 12)), fill("MSFT", "sell", 5, at(4, 13)),
+		// Wednesday: held overnight, sold the next day is not a day trade
+		fill("TSLA", "buy", 3, at(5, 15)), fill("TSLA", "sell", 3, at(6, 10)),
+		// Thursday: a short covered the same day, then round trips twice
+		fill("GME", "sell", 4, at(6, 10)), fill("GME", "buy", 4, at(6, 11)),
+		fill("NVDA", "buy", 1, at(6, 10)), fill("NVDA", "sell", 1, at(6, 11)),
+		fill("NVDA", "buy", 1, at(6, 12)), fill("NVDA", "sell", 1, at(6, 13)),
+		// Today: added to yesterday's position, still held
+		fill("AMD", "buy", 2, at(7, 15)), fill("AMD", "buy", 2, at(10, 10)),
+		// Today: selling yesterday's shares opens nothing
+		fill("META", "buy", 2, at(7, 15)), fill("META", "sell", 1, at(10, 10)),
+		// After now: not seen yet
+		fill("AMD", "sell", 4, at(10, 15)),
+	}
+
+	c := CountDayTrades(records, time.Date(2025, 3, 4, 0, 0, 0, 0, loc), at(10, 11), loc)
+	var got []string
+	for _, d := range c.DayTrades {
+		got = append(got, d.Symbol+" "+d.Date)
+	}
+	want := "MSFT 2025-03-04,GME 2025-03-06,NVDA 2025-03-06,NVDA 2025-03-06"
+	if strings.Join(got, ",") != want {
+		t.Errorf("day trades = %v, want %s", got, want)
+	}
+	if strings.Join(c.OpenedToday, ",") != "AMD" {
+		t.Errorf("opened today = %v", c.OpenedToday)
+	}
+}
+
+func TestCheckClose(t *testing.T) {
+	cal := calendar.New()
+	loc := cal.Location()
+	now := time.Date(2025, 3, 7, 14, 0, 0, 0, loc)
+	records := []fills.Record{
+		fill("NVDA", "buy", 1, now.Add(-3*time.Hour)), fill("NVDA", "sell", 1, now.Add(-2*time.Hour)),
+		fill("AAPL", "buy", 1, now.Add(-time.Hour)),
+		fill("MSFT", "buy", 1, now.Add(-26*time.Hour)),
+	}
+	m, err := New(t.TempDir()+"/policy.json", func(time.Time) []fills.Record { return records }, cal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetClock(func() time.Time { return now })
+	account := Account{Equity: 20000, DayTradeCount: 2}
+	m.SetAccountSource(func() (Account, error) { return account, nil })
+	var alerts []string
+	m.SetNotifier(func(title, message string, _ map[string]interface{}) { alerts = append(alerts, title) })
+
+	s := m.Status()
+	if !s.Applies || s.JournalDayTrades != 1 || s.BrokerDayTrades != 2 || s.DayTrades != 2 || s.Remaining != 1 || s.WindowStart != "2025-03-03" {
+		t.Errorf("status = %+v", s)
+	}
+	if err := m.CheckClose("AAPL"); err != nil {
+		t.Errorf("third day trade refused: %v", err)
+	}
+
+	// The broker has counted a third elsewhere; the cached read is reused
+	// for a minute
+	account.DayTradeCount = 3
+	if err := m.CheckClose("AAPL"); err != nil {
+		t.Errorf("cached account not reused: %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	if err := m.CheckClose("aapl"); !errors.Is(err, ErrDayTradeLimit) {
+		t.Errorf("fourth day trade = %v", err)
+	}
+	if err := m.CheckClose("MSFT"); err != nil {
+		t.Errorf("closing yesterday's position refused: %v", err)
+	}
+	m.CheckClose("AAPL")
+	if len(alerts) != 1 || alerts[0] != "Day trade refused" {
+		t.Errorf("alerts = %v, want one refusal", alerts)
+	}
+
+	p := DefaultPolicy()
+	p.Mode = ModeWarn
+	if err := m.SetPolicy(p); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.CheckClose("AAPL"); err != nil {
+		t.Errorf("warn mode refused: %v", err)
+	}
+
+	account.Equity = 30000
+	now = now.Add(2 * time.Minute)
+	if s := m.Status(); s.Applies || s.Remaining != -1 {
+		t.Errorf("limit applies over min_equity: %+v", s)
+	}
+
+	p.Reserve = 4
+	if err := m.SetPolicy(p); err == nil {
+		t.Error("reserve above max_day_trades accepted")
+	}
+}
@@ -0,0 +1,79 @@
+package portfoliostream
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Op is one JSON Patch (RFC 6902) operation. Only add, remove and replace
+// are produced; arrays that changed are replaced whole.
+type Op struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"` // JSON Pointer (RFC 6901)
+	Value interface{} `json:"value"`
+}
+
+// MarshalJSON leaves out the value of removals, and only of removals, so
+// a member set to null is still sent as one.
+func (o Op) MarshalJSON() ([]byte, error) {
+	if o.Op == "remove" {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{o.Op, o.Path})
+	}
+	type op Op
+	return json.Marshal(op(o))
+}
+
+// Diff returns the operations that turn from into to, both generic JSON as
+// decoded by encoding/json. Object members are visited in key order so the
+// same change always produces the same patch.
+func Diff(from, to interface{}) []Op {
+	return diff("", from, to, nil)
+}
+
+func diff(path string, from, to interface{}, ops []Op) []Op {
+	fm, fok := from.(map[string]interface{})
+	tm, tok := to.(map[string]interface{})
+	if !fok || !tok {
+		if reflect.DeepEqual(from, to) {
+			return ops
+		}
+		return append(ops, Op{Op: "replace", Path: path, Value: to})
+	}
+
+	keys := make([]string, 0, len(fm)+len(tm))
+	for k := range fm {
+		keys = append(keys, k)
+	}
+	for k := range tm {
+		if _, ok := fm[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		child := path + "/" + escapePointer(k)
+		fv, inFrom := fm[k]
+		tv, inTo := tm[k]
+		switch {
+		case !inTo:
+			ops = append(ops, Op{Op: "remove", Path: child})
+		case !inFrom:
+			ops = append(ops, Op{Op: "add", Path: child, Value: tv})
+		default:
+			ops = diff(child, fv, tv, ops)
+		}
+	}
+	return ops
+}
+
+// pointerEscaper escapes a JSON Pointer reference token.
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+func escapePointer(token string) string {
+	return pointerEscaper.Replace(token)
+}
@@ -0,0 +1,237 @@
+// Package portfoliostream pushes live portfolio updates — positions marked
+// to the ticker stream, equity and daily P&L — to WebSocket clients. After
+// a full keyframe each client is sent only what changed, as JSON patches.
+package portfoliostream
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func logger() *slog.Logger { return slog.With("module", "portfoliostream") }
+
+const (
+	// writeWait bounds how long a write to one client may take.
+	writeWait = 5 * time.Second
+	// sendBuffer is how many updates may queue for a slow client before
+	// further updates to it are dropped.
+	sendBuffer = 16
+	// keyframeEvery and keyframeInterval bound the patches sent between
+	// full updates, so a client that misapplied one recovers.
+	keyframeEvery    = 50
+	keyframeInterval = time.Minute
+)
+
+// Message is the envelope sent to clients. Keyframes carry the full state
+// in Data; patches carry the changes since the previous update in Ops.
+type Message struct {
+	Type string `json:"type"` // the hub's kind for a keyframe, kind_patch for a patch
+	// Generation is the algorithm state generation the update belongs to.
+	// A jump larger than one means other state changed in between, and a
+	// client that needs signals and positions to agree should fetch
+	// /api/snapshot rather than combine this update with older reads.
+	Generation uint64 `json:"generation"`
+	// Base is the generation of the update a patch applies to. A client
+	// holding any other generation should ask for a keyframe.
+	Base uint64      `json:"base,omitempty"`
+	Data interface{} `json:"data,omitempty"`
+	Ops  []Op        `json:"ops,omitempty"`
+}
+
+type client struct {
+	conn *websocket.Conn
+	send chan []byte
+	// synced is set while the client has every update since its last
+	// keyframe, so patches apply to what it holds
+	synced bool
+}
+
+// Hub fans updates out to connected clients. New clients, clients that
+// dropped an update and clients that ask for one receive a keyframe of
+// the latest state; the rest receive patches.
+type Hub struct {
+	kind string
+	path string
+
+	mu           sync.Mutex
+	clients      map[*client]bool
+	state        interface{} // latest update as generic JSON
+	generation   uint64
+	last         []byte // latest update as a keyframe
+	sinceKey     int
+	lastKeyframe time.Time
+	upgrader     websocket.Upgrader
+}
+
+// NewHub creates an empty portfolio hub served at /ws/portfolio.
+func NewHub() *Hub {
+	return NewStreamHub("portfolio", "/ws/portfolio")
+}
+
+// NewStreamHub creates an empty hub for updates of kind served at path.
+func NewStreamHub(kind, path string) *Hub {
+	return &Hub{
+		kind:    kind,
+		path:    path,
+		clients: make(map[*client]bool),
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+			CheckOrigin: func(r *http.Request) bool {
+				// Allow all origins until SetCheckOrigin applies the
+				// REST API's CORS policy
+				return true
+			},
+		},
+	}
+}
+
+// SetCheckOrigin sets which browser origins may connect. Call it before
+// serving.
+func (h *Hub) SetCheckOrigin(check func(r *http.Request) bool) {
+	h.upgrader.CheckOrigin = check
+}
+
+// Publish sends data, as of generation, to every client: a patch against
+// the previous update when the client holds it, otherwise a keyframe.
+// Every keyframeEvery updates or keyframeInterval, and whenever the patch
+// would be no smaller, everyone gets a keyframe. Clients that have fallen
+// behind miss the update rather than stalling the caller, and get a
+// keyframe next.
+func (h *Hub) Publish(generation uint64, data interface{}) {
+	state, err := toJSON(data)
+	if err != nil {
+		logger().Error("Failed to encode update", "kind", h.kind, "error", err)
+		return
+	}
+	keyframe, err := json.Marshal(Message{Type: h.kind, Generation: generation, Data: state})
+	if err != nil {
+		logger().Error("Failed to encode update", "kind", h.kind, "error", err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var patch []byte
+	if h.last != nil && h.sinceKey < keyframeEvery && time.Since(h.lastKeyframe) < keyframeInterval {
+		patch, err = json.Marshal(Message{Type: h.kind + "_patch", Generation: generation, Base: h.generation, Ops: Diff(h.state, state)})
+		if err != nil || len(patch) >= len(keyframe) {
+			patch = nil
+		}
+	}
+	if patch == nil {
+		h.sinceKey = 0
+		h.lastKeyframe = time.Now()
+	} else {
+		h.sinceKey++
+	}
+	h.state, h.generation, h.last = state, generation, keyframe
+	for c := range h.clients {
+		payload := patch
+		if payload == nil || !c.synced {
+			payload = keyframe
+		}
+		select {
+		case c.send <- payload:
+			c.synced = true
+		default:
+			c.synced = false
+		}
+	}
+}
+
+// toJSON converts v to the maps, slices and scalars encoding/json decodes
+// it into, the form Diff compares.
+func toJSON(v interface{}) (interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out interface{}
+	err = json.Unmarshal(raw, &out)
+	return out, err
+}
+
+// Clients returns the number of connected clients.
+func (h *Hub) Clients() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.clients)
+}
+
+// RegisterRoutes registers the stream with mux.
+func (h *Hub) RegisterRoutes(mux *http.ServeMux) {
+	// WS /ws/portfolio - live positions, equity and daily P&L
+	// WS /ws/state - signals, market data and portfolio
+	mux.HandleFunc(h.path, h.handleWebSocket)
+}
+
+func (h *Hub) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger().Error("Failed to upgrade portfolio stream", "error", err)
+		return
+	}
+
+	c := &client{conn: conn, send: make(chan []byte, sendBuffer)}
+	h.mu.Lock()
+	h.clients[c] = true
+	if h.last != nil {
+		c.send <- h.last
+		c.synced = true
+	}
+	h.mu.Unlock()
+
+	go h.writeLoop(c)
+	h.readLoop(c)
+}
+
+// readLoop answers {"type":"keyframe"} with the latest keyframe, discards
+// other client messages and unregisters the client once the connection
+// closes.
+func (h *Hub) readLoop(c *client) {
+	defer func() {
+		h.mu.Lock()
+		delete(h.clients, c)
+		close(c.send)
+		h.mu.Unlock()
+		c.conn.Close()
+	}()
+	for {
+		_, payload, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var req struct {
+			Type string `json:"type"`
+		}
+		if json.Unmarshal(payload, &req) != nil || req.Type != "keyframe" {
+			continue
+		}
+		h.mu.Lock()
+		if h.last != nil {
+			select {
+			case c.send <- h.last:
+				c.synced = true
+			default:
+				c.synced = false
+			}
+		}
+		h.mu.Unlock()
+	}
+}
+
+func (h *Hub) writeLoop(c *client) {
+	for payload := range c.send {
+		c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+		if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+			c.conn.Close()
+			return
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package portfoliostream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestHubSendsLatestAndLiveUpdates(t *testing.T) {
+	h := NewHub()
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	h.Publish(3, map[string]float64{"total_value": 1})
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws/portfolio", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+	read := func() (float64, uint64) {
+		var msg struct {
+			Type       string             `json:"type"`
+			Generation uint64             `json:"generation"`
+			Data       map[string]float64 `json:"data"`
+		}
+		_, payload, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(payload, &msg); err != nil || msg.Type != "portfolio" {
+			t.Fatalf("message %s: %v", payload, err)
+		}
+		return msg.Data["total_value"], msg.Generation
+	}
+
+	if v, gen := read(); v != 1 || gen != 3 {
+		t.Errorf("initial update = %v at generation %d, want latest published", v, gen)
+	}
+	h.Publish(5, map[string]float64{"total_value": 2})
+	if v, gen := read(); v != 2 || gen != 5 {
+		t.Errorf("live update = %v at generation %d", v, gen)
+	}
+
+	conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for h.Clients() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if h.Clients() != 0 {
+		t.Error("closed client not unregistered")
+	}
+}
+
+// apply applies ops produced by Diff to doc.
+func apply(t *testing.T, doc interface{}, ops []Op) interface{} {
+	t.Helper()
+	unescape := strings.NewReplacer("~1", "/", "~0", "~")
+	for _, op := range ops {
+		if op.Path == "" {
+			doc = op.Value
+			continue
+		}
+		tokens := strings.Split(op.Path[1:], "/")
+		parent := doc
+		for _, tok := range tokens[:len(tokens)-1] {
+			parent = parent.(map[string]interface{})[unescape.Replace(tok)]
+		}
+		m := parent.(map[string]interface{})
+		key := unescape.Replace(tokens[len(tokens)-1])
+		if op.Op == "remove" {
+			delete(m, key)
+		} else {
+			m[key] = op.Value
+		}
+	}
+	return doc
+}
+
+func TestDiff(t *testing.T) {
+	decode := func(s string) interface{} {
+		var v interface{}
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	from := decode(`{"equity":100,"positions":{"AAPL":{"qty":1,"price":10},"MSFT":{"qty":2}},"tags":["a"],"note":"x"}`)
+	to := decode(`{"equity":101,"positions":{"AAPL":{"qty":1,"price":11},"BRK/B":{"qty":3}},"tags":["a","b"],"note":null}`)
+
+	ops := Diff(from, to)
+	raw, _ := json.Marshal(ops)
+	want := `[{"op":"replace","path":"/equity","value":101},` +
+		`{"op":"replace","path":"/note","value":null},` +
+		`{"op":"replace","path":"/positions/AAPL/price","value":11},` +
+		`{"op":"add","path":"/positions/BRK~1B","value":{"qty":3}},` +
+		`{"op":"remove","path":"/positions/MSFT"},` +
+		`{"op":"replace","path":"/tags","value":["a","b"]}]`
+	if string(raw) != want {
+		t.Fatalf("ops = %s", raw)
+	}
+	if got := apply(t, decode(`{"equity":100,"positions":{"AAPL":{"qty":1,"price":10},"MSFT":{"qty":2}},"tags":["a"],"note":"x"}`), ops); !reflect.DeepEqual(got, to) {
+		t.Errorf("patched = %v", got)
+	}
+	if ops := Diff(to, decode(`[1]`)); len(ops) != 1 || ops[0].Path != "" {
+		t.Errorf("root change = %v", ops)
+	}
+}
+
+func TestHubSendsPatchesBetweenKeyframes(t *testing.T) {
+	h := NewStreamHub("state", "/ws/state")
+	mux := http.NewServeMux()
+	h.RegisterRoutes(mux)
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	watchlist := func(moved string, price float64) map[string]interface{} {
+		quotes := make(map[string]interface{})
+		for i := 0; i < 100; i++ {
+			quotes[fmt.Sprintf("SYM%03d", i)] = map[string]float64{"price": 100, "volume": 1e6}
+		}
+		quotes[moved] = map[string]float64{"price": price, "volume": 1e6}
+		return map[string]interface{}{"market_data": quotes}
+	}
+	h.Publish(1, watchlist("SYM000", 100))
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws/state", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	read := func() (Message, int) {
+		_, payload, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var msg Message
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg, len(payload)
+	}
+
+	key, full := read()
+	if key.Type != "state" || key.Generation != 1 || key.Data == nil {
+		t.Fatalf("first message = %+v", key)
+	}
+	state := key.Data
+
+	h.Publish(2, watchlist("SYM042", 101.5))
+	patch, size := read()
+	if patch.Type != "state_patch" || patch.Generation != 2 || patch.Base != 1 || len(patch.Ops) != 1 {
+		t.Fatalf("patch = %+v", patch)
+	}
+	if size*10 > full {
+		t.Errorf("patch of %d bytes for a %d-byte state", size, full)
+	}
+	want, _ := toJSON(watchlist("SYM042", 101.5))
+	if state = apply(t, state, patch.Ops); !reflect.DeepEqual(state, want) {
+		t.Error("patched state differs from the published one")
+	}
+
+	// A client that lost track asks for the latest keyframe
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"type":"keyframe"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if key, _ := read(); key.Type != "state" || key.Generation != 2 {
+		t.Errorf("requested keyframe = %+v", key)
+	}
+}
@@ -0,0 +1,28 @@
+package premarket
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+)
+
+// AlpacaAssets adapts an Alpaca trading client to AssetChecker.
+type AlpacaAssets struct {
+	Client *alpaca.Client
+}
+
+// CheckTradable fails when the asset is inactive or not tradable.
+func (a AlpacaAssets) CheckTradable(ctx context.Context, symbol string) error {
+	asset, err := a.Client.GetAsset(symbol)
+	if err != nil {
+		return fmt.Errorf("failed to look up asset: %w", err)
+	}
+	if asset.Status != alpaca.AssetActive {
+		return fmt.Errorf("asset status is %s", asset.Status)
+	}
+	if !asset.Tradable {
+		return fmt.Errorf("asset is not tradable")
+	}
+	return nil
+}
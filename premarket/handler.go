@@ -0,0 +1,50 @@
+package premarket
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Handler exposes the pre-market routine over HTTP.
+type Handler struct {
+	routine *Routine
+}
+
+// NewHandler creates a handler for routine.
+func NewHandler(routine *Routine) *Handler {
+	return &Handler{routine: routine}
+}
+
+// RegisterRoutes registers the pre-market routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/premarket - last preparation report
+	mux.HandleFunc("/api/premarket", h.handleReport)
+
+	// POST /api/premarket/run - prepare now and return the report
+	mux.HandleFunc("/api/premarket/run", h.handleRun)
+}
+
+func (h *Handler) handleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"report": h.routine.LastReport(),
+	})
+}
+
+func (h *Handler) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	report, err := h.routine.Run(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"report": report,
+	})
+}
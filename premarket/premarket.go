@@ -0,0 +1,264 @@
+// Package premarket prepares the system for the open: it refreshes the
+// historical cache for watched symbols, recomputes indicator baselines and
+// volatility estimates, preloads history at each symbol's chosen signal
+// timeframe, confirms every symbol is still tradable, refreshes
+// the earnings calendar, re-arms the scheduler and posts a "Ready for
+// market open" notification listing anything that needs attention and the
+// week's earnings reports.
+package premarket
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/earnings"
+)
+
+// DefaultLookbackDays is how much daily history the routine refreshes.
+const DefaultLookbackDays = 120
+
+// EarningsDays is how many calendar days of earnings reports the report
+// lists.
+const EarningsDays = 7
+
+// History refreshes cached bars and the baselines derived from them.
+// Both methods return per-symbol failures.
+type History interface {
+	RefreshHistoricalCache(symbols []string, lookbackDays int) map[string]error
+	RecomputeBaselines(symbols []string) map[string]error
+}
+
+// TimeFrames preloads history at each symbol's signal timeframe, chosen
+// from the baselines, and returns the choices with per-symbol failures.
+// History sources that implement it are asked once baselines are
+// recomputed.
+type TimeFrames interface {
+	PreloadSymbolTimeFrames(symbols []string) (map[string]string, map[string]error)
+}
+
+// AssetChecker reports whether a symbol can currently be traded.
+type AssetChecker interface {
+	CheckTradable(ctx context.Context, symbol string) error
+}
+
+// EarningsCalendar refreshes and lists upcoming earnings reports.
+type EarningsCalendar interface {
+	Refresh(ctx context.Context) error
+	Upcoming(days int, held map[string]bool) []earnings.Upcoming
+	Held(ctx context.Context) map[string]bool
+}
+
+// Notifier posts a system notification.
+type Notifier func(title, message string, metadata map[string]interface{})
+
+// Issue is one problem found during preparation.
+type Issue struct {
+	Step    string `json:"step"` // history, baselines, timeframes, tradability, earnings
+	Symbol  string `json:"symbol"`
+	Message string `json:"message"`
+}
+
+// Report is the outcome of one run.
+type Report struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Symbols    []string  `json:"symbols"`
+	Refreshed  int       `json:"refreshed"`
+	Baselines  int       `json:"baselines"`
+	// TimeFrames is each symbol's signal timeframe
+	TimeFrames map[string]string `json:"timeframes,omitempty"`
+	Tradable   int               `json:"tradable"`
+	Untradable []string          `json:"untradable,omitempty"`
+	// Earnings lists the reports due within EarningsDays
+	Earnings []earnings.Upcoming `json:"earnings,omitempty"`
+	Issues   []Issue             `json:"issues"`
+	Ready    bool                `json:"ready"`
+}
+
+// Routine runs the pre-market preparation. Dependencies are optional; a
+// missing one skips its step and is noted in the report.
+type Routine struct {
+	LookbackDays int
+
+	mu      sync.Mutex
+	symbols func() []string
+	history History
+	assets  AssetChecker
+	cal     EarningsCalendar
+	rearm   func()
+	notify  Notifier
+	last    *Report
+	running bool
+}
+
+// New creates a routine over the watched symbol list.
+func New(symbols func() []string, history History) *Routine {
+	return &Routine{LookbackDays: DefaultLookbackDays, symbols: symbols, history: history}
+}
+
+// SetAssetChecker sets the tradability check.
+func (r *Routine) SetAssetChecker(a AssetChecker) { r.mu.Lock(); r.assets = a; r.mu.Unlock() }
+
+// SetEarnings sets the earnings calendar refreshed and listed each run.
+func (r *Routine) SetEarnings(c EarningsCalendar) { r.mu.Lock(); r.cal = c; r.mu.Unlock() }
+
+// SetRearm sets the function that re-arms scheduled jobs.
+func (r *Routine) SetRearm(fn func()) { r.mu.Lock(); r.rearm = fn; r.mu.Unlock() }
+
+// SetNotifier sets where the readiness notification goes.
+func (r *Routine) SetNotifier(n Notifier) { r.mu.Lock(); r.notify = n; r.mu.Unlock() }
+
+// LastReport returns the most recent report, or nil before the first run.
+func (r *Routine) LastReport() *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.last == nil {
+		return nil
+	}
+	cp := *r.last
+	return &cp
+}
+
+// Run performs every preparation step and posts the readiness
+// notification. Per-symbol failures become issues, not errors; an error is
+// only returned when a run is already in progress.
+func (r *Routine) Run(ctx context.Context) (Report, error) {
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return Report{}, fmt.Errorf("pre-market routine is already running")
+	}
+	r.running = true
+	symbols, history, assets, cal, rearm, notify := r.symbols, r.history, r.assets, r.cal, r.rearm, r.notify
+	lookback := r.LookbackDays
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		r.running = false
+		r.mu.Unlock()
+	}()
+
+	report := Report{StartedAt: time.Now(), Issues: []Issue{}}
+	if symbols != nil {
+		report.Symbols = dedupe(symbols())
+	}
+
+	if history == nil {
+		report.Issues = append(report.Issues, Issue{Step: "history", Message: "no history source configured"})
+	} else {
+		failed := history.RefreshHistoricalCache(report.Symbols, lookback)
+		report.Refreshed = len(report.Symbols) - len(failed)
+		report.Issues = append(report.Issues, issuesFrom("history", failed)...)
+
+		failed = history.RecomputeBaselines(report.Symbols)
+		report.Baselines = len(report.Symbols) - len(failed)
+		report.Issues = append(report.Issues, issuesFrom("baselines", failed)...)
+
+		if tf, ok := history.(TimeFrames); ok {
+			report.TimeFrames, failed = tf.PreloadSymbolTimeFrames(report.Symbols)
+			report.Issues = append(report.Issues, issuesFrom("timeframes", failed)...)
+		}
+	}
+
+	if assets == nil {
+		report.Issues = append(report.Issues, Issue{Step: "tradability", Message: "tradability check skipped: no broker configured"})
+	} else {
+		for _, sym := range report.Symbols {
+			if ctx.Err() != nil {
+				break
+			}
+			if err := assets.CheckTradable(ctx, sym); err != nil {
+				report.Untradable = append(report.Untradable, sym)
+				report.Issues = append(report.Issues, Issue{Step: "tradability", Symbol: sym, Message: err.Error()})
+				continue
+			}
+			report.Tradable++
+		}
+	}
+
+	if cal != nil {
+		if err := cal.Refresh(ctx); err != nil {
+			report.Issues = append(report.Issues, Issue{Step: "earnings", Message: err.Error()})
+		}
+		report.Earnings = cal.Upcoming(EarningsDays, cal.Held(ctx))
+	}
+
+	if rearm != nil {
+		rearm()
+	}
+
+	report.FinishedAt = time.Now()
+	report.Ready = len(report.Issues) == 0
+
+	r.mu.Lock()
+	cp := report
+	r.last = &cp
+	r.mu.Unlock()
+
+	if notify != nil {
+		notify("Ready for market open", Summary(report), map[string]interface{}{
+			"symbols":    len(report.Symbols),
+			"issues":     report.Issues,
+			"untradable": report.Untradable,
+			"earnings":   report.Earnings,
+		})
+	}
+	return report, nil
+}
+
+// Summary renders the report as the notification message.
+func Summary(report Report) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Prepared %d symbols: %d history refreshed, %d baselines, %d tradable.",
+		len(report.Symbols), report.Refreshed, report.Baselines, report.Tradable)
+	if len(report.Issues) == 0 {
+		b.WriteString(" No issues found.")
+	} else {
+		fmt.Fprintf(&b, " %d issue(s):", len(report.Issues))
+		for _, is := range report.Issues {
+			if is.Symbol != "" {
+				fmt.Fprintf(&b, "\n- %s %s: %s", is.Step, is.Symbol, is.Message)
+			} else {
+				fmt.Fprintf(&b, "\n- %s: %s", is.Step, is.Message)
+			}
+		}
+	}
+	if len(report.Earnings) > 0 {
+		fmt.Fprintf(&b, "\nEarnings in the next %d days:", EarningsDays)
+		for _, e := range report.Earnings {
+			fmt.Fprintf(&b, "\n- %s %s", e.Symbol, e.When())
+			if e.Held {
+				b.WriteString(" (held)")
+			}
+		}
+	}
+	return b.String()
+}
+
+func issuesFrom(step string, failed map[string]error) []Issue {
+	issues := make([]Issue, 0, len(failed))
+	for sym, err := range failed {
+		issues = append(issues, Issue{Step: step, Symbol: sym, Message: err.Error()})
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Symbol < issues[j].Symbol })
+	return issues
+}
+
+func dedupe(symbols []string) []string {
+	seen := make(map[string]bool, len(symbols))
+	out := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
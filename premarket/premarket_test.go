@@ -0,0 +1,91 @@
+package premarket
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type fakeHistory struct {
+	refreshed []string
+	lookback  int
+}
+
+func (f *fakeHistory) RefreshHistoricalCache(symbols []string, lookbackDays int) map[string]error {
+	f.refreshed, f.lookback = symbols, lookbackDays
+	return map[string]error{"ZZZZ": errors.New("no bars returned")}
+}
+
+func (f *fakeHistory) RecomputeBaselines(symbols []string) map[string]error {
+	return map[string]error{"ZZZZ": errors.New("need at least 20 daily bars")}
+}
+
+func (f *fakeHistory) PreloadSymbolTimeFrames(symbols []string) (map[string]string, map[string]error) {
+	return map[string]string{"AAPL": "1Min", "MSFT": "1Min", "ZZZZ": "1D"}, nil
+}
+
+type fakeAssets map[string]bool
+
+func (f fakeAssets) CheckTradable(ctx context.Context, symbol string) error {
+	if !f[symbol] {
+		return errors.New("asset is not tradable")
+	}
+	return nil
+}
+
+func TestRunReportsIssues(t *testing.T) {
+	history := &fakeHistory{}
+	r := New(func() []string { return []string{"aapl", "MSFT", "AAPL", "ZZZZ"} }, history)
+	r.SetAssetChecker(fakeAssets{"AAPL": true, "MSFT": true})
+
+	rearmed := false
+	r.SetRearm(func() { rearmed = true })
+
+	var title, message string
+	r.SetNotifier(func(t, m string, _ map[string]interface{}) { title, message = t, m })
+
+	report, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(history.refreshed, ","); got != "AAPL,MSFT,ZZZZ" {
+		t.Errorf("refreshed symbols = %s", got)
+	}
+	if history.lookback != DefaultLookbackDays {
+		t.Errorf("lookback = %d", history.lookback)
+	}
+	if report.Refreshed != 2 || report.Baselines != 2 || report.Tradable != 2 {
+		t.Errorf("counts = %+v", report)
+	}
+	if report.TimeFrames["AAPL"] != "1Min" {
+		t.Errorf("timeframes = %v", report.TimeFrames)
+	}
+	if len(report.Untradable) != 1 || report.Untradable[0] != "ZZZZ" {
+		t.Errorf("untradable = %v", report.Untradable)
+	}
+	if len(report.Issues) != 3 || report.Ready {
+		t.Errorf("issues = %+v, ready = %v", report.Issues, report.Ready)
+	}
+	if !rearmed {
+		t.Error("scheduler was not re-armed")
+	}
+	if title != "Ready for market open" || !strings.Contains(message, "3 issue(s)") {
+		t.Errorf("notification = %q / %q", title, message)
+	}
+	if last := r.LastReport(); last == nil || last.Tradable != 2 {
+		t.Errorf("last report = %+v", last)
+	}
+}
+
+func TestRunWithoutBrokerNotesSkippedCheck(t *testing.T) {
+	r := New(func() []string { return nil }, nil)
+	report, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Issues) != 2 || report.Issues[1].Step != "tradability" {
+		t.Errorf("issues = %+v", report.Issues)
+	}
+}
@@ -0,0 +1,518 @@
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/shopspring/decimal"
+)
+
+// DefaultCash is the simulated account's starting cash.
+const DefaultCash = 100000.0
+
+// ErrOrderNotFound is returned for unknown simulated order IDs.
+var ErrOrderNotFound = errors.New("order not found")
+
+// simPosition is a signed holding; negative quantities are short.
+type simPosition struct {
+	qty      float64
+	avgPrice float64
+}
+
+// simQuote is the latest market for a symbol.
+type simQuote struct {
+	last, bid, ask float64
+}
+
+// Broker is an in-memory brokerage that fills orders against the replayed
+// market. Market orders fill at the ask (buys) or bid (sells), or the last
+// trade without a quote; limit orders fill once the market reaches them.
+// Stop orders trigger when the last trade reaches the stop price and then
+// fill as a market order, or rest as a limit order for stop-limits.
+// There is no slippage, partial fill or commission. It satisfies the
+// broker interfaces of the algorithm, order manager and execution
+// algorithms.
+type Broker struct {
+	clock *Clock
+
+	mu         sync.Mutex
+	cash       float64
+	openEquity float64 // equity at the start of the replay, the day's base
+	positions  map[string]*simPosition
+	quotes     map[string]simQuote
+	orders     map[string]*alpaca.Order
+	open       []string        // resting order IDs, oldest first
+	triggered  map[string]bool // stop orders whose stop price has traded
+	seq        int
+	fills      int
+}
+
+// NewBroker returns a simulated account holding cash, timed by clock.
+func NewBroker(clock *Clock, cash float64) *Broker {
+	return &Broker{
+		clock:      clock,
+		cash:       cash,
+		openEquity: cash,
+		positions:  make(map[string]*simPosition),
+		quotes:     make(map[string]simQuote),
+		orders:     make(map[string]*alpaca.Order),
+		triggered:  make(map[string]bool),
+	}
+}
+
+// Mark updates the market for symbol, triggers resting stop orders and
+// fills resting orders the new prices reach. Zero values leave that side unchanged.
+func (b *Broker) Mark(symbol string, last, bid, ask float64) {
+	symbol = strings.ToUpper(symbol)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q := b.quotes[symbol]
+	if last > 0 {
+		q.last = last
+	}
+	if bid > 0 {
+		q.bid = bid
+	}
+	if ask > 0 {
+		q.ask = ask
+	}
+	b.quotes[symbol] = q
+
+	open := b.open[:0]
+	for _, id := range b.open {
+		o := b.orders[id]
+		if o.Symbol == symbol {
+			if price, ok := b.restingFillLocked(o); ok {
+				b.fillLocked(o, price)
+				continue
+			}
+		}
+		open = append(open, id)
+	}
+	b.open = open
+}
+
+// GetAccount implements the algorithm's broker.
+func (b *Broker) GetAccount() (*alpaca.Account, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var long, short float64
+	for sym, p := range b.positions {
+		v := p.qty * b.markLocked(sym, p)
+		if v >= 0 {
+			long += v
+		} else {
+			short += v
+		}
+	}
+	equity := b.cash + long + short
+	return &alpaca.Account{
+		ID:                  "replay-account",
+		AccountNumber:       "REPLAY",
+		Status:              "ACTIVE",
+		Currency:            "USD",
+		BuyingPower:         dec(math.Max(b.cash, 0)),
+		Cash:                dec(b.cash),
+		PortfolioValue:      dec(equity),
+		Equity:              dec(equity),
+		LastEquity:          dec(b.openEquity),
+		LongMarketValue:     dec(long),
+		ShortMarketValue:    dec(short),
+		PositionMarketValue: dec(long + short),
+		Multiplier:          decimal.NewFromInt(1),
+		ShortingEnabled:     true,
+		CreatedAt:           b.clock.Now(),
+	}, nil
+}
+
+// GetPositions implements the algorithm's broker.
+func (b *Broker) GetPositions() ([]alpaca.Position, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	symbols := make([]string, 0, len(b.positions))
+	for sym := range b.positions {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+
+	out := make([]alpaca.Position, 0, len(symbols))
+	for _, sym := range symbols {
+		p := b.positions[sym]
+		mark := b.markLocked(sym, p)
+		side := "long"
+		if p.qty < 0 {
+			side = "short"
+		}
+		value := dec(p.qty * mark)
+		pl := dec((mark - p.avgPrice) * p.qty)
+		price := dec(mark)
+		out = append(out, alpaca.Position{
+			Symbol:        sym,
+			Exchange:      "REPLAY",
+			AssetClass:    alpaca.USEquity,
+			Qty:           dec(p.qty),
+			QtyAvailable:  dec(p.qty),
+			AvgEntryPrice: dec(p.avgPrice),
+			Side:          side,
+			MarketValue:   &value,
+			CostBasis:     dec(p.qty * p.avgPrice),
+			UnrealizedPL:  &pl,
+			CurrentPrice:  &price,
+		})
+	}
+	return out, nil
+}
+
+// PlaceOrder implements the algorithm's, order manager's and execution
+// algorithms' broker. Market, limit, stop and stop-limit orders are
+// supported; a market order needs a price for its symbol.
+func (b *Broker) PlaceOrder(req alpaca.PlaceOrderRequest) (*alpaca.Order, error) {
+	if err := checkRequest(req); err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	o, err := b.newOrderLocked(req)
+	if err != nil {
+		return nil, err
+	}
+	return b.submitLocked(o), nil
+}
+
+// PlaceOrders places every order or none: all are checked, including that
+// market orders have a price, before any is placed. It makes multi-leg
+// orders atomic in a replay.
+func (b *Broker) PlaceOrders(reqs []alpaca.PlaceOrderRequest) ([]*alpaca.Order, error) {
+	for i, req := range reqs {
+		if err := checkRequest(req); err != nil {
+			return nil, fmt.Errorf("order %d: %w", i+1, err)
+		}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	prepared := make([]*alpaca.Order, len(reqs))
+	for i, req := range reqs {
+		o, err := b.newOrderLocked(req)
+		if err != nil {
+			return nil, fmt.Errorf("order %d: %w", i+1, err)
+		}
+		prepared[i] = o
+	}
+	placed := make([]*alpaca.Order, len(prepared))
+	for i, o := range prepared {
+		placed[i] = b.submitLocked(o)
+	}
+	return placed, nil
+}
+
+// checkRequest checks what can be checked of req without the market.
+func checkRequest(req alpaca.PlaceOrderRequest) error {
+	if req.Symbol == "" {
+		return errors.New("symbol is required")
+	}
+	if req.Side != alpaca.Buy && req.Side != alpaca.Sell {
+		return fmt.Errorf("unsupported side %q", req.Side)
+	}
+	switch req.Type {
+	case alpaca.Market, alpaca.Limit, alpaca.Stop, alpaca.StopLimit:
+	default:
+		return fmt.Errorf("unsupported order type %q in replay", req.Type)
+	}
+	if (req.Type == alpaca.Limit || req.Type == alpaca.StopLimit) && (req.LimitPrice == nil || !req.LimitPrice.IsPositive()) {
+		return fmt.Errorf("%s orders need a positive limit_price", req.Type)
+	}
+	if (req.Type == alpaca.Stop || req.Type == alpaca.StopLimit) && (req.StopPrice == nil || !req.StopPrice.IsPositive()) {
+		return fmt.Errorf("%s orders need a positive stop_price", req.Type)
+	}
+	return nil
+}
+
+// newOrderLocked builds the order for req, without an ID, checking it can
+// be placed against the current market.
+func (b *Broker) newOrderLocked(req alpaca.PlaceOrderRequest) (*alpaca.Order, error) {
+	symbol := strings.ToUpper(req.Symbol)
+	qty := 0.0
+	switch {
+	case req.Qty != nil:
+		qty = req.Qty.InexactFloat64()
+	case req.Notional != nil:
+		if price := b.quotes[symbol].last; price > 0 {
+			qty = req.Notional.InexactFloat64() / price
+		}
+	}
+	if qty <= 0 {
+		return nil, errors.New("order quantity must be positive")
+	}
+
+	now := b.clock.Now()
+	q := dec(qty)
+	o := &alpaca.Order{
+		ClientOrderID: req.ClientOrderID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		SubmittedAt:   now,
+		Symbol:        symbol,
+		AssetClass:    alpaca.USEquity,
+		Type:          req.Type,
+		Side:          req.Side,
+		TimeInForce:   req.TimeInForce,
+		Status:        "new",
+		Qty:           &q,
+		LimitPrice:    req.LimitPrice,
+		StopPrice:     req.StopPrice,
+	}
+	if req.Type == alpaca.Market && b.marketFillLocked(o) <= 0 {
+		return nil, fmt.Errorf("no replayed price for %s yet", symbol)
+	}
+	return o, nil
+}
+
+// submitLocked numbers o and fills it, or rests it until the market
+// reaches it.
+func (b *Broker) submitLocked(o *alpaca.Order) *alpaca.Order {
+	b.seq++
+	o.ID = fmt.Sprintf("replay-%d", b.seq)
+	if o.ClientOrderID == "" {
+		o.ClientOrderID = o.ID
+	}
+	b.orders[o.ID] = o
+
+	if o.Type == alpaca.Market {
+		b.fillLocked(o, b.marketFillLocked(o))
+		return copyOrder(o)
+	}
+	if price, ok := b.restingFillLocked(o); ok {
+		b.fillLocked(o, price)
+	} else {
+		b.open = append(b.open, o.ID)
+	}
+	return copyOrder(o)
+}
+
+// GetOrder implements the order manager's and execution algorithms'
+// broker.
+func (b *Broker) GetOrder(orderID string) (*alpaca.Order, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	o, ok := b.orders[orderID]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	return copyOrder(o), nil
+}
+
+// CancelOrder implements the order manager's and execution algorithms'
+// broker.
+func (b *Broker) CancelOrder(orderID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	o, ok := b.orders[orderID]
+	if !ok {
+		return ErrOrderNotFound
+	}
+	if !b.removeOpenLocked(orderID) {
+		return fmt.Errorf("order %s is %s", orderID, o.Status)
+	}
+	now := b.clock.Now()
+	o.Status = "canceled"
+	o.CanceledAt = &now
+	o.UpdatedAt = now
+	return nil
+}
+
+// ReplaceOrder implements the order manager's broker. As at Alpaca, the
+// resting order is marked replaced and a new order takes its place.
+func (b *Broker) ReplaceOrder(orderID string, req alpaca.ReplaceOrderRequest) (*alpaca.Order, error) {
+	b.mu.Lock()
+	old, ok := b.orders[orderID]
+	if !ok {
+		b.mu.Unlock()
+		return nil, ErrOrderNotFound
+	}
+	if !b.removeOpenLocked(orderID) {
+		b.mu.Unlock()
+		return nil, fmt.Errorf("order %s is %s", orderID, old.Status)
+	}
+	place := alpaca.PlaceOrderRequest{
+		Symbol:        old.Symbol,
+		Qty:           old.Qty,
+		Side:          old.Side,
+		Type:          old.Type,
+		TimeInForce:   old.TimeInForce,
+		LimitPrice:    old.LimitPrice,
+		StopPrice:     old.StopPrice,
+		ClientOrderID: req.ClientOrderID,
+	}
+	if req.Qty != nil {
+		place.Qty = req.Qty
+	}
+	if req.LimitPrice != nil {
+		place.LimitPrice = req.LimitPrice
+	}
+	if req.StopPrice != nil {
+		place.StopPrice = req.StopPrice
+	}
+	if req.TimeInForce != "" {
+		place.TimeInForce = req.TimeInForce
+	}
+	b.mu.Unlock()
+
+	o, err := b.PlaceOrder(place)
+	if err != nil {
+		// Put the original back; the replace never happened
+		b.mu.Lock()
+		b.open = append(b.open, orderID)
+		b.mu.Unlock()
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	old.Status = "replaced"
+	old.ReplacedAt = &now
+	old.ReplacedBy = &o.ID
+	old.UpdatedAt = now
+	b.orders[o.ID].Replaces = &orderID
+	o.Replaces = &orderID
+	return o, nil
+}
+
+// Fills returns how many orders have filled.
+func (b *Broker) Fills() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.fills
+}
+
+func (b *Broker) removeOpenLocked(orderID string) bool {
+	for i, id := range b.open {
+		if id == orderID {
+			b.open = append(b.open[:i], b.open[i+1:]...)
+			delete(b.triggered, orderID)
+			return true
+		}
+	}
+	return false
+}
+
+// marketFillLocked returns the price a market order fills at, zero when
+// nothing has traded or been quoted.
+func (b *Broker) marketFillLocked(o *alpaca.Order) float64 {
+	q := b.quotes[o.Symbol]
+	if o.Side == alpaca.Buy && q.ask > 0 {
+		return q.ask
+	}
+	if o.Side == alpaca.Sell && q.bid > 0 {
+		return q.bid
+	}
+	return q.last
+}
+
+// restingFillLocked reports whether a resting order fills now and at what
+// price. A stop order triggers once the last trade, or the market side
+// without one, reaches its stop: at or above it for a buy, at or below for
+// a sell. Triggered, a stop fills at the market, gaps included, and a
+// stop-limit works as a limit order.
+func (b *Broker) restingFillLocked(o *alpaca.Order) (float64, bool) {
+	if o.Type != alpaca.Stop && o.Type != alpaca.StopLimit {
+		return b.limitFillLocked(o)
+	}
+	if !b.triggered[o.ID] {
+		trade := b.quotes[o.Symbol].last
+		if trade <= 0 {
+			trade = b.marketFillLocked(o)
+		}
+		stop := o.StopPrice.InexactFloat64()
+		if trade <= 0 || (o.Side == alpaca.Buy && trade < stop) || (o.Side == alpaca.Sell && trade > stop) {
+			return 0, false
+		}
+		b.triggered[o.ID] = true
+	}
+	if o.Type == alpaca.StopLimit {
+		return b.limitFillLocked(o)
+	}
+	price := b.marketFillLocked(o)
+	return price, price > 0
+}
+
+// limitFillLocked reports whether a limit order is marketable and the
+// price it fills at: its limit or better.
+func (b *Broker) limitFillLocked(o *alpaca.Order) (float64, bool) {
+	market := b.marketFillLocked(o)
+	if market <= 0 || o.LimitPrice == nil {
+		return 0, false
+	}
+	limit := o.LimitPrice.InexactFloat64()
+	if o.Side == alpaca.Buy && market <= limit {
+		return market, true
+	}
+	if o.Side == alpaca.Sell && market >= limit {
+		return market, true
+	}
+	return 0, false
+}
+
+// fillLocked fills o in full at price and books it to the account.
+func (b *Broker) fillLocked(o *alpaca.Order, price float64) {
+	qty := o.Qty.InexactFloat64()
+	signed := qty
+	if o.Side == alpaca.Sell {
+		signed = -qty
+	}
+
+	p := b.positions[o.Symbol]
+	if p == nil {
+		p = &simPosition{}
+		b.positions[o.Symbol] = p
+	}
+	switch {
+	case p.qty == 0 || (p.qty > 0) == (signed > 0):
+		// Opening or adding: average in
+		p.avgPrice = (p.avgPrice*math.Abs(p.qty) + price*qty) / (math.Abs(p.qty) + qty)
+		p.qty += signed
+	case math.Abs(signed) <= math.Abs(p.qty):
+		p.qty += signed
+	default:
+		// Flipping through zero: the remainder opens at the fill price
+		p.qty += signed
+		p.avgPrice = price
+	}
+	if math.Abs(p.qty) < 1e-9 {
+		delete(b.positions, o.Symbol)
+	}
+	b.cash -= signed * price
+
+	now := b.clock.Now()
+	avg := dec(price)
+	o.Status = "filled"
+	o.FilledQty = *o.Qty
+	o.FilledAvgPrice = &avg
+	o.FilledAt = &now
+	o.UpdatedAt = now
+	delete(b.triggered, o.ID)
+	b.fills++
+}
+
+// markLocked is the price a position is valued at: the last trade, or its
+// entry before anything has traded.
+func (b *Broker) markLocked(symbol string, p *simPosition) float64 {
+	if last := b.quotes[symbol].last; last > 0 {
+		return last
+	}
+	return p.avgPrice
+}
+
+func copyOrder(o *alpaca.Order) *alpaca.Order {
+	c := *o
+	return &c
+}
+
+func dec(v float64) decimal.Decimal {
+	return decimal.NewFromFloat(v).Round(4)
+}
@@ -0,0 +1,69 @@
+package replay
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Handler exposes a running replay over HTTP.
+type Handler struct {
+	runner *Runner
+}
+
+// NewHandler creates a handler for runner.
+func NewHandler(runner *Runner) *Handler {
+	return &Handler{runner: runner}
+}
+
+// RegisterRoutes registers the replay routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/replay - state, simulated time and progress
+	mux.HandleFunc("/api/replay", h.handleStatus)
+
+	// POST /api/replay/speed - {"speed": "10x"}
+	mux.HandleFunc("/api/replay/speed", h.handleSpeed)
+
+	// POST /api/replay/pause, /api/replay/resume
+	mux.HandleFunc("/api/replay/pause", h.handleControl(h.runner.Pause))
+	mux.HandleFunc("/api/replay/resume", h.handleControl(h.runner.Resume))
+}
+
+func (h *Handler) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(h.runner.Status())
+}
+
+func (h *Handler) handleSpeed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req struct {
+		Speed string `json:"speed"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	speed, err := ParseSpeed(req.Speed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.runner.SetSpeed(speed)
+	json.NewEncoder(w).Encode(h.runner.Status())
+}
+
+func (h *Handler) handleControl(action func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		action()
+		json.NewEncoder(w).Encode(h.runner.Status())
+	}
+}
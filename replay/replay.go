@@ -0,0 +1,353 @@
+// Package replay drives the engine from a past session instead of the live
+// feed. Recorded ticks or historical minute bars are played back in time
+// order at a chosen speed: each event advances a simulated clock, marks a
+// simulated broker and is published as if the ticker had polled it, so the
+// live decision pipeline can be watched on any recorded day.
+package replay
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/rileyseaburg/go-trader/ticker"
+	"github.com/rileyseaburg/go-trader/ticks"
+)
+
+// Event sources.
+const (
+	SourceTicks = "ticks"
+	SourceBars  = "bars"
+)
+
+// Runner states.
+const (
+	StateIdle     = "idle"
+	StateRunning  = "running"
+	StatePaused   = "paused"
+	StateFinished = "finished"
+)
+
+// maxSleep caps the real-time wait between two events, so overnight or
+// lunchtime gaps in the data do not stall a 1x replay.
+const maxSleep = 5 * time.Second
+
+// Clock is the simulated time of a replay. It only moves forward.
+type Clock struct {
+	mu  sync.RWMutex
+	now time.Time
+}
+
+// NewClock returns a clock set to start.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start}
+}
+
+// Now returns the simulated time.
+func (c *Clock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.now
+}
+
+// Advance moves the clock to t if that is later.
+func (c *Clock) Advance(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if t.After(c.now) {
+		c.now = t
+	}
+}
+
+// ParseSpeed parses a replay speed such as "1x", "10x", "2.5" or "max".
+// Max, returned as zero, plays events back without waiting.
+func ParseSpeed(s string) (float64, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "max" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, "x"), 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid replay speed %q: use a multiple such as 1x or 10x, or max", s)
+	}
+	return v, nil
+}
+
+// FormatSpeed is the inverse of ParseSpeed.
+func FormatSpeed(speed float64) string {
+	if speed <= 0 {
+		return "max"
+	}
+	return strconv.FormatFloat(speed, 'f', -1, 64) + "x"
+}
+
+// Event is one replayed market update.
+type Event struct {
+	Tick ticks.Tick
+	Bar  *marketdata.Bar // the minute bar a bar replay's trade came from
+}
+
+// LoadTicks reads symbols' recorded ticks between from and to, merged in
+// time order.
+func LoadTicks(store *ticks.Store, symbols []string, from, to time.Time) ([]Event, error) {
+	var events []Event
+	for _, sym := range symbols {
+		err := store.Replay(sym, from, to, func(t ticks.Tick) error {
+			events = append(events, Event{Tick: t})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("read ticks for %s: %w", sym, err)
+		}
+	}
+	sortEvents(events)
+	return events, nil
+}
+
+// LoadBars fetches symbols' minute bars between from and to and turns each
+// into a trade at the bar's close, stamped at the end of the minute.
+func LoadBars(md *marketdata.Client, symbols []string, from, to time.Time) ([]Event, error) {
+	bars, err := md.GetMultiBars(symbols, marketdata.GetBarsRequest{
+		TimeFrame: marketdata.OneMin,
+		Start:     from,
+		End:       to,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fetch minute bars: %w", err)
+	}
+	return BarEvents(bars), nil
+}
+
+// BarEvents turns minute bars into trade events in time order.
+func BarEvents(bars map[string][]marketdata.Bar) []Event {
+	var events []Event
+	for sym, list := range bars {
+		for i := range list {
+			bar := list[i]
+			events = append(events, Event{
+				Tick: ticks.Tick{
+					Time:   bar.Timestamp.Add(time.Minute),
+					Symbol: strings.ToUpper(sym),
+					Kind:   ticks.KindTrade,
+					Price:  bar.Close,
+					Size:   float64(bar.Volume),
+				},
+				Bar: &bar,
+			})
+		}
+	}
+	sortEvents(events)
+	return events
+}
+
+func sortEvents(events []Event) {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Tick.Time.Before(events[j].Tick.Time) })
+}
+
+// Status reports a replay's progress.
+type Status struct {
+	State     string    `json:"state"`
+	Source    string    `json:"source"`
+	Day       string    `json:"day"`
+	Speed     string    `json:"speed"`
+	SimTime   time.Time `json:"sim_time"`
+	Events    int       `json:"events"`
+	Played    int       `json:"played"`
+	Progress  float64   `json:"progress"` // 0 to 1
+	Fills     int       `json:"fills"`
+	StartedAt time.Time `json:"started_at,omitempty"`
+}
+
+// Runner plays events back through the engine.
+type Runner struct {
+	clock   *Clock
+	broker  *Broker
+	events  []Event
+	source  string
+	day     string
+	publish func(ticker.TickerData)
+	tick    func(now time.Time) // advances clock-driven work such as the scheduler
+
+	mu        sync.Mutex
+	speed     float64
+	state     string
+	played    int
+	startedAt time.Time
+	resume    chan struct{}
+}
+
+// NewRunner returns a runner that plays events at speed, marking broker,
+// publishing each symbol's latest trade and quote through publish and
+// calling tick at every simulated minute.
+func NewRunner(clock *Clock, broker *Broker, events []Event, source, day string, speed float64,
+	publish func(ticker.TickerData), tick func(now time.Time)) *Runner {
+	return &Runner{
+		clock:   clock,
+		broker:  broker,
+		events:  events,
+		source:  source,
+		day:     day,
+		publish: publish,
+		tick:    tick,
+		speed:   speed,
+		state:   StateIdle,
+		resume:  make(chan struct{}),
+	}
+}
+
+// Status returns the replay's progress.
+func (r *Runner) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := Status{
+		State:     r.state,
+		Source:    r.source,
+		Day:       r.day,
+		Speed:     FormatSpeed(r.speed),
+		SimTime:   r.clock.Now(),
+		Events:    len(r.events),
+		Played:    r.played,
+		StartedAt: r.startedAt,
+	}
+	if len(r.events) > 0 {
+		s.Progress = float64(r.played) / float64(len(r.events))
+	}
+	if r.broker != nil {
+		s.Fills = r.broker.Fills()
+	}
+	return s
+}
+
+// SetSpeed changes the playback speed; zero is max.
+func (r *Runner) SetSpeed(speed float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.speed = speed
+}
+
+// Pause holds playback after the current event.
+func (r *Runner) Pause() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state == StateRunning {
+		r.state = StatePaused
+	}
+}
+
+// Resume continues a paused replay.
+func (r *Runner) Resume() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.state == StatePaused {
+		r.state = StateRunning
+		close(r.resume)
+		r.resume = make(chan struct{})
+	}
+}
+
+// Run plays every event, then returns. It stops early when ctx is done.
+func (r *Runner) Run(ctx context.Context) {
+	r.mu.Lock()
+	r.state = StateRunning
+	r.startedAt = time.Now()
+	r.mu.Unlock()
+
+	trades := make(map[string]*marketdata.Trade)
+	quotes := make(map[string]*marketdata.Quote)
+	var lastMinute time.Time
+	var prev time.Time
+	for i, ev := range r.events {
+		if !r.wait(ctx, prev, ev.Tick.Time) {
+			return
+		}
+		prev = ev.Tick.Time
+		r.clock.Advance(ev.Tick.Time)
+		now := r.clock.Now()
+
+		t := ev.Tick
+		switch t.Kind {
+		case ticks.KindTrade:
+			trades[t.Symbol] = &marketdata.Trade{
+				Timestamp: t.Time,
+				Price:     t.Price,
+				Size:      uint32(t.Size),
+				Exchange:  t.Exchange,
+				ID:        t.ID,
+			}
+			r.broker.Mark(t.Symbol, t.Price, 0, 0)
+		case ticks.KindQuote:
+			quotes[t.Symbol] = &marketdata.Quote{
+				Timestamp: t.Time,
+				BidPrice:  t.Bid,
+				BidSize:   uint32(t.BidSize),
+				AskPrice:  t.Ask,
+				AskSize:   uint32(t.AskSize),
+			}
+			r.broker.Mark(t.Symbol, 0, t.Bid, t.Ask)
+		}
+
+		// The ticker's data handler expects a trade, so a symbol is only
+		// published once it has traded
+		if trade := trades[t.Symbol]; trade != nil {
+			r.publish(ticker.TickerData{
+				Symbol:      t.Symbol,
+				Trade:       trade,
+				Quote:       quotes[t.Symbol],
+				Bar:         ev.Bar,
+				LastUpdated: now,
+			})
+		}
+
+		if minute := now.Truncate(time.Minute); minute.After(lastMinute) {
+			lastMinute = minute
+			if r.tick != nil {
+				r.tick(now)
+			}
+		}
+
+		r.mu.Lock()
+		r.played = i + 1
+		r.mu.Unlock()
+	}
+
+	r.mu.Lock()
+	r.state = StateFinished
+	r.mu.Unlock()
+}
+
+// wait sleeps for the simulated gap between two events scaled by the
+// speed, and holds while paused. It returns false when ctx is done.
+func (r *Runner) wait(ctx context.Context, prev, next time.Time) bool {
+	for {
+		r.mu.Lock()
+		paused := r.state == StatePaused
+		resume := r.resume
+		speed := r.speed
+		r.mu.Unlock()
+		if !paused {
+			if speed <= 0 || prev.IsZero() || !next.After(prev) {
+				return ctx.Err() == nil
+			}
+			d := time.Duration(float64(next.Sub(prev)) / speed)
+			if d > maxSleep {
+				d = maxSleep
+			}
+			select {
+			case <-ctx.Done():
+				return false
+			case <-time.After(d):
+				return true
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-resume:
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package replay
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+	"github.com/rileyseaburg/go-trader/ticker"
+	"github.com/rileyseaburg/go-trader/ticks"
+	"github.com/shopspring/decimal"
+)
+
+var day = time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+func decPtr(v float64) *decimal.Decimal {
+	d := decimal.NewFromFloat(v)
+	return &d
+}
+
+func TestParseSpeed(t *testing.T) {
+	for in, want := range map[string]float64{"1x": 1, "10X": 10, "2.5": 2.5, "max": 0} {
+		got, err := ParseSpeed(in)
+		if err != nil || got != want {
+			t.Errorf("ParseSpeed(%q) = %v, %v; want %v", in, got, err, want)
+		}
+		if back, _ := ParseSpeed(FormatSpeed(got)); back != got {
+			t.Errorf("FormatSpeed(%v) does not round-trip", got)
+		}
+	}
+	for _, in := range []string{"", "fast", "0x", "-1"} {
+		if _, err := ParseSpeed(in); err == nil {
+			t.Errorf("ParseSpeed(%q) accepted", in)
+		}
+	}
+}
+
+func TestBrokerMarketOrdersFillAtTheTouch(t *testing.T) {
+	b := NewBroker(NewClock(day), 10000)
+	if _, err := b.PlaceOrder(alpaca.PlaceOrderRequest{Symbol: "AAPL", Qty: decPtr(10), Side: alpaca.Buy, Type: alpaca.Market}); err == nil {
+		t.Fatal("market order filled with no price")
+	}
+	b.Mark("AAPL", 100, 99.9, 100.1)
+
+	o, err := b.PlaceOrder(alpaca.PlaceOrderRequest{Symbol: "aapl", Qty: decPtr(10), Side: alpaca.Buy, Type: alpaca.Market})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Status != "filled" || o.FilledAvgPrice.InexactFloat64() != 100.1 {
+		t.Errorf("buy filled %s at %v, want filled at the ask", o.Status, o.FilledAvgPrice)
+	}
+
+	b.Mark("AAPL", 105, 104.9, 105.1)
+	acct, _ := b.GetAccount()
+	if cash := acct.Cash.InexactFloat64(); cash != 10000-1001 {
+		t.Errorf("cash = %v, want %v", cash, 10000-1001.0)
+	}
+	if eq := acct.Equity.InexactFloat64(); eq != 10000-1001+1050 {
+		t.Errorf("equity = %v, want cash plus 10 × 105", eq)
+	}
+	positions, _ := b.GetPositions()
+	if len(positions) != 1 || positions[0].Qty.InexactFloat64() != 10 || positions[0].UnrealizedPL.InexactFloat64() != 49 {
+		t.Fatalf("positions = %+v", positions)
+	}
+
+	b.PlaceOrder(alpaca.PlaceOrderRequest{Symbol: "AAPL", Qty: decPtr(10), Side: alpaca.Sell, Type: alpaca.Market})
+	if positions, _ := b.GetPositions(); len(positions) != 0 {
+		t.Errorf("position left after selling it all: %+v", positions)
+	}
+	if b.Fills() != 2 {
+		t.Errorf("Fills() = %d, want 2", b.Fills())
+	}
+}
+
+func TestBrokerPlacesOrdersAllOrNone(t *testing.T) {
+	b := NewBroker(NewClock(day), 10000)
+	b.Mark("KO", 60, 59.99, 60.01)
+	pair := []alpaca.PlaceOrderRequest{
+		{Symbol: "KO", Qty: decPtr(20), Side: alpaca.Sell, Type: alpaca.Market},
+		{Symbol: "PEP", Qty: decPtr(7), Side: alpaca.Buy, Type: alpaca.Market},
+	}
+	if _, err := b.PlaceOrders(pair); err == nil {
+		t.Fatal("pair placed with no price for PEP")
+	}
+	if b.Fills() != 0 {
+		t.Fatalf("KO leg filled alone: %d fills", b.Fills())
+	}
+
+	b.Mark("PEP", 170, 169.95, 170.05)
+	orders, err := b.PlaceOrders(pair)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 || orders[0].ID == orders[1].ID || orders[0].Status != "filled" || orders[1].Status != "filled" {
+		t.Errorf("orders = %+v", orders)
+	}
+}
+
+func TestBrokerLimitOrdersRestUntilReached(t *testing.T) {
+	b := NewBroker(NewClock(day), 10000)
+	b.Mark("AAPL", 100, 99.9, 100.1)
+	o, err := b.PlaceOrder(alpaca.PlaceOrderRequest{Symbol: "AAPL", Qty: decPtr(5), Side: alpaca.Buy, Type: alpaca.Limit, LimitPrice: decPtr(99)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Status != "new" {
+		t.Fatalf("limit below the ask is %s, want resting", o.Status)
+	}
+
+	replaced, err := b.ReplaceOrder(o.ID, alpaca.ReplaceOrderRequest{LimitPrice: decPtr(99.5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old, _ := b.GetOrder(o.ID); old.Status != "replaced" || *old.ReplacedBy != replaced.ID {
+		t.Errorf("original order %s, want replaced by %s", old.Status, replaced.ID)
+	}
+
+	b.Mark("AAPL", 99.4, 99.3, 99.45)
+	filled, _ := b.GetOrder(replaced.ID)
+	if filled.Status != "filled" || filled.FilledAvgPrice.InexactFloat64() != 99.45 {
+		t.Errorf("replacement %s at %v, want filled at the ask once it crossed", filled.Status, filled.FilledAvgPrice)
+	}
+	if err := b.CancelOrder(replaced.ID); err == nil {
+		t.Error("canceled a filled order")
+	}
+}
+
+func TestBrokerStopOrdersTriggerOnTheTrade(t *testing.T) {
+	b := NewBroker(NewClock(day), 10000)
+	b.Mark("AAPL", 100, 99.9, 100.1)
+	if _, err := b.PlaceOrder(alpaca.PlaceOrderRequest{Symbol: "AAPL", Qty: decPtr(10), Side: alpaca.Buy, Type: alpaca.Market}); err != nil {
+		t.Fatal(err)
+	}
+	stop, err := b.PlaceOrder(alpaca.PlaceOrderRequest{Symbol: "AAPL", Qty: decPtr(10), Side: alpaca.Sell, Type: alpaca.Stop, StopPrice: decPtr(95)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stopLimit, err := b.PlaceOrder(alpaca.PlaceOrderRequest{Symbol: "AAPL", Qty: decPtr(5), Side: alpaca.Buy, Type: alpaca.StopLimit, StopPrice: decPtr(105), LimitPrice: decPtr(105.5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stop.Status != "new" || stopLimit.Status != "new" {
+		t.Fatalf("stops away from the market are %s and %s, want resting", stop.Status, stopLimit.Status)
+	}
+	if _, err := b.PlaceOrder(alpaca.PlaceOrderRequest{Symbol: "AAPL", Qty: decPtr(1), Side: alpaca.Sell, Type: alpaca.StopLimit, StopPrice: decPtr(95)}); err == nil {
+		t.Error("placed a stop-limit without a limit price")
+	}
+
+	// The open gaps through the stop, which fills at the bid below it
+	b.Mark("AAPL", 93, 92.9, 93.1)
+	if o, _ := b.GetOrder(stop.ID); o.Status != "filled" || o.FilledAvgPrice.InexactFloat64() != 92.9 {
+		t.Errorf("sell stop %s at %v, want filled at the bid through the gap", o.Status, o.FilledAvgPrice)
+	}
+
+	// The buy stop triggers at 106 but its limit is below the ask; it fills
+	// once the market comes back under the limit
+	b.Mark("AAPL", 106, 105.9, 106.1)
+	if o, _ := b.GetOrder(stopLimit.ID); o.Status != "new" {
+		t.Fatalf("stop-limit above its limit is %s, want resting", o.Status)
+	}
+	b.Mark("AAPL", 104, 103.9, 104.1)
+	if o, _ := b.GetOrder(stopLimit.ID); o.Status != "filled" || o.FilledAvgPrice.InexactFloat64() != 104.1 {
+		t.Errorf("triggered stop-limit %s at %v, want filled at the ask under its limit", o.Status, o.FilledAvgPrice)
+	}
+}
+
+func TestBarEventsTradeAtTheCloseOfEachMinute(t *testing.T) {
+	open := day.Add(14*time.Hour + 30*time.Minute)
+	events := BarEvents(map[string][]marketdata.Bar{
+		"msft": {{Timestamp: open, Close: 300, Volume: 1000}},
+		"AAPL": {{Timestamp: open.Add(-time.Minute), Close: 100, Volume: 500}, {Timestamp: open, Close: 101, Volume: 700}},
+	})
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3", len(events))
+	}
+	first := events[0]
+	if first.Tick.Symbol != "AAPL" || !first.Tick.Time.Equal(open) || first.Tick.Price != 100 || first.Bar == nil {
+		t.Errorf("first event = %+v", first)
+	}
+	if events[2].Tick.Symbol != "MSFT" && events[1].Tick.Symbol != "MSFT" {
+		t.Errorf("MSFT missing from %+v", events)
+	}
+}
+
+func TestRunnerPlaysEventsInOrder(t *testing.T) {
+	clock := NewClock(day)
+	broker := NewBroker(clock, DefaultCash)
+	t0 := day.Add(15 * time.Hour)
+	events := []Event{
+		{Tick: ticks.Tick{Time: t0, Symbol: "AAPL", Kind: ticks.KindQuote, Bid: 99, Ask: 101}},
+		{Tick: ticks.Tick{Time: t0.Add(time.Second), Symbol: "AAPL", Kind: ticks.KindTrade, Price: 100, Size: 10}},
+		{Tick: ticks.Tick{Time: t0.Add(2 * time.Minute), Symbol: "AAPL", Kind: ticks.KindTrade, Price: 102, Size: 5}},
+	}
+
+	var published []ticker.TickerData
+	var ticked []time.Time
+	r := NewRunner(clock, broker, events, SourceTicks, "2026-03-02", 0,
+		func(d ticker.TickerData) { published = append(published, d) },
+		func(now time.Time) { ticked = append(ticked, now) })
+	r.Run(context.Background())
+
+	// The quote alone is held back until the symbol has traded
+	if len(published) != 2 {
+		t.Fatalf("published %d updates, want 2", len(published))
+	}
+	if published[0].Quote == nil || published[0].Quote.AskPrice != 101 || published[1].Trade.Price != 102 {
+		t.Errorf("published = %+v", published)
+	}
+	if len(ticked) != 2 {
+		t.Errorf("ticked %d times, want once per simulated minute", len(ticked))
+	}
+	if !clock.Now().Equal(t0.Add(2 * time.Minute)) {
+		t.Errorf("clock at %v, want the last event", clock.Now())
+	}
+	st := r.Status()
+	if st.State != StateFinished || st.Played != 3 || st.Progress != 1 || st.Speed != "max" {
+		t.Errorf("status = %+v", st)
+	}
+}
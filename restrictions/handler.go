@@ -0,0 +1,191 @@
+package restrictions
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/paging"
+)
+
+// Handler exposes the restriction lists over HTTP.
+type Handler struct {
+	manager *Manager
+	caller  func(r *http.Request) string
+}
+
+// NewHandler creates a handler for manager. caller names who made a
+// request for the journal, e.g. from its auth token; changes by unnamed
+// callers are journaled under their remote address.
+func NewHandler(manager *Manager, caller func(r *http.Request) string) *Handler {
+	return &Handler{manager: manager, caller: caller}
+}
+
+// RegisterRoutes registers the restriction routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/restrictions - policy, blocklist and allowlist
+	// POST /api/restrictions - add a symbol to a list or replace its entry
+	mux.HandleFunc("/api/restrictions", h.json(h.handleLists))
+
+	// GET/PUT/DELETE /api/restrictions/{list}/{symbol} - one entry; list is block or allow
+	mux.HandleFunc("/api/restrictions/{list}/{symbol}", h.json(h.handleEntry))
+
+	// GET/POST /api/restrictions/policy - read or update how the lists are enforced
+	mux.HandleFunc("/api/restrictions/policy", h.json(h.handlePolicy))
+
+	// GET /api/restrictions/check?symbol= - whether a symbol may be traded
+	mux.HandleFunc("/api/restrictions/check", h.json(h.handleCheck))
+
+	// GET /api/restrictions/journal?symbol=&limit=&cursor=&sort=&filter= - changes with who made them, newest first
+	mux.HandleFunc("/api/restrictions/journal", h.json(h.handleJournal))
+}
+
+func (h *Handler) json(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		next(w, r)
+	}
+}
+
+func (h *Handler) by(r *http.Request) string {
+	if h.caller != nil {
+		if by := h.caller(r); by != "" {
+			return by
+		}
+	}
+	return r.RemoteAddr
+}
+
+// entryRequest is an entry as clients send it.
+type entryRequest struct {
+	Symbol string     `json:"symbol"`
+	List   string     `json:"list"`
+	Reason string     `json:"reason"`
+	Until  *time.Time `json:"until"`
+}
+
+func (h *Handler) handleLists(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.manager.List())
+	case http.MethodPost:
+		var req entryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		h.put(w, r, req)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleEntry(w http.ResponseWriter, r *http.Request) {
+	list, symbol := r.PathValue("list"), r.PathValue("symbol")
+	if list != ListBlock && list != ListAllow {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		e, err := h.manager.Get(list, symbol)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(e)
+	case http.MethodPut:
+		var req entryRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+		req.List, req.Symbol = list, symbol
+		h.put(w, r, req)
+	case http.MethodDelete:
+		err := h.manager.Remove(list, symbol, h.by(r))
+		switch {
+		case errors.Is(err, ErrNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) put(w http.ResponseWriter, r *http.Request, req entryRequest) {
+	e, err := h.manager.Put(Entry{Symbol: req.Symbol, List: req.List, Reason: req.Reason, Until: req.Until}, h.by(r))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	json.NewEncoder(w).Encode(e)
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.manager.Policy())
+	case http.MethodPost, http.MethodPut:
+		// Start from the current policy so partial updates work
+		policy := h.manager.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.manager.SetPolicy(policy, h.by(r)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(policy)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	symbol := normalize(r.URL.Query().Get("symbol"))
+	if symbol == "" {
+		http.Error(w, "symbol is required", http.StatusBadRequest)
+		return
+	}
+	resp := map[string]interface{}{"symbol": symbol, "allowed": true}
+	if err := h.manager.Check(symbol); err != nil {
+		resp["allowed"], resp["reason"] = false, err.Error()
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// journalList pages the journal, newest first by default.
+var journalList = paging.List[Change]{
+	Fields: []paging.Field[Change]{
+		{Name: "time", Sort: func(c Change) paging.Key { return paging.Time(c.Time) }},
+		{Name: "symbol", Sort: func(c Change) paging.Key { return paging.Text(c.Symbol) }, Filter: func(c Change) string { return c.Symbol }},
+		{Name: "action", Filter: func(c Change) string { return c.Action }},
+		{Name: "list", Filter: func(c Change) string { return c.List }},
+		{Name: "by", Filter: func(c Change) string { return c.By }},
+	},
+	ID: func(c Change) string {
+		return c.Time.UTC().Format(time.RFC3339Nano) + "/" + c.Action + "/" + c.List + "/" + c.Symbol
+	},
+	Sort: "-time",
+}
+
+func (h *Handler) handleJournal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	journalList.Serve(w, r, h.manager.Journal(r.URL.Query().Get("symbol"), 0))
+}
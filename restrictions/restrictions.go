@@ -0,0 +1,365 @@
+// Package restrictions keeps the symbols the system must not trade: a
+// blocklist, for compliance holds and personal blackouts, and an optional
+// allowlist that, when strict, is the only thing that may be traded. The
+// trade guard and the ticker's watch list consult it, and every change is
+// journaled with who made it and what it replaced.
+package restrictions
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+func logger() *slog.Logger { return slog.With("module", "restrictions") }
+
+// Lists.
+const (
+	ListBlock = "block"
+	ListAllow = "allow"
+)
+
+// Journal entry actions.
+const (
+	ActionAdd    = "add"
+	ActionUpdate = "update"
+	ActionRemove = "remove"
+	ActionPolicy = "policy"
+)
+
+// maxRecent bounds the journal entries kept in memory; the file keeps
+// everything.
+const maxRecent = 500
+
+// ErrRestricted is wrapped by every refusal Check returns.
+var ErrRestricted = errors.New("symbol is restricted")
+
+// ErrNotFound is returned for entries that are not on the list.
+var ErrNotFound = errors.New("restriction not found")
+
+// Policy configures how the lists are enforced.
+type Policy struct {
+	// StrictAllowlist refuses every symbol not on the allowlist
+	StrictAllowlist bool `json:"strict_allowlist"`
+	// AllowClosing lets trades that only reduce or close a position
+	// through, so holdings from before a restriction can be unwound
+	AllowClosing bool `json:"allow_closing"`
+}
+
+// DefaultPolicy enforces the blocklist alone and lets existing positions
+// be closed.
+func DefaultPolicy() Policy {
+	return Policy{StrictAllowlist: false, AllowClosing: true}
+}
+
+// Entry is one symbol on a list.
+type Entry struct {
+	Symbol  string    `json:"symbol"`
+	List    string    `json:"list"` // block or allow
+	Reason  string    `json:"reason,omitempty"`
+	AddedBy string    `json:"added_by,omitempty"`
+	AddedAt time.Time `json:"added_at"`
+	// Until ends the restriction, for blackouts; nil means indefinitely
+	Until *time.Time `json:"until,omitempty"`
+}
+
+// Active reports whether the entry is in force at now.
+func (e Entry) Active(now time.Time) bool {
+	return e.Until == nil || now.Before(*e.Until)
+}
+
+// Change is one journaled change to the lists or policy.
+type Change struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	By     string    `json:"by,omitempty"`
+	Symbol string    `json:"symbol,omitempty"`
+	List   string    `json:"list,omitempty"`
+	Before *Entry    `json:"before,omitempty"`
+	After  *Entry    `json:"after,omitempty"`
+	// Policy is the policy in force after a policy change
+	Policy *Policy `json:"policy,omitempty"`
+}
+
+// Lists is the policy and both lists, symbols in alphabetical order.
+type Lists struct {
+	Policy    Policy  `json:"policy"`
+	Blocklist []Entry `json:"blocklist"`
+	Allowlist []Entry `json:"allowlist"`
+}
+
+// state is what survives a restart.
+type state struct {
+	Policy  Policy           `json:"policy"`
+	Entries map[string]Entry `json:"entries"` // by list/SYMBOL
+}
+
+// Manager holds the lists. It is safe for concurrent use.
+type Manager struct {
+	statePath string
+
+	mu      sync.Mutex
+	state   state
+	now     func() time.Time
+	journal *os.File
+	recent  []Change
+}
+
+// New opens the lists and journal in dir, using policy unless one was
+// saved.
+func New(dir string, policy Policy) (*Manager, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create restrictions directory: %w", err)
+	}
+	m := &Manager{
+		statePath: filepath.Join(dir, "restrictions.json"),
+		state:     state{Policy: policy, Entries: make(map[string]Entry)},
+		now:       time.Now,
+	}
+
+	if data, err := os.ReadFile(m.statePath); err == nil {
+		var saved state
+		if err := json.Unmarshal(data, &saved); err != nil {
+			return nil, fmt.Errorf("failed to decode restrictions: %w", err)
+		}
+		m.state.Policy = saved.Policy
+		for key, e := range saved.Entries {
+			m.state.Entries[key] = e
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read restrictions: %w", err)
+	}
+
+	path := filepath.Join(dir, "journal.jsonl")
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			var c Change
+			if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+				logger().Warn("Skipping malformed restrictions journal line", "error", err)
+				continue
+			}
+			m.remember(c)
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read restrictions journal: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to open restrictions journal: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open restrictions journal for writing: %w", err)
+	}
+	m.journal = f
+	return m, nil
+}
+
+// SetClock replaces the clock, for replays and tests.
+func (m *Manager) SetClock(now func() time.Time) { m.mu.Lock(); m.now = now; m.mu.Unlock() }
+
+// Policy returns the current policy.
+func (m *Manager) Policy() Policy {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state.Policy
+}
+
+// SetPolicy replaces the policy, journaling the change as made by by.
+func (m *Manager) SetPolicy(p Policy, by string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if p == m.state.Policy {
+		return nil
+	}
+	before := m.state.Policy
+	m.state.Policy = p
+	if err := m.saveLocked(); err != nil {
+		m.state.Policy = before
+		return err
+	}
+	m.writeLocked(Change{Time: m.now(), Action: ActionPolicy, By: by, Policy: &p})
+	logger().Info("Updated trade restriction policy", "strict_allowlist", p.StrictAllowlist, "allow_closing", p.AllowClosing, "by", by)
+	return nil
+}
+
+// List returns the policy and both lists.
+func (m *Manager) List() Lists {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l := Lists{Policy: m.state.Policy, Blocklist: []Entry{}, Allowlist: []Entry{}}
+	for _, e := range m.state.Entries {
+		if e.List == ListBlock {
+			l.Blocklist = append(l.Blocklist, e)
+		} else {
+			l.Allowlist = append(l.Allowlist, e)
+		}
+	}
+	sort.Slice(l.Blocklist, func(i, j int) bool { return l.Blocklist[i].Symbol < l.Blocklist[j].Symbol })
+	sort.Slice(l.Allowlist, func(i, j int) bool { return l.Allowlist[i].Symbol < l.Allowlist[j].Symbol })
+	return l
+}
+
+// Get returns symbol's entry on list.
+func (m *Manager) Get(list, symbol string) (Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.state.Entries[key(list, normalize(symbol))]
+	if !ok {
+		return Entry{}, ErrNotFound
+	}
+	return e, nil
+}
+
+// Put adds e to its list or replaces the entry already there, journaling
+// the change as made by by. It returns the entry as stored.
+func (m *Manager) Put(e Entry, by string) (Entry, error) {
+	e.Symbol = normalize(e.Symbol)
+	if e.Symbol == "" {
+		return Entry{}, errors.New("symbol is required")
+	}
+	if e.List != ListBlock && e.List != ListAllow {
+		return Entry{}, fmt.Errorf("list must be %s or %s", ListBlock, ListAllow)
+	}
+	e.Reason = strings.TrimSpace(e.Reason)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if e.Until != nil && !e.Until.After(now) {
+		return Entry{}, errors.New("until must be in the future")
+	}
+	k := key(e.List, e.Symbol)
+	before, existed := m.state.Entries[k]
+	e.AddedBy, e.AddedAt = by, now
+	m.state.Entries[k] = e
+	if err := m.saveLocked(); err != nil {
+		if existed {
+			m.state.Entries[k] = before
+		} else {
+			delete(m.state.Entries, k)
+		}
+		return Entry{}, err
+	}
+
+	c := Change{Time: now, Action: ActionAdd, By: by, Symbol: e.Symbol, List: e.List, After: &e}
+	if existed {
+		c.Action, c.Before = ActionUpdate, &before
+	}
+	m.writeLocked(c)
+	logger().Info("Updated trade restriction", "action", c.Action, "list", e.List, "symbol", e.Symbol, "reason", e.Reason, "by", by)
+	return e, nil
+}
+
+// Remove takes symbol off list, journaling the change as made by by.
+func (m *Manager) Remove(list, symbol, by string) error {
+	symbol = normalize(symbol)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k := key(list, symbol)
+	before, ok := m.state.Entries[k]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(m.state.Entries, k)
+	if err := m.saveLocked(); err != nil {
+		m.state.Entries[k] = before
+		return err
+	}
+	m.writeLocked(Change{Time: m.now(), Action: ActionRemove, By: by, Symbol: symbol, List: list, Before: &before})
+	logger().Info("Removed trade restriction", "list", list, "symbol", symbol, "by", by)
+	return nil
+}
+
+// Check returns an error wrapping ErrRestricted if symbol is on the
+// blocklist, or if the allowlist is strict and symbol is not on it.
+// Expired entries are ignored.
+func (m *Manager) Check(symbol string) error {
+	symbol = normalize(symbol)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if e, ok := m.state.Entries[key(ListBlock, symbol)]; ok && e.Active(now) {
+		if e.Reason != "" {
+			return fmt.Errorf("%w: %s is on the blocklist (%s)", ErrRestricted, symbol, e.Reason)
+		}
+		return fmt.Errorf("%w: %s is on the blocklist", ErrRestricted, symbol)
+	}
+	if m.state.Policy.StrictAllowlist {
+		if e, ok := m.state.Entries[key(ListAllow, symbol)]; !ok || !e.Active(now) {
+			return fmt.Errorf("%w: %s is not on the allowlist", ErrRestricted, symbol)
+		}
+	}
+	return nil
+}
+
+// Journal returns recent changes, newest first, optionally only those
+// for symbol.
+func (m *Manager) Journal(symbol string, limit int) []Change {
+	symbol = normalize(symbol)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := []Change{}
+	for i := len(m.recent) - 1; i >= 0 && (limit <= 0 || len(out) < limit); i-- {
+		if symbol == "" || m.recent[i].Symbol == symbol {
+			out = append(out, m.recent[i])
+		}
+	}
+	return out
+}
+
+func (m *Manager) saveLocked() error {
+	data, err := json.MarshalIndent(m.state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode restrictions: %w", err)
+	}
+	tmp := m.statePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write restrictions: %w", err)
+	}
+	if err := os.Rename(tmp, m.statePath); err != nil {
+		return fmt.Errorf("failed to save restrictions: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager) writeLocked(c Change) {
+	m.remember(c)
+	if line, err := json.Marshal(c); err != nil {
+		logger().Error("Failed to encode restrictions journal entry", "action", c.Action, "error", err)
+	} else if _, err := m.journal.Write(append(line, '\n')); err != nil {
+		logger().Error("Failed to write restrictions journal entry", "action", c.Action, "error", err)
+	}
+}
+
+func (m *Manager) remember(c Change) {
+	m.recent = append(m.recent, c)
+	if len(m.recent) > maxRecent {
+		m.recent = m.recent[len(m.recent)-maxRecent:]
+	}
+}
+
+// Close closes the journal.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.journal.Close()
+}
+
+func normalize(symbol string) string {
+	return strings.ToUpper(strings.TrimSpace(symbol))
+}
+
+func key(list, symbol string) string {
+	return list + "/" + symbol
+}
@@ -0,0 +1,147 @@
+package restrictions
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListsAndJournalSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+	m, err := New(dir, DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetClock(func() time.Time { return now })
+
+	until := now.Add(time.Hour)
+	if _, err := m.Put(Entry{Symbol: " tsla ", List: ListBlock, Reason: "insider blackout", Until: &until}, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Put(Entry{Symbol: "GME", List: ListBlock}, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Put(Entry{Symbol: "GME", List: ListBlock, Reason: "compliance"}, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Put(Entry{Symbol: "AAPL", List: ListAllow}, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Check("tsla"); !errors.Is(err, ErrRestricted) || !strings.Contains(err.Error(), "insider blackout") {
+		t.Errorf("TSLA check = %v", err)
+	}
+	if err := m.Check("MSFT"); err != nil {
+		t.Errorf("MSFT refused without a strict allowlist: %v", err)
+	}
+	if err := m.SetPolicy(Policy{StrictAllowlist: true, AllowClosing: true}, "carol"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Check("MSFT"); !errors.Is(err, ErrRestricted) {
+		t.Errorf("MSFT allowed off a strict allowlist")
+	}
+	if err := m.Remove(ListBlock, "gme", "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Remove(ListBlock, "GME", "bob"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("second remove = %v", err)
+	}
+	m.Close()
+
+	m, err = New(dir, DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	m.SetClock(func() time.Time { return now.Add(2 * time.Hour) })
+
+	if p := m.Policy(); !p.StrictAllowlist {
+		t.Errorf("policy not restored: %+v", p)
+	}
+	l := m.List()
+	if len(l.Blocklist) != 1 || l.Blocklist[0].Symbol != "TSLA" || len(l.Allowlist) != 1 {
+		t.Errorf("lists = %+v", l)
+	}
+	if err := m.Check("TSLA"); !strings.Contains(err.Error(), "not on the allowlist") {
+		t.Errorf("expired blackout check = %v, want only the allowlist refusal", err)
+	}
+	if err := m.Check("AAPL"); err != nil {
+		t.Errorf("AAPL = %v", err)
+	}
+
+	var actions []string
+	for _, c := range m.Journal("", 0) {
+		actions = append(actions, c.Action+" "+c.Symbol+" "+c.By)
+	}
+	want := "remove GME bob,policy  carol,add AAPL bob,update GME bob,add GME alice,add TSLA alice"
+	if got := strings.Join(actions, ","); got != want {
+		t.Errorf("journal = %s, want %s", got, want)
+	}
+	if gme := m.Journal("gme", 0); len(gme) != 3 || gme[1].Before == nil || gme[1].Before.Reason != "" || gme[1].After.Reason != "compliance" {
+		t.Errorf("GME journal = %+v", gme)
+	}
+}
+
+func TestPutValidates(t *testing.T) {
+	m, err := New(t.TempDir(), DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	past := time.Now().Add(-time.Minute)
+	for _, e := range []Entry{
+		{Symbol: "", List: ListBlock},
+		{Symbol: "AAPL", List: "deny"},
+		{Symbol: "AAPL", List: ListBlock, Until: &past},
+	} {
+		if _, err := m.Put(e, "x"); err == nil {
+			t.Errorf("Put(%+v) accepted", e)
+		}
+	}
+	if n := len(m.Journal("", 0)); n != 0 {
+		t.Errorf("%d journal entries for refused puts", n)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	m, err := New(t.TempDir(), DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	mux := http.NewServeMux()
+	NewHandler(m, func(r *http.Request) string { return r.Header.Get("X-User") }).RegisterRoutes(mux)
+
+	serve := func(method, path, body string) (int, string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-User", "dana")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+	for _, c := range []struct {
+		method, path, body string
+		code               int
+		contains           string
+	}{
+		{"POST", "/api/restrictions", `{"symbol":"nflx","list":"block","reason":"earnings"}`, 200, `"added_by":"dana"`},
+		{"PUT", "/api/restrictions/allow/aapl", ``, 200, `"symbol":"AAPL"`},
+		{"GET", "/api/restrictions/block/NFLX", ``, 200, `"reason":"earnings"`},
+		{"GET", "/api/restrictions/check?symbol=nflx", ``, 200, `"allowed":false`},
+		{"GET", "/api/restrictions/deny/NFLX", ``, 404, ``},
+		{"POST", "/api/restrictions/policy", `{"strict_allowlist":true}`, 200, `"allow_closing":true`},
+		{"GET", "/api/restrictions/check?symbol=MSFT", ``, 200, `not on the allowlist`},
+		{"DELETE", "/api/restrictions/block/nflx", ``, 204, ``},
+		{"DELETE", "/api/restrictions/block/nflx", ``, 404, ``},
+		{"GET", "/api/restrictions", ``, 200, `"blocklist":[]`},
+		{"GET", "/api/restrictions/journal?symbol=NFLX", ``, 200, `"action":"remove"`},
+	} {
+		code, body := serve(c.method, c.path, c.body)
+		if code != c.code || !strings.Contains(body, c.contains) {
+			t.Errorf("%s %s = %d %s, want %d containing %s", c.method, c.path, code, body, c.code, c.contains)
+		}
+	}
+}
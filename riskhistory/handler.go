@@ -0,0 +1,89 @@
+package riskhistory
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+	"github.com/rileyseaburg/go-trader/paging"
+)
+
+// Handler exposes the risk decision history over HTTP.
+type Handler struct {
+	history *History
+}
+
+// NewHandler creates a handler for history.
+func NewHandler(history *History) *Handler {
+	return &Handler{history: history}
+}
+
+// RegisterRoutes registers the risk history routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/risk/history?days=&guard=&symbol=&near_miss=&bucket=&limit=&cursor=&sort=&filter= - denials and near misses per guard over time
+	mux.HandleFunc("/api/risk/history", h.handleHistory)
+}
+
+// recentList pages a report's recent denials and near misses, newest
+// first by default.
+var recentList = paging.List[algorithm.GuardDecision]{
+	Fields: []paging.Field[algorithm.GuardDecision]{
+		{Name: "at", Sort: func(d algorithm.GuardDecision) paging.Key { return paging.Time(d.At) }},
+		{Name: "symbol", Sort: func(d algorithm.GuardDecision) paging.Key { return paging.Text(d.Symbol) }, Filter: func(d algorithm.GuardDecision) string { return d.Symbol }},
+		{Name: "guard", Filter: func(d algorithm.GuardDecision) string { return d.Guard }},
+		{Name: "allowed", Filter: func(d algorithm.GuardDecision) string { return strconv.FormatBool(d.Allowed) }},
+		{Name: "source", Filter: func(d algorithm.GuardDecision) string { return d.Source }},
+	},
+	ID: func(d algorithm.GuardDecision) string {
+		return d.At.UTC().Format(time.RFC3339Nano) + "/" + d.Guard + "/" + d.Symbol + "/" + d.Signal
+	},
+	Sort:  "-at",
+	Limit: 50,
+}
+
+func (h *Handler) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	days, err := strconv.Atoi(q.Get("days"))
+	if q.Get("days") == "" {
+		days, err = 30, nil
+	}
+	if err != nil || days <= 0 {
+		http.Error(w, "days must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	nearMiss := DefaultNearMiss
+	if v := q.Get("near_miss"); v != "" {
+		if nearMiss, err = strconv.ParseFloat(v, 64); err != nil || nearMiss <= 0 || nearMiss >= 1 {
+			http.Error(w, "near_miss must be between 0 and 1", http.StatusBadRequest)
+			return
+		}
+	}
+	bucket := q.Get("bucket")
+	if bucket != "" && bucket != "day" && bucket != "hour" {
+		http.Error(w, "bucket must be day or hour", http.StatusBadRequest)
+		return
+	}
+	req, err := recentList.Parse(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	rep := h.history.Report(Query{
+		Since:    time.Now().AddDate(0, 0, -days),
+		Guard:    q.Get("guard"),
+		Symbol:   q.Get("symbol"),
+		NearMiss: nearMiss,
+		Bucket:   bucket,
+		Limit:    -1,
+	})
+	page := recentList.Apply(rep.Recent, req)
+	rep.Recent = page.Items
+	paging.SetHeaders(w, page.Total, page.NextCursor)
+	json.NewEncoder(w).Encode(rep)
+}
@@ -0,0 +1,307 @@
+// Package riskhistory journals every risk-engine decision — each trade
+// guard's allow or deny for a signal headed for execution, with the margin
+// to the guard's limit where the guard measures one — and reports how
+// often each guard refuses trades or nearly does over time. That tells a
+// binding limit from one that never comes close and one that blocks
+// constantly.
+package riskhistory
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+)
+
+func logger() *slog.Logger { return slog.With("module", "riskhistory") }
+
+// maxDecisions bounds the decisions kept in memory for reports; the
+// journal keeps everything.
+const maxDecisions = 50000
+
+// DefaultNearMiss is the margin under which an allowed decision counts as
+// a near miss: within 10% of the limit.
+const DefaultNearMiss = 0.1
+
+// Assessments of a guard over a report's window.
+const (
+	AssessmentInsufficient = "insufficient_data" // fewer than minDecisions
+	AssessmentBlocking     = "blocking"          // refuses at least blockingRate of decisions
+	AssessmentBinding      = "binding"           // refuses some, or nearly refuses often
+	AssessmentLoose        = "loose"             // never close to its limit
+	AssessmentOK           = "ok"
+)
+
+const (
+	minDecisions    = 10
+	blockingRate    = 0.25
+	bindingNearMiss = 0.05 // near misses as a share of measured decisions
+	looseMargin     = 0.5  // median margin of a loose guard
+	looseMeasured   = 20   // measured decisions needed to call a guard loose
+)
+
+// History journals decisions and reports over them. It is safe for
+// concurrent use.
+type History struct {
+	loc *time.Location
+
+	mu        sync.Mutex
+	decisions []algorithm.GuardDecision
+	journal   *os.File
+}
+
+// New opens the journal at path, loading it for reports. Days are
+// bucketed in loc.
+func New(path string, loc *time.Location) (*History, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create risk history directory: %w", err)
+	}
+	h := &History{loc: loc}
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			var d algorithm.GuardDecision
+			if err := json.Unmarshal(scanner.Bytes(), &d); err != nil {
+				logger().Warn("Skipping malformed risk history line", "error", err)
+				continue
+			}
+			h.remember(d)
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read risk history: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to open risk history: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open risk history for writing: %w", err)
+	}
+	h.journal = f
+	return h, nil
+}
+
+// Record journals d. It is meant for the algorithm's guard observer.
+func (h *History) Record(d algorithm.GuardDecision) {
+	if d.At.IsZero() {
+		d.At = time.Now()
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remember(d)
+	if line, err := json.Marshal(d); err != nil {
+		logger().Error("Failed to encode risk decision", "guard", d.Guard, "error", err)
+	} else if _, err := h.journal.Write(append(line, '\n')); err != nil {
+		logger().Error("Failed to write risk decision", "guard", d.Guard, "error", err)
+	}
+}
+
+func (h *History) remember(d algorithm.GuardDecision) {
+	h.decisions = append(h.decisions, d)
+	if len(h.decisions) > maxDecisions {
+		h.decisions = h.decisions[len(h.decisions)-maxDecisions:]
+	}
+}
+
+// Close closes the journal.
+func (h *History) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.journal.Close()
+}
+
+// Query selects the decisions a report covers.
+type Query struct {
+	Since    time.Time
+	Until    time.Time // zero for now
+	Guard    string    // empty for every guard
+	Symbol   string    // empty for every symbol
+	NearMiss float64   // zero for DefaultNearMiss
+	Bucket   string    // day or hour
+	Limit    int       // recent denials and near misses listed, 50 when zero, all when negative
+}
+
+// GuardStats summarizes one guard's decisions.
+type GuardStats struct {
+	Guard      string `json:"guard"`
+	Decisions  int    `json:"decisions"`
+	Allowed    int    `json:"allowed"`
+	Denied     int    `json:"denied"`
+	Measured   int    `json:"measured"` // decisions with a margin to the limit
+	NearMisses int    `json:"near_misses"`
+	// DenyRate is denials over decisions
+	DenyRate float64 `json:"deny_rate"`
+	// MedianMargin and MinMargin are over measured allowed decisions
+	MedianMargin *float64 `json:"median_margin,omitempty"`
+	MinMargin    *float64 `json:"min_margin,omitempty"`
+	Assessment   string   `json:"assessment"`
+}
+
+// Bucket counts one guard's decisions in one period.
+type Bucket struct {
+	Start      time.Time `json:"start"`
+	Guard      string    `json:"guard"`
+	Decisions  int       `json:"decisions"`
+	Denied     int       `json:"denied"`
+	NearMisses int       `json:"near_misses"`
+}
+
+// Report is breach frequency and near misses per guard over a window.
+type Report struct {
+	Since    time.Time                 `json:"since"`
+	Until    time.Time                 `json:"until"`
+	NearMiss float64                   `json:"near_miss"`
+	Bucket   string                    `json:"bucket"`
+	Guards   []GuardStats              `json:"guards"`
+	Series   []Bucket                  `json:"series"`
+	Recent   []algorithm.GuardDecision `json:"recent"` // denials and near misses, newest first
+}
+
+// nearMiss reports whether d was allowed within threshold of its limit.
+func nearMiss(d algorithm.GuardDecision, threshold float64) bool {
+	return d.Allowed && d.Measure != nil && d.Measure.Margin < threshold
+}
+
+// Report summarizes the decisions q selects.
+func (h *History) Report(q Query) Report {
+	if q.Until.IsZero() {
+		q.Until = time.Now()
+	}
+	if q.NearMiss <= 0 {
+		q.NearMiss = DefaultNearMiss
+	}
+	if q.Bucket != "hour" {
+		q.Bucket = "day"
+	}
+	if q.Limit == 0 {
+		q.Limit = 50
+	}
+
+	h.mu.Lock()
+	selected := make([]algorithm.GuardDecision, 0, len(h.decisions))
+	for _, d := range h.decisions {
+		if d.At.Before(q.Since) || d.At.After(q.Until) ||
+			(q.Guard != "" && !strings.EqualFold(d.Guard, q.Guard)) ||
+			(q.Symbol != "" && !strings.EqualFold(d.Symbol, q.Symbol)) {
+			continue
+		}
+		selected = append(selected, d)
+	}
+	h.mu.Unlock()
+
+	rep := Report{Since: q.Since, Until: q.Until, NearMiss: q.NearMiss, Bucket: q.Bucket,
+		Guards: []GuardStats{}, Series: []Bucket{}, Recent: []algorithm.GuardDecision{}}
+	stats := make(map[string]*GuardStats)
+	margins := make(map[string][]float64)
+	buckets := make(map[string]*Bucket)
+	for _, d := range selected {
+		s, ok := stats[d.Guard]
+		if !ok {
+			s = &GuardStats{Guard: d.Guard}
+			stats[d.Guard] = s
+		}
+		start := h.bucketStart(d.At, q.Bucket)
+		key := d.Guard + "|" + start.Format(time.RFC3339)
+		b, ok := buckets[key]
+		if !ok {
+			b = &Bucket{Start: start, Guard: d.Guard}
+			buckets[key] = b
+		}
+
+		s.Decisions++
+		b.Decisions++
+		if d.Allowed {
+			s.Allowed++
+		} else {
+			s.Denied++
+			b.Denied++
+		}
+		if d.Measure != nil {
+			s.Measured++
+			if d.Allowed {
+				margins[d.Guard] = append(margins[d.Guard], d.Measure.Margin)
+			}
+		}
+		if nearMiss(d, q.NearMiss) {
+			s.NearMisses++
+			b.NearMisses++
+		}
+	}
+
+	for guard, s := range stats {
+		s.DenyRate = round4(float64(s.Denied) / float64(s.Decisions))
+		if m := margins[guard]; len(m) > 0 {
+			sort.Float64s(m)
+			median, least := round4(m[len(m)/2]), round4(m[0])
+			if len(m)%2 == 0 {
+				median = round4((m[len(m)/2-1] + m[len(m)/2]) / 2)
+			}
+			s.MedianMargin, s.MinMargin = &median, &least
+		}
+		s.Assessment = assess(*s)
+		rep.Guards = append(rep.Guards, *s)
+	}
+	sort.Slice(rep.Guards, func(i, j int) bool {
+		if rep.Guards[i].Denied != rep.Guards[j].Denied {
+			return rep.Guards[i].Denied > rep.Guards[j].Denied
+		}
+		return rep.Guards[i].Guard < rep.Guards[j].Guard
+	})
+	for _, b := range buckets {
+		rep.Series = append(rep.Series, *b)
+	}
+	sort.Slice(rep.Series, func(i, j int) bool {
+		if !rep.Series[i].Start.Equal(rep.Series[j].Start) {
+			return rep.Series[i].Start.Before(rep.Series[j].Start)
+		}
+		return rep.Series[i].Guard < rep.Series[j].Guard
+	})
+	for i := len(selected) - 1; i >= 0 && (q.Limit < 0 || len(rep.Recent) < q.Limit); i-- {
+		if d := selected[i]; !d.Allowed || nearMiss(d, q.NearMiss) {
+			rep.Recent = append(rep.Recent, d)
+		}
+	}
+	return rep
+}
+
+// assess judges whether a guard's limit is binding, too loose or blocking
+// most of what reaches it.
+func assess(s GuardStats) string {
+	switch {
+	case s.Decisions < minDecisions:
+		return AssessmentInsufficient
+	case s.DenyRate >= blockingRate:
+		return AssessmentBlocking
+	case s.Denied > 0 || (s.Measured > 0 && float64(s.NearMisses)/float64(s.Measured) >= bindingNearMiss):
+		return AssessmentBinding
+	case s.Measured >= looseMeasured && s.MedianMargin != nil && *s.MedianMargin >= looseMargin:
+		return AssessmentLoose
+	}
+	return AssessmentOK
+}
+
+func (h *History) bucketStart(t time.Time, bucket string) time.Time {
+	t = t.In(h.loc)
+	if bucket == "hour" {
+		return t.Truncate(time.Hour)
+	}
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, h.loc)
+}
+
+func round4(v float64) float64 { return math.Round(v*1e4) / 1e4 }
@@ -0,0 +1,125 @@
+package riskhistory
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+)
+
+func TestReportAssessesGuards(t *testing.T) {
+	ny, _ := time.LoadLocation("America/New_York")
+	path := filepath.Join(t.TempDir(), "decisions.jsonl")
+	h, err := New(path, ny)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := time.Date(2026, 3, 2, 10, 0, 0, 0, ny)
+	record := func(at time.Time, guard string, allowed bool, margin float64) {
+		d := algorithm.GuardDecision{At: at, Symbol: "AAPL", Signal: algorithm.SignalBuy, Guard: guard, Allowed: allowed}
+		if !allowed {
+			d.Reason = "over the limit"
+		}
+		if margin != 0 {
+			d.Measure = &algorithm.GuardMeasure{Metric: "m", Margin: margin}
+		}
+		h.Record(d)
+	}
+	for i := 0; i < 20; i++ {
+		at := day.Add(time.Duration(i) * time.Minute)
+		// far from its limit every time
+		record(at, "position caps", true, 0.8)
+		// refuses half of what reaches it
+		record(at, "liquidity", i%2 == 0, 0.3)
+		// allowed, but within 10% of the limit a quarter of the time
+		margin := 0.4
+		if i%4 == 0 {
+			margin = 0.05
+		}
+		record(at.AddDate(0, 0, 1), "risk reward", true, margin)
+	}
+	record(day, "drawdown", false, 0)
+
+	rep := h.Report(Query{Since: day.AddDate(0, 0, -1), Until: day.AddDate(0, 0, 2)})
+	want := map[string]string{
+		"position caps": AssessmentLoose,
+		"liquidity":     AssessmentBlocking,
+		"risk reward":   AssessmentBinding,
+		"drawdown":      AssessmentInsufficient,
+	}
+	if len(rep.Guards) != len(want) {
+		t.Fatalf("guards = %+v", rep.Guards)
+	}
+	if rep.Guards[0].Guard != "liquidity" || rep.Guards[0].Denied != 10 || rep.Guards[0].DenyRate != 0.5 {
+		t.Errorf("most denied = %+v", rep.Guards[0])
+	}
+	for _, s := range rep.Guards {
+		if s.Assessment != want[s.Guard] {
+			t.Errorf("%s assessed %s, want %s", s.Guard, s.Assessment, want[s.Guard])
+		}
+		if s.Guard == "risk reward" && (s.NearMisses != 5 || *s.MinMargin != 0.05 || *s.MedianMargin != 0.4) {
+			t.Errorf("risk reward = %+v", s)
+		}
+	}
+	// Three guards on the first day, one on the second
+	if len(rep.Series) != 4 || !rep.Series[3].Start.Equal(time.Date(2026, 3, 3, 0, 0, 0, 0, ny)) {
+		t.Errorf("series = %+v", rep.Series)
+	}
+	// 11 denials and 5 near misses, newest first
+	if len(rep.Recent) != 16 || rep.Recent[0].Guard != "drawdown" {
+		t.Errorf("recent = %d, first %+v", len(rep.Recent), rep.Recent[0])
+	}
+
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+	h, err = New(path, ny)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	rep = h.Report(Query{Since: day.AddDate(0, 0, -1), Until: day.AddDate(0, 0, 2), Guard: "LIQUIDITY", Bucket: "hour", Limit: 3})
+	if len(rep.Guards) != 1 || rep.Guards[0].Decisions != 20 || len(rep.Series) != 1 || len(rep.Recent) != 3 {
+		t.Errorf("reloaded liquidity report = %+v", rep)
+	}
+}
+
+func TestHandlerPagesRecent(t *testing.T) {
+	h, err := New(filepath.Join(t.TempDir(), "decisions.jsonl"), time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Add(-time.Hour)
+	for i := 0; i < 5; i++ {
+		h.Record(algorithm.GuardDecision{At: start.Add(time.Duration(i) * time.Minute), Symbol: "AAPL", Guard: "liquidity"})
+	}
+	h.Record(algorithm.GuardDecision{At: start, Symbol: "MSFT", Guard: "drawdown"})
+	mux := http.NewServeMux()
+	NewHandler(h).RegisterRoutes(mux)
+
+	get := func(query string) (*httptest.ResponseRecorder, Report) {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/risk/history"+query, nil))
+		var rep Report
+		json.NewDecoder(rec.Body).Decode(&rep)
+		return rec, rep
+	}
+	rec, rep := get("?limit=2&filter=symbol:aapl")
+	if len(rep.Recent) != 2 || rep.Recent[0].At.Before(rep.Recent[1].At) || rec.Header().Get("X-Total-Count") != "5" {
+		t.Fatalf("first page %+v, total %s", rep.Recent, rec.Header().Get("X-Total-Count"))
+	}
+	if len(rep.Guards) != 2 {
+		t.Errorf("paging the recent decisions narrowed the guard stats to %+v", rep.Guards)
+	}
+	_, next := get("?limit=2&filter=symbol:aapl&cursor=" + rec.Header().Get("X-Next-Cursor"))
+	if len(next.Recent) != 2 || !next.Recent[0].At.Before(rep.Recent[1].At) {
+		t.Errorf("second page %+v", next.Recent)
+	}
+	if rec, _ := get("?limit=0"); rec.Code != http.StatusBadRequest {
+		t.Errorf("limit=0 answered %d", rec.Code)
+	}
+}
@@ -0,0 +1,182 @@
+package router
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CORSPolicy decides which cross-origin browser requests are allowed.
+type CORSPolicy struct {
+	// AllowedOrigins are exact origins such as "https://trader.example.com",
+	// subdomain wildcards such as "https://*.example.com", or "*" for any
+	AllowedOrigins []string `json:"allowed_origins"`
+	AllowedMethods []string `json:"allowed_methods"`
+	AllowedHeaders []string `json:"allowed_headers"`
+	ExposedHeaders []string `json:"exposed_headers,omitempty"`
+	// AllowCredentials lets browsers send cookies and read responses to
+	// credentialed requests; it needs explicit origins
+	AllowCredentials bool `json:"allow_credentials"`
+	// MaxAgeSeconds is how long browsers may cache a preflight; 0 omits it
+	MaxAgeSeconds int `json:"max_age_seconds"`
+}
+
+// DefaultCORSPolicy allows no cross-origin requests, so only pages served
+// by the API's own origin may call it, and lets browsers read the paging
+// headers of list endpoints and the trading mode header once origins are
+// allowed.
+func DefaultCORSPolicy() CORSPolicy {
+	return CORSPolicy{
+		AllowedOrigins: []string{},
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
+		AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key"},
+		ExposedHeaders: []string{"X-Total-Count", "X-Next-Cursor", "X-Trading-Mode"},
+		MaxAgeSeconds:  600,
+	}
+}
+
+// Validate checks the policy for usable values.
+func (p CORSPolicy) Validate() error {
+	if len(p.AllowedMethods) == 0 {
+		return errors.New("allowed_methods must not be empty")
+	}
+	for _, m := range p.AllowedMethods {
+		if m == "" || m != strings.ToUpper(m) || strings.ContainsAny(m, " ,") {
+			return fmt.Errorf("invalid method %q: methods are upper-case tokens", m)
+		}
+	}
+	for _, o := range p.AllowedOrigins {
+		if o == "*" {
+			if p.AllowCredentials {
+				return errors.New(`allow_credentials needs explicit origins, not "*"`)
+			}
+			continue
+		}
+		u, err := url.Parse(o)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			return fmt.Errorf("invalid origin %q: want scheme://host[:port]", o)
+		}
+		if host := strings.TrimPrefix(u.Host, "*."); strings.Contains(host, "*") {
+			return fmt.Errorf("invalid origin %q: only a leading *. subdomain wildcard is allowed", o)
+		}
+	}
+	if p.MaxAgeSeconds < 0 {
+		return errors.New("max_age_seconds must not be negative")
+	}
+	return nil
+}
+
+// LoadCORSPolicy reads a policy from the JSON file at path. Fields the
+// file leaves out keep their defaults; an empty path gives the default
+// policy.
+func LoadCORSPolicy(path string) (CORSPolicy, error) {
+	p := DefaultCORSPolicy()
+	if path == "" {
+		return p, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(data, &p); err != nil {
+		return p, fmt.Errorf("invalid CORS config %s: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return p, fmt.Errorf("invalid CORS config %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// AnyOrigin reports whether the policy allows every origin.
+func (p CORSPolicy) AnyOrigin() bool {
+	for _, o := range p.AllowedOrigins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsOrigin reports whether browsers at origin may call the API.
+func (p CORSPolicy) AllowsOrigin(origin string) bool {
+	origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+	if origin == "" {
+		return false
+	}
+	for _, o := range p.AllowedOrigins {
+		o = strings.ToLower(strings.TrimSuffix(o, "/"))
+		if o == "*" || o == origin {
+			return true
+		}
+		// https://*.example.com allows https://a.example.com, not the apex
+		if scheme, host, ok := strings.Cut(o, "://*."); ok {
+			rest, found := strings.CutPrefix(origin, scheme+"://")
+			if found && strings.HasSuffix(rest, "."+host) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckOrigin reports whether a WebSocket upgrade may proceed: requests
+// without an Origin header come from outside a browser and are allowed, as
+// are pages served by the API's own origin.
+func (p CORSPolicy) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return origin == "" || sameOrigin(r, origin) || p.AllowsOrigin(origin)
+}
+
+// sameOrigin reports whether origin is the host r was sent to.
+func sameOrigin(r *http.Request, origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host)
+}
+
+// CORS applies policy to every request: allowed origins get the CORS
+// response headers, other origins none, so browsers refuse to expose the
+// response to them. OPTIONS requests are answered here without reaching
+// a handler.
+func CORS(policy CORSPolicy) Middleware {
+	methods := strings.Join(policy.AllowedMethods, ", ")
+	headers := strings.Join(policy.AllowedHeaders, ", ")
+	exposed := strings.Join(policy.ExposedHeaders, ", ")
+	wildcard := policy.AnyOrigin() && !policy.AllowCredentials
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			if origin != "" && policy.AllowsOrigin(origin) {
+				h := w.Header()
+				if wildcard {
+					h.Set("Access-Control-Allow-Origin", "*")
+				} else {
+					h.Set("Access-Control-Allow-Origin", origin)
+					h.Add("Vary", "Origin")
+				}
+				if policy.AllowCredentials {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+				if exposed != "" {
+					h.Set("Access-Control-Expose-Headers", exposed)
+				}
+				if r.Method == http.MethodOptions {
+					h.Set("Access-Control-Allow-Methods", methods)
+					h.Set("Access-Control-Allow-Headers", headers)
+					if policy.MaxAgeSeconds > 0 {
+						h.Set("Access-Control-Max-Age", strconv.Itoa(policy.MaxAgeSeconds))
+					}
+				}
+			}
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
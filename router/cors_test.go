@@ -0,0 +1,93 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCORSPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cors.json")
+	os.WriteFile(path, []byte(`{"allowed_origins": ["https://trader.example.com", "https://*.example.org"], "allow_credentials": true}`), 0644)
+	p, err := LoadCORSPolicy(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.AllowedMethods) == 0 || p.MaxAgeSeconds != 600 {
+		t.Errorf("defaults not kept: %+v", p)
+	}
+	for origin, want := range map[string]bool{
+		"https://trader.example.com":  true,
+		"https://TRADER.example.com/": true,
+		"http://trader.example.com":   false,
+		"https://a.b.example.org":     true,
+		"https://example.org":         false,
+		"https://evil-example.org":    false,
+		"":                            false,
+	} {
+		if got := p.AllowsOrigin(origin); got != want {
+			t.Errorf("AllowsOrigin(%q) = %v", origin, got)
+		}
+	}
+
+	h := CORS(p)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusTeapot) }))
+	serve := func(method, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/account", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	rec := serve(http.MethodGet, "https://trader.example.com")
+	if rec.Code != http.StatusTeapot || rec.Header().Get("Access-Control-Allow-Origin") != "https://trader.example.com" ||
+		rec.Header().Get("Access-Control-Allow-Credentials") != "true" || rec.Header().Get("Vary") != "Origin" {
+		t.Errorf("allowed origin got %d %v", rec.Code, rec.Header())
+	}
+	rec = serve(http.MethodOptions, "https://a.example.org")
+	if rec.Code != http.StatusOK || rec.Header().Get("Access-Control-Allow-Methods") == "" || rec.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("preflight got %d %v", rec.Code, rec.Header())
+	}
+	rec = serve(http.MethodGet, "https://evil.com")
+	if rec.Code != http.StatusTeapot || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("other origin got %d %v", rec.Code, rec.Header())
+	}
+
+	h = CORS(DefaultCORSPolicy())(http.NotFoundHandler())
+	rec = serve(http.MethodGet, "https://anywhere.test")
+	if rec.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("default policy got %v", rec.Header())
+	}
+
+	// WebSockets: the default admits only the API's own origin
+	upgrade := func(origin string) bool {
+		req := httptest.NewRequest(http.MethodGet, "http://trader.local:8080/ws/portfolio", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		return DefaultCORSPolicy().CheckOrigin(req)
+	}
+	if !upgrade("http://trader.local:8080") || !upgrade("") || upgrade("https://evil.com") || upgrade("http://trader.local:3000") {
+		t.Error("default WebSocket origin check")
+	}
+}
+
+func TestCORSPolicyValidate(t *testing.T) {
+	for name, p := range map[string]CORSPolicy{
+		"credentials with any origin": {AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}, AllowCredentials: true},
+		"origin with a path":          {AllowedOrigins: []string{"https://a.com/app"}, AllowedMethods: []string{"GET"}},
+		"inner wildcard":              {AllowedOrigins: []string{"https://a.*.com"}, AllowedMethods: []string{"GET"}},
+		"lower-case method":           {AllowedOrigins: []string{"*"}, AllowedMethods: []string{"get"}},
+		"no methods":                  {AllowedOrigins: []string{"*"}},
+	} {
+		if p.Validate() == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+	if err := DefaultCORSPolicy().Validate(); err != nil {
+		t.Errorf("default policy: %v", err)
+	}
+}
@@ -0,0 +1,157 @@
+package router
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// Methods refuses requests whose method is not one of methods with 405 and
+// an Allow header. Put it after CORS so preflight requests are answered.
+func Methods(methods ...string) Middleware {
+	allow := strings.Join(methods, ", ")
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for _, m := range methods {
+				if r.Method == m {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			w.Header().Set("Allow", allow)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		})
+	}
+}
+
+// Recover turns a panicking handler into a 500 response and logs the
+// panic with its stack, instead of dropping the connection.
+func Recover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			p := recover()
+			if p == nil {
+				return
+			}
+			if p == http.ErrAbortHandler {
+				panic(p)
+			}
+			logger().Error("HTTP handler panicked", "method", r.Method, "path", r.URL.Path,
+				"panic", p, "stack", string(debug.Stack()))
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// JSON makes JSON the Content-Type of responses to requests under prefix,
+// such as "/api/", unless the handler sets its own before writing, as a
+// CSV export or a file server does.
+func JSON(prefix string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, prefix) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(&jsonWriter{ResponseWriter: w}, r)
+		})
+	}
+}
+
+// jsonWriter defaults the Content-Type to JSON when the response starts.
+type jsonWriter struct {
+	http.ResponseWriter
+	started bool
+}
+
+func (j *jsonWriter) start() {
+	if j.started {
+		return
+	}
+	j.started = true
+	if h := j.Header(); h.Get("Content-Type") == "" {
+		h.Set("Content-Type", "application/json")
+	}
+}
+
+func (j *jsonWriter) WriteHeader(code int) {
+	j.start()
+	j.ResponseWriter.WriteHeader(code)
+}
+
+func (j *jsonWriter) Write(b []byte) (int, error) {
+	j.start()
+	return j.ResponseWriter.Write(b)
+}
+
+func (j *jsonWriter) Flush() {
+	j.start()
+	if f, ok := j.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (j *jsonWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := j.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	return h.Hijack()
+}
+
+// Log logs every request at debug level with its status and latency, and
+// server errors at error level.
+func Log(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		level := slog.LevelDebug
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger().Log(r.Context(), level, "HTTP request", "method", r.Method, "path", r.URL.Path,
+			"status", rec.status, "latency_ms", float64(time.Since(start).Microseconds())/1000)
+	})
+}
+
+// statusRecorder captures the response status while still supporting
+// streaming and connection upgrades.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (s *statusRecorder) WriteHeader(code int) {
+	if !s.wroteHeader {
+		s.status, s.wroteHeader = code, true
+	}
+	s.ResponseWriter.WriteHeader(code)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	return s.ResponseWriter.Write(b)
+}
+
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	s.status, s.wroteHeader = http.StatusSwitchingProtocols, true
+	return h.Hijack()
+}
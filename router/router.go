@@ -0,0 +1,136 @@
+// Package router dispatches HTTP requests with the standard library's
+// pattern mux, adding route groups that share a path prefix and
+// middleware, per-route middleware, and aliases that serve a route under
+// more than one path.
+package router
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+func logger() *slog.Logger { return slog.With("module", "router") }
+
+// Middleware wraps a handler with behavior shared by several routes.
+type Middleware func(http.Handler) http.Handler
+
+// Router registers routes on a mux shared with its groups. A group adds a
+// path prefix and middleware to the routes registered through it.
+type Router struct {
+	root       bool
+	shared     *shared
+	prefix     string
+	middleware []Middleware
+}
+
+// shared is the state every group of a router registers into.
+type shared struct {
+	mux     *http.ServeMux
+	aliases *http.ServeMux
+	// middleware wraps every request, set by Use on the router New returns
+	middleware []Middleware
+}
+
+// New creates an empty router.
+func New() *Router {
+	return &Router{root: true, shared: &shared{mux: http.NewServeMux(), aliases: http.NewServeMux()}}
+}
+
+// Use adds middleware, outermost first. On the router New returns it
+// wraps every request, including those to routes registered on Mux and
+// to unmatched paths; on a group it wraps the routes registered through
+// the group from then on.
+func (rt *Router) Use(mw ...Middleware) {
+	if rt.root {
+		rt.shared.middleware = append(rt.shared.middleware, mw...)
+		return
+	}
+	rt.middleware = append(rt.middleware, mw...)
+}
+
+// Group returns a router whose routes are registered under prefix and
+// wrapped with this router's group middleware.
+func (rt *Router) Group(prefix string) *Router {
+	return rt.derive(rt.prefix+strings.TrimSuffix(prefix, "/"), nil)
+}
+
+// With returns a router with the same prefix whose routes are also
+// wrapped with mw, for middleware that only some routes need.
+func (rt *Router) With(mw ...Middleware) *Router {
+	return rt.derive(rt.prefix, mw)
+}
+
+func (rt *Router) derive(prefix string, mw []Middleware) *Router {
+	middleware := make([]Middleware, 0, len(rt.middleware)+len(mw))
+	middleware = append(append(middleware, rt.middleware...), mw...)
+	return &Router{shared: rt.shared, prefix: prefix, middleware: middleware}
+}
+
+// Handle registers h for pattern, a standard library mux pattern such as
+// "/baskets/{id}" or "GET /baskets/{id}/export" relative to the group's
+// prefix. Path wildcards are read with r.PathValue.
+func (rt *Router) Handle(pattern string, h http.Handler) {
+	for i := len(rt.middleware) - 1; i >= 0; i-- {
+		h = rt.middleware[i](h)
+	}
+	rt.shared.mux.Handle(rt.pattern(pattern), h)
+}
+
+// HandleFunc registers f for pattern, as Handle.
+func (rt *Router) HandleFunc(pattern string, f func(http.ResponseWriter, *http.Request)) {
+	rt.Handle(pattern, http.HandlerFunc(f))
+}
+
+// pattern prefixes the path of pattern, keeping any method in front.
+func (rt *Router) pattern(pattern string) string {
+	if method, path, ok := strings.Cut(pattern, " "); ok && strings.HasPrefix(path, "/") {
+		return method + " " + rt.prefix + path
+	}
+	return rt.prefix + pattern
+}
+
+// Mux is the mux routes are registered on, for handlers that register
+// their own routes. Those routes get the middleware added with Use on the
+// router New returns, but no group middleware.
+func (rt *Router) Mux() *http.ServeMux { return rt.shared.mux }
+
+// wildcard matches the wildcards of a pattern, {name} or {name...}.
+var wildcard = regexp.MustCompile(`\{(\w+)(\.\.\.)?\}`)
+
+// Alias serves requests matching pattern as if they were made to target,
+// both relative to the group's prefix. Target refers to the wildcards of
+// pattern: Alias("/v1/{path...}", "/{path...}") serves every route under
+// /v1 as well. Aliases are resolved before any middleware runs, so
+// middleware only sees the target path; an alias may lead to another.
+func (rt *Router) Alias(pattern, target string) {
+	target = rt.prefix + target
+	rt.shared.aliases.HandleFunc(rt.pattern(pattern), func(w http.ResponseWriter, r *http.Request) {
+		path := wildcard.ReplaceAllStringFunc(target, func(m string) string {
+			return r.PathValue(wildcard.FindStringSubmatch(m)[1])
+		})
+		aliased := new(http.Request)
+		*aliased = *r
+		aliased.URL = new(url.URL)
+		*aliased.URL = *r.URL
+		aliased.URL.Path, aliased.URL.RawPath = path, ""
+		aliased.RequestURI = aliased.URL.RequestURI()
+		rt.ServeHTTP(w, aliased)
+	})
+}
+
+// ServeHTTP resolves aliases, then dispatches the request through the
+// router's middleware to the matching route.
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _, pattern := rt.shared.aliases.Handler(r); pattern != "" {
+		rt.shared.aliases.ServeHTTP(w, r)
+		return
+	}
+	var h http.Handler = rt.shared.mux
+	for i := len(rt.shared.middleware) - 1; i >= 0; i-- {
+		h = rt.shared.middleware[i](h)
+	}
+	h.ServeHTTP(w, r)
+}
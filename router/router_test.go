@@ -0,0 +1,97 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGroupsMiddlewareAndAliases(t *testing.T) {
+	var order []string
+	tag := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name+" "+r.URL.Path)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+	rt := New()
+	rt.Use(tag("root"))
+	api := rt.Group("/api/")
+	api.Use(tag("api"))
+	items := api.Group("/items")
+	items.With(Methods(http.MethodPost)).HandleFunc("/{id}/move", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("move " + r.PathValue("id")))
+	})
+	items.HandleFunc("/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("item " + r.PathValue("id")))
+	})
+	rt.Mux().HandleFunc("/api/own", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("own")) })
+	items.Alias("/move/{id}", "/{id}/move")
+	rt.Alias("/api/v1/{path...}", "/api/{path...}")
+
+	serve := func(method, path string) (int, string) {
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec.Code, strings.TrimSpace(rec.Body.String())
+	}
+	for _, c := range []struct {
+		method, path string
+		code         int
+		body         string
+		order        []string
+	}{
+		{"GET", "/api/items/a", 200, "item a", []string{"root /api/items/a", "api /api/items/a"}},
+		{"GET", "/api/v1/items/b", 200, "item b", []string{"root /api/items/b", "api /api/items/b"}},
+		{"POST", "/api/v1/items/move/c", 200, "move c", []string{"root /api/items/c/move", "api /api/items/c/move"}},
+		{"GET", "/api/items/c/move", 405, "Method not allowed", []string{"root /api/items/c/move", "api /api/items/c/move"}},
+		{"GET", "/api/v1/own", 200, "own", []string{"root /api/own"}},
+		{"GET", "/missing", 404, "404 page not found", []string{"root /missing"}},
+	} {
+		order = nil
+		code, body := serve(c.method, c.path)
+		if code != c.code || body != c.body || strings.Join(order, ",") != strings.Join(c.order, ",") {
+			t.Errorf("%s %s = %d %q through %v, want %d %q through %v", c.method, c.path, code, body, order, c.code, c.body, c.order)
+		}
+	}
+}
+
+func TestRecover(t *testing.T) {
+	rt := New()
+	rt.Use(Recover)
+	rt.HandleFunc("/boom", func(w http.ResponseWriter, r *http.Request) { panic("boom") })
+
+	rec := httptest.NewRecorder()
+	rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/boom", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("panic gave %d", rec.Code)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	rt := New()
+	rt.Use(JSON("/api/"))
+	rt.HandleFunc("/api/status", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(`{}`)) })
+	rt.HandleFunc("/api/export", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Write([]byte("a,b\n"))
+	})
+	rt.HandleFunc("/api/missing", func(w http.ResponseWriter, r *http.Request) { http.Error(w, "not found", http.StatusNotFound) })
+	rt.HandleFunc("/index.html", func(w http.ResponseWriter, r *http.Request) { w.Write([]byte("<html></html>")) })
+
+	cases := map[string]string{
+		"/api/status":  "application/json",
+		"/api/export":  "text/csv",
+		"/api/missing": "text/plain; charset=utf-8",
+		"/index.html":  "text/html; charset=utf-8",
+	}
+	for path, want := range cases {
+		rec := httptest.NewRecorder()
+		rt.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if got := rec.Header().Get("Content-Type"); got != want {
+			t.Errorf("%s Content-Type = %q, want %q", path, got, want)
+		}
+	}
+}
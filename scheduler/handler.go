@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Handler exposes scheduled jobs over HTTP.
+type Handler struct {
+	scheduler *Scheduler
+}
+
+// NewHandler creates a handler for scheduler.
+func NewHandler(scheduler *Scheduler) *Handler {
+	return &Handler{scheduler: scheduler}
+}
+
+// RegisterRoutes registers the scheduler routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/scheduler - jobs with next and last run
+	mux.HandleFunc("/api/scheduler", h.handleStatus)
+
+	// POST /api/scheduler/run?job=premarket - run a job now
+	mux.HandleFunc("/api/scheduler/run", h.handleRun)
+}
+
+func (h *Handler) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"jobs": h.scheduler.Status(),
+	})
+}
+
+func (h *Handler) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimSpace(r.URL.Query().Get("job"))
+	if name == "" {
+		http.Error(w, "Job is required", http.StatusBadRequest)
+		return
+	}
+	if err := h.scheduler.Trigger(name); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"job":     name,
+	})
+}
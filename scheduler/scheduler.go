@@ -0,0 +1,259 @@
+// Package scheduler runs named jobs at times derived from the market
+// calendar — "45 minutes before the open", "after the close" — and keeps
+// per-job status so runs can be inspected and triggered over HTTP.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/calendar"
+)
+
+func logger() *slog.Logger { return slog.With("module", "scheduler") }
+
+// Schedule returns the first run time strictly after now. A zero time
+// means the job has nothing scheduled.
+type Schedule func(now time.Time) time.Time
+
+// BeforeOpen schedules a job d before each regular session opens.
+func BeforeOpen(cal *calendar.Calendar, d time.Duration) Schedule {
+	return func(now time.Time) time.Time {
+		s := cal.NextSession(now)
+		for i := 0; i < 2 && !s.Open.IsZero(); i++ {
+			if at := s.Open.Add(-d); at.After(now) {
+				return at
+			}
+			s = cal.NextSession(s.Close)
+		}
+		return time.Time{}
+	}
+}
+
+// AfterClose schedules a job d after each regular session closes.
+func AfterClose(cal *calendar.Calendar, d time.Duration) Schedule {
+	return func(now time.Time) time.Time {
+		// Today's or the previous session may have closed but still be due
+		if today, ok := cal.SessionFor(now); ok {
+			if at := today.Close.Add(d); at.After(now) {
+				return at
+			}
+		}
+		if prev := cal.PreviousSession(now); !prev.Close.IsZero() {
+			if at := prev.Close.Add(d); at.After(now) {
+				return at
+			}
+		}
+		s := cal.NextSession(now)
+		for i := 0; i < 2 && !s.Close.IsZero(); i++ {
+			if at := s.Close.Add(d); at.After(now) {
+				return at
+			}
+			s = cal.NextSession(s.Close)
+		}
+		return time.Time{}
+	}
+}
+
+// Job is a named unit of scheduled work.
+type Job struct {
+	Name        string
+	Description string
+	Schedule    Schedule
+	Run         func(ctx context.Context) error
+}
+
+// JobStatus reports a job's schedule and last outcome.
+type JobStatus struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	NextRun     *time.Time `json:"next_run,omitempty"`
+	LastRun     *time.Time `json:"last_run,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	Duration    string     `json:"last_duration,omitempty"`
+	Running     bool       `json:"running"`
+	Runs        int        `json:"runs"`
+}
+
+type jobState struct {
+	job      Job
+	next     time.Time
+	lastRun  time.Time
+	lastErr  error
+	duration time.Duration
+	running  bool
+	runs     int
+}
+
+// Scheduler polls its jobs and runs the ones that are due. Each job runs
+// at most once at a time.
+type Scheduler struct {
+	interval time.Duration
+	now      func() time.Time
+
+	mu   sync.Mutex
+	jobs map[string]*jobState
+	ctx  context.Context
+}
+
+// New creates a scheduler that checks for due jobs every interval.
+func New(interval time.Duration) *Scheduler {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return &Scheduler{
+		interval: interval,
+		now:      time.Now,
+		jobs:     make(map[string]*jobState),
+		ctx:      context.Background(),
+	}
+}
+
+// SetClock replaces the clock used to compute schedules, for replays that
+// drive Tick from simulated time instead of calling Run. Call it before
+// adding jobs.
+func (s *Scheduler) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// Add registers job, replacing any job of the same name.
+func (s *Scheduler) Add(job Job) error {
+	if job.Name == "" || job.Run == nil || job.Schedule == nil {
+		return fmt.Errorf("job needs a name, a schedule and a run function")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[job.Name] = &jobState{job: job, next: job.Schedule(s.now())}
+	return nil
+}
+
+// Rearm recomputes every job's next run time from the calendar, e.g.
+// after a holiday table or clock change.
+func (s *Scheduler) Rearm() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for _, st := range s.jobs {
+		st.next = st.job.Schedule(now)
+	}
+}
+
+// Status returns all jobs ordered by next run time.
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]JobStatus, 0, len(s.jobs))
+	for _, st := range s.jobs {
+		js := JobStatus{
+			Name:        st.job.Name,
+			Description: st.job.Description,
+			Running:     st.running,
+			Runs:        st.runs,
+		}
+		if !st.next.IsZero() {
+			next := st.next
+			js.NextRun = &next
+		}
+		if !st.lastRun.IsZero() {
+			last := st.lastRun
+			js.LastRun = &last
+			js.Duration = st.duration.String()
+		}
+		if st.lastErr != nil {
+			js.LastError = st.lastErr.Error()
+		}
+		out = append(out, js)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].NextRun == nil || out[j].NextRun == nil {
+			return out[j].NextRun == nil && out[i].NextRun != nil
+		}
+		return out[i].NextRun.Before(*out[j].NextRun)
+	})
+	return out
+}
+
+// Trigger runs the named job now in the background without changing its
+// schedule.
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	st, ok := s.jobs[name]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("no job named %q", name)
+	}
+	if st.running {
+		s.mu.Unlock()
+		return fmt.Errorf("job %q is already running", name)
+	}
+	st.running = true
+	ctx := s.ctx
+	s.mu.Unlock()
+
+	go s.execute(ctx, st)
+	return nil
+}
+
+// Tick starts every job due at now and advances its schedule.
+func (s *Scheduler) Tick(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	var due []*jobState
+	for _, st := range s.jobs {
+		if st.next.IsZero() || now.Before(st.next) {
+			continue
+		}
+		st.next = st.job.Schedule(now)
+		if st.running {
+			logger().Warn("Skipping job, previous run still in progress", "job", st.job.Name)
+			continue
+		}
+		st.running = true
+		due = append(due, st)
+	}
+	s.mu.Unlock()
+
+	for _, st := range due {
+		go s.execute(ctx, st)
+	}
+}
+
+func (s *Scheduler) execute(ctx context.Context, st *jobState) {
+	started := s.now()
+	logger().Info("Running job", "job", st.job.Name)
+	err := st.job.Run(ctx)
+	if err != nil {
+		logger().Error("Job failed", "job", st.job.Name, "error", err)
+	}
+
+	s.mu.Lock()
+	st.running = false
+	st.lastRun = started
+	st.lastErr = err
+	st.duration = s.now().Sub(started)
+	st.runs++
+	s.mu.Unlock()
+}
+
+// Run polls for due jobs until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+
+	t := time.NewTicker(s.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			s.Tick(ctx, now)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/calendar"
+)
+
+func TestBeforeOpen(t *testing.T) {
+	cal := calendar.New()
+	loc := cal.Location()
+	sched := BeforeOpen(cal, 45*time.Minute)
+
+	// Friday evening → Monday 08:45
+	got := sched(time.Date(2024, 5, 3, 18, 0, 0, 0, loc))
+	if want := time.Date(2024, 5, 6, 8, 45, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("after Friday close = %v, want %v", got, want)
+	}
+
+	// Monday 09:00 is past today's slot even though the session hasn't opened
+	got = sched(time.Date(2024, 5, 6, 9, 0, 0, 0, loc))
+	if want := time.Date(2024, 5, 7, 8, 45, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("after slot = %v, want %v", got, want)
+	}
+
+	// Thursday before Good Friday 2024 → the following Monday
+	got = sched(time.Date(2024, 3, 28, 12, 0, 0, 0, loc))
+	if want := time.Date(2024, 4, 1, 8, 45, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("over holiday = %v, want %v", got, want)
+	}
+}
+
+func TestAfterClose(t *testing.T) {
+	cal := calendar.New()
+	loc := cal.Location()
+	sched := AfterClose(cal, 15*time.Minute)
+
+	// Black Friday closes early at 13:00
+	got := sched(time.Date(2024, 11, 29, 10, 0, 0, 0, loc))
+	if want := time.Date(2024, 11, 29, 13, 15, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("early close = %v, want %v", got, want)
+	}
+
+	// Just after the close, the slot for the same day is still due
+	got = sched(time.Date(2024, 5, 6, 16, 5, 0, 0, loc))
+	if want := time.Date(2024, 5, 6, 16, 15, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("post-close = %v, want %v", got, want)
+	}
+}
+
+func TestTickRunsDueJobsAndReschedules(t *testing.T) {
+	s := New(time.Second)
+	base := time.Date(2024, 5, 6, 8, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return base }
+
+	done := make(chan struct{}, 1)
+	err := s.Add(Job{
+		Name:     "prep",
+		Schedule: func(now time.Time) time.Time { return now.Truncate(time.Hour).Add(time.Hour) },
+		Run: func(ctx context.Context) error {
+			done <- struct{}{}
+			return errors.New("boom")
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.Tick(context.Background(), base.Add(30*time.Minute))
+	select {
+	case <-done:
+		t.Fatal("job ran before it was due")
+	default:
+	}
+
+	s.Tick(context.Background(), base.Add(time.Hour))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("due job did not run")
+	}
+
+	// Let execute record the outcome
+	deadline := time.Now().Add(time.Second)
+	for {
+		st := s.Status()[0]
+		if st.Runs == 1 {
+			if st.LastError != "boom" {
+				t.Errorf("last error = %q", st.LastError)
+			}
+			if want := base.Add(2 * time.Hour); st.NextRun == nil || !st.NextRun.Equal(want) {
+				t.Errorf("next run = %v, want %v", st.NextRun, want)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("run was not recorded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := s.Trigger("missing"); err == nil {
+		t.Error("expected error triggering unknown job")
+	}
+}
@@ -0,0 +1,138 @@
+package secrets
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// liveKeyPrefix starts every live Alpaca key ID; paper keys start with PK.
+const liveKeyPrefix = "AK"
+
+// Alpaca key names per trading mode, in lookup order.
+var (
+	PaperKeyNames    = []string{"PAPER_ALPACA_API_KEY"}
+	PaperSecretNames = []string{"PAPER_ALPACA_SECRET_KEY", "PAPAER_ALPACA_SECRET_KEY"} // the second is a common typo
+	LiveKeyNames     = []string{"LIVE_ALPACA_API_KEY"}
+	LiveSecretNames  = []string{"LIVE_ALPACA_SECRET_KEY"}
+)
+
+// Credentials is an Alpaca key pair. Pass it by reference. It never
+// prints, marshals or logs the key or secret, only a fingerprint of the key
+// ID and where the pair came from.
+type Credentials struct {
+	KeyID  string
+	Secret string
+	Source string // the key ID's source, such as env:PAPER_ALPACA_API_KEY
+	Paper  bool
+	Mock   bool // placeholder keys for mock and replay runs
+}
+
+// MockCredentials returns placeholder keys that are never sent anywhere.
+func MockCredentials(paper bool) *Credentials {
+	return &Credentials{KeyID: "MOCK_ALPACA_API_KEY", Secret: "MOCK_ALPACA_SECRET_KEY", Source: "mock", Paper: paper, Mock: true}
+}
+
+// Alpaca loads the key pair for the trading mode.
+func (l *Loader) Alpaca(ctx context.Context, paper bool) (*Credentials, error) {
+	keyNames, secretNames := LiveKeyNames, LiveSecretNames
+	if paper {
+		keyNames, secretNames = PaperKeyNames, PaperSecretNames
+	}
+	keyID, source, err := l.Lookup(ctx, keyNames...)
+	if err != nil {
+		return nil, err
+	}
+	secret, _, err := l.Lookup(ctx, secretNames...)
+	if err != nil {
+		return nil, err
+	}
+	return &Credentials{KeyID: keyID, Secret: secret, Source: source, Paper: paper}, nil
+}
+
+// Empty reports whether either half of the pair is missing.
+func (c *Credentials) Empty() bool {
+	return c == nil || c.KeyID == "" || c.Secret == ""
+}
+
+// IsLiveKey reports whether the key ID is a live-trading key.
+func (c *Credentials) IsLiveKey() bool {
+	return c != nil && strings.HasPrefix(c.KeyID, liveKeyPrefix)
+}
+
+// Fingerprint identifies the key ID without revealing any of it: the first
+// 12 hex digits of its SHA-256.
+func (c *Credentials) Fingerprint() string {
+	if c == nil || c.KeyID == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(c.KeyID))
+	return hex.EncodeToString(sum[:6])
+}
+
+// Redact replaces the key and secret wherever they appear in s, for error
+// messages that may echo a request.
+func (c *Credentials) Redact(s string) string {
+	if c == nil {
+		return s
+	}
+	for _, v := range []string{c.Secret, c.KeyID} {
+		if v != "" {
+			s = strings.ReplaceAll(s, v, "[REDACTED]")
+		}
+	}
+	return s
+}
+
+// Summary is what may be shown of a key pair.
+type Summary struct {
+	Fingerprint string `json:"fingerprint"`
+	Source      string `json:"source"`
+	Paper       bool   `json:"paper"`
+	Mock        bool   `json:"mock,omitempty"`
+}
+
+// Summary returns the credentials' displayable fields.
+func (c *Credentials) Summary() Summary {
+	if c == nil {
+		return Summary{}
+	}
+	return Summary{Fingerprint: c.Fingerprint(), Source: c.Source, Paper: c.Paper, Mock: c.Mock}
+}
+
+// String implements fmt.Stringer without the key or secret.
+func (c *Credentials) String() string {
+	s := c.Summary()
+	return fmt.Sprintf("alpaca credentials %s from %s", s.Fingerprint, s.Source)
+}
+
+// Format prints String for every verb, so %+v and %#v cannot reveal the
+// fields.
+func (c *Credentials) Format(f fmt.State, _ rune) {
+	fmt.Fprint(f, c.String())
+}
+
+// MarshalJSON marshals the summary only.
+func (c *Credentials) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Summary())
+}
+
+// UnmarshalJSON refuses to decode keys, so a request body cannot set them.
+func (c *Credentials) UnmarshalJSON([]byte) error {
+	return errors.New("credentials cannot be decoded")
+}
+
+// LogValue implements slog.LogValuer with the summary only.
+func (c *Credentials) LogValue() slog.Value {
+	s := c.Summary()
+	return slog.GroupValue(
+		slog.String("fingerprint", s.Fingerprint),
+		slog.String("source", s.Source),
+		slog.Bool("paper", s.Paper),
+	)
+}
@@ -0,0 +1,43 @@
+package secrets
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Handler exposes the credentials' summary and validation over HTTP. No
+// route ever returns the key or secret.
+type Handler struct {
+	creds     *Credentials
+	validator *Validator
+}
+
+// NewHandler creates a handler for creds.
+func NewHandler(creds *Credentials, validator *Validator) *Handler {
+	return &Handler{creds: creds, validator: validator}
+}
+
+// RegisterRoutes registers the credential routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/credentials - key fingerprint, source and mode
+	mux.HandleFunc("/api/credentials", h.handleSummary)
+
+	// GET|POST /api/credentials/validate - check the keys against Alpaca
+	mux.HandleFunc("/api/credentials/validate", h.handleValidate)
+}
+
+func (h *Handler) handleSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(h.creds.Summary())
+}
+
+func (h *Handler) handleValidate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(h.validator.Validate())
+}
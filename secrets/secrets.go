@@ -0,0 +1,140 @@
+// Package secrets loads credentials from a chain of providers — command
+// line flags, the environment, a secrets directory and optionally Vault —
+// and hands them out as typed values that never print their contents.
+// Every value a Loader returns is registered for log redaction first.
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound is returned when no provider has a secret.
+var ErrNotFound = errors.New("secret not found")
+
+// Provider is one source of secrets.
+type Provider interface {
+	// Lookup returns the secret named key and where it was found, or an
+	// error wrapping ErrNotFound when the provider does not have it.
+	Lookup(ctx context.Context, key string) (value, source string, err error)
+}
+
+// Env reads secrets from environment variables named by the key.
+type Env struct{}
+
+// Lookup implements Provider.
+func (Env) Lookup(_ context.Context, key string) (string, string, error) {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v, "env:" + key, nil
+	}
+	return "", "", ErrNotFound
+}
+
+// File reads secrets from one file per key in Dir, as Docker and
+// Kubernetes mount them. The key's file name may also be lower case.
+type File struct {
+	Dir string
+}
+
+// Lookup implements Provider.
+func (f File) Lookup(_ context.Context, key string) (string, string, error) {
+	for _, name := range []string{key, strings.ToLower(key)} {
+		path := filepath.Join(f.Dir, name)
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", "", fmt.Errorf("read secret file %s: %w", path, err)
+		}
+		if v := strings.TrimSpace(string(data)); v != "" {
+			return v, "file:" + path, nil
+		}
+	}
+	return "", "", ErrNotFound
+}
+
+// Static serves fixed values, such as keys given on the command line.
+type Static struct {
+	Name   string // reported as the source
+	Values map[string]string
+}
+
+// Lookup implements Provider.
+func (s Static) Lookup(_ context.Context, key string) (string, string, error) {
+	if v := s.Values[key]; v != "" {
+		return v, s.Name, nil
+	}
+	return "", "", ErrNotFound
+}
+
+// VaultReader reads one field of a KV secret; cartography.VaultLoader
+// implements it.
+type VaultReader interface {
+	Field(ctx context.Context, path, field string) (string, error)
+}
+
+// Vault reads secrets as fields of the KV secret at Path. A key's field is
+// its lower-case name unless Fields maps it.
+type Vault struct {
+	Reader VaultReader
+	Path   string
+	Fields map[string]string
+}
+
+// Lookup implements Provider. Every Vault error, including an unreachable
+// server, is reported as not found so the next provider is tried.
+func (v Vault) Lookup(ctx context.Context, key string) (string, string, error) {
+	field := v.Fields[key]
+	if field == "" {
+		field = strings.ToLower(key)
+	}
+	value, err := v.Reader.Field(ctx, v.Path, field)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	return value, "vault:" + v.Path + "#" + field, nil
+}
+
+// Loader looks secrets up through its providers in order.
+type Loader struct {
+	providers []Provider
+	redact    func(values ...string)
+}
+
+// NewLoader returns a loader trying providers in order. redact, when not
+// nil, receives every value found before it is returned.
+func NewLoader(redact func(values ...string), providers ...Provider) *Loader {
+	return &Loader{providers: providers, redact: redact}
+}
+
+// Lookup returns the first of keys found in the first provider that has
+// any of them, and where it was found.
+func (l *Loader) Lookup(ctx context.Context, keys ...string) (value, source string, err error) {
+	var errs []error
+	for _, p := range l.providers {
+		for _, key := range keys {
+			value, source, err := p.Lookup(ctx, key)
+			if err == nil {
+				if l.redact != nil {
+					l.redact(value)
+				}
+				return value, source, nil
+			}
+			if !errors.Is(err, ErrNotFound) {
+				return "", "", err
+			}
+			if err != ErrNotFound {
+				errs = append(errs, err) // a provider's reason, such as Vault being unreachable
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return "", "", fmt.Errorf("%w: %s (%v)", ErrNotFound, strings.Join(keys, " or "), errors.Join(errs...))
+	}
+	return "", "", fmt.Errorf("%w: %s", ErrNotFound, strings.Join(keys, " or "))
+}
@@ -0,0 +1,131 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type stubVault struct {
+	fields map[string]string
+	err    error
+}
+
+func (s stubVault) Field(_ context.Context, path, field string) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	if v, ok := s.fields[path+"#"+field]; ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("vault: field %q not present at %s", field, path)
+}
+
+func TestLoaderTriesProvidersInOrderAndRegistersValues(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "paper_alpaca_secret_key"), []byte("file-secret-value\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PAPER_ALPACA_API_KEY", "PKENVKEY000000000000")
+	t.Setenv("PAPER_ALPACA_SECRET_KEY", "")
+
+	var redacted []string
+	l := NewLoader(func(v ...string) { redacted = append(redacted, v...) },
+		Vault{Reader: stubVault{err: errors.New("connection refused")}, Path: "secret/go-trader/alpaca"},
+		Env{},
+		File{Dir: dir},
+	)
+	creds, err := l.Alpaca(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if creds.KeyID != "PKENVKEY000000000000" || creds.Secret != "file-secret-value" {
+		t.Fatalf("creds = %q/%q", creds.KeyID, creds.Secret)
+	}
+	if creds.Source != "env:PAPER_ALPACA_API_KEY" || !creds.Paper {
+		t.Fatalf("summary = %+v", creds.Summary())
+	}
+	if len(redacted) != 2 {
+		t.Fatalf("registered for redaction: %d values", len(redacted))
+	}
+
+	_, _, err = l.Lookup(context.Background(), "MISSING_KEY")
+	if !errors.Is(err, ErrNotFound) || !strings.Contains(err.Error(), "connection refused") {
+		t.Fatalf("missing key error = %v", err)
+	}
+}
+
+func TestVaultFieldMapping(t *testing.T) {
+	l := NewLoader(nil, Vault{
+		Reader: stubVault{fields: map[string]string{"secret/go-trader/fred#api_key": "fred-value"}},
+		Path:   "secret/go-trader/fred",
+		Fields: map[string]string{"FRED_API_KEY": "api_key"},
+	})
+	v, source, err := l.Lookup(context.Background(), "FRED_API_KEY")
+	if err != nil || v != "fred-value" || source != "vault:secret/go-trader/fred#api_key" {
+		t.Fatalf("lookup = %q %q %v", v, source, err)
+	}
+}
+
+func TestCredentialsNeverPrintKeys(t *testing.T) {
+	creds := &Credentials{KeyID: "PKSECRETKEYID0000000", Secret: "very-secret-value", Source: "env:PAPER_ALPACA_API_KEY", Paper: true}
+	data, err := json.Marshal(struct{ C *Credentials }{creds})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, out := range []string{fmt.Sprintf("%v %+v %#v %s", creds, creds, creds, creds), string(data)} {
+		if strings.Contains(out, "PKSECRET") || strings.Contains(out, "very-secret") {
+			t.Fatalf("credentials leaked: %s", out)
+		}
+	}
+	if !strings.Contains(string(data), creds.Fingerprint()) {
+		t.Fatalf("marshaled credentials lack the fingerprint: %s", data)
+	}
+	if err := json.Unmarshal([]byte(`{"KeyID":"x"}`), creds); err == nil {
+		t.Fatal("credentials decoded from JSON")
+	}
+}
+
+func TestValidatorReportsRejectedKeysWithoutEchoingThem(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("APCA-API-SECRET-KEY") != "good-secret-value" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprintf(w, `{"code":40110000,"message":"bad key %s"}`, r.Header.Get("APCA-API-KEY-ID"))
+			return
+		}
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/account"):
+			fmt.Fprint(w, `{"id":"1","status":"ACTIVE"}`)
+		default:
+			fmt.Fprint(w, `{"symbol":"SPY","trade":{"t":"2025-01-02T15:04:05Z","p":500,"s":1}}`)
+		}
+	}))
+	defer srv.Close()
+
+	good := &Credentials{KeyID: "PKGOODKEY00000000000", Secret: "good-secret-value", Paper: true}
+	res := NewValidator(good, srv.URL, srv.URL).Validate()
+	if !res.Valid || len(res.Checks) != 2 || res.Checks[0].Detail != "account ACTIVE" {
+		t.Fatalf("good credentials = %+v", res)
+	}
+
+	bad := &Credentials{KeyID: "PKBADKEY000000000000", Secret: "bad-secret-value", Paper: true}
+	res = NewValidator(bad, srv.URL, srv.URL).Validate()
+	if res.Valid || res.Checks[0].Status != http.StatusUnauthorized {
+		t.Fatalf("bad credentials = %+v", res)
+	}
+	data, _ := json.Marshal(res)
+	if strings.Contains(string(data), "PKBADKEY") {
+		t.Fatalf("validation echoed the key: %s", data)
+	}
+
+	if res := NewValidator(MockCredentials(true), srv.URL, srv.URL).Validate(); res.Skipped == "" || res.Valid {
+		t.Fatalf("mock credentials = %+v", res)
+	}
+}
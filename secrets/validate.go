@@ -0,0 +1,110 @@
+package secrets
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/alpacahq/alpaca-trade-api-go/v3/alpaca"
+	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
+)
+
+// validateTimeout bounds each validation request.
+const validateTimeout = 10 * time.Second
+
+// Check is the outcome of one API call made with the credentials.
+type Check struct {
+	API    string `json:"api"` // trading or market_data
+	OK     bool   `json:"ok"`
+	Status int    `json:"status,omitempty"` // HTTP status of a rejected call
+	Detail string `json:"detail,omitempty"`
+}
+
+// Validation reports whether Alpaca accepts the credentials.
+type Validation struct {
+	Summary
+	Valid     bool      `json:"valid"`
+	Skipped   string    `json:"skipped,omitempty"` // why nothing was checked
+	Checks    []Check   `json:"checks,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Validator checks credentials against the trading and market data APIs.
+type Validator struct {
+	creds      *Credentials
+	tradingURL string
+	dataURL    string // empty for the SDK's default
+	skip       string
+}
+
+// NewValidator returns a validator calling the trading API at tradingURL.
+func NewValidator(creds *Credentials, tradingURL, dataURL string) *Validator {
+	v := &Validator{creds: creds, tradingURL: tradingURL, dataURL: dataURL}
+	if creds.Mock {
+		v.skip = "mock credentials are never sent to Alpaca"
+	}
+	return v
+}
+
+// Validate reads the account and the latest SPY trade with the
+// credentials. Error details have the key and secret redacted.
+func (v *Validator) Validate() Validation {
+	out := Validation{Summary: v.creds.Summary(), CheckedAt: time.Now()}
+	if v.skip != "" {
+		out.Skipped = v.skip
+		return out
+	}
+	httpClient := &http.Client{Timeout: validateTimeout}
+
+	trading := alpaca.NewClient(alpaca.ClientOpts{
+		APIKey:     v.creds.KeyID,
+		APISecret:  v.creds.Secret,
+		BaseURL:    v.tradingURL,
+		RetryLimit: -1,
+		HTTPClient: httpClient,
+	})
+	account, err := trading.GetAccount()
+	check := v.check("trading", err)
+	if err == nil {
+		check.Detail = "account " + string(account.Status)
+	}
+	out.Checks = append(out.Checks, check)
+
+	data := marketdata.NewClient(marketdata.ClientOpts{
+		APIKey:     v.creds.KeyID,
+		APISecret:  v.creds.Secret,
+		BaseURL:    v.dataURL,
+		RetryLimit: -1,
+		HTTPClient: httpClient,
+	})
+	_, err = data.GetLatestTrade("SPY", marketdata.GetLatestTradeRequest{})
+	out.Checks = append(out.Checks, v.check("market_data", err))
+
+	out.Valid = true
+	for _, c := range out.Checks {
+		out.Valid = out.Valid && c.OK
+	}
+	return out
+}
+
+func (v *Validator) check(api string, err error) Check {
+	c := Check{API: api, OK: err == nil}
+	if err == nil {
+		return c
+	}
+	var apiErr *alpaca.APIError
+	if errors.As(err, &apiErr) {
+		c.Status = apiErr.StatusCode
+		switch apiErr.StatusCode {
+		case http.StatusUnauthorized:
+			c.Detail = "key or secret rejected"
+		case http.StatusForbidden:
+			c.Detail = "key lacks access"
+		default:
+			c.Detail = v.creds.Redact(apiErr.Message)
+		}
+		return c
+	}
+	c.Detail = v.creds.Redact(err.Error())
+	return c
+}
@@ -0,0 +1,212 @@
+package shadow
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+)
+
+// Journal entry actions.
+const (
+	ActionStart = "start" // a book opened with Cash
+	ActionOpen  = "open"
+	ActionClose = "close"
+	ActionHold  = "hold"
+	ActionSkip  = "skip" // a signal the book could not act on
+)
+
+// Position is a virtual long position.
+type Position struct {
+	Qty       float64   `json:"qty"`
+	AvgPrice  float64   `json:"avg_price"`
+	LastPrice float64   `json:"last_price"`
+	OpenedAt  time.Time `json:"opened_at"`
+}
+
+// Book is one decision source's virtual portfolio. Books are long-only
+// and fill at the signal's market price, so every source trades under the
+// same rules.
+type Book struct {
+	Source      string
+	InitialCash float64
+	Cash        float64
+	Positions   map[string]*Position
+	Realized    float64
+	Signals     int
+	Trades      int // closed round trips
+	Wins        int
+	Started     time.Time
+	peak        float64
+	maxDrawdown float64
+}
+
+// NewBook opens a book with cash at start.
+func NewBook(source string, cash float64, start time.Time) *Book {
+	return &Book{
+		Source:      source,
+		InitialCash: cash,
+		Cash:        cash,
+		Positions:   make(map[string]*Position),
+		Started:     start,
+		peak:        cash,
+	}
+}
+
+// Equity is cash plus positions at their last price.
+func (b *Book) Equity() float64 {
+	eq := b.Cash
+	for _, p := range b.Positions {
+		eq += p.Qty * p.LastPrice
+	}
+	return eq
+}
+
+// Decide works out what the book does with signal at price, sizing opens
+// at positionPercent of equity. The book is not changed.
+func (b *Book) Decide(signal *algorithm.TradeSignal, price, positionPercent float64) Entry {
+	e := Entry{
+		Source:     b.Source,
+		Symbol:     signal.Symbol,
+		Signal:     signal.Signal,
+		Confidence: signal.Confidence,
+		Price:      price,
+		Action:     ActionHold,
+		Reasoning:  signal.Reasoning,
+	}
+	pos, held := b.Positions[signal.Symbol]
+	switch {
+	case signal.Signal == algorithm.SignalHold:
+	case price <= 0:
+		e.Action, e.Note = ActionSkip, "no price"
+	case signal.Signal == algorithm.SignalBuy && held:
+		e.Action, e.Note = ActionSkip, "already long"
+	case signal.Signal == algorithm.SignalBuy:
+		qty := math.Floor(b.Equity() * positionPercent / 100 / price)
+		if qty*price > b.Cash {
+			qty = math.Floor(b.Cash / price)
+		}
+		if qty < 1 {
+			e.Action, e.Note = ActionSkip, "not enough cash"
+			break
+		}
+		e.Action, e.Qty = ActionOpen, qty
+	case (signal.Signal == algorithm.SignalSell || signal.Signal == algorithm.SignalClose) && held:
+		e.Action, e.Qty = ActionClose, pos.Qty
+		e.PnL = round2((price - pos.AvgPrice) * pos.Qty)
+	case signal.Signal == algorithm.SignalSell || signal.Signal == algorithm.SignalClose:
+		e.Action, e.Note = ActionSkip, "nothing to sell; books are long-only"
+	default:
+		e.Action, e.Note = ActionSkip, "unknown signal"
+	}
+	return e
+}
+
+// Apply books e, made by Decide or read back from the journal, and fills
+// in the cash and equity after it.
+func (b *Book) Apply(e *Entry) {
+	if e.Action != ActionStart {
+		b.Signals++
+	}
+	switch e.Action {
+	case ActionOpen:
+		b.Cash -= e.Qty * e.Price
+		b.Positions[e.Symbol] = &Position{Qty: e.Qty, AvgPrice: e.Price, LastPrice: e.Price, OpenedAt: e.Time}
+	case ActionClose:
+		b.Cash += e.Qty * e.Price
+		b.Realized += e.PnL
+		b.Trades++
+		if e.PnL > 0 {
+			b.Wins++
+		}
+		delete(b.Positions, e.Symbol)
+	}
+	b.Mark(e.Symbol, e.Price)
+	e.Cash = round2(b.Cash)
+	e.Equity = round2(b.Equity())
+}
+
+// Mark reprices a held symbol and tracks the drawdown. It reports whether
+// the book holds symbol.
+func (b *Book) Mark(symbol string, price float64) bool {
+	p, ok := b.Positions[symbol]
+	if ok && price > 0 {
+		p.LastPrice = price
+	}
+	eq := b.Equity()
+	if eq > b.peak {
+		b.peak = eq
+	}
+	if b.peak > 0 {
+		b.maxDrawdown = math.Max(b.maxDrawdown, (b.peak-eq)/b.peak)
+	}
+	return ok
+}
+
+// PositionStats is an open virtual position.
+type PositionStats struct {
+	Symbol     string    `json:"symbol"`
+	Qty        float64   `json:"qty"`
+	AvgPrice   float64   `json:"avg_price"`
+	LastPrice  float64   `json:"last_price"`
+	Unrealized float64   `json:"unrealized"`
+	OpenedAt   time.Time `json:"opened_at"`
+}
+
+// BookStats is a book's performance.
+type BookStats struct {
+	Source         string          `json:"source"`
+	Live           bool            `json:"live"` // the source whose signals are traded
+	Started        time.Time       `json:"started"`
+	InitialCash    float64         `json:"initial_cash"`
+	Cash           float64         `json:"cash"`
+	Equity         float64         `json:"equity"`
+	ReturnPct      float64         `json:"return_pct"`
+	Realized       float64         `json:"realized"`
+	Unrealized     float64         `json:"unrealized"`
+	Signals        int             `json:"signals"`
+	Trades         int             `json:"trades"`
+	Wins           int             `json:"wins"`
+	WinRate        float64         `json:"win_rate"` // wins over closed trades
+	MaxDrawdownPct float64         `json:"max_drawdown_pct"`
+	Positions      []PositionStats `json:"positions"`
+}
+
+// Stats summarizes the book.
+func (b *Book) Stats() BookStats {
+	s := BookStats{
+		Source:         b.Source,
+		Started:        b.Started,
+		InitialCash:    b.InitialCash,
+		Cash:           round2(b.Cash),
+		Equity:         round2(b.Equity()),
+		Realized:       round2(b.Realized),
+		Signals:        b.Signals,
+		Trades:         b.Trades,
+		Wins:           b.Wins,
+		MaxDrawdownPct: round2(b.maxDrawdown * 100),
+		Positions:      []PositionStats{},
+	}
+	if b.InitialCash > 0 {
+		s.ReturnPct = round2((b.Equity() - b.InitialCash) / b.InitialCash * 100)
+	}
+	if b.Trades > 0 {
+		s.WinRate = round2(float64(b.Wins) / float64(b.Trades))
+	}
+	for sym, p := range b.Positions {
+		u := (p.LastPrice - p.AvgPrice) * p.Qty
+		s.Unrealized += u
+		s.Positions = append(s.Positions, PositionStats{
+			Symbol: sym, Qty: p.Qty, AvgPrice: p.AvgPrice, LastPrice: p.LastPrice,
+			Unrealized: round2(u), OpenedAt: p.OpenedAt,
+		})
+	}
+	s.Unrealized = round2(s.Unrealized)
+	sort.Slice(s.Positions, func(i, j int) bool { return s.Positions[i].Symbol < s.Positions[j].Symbol })
+	return s
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
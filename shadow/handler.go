@@ -0,0 +1,97 @@
+package shadow
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/paging"
+)
+
+// Handler exposes the shadow books over HTTP.
+type Handler struct {
+	tracker *Tracker
+}
+
+// NewHandler creates a handler for tracker.
+func NewHandler(tracker *Tracker) *Handler {
+	return &Handler{tracker: tracker}
+}
+
+// RegisterRoutes registers the shadow routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/shadow - every source's virtual performance, live first
+	mux.HandleFunc("/api/shadow", h.handleReport)
+
+	// GET /api/shadow/journal?source=&symbol=&limit=&cursor=&sort=&filter= - booked signals, newest first
+	mux.HandleFunc("/api/shadow/journal", h.handleJournal)
+
+	// GET/POST /api/shadow/policy - read or update the shadow policy
+	mux.HandleFunc("/api/shadow/policy", h.handlePolicy)
+
+	// POST /api/shadow/reset - start every book over with the policy's cash
+	mux.HandleFunc("/api/shadow/reset", h.handleReset)
+}
+
+func (h *Handler) handleReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(h.tracker.Report())
+}
+
+// journalList pages the journal, newest first by default.
+var journalList = paging.List[Entry]{
+	Fields: []paging.Field[Entry]{
+		{Name: "time", Sort: func(e Entry) paging.Key { return paging.Time(e.Time) }},
+		{Name: "symbol", Sort: func(e Entry) paging.Key { return paging.Text(e.Symbol) }, Filter: func(e Entry) string { return e.Symbol }},
+		{Name: "pnl", Sort: func(e Entry) paging.Key { return paging.Number(e.PnL) }},
+		{Name: "source", Filter: func(e Entry) string { return e.Source }},
+		{Name: "action", Filter: func(e Entry) string { return e.Action }},
+		{Name: "signal", Filter: func(e Entry) string { return e.Signal }},
+	},
+	ID: func(e Entry) string {
+		return e.Time.UTC().Format(time.RFC3339Nano) + "/" + e.Source + "/" + e.Symbol + "/" + e.Action
+	},
+	Sort: "-time",
+}
+
+func (h *Handler) handleJournal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	journalList.Serve(w, r, h.tracker.Journal(q.Get("source"), q.Get("symbol"), 0))
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.tracker.Policy())
+	case http.MethodPost, http.MethodPut:
+		// Start from the current policy so partial updates work
+		policy := h.tracker.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.tracker.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(policy)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleReset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.tracker.Reset()
+	json.NewEncoder(w).Encode(h.tracker.Report())
+}
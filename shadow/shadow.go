@@ -0,0 +1,365 @@
+// Package shadow runs every decision source against its own virtual
+// portfolio. Whenever the live source — the one whose signals are traded —
+// produces a signal, the other sources are asked for theirs on the same
+// market data, and every signal is journaled and booked, so the realized
+// performance of Claude and the quant pipeline can be compared over time.
+package shadow
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+)
+
+func logger() *slog.Logger { return slog.With("module", "shadow") }
+
+// Decision sources.
+const (
+	SourceClaude = "claude"
+	SourceQuant  = "quant"
+)
+
+// maxRecent bounds the journal entries kept in memory; the file keeps
+// everything.
+const maxRecent = 2000
+
+// Policy configures shadow trading.
+type Policy struct {
+	Enabled         bool    `json:"enabled"`
+	Live            string  `json:"live"`             // claude or quant
+	InitialCash     float64 `json:"initial_cash"`     // for books opened from now on
+	PositionPercent float64 `json:"position_percent"` // of book equity per open
+}
+
+// DefaultPolicy shadows the quant pipeline while Claude trades live, with
+// $100,000 books opening 5% positions.
+func DefaultPolicy() Policy {
+	return Policy{Enabled: true, Live: SourceClaude, InitialCash: 100000, PositionPercent: 5}
+}
+
+// Validate checks the policy for usable values.
+func (p Policy) Validate() error {
+	if p.Live != SourceClaude && p.Live != SourceQuant {
+		return errors.New("live must be claude or quant")
+	}
+	if p.InitialCash <= 0 {
+		return errors.New("initial_cash must be positive")
+	}
+	if p.PositionPercent <= 0 || p.PositionPercent > 100 {
+		return errors.New("position_percent must be above 0 and at most 100")
+	}
+	return nil
+}
+
+// Classify maps a signal's source to a decision source, or an empty
+// string for signals from neither, such as system holds.
+func Classify(source string) string {
+	switch source {
+	case "claude":
+		return SourceClaude
+	case "quant", "fallback:quant":
+		return SourceQuant
+	}
+	return ""
+}
+
+// Entry is one journaled signal and what its book did with it.
+type Entry struct {
+	Time       time.Time `json:"time"`
+	Source     string    `json:"source"`
+	Live       bool      `json:"live"`
+	Symbol     string    `json:"symbol,omitempty"`
+	Signal     string    `json:"signal,omitempty"`
+	Confidence *float64  `json:"confidence,omitempty"`
+	Price      float64   `json:"price,omitempty"`
+	Action     string    `json:"action"`
+	Qty        float64   `json:"qty,omitempty"`
+	PnL        float64   `json:"pnl,omitempty"` // realized, on closes
+	Cash       float64   `json:"cash"`          // after the entry
+	Equity     float64   `json:"equity"`        // after the entry
+	Note       string    `json:"note,omitempty"`
+	Reasoning  string    `json:"reasoning,omitempty"`
+}
+
+// Report compares the books.
+type Report struct {
+	Policy Policy      `json:"policy"`
+	Books  []BookStats `json:"books"` // live first
+}
+
+// Tracker owns the books and the journal. It is safe for concurrent use.
+type Tracker struct {
+	generators map[string]algorithm.ClaudeClientInterface
+
+	mu      sync.Mutex
+	policy  Policy
+	books   map[string]*Book
+	recent  []Entry
+	path    string
+	journal *os.File
+	now     func() time.Time
+}
+
+// New opens the journal at path, rebuilding the books from it, and asks
+// generators for the signals of sources other than the one observed.
+func New(path string, policy Policy, generators map[string]algorithm.ClaudeClientInterface) (*Tracker, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create shadow journal directory: %w", err)
+	}
+	t := &Tracker{
+		generators: generators,
+		policy:     policy,
+		books:      make(map[string]*Book),
+		path:       path,
+		now:        time.Now,
+	}
+	if err := readJournal(path, t.replay); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open shadow journal for writing: %w", err)
+	}
+	t.journal = f
+	return t, nil
+}
+
+// readJournal calls fn with each entry journaled at path, oldest first.
+func readJournal(path string, fn func(Entry)) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to open shadow journal: %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			logger().Warn("Skipping malformed shadow journal line", "error", err)
+			continue
+		}
+		fn(e)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read shadow journal: %w", err)
+	}
+	return nil
+}
+
+// replay rebuilds a book from a journaled entry.
+func (t *Tracker) replay(e Entry) {
+	if e.Action == ActionStart {
+		t.books[e.Source] = NewBook(e.Source, e.Cash, e.Time)
+	} else if b, ok := t.books[e.Source]; ok {
+		b.Apply(&e)
+	}
+	t.remember(e)
+}
+
+// SetClock replaces the clock, for replays.
+func (t *Tracker) SetClock(now func() time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.now = now
+}
+
+// Policy returns the current policy.
+func (t *Tracker) Policy() Policy {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.policy
+}
+
+// SetPolicy validates and replaces the policy. Existing books keep their
+// starting cash.
+func (t *Tracker) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.policy = p
+	return nil
+}
+
+// Observe books signal from its source and, for each other source, the
+// signal that source gives on the same market data. Generating the other
+// signals can be slow; call it off the request path.
+func (t *Tracker) Observe(signal *algorithm.TradeSignal, md algorithm.MarketData, portfolio algorithm.PortfolioData) {
+	if signal == nil || !t.Policy().Enabled {
+		return
+	}
+	source := Classify(signal.Source)
+	if source == "" {
+		return
+	}
+	t.Record(source, signal, md.Price)
+
+	names := make([]string, 0, len(t.generators))
+	for name := range t.generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if name == source {
+			continue
+		}
+		other, err := t.generators[name].GenerateTradeSignal(signal.Symbol, md, portfolio)
+		if err != nil || other == nil {
+			logger().Warn("Shadow signal failed", "source", name, "symbol", signal.Symbol, "error", err)
+			continue
+		}
+		// A guarded source may answer from its fallback; that is not its
+		// own decision
+		if other.Source != "" && Classify(other.Source) != name {
+			logger().Info("Shadow signal came from another source, not booked", "source", name, "symbol", signal.Symbol, "from", other.Source)
+			continue
+		}
+		t.Record(name, other, md.Price)
+	}
+}
+
+// Record books signal for source at price and journals it.
+func (t *Tracker) Record(source string, signal *algorithm.TradeSignal, price float64) Entry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	b := t.bookLocked(source, now)
+	e := b.Decide(signal, price, t.policy.PositionPercent)
+	e.Time = now
+	e.Live = source == t.policy.Live
+	b.Apply(&e)
+	t.writeLocked(e)
+	return e
+}
+
+// bookLocked returns source's book, opening and journaling it first if
+// needed.
+func (t *Tracker) bookLocked(source string, now time.Time) *Book {
+	if b, ok := t.books[source]; ok {
+		return b
+	}
+	b := NewBook(source, t.policy.InitialCash, now)
+	t.books[source] = b
+	t.writeLocked(Entry{Time: now, Source: source, Live: source == t.policy.Live, Action: ActionStart, Cash: b.Cash, Equity: b.Cash})
+	return b
+}
+
+func (t *Tracker) writeLocked(e Entry) {
+	t.remember(e)
+	if line, err := json.Marshal(e); err != nil {
+		logger().Error("Failed to encode shadow journal entry", "source", e.Source, "error", err)
+	} else if _, err := t.journal.Write(append(line, '\n')); err != nil {
+		logger().Error("Failed to write shadow journal entry", "source", e.Source, "error", err)
+	}
+}
+
+func (t *Tracker) remember(e Entry) {
+	t.recent = append(t.recent, e)
+	if len(t.recent) > maxRecent {
+		t.recent = t.recent[len(t.recent)-maxRecent:]
+	}
+}
+
+// Mark reprices symbol in every book holding it.
+func (t *Tracker) Mark(symbol string, price float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, b := range t.books {
+		if _, ok := b.Positions[symbol]; ok {
+			b.Mark(symbol, price)
+		}
+	}
+}
+
+// Reset closes every book and opens fresh ones with the policy's cash.
+func (t *Tracker) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	sources := make([]string, 0, len(t.books))
+	for source := range t.books {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	t.books = make(map[string]*Book)
+	for _, source := range sources {
+		t.bookLocked(source, t.now())
+	}
+	logger().Info("Reset shadow books", "sources", sources, "initial_cash", t.policy.InitialCash)
+}
+
+// Report returns every book's performance, the live source first.
+func (t *Tracker) Report() Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r := Report{Policy: t.policy, Books: []BookStats{}}
+	for _, b := range t.books {
+		s := b.Stats()
+		s.Live = b.Source == t.policy.Live
+		r.Books = append(r.Books, s)
+	}
+	sort.Slice(r.Books, func(i, j int) bool {
+		if r.Books[i].Live != r.Books[j].Live {
+			return r.Books[i].Live
+		}
+		return r.Books[i].Source < r.Books[j].Source
+	})
+	return r
+}
+
+// Journal returns recent entries, newest first, optionally for one source
+// or symbol.
+func (t *Tracker) Journal(source, symbol string, limit int) []Entry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	symbol = strings.ToUpper(symbol)
+	out := []Entry{}
+	for i := len(t.recent) - 1; i >= 0 && (limit <= 0 || len(out) < limit); i-- {
+		e := t.recent[i]
+		if (source != "" && e.Source != source) || (symbol != "" && e.Symbol != symbol) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// Close closes the journal.
+func (t *Tracker) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.journal.Close()
+}
+
+// Entries returns the signals journaled for source since since, oldest
+// first. It reads the whole journal, not just the recent entries kept in
+// memory.
+func (t *Tracker) Entries(source string, since time.Time) ([]Entry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := []Entry{}
+	err := readJournal(t.path, func(e Entry) {
+		if e.Action == ActionStart || e.Source != source || e.Time.Before(since) {
+			return
+		}
+		out = append(out, e)
+	})
+	return out, err
+}
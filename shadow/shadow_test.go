@@ -0,0 +1,142 @@
+package shadow
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+)
+
+type stubSource struct {
+	signal string
+	source string
+	err    error
+	calls  int
+}
+
+func (s *stubSource) GenerateTradeSignal(symbol string, _ algorithm.MarketData, _ algorithm.PortfolioData) (*algorithm.TradeSignal, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &algorithm.TradeSignal{Symbol: symbol, Signal: s.signal, Source: s.source}, nil
+}
+
+func TestBookRoundTrip(t *testing.T) {
+	b := NewBook(SourceQuant, 10000, time.Now())
+	open := b.Decide(&algorithm.TradeSignal{Symbol: "AAPL", Signal: algorithm.SignalBuy}, 100, 10)
+	if open.Action != ActionOpen || open.Qty != 10 {
+		t.Fatalf("open = %+v", open)
+	}
+	b.Apply(&open)
+	if again := b.Decide(&algorithm.TradeSignal{Symbol: "AAPL", Signal: algorithm.SignalBuy}, 100, 10); again.Action != ActionSkip {
+		t.Errorf("second buy = %+v", again)
+	}
+
+	b.Mark("AAPL", 90)
+	b.Mark("AAPL", 120)
+	closeE := b.Decide(&algorithm.TradeSignal{Symbol: "AAPL", Signal: algorithm.SignalSell}, 120, 10)
+	b.Apply(&closeE)
+	s := b.Stats()
+	if closeE.PnL != 200 || s.Equity != 10200 || s.ReturnPct != 2 || s.Trades != 1 || s.WinRate != 1 || len(s.Positions) != 0 {
+		t.Fatalf("stats = %+v", s)
+	}
+	if s.MaxDrawdownPct != 1 { // 10,000 to 9,900 while AAPL sat at 90
+		t.Errorf("max drawdown = %v", s.MaxDrawdownPct)
+	}
+	if short := b.Decide(&algorithm.TradeSignal{Symbol: "MSFT", Signal: algorithm.SignalSell}, 300, 10); short.Action != ActionSkip {
+		t.Errorf("sell without a position = %+v", short)
+	}
+}
+
+func TestTrackerShadowsOtherSourcesAndReplaysJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	quant := &stubSource{signal: algorithm.SignalBuy}
+	claude := &stubSource{signal: algorithm.SignalHold}
+	tr, err := New(path, DefaultPolicy(), map[string]algorithm.ClaudeClientInterface{SourceClaude: claude, SourceQuant: quant})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Claude's live hold; quant is asked on the same data and buys
+	md := algorithm.MarketData{Symbol: "AAPL", Price: 100}
+	tr.Observe(&algorithm.TradeSignal{Symbol: "AAPL", Signal: algorithm.SignalHold, Source: "claude"}, md, algorithm.PortfolioData{})
+	if claude.calls != 0 || quant.calls != 1 {
+		t.Fatalf("calls claude=%d quant=%d", claude.calls, quant.calls)
+	}
+	tr.Mark("AAPL", 110)
+
+	// A fallback answer from the Claude generator is not Claude's decision
+	claude.source = "fallback:quant"
+	tr.Observe(&algorithm.TradeSignal{Symbol: "MSFT", Signal: algorithm.SignalHold, Source: "fallback:quant"}, md, algorithm.PortfolioData{})
+	if claude.calls != 1 {
+		t.Fatalf("claude calls = %d", claude.calls)
+	}
+
+	r := tr.Report()
+	if len(r.Books) != 2 || r.Books[0].Source != SourceClaude || !r.Books[0].Live || r.Books[0].Signals != 1 {
+		t.Fatalf("report = %+v", r.Books)
+	}
+	q := r.Books[1]
+	if q.Signals != 2 || q.Equity != 100500 || len(q.Positions) != 1 {
+		t.Fatalf("quant book = %+v", q)
+	}
+	if j := tr.Journal(SourceQuant, "", 0); len(j) != 3 || j[1].Action != ActionOpen || j[2].Action != ActionStart {
+		t.Fatalf("journal = %+v", j)
+	}
+	if j := tr.Journal("", "aapl", 0); len(j) != 2 {
+		t.Fatalf("journal = %+v", j)
+	}
+	tr.Close()
+
+	// Reopening rebuilds the books from the journal at their fill prices
+	tr, err = New(path, DefaultPolicy(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+	r = tr.Report()
+	if len(r.Books) != 2 || r.Books[1].Equity != 100000 || r.Books[1].Positions[0].Qty != 50 {
+		t.Fatalf("reloaded = %+v", r.Books)
+	}
+	// The whole journal is read back, without the books' start entries
+	if e, err := tr.Entries(SourceQuant, time.Time{}); err != nil || len(e) != 2 || e[0].Symbol != "AAPL" || e[0].Signal != algorithm.SignalBuy {
+		t.Fatalf("entries = %+v, %v", e, err)
+	}
+	if e, _ := tr.Entries(SourceQuant, time.Now().Add(time.Hour)); len(e) != 0 {
+		t.Errorf("entries from the future = %+v", e)
+	}
+}
+
+func TestObserveSkipsFailuresAndDisabledPolicy(t *testing.T) {
+	quant := &stubSource{err: errors.New("no history")}
+	tr, err := New(filepath.Join(t.TempDir(), "journal.jsonl"), DefaultPolicy(), map[string]algorithm.ClaudeClientInterface{SourceQuant: quant})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.Close()
+
+	tr.Observe(&algorithm.TradeSignal{Symbol: "AAPL", Signal: algorithm.SignalHold, Source: "system"}, algorithm.MarketData{Price: 1}, algorithm.PortfolioData{})
+	if quant.calls != 0 || len(tr.Report().Books) != 0 {
+		t.Fatal("system signals are not booked")
+	}
+	tr.Observe(&algorithm.TradeSignal{Symbol: "AAPL", Signal: algorithm.SignalBuy, Source: "claude"}, algorithm.MarketData{Price: 100}, algorithm.PortfolioData{})
+	if quant.calls != 1 || len(tr.Report().Books) != 1 {
+		t.Fatalf("books = %+v", tr.Report().Books)
+	}
+
+	p := tr.Policy()
+	p.Enabled = false
+	if err := tr.SetPolicy(p); err != nil {
+		t.Fatal(err)
+	}
+	tr.Observe(&algorithm.TradeSignal{Symbol: "AAPL", Signal: algorithm.SignalBuy, Source: "claude"}, algorithm.MarketData{Price: 100}, algorithm.PortfolioData{})
+	if quant.calls != 1 {
+		t.Error("disabled policy still generated shadow signals")
+	}
+	if err := tr.SetPolicy(Policy{Live: "gpt", InitialCash: 1, PositionPercent: 1}); err == nil {
+		t.Error("unknown live source accepted")
+	}
+}
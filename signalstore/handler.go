@@ -0,0 +1,182 @@
+package signalstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/paging"
+)
+
+// Handler serves signal history over HTTP.
+type Handler struct {
+	store *Store
+}
+
+// NewHandler creates a handler for store.
+func NewHandler(store *Store) *Handler {
+	return &Handler{store: store}
+}
+
+// RegisterRoutes registers the history route with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/signals/history?symbol=&from=&to=&signal=&source=&tag=&prompt_variant=&q=&limit=&cursor=&sort=&filter=
+	// offset= pages by position instead of cursor, as before cursors
+	mux.HandleFunc("/api/signals/history", h.handleHistory)
+
+	// GET /api/reports/signal-heatmap?days=&from=&to=&source=&signal=&tz=
+	mux.HandleFunc("/api/reports/signal-heatmap", h.handleHeatmap)
+}
+
+// historyList pages signal history, newest first by default.
+var historyList = paging.List[Record]{
+	Fields: []paging.Field[Record]{
+		{Name: "timestamp", Sort: func(r Record) paging.Key { return paging.Time(r.Timestamp) }},
+		{Name: "symbol", Sort: func(r Record) paging.Key { return paging.Text(r.Symbol) }, Filter: func(r Record) string { return r.Symbol }},
+		{Name: "confidence", Sort: func(r Record) paging.Key {
+			if r.Confidence == nil {
+				return paging.Number(-1)
+			}
+			return paging.Number(*r.Confidence)
+		}},
+		{Name: "signal", Filter: func(r Record) string { return r.Signal }},
+		{Name: "source", Filter: func(r Record) string { return r.Source }},
+		{Name: "tag", Filter: func(r Record) string { return r.Tag }},
+		{Name: "prompt_variant", Filter: func(r Record) string { return r.PromptVariant }},
+		{Name: "order_type", Filter: func(r Record) string { return r.OrderType }},
+	},
+	ID:   func(r Record) string { return r.ID },
+	Sort: "-timestamp",
+}
+
+func (h *Handler) handleHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	q := Query{
+		Symbol: params.Get("symbol"),
+		Signal: params.Get("signal"),
+		Source: params.Get("source"),
+		Tag:    params.Get("tag"),
+		Text:   params.Get("q"),
+
+		PromptVariant: params.Get("prompt_variant"),
+	}
+
+	var err error
+	if q.From, err = parseTime(params.Get("from"), false); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.To, err = parseTime(params.Get("to"), true); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if params.Has("offset") {
+		if q.Limit, err = parseInt(params.Get("limit")); err != nil {
+			http.Error(w, "limit must be an integer", http.StatusBadRequest)
+			return
+		}
+		if q.Offset, err = parseInt(params.Get("offset")); err != nil {
+			http.Error(w, "offset must be an integer", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(h.store.Query(q))
+		return
+	}
+	page, ok := historyList.Page(w, r, h.store.Select(q))
+	if !ok {
+		return
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+// defaultHeatmapZone is the market's time zone, the default for heatmap
+// hours.
+const defaultHeatmapZone = "America/New_York"
+
+func (h *Handler) handleHeatmap(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	params := r.URL.Query()
+	q := HeatmapQuery{Source: params.Get("source"), Signal: params.Get("signal")}
+	zone := params.Get("tz")
+	if zone == "" {
+		zone = defaultHeatmapZone
+	}
+	var err error
+	if q.Location, err = time.LoadLocation(zone); err != nil {
+		http.Error(w, fmt.Sprintf("unknown time zone %q", zone), http.StatusBadRequest)
+		return
+	}
+	if q.From, err = parseTime(params.Get("from"), false); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.To, err = parseTime(params.Get("to"), true); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	// Without from, the window is the last days days, 7 by default
+	if q.From.IsZero() {
+		days, err := parseInt(params.Get("days"))
+		if err != nil || days < 0 {
+			http.Error(w, "days must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		if days == 0 {
+			days = 7
+		}
+		end := q.To
+		if end.IsZero() {
+			end = time.Now()
+		}
+		q.From = end.AddDate(0, 0, -days)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.store.Heatmap(q))
+}
+
+// parseTime accepts RFC3339 or YYYY-MM-DD. A bare date used as an upper
+// bound covers the whole day.
+func parseTime(v string, endOfDay bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: use RFC3339 or YYYY-MM-DD", v)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
+
+func parseInt(v string) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(v)
+}
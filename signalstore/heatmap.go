@@ -0,0 +1,100 @@
+package signalstore
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// HeatmapQuery selects the signals a heatmap counts. Zero times leave the
+// window open at that end; hours are taken in Location, UTC when nil.
+type HeatmapQuery struct {
+	From     time.Time
+	To       time.Time
+	Source   string
+	Signal   string
+	Location *time.Location
+}
+
+// HeatmapCell counts the signals in one hour of the day.
+type HeatmapCell struct {
+	Count int `json:"count"`
+	// Rated is how many carried a confidence; AvgConfidence averages them
+	Rated         int     `json:"rated"`
+	AvgConfidence float64 `json:"avg_confidence"`
+}
+
+func (c *HeatmapCell) add(r Record) {
+	c.Count++
+	if r.Confidence != nil {
+		c.AvgConfidence = (c.AvgConfidence*float64(c.Rated) + *r.Confidence) / float64(c.Rated+1)
+		c.Rated++
+	}
+}
+
+// HeatmapRow is one symbol's signals by hour of day, 0 to 23.
+type HeatmapRow struct {
+	Symbol string          `json:"symbol"`
+	Total  HeatmapCell     `json:"total"`
+	Hours  [24]HeatmapCell `json:"hours"`
+}
+
+// Heatmap is signal activity by hour of day and symbol, busiest symbol
+// first, with each hour's total across symbols.
+type Heatmap struct {
+	From     *time.Time      `json:"from,omitempty"`
+	To       *time.Time      `json:"to,omitempty"`
+	Timezone string          `json:"timezone"`
+	Total    HeatmapCell     `json:"total"`
+	Hours    [24]HeatmapCell `json:"hours"`
+	Symbols  []HeatmapRow    `json:"symbols"`
+}
+
+// Heatmap counts the signals matching q by hour of day and symbol, with
+// their average confidence.
+func (s *Store) Heatmap(q HeatmapQuery) Heatmap {
+	loc := q.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	h := Heatmap{Timezone: loc.String(), Symbols: []HeatmapRow{}}
+	if !q.From.IsZero() {
+		h.From = &q.From
+	}
+	if !q.To.IsZero() {
+		h.To = &q.To
+	}
+
+	rows := make(map[string]*HeatmapRow)
+	s.mu.RLock()
+	for _, r := range s.records {
+		if q.Source != "" && !strings.EqualFold(r.Source, q.Source) ||
+			q.Signal != "" && !strings.EqualFold(r.Signal, q.Signal) ||
+			!q.From.IsZero() && r.Timestamp.Before(q.From) ||
+			!q.To.IsZero() && r.Timestamp.After(q.To) {
+			continue
+		}
+		row, ok := rows[r.Symbol]
+		if !ok {
+			row = &HeatmapRow{Symbol: r.Symbol}
+			rows[r.Symbol] = row
+		}
+		hour := r.Timestamp.In(loc).Hour()
+		row.Hours[hour].add(r)
+		row.Total.add(r)
+		h.Hours[hour].add(r)
+		h.Total.add(r)
+	}
+	s.mu.RUnlock()
+
+	for _, row := range rows {
+		h.Symbols = append(h.Symbols, *row)
+	}
+	sort.Slice(h.Symbols, func(i, j int) bool {
+		if h.Symbols[i].Total.Count != h.Symbols[j].Total.Count {
+			return h.Symbols[i].Total.Count > h.Symbols[j].Total.Count
+		}
+		return h.Symbols[i].Symbol < h.Symbols[j].Symbol
+	})
+	return h
+}
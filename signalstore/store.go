@@ -0,0 +1,261 @@
+// Package signalstore persists every generated trade signal — reasoning,
+// confidence, source and the market snapshot it was generated against —
+// to an append-only JSONL file, and answers filtered, paginated queries
+// over that history.
+package signalstore
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+func logger() *slog.Logger { return slog.With("module", "signalstore") }
+
+// Pagination bounds for Query.
+const (
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+// Snapshot is the market state a signal was generated against.
+type Snapshot struct {
+	Price     float64  `json:"price"`
+	High24h   float64  `json:"high_24h"`
+	Low24h    float64  `json:"low_24h"`
+	Volume24h float64  `json:"volume_24h"`
+	Change24h float64  `json:"change_24h"`
+	Patterns  []string `json:"patterns,omitempty"`
+}
+
+// RiskReward is the expected risk and reward recorded with a signal that
+// opens a position. Amounts are per share.
+type RiskReward struct {
+	Direction     string  `json:"direction"`
+	Entry         float64 `json:"entry"`
+	Stop          float64 `json:"stop"`
+	Target        float64 `json:"target"`
+	LevelSource   string  `json:"level_source"`
+	StopSource    string  `json:"stop_source,omitempty"`
+	Risk          float64 `json:"risk"`
+	Reward        float64 `json:"reward"`
+	RMultiple     float64 `json:"r_multiple"`
+	Probability   float64 `json:"probability"`
+	ExpectedR     float64 `json:"expected_r"`
+	ExpectedValue float64 `json:"expected_value"`
+}
+
+// Analysis is the structured reasoning recorded with a signal.
+type Analysis struct {
+	Thesis            string   `json:"thesis"`
+	KeyRisks          []string `json:"key_risks"`
+	InvalidationLevel *float64 `json:"invalidation_level,omitempty"`
+	TimeHorizon       string   `json:"time_horizon"`
+}
+
+// AIUsage is the Claude tokens a signal consumed and their cost in
+// dollars.
+type AIUsage struct {
+	InputTokens  int     `json:"input_tokens"`
+	OutputTokens int     `json:"output_tokens"`
+	Estimated    bool    `json:"estimated,omitempty"`
+	Cost         float64 `json:"cost"`
+}
+
+// Record is one persisted signal.
+type Record struct {
+	ID         string      `json:"id"`
+	Symbol     string      `json:"symbol"`
+	Signal     string      `json:"signal"`
+	OrderType  string      `json:"order_type,omitempty"`
+	LimitPrice *float64    `json:"limit_price,omitempty"`
+	StopPrice  *float64    `json:"stop_price,omitempty"`
+	Confidence *float64    `json:"confidence,omitempty"`
+	Reasoning  string      `json:"reasoning"`
+	Analysis   *Analysis   `json:"analysis,omitempty"`
+	Source     string      `json:"source"`        // claude, algorithm:<type>, system, ...
+	Tag        string      `json:"tag,omitempty"` // strategy tag its orders carry
+	Timestamp  time.Time   `json:"timestamp"`
+	Market     *Snapshot   `json:"market,omitempty"`
+	RiskReward *RiskReward `json:"risk_reward,omitempty"`
+	AIUsage    *AIUsage    `json:"ai_usage,omitempty"`
+	// PromptVariant names the Claude prompt variant the signal was
+	// generated with
+	PromptVariant string `json:"prompt_variant,omitempty"`
+}
+
+// Query filters history. Zero values match everything.
+type Query struct {
+	Symbol string
+	Signal string
+	Source string
+	Tag    string
+	Text   string // case-insensitive substring of the reasoning
+	From   time.Time
+	To     time.Time
+	Limit  int
+	Offset int
+
+	// PromptVariant matches Claude signals generated with that prompt
+	PromptVariant string
+}
+
+// Page is one page of query results, newest first.
+type Page struct {
+	Items  []Record `json:"items"`
+	Total  int      `json:"total"`
+	Limit  int      `json:"limit"`
+	Offset int      `json:"offset"`
+}
+
+// Store is an append-only signal log with an in-memory index.
+type Store struct {
+	path string
+
+	mu      sync.RWMutex
+	file    *os.File
+	records []Record
+	seq     int
+}
+
+// Open loads the history at path (creating it if needed) and opens it for
+// appending. Malformed lines are skipped with a warning rather than
+// failing startup.
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create signal history directory: %w", err)
+	}
+
+	s := &Store{path: path}
+	if f, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		line := 0
+		for scanner.Scan() {
+			line++
+			var r Record
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				logger().Warn("Skipping malformed signal history line", "line", line, "error", err)
+				continue
+			}
+			s.records = append(s.records, r)
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read signal history: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to open signal history: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open signal history for writing: %w", err)
+	}
+	s.file = f
+	return s, nil
+}
+
+// Append persists r, filling in ID and Timestamp when empty, and returns
+// the stored record.
+func (s *Store) Append(r Record) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r.Timestamp.IsZero() {
+		r.Timestamp = time.Now()
+	}
+	if r.ID == "" {
+		s.seq++
+		r.ID = fmt.Sprintf("sig_%d_%d", r.Timestamp.UnixNano(), s.seq)
+	}
+	r.Symbol = strings.ToUpper(r.Symbol)
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return Record{}, fmt.Errorf("failed to encode signal: %w", err)
+	}
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return Record{}, fmt.Errorf("failed to write signal history: %w", err)
+	}
+	s.records = append(s.records, r)
+	return r, nil
+}
+
+// Query returns a page of the records matching q, newest first.
+func (s *Store) Query(q Query) Page {
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	matched := s.Select(q)
+	page := Page{Items: []Record{}, Total: len(matched), Limit: q.Limit, Offset: q.Offset}
+	if q.Offset < len(matched) {
+		end := q.Offset + q.Limit
+		if end > len(matched) {
+			end = len(matched)
+		}
+		page.Items = matched[q.Offset:end]
+	}
+	return page
+}
+
+// Select returns every record matching q, newest first, ignoring its
+// limit and offset.
+func (s *Store) Select(q Query) []Record {
+	symbol := strings.ToUpper(q.Symbol)
+	text := strings.ToLower(q.Text)
+
+	s.mu.RLock()
+	var matched []Record
+	for _, r := range s.records {
+		if symbol != "" && r.Symbol != symbol {
+			continue
+		}
+		if q.Signal != "" && !strings.EqualFold(r.Signal, q.Signal) {
+			continue
+		}
+		if q.Source != "" && !strings.EqualFold(r.Source, q.Source) {
+			continue
+		}
+		if q.Tag != "" && !strings.EqualFold(r.Tag, q.Tag) {
+			continue
+		}
+		if q.PromptVariant != "" && !strings.EqualFold(r.PromptVariant, q.PromptVariant) {
+			continue
+		}
+		if !q.From.IsZero() && r.Timestamp.Before(q.From) {
+			continue
+		}
+		if !q.To.IsZero() && r.Timestamp.After(q.To) {
+			continue
+		}
+		if text != "" && !strings.Contains(strings.ToLower(r.Reasoning), text) {
+			continue
+		}
+		matched = append(matched, r)
+	}
+	s.mu.RUnlock()
+
+	sort.SliceStable(matched, func(i, j int) bool { return matched[i].Timestamp.After(matched[j].Timestamp) })
+	return matched
+}
+
+// Close closes the underlying file.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,123 @@
+package signalstore
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendQueryAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "signals", "history.jsonl")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC)
+	conf := 0.8
+	records := []Record{
+		{Symbol: "aapl", Signal: "buy", Reasoning: "Strong earnings momentum", Source: "claude", Timestamp: base, Confidence: &conf,
+			Market: &Snapshot{Price: 190, Patterns: []string{"hammer"}}},
+		{Symbol: "AAPL", Signal: "hold", Reasoning: "Waiting for confirmation", Source: "claude", Timestamp: base.Add(time.Hour)},
+		{Symbol: "MSFT", Signal: "buy", Reasoning: "Cloud EARNINGS beat", Source: "algorithm:hrp", Timestamp: base.Add(2 * time.Hour)},
+		{Symbol: "MSFT", Signal: "sell", Reasoning: "Momentum fading", Source: "claude", Timestamp: base.Add(48 * time.Hour)},
+	}
+	for _, r := range records {
+		if _, err := s.Append(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	page := s.Query(Query{Signal: "buy"})
+	if page.Total != 2 || page.Items[0].Symbol != "MSFT" {
+		t.Fatalf("buy query = %+v, want 2 results newest first", page)
+	}
+
+	page = s.Query(Query{Text: "earnings"})
+	if page.Total != 2 {
+		t.Errorf("text search total = %d, want 2", page.Total)
+	}
+
+	page = s.Query(Query{Symbol: "aapl", To: base.Add(30 * time.Minute)})
+	if page.Total != 1 || page.Items[0].Market == nil || page.Items[0].Market.Patterns[0] != "hammer" {
+		t.Errorf("symbol/to query = %+v", page)
+	}
+
+	page = s.Query(Query{Limit: 2, Offset: 2})
+	if page.Total != 4 || len(page.Items) != 2 || page.Items[1].Signal != "buy" {
+		t.Errorf("paged query = %+v", page)
+	}
+	if page = s.Query(Query{Offset: 10}); len(page.Items) != 0 {
+		t.Errorf("offset past end returned %d items", len(page.Items))
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopened.Close()
+	page = reopened.Query(Query{Source: "algorithm:hrp"})
+	if page.Total != 1 || page.Items[0].ID == "" {
+		t.Errorf("reloaded query = %+v", page)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	end, err := parseTime("2024-05-01", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if end.Hour() != 23 || end.Day() != 1 {
+		t.Errorf("end-of-day bound = %v", end)
+	}
+	if _, err := parseTime("yesterday", false); err == nil {
+		t.Error("expected error for unparseable time")
+	}
+}
+
+func TestHeatmap(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "history.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	ny, _ := time.LoadLocation("America/New_York")
+	at := func(day, hour, min int) time.Time { return time.Date(2024, 5, day, hour, min, 0, 0, ny) }
+	conf := func(c float64) *float64 { return &c }
+	for _, r := range []Record{
+		{Symbol: "AAPL", Signal: "buy", Source: "claude", Timestamp: at(1, 9, 35), Confidence: conf(0.8)},
+		{Symbol: "AAPL", Signal: "hold", Source: "claude", Timestamp: at(1, 9, 50), Confidence: conf(0.4)},
+		{Symbol: "AAPL", Signal: "sell", Source: "claude", Timestamp: at(2, 15, 0)},
+		{Symbol: "MSFT", Signal: "buy", Source: "algorithm:hrp", Timestamp: at(2, 9, 40), Confidence: conf(0.9)},
+		{Symbol: "MSFT", Signal: "buy", Source: "claude", Timestamp: at(9, 9, 40)},
+	} {
+		if _, err := s.Append(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	h := s.Heatmap(HeatmapQuery{From: at(1, 0, 0), To: at(3, 0, 0), Location: ny})
+	if h.Total.Count != 4 || len(h.Symbols) != 2 || h.Symbols[0].Symbol != "AAPL" {
+		t.Fatalf("heatmap = %+v", h)
+	}
+	nine := h.Symbols[0].Hours[9]
+	if nine.Count != 2 || nine.Rated != 2 || math.Abs(nine.AvgConfidence-0.6) > 1e-9 {
+		t.Errorf("AAPL 9:00 = %+v", nine)
+	}
+	if c := h.Symbols[0].Hours[15]; c.Count != 1 || c.Rated != 0 || c.AvgConfidence != 0 {
+		t.Errorf("AAPL 15:00 = %+v", c)
+	}
+	if h.Hours[9].Count != 3 || h.Symbols[0].Total.Count != 3 {
+		t.Errorf("hour totals = %+v, AAPL total %+v", h.Hours[9], h.Symbols[0].Total)
+	}
+
+	// Hours follow the requested zone; filters apply
+	h = s.Heatmap(HeatmapQuery{Source: "claude", Signal: "buy"})
+	if h.Timezone != "UTC" || h.Total.Count != 2 || h.Hours[13].Count != 2 {
+		t.Errorf("UTC claude buys = %+v", h)
+	}
+}
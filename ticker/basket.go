@@ -1,316 +1,269 @@
-package ticker
-
-import (
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"log"
-	"os"
-	"path/filepath"
-	"sync"
-	"time"
-)
-
-// TickerBasket represents a collection of related ticker symbols
-type TickerBasket struct {
-	ID          string   `json:"id"`
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	Symbols     []string `json:"symbols"`
-	CreatedAt   string   `json:"created_at"`
-	UpdatedAt   string   `json:"updated_at"`
-	CreatedBy   string   `json:"created_by,omitempty"`
-	Tags        []string `json:"tags,omitempty"`
-	IsActive    bool     `json:"is_active"`
-	Category    string   `json:"category,omitempty"`
-}
-
-// BasketManager manages ticker baskets
-type BasketManager struct {
-	dataDir string
-	baskets map[string]*TickerBasket
-	mutex   sync.RWMutex
-}
-
-// NewBasketManager creates a new basket manager
-func NewBasketManager(dataDir string) (*BasketManager, error) {
-	// Create data directory if it doesn't exist
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create data directory: %w", err)
-	}
-
-	manager := &BasketManager{
-		dataDir: dataDir,
-		baskets: make(map[string]*TickerBasket),
-	}
-
-	// Load existing baskets
-	if err := manager.loadBaskets(); err != nil {
-		log.Printf("Warning: Failed to load baskets: %v", err)
-	}
-
-	return manager, nil
-}
-
-// loadBaskets loads all basket files from the data directory
-func (m *BasketManager) loadBaskets() error {
-	basketDir := filepath.Join(m.dataDir, "baskets")
-	if err := os.MkdirAll(basketDir, 0755); err != nil {
-		return fmt.Errorf("failed to create baskets directory: %w", err)
-	}
-
-	files, err := ioutil.ReadDir(basketDir)
-	if err != nil {
-		return fmt.Errorf("failed to read baskets directory: %w", err)
-	}
-
-	for _, file := range files {
-		if file.IsDir() || filepath.Ext(file.Name()) != ".json" {
-			continue
-		}
-
-		filePath := filepath.Join(basketDir, file.Name())
-		data, err := ioutil.ReadFile(filePath)
-		if err != nil {
-			log.Printf("Warning: Failed to read basket file %s: %v", filePath, err)
-			continue
-		}
-
-		var basket TickerBasket
-		if err := json.Unmarshal(data, &basket); err != nil {
-			log.Printf("Warning: Failed to parse basket file %s: %v", filePath, err)
-			continue
-		}
-
-		if basket.ID == "" {
-			log.Printf("Warning: Basket file %s has no ID", filePath)
-			continue
-		}
-
-		m.mutex.Lock()
-		m.baskets[basket.ID] = &basket
-		m.mutex.Unlock()
-	}
-
-	log.Printf("Loaded %d ticker baskets from %s", len(m.baskets), basketDir)
-	return nil
-}
-
-// SaveBasket saves a ticker basket to disk
-func (m *BasketManager) SaveBasket(basket *TickerBasket) error {
-	// Validate basket
-	if basket.ID == "" {
-		basket.ID = generateID()
-	}
-	if basket.CreatedAt == "" {
-		basket.CreatedAt = time.Now().Format(time.RFC3339)
-	}
-	basket.UpdatedAt = time.Now().Format(time.RFC3339)
-
-	// Add to memory cache
-	m.mutex.Lock()
-	m.baskets[basket.ID] = basket
-	m.mutex.Unlock()
-
-	// Save to disk
-	basketDir := filepath.Join(m.dataDir, "baskets")
-	if err := os.MkdirAll(basketDir, 0755); err != nil {
-		return fmt.Errorf("failed to create baskets directory: %w", err)
-	}
-
-	filePath := filepath.Join(basketDir, fmt.Sprintf("%s.json", basket.ID))
-	data, err := json.MarshalIndent(basket, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal basket: %w", err)
-	}
-
-	if err := ioutil.WriteFile(filePath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write basket file: %w", err)
-	}
-
-	log.Printf("Saved ticker basket %s (%s) with %d symbols", 
-		basket.ID, basket.Name, len(basket.Symbols))
-	return nil
-}
-
-// GetBasket retrieves a ticker basket by ID
-func (m *BasketManager) GetBasket(id string) (*TickerBasket, error) {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
-
-	basket, exists := m.baskets[id]
-	if !exists {
-		return nil, fmt.Errorf("basket not found: %s", id)
-	}
-
-	// Return a copy to avoid race conditions
-	basketCopy := *basket
-	return &basketCopy, nil
-}
-
-// DeleteBasket deletes a ticker basket
-func (m *BasketManager) DeleteBasket(id string) error {
-	// Check if basket exists
-	m.mutex.RLock()
-	_, exists := m.baskets[id]
-	m.mutex.RUnlock()
-
-	if !exists {
-		return fmt.Errorf("basket not found: %s", id)
-	}
-
-	// Delete from memory cache
-	m.mutex.Lock()
-	delete(m.baskets, id)
-	m.mutex.Unlock()
-
-	// Delete from disk
-	basketDir := filepath.Join(m.dataDir, "baskets")
-	filePath := filepath.Join(basketDir, fmt.Sprintf("%s.json", id))
-	if err := os.Remove(filePath); err != nil {
-		return fmt.Errorf("failed to delete basket file: %w", err)
-	}
-
-	log.Printf("Deleted ticker basket %s", id)
-	return nil
-}
-
-// ListBaskets returns a list of all ticker baskets
-func (m *BasketManager) ListBaskets() []TickerBasket {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
-
-	baskets := make([]TickerBasket, 0, len(m.baskets))
-	for _, basket := range m.baskets {
-		baskets = append(baskets, *basket)
-	}
-
-	return baskets
-}
-
-// AddSymbolToBasket adds a symbol to a ticker basket
-func (m *BasketManager) AddSymbolToBasket(basketID, symbol string) error {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-
-	basket, exists := m.baskets[basketID]
-	if !exists {
-		return fmt.Errorf("basket not found: %s", basketID)
-	}
-
-	// Check if symbol already exists
-	for _, s := range basket.Symbols {
-		if s == symbol {
-			// Symbol already exists, no need to add
-			return nil
-		}
-	}
-
-	// Add symbol
-	basket.Symbols = append(basket.Symbols, symbol)
-	basket.UpdatedAt = time.Now().Format(time.RFC3339)
-
-	// Save to disk
-	basketDir := filepath.Join(m.dataDir, "baskets")
-	filePath := filepath.Join(basketDir, fmt.Sprintf("%s.json", basketID))
-	data, err := json.MarshalIndent(basket, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal basket: %w", err)
-	}
-
-	if err := ioutil.WriteFile(filePath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write basket file: %w", err)
-	}
-
-	log.Printf("Added symbol %s to basket %s (%s)", symbol, basketID, basket.Name)
-	return nil
-}
-
-// RemoveSymbolFromBasket removes a symbol from a ticker basket
-func (m *BasketManager) RemoveSymbolFromBasket(basketID, symbol string) error {
-	m.mutex.Lock()
-	defer m.mutex.Unlock()
-
-	basket, exists := m.baskets[basketID]
-	if !exists {
-		return fmt.Errorf("basket not found: %s", basketID)
-	}
-
-	// Find and remove symbol
-	for i, s := range basket.Symbols {
-		if s == symbol {
-			// Remove symbol
-			basket.Symbols = append(basket.Symbols[:i], basket.Symbols[i+1:]...)
-			basket.UpdatedAt = time.Now().Format(time.RFC3339)
-
-			// Save to disk
-			basketDir := filepath.Join(m.dataDir, "baskets")
-			filePath := filepath.Join(basketDir, fmt.Sprintf("%s.json", basketID))
-			data, err := json.MarshalIndent(basket, "", "  ")
-			if err != nil {
-				return fmt.Errorf("failed to marshal basket: %w", err)
-			}
-
-			if err := ioutil.WriteFile(filePath, data, 0644); err != nil {
-				return fmt.Errorf("failed to write basket file: %w", err)
-			}
-
-			log.Printf("Removed symbol %s from basket %s (%s)", symbol, basketID, basket.Name)
-			return nil
-		}
-	}
-
-	// Symbol not found
-	return fmt.Errorf("symbol not found in basket: %s", symbol)
-}
-
-// GetBasketsBySymbol returns a list of baskets containing a specific symbol
-func (m *BasketManager) GetBasketsBySymbol(symbol string) []TickerBasket {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
-
-	var baskets []TickerBasket
-	for _, basket := range m.baskets {
-		for _, s := range basket.Symbols {
-			if s == symbol {
-				baskets = append(baskets, *basket)
-				break
-			}
-		}
-	}
-
-	return baskets
-}
-
-// GetSymbolsFromBaskets returns a unique list of all symbols from a set of basket IDs
-func (m *BasketManager) GetSymbolsFromBaskets(basketIDs []string) []string {
-	m.mutex.RLock()
-	defer m.mutex.RUnlock()
-
-	// Use a map for deduplication
-	symbolMap := make(map[string]bool)
-
-	for _, basketID := range basketIDs {
-		basket, exists := m.baskets[basketID]
-		if !exists {
-			continue
-		}
-
-		for _, symbol := range basket.Symbols {
-			symbolMap[symbol] = true
-		}
-	}
-
-	// Convert map keys to slice
-	symbols := make([]string, 0, len(symbolMap))
-	for symbol := range symbolMap {
-		symbols = append(symbols, symbol)
-	}
-
-	return symbols
-}
-
-// Helper function to generate a simple ID
-func generateID() string {
-	return fmt.Sprintf("basket_%d", time.Now().UnixNano())
-}
\ No newline at end of file
+package ticker
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TickerBasket represents a collection of related ticker symbols
+type TickerBasket struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Symbols     []string `json:"symbols"`
+	CreatedAt   string   `json:"created_at"`
+	UpdatedAt   string   `json:"updated_at"`
+	CreatedBy   string   `json:"created_by,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	IsActive    bool     `json:"is_active"`
+	Category    string   `json:"category,omitempty"`
+	// Version increases with every change; updates carrying a stale one
+	// are refused
+	Version int `json:"version"`
+}
+
+// BasketManager manages ticker baskets, keeping them in memory and
+// writing every change through to its store
+type BasketManager struct {
+	store   BasketStore
+	baskets map[string]*TickerBasket
+	mutex   sync.RWMutex
+}
+
+// NewBasketManager creates a basket manager backed by basket files in
+// dataDir
+func NewBasketManager(dataDir string) (*BasketManager, error) {
+	store, err := NewFileBasketStore(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	return NewBasketManagerWithStore(store), nil
+}
+
+// NewBasketManagerWithStore creates a basket manager backed by store and
+// loads its baskets. Baskets saved before versioning start at version 1.
+func NewBasketManagerWithStore(store BasketStore) *BasketManager {
+	manager := &BasketManager{
+		store:   store,
+		baskets: make(map[string]*TickerBasket),
+	}
+
+	baskets, err := store.Load()
+	if err != nil {
+		logger().Warn("Failed to load baskets", "error", err)
+	}
+	for i := range baskets {
+		basket := baskets[i]
+		if basket.Version < 1 {
+			basket.Version = 1
+		}
+		manager.baskets[basket.ID] = &basket
+	}
+	logger().Info("Loaded ticker baskets", "count", len(manager.baskets))
+	return manager
+}
+
+// SaveBasket creates or updates a ticker basket. An update must carry the
+// version it was read at: without one it fails with ErrVersionRequired,
+// and with an older one with ErrVersionConflict, instead of overwriting a
+// newer edit. On success basket holds the new version.
+func (m *BasketManager) SaveBasket(basket *TickerBasket) error {
+	if basket.ID == "" {
+		basket.ID = generateID()
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	version := 1
+	if existing, ok := m.baskets[basket.ID]; ok {
+		if basket.Version == 0 {
+			return fmt.Errorf("%w: basket %s exists; send the version it was read at", ErrVersionRequired, basket.ID)
+		}
+		if basket.Version != existing.Version {
+			return fmt.Errorf("%w: basket %s is at version %d, not %d", ErrVersionConflict, basket.ID, existing.Version, basket.Version)
+		}
+		version = existing.Version + 1
+		if basket.CreatedAt == "" {
+			basket.CreatedAt = existing.CreatedAt
+		}
+	}
+	if basket.CreatedAt == "" {
+		basket.CreatedAt = time.Now().Format(time.RFC3339)
+	}
+	basket.UpdatedAt = time.Now().Format(time.RFC3339)
+	basket.Version = version
+
+	saved := *basket
+	if err := m.store.Put(saved); err != nil {
+		return err
+	}
+	m.baskets[saved.ID] = &saved
+
+	logger().Info("Saved ticker basket", "id", basket.ID, "name", basket.Name, "symbols", len(basket.Symbols), "version", basket.Version)
+	return nil
+}
+
+// GetBasket retrieves a ticker basket by ID
+func (m *BasketManager) GetBasket(id string) (*TickerBasket, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	basket, exists := m.baskets[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrBasketNotFound, id)
+	}
+
+	// Return a copy to avoid race conditions
+	basketCopy := *basket
+	return &basketCopy, nil
+}
+
+// DeleteBasket deletes a ticker basket
+func (m *BasketManager) DeleteBasket(id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, exists := m.baskets[id]; !exists {
+		return fmt.Errorf("%w: %s", ErrBasketNotFound, id)
+	}
+	if err := m.store.Delete(id); err != nil {
+		return err
+	}
+	delete(m.baskets, id)
+
+	logger().Info("Deleted ticker basket", "id", id)
+	return nil
+}
+
+// ListBaskets returns a list of all ticker baskets
+func (m *BasketManager) ListBaskets() []TickerBasket {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	baskets := make([]TickerBasket, 0, len(m.baskets))
+	for _, basket := range m.baskets {
+		baskets = append(baskets, *basket)
+	}
+
+	return baskets
+}
+
+// AddSymbolToBasket adds a symbol to a ticker basket
+func (m *BasketManager) AddSymbolToBasket(basketID, symbol string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	basket, exists := m.baskets[basketID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrBasketNotFound, basketID)
+	}
+
+	// Check if symbol already exists
+	for _, s := range basket.Symbols {
+		if s == symbol {
+			// Symbol already exists, no need to add
+			return nil
+		}
+	}
+
+	updated := *basket
+	updated.Symbols = append(append([]string(nil), basket.Symbols...), symbol)
+	if err := m.putLocked(&updated); err != nil {
+		return err
+	}
+
+	logger().Info("Added symbol to basket", "symbol", symbol, "basket", basketID, "name", basket.Name)
+	return nil
+}
+
+// RemoveSymbolFromBasket removes a symbol from a ticker basket
+func (m *BasketManager) RemoveSymbolFromBasket(basketID, symbol string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	basket, exists := m.baskets[basketID]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrBasketNotFound, basketID)
+	}
+
+	// Find and remove symbol
+	for i, s := range basket.Symbols {
+		if s == symbol {
+			updated := *basket
+			updated.Symbols = append(append([]string(nil), basket.Symbols[:i]...), basket.Symbols[i+1:]...)
+			if err := m.putLocked(&updated); err != nil {
+				return err
+			}
+
+			logger().Info("Removed symbol from basket", "symbol", symbol, "basket", basketID, "name", basket.Name)
+			return nil
+		}
+	}
+
+	// Symbol not found
+	return fmt.Errorf("symbol not found in basket: %s", symbol)
+}
+
+// putLocked stores basket as the next version of itself.
+func (m *BasketManager) putLocked(basket *TickerBasket) error {
+	basket.Version++
+	basket.UpdatedAt = time.Now().Format(time.RFC3339)
+	if err := m.store.Put(*basket); err != nil {
+		return err
+	}
+	m.baskets[basket.ID] = basket
+	return nil
+}
+
+// GetBasketsBySymbol returns a list of baskets containing a specific symbol
+func (m *BasketManager) GetBasketsBySymbol(symbol string) []TickerBasket {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	var baskets []TickerBasket
+	for _, basket := range m.baskets {
+		for _, s := range basket.Symbols {
+			if s == symbol {
+				baskets = append(baskets, *basket)
+				break
+			}
+		}
+	}
+
+	return baskets
+}
+
+// GetSymbolsFromBaskets returns a unique list of all symbols from a set of basket IDs
+func (m *BasketManager) GetSymbolsFromBaskets(basketIDs []string) []string {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	// Use a map for deduplication
+	symbolMap := make(map[string]bool)
+
+	for _, basketID := range basketIDs {
+		basket, exists := m.baskets[basketID]
+		if !exists {
+			continue
+		}
+
+		for _, symbol := range basket.Symbols {
+			symbolMap[symbol] = true
+		}
+	}
+
+	// Convert map keys to slice
+	symbols := make([]string, 0, len(symbolMap))
+	for symbol := range symbolMap {
+		symbols = append(symbols, symbol)
+	}
+
+	return symbols
+}
+
+// Helper function to generate a simple ID
+func generateID() string {
+	return fmt.Sprintf("basket_%d", time.Now().UnixNano())
+}
@@ -0,0 +1,240 @@
+package ticker
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BasketSchemaVersion is the version written into basket exports. Bump it
+// when a change to TickerBasket would be misread by an older instance.
+const BasketSchemaVersion = 1
+
+// Basket export formats.
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// basketCSVHeader is the column order of CSV exports. Tags and symbols are
+// joined with semicolons.
+var basketCSVHeader = []string{"schema_version", "id", "name", "description", "category", "tags", "is_active", "symbols"}
+
+// BasketExport is the document written by an export and read by an
+// import. Settings added to TickerBasket travel with it automatically.
+type BasketExport struct {
+	SchemaVersion int            `json:"schema_version"`
+	ExportedAt    string         `json:"exported_at"`
+	Baskets       []TickerBasket `json:"baskets"`
+}
+
+// BasketImportResult reports what an import did with each basket.
+type BasketImportResult struct {
+	Imported []string `json:"imported"`
+	Skipped  []string `json:"skipped"` // IDs that already existed
+}
+
+// ExportBaskets encodes baskets in format.
+func ExportBaskets(baskets []TickerBasket, format string) ([]byte, error) {
+	switch format {
+	case "", FormatJSON:
+		return json.MarshalIndent(BasketExport{
+			SchemaVersion: BasketSchemaVersion,
+			ExportedAt:    time.Now().Format(time.RFC3339),
+			Baskets:       baskets,
+		}, "", "  ")
+	case FormatCSV:
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write(basketCSVHeader)
+		for _, b := range baskets {
+			w.Write([]string{
+				strconv.Itoa(BasketSchemaVersion),
+				b.ID,
+				b.Name,
+				b.Description,
+				b.Category,
+				strings.Join(b.Tags, ";"),
+				strconv.FormatBool(b.IsActive),
+				strings.Join(b.Symbols, ";"),
+			})
+		}
+		w.Flush()
+		return buf.Bytes(), w.Error()
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// ParseBasketImport decodes an export in format. JSON may also be a bare
+// basket or array of baskets from before exports were versioned. Exports
+// from a newer schema are refused rather than silently dropping fields.
+func ParseBasketImport(data []byte, format string) ([]TickerBasket, error) {
+	var baskets []TickerBasket
+	switch format {
+	case "", FormatJSON:
+		trimmed := bytes.TrimSpace(data)
+		switch {
+		case len(trimmed) == 0:
+			return nil, fmt.Errorf("empty import")
+		case trimmed[0] == '[':
+			if err := json.Unmarshal(trimmed, &baskets); err != nil {
+				return nil, fmt.Errorf("invalid basket list: %w", err)
+			}
+		default:
+			var doc struct {
+				BasketExport
+				TickerBasket
+			}
+			if err := json.Unmarshal(trimmed, &doc); err != nil {
+				return nil, fmt.Errorf("invalid basket export: %w", err)
+			}
+			if err := checkSchemaVersion(doc.SchemaVersion); err != nil {
+				return nil, err
+			}
+			baskets = doc.Baskets
+			if doc.Baskets == nil && (doc.TickerBasket.ID != "" || doc.TickerBasket.Name != "") {
+				baskets = []TickerBasket{doc.TickerBasket}
+			}
+		}
+	case FormatCSV:
+		var err error
+		if baskets, err = parseBasketCSV(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+
+	for i := range baskets {
+		b := &baskets[i]
+		b.Name = strings.TrimSpace(b.Name)
+		if b.Name == "" {
+			return nil, fmt.Errorf("basket %d has no name", i+1)
+		}
+		// IDs become file names
+		if strings.ContainsAny(b.ID, `/\`) || strings.Contains(b.ID, "..") {
+			return nil, fmt.Errorf("basket %q has an invalid id", b.Name)
+		}
+		b.Symbols = normalizeSymbols(b.Symbols)
+	}
+	return baskets, nil
+}
+
+func checkSchemaVersion(v int) error {
+	if v > BasketSchemaVersion {
+		return fmt.Errorf("export uses schema version %d; this instance reads up to %d", v, BasketSchemaVersion)
+	}
+	return nil
+}
+
+func parseBasketCSV(r io.Reader) ([]TickerBasket, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("empty import")
+	}
+	col := make(map[string]int, len(rows[0]))
+	for i, name := range rows[0] {
+		col[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := col["name"]; !ok {
+		return nil, fmt.Errorf("CSV header must include name")
+	}
+	get := func(row []string, name string) string {
+		if i, ok := col[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var baskets []TickerBasket
+	for n, row := range rows[1:] {
+		if v := get(row, "schema_version"); v != "" {
+			version, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: invalid schema_version %q", n+2, v)
+			}
+			if err := checkSchemaVersion(version); err != nil {
+				return nil, err
+			}
+		}
+		active := true
+		if v := get(row, "is_active"); v != "" {
+			var err error
+			if active, err = strconv.ParseBool(v); err != nil {
+				return nil, fmt.Errorf("row %d: invalid is_active %q", n+2, v)
+			}
+		}
+		baskets = append(baskets, TickerBasket{
+			ID:          get(row, "id"),
+			Name:        get(row, "name"),
+			Description: get(row, "description"),
+			Category:    get(row, "category"),
+			Tags:        splitList(get(row, "tags")),
+			IsActive:    active,
+			Symbols:     splitList(get(row, "symbols")),
+		})
+	}
+	return baskets, nil
+}
+
+// splitList splits a semicolon-separated cell, dropping empty entries.
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ";") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// normalizeSymbols upper-cases and de-duplicates symbols, keeping order.
+func normalizeSymbols(symbols []string) []string {
+	seen := make(map[string]bool, len(symbols))
+	out := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	return out
+}
+
+// ImportBaskets saves baskets. A basket whose ID already exists is skipped
+// unless overwrite is set, in which case it is replaced whatever its
+// version but keeps its original creation time. Baskets without an ID get a new one.
+func (m *BasketManager) ImportBaskets(baskets []TickerBasket, overwrite bool) (BasketImportResult, error) {
+	result := BasketImportResult{Imported: []string{}, Skipped: []string{}}
+	for i := range baskets {
+		b := baskets[i]
+		// Versions belong to the instance that exported the basket
+		b.Version = 0
+		if b.ID != "" {
+			if existing, err := m.GetBasket(b.ID); err == nil {
+				if !overwrite {
+					result.Skipped = append(result.Skipped, b.ID)
+					continue
+				}
+				b.CreatedAt = existing.CreatedAt
+				b.Version = existing.Version
+			}
+		}
+		if err := m.SaveBasket(&b); err != nil {
+			return result, fmt.Errorf("failed to import basket %q: %w", b.Name, err)
+		}
+		result.Imported = append(result.Imported, b.ID)
+	}
+	return result, nil
+}
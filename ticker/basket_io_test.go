@@ -0,0 +1,84 @@
+package ticker
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBasketImportRefusesNewerSchema(t *testing.T) {
+	_, err := ParseBasketImport([]byte(`{"schema_version": 2, "baskets": [{"id": "tech", "name": "Tech"}]}`), FormatJSON)
+	if err == nil || !strings.Contains(err.Error(), "schema version 2") {
+		t.Errorf("JSON: %v", err)
+	}
+	csv := "schema_version,id,name,symbols\n2,tech,Tech,AAPL\n"
+	if _, err := ParseBasketImport([]byte(csv), FormatCSV); err == nil {
+		t.Error("CSV from a newer schema accepted")
+	}
+}
+
+func TestParseBasketImportReadsLegacyJSON(t *testing.T) {
+	// A bare basket, as saved before exports were versioned
+	baskets, err := ParseBasketImport([]byte(`{"id": "tech", "name": " Tech ", "symbols": ["aapl", "MSFT", "AAPL"]}`), FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(baskets) != 1 || baskets[0].Name != "Tech" || !reflect.DeepEqual(baskets[0].Symbols, []string{"AAPL", "MSFT"}) {
+		t.Errorf("bare basket = %+v", baskets)
+	}
+
+	baskets, err = ParseBasketImport([]byte(`[{"name": "Tech"}, {"name": "Energy", "symbols": ["XOM"]}]`), FormatJSON)
+	if err != nil || len(baskets) != 2 || baskets[1].Symbols[0] != "XOM" {
+		t.Errorf("bare list = %+v, %v", baskets, err)
+	}
+}
+
+func TestBasketCSVRoundTrip(t *testing.T) {
+	want := []TickerBasket{
+		{ID: "tech", Name: "Tech", Description: "Large caps, mostly", Category: "sector", Tags: []string{"core", "growth"}, IsActive: true, Symbols: []string{"AAPL", "MSFT"}},
+		{ID: "energy", Name: "Energy", Symbols: []string{"XOM"}},
+	}
+	data, err := ExportBaskets(want, FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseBasketImport(data, FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip:\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func TestParseBasketImportRejectsInvalidIDs(t *testing.T) {
+	for _, id := range []string{"../etc", "a/b", `a\b`} {
+		doc := `{"schema_version": 1, "baskets": [{"id": "` + strings.ReplaceAll(id, `\`, `\\`) + `", "name": "Bad"}]}`
+		if _, err := ParseBasketImport([]byte(doc), FormatJSON); err == nil {
+			t.Errorf("id %q accepted", id)
+		}
+	}
+	if _, err := ParseBasketImport([]byte(`[{"id": "tech"}]`), FormatJSON); err == nil {
+		t.Error("basket without a name accepted")
+	}
+}
+
+func TestImportBasketsOverwritesExisting(t *testing.T) {
+	store, err := OpenBasketStore(StoreFile, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewBasketManagerWithStore(store)
+	m.SaveBasket(&TickerBasket{ID: "tech", Name: "Tech", Symbols: []string{"AAPL"}})
+
+	imported := []TickerBasket{{ID: "tech", Name: "Technology", Symbols: []string{"MSFT"}, Version: 7}}
+	if res, err := m.ImportBaskets(imported, false); err != nil || len(res.Skipped) != 1 {
+		t.Fatalf("without overwrite: %+v, %v", res, err)
+	}
+	if res, err := m.ImportBaskets(imported, true); err != nil || len(res.Imported) != 1 {
+		t.Fatalf("overwrite: %+v, %v", res, err)
+	}
+	if got, _ := m.GetBasket("tech"); got.Name != "Technology" || got.Version != 2 {
+		t.Errorf("after overwrite %+v", got)
+	}
+}
@@ -0,0 +1,150 @@
+package ticker
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteBasketStore keeps baskets as JSON documents in one SQLite
+// database, <dataDir>/baskets.db, written transactionally.
+type SQLiteBasketStore struct {
+	db *sql.DB
+}
+
+// OpenSQLiteBasketStore opens or creates the database in dataDir. When
+// the database is new and dataDir has basket files from the file store,
+// they are imported; the files are left in place.
+func OpenSQLiteBasketStore(dataDir string) (*SQLiteBasketStore, error) {
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	path := filepath.Join(dataDir, "baskets.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open basket database: %w", err)
+	}
+	// One connection serializes writers instead of failing them as busy
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS baskets (
+		id         TEXT PRIMARY KEY,
+		version    INTEGER NOT NULL,
+		updated_at TEXT NOT NULL,
+		data       TEXT NOT NULL
+	)`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create basket table: %w", err)
+	}
+	s := &SQLiteBasketStore{db: db}
+	if err := s.importFiles(dataDir); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// importFiles copies the file store's baskets into an empty database.
+func (s *SQLiteBasketStore) importFiles(dataDir string) error {
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM baskets`).Scan(&count); err != nil {
+		return fmt.Errorf("failed to count baskets: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "baskets")); err != nil {
+		return nil
+	}
+	files, err := NewFileBasketStore(dataDir)
+	if err != nil {
+		return err
+	}
+	baskets, err := files.Load()
+	if err != nil || len(baskets) == 0 {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to import basket files: %w", err)
+	}
+	defer tx.Rollback()
+	for _, b := range baskets {
+		if b.Version < 1 {
+			b.Version = 1
+		}
+		if err := put(tx, b); err != nil {
+			return fmt.Errorf("failed to import basket %s: %w", b.ID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to import basket files: %w", err)
+	}
+	logger().Info("Imported basket files into SQLite", "count", len(baskets), "dir", filepath.Join(dataDir, "baskets"))
+	return nil
+}
+
+// Load returns every basket in the database, skipping unreadable rows.
+func (s *SQLiteBasketStore) Load() ([]TickerBasket, error) {
+	rows, err := s.db.Query(`SELECT id, data FROM baskets`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baskets: %w", err)
+	}
+	defer rows.Close()
+
+	var baskets []TickerBasket
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, fmt.Errorf("failed to read baskets: %w", err)
+		}
+		var basket TickerBasket
+		if err := json.Unmarshal([]byte(data), &basket); err != nil {
+			logger().Warn("Failed to parse stored basket", "id", id, "error", err)
+			continue
+		}
+		baskets = append(baskets, basket)
+	}
+	return baskets, rows.Err()
+}
+
+// Put creates or replaces basket.
+func (s *SQLiteBasketStore) Put(basket TickerBasket) error {
+	return put(s.db, basket)
+}
+
+// execer is what put needs of a database or transaction.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func put(db execer, basket TickerBasket) error {
+	data, err := json.Marshal(basket)
+	if err != nil {
+		return fmt.Errorf("failed to marshal basket: %w", err)
+	}
+	_, err = db.Exec(`INSERT INTO baskets (id, version, updated_at, data) VALUES (?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET version = excluded.version, updated_at = excluded.updated_at, data = excluded.data`,
+		basket.ID, basket.Version, basket.UpdatedAt, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to write basket: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the basket with id.
+func (s *SQLiteBasketStore) Delete(id string) error {
+	if _, err := s.db.Exec(`DELETE FROM baskets WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete basket: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database.
+func (s *SQLiteBasketStore) Close() error {
+	return s.db.Close()
+}
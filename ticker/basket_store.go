@@ -0,0 +1,130 @@
+package ticker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Basket store backends.
+const (
+	StoreFile   = "file"
+	StoreSQLite = "sqlite"
+)
+
+// ErrBasketNotFound is wrapped by lookups of baskets that do not exist.
+var ErrBasketNotFound = errors.New("basket not found")
+
+// ErrVersionConflict is wrapped by saves of a basket that changed since
+// the version the caller read.
+var ErrVersionConflict = errors.New("basket was changed by someone else")
+
+// ErrVersionRequired is wrapped by updates of a basket that carry no
+// version, which could silently overwrite another edit.
+var ErrVersionRequired = errors.New("basket version required")
+
+// BasketStore persists baskets for a BasketManager, which keeps them in
+// memory and checks versions; a store only reads and writes them.
+type BasketStore interface {
+	// Load returns every stored basket.
+	Load() ([]TickerBasket, error)
+	// Put creates or replaces basket.
+	Put(basket TickerBasket) error
+	// Delete removes the basket with id.
+	Delete(id string) error
+}
+
+// OpenBasketStore opens the backend named kind, file or sqlite, in
+// dataDir. A new SQLite store imports the baskets of the file store in
+// the same directory.
+func OpenBasketStore(kind, dataDir string) (BasketStore, error) {
+	switch strings.ToLower(kind) {
+	case "", StoreFile:
+		return NewFileBasketStore(dataDir)
+	case StoreSQLite:
+		return OpenSQLiteBasketStore(dataDir)
+	}
+	return nil, fmt.Errorf("unknown basket store %q: want %s or %s", kind, StoreFile, StoreSQLite)
+}
+
+// FileBasketStore keeps each basket in its own JSON file under
+// <dataDir>/baskets.
+type FileBasketStore struct {
+	dir string
+}
+
+// NewFileBasketStore creates the baskets directory in dataDir if needed.
+func NewFileBasketStore(dataDir string) (*FileBasketStore, error) {
+	dir := filepath.Join(dataDir, "baskets")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create baskets directory: %w", err)
+	}
+	return &FileBasketStore{dir: dir}, nil
+}
+
+// Load reads every basket file, skipping unreadable ones.
+func (s *FileBasketStore) Load() ([]TickerBasket, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baskets directory: %w", err)
+	}
+
+	var baskets []TickerBasket
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		filePath := filepath.Join(s.dir, entry.Name())
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			logger().Warn("Failed to read basket file", "path", filePath, "error", err)
+			continue
+		}
+
+		var basket TickerBasket
+		if err := json.Unmarshal(data, &basket); err != nil {
+			logger().Warn("Failed to parse basket file", "path", filePath, "error", err)
+			continue
+		}
+
+		if basket.ID == "" {
+			logger().Warn("Basket file has no ID", "path", filePath)
+			continue
+		}
+		baskets = append(baskets, basket)
+	}
+	return baskets, nil
+}
+
+// Put writes the basket's file through a temporary file, so a crash
+// never leaves it half written.
+func (s *FileBasketStore) Put(basket TickerBasket) error {
+	data, err := json.MarshalIndent(basket, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal basket: %w", err)
+	}
+	path := s.path(basket.ID)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("failed to write basket file: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to write basket file: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the basket's file.
+func (s *FileBasketStore) Delete(id string) error {
+	if err := os.Remove(s.path(id)); err != nil {
+		return fmt.Errorf("failed to delete basket file: %w", err)
+	}
+	return nil
+}
+
+func (s *FileBasketStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
@@ -0,0 +1,90 @@
+package ticker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestBasketVersionsRefuseStaleUpdates(t *testing.T) {
+	for _, kind := range []string{StoreFile, StoreSQLite} {
+		t.Run(kind, func(t *testing.T) {
+			dir := t.TempDir()
+			store, err := OpenBasketStore(kind, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m := NewBasketManagerWithStore(store)
+			b := &TickerBasket{ID: "tech", Name: "Tech", Symbols: []string{"AAPL"}}
+			if err := m.SaveBasket(b); err != nil || b.Version != 1 {
+				t.Fatalf("create: version %d, %v", b.Version, err)
+			}
+
+			// Two editors read version 1; the second save is refused
+			first, _ := m.GetBasket("tech")
+			second, _ := m.GetBasket("tech")
+			first.Name = "Technology"
+			if err := m.SaveBasket(first); err != nil || first.Version != 2 {
+				t.Fatalf("first edit: version %d, %v", first.Version, err)
+			}
+			second.Symbols = []string{"MSFT"}
+			if err := m.SaveBasket(second); !errors.Is(err, ErrVersionConflict) {
+				t.Fatalf("stale edit: %v", err)
+			}
+			// An update without a version is refused, not last-write-wins
+			if err := m.SaveBasket(&TickerBasket{ID: "tech", Name: "Blind"}); !errors.Is(err, ErrVersionRequired) {
+				t.Fatalf("unversioned edit: %v", err)
+			}
+			if err := m.AddSymbolToBasket("tech", "NVDA"); err != nil {
+				t.Fatal(err)
+			}
+
+			if s, ok := store.(*SQLiteBasketStore); ok {
+				s.Close()
+				if store, err = OpenBasketStore(kind, dir); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := NewBasketManagerWithStore(store).GetBasket("tech")
+			if err != nil || got.Name != "Technology" || got.Version != 3 || len(got.Symbols) != 2 || got.CreatedAt != b.CreatedAt {
+				t.Fatalf("reloaded %+v, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestSQLiteStoreImportsBasketFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "baskets"), 0755)
+	// Written before baskets had versions
+	os.WriteFile(filepath.Join(dir, "baskets", "energy.json"), []byte(`{"id": "energy", "name": "Energy", "symbols": ["XOM"]}`), 0644)
+
+	store, err := OpenSQLiteBasketStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	m := NewBasketManagerWithStore(store)
+	got, err := m.GetBasket("energy")
+	if err != nil || got.Version != 1 || got.Symbols[0] != "XOM" {
+		t.Fatalf("imported %+v, %v", got, err)
+	}
+
+	// Concurrent adds of the same symbol change the basket once
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := m.AddSymbolToBasket("energy", "CVX"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if got, _ := m.GetBasket("energy"); got.Version != 2 || len(got.Symbols) != 2 {
+		t.Errorf("after concurrent adds %+v", got)
+	}
+}
@@ -2,12 +2,24 @@ module github.com/rileyseaburg/go-trader/ticker
 
 go 1.23.4
 
-require github.com/alpacahq/alpaca-trade-api-go/v3 v3.8.1
+require (
+	github.com/alpacahq/alpaca-trade-api-go/v3 v3.8.1
+	modernc.org/sqlite v1.34.5
+)
 
 require (
 	cloud.google.com/go v0.118.2 // indirect
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
 	github.com/josharian/intern v1.0.0 // indirect
 	github.com/mailru/easyjson v0.9.0 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
 	github.com/shopspring/decimal v1.4.0 // indirect
 	github.com/stretchr/testify v1.10.0 // indirect
+	golang.org/x/sys v0.29.0 // indirect
+	modernc.org/libc v1.55.3 // indirect
+	modernc.org/mathutil v1.6.0 // indirect
+	modernc.org/memory v1.8.0 // indirect
 )
@@ -0,0 +1,341 @@
+package ticker
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultMaxSymbols caps the symbols polled at once. Every symbol costs
+// three data API calls per poll, so the cap keeps the poller inside the
+// rate limit.
+const DefaultMaxSymbols = 50
+
+// ErrTooManySymbols is returned when a watch list change cannot fit under
+// the cap even after evicting idle symbols.
+var ErrTooManySymbols = errors.New("subscription limit reached")
+
+// ErrSymbolRefused is returned when the symbol filter refuses a symbol
+// being added to the watch list.
+var ErrSymbolRefused = errors.New("symbol refused")
+
+// Pin sources.
+const (
+	PinPositions = "positions"
+	PinOrders    = "orders"
+)
+
+// Subscription describes one polled symbol.
+type Subscription struct {
+	Symbol   string    `json:"symbol"`
+	Watched  bool      `json:"watched"`
+	PinnedBy []string  `json:"pinned_by,omitempty"` // pin sources; pinned symbols are never evicted
+	LastUsed time.Time `json:"last_used,omitempty"`
+}
+
+// UpdateSymbols replaces the watch list. Pinned symbols, such as open
+// positions, keep being polled whether or not they are in it.
+func (ts *TickerServer) UpdateSymbols(symbols []string) error {
+	symbols = normalizeSymbols(symbols)
+	if err := ts.checkSymbols(symbols); err != nil {
+		return err
+	}
+	ts.symbolsMutex.Lock()
+	defer ts.symbolsMutex.Unlock()
+
+	if _, err := ts.applyLocked(symbols, symbols, true); err != nil {
+		return err
+	}
+	logger().Info("Updated ticker symbols", "symbols", ts.symbols)
+	return nil
+}
+
+// AddSymbols adds symbols to the watch list. When that takes the total
+// over the cap, the least recently used watch-only symbols are evicted to
+// make room; they are returned.
+func (ts *TickerServer) AddSymbols(symbols []string) ([]string, error) {
+	symbols = normalizeSymbols(symbols)
+	if len(symbols) == 0 {
+		return nil, nil
+	}
+	if err := ts.checkSymbols(symbols); err != nil {
+		return nil, err
+	}
+	ts.symbolsMutex.Lock()
+	defer ts.symbolsMutex.Unlock()
+
+	watch := append([]string(nil), ts.symbols...)
+	for _, sym := range symbols {
+		if _, ok := ts.lastUsed[sym]; !ok {
+			watch = append(watch, sym)
+		}
+	}
+	evicted, err := ts.applyLocked(watch, symbols, true)
+	if err != nil {
+		return nil, err
+	}
+	logger().Info("Added ticker symbols", "symbols", symbols)
+	logEvicted(evicted)
+	return evicted, nil
+}
+
+// RemoveSymbols drops symbols from the watch list. A pinned symbol is
+// still polled until its pin is released.
+func (ts *TickerServer) RemoveSymbols(symbols []string) {
+	symbols = normalizeSymbols(symbols)
+	if len(symbols) == 0 {
+		return
+	}
+	remove := make(map[string]bool, len(symbols))
+	for _, sym := range symbols {
+		remove[sym] = true
+	}
+	ts.symbolsMutex.Lock()
+	defer ts.symbolsMutex.Unlock()
+
+	watch := make([]string, 0, len(ts.symbols))
+	for _, sym := range ts.symbols {
+		if !remove[sym] {
+			watch = append(watch, sym)
+		}
+	}
+	ts.applyLocked(watch, nil, false)
+	logger().Info("Removed ticker symbols", "symbols", symbols)
+}
+
+// SetSymbolFilter sets a check every symbol added to the watch list must
+// pass, such as a trade restriction list. UpdateSymbols and AddSymbols
+// change nothing if any symbol is refused. Pinned symbols are not checked,
+// so held positions keep being priced.
+func (ts *TickerServer) SetSymbolFilter(check func(symbol string) error) {
+	ts.symbolsMutex.Lock()
+	defer ts.symbolsMutex.Unlock()
+	ts.filter = check
+}
+
+// CheckSymbol returns the symbol filter's refusal of symbol, if any.
+func (ts *TickerServer) CheckSymbol(symbol string) error {
+	return ts.checkSymbols([]string{strings.ToUpper(strings.TrimSpace(symbol))})
+}
+
+// checkSymbols returns an error wrapping ErrSymbolRefused with every
+// refusal of the filter.
+func (ts *TickerServer) checkSymbols(symbols []string) error {
+	ts.symbolsMutex.RLock()
+	check := ts.filter
+	ts.symbolsMutex.RUnlock()
+	if check == nil {
+		return nil
+	}
+	var refused []error
+	for _, sym := range symbols {
+		if err := check(sym); err != nil {
+			refused = append(refused, err)
+		}
+	}
+	if len(refused) > 0 {
+		return fmt.Errorf("%w: %w", ErrSymbolRefused, errors.Join(refused...))
+	}
+	return nil
+}
+
+// Touch marks symbol as used so it is the last to be evicted. Symbols not
+// on the watch list are ignored.
+func (ts *TickerServer) Touch(symbol string) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	ts.symbolsMutex.Lock()
+	defer ts.symbolsMutex.Unlock()
+	if _, ok := ts.lastUsed[symbol]; ok {
+		ts.lastUsed[symbol] = time.Now()
+	}
+}
+
+// SetPinnedSymbols sets the symbols pinned by source, such as held
+// positions or pending orders. Pinned symbols are always polled, even
+// past the cap, and are not reported by GetSymbols.
+func (ts *TickerServer) SetPinnedSymbols(source string, symbols []string) {
+	ts.symbolsMutex.Lock()
+	defer ts.symbolsMutex.Unlock()
+	if len(symbols) == 0 {
+		delete(ts.pinned, source)
+	} else {
+		ts.pinned[source] = normalizeSymbols(symbols)
+	}
+	// Pins take precedence; evict idle watch-only symbols to stay under
+	// the cap where that is possible
+	evicted, _ := ts.applyLocked(ts.symbols, nil, false)
+	logEvicted(evicted)
+}
+
+// SetMaxSymbols changes the cap on polled symbols, evicting idle
+// watch-only symbols if the new cap is lower. Zero removes the cap.
+func (ts *TickerServer) SetMaxSymbols(n int) {
+	if n < 0 {
+		n = 0
+	}
+	ts.symbolsMutex.Lock()
+	defer ts.symbolsMutex.Unlock()
+	ts.maxSymbols = n
+	evicted, _ := ts.applyLocked(ts.symbols, nil, false)
+	logEvicted(evicted)
+}
+
+// MaxSymbols returns the cap on polled symbols; zero means none.
+func (ts *TickerServer) MaxSymbols() int {
+	ts.symbolsMutex.RLock()
+	defer ts.symbolsMutex.RUnlock()
+	return ts.maxSymbols
+}
+
+// applyLocked makes watch the watch list, marking touched symbols used now
+// and evicting the least recently used watch-only symbols while the total
+// is over the cap. Touched and pinned symbols are never evicted. When
+// strict is set and the result would still be over the cap, nothing
+// changes and ErrTooManySymbols is returned. Callers must hold
+// ts.symbolsMutex.
+func (ts *TickerServer) applyLocked(watch, touched []string, strict bool) ([]string, error) {
+	now := time.Now()
+	lastUsed := make(map[string]time.Time, len(watch))
+	for _, sym := range watch {
+		lastUsed[sym] = ts.lastUsed[sym]
+	}
+	keep := make(map[string]bool, len(touched))
+	for _, sym := range touched {
+		lastUsed[sym] = now
+		keep[sym] = true
+	}
+	pinned := ts.pinnedSetLocked()
+	for sym := range pinned {
+		keep[sym] = true
+	}
+
+	total := len(pinned)
+	var candidates []string
+	for _, sym := range watch {
+		if !pinned[sym] {
+			total++
+			if !keep[sym] {
+				candidates = append(candidates, sym)
+			}
+		}
+	}
+
+	var evicted []string
+	if ts.maxSymbols > 0 && total > ts.maxSymbols {
+		// Least recently used first; ties go to the earlier-added symbol
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return lastUsed[candidates[i]].Before(lastUsed[candidates[j]])
+		})
+		over := total - ts.maxSymbols
+		if over > len(candidates) {
+			if strict {
+				return nil, fmt.Errorf("%w: %d symbols requested, cap is %d", ErrTooManySymbols, total, ts.maxSymbols)
+			}
+			over = len(candidates)
+		}
+		evicted = candidates[:over]
+		for _, sym := range evicted {
+			delete(lastUsed, sym)
+		}
+	}
+
+	kept := make([]string, 0, len(watch)-len(evicted))
+	for _, sym := range watch {
+		if _, ok := lastUsed[sym]; ok {
+			kept = append(kept, sym)
+		}
+	}
+	ts.symbols = kept
+	ts.lastUsed = lastUsed
+	return evicted, nil
+}
+
+// pinnedSetLocked returns every pinned symbol. Callers must hold
+// ts.symbolsMutex.
+func (ts *TickerServer) pinnedSetLocked() map[string]bool {
+	set := make(map[string]bool)
+	for _, symbols := range ts.pinned {
+		for _, sym := range symbols {
+			set[sym] = true
+		}
+	}
+	return set
+}
+
+func logEvicted(evicted []string) {
+	if len(evicted) > 0 {
+		logger().Info("Evicted idle ticker symbols to stay under the subscription cap", "symbols", evicted)
+	}
+}
+
+// pollSymbols returns the watch list followed by any pinned symbols not
+// already in it, sorted.
+func (ts *TickerServer) pollSymbols() []string {
+	ts.symbolsMutex.RLock()
+	defer ts.symbolsMutex.RUnlock()
+
+	symbols := append([]string(nil), ts.symbols...)
+	seen := make(map[string]bool, len(symbols))
+	for _, sym := range symbols {
+		seen[sym] = true
+	}
+	var extra []string
+	for sym := range ts.pinnedSetLocked() {
+		if !seen[sym] {
+			extra = append(extra, sym)
+		}
+	}
+	sort.Strings(extra)
+	return append(symbols, extra...)
+}
+
+// GetSymbols returns the current list of symbols
+func (ts *TickerServer) GetSymbols() []string {
+	ts.symbolsMutex.RLock()
+	defer ts.symbolsMutex.RUnlock()
+
+	symbols := make([]string, len(ts.symbols))
+	copy(symbols, ts.symbols)
+	return symbols
+}
+
+// Subscriptions lists every polled symbol with why it is polled, watch
+// list first.
+func (ts *TickerServer) Subscriptions() []Subscription {
+	ts.symbolsMutex.RLock()
+	defer ts.symbolsMutex.RUnlock()
+
+	bySymbol := make(map[string]*Subscription)
+	var out []*Subscription
+	for _, sym := range ts.symbols {
+		s := &Subscription{Symbol: sym, Watched: true, LastUsed: ts.lastUsed[sym]}
+		bySymbol[sym] = s
+		out = append(out, s)
+	}
+	sources := make([]string, 0, len(ts.pinned))
+	for source := range ts.pinned {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	var pinnedOnly []*Subscription
+	for _, source := range sources {
+		for _, sym := range ts.pinned[source] {
+			s, ok := bySymbol[sym]
+			if !ok {
+				s = &Subscription{Symbol: sym}
+				bySymbol[sym] = s
+				pinnedOnly = append(pinnedOnly, s)
+			}
+			s.PinnedBy = append(s.PinnedBy, source)
+		}
+	}
+	sort.Slice(pinnedOnly, func(i, j int) bool { return pinnedOnly[i].Symbol < pinnedOnly[j].Symbol })
+
+	result := make([]Subscription, 0, len(out)+len(pinnedOnly))
+	for _, s := range append(out, pinnedOnly...) {
+		result = append(result, *s)
+	}
+	return result
+}
@@ -0,0 +1,98 @@
+package ticker
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func newTestTickerServer(t *testing.T, max int) *TickerServer {
+	t.Helper()
+	ts := NewTickerServer(context.Background(), true, "MOCK_ALPACA_API_KEY", "")
+	ts.SetMaxSymbols(max)
+	return ts
+}
+
+// age sets each symbol's last use, oldest first, a minute apart.
+func age(ts *TickerServer, symbols ...string) {
+	start := time.Now().Add(-time.Hour)
+	for i, sym := range symbols {
+		ts.lastUsed[sym] = start.Add(time.Duration(i) * time.Minute)
+	}
+}
+
+func TestAddSymbolsEvictsLeastRecentlyUsed(t *testing.T) {
+	ts := newTestTickerServer(t, 3)
+	if err := ts.UpdateSymbols([]string{"AAPL", "MSFT", "NVDA"}); err != nil {
+		t.Fatal(err)
+	}
+	age(ts, "MSFT", "AAPL", "NVDA")
+
+	evicted, err := ts.AddSymbols([]string{"tsla", "AMD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(evicted, []string{"MSFT", "AAPL"}) {
+		t.Errorf("evicted %v, want the two least recently used", evicted)
+	}
+	if got := ts.GetSymbols(); !reflect.DeepEqual(got, []string{"NVDA", "TSLA", "AMD"}) {
+		t.Errorf("watching %v", got)
+	}
+
+	// Adding more than the cap at once changes nothing
+	if _, err := ts.AddSymbols([]string{"A", "B", "C", "D"}); !errors.Is(err, ErrTooManySymbols) {
+		t.Errorf("over the cap: %v", err)
+	}
+	if got := ts.GetSymbols(); len(got) != 3 {
+		t.Errorf("refused add changed the watch list to %v", got)
+	}
+}
+
+func TestPinnedSymbolsAreNeverEvicted(t *testing.T) {
+	ts := newTestTickerServer(t, 3)
+	ts.UpdateSymbols([]string{"AAPL", "MSFT"})
+	age(ts, "AAPL", "MSFT")
+	ts.SetPinnedSymbols(PinPositions, []string{"AAPL", "XOM"})
+
+	// AAPL is the oldest but held, so MSFT makes room
+	evicted, err := ts.AddSymbols([]string{"NVDA"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(evicted, []string{"MSFT"}) {
+		t.Errorf("evicted %v", evicted)
+	}
+	if got := ts.pollSymbols(); !reflect.DeepEqual(got, []string{"AAPL", "NVDA", "XOM"}) {
+		t.Errorf("polling %v", got)
+	}
+
+	// Pins past the cap are kept; only watch-only symbols go
+	ts.SetPinnedSymbols(PinOrders, []string{"GE", "F"})
+	if got := ts.pollSymbols(); !reflect.DeepEqual(got, []string{"AAPL", "F", "GE", "XOM"}) {
+		t.Errorf("polling %v after pinning past the cap", got)
+	}
+	if _, err := ts.AddSymbols([]string{"AMD"}); !errors.Is(err, ErrTooManySymbols) {
+		t.Errorf("add with only pinned symbols left: %v", err)
+	}
+}
+
+func TestTouchRefreshesRecency(t *testing.T) {
+	ts := newTestTickerServer(t, 3)
+	ts.UpdateSymbols([]string{"AAPL", "MSFT", "NVDA"})
+	age(ts, "AAPL", "MSFT", "NVDA")
+	ts.Touch("aapl")
+	ts.Touch("TSLA") // not watched, ignored
+
+	evicted, err := ts.AddSymbols([]string{"AMD"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(evicted, []string{"MSFT"}) {
+		t.Errorf("evicted %v, want MSFT now that AAPL was used", evicted)
+	}
+	if _, ok := ts.lastUsed["TSLA"]; ok {
+		t.Error("touching an unwatched symbol subscribed it")
+	}
+}
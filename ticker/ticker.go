@@ -3,7 +3,7 @@ package ticker
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"math"
 	"os"
 	"sync"
@@ -12,6 +12,12 @@ import (
 	"github.com/alpacahq/alpaca-trade-api-go/v3/marketdata"
 )
 
+// logger tags the package's records with its module, for per-module levels.
+func logger() *slog.Logger { return slog.With("module", "ticker") }
+
+// pollInterval is how often the poller fetches the latest data.
+const pollInterval = 5 * time.Second
+
 // TickerData represents the current market data for a ticker
 type TickerData struct {
 	Symbol      string            `json:"symbol"`
@@ -21,13 +27,29 @@ type TickerData struct {
 	LastUpdated time.Time         `json:"last_updated"`
 }
 
+// Health describes the poller's connection to the market data API.
+type Health struct {
+	Mock                bool      `json:"mock"`
+	Polling             bool      `json:"polling"`
+	LastPoll            time.Time `json:"last_poll,omitempty"`  // last poll that returned data
+	PollInterval        string    `json:"poll_interval"`        // time between polls
+	Errors              int       `json:"errors"`               // failed symbol requests since start
+	ConsecutiveFailures int       `json:"consecutive_failures"` // polls in a row where every symbol failed
+	LastError           string    `json:"last_error,omitempty"`
+	LastErrorAt         time.Time `json:"last_error_at,omitempty"`
+}
+
 // TickerDataHandler is a function that handles ticker data
 type TickerDataHandler func(symbol string, data TickerData)
 
 // TickerServer manages connections to Alpaca market data streaming API
 type TickerServer struct {
 	mdClient     *marketdata.Client
-	symbols      []string
+	symbols      []string             // watch list, in the order added
+	lastUsed     map[string]time.Time // when each watch-list symbol was last added or used
+	pinned       map[string][]string  // by source; polled regardless of the watch list and cap
+	maxSymbols   int                  // cap on polled symbols, zero for none
+	filter       func(string) error   // refuses symbols added to the watch list
 	symbolsMutex sync.RWMutex
 	dataHandler  TickerDataHandler
 	ctx          context.Context
@@ -36,6 +58,7 @@ type TickerServer struct {
 
 	// Keep track of last data received
 	lastData  map[string]TickerData
+	health    Health
 	dataMutex sync.RWMutex
 }
 
@@ -53,24 +76,38 @@ func NewTickerServer(ctx context.Context, isPaperTrading bool, apiKey, apiSecret
 	})
 
 	return &TickerServer{
-		mdClient: mdClient,
-		symbols:  []string{},
-		ctx:      childCtx,
-		cancel:   cancel,
-		mockMode: mockMode,
-		lastData: make(map[string]TickerData),
+		mdClient:   mdClient,
+		symbols:    []string{},
+		lastUsed:   make(map[string]time.Time),
+		pinned:     make(map[string][]string),
+		maxSymbols: DefaultMaxSymbols,
+		ctx:        childCtx,
+		cancel:     cancel,
+		mockMode:   mockMode,
+		lastData:   make(map[string]TickerData),
+		health:     Health{Mock: mockMode, PollInterval: pollInterval.String()},
 	}
 }
 
+// SetMarketDataClient replaces the client quotes, trades and bars are
+// polled with, so polling shares the server's request queue. Call it
+// before Start.
+func (ts *TickerServer) SetMarketDataClient(c *marketdata.Client) {
+	ts.mdClient = c
+}
+
 // Start initializes the ticker server
 func (ts *TickerServer) Start() error {
 	if ts.mockMode {
-		log.Println("Ticker server started in mock mode")
+		logger().Info("Ticker server started in mock mode")
 	} else {
-		log.Println("Ticker server started successfully")
+		logger().Info("Ticker server started")
 	}
 
 	// Start polling for data
+	ts.dataMutex.Lock()
+	ts.health.Polling = true
+	ts.dataMutex.Unlock()
 	go ts.pollForData()
 
 	return nil
@@ -79,12 +116,12 @@ func (ts *TickerServer) Start() error {
 // Stop shuts down the ticker server
 func (ts *TickerServer) Stop() {
 	ts.cancel()
-	log.Println("Ticker server stopped")
+	logger().Info("Ticker server stopped")
 }
 
 // pollForData polls for market data for the subscribed symbols
 func (ts *TickerServer) pollForData() {
-	ticker := time.NewTicker(5 * time.Second)
+	ticker := time.NewTicker(pollInterval)
 	defer ticker.Stop()
 
 	// Seed initial data quickly so the UI has something to display in mock mode.
@@ -104,11 +141,7 @@ func (ts *TickerServer) pollForData() {
 
 // updateMarketData fetches latest market data for all symbols
 func (ts *TickerServer) updateMarketData() {
-	ts.symbolsMutex.RLock()
-	symbols := make([]string, len(ts.symbols))
-	copy(symbols, ts.symbols)
-	ts.symbolsMutex.RUnlock()
-
+	symbols := ts.pollSymbols()
 	if len(symbols) == 0 {
 		return
 	}
@@ -119,20 +152,24 @@ func (ts *TickerServer) updateMarketData() {
 	}
 
 	// Get latest quotes for all symbols
+	ok := 0
 	for _, symbol := range symbols {
 		// Get quote
 		quote, err := ts.mdClient.GetLatestQuote(symbol, marketdata.GetLatestQuoteRequest{})
 		if err != nil {
-			log.Printf("Error getting quote for %s: %v", symbol, err)
+			logger().Warn("Failed to get quote", "symbol", symbol, "error", err)
+			ts.recordPollError(fmt.Errorf("quote for %s: %w", symbol, err))
 			continue
 		}
 
 		// Get trade
 		trade, err := ts.mdClient.GetLatestTrade(symbol, marketdata.GetLatestTradeRequest{})
 		if err != nil {
-			log.Printf("Error getting trade for %s: %v", symbol, err)
+			logger().Warn("Failed to get trade", "symbol", symbol, "error", err)
+			ts.recordPollError(fmt.Errorf("trade for %s: %w", symbol, err))
 			continue
 		}
+		ok++
 
 		// Create ticker data
 		data := TickerData{
@@ -157,6 +194,34 @@ func (ts *TickerServer) updateMarketData() {
 
 		ts.storeAndPublish(symbol, data)
 	}
+	ts.recordPoll(ok > 0)
+}
+
+// recordPoll notes the outcome of a poll for Health.
+func (ts *TickerServer) recordPoll(anyData bool) {
+	ts.dataMutex.Lock()
+	defer ts.dataMutex.Unlock()
+	if anyData {
+		ts.health.LastPoll = time.Now()
+		ts.health.ConsecutiveFailures = 0
+	} else {
+		ts.health.ConsecutiveFailures++
+	}
+}
+
+func (ts *TickerServer) recordPollError(err error) {
+	ts.dataMutex.Lock()
+	defer ts.dataMutex.Unlock()
+	ts.health.Errors++
+	ts.health.LastError = err.Error()
+	ts.health.LastErrorAt = time.Now()
+}
+
+// Health reports the poller's connection to the market data API.
+func (ts *TickerServer) Health() Health {
+	ts.dataMutex.RLock()
+	defer ts.dataMutex.RUnlock()
+	return ts.health
 }
 
 func (ts *TickerServer) updateMockMarketData(symbols []string) {
@@ -185,6 +250,7 @@ func (ts *TickerServer) updateMockMarketData(symbols []string) {
 		}
 		ts.storeAndPublish(symbol, data)
 	}
+	ts.recordPoll(true)
 }
 
 func (ts *TickerServer) storeAndPublish(symbol string, data TickerData) {
@@ -199,27 +265,18 @@ func (ts *TickerServer) storeAndPublish(symbol string, data TickerData) {
 	}
 }
 
-// UpdateSymbols updates the symbols to subscribe to
-func (ts *TickerServer) UpdateSymbols(symbols []string) error {
-	ts.symbolsMutex.Lock()
-	defer ts.symbolsMutex.Unlock()
-
-	// Update symbols list
-	ts.symbols = make([]string, len(symbols))
-	copy(ts.symbols, symbols)
-
-	log.Printf("Updated ticker symbols: %v", ts.symbols)
-	return nil
-}
-
-// GetSymbols returns the current list of symbols
-func (ts *TickerServer) GetSymbols() []string {
-	ts.symbolsMutex.RLock()
-	defer ts.symbolsMutex.RUnlock()
+// Inject stores and publishes data as if it had been polled. Replays feed
+// recorded ticks through it instead of starting the poller; the handler
+// runs before Inject returns so events are handled in order.
+func (ts *TickerServer) Inject(data TickerData) {
+	ts.dataMutex.Lock()
+	ts.lastData[data.Symbol] = data
+	ts.health.LastPoll = time.Now()
+	ts.dataMutex.Unlock()
 
-	symbols := make([]string, len(ts.symbols))
-	copy(symbols, ts.symbols)
-	return symbols
+	if ts.dataHandler != nil {
+		ts.dataHandler(data.Symbol, data)
+	}
 }
 
 // SetDataHandler sets the handler for ticker data
@@ -227,8 +284,11 @@ func (ts *TickerServer) SetDataHandler(handler TickerDataHandler) {
 	ts.dataHandler = handler
 }
 
-// GetLastData returns the last data for a symbol
+// GetLastData returns the last data for a symbol and counts as a use of
+// it for subscription eviction
 func (ts *TickerServer) GetLastData(symbol string) (TickerData, error) {
+	ts.Touch(symbol)
+
 	ts.dataMutex.RLock()
 	defer ts.dataMutex.RUnlock()
 
@@ -0,0 +1,175 @@
+package ticks
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Bounds on raw ticks returned by one request.
+const (
+	DefaultLimit = 1000
+	MaxLimit     = 10000
+)
+
+// Handler exposes recorded ticks over HTTP.
+type Handler struct {
+	store *Store
+}
+
+// NewHandler creates a handler for store.
+func NewHandler(store *Store) *Handler {
+	return &Handler{store: store}
+}
+
+// RegisterRoutes registers the tick routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/ticks - recording policy and per-symbol history on disk
+	mux.HandleFunc("/api/ticks", h.handleStats)
+
+	// GET/POST /api/ticks/policy - read or update the recording policy
+	mux.HandleFunc("/api/ticks/policy", h.handlePolicy)
+
+	// GET /api/ticks/{symbol}?from=&to=&kind=&limit= - raw ticks in order
+	// GET /api/ticks/{symbol}/bars?from=&to=&interval=1m - bars built from trades
+	mux.HandleFunc("/api/ticks/", h.handleSymbol)
+}
+
+func (h *Handler) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"policy":   h.store.Policy(),
+		"recorded": h.store.Recorded(),
+		"symbols":  h.store.Stats(),
+	})
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.store.Policy())
+	case http.MethodPost:
+		// Decode onto the current policy so partial updates work
+		p := h.store.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.store.SetPolicy(p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(h.store.Policy())
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleSymbol(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	symbol, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/ticks/"), "/")
+	if symbol == "" || (action != "" && action != "bars") {
+		http.NotFound(w, r)
+		return
+	}
+	symbol = strings.ToUpper(symbol)
+
+	params := r.URL.Query()
+	from, err := parseTime(params.Get("from"), false)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := parseTime(params.Get("to"), true)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if action == "bars" {
+		interval := time.Minute
+		if v := params.Get("interval"); v != "" {
+			if interval, err = time.ParseDuration(v); err != nil || interval <= 0 {
+				http.Error(w, "interval must be a positive duration such as 1m or 5m", http.StatusBadRequest)
+				return
+			}
+		}
+		bars, err := h.store.Bars(symbol, from, to, interval)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"symbol":   symbol,
+			"interval": interval.String(),
+			"bars":     bars,
+		})
+		return
+	}
+
+	kind := params.Get("kind")
+	if kind != "" && kind != KindTrade && kind != KindQuote {
+		http.Error(w, "kind must be trade or quote", http.StatusBadRequest)
+		return
+	}
+	limit := DefaultLimit
+	if v := params.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		if limit > MaxLimit {
+			limit = MaxLimit
+		}
+	}
+	ticks := []Tick{}
+	truncated := false
+	err = h.store.Replay(symbol, from, to, func(t Tick) error {
+		if kind != "" && t.Kind != kind {
+			return nil
+		}
+		if len(ticks) == limit {
+			truncated = true
+			return ErrStop
+		}
+		ticks = append(ticks, t)
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"symbol":    symbol,
+		"ticks":     ticks,
+		"truncated": truncated,
+	})
+}
+
+// parseTime accepts RFC3339 or YYYY-MM-DD. A bare date used as an upper
+// bound covers the whole day.
+func parseTime(v string, endOfDay bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: use RFC3339 or YYYY-MM-DD", v)
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
@@ -0,0 +1,480 @@
+package ticks
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+func logger() *slog.Logger { return slog.With("module", "ticks") }
+
+const (
+	dateLayout = "2006-01-02"
+	fileSuffix = ".jsonl.gz"
+)
+
+// ErrStop can be returned from a Replay callback to end the replay early
+// without an error.
+var ErrStop = errors.New("stop replay")
+
+// dayFile is the open file a symbol's ticks for one day are appended to.
+type dayFile struct {
+	date string
+	file *os.File
+	gz   *gzip.Writer
+}
+
+// SymbolStats describes the recorded history of one symbol.
+type SymbolStats struct {
+	Symbol string `json:"symbol"`
+	Days   int    `json:"days"`
+	Bytes  int64  `json:"bytes"`
+	First  string `json:"first"` // earliest day on disk
+	Last   string `json:"last"`
+}
+
+// Store records ticks under dir/<SYMBOL>/<YYYY-MM-DD>.jsonl.gz and reads
+// them back. Days are exchange days in loc.
+type Store struct {
+	dir string
+	loc *time.Location
+
+	mu     sync.Mutex
+	policy Policy
+	open   map[string]*dayFile // by symbol
+	last   map[string]Tick     // last tick recorded per symbol and kind
+	count  int64               // ticks recorded since start
+}
+
+// Open returns a store rooted at dir, creating it if needed.
+func Open(dir string, loc *time.Location, policy Policy) (*Store, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create tick directory: %w", err)
+	}
+	return &Store{
+		dir:    dir,
+		loc:    loc,
+		policy: policy,
+		open:   make(map[string]*dayFile),
+		last:   make(map[string]Tick),
+	}, nil
+}
+
+// Policy returns the recording policy.
+func (s *Store) Policy() Policy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.policy
+}
+
+// SetPolicy replaces the recording policy. Switching recording off closes
+// the open files.
+func (s *Store) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	symbols := make([]string, 0, len(p.Symbols))
+	for _, sym := range p.Symbols {
+		if sym = strings.ToUpper(strings.TrimSpace(sym)); sym != "" {
+			symbols = append(symbols, sym)
+		}
+	}
+	p.Symbols = symbols
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.policy = p
+	if !p.Enabled {
+		s.closeAllLocked()
+	}
+	return nil
+}
+
+// Record appends ticks when recording is on. A tick identical to the last
+// one recorded for its symbol and kind — the same trade or quote seen on
+// another poll — is skipped.
+func (s *Store) Record(ticks ...Tick) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.policy.Enabled {
+		return nil
+	}
+	for _, t := range ticks {
+		if t.Time.IsZero() || t.Symbol == "" {
+			continue
+		}
+		t.Symbol = strings.ToUpper(t.Symbol)
+		if !s.recordsLocked(t.Symbol) {
+			continue
+		}
+		key := t.Symbol + "|" + t.Kind
+		if s.last[key] == t {
+			continue
+		}
+		df, err := s.fileLocked(t.Symbol, t.Time.In(s.loc).Format(dateLayout))
+		if err != nil {
+			return err
+		}
+		line, err := json.Marshal(t)
+		if err != nil {
+			return err
+		}
+		if _, err := df.gz.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("failed to write tick for %s: %w", t.Symbol, err)
+		}
+		s.last[key] = t
+		s.count++
+	}
+	return nil
+}
+
+func (s *Store) recordsLocked(symbol string) bool {
+	if len(s.policy.Symbols) == 0 {
+		return true
+	}
+	for _, sym := range s.policy.Symbols {
+		if sym == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+// fileLocked returns the open file for symbol's ticks on date, rolling
+// over from the previous day's file. Callers must hold s.mu.
+func (s *Store) fileLocked(symbol, date string) (*dayFile, error) {
+	if df, ok := s.open[symbol]; ok {
+		if df.date == date {
+			return df, nil
+		}
+		if err := df.close(); err != nil {
+			logger().Error("Failed to close tick file", "symbol", symbol, "date", df.date, "error", err)
+		}
+		delete(s.open, symbol)
+	}
+
+	path := s.path(symbol, date)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create tick directory: %w", err)
+	}
+	if err := repair(path); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tick file: %w", err)
+	}
+	// Each session appends a new gzip member; readers see one stream
+	df := &dayFile{date: date, file: f, gz: gzip.NewWriter(f)}
+	s.open[symbol] = df
+	return df, nil
+}
+
+func (df *dayFile) close() error {
+	err := df.gz.Close()
+	if cerr := df.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Flush pushes buffered ticks in every open file to disk so they can be
+// read back.
+func (s *Store) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushLocked("")
+}
+
+// flushLocked flushes symbol's open file, or all of them for "".
+func (s *Store) flushLocked(symbol string) {
+	for sym, df := range s.open {
+		if symbol != "" && sym != symbol {
+			continue
+		}
+		if err := df.gz.Flush(); err != nil {
+			logger().Error("Failed to flush tick file", "symbol", sym, "error", err)
+		}
+	}
+}
+
+func (s *Store) closeAllLocked() {
+	for sym, df := range s.open {
+		if err := df.close(); err != nil {
+			logger().Error("Failed to close tick file", "symbol", sym, "date", df.date, "error", err)
+		}
+	}
+	s.open = make(map[string]*dayFile)
+}
+
+// Close finishes every open file.
+func (s *Store) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeAllLocked()
+}
+
+// Run flushes open files every interval and applies retention once an
+// hour until ctx is done, then closes the store.
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	flush := time.NewTicker(interval)
+	defer flush.Stop()
+	prune := time.NewTicker(time.Hour)
+	defer prune.Stop()
+
+	s.Prune(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			s.Close()
+			return
+		case <-flush.C:
+			s.Flush()
+		case now := <-prune.C:
+			s.Prune(now)
+		}
+	}
+}
+
+// Prune deletes day files older than the retention policy and returns how
+// many were removed. Files still open for writing are kept.
+func (s *Store) Prune(now time.Time) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cutoff := now.In(s.loc).AddDate(0, 0, -s.policy.RetentionDays).Format(dateLayout)
+	openDates := make(map[string]string, len(s.open))
+	for sym, df := range s.open {
+		openDates[sym] = df.date
+	}
+
+	removed := 0
+	for _, sym := range s.Symbols() {
+		for _, date := range s.days(sym) {
+			if date >= cutoff || openDates[sym] == date {
+				continue
+			}
+			if err := os.Remove(s.path(sym, date)); err != nil {
+				logger().Error("Failed to remove tick file", "symbol", sym, "date", date, "error", err)
+				continue
+			}
+			removed++
+		}
+		os.Remove(filepath.Join(s.dir, sym)) // only succeeds once empty
+	}
+	if removed > 0 {
+		logger().Info("Pruned tick files", "removed", removed, "older_than", cutoff)
+	}
+	return removed
+}
+
+// Symbols lists the symbols with recorded ticks.
+func (s *Store) Symbols() []string {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil
+	}
+	var symbols []string
+	for _, e := range entries {
+		if e.IsDir() {
+			symbols = append(symbols, e.Name())
+		}
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// days lists the dates recorded for symbol, oldest first.
+func (s *Store) days(symbol string) []string {
+	entries, err := os.ReadDir(filepath.Join(s.dir, symbol))
+	if err != nil {
+		return nil
+	}
+	var dates []string
+	for _, e := range entries {
+		if date, ok := strings.CutSuffix(e.Name(), fileSuffix); ok && !e.IsDir() {
+			dates = append(dates, date)
+		}
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+// Stats summarizes the recorded history of every symbol.
+func (s *Store) Stats() []SymbolStats {
+	var stats []SymbolStats
+	for _, sym := range s.Symbols() {
+		dates := s.days(sym)
+		if len(dates) == 0 {
+			continue
+		}
+		st := SymbolStats{Symbol: sym, Days: len(dates), First: dates[0], Last: dates[len(dates)-1]}
+		for _, date := range dates {
+			if info, err := os.Stat(s.path(sym, date)); err == nil {
+				st.Bytes += info.Size()
+			}
+		}
+		stats = append(stats, st)
+	}
+	return stats
+}
+
+// Recorded returns how many ticks have been recorded since the store was
+// opened.
+func (s *Store) Recorded() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// Replay calls fn with symbol's recorded ticks from from to to inclusive,
+// in the order they were recorded. Zero bounds are open. Returning ErrStop
+// from fn ends the replay early; any other error is returned.
+func (s *Store) Replay(symbol string, from, to time.Time, fn func(Tick) error) error {
+	symbol = strings.ToUpper(symbol)
+	s.mu.Lock()
+	s.flushLocked(symbol)
+	s.mu.Unlock()
+
+	var fromDate, toDate string
+	if !from.IsZero() {
+		fromDate = from.In(s.loc).Format(dateLayout)
+	}
+	if !to.IsZero() {
+		toDate = to.In(s.loc).Format(dateLayout)
+	}
+	for _, date := range s.days(symbol) {
+		if (fromDate != "" && date < fromDate) || (toDate != "" && date > toDate) {
+			continue
+		}
+		err := readFile(s.path(symbol, date), func(t Tick) error {
+			if (!from.IsZero() && t.Time.Before(from)) || (!to.IsZero() && t.Time.After(to)) {
+				return nil
+			}
+			return fn(t)
+		})
+		if errors.Is(err, ErrStop) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Bars replays symbol's trades from from to to into bars of interval.
+func (s *Store) Bars(symbol string, from, to time.Time, interval time.Duration) ([]Bar, error) {
+	b := NewBarBuilder(interval)
+	err := s.Replay(symbol, from, to, func(t Tick) error {
+		b.Add(t)
+		return nil
+	})
+	return b.Bars(), err
+}
+
+func (s *Store) path(symbol, date string) string {
+	return filepath.Join(s.dir, symbol, date+fileSuffix)
+}
+
+// readFile decodes every tick in a day file. A file cut short by a crash
+// yields the ticks before the cut.
+func readFile(path string, fn func(Tick) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open tick file: %w", err)
+	}
+	defer f.Close()
+	_, err = decode(f, fn)
+	return err
+}
+
+// decode streams ticks from gzip data to fn. truncated reports whether the
+// data ended mid-stream.
+func decode(r io.Reader, fn func(Tick) error) (truncated bool, err error) {
+	gz, err := gzip.NewReader(r)
+	if err == io.EOF {
+		return false, nil // empty file
+	}
+	if err != nil {
+		return true, nil
+	}
+	defer gz.Close()
+
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var t Tick
+		if err := json.Unmarshal(scanner.Bytes(), &t); err != nil {
+			return true, nil // a partial last line
+		}
+		if err := fn(t); err != nil {
+			return false, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return true, nil
+	}
+	return false, nil
+}
+
+// repair rewrites a day file cut short by a crash so that new ticks can be
+// appended after it and still be read back.
+func repair(path string) error {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open tick file: %w", err)
+	}
+	var ticks []Tick
+	truncated, _ := decode(f, func(t Tick) error {
+		ticks = append(ticks, t)
+		return nil
+	})
+	f.Close()
+	if !truncated {
+		return nil
+	}
+
+	tmp := path + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to repair tick file: %w", err)
+	}
+	gz := gzip.NewWriter(out)
+	enc := json.NewEncoder(gz)
+	for _, t := range ticks {
+		if err = enc.Encode(t); err != nil {
+			break
+		}
+	}
+	if cerr := gz.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to repair tick file: %w", err)
+	}
+	logger().Warn("Repaired truncated tick file", "path", path, "kept", len(ticks))
+	return nil
+}
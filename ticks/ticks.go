@@ -0,0 +1,111 @@
+// Package ticks records the raw ticker stream — every distinct trade and
+// quote — to gzip-compressed, append-only JSONL files, one per symbol per
+// exchange day, prunes them by a retention policy, and replays them in
+// time order for backtests and bar building.
+package ticks
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// Tick kinds.
+const (
+	KindTrade = "trade"
+	KindQuote = "quote"
+)
+
+// Tick is one recorded trade or quote.
+type Tick struct {
+	Time     time.Time `json:"t"`
+	Symbol   string    `json:"sym"`
+	Kind     string    `json:"k"`
+	Price    float64   `json:"p,omitempty"` // trade price
+	Size     float64   `json:"s,omitempty"` // trade size
+	Exchange string    `json:"x,omitempty"`
+	ID       int64     `json:"id,omitempty"` // trade ID
+	Bid      float64   `json:"bp,omitempty"`
+	BidSize  float64   `json:"bs,omitempty"`
+	Ask      float64   `json:"ap,omitempty"`
+	AskSize  float64   `json:"as,omitempty"`
+}
+
+// Policy decides what is recorded and for how long.
+type Policy struct {
+	Enabled       bool     `json:"enabled"`
+	RetentionDays int      `json:"retention_days"`    // day files older than this are deleted
+	Symbols       []string `json:"symbols,omitempty"` // empty records every polled symbol
+}
+
+// DefaultPolicy keeps 30 days of ticks for every symbol once recording is
+// switched on. Recording is off by default.
+func DefaultPolicy() Policy {
+	return Policy{RetentionDays: 30}
+}
+
+// Validate checks the policy for usable values.
+func (p Policy) Validate() error {
+	if p.RetentionDays < 1 {
+		return errors.New("retention_days must be at least 1")
+	}
+	return nil
+}
+
+// Bar is an OHLCV bar built from trade ticks.
+type Bar struct {
+	Time   time.Time `json:"t"` // start of the interval
+	Open   float64   `json:"o"`
+	High   float64   `json:"h"`
+	Low    float64   `json:"l"`
+	Close  float64   `json:"c"`
+	Volume float64   `json:"v"`
+	Trades int       `json:"n"`
+	VWAP   float64   `json:"vw"`
+}
+
+// BarBuilder aggregates trade ticks, fed in time order, into bars of a
+// fixed interval. Quotes are ignored and intervals without trades are
+// skipped.
+type BarBuilder struct {
+	interval time.Duration
+	bars     []Bar
+	notional float64 // price × size in the current bar, for its VWAP
+}
+
+// NewBarBuilder returns a builder for bars of interval.
+func NewBarBuilder(interval time.Duration) *BarBuilder {
+	return &BarBuilder{interval: interval}
+}
+
+// Add folds a tick into the current bar, starting a new one when the tick
+// falls in a later interval.
+func (b *BarBuilder) Add(t Tick) {
+	if t.Kind != KindTrade || t.Price <= 0 {
+		return
+	}
+	start := t.Time.Truncate(b.interval)
+	n := len(b.bars)
+	if n == 0 || start.After(b.bars[n-1].Time) {
+		b.bars = append(b.bars, Bar{Time: start, Open: t.Price, High: t.Price, Low: t.Price})
+		b.notional = 0
+		n++
+	}
+	bar := &b.bars[n-1]
+	bar.High = math.Max(bar.High, t.Price)
+	bar.Low = math.Min(bar.Low, t.Price)
+	bar.Close = t.Price
+	bar.Volume += t.Size
+	bar.Trades++
+	b.notional += t.Price * t.Size
+	if bar.Volume > 0 {
+		bar.VWAP = b.notional / bar.Volume
+	} else {
+		bar.VWAP = t.Price
+	}
+}
+
+// Bars returns the bars built so far.
+func (b *BarBuilder) Bars() []Bar {
+	return b.bars
+}
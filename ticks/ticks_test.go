@@ -0,0 +1,180 @@
+package ticks
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	p := DefaultPolicy()
+	p.Enabled = true
+	s, err := Open(t.TempDir(), time.UTC, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func trade(at time.Time, price, size float64, id int64) Tick {
+	return Tick{Time: at, Symbol: "aapl", Kind: KindTrade, Price: price, Size: size, ID: id}
+}
+
+func replayAll(t *testing.T, s *Store, symbol string, from, to time.Time) []Tick {
+	t.Helper()
+	var got []Tick
+	if err := s.Replay(symbol, from, to, func(tk Tick) error {
+		got = append(got, tk)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestRecordSkipsRepeatsAndReplaysInOrder(t *testing.T) {
+	s := openTestStore(t)
+	t0 := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	quote := Tick{Time: t0, Symbol: "AAPL", Kind: KindQuote, Bid: 99.9, Ask: 100.1}
+	if err := s.Record(trade(t0, 100, 10, 1), quote, trade(t0, 100, 10, 1), quote, trade(t0.Add(time.Second), 101, 5, 2)); err != nil {
+		t.Fatal(err)
+	}
+	got := replayAll(t, s, "AAPL", time.Time{}, time.Time{})
+	if len(got) != 3 {
+		t.Fatalf("replayed %d ticks, want 3 with repeats skipped", len(got))
+	}
+	if got[0].ID != 1 || got[1].Kind != KindQuote || got[2].ID != 2 {
+		t.Errorf("ticks out of order: %+v", got)
+	}
+	if got[0].Symbol != "AAPL" {
+		t.Errorf("symbol = %q, want upper case", got[0].Symbol)
+	}
+	if s.Recorded() != 3 {
+		t.Errorf("Recorded() = %d, want 3", s.Recorded())
+	}
+}
+
+func TestDisabledPolicyRecordsNothing(t *testing.T) {
+	s, err := Open(t.TempDir(), time.UTC, DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Record(trade(time.Now(), 100, 1, 1))
+	if len(s.Symbols()) != 0 {
+		t.Errorf("recorded while disabled: %v", s.Symbols())
+	}
+}
+
+func TestSymbolFilter(t *testing.T) {
+	s := openTestStore(t)
+	p := s.Policy()
+	p.Symbols = []string{" msft "}
+	if err := s.SetPolicy(p); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	s.Record(trade(now, 100, 1, 1), Tick{Time: now, Symbol: "MSFT", Kind: KindTrade, Price: 300, Size: 1})
+	if syms := s.Symbols(); len(syms) != 1 || syms[0] != "MSFT" {
+		t.Errorf("Symbols() = %v, want [MSFT]", syms)
+	}
+}
+
+func TestDayFilesAndRangeReplay(t *testing.T) {
+	s := openTestStore(t)
+	day1 := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	s.Record(trade(day1, 100, 1, 1), trade(day2, 102, 1, 2), trade(day2.Add(time.Hour), 103, 1, 3))
+
+	stats := s.Stats()
+	if len(stats) != 1 || stats[0].Days != 2 || stats[0].First != "2026-03-02" || stats[0].Last != "2026-03-03" {
+		t.Fatalf("Stats() = %+v", stats)
+	}
+	got := replayAll(t, s, "aapl", day2, day2.Add(30*time.Minute))
+	if len(got) != 1 || got[0].ID != 2 {
+		t.Errorf("range replay = %+v, want only trade 2", got)
+	}
+}
+
+func TestAppendAcrossSessions(t *testing.T) {
+	dir := t.TempDir()
+	p := DefaultPolicy()
+	p.Enabled = true
+	t0 := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+
+	s1, _ := Open(dir, time.UTC, p)
+	s1.Record(trade(t0, 100, 1, 1))
+	s1.Close()
+	s2, _ := Open(dir, time.UTC, p)
+	defer s2.Close()
+	s2.Record(trade(t0.Add(time.Second), 101, 1, 2))
+
+	if got := replayAll(t, s2, "AAPL", time.Time{}, time.Time{}); len(got) != 2 {
+		t.Errorf("replayed %d ticks across sessions, want 2", len(got))
+	}
+}
+
+func TestTruncatedFileIsRepairedBeforeAppending(t *testing.T) {
+	dir := t.TempDir()
+	p := DefaultPolicy()
+	p.Enabled = true
+	t0 := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+
+	// A crash leaves a flushed but unfinished gzip member behind
+	s1, _ := Open(dir, time.UTC, p)
+	s1.Record(trade(t0, 100, 1, 1), trade(t0.Add(time.Second), 100.5, 1, 2))
+	s1.Flush()
+
+	s2, _ := Open(dir, time.UTC, p)
+	defer s2.Close()
+	s2.Record(trade(t0.Add(2*time.Second), 101, 1, 3))
+	s2.Flush()
+
+	got := replayAll(t, s2, "AAPL", time.Time{}, time.Time{})
+	if len(got) != 3 || got[2].ID != 3 {
+		t.Errorf("replay after repair = %+v, want trades 1-3", got)
+	}
+}
+
+func TestPruneRemovesExpiredDays(t *testing.T) {
+	s := openTestStore(t)
+	old := time.Date(2026, 1, 2, 15, 0, 0, 0, time.UTC)
+	recent := time.Date(2026, 3, 1, 15, 0, 0, 0, time.UTC)
+	s.Record(trade(old, 100, 1, 1))
+	s.Record(trade(recent, 100, 1, 2))
+	s.Close()
+
+	if n := s.Prune(time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)); n != 1 {
+		t.Fatalf("Prune() removed %d files, want 1", n)
+	}
+	if _, err := os.Stat(filepath.Join(s.dir, "AAPL", "2026-03-01"+fileSuffix)); err != nil {
+		t.Errorf("recent day pruned: %v", err)
+	}
+}
+
+func TestBarBuilder(t *testing.T) {
+	t0 := time.Date(2026, 3, 2, 15, 0, 10, 0, time.UTC)
+	b := NewBarBuilder(time.Minute)
+	b.Add(trade(t0, 100, 10, 1))
+	b.Add(Tick{Time: t0, Kind: KindQuote, Bid: 50, Ask: 150})
+	b.Add(trade(t0.Add(20*time.Second), 102, 30, 2))
+	b.Add(trade(t0.Add(30*time.Second), 99, 10, 3))
+	b.Add(trade(t0.Add(3*time.Minute), 101, 5, 4))
+
+	bars := b.Bars()
+	if len(bars) != 2 {
+		t.Fatalf("built %d bars, want 2 with the empty minutes skipped", len(bars))
+	}
+	first := bars[0]
+	if first.Open != 100 || first.High != 102 || first.Low != 99 || first.Close != 99 || first.Volume != 50 || first.Trades != 3 {
+		t.Errorf("first bar = %+v", first)
+	}
+	if want := (100*10 + 102*30 + 99*10) / 50.0; first.VWAP != want {
+		t.Errorf("VWAP = %v, want %v", first.VWAP, want)
+	}
+	if !bars[1].Time.Equal(time.Date(2026, 3, 2, 15, 3, 0, 0, time.UTC)) {
+		t.Errorf("second bar starts %v", bars[1].Time)
+	}
+}
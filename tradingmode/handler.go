@@ -0,0 +1,67 @@
+package tradingmode
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rileyseaburg/go-trader/users"
+)
+
+// Handler exposes the trading mode over HTTP.
+type Handler struct {
+	mode *Mode
+}
+
+// NewHandler creates a handler for mode.
+func NewHandler(mode *Mode) *Handler {
+	return &Handler{mode: mode}
+}
+
+// RegisterRoutes registers the trading mode routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/trading-mode - mode, execution state and the confirm_live window
+	mux.HandleFunc("/api/trading-mode", h.handleStatus)
+
+	// POST /api/trading-mode/enable - leave safe mode, {"reason"} optional; admin only
+	mux.HandleFunc("/api/trading-mode/enable", h.handleSet(true))
+
+	// POST /api/trading-mode/disable - return to safe mode, {"reason"} optional; admin only
+	mux.HandleFunc("/api/trading-mode/disable", h.handleSet(false))
+}
+
+func (h *Handler) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(h.mode.Status())
+}
+
+func (h *Handler) handleSet(enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		caller := users.FromContext(r.Context())
+		if !caller.IsAdmin() {
+			http.Error(w, users.ErrForbidden.Error(), http.StatusForbidden)
+			return
+		}
+		var req struct {
+			Reason string `json:"reason"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+		if enabled {
+			h.mode.Enable(caller.ID, req.Reason)
+		} else {
+			h.mode.Disable(caller.ID, req.Reason)
+		}
+		json.NewEncoder(w).Encode(h.mode.Status())
+	}
+}
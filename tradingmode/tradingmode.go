@@ -0,0 +1,273 @@
+// Package tradingmode makes the engine's trading mode explicit. Every
+// response names the mode in an X-Trading-Mode header. In live trading the
+// engine starts in safe mode, with execution disabled until an admin
+// enables it, and order requests made in the first minutes after startup
+// must carry confirm_live=true, so a script or page pointed at the wrong
+// instance cannot place real orders by accident.
+package tradingmode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/datadir"
+)
+
+func logger() *slog.Logger { return slog.With("module", "tradingmode") }
+
+// Header names the trading mode on every response.
+const Header = "X-Trading-Mode"
+
+// ConfirmParam is the query parameter order requests confirm live trading
+// with.
+const ConfirmParam = "confirm_live"
+
+// maxChanges bounds the enable and disable history kept in memory.
+const maxChanges = 50
+
+var (
+	// ErrSafeMode refuses orders while execution is disabled.
+	ErrSafeMode = errors.New("execution is disabled in safe mode; an admin must enable it")
+	// ErrConfirmLive refuses order requests without confirm_live=true in
+	// the window after a live startup.
+	ErrConfirmLive = errors.New("live trading: order requests need confirm_live=true")
+)
+
+// Policy configures the safeguards. Both only apply in live trading.
+type Policy struct {
+	// SafeMode starts the engine with execution disabled until an admin
+	// enables it
+	SafeMode bool `json:"safe_mode"`
+	// ConfirmLiveMinutes is how long after startup order requests need
+	// confirm_live=true; 0 never
+	ConfirmLiveMinutes int `json:"confirm_live_minutes"`
+}
+
+// DefaultPolicy starts in safe mode and asks for confirm_live during the
+// first 30 minutes.
+func DefaultPolicy() Policy {
+	return Policy{SafeMode: true, ConfirmLiveMinutes: 30}
+}
+
+// Validate checks the policy for usable values.
+func (p Policy) Validate() error {
+	if p.ConfirmLiveMinutes < 0 || p.ConfirmLiveMinutes > 24*60 {
+		return errors.New("confirm_live_minutes must be between 0 and 1440")
+	}
+	return nil
+}
+
+// Change records execution being enabled or disabled.
+type Change struct {
+	Enabled bool      `json:"enabled"`
+	By      string    `json:"by"`
+	Reason  string    `json:"reason,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// Status is the mode and the state of its safeguards.
+type Status struct {
+	Mode             string    `json:"mode"` // live, paper, mock or replay
+	Live             bool      `json:"live"`
+	Policy           Policy    `json:"policy"`
+	StartedAt        time.Time `json:"started_at"`
+	ExecutionEnabled bool      `json:"execution_enabled"`
+	// ConfirmLiveUntil is when order requests stop needing confirm_live,
+	// set while they still do
+	ConfirmLiveUntil *time.Time `json:"confirm_live_until,omitempty"`
+	Changes          []Change   `json:"changes"` // newest first
+}
+
+// Notifier is told when execution is enabled or disabled.
+type Notifier func(title, message string, metadata map[string]interface{})
+
+// Mode holds the engine's trading mode and whether it may execute.
+type Mode struct {
+	mu      sync.RWMutex
+	name    string
+	policy  Policy
+	started time.Time
+	enabled bool
+	changes []Change
+	now     func() time.Time
+	notify  Notifier
+}
+
+// New returns the mode named name, one of the datadir modes, starting now.
+// Outside live trading execution is always enabled and policy is kept only
+// for reporting.
+func New(name string, policy Policy) (*Mode, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	m := &Mode{name: name, policy: policy, now: time.Now}
+	m.started = m.now()
+	m.enabled = name != datadir.ModeLive || !policy.SafeMode
+	if !m.enabled {
+		logger().Warn("Live trading started in safe mode: execution is disabled until an admin enables it")
+	}
+	return m, nil
+}
+
+// SetClock replaces the clock the confirm_live window is measured on.
+func (m *Mode) SetClock(now func() time.Time) { m.mu.Lock(); m.now = now; m.mu.Unlock() }
+
+// SetNotifier registers a callback for execution being enabled or
+// disabled.
+func (m *Mode) SetNotifier(n Notifier) { m.mu.Lock(); m.notify = n; m.mu.Unlock() }
+
+// Name returns the mode's name.
+func (m *Mode) Name() string { return m.name }
+
+// Live reports whether orders reach a live account.
+func (m *Mode) Live() bool { return m.name == datadir.ModeLive }
+
+// ExecutionEnabled reports whether orders may be placed.
+func (m *Mode) ExecutionEnabled() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.enabled
+}
+
+// CheckExecution returns ErrSafeMode while execution is disabled.
+func (m *Mode) CheckExecution() error {
+	if !m.ExecutionEnabled() {
+		return ErrSafeMode
+	}
+	return nil
+}
+
+// Enable lets orders be placed, recording who asked and why.
+func (m *Mode) Enable(by, reason string) { m.set(true, by, reason) }
+
+// Disable stops orders being placed until Enable is called, the same safe
+// mode a live startup begins in.
+func (m *Mode) Disable(by, reason string) { m.set(false, by, reason) }
+
+func (m *Mode) set(enabled bool, by, reason string) {
+	m.mu.Lock()
+	if m.enabled == enabled {
+		m.mu.Unlock()
+		return
+	}
+	m.enabled = enabled
+	change := Change{Enabled: enabled, By: by, Reason: reason, At: m.now()}
+	m.changes = append(m.changes, change)
+	if over := len(m.changes) - maxChanges; over > 0 {
+		m.changes = m.changes[over:]
+	}
+	notify := m.notify
+	m.mu.Unlock()
+
+	title := fmt.Sprintf("Execution enabled (%s)", m.name)
+	if !enabled {
+		title = fmt.Sprintf("Execution disabled (%s)", m.name)
+	}
+	logger().Warn(title, "by", by, "reason", reason)
+	if notify != nil {
+		message := "Orders may be placed again."
+		if !enabled {
+			message = "Orders are refused until an admin enables execution."
+		}
+		if reason != "" {
+			message += " Reason: " + reason
+		}
+		notify(title, message, map[string]interface{}{"mode": m.name, "enabled": enabled, "by": by})
+	}
+}
+
+// confirmUntilLocked returns when order requests stop needing
+// confirm_live, and whether they still do.
+func (m *Mode) confirmUntilLocked() (time.Time, bool) {
+	if !m.Live() || m.policy.ConfirmLiveMinutes == 0 {
+		return time.Time{}, false
+	}
+	until := m.started.Add(time.Duration(m.policy.ConfirmLiveMinutes) * time.Minute)
+	return until, m.now().Before(until)
+}
+
+// Status returns the mode and its safeguards.
+func (m *Mode) Status() Status {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s := Status{
+		Mode:             m.name,
+		Live:             m.Live(),
+		Policy:           m.policy,
+		StartedAt:        m.started,
+		ExecutionEnabled: m.enabled,
+		Changes:          make([]Change, len(m.changes)),
+	}
+	if until, ok := m.confirmUntilLocked(); ok {
+		s.ConfirmLiveUntil = &until
+	}
+	for i, c := range m.changes {
+		s.Changes[len(m.changes)-1-i] = c
+	}
+	return s
+}
+
+// Announce names the mode on every response. Put it outermost, so CORS
+// preflights and authentication refusals name it too.
+func (m *Mode) Announce(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(Header, m.name)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Middleware guards the order routes, standard library mux patterns such
+// as "POST /api/executeTrade": while execution is disabled they are
+// refused with 423, and in the confirm_live window they need
+// confirm_live=true or are refused with 428. dryRunRoutes are order
+// routes whose handlers place nothing for dry_run=true in the query;
+// those requests pass.
+func (m *Mode) Middleware(next http.Handler, orderRoutes, dryRunRoutes []string) http.Handler {
+	routes, dryRuns := http.NewServeMux(), http.NewServeMux()
+	for _, pattern := range orderRoutes {
+		routes.HandleFunc(pattern, func(http.ResponseWriter, *http.Request) {})
+	}
+	for _, pattern := range dryRunRoutes {
+		routes.HandleFunc(pattern, func(http.ResponseWriter, *http.Request) {})
+		dryRuns.HandleFunc(pattern, func(http.ResponseWriter, *http.Request) {})
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, pattern := routes.Handler(r); pattern == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if _, pattern := dryRuns.Handler(r); pattern != "" && r.URL.Query().Get("dry_run") == "true" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		m.mu.RLock()
+		enabled := m.enabled
+		until, confirming := m.confirmUntilLocked()
+		m.mu.RUnlock()
+		switch {
+		case !enabled:
+			refuse(w, http.StatusLocked, map[string]interface{}{"error": ErrSafeMode.Error(), "success": false, "safe_mode": true})
+		case confirming && r.URL.Query().Get(ConfirmParam) != "true":
+			refuse(w, http.StatusPreconditionRequired, map[string]interface{}{"error": ErrConfirmLive.Error(), "success": false,
+				"confirm_live_until": until})
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func refuse(w http.ResponseWriter, status int, body map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
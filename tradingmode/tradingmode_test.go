@@ -0,0 +1,103 @@
+package tradingmode
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/datadir"
+	"github.com/rileyseaburg/go-trader/users"
+)
+
+func serve(m *Mode, method, target string) *httptest.ResponseRecorder {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	h := m.Announce(m.Middleware(ok, []string{"POST /api/confirmations/{id}/confirm"}, []string{"POST /api/executeTrade"}))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	return rec
+}
+
+func TestLiveStartsSafeAndAsksForConfirmation(t *testing.T) {
+	now := time.Date(2026, 9, 15, 9, 0, 0, 0, time.UTC)
+	m, err := New(datadir.ModeLive, DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetClock(func() time.Time { return now })
+	m.started = now
+	var alerts []string
+	m.SetNotifier(func(title, _ string, _ map[string]interface{}) { alerts = append(alerts, title) })
+
+	cases := []struct {
+		name   string
+		method string
+		target string
+		want   int
+	}{
+		{"reads pass", http.MethodGet, "/api/positions", http.StatusOK},
+		{"safe mode", http.MethodPost, "/api/executeTrade", http.StatusLocked},
+		{"dry runs pass", http.MethodPost, "/api/executeTrade?dry_run=true", http.StatusOK},
+		{"confirm in safe mode", http.MethodPost, "/api/confirmations/c1/confirm?confirm_live=true", http.StatusLocked},
+		{"confirm ignores dry_run", http.MethodPost, "/api/confirmations/c1/confirm?dry_run=true", http.StatusLocked},
+	}
+	for _, c := range cases {
+		rec := serve(m, c.method, c.target)
+		if rec.Code != c.want || rec.Header().Get(Header) != "live" {
+			t.Errorf("%s: %d, mode %q", c.name, rec.Code, rec.Header().Get(Header))
+		}
+	}
+	if err := m.CheckExecution(); err != ErrSafeMode {
+		t.Errorf("CheckExecution = %v", err)
+	}
+
+	m.Enable("admin", "checked the keys")
+	if rec := serve(m, http.MethodPost, "/api/executeTrade"); rec.Code != http.StatusPreconditionRequired {
+		t.Errorf("without confirm_live = %d", rec.Code)
+	}
+	if rec := serve(m, http.MethodPost, "/api/executeTrade?confirm_live=true"); rec.Code != http.StatusOK {
+		t.Errorf("with confirm_live = %d", rec.Code)
+	}
+	now = now.Add(31 * time.Minute)
+	if rec := serve(m, http.MethodPost, "/api/executeTrade"); rec.Code != http.StatusOK {
+		t.Errorf("after the window = %d", rec.Code)
+	}
+	if s := m.Status(); s.ConfirmLiveUntil != nil || !s.ExecutionEnabled || len(s.Changes) != 1 || s.Changes[0].By != "admin" {
+		t.Errorf("status = %+v", s)
+	}
+	if len(alerts) != 1 || alerts[0] != "Execution enabled (live)" {
+		t.Errorf("alerts = %v", alerts)
+	}
+}
+
+func TestPaperIsNotGuarded(t *testing.T) {
+	m, err := New(datadir.ModePaper, DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := serve(m, http.MethodPost, "/api/executeTrade"); rec.Code != http.StatusOK || rec.Header().Get(Header) != "paper" {
+		t.Errorf("paper order = %d, mode %q", rec.Code, rec.Header().Get(Header))
+	}
+	if _, err := New(datadir.ModeLive, Policy{ConfirmLiveMinutes: -1}); err == nil {
+		t.Error("negative confirm_live_minutes accepted")
+	}
+}
+
+func TestOnlyAdminsEnable(t *testing.T) {
+	m, _ := New(datadir.ModeLive, DefaultPolicy())
+	mux := http.NewServeMux()
+	NewHandler(m).RegisterRoutes(mux)
+
+	trader := users.WithUser(context.Background(), users.User{ID: "trader", Role: users.RoleUser})
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/trading-mode/enable", nil).WithContext(trader))
+	if rec.Code != http.StatusForbidden || m.ExecutionEnabled() {
+		t.Errorf("user enable = %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/trading-mode/enable", nil))
+	if rec.Code != http.StatusOK || !m.ExecutionEnabled() {
+		t.Errorf("admin enable = %d", rec.Code)
+	}
+}
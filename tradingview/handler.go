@@ -0,0 +1,73 @@
+package tradingview
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/approvals"
+)
+
+// maxBody bounds an alert body; real alerts are a few hundred bytes.
+const maxBody = 64 * 1024
+
+// Handler receives TradingView alerts.
+type Handler struct {
+	secret string
+	queue  *approvals.Queue
+	now    func() time.Time
+}
+
+// NewHandler creates a handler submitting alerts signed with secret to
+// queue. With an empty secret every alert is refused.
+func NewHandler(secret string, queue *approvals.Queue) *Handler {
+	return &Handler{secret: secret, queue: queue, now: time.Now}
+}
+
+// RegisterRoutes registers the alert route with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// POST /api/webhooks/tradingview - a TradingView alert; the signal goes to the approval queue
+	mux.HandleFunc("/api/webhooks/tradingview", h.handleAlert)
+}
+
+func (h *Handler) handleAlert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if h.secret == "" {
+		http.Error(w, "TradingView alerts are disabled: no TRADINGVIEW_WEBHOOK_SECRET is configured", http.StatusServiceUnavailable)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxBody+1))
+	if err != nil || len(body) > maxBody {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	alert, err := Decode(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := alert.CheckSecret(h.secret); err != nil {
+		logger().Warn("Refused TradingView alert with a bad secret", "remote_addr", r.RemoteAddr)
+		http.Error(w, "Invalid secret", http.StatusUnauthorized)
+		return
+	}
+	signal, err := alert.TradeSignal(h.now())
+	if err != nil {
+		logger().Warn("Refused malformed TradingView alert", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	item, err := h.queue.Submit(signal)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(approvals.StatusCode(item))
+	json.NewEncoder(w).Encode(item)
+}
@@ -0,0 +1,203 @@
+// Package tradingview turns TradingView alert webhooks into trade
+// signals. An alert's message is a JSON object written in the alert
+// dialog, usually with TradingView placeholders, for example:
+//
+//	{"secret": "...", "ticker": "{{exchange}}:{{ticker}}",
+//	 "action": "{{strategy.order.action}}", "price": {{close}},
+//	 "qty": {{strategy.order.contracts}}, "comment": "{{strategy.order.comment}}"}
+//
+// TradingView cannot set request headers, so the shared secret travels in
+// the body. Accepted signals go to the approval queue tagged with source
+// tradingview.
+package tradingview
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+)
+
+func logger() *slog.Logger { return slog.With("module", "tradingview") }
+
+// Source is the signal source for TradingView alerts.
+const Source = "tradingview"
+
+// ErrUnauthorized is returned for a missing or wrong secret.
+var ErrUnauthorized = errors.New("invalid alert secret")
+
+// Number is a JSON number that may also arrive as a string, since alert
+// templates often quote their placeholders.
+type Number float64
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (n *Number) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(strings.TrimSpace(string(data)), `"`)
+	if s == "" || s == "null" {
+		*n = 0
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("not a number: %s", data)
+	}
+	*n = Number(v)
+	return nil
+}
+
+// Alert is a TradingView alert payload. Aliases cover the common
+// template conventions.
+type Alert struct {
+	Secret     string `json:"secret"`
+	Passphrase string `json:"passphrase"` // alias of secret
+
+	Ticker string `json:"ticker"` // with or without an EXCHANGE: prefix
+	Symbol string `json:"symbol"` // alias of ticker
+
+	Action string `json:"action"` // buy, sell, long, short, exit, close or flat
+	Side   string `json:"side"`   // alias of action
+	Signal string `json:"signal"` // alias of action
+
+	OrderType  string `json:"order_type"` // market (default), limit, stop or stop_limit
+	Price      Number `json:"price"`      // limit price for limit orders
+	LimitPrice Number `json:"limit_price"`
+	StopPrice  Number `json:"stop_price"` // for stop and stop_limit orders
+
+	Qty             Number `json:"qty"`
+	Contracts       Number `json:"contracts"` // alias of qty
+	Notional        Number `json:"notional"`
+	PercentOfEquity Number `json:"percent_of_equity"`
+
+	Confidence Number `json:"confidence"` // 0-1
+	Comment    string `json:"comment"`
+	Strategy   string `json:"strategy"` // strategy tag for the client order ID
+	Execution  string `json:"execution"`
+	Time       string `json:"time"` // {{timenow}}, informational
+}
+
+// CheckSecret reports whether the alert carries secret. An empty secret
+// never matches.
+func (a Alert) CheckSecret(secret string) error {
+	got := a.Secret
+	if got == "" {
+		got = a.Passphrase
+	}
+	if secret == "" || subtle.ConstantTimeCompare([]byte(got), []byte(secret)) != 1 {
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// TradeSignal maps the alert to a trade signal received at now.
+func (a Alert) TradeSignal(now time.Time) (*algorithm.TradeSignal, error) {
+	symbol := firstOf(a.Ticker, a.Symbol)
+	if i := strings.LastIndex(symbol, ":"); i >= 0 {
+		symbol = symbol[i+1:]
+	}
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	if symbol == "" {
+		return nil, errors.New("ticker is required")
+	}
+
+	var side string
+	switch action := strings.ToLower(strings.TrimSpace(firstOf(a.Action, a.Side, a.Signal))); action {
+	case "buy", "long":
+		side = algorithm.SignalBuy
+	case "sell", "short":
+		side = algorithm.SignalSell
+	case "exit", "close", "flat":
+		side = algorithm.SignalClose
+	case "":
+		return nil, errors.New("action is required")
+	default:
+		return nil, fmt.Errorf("unsupported action %q: use buy, sell, long, short, exit, close or flat", action)
+	}
+
+	signal := &algorithm.TradeSignal{
+		Symbol:    symbol,
+		Signal:    side,
+		OrderType: "market",
+		Timestamp: now,
+		Reasoning: strings.TrimSpace(a.Comment),
+		Source:    Source,
+	}
+	if signal.Reasoning == "" {
+		signal.Reasoning = "TradingView alert"
+	}
+
+	orderType, err := algorithm.NormalizeOrderType(a.OrderType)
+	if err != nil {
+		return nil, err
+	}
+	signal.OrderType = orderType
+	if orderType == algorithm.OrderTypeLimit || orderType == algorithm.OrderTypeStopLimit {
+		price := float64(a.LimitPrice)
+		if price == 0 {
+			price = float64(a.Price)
+		}
+		if price <= 0 {
+			return nil, fmt.Errorf("a %s order needs a positive price or limit_price", orderType)
+		}
+		signal.LimitPrice = &price
+	}
+	if a.StopPrice != 0 {
+		stop := float64(a.StopPrice)
+		signal.StopPrice = &stop
+	}
+	if err := signal.ValidateOrder(); err != nil {
+		return nil, err
+	}
+
+	qty := float64(a.Qty)
+	if qty == 0 {
+		qty = float64(a.Contracts)
+	}
+	if qty != 0 || a.Notional != 0 || a.PercentOfEquity != 0 {
+		signal.Size = &algorithm.TradeSize{Qty: qty, Notional: float64(a.Notional), PercentOfEquity: float64(a.PercentOfEquity)}
+		if err := signal.Size.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	if a.Confidence != 0 {
+		if a.Confidence < 0 || a.Confidence > 1 {
+			return nil, errors.New("confidence must be between 0 and 1")
+		}
+		confidence := float64(a.Confidence)
+		signal.Confidence = &confidence
+	}
+
+	execution, err := algorithm.NormalizeExecution(a.Execution)
+	if err != nil {
+		return nil, err
+	}
+	signal.Execution = execution
+	if signal.Tag, err = algorithm.NormalizeTag(a.Strategy); err != nil {
+		return nil, err
+	}
+	return signal, nil
+}
+
+func firstOf(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Decode parses an alert body.
+func Decode(body []byte) (Alert, error) {
+	var a Alert
+	if err := json.Unmarshal(body, &a); err != nil {
+		return Alert{}, fmt.Errorf("alert message must be a JSON object: %w", err)
+	}
+	return a, nil
+}
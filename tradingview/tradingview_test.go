@@ -0,0 +1,101 @@
+package tradingview
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+	"github.com/rileyseaburg/go-trader/approvals"
+)
+
+func TestAlertMapsToSignal(t *testing.T) {
+	now := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	alert, err := Decode([]byte(`{"passphrase": "s", "ticker": "NASDAQ:aapl", "action": "long",
+		"order_type": "limit", "price": "189.5", "contracts": 10, "confidence": 0.7,
+		"comment": "breakout", "strategy": "tv-breakout"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alert.CheckSecret("s") != nil || alert.CheckSecret("x") == nil || alert.CheckSecret("") == nil {
+		t.Error("secret check")
+	}
+	s, err := alert.TradeSignal(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Symbol != "AAPL" || s.Signal != algorithm.SignalBuy || s.OrderType != "limit" || *s.LimitPrice != 189.5 ||
+		s.Size == nil || s.Size.Qty != 10 || *s.Confidence != 0.7 || s.Reasoning != "breakout" ||
+		s.Source != Source || s.Tag != "tv-breakout" || !s.Timestamp.Equal(now) {
+		t.Fatalf("signal = %+v", s)
+	}
+
+	alert, _ = Decode([]byte(`{"ticker": "AAPL", "action": "exit", "order_type": "stop-limit", "stop_price": 180, "limit_price": 179.5}`))
+	if s, err := alert.TradeSignal(now); err != nil || s.OrderType != algorithm.OrderTypeStopLimit || *s.StopPrice != 180 || *s.LimitPrice != 179.5 {
+		t.Fatalf("stop-limit signal = %+v, %v", s, err)
+	}
+
+	for body, want := range map[string]string{
+		`{"ticker": "AAPL", "action": "exit"}`:                                              "",
+		`{"ticker": "AAPL"}`:                                                                "action is required",
+		`{"ticker": "AAPL", "action": "buy", "order_type": "trailing_stop"}`:                "unknown order type",
+		`{"ticker": "AAPL", "action": "buy", "order_type": "stop"}`:                         "positive stop_price",
+		`{"ticker": "AAPL", "action": "buy", "order_type": "limit"}`:                        "positive price",
+		`{"ticker": "AAPL", "action": "sell", "order_type": "stop_limit", "stop_price": 9}`: "positive price",
+		`{"action": "buy"}`: "ticker is required",
+	} {
+		a, err := Decode([]byte(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := a.TradeSignal(now)
+		switch {
+		case want == "" && (err != nil || s.Signal != algorithm.SignalClose || s.OrderType != "market" || s.Size != nil):
+			t.Errorf("%s: %+v, %v", body, s, err)
+		case want != "" && (err == nil || !strings.Contains(err.Error(), want)):
+			t.Errorf("%s: error %v, want %q", body, err, want)
+		}
+	}
+}
+
+func TestHandlerQueuesAlerts(t *testing.T) {
+	queue, err := approvals.New(filepath.Join(t.TempDir(), "queue.json"), approvals.DefaultPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	NewHandler("s3cret", queue).RegisterRoutes(mux)
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/webhooks/tradingview", strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := post(`{"secret": "nope", "ticker": "AAPL", "action": "buy"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("bad secret: %d", rec.Code)
+	}
+	if rec := post(`not json`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad body: %d", rec.Code)
+	}
+	rec := post(`{"secret": "s3cret", "ticker": "AAPL", "action": "buy"}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("alert: %d %s", rec.Code, rec.Body)
+	}
+	var item approvals.Item
+	json.NewDecoder(rec.Body).Decode(&item)
+	if item.Status != approvals.StatusPending || item.Signal.Source != Source {
+		t.Fatalf("item = %+v", item)
+	}
+
+	disabled := http.NewServeMux()
+	NewHandler("", queue).RegisterRoutes(disabled)
+	rec = httptest.NewRecorder()
+	disabled.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/webhooks/tradingview", strings.NewReader(`{}`)))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("without a secret: %d", rec.Code)
+	}
+}
@@ -0,0 +1,57 @@
+package tsdb
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Handler exposes the writer over HTTP.
+type Handler struct {
+	writer *Writer
+}
+
+// NewHandler creates a handler for writer.
+func NewHandler(writer *Writer) *Handler {
+	return &Handler{writer: writer}
+}
+
+// RegisterRoutes registers the time-series routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/tsdb - backend, buffered and written points, last error
+	mux.HandleFunc("/api/tsdb", h.handleStats)
+
+	// GET/POST /api/tsdb/policy - what is written and how it is batched
+	mux.HandleFunc("/api/tsdb/policy", h.handlePolicy)
+}
+
+func (h *Handler) handleStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"policy": h.writer.Policy(),
+		"stats":  h.writer.Stats(),
+	})
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.writer.Policy())
+	case http.MethodPost:
+		// Decode onto the current policy so partial updates work
+		p := h.writer.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.writer.SetPolicy(p); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(h.writer.Policy())
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
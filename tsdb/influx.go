@@ -0,0 +1,112 @@
+package tsdb
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Influx writes points to InfluxDB 2.x, or 1.8+ through its 2.x
+// compatibility API, in line protocol.
+type Influx struct {
+	endpoint string
+	token    string
+	client   *http.Client
+}
+
+// NewInflux creates a sink for bucket in org on the server at baseURL,
+// such as http://localhost:8086. For InfluxDB 1.8 the bucket is
+// "database/retention-policy", org is ignored and token is
+// "username:password".
+func NewInflux(baseURL, org, bucket, token string) (*Influx, error) {
+	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid InfluxDB URL %q", baseURL)
+	}
+	if bucket == "" {
+		return nil, fmt.Errorf("an InfluxDB bucket is required")
+	}
+	u.Path += "/api/v2/write"
+	u.RawQuery = url.Values{"org": {org}, "bucket": {bucket}, "precision": {"ns"}}.Encode()
+	return &Influx{endpoint: u.String(), token: token, client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+// Name implements Sink.
+func (i *Influx) Name() string { return "influxdb" }
+
+// Write implements Sink.
+func (i *Influx) Write(ctx context.Context, points []Point) error {
+	var body bytes.Buffer
+	for _, p := range points {
+		writeLine(&body, p)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, i.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if i.token != "" {
+		req.Header.Set("Authorization", "Token "+i.token)
+	}
+	resp, err := i.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("InfluxDB returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Line protocol escaping: measurements escape commas and spaces; tag keys,
+// tag values and field keys also escape equals signs.
+var (
+	measurementEscaper = strings.NewReplacer(`,`, `\,`, ` `, `\ `)
+	keyEscaper         = strings.NewReplacer(`,`, `\,`, `=`, `\=`, ` `, `\ `)
+)
+
+// writeLine appends p in line protocol, with tags and fields sorted so
+// the output is stable.
+func writeLine(buf *bytes.Buffer, p Point) {
+	buf.WriteString(measurementEscaper.Replace(p.Measurement))
+	for _, k := range sortedKeys(p.Tags) {
+		if p.Tags[k] == "" {
+			continue
+		}
+		buf.WriteByte(',')
+		buf.WriteString(keyEscaper.Replace(k))
+		buf.WriteByte('=')
+		buf.WriteString(keyEscaper.Replace(p.Tags[k]))
+	}
+	for n, k := range sortedKeys(p.Fields) {
+		if n == 0 {
+			buf.WriteByte(' ')
+		} else {
+			buf.WriteByte(',')
+		}
+		buf.WriteString(keyEscaper.Replace(k))
+		buf.WriteByte('=')
+		buf.WriteString(strconv.FormatFloat(p.Fields[k], 'f', -1, 64))
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatInt(p.Time.UnixNano(), 10))
+	buf.WriteByte('\n')
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
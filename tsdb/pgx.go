@@ -0,0 +1,7 @@
+//go:build timescale
+
+package tsdb
+
+// Building with -tags timescale links pgx's database/sql driver, registered
+// as "pgx", which the TimescaleDB sink is opened with.
+import _ "github.com/jackc/pgx/v5/stdlib"
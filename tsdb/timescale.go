@@ -0,0 +1,99 @@
+package tsdb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// Execer runs SQL statements; *sql.DB satisfies it.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+var tableName = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// Timescale writes points to a TimescaleDB hypertable in narrow form, one
+// row per field:
+//
+//	time timestamptz, measurement text, tags jsonb, field text, value double precision
+//
+// so new fields need no schema change and Grafana can filter on
+// measurement, field and tags->>'symbol'.
+type Timescale struct {
+	db    Execer
+	table string
+}
+
+// NewTimescale creates a sink writing to table through db, which must
+// accept Go slices as Postgres arrays, as pgx's database/sql driver does.
+func NewTimescale(db Execer, table string) (*Timescale, error) {
+	if !tableName.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	return &Timescale{db: db, table: table}, nil
+}
+
+// Init creates the table, its hypertable and an index for the usual
+// measurement and field lookups if they do not exist.
+func (t *Timescale) Init(ctx context.Context) error {
+	stmts := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	time TIMESTAMPTZ NOT NULL,
+	measurement TEXT NOT NULL,
+	tags JSONB NOT NULL DEFAULT '{}',
+	field TEXT NOT NULL,
+	value DOUBLE PRECISION NOT NULL
+)`, t.table),
+		fmt.Sprintf(`SELECT create_hypertable('%s', 'time', if_not_exists => TRUE)`, t.table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS %s_measurement_field_time ON %s (measurement, field, time DESC)`, t.table, t.table),
+	}
+	for _, stmt := range stmts {
+		if _, err := t.db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("failed to prepare TimescaleDB table: %w", err)
+		}
+	}
+	return nil
+}
+
+// Name implements Sink.
+func (t *Timescale) Name() string { return "timescaledb" }
+
+// Write implements Sink. The points go in one statement, as arrays
+// unnested into rows, so a failed write leaves nothing behind to be
+// duplicated when it is retried.
+func (t *Timescale) Write(ctx context.Context, points []Point) error {
+	var (
+		times        []time.Time
+		measurements []string
+		tags         []string
+		fields       []string
+		values       []float64
+	)
+	for _, p := range points {
+		tagJSON := []byte("{}")
+		if len(p.Tags) > 0 {
+			var err error
+			if tagJSON, err = json.Marshal(p.Tags); err != nil {
+				return err
+			}
+		}
+		for _, field := range sortedKeys(p.Fields) {
+			times = append(times, p.Time.UTC())
+			measurements = append(measurements, p.Measurement)
+			tags = append(tags, string(tagJSON))
+			fields = append(fields, field)
+			values = append(values, p.Fields[field])
+		}
+	}
+	if len(times) == 0 {
+		return nil
+	}
+	_, err := t.db.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (time, measurement, tags, field, value)
+SELECT * FROM unnest($1::timestamptz[], $2::text[], $3::jsonb[], $4::text[], $5::float8[])`, t.table),
+		times, measurements, tags, fields, values)
+	return err
+}
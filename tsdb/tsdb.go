@@ -0,0 +1,381 @@
+// Package tsdb writes bars, indicator values and portfolio metrics to an
+// external time-series database — InfluxDB or TimescaleDB — so long-horizon
+// analytics and Grafana dashboards can read them without the process
+// keeping everything in memory. Points are buffered and written in batches
+// from one goroutine; when the database is slow or down the buffer keeps
+// the newest points and drops the oldest, so trading never waits on it.
+package tsdb
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+)
+
+func logger() *slog.Logger { return slog.With("module", "tsdb") }
+
+// Measurements written.
+const (
+	MeasurementBar        = "bars"
+	MeasurementIndicators = "indicators"
+	MeasurementPortfolio  = "portfolio"
+	MeasurementPosition   = "positions"
+)
+
+// Point is one timestamped set of values.
+type Point struct {
+	Measurement string
+	Tags        map[string]string
+	Fields      map[string]float64
+	Time        time.Time
+}
+
+// Sink is a time-series database points are written to.
+type Sink interface {
+	// Name identifies the backend, such as "influxdb".
+	Name() string
+	// Write stores points; an error means none of them should be assumed
+	// written.
+	Write(ctx context.Context, points []Point) error
+}
+
+// Policy decides what is written and how it is batched.
+type Policy struct {
+	Bars            bool `json:"bars"`
+	Indicators      bool `json:"indicators"`
+	Portfolio       bool `json:"portfolio"`
+	IntervalSeconds int  `json:"interval_seconds"` // how often the buffer is flushed and the portfolio sampled
+	BatchSize       int  `json:"batch_size"`       // points per write
+	MaxBuffer       int  `json:"max_buffer"`       // points held while the database is unreachable
+}
+
+// DefaultPolicy writes everything every 10 seconds, 500 points at a time,
+// holding up to 50,000 points through an outage.
+func DefaultPolicy() Policy {
+	return Policy{Bars: true, Indicators: true, Portfolio: true, IntervalSeconds: 10, BatchSize: 500, MaxBuffer: 50000}
+}
+
+// Validate checks the policy for usable values.
+func (p Policy) Validate() error {
+	if p.IntervalSeconds < 1 || p.IntervalSeconds > 3600 {
+		return errors.New("interval_seconds must be between 1 and 3600")
+	}
+	if p.BatchSize < 1 || p.BatchSize > 10000 {
+		return errors.New("batch_size must be between 1 and 10000")
+	}
+	if p.MaxBuffer < p.BatchSize {
+		return errors.New("max_buffer must be at least batch_size")
+	}
+	return nil
+}
+
+// Sources supply the values sampled on each interval. Either may be nil.
+type Sources struct {
+	Portfolio func() algorithm.PortfolioData
+	Baselines func() map[string]algorithm.SymbolBaseline
+}
+
+// Stats describes what has been written.
+type Stats struct {
+	Enabled   bool       `json:"enabled"`
+	Backend   string     `json:"backend,omitempty"`
+	Buffered  int        `json:"buffered"`
+	Written   int64      `json:"written"`
+	Dropped   int64      `json:"dropped"` // oldest points discarded from a full buffer
+	Failures  int64      `json:"failures"`
+	LastWrite *time.Time `json:"last_write,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// Writer buffers points and writes them to a sink. A writer without a sink
+// is disabled and ignores everything. It is safe for concurrent use.
+type Writer struct {
+	sink Sink
+
+	mu       sync.Mutex
+	policy   Policy
+	sources  Sources
+	buf      []Point
+	lastBar  map[string]time.Time // newest bar written per symbol and timeframe
+	lastAsOf map[string]time.Time // newest baseline written per symbol
+	stats    Stats
+	now      func() time.Time
+	ready    chan struct{} // signalled when a full batch is buffered
+}
+
+// New creates a writer for sink, which may be nil to disable writing.
+func New(sink Sink, policy Policy) (*Writer, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	w := &Writer{
+		sink:     sink,
+		policy:   policy,
+		lastBar:  make(map[string]time.Time),
+		lastAsOf: make(map[string]time.Time),
+		now:      time.Now,
+		ready:    make(chan struct{}, 1),
+	}
+	if sink != nil {
+		w.stats.Enabled, w.stats.Backend = true, sink.Name()
+	}
+	return w, nil
+}
+
+// SetClock replaces the clock, for replays and tests.
+func (w *Writer) SetClock(now func() time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.now = now
+}
+
+// SetSources sets what is sampled on each interval.
+func (w *Writer) SetSources(s Sources) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.sources = s
+}
+
+// Policy returns the current policy.
+func (w *Writer) Policy() Policy {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.policy
+}
+
+// SetPolicy validates and replaces the policy. The interval applies from
+// the next flush.
+func (w *Writer) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.policy = p
+	w.trimLocked()
+	return nil
+}
+
+// Stats returns what has been written so far.
+func (w *Writer) Stats() Stats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := w.stats
+	s.Buffered = len(w.buf)
+	return s
+}
+
+// Bar queues a bar. Bars no newer than the last one written for the
+// symbol and timeframe are skipped, since pollers see the same bar again.
+func (w *Writer) Bar(timeframe string, bar algorithm.BarData) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.sink == nil || !w.policy.Bars {
+		return
+	}
+	key := bar.Symbol + "/" + timeframe
+	if last, ok := w.lastBar[key]; ok && !bar.Timestamp.After(last) {
+		return
+	}
+	w.lastBar[key] = bar.Timestamp
+	fields := map[string]float64{
+		"open":   bar.Open,
+		"high":   bar.High,
+		"low":    bar.Low,
+		"close":  bar.Close,
+		"volume": float64(bar.Volume),
+	}
+	if bar.VWAP > 0 {
+		fields["vwap"] = bar.VWAP
+	}
+	w.addLocked(Point{
+		Measurement: MeasurementBar,
+		Tags:        map[string]string{"symbol": bar.Symbol, "timeframe": timeframe},
+		Fields:      fields,
+		Time:        bar.Timestamp,
+	})
+}
+
+// sample queues the portfolio and any baselines computed since the last
+// sample.
+func (w *Writer) sample() {
+	w.mu.Lock()
+	sources, policy, now := w.sources, w.policy, w.now()
+	w.mu.Unlock()
+
+	var points []Point
+	if policy.Portfolio && sources.Portfolio != nil {
+		p := sources.Portfolio()
+		points = append(points, Point{
+			Measurement: MeasurementPortfolio,
+			Fields: map[string]float64{
+				"balance":      p.Balance,
+				"total_value":  p.TotalValue,
+				"daily_pnl":    p.DailyPnL,
+				"daily_return": p.DailyReturn,
+				"positions":    float64(len(p.Positions)),
+			},
+			Time: now,
+		})
+		for symbol, pos := range p.Positions {
+			points = append(points, Point{
+				Measurement: MeasurementPosition,
+				Tags:        map[string]string{"symbol": symbol},
+				Fields: map[string]float64{
+					"quantity":     pos.Quantity,
+					"avg_price":    pos.AvgPrice,
+					"price":        pos.CurrentPrice,
+					"market_value": pos.MarketVal,
+					"profit":       pos.Profit,
+					"return":       pos.Return,
+				},
+				Time: now,
+			})
+		}
+	}
+
+	var baselines map[string]algorithm.SymbolBaseline
+	if policy.Indicators && sources.Baselines != nil {
+		baselines = sources.Baselines()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.sink == nil {
+		return
+	}
+	for _, b := range baselines {
+		if last, ok := w.lastAsOf[b.Symbol]; ok && !b.AsOf.After(last) {
+			continue
+		}
+		w.lastAsOf[b.Symbol] = b.AsOf
+		fields := map[string]float64{
+			"last_close":        b.LastClose,
+			"sma_20":            b.SMA20,
+			"atr_14":            b.ATR14,
+			"rsi_14":            b.RSI14,
+			"avg_volume_20":     b.AvgVolume20,
+			"annual_volatility": b.AnnualVolatility,
+		}
+		if b.SMA50 > 0 {
+			fields["sma_50"] = b.SMA50
+		}
+		points = append(points, Point{
+			Measurement: MeasurementIndicators,
+			Tags:        map[string]string{"symbol": b.Symbol},
+			Fields:      fields,
+			Time:        b.AsOf,
+		})
+	}
+	for _, p := range points {
+		w.addLocked(p)
+	}
+}
+
+// addLocked buffers a point, dropping non-finite fields, and asks for a
+// flush once a batch is ready.
+func (w *Writer) addLocked(p Point) {
+	for k, v := range p.Fields {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			delete(p.Fields, k)
+		}
+	}
+	if len(p.Fields) == 0 {
+		return
+	}
+	w.buf = append(w.buf, p)
+	w.trimLocked()
+	if len(w.buf) >= w.policy.BatchSize {
+		select {
+		case w.ready <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// trimLocked drops the oldest points beyond the buffer limit.
+func (w *Writer) trimLocked() {
+	if over := len(w.buf) - w.policy.MaxBuffer; over > 0 {
+		w.buf = append(w.buf[:0], w.buf[over:]...)
+		w.stats.Dropped += int64(over)
+	}
+}
+
+// Flush writes buffered points in batches until the buffer is empty or a
+// write fails. Failed points go back to the front of the buffer.
+func (w *Writer) Flush(ctx context.Context) error {
+	for {
+		w.mu.Lock()
+		if w.sink == nil || len(w.buf) == 0 {
+			w.mu.Unlock()
+			return nil
+		}
+		n := min(len(w.buf), w.policy.BatchSize)
+		batch := append([]Point(nil), w.buf[:n]...)
+		w.buf = w.buf[n:]
+		w.mu.Unlock()
+
+		err := w.sink.Write(ctx, batch)
+
+		w.mu.Lock()
+		if err != nil {
+			w.buf = append(batch, w.buf...)
+			w.trimLocked()
+			w.stats.Failures++
+			w.stats.LastError = err.Error()
+			w.mu.Unlock()
+			return err
+		}
+		now := w.now()
+		w.stats.Written += int64(n)
+		w.stats.LastWrite = &now
+		w.stats.LastError = ""
+		w.mu.Unlock()
+	}
+}
+
+// Run samples and flushes on the policy's interval, and as soon as a batch
+// fills, until ctx is done. A disabled writer returns at once.
+func (w *Writer) Run(ctx context.Context) {
+	if w.sink == nil {
+		return
+	}
+	failing := false
+	flush := func(ctx context.Context) {
+		err := w.Flush(ctx)
+		if err != nil && !failing {
+			logger().Warn("Time-series write failed; buffering", "backend", w.sink.Name(), "error", err)
+		} else if err == nil && failing {
+			logger().Info("Time-series writes recovered", "backend", w.sink.Name())
+		}
+		failing = err != nil
+	}
+	for {
+		// While the database is failing, wait out the interval rather
+		// than retrying each time a batch fills
+		ready := w.ready
+		if failing {
+			ready = nil
+		}
+		timer := time.NewTimer(time.Duration(w.Policy().IntervalSeconds) * time.Second)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			// One last attempt so a clean shutdown loses nothing
+			fctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			flush(fctx)
+			cancel()
+			return
+		case <-ready:
+			timer.Stop()
+		case <-timer.C:
+			w.sample()
+		}
+		flush(ctx)
+	}
+}
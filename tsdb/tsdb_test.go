@@ -0,0 +1,181 @@
+package tsdb
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+)
+
+// memSink records writes and fails while err is set.
+type memSink struct {
+	points []Point
+	err    error
+}
+
+func (m *memSink) Name() string { return "mem" }
+
+func (m *memSink) Write(_ context.Context, points []Point) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.points = append(m.points, points...)
+	return nil
+}
+
+func TestWriterBuffersThroughOutages(t *testing.T) {
+	sink := &memSink{}
+	policy := DefaultPolicy()
+	policy.BatchSize, policy.MaxBuffer = 2, 6
+	w, err := New(sink, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	w.SetClock(func() time.Time { return now })
+	w.SetSources(Sources{
+		Portfolio: func() algorithm.PortfolioData {
+			return algorithm.PortfolioData{Balance: 5000, TotalValue: 10000, DailyPnL: 25,
+				Positions: map[string]algorithm.PositionData{"AAPL": {Quantity: 10, CurrentPrice: 195}}}
+		},
+		Baselines: func() map[string]algorithm.SymbolBaseline {
+			return map[string]algorithm.SymbolBaseline{"AAPL": {Symbol: "AAPL", AsOf: now.Add(-24 * time.Hour), SMA20: 190, RSI14: 55}}
+		},
+	})
+
+	bar := algorithm.BarData{Symbol: "AAPL", Timestamp: now, Open: 1, High: 2, Low: 0.5, Close: 1.5, Volume: 100}
+	w.Bar("1Min", bar)
+	w.Bar("1Min", bar) // the poller sees the same bar again
+	w.sample()
+	w.sample() // the baseline has not changed
+	if got := w.Stats().Buffered; got != 6 {
+		t.Fatalf("buffered = %d, want a bar, two portfolio samples with a position each and the indicators once", got)
+	}
+
+	// An outage keeps the newest points up to the buffer limit, dropping
+	// the first bar
+	sink.err = errors.New("connection refused")
+	if err := w.Flush(context.Background()); err == nil {
+		t.Fatal("flush succeeded during the outage")
+	}
+	w.Bar("1Min", algorithm.BarData{Symbol: "AAPL", Timestamp: now.Add(time.Minute), Close: 1.6})
+	if s := w.Stats(); s.Buffered != 6 || s.Dropped != 1 || s.Failures != 1 || s.LastError != "connection refused" {
+		t.Fatalf("stats during the outage = %+v", s)
+	}
+
+	sink.err = nil
+	if err := w.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if s := w.Stats(); s.Buffered != 0 || s.Written != 6 || s.LastError != "" {
+		t.Fatalf("stats after recovery = %+v", s)
+	}
+	if sink.points[0].Measurement != MeasurementPortfolio || sink.points[5].Time != now.Add(time.Minute) {
+		t.Fatalf("points written out of order: %+v", sink.points)
+	}
+
+	// A disabled writer ignores everything
+	off, _ := New(nil, DefaultPolicy())
+	off.Bar("1Min", bar)
+	if s := off.Stats(); s.Enabled || s.Buffered != 0 {
+		t.Fatalf("disabled writer = %+v", s)
+	}
+}
+
+func TestInfluxWritesLineProtocol(t *testing.T) {
+	var body, query, auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		body, query, auth = string(b), r.URL.RawQuery, r.Header.Get("Authorization")
+		if strings.Contains(body, "bad") {
+			http.Error(w, `{"message":"partial write"}`, http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sink, err := NewInflux(srv.URL+"/", "desk", "trading", "tok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Unix(1700000000, 5)
+	err = sink.Write(context.Background(), []Point{
+		{Measurement: MeasurementBar, Tags: map[string]string{"symbol": "BRK B", "timeframe": "1Min"}, Fields: map[string]float64{"close": 412.5, "volume": 1200}, Time: at},
+		{Measurement: MeasurementPortfolio, Fields: map[string]float64{"total_value": 100000}, Time: at},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "bars,symbol=BRK\\ B,timeframe=1Min close=412.5,volume=1200 1700000000000000005\n" +
+		"portfolio total_value=100000 1700000000000000005\n"
+	if body != want {
+		t.Errorf("body = %q, want %q", body, want)
+	}
+	if query != "bucket=trading&org=desk&precision=ns" || auth != "Token tok" {
+		t.Errorf("query %q, auth %q", query, auth)
+	}
+	err = sink.Write(context.Background(), []Point{{Measurement: "bad", Fields: map[string]float64{"x": 1}, Time: at}})
+	if err == nil || !strings.Contains(err.Error(), "partial write") {
+		t.Errorf("rejected write: %v", err)
+	}
+
+	var buf bytes.Buffer
+	writeLine(&buf, Point{Measurement: "a,b", Tags: map[string]string{"k=1": "v,2", "empty": ""}, Fields: map[string]float64{"f": -0.25}, Time: time.Unix(0, 1)})
+	if got := buf.String(); got != "a\\,b,k\\=1=v\\,2 f=-0.25 1\n" {
+		t.Fatalf("escaped line = %q", got)
+	}
+}
+
+// execRecorder records statements instead of running them.
+type execRecorder struct {
+	queries []string
+	args    [][]interface{}
+}
+
+func (e *execRecorder) ExecContext(_ context.Context, query string, args ...interface{}) (sql.Result, error) {
+	e.queries = append(e.queries, query)
+	e.args = append(e.args, args)
+	return nil, nil
+}
+
+func TestTimescaleWritesOneRowPerField(t *testing.T) {
+	db := &execRecorder{}
+	if _, err := NewTimescale(db, "metrics; DROP TABLE x"); err == nil {
+		t.Fatal("accepted an unsafe table name")
+	}
+	sink, err := NewTimescale(db, "trader_metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Init(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.queries) != 3 || !strings.Contains(db.queries[1], "create_hypertable('trader_metrics'") {
+		t.Fatalf("init ran %q", db.queries)
+	}
+
+	at := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	err = sink.Write(context.Background(), []Point{
+		{Measurement: MeasurementIndicators, Tags: map[string]string{"symbol": "AAPL"}, Fields: map[string]float64{"sma_20": 190, "rsi_14": 55}, Time: at},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(db.queries) != 4 || !strings.HasPrefix(db.queries[3], "INSERT INTO trader_metrics") {
+		t.Fatalf("write ran %q", db.queries[3:])
+	}
+	args := db.args[3]
+	fields, values, tags := args[3].([]string), args[4].([]float64), args[2].([]string)
+	if len(fields) != 2 || fields[0] != "rsi_14" || values[0] != 55 || fields[1] != "sma_20" || tags[0] != `{"symbol":"AAPL"}` {
+		t.Fatalf("args = %+v", args)
+	}
+}
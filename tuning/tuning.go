@@ -0,0 +1,427 @@
+// Package tuning changes a running strategy's parameters through a
+// guarded rollout. The new parameters run as a shadow of the strategy's
+// algorithm instance for a number of signals, each scored by the return
+// to the next signal on the same symbol against the current parameters'
+// signal. A paired t-test on the score differences then commits the new
+// parameters when they did significantly better, or rolls them back.
+package tuning
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+func logger() *slog.Logger { return slog.With("module", "tuning") }
+
+// Trial states.
+const (
+	StateRunning    = "running"
+	StateCommitted  = "committed"
+	StateRolledBack = "rolled_back"
+	StateCanceled   = "canceled"
+)
+
+// Defaults for a trial request.
+const (
+	DefaultSignals   = 20
+	DefaultThreshold = 1.645 // one-sided 95%
+)
+
+// maxTrials bounds the finished trials kept per strategy.
+const maxTrials = 20
+
+var (
+	// ErrNotFound is returned for a strategy without a registered
+	// instance or without a running trial.
+	ErrNotFound = errors.New("not found")
+	// ErrTrialRunning is returned when starting a trial for a strategy
+	// that already has one.
+	ErrTrialRunning = errors.New("a trial is already running")
+	// ErrInvalid wraps every refusal of a trial request.
+	ErrInvalid = errors.New("invalid trial")
+)
+
+// Registry is the part of the algorithm instance registry trials use.
+type Registry interface {
+	Get(id string) (algorithm.AlgorithmInstance, bool)
+	Put(spec algorithm.InstanceSpec) (algorithm.AlgorithmInstance, error)
+	SetShadow(id string, params map[string]interface{}) (algorithm.AlgorithmInstance, error)
+	ClearShadow(id string)
+}
+
+// Notifier is told when a trial is decided.
+type Notifier func(title, message string, metadata map[string]interface{})
+
+// Request asks for new parameters to be tried on a strategy.
+type Request struct {
+	Parameters map[string]interface{} `json:"parameters"`
+	Signals    int                    `json:"signals"`   // scored signals before deciding; 0 means DefaultSignals
+	Threshold  float64                `json:"threshold"` // t statistic to commit at; 0 means DefaultThreshold
+}
+
+// Observation is one fresh run of the strategy with both parameter sets.
+// It is scored once the next run on the same symbol gives the return.
+type Observation struct {
+	Time      time.Time `json:"time"`
+	Symbol    string    `json:"symbol"`
+	Price     float64   `json:"price"`
+	Baseline  string    `json:"baseline"`  // the current parameters' signal
+	Candidate string    `json:"candidate"` // the new parameters' signal
+	Scored    bool      `json:"scored"`
+	// Return is the percent move to the next run; a signal scores it for
+	// a buy, its negative for a sell and nothing for a hold
+	Return         float64 `json:"return,omitempty"`
+	BaselineScore  float64 `json:"baseline_score,omitempty"`
+	CandidateScore float64 `json:"candidate_score,omitempty"`
+}
+
+// Verdict is the paired comparison of scored observations.
+type Verdict struct {
+	N              int     `json:"n"`
+	BaselineMean   float64 `json:"baseline_mean"`
+	CandidateMean  float64 `json:"candidate_mean"`
+	MeanDifference float64 `json:"mean_difference"` // candidate less baseline
+	StdDev         float64 `json:"std_dev"`         // of the differences
+	T              float64 `json:"t"`
+	Threshold      float64 `json:"threshold"`
+	Significant    bool    `json:"significant"` // the candidate did better at the threshold
+}
+
+// Trial is one guarded rollout of new parameters.
+type Trial struct {
+	ID           string             `json:"id"`
+	Strategy     string             `json:"strategy"` // the algorithm instance ID
+	State        string             `json:"state"`
+	Signals      int                `json:"signals"`
+	Threshold    float64            `json:"threshold"`
+	Baseline     map[string]float64 `json:"baseline"`
+	Candidate    map[string]float64 `json:"candidate"`
+	Observations []Observation      `json:"observations"`
+	Scored       int                `json:"scored"`
+	Verdict      *Verdict           `json:"verdict,omitempty"`
+	Reason       string             `json:"reason,omitempty"`
+	StartedAt    time.Time          `json:"started_at"`
+	DecidedAt    *time.Time         `json:"decided_at,omitempty"`
+}
+
+// Manager runs trials against a registry. It is safe for concurrent use.
+type Manager struct {
+	path     string
+	registry Registry
+
+	mu     sync.Mutex
+	trials []*Trial // oldest first
+	notify Notifier
+	now    func() time.Time
+}
+
+// New loads the trials saved at path. Trials that were running resume
+// their shadow, or are canceled when their instance is gone.
+func New(path string, registry Registry) (*Manager, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create tuning directory: %w", err)
+	}
+	m := &Manager{path: path, registry: registry, now: time.Now}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &m.trials); err != nil {
+			return nil, fmt.Errorf("failed to parse trials: %w", err)
+		}
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to read trials: %w", err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.trials {
+		if t.State != StateRunning {
+			continue
+		}
+		if _, err := registry.SetShadow(t.Strategy, parameters(t.Candidate)); err != nil {
+			m.finishLocked(t, StateCanceled, fmt.Sprintf("could not resume: %v", err))
+		}
+	}
+	return m, m.saveLocked()
+}
+
+// SetClock replaces the clock stamping observations and decisions.
+func (m *Manager) SetClock(now func() time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.now = now
+}
+
+// SetNotifier registers fn to hear about decided trials.
+func (m *Manager) SetNotifier(fn Notifier) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.notify = fn
+}
+
+// Start runs req's parameters as a shadow of strategy.
+func (m *Manager) Start(strategy string, req Request) (Trial, error) {
+	if req.Signals == 0 {
+		req.Signals = DefaultSignals
+	}
+	if req.Threshold == 0 {
+		req.Threshold = DefaultThreshold
+	}
+	switch {
+	case len(req.Parameters) == 0:
+		return Trial{}, fmt.Errorf("%w: parameters are required", ErrInvalid)
+	case req.Signals < 2:
+		return Trial{}, fmt.Errorf("%w: signals must be at least 2", ErrInvalid)
+	case req.Threshold < 0:
+		return Trial{}, fmt.Errorf("%w: threshold must not be negative", ErrInvalid)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.activeLocked(strategy) != nil {
+		return Trial{}, fmt.Errorf("%w for %s", ErrTrialRunning, strategy)
+	}
+	inst, ok := m.registry.Get(strategy)
+	if !ok {
+		return Trial{}, fmt.Errorf("%w: no algorithm instance %s", ErrNotFound, strategy)
+	}
+	shadow, err := m.registry.SetShadow(strategy, req.Parameters)
+	if err != nil {
+		return Trial{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	now := m.now()
+	t := &Trial{
+		ID:           fmt.Sprintf("%s-%d", strategy, now.UnixNano()),
+		Strategy:     strategy,
+		State:        StateRunning,
+		Signals:      req.Signals,
+		Threshold:    req.Threshold,
+		Baseline:     inst.Config.AdditionalParams,
+		Candidate:    shadow.Config.AdditionalParams,
+		Observations: []Observation{},
+		StartedAt:    now,
+	}
+	m.trials = append(m.trials, t)
+	if err := m.saveLocked(); err != nil {
+		m.trials = m.trials[:len(m.trials)-1]
+		m.registry.ClearShadow(strategy)
+		return Trial{}, err
+	}
+	logger().Info("Parameter trial started", "strategy", strategy, "signals", t.Signals, "candidate", t.Candidate)
+	return *t, nil
+}
+
+// Cancel rolls back strategy's running trial without a verdict.
+func (m *Manager) Cancel(strategy string) (Trial, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := m.activeLocked(strategy)
+	if t == nil {
+		return Trial{}, fmt.Errorf("%w: no trial running for %s", ErrNotFound, strategy)
+	}
+	m.finishLocked(t, StateCanceled, "canceled")
+	return *t, m.saveLocked()
+}
+
+// Trials returns strategy's trials, newest first.
+func (m *Manager) Trials(strategy string) []Trial {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := []Trial{}
+	for i := len(m.trials) - 1; i >= 0; i-- {
+		if m.trials[i].Strategy == strategy {
+			out = append(out, *m.trials[i])
+		}
+	}
+	return out
+}
+
+// Observe records a fresh run of a strategy on trial, scores the previous
+// run on the same symbol and decides the trial once enough are scored.
+// It is the instance registry's run handler.
+func (m *Manager) Observe(run *algorithm.InstanceRun, current *types.MarketData) {
+	if run == nil || run.Shadow == nil || run.Result.Result == nil || current == nil || current.Price <= 0 {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := m.activeLocked(run.Instance.ID)
+	if t == nil {
+		return
+	}
+	for i := len(t.Observations) - 1; i >= 0; i-- {
+		prev := &t.Observations[i]
+		if prev.Symbol != run.Symbol {
+			continue
+		}
+		if !prev.Scored && prev.Price > 0 {
+			prev.Return = (current.Price - prev.Price) / prev.Price * 100
+			prev.BaselineScore = direction(prev.Baseline) * prev.Return
+			prev.CandidateScore = direction(prev.Candidate) * prev.Return
+			prev.Scored = true
+			t.Scored++
+		}
+		break
+	}
+	t.Observations = append(t.Observations, Observation{
+		Time:      m.now(),
+		Symbol:    run.Symbol,
+		Price:     current.Price,
+		Baseline:  run.Result.Result.Signal,
+		Candidate: run.Shadow.Signal,
+	})
+	if t.Scored >= t.Signals {
+		m.decideLocked(t)
+	}
+	if err := m.saveLocked(); err != nil {
+		logger().Error("Failed to save trial", "strategy", t.Strategy, "error", err)
+	}
+}
+
+// Compare runs the paired t-test on the scored observations: the
+// candidate is significantly better when the mean score difference is at
+// least threshold standard errors above zero.
+func Compare(observations []Observation, threshold float64) Verdict {
+	v := Verdict{Threshold: threshold}
+	var diffs []float64
+	for _, o := range observations {
+		if !o.Scored {
+			continue
+		}
+		v.BaselineMean += o.BaselineScore
+		v.CandidateMean += o.CandidateScore
+		diffs = append(diffs, o.CandidateScore-o.BaselineScore)
+	}
+	v.N = len(diffs)
+	if v.N == 0 {
+		return v
+	}
+	n := float64(v.N)
+	v.BaselineMean /= n
+	v.CandidateMean /= n
+	v.MeanDifference = v.CandidateMean - v.BaselineMean
+	if v.N < 2 {
+		return v
+	}
+	var ss float64
+	for _, d := range diffs {
+		ss += (d - v.MeanDifference) * (d - v.MeanDifference)
+	}
+	v.StdDev = math.Sqrt(ss / (n - 1))
+	if v.StdDev == 0 {
+		// Identical differences: better only if they are all gains
+		v.Significant = v.MeanDifference > 0
+		return v
+	}
+	v.T = v.MeanDifference / (v.StdDev / math.Sqrt(n))
+	v.Significant = v.T >= threshold
+	return v
+}
+
+func (m *Manager) decideLocked(t *Trial) {
+	v := Compare(t.Observations, t.Threshold)
+	t.Verdict = &v
+	if !v.Significant {
+		m.finishLocked(t, StateRolledBack, fmt.Sprintf("t = %.2f below %.2f", v.T, t.Threshold))
+		return
+	}
+	inst, ok := m.registry.Get(t.Strategy)
+	if !ok {
+		m.finishLocked(t, StateCanceled, "algorithm instance no longer registered")
+		return
+	}
+	spec := inst.Spec()
+	spec.Parameters = parameters(t.Candidate)
+	if _, err := m.registry.Put(spec); err != nil {
+		m.finishLocked(t, StateRolledBack, fmt.Sprintf("commit failed: %v", err))
+		return
+	}
+	m.finishLocked(t, StateCommitted, fmt.Sprintf("t = %.2f at or above %.2f", v.T, t.Threshold))
+}
+
+// finishLocked ends t, stops its shadow and tells the notifier.
+func (m *Manager) finishLocked(t *Trial, state, reason string) {
+	now := m.now()
+	t.State, t.Reason, t.DecidedAt = state, reason, &now
+	m.registry.ClearShadow(t.Strategy)
+	m.pruneLocked(t.Strategy)
+	logger().Info("Parameter trial finished", "strategy", t.Strategy, "state", state, "reason", reason)
+	if m.notify != nil {
+		title := "Parameters " + strings.ReplaceAll(state, "_", " ")
+		m.notify(title, fmt.Sprintf("Trial of new parameters for %s: %s", t.Strategy, reason), map[string]interface{}{
+			"strategy":  t.Strategy,
+			"trial":     t.ID,
+			"state":     state,
+			"candidate": t.Candidate,
+			"verdict":   t.Verdict,
+		})
+	}
+}
+
+// pruneLocked drops strategy's oldest finished trials beyond maxTrials.
+func (m *Manager) pruneLocked(strategy string) {
+	finished := 0
+	for i := len(m.trials) - 1; i >= 0; i-- {
+		t := m.trials[i]
+		if t.Strategy != strategy || t.State == StateRunning {
+			continue
+		}
+		if finished++; finished > maxTrials {
+			m.trials = append(m.trials[:i], m.trials[i+1:]...)
+		}
+	}
+}
+
+func (m *Manager) activeLocked(strategy string) *Trial {
+	for _, t := range m.trials {
+		if t.Strategy == strategy && t.State == StateRunning {
+			return t
+		}
+	}
+	return nil
+}
+
+func (m *Manager) saveLocked() error {
+	data, err := json.MarshalIndent(m.trials, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode trials: %w", err)
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write trials: %w", err)
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		return fmt.Errorf("failed to save trials: %w", err)
+	}
+	return nil
+}
+
+// direction is +1 for a buy, -1 for a sell and 0 otherwise.
+func direction(signal string) float64 {
+	switch strings.ToLower(signal) {
+	case algorithm.SignalBuy:
+		return 1
+	case algorithm.SignalSell:
+		return -1
+	}
+	return 0
+}
+
+// parameters converts stored parameters to the form instance specs take.
+func parameters(params map[string]float64) map[string]interface{} {
+	out := make(map[string]interface{}, len(params))
+	for k, v := range params {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,199 @@
+package tuning
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rileyseaburg/go-trader/algorithm"
+	"github.com/rileyseaburg/go-trader/algorithm/algo"
+	"github.com/rileyseaburg/go-trader/types"
+)
+
+// fakeRegistry keeps one parameter set per instance and its shadow.
+type fakeRegistry struct {
+	params  map[string]map[string]float64
+	shadows map[string]map[string]float64
+}
+
+func newFakeRegistry() *fakeRegistry {
+	return &fakeRegistry{
+		params:  map[string]map[string]float64{"tb": {"stop_loss": 1}},
+		shadows: map[string]map[string]float64{},
+	}
+}
+
+func toFloats(params map[string]interface{}) map[string]float64 {
+	out := make(map[string]float64, len(params))
+	for k, v := range params {
+		out[k], _ = v.(float64)
+	}
+	return out
+}
+
+func (f *fakeRegistry) Get(id string) (algorithm.AlgorithmInstance, bool) {
+	p, ok := f.params[id]
+	return algorithm.AlgorithmInstance{ID: id, Config: algo.AlgorithmConfig{AdditionalParams: p}}, ok
+}
+
+func (f *fakeRegistry) Put(spec algorithm.InstanceSpec) (algorithm.AlgorithmInstance, error) {
+	f.params[spec.ID] = toFloats(spec.Parameters)
+	inst, _ := f.Get(spec.ID)
+	return inst, nil
+}
+
+func (f *fakeRegistry) SetShadow(id string, params map[string]interface{}) (algorithm.AlgorithmInstance, error) {
+	if _, ok := f.params[id]; !ok {
+		return algorithm.AlgorithmInstance{}, algorithm.ErrInstanceNotFound
+	}
+	if v, _ := params["stop_loss"].(float64); v <= 0 {
+		return algorithm.AlgorithmInstance{}, algorithm.ErrInvalidInstance
+	}
+	f.shadows[id] = toFloats(params)
+	return algorithm.AlgorithmInstance{ID: id, Config: algo.AlgorithmConfig{AdditionalParams: f.shadows[id]}}, nil
+}
+
+func (f *fakeRegistry) ClearShadow(id string) { delete(f.shadows, id) }
+
+// observe feeds one fresh run of tb on symbol at price.
+func observe(m *Manager, symbol string, price float64, baseline, candidate string) {
+	m.Observe(&algorithm.InstanceRun{
+		Instance: algorithm.AlgorithmInstance{ID: "tb"},
+		Symbol:   symbol,
+		Result:   algo.CachedResult{Result: &algo.AlgorithmResult{Signal: baseline}},
+		Shadow:   &algo.AlgorithmResult{Signal: candidate},
+	}, &types.MarketData{Symbol: symbol, Price: price})
+}
+
+func TestTrialCommitsSignificantImprovement(t *testing.T) {
+	reg := newFakeRegistry()
+	m, err := New(filepath.Join(t.TempDir(), "trials.json"), reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var notes []string
+	m.SetNotifier(func(title, _ string, _ map[string]interface{}) { notes = append(notes, title) })
+
+	if _, err := m.Start("missing", Request{Parameters: map[string]interface{}{"stop_loss": 2.0}}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unknown strategy err = %v", err)
+	}
+	if _, err := m.Start("tb", Request{Parameters: map[string]interface{}{"stop_loss": -2.0}}); !errors.Is(err, ErrInvalid) {
+		t.Errorf("invalid parameters err = %v", err)
+	}
+	trial, err := m.Start("tb", Request{Parameters: map[string]interface{}{"stop_loss": 2.0}, Signals: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trial.Baseline["stop_loss"] != 1 || trial.Candidate["stop_loss"] != 2 || reg.shadows["tb"] == nil {
+		t.Fatalf("trial = %+v", trial)
+	}
+	if _, err := m.Start("tb", Request{Parameters: map[string]interface{}{"stop_loss": 3.0}}); !errors.Is(err, ErrTrialRunning) {
+		t.Errorf("second trial err = %v", err)
+	}
+
+	// AAPL rises and MSFT falls: the candidate buys one and sells the
+	// other while the baseline holds. Interleaved symbols are scored
+	// against their own next run.
+	for i, p := range []float64{100, 101, 103} {
+		observe(m, "AAPL", p, algorithm.SignalHold, algorithm.SignalBuy)
+		observe(m, "MSFT", 50-float64(i), algorithm.SignalHold, algorithm.SignalSell)
+	}
+	got := m.Trials("tb")[0]
+	if got.State != StateCommitted || got.Verdict == nil || !got.Verdict.Significant || got.Verdict.N != 4 {
+		t.Fatalf("trial = %+v, verdict %+v", got, got.Verdict)
+	}
+	if reg.params["tb"]["stop_loss"] != 2 || reg.shadows["tb"] != nil {
+		t.Errorf("registry after commit = %+v, shadows %+v", reg.params, reg.shadows)
+	}
+	if len(notes) != 1 || notes[0] != "Parameters committed" {
+		t.Errorf("notifications = %v", notes)
+	}
+}
+
+func TestTrialRollsBackAndResumes(t *testing.T) {
+	reg := newFakeRegistry()
+	path := filepath.Join(t.TempDir(), "trials.json")
+	m, err := New(path, reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Start("tb", Request{Parameters: map[string]interface{}{"stop_loss": 2.0}, Signals: 3}); err != nil {
+		t.Fatal(err)
+	}
+	observe(m, "AAPL", 100, algorithm.SignalBuy, algorithm.SignalSell)
+
+	// A restart resumes the running trial's shadow
+	delete(reg.shadows, "tb")
+	m, err = New(path, reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reg.shadows["tb"] == nil || len(m.Trials("tb")[0].Observations) != 1 {
+		t.Fatalf("trial not resumed: %+v", m.Trials("tb"))
+	}
+
+	// Mixed results are not significant
+	for _, p := range []float64{102, 101, 103} {
+		observe(m, "AAPL", p, algorithm.SignalBuy, algorithm.SignalSell)
+	}
+	got := m.Trials("tb")[0]
+	if got.State != StateRolledBack || reg.params["tb"]["stop_loss"] != 1 || reg.shadows["tb"] != nil {
+		t.Errorf("trial = %+v, registry %+v", got, reg.params)
+	}
+
+	// A trial whose instance is gone is canceled on restart
+	if _, err := m.Start("tb", Request{Parameters: map[string]interface{}{"stop_loss": 2.0}}); err != nil {
+		t.Fatal(err)
+	}
+	delete(reg.params, "tb")
+	if m, err = New(path, reg); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Trials("tb"); len(got) != 2 || got[0].State != StateCanceled {
+		t.Errorf("trials = %+v", got)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	obs := func(base, cand float64) Observation {
+		return Observation{Scored: true, BaselineScore: base, CandidateScore: cand}
+	}
+	v := Compare([]Observation{obs(0, 1), obs(0, 2), obs(0, 1.5), {Baseline: "buy"}}, DefaultThreshold)
+	if v.N != 3 || v.MeanDifference != 1.5 || v.StdDev != 0.5 || v.T < 5.19 || v.T > 5.2 || !v.Significant {
+		t.Errorf("verdict = %+v", v)
+	}
+	if v := Compare([]Observation{obs(1, 1), obs(2, 2)}, DefaultThreshold); v.Significant || v.T != 0 {
+		t.Errorf("identical configs verdict = %+v", v)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	m, err := New(filepath.Join(t.TempDir(), "trials.json"), newFakeRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	NewHandler(m).RegisterRoutes(mux)
+	for _, c := range []struct {
+		method, path, body string
+		code               int
+		contains           string
+	}{
+		{"POST", "/api/strategies/tb/tune", `{"parameters":{"stop_loss":2}}`, 201, `"state":"running"`},
+		{"POST", "/api/strategies/tb/tune", `{"parameters":{"stop_loss":3}}`, 409, ``},
+		{"POST", "/api/strategies/nope/tune", `{"parameters":{"stop_loss":3}}`, 404, ``},
+		{"POST", "/api/strategies/tb/tune", `{`, 400, ``},
+		{"GET", "/api/strategies/tb/tune", ``, 200, `"signals":20`},
+		{"DELETE", "/api/strategies/tb/tune", ``, 200, `"state":"canceled"`},
+		{"DELETE", "/api/strategies/tb/tune", ``, 404, ``},
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(c.method, c.path, strings.NewReader(c.body)))
+		if rec.Code != c.code || !strings.Contains(rec.Body.String(), c.contains) {
+			t.Errorf("%s %s = %d %s, want %d containing %s", c.method, c.path, rec.Code, rec.Body.String(), c.code, c.contains)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package universe
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// Handler exposes the universes over HTTP.
+type Handler struct {
+	manager *Manager
+}
+
+// NewHandler creates a handler for manager.
+func NewHandler(manager *Manager) *Handler {
+	return &Handler{manager: manager}
+}
+
+// RegisterRoutes registers the universe routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/universes - every universe's size, version, source and last refresh
+	mux.HandleFunc("/api/universes", h.handleList)
+
+	// GET/POST /api/universes/{name}?version= - a universe's symbols, or create or replace its definition
+	mux.HandleFunc("/api/universes/{name}", h.handleUniverse)
+
+	// GET /api/universes/{name}/versions - every version, newest first, with what it added and removed
+	mux.HandleFunc("/api/universes/{name}/versions", h.handleVersions)
+
+	// GET /api/universes/{name}/diff?from=&to=&against= - symbols added and removed between versions or universes
+	mux.HandleFunc("/api/universes/{name}/diff", h.handleDiff)
+
+	// POST /api/universes/{name}/refresh - read the universe's source now
+	mux.HandleFunc("/api/universes/{name}/refresh", h.handleRefresh)
+}
+
+// writeError maps an error to its status.
+func writeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusBadRequest)
+}
+
+// versionParam parses an optional version query parameter, 0 when unset.
+func versionParam(r *http.Request, name string) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		return 0, errors.New(name + " must be a positive integer")
+	}
+	return n, nil
+}
+
+func (h *Handler) handleList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(h.manager.List())
+}
+
+func (h *Handler) handleUniverse(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	switch r.Method {
+	case http.MethodGet:
+		version, err := versionParam(r, "version")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if version > 0 {
+			v, err := h.manager.At(name, version)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			json.NewEncoder(w).Encode(v)
+			return
+		}
+		u, ok := h.manager.Get(name)
+		if !ok {
+			http.Error(w, ErrNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(u)
+	case http.MethodPost, http.MethodPut:
+		var u Universe
+		if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		u.Name = name
+		saved, err := h.manager.Put(u)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(saved)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleVersions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	versions, err := h.manager.Versions(r.PathValue("name"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(versions)
+}
+
+// handleDiff compares name at from with against, or name, at to. Within
+// one universe from defaults to the version before to, and to to the
+// current one.
+func (h *Handler) handleDiff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := r.PathValue("name")
+	from, err := versionParam(r, "from")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := versionParam(r, "to")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	other := r.URL.Query().Get("against")
+	if other == "" {
+		other = name
+		if from == 0 {
+			cur, err := h.manager.At(name, to)
+			if err != nil {
+				writeError(w, err)
+				return
+			}
+			if from = cur.Version - 1; from < 1 {
+				from = cur.Version
+			}
+		}
+	}
+	d, err := h.manager.Compare(name, from, other, to)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	json.NewEncoder(w).Encode(d)
+}
+
+func (h *Handler) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	u, err := h.manager.Refresh(r.Context(), r.PathValue("name"))
+	if err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrNoSource) {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusBadGateway)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error(), "universe": u.summary()})
+		return
+	}
+	json.NewEncoder(w).Encode(u.summary())
+}
@@ -0,0 +1,149 @@
+package universe
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// maxBody bounds a source's response.
+const maxBody = 8 << 20
+
+// HTTP reads sources over HTTP.
+type HTTP struct {
+	Client *http.Client
+}
+
+// NewHTTP builds a fetcher with a sensible default timeout.
+func NewHTTP() *HTTP {
+	return &HTTP{Client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Fetch implements Fetcher.
+func (h *HTTP) Fetch(ctx context.Context, src Source) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("source returned status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data, src)
+}
+
+// Parse reads symbols from a source's content: a CSV with a header row, a
+// JSON array of symbols or of objects, or one symbol per line with #
+// comments.
+func Parse(data []byte, src Source) ([]string, error) {
+	field := strings.ToLower(src.Field)
+	if field == "" {
+		field = "symbol"
+	}
+	switch format(src) {
+	case "csv":
+		return parseCSV(data, field)
+	case "json":
+		return parseJSON(data, field)
+	}
+	var out []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line != "" {
+			out = append(out, line)
+		}
+	}
+	return out, scanner.Err()
+}
+
+// format is the source's format, or the one its URL's extension names.
+func format(src Source) string {
+	if src.Format != "" {
+		return src.Format
+	}
+	if u, err := url.Parse(src.URL); err == nil {
+		switch strings.ToLower(path.Ext(u.Path)) {
+		case ".csv":
+			return "csv"
+		case ".json":
+			return "json"
+		}
+	}
+	return "lines"
+}
+
+func parseCSV(data []byte, field string) ([]string, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	col := -1
+	for i, name := range records[0] {
+		if strings.ToLower(strings.TrimSpace(name)) == field {
+			col = i
+			break
+		}
+	}
+	if col < 0 {
+		return nil, fmt.Errorf("CSV has no %s column", field)
+	}
+	var out []string
+	for _, rec := range records[1:] {
+		if col < len(rec) {
+			out = append(out, rec[col])
+		}
+	}
+	return out, nil
+}
+
+func parseJSON(data []byte, field string) ([]string, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("invalid JSON: expected an array: %w", err)
+	}
+	var out []string
+	for _, item := range items {
+		var s string
+		if json.Unmarshal(item, &s) == nil {
+			out = append(out, s)
+			continue
+		}
+		var obj map[string]interface{}
+		if err := json.Unmarshal(item, &obj); err != nil {
+			return nil, fmt.Errorf("invalid JSON: expected symbols or objects: %w", err)
+		}
+		for k, v := range obj {
+			if s, ok := v.(string); ok && strings.ToLower(k) == field {
+				out = append(out, s)
+				break
+			}
+		}
+	}
+	return out, nil
+}
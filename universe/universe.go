@@ -0,0 +1,649 @@
+// Package universe keeps named symbol universes — index constituents,
+// sector lists — as definition files in one directory, so the modules
+// that measure or scan a set of symbols share one list. A universe with a
+// source is refreshed from it on a schedule, and every change to its
+// constituents, from a refresh, an edit of the file or the API, is kept as
+// a numbered version that can be read back and diffed.
+package universe
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+func logger() *slog.Logger { return slog.With("module", "universe") }
+
+// Origins of a version.
+const (
+	OriginFile    = "file"    // the definition file was edited
+	OriginRefresh = "refresh" // fetched from the universe's source
+	OriginAPI     = "api"
+)
+
+// maxSymbols bounds a universe.
+const maxSymbols = 5000
+
+// maxRemoved is the share of constituents a refresh may drop. A source
+// answering with far fewer names is more likely broken than the index
+// reconstituted, so the refresh is refused and the universe kept.
+const maxRemoved = 0.5
+
+// ErrNotFound is returned for a universe or version that does not exist.
+var ErrNotFound = errors.New("universe not found")
+
+// ErrNoSource is returned when refreshing a universe without a source, or
+// without a fetcher to read it.
+var ErrNoSource = errors.New("universe cannot be refreshed")
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Source is where a universe's constituents are refreshed from.
+type Source struct {
+	URL string `json:"url"`
+	// Format is csv, json or lines; by default it follows the URL's
+	// extension, and lines otherwise
+	Format string `json:"format,omitempty"`
+	// Field is the CSV column or the JSON object key holding the symbol,
+	// symbol by default, matched case-insensitively
+	Field string `json:"field,omitempty"`
+	// RefreshHours is the least time between reads of the source, a day
+	// by default; due sources are read before each session opens
+	RefreshHours int `json:"refresh_hours,omitempty"`
+}
+
+// Universe is one definition file, data/universes/<name>.json.
+type Universe struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Symbols     []string `json:"symbols"`
+	Source      *Source  `json:"source,omitempty"`
+	// Version is the version the symbols are, kept by the manager
+	Version     int       `json:"version"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	RefreshedAt time.Time `json:"refreshed_at,omitempty"` // last read of the source
+	LastError   string    `json:"last_error,omitempty"`   // of the last refresh
+}
+
+// Summary describes a universe without its symbols.
+type Summary struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Size        int       `json:"size"`
+	Version     int       `json:"version"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Source      *Source   `json:"source,omitempty"`
+	RefreshedAt time.Time `json:"refreshed_at,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// Version is one set of a universe's constituents.
+type Version struct {
+	Version int       `json:"version"`
+	Time    time.Time `json:"time"`
+	Origin  string    `json:"origin"`
+	Symbols []string  `json:"symbols"`
+	Added   []string  `json:"added,omitempty"` // against the version before
+	Removed []string  `json:"removed,omitempty"`
+}
+
+// Diff is the difference between two sets of constituents.
+type Diff struct {
+	From      string   `json:"from"` // name@version
+	To        string   `json:"to"`
+	Added     []string `json:"added"` // in To only
+	Removed   []string `json:"removed"`
+	Unchanged int      `json:"unchanged"`
+}
+
+// Fetcher reads constituents from a source.
+type Fetcher interface {
+	Fetch(ctx context.Context, src Source) ([]string, error)
+}
+
+// Validate checks the definition.
+func (u Universe) Validate() error {
+	if !validName.MatchString(u.Name) {
+		return errors.New("name must be lower-case letters, digits, - or _, at most 64 characters")
+	}
+	if n := len(normalize(u.Symbols)); n == 0 && u.Source == nil {
+		return errors.New("a universe needs symbols or a source")
+	} else if n > maxSymbols {
+		return fmt.Errorf("a universe holds at most %d symbols", maxSymbols)
+	}
+	if s := u.Source; s != nil {
+		if !strings.HasPrefix(s.URL, "http://") && !strings.HasPrefix(s.URL, "https://") {
+			return errors.New("source url must be http or https")
+		}
+		switch s.Format {
+		case "", "csv", "json", "lines":
+		default:
+			return errors.New("source format must be csv, json or lines")
+		}
+		if s.RefreshHours < 0 || s.RefreshHours > 24*31 {
+			return errors.New("source refresh_hours must be between 0 and 744")
+		}
+	}
+	return nil
+}
+
+func (u Universe) summary() Summary {
+	return Summary{Name: u.Name, Description: u.Description, Size: len(u.Symbols), Version: u.Version, UpdatedAt: u.UpdatedAt,
+		Source: u.Source, RefreshedAt: u.RefreshedAt, LastError: u.LastError}
+}
+
+// refreshEvery is how often the universe's source is due.
+func (u Universe) refreshEvery() time.Duration {
+	if u.Source == nil || u.Source.RefreshHours == 0 {
+		return 24 * time.Hour
+	}
+	return time.Duration(u.Source.RefreshHours) * time.Hour
+}
+
+// Manager loads the universes in a directory and keeps their versions. It
+// is safe for concurrent use.
+type Manager struct {
+	dir string
+
+	mu        sync.Mutex
+	universes map[string]*Universe
+	fetcher   Fetcher
+	onChange  func(Universe)
+	now       func() time.Time
+}
+
+// New loads the universes in dir, recording a version for any whose file
+// was edited since it was last read.
+func New(dir string) (*Manager, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "versions"), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create universes directory: %w", err)
+	}
+	m := &Manager{dir: dir, universes: make(map[string]*Universe), now: time.Now}
+	if err := m.Reload(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SetClock replaces the clock, for tests.
+func (m *Manager) SetClock(now func() time.Time) { m.mu.Lock(); m.now = now; m.mu.Unlock() }
+
+// SetFetcher sets how sources are read. Without one universes are only
+// changed by their files and the API.
+func (m *Manager) SetFetcher(f Fetcher) { m.mu.Lock(); m.fetcher = f; m.mu.Unlock() }
+
+// SetOnChange registers fn to receive a universe whenever its
+// constituents change.
+func (m *Manager) SetOnChange(fn func(Universe)) { m.mu.Lock(); m.onChange = fn; m.mu.Unlock() }
+
+// Reload reads every definition file in the directory. Files that fail to
+// parse or validate are logged and skipped, keeping what was loaded
+// before.
+func (m *Manager) Reload() error {
+	paths, err := filepath.Glob(filepath.Join(m.dir, "*.json"))
+	if err != nil {
+		return fmt.Errorf("failed to list universes: %w", err)
+	}
+	var changed []Universe
+	seen := make(map[string]bool, len(paths))
+	m.mu.Lock()
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		seen[name] = true
+		data, err := os.ReadFile(path)
+		if err != nil {
+			logger().Warn("Failed to read universe", "path", path, "error", err)
+			continue
+		}
+		var u Universe
+		if err := json.Unmarshal(data, &u); err != nil {
+			logger().Warn("Skipping malformed universe", "path", path, "error", err)
+			continue
+		}
+		if u.Name == "" {
+			u.Name = name
+		}
+		if err := u.Validate(); err != nil || u.Name != name {
+			logger().Warn("Skipping invalid universe", "path", path, "error", err, "name", u.Name)
+			continue
+		}
+		u.Symbols = normalize(u.Symbols)
+		last, err := m.lastVersion(name)
+		if err != nil {
+			logger().Warn("Failed to read universe versions", "universe", name, "error", err)
+			continue
+		}
+		if last == nil || !equal(last.Symbols, u.Symbols) {
+			if len(u.Symbols) > 0 || last != nil {
+				if err := m.recordLocked(&u, last, OriginFile); err != nil {
+					logger().Warn("Failed to version universe", "universe", name, "error", err)
+					continue
+				}
+				changed = append(changed, u)
+			}
+		} else {
+			u.Version = last.Version
+		}
+		m.universes[name] = &u
+	}
+	// A deleted file removes its universe; its versions are kept
+	for name := range m.universes {
+		if !seen[name] {
+			delete(m.universes, name)
+		}
+	}
+	onChange := m.onChange
+	m.mu.Unlock()
+	if onChange != nil {
+		for _, u := range changed {
+			onChange(u)
+		}
+	}
+	return nil
+}
+
+// Seed writes u as a definition file unless a universe of its name
+// exists, so defaults can be provided without overwriting edits.
+func (m *Manager) Seed(u Universe) error {
+	m.mu.Lock()
+	_, exists := m.universes[u.Name]
+	m.mu.Unlock()
+	if exists {
+		return nil
+	}
+	_, err := m.Put(u)
+	return err
+}
+
+// Put creates or replaces a universe's definition. A change of symbols is
+// recorded as a new version; a definition without symbols keeps those the
+// universe has, so a source can be changed without clearing them.
+func (m *Manager) Put(u Universe) (Universe, error) {
+	if err := u.Validate(); err != nil {
+		return Universe{}, err
+	}
+	u.Symbols = normalize(u.Symbols)
+	m.mu.Lock()
+	if prev, ok := m.universes[u.Name]; ok {
+		if len(u.Symbols) == 0 {
+			u.Symbols = prev.Symbols
+		}
+		u.Version, u.UpdatedAt, u.RefreshedAt, u.LastError = prev.Version, prev.UpdatedAt, prev.RefreshedAt, prev.LastError
+	} else {
+		u.Version, u.UpdatedAt, u.RefreshedAt, u.LastError = 0, time.Time{}, time.Time{}, ""
+	}
+	last, err := m.lastVersion(u.Name)
+	if err != nil {
+		m.mu.Unlock()
+		return Universe{}, err
+	}
+	changed := (last == nil && len(u.Symbols) > 0) || (last != nil && !equal(last.Symbols, u.Symbols))
+	if changed {
+		if err := m.recordLocked(&u, last, OriginAPI); err != nil {
+			m.mu.Unlock()
+			return Universe{}, err
+		}
+	}
+	if err := m.saveLocked(&u); err != nil {
+		m.mu.Unlock()
+		return Universe{}, err
+	}
+	m.universes[u.Name] = &u
+	onChange := m.onChange
+	m.mu.Unlock()
+	if changed && onChange != nil {
+		onChange(u)
+	}
+	return clone(u), nil
+}
+
+// List summarizes every universe, by name.
+func (m *Manager) List() []Summary {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Summary, 0, len(m.universes))
+	for _, u := range m.universes {
+		out = append(out, u.summary())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Get returns a universe.
+func (m *Manager) Get(name string) (Universe, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.universes[name]
+	if !ok {
+		return Universe{}, false
+	}
+	return clone(*u), true
+}
+
+// Symbols returns a universe's constituents, or nil when it does not
+// exist.
+func (m *Manager) Symbols(name string) []string {
+	u, ok := m.Get(name)
+	if !ok {
+		return nil
+	}
+	return u.Symbols
+}
+
+// Versions returns a universe's versions, newest first.
+func (m *Manager) Versions(name string) ([]Version, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.universes[name]; !ok {
+		return nil, ErrNotFound
+	}
+	out := []Version{}
+	err := m.readVersions(name, func(v Version) { out = append(out, v) })
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out, err
+}
+
+// At returns version v of a universe's constituents; 0 is the current
+// version.
+func (m *Manager) At(name string, v int) (Version, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.universes[name]
+	if !ok {
+		return Version{}, ErrNotFound
+	}
+	if v == 0 {
+		v = u.Version
+	}
+	var found *Version
+	err := m.readVersions(name, func(got Version) {
+		if got.Version == v {
+			found = &got
+		}
+	})
+	if err != nil {
+		return Version{}, err
+	}
+	if found == nil {
+		return Version{}, fmt.Errorf("%w: %s has no version %d", ErrNotFound, name, v)
+	}
+	return *found, nil
+}
+
+// Compare diffs two universes, or two versions of one; version 0 is the
+// current one.
+func (m *Manager) Compare(from string, fromVersion int, to string, toVersion int) (Diff, error) {
+	a, err := m.At(from, fromVersion)
+	if err != nil {
+		return Diff{}, err
+	}
+	b, err := m.At(to, toVersion)
+	if err != nil {
+		return Diff{}, err
+	}
+	added, removed := diff(a.Symbols, b.Symbols)
+	return Diff{
+		From:      fmt.Sprintf("%s@%d", from, a.Version),
+		To:        fmt.Sprintf("%s@%d", to, b.Version),
+		Added:     added,
+		Removed:   removed,
+		Unchanged: len(b.Symbols) - len(added),
+	}, nil
+}
+
+// Refresh reads a universe's source and records a version if its
+// constituents changed. The outcome is kept on the universe either way.
+func (m *Manager) Refresh(ctx context.Context, name string) (Universe, error) {
+	m.mu.Lock()
+	u, ok := m.universes[name]
+	fetcher := m.fetcher
+	m.mu.Unlock()
+	if !ok {
+		return Universe{}, ErrNotFound
+	}
+	if u.Source == nil {
+		return Universe{}, fmt.Errorf("%w: %s has no source", ErrNoSource, name)
+	}
+	if fetcher == nil {
+		return Universe{}, fmt.Errorf("%w: no fetcher configured", ErrNoSource)
+	}
+	symbols, err := fetcher.Fetch(ctx, *u.Source)
+	symbols = normalize(symbols)
+	if err == nil && len(symbols) == 0 {
+		err = errors.New("source returned no symbols")
+	}
+	if err == nil && len(symbols) > maxSymbols {
+		err = fmt.Errorf("source returned %d symbols, more than %d", len(symbols), maxSymbols)
+	}
+
+	m.mu.Lock()
+	u, ok = m.universes[name]
+	if !ok {
+		m.mu.Unlock()
+		return Universe{}, ErrNotFound
+	}
+	next := clone(*u)
+	next.RefreshedAt = m.now()
+	changed := false
+	if err == nil {
+		if _, removed := diff(next.Symbols, symbols); len(next.Symbols) > 0 && float64(len(removed)) > maxRemoved*float64(len(next.Symbols)) {
+			err = fmt.Errorf("source would remove %d of %d symbols; keeping the current list", len(removed), len(next.Symbols))
+		}
+	}
+	if err == nil && !equal(next.Symbols, symbols) {
+		var last *Version
+		if last, err = m.lastVersion(name); err == nil {
+			next.Symbols = symbols
+			err = m.recordLocked(&next, last, OriginRefresh)
+			changed = err == nil
+		}
+		if err != nil {
+			next.Symbols = u.Symbols
+		}
+	}
+	next.LastError = ""
+	if err != nil {
+		next.LastError = err.Error()
+	}
+	if saveErr := m.saveLocked(&next); saveErr != nil && err == nil {
+		err = saveErr
+	}
+	m.universes[name] = &next
+	onChange := m.onChange
+	m.mu.Unlock()
+
+	if err != nil {
+		return clone(next), fmt.Errorf("refresh universe %s: %w", name, err)
+	}
+	logger().Info("Refreshed universe", "universe", name, "symbols", len(next.Symbols), "version", next.Version, "changed", changed)
+	if changed && onChange != nil {
+		onChange(next)
+	}
+	return clone(next), nil
+}
+
+// RefreshDue reloads the definition files and refreshes every universe
+// whose source is due. Failures are logged and the first returned once
+// the rest are done.
+func (m *Manager) RefreshDue(ctx context.Context) error {
+	if err := m.Reload(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	now := m.now()
+	var due []string
+	for name, u := range m.universes {
+		if u.Source != nil && now.Sub(u.RefreshedAt) >= u.refreshEvery() {
+			due = append(due, name)
+		}
+	}
+	m.mu.Unlock()
+	sort.Strings(due)
+	var first error
+	for _, name := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if _, err := m.Refresh(ctx, name); err != nil {
+			logger().Warn("Universe refresh failed", "universe", name, "error", err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// recordLocked appends u's symbols as the version after last and stamps u
+// with it.
+func (m *Manager) recordLocked(u *Universe, last *Version, origin string) error {
+	v := Version{Version: 1, Time: m.now(), Origin: origin, Symbols: u.Symbols}
+	if last != nil {
+		v.Version = last.Version + 1
+		v.Added, v.Removed = diff(last.Symbols, u.Symbols)
+	}
+	line, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode universe version: %w", err)
+	}
+	f, err := os.OpenFile(m.versionsPath(u.Name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open universe versions: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write universe version: %w", err)
+	}
+	u.Version, u.UpdatedAt = v.Version, v.Time
+	if origin == OriginFile {
+		// Stamp the edited file with its version
+		return m.saveLocked(u)
+	}
+	return nil
+}
+
+func (m *Manager) versionsPath(name string) string {
+	return filepath.Join(m.dir, "versions", name+".jsonl")
+}
+
+// readVersions calls fn with each of a universe's versions, oldest first.
+func (m *Manager) readVersions(name string, fn func(Version)) error {
+	f, err := os.Open(m.versionsPath(name))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("failed to open universe versions: %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var v Version
+		if err := json.Unmarshal(scanner.Bytes(), &v); err != nil {
+			logger().Warn("Skipping malformed universe version", "universe", name, "error", err)
+			continue
+		}
+		fn(v)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read universe versions: %w", err)
+	}
+	return nil
+}
+
+// lastVersion returns a universe's newest version, or nil before its
+// first.
+func (m *Manager) lastVersion(name string) (*Version, error) {
+	var last *Version
+	err := m.readVersions(name, func(v Version) { last = &v })
+	return last, err
+}
+
+func (m *Manager) saveLocked(u *Universe) error {
+	data, err := json.MarshalIndent(u, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode universe: %w", err)
+	}
+	path := filepath.Join(m.dir, u.Name+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write universe: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to save universe: %w", err)
+	}
+	return nil
+}
+
+// normalize upper-cases symbols, drops blanks and duplicates and sorts
+// them.
+func normalize(symbols []string) []string {
+	seen := make(map[string]bool, len(symbols))
+	out := make([]string, 0, len(symbols))
+	for _, s := range symbols {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		if s != "" && !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// diff returns the symbols of b not in a and of a not in b.
+func diff(a, b []string) (added, removed []string) {
+	in := func(list []string) map[string]bool {
+		set := make(map[string]bool, len(list))
+		for _, s := range list {
+			set[s] = true
+		}
+		return set
+	}
+	inA, inB := in(a), in(b)
+	added, removed = []string{}, []string{}
+	for _, s := range b {
+		if !inA[s] {
+			added = append(added, s)
+		}
+	}
+	for _, s := range a {
+		if !inB[s] {
+			removed = append(removed, s)
+		}
+	}
+	return added, removed
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func clone(u Universe) Universe {
+	u.Symbols = append([]string(nil), u.Symbols...)
+	if u.Source != nil {
+		src := *u.Source
+		u.Source = &src
+	}
+	return u
+}
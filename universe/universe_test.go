@@ -0,0 +1,214 @@
+package universe
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type stubFetcher struct {
+	symbols []string
+	err     error
+	calls   int
+}
+
+func (f *stubFetcher) Fetch(_ context.Context, _ Source) ([]string, error) {
+	f.calls++
+	return f.symbols, f.err
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileEditsAreVersioned(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "tech.json", `{"description": "Large-cap tech", "symbols": ["msft", "AAPL", " nvda", "AAPL"]}`)
+	writeFile(t, dir, "Bad Name.json", `{"symbols": ["SPY"]}`)
+	writeFile(t, dir, "broken.json", `{"symbols": [`)
+
+	m, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := m.List(); len(list) != 1 || list[0].Name != "tech" || list[0].Size != 3 || list[0].Version != 1 {
+		t.Fatalf("list = %+v", list)
+	}
+	if got := m.Symbols("tech"); !reflect.DeepEqual(got, []string{"AAPL", "MSFT", "NVDA"}) {
+		t.Errorf("symbols = %v", got)
+	}
+
+	// Reopening an unedited file records nothing new
+	if m, err = New(dir); err != nil {
+		t.Fatal(err)
+	}
+	var changes []Universe
+	m.SetOnChange(func(u Universe) { changes = append(changes, u) })
+	writeFile(t, dir, "tech.json", `{"symbols": ["AAPL", "MSFT", "AVGO"]}`)
+	if err := m.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Version != 2 {
+		t.Fatalf("changes = %+v", changes)
+	}
+	versions, _ := m.Versions("tech")
+	if len(versions) != 2 || versions[0].Origin != OriginFile || !reflect.DeepEqual(versions[0].Added, []string{"AVGO"}) || !reflect.DeepEqual(versions[0].Removed, []string{"NVDA"}) {
+		t.Fatalf("versions = %+v", versions)
+	}
+	if old, err := m.At("tech", 1); err != nil || len(old.Symbols) != 3 || old.Symbols[2] != "NVDA" {
+		t.Errorf("version 1 = %+v, %v", old, err)
+	}
+	if _, err := m.At("tech", 7); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing version: %v", err)
+	}
+
+	// Another universe diffed against this one
+	if _, err := m.Put(Universe{Name: "chips", Symbols: []string{"NVDA", "AVGO", "AMD"}}); err != nil {
+		t.Fatal(err)
+	}
+	d, err := m.Compare("tech", 0, "chips", 0)
+	if err != nil || d.From != "tech@2" || d.To != "chips@1" || !reflect.DeepEqual(d.Added, []string{"AMD", "NVDA"}) || d.Unchanged != 1 {
+		t.Errorf("diff = %+v, %v", d, err)
+	}
+	if _, err := m.Put(Universe{Name: "empty"}); err == nil {
+		t.Error("universe without symbols or source accepted")
+	}
+
+	// A deleted file drops its universe
+	os.Remove(filepath.Join(dir, "chips.json"))
+	m.Reload()
+	if _, ok := m.Get("chips"); ok {
+		t.Error("deleted universe still loaded")
+	}
+}
+
+func TestRefreshFromSource(t *testing.T) {
+	dir := t.TempDir()
+	m, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 9, 16, 8, 0, 0, 0, time.UTC)
+	m.SetClock(func() time.Time { return now })
+	if _, err := m.Put(Universe{Name: "dow", Source: &Source{URL: "https://example.com/dow.csv", RefreshHours: 24}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Refresh(context.Background(), "dow"); !errors.Is(err, ErrNoSource) {
+		t.Errorf("refresh without a fetcher: %v", err)
+	}
+
+	f := &stubFetcher{symbols: []string{"AAPL", "MSFT", "JPM", "KO"}}
+	m.SetFetcher(f)
+	if err := m.RefreshDue(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	u, _ := m.Get("dow")
+	if f.calls != 1 || u.Version != 1 || len(u.Symbols) != 4 || !u.RefreshedAt.Equal(now) {
+		t.Fatalf("after refresh = %+v", u)
+	}
+	// Not due again until a day has passed
+	now = now.Add(time.Hour)
+	m.RefreshDue(context.Background())
+	if f.calls != 1 {
+		t.Errorf("refreshed before due: %d calls", f.calls)
+	}
+
+	// A source that loses most of the list is refused
+	now = now.Add(24 * time.Hour)
+	f.symbols = []string{"AAPL"}
+	if err := m.RefreshDue(context.Background()); err == nil {
+		t.Fatal("refresh dropping three of four symbols accepted")
+	}
+	u, _ = m.Get("dow")
+	if len(u.Symbols) != 4 || u.Version != 1 || !strings.Contains(u.LastError, "remove 3 of 4") {
+		t.Errorf("after refused refresh = %+v", u)
+	}
+
+	// A reconstitution is versioned and clears the error, and survives a
+	// reopen
+	f.symbols = []string{"AAPL", "MSFT", "JPM", "NVDA"}
+	if u, err = m.Refresh(context.Background(), "dow"); err != nil || u.Version != 2 || u.LastError != "" {
+		t.Fatalf("refresh = %+v, %v", u, err)
+	}
+	reopened, _ := New(dir)
+	if got, _ := reopened.Get("dow"); got.Version != 2 || !reflect.DeepEqual(got.Symbols, []string{"AAPL", "JPM", "MSFT", "NVDA"}) {
+		t.Errorf("reopened = %+v", got)
+	}
+	if versions, _ := reopened.Versions("dow"); len(versions) != 2 || versions[0].Origin != OriginRefresh || versions[0].Removed[0] != "KO" {
+		t.Errorf("versions = %+v", versions)
+	}
+}
+
+func TestParseFormats(t *testing.T) {
+	cases := []struct {
+		src  Source
+		data string
+		want []string
+	}{
+		{Source{URL: "https://example.com/sp500.csv"}, "Symbol,Name\nAAPL,Apple\n\"BRK.B\",\"Berkshire, Class B\"\n", []string{"AAPL", "BRK.B"}},
+		{Source{URL: "https://example.com/list", Format: "csv", Field: "Ticker"}, "name,ticker\nApple,AAPL\n", []string{"AAPL"}},
+		{Source{URL: "https://example.com/x.json"}, `["AAPL", "MSFT"]`, []string{"AAPL", "MSFT"}},
+		{Source{URL: "https://example.com/x.json"}, `[{"Symbol": "AAPL", "weight": 7}, {"symbol": "MSFT"}]`, []string{"AAPL", "MSFT"}},
+		{Source{URL: "https://example.com/x.txt"}, "# tech\nAAPL\n\nMSFT # software\n", []string{"AAPL", "MSFT"}},
+	}
+	for _, c := range cases {
+		got, err := Parse([]byte(c.data), c.src)
+		if err != nil || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s %s = %v, %v", c.src.URL, c.src.Format, got, err)
+		}
+	}
+	if _, err := Parse([]byte("name\nApple\n"), Source{Format: "csv"}); err == nil {
+		t.Error("CSV without a symbol column accepted")
+	}
+}
+
+func TestHandlerDiffsVersions(t *testing.T) {
+	m, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Put(Universe{Name: "energy", Symbols: []string{"XOM", "CVX", "COP"}})
+	m.Put(Universe{Name: "energy", Symbols: []string{"XOM", "CVX", "EOG"}})
+	mux := http.NewServeMux()
+	NewHandler(m).RegisterRoutes(mux)
+
+	for path, want := range map[string]string{
+		"/api/universes/energy/diff":             `"from":"energy@1","to":"energy@2","added":["EOG"],"removed":["COP"],"unchanged":2`,
+		"/api/universes/energy/diff?from=2&to=1": `"added":["COP"],"removed":["EOG"]`,
+		"/api/universes/energy?version=1":        `"symbols":["COP","CVX","XOM"]`,
+		"/api/universes":                         `"name":"energy","size":3,"version":2`,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("%s = %d %s", path, rec.Code, rec.Body.String())
+		}
+	}
+	for path, want := range map[string]int{
+		"/api/universes/solar":                     http.StatusNotFound,
+		"/api/universes/energy/diff?against=solar": http.StatusNotFound,
+		"/api/universes/energy/diff?from=x":        http.StatusBadRequest,
+		"/api/universes/energy/versions":           http.StatusOK,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("%s = %d, want %d", path, rec.Code, want)
+		}
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/universes/energy/refresh", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("refresh without a source = %d", rec.Code)
+	}
+}
@@ -0,0 +1,108 @@
+package users
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type contextKey struct{}
+
+// FromContext returns the user a request was authenticated as, or the
+// default user when authentication is off.
+func FromContext(ctx context.Context) User {
+	if u, ok := ctx.Value(contextKey{}).(User); ok {
+		return u
+	}
+	return defaultUser()
+}
+
+// WithUser returns ctx carrying u, for handlers called outside the
+// middleware and for tests.
+func WithUser(ctx context.Context, u User) context.Context {
+	return context.WithValue(ctx, contextKey{}, u)
+}
+
+// Scope returns the user ID a request acts for: the caller's own, or for
+// an admin the ?user= query parameter when it names an existing user or
+// the default user, whose resources predate authentication.
+func (s *Store) Scope(r *http.Request) (string, error) {
+	u := FromContext(r.Context())
+	id := r.URL.Query().Get("user")
+	if id == "" || id == u.ID {
+		return u.ID, nil
+	}
+	if !u.IsAdmin() {
+		return "", ErrForbidden
+	}
+	if id == DefaultID {
+		return id, nil
+	}
+	if _, err := s.Get(id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// bearerToken returns the request's API token from an "Authorization:
+// Bearer" or X-API-Key header.
+func bearerToken(r *http.Request) string {
+	if v, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(v)
+	}
+	return strings.TrimSpace(r.Header.Get("X-API-Key"))
+}
+
+// Caller identifies who made a request, for the audit log: the user ID the
+// token belongs to, or nothing while authentication is off.
+func (s *Store) Caller(r *http.Request) string {
+	if u, ok := s.Authenticate(bearerToken(r)); ok {
+		return u.ID
+	}
+	return ""
+}
+
+// upgradeToken returns a WebSocket upgrade's API token: a header as for
+// any request, or the token query parameter, since browsers cannot set
+// headers on a WebSocket.
+func upgradeToken(r *http.Request) string {
+	if token := bearerToken(r); token != "" {
+		return token
+	}
+	return r.URL.Query().Get("token")
+}
+
+// Middleware authenticates /api/ requests and /ws/ WebSocket upgrades to
+// next once any user exists, refusing those without a valid token, and
+// records the user on the request context. Paths in public check their
+// own credentials, such as signatures, and pass through, as do other paths
+// and CORS preflights.
+func (s *Store) Middleware(next http.Handler, public ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		api, ws := strings.HasPrefix(r.URL.Path, "/api/"), strings.HasPrefix(r.URL.Path, "/ws/")
+		if !api && !ws || r.Method == http.MethodOptions || !s.Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		for _, p := range public {
+			if r.URL.Path == p {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+		token := bearerToken(r)
+		if ws {
+			token = upgradeToken(r)
+		}
+		u, ok := s.Authenticate(token)
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="go-trader"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "a valid API token is required"})
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), u)))
+	})
+}
@@ -0,0 +1,199 @@
+package users
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Handler exposes users and their settings over HTTP.
+type Handler struct {
+	store *Store
+}
+
+// NewHandler creates a handler for store.
+func NewHandler(store *Store) *Handler {
+	return &Handler{store: store}
+}
+
+// RegisterRoutes registers the user routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/me - the caller and their settings
+	mux.HandleFunc("/api/me", h.handleMe)
+
+	// GET/POST /api/me/settings - watchlist, notification preferences and
+	// manual control; admins may pass ?user=
+	mux.HandleFunc("/api/me/settings", h.handleSettings)
+
+	// GET/POST /api/users - every user with their settings, or create one
+	mux.HandleFunc("/api/users", h.handleUsers)
+
+	// GET/DELETE /api/users/{id} - one user, or remove them
+	// POST /api/users/{id}/token - issue a new token
+	mux.HandleFunc("/api/users/", h.handleUser)
+}
+
+// Profile is a user as the API shows them.
+type Profile struct {
+	User     User     `json:"user"`
+	Settings Settings `json:"settings"`
+}
+
+func (h *Handler) profile(u User) Profile {
+	u.TokenHash = ""
+	return Profile{User: u, Settings: h.store.Settings(u.ID)}
+}
+
+// requireAdmin writes a 403 unless the caller is an admin.
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if !FromContext(r.Context()).IsAdmin() {
+		http.Error(w, ErrForbidden.Error(), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	http.Error(w, err.Error(), HTTPStatus(err))
+}
+
+func (h *Handler) handleMe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"profile":      h.profile(FromContext(r.Context())),
+		"auth_enabled": h.store.Enabled(),
+	})
+}
+
+func (h *Handler) handleSettings(w http.ResponseWriter, r *http.Request) {
+	id, err := h.store.Scope(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.store.Settings(id))
+	case http.MethodPost:
+		// Decode onto the current settings so partial updates work
+		update := h.store.Settings(id)
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		settings, err := h.store.UpdateSettings(id, func(s *Settings) { *s = update })
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(settings)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleUsers(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		// The admin view across users, the default user's resources first
+		out := []Profile{h.profile(defaultUser())}
+		for _, u := range h.store.List() {
+			out = append(out, h.profile(u))
+		}
+		json.NewEncoder(w).Encode(out)
+	case http.MethodPost:
+		var req struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			Role string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Role == "" {
+			req.Role = RoleUser
+		}
+		u, token, err := h.store.Create(req.ID, req.Name, req.Role)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(map[string]interface{}{"profile": h.profile(u), "token": token})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleUser(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/users/"), "/")
+	if id == "" || (action != "" && action != "token") {
+		http.NotFound(w, r)
+		return
+	}
+	caller := FromContext(r.Context())
+
+	if action == "token" {
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		// Users may rotate their own token
+		if id != caller.ID && !requireAdmin(w, r) {
+			return
+		}
+		token, err := h.store.RotateToken(id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id": id, "token": token})
+		return
+	}
+
+	if !requireAdmin(w, r) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		u, err := h.store.Get(id)
+		if err != nil && id == DefaultID {
+			u, err = defaultUser(), nil
+		}
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(h.profile(u))
+	case http.MethodDelete:
+		if err := h.store.Delete(id); err != nil {
+			writeError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"message": "User deleted", "id": id})
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HTTPStatus maps the store's errors to response codes, for handlers of
+// other packages' user-scoped resources.
+func HTTPStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, ErrExists):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,423 @@
+// Package users holds API users, their bearer tokens and the resources
+// kept per user: a watchlist, notification preferences and the manual
+// control setting. Baskets live in each user's data directory.
+//
+// Authentication is off until the first user is created. Until then every
+// request acts as the built-in default user, an admin whose baskets are the
+// ones kept in the data directory's root, so a single-user install keeps
+// working unchanged.
+package users
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+func logger() *slog.Logger { return slog.With("module", "users") }
+
+// Roles.
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+// DefaultID is the user requests act as while authentication is off.
+const DefaultID = "default"
+
+// tokenPrefix marks API tokens so they are recognisable in configs and
+// redacted from logs like other bearer tokens.
+const tokenPrefix = "gt_"
+
+var (
+	// ErrNotFound is returned for an unknown user ID.
+	ErrNotFound = errors.New("user not found")
+	// ErrExists is returned when creating a user whose ID is taken.
+	ErrExists = errors.New("user already exists")
+	// ErrForbidden is returned when a user acts for another without the
+	// admin role.
+	ErrForbidden = errors.New("admin role required")
+
+	validID = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+)
+
+// User is an API user. The token itself is never stored, only its hash.
+type User struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name,omitempty"`
+	Role      string    `json:"role"`
+	TokenHash string    `json:"token_hash,omitempty"` // blanked in API responses
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsAdmin reports whether u may manage users and act for others.
+func (u User) IsAdmin() bool { return u.Role == RoleAdmin }
+
+// NotificationPrefs select the notifications a user sees.
+type NotificationPrefs struct {
+	MinPriority string   `json:"min_priority,omitempty"` // low (default), medium or high
+	MutedTypes  []string `json:"muted_types,omitempty"`  // e.g. market_event
+}
+
+var priorityRank = map[string]int{"": 0, "low": 0, "medium": 1, "high": 2}
+
+// Validate checks the preferences for known priorities.
+func (p NotificationPrefs) Validate() error {
+	if _, ok := priorityRank[p.MinPriority]; !ok {
+		return errors.New("min_priority must be low, medium or high")
+	}
+	return nil
+}
+
+// Allows reports whether a notification of priority and kind passes the
+// preferences.
+func (p NotificationPrefs) Allows(priority, kind string) bool {
+	if priorityRank[priority] < priorityRank[p.MinPriority] {
+		return false
+	}
+	for _, t := range p.MutedTypes {
+		if t == kind {
+			return false
+		}
+	}
+	return true
+}
+
+// Settings are one user's resources.
+type Settings struct {
+	Watchlist     []string          `json:"watchlist"`
+	Notifications NotificationPrefs `json:"notifications"`
+	ManualControl bool              `json:"manual_control"`
+	UpdatedAt     time.Time         `json:"updated_at,omitempty"`
+}
+
+func (st Settings) clone() Settings {
+	st.Watchlist = append([]string{}, st.Watchlist...)
+	st.Notifications.MutedTypes = append([]string(nil), st.Notifications.MutedTypes...)
+	return st
+}
+
+// Store keeps users in <dir>/users.json and each user's settings in
+// <dir>/<id>/settings.json. It is safe for concurrent use.
+type Store struct {
+	dir string
+
+	mu       sync.Mutex
+	users    map[string]User
+	byToken  map[string]string // token hash to user ID
+	settings map[string]Settings
+	now      func() time.Time
+}
+
+// Open loads the users saved under dir.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create users directory: %w", err)
+	}
+	s := &Store{
+		dir:      dir,
+		users:    make(map[string]User),
+		byToken:  make(map[string]string),
+		settings: make(map[string]Settings),
+		now:      time.Now,
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "users.json"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read users: %w", err)
+	}
+	if err == nil {
+		var list []User
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, fmt.Errorf("failed to decode users: %w", err)
+		}
+		for _, u := range list {
+			s.users[u.ID] = u
+			s.byToken[u.TokenHash] = u.ID
+		}
+	}
+	return s, nil
+}
+
+// Enabled reports whether authentication is on, i.e. any user exists.
+func (s *Store) Enabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.users) > 0
+}
+
+// List returns the users sorted by ID.
+func (s *Store) List() []User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		out = append(out, u)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Get returns one user. The default user exists while authentication is
+// off.
+func (s *Store) Get(id string) (User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if u, ok := s.users[id]; ok {
+		return u, nil
+	}
+	if id == DefaultID && len(s.users) == 0 {
+		return defaultUser(), nil
+	}
+	return User{}, ErrNotFound
+}
+
+func defaultUser() User {
+	return User{ID: DefaultID, Name: "Default", Role: RoleAdmin}
+}
+
+// Create adds a user and returns its token, which is not kept and cannot
+// be shown again. The first user must be an admin, since creating it
+// turns authentication on.
+func (s *Store) Create(id, name, role string) (User, string, error) {
+	id = strings.ToLower(strings.TrimSpace(id))
+	if !validID.MatchString(id) || id == DefaultID {
+		return User{}, "", fmt.Errorf("user ID must be 1-64 lowercase letters, digits, '.', '_' or '-' and not %q", DefaultID)
+	}
+	if role != RoleUser && role != RoleAdmin {
+		return User{}, "", errors.New("role must be user or admin")
+	}
+	token, hash, err := newToken()
+	if err != nil {
+		return User{}, "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[id]; ok {
+		return User{}, "", ErrExists
+	}
+	if len(s.users) == 0 && role != RoleAdmin {
+		return User{}, "", errors.New("the first user must be an admin")
+	}
+	u := User{ID: id, Name: name, Role: role, TokenHash: hash, CreatedAt: s.now()}
+	s.users[id] = u
+	s.byToken[hash] = id
+	if err := s.saveUsersLocked(); err != nil {
+		delete(s.users, id)
+		delete(s.byToken, hash)
+		return User{}, "", err
+	}
+	logger().Info("User created", "user", id, "role", role)
+	return u, token, nil
+}
+
+// RotateToken replaces a user's token and returns the new one.
+func (s *Store) RotateToken(id string) (string, error) {
+	token, hash, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return "", ErrNotFound
+	}
+	delete(s.byToken, u.TokenHash)
+	u.TokenHash = hash
+	s.users[id] = u
+	s.byToken[hash] = id
+	if err := s.saveUsersLocked(); err != nil {
+		return "", err
+	}
+	logger().Info("User token rotated", "user", id)
+	return token, nil
+}
+
+// Delete removes a user. Their settings and baskets stay on disk. The last
+// admin cannot be removed while other users remain.
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if u.IsAdmin() && len(s.users) > 1 {
+		admins := 0
+		for _, other := range s.users {
+			if other.IsAdmin() {
+				admins++
+			}
+		}
+		if admins == 1 {
+			return errors.New("cannot remove the last admin while other users remain")
+		}
+	}
+	delete(s.users, id)
+	delete(s.byToken, u.TokenHash)
+	if err := s.saveUsersLocked(); err != nil {
+		return err
+	}
+	logger().Info("User deleted", "user", id)
+	return nil
+}
+
+// Authenticate returns the user a token belongs to.
+func (s *Store) Authenticate(token string) (User, bool) {
+	if !strings.HasPrefix(token, tokenPrefix) {
+		return User{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.byToken[hashToken(token)]
+	if !ok {
+		return User{}, false
+	}
+	return s.users[id], true
+}
+
+// Dir returns the directory a user's files are kept in. The default user's
+// is the data directory root the store was opened under, so baskets from
+// before users existed stay with it.
+func (s *Store) Dir(id string) string {
+	if id == DefaultID {
+		return filepath.Dir(s.dir)
+	}
+	return filepath.Join(s.dir, id)
+}
+
+// Settings returns a copy of a user's settings.
+func (s *Store) Settings(id string) Settings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.settingsLocked(id).clone()
+}
+
+// UpdateSettings applies fn to a copy of a user's settings and saves the
+// result if it is valid.
+func (s *Store) UpdateSettings(id string, fn func(*Settings)) (Settings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.settingsLocked(id).clone()
+	fn(&st)
+	if err := st.Notifications.Validate(); err != nil {
+		return Settings{}, err
+	}
+	st.Watchlist = normalizeSymbols(st.Watchlist)
+	st.UpdatedAt = s.now()
+
+	path := filepath.Join(s.settingsDir(id), "settings.json")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return Settings{}, fmt.Errorf("failed to create settings directory: %w", err)
+	}
+	if err := writeJSON(path, st); err != nil {
+		return Settings{}, err
+	}
+	s.settings[id] = st
+	return st, nil
+}
+
+// WatchedByOthers returns the symbols on any watchlist but id's.
+func (s *Store) WatchedByOthers(id string) map[string]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := []string{DefaultID}
+	for uid := range s.users {
+		ids = append(ids, uid)
+	}
+	out := make(map[string]bool)
+	for _, uid := range ids {
+		if uid == id {
+			continue
+		}
+		for _, sym := range s.settingsLocked(uid).Watchlist {
+			out[sym] = true
+		}
+	}
+	return out
+}
+
+// settingsDir keeps the default user's settings beside the other users'
+// rather than in the data directory root.
+func (s *Store) settingsDir(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+func (s *Store) settingsLocked(id string) Settings {
+	if st, ok := s.settings[id]; ok {
+		return st
+	}
+	st := Settings{Watchlist: []string{}}
+	if data, err := os.ReadFile(filepath.Join(s.settingsDir(id), "settings.json")); err == nil {
+		if err := json.Unmarshal(data, &st); err != nil {
+			logger().Warn("Ignoring unreadable user settings", "user", id, "error", err)
+			st = Settings{Watchlist: []string{}}
+		}
+	}
+	s.settings[id] = st
+	return st
+}
+
+func (s *Store) saveUsersLocked() error {
+	list := make([]User, 0, len(s.users))
+	for _, u := range s.users {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return writeJSON(filepath.Join(s.dir, "users.json"), list)
+}
+
+// writeJSON saves v to path atomically.
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return os.Rename(tmp, path)
+}
+
+func newToken() (token, hash string, err error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	token = tokenPrefix + hex.EncodeToString(b)
+	return token, hashToken(token), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeSymbols upper-cases, trims and de-duplicates symbols, keeping
+// their order.
+func normalizeSymbols(symbols []string) []string {
+	out := make([]string, 0, len(symbols))
+	seen := make(map[string]bool)
+	for _, sym := range symbols {
+		sym = strings.ToUpper(strings.TrimSpace(sym))
+		if sym == "" || seen[sym] {
+			continue
+		}
+		seen[sym] = true
+		out = append(out, sym)
+	}
+	return out
+}
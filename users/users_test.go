@@ -0,0 +1,149 @@
+package users
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreCreateAuthenticateAndReload(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "users")
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Enabled() {
+		t.Fatal("authentication on with no users")
+	}
+	if _, err := s.Get(DefaultID); err != nil {
+		t.Fatalf("default user missing while auth is off: %v", err)
+	}
+
+	if _, _, err := s.Create("alice", "Alice", RoleUser); err == nil {
+		t.Fatal("first user created without the admin role")
+	}
+	_, adminToken, err := s.Create("root", "Root", RoleAdmin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, aliceToken, err := s.Create("Alice", "Alice", RoleUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := s.Create("alice", "", RoleUser); err != ErrExists {
+		t.Fatalf("duplicate create: %v", err)
+	}
+	if _, err := s.Get(DefaultID); err != ErrNotFound {
+		t.Fatalf("default user still present once users exist: %v", err)
+	}
+
+	if u, ok := s.Authenticate(aliceToken); !ok || u.ID != "alice" {
+		t.Fatalf("authenticate alice: %+v %v", u, ok)
+	}
+	if _, ok := s.Authenticate("gt_nope"); ok {
+		t.Fatal("unknown token accepted")
+	}
+	if err := s.Delete("root"); err == nil {
+		t.Fatal("last admin deleted while another user remains")
+	}
+
+	if _, err := s.UpdateSettings("alice", func(st *Settings) {
+		st.Watchlist = []string{" aapl", "MSFT", "AAPL"}
+		st.Notifications.MinPriority = "medium"
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.UpdateSettings("alice", func(st *Settings) {
+		st.Notifications.MinPriority = "urgent"
+	}); err == nil {
+		t.Fatal("unknown priority accepted")
+	}
+
+	reopened, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, ok := reopened.Authenticate(adminToken); !ok || !u.IsAdmin() {
+		t.Fatalf("admin after reload: %+v %v", u, ok)
+	}
+	st := reopened.Settings("alice")
+	if len(st.Watchlist) != 2 || st.Watchlist[0] != "AAPL" || st.Watchlist[1] != "MSFT" {
+		t.Fatalf("watchlist = %v", st.Watchlist)
+	}
+	if st.Notifications.Allows("low", "market_event") || !st.Notifications.Allows("high", "market_event") {
+		t.Fatalf("preferences not applied: %+v", st.Notifications)
+	}
+	if others := reopened.WatchedByOthers("root"); !others["AAPL"] || others["SPY"] {
+		t.Fatalf("watched by others = %v", others)
+	}
+}
+
+func TestMiddlewareAndScope(t *testing.T) {
+	s, err := Open(filepath.Join(t.TempDir(), "users"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var scoped string
+	var scopeErr error
+	h := s.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scoped, scopeErr = s.Scope(r)
+	}), "/api/webhooks/tradingview")
+
+	do := func(path, token string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Off until the first user exists
+	if code := do("/api/baskets", ""); code != http.StatusOK || scoped != DefaultID {
+		t.Fatalf("auth off: %d %q", code, scoped)
+	}
+
+	_, adminToken, _ := s.Create("root", "", RoleAdmin)
+	_, bobToken, _ := s.Create("bob", "", RoleUser)
+
+	if code := do("/api/baskets", ""); code != http.StatusUnauthorized {
+		t.Fatalf("no token: %d", code)
+	}
+	if code := do("/api/baskets", "gt_wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("bad token: %d", code)
+	}
+	if code := do("/api/webhooks/tradingview", ""); code != http.StatusOK {
+		t.Fatalf("public path: %d", code)
+	}
+	if code := do("/", ""); code != http.StatusOK {
+		t.Fatalf("dashboard: %d", code)
+	}
+	// WebSockets need a token too, in the query when the browser opens them
+	if code := do("/ws/portfolio", ""); code != http.StatusUnauthorized {
+		t.Fatalf("websocket without a token: %d", code)
+	}
+	if code := do("/ws/portfolio?token="+bobToken, ""); code != http.StatusOK || scoped != "bob" {
+		t.Fatalf("websocket with a query token: %d %q", code, scoped)
+	}
+	if code := do("/api/baskets?token="+bobToken, ""); code != http.StatusUnauthorized {
+		t.Fatalf("API token in the query: %d", code)
+	}
+
+	if do("/api/baskets", bobToken); scoped != "bob" || scopeErr != nil {
+		t.Fatalf("bob scope: %q %v", scoped, scopeErr)
+	}
+	if do("/api/baskets?user=root", bobToken); scopeErr != ErrForbidden {
+		t.Fatalf("bob acting for root: %v", scopeErr)
+	}
+	if do("/api/baskets?user=bob", adminToken); scoped != "bob" || scopeErr != nil {
+		t.Fatalf("admin acting for bob: %q %v", scoped, scopeErr)
+	}
+	if do("/api/baskets?user=default", adminToken); scoped != DefaultID {
+		t.Fatalf("admin acting for default: %q %v", scoped, scopeErr)
+	}
+	if do("/api/baskets?user=nobody", adminToken); scopeErr != ErrNotFound {
+		t.Fatalf("admin acting for unknown user: %v", scopeErr)
+	}
+}
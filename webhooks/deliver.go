@@ -0,0 +1,148 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Request headers. The signature is "sha256=" and the hex HMAC-SHA256,
+// keyed with the endpoint's secret, of the timestamp header, a dot and the
+// body; receivers should recompute it and reject stale timestamps.
+const (
+	HeaderEvent     = "X-GoTrader-Event"
+	HeaderDelivery  = "X-GoTrader-Delivery"
+	HeaderTimestamp = "X-GoTrader-Timestamp"
+	HeaderSignature = "X-GoTrader-Signature"
+)
+
+// Sign returns the signature header value for body sent at timestamp
+// (Unix seconds).
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Step attempts every pending delivery that is due, concurrently, and
+// waits for them.
+func (d *Dispatcher) Step(ctx context.Context) {
+	d.mu.Lock()
+	now := d.now()
+	policy := d.policy
+	type job struct {
+		dl     Delivery
+		secret string
+	}
+	var jobs []job
+	for id, dl := range d.queue {
+		if d.inFlight[id] || dl.NextAttempt.After(now) {
+			continue
+		}
+		e, ok := d.endpoints[dl.EndpointID]
+		if !ok {
+			delete(d.queue, id)
+			d.finishLocked(dl, StatusDiscarded, "endpoint removed")
+			continue
+		}
+		d.inFlight[id] = true
+		jobs = append(jobs, job{dl: *dl, secret: e.Secret})
+	}
+	d.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, j := range jobs {
+		wg.Add(1)
+		go func(dl Delivery, secret string) {
+			defer wg.Done()
+			code, err := d.send(ctx, dl, secret, time.Duration(policy.TimeoutSeconds)*time.Second)
+			d.record(dl.ID, code, err, policy)
+		}(j.dl, j.secret)
+	}
+	wg.Wait()
+}
+
+// send posts one attempt and returns the response status.
+func (d *Dispatcher) send(ctx context.Context, dl Delivery, secret string, timeout time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dl.URL, bytes.NewReader(dl.Body))
+	if err != nil {
+		return 0, err
+	}
+	ts := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-trader-webhooks/1")
+	req.Header.Set(HeaderEvent, dl.Event)
+	req.Header.Set(HeaderDelivery, dl.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, ts, dl.Body))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// record applies an attempt's outcome: delivered, retried after a backoff
+// or dead-lettered. Client errors other than timeouts and rate limits are
+// not retried.
+func (d *Dispatcher) record(id string, code int, err error, policy Policy) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.inFlight, id)
+	dl, ok := d.queue[id]
+	if !ok {
+		return
+	}
+	dl.Attempts++
+	dl.LastCode = code
+	if err == nil {
+		dl.LastError = ""
+		delete(d.queue, id)
+		d.finishLocked(dl, StatusDelivered, "")
+		return
+	}
+	dl.LastError = err.Error()
+	permanent := code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+	if permanent || dl.Attempts >= policy.MaxAttempts {
+		delete(d.queue, id)
+		d.finishLocked(dl, StatusDead, "")
+		logger().Warn("Webhook delivery dead-lettered", "id", id, "url", dl.URL, "event", dl.Event, "attempts", dl.Attempts, "error", err)
+		return
+	}
+	dl.NextAttempt = d.now().Add(policy.backoff(dl.Attempts))
+	d.writeLocked(*dl)
+	logger().Info("Webhook delivery failed, retrying", "id", id, "url", dl.URL, "attempts", dl.Attempts, "next_attempt", dl.NextAttempt, "error", err)
+}
+
+// Run delivers queued events until ctx is done, checking every second and
+// as soon as something is queued.
+func (d *Dispatcher) Run(ctx context.Context) {
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		case <-d.wake:
+		}
+		d.Step(ctx)
+	}
+}
@@ -0,0 +1,179 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/rileyseaburg/go-trader/paging"
+)
+
+// Handler exposes the webhooks over HTTP.
+type Handler struct {
+	dispatcher *Dispatcher
+}
+
+// NewHandler creates a handler for dispatcher.
+func NewHandler(dispatcher *Dispatcher) *Handler {
+	return &Handler{dispatcher: dispatcher}
+}
+
+// RegisterRoutes registers the webhook routes with mux.
+func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+	// GET /api/webhooks - registered endpoints, without secrets
+	// POST /api/webhooks - register {"url", "events", "secret", "description"}
+	mux.HandleFunc("/api/webhooks", h.handleEndpoints)
+
+	// DELETE /api/webhooks/{id} - remove an endpoint
+	// POST /api/webhooks/{id}/enable, /disable - resume or pause an endpoint
+	// POST /api/webhooks/{id}/test - send a test event
+	mux.HandleFunc("/api/webhooks/", h.handleEndpoint)
+
+	// GET /api/webhooks/deliveries?status=&limit= - queued, delivered and dead-lettered deliveries, newest first
+	mux.HandleFunc("/api/webhooks/deliveries", h.handleDeliveries)
+
+	// POST /api/webhooks/deliveries/{id}/retry, /discard - redeliver or drop a dead letter
+	mux.HandleFunc("/api/webhooks/deliveries/", h.handleDelivery)
+
+	// GET/POST /api/webhooks/policy - read or update the retry policy
+	mux.HandleFunc("/api/webhooks/policy", h.handlePolicy)
+}
+
+func (h *Handler) handleEndpoints(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"endpoints": h.dispatcher.Endpoints(),
+			"events":    Events,
+		})
+	case http.MethodPost:
+		var req Endpoint
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		e, err := h.dispatcher.Register(Endpoint{URL: req.URL, Secret: req.Secret, Events: req.Events, Description: req.Description})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(e)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleEndpoint(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/webhooks/"), "/")
+	var (
+		result interface{}
+		err    error
+	)
+	switch {
+	case id == "":
+		http.NotFound(w, r)
+		return
+	case action == "" && r.Method == http.MethodDelete:
+		err = h.dispatcher.Remove(id)
+		result = map[string]interface{}{"success": true, "removed": id}
+	case (action == "enable" || action == "disable") && r.Method == http.MethodPost:
+		result, err = h.dispatcher.SetEnabled(id, action == "enable")
+	case action == "test" && r.Method == http.MethodPost:
+		result, err = h.dispatcher.Test(id)
+	case action == "" || action == "enable" || action == "disable" || action == "test":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *Handler) handleDeliveries(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	status := q.Get("status")
+	switch status {
+	case "", StatusPending, StatusDelivered, StatusDead, StatusDiscarded:
+	default:
+		http.Error(w, "status must be pending, delivered, dead or discarded", http.StatusBadRequest)
+		return
+	}
+	deliveryList.Serve(w, r, h.dispatcher.Deliveries(status, 0))
+}
+
+// deliveryList pages the deliveries, newest first.
+var deliveryList = paging.List[Delivery]{
+	Fields: []paging.Field[Delivery]{
+		{Name: "created_at", Sort: func(d Delivery) paging.Key { return paging.Time(d.CreatedAt) }},
+		{Name: "attempts", Sort: func(d Delivery) paging.Key { return paging.Number(float64(d.Attempts)) }},
+		{Name: "event", Sort: func(d Delivery) paging.Key { return paging.Text(d.Event) }, Filter: func(d Delivery) string { return d.Event }},
+		{Name: "endpoint_id", Filter: func(d Delivery) string { return d.EndpointID }},
+	},
+	ID:   func(d Delivery) string { return d.ID },
+	Sort: "-created_at",
+}
+
+func (h *Handler) handleDelivery(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/webhooks/deliveries/"), "/")
+	if id == "" || (action != "retry" && action != "discard") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var (
+		result interface{}
+		err    error
+	)
+	if action == "retry" {
+		result, err = h.dispatcher.Redeliver(id)
+	} else {
+		err = h.dispatcher.Discard(id)
+		result = map[string]interface{}{"success": true, "discarded": id}
+	}
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "Dead letter not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *Handler) handlePolicy(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(h.dispatcher.Policy())
+	case http.MethodPost, http.MethodPut:
+		// Start from the current policy so partial updates work
+		policy := h.dispatcher.Policy()
+		if err := json.NewDecoder(r.Body).Decode(&policy); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := h.dispatcher.SetPolicy(policy); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(policy)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,595 @@
+// Package webhooks posts trading events to registered URLs. Each delivery
+// is signed with the endpoint's secret, retried with exponential backoff
+// on failure and, once its attempts are used up, parked in a dead-letter
+// queue where it can be inspected and redelivered. Endpoints and every
+// delivery's state are kept on disk, so queued deliveries survive a
+// restart.
+package webhooks
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+func logger() *slog.Logger { return slog.With("module", "webhooks") }
+
+// Events.
+const (
+	EventSignalGenerated = "signal_generated"
+	EventOrderFilled     = "order_filled"
+	EventRiskBreach      = "risk_breach"
+	EventTest            = "test" // sent on request to one endpoint
+)
+
+// Events lists the events endpoints can subscribe to.
+var Events = []string{EventSignalGenerated, EventOrderFilled, EventRiskBreach}
+
+// Delivery states.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"      // attempts used up or refused; in the dead-letter queue
+	StatusDiscarded = "discarded" // removed from the dead-letter queue
+)
+
+const (
+	// maxRecent bounds the finished deliveries kept in memory; the journal
+	// keeps everything.
+	maxRecent = 500
+	// maxQueue bounds pending deliveries; past it the oldest are
+	// dead-lettered.
+	maxQueue = 1000
+)
+
+// ErrNotFound is returned for an unknown endpoint or delivery.
+var ErrNotFound = errors.New("not found")
+
+// Policy configures delivery.
+type Policy struct {
+	MaxAttempts           int `json:"max_attempts"`
+	InitialBackoffSeconds int `json:"initial_backoff_seconds"` // doubled after each failure
+	MaxBackoffSeconds     int `json:"max_backoff_seconds"`
+	TimeoutSeconds        int `json:"timeout_seconds"` // per attempt
+}
+
+// DefaultPolicy tries six times over about five minutes with ten-second
+// timeouts.
+func DefaultPolicy() Policy {
+	return Policy{MaxAttempts: 6, InitialBackoffSeconds: 10, MaxBackoffSeconds: 300, TimeoutSeconds: 10}
+}
+
+// Validate checks the policy for usable values.
+func (p Policy) Validate() error {
+	if p.MaxAttempts < 1 || p.MaxAttempts > 50 {
+		return errors.New("max_attempts must be between 1 and 50")
+	}
+	if p.InitialBackoffSeconds < 1 {
+		return errors.New("initial_backoff_seconds must be at least 1")
+	}
+	if p.MaxBackoffSeconds < p.InitialBackoffSeconds {
+		return errors.New("max_backoff_seconds must be at least initial_backoff_seconds")
+	}
+	if p.TimeoutSeconds < 1 || p.TimeoutSeconds > 120 {
+		return errors.New("timeout_seconds must be between 1 and 120")
+	}
+	return nil
+}
+
+// backoff returns the wait after the given number of failed attempts.
+func (p Policy) backoff(attempts int) time.Duration {
+	d := time.Duration(p.InitialBackoffSeconds) * time.Second
+	for i := 1; i < attempts && d < time.Duration(p.MaxBackoffSeconds)*time.Second; i++ {
+		d *= 2
+	}
+	if max := time.Duration(p.MaxBackoffSeconds) * time.Second; d > max {
+		d = max
+	}
+	return d
+}
+
+// Endpoint is a registered URL. Events empty subscribes to every event.
+type Endpoint struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret,omitempty"` // only returned when the endpoint is created
+	Events      []string  `json:"events"`
+	Enabled     bool      `json:"enabled"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// wants reports whether e receives event.
+func (e *Endpoint) wants(event string) bool {
+	if !e.Enabled {
+		return false
+	}
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, ev := range e.Events {
+		if ev == event {
+			return true
+		}
+	}
+	return false
+}
+
+// redacted returns e without its secret, for listings.
+func (e Endpoint) redacted() Endpoint {
+	e.Secret = ""
+	e.Events = append([]string{}, e.Events...)
+	return e
+}
+
+// Envelope is the body posted for an event.
+type Envelope struct {
+	ID    string          `json:"id"` // shared by every endpoint's delivery of the event
+	Event string          `json:"event"`
+	Time  time.Time       `json:"time"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// Delivery is one event on its way to one endpoint.
+type Delivery struct {
+	ID          string          `json:"id"`
+	EndpointID  string          `json:"endpoint_id"`
+	URL         string          `json:"url"`
+	Event       string          `json:"event"`
+	Body        json.RawMessage `json:"body"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	LastCode    int             `json:"last_code,omitempty"`
+	LastError   string          `json:"last_error,omitempty"`
+	NextAttempt time.Time       `json:"next_attempt,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	FinishedAt  *time.Time      `json:"finished_at,omitempty"`
+}
+
+// Dispatcher holds the endpoints and delivers events to them. It is safe
+// for concurrent use.
+type Dispatcher struct {
+	endpointsPath string
+	client        *http.Client
+	wake          chan struct{}
+
+	mu        sync.Mutex
+	policy    Policy
+	endpoints map[string]*Endpoint
+	queue     map[string]*Delivery // pending, by ID
+	inFlight  map[string]bool
+	dead      map[string]*Delivery
+	recent    []Delivery
+	journal   *os.File
+	seq       int
+	now       func() time.Time
+}
+
+// New opens the endpoints and delivery journal in dir. Deliveries still
+// pending when the process stopped are queued again.
+func New(dir string, policy Policy) (*Dispatcher, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create webhooks directory: %w", err)
+	}
+	d := &Dispatcher{
+		endpointsPath: filepath.Join(dir, "endpoints.json"),
+		client:        &http.Client{},
+		wake:          make(chan struct{}, 1),
+		policy:        policy,
+		endpoints:     make(map[string]*Endpoint),
+		queue:         make(map[string]*Delivery),
+		inFlight:      make(map[string]bool),
+		dead:          make(map[string]*Delivery),
+		now:           time.Now,
+	}
+
+	if data, err := os.ReadFile(d.endpointsPath); err == nil {
+		var list []*Endpoint
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, fmt.Errorf("failed to decode webhook endpoints: %w", err)
+		}
+		for _, e := range list {
+			d.endpoints[e.ID] = e
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read webhook endpoints: %w", err)
+	}
+
+	path := filepath.Join(dir, "deliveries.jsonl")
+	if f, err := os.Open(path); err == nil {
+		// The last line for a delivery is its current state
+		latest := make(map[string]Delivery)
+		var order []string
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+		for scanner.Scan() {
+			var dl Delivery
+			if err := json.Unmarshal(scanner.Bytes(), &dl); err != nil {
+				logger().Warn("Skipping malformed webhook delivery journal line", "error", err)
+				continue
+			}
+			if _, seen := latest[dl.ID]; !seen {
+				order = append(order, dl.ID)
+			}
+			latest[dl.ID] = dl
+		}
+		f.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read webhook delivery journal: %w", err)
+		}
+		for _, id := range order {
+			dl := latest[id]
+			switch dl.Status {
+			case StatusPending:
+				d.queue[id] = &dl
+			case StatusDead:
+				d.dead[id] = &dl
+			default:
+				d.remember(dl)
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to open webhook delivery journal: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open webhook delivery journal for writing: %w", err)
+	}
+	d.journal = f
+	return d, nil
+}
+
+// SetClock replaces the clock, for replays and tests.
+func (d *Dispatcher) SetClock(now func() time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.now = now
+}
+
+// Policy returns the current policy.
+func (d *Dispatcher) Policy() Policy {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.policy
+}
+
+// SetPolicy validates and replaces the policy.
+func (d *Dispatcher) SetPolicy(p Policy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.policy = p
+	return nil
+}
+
+// Register validates and adds an endpoint. Without a secret one is
+// generated. The returned endpoint is the only copy that includes the
+// secret.
+func (d *Dispatcher) Register(e Endpoint) (Endpoint, error) {
+	u, err := url.Parse(e.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Endpoint{}, errors.New("url must be an absolute http or https URL")
+	}
+	for _, ev := range e.Events {
+		if !knownEvent(ev) {
+			return Endpoint{}, fmt.Errorf("unknown event %q (want %s)", ev, strings.Join(Events, ", "))
+		}
+	}
+	if e.Secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return Endpoint{}, fmt.Errorf("failed to generate secret: %w", err)
+		}
+		e.Secret = hex.EncodeToString(b)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.seq++
+	e.ID = fmt.Sprintf("wh_%d_%d", d.now().UnixNano(), d.seq)
+	e.CreatedAt = d.now()
+	e.Enabled = true
+	stored := e
+	d.endpoints[e.ID] = &stored
+	if err := d.saveEndpointsLocked(); err != nil {
+		delete(d.endpoints, e.ID)
+		return Endpoint{}, err
+	}
+	logger().Info("Registered webhook", "id", e.ID, "url", e.URL, "events", e.Events)
+	return e, nil
+}
+
+// SetEnabled pauses or resumes an endpoint.
+func (d *Dispatcher) SetEnabled(id string, enabled bool) (Endpoint, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.endpoints[id]
+	if !ok {
+		return Endpoint{}, ErrNotFound
+	}
+	e.Enabled = enabled
+	if err := d.saveEndpointsLocked(); err != nil {
+		return Endpoint{}, err
+	}
+	return e.redacted(), nil
+}
+
+// Remove deletes an endpoint. Its queued deliveries are dropped.
+func (d *Dispatcher) Remove(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.endpoints[id]; !ok {
+		return ErrNotFound
+	}
+	delete(d.endpoints, id)
+	for qid, dl := range d.queue {
+		if dl.EndpointID == id {
+			delete(d.queue, qid)
+			d.finishLocked(dl, StatusDiscarded, "endpoint removed")
+		}
+	}
+	return d.saveEndpointsLocked()
+}
+
+// Endpoints lists the endpoints without their secrets, oldest first.
+func (d *Dispatcher) Endpoints() []Endpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make([]Endpoint, 0, len(d.endpoints))
+	for _, e := range d.endpoints {
+		out = append(out, e.redacted())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+func (d *Dispatcher) saveEndpointsLocked() error {
+	list := make([]*Endpoint, 0, len(d.endpoints))
+	for _, e := range d.endpoints {
+		list = append(list, e)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook endpoints: %w", err)
+	}
+	// Secrets live here; keep the file private
+	tmp := d.endpointsPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write webhook endpoints: %w", err)
+	}
+	if err := os.Rename(tmp, d.endpointsPath); err != nil {
+		return fmt.Errorf("failed to save webhook endpoints: %w", err)
+	}
+	return nil
+}
+
+// Emit queues event with data for every endpoint subscribed to it. It
+// does not wait for delivery.
+func (d *Dispatcher) Emit(event string, data interface{}) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var targets []*Endpoint
+	for _, e := range d.endpoints {
+		if e.wants(event) {
+			targets = append(targets, e)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+	d.queueLocked(event, data, targets)
+}
+
+// Test queues a test event for one endpoint, enabled or not.
+func (d *Dispatcher) Test(id string) (Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e, ok := d.endpoints[id]
+	if !ok {
+		return Delivery{}, ErrNotFound
+	}
+	out := d.queueLocked(EventTest, map[string]string{"message": "test delivery from go-trader"}, []*Endpoint{e})
+	if len(out) == 0 {
+		return Delivery{}, errors.New("failed to encode test event")
+	}
+	return out[0], nil
+}
+
+func (d *Dispatcher) queueLocked(event string, data interface{}, targets []*Endpoint) []Delivery {
+	now := d.now()
+	raw, err := json.Marshal(data)
+	if err != nil {
+		logger().Error("Failed to encode webhook event", "event", event, "error", err)
+		return nil
+	}
+	d.seq++
+	body, err := json.Marshal(Envelope{ID: fmt.Sprintf("evt_%d_%d", now.UnixNano(), d.seq), Event: event, Time: now, Data: raw})
+	if err != nil {
+		logger().Error("Failed to encode webhook envelope", "event", event, "error", err)
+		return nil
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].ID < targets[j].ID })
+	out := make([]Delivery, 0, len(targets))
+	for _, e := range targets {
+		d.seq++
+		dl := &Delivery{
+			ID:          fmt.Sprintf("dlv_%d_%d", now.UnixNano(), d.seq),
+			EndpointID:  e.ID,
+			URL:         e.URL,
+			Event:       event,
+			Body:        body,
+			Status:      StatusPending,
+			NextAttempt: now,
+			CreatedAt:   now,
+		}
+		d.queue[dl.ID] = dl
+		d.writeLocked(*dl)
+		out = append(out, *dl)
+	}
+	d.trimQueueLocked()
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return out
+}
+
+// trimQueueLocked dead-letters the oldest pending deliveries past maxQueue.
+func (d *Dispatcher) trimQueueLocked() {
+	if len(d.queue) <= maxQueue {
+		return
+	}
+	pending := make([]*Delivery, 0, len(d.queue))
+	for _, dl := range d.queue {
+		if !d.inFlight[dl.ID] {
+			pending = append(pending, dl)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].CreatedAt.Before(pending[j].CreatedAt) })
+	for _, dl := range pending {
+		if len(d.queue) <= maxQueue {
+			break
+		}
+		delete(d.queue, dl.ID)
+		dl.LastError = "queue full"
+		d.finishLocked(dl, StatusDead, "")
+	}
+}
+
+// finishLocked moves dl out of the queue into its final state.
+func (d *Dispatcher) finishLocked(dl *Delivery, status, note string) {
+	now := d.now()
+	dl.Status = status
+	dl.NextAttempt = time.Time{}
+	dl.FinishedAt = &now
+	if note != "" {
+		dl.LastError = note
+	}
+	if status == StatusDead {
+		d.dead[dl.ID] = dl
+	} else {
+		d.remember(*dl)
+	}
+	d.writeLocked(*dl)
+}
+
+func (d *Dispatcher) writeLocked(dl Delivery) {
+	if line, err := json.Marshal(dl); err != nil {
+		logger().Error("Failed to encode webhook delivery", "id", dl.ID, "error", err)
+	} else if _, err := d.journal.Write(append(line, '\n')); err != nil {
+		logger().Error("Failed to write webhook delivery", "id", dl.ID, "error", err)
+	}
+}
+
+func (d *Dispatcher) remember(dl Delivery) {
+	d.recent = append(d.recent, dl)
+	if len(d.recent) > maxRecent {
+		d.recent = d.recent[len(d.recent)-maxRecent:]
+	}
+}
+
+// Deliveries returns deliveries in status (pending, delivered, dead or
+// discarded; every status when empty), newest first.
+func (d *Dispatcher) Deliveries(status string, limit int) []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var all []Delivery
+	if status == "" || status == StatusPending {
+		for _, dl := range d.queue {
+			all = append(all, *dl)
+		}
+	}
+	if status == "" || status == StatusDead {
+		for _, dl := range d.dead {
+			all = append(all, *dl)
+		}
+	}
+	for _, dl := range d.recent {
+		if status == "" || dl.Status == status {
+			all = append(all, dl)
+		}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].CreatedAt.After(all[j].CreatedAt) })
+	if limit > 0 && len(all) > limit {
+		all = all[:limit]
+	}
+	if all == nil {
+		all = []Delivery{}
+	}
+	return all
+}
+
+// Redeliver moves a dead letter back into the queue with fresh attempts.
+func (d *Dispatcher) Redeliver(id string) (Delivery, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dl, ok := d.dead[id]
+	if !ok {
+		return Delivery{}, ErrNotFound
+	}
+	e, ok := d.endpoints[dl.EndpointID]
+	if !ok {
+		return Delivery{}, errors.New("the delivery's endpoint was removed")
+	}
+	delete(d.dead, id)
+	dl.URL = e.URL
+	dl.Status = StatusPending
+	dl.Attempts = 0
+	dl.NextAttempt = d.now()
+	dl.FinishedAt = nil
+	d.queue[id] = dl
+	d.writeLocked(*dl)
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return *dl, nil
+}
+
+// Discard drops a dead letter.
+func (d *Dispatcher) Discard(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	dl, ok := d.dead[id]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(d.dead, id)
+	d.finishLocked(dl, StatusDiscarded, "")
+	return nil
+}
+
+// Close closes the delivery journal.
+func (d *Dispatcher) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.journal.Close()
+}
+
+func knownEvent(event string) bool {
+	for _, ev := range Events {
+		if ev == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDeliveriesAreSignedRetriedAndDeadLettered(t *testing.T) {
+	var mu sync.Mutex
+	failing := true
+	var got []Envelope
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		if r.Header.Get(HeaderSignature) != Sign("s3cret", ts, body) {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if failing {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		var env Envelope
+		json.Unmarshal(body, &env)
+		got = append(got, env)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	policy := Policy{MaxAttempts: 2, InitialBackoffSeconds: 10, MaxBackoffSeconds: 60, TimeoutSeconds: 5}
+	d, err := New(dir, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 2, 15, 0, 0, 0, time.UTC)
+	d.SetClock(func() time.Time { return now })
+	ctx := context.Background()
+
+	if _, err := d.Register(Endpoint{URL: "ftp://example.com"}); err == nil {
+		t.Error("non-http URL accepted")
+	}
+	if _, err := d.Register(Endpoint{URL: srv.URL, Events: []string{"nope"}}); err == nil {
+		t.Error("unknown event accepted")
+	}
+	e, err := d.Register(Endpoint{URL: srv.URL, Secret: "s3cret", Events: []string{EventOrderFilled}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Endpoints()[0].Secret != "" {
+		t.Error("listing leaked the secret")
+	}
+
+	d.Emit(EventSignalGenerated, map[string]string{"symbol": "AAPL"}) // not subscribed
+	d.Emit(EventOrderFilled, map[string]string{"symbol": "AAPL"})
+	d.Step(ctx)
+	pending := d.Deliveries(StatusPending, 0)
+	if len(pending) != 1 || pending[0].Attempts != 1 || pending[0].LastCode != 503 {
+		t.Fatalf("pending = %+v", pending)
+	}
+
+	// Not due until the backoff passes
+	d.Step(ctx)
+	if d.Deliveries(StatusPending, 0)[0].Attempts != 1 {
+		t.Fatal("retried before the backoff")
+	}
+	now = now.Add(10 * time.Second)
+	d.Step(ctx)
+	dead := d.Deliveries(StatusDead, 0)
+	if len(dead) != 1 || dead[0].Attempts != 2 {
+		t.Fatalf("dead = %+v", dead)
+	}
+	d.Close()
+
+	// The dead letter survives a restart and can be redelivered
+	d, err = New(dir, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	d.SetClock(func() time.Time { return now })
+	mu.Lock()
+	failing = false
+	mu.Unlock()
+	if _, err := d.Redeliver(dead[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	d.Step(ctx)
+	if delivered := d.Deliveries(StatusDelivered, 0); len(delivered) != 1 || len(d.Deliveries(StatusDead, 0)) != 0 {
+		t.Fatalf("delivered = %+v", delivered)
+	}
+	if len(got) != 1 || got[0].Event != EventOrderFilled || string(got[0].Data) != `{"symbol":"AAPL"}` {
+		t.Fatalf("received = %+v", got)
+	}
+
+	// Refusals are not retried
+	if _, err := d.Register(Endpoint{URL: srv.URL, Secret: "wrong"}); err != nil {
+		t.Fatal(err)
+	}
+	d.Emit(EventRiskBreach, map[string]string{"title": "drawdown"})
+	d.Step(ctx)
+	if dead := d.Deliveries(StatusDead, 0); len(dead) != 1 || dead[0].Attempts != 1 || dead[0].LastCode != 401 {
+		t.Fatalf("refused = %+v", dead)
+	}
+	if err := d.Remove(e.ID); err != nil || len(d.Endpoints()) != 1 {
+		t.Fatalf("remove: %v, %+v", err, d.Endpoints())
+	}
+}